@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/annotate"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+type AnnotateCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	nameOrID    string
+	annotations []string
+}
+
+// NewAnnotateCommand returns the annotate command.
+func NewAnnotateCommand(rootCmd *RootCommand, app *kingpin.Application) *AnnotateCommand {
+	c := &AnnotateCommand{rootCmd: rootCmd}
+
+	c.Cmd = app.Command("annotate", "Set or remove free-form metadata of a sandbox (e.g. ticket URLs, owners, notes).")
+	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+	c.Cmd.Arg("annotations", "Annotations to set (KEY=VALUE) or remove (KEY-).").Required().StringsVar(&c.annotations)
+
+	return c
+}
+
+func (c AnnotateCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c AnnotateCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	req := annotate.Request{NameOrID: c.nameOrID, Set: map[string]string{}}
+	for _, a := range c.annotations {
+		if k, v, ok := strings.Cut(a, "="); ok {
+			req.Set[k] = v
+			continue
+		}
+		k, ok := strings.CutSuffix(a, "-")
+		if !ok {
+			return fmt.Errorf("invalid annotation %q, use KEY=VALUE to set it or KEY- to remove it: %w", a, model.ErrNotValid)
+		}
+		req.Remove = append(req.Remove, k)
+	}
+
+	// Initialize storage (SQLite).
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	svc, err := annotate.NewService(annotate.ServiceConfig{
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	sandbox, err := svc.Run(ctx, req)
+	if err != nil {
+		return fmt.Errorf("could not annotate sandbox: %w", err)
+	}
+
+	msg := fmt.Sprintf("Sandbox %s annotations updated", sandbox.Name)
+	if err := printSandboxResult(c.rootCmd, *sandbox, msg); err != nil {
+		return fmt.Errorf("could not print message: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/hostcapacity"
+	"github.com/slok/sbx/internal/capacity"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// CapacityCommand shows the host capacity and its allocation to the sandboxes.
+type CapacityCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+}
+
+// NewCapacityCommand returns the capacity command.
+func NewCapacityCommand(rootCmd *RootCommand, app *kingpin.Application) *CapacityCommand {
+	c := &CapacityCommand{rootCmd: rootCmd}
+
+	c.Cmd = app.Command("capacity", "Show the host capacity and the resources allocated to the sandboxes.")
+
+	return c
+}
+
+func (c CapacityCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c CapacityCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	planner, err := newCapacityPlanner(repo, c.rootCmd.VMsDir, model.AdmissionModeOff, logger)
+	if err != nil {
+		return err
+	}
+
+	svc, err := hostcapacity.NewService(hostcapacity.ServiceConfig{
+		Planner: planner,
+		Logger:  logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	hc, err := svc.Run(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get host capacity: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	if err := p.PrintHostCapacity(*hc); err != nil {
+		return fmt.Errorf("could not print host capacity: %w", err)
+	}
+
+	return nil
+}
+
+// newCapacityPlanner returns the capacity planner of the sandbox VMs dir with the default
+// overcommit ratios.
+func newCapacityPlanner(repo storage.Repository, vmsDir string, mode model.AdmissionMode, logger log.Logger) (*capacity.Planner, error) {
+	planner, err := capacity.NewPlanner(capacity.PlannerConfig{
+		Repository: repo,
+		DataDir:    vmsDir,
+		Mode:       mode,
+		Logger:     logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create capacity planner: %w", err)
+	}
+	return planner, nil
+}
@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/clipboard"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// ClipCommand is the parent command for the clipboard and drop directory subcommands.
+type ClipCommand struct {
+	Cmd *kingpin.CmdClause
+}
+
+// NewClipCommand returns the clip parent command.
+func NewClipCommand(app *kingpin.Application) *ClipCommand {
+	c := &ClipCommand{}
+
+	c.Cmd = app.Command("clip", "Move snippets and files between the host and a running sandbox clipboard and drop directory.")
+
+	return c
+}
+
+// newClipboardService returns the clipboard service using the engine of the sandbox.
+func newClipboardService(ctx context.Context, rootCmd *RootCommand, nameOrID string) (*clipboard.Service, error) {
+	logger := rootCmd.Logger
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    rootCmd.DBPath,
+		Namespace: rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create repository: %w", err)
+	}
+
+	sandbox, err := repo.GetSandboxByName(ctx, nameOrID)
+	if err != nil {
+		sandbox, err = repo.GetSandbox(ctx, nameOrID)
+		if err != nil {
+			return nil, fmt.Errorf("could not find sandbox: %w", err)
+		}
+	}
+
+	eng, err := newEngineFromConfig(sandbox.Config, repo, rootCmd.VMsDir, logger)
+	if err != nil {
+		return nil, fmt.Errorf("could not create engine: %w", err)
+	}
+
+	svc, err := clipboard.NewService(clipboard.ServiceConfig{
+		Engine:     eng,
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	return svc, nil
+}
@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/clipboard"
+)
+
+// ClipDropCommand copies local files into the sandbox drop directory.
+type ClipDropCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	nameOrID string
+	paths    []string
+}
+
+// NewClipDropCommand returns the clip drop command.
+func NewClipDropCommand(rootCmd *RootCommand, clipCmd *ClipCommand) *ClipDropCommand {
+	c := &ClipDropCommand{rootCmd: rootCmd}
+
+	c.Cmd = clipCmd.Cmd.Command("drop", "Copy local files or directories into the sandbox drop directory (/tmp/sbx/drop).")
+	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+	c.Cmd.Arg("paths", "Local files or directories.").Required().StringsVar(&c.paths)
+
+	return c
+}
+
+func (c ClipDropCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c ClipDropCommand) Run(ctx context.Context) error {
+	svc, err := newClipboardService(ctx, c.rootCmd, c.nameOrID)
+	if err != nil {
+		return err
+	}
+
+	paths, err := svc.Drop(ctx, clipboard.DropRequest{NameOrID: c.nameOrID, Paths: c.paths})
+	if err != nil {
+		return fmt.Errorf("could not drop files: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	return p.PrintMessage(fmt.Sprintf("Dropped into %s: %s", c.nameOrID, strings.Join(paths, ", ")))
+}
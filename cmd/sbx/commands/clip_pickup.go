@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/clipboard"
+)
+
+// ClipPickupCommand copies the sandbox drop directory entries to a local directory.
+type ClipPickupCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	nameOrID string
+	dir      string
+}
+
+// NewClipPickupCommand returns the clip pickup command.
+func NewClipPickupCommand(rootCmd *RootCommand, clipCmd *ClipCommand) *ClipPickupCommand {
+	c := &ClipPickupCommand{rootCmd: rootCmd}
+
+	c.Cmd = clipCmd.Cmd.Command("pickup", "Copy the entries of the sandbox drop directory (/tmp/sbx/drop) to a local directory.")
+	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+	c.Cmd.Arg("dir", "Local directory.").Default(".").StringVar(&c.dir)
+
+	return c
+}
+
+func (c ClipPickupCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c ClipPickupCommand) Run(ctx context.Context) error {
+	svc, err := newClipboardService(ctx, c.rootCmd, c.nameOrID)
+	if err != nil {
+		return err
+	}
+
+	paths, err := svc.Pickup(ctx, clipboard.PickupRequest{NameOrID: c.nameOrID, LocalDir: c.dir})
+	if err != nil {
+		return fmt.Errorf("could not pick up files: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	if len(paths) == 0 {
+		return p.PrintMessage(fmt.Sprintf("Nothing to pick up in %s", c.nameOrID))
+	}
+	return p.PrintMessage(fmt.Sprintf("Picked up from %s: %s", c.nameOrID, strings.Join(paths, ", ")))
+}
@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+)
+
+// ClipPullCommand prints the sandbox clipboard.
+type ClipPullCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	nameOrID string
+}
+
+// NewClipPullCommand returns the clip pull command.
+func NewClipPullCommand(rootCmd *RootCommand, clipCmd *ClipCommand) *ClipPullCommand {
+	c := &ClipPullCommand{rootCmd: rootCmd}
+
+	c.Cmd = clipCmd.Cmd.Command("pull", "Print the sandbox clipboard (/tmp/sbx/clipboard) to the standard output.")
+	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+
+	return c
+}
+
+func (c ClipPullCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c ClipPullCommand) Run(ctx context.Context) error {
+	svc, err := newClipboardService(ctx, c.rootCmd, c.nameOrID)
+	if err != nil {
+		return err
+	}
+
+	data, err := svc.Pull(ctx, c.nameOrID)
+	if err != nil {
+		return fmt.Errorf("could not pull clipboard: %w", err)
+	}
+
+	_, err = c.rootCmd.Stdout.Write(data)
+	return err
+}
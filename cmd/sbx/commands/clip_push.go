@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/clipboard"
+)
+
+// ClipPushCommand replaces the sandbox clipboard.
+type ClipPushCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	nameOrID string
+	text     string
+}
+
+// NewClipPushCommand returns the clip push command.
+func NewClipPushCommand(rootCmd *RootCommand, clipCmd *ClipCommand) *ClipPushCommand {
+	c := &ClipPushCommand{rootCmd: rootCmd}
+
+	c.Cmd = clipCmd.Cmd.Command("push", "Replace the sandbox clipboard (/tmp/sbx/clipboard) with the standard input.")
+	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+	c.Cmd.Flag("text", "Push the text instead of the standard input.").StringVar(&c.text)
+
+	return c
+}
+
+func (c ClipPushCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c ClipPushCommand) Run(ctx context.Context) error {
+	in := c.rootCmd.Stdin
+	if c.text != "" {
+		in = strings.NewReader(c.text)
+	}
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return fmt.Errorf("could not read input: %w", err)
+	}
+
+	svc, err := newClipboardService(ctx, c.rootCmd, c.nameOrID)
+	if err != nil {
+		return err
+	}
+
+	if err := svc.Push(ctx, clipboard.PushRequest{NameOrID: c.nameOrID, Data: data}); err != nil {
+		return fmt.Errorf("could not push clipboard: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	return p.PrintMessage(fmt.Sprintf("Pushed %d bytes to %s clipboard", len(data), c.nameOrID))
+}
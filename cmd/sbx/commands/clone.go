@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/clone"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+type CloneCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	srcNameOrID string
+	name        string
+
+	// Resource overrides.
+	cpu  float64
+	mem  int
+	disk int
+}
+
+// NewCloneCommand returns the clone command.
+func NewCloneCommand(rootCmd *RootCommand, app *kingpin.Application) *CloneCommand {
+	c := &CloneCommand{rootCmd: rootCmd}
+
+	c.Cmd = app.Command("clone", "Clone a stopped sandbox (rootfs and config) into a new sandbox.")
+	c.Cmd.Arg("source", "Source sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.srcNameOrID)
+	c.Cmd.Arg("name", "Name for the new sandbox.").Required().StringVar(&c.name)
+
+	// Resource overrides (unset uses the source sandbox values).
+	c.Cmd.Flag("cpu", "Number of VCPUs (default: same as source).").Float64Var(&c.cpu)
+	c.Cmd.Flag("mem", "Memory in MB (default: same as source).").IntVar(&c.mem)
+	c.Cmd.Flag("disk", "Disk in GB, can't be smaller than the source (default: same as source).").IntVar(&c.disk)
+
+	return c
+}
+
+func (c CloneCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c CloneCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	// Initialize storage (SQLite).
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	// Get source sandbox to determine which engine to use.
+	src, err := repo.GetSandboxByName(ctx, c.srcNameOrID)
+	if err != nil {
+		// Try by ID if name lookup failed
+		src, err = repo.GetSandbox(ctx, c.srcNameOrID)
+		if err != nil {
+			return fmt.Errorf("could not find sandbox: %w", err)
+		}
+	}
+
+	// Initialize engine based on sandbox configuration.
+	eng, err := newEngineFromConfig(src.Config, repo, c.rootCmd.VMsDir, logger)
+	if err != nil {
+		return fmt.Errorf("could not create engine: %w", err)
+	}
+
+	// Create clone service.
+	svc, err := clone.NewService(clone.ServiceConfig{
+		Engine:     eng,
+		Repository: repo,
+		Logger:     logger,
+		VMsDir:     c.rootCmd.VMsDir,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	// Execute clone.
+	sb, err := svc.Run(ctx, clone.Request{
+		NameOrID: c.srcNameOrID,
+		Name:     c.name,
+		Resources: model.Resources{
+			VCPUs:    c.cpu,
+			MemoryMB: c.mem,
+			DiskGB:   c.disk,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("could not clone sandbox: %w", err)
+	}
+
+	// Print success message.
+	msg := fmt.Sprintf("Cloned sandbox %s into %s (%s)", src.Name, sb.Name, sb.ID)
+	if err := printSandboxResult(c.rootCmd, *sb, msg); err != nil {
+		return fmt.Errorf("could not print message: %w", err)
+	}
+
+	return nil
+}
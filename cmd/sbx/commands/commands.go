@@ -8,7 +8,9 @@ import (
 	"github.com/alecthomas/kingpin/v2"
 	"k8s.io/client-go/util/homedir"
 
+	"github.com/slok/sbx/internal/conventions"
 	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
 )
 
 const (
@@ -29,17 +31,26 @@ type Command interface {
 // for all the commands.
 type RootCommand struct {
 	// Global flags.
-	Debug      bool
-	NoLog      bool
-	NoColor    bool
-	LoggerType string
-	DBPath     string
+	Debug         bool
+	NoLog         bool
+	NoColor       bool
+	LoggerType    string
+	DBPath        string
+	VMsDir        string
+	SnapshotsDir  string
+	SnapshotStore string
+	HooksDir      string
+	Namespace     string
+	Output        string
+	Remote        string
+	RemoteBin     string
 
 	// Global instances.
-	Stdin  io.Reader
-	Stdout io.Writer
-	Stderr io.Writer
-	Logger log.Logger
+	Stdin   io.Reader
+	Stdout  io.Writer
+	Stderr  io.Writer
+	Logger  log.Logger
+	Version string
 }
 
 // NewRootCommand initializes the main root configuration.
@@ -53,6 +64,16 @@ func NewRootCommand(app *kingpin.Application) *RootCommand {
 
 	defaultDBPath := filepath.Join(homedir.HomeDir(), ".sbx", "sbx.db")
 	app.Flag("db-path", "Path to the SQLite database file.").Envar("SBX_DB_PATH").Default(defaultDBPath).StringVar(&c.DBPath)
+	defaultVMsDir := conventions.VMsPath(filepath.Join(homedir.HomeDir(), conventions.DefaultDataDir))
+	app.Flag("vms-dir", "Directory of the sandbox VMs (rootfs copies, SSH keys and runtime files), can be on a different filesystem than the database.").Envar("SBX_VMS_DIR").Default(defaultVMsDir).StringVar(&c.VMsDir)
+	app.Flag("snapshots-dir", "Directory of the snapshot images (default: the images directory).").Envar("SBX_SNAPSHOTS_DIR").StringVar(&c.SnapshotsDir)
+	app.Flag("snapshot-store", "Object storage sharing the snapshot images between hosts: s3://BUCKET[/PREFIX] or gs://BUCKET[/PREFIX], with the endpoint, region, sse, sse_kms_key_id and part_size query options (credentials from the AWS_* env vars).").Envar("SBX_SNAPSHOT_STORE").StringVar(&c.SnapshotStore)
+	defaultHooksDir := conventions.HooksPath(filepath.Join(homedir.HomeDir(), conventions.DefaultDataDir))
+	app.Flag("hooks-dir", "Directory of the host hooks, the executables of its pre-create, post-start, pre-stop and post-remove subdirectories run on the sandbox lifecycle events.").Envar("SBX_HOOKS_DIR").Default(defaultHooksDir).StringVar(&c.HooksDir)
+	app.Flag("namespace", "Namespace of the sandboxes, isolates the sandboxes of teams or projects sharing the host ('*' selects all the namespaces).").Envar("SBX_NAMESPACE").Default(model.DefaultNamespace).StringVar(&c.Namespace)
+	app.Flag("output", "Output format (table, json, yaml).").Short('o').Default(OutputFormatTable).EnumVar(&c.Output, OutputFormatTable, OutputFormatJSON, OutputFormatYAML)
+	app.Flag("remote", "Run the command with sbx on a remote Linux host over SSH (user@host or ssh://user@host:port), for non-Linux machines.").Envar("SBX_REMOTE").StringVar(&c.Remote)
+	app.Flag("remote-bin", "Path of the sbx binary on the remote host.").Envar("SBX_REMOTE_BIN").Default("sbx").StringVar(&c.RemoteBin)
 
 	return c
 }
@@ -0,0 +1,270 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+	"k8s.io/client-go/util/homedir"
+
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// CompletionCommand prints shell completion scripts.
+type CompletionCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	shell string
+}
+
+// NewCompletionCommand returns the completion command.
+func NewCompletionCommand(rootCmd *RootCommand, app *kingpin.Application) *CompletionCommand {
+	c := &CompletionCommand{rootCmd: rootCmd}
+
+	c.Cmd = app.Command("completion", "Generate a shell completion script (e.g. 'source <(sbx completion bash)').")
+	c.Cmd.Arg("shell", "Shell type (bash, zsh, fish).").Required().EnumVar(&c.shell, "bash", "zsh", "fish")
+
+	return c
+}
+
+func (c CompletionCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c CompletionCommand) Run(ctx context.Context) error {
+	var script string
+	switch c.shell {
+	case "bash":
+		script = bashCompletionScript
+	case "zsh":
+		script = zshCompletionScript
+	case "fish":
+		script = fishCompletionScript
+	default:
+		return fmt.Errorf("unsupported shell: %s", c.shell)
+	}
+
+	_, err := fmt.Fprint(c.rootCmd.Stdout, script)
+	return err
+}
+
+// CompleteCommand is the hidden helper the completion scripts call. It receives the
+// words of the command line (the last one being the word under completion) and
+// prints the completion candidates, one per line.
+type CompleteCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+	app     *kingpin.Application
+
+	words []string
+}
+
+// NewCompleteCommand returns the hidden __complete command.
+func NewCompleteCommand(rootCmd *RootCommand, app *kingpin.Application) *CompleteCommand {
+	c := &CompleteCommand{rootCmd: rootCmd, app: app}
+
+	c.Cmd = app.Command("__complete", "Internal: print completion candidates for the given words.").Hidden()
+	c.Cmd.Arg("words", "Command line words (use -- before them).").StringsVar(&c.words)
+
+	return c
+}
+
+func (c CompleteCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c CompleteCommand) Run(ctx context.Context) error {
+	for _, candidate := range completeWords(c.app, c.words) {
+		fmt.Fprintln(c.rootCmd.Stdout, candidate)
+	}
+	return nil
+}
+
+// completeWords returns the completion candidates for the last word using the
+// application model and the hint actions registered on args and flags.
+func completeWords(app *kingpin.Application, words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+
+	current := words[len(words)-1]
+	previous := ""
+	if len(words) > 1 {
+		previous = words[len(words)-2]
+	}
+
+	// Errors are expected on partial command lines, the context is still usable.
+	pctx, _ := app.ParseContext(words)
+
+	var (
+		cmdCompletion  func() []string
+		flagCompletion func(name, value string) ([]string, bool, bool)
+	)
+	if pctx != nil && pctx.SelectedCommand != nil {
+		cmdCompletion = func() []string { return pctx.SelectedCommand.CmdCompletion(pctx) }
+		flagCompletion = pctx.SelectedCommand.FlagCompletion
+	} else {
+		cmdCompletion = func() []string {
+			if pctx == nil {
+				return nil
+			}
+			return app.CmdCompletion(pctx)
+		}
+		flagCompletion = app.FlagCompletion
+	}
+
+	var candidates []string
+	switch {
+	case strings.HasPrefix(current, "--"):
+		candidates = completeFlag(app, flagCompletion, strings.TrimPrefix(current, "--"), "")
+	case strings.HasPrefix(previous, "--") && !strings.Contains(previous, "="):
+		candidates = completeFlag(app, flagCompletion, strings.TrimPrefix(previous, "--"), current)
+		if candidates == nil {
+			candidates = cmdCompletion()
+		}
+	default:
+		candidates = cmdCompletion()
+	}
+
+	return filterCandidates(candidates, current)
+}
+
+// completeFlag returns the flag names (or flag values when the flag is complete)
+// for the selected command, falling back to the application global flags.
+func completeFlag(app *kingpin.Application, flagCompletion func(name, value string) ([]string, bool, bool), name, value string) []string {
+	options, flagMatched, valueMatched := flagCompletion(name, value)
+	if !flagMatched {
+		topOptions, topFlagMatched, topValueMatched := app.FlagCompletion(name, value)
+		if topFlagMatched {
+			options, flagMatched, valueMatched = topOptions, true, topValueMatched
+		} else {
+			options = append(options, topOptions...)
+		}
+	}
+
+	// A complete flag with a complete (or free) value: continue with args/commands.
+	if flagMatched && valueMatched {
+		return nil
+	}
+
+	return options
+}
+
+func filterCandidates(candidates []string, prefix string) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, c := range candidates {
+		if seen[c] || !strings.HasPrefix(c, prefix) {
+			continue
+		}
+		seen[c] = true
+		result = append(result, c)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// sandboxNameHints returns a hint action that completes the sandbox names stored in the database.
+func sandboxNameHints(rootCmd *RootCommand) kingpin.HintAction {
+	return func() []string {
+		// Completion must not create a database as a side effect.
+		if _, err := os.Stat(rootCmd.DBPath); err != nil {
+			return nil
+		}
+
+		ctx := context.Background()
+		repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+			DBPath:    rootCmd.DBPath,
+			Namespace: rootCmd.Namespace,
+			Logger:    log.Noop,
+		})
+		if err != nil {
+			return nil
+		}
+		defer repo.Close()
+
+		sandboxes, err := repo.ListSandboxes(ctx)
+		if err != nil {
+			return nil
+		}
+
+		names := make([]string, 0, len(sandboxes))
+		for _, sb := range sandboxes {
+			names = append(names, sb.Name)
+		}
+		return names
+	}
+}
+
+// imageNameHints returns a hint action that completes the locally installed images
+// (releases and snapshots). The images directory is read when the hint is resolved,
+// falling back to the default one when the flag has not been set.
+func imageNameHints(rootCmd *RootCommand, imagesDir *string) kingpin.HintAction {
+	return func() []string {
+		dir := *imagesDir
+		if dir == "" {
+			dir = filepath.Join(homedir.HomeDir(), image.DefaultImagesDir)
+		}
+
+		mgr, err := image.NewLocalImageManager(image.LocalImageManagerConfig{
+			ImagesDir:    dir,
+			SnapshotsDir: rootCmd.SnapshotsDir,
+			Logger:       log.Noop,
+		})
+		if err != nil {
+			return nil
+		}
+
+		images, err := mgr.List(context.Background())
+		if err != nil {
+			return nil
+		}
+
+		names := make([]string, 0, len(images))
+		for _, img := range images {
+			names = append(names, img.Version)
+		}
+		return names
+	}
+}
+
+const bashCompletionScript = `# bash completion for sbx.
+_sbx_complete() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local IFS=$'\n'
+    COMPREPLY=( $(compgen -W "$(sbx __complete -- "${COMP_WORDS[@]:1:$COMP_CWORD}" 2>/dev/null)" -- "${cur}") )
+}
+complete -o default -F _sbx_complete sbx
+`
+
+const zshCompletionScript = `#compdef sbx
+# zsh completion for sbx.
+_sbx() {
+    local -a candidates
+    candidates=("${(@f)$(sbx __complete -- "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    candidates=(${candidates:#})
+    if (( ${#candidates} )); then
+        compadd -a candidates
+    else
+        _files
+    fi
+}
+compdef _sbx sbx
+`
+
+const fishCompletionScript = `# fish completion for sbx.
+function __sbx_complete
+    set -l tokens (commandline -opc) (commandline -ct)
+    set -l candidates (sbx __complete -- $tokens[2..-1] 2>/dev/null)
+    if test (count $candidates) -gt 0
+        printf '%s\n' $candidates
+    else
+        __fish_complete_path (commandline -ct)
+    end
+end
+complete -c sbx -f -a '(__sbx_complete)'
+`
@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"golang.org/x/term"
+
+	"github.com/slok/sbx/internal/app/connections"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// ConnectionsCommand shows the active network connections of a sandbox.
+type ConnectionsCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	nameOrID string
+	interval time.Duration
+	once     bool
+}
+
+// NewConnectionsCommand returns the connections command.
+func NewConnectionsCommand(rootCmd *RootCommand, app *kingpin.Application) *ConnectionsCommand {
+	c := &ConnectionsCommand{rootCmd: rootCmd}
+
+	c.Cmd = app.Command("connections", "Show the active network connections of a sandbox with their domain and traffic, refreshed until Ctrl+C.")
+	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+	c.Cmd.Flag("interval", "Time between the refreshes.").Default("2s").DurationVar(&c.interval)
+	c.Cmd.Flag("once", "Print the connections once instead of refreshing them (the default without a terminal or with structured output).").BoolVar(&c.once)
+
+	return c
+}
+
+func (c ConnectionsCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c ConnectionsCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	if c.interval <= 0 {
+		return fmt.Errorf("interval must be greater than 0: %w", model.ErrNotValid)
+	}
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	// Get sandbox to determine which engine to use.
+	sandbox, err := repo.GetSandboxByName(ctx, c.nameOrID)
+	if err != nil {
+		// Try by ID if name lookup failed
+		sandbox, err = repo.GetSandbox(ctx, c.nameOrID)
+		if err != nil {
+			return fmt.Errorf("could not find sandbox: %w", err)
+		}
+	}
+
+	eng, err := newEngineFromConfig(sandbox.Config, repo, c.rootCmd.VMsDir, logger)
+	if err != nil {
+		return fmt.Errorf("could not create engine: %w", err)
+	}
+
+	svc, err := connections.NewService(connections.ServiceConfig{
+		Engine:     eng,
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	out, ok := c.rootCmd.Stdout.(*os.File)
+	if c.once || !ok || !term.IsTerminal(int(out.Fd())) || c.rootCmd.structuredOutput("") {
+		conns, err := svc.Run(ctx, connections.Request{NameOrID: sandbox.ID})
+		if err != nil {
+			return fmt.Errorf("could not list sandbox connections: %w", err)
+		}
+		if err := p.PrintConnections(conns); err != nil {
+			return fmt.Errorf("could not print connections: %w", err)
+		}
+		return nil
+	}
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		conns, err := svc.Run(ctx, connections.Request{NameOrID: sandbox.ID})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("could not list sandbox connections: %w", err)
+		}
+
+		// The view is rendered before clearing the screen to avoid flickering.
+		var buf bytes.Buffer
+		buf.WriteString("\033[H\033[2J")
+		fmt.Fprintf(&buf, "Sandbox:      %s\nConnections:  %d\n\n", sandbox.Name, len(conns))
+		if err := newPrinter(OutputFormatTable, &buf).PrintConnections(conns); err != nil {
+			return fmt.Errorf("could not print connections: %w", err)
+		}
+		if _, err := buf.WriteTo(out); err != nil {
+			return fmt.Errorf("could not print connections: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
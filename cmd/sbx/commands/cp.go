@@ -3,10 +3,14 @@ package commands
 import (
 	"context"
 	"fmt"
+	"io"
 
 	"github.com/alecthomas/kingpin/v2"
+	"github.com/alecthomas/units"
 
 	"github.com/slok/sbx/internal/app/copy"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/printer"
 	"github.com/slok/sbx/internal/storage/sqlite"
 )
 
@@ -16,6 +20,11 @@ type CpCommand struct {
 
 	source      string
 	destination string
+	compress    bool
+	progress    bool
+	limitRate   units.Base2Bytes
+	retries     int
+	checksum    bool
 }
 
 // NewCpCommand returns the cp command.
@@ -27,6 +36,11 @@ func NewCpCommand(rootCmd *RootCommand, app *kingpin.Application) *CpCommand {
 	c.Cmd = app.Command("cp", "Copy files between host and sandbox.")
 	c.Cmd.Arg("source", "Source path (local path or sandbox:/path).").Required().StringVar(&c.source)
 	c.Cmd.Arg("destination", "Destination path (local path or sandbox:/path).").Required().StringVar(&c.destination)
+	c.Cmd.Flag("compress", "Send the files as a compressed stream (faster for compressible files on slow links, needs tar and gzip in the sandbox).").BoolVar(&c.compress)
+	c.Cmd.Flag("progress", "Show the copy progress on stderr.").BoolVar(&c.progress)
+	c.Cmd.Flag("limit-rate", "Limit the copy rate per second (e.g. 10MB, 0 is unlimited).").Default("0").BytesVar(&c.limitRate)
+	c.Cmd.Flag("retries", "Times the copy is resumed when the sandbox connection breaks (0 doesn't retry).").Default("3").IntVar(&c.retries)
+	c.Cmd.Flag("checksum", "Verify the SHA-256 of the copied files against the source (needs sha256sum in the sandbox).").BoolVar(&c.checksum)
 
 	return c
 }
@@ -44,8 +58,9 @@ func (c CpCommand) Run(ctx context.Context) error {
 
 	// Initialize storage (SQLite).
 	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
-		DBPath: c.rootCmd.DBPath,
-		Logger: logger,
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
 	})
 	if err != nil {
 		return fmt.Errorf("could not create repository: %w", err)
@@ -62,7 +77,7 @@ func (c CpCommand) Run(ctx context.Context) error {
 	}
 
 	// Initialize engine based on sandbox configuration.
-	eng, err := newEngineFromConfig(sandbox.Config, repo, logger)
+	eng, err := newEngineFromConfig(sandbox.Config, repo, c.rootCmd.VMsDir, logger)
 	if err != nil {
 		return fmt.Errorf("could not create engine: %w", err)
 	}
@@ -78,19 +93,42 @@ func (c CpCommand) Run(ctx context.Context) error {
 	}
 
 	// Execute copy operation.
-	if err := svc.Run(ctx, copy.Request{
-		Source:      c.source,
-		Destination: c.destination,
-	}); err != nil {
+	req := copy.Request{
+		Source:         c.source,
+		Destination:    c.destination,
+		Compress:       c.compress,
+		BandwidthLimit: int64(c.limitRate),
+		Retries:        c.retries,
+		Checksum:       c.checksum,
+	}
+	if c.progress {
+		req.Progress = func(p model.CopyProgress) {
+			printCopyProgress(c.rootCmd.Stderr, p)
+		}
+	}
+	err = svc.Run(ctx, req)
+	if c.progress {
+		fmt.Fprintln(c.rootCmd.Stderr)
+	}
+	if err != nil {
 		return err
 	}
 
 	// Print success message.
+	msg := fmt.Sprintf("Copied %s:%s to %s", sandbox.Name, parsed.RemotePath, parsed.LocalPath)
 	if parsed.ToSandbox {
-		fmt.Fprintf(c.rootCmd.Stdout, "Copied %s to %s:%s\n", parsed.LocalPath, sandbox.Name, parsed.RemotePath)
-	} else {
-		fmt.Fprintf(c.rootCmd.Stdout, "Copied %s:%s to %s\n", sandbox.Name, parsed.RemotePath, parsed.LocalPath)
+		msg = fmt.Sprintf("Copied %s to %s:%s", parsed.LocalPath, sandbox.Name, parsed.RemotePath)
 	}
 
-	return nil
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	return p.PrintMessage(msg)
+}
+
+func printCopyProgress(w io.Writer, p model.CopyProgress) {
+	if p.TotalBytes > 0 {
+		pct := float64(p.Bytes) / float64(p.TotalBytes) * 100
+		fmt.Fprintf(w, "\r  %s / %s (%3.0f%%)", printer.FormatBytes(p.Bytes), printer.FormatBytes(p.TotalBytes), pct)
+		return
+	}
+	fmt.Fprintf(w, "\r  %s copied", printer.FormatBytes(p.Bytes))
 }
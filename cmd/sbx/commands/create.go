@@ -3,18 +3,28 @@ package commands
 import (
 	"context"
 	"fmt"
+	"maps"
+	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/alecthomas/kingpin/v2"
 	"k8s.io/client-go/util/homedir"
 
 	"github.com/slok/sbx/internal/app/create"
+	"github.com/slok/sbx/internal/app/createstart"
+	"github.com/slok/sbx/internal/app/exec"
+	"github.com/slok/sbx/internal/app/templateget"
+	"github.com/slok/sbx/internal/capacity"
 	"github.com/slok/sbx/internal/image"
 	"github.com/slok/sbx/internal/model"
 	"github.com/slok/sbx/internal/sandbox"
 	"github.com/slok/sbx/internal/sandbox/fake"
 	"github.com/slok/sbx/internal/sandbox/firecracker"
+	"github.com/slok/sbx/internal/storage"
 	"github.com/slok/sbx/internal/storage/sqlite"
+	utilsenv "github.com/slok/sbx/internal/utils/env"
 )
 
 type CreateCommand struct {
@@ -22,21 +32,69 @@ type CreateCommand struct {
 	rootCmd *RootCommand
 
 	// Required flags.
-	name   string
-	engine string
+	name        string
+	engine      string
+	engineSet   bool
+	template    string
+	ifNotExists bool
+	persistent  bool
+	admission   string
+	quiet       bool
 
 	// Resource flags.
-	cpu  float64
-	mem  int
-	disk int
+	cpu     float64
+	mem     int
+	disk    int
+	maxCPU  float64
+	maxMem  int
+	cpuSet  bool
+	memSet  bool
+	diskSet bool
 
 	// Firecracker-specific flags.
 	firecrackerRootFS string
 	firecrackerKernel string
 
+	// Boot flags.
+	kernelArgs     []string
+	readOnlyRootFS bool
+	noConsole      bool
+	init           string
+	profile        string
+
+	// Seccomp flags.
+	seccomp       string
+	seccompFilter string
+
+	// Network flags.
+	networks        []string
+	ports           []string
+	egressInterface string
+	mtu             int
+	clampMSS        bool
+
 	// Image flags.
 	fromImage string
 	imagesDir string
+
+	// Provisioning flags.
+	userDataFile string
+
+	// Clock flags.
+	clockDate   string
+	clockOffset time.Duration
+
+	// Exec profile flags.
+	execPaths  []string
+	execLocale string
+	execUmask  string
+	execUlimit []string
+	execShell  string
+
+	// Start flags.
+	start    bool
+	envSpecs []string
+	command  []string
 }
 
 // NewCreateCommand returns the create command.
@@ -47,53 +105,221 @@ func NewCreateCommand(rootCmd *RootCommand, app *kingpin.Application) *CreateCom
 
 	// Required flags.
 	c.Cmd.Flag("name", "Name for the sandbox.").Short('n').Required().StringVar(&c.name)
-	c.Cmd.Flag("engine", "Engine type (firecracker, fake).").Default("firecracker").EnumVar(&c.engine, "firecracker", "fake")
+	c.Cmd.Flag("engine", "Engine type (firecracker, fake).").Default("firecracker").IsSetByUser(&c.engineSet).EnumVar(&c.engine, "firecracker", "fake")
+	c.Cmd.Flag("template", "Create the sandbox from a stored template (see 'sbx template'), the set flags override the template spec.").StringVar(&c.template)
+	c.Cmd.Flag("if-not-exists", "Don't fail if a sandbox with the same name and spec already exists.").BoolVar(&c.ifNotExists)
+	c.Cmd.Flag("persistent", "Start the sandbox again with 'sbx recover' when the host reboot kills it while running.").BoolVar(&c.persistent)
+	c.Cmd.Flag("admission", "Check the host capacity (see 'sbx capacity'): off, warn or enforce (refuse the sandbox).").Default(string(model.AdmissionModeOff)).EnumVar(&c.admission, string(model.AdmissionModeOff), string(model.AdmissionModeWarn), string(model.AdmissionModeEnforce))
+	c.Cmd.Flag("quiet", "Only print the sandbox ID on stdout, the messages go to stderr.").Short('q').BoolVar(&c.quiet)
 
 	// Resource flags.
-	c.Cmd.Flag("cpu", "Number of VCPUs (can be fractional, e.g., 0.5, 1.5).").Default("2").Float64Var(&c.cpu)
-	c.Cmd.Flag("mem", "Memory in MB.").Default("2048").IntVar(&c.mem)
-	c.Cmd.Flag("disk", "Disk in GB.").Default("10").IntVar(&c.disk)
+	c.Cmd.Flag("cpu", "Number of VCPUs (can be fractional, e.g., 0.5, 1.5).").Default("2").IsSetByUser(&c.cpuSet).Float64Var(&c.cpu)
+	c.Cmd.Flag("mem", "Memory in MB.").Default("2048").IsSetByUser(&c.memSet).IntVar(&c.mem)
+	c.Cmd.Flag("disk", "Disk in GB.").Default("10").IsSetByUser(&c.diskSet).IntVar(&c.disk)
+	c.Cmd.Flag("max-cpu", "Number of VCPUs the running sandbox can be scaled up to with 'sbx scale' (default: --cpu).").Float64Var(&c.maxCPU)
+	c.Cmd.Flag("max-mem", "Memory in MB the running sandbox can be scaled up to with 'sbx scale' (default: --mem).").IntVar(&c.maxMem)
 
 	// Firecracker-specific flags.
 	c.Cmd.Flag("firecracker-root-fs", "Path to rootfs image (required for firecracker engine).").StringVar(&c.firecrackerRootFS)
 	c.Cmd.Flag("firecracker-kernel", "Path to kernel image (required for firecracker engine).").StringVar(&c.firecrackerKernel)
 
+	// Boot flags.
+	c.Cmd.Flag("kernel-arg", "Extra kernel command line argument, overrides the default with the same key (e.g. panic=5). Repeatable.").StringsVar(&c.kernelArgs)
+	c.Cmd.Flag("read-only-rootfs", "Attach the rootfs as read-only (the image init must provide writable paths).").BoolVar(&c.readOnlyRootFS)
+	c.Cmd.Flag("no-console", "Disable the guest serial console to speed up the boot.").BoolVar(&c.noConsole)
+	c.Cmd.Flag("init", "Override the guest init binary path.").StringVar(&c.init)
+	c.Cmd.Flag("profile", "Kernel profile: fast-boot, debug, hardened or one shipped by the image (see 'sbx image inspect').").StringVar(&c.profile)
+
+	// Seccomp flags.
+	c.Cmd.Flag("seccomp", "Syscall filtering of the VM process: default (Firecracker filters), disabled or custom (requires --seccomp-filter).").Default("default").EnumVar(&c.seccomp, "default", string(model.SeccompModeDisabled), string(model.SeccompModeCustom))
+	c.Cmd.Flag("seccomp-filter", "Path of the compiled custom seccomp filters of the VM process (e.g. by seccompiler-bin), implies --seccomp=custom.").StringVar(&c.seccompFilter)
+
+	// Network flags.
+	c.Cmd.Flag("network", "Additional network interface: 'nat[:EGRESS_FILE]' (egress policy from a session file) or 'isolated:NAME'. Repeatable.").StringsVar(&c.networks)
+	c.Cmd.Flag("mtu", "MTU of the sandbox network interfaces (576-9000), lower it for uplinks with a smaller MTU (e.g. VPN tunnels). Default: 1500.").IntVar(&c.mtu)
+	c.Cmd.Flag("clamp-mss", "Clamp the MSS of the sandbox TCP connections to the MTU of the host route, avoids the hangs on large transfers through uplinks with a smaller MTU.").BoolVar(&c.clampMSS)
+	c.Cmd.Flag("egress-interface", "Host network interface the NAT traffic goes out through instead of the default route (e.g. eth1, wg0), the traffic is dropped when it's down.").StringVar(&c.egressInterface)
+	c.Cmd.Flag("port", "Named port of a sandbox service 'NAME=PORT' (e.g. web=3000), 'sbx forward' can use the name. Repeatable.").StringsVar(&c.ports)
+
 	// Image flags.
-	c.Cmd.Flag("from-image", "Use a pulled image version (e.g. v0.1.0). Run 'sbx image pull' first.").StringVar(&c.fromImage)
+	c.Cmd.Flag("from-image", "Use a pulled image version (e.g. v0.1.0). Run 'sbx image pull' first.").HintAction(imageNameHints(rootCmd, &c.imagesDir)).StringVar(&c.fromImage)
 
 	defaultImagesDir := filepath.Join(homedir.HomeDir(), image.DefaultImagesDir)
 	c.Cmd.Flag("images-dir", "Local directory for images (used with --from-image).").Default(defaultImagesDir).StringVar(&c.imagesDir)
 
+	// Provisioning flags.
+	c.Cmd.Flag("user-data", "Path to a shell script or cloud-config file executed in the guest on the first boot.").StringVar(&c.userDataFile)
+
+	// Clock flags.
+	c.Cmd.Flag("clock-date", "Set the guest clock to this date (RFC3339) on every start, the guest time sync is disabled.").StringVar(&c.clockDate)
+	c.Cmd.Flag("clock-offset", "Offset the guest clock from the host time on every start (e.g. --clock-offset=-24h), the guest time sync is disabled.").DurationVar(&c.clockOffset)
+
+	// Exec profile flags.
+	c.Cmd.Flag("exec-path", "Guest directory added in front of the PATH of every exec and shell. Repeatable, in order.").StringsVar(&c.execPaths)
+	c.Cmd.Flag("exec-locale", "Locale (LANG and LC_ALL) of every exec and shell (e.g. C.UTF-8).").StringVar(&c.execLocale)
+	c.Cmd.Flag("exec-umask", "Octal umask of every exec and shell (e.g. 027).").StringVar(&c.execUmask)
+	c.Cmd.Flag("exec-ulimit", "Resource limit 'NAME=VALUE' of every exec and shell (e.g. nofile=65536, core=unlimited). Repeatable.").StringsVar(&c.execUlimit)
+	c.Cmd.Flag("exec-shell", "Interactive shell of 'sbx shell' (e.g. /bin/bash).").StringVar(&c.execShell)
+
+	// Start flags.
+	c.Cmd.Flag("start", "Start the sandbox once created, a sandbox whose start fails or is interrupted is removed.").BoolVar(&c.start)
+	c.Cmd.Flag("env", "Session environment variables of the start (KEY=VALUE or KEY from current environment, requires --start). Can be repeated.").Short('e').StringsVar(&c.envSpecs)
+	c.Cmd.Arg("command", "Command executed in the started sandbox, exiting with its exit code (requires --start, use -- before the command).").StringsVar(&c.command)
+
 	return c
 }
 
 func (c CreateCommand) Name() string { return c.Cmd.FullCommand() }
 
+// seccompOptions returns the seccomp options of the flags, a filter without mode
+// uses the custom mode. The filter path is made absolute, the VM process doesn't
+// run in the current directory.
+func (c CreateCommand) seccompOptions() (model.SeccompOptions, error) {
+	var opts model.SeccompOptions
+	if c.seccompFilter != "" {
+		filter, err := filepath.Abs(c.seccompFilter)
+		if err != nil {
+			return opts, fmt.Errorf("invalid seccomp filter path: %w", err)
+		}
+		opts.Filter = filter
+	}
+
+	switch {
+	case c.seccomp != "default":
+		opts.Mode = model.SeccompMode(c.seccomp)
+	case c.seccompFilter != "":
+		opts.Mode = model.SeccompModeCustom
+	}
+	return opts, nil
+}
+
+// execProfile returns the exec profile of the flags, nil without them.
+func (c CreateCommand) execProfile() (*model.ExecProfile, error) {
+	return parseExecProfile(c.execPaths, c.execLocale, c.execUmask, c.execUlimit, c.execShell)
+}
+
+// applyTemplate sets the spec of a template on the flags the user didn't set. The
+// user data, ports, exec profile and session are merged when the flags are parsed.
+func (c *CreateCommand) applyTemplate(t model.SandboxTemplate) {
+	if !c.engineSet {
+		c.engine = t.Engine
+	}
+	if c.fromImage == "" && c.firecrackerRootFS == "" && c.firecrackerKernel == "" {
+		c.fromImage = t.Image
+	}
+	if c.profile == "" {
+		c.profile = t.Profile
+	}
+	if !c.cpuSet {
+		c.cpu = t.Resources.VCPUs
+	}
+	if !c.memSet {
+		c.mem = t.Resources.MemoryMB
+	}
+	if !c.diskSet {
+		c.disk = t.Resources.DiskGB
+	}
+	if c.maxCPU == 0 {
+		c.maxCPU = t.Resources.MaxVCPUs
+	}
+	if c.maxMem == 0 {
+		c.maxMem = t.Resources.MaxMemoryMB
+	}
+}
+
+// parseNamedPorts returns the named ports of the --port flags, nil without them.
+func parseNamedPorts(specs []string) (map[string]int, error) {
+	var ports map[string]int
+	for _, p := range specs {
+		name, port, err := model.ParseNamedPort(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --port %q: %w", p, err)
+		}
+		if ports == nil {
+			ports = map[string]int{}
+		}
+		ports[name] = port
+	}
+	return ports, nil
+}
+
+// parseExecProfile returns the exec profile of the exec flags, nil without them.
+func parseExecProfile(paths []string, locale, umask string, ulimits []string, shell string) (*model.ExecProfile, error) {
+	p := model.ExecProfile{
+		PathPrepend: paths,
+		Locale:      locale,
+		Umask:       umask,
+		Shell:       shell,
+	}
+	for _, l := range ulimits {
+		name, value, err := model.ParseUlimit(l)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --exec-ulimit %q: %w", l, err)
+		}
+		if p.Ulimits == nil {
+			p.Ulimits = map[string]string{}
+		}
+		p.Ulimits[name] = value
+	}
+
+	if p.IsZero() {
+		return nil, nil
+	}
+	return &p, nil
+}
+
 func (c CreateCommand) Run(ctx context.Context) error {
 	logger := c.rootCmd.Logger
 
 	// Validate conflicting flags.
 	if c.fromImage != "" && c.firecrackerRootFS != "" {
-		return fmt.Errorf("--from-image and --firecracker-root-fs cannot be used together")
+		return fmt.Errorf("--from-image and --firecracker-root-fs cannot be used together: %w", model.ErrNotValid)
 	}
 	if c.fromImage != "" && c.firecrackerKernel != "" {
-		return fmt.Errorf("--from-image and --firecracker-kernel cannot be used together")
+		return fmt.Errorf("--from-image and --firecracker-kernel cannot be used together: %w", model.ErrNotValid)
+	}
+	if c.rootCmd.Namespace == model.AllNamespaces {
+		return fmt.Errorf("a sandbox is created in a single namespace, --namespace can't select all of them: %w", model.ErrNotValid)
 	}
+	if !c.start && (len(c.command) > 0 || len(c.envSpecs) > 0) {
+		return fmt.Errorf("a command and --env require --start: %w", model.ErrNotValid)
+	}
+	sessionEnv, err := utilsenv.ParseSpecs(c.envSpecs)
+	if err != nil {
+		return fmt.Errorf("invalid --env value: %w", err)
+	}
+
 	// Initialize storage (SQLite).
 	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
-		DBPath: c.rootCmd.DBPath,
-		Logger: logger,
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
 	})
 	if err != nil {
 		return fmt.Errorf("could not create repository: %w", err)
 	}
 
+	var tpl *model.SandboxTemplate
+	if c.template != "" {
+		svc, err := templateget.NewService(templateget.ServiceConfig{Repository: repo, Logger: logger})
+		if err != nil {
+			return fmt.Errorf("could not create service: %w", err)
+		}
+		tpl, err = svc.Run(ctx, templateget.Request{Name: c.template})
+		if err != nil {
+			return fmt.Errorf("could not get template: %w", err)
+		}
+		c.applyTemplate(*tpl)
+	}
+
 	// Resolve image paths if --from-image is set.
 	var firecrackerBinaryPath string
+	profiles := model.DefaultKernelProfiles
 	if c.fromImage != "" {
 		mgr, err := image.NewLocalImageManager(image.LocalImageManagerConfig{
-			ImagesDir: c.imagesDir,
-			Logger:    logger,
+			ImagesDir:    c.imagesDir,
+			SnapshotsDir: c.rootCmd.SnapshotsDir,
+			Logger:       logger,
 		})
 		if err != nil {
 			return fmt.Errorf("could not create image manager: %w", err)
@@ -103,37 +329,141 @@ func (c CreateCommand) Run(ctx context.Context) error {
 		if err != nil {
 			return fmt.Errorf("could not check image: %w", err)
 		}
+		if !exists {
+			exists, err = fetchMissingSnapshot(ctx, c.rootCmd, c.imagesDir, c.fromImage)
+			if err != nil {
+				return err
+			}
+		}
 		if !exists {
 			return fmt.Errorf("image %s is not installed, run 'sbx image pull %s' first", c.fromImage, c.fromImage)
 		}
+		if err := image.CheckHostArch(ctx, mgr, c.fromImage); err != nil {
+			return fmt.Errorf("image %s can't be used on this host: %w", c.fromImage, err)
+		}
 
 		c.firecrackerKernel = mgr.KernelPath(c.fromImage)
 		c.firecrackerRootFS = mgr.RootFSPath(c.fromImage)
 		firecrackerBinaryPath = mgr.FirecrackerPath(c.fromImage)
+
+		manifest, err := mgr.GetManifest(ctx, c.fromImage)
+		if err != nil {
+			return fmt.Errorf("could not get image manifest: %w", err)
+		}
+		profiles = manifest.KernelProfiles()
+	}
+
+	var profile *model.KernelProfile
+	if c.profile != "" {
+		p, err := model.FindKernelProfile(profiles, c.profile)
+		if err != nil {
+			return err
+		}
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("invalid kernel profile %q: %w", c.profile, err)
+		}
+		profile = &p
+	}
+
+	networks, err := parseNetworkFlags(ctx, c.networks)
+	if err != nil {
+		return err
+	}
+
+	ports, err := parseNamedPorts(c.ports)
+	if err != nil {
+		return err
+	}
+
+	var userData string
+	if c.userDataFile != "" {
+		data, err := os.ReadFile(c.userDataFile)
+		if err != nil {
+			return fmt.Errorf("could not read user data: %w", err)
+		}
+		userData = string(data)
+	}
+
+	sessionCfg := model.SessionConfig{Env: sessionEnv}
+	if tpl != nil {
+		if len(tpl.Ports) > 0 {
+			merged := maps.Clone(tpl.Ports)
+			maps.Copy(merged, ports)
+			ports = merged
+		}
+		if userData == "" {
+			userData = tpl.UserData
+		}
+		sessionCfg = tpl.SessionConfig()
+		sessionCfg.Env = utilsenv.MergeMaps(tpl.Env, sessionEnv)
+	}
+
+	var clock *model.ClockConfig
+	if c.clockDate != "" || c.clockOffset != 0 {
+		clock = &model.ClockConfig{Offset: c.clockOffset}
+		if c.clockDate != "" {
+			clock.BootTime, err = time.Parse(time.RFC3339, c.clockDate)
+			if err != nil {
+				return fmt.Errorf("invalid --clock-date, must be RFC3339 (e.g. 2030-01-01T00:00:00Z): %w", model.ErrNotValid)
+			}
+		}
+	}
+
+	execProfile, err := c.execProfile()
+	if err != nil {
+		return err
+	}
+	if execProfile == nil && tpl != nil {
+		execProfile = tpl.ExecProfile
 	}
 
 	// Build SandboxConfig from CLI flags.
 	cfg := model.SandboxConfig{
-		Name: c.name,
+		Name:  c.name,
+		Image: c.fromImage,
 		Resources: model.Resources{
-			VCPUs:    c.cpu,
-			MemoryMB: c.mem,
-			DiskGB:   c.disk,
+			VCPUs:       c.cpu,
+			MemoryMB:    c.mem,
+			DiskGB:      c.disk,
+			MaxVCPUs:    c.maxCPU,
+			MaxMemoryMB: c.maxMem,
 		},
+		UserData:    userData,
+		Clock:       clock,
+		Ports:       ports,
+		ExecProfile: execProfile,
 	}
 
 	switch c.engine {
 	case "firecracker":
 		if c.firecrackerRootFS == "" {
-			return fmt.Errorf("--firecracker-root-fs or --from-image is required when using firecracker engine")
+			return fmt.Errorf("--firecracker-root-fs or --from-image is required when using firecracker engine: %w", model.ErrNotValid)
 		}
 		if c.firecrackerKernel == "" {
-			return fmt.Errorf("--firecracker-kernel or --from-image is required when using firecracker engine")
+			return fmt.Errorf("--firecracker-kernel or --from-image is required when using firecracker engine: %w", model.ErrNotValid)
+		}
+
+		seccomp, err := c.seccompOptions()
+		if err != nil {
+			return err
 		}
 
 		cfg.FirecrackerEngine = &model.FirecrackerEngineConfig{
 			RootFS:      c.firecrackerRootFS,
 			KernelImage: c.firecrackerKernel,
+			KernelArgs:  c.kernelArgs,
+			Boot: model.BootOptions{
+				ReadOnlyRootFS: c.readOnlyRootFS,
+				DisableConsole: c.noConsole,
+				Init:           c.init,
+			},
+			Networks:        networks,
+			Seccomp:         seccomp,
+			EgressInterface: c.egressInterface,
+			Network:         model.NetworkOptions{MTU: c.mtu, ClampMSS: c.clampMSS},
+		}
+		if profile != nil {
+			profile.Apply(cfg.FirecrackerEngine)
 		}
 	case "fake":
 		cfg.FirecrackerEngine = &model.FirecrackerEngineConfig{
@@ -147,6 +477,7 @@ func (c CreateCommand) Run(ctx context.Context) error {
 	switch c.engine {
 	case "firecracker":
 		eng, err = firecracker.NewEngine(firecracker.EngineConfig{
+			VMsDir:            c.rootCmd.VMsDir,
 			FirecrackerBinary: firecrackerBinaryPath,
 			Repository:        repo,
 			Logger:            logger,
@@ -160,32 +491,176 @@ func (c CreateCommand) Run(ctx context.Context) error {
 		return fmt.Errorf("could not create engine: %w", err)
 	}
 
-	// Create service.
+	planner, err := newCapacityPlanner(repo, c.rootCmd.VMsDir, model.AdmissionMode(c.admission), logger)
+	if err != nil {
+		return err
+	}
+
+	progress, finishProgress := newProgress(c.rootCmd)
+	createOpts := create.CreateOptions{
+		Config:      cfg,
+		Namespace:   c.rootCmd.Namespace,
+		IfNotExists: c.ifNotExists,
+		Persistent:  c.persistent,
+		Progress:    progress,
+	}
+
+	var sb *model.Sandbox
+	if c.start {
+		sb, err = c.createAndStart(ctx, eng, repo, planner, createstart.Request{
+			Create:        createOpts,
+			SessionConfig: sessionCfg,
+		})
+	} else {
+		sb, err = c.create(ctx, eng, repo, planner, createOpts)
+	}
+	finishProgress(err)
+	if err != nil {
+		return err
+	}
+
+	// Output success message.
+	var msg strings.Builder
+	switch {
+	case c.ifNotExists && c.start:
+		msg.WriteString("Sandbox exists with the requested spec and is running!\n")
+	case c.ifNotExists:
+		msg.WriteString("Sandbox exists with the requested spec!\n")
+	case c.start:
+		msg.WriteString("Sandbox created and started successfully!\n")
+	default:
+		msg.WriteString("Sandbox created successfully!\n")
+	}
+	fmt.Fprintf(&msg, "  ID:     %s\n", sb.ID)
+	fmt.Fprintf(&msg, "  Name:   %s\n", sb.Name)
+	fmt.Fprintf(&msg, "  Status: %s", sb.Status)
+	if sb.Config.FirecrackerEngine != nil {
+		fmt.Fprintf(&msg, "\n  Engine: firecracker")
+	}
+	if c.quiet {
+		err = printQuietResult(c.rootCmd, sb.ID, msg.String())
+	} else {
+		err = printSandboxResult(c.rootCmd, *sb, msg.String())
+	}
+	if err != nil {
+		return fmt.Errorf("could not print result: %w", err)
+	}
+
+	if len(c.command) > 0 {
+		return c.exec(ctx, eng, repo, *sb)
+	}
+
+	return nil
+}
+
+func (c CreateCommand) create(ctx context.Context, eng sandbox.Engine, repo storage.Repository, planner *capacity.Planner, opts create.CreateOptions) (*model.Sandbox, error) {
+	hooksRunner, err := newHooksRunner(c.rootCmd, c.rootCmd.Logger)
+	if err != nil {
+		return nil, fmt.Errorf("could not create hooks runner: %w", err)
+	}
+
 	svc, err := create.NewService(create.ServiceConfig{
 		Engine:     eng,
 		Repository: repo,
-		Logger:     logger,
+		Admission:  planner,
+		Hooks:      hooksRunner,
+		Logger:     c.rootCmd.Logger,
 	})
 	if err != nil {
-		return fmt.Errorf("could not create service: %w", err)
+		return nil, fmt.Errorf("could not create service: %w", err)
 	}
 
-	// Execute create.
-	sb, err := svc.Create(ctx, create.CreateOptions{
-		Config: cfg,
+	sb, err := svc.Create(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("could not create sandbox: %w", err)
+	}
+
+	return sb, nil
+}
+
+func (c CreateCommand) createAndStart(ctx context.Context, eng sandbox.Engine, repo storage.Repository, planner *capacity.Planner, req createstart.Request) (*model.Sandbox, error) {
+	hooksRunner, err := newHooksRunner(c.rootCmd, c.rootCmd.Logger)
+	if err != nil {
+		return nil, fmt.Errorf("could not create hooks runner: %w", err)
+	}
+
+	svc, err := createstart.NewService(createstart.ServiceConfig{
+		Engine:     eng,
+		Repository: repo,
+		Admission:  planner,
+		Hooks:      hooksRunner,
+		Logger:     c.rootCmd.Logger,
 	})
 	if err != nil {
-		return fmt.Errorf("could not create sandbox: %w", err)
+		return nil, fmt.Errorf("could not create service: %w", err)
 	}
 
-	// Output success message.
-	fmt.Fprintf(c.rootCmd.Stdout, "Sandbox created successfully!\n")
-	fmt.Fprintf(c.rootCmd.Stdout, "  ID:     %s\n", sb.ID)
-	fmt.Fprintf(c.rootCmd.Stdout, "  Name:   %s\n", sb.Name)
-	fmt.Fprintf(c.rootCmd.Stdout, "  Status: %s\n", sb.Status)
-	if sb.Config.FirecrackerEngine != nil {
-		fmt.Fprintf(c.rootCmd.Stdout, "  Engine: firecracker\n")
+	res, err := svc.Run(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("could not create and start sandbox: %w", err)
 	}
 
+	return res.Sandbox, nil
+}
+
+// exec runs the command in the started sandbox and exits with its exit code, like
+// the exec command.
+func (c CreateCommand) exec(ctx context.Context, eng sandbox.Engine, repo storage.Repository, sb model.Sandbox) error {
+	svc, err := exec.NewService(exec.ServiceConfig{
+		Engine:     eng,
+		Repository: repo,
+		Logger:     c.rootCmd.Logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	result, err := svc.Run(ctx, exec.Request{
+		NameOrID: sb.ID,
+		Command:  c.command,
+		Opts: model.ExecOpts{
+			Stdin:  os.Stdin,
+			Stdout: os.Stdout,
+			Stderr: os.Stderr,
+		},
+		Caller: "cli",
+	})
+	if err != nil {
+		return fmt.Errorf("could not execute command: %w", err)
+	}
+
+	os.Exit(result.ExitCode)
 	return nil
 }
+
+// parseNetworkFlags parses the --network flag values.
+func parseNetworkFlags(ctx context.Context, values []string) ([]model.NetworkInterface, error) {
+	var nets []model.NetworkInterface
+	for _, v := range values {
+		mode, arg, _ := strings.Cut(v, ":")
+		switch model.NetworkMode(mode) {
+		case model.NetworkModeNAT:
+			n := model.NetworkInterface{Mode: model.NetworkModeNAT}
+			if arg != "" {
+				sessionCfg, err := loadSessionConfig(ctx, arg)
+				if err != nil {
+					return nil, fmt.Errorf("invalid --network %q: %w", v, err)
+				}
+				if sessionCfg.Egress == nil {
+					return nil, fmt.Errorf("invalid --network %q: session file has no egress policy: %w", v, model.ErrNotValid)
+				}
+				n.Egress = sessionCfg.Egress
+			}
+			nets = append(nets, n)
+		case model.NetworkModeIsolated:
+			if arg == "" {
+				return nil, fmt.Errorf("invalid --network %q: isolated networks require a name (isolated:NAME): %w", v, model.ErrNotValid)
+			}
+			nets = append(nets, model.NetworkInterface{Mode: model.NetworkModeIsolated, Network: arg})
+		default:
+			return nil, fmt.Errorf("invalid --network %q: mode must be nat or isolated: %w", v, model.ErrNotValid)
+		}
+	}
+
+	return nets, nil
+}
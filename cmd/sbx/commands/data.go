@@ -0,0 +1,17 @@
+package commands
+
+import "github.com/alecthomas/kingpin/v2"
+
+// DataCommand is the parent command for the sandbox data directories subcommands.
+type DataCommand struct {
+	Cmd *kingpin.CmdClause
+}
+
+// NewDataCommand returns the data parent command.
+func NewDataCommand(app *kingpin.Application) *DataCommand {
+	c := &DataCommand{}
+
+	c.Cmd = app.Command("data", "Manage the directories of the sandbox VMs, images and snapshots.")
+
+	return c
+}
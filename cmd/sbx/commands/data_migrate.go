@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/alecthomas/kingpin/v2"
+	"k8s.io/client-go/util/homedir"
+
+	"github.com/slok/sbx/internal/app/datamigrate"
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// DataMigrateCommand moves the sandbox VMs, images and snapshots to new directories.
+type DataMigrateCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	imagesDir      string
+	toVMsDir       string
+	toImagesDir    string
+	toSnapshotsDir string
+	dryRun         bool
+}
+
+// NewDataMigrateCommand returns the data migrate command.
+func NewDataMigrateCommand(rootCmd *RootCommand, dataCmd *DataCommand) *DataMigrateCommand {
+	c := &DataMigrateCommand{rootCmd: rootCmd}
+
+	c.Cmd = dataCmd.Cmd.Command("migrate", "Move the sandbox VMs, images and snapshots from the current directories (--vms-dir, --images-dir and --snapshots-dir) to new ones, e.g. the VM rootfs copies to a faster filesystem. All the sandboxes must be stopped.")
+	defaultImagesDir := filepath.Join(homedir.HomeDir(), image.DefaultImagesDir)
+	c.Cmd.Flag("images-dir", "Current local directory for images.").Default(defaultImagesDir).StringVar(&c.imagesDir)
+	c.Cmd.Flag("to-vms-dir", "New directory of the sandbox VMs.").StringVar(&c.toVMsDir)
+	c.Cmd.Flag("to-images-dir", "New directory of the images.").StringVar(&c.toImagesDir)
+	c.Cmd.Flag("to-snapshots-dir", "New directory of the snapshot images (default: the new images directory).").StringVar(&c.toSnapshotsDir)
+	c.Cmd.Flag("dry-run", "Only print the moves.").BoolVar(&c.dryRun)
+
+	return c
+}
+
+func (c DataMigrateCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c DataMigrateCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	if c.toVMsDir == "" && c.toImagesDir == "" && c.toSnapshotsDir == "" {
+		return fmt.Errorf("--to-vms-dir, --to-images-dir or --to-snapshots-dir is required: %w", model.ErrNotValid)
+	}
+
+	from := model.DataLayout{
+		VMsDir:       c.rootCmd.VMsDir,
+		ImagesDir:    c.imagesDir,
+		SnapshotsDir: cmp.Or(c.rootCmd.SnapshotsDir, c.imagesDir),
+	}
+	to := model.DataLayout{
+		VMsDir:       cmp.Or(c.toVMsDir, from.VMsDir),
+		ImagesDir:    cmp.Or(c.toImagesDir, from.ImagesDir),
+		SnapshotsDir: cmp.Or(c.toSnapshotsDir, c.toImagesDir, from.SnapshotsDir),
+	}
+	for _, dir := range []*string{&from.VMsDir, &from.ImagesDir, &from.SnapshotsDir, &to.VMsDir, &to.ImagesDir, &to.SnapshotsDir} {
+		abs, err := filepath.Abs(*dir)
+		if err != nil {
+			return fmt.Errorf("could not resolve %s: %w", *dir, err)
+		}
+		*dir = abs
+	}
+
+	// The VMs of every namespace are moved.
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: model.AllNamespaces,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+	defer repo.Close()
+
+	imgMgr, err := image.NewLocalImageManager(image.LocalImageManagerConfig{
+		ImagesDir:    from.ImagesDir,
+		SnapshotsDir: from.SnapshotsDir,
+		Logger:       logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create image manager: %w", err)
+	}
+
+	svc, err := datamigrate.NewService(datamigrate.ServiceConfig{
+		Repository:   repo,
+		ImageManager: imgMgr,
+		ImageMover:   imgMgr,
+		Logger:       logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	moves, err := svc.Run(ctx, datamigrate.Request{From: from, To: to, DryRun: c.dryRun})
+	if err != nil {
+		return fmt.Errorf("could not migrate data (%d directories moved): %w", len(moves), err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	if err := p.PrintDataMoves(moves); err != nil {
+		return fmt.Errorf("could not print moves: %w", err)
+	}
+
+	return nil
+}
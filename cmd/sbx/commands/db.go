@@ -0,0 +1,17 @@
+package commands
+
+import "github.com/alecthomas/kingpin/v2"
+
+// DBCommand is the parent command for the database maintenance subcommands.
+type DBCommand struct {
+	Cmd *kingpin.CmdClause
+}
+
+// NewDBCommand returns the db parent command.
+func NewDBCommand(app *kingpin.Application) *DBCommand {
+	c := &DBCommand{}
+
+	c.Cmd = app.Command("db", "Maintain the sbx database.")
+
+	return c
+}
@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/dbcompact"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/printer"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// DBCompactCommand checkpoints, checks and compacts the database.
+type DBCompactCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+}
+
+// NewDBCompactCommand returns the db compact command.
+func NewDBCompactCommand(rootCmd *RootCommand, dbCmd *DBCommand) *DBCompactCommand {
+	c := &DBCompactCommand{rootCmd: rootCmd}
+
+	c.Cmd = dbCmd.Cmd.Command("compact", "Checkpoint the write-ahead log, check the integrity and reclaim the space of the deleted rows of the database.")
+
+	return c
+}
+
+func (c DBCompactCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c DBCompactCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+	defer repo.Close()
+
+	svc, err := dbcompact.NewService(dbcompact.ServiceConfig{
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	res, err := svc.Run(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(res.IntegrityErrors) > 0 {
+		return fmt.Errorf("database integrity check failed, the database was not compacted: %s: %w", strings.Join(res.IntegrityErrors, "; "), model.ErrNotValid)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	return p.PrintMessage(fmt.Sprintf("Compacted database from %s to %s in %s",
+		printer.FormatBytes(res.SizeBefore), printer.FormatBytes(res.SizeAfter), res.Duration.Round(time.Millisecond)))
+}
@@ -0,0 +1,17 @@
+package commands
+
+import "github.com/alecthomas/kingpin/v2"
+
+// DNSCommand is the parent command for the sandbox DNS query subcommands.
+type DNSCommand struct {
+	Cmd *kingpin.CmdClause
+}
+
+// NewDNSCommand returns the dns parent command.
+func NewDNSCommand(app *kingpin.Application) *DNSCommand {
+	c := &DNSCommand{}
+
+	c.Cmd = app.Command("dns", "Inspect the DNS queries made by the sandboxes with an egress policy.")
+
+	return c
+}
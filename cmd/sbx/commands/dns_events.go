@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/dnsevents"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// DNSEventsCommand shows the DNS queries made by a sandbox.
+type DNSEventsCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	nameOrID string
+	domain   string
+	limit    int
+	since    time.Duration
+}
+
+// NewDNSEventsCommand returns the dns events command.
+func NewDNSEventsCommand(rootCmd *RootCommand, dnsCmd *DNSCommand) *DNSEventsCommand {
+	c := &DNSEventsCommand{rootCmd: rootCmd}
+
+	c.Cmd = dnsCmd.Cmd.Command("events", "Show the DNS queries made by a sandbox with the egress decision.")
+	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+	c.Cmd.Flag("domain", "Show only the queries of this domain.").StringVar(&c.domain)
+	c.Cmd.Flag("limit", "Show only the latest N queries (0 shows all).").Default("0").IntVar(&c.limit)
+	c.Cmd.Flag("since", "Show only the queries made in this period (e.g. 1h).").DurationVar(&c.since)
+
+	return c
+}
+
+func (c DNSEventsCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c DNSEventsCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	if c.since < 0 {
+		return fmt.Errorf("--since can't be negative: %w", model.ErrNotValid)
+	}
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	// Get sandbox to determine which engine to use.
+	sandbox, err := repo.GetSandboxByName(ctx, c.nameOrID)
+	if err != nil {
+		// Try by ID if name lookup failed
+		sandbox, err = repo.GetSandbox(ctx, c.nameOrID)
+		if err != nil {
+			return fmt.Errorf("could not find sandbox: %w", err)
+		}
+	}
+
+	eng, err := newEngineFromConfig(sandbox.Config, repo, c.rootCmd.VMsDir, logger)
+	if err != nil {
+		return fmt.Errorf("could not create engine: %w", err)
+	}
+
+	svc, err := dnsevents.NewService(dnsevents.ServiceConfig{
+		Engine:     eng,
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	req := dnsevents.Request{
+		NameOrID: c.nameOrID,
+		Filter:   model.DNSEventFilter{Domain: c.domain, Limit: c.limit},
+	}
+	if c.since > 0 {
+		req.Filter.Since = time.Now().Add(-c.since)
+	}
+
+	events, err := svc.Run(ctx, req)
+	if err != nil {
+		return fmt.Errorf("could not get DNS events: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	if err := p.PrintDNSEvents(events); err != nil {
+		return fmt.Errorf("could not print DNS events: %w", err)
+	}
+
+	return nil
+}
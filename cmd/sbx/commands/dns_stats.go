@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/dnsstats"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// DNSStatsCommand shows the DNS queries made by a sandbox aggregated per domain.
+type DNSStatsCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	nameOrID string
+	since    time.Duration
+}
+
+// NewDNSStatsCommand returns the dns stats command.
+func NewDNSStatsCommand(rootCmd *RootCommand, dnsCmd *DNSCommand) *DNSStatsCommand {
+	c := &DNSStatsCommand{rootCmd: rootCmd}
+
+	c.Cmd = dnsCmd.Cmd.Command("stats", "Show the DNS queries made by a sandbox aggregated per domain.")
+	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+	c.Cmd.Flag("since", "Aggregate only the queries made in this period (e.g. 1h).").DurationVar(&c.since)
+
+	return c
+}
+
+func (c DNSStatsCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c DNSStatsCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	if c.since < 0 {
+		return fmt.Errorf("--since can't be negative: %w", model.ErrNotValid)
+	}
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	// Get sandbox to determine which engine to use.
+	sandbox, err := repo.GetSandboxByName(ctx, c.nameOrID)
+	if err != nil {
+		// Try by ID if name lookup failed
+		sandbox, err = repo.GetSandbox(ctx, c.nameOrID)
+		if err != nil {
+			return fmt.Errorf("could not find sandbox: %w", err)
+		}
+	}
+
+	eng, err := newEngineFromConfig(sandbox.Config, repo, c.rootCmd.VMsDir, logger)
+	if err != nil {
+		return fmt.Errorf("could not create engine: %w", err)
+	}
+
+	svc, err := dnsstats.NewService(dnsstats.ServiceConfig{
+		Engine:     eng,
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	req := dnsstats.Request{NameOrID: c.nameOrID}
+	if c.since > 0 {
+		req.Since = time.Now().Add(-c.since)
+	}
+
+	stats, err := svc.Run(ctx, req)
+	if err != nil {
+		return fmt.Errorf("could not get DNS stats: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	if err := p.PrintDNSStats(*stats); err != nil {
+		return fmt.Errorf("could not print DNS stats: %w", err)
+	}
+
+	return nil
+}
@@ -3,11 +3,22 @@ package commands
 import (
 	"context"
 	"fmt"
+	"path/filepath"
+	"time"
 
 	"github.com/alecthomas/kingpin/v2"
+	"k8s.io/client-go/util/homedir"
 
+	"github.com/slok/sbx/internal/app/create"
+	"github.com/slok/sbx/internal/app/createstart"
+	"github.com/slok/sbx/internal/app/netcheck"
+	"github.com/slok/sbx/internal/app/remove"
+	"github.com/slok/sbx/internal/image"
 	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/printer"
 	"github.com/slok/sbx/internal/sandbox/firecracker"
+	"github.com/slok/sbx/internal/storage"
+	"github.com/slok/sbx/internal/storage/sqlite"
 )
 
 type DoctorCommand struct {
@@ -15,6 +26,13 @@ type DoctorCommand struct {
 	rootCmd *RootCommand
 
 	engine string
+	format string
+
+	connectivitySandbox string
+	connectivityImage   string
+	imagesDir           string
+	egressPolicy        string
+	pingTargets         []string
 }
 
 // NewDoctorCommand returns the doctor command.
@@ -23,6 +41,13 @@ func NewDoctorCommand(rootCmd *RootCommand, app *kingpin.Application) *DoctorCom
 
 	c.Cmd = app.Command("doctor", "Run preflight checks for sandbox engines.")
 	c.Cmd.Flag("engine", "Engine to check (firecracker, all).").Default("all").EnumVar(&c.engine, "firecracker", "all")
+	c.Cmd.Flag("format", "Output format (table, json, yaml), overrides the global --output flag.").EnumVar(&c.format, OutputFormatTable, OutputFormatJSON, OutputFormatYAML)
+	c.Cmd.Flag("connectivity-sandbox", "Check the connectivity pinging from inside this running sandbox.").HintAction(sandboxNameHints(rootCmd)).StringVar(&c.connectivitySandbox)
+	defaultImagesDir := filepath.Join(homedir.HomeDir(), image.DefaultImagesDir)
+	c.Cmd.Flag("connectivity-image", "Check the connectivity pinging from inside a temporary test VM of this pulled image, removed after the check.").HintAction(imageNameHints(rootCmd, &c.imagesDir)).StringVar(&c.connectivityImage)
+	c.Cmd.Flag("images-dir", "Local directory for images (used with --connectivity-image).").Default(defaultImagesDir).StringVar(&c.imagesDir)
+	c.Cmd.Flag("egress-policy", "Name of a stored egress policy (see 'sbx policy') the test VM is started with (used with --connectivity-image).").StringVar(&c.egressPolicy)
+	c.Cmd.Flag("ping-target", "Address or domain pinged by the connectivity check (repeatable, default 1.1.1.1).").StringsVar(&c.pingTargets)
 
 	return c
 }
@@ -31,96 +56,200 @@ func (c DoctorCommand) Name() string { return c.Cmd.FullCommand() }
 
 func (c DoctorCommand) Run(ctx context.Context) error {
 	logger := c.rootCmd.Logger
-	out := c.rootCmd.Stdout
 
-	var allResults []engineCheckResults
+	var allChecks []printer.EngineChecks
 
 	// Check Firecracker engine
 	if c.engine == "firecracker" || c.engine == "all" {
 		fcEngine, err := firecracker.NewEngine(firecracker.EngineConfig{
+			VMsDir: c.rootCmd.VMsDir,
 			Logger: logger,
 		})
 		if err != nil {
 			return fmt.Errorf("could not create firecracker engine: %w", err)
 		}
 
-		results := fcEngine.Check(ctx)
-		allResults = append(allResults, engineCheckResults{
-			name:    "firecracker",
-			results: results,
+		allChecks = append(allChecks, printer.EngineChecks{
+			Engine:  "firecracker",
+			Results: fcEngine.Check(ctx),
 		})
 	}
 
-	// Print results
-	totalErrors := 0
-	totalWarnings := 0
+	// Check the connectivity from inside a VM.
+	if c.connectivitySandbox != "" || c.connectivityImage != "" {
+		checks, err := c.connectivityChecks(ctx)
+		if err != nil {
+			return err
+		}
+		allChecks = append(allChecks, checks)
+	}
 
-	for _, er := range allResults {
-		fmt.Fprintf(out, "\nChecking %s engine...\n", er.name)
-		for _, r := range er.results {
-			icon := getStatusIcon(r.Status)
-			fmt.Fprintf(out, "  %s %-20s %s\n", icon, r.ID, r.Message)
+	// Print results
+	p := newPrinter(c.rootCmd.outputFormat(c.format), c.rootCmd.Stdout)
+	if err := p.PrintChecks(allChecks); err != nil {
+		return fmt.Errorf("could not print checks: %w", err)
+	}
 
-			switch r.Status {
-			case model.CheckStatusError:
+	// Only the hard failures fail the command, warnings exit with 0.
+	totalErrors := 0
+	for _, ec := range allChecks {
+		for _, r := range ec.Results {
+			if r.Status.Severity() == model.CheckSeverityFail {
 				totalErrors++
-			case model.CheckStatusWarning:
-				totalWarnings++
 			}
 		}
 	}
+	if totalErrors > 0 {
+		return fmt.Errorf("preflight checks failed with %d error(s)", totalErrors)
+	}
+
+	return nil
+}
+
+// connectivityChecks pings the targets from inside the connectivity sandbox, or a
+// temporary test VM of the connectivity image.
+func (c DoctorCommand) connectivityChecks(ctx context.Context) (printer.EngineChecks, error) {
+	logger := c.rootCmd.Logger
+
+	if c.connectivitySandbox != "" && c.connectivityImage != "" {
+		return printer.EngineChecks{}, fmt.Errorf("--connectivity-sandbox and --connectivity-image can't be used together: %w", model.ErrNotValid)
+	}
+	if c.egressPolicy != "" && c.connectivityImage == "" {
+		return printer.EngineChecks{}, fmt.Errorf("--egress-policy requires --connectivity-image: %w", model.ErrNotValid)
+	}
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return printer.EngineChecks{}, fmt.Errorf("could not create repository: %w", err)
+	}
+
+	nameOrID := c.connectivitySandbox
+	var firecrackerBinary string
+	if c.connectivityImage != "" {
+		mgr, err := image.NewLocalImageManager(image.LocalImageManagerConfig{
+			ImagesDir:    c.imagesDir,
+			SnapshotsDir: c.rootCmd.SnapshotsDir,
+			Logger:       logger,
+		})
+		if err != nil {
+			return printer.EngineChecks{}, fmt.Errorf("could not create image manager: %w", err)
+		}
+		exists, err := mgr.Exists(ctx, c.connectivityImage)
+		if err != nil {
+			return printer.EngineChecks{}, fmt.Errorf("could not check image: %w", err)
+		}
+		if !exists {
+			return printer.EngineChecks{}, fmt.Errorf("image %s is not installed, run 'sbx image pull %s' first", c.connectivityImage, c.connectivityImage)
+		}
+		firecrackerBinary = mgr.FirecrackerPath(c.connectivityImage)
 
-	// Summary
-	fmt.Fprintln(out)
-	if totalErrors == 0 && totalWarnings == 0 {
-		fmt.Fprintln(out, "All checks passed!")
-	} else {
-		var summary []string
-		if totalErrors > 0 {
-			summary = append(summary, fmt.Sprintf("%d error(s)", totalErrors))
+		eng, err := firecracker.NewEngine(firecracker.EngineConfig{
+			VMsDir:            c.rootCmd.VMsDir,
+			FirecrackerBinary: firecrackerBinary,
+			Repository:        repo,
+			Logger:            logger,
+		})
+		if err != nil {
+			return printer.EngineChecks{}, fmt.Errorf("could not create engine: %w", err)
 		}
-		if totalWarnings > 0 {
-			summary = append(summary, fmt.Sprintf("%d warning(s)", totalWarnings))
+
+		sb, err := c.startTestVM(ctx, eng, repo, mgr)
+		if sb != nil {
+			defer c.removeTestVM(eng, repo, sb.ID)
 		}
-		fmt.Fprintf(out, "%s\n", joinWithComma(summary))
+		if err != nil {
+			return printer.EngineChecks{}, err
+		}
+		nameOrID = sb.ID
 	}
 
-	// Return error if there are any errors
-	if totalErrors > 0 {
-		return fmt.Errorf("preflight checks failed with %d error(s)", totalErrors)
+	sb, err := repo.GetSandboxByName(ctx, nameOrID)
+	if err != nil {
+		sb, err = repo.GetSandbox(ctx, nameOrID)
+		if err != nil {
+			return printer.EngineChecks{}, fmt.Errorf("could not find sandbox: %w", err)
+		}
 	}
 
-	return nil
-}
+	eng, err := newEngineFromConfig(sb.Config, repo, c.rootCmd.VMsDir, logger)
+	if err != nil {
+		return printer.EngineChecks{}, fmt.Errorf("could not create engine: %w", err)
+	}
 
-type engineCheckResults struct {
-	name    string
-	results []model.CheckResult
-}
+	svc, err := netcheck.NewService(netcheck.ServiceConfig{
+		Engine:     eng,
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return printer.EngineChecks{}, fmt.Errorf("could not create service: %w", err)
+	}
 
-func getStatusIcon(status model.CheckStatus) string {
-	switch status {
-	case model.CheckStatusOK:
-		return "OK"
-	case model.CheckStatusWarning:
-		return "!!"
-	case model.CheckStatusError:
-		return "XX"
-	default:
-		return "??"
+	results, err := svc.Run(ctx, netcheck.Request{NameOrID: sb.ID, Targets: c.pingTargets})
+	if err != nil {
+		return printer.EngineChecks{}, fmt.Errorf("could not check connectivity: %w", err)
 	}
+
+	return printer.EngineChecks{Engine: "connectivity", Results: results}, nil
 }
 
-func joinWithComma(parts []string) string {
-	if len(parts) == 0 {
-		return ""
+// startTestVM creates and starts the temporary test VM of the connectivity check.
+// The returned sandbox must be removed even on errors.
+func (c DoctorCommand) startTestVM(ctx context.Context, eng *firecracker.Engine, repo storage.Repository, mgr image.ImageManager) (*model.Sandbox, error) {
+	svc, err := createstart.NewService(createstart.ServiceConfig{
+		Engine:     eng,
+		Repository: repo,
+		Logger:     c.rootCmd.Logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
 	}
-	if len(parts) == 1 {
-		return parts[0]
+
+	name := fmt.Sprintf("sbx-doctor-%d", time.Now().Unix())
+	res, err := svc.Run(ctx, createstart.Request{
+		Create: create.CreateOptions{
+			Config: model.SandboxConfig{
+				Name:      name,
+				Image:     c.connectivityImage,
+				Resources: model.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 10},
+				FirecrackerEngine: &model.FirecrackerEngineConfig{
+					RootFS:      mgr.RootFSPath(c.connectivityImage),
+					KernelImage: mgr.KernelPath(c.connectivityImage),
+				},
+			},
+			Namespace: c.rootCmd.Namespace,
+		},
+		SessionConfig: model.SessionConfig{EgressPolicyName: c.egressPolicy},
+	})
+	if err != nil {
+		// The partially created test VM is removed by name.
+		if sb, getErr := repo.GetSandboxByName(ctx, name); getErr == nil {
+			return sb, fmt.Errorf("could not start test VM: %w", err)
+		}
+		return nil, fmt.Errorf("could not start test VM: %w", err)
+	}
+
+	return res.Sandbox, nil
+}
+
+// removeTestVM force removes the temporary test VM, even when the check was cancelled.
+func (c DoctorCommand) removeTestVM(eng *firecracker.Engine, repo storage.Repository, id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	svc, err := remove.NewService(remove.ServiceConfig{
+		Engine:     eng,
+		Repository: repo,
+		Logger:     c.rootCmd.Logger,
+	})
+	if err == nil {
+		_, err = svc.Run(ctx, remove.Request{NameOrID: id, Force: true})
 	}
-	result := parts[0]
-	for i := 1; i < len(parts); i++ {
-		result += ", " + parts[i]
+	if err != nil {
+		c.rootCmd.Logger.Warningf("Could not remove test VM %s: %v", id, err)
 	}
-	return result
 }
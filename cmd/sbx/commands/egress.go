@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/egresstest"
+)
+
+// EgressCommand is the parent command for egress policy subcommands.
+type EgressCommand struct {
+	Cmd *kingpin.CmdClause
+}
+
+// NewEgressCommand returns the egress parent command.
+func NewEgressCommand(app *kingpin.Application) *EgressCommand {
+	c := &EgressCommand{}
+
+	c.Cmd = app.Command("egress", "Manage egress policies.")
+
+	return c
+}
+
+// EgressTestCommand evaluates targets against an egress policy without a sandbox.
+type EgressTestCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	configFile string
+	targets    []string
+}
+
+// NewEgressTestCommand returns the egress test command.
+func NewEgressTestCommand(rootCmd *RootCommand, egressCmd *EgressCommand) *EgressTestCommand {
+	c := &EgressTestCommand{rootCmd: rootCmd}
+
+	c.Cmd = egressCmd.Cmd.Command("test", "Show the action an egress policy applies to URLs, hosts or IPs, without a sandbox.")
+	c.Cmd.Flag("file", "Path to a session configuration YAML file with the egress policy.").Short('f').Required().StringVar(&c.configFile)
+	c.Cmd.Arg("targets", "URLs, hosts or IPs to test.").Required().StringsVar(&c.targets)
+
+	return c
+}
+
+func (c EgressTestCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c EgressTestCommand) Run(ctx context.Context) error {
+	policy, err := loadEgressPolicy(ctx, c.configFile)
+	if err != nil {
+		return err
+	}
+
+	svc, err := egresstest.NewService(egresstest.ServiceConfig{
+		Logger: c.rootCmd.Logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	results, err := svc.Run(ctx, egresstest.Request{
+		Policy:  policy,
+		Targets: c.targets,
+	})
+	if err != nil {
+		return fmt.Errorf("could not test egress policy: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	if err := p.PrintEgressTest(results); err != nil {
+		return fmt.Errorf("could not print egress test results: %w", err)
+	}
+
+	return nil
+}
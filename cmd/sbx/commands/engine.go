@@ -10,9 +10,10 @@ import (
 )
 
 // newEngineFromConfig creates an engine based on the sandbox configuration.
-func newEngineFromConfig(cfg model.SandboxConfig, repo storage.Repository, logger log.Logger) (sandbox.Engine, error) {
+func newEngineFromConfig(cfg model.SandboxConfig, repo storage.Repository, vmsDir string, logger log.Logger) (sandbox.Engine, error) {
 	if cfg.FirecrackerEngine != nil {
 		return firecracker.NewEngine(firecracker.EngineConfig{
+			VMsDir:     vmsDir,
 			Repository: repo,
 			Logger:     logger,
 		})
@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/alecthomas/kingpin/v2"
+	"github.com/alecthomas/units"
 
 	"github.com/slok/sbx/internal/app/exec"
 	"github.com/slok/sbx/internal/model"
@@ -17,12 +18,14 @@ type ExecCommand struct {
 	Cmd     *kingpin.CmdClause
 	rootCmd *RootCommand
 
-	nameOrID   string
-	command    []string
-	workingDir string
-	envSpecs   []string
-	tty        bool
-	files      []string
+	nameOrID      string
+	command       []string
+	workingDir    string
+	envSpecs      []string
+	tty           bool
+	files         []string
+	maxOutput     units.Base2Bytes
+	onOutputLimit string
 }
 
 // NewExecCommand returns the exec command.
@@ -30,12 +33,14 @@ func NewExecCommand(rootCmd *RootCommand, app *kingpin.Application) *ExecCommand
 	c := &ExecCommand{rootCmd: rootCmd}
 
 	c.Cmd = app.Command("exec", "Execute a command in a running sandbox.")
-	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().StringVar(&c.nameOrID)
+	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
 	c.Cmd.Arg("command", "Command to execute (use -- before command).").Required().StringsVar(&c.command)
 	c.Cmd.Flag("workdir", "Working directory for command execution.").Short('w').StringVar(&c.workingDir)
 	c.Cmd.Flag("env", "Environment variables (KEY=VALUE or KEY from current environment). Can be repeated.").Short('e').StringsVar(&c.envSpecs)
 	c.Cmd.Flag("tty", "Allocate a pseudo-TTY.").Short('t').BoolVar(&c.tty)
 	c.Cmd.Flag("file", "Upload local file to sandbox before exec (into workdir). Can be repeated.").Short('f').StringsVar(&c.files)
+	c.Cmd.Flag("max-output", "Limit the command output, stdout and stderr combined (e.g. 10MB, 0 is unlimited).").Default("0").BytesVar(&c.maxOutput)
+	c.Cmd.Flag("on-output-limit", "What to do when the output exceeds --max-output: truncate (discard the rest) or abort (kill the command).").Default(string(model.ExecOutputLimitTruncate)).EnumVar(&c.onOutputLimit, string(model.ExecOutputLimitTruncate), string(model.ExecOutputLimitAbort))
 
 	return c
 }
@@ -52,8 +57,9 @@ func (c ExecCommand) Run(ctx context.Context) error {
 
 	// Initialize storage (SQLite).
 	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
-		DBPath: c.rootCmd.DBPath,
-		Logger: logger,
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
 	})
 	if err != nil {
 		return fmt.Errorf("could not create repository: %w", err)
@@ -70,7 +76,7 @@ func (c ExecCommand) Run(ctx context.Context) error {
 	}
 
 	// Initialize engine based on sandbox configuration.
-	eng, err := newEngineFromConfig(sandbox.Config, repo, logger)
+	eng, err := newEngineFromConfig(sandbox.Config, repo, c.rootCmd.VMsDir, logger)
 	if err != nil {
 		return fmt.Errorf("could not create engine: %w", err)
 	}
@@ -85,24 +91,57 @@ func (c ExecCommand) Run(ctx context.Context) error {
 		return fmt.Errorf("could not create service: %w", err)
 	}
 
+	// Machine readable output captures the command output into the result instead of
+	// streaming it to the terminal.
+	structured := c.rootCmd.structuredOutput("")
+	if structured && c.tty {
+		return fmt.Errorf("--tty can't be used with %s output: %w", c.rootCmd.outputFormat(""), model.ErrNotValid)
+	}
+
+	opts := model.ExecOpts{
+		WorkingDir: c.workingDir,
+		Env:        cmdEnv,
+		Stdin:      os.Stdin,
+		Stdout:     os.Stdout,
+		Stderr:     os.Stderr,
+		Tty:        c.tty,
+	}
+	if structured {
+		opts.Stdout = nil
+		opts.Stderr = nil
+	}
+
 	// Execute command with stdin/stdout/stderr wired directly to the terminal.
 	result, err := svc.Run(ctx, exec.Request{
-		NameOrID: c.nameOrID,
-		Command:  c.command,
-		Files:    c.files,
-		Opts: model.ExecOpts{
-			WorkingDir: c.workingDir,
-			Env:        cmdEnv,
-			Stdin:      os.Stdin,
-			Stdout:     os.Stdout,
-			Stderr:     os.Stderr,
-			Tty:        c.tty,
-		},
+		NameOrID:          c.nameOrID,
+		Command:           c.command,
+		Files:             c.files,
+		Opts:              opts,
+		CaptureOutput:     structured,
+		MaxOutputBytes:    int64(c.maxOutput),
+		OutputLimitPolicy: model.ExecOutputLimitPolicy(c.onOutputLimit),
+		Caller:            "cli",
 	})
 	if err != nil {
 		return fmt.Errorf("could not execute command: %w", err)
 	}
 
+	if structured {
+		p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+		if err := p.PrintExecResult(*result); err != nil {
+			return fmt.Errorf("could not print result: %w", err)
+		}
+	}
+
+	if result.OutputLimitExceeded {
+		if c.onOutputLimit == string(model.ExecOutputLimitAbort) {
+			return fmt.Errorf("command aborted, its output exceeded the %s limit", c.maxOutput)
+		}
+		if !structured {
+			fmt.Fprintf(c.rootCmd.Stderr, "sbx: output truncated, it exceeded the %s limit\n", c.maxOutput)
+		}
+	}
+
 	// Exit with the command's exit code
 	os.Exit(result.ExitCode)
 	return nil
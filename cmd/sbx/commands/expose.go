@@ -0,0 +1,181 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/forward"
+	"github.com/slok/sbx/internal/ingress"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/sqlite"
+	"github.com/slok/sbx/internal/tlscert"
+)
+
+type ExposeCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	targets []string
+	listen  string
+	tls     bool
+	tlsCert string
+	tlsKey  string
+}
+
+// NewExposeCommand returns the expose command.
+func NewExposeCommand(rootCmd *RootCommand, app *kingpin.Application) *ExposeCommand {
+	c := &ExposeCommand{rootCmd: rootCmd}
+
+	c.Cmd = app.Command("expose", "Expose sandbox HTTP services on a host ingress routing http://<sandbox-name>.localhost to each sandbox.")
+	c.Cmd.Arg("targets", "Sandbox HTTP services 'NAME:PORT', the port can be a named port of the sandbox (e.g., web:3000 or web:http).").Required().StringsVar(&c.targets)
+	c.Cmd.Flag("listen", "Ingress listen address.").Default(ingress.DefaultAddress).StringVar(&c.listen)
+	c.Cmd.Flag("tls", "Serve https with a generated localhost certificate unless --tls-cert is set, the sandboxes get plain HTTP.").BoolVar(&c.tls)
+	c.Cmd.Flag("tls-cert", "TLS certificate PEM file of the ingress (implies --tls).").StringVar(&c.tlsCert)
+	c.Cmd.Flag("tls-key", "TLS key PEM file of --tls-cert.").StringVar(&c.tlsKey)
+
+	return c
+}
+
+func (c ExposeCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c ExposeCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	// Initialize storage (SQLite).
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	// Resolve the sandboxes and their ports before listening.
+	type target struct {
+		sandbox model.Sandbox
+		svc     *forward.Service
+		port    int
+	}
+	targets := make([]target, 0, len(c.targets))
+	for _, t := range c.targets {
+		nameOrID, port, ok := strings.Cut(t, ":")
+		if !ok || nameOrID == "" || port == "" {
+			return fmt.Errorf("invalid target %q, expected 'NAME:PORT': %w", t, model.ErrNotValid)
+		}
+
+		sandbox, err := repo.GetSandboxByName(ctx, nameOrID)
+		if err != nil {
+			sandbox, err = repo.GetSandbox(ctx, nameOrID)
+			if err != nil {
+				return fmt.Errorf("could not find sandbox: %w", err)
+			}
+		}
+
+		pm, err := model.ParseNamedPortMapping(port, sandbox.Config.Ports)
+		if err != nil {
+			return fmt.Errorf("invalid target %q: %w", t, err)
+		}
+
+		eng, err := newEngineFromConfig(sandbox.Config, repo, c.rootCmd.VMsDir, logger)
+		if err != nil {
+			return fmt.Errorf("could not create engine: %w", err)
+		}
+		svc, err := forward.NewService(forward.ServiceConfig{
+			Engine:     eng,
+			Repository: repo,
+			Logger:     logger,
+		})
+		if err != nil {
+			return fmt.Errorf("could not create service: %w", err)
+		}
+
+		targets = append(targets, target{sandbox: *sandbox, svc: svc, port: pm.RemotePort})
+	}
+
+	srvCfg := ingress.ServerConfig{Address: c.listen, Logger: logger}
+	if fwdTLS := forwardTLS(c.tls, c.tlsCert, c.tlsKey); fwdTLS != nil {
+		resolved, err := tlscert.Resolve(*fwdTLS, defaultTLSDir())
+		if err != nil {
+			return fmt.Errorf("invalid tls: %w", err)
+		}
+		srvCfg.TLS, err = tlscert.ServerConfig(resolved.CertFile, resolved.KeyFile)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.rootCmd.Stdout, "TLS certificate: %s\n", resolved.CertFile)
+	}
+
+	srv, err := ingress.NewServer(srvCfg)
+	if err != nil {
+		return fmt.Errorf("could not start ingress: %w", err)
+	}
+	defer srv.Close(context.Background())
+
+	// Setup signal handling for graceful shutdown
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		fmt.Fprintln(c.rootCmd.Stdout) // New line after ^C
+		cancel()
+	}()
+
+	fmt.Fprintf(c.rootCmd.Stdout, "Exposing sandboxes on %s (press Ctrl+C to stop):\n", srv.Addr())
+
+	// Each sandbox is routed to the local end of a port forward on a free port,
+	// the first failure stops all of them.
+	var (
+		wg      sync.WaitGroup
+		errOnce sync.Once
+		runErr  error
+	)
+	fail := func(err error) {
+		errOnce.Do(func() { runErr = err })
+		cancel()
+	}
+	for _, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := t.svc.Run(ctx, forward.Request{
+				NameOrID: t.sandbox.ID,
+				Ports:    []model.PortMapping{{BindAddress: "127.0.0.1", RemotePort: t.port}},
+				Ready: func(ports []model.PortMapping) {
+					target := net.JoinHostPort("127.0.0.1", strconv.Itoa(ports[0].LocalPort))
+					if err := srv.AddRoute(t.sandbox.Name, target); err != nil {
+						fail(fmt.Errorf("could not expose %s: %w", t.sandbox.Name, err))
+						return
+					}
+					fmt.Fprintf(c.rootCmd.Stdout, "  %s -> %s:%d\n", srv.URL(t.sandbox.Name), t.sandbox.Name, t.port)
+				},
+			})
+			// A quarantined sandbox stops being exposed, the others keep being served.
+			if errors.Is(err, forward.ErrQuarantined) {
+				srv.RemoveRoute(t.sandbox.Name)
+				fmt.Fprintf(c.rootCmd.Stdout, "  %s quarantined, no longer exposed\n", t.sandbox.Name)
+				return
+			}
+			if err != nil && !errors.Is(err, context.Canceled) {
+				fail(fmt.Errorf("could not expose %s: %w", t.sandbox.Name, err))
+			}
+		}()
+	}
+	wg.Wait()
+
+	return runErr
+}
@@ -5,11 +5,14 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 
 	"github.com/alecthomas/kingpin/v2"
+	"k8s.io/client-go/util/homedir"
 
 	"github.com/slok/sbx/internal/app/forward"
+	"github.com/slok/sbx/internal/conventions"
 	"github.com/slok/sbx/internal/model"
 	"github.com/slok/sbx/internal/storage/sqlite"
 )
@@ -21,6 +24,9 @@ type ForwardCommand struct {
 	nameOrID string
 	ports    []string
 	host     string
+	tls      bool
+	tlsCert  string
+	tlsKey   string
 }
 
 // NewForwardCommand returns the forward command.
@@ -30,9 +36,12 @@ func NewForwardCommand(rootCmd *RootCommand, app *kingpin.Application) *ForwardC
 	}
 
 	c.Cmd = app.Command("forward", "Forward ports from localhost to a running sandbox.")
-	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().StringVar(&c.nameOrID)
-	c.Cmd.Arg("ports", "Port mappings (e.g., 8080 or 8080:8080).").Required().StringsVar(&c.ports)
+	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+	c.Cmd.Arg("ports", "Port mappings, the remote port can be a named port of the sandbox (e.g., 8080, 9000:8080, web or 9000:web), a 0 local port binds a free port (e.g., 0:8080).").Required().StringsVar(&c.ports)
 	c.Cmd.Flag("host", "Local address to bind on (e.g., localhost, 0.0.0.0).").Default("localhost").StringVar(&c.host)
+	c.Cmd.Flag("tls", "Terminate TLS on the local ports and forward plain connections, with a generated localhost certificate unless --tls-cert is set.").BoolVar(&c.tls)
+	c.Cmd.Flag("tls-cert", "TLS certificate PEM file of the local ports (implies --tls).").StringVar(&c.tlsCert)
+	c.Cmd.Flag("tls-key", "TLS key PEM file of --tls-cert.").StringVar(&c.tlsKey)
 
 	return c
 }
@@ -42,21 +51,11 @@ func (c ForwardCommand) Name() string { return c.Cmd.FullCommand() }
 func (c ForwardCommand) Run(ctx context.Context) error {
 	logger := c.rootCmd.Logger
 
-	// Parse port mappings
-	portMappings := make([]model.PortMapping, 0, len(c.ports))
-	for _, p := range c.ports {
-		pm, err := model.ParsePortMapping(p)
-		if err != nil {
-			return fmt.Errorf("invalid port mapping %q: %w", p, err)
-		}
-		pm.BindAddress = c.host
-		portMappings = append(portMappings, pm)
-	}
-
 	// Initialize storage (SQLite).
 	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
-		DBPath: c.rootCmd.DBPath,
-		Logger: logger,
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
 	})
 	if err != nil {
 		return fmt.Errorf("could not create repository: %w", err)
@@ -72,8 +71,21 @@ func (c ForwardCommand) Run(ctx context.Context) error {
 		}
 	}
 
+	// Parse port mappings, resolving the sandbox named ports.
+	fwdTLS := forwardTLS(c.tls, c.tlsCert, c.tlsKey)
+	portMappings := make([]model.PortMapping, 0, len(c.ports))
+	for _, p := range c.ports {
+		pm, err := model.ParseNamedPortMapping(p, sandbox.Config.Ports)
+		if err != nil {
+			return fmt.Errorf("invalid port mapping %q: %w", p, err)
+		}
+		pm.BindAddress = c.host
+		pm.TLS = fwdTLS
+		portMappings = append(portMappings, pm)
+	}
+
 	// Initialize engine based on sandbox configuration.
-	eng, err := newEngineFromConfig(sandbox.Config, repo, logger)
+	eng, err := newEngineFromConfig(sandbox.Config, repo, c.rootCmd.VMsDir, logger)
 	if err != nil {
 		return fmt.Errorf("could not create engine: %w", err)
 	}
@@ -83,19 +95,12 @@ func (c ForwardCommand) Run(ctx context.Context) error {
 		Engine:     eng,
 		Repository: repo,
 		Logger:     logger,
+		TLSDir:     defaultTLSDir(),
 	})
 	if err != nil {
 		return fmt.Errorf("could not create service: %w", err)
 	}
 
-	// Print forwarding info
-	fmt.Fprintf(c.rootCmd.Stdout, "Forwarding ports for %s:\n", sandbox.Name)
-	for _, pm := range portMappings {
-		fmt.Fprintf(c.rootCmd.Stdout, "  %s:%d -> sandbox:%d\n", pm.ListenAddress(), pm.LocalPort, pm.RemotePort)
-	}
-	fmt.Fprintln(c.rootCmd.Stdout)
-	fmt.Fprintln(c.rootCmd.Stdout, "Press Ctrl+C to stop")
-
 	// Setup signal handling for graceful shutdown
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -108,13 +113,38 @@ func (c ForwardCommand) Run(ctx context.Context) error {
 		cancel()
 	}()
 
-	// Start port forwarding (blocks until cancelled)
+	// Start port forwarding (blocks until cancelled), the forwarding info is
+	// printed once listening so the free (0) local ports are known.
 	if err := svc.Run(ctx, forward.Request{
 		NameOrID: c.nameOrID,
 		Ports:    portMappings,
+		Ready: func(ports []model.PortMapping) {
+			fmt.Fprintf(c.rootCmd.Stdout, "Forwarding ports for %s:\n", sandbox.Name)
+			for _, pm := range ports {
+				fmt.Fprintf(c.rootCmd.Stdout, "  %s:%d -> sandbox:%d\n", pm.ListenAddress(), pm.LocalPort, pm.RemotePort)
+			}
+			if len(ports) > 0 && ports[0].TLS != nil {
+				fmt.Fprintf(c.rootCmd.Stdout, "TLS certificate: %s\n", ports[0].TLS.CertFile)
+			}
+			fmt.Fprintln(c.rootCmd.Stdout)
+			fmt.Fprintln(c.rootCmd.Stdout, "Press Ctrl+C to stop")
+		},
 	}); err != nil {
 		return fmt.Errorf("port forwarding failed: %w", err)
 	}
 
 	return nil
 }
+
+// forwardTLS returns the TLS of the --tls flags, nil without TLS.
+func forwardTLS(enabled bool, certFile, keyFile string) *model.ForwardTLS {
+	if !enabled && certFile == "" && keyFile == "" {
+		return nil
+	}
+	return &model.ForwardTLS{CertFile: certFile, KeyFile: keyFile}
+}
+
+// defaultTLSDir returns the directory of the generated localhost certificate.
+func defaultTLSDir() string {
+	return filepath.Join(homedir.HomeDir(), conventions.DefaultDataDir, conventions.TLSDir)
+}
@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/exechistory"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// HistoryCommand shows the commands executed in a sandbox.
+type HistoryCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	nameOrID string
+	limit    int
+	since    time.Duration
+}
+
+// NewHistoryCommand returns the history command.
+func NewHistoryCommand(rootCmd *RootCommand, app *kingpin.Application) *HistoryCommand {
+	c := &HistoryCommand{rootCmd: rootCmd}
+
+	c.Cmd = app.Command("history", "Show the commands executed in a sandbox (exec and shell).")
+	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+	c.Cmd.Flag("limit", "Show only the latest N commands (0 shows all).").Default("0").IntVar(&c.limit)
+	c.Cmd.Flag("since", "Show only the commands executed in this period (e.g. 24h).").DurationVar(&c.since)
+
+	return c
+}
+
+func (c HistoryCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c HistoryCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	if c.since < 0 {
+		return fmt.Errorf("--since can't be negative: %w", model.ErrNotValid)
+	}
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	svc, err := exechistory.NewService(exechistory.ServiceConfig{
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	req := exechistory.Request{NameOrID: c.nameOrID, Limit: c.limit}
+	if c.since > 0 {
+		req.Since = time.Now().Add(-c.since)
+	}
+
+	records, err := svc.Run(ctx, req)
+	if err != nil {
+		return fmt.Errorf("could not get exec history: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	if err := p.PrintExecHistory(records); err != nil {
+		return fmt.Errorf("could not print exec history: %w", err)
+	}
+
+	return nil
+}
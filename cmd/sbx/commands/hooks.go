@@ -0,0 +1,14 @@
+package commands
+
+import (
+	"github.com/slok/sbx/internal/hooks"
+	"github.com/slok/sbx/internal/log"
+)
+
+// newHooksRunner creates the runner of the host hooks of the hooks directory.
+func newHooksRunner(rootCmd *RootCommand, logger log.Logger) (hooks.Runner, error) {
+	return hooks.NewRunner(hooks.RunnerConfig{
+		Dir:    rootCmd.HooksDir,
+		Logger: logger,
+	})
+}
@@ -0,0 +1,19 @@
+package commands
+
+import (
+	"github.com/alecthomas/kingpin/v2"
+)
+
+// IdleCommand is the parent command for idle subcommands.
+type IdleCommand struct {
+	Cmd *kingpin.CmdClause
+}
+
+// NewIdleCommand returns the idle parent command.
+func NewIdleCommand(app *kingpin.Application) *IdleCommand {
+	c := &IdleCommand{}
+
+	c.Cmd = app.Command("idle", "Manage the idle policies that stop the sandboxes without activity.")
+
+	return c
+}
@@ -0,0 +1,31 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/alecthomas/kingpin/v2"
+)
+
+// IdleRmCommand removes the idle policy of a sandbox.
+type IdleRmCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	nameOrID string
+}
+
+// NewIdleRmCommand returns the idle rm command.
+func NewIdleRmCommand(rootCmd *RootCommand, idleCmd *IdleCommand) *IdleRmCommand {
+	c := &IdleRmCommand{rootCmd: rootCmd}
+
+	c.Cmd = idleCmd.Cmd.Command("rm", "Remove the idle policy of a sandbox.")
+	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+
+	return c
+}
+
+func (c IdleRmCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c IdleRmCommand) Run(ctx context.Context) error {
+	return setIdlePolicy(ctx, c.rootCmd, c.nameOrID, nil)
+}
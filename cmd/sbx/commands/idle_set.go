@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/idlepolicy"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// IdleSetCommand sets the idle policy of a sandbox.
+type IdleSetCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	nameOrID string
+	timeout  time.Duration
+	action   string
+}
+
+// NewIdleSetCommand returns the idle set command.
+func NewIdleSetCommand(rootCmd *RootCommand, idleCmd *IdleCommand) *IdleSetCommand {
+	c := &IdleSetCommand{rootCmd: rootCmd}
+
+	c.Cmd = idleCmd.Cmd.Command("set", "Set the idle policy of a sandbox, applied by sbx idle suspend.")
+	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+	c.Cmd.Flag("timeout", "How long the sandbox can be idle before it's suspended (e.g. 30m).").Required().DurationVar(&c.timeout)
+	c.Cmd.Flag("action", "How the idle sandbox is suspended.").Default(string(model.IdleActionStop)).EnumVar(&c.action, string(model.IdleActionStop))
+
+	return c
+}
+
+func (c IdleSetCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c IdleSetCommand) Run(ctx context.Context) error {
+	return setIdlePolicy(ctx, c.rootCmd, c.nameOrID, &model.IdlePolicy{Timeout: c.timeout, Action: model.IdleAction(c.action)})
+}
+
+// setIdlePolicy sets or removes (nil policy) the idle policy of a sandbox.
+func setIdlePolicy(ctx context.Context, rootCmd *RootCommand, nameOrID string, policy *model.IdlePolicy) error {
+	logger := rootCmd.Logger
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    rootCmd.DBPath,
+		Namespace: rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	svc, err := idlepolicy.NewService(idlepolicy.ServiceConfig{
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	sandbox, err := svc.Run(ctx, idlepolicy.Request{NameOrID: nameOrID, Policy: policy})
+	if err != nil {
+		return fmt.Errorf("could not set idle policy: %w", err)
+	}
+
+	msg := fmt.Sprintf("Removed sandbox %s idle policy", sandbox.Name)
+	if sandbox.IdlePolicy != nil {
+		msg = fmt.Sprintf("Sandbox %s will %s after %s idle", sandbox.Name, sandbox.IdlePolicy.Action, sandbox.IdlePolicy.Timeout)
+	}
+	if err := printSandboxResult(rootCmd, *sandbox, msg); err != nil {
+		return fmt.Errorf("could not print message: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/idlecheck"
+	"github.com/slok/sbx/internal/app/stop"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// IdleSuspendCommand suspends the sandboxes idle longer than their idle policy timeout.
+type IdleSuspendCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	watch    bool
+	interval time.Duration
+}
+
+// NewIdleSuspendCommand returns the idle suspend command.
+func NewIdleSuspendCommand(rootCmd *RootCommand, idleCmd *IdleCommand) *IdleSuspendCommand {
+	c := &IdleSuspendCommand{rootCmd: rootCmd}
+
+	c.Cmd = idleCmd.Cmd.Command("suspend", "Suspend the running sandboxes idle longer than their idle policy timeout (run it periodically, or with --watch).")
+	c.Cmd.Flag("watch", "Keep checking the sandboxes until Ctrl+C.").BoolVar(&c.watch)
+	c.Cmd.Flag("interval", "Time between the checks in watch mode, shorter than the idle timeouts.").Default("1m").DurationVar(&c.interval)
+
+	return c
+}
+
+func (c IdleSuspendCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c IdleSuspendCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	if c.interval <= 0 {
+		return fmt.Errorf("interval must be greater than 0: %w", model.ErrNotValid)
+	}
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	if !c.watch {
+		return c.suspend(ctx, repo)
+	}
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		// A failed check is retried on the next one.
+		if err := c.suspend(ctx, repo); err != nil {
+			logger.Warningf("Could not suspend idle sandboxes: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// suspend stops the idle sandboxes and prints them, a sandbox that can't be checked
+// or stopped doesn't stop the others.
+func (c IdleSuspendCommand) suspend(ctx context.Context, repo storage.Repository) error {
+	sandboxes, err := repo.ListSandboxes(ctx)
+	if err != nil {
+		return fmt.Errorf("could not list sandboxes: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	var errs []error
+	for _, sb := range sandboxes {
+		if sb.Status != model.SandboxStatusRunning || sb.IdlePolicy == nil {
+			continue
+		}
+
+		status, err := c.suspendIfIdle(ctx, repo, sb)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sandbox %s: %w", sb.Name, err))
+			continue
+		}
+		if !status.Idle {
+			continue
+		}
+
+		if err := p.PrintMessage(fmt.Sprintf("Stopped sandbox %s, idle for %s", sb.Name, status.IdleFor.Round(time.Second))); err != nil {
+			return fmt.Errorf("could not print message: %w", err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// suspendIfIdle stops the sandbox when it's idle and returns its idle status.
+func (c IdleSuspendCommand) suspendIfIdle(ctx context.Context, repo storage.Repository, sb model.Sandbox) (*model.IdleStatus, error) {
+	logger := c.rootCmd.Logger
+
+	eng, err := newEngineFromConfig(sb.Config, repo, c.rootCmd.VMsDir, logger)
+	if err != nil {
+		return nil, fmt.Errorf("could not create engine: %w", err)
+	}
+
+	checkSvc, err := idlecheck.NewService(idlecheck.ServiceConfig{
+		Engine:     eng,
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	status, err := checkSvc.Run(ctx, idlecheck.Request{NameOrID: sb.ID})
+	if err != nil {
+		return nil, fmt.Errorf("could not check sandbox activity: %w", err)
+	}
+	if !status.Idle {
+		return nil, nil
+	}
+
+	stopSvc, err := stop.NewService(stop.ServiceConfig{
+		Engine:     eng,
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	if _, err := stopSvc.Run(ctx, stop.Request{NameOrID: sb.ID}); err != nil {
+		return nil, fmt.Errorf("could not stop sandbox: %w", err)
+	}
+
+	return status, nil
+}
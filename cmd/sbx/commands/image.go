@@ -14,15 +14,16 @@ import (
 
 // ImageCommand is the parent command for image management subcommands.
 type ImageCommand struct {
-	Cmd *kingpin.CmdClause
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
 
 	repo      string
 	imagesDir string
 }
 
 // NewImageCommand returns the image parent command.
-func NewImageCommand(app *kingpin.Application) *ImageCommand {
-	c := &ImageCommand{}
+func NewImageCommand(rootCmd *RootCommand, app *kingpin.Application) *ImageCommand {
+	c := &ImageCommand{rootCmd: rootCmd}
 
 	c.Cmd = app.Command("image", "Manage VM images.")
 	c.Cmd.Flag("repo", "GitHub repository for images.").Default(image.DefaultRepo).StringVar(&c.repo)
@@ -36,8 +37,9 @@ func NewImageCommand(app *kingpin.Application) *ImageCommand {
 // newLocalImageManager creates a LocalImageManager for local image operations.
 func newLocalImageManager(imgCmd *ImageCommand, logger log.Logger) (image.ImageManager, error) {
 	mgr, err := image.NewLocalImageManager(image.LocalImageManagerConfig{
-		ImagesDir: imgCmd.imagesDir,
-		Logger:    logger,
+		ImagesDir:    imgCmd.imagesDir,
+		SnapshotsDir: imgCmd.rootCmd.SnapshotsDir,
+		Logger:       logger,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("could not create local image manager: %w", err)
@@ -45,6 +47,19 @@ func newLocalImageManager(imgCmd *ImageCommand, logger log.Logger) (image.ImageM
 	return mgr, nil
 }
 
+// newImageBundler creates a LocalImageBundler for image export and import.
+func newImageBundler(imgCmd *ImageCommand, logger log.Logger) (image.ImageBundler, error) {
+	b, err := image.NewLocalImageBundler(image.LocalImageBundlerConfig{
+		ImagesDir:    imgCmd.imagesDir,
+		SnapshotsDir: imgCmd.rootCmd.SnapshotsDir,
+		Logger:       logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create image bundler: %w", err)
+	}
+	return b, nil
+}
+
 // newImagePuller creates a GitHubImagePuller for remote image operations.
 func newImagePuller(imgCmd *ImageCommand, logger log.Logger) (image.ImagePuller, error) {
 	p, err := image.NewGitHubImagePuller(image.GitHubImagePullerConfig{
@@ -58,3 +73,16 @@ func newImagePuller(imgCmd *ImageCommand, logger log.Logger) (image.ImagePuller,
 	}
 	return p, nil
 }
+
+// newImageCustomizer creates a LocalImageCustomizer for the pulled image customizations.
+func newImageCustomizer(imgCmd *ImageCommand, logger log.Logger) (image.ImageCustomizer, error) {
+	c, err := image.NewLocalImageCustomizer(image.LocalImageCustomizerConfig{
+		ImagesDir:    imgCmd.imagesDir,
+		SnapshotsDir: imgCmd.rootCmd.SnapshotsDir,
+		Logger:       logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create image customizer: %w", err)
+	}
+	return c, nil
+}
@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/imageadd"
+	"github.com/slok/sbx/internal/image"
+)
+
+// ImageAddCommand installs an image from a user built kernel and rootfs.
+type ImageAddCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+	imgCmd  *ImageCommand
+
+	name          string
+	kernel        string
+	rootfs        string
+	firecracker   string
+	kernelVersion string
+	distro        string
+	distroVersion string
+	profile       string
+	force         bool
+}
+
+// NewImageAddCommand returns the image add command.
+func NewImageAddCommand(rootCmd *RootCommand, imgCmd *ImageCommand) *ImageAddCommand {
+	c := &ImageAddCommand{rootCmd: rootCmd, imgCmd: imgCmd}
+
+	c.Cmd = imgCmd.Cmd.Command("add", "Install an image from a user built kernel and rootfs, generating its manifest.")
+	c.Cmd.Arg("name", "Image name.").Required().StringVar(&c.name)
+	c.Cmd.Flag("kernel", "Kernel binary path.").Required().StringVar(&c.kernel)
+	c.Cmd.Flag("rootfs", "Rootfs ext4 image path.").Required().StringVar(&c.rootfs)
+	c.Cmd.Flag("firecracker", "Firecracker binary stored with the image.").StringVar(&c.firecracker)
+	c.Cmd.Flag("kernel-version", "Kernel version recorded in the manifest.").StringVar(&c.kernelVersion)
+	c.Cmd.Flag("distro", "Rootfs distribution recorded in the manifest (e.g. debian).").StringVar(&c.distro)
+	c.Cmd.Flag("distro-version", "Rootfs distribution version recorded in the manifest.").StringVar(&c.distroVersion)
+	c.Cmd.Flag("profile", "Rootfs profile recorded in the manifest.").StringVar(&c.profile)
+	c.Cmd.Flag("force", "Replace the image if already installed.").BoolVar(&c.force)
+
+	return c
+}
+
+func (c ImageAddCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c ImageAddCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	adder, err := image.NewLocalImageAdder(image.LocalImageAdderConfig{
+		ImagesDir: c.imgCmd.imagesDir,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create image adder: %w", err)
+	}
+
+	mgr, err := newLocalImageManager(c.imgCmd, logger)
+	if err != nil {
+		return err
+	}
+
+	svc, err := imageadd.NewService(imageadd.ServiceConfig{
+		Adder:   adder,
+		Manager: mgr,
+		Logger:  logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	_, err = svc.Run(ctx, imageadd.Request{Options: image.AddImageOptions{
+		Name:           c.name,
+		KernelSrc:      c.kernel,
+		RootFSSrc:      c.rootfs,
+		FirecrackerSrc: c.firecracker,
+		KernelVersion:  c.kernelVersion,
+		Distro:         c.distro,
+		DistroVersion:  c.distroVersion,
+		Profile:        c.profile,
+		Force:          c.force,
+	}})
+	if err != nil {
+		return fmt.Errorf("could not add image: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	return p.PrintMessage(fmt.Sprintf("Added image %s", c.name))
+}
@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/imagedu"
+)
+
+// ImageDuCommand reports the disk usage of the installed images.
+type ImageDuCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+	imgCmd  *ImageCommand
+}
+
+// NewImageDuCommand returns the image du command.
+func NewImageDuCommand(rootCmd *RootCommand, imgCmd *ImageCommand) *ImageDuCommand {
+	c := &ImageDuCommand{rootCmd: rootCmd, imgCmd: imgCmd}
+
+	c.Cmd = imgCmd.Cmd.Command("du", "Show the disk usage of the installed images, including the artifacts shared between them.")
+
+	return c
+}
+
+func (c ImageDuCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c ImageDuCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	mgr, err := newLocalImageManager(c.imgCmd, logger)
+	if err != nil {
+		return err
+	}
+
+	svc, err := imagedu.NewService(imagedu.ServiceConfig{
+		Manager: mgr,
+		Logger:  logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	usage, err := svc.Run(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get images disk usage: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	if err := p.PrintImageDiskUsage(*usage); err != nil {
+		return fmt.Errorf("could not print images disk usage: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/imageexport"
+)
+
+// ImageExportCommand exports an installed image as a self-contained bundle.
+type ImageExportCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+	imgCmd  *ImageCommand
+
+	version string
+	output  string
+}
+
+// NewImageExportCommand returns the image export command.
+func NewImageExportCommand(rootCmd *RootCommand, imgCmd *ImageCommand) *ImageExportCommand {
+	c := &ImageExportCommand{rootCmd: rootCmd, imgCmd: imgCmd}
+
+	c.Cmd = imgCmd.Cmd.Command("export", "Export an installed image as a self-contained bundle for offline hosts.")
+	c.Cmd.Arg("version", "Image version to export (e.g. v0.1.0).").Required().HintAction(imageNameHints(rootCmd, &imgCmd.imagesDir)).StringVar(&c.version)
+	// The -o short flag is the global output format.
+	c.Cmd.Flag("file", "Bundle file path.").Short('f').Required().StringVar(&c.output)
+
+	return c
+}
+
+func (c ImageExportCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c ImageExportCommand) Run(ctx context.Context) (err error) {
+	logger := c.rootCmd.Logger
+
+	bundler, err := newImageBundler(c.imgCmd, logger)
+	if err != nil {
+		return err
+	}
+
+	svc, err := imageexport.NewService(imageexport.ServiceConfig{
+		Bundler: bundler,
+		Logger:  logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	f, err := os.Create(c.output)
+	if err != nil {
+		return fmt.Errorf("could not create bundle file: %w", err)
+	}
+	// The bundle can be written to non regular files (e.g. /dev/stdout).
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("could not stat bundle file: %w", err)
+	}
+	regular := info.Mode().IsRegular()
+
+	defer func() {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("could not write bundle file: %w", cerr)
+		}
+		// Don't leave broken bundles around.
+		if err != nil && regular {
+			os.Remove(c.output)
+		}
+	}()
+
+	if err := svc.Run(ctx, imageexport.Request{Version: c.version, Output: f}); err != nil {
+		return fmt.Errorf("could not export image: %w", err)
+	}
+
+	if !regular {
+		return nil
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	return p.PrintMessage(fmt.Sprintf("Exported image %s to %s", c.version, c.output))
+}
@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/imagefromoci"
+	"github.com/slok/sbx/internal/image"
+)
+
+// ImageFromOCICommand converts an OCI/Docker image into an image.
+type ImageFromOCICommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+	imgCmd  *ImageCommand
+
+	source    string
+	name      string
+	baseImage string
+	kernel    string
+	init      string
+	sizeMB    int
+	force     bool
+}
+
+// NewImageFromOCICommand returns the image from-oci command.
+func NewImageFromOCICommand(rootCmd *RootCommand, imgCmd *ImageCommand) *ImageFromOCICommand {
+	c := &ImageFromOCICommand{rootCmd: rootCmd, imgCmd: imgCmd}
+
+	c.Cmd = imgCmd.Cmd.Command("from-oci", "Convert an OCI/Docker image into a bootable image.")
+	c.Cmd.Arg("image", "OCI image layout or 'docker save' archive path, or an image reference exported with docker or podman.").Required().StringVar(&c.source)
+	c.Cmd.Flag("name", "Image name.").Short('n').Required().StringVar(&c.name)
+	c.Cmd.Flag("base-image", "Installed image the kernel, guest init and firecracker binary are taken from (default: the most recently installed release).").HintAction(imageNameHints(rootCmd, &imgCmd.imagesDir)).StringVar(&c.baseImage)
+	c.Cmd.Flag("kernel", "Kernel binary path, overrides the base image kernel.").StringVar(&c.kernel)
+	c.Cmd.Flag("init", "Guest init binary path, overrides the base image init.").StringVar(&c.init)
+	c.Cmd.Flag("size-mb", "Rootfs size in MB (default: the image content with some room).").IntVar(&c.sizeMB)
+	c.Cmd.Flag("force", "Replace the image if already installed.").BoolVar(&c.force)
+
+	return c
+}
+
+func (c ImageFromOCICommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c ImageFromOCICommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	builder, err := image.NewLocalRootFSBuilder(image.LocalRootFSBuilderConfig{
+		TmpDir: c.imgCmd.imagesDir,
+		Logger: logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create rootfs builder: %w", err)
+	}
+
+	adder, err := image.NewLocalImageAdder(image.LocalImageAdderConfig{
+		ImagesDir: c.imgCmd.imagesDir,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create image adder: %w", err)
+	}
+
+	mgr, err := newLocalImageManager(c.imgCmd, logger)
+	if err != nil {
+		return err
+	}
+
+	svc, err := imagefromoci.NewService(imagefromoci.ServiceConfig{
+		Builder: builder,
+		Adder:   adder,
+		Manager: mgr,
+		TmpDir:  c.imgCmd.imagesDir,
+		Logger:  logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	_, err = svc.Run(ctx, imagefromoci.Request{
+		Name:       c.name,
+		Source:     c.source,
+		BaseImage:  c.baseImage,
+		KernelPath: c.kernel,
+		InitPath:   c.init,
+		SizeMB:     c.sizeMB,
+		Force:      c.force,
+	})
+	if err != nil {
+		return fmt.Errorf("could not convert OCI image: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	return p.PrintMessage(fmt.Sprintf("Created image %s from %s", c.name, c.source))
+}
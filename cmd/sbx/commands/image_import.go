@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/imageimport"
+)
+
+// ImageImportCommand installs an image from a bundle.
+type ImageImportCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+	imgCmd  *ImageCommand
+
+	bundle string
+	force  bool
+}
+
+// NewImageImportCommand returns the image import command.
+func NewImageImportCommand(rootCmd *RootCommand, imgCmd *ImageCommand) *ImageImportCommand {
+	c := &ImageImportCommand{rootCmd: rootCmd, imgCmd: imgCmd}
+
+	c.Cmd = imgCmd.Cmd.Command("import", "Install an image from a bundle created with 'image export'.")
+	c.Cmd.Arg("bundle", "Bundle file path (plain or gzip compressed tar).").Required().StringVar(&c.bundle)
+	c.Cmd.Flag("force", "Replace the image if already installed.").BoolVar(&c.force)
+
+	return c
+}
+
+func (c ImageImportCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c ImageImportCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	bundler, err := newImageBundler(c.imgCmd, logger)
+	if err != nil {
+		return err
+	}
+
+	svc, err := imageimport.NewService(imageimport.ServiceConfig{
+		Bundler: bundler,
+		Logger:  logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	f, err := os.Open(c.bundle)
+	if err != nil {
+		return fmt.Errorf("could not open bundle: %w", err)
+	}
+	defer f.Close()
+
+	name, err := svc.Run(ctx, imageimport.Request{Input: f, Force: c.force})
+	if err != nil {
+		return fmt.Errorf("could not import image: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	return p.PrintMessage(fmt.Sprintf("Imported image %s", name))
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/alecthomas/kingpin/v2"
 
 	"github.com/slok/sbx/internal/app/imageinspect"
-	"github.com/slok/sbx/internal/printer"
 )
 
 // ImageInspectCommand inspects an image release manifest.
@@ -25,8 +24,8 @@ func NewImageInspectCommand(rootCmd *RootCommand, imgCmd *ImageCommand) *ImageIn
 	c := &ImageInspectCommand{rootCmd: rootCmd, imgCmd: imgCmd}
 
 	c.Cmd = imgCmd.Cmd.Command("inspect", "Inspect an image release manifest.")
-	c.Cmd.Arg("version", "Image version to inspect (e.g. v0.1.0).").Required().StringVar(&c.version)
-	c.Cmd.Flag("format", "Output format (table, json).").Default("table").EnumVar(&c.format, "table", "json")
+	c.Cmd.Arg("version", "Image version to inspect (e.g. v0.1.0).").Required().HintAction(imageNameHints(rootCmd, &imgCmd.imagesDir)).StringVar(&c.version)
+	c.Cmd.Flag("format", "Output format (table, json, yaml). Deprecated: use the global --output flag.").EnumVar(&c.format, OutputFormatTable, OutputFormatJSON, OutputFormatYAML)
 
 	return c
 }
@@ -62,13 +61,7 @@ func (c ImageInspectCommand) Run(ctx context.Context) error {
 	}
 
 	// Print output.
-	var p printer.Printer
-	switch c.format {
-	case "json":
-		p = printer.NewJSONPrinter(c.rootCmd.Stdout)
-	default:
-		p = printer.NewTablePrinter(c.rootCmd.Stdout)
-	}
+	p := newPrinter(c.rootCmd.outputFormat(c.format), c.rootCmd.Stdout)
 
 	if err := p.PrintImageInspect(*manifest); err != nil {
 		return fmt.Errorf("could not print image manifest: %w", err)
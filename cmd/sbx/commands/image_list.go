@@ -7,7 +7,6 @@ import (
 	"github.com/alecthomas/kingpin/v2"
 
 	"github.com/slok/sbx/internal/app/imagelist"
-	"github.com/slok/sbx/internal/printer"
 )
 
 // ImageListCommand lists available image releases.
@@ -24,7 +23,7 @@ func NewImageListCommand(rootCmd *RootCommand, imgCmd *ImageCommand) *ImageListC
 	c := &ImageListCommand{rootCmd: rootCmd, imgCmd: imgCmd}
 
 	c.Cmd = imgCmd.Cmd.Command("list", "List available image releases.")
-	c.Cmd.Flag("format", "Output format (table, json).").Default("table").EnumVar(&c.format, "table", "json")
+	c.Cmd.Flag("format", "Output format (table, json, yaml). Deprecated: use the global --output flag.").EnumVar(&c.format, OutputFormatTable, OutputFormatJSON, OutputFormatYAML)
 
 	return c
 }
@@ -59,13 +58,7 @@ func (c ImageListCommand) Run(ctx context.Context) error {
 	}
 
 	// Print output.
-	var p printer.Printer
-	switch c.format {
-	case "json":
-		p = printer.NewJSONPrinter(c.rootCmd.Stdout)
-	default:
-		p = printer.NewTablePrinter(c.rootCmd.Stdout)
-	}
+	p := newPrinter(c.rootCmd.outputFormat(c.format), c.rootCmd.Stdout)
 
 	if err := p.PrintImageList(releases); err != nil {
 		return fmt.Errorf("could not print image list: %w", err)
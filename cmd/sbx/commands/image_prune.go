@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/imageprune"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// ImagePruneCommand removes installed images that no sandbox depends on.
+type ImagePruneCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+	imgCmd  *ImageCommand
+
+	olderThan time.Duration
+	dryRun    bool
+}
+
+// NewImagePruneCommand returns the image prune command.
+func NewImagePruneCommand(rootCmd *RootCommand, imgCmd *ImageCommand) *ImagePruneCommand {
+	c := &ImagePruneCommand{rootCmd: rootCmd, imgCmd: imgCmd}
+
+	c.Cmd = imgCmd.Cmd.Command("prune", "Remove installed images that no sandbox was created from.")
+	c.Cmd.Flag("older-than", "Only prune images installed more than this duration ago (e.g. 168h).").Default("0s").DurationVar(&c.olderThan)
+	c.Cmd.Flag("dry-run", "Show the images that would be pruned without removing them.").BoolVar(&c.dryRun)
+
+	return c
+}
+
+func (c ImagePruneCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c ImagePruneCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	mgr, err := newLocalImageManager(c.imgCmd, logger)
+	if err != nil {
+		return err
+	}
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	svc, err := imageprune.NewService(imageprune.ServiceConfig{
+		Manager:    mgr,
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	pruned, err := svc.Run(ctx, imageprune.Request{OlderThan: c.olderThan, DryRun: c.dryRun})
+	if err != nil {
+		return fmt.Errorf("could not prune images: %w", err)
+	}
+
+	verb := "Pruned"
+	if c.dryRun {
+		verb = "Would prune"
+	}
+	msg := fmt.Sprintf("%s %d images", verb, len(pruned))
+	for _, name := range pruned {
+		msg += fmt.Sprintf("\n  %s", name)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	return p.PrintMessage(msg)
+}
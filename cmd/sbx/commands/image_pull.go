@@ -3,11 +3,15 @@ package commands
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/alecthomas/kingpin/v2"
+	"github.com/alecthomas/units"
 
 	"github.com/slok/sbx/internal/app/imagepull"
-	"github.com/slok/sbx/internal/printer"
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/model"
 )
 
 // ImagePullCommand pulls an image release.
@@ -16,8 +20,12 @@ type ImagePullCommand struct {
 	rootCmd *RootCommand
 	imgCmd  *ImageCommand
 
-	version string
-	force   bool
+	version     string
+	force       bool
+	concurrency int
+	limitRate   units.Base2Bytes
+	runs        []string
+	files       []string
 }
 
 // NewImagePullCommand returns the image pull command.
@@ -27,6 +35,10 @@ func NewImagePullCommand(rootCmd *RootCommand, imgCmd *ImageCommand) *ImagePullC
 	c.Cmd = imgCmd.Cmd.Command("pull", "Pull an image release.")
 	c.Cmd.Arg("version", "Image version to pull (e.g. v0.1.0).").Required().StringVar(&c.version)
 	c.Cmd.Flag("force", "Force re-download even if already installed.").BoolVar(&c.force)
+	c.Cmd.Flag("concurrency", "Number of parallel chunk downloads per artifact.").Default(strconv.Itoa(image.DefaultPullConcurrency)).IntVar(&c.concurrency)
+	c.Cmd.Flag("limit-rate", "Limit the download rate per second (e.g. 10MB, 0 is unlimited).").Default("0").BytesVar(&c.limitRate)
+	c.Cmd.Flag("customize-run", "Shell command run in the image rootfs after the pull, producing a derived image (repeatable, requires root).").StringsVar(&c.runs)
+	c.Cmd.Flag("customize-file", "Local file copied into the image rootfs after the pull, as LOCAL:GUEST (repeatable, requires root).").StringsVar(&c.files)
 
 	return c
 }
@@ -36,32 +48,76 @@ func (c ImagePullCommand) Name() string { return c.Cmd.FullCommand() }
 func (c ImagePullCommand) Run(ctx context.Context) error {
 	logger := c.rootCmd.Logger
 
+	customize, err := c.customization()
+	if err != nil {
+		return err
+	}
+
 	puller, err := newImagePuller(c.imgCmd, logger)
 	if err != nil {
 		return err
 	}
+	customizer, err := newImageCustomizer(c.imgCmd, logger)
+	if err != nil {
+		return err
+	}
 
 	svc, err := imagepull.NewService(imagepull.ServiceConfig{
-		Puller: puller,
-		Logger: logger,
+		Puller:     puller,
+		Customizer: customizer,
+		Logger:     logger,
 	})
 	if err != nil {
 		return fmt.Errorf("could not create service: %w", err)
 	}
 
-	result, err := svc.Run(ctx, imagepull.Request{
-		Version:      c.version,
-		Force:        c.force,
-		StatusWriter: c.rootCmd.Stderr,
-	})
+	req := imagepull.Request{
+		Version:        c.version,
+		Force:          c.force,
+		StatusWriter:   c.rootCmd.Stderr,
+		Concurrency:    c.concurrency,
+		BandwidthLimit: int64(c.limitRate),
+		Customize:      customize,
+	}
+	// On a terminal the downloads are rendered as progress steps instead of the
+	// status lines.
+	progress, finishProgress := newProgress(c.rootCmd)
+	if progress != nil {
+		req.StatusWriter = nil
+		req.OnProgress = func(p image.PullProgress) { progress.Report(p.ProgressEvent()) }
+	}
+	result, err := svc.Run(ctx, req)
+	finishProgress(err)
 	if err != nil {
 		return fmt.Errorf("could not pull image: %w", err)
 	}
 
 	// Print success message.
-	p := printer.NewTablePrinter(c.rootCmd.Stdout)
-	if result.Skipped {
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	switch {
+	case result.Customized != nil && result.Customized.Skipped:
+		return p.PrintMessage(fmt.Sprintf("Customized image %s already installed (use --force to recreate)", result.Customized.Name))
+	case result.Customized != nil:
+		return p.PrintMessage(fmt.Sprintf("Successfully customized image %s into %s", result.Version, result.Customized.Name))
+	case result.Skipped:
 		return p.PrintMessage(fmt.Sprintf("Image %s already installed (use --force to re-download)", result.Version))
 	}
 	return p.PrintMessage(fmt.Sprintf("Successfully pulled image %s", result.Version))
 }
+
+// customization returns the customization of the flags, nil without customization flags.
+func (c ImagePullCommand) customization() (*model.ImageCustomization, error) {
+	if len(c.runs) == 0 && len(c.files) == 0 {
+		return nil, nil
+	}
+
+	cust := &model.ImageCustomization{Commands: c.runs}
+	for _, f := range c.files {
+		src, dst, ok := strings.Cut(f, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid customize file %q, expected LOCAL:GUEST: %w", f, model.ErrNotValid)
+		}
+		cust.Files = append(cust.Files, model.ImageCustomizationFile{Src: src, Dst: dst})
+	}
+	return cust, nil
+}
@@ -7,7 +7,7 @@ import (
 	"github.com/alecthomas/kingpin/v2"
 
 	"github.com/slok/sbx/internal/app/imagerm"
-	"github.com/slok/sbx/internal/printer"
+	"github.com/slok/sbx/internal/storage/sqlite"
 )
 
 // ImageRmCommand removes an installed image.
@@ -17,6 +17,7 @@ type ImageRmCommand struct {
 	imgCmd  *ImageCommand
 
 	version string
+	force   bool
 }
 
 // NewImageRmCommand returns the image rm command.
@@ -24,7 +25,8 @@ func NewImageRmCommand(rootCmd *RootCommand, imgCmd *ImageCommand) *ImageRmComma
 	c := &ImageRmCommand{rootCmd: rootCmd, imgCmd: imgCmd}
 
 	c.Cmd = imgCmd.Cmd.Command("rm", "Remove an installed image.")
-	c.Cmd.Arg("version", "Image version to remove (e.g. v0.1.0).").Required().StringVar(&c.version)
+	c.Cmd.Arg("version", "Image version to remove (e.g. v0.1.0).").Required().HintAction(imageNameHints(rootCmd, &imgCmd.imagesDir)).StringVar(&c.version)
+	c.Cmd.Flag("force", "Remove the image even if sandboxes were created from it.").BoolVar(&c.force)
 
 	return c
 }
@@ -39,18 +41,28 @@ func (c ImageRmCommand) Run(ctx context.Context) error {
 		return err
 	}
 
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
 	svc, err := imagerm.NewService(imagerm.ServiceConfig{
-		Manager: mgr,
-		Logger:  logger,
+		Manager:    mgr,
+		Repository: repo,
+		Logger:     logger,
 	})
 	if err != nil {
 		return fmt.Errorf("could not create service: %w", err)
 	}
 
-	if err := svc.Run(ctx, imagerm.Request{Version: c.version}); err != nil {
+	if err := svc.Run(ctx, imagerm.Request{Version: c.version, Force: c.force}); err != nil {
 		return fmt.Errorf("could not remove image: %w", err)
 	}
 
-	p := printer.NewTablePrinter(c.rootCmd.Stdout)
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
 	return p.PrintMessage(fmt.Sprintf("Removed image %s", c.version))
 }
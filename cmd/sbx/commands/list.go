@@ -3,13 +3,14 @@ package commands
 import (
 	"context"
 	"fmt"
+	"io"
 	"strings"
+	"time"
 
 	"github.com/alecthomas/kingpin/v2"
 
 	"github.com/slok/sbx/internal/app/list"
 	"github.com/slok/sbx/internal/model"
-	"github.com/slok/sbx/internal/printer"
 	"github.com/slok/sbx/internal/storage/sqlite"
 )
 
@@ -19,6 +20,8 @@ type ListCommand struct {
 
 	statusFilter string
 	format       string
+	watch        bool
+	interval     time.Duration
 }
 
 // NewListCommand returns the list command.
@@ -27,7 +30,9 @@ func NewListCommand(rootCmd *RootCommand, app *kingpin.Application) *ListCommand
 
 	c.Cmd = app.Command("list", "List all sandboxes.")
 	c.Cmd.Flag("status", "Filter by status (running, stopped, pending, failed).").StringVar(&c.statusFilter)
-	c.Cmd.Flag("format", "Output format (table, json).").Default("table").EnumVar(&c.format, "table", "json")
+	c.Cmd.Flag("format", "Output format (table, json, yaml). Deprecated: use the global --output flag.").EnumVar(&c.format, OutputFormatTable, OutputFormatJSON, OutputFormatYAML)
+	c.Cmd.Flag("watch", "Keep the list updated on the sandbox changes until Ctrl+C, highlighting the changed rows (requires a terminal and the table output).").Short('w').BoolVar(&c.watch)
+	c.Cmd.Flag("interval", "Time between the refreshes of the watched list without changes.").Default("2s").DurationVar(&c.interval)
 
 	return c
 }
@@ -46,14 +51,19 @@ func (c ListCommand) Run(ctx context.Context) error {
 		case model.SandboxStatusPending, model.SandboxStatusRunning, model.SandboxStatusStopped, model.SandboxStatusFailed:
 			statusFilter = &status
 		default:
-			return fmt.Errorf("invalid status filter: %s (must be: running, stopped, pending, failed)", c.statusFilter)
+			return fmt.Errorf("invalid status filter: %s (must be: running, stopped, pending, failed): %w", c.statusFilter, model.ErrNotValid)
 		}
 	}
 
+	if c.interval <= 0 {
+		return fmt.Errorf("interval must be greater than 0: %w", model.ErrNotValid)
+	}
+
 	// Initialize storage (SQLite).
 	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
-		DBPath: c.rootCmd.DBPath,
-		Logger: logger,
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
 	})
 	if err != nil {
 		return fmt.Errorf("could not create repository: %w", err)
@@ -68,26 +78,25 @@ func (c ListCommand) Run(ctx context.Context) error {
 		return fmt.Errorf("could not create service: %w", err)
 	}
 
-	// Execute list.
-	sandboxes, err := svc.Run(ctx, list.Request{
-		StatusFilter: statusFilter,
-	})
-	if err != nil {
-		return fmt.Errorf("could not list sandboxes: %w", err)
-	}
-
-	// Print output.
-	var p printer.Printer
-	switch c.format {
-	case "json":
-		p = printer.NewJSONPrinter(c.rootCmd.Stdout)
-	default: // table
-		p = printer.NewTablePrinter(c.rootCmd.Stdout)
+	printList := func(w io.Writer, format string) error {
+		sandboxes, err := svc.Run(ctx, list.Request{
+			StatusFilter: statusFilter,
+		})
+		if err != nil {
+			return fmt.Errorf("could not list sandboxes: %w", err)
+		}
+		if err := newPrinter(format, w).PrintList(sandboxes); err != nil {
+			return fmt.Errorf("could not print list: %w", err)
+		}
+		return nil
 	}
 
-	if err := p.PrintList(sandboxes); err != nil {
-		return fmt.Errorf("could not print list: %w", err)
+	// The watched list is refreshed with the same service, driven by the stored changes.
+	if out, ok := c.rootCmd.watchTerminal(c.format); c.watch && ok {
+		return watchView(ctx, out, repo, c.interval, func(w io.Writer) error {
+			return printList(w, OutputFormatTable)
+		})
 	}
 
-	return nil
+	return printList(c.rootCmd.Stdout, c.rootCmd.outputFormat(c.format))
 }
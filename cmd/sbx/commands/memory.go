@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/memorytarget"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+type MemoryCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	nameOrID string
+	targetMB int
+}
+
+// NewMemoryCommand returns the memory command.
+func NewMemoryCommand(rootCmd *RootCommand, app *kingpin.Application) *MemoryCommand {
+	c := &MemoryCommand{rootCmd: rootCmd}
+
+	c.Cmd = app.Command("memory", "Set the memory a running sandbox keeps, the rest is released to the host.")
+	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+	c.Cmd.Arg("target-mb", "Memory in MB the sandbox keeps (0 gives back all the sandbox memory).").Default("0").IntVar(&c.targetMB)
+
+	return c
+}
+
+func (c MemoryCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c MemoryCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	// Initialize storage (SQLite).
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	// Get sandbox to determine which engine to use.
+	sandbox, err := repo.GetSandboxByName(ctx, c.nameOrID)
+	if err != nil {
+		// Try by ID if name lookup failed
+		sandbox, err = repo.GetSandbox(ctx, c.nameOrID)
+		if err != nil {
+			return fmt.Errorf("could not find sandbox: %w", err)
+		}
+	}
+
+	// Initialize engine based on sandbox configuration.
+	eng, err := newEngineFromConfig(sandbox.Config, repo, c.rootCmd.VMsDir, logger)
+	if err != nil {
+		return fmt.Errorf("could not create engine: %w", err)
+	}
+
+	// Create memory target service.
+	svc, err := memorytarget.NewService(memorytarget.ServiceConfig{
+		Engine:     eng,
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	// Execute memory target.
+	sandbox, err = svc.Run(ctx, memorytarget.Request{
+		NameOrID: c.nameOrID,
+		TargetMB: c.targetMB,
+	})
+	if err != nil {
+		return fmt.Errorf("could not set sandbox memory target: %w", err)
+	}
+
+	// Print success message.
+	targetMB := c.targetMB
+	if targetMB == 0 {
+		targetMB = sandbox.Config.Resources.MemoryMB
+	}
+	msg := fmt.Sprintf("Sandbox %s memory target set to %dMB of %dMB", sandbox.Name, targetMB, sandbox.Config.Resources.MemoryMB)
+	if err := printSandboxResult(c.rootCmd, *sandbox, msg); err != nil {
+		return fmt.Errorf("could not print message: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/namespacelist"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// NamespaceListCommand lists the namespaces with sandboxes.
+type NamespaceListCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+}
+
+// NewNamespaceListCommand returns the namespace list command.
+func NewNamespaceListCommand(rootCmd *RootCommand, namespaceCmd *NamespaceCommand) *NamespaceListCommand {
+	c := &NamespaceListCommand{rootCmd: rootCmd}
+
+	c.Cmd = namespaceCmd.Cmd.Command("list", "List the namespaces with sandboxes, all of them with --namespace='*'.")
+
+	return c
+}
+
+func (c NamespaceListCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c NamespaceListCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	svc, err := namespacelist.NewService(namespacelist.ServiceConfig{
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	namespaces, err := svc.Run(ctx)
+	if err != nil {
+		return fmt.Errorf("could not list namespaces: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	if err := p.PrintNamespaceList(namespaces); err != nil {
+		return fmt.Errorf("could not print namespaces: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/printer"
+)
+
+const (
+	// OutputFormatTable is the human readable output format.
+	OutputFormatTable = "table"
+	// OutputFormatJSON is the JSON output format.
+	OutputFormatJSON = "json"
+	// OutputFormatYAML is the YAML output format.
+	OutputFormatYAML = "yaml"
+)
+
+// Exit codes used by the CLI. Commands that run user commands (exec, shell) exit
+// with the exit code of the executed command instead.
+const (
+	// ExitCodeOK is returned when the command succeeded.
+	ExitCodeOK = 0
+	// ExitCodeError is returned on engine, storage and other unclassified errors.
+	ExitCodeError = 1
+	// ExitCodeNotValid is returned on invalid input or invalid operations.
+	ExitCodeNotValid = 2
+	// ExitCodeNotFound is returned when the resource does not exist.
+	ExitCodeNotFound = 3
+	// ExitCodeAlreadyExists is returned when the resource already exists.
+	ExitCodeAlreadyExists = 4
+	// ExitCodeConflict is returned when the sandbox is being changed by another command.
+	ExitCodeConflict = 5
+	// ExitCodeBusy is returned when a host resource is locked by another client of
+	// the data directory.
+	ExitCodeBusy = 6
+	// ExitCodeTimeout is returned when the command timed out (e.g. sbx wait).
+	ExitCodeTimeout = 124
+	// ExitCodeCanceled is returned when the command has been interrupted.
+	ExitCodeCanceled = 130
+)
+
+// ExitCode returns the process exit code for a command error.
+func ExitCode(err error) int {
+	return errorInfo(err).ExitCode
+}
+
+// PrintError prints the error to stderr using the selected output format.
+func PrintError(rootCmd RootCommand, err error) {
+	if err == nil {
+		return
+	}
+	stderr := rootCmd.Stderr
+	if stderr == nil {
+		return
+	}
+	_ = newPrinter(rootCmd.Output, stderr).PrintError(errorInfo(err))
+}
+
+func errorInfo(err error) printer.ErrorInfo {
+	info := printer.ErrorInfo{Message: err.Error()}
+	switch {
+	case errors.Is(err, model.ErrNotFound):
+		info.Code, info.ExitCode = "not_found", ExitCodeNotFound
+	case errors.Is(err, model.ErrAlreadyExists):
+		info.Code, info.ExitCode = "already_exists", ExitCodeAlreadyExists
+	case errors.Is(err, model.ErrNotValid):
+		info.Code, info.ExitCode = "not_valid", ExitCodeNotValid
+	case errors.Is(err, model.ErrConflict):
+		info.Code, info.ExitCode = "conflict", ExitCodeConflict
+	case errors.Is(err, model.ErrBusy):
+		info.Code, info.ExitCode = "busy", ExitCodeBusy
+	case errors.Is(err, context.DeadlineExceeded):
+		info.Code, info.ExitCode = "timeout", ExitCodeTimeout
+	case errors.Is(err, context.Canceled):
+		info.Code, info.ExitCode = "canceled", ExitCodeCanceled
+	default:
+		info.Code, info.ExitCode = "error", ExitCodeError
+	}
+	return info
+}
+
+// outputFormat returns the command output format. The deprecated per-command
+// --format flag takes precedence over the global --output flag when set.
+func (r RootCommand) outputFormat(cmdFormat string) string {
+	if cmdFormat != "" {
+		return cmdFormat
+	}
+	if r.Output == "" {
+		return OutputFormatTable
+	}
+	return r.Output
+}
+
+// structuredOutput returns true when the command output is machine readable.
+func (r RootCommand) structuredOutput(cmdFormat string) bool {
+	return r.outputFormat(cmdFormat) != OutputFormatTable
+}
+
+func newPrinter(format string, w io.Writer) printer.Printer {
+	switch format {
+	case OutputFormatJSON:
+		return printer.NewJSONPrinter(w)
+	case OutputFormatYAML:
+		return printer.NewYAMLPrinter(w)
+	default:
+		return printer.NewTablePrinter(w)
+	}
+}
+
+// printSandboxResult prints the result of a sandbox operation: the message in table
+// output and the sandbox status in the machine readable outputs.
+func printSandboxResult(rootCmd *RootCommand, sandbox model.Sandbox, msg string) error {
+	p := newPrinter(rootCmd.outputFormat(""), rootCmd.Stdout)
+	if rootCmd.structuredOutput("") {
+		return p.PrintStatus(sandbox)
+	}
+	return p.PrintMessage(msg)
+}
+
+// printQuietResult prints the result of an operation with --quiet: only the
+// resulting ID or name on stdout, so scripts can capture it, and the message on
+// stderr. It takes precedence over the output format.
+func printQuietResult(rootCmd *RootCommand, id, msg string) error {
+	if msg != "" {
+		if err := newPrinter(OutputFormatTable, rootCmd.Stderr).PrintMessage(msg); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(rootCmd.Stdout, id)
+	return err
+}
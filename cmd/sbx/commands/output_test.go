@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestPrintQuietResult(t *testing.T) {
+	tests := map[string]struct {
+		output    string
+		id        string
+		msg       string
+		expStdout string
+		expStderr string
+	}{
+		"The ID should be printed on stdout and the message on stderr.": {
+			id:        "01K0SNAPSHOTID",
+			msg:       "Snapshot image created: my-snap",
+			expStdout: "01K0SNAPSHOTID\n",
+			expStderr: "Snapshot image created: my-snap\n",
+		},
+
+		"A structured output format should not change the quiet output.": {
+			output:    OutputFormatJSON,
+			id:        "01K0SANDBOXID",
+			msg:       "Sandbox created successfully!",
+			expStdout: "01K0SANDBOXID\n",
+			expStderr: "Sandbox created successfully!\n",
+		},
+
+		"Without message only the ID should be printed.": {
+			output:    OutputFormatYAML,
+			id:        "01K0SANDBOXID",
+			expStdout: "01K0SANDBOXID\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			rootCmd := &RootCommand{Output: test.output, Stdout: &stdout, Stderr: &stderr}
+
+			err := printQuietResult(rootCmd, test.id, test.msg)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expStdout, stdout.String())
+			assert.Equal(t, test.expStderr, stderr.String())
+		})
+	}
+}
+
+func TestRunOnSelectionQuiet(t *testing.T) {
+	op := func(nameOrID string) (*model.Sandbox, string, error) {
+		if nameOrID == "broken" {
+			return nil, "", fmt.Errorf("something")
+		}
+		return &model.Sandbox{ID: "ID-" + nameOrID, Name: nameOrID, Status: model.SandboxStatusRunning}, "Sandbox " + nameOrID + " started", nil
+	}
+
+	tests := map[string]struct {
+		output    string
+		sel       sandboxSelection
+		expStdout string
+		expErr    bool
+	}{
+		"A started sandbox should print only its ID.": {
+			sel:       sandboxSelection{Names: []string{"dev"}},
+			expStdout: "ID-dev\n",
+		},
+
+		"A started sandbox with a structured output should print only its ID.": {
+			output:    OutputFormatJSON,
+			sel:       sandboxSelection{Names: []string{"dev"}},
+			expStdout: "ID-dev\n",
+		},
+
+		"The sandboxes started with a pattern should print only their IDs.": {
+			output:    OutputFormatYAML,
+			sel:       sandboxSelection{Names: []string{"dev-1", "dev-2"}, Pattern: true},
+			expStdout: "ID-dev-1\nID-dev-2\n",
+		},
+
+		"The sandboxes failed with a pattern should not print their IDs.": {
+			output:    OutputFormatJSON,
+			sel:       sandboxSelection{Names: []string{"dev-1", "broken"}, Pattern: true},
+			expStdout: "ID-dev-1\n",
+			expErr:    true,
+		},
+
+		"A pattern without sandboxes should not print anything.": {
+			sel: sandboxSelection{Pattern: true},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			rootCmd := &RootCommand{Output: test.output, Stdout: &stdout, Stderr: &stderr}
+
+			err := runOnSelection(rootCmd, &test.sel, true, op)
+			if test.expErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.expStdout, stdout.String())
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/alecthomas/units"
+	"golang.org/x/term"
+
+	"github.com/slok/sbx/internal/app/capture"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/printer"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// PcapCommand captures the network traffic of a sandbox.
+type PcapCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	nameOrID string
+	file     string
+	iface    string
+	filter   string
+	duration time.Duration
+	maxSize  units.Base2Bytes
+	maxCount int
+	snapLen  int
+}
+
+// NewPcapCommand returns the pcap command.
+func NewPcapCommand(rootCmd *RootCommand, app *kingpin.Application) *PcapCommand {
+	c := &PcapCommand{rootCmd: rootCmd}
+
+	c.Cmd = app.Command("pcap", "Capture the network traffic of a running sandbox in pcap format (Wireshark, tcpdump) until Ctrl+C or a limit, needs tcpdump on the host.")
+	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+	// The -o short flag is the global output format.
+	c.Cmd.Flag("file", "Capture file path, '-' writes the pcap stream to stdout (e.g. piped to 'wireshark -k -i -').").Short('f').Default("-").StringVar(&c.file)
+	c.Cmd.Flag("interface", "Guest network interface captured (eth0, eth1...).").Short('i').Default("eth0").StringVar(&c.iface)
+	c.Cmd.Flag("filter", "BPF filter expression with the pcap-filter syntax (e.g. 'tcp port 443').").StringVar(&c.filter)
+	c.Cmd.Flag("duration", "Stop the capture after this time (0 is until Ctrl+C).").Default("0").DurationVar(&c.duration)
+	c.Cmd.Flag("max-size", "Stop the capture before the file exceeds this size (e.g. 100MB, 0 is unlimited).").Default("0").BytesVar(&c.maxSize)
+	c.Cmd.Flag("count", "Stop the capture after these packets (0 is unlimited).").Short('c').IntVar(&c.maxCount)
+	c.Cmd.Flag("snaplen", "Bytes captured of each packet (0 is the whole packet).").IntVar(&c.snapLen)
+
+	return c
+}
+
+func (c PcapCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c PcapCommand) Run(ctx context.Context) (err error) {
+	logger := c.rootCmd.Logger
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	// Get sandbox to determine which engine to use.
+	sandbox, err := repo.GetSandboxByName(ctx, c.nameOrID)
+	if err != nil {
+		// Try by ID if name lookup failed
+		sandbox, err = repo.GetSandbox(ctx, c.nameOrID)
+		if err != nil {
+			return fmt.Errorf("could not find sandbox: %w", err)
+		}
+	}
+
+	eng, err := newEngineFromConfig(sandbox.Config, repo, c.rootCmd.VMsDir, logger)
+	if err != nil {
+		return fmt.Errorf("could not create engine: %w", err)
+	}
+
+	svc, err := capture.NewService(capture.ServiceConfig{
+		Engine:     eng,
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	var out io.Writer = c.rootCmd.Stdout
+	if c.file == "-" {
+		if f, ok := c.rootCmd.Stdout.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+			return fmt.Errorf("refusing to write the pcap stream to a terminal, use --file or a pipe: %w", model.ErrNotValid)
+		}
+	} else {
+		f, err := os.Create(c.file)
+		if err != nil {
+			return fmt.Errorf("could not create capture file: %w", err)
+		}
+		defer func() {
+			if cerr := f.Close(); cerr != nil && err == nil {
+				err = fmt.Errorf("could not write capture file: %w", cerr)
+			}
+		}()
+		out = f
+	}
+
+	result, err := svc.Run(ctx, capture.Request{
+		NameOrID: sandbox.ID,
+		Output:   out,
+		Opts: model.CaptureOpts{
+			Interface:  c.iface,
+			Filter:     c.filter,
+			Duration:   c.duration,
+			MaxBytes:   int64(c.maxSize),
+			MaxPackets: c.maxCount,
+			SnapLen:    c.snapLen,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("could not capture sandbox traffic: %w", err)
+	}
+
+	msg := fmt.Sprintf("Captured %d packets (%s) of sandbox %s %s in %s", result.Packets, printer.FormatBytes(result.Bytes), sandbox.Name, result.Interface, result.Duration.Round(time.Second))
+	if result.LimitReached {
+		msg += ", the capture limit was reached"
+	}
+	// The pcap stream owns stdout.
+	if c.file == "-" {
+		if !c.rootCmd.structuredOutput("") {
+			fmt.Fprintln(c.rootCmd.Stderr, msg)
+		}
+		return nil
+	}
+	if err := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout).PrintMessage(msg); err != nil {
+		return fmt.Errorf("could not print message: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/persist"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// PersistCommand flags a sandbox to be started again by 'sbx recover' after a host
+// reboot, or removes the flag.
+type PersistCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	nameOrID string
+	disable  bool
+}
+
+// NewPersistCommand returns the persist command.
+func NewPersistCommand(rootCmd *RootCommand, app *kingpin.Application) *PersistCommand {
+	c := &PersistCommand{rootCmd: rootCmd}
+
+	c.Cmd = app.Command("persist", "Flag a sandbox as persistent, 'sbx recover' starts it again when a host reboot kills it while running.")
+	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+	c.Cmd.Flag("disable", "Remove the persistent flag, the sandbox is left stopped after a host reboot.").BoolVar(&c.disable)
+
+	return c
+}
+
+func (c PersistCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c PersistCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	svc, err := persist.NewService(persist.ServiceConfig{
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	sandbox, err := svc.Run(ctx, persist.Request{NameOrID: c.nameOrID, Persistent: !c.disable})
+	if err != nil {
+		return fmt.Errorf("could not update sandbox persistence: %w", err)
+	}
+
+	msg := fmt.Sprintf("Sandbox %s is persistent", sandbox.Name)
+	if c.disable {
+		msg = fmt.Sprintf("Sandbox %s is not persistent", sandbox.Name)
+	}
+	if err := printSandboxResult(c.rootCmd, *sandbox, msg); err != nil {
+		return fmt.Errorf("could not print message: %w", err)
+	}
+
+	return nil
+}
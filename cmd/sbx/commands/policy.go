@@ -0,0 +1,37 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// PolicyCommand is the parent command for named egress policy subcommands.
+type PolicyCommand struct {
+	Cmd *kingpin.CmdClause
+}
+
+// NewPolicyCommand returns the policy parent command.
+func NewPolicyCommand(app *kingpin.Application) *PolicyCommand {
+	c := &PolicyCommand{}
+
+	c.Cmd = app.Command("policy", "Manage named egress policies shared by the sandboxes.")
+
+	return c
+}
+
+// loadEgressPolicy loads the egress policy of a session configuration YAML file.
+func loadEgressPolicy(ctx context.Context, path string) (model.EgressPolicy, error) {
+	sessionCfg, err := loadSessionConfig(ctx, path)
+	if err != nil {
+		return model.EgressPolicy{}, err
+	}
+	if sessionCfg.Egress == nil {
+		return model.EgressPolicy{}, fmt.Errorf("session config %q has no egress policy: %w", path, model.ErrNotValid)
+	}
+
+	return *sessionCfg.Egress, nil
+}
@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/policycreate"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// PolicyCreateCommand creates a named egress policy.
+type PolicyCreateCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	name       string
+	configFile string
+}
+
+// NewPolicyCreateCommand returns the policy create command.
+func NewPolicyCreateCommand(rootCmd *RootCommand, policyCmd *PolicyCommand) *PolicyCreateCommand {
+	c := &PolicyCreateCommand{rootCmd: rootCmd}
+
+	c.Cmd = policyCmd.Cmd.Command("create", "Create a named egress policy from the egress section of a session file.")
+	c.Cmd.Arg("name", "Policy name.").Required().StringVar(&c.name)
+	c.Cmd.Flag("file", "Path to a session configuration YAML file with the egress policy.").Short('f').Required().StringVar(&c.configFile)
+
+	return c
+}
+
+func (c PolicyCreateCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c PolicyCreateCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	policy, err := loadEgressPolicy(ctx, c.configFile)
+	if err != nil {
+		return err
+	}
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	svc, err := policycreate.NewService(policycreate.ServiceConfig{
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	if _, err := svc.Run(ctx, policycreate.Request{Name: c.name, Policy: policy}); err != nil {
+		return fmt.Errorf("could not create policy: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	return p.PrintMessage(fmt.Sprintf("Created policy %s", c.name))
+}
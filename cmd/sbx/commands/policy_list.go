@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/policylist"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// PolicyListCommand lists the named egress policies.
+type PolicyListCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+}
+
+// NewPolicyListCommand returns the policy list command.
+func NewPolicyListCommand(rootCmd *RootCommand, policyCmd *PolicyCommand) *PolicyListCommand {
+	c := &PolicyListCommand{rootCmd: rootCmd}
+
+	c.Cmd = policyCmd.Cmd.Command("list", "List the named egress policies.")
+
+	return c
+}
+
+func (c PolicyListCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c PolicyListCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	svc, err := policylist.NewService(policylist.ServiceConfig{
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	policies, err := svc.Run(ctx)
+	if err != nil {
+		return fmt.Errorf("could not list policies: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	if err := p.PrintPolicyList(policies); err != nil {
+		return fmt.Errorf("could not print policies: %w", err)
+	}
+
+	return nil
+}
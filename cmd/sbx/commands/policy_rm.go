@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/policyrm"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// PolicyRmCommand removes a named egress policy.
+type PolicyRmCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	name string
+}
+
+// NewPolicyRmCommand returns the policy rm command.
+func NewPolicyRmCommand(rootCmd *RootCommand, policyCmd *PolicyCommand) *PolicyRmCommand {
+	c := &PolicyRmCommand{rootCmd: rootCmd}
+
+	c.Cmd = policyCmd.Cmd.Command("rm", "Remove a named egress policy.")
+	c.Cmd.Arg("name", "Policy name.").Required().StringVar(&c.name)
+
+	return c
+}
+
+func (c PolicyRmCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c PolicyRmCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	svc, err := policyrm.NewService(policyrm.ServiceConfig{
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	if err := svc.Run(ctx, policyrm.Request{Name: c.name}); err != nil {
+		return fmt.Errorf("could not remove policy: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	return p.PrintMessage(fmt.Sprintf("Removed policy %s", c.name))
+}
@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/policyupdate"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// PolicyUpdateCommand replaces a named egress policy.
+type PolicyUpdateCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	name       string
+	configFile string
+}
+
+// NewPolicyUpdateCommand returns the policy update command.
+func NewPolicyUpdateCommand(rootCmd *RootCommand, policyCmd *PolicyCommand) *PolicyUpdateCommand {
+	c := &PolicyUpdateCommand{rootCmd: rootCmd}
+
+	c.Cmd = policyCmd.Cmd.Command("update", "Replace a named egress policy with the egress section of a session file, the sandboxes get it on their next start.")
+	c.Cmd.Arg("name", "Policy name.").Required().StringVar(&c.name)
+	c.Cmd.Flag("file", "Path to a session configuration YAML file with the egress policy.").Short('f').Required().StringVar(&c.configFile)
+
+	return c
+}
+
+func (c PolicyUpdateCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c PolicyUpdateCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	policy, err := loadEgressPolicy(ctx, c.configFile)
+	if err != nil {
+		return err
+	}
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	svc, err := policyupdate.NewService(policyupdate.ServiceConfig{
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	if _, err := svc.Run(ctx, policyupdate.Request{Name: c.name, Policy: policy}); err != nil {
+		return fmt.Errorf("could not update policy: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	return p.PrintMessage(fmt.Sprintf("Updated policy %s", c.name))
+}
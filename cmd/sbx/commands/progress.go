@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+const (
+	progressRefresh  = 100 * time.Millisecond
+	progressBarWidth = 20
+)
+
+var progressSpinner = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// progressRenderer renders the progress of the long operations on the terminal: a
+// spinner with the current step (and a bar when its completion is known) and a
+// line for each finished step.
+type progressRenderer struct {
+	w io.Writer
+
+	mu      sync.Mutex
+	current *model.ProgressEvent
+	frame   int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newProgress returns the progress func of a long operation and the func to call
+// when it ends. Without a terminal on stderr, or with structured output, there is
+// no progress (nil func), the logs are enough for scripts.
+func newProgress(rootCmd *RootCommand) (model.ProgressFunc, func(err error)) {
+	f, ok := rootCmd.Stderr.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) || rootCmd.structuredOutput("") {
+		return nil, func(error) {}
+	}
+
+	r := &progressRenderer{
+		w:    f,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go r.run()
+
+	return r.report, r.finish
+}
+
+func (r *progressRenderer) run() {
+	defer close(r.done)
+
+	t := time.NewTicker(progressRefresh)
+	defer t.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-t.C:
+			r.mu.Lock()
+			r.frame++
+			r.draw()
+			r.mu.Unlock()
+		}
+	}
+}
+
+func (r *progressRenderer) report(ev model.ProgressEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.current != nil && r.current.Step != ev.Step {
+		r.endStep("✓")
+	}
+	r.current = &ev
+	r.draw()
+}
+
+// finish stops the spinner and marks the last step as finished or failed.
+func (r *progressRenderer) finish(err error) {
+	close(r.stop)
+	<-r.done
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.current == nil {
+		return
+	}
+	mark := "✓"
+	if err != nil {
+		mark = "✗"
+	}
+	r.endStep(mark)
+	r.current = nil
+}
+
+// draw must be called with the lock held.
+func (r *progressRenderer) draw() {
+	if r.current == nil {
+		return
+	}
+
+	line := fmt.Sprintf("%s %s", progressSpinner[r.frame%len(progressSpinner)], r.current.Message)
+	if p := r.current.Percent; p >= 0 {
+		filled := min(int(p/100*progressBarWidth), progressBarWidth)
+		line += fmt.Sprintf(" [%s%s] %3.0f%%", strings.Repeat("=", filled), strings.Repeat(" ", progressBarWidth-filled), p)
+	}
+	fmt.Fprintf(r.w, "\r\033[K%s", line)
+}
+
+// endStep must be called with the lock held.
+func (r *progressRenderer) endStep(mark string) {
+	fmt.Fprintf(r.w, "\r\033[K%s %s\n", mark, r.current.Message)
+}
@@ -3,24 +3,43 @@ package commands
 import (
 	"context"
 	"fmt"
+	"net"
+	"strings"
+	"time"
 
 	"github.com/alecthomas/kingpin/v2"
 
+	"github.com/slok/sbx/internal/events"
+	"github.com/slok/sbx/internal/model"
 	"github.com/slok/sbx/internal/proxy"
 )
 
+// eventsCloseTimeout is the maximum time to send the pending events on exit.
+const eventsCloseTimeout = 10 * time.Second
+
 // ProxyCommand runs a standalone network proxy with domain-based rules.
 type ProxyCommand struct {
 	Cmd     *kingpin.CmdClause
 	rootCmd *RootCommand
 
 	bindAddress   string
+	bindInterface string
 	port          int
 	tlsPort       int
 	dnsPort       int
-	dnsUpstream   string
+	dnsUpstreams  []string
 	defaultPolicy string
+	failClosed    bool
+	dnsEventsFile string
+	sessionsFile  string
 	rules         []string
+	systemSvcs    []string
+	setsTable     string
+	icmpSet       string
+	ntpSet        string
+	sandboxID     string
+	sandboxName   string
+	eventSinks    string
 }
 
 // NewProxyCommand returns the proxy command.
@@ -29,12 +48,23 @@ func NewProxyCommand(rootCmd *RootCommand, app *kingpin.Application) *ProxyComma
 
 	c.Cmd = app.Command("internal-vm-proxy", "Internal: run a network proxy with domain-based rules.").Hidden()
 	c.Cmd.Flag("bind-address", "IP address to bind proxy listeners to (empty for all interfaces).").Default("").StringVar(&c.bindAddress)
+	c.Cmd.Flag("bind-interface", "Host network interface the proxied connections and DNS queries go out through (empty uses the routing table).").Default("").StringVar(&c.bindInterface)
 	c.Cmd.Flag("port", "Port to listen on for HTTP/HTTPS proxy.").Default("9666").IntVar(&c.port)
 	c.Cmd.Flag("tls-port", "Port to listen on for transparent TLS proxy (0 to disable).").Default("0").IntVar(&c.tlsPort)
 	c.Cmd.Flag("dns-port", "Port to listen on for DNS proxy (0 to disable).").Default("0").IntVar(&c.dnsPort)
-	c.Cmd.Flag("dns-upstream", "Upstream DNS resolver address.").Default("8.8.8.8:53").StringVar(&c.dnsUpstream)
+	c.Cmd.Flag("dns-upstream", "Upstream DNS resolver (repeatable, tried in order): IP[:port], tls://host[:port] or https://host/path (default 8.8.8.8:53).").StringsVar(&c.dnsUpstreams)
 	c.Cmd.Flag("default-policy", "Default policy when no rule matches.").Default("allow").EnumVar(&c.defaultPolicy, "allow", "deny")
+	c.Cmd.Flag("fail-closed", "Deny the allowed traffic whose destination can't be verified (unresolvable or non public addresses).").BoolVar(&c.failClosed)
+	c.Cmd.Flag("dns-events-file", "File to record the DNS queries to (empty to disable).").Default("").StringVar(&c.dnsEventsFile)
+	c.Cmd.Flag("sessions-file", "File to record the forwarded connections to (empty to disable).").Default("").StringVar(&c.sessionsFile)
 	c.Cmd.Flag("rule", `Rule in JSON format (repeatable). E.g.: {"action":"allow","domain":"*.github.com"}`).StringsVar(&c.rules)
+	c.Cmd.Flag("allow-system-service", "System service allowed with built-in rules after the rules (repeatable): "+strings.Join(proxy.SystemServices(), ", ")+".").StringsVar(&c.systemSvcs)
+	c.Cmd.Flag("sets-table", "nftables table of the ICMP and NTP sets.").Default("").StringVar(&c.setsTable)
+	c.Cmd.Flag("icmp-set", "nftables set the resolved addresses of the ICMP rules are added to, so they can be pinged (empty to disable).").Default("").StringVar(&c.icmpSet)
+	c.Cmd.Flag("ntp-set", "nftables set the resolved addresses of the NTP system service are added to, so they can be reached on UDP 123 (empty to disable).").Default("").StringVar(&c.ntpSet)
+	c.Cmd.Flag("sandbox-id", "Sandbox ID of the emitted events.").StringVar(&c.sandboxID)
+	c.Cmd.Flag("sandbox-name", "Sandbox name of the emitted events.").StringVar(&c.sandboxName)
+	c.Cmd.Flag("event-sinks", `Event sinks of the egress denials in JSON format. E.g.: [{"type":"journald"}]`).Envar(events.SinksEnv).StringVar(&c.eventSinks)
 
 	return c
 }
@@ -53,6 +83,11 @@ func (c ProxyCommand) Run(ctx context.Context) error {
 		}
 		rules = append(rules, r)
 	}
+	systemRules, err := proxy.SystemServiceRules(c.systemSvcs)
+	if err != nil {
+		return fmt.Errorf("invalid system service: %w", err)
+	}
+	rules = append(rules, systemRules...)
 
 	// Create matcher.
 	matcher, err := proxy.NewRuleMatcher(proxy.Action(c.defaultPolicy), rules)
@@ -60,6 +95,30 @@ func (c ProxyCommand) Run(ctx context.Context) error {
 		return fmt.Errorf("could not create rule matcher: %w", err)
 	}
 
+	// Send the denials to the event sinks.
+	sinks, err := events.ParseSinkConfigs(c.eventSinks)
+	if err != nil {
+		return fmt.Errorf("invalid event sinks: %w", err)
+	}
+	var denials proxy.DenialRecorder
+	if len(sinks) > 0 {
+		emitter, err := events.NewEmitter(sinks, logger)
+		if err != nil {
+			return fmt.Errorf("could not create event emitter: %w", err)
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), eventsCloseTimeout)
+			defer cancel()
+			if err := emitter.Close(ctx); err != nil {
+				logger.Warningf("Could not send events: %v", err)
+			}
+		}()
+		denials = denialEmitter{
+			emitter: emitter,
+			sandbox: model.Sandbox{ID: c.sandboxID, Name: c.sandboxName, Namespace: c.rootCmd.Namespace},
+		}
+	}
+
 	// Build listen address helper: bind to specific IP or all interfaces.
 	listenAddr := func(port int) string {
 		if c.bindAddress != "" {
@@ -68,20 +127,22 @@ func (c ProxyCommand) Run(ctx context.Context) error {
 		return fmt.Sprintf(":%d", port)
 	}
 
-	// Log configuration.
-	logger.Infof("starting proxy on %s with default policy %q (%d rules loaded)", listenAddr(c.port), c.defaultPolicy, len(rules))
-	for i, r := range rules {
-		logger.Infof("  rule[%d]: %s %s", i, r.Action, r.Domain)
+	// The connections go out through the egress interface when set, including the
+	// lookups of the fail-closed checks.
+	var dialer *net.Dialer
+	var dialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+	var lookupIP func(ctx context.Context, network, host string) ([]net.IP, error)
+	if c.bindInterface != "" {
+		dialer = proxy.InterfaceDialer(c.bindInterface, 10*time.Second)
+		dialContext = dialer.DialContext
+		lookupIP = (&net.Resolver{PreferGo: true, Dial: dialer.DialContext}).LookupIP
+		logger.Infof("proxied connections go out through interface %s", c.bindInterface)
 	}
 
-	// Create HTTP proxy.
-	httpProxy, err := proxy.NewProxy(proxy.ProxyConfig{
-		ListenAddr: listenAddr(c.port),
-		Matcher:    matcher,
-		Logger:     logger,
-	})
-	if err != nil {
-		return fmt.Errorf("could not create HTTP proxy: %w", err)
+	// Log configuration.
+	logger.Infof("starting proxy on %s with default policy %q (%d rules loaded, fail-closed: %t, system services: %v)", listenAddr(c.port), c.defaultPolicy, len(rules), c.failClosed, c.systemSvcs)
+	for i, r := range rules {
+		logger.Infof("  rule[%d]: %s %s (icmp: %t)", i, r.Action, r.Domain, r.ICMP)
 	}
 
 	// Collect all proxies to run concurrently.
@@ -90,15 +151,45 @@ func (c ProxyCommand) Run(ctx context.Context) error {
 		run  func(context.Context) error
 	}
 	var proxies []runnable
+
+	// Track the forwarded connections if enabled.
+	var sessions *proxy.SessionTable
+	if c.sessionsFile != "" {
+		sessions, err = proxy.NewSessionTable(proxy.SessionTableConfig{Path: c.sessionsFile, Logger: logger})
+		if err != nil {
+			return fmt.Errorf("could not create session table: %w", err)
+		}
+		proxies = append(proxies, runnable{name: "sessions", run: sessions.Run})
+	}
+
+	// Create HTTP proxy.
+	httpProxy, err := proxy.NewProxy(proxy.ProxyConfig{
+		ListenAddr:  listenAddr(c.port),
+		Matcher:     matcher,
+		Logger:      logger,
+		DialContext: dialContext,
+		FailClosed:  c.failClosed,
+		LookupIP:    lookupIP,
+		Denials:     denials,
+		Sessions:    sessions,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create HTTP proxy: %w", err)
+	}
 	proxies = append(proxies, runnable{name: "HTTP", run: httpProxy.Run})
 
 	// Create TLS proxy if enabled.
 	if c.tlsPort > 0 {
 		logger.Infof("starting transparent TLS proxy on %s", listenAddr(c.tlsPort))
 		tlsProxy, err := proxy.NewTLSProxy(proxy.TLSProxyConfig{
-			ListenAddr: listenAddr(c.tlsPort),
-			Matcher:    matcher,
-			Logger:     logger,
+			ListenAddr:  listenAddr(c.tlsPort),
+			Matcher:     matcher,
+			Logger:      logger,
+			DialContext: dialContext,
+			FailClosed:  c.failClosed,
+			LookupIP:    lookupIP,
+			Denials:     denials,
+			Sessions:    sessions,
 		})
 		if err != nil {
 			return fmt.Errorf("could not create TLS proxy: %w", err)
@@ -108,12 +199,43 @@ func (c ProxyCommand) Run(ctx context.Context) error {
 
 	// Create DNS proxy if enabled.
 	if c.dnsPort > 0 {
-		logger.Infof("starting DNS proxy on %s with upstream %s", listenAddr(c.dnsPort), c.dnsUpstream)
+		upstreams := "default"
+		if len(c.dnsUpstreams) > 0 {
+			upstreams = strings.Join(c.dnsUpstreams, ", ")
+		}
+		logger.Infof("starting DNS proxy on %s with upstreams %s", listenAddr(c.dnsPort), upstreams)
+
+		var dnsEvents proxy.DNSEventRecorder
+		if c.dnsEventsFile != "" {
+			eventLog, err := proxy.NewDNSEventLog(proxy.DNSEventLogConfig{Path: c.dnsEventsFile, Logger: logger})
+			if err != nil {
+				return fmt.Errorf("could not create DNS event log: %w", err)
+			}
+			defer eventLog.Close()
+			dnsEvents = eventLog
+		}
+
+		var icmp, ntp proxy.AddressAllower
+		if c.icmpSet != "" {
+			logger.Infof("allowing pings to the ICMP rule addresses with nftables set %s/%s", c.setsTable, c.icmpSet)
+			icmp = proxy.NewNftablesAddressAllower(c.setsTable, c.icmpSet)
+		}
+		if c.ntpSet != "" {
+			logger.Infof("allowing NTP to the NTP service addresses with nftables set %s/%s", c.setsTable, c.ntpSet)
+			ntp = proxy.NewNftablesAddressAllower(c.setsTable, c.ntpSet)
+		}
+
 		dnsProxy, err := proxy.NewDNSProxy(proxy.DNSProxyConfig{
 			ListenAddr: listenAddr(c.dnsPort),
-			Upstream:   c.dnsUpstream,
+			Upstreams:  c.dnsUpstreams,
+			Dialer:     dialer,
 			Matcher:    matcher,
 			Logger:     logger,
+			FailClosed: c.failClosed,
+			Events:     dnsEvents,
+			Denials:    denials,
+			ICMP:       icmp,
+			NTP:        ntp,
 		})
 		if err != nil {
 			return fmt.Errorf("could not create DNS proxy: %w", err)
@@ -135,3 +257,21 @@ func (c ProxyCommand) Run(ctx context.Context) error {
 	// Wait for first completion (error or context cancel).
 	return <-errCh
 }
+
+// denialEmitter emits the proxy denials as egress denied events of a sandbox.
+type denialEmitter struct {
+	emitter *events.Emitter
+	sandbox model.Sandbox
+}
+
+func (d denialEmitter) RecordDenial(den proxy.Denial) {
+	attrs := map[string]string{
+		"protocol": den.Protocol,
+		"target":   den.Target,
+		"reason":   den.Reason,
+	}
+	if den.Domain != "" {
+		attrs["domain"] = den.Domain
+	}
+	d.emitter.Emit(model.NewSandboxEvent(model.EventTypeEgressDenied, d.sandbox, attrs))
+}
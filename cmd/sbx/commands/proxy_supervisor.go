@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/events"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/proxy"
+	"github.com/slok/sbx/internal/sandbox/firecracker"
+)
+
+// ProxySupervisorCommand runs the egress proxy of a sandbox network interface and
+// restarts it if it crashes.
+type ProxySupervisorCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	vmDir           string
+	nicID           string
+	tapDevice       string
+	gateway         string
+	vmIP            string
+	hostLocksDir    string
+	port            int
+	tlsPort         int
+	dnsPort         int
+	defaultPolicy   string
+	failClosed      bool
+	egressInterface string
+	dnsUpstreams    []string
+	rules           []string
+	systemSvcs      []string
+	sandboxID       string
+	sandboxName     string
+	eventSinks      string
+}
+
+// NewProxySupervisorCommand returns the proxy supervisor command.
+func NewProxySupervisorCommand(rootCmd *RootCommand, app *kingpin.Application) *ProxySupervisorCommand {
+	c := &ProxySupervisorCommand{rootCmd: rootCmd}
+
+	c.Cmd = app.Command("internal-vm-proxy-supervisor", "Internal: run and restart the egress proxy of a sandbox.").Hidden()
+	c.Cmd.Flag("vm-dir", "Sandbox VM directory.").Required().StringVar(&c.vmDir)
+	c.Cmd.Flag("nic-id", "Network interface of the proxy (empty for the primary one).").Default("").StringVar(&c.nicID)
+	c.Cmd.Flag("tap-device", "TAP device of the network interface.").Required().StringVar(&c.tapDevice)
+	c.Cmd.Flag("gateway", "Gateway IP of the network interface, the proxy listens on it.").Required().StringVar(&c.gateway)
+	c.Cmd.Flag("vm-ip", "VM IP of the network interface.").Required().StringVar(&c.vmIP)
+	c.Cmd.Flag("host-locks-dir", "Host resource locks directory of the data directory, taken to redirect the VM traffic.").StringVar(&c.hostLocksDir)
+	c.Cmd.Flag("port", "Port for the HTTP/HTTPS proxy.").Required().IntVar(&c.port)
+	c.Cmd.Flag("tls-port", "Port for the transparent TLS proxy.").Required().IntVar(&c.tlsPort)
+	c.Cmd.Flag("dns-port", "Port for the DNS proxy.").Required().IntVar(&c.dnsPort)
+	c.Cmd.Flag("default-policy", "Default policy when no rule matches.").Default("allow").EnumVar(&c.defaultPolicy, "allow", "deny")
+	c.Cmd.Flag("fail-closed", "Deny the allowed traffic whose destination can't be verified.").BoolVar(&c.failClosed)
+	c.Cmd.Flag("egress-interface", "Host network interface the proxy connections go out through (empty uses the routing table).").StringVar(&c.egressInterface)
+	c.Cmd.Flag("dns-upstream", "Upstream DNS resolver (repeatable, tried in order).").StringsVar(&c.dnsUpstreams)
+	c.Cmd.Flag("rule", `Rule in JSON format (repeatable). E.g.: {"action":"allow","domain":"*.github.com"}`).StringsVar(&c.rules)
+	c.Cmd.Flag("allow-system-service", "System service allowed with built-in rules after the rules (repeatable).").StringsVar(&c.systemSvcs)
+	c.Cmd.Flag("sandbox-id", "Sandbox ID of the emitted events.").StringVar(&c.sandboxID)
+	c.Cmd.Flag("sandbox-name", "Sandbox name of the emitted events.").StringVar(&c.sandboxName)
+	c.Cmd.Flag("event-sinks", `Event sinks in JSON format. E.g.: [{"type":"journald"}]`).Envar(events.SinksEnv).StringVar(&c.eventSinks)
+
+	return c
+}
+
+func (c ProxySupervisorCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c ProxySupervisorCommand) Run(ctx context.Context) error {
+	rules := make([]model.EgressRule, 0, len(c.rules))
+	for _, raw := range c.rules {
+		r, err := proxy.ParseRule(raw)
+		if err != nil {
+			return fmt.Errorf("invalid rule %q: %w", raw, err)
+		}
+		rules = append(rules, model.EgressRule{Domain: r.Domain, Action: model.EgressAction(r.Action), ICMP: r.ICMP})
+	}
+
+	systemSvcs := make([]model.EgressSystemService, 0, len(c.systemSvcs))
+	for _, s := range c.systemSvcs {
+		systemSvcs = append(systemSvcs, model.EgressSystemService(s))
+	}
+
+	sinks, err := events.ParseSinkConfigs(c.eventSinks)
+	if err != nil {
+		return fmt.Errorf("invalid event sinks: %w", err)
+	}
+
+	return firecracker.SuperviseProxy(ctx, firecracker.ProxySupervisorConfig{
+		VMDir:        c.vmDir,
+		NICID:        c.nicID,
+		TapDevice:    c.tapDevice,
+		Gateway:      c.gateway,
+		VMIP:         c.vmIP,
+		HostLocksDir: c.hostLocksDir,
+		Egress: model.EgressPolicy{
+			Default:             model.EgressAction(c.defaultPolicy),
+			Rules:               rules,
+			FailClosed:          c.failClosed,
+			DNSUpstreams:        c.dnsUpstreams,
+			AllowSystemServices: systemSvcs,
+		},
+		EgressInterface: c.egressInterface,
+		Ports: firecracker.ProxyPorts{
+			HTTPPort: c.port,
+			TLSPort:  c.tlsPort,
+			DNSPort:  c.dnsPort,
+		},
+		EventSinks:  sinks,
+		SandboxID:   c.sandboxID,
+		SandboxName: c.sandboxName,
+		Namespace:   c.rootCmd.Namespace,
+		Logger:      c.rootCmd.Logger,
+	})
+}
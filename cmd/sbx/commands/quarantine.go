@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/quarantine"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// QuarantineCommand isolates a sandbox without stopping it, or lifts its quarantine.
+type QuarantineCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	nameOrID string
+	release  bool
+}
+
+// NewQuarantineCommand returns the quarantine command.
+func NewQuarantineCommand(rootCmd *RootCommand, app *kingpin.Application) *QuarantineCommand {
+	c := &QuarantineCommand{rootCmd: rootCmd}
+
+	c.Cmd = app.Command("quarantine", "Cut all the network traffic of a sandbox and revoke its port forwards without stopping it, so it can be investigated. Commands and copies keep working.")
+	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+	c.Cmd.Flag("release", "Lift the quarantine, restoring the sandbox network.").BoolVar(&c.release)
+
+	return c
+}
+
+func (c QuarantineCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c QuarantineCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	// Get sandbox to determine which engine to use.
+	sandbox, err := repo.GetSandboxByName(ctx, c.nameOrID)
+	if err != nil {
+		// Try by ID if name lookup failed
+		sandbox, err = repo.GetSandbox(ctx, c.nameOrID)
+		if err != nil {
+			return fmt.Errorf("could not find sandbox: %w", err)
+		}
+	}
+
+	eng, err := newEngineFromConfig(sandbox.Config, repo, c.rootCmd.VMsDir, logger)
+	if err != nil {
+		return fmt.Errorf("could not create engine: %w", err)
+	}
+
+	svc, err := quarantine.NewService(quarantine.ServiceConfig{
+		Engine:     eng,
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	sandbox, err = svc.Run(ctx, quarantine.Request{NameOrID: c.nameOrID, Release: c.release})
+	if err != nil {
+		return fmt.Errorf("could not update sandbox quarantine: %w", err)
+	}
+
+	msg := fmt.Sprintf("Sandbox %s quarantined", sandbox.Name)
+	if c.release {
+		msg = fmt.Sprintf("Sandbox %s released from quarantine", sandbox.Name)
+	}
+	if err := printSandboxResult(c.rootCmd, *sandbox, msg); err != nil {
+		return fmt.Errorf("could not print message: %w", err)
+	}
+
+	return nil
+}
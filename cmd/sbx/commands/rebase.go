@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/alecthomas/kingpin/v2"
+	"k8s.io/client-go/util/homedir"
+
+	"github.com/slok/sbx/internal/app/rebase"
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+type RebaseCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	nameOrID          string
+	image             string
+	imagesDir         string
+	firecrackerRootFS string
+	firecrackerKernel string
+	preservePaths     []string
+}
+
+// NewRebaseCommand returns the rebase command.
+func NewRebaseCommand(rootCmd *RootCommand, app *kingpin.Application) *RebaseCommand {
+	c := &RebaseCommand{rootCmd: rootCmd}
+
+	c.Cmd = app.Command("rebase", "Move a stopped sandbox to a new base image, keeping the preserved paths of its disk.")
+	c.Cmd.Arg("name", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+	c.Cmd.Flag("image", "New pulled image version (e.g. v0.2.0).").HintAction(imageNameHints(rootCmd, &c.imagesDir)).StringVar(&c.image)
+	defaultImagesDir := filepath.Join(homedir.HomeDir(), image.DefaultImagesDir)
+	c.Cmd.Flag("images-dir", "Local directory for images (used with --image).").Default(defaultImagesDir).StringVar(&c.imagesDir)
+	c.Cmd.Flag("firecracker-root-fs", "Path to the new rootfs image.").StringVar(&c.firecrackerRootFS)
+	c.Cmd.Flag("firecracker-kernel", "Path to the new kernel image (default: keep the current kernel).").StringVar(&c.firecrackerKernel)
+	c.Cmd.Flag("preserve", "Absolute path copied from the current disk into the new one, replacing the image files (repeatable, e.g. /root).").StringsVar(&c.preservePaths)
+
+	return c
+}
+
+func (c RebaseCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c RebaseCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	if c.image == "" && c.firecrackerRootFS == "" {
+		return fmt.Errorf("--image or --firecracker-root-fs is required: %w", model.ErrNotValid)
+	}
+	if c.image != "" && (c.firecrackerRootFS != "" || c.firecrackerKernel != "") {
+		return fmt.Errorf("--image can't be used with --firecracker-root-fs or --firecracker-kernel: %w", model.ErrNotValid)
+	}
+
+	// Resolve image paths if --image is set.
+	if c.image != "" {
+		mgr, err := image.NewLocalImageManager(image.LocalImageManagerConfig{
+			ImagesDir:    c.imagesDir,
+			SnapshotsDir: c.rootCmd.SnapshotsDir,
+			Logger:       logger,
+		})
+		if err != nil {
+			return fmt.Errorf("could not create image manager: %w", err)
+		}
+
+		exists, err := mgr.Exists(ctx, c.image)
+		if err != nil {
+			return fmt.Errorf("could not check image: %w", err)
+		}
+		if !exists {
+			exists, err = fetchMissingSnapshot(ctx, c.rootCmd, c.imagesDir, c.image)
+			if err != nil {
+				return err
+			}
+		}
+		if !exists {
+			return fmt.Errorf("image %s is not installed, run 'sbx image pull %s' first", c.image, c.image)
+		}
+		if err := image.CheckHostArch(ctx, mgr, c.image); err != nil {
+			return fmt.Errorf("image %s can't be used on this host: %w", c.image, err)
+		}
+
+		c.firecrackerKernel = mgr.KernelPath(c.image)
+		c.firecrackerRootFS = mgr.RootFSPath(c.image)
+	}
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	// Get sandbox to determine which engine to use.
+	sb, err := repo.GetSandboxByName(ctx, c.nameOrID)
+	if err != nil {
+		// Try by ID if name lookup failed
+		sb, err = repo.GetSandbox(ctx, c.nameOrID)
+		if err != nil {
+			return fmt.Errorf("could not find sandbox: %w", err)
+		}
+	}
+
+	eng, err := newEngineFromConfig(sb.Config, repo, c.rootCmd.VMsDir, logger)
+	if err != nil {
+		return fmt.Errorf("could not create engine: %w", err)
+	}
+
+	svc, err := rebase.NewService(rebase.ServiceConfig{
+		Engine:     eng,
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	progress, finishProgress := newProgress(c.rootCmd)
+	rebased, err := svc.Run(ctx, rebase.Request{
+		NameOrID:      c.nameOrID,
+		Image:         c.image,
+		RootFS:        c.firecrackerRootFS,
+		KernelImage:   c.firecrackerKernel,
+		PreservePaths: c.preservePaths,
+		Progress:      progress,
+	})
+	finishProgress(err)
+	if err != nil {
+		return fmt.Errorf("could not rebase sandbox: %w", err)
+	}
+
+	base := rebased.Config.Image
+	if base == "" {
+		base = c.firecrackerRootFS
+	}
+	msg := fmt.Sprintf("Rebased sandbox %s on %s", rebased.Name, base)
+	if err := printSandboxResult(c.rootCmd, *rebased, msg); err != nil {
+		return fmt.Errorf("could not print message: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/recovery"
+	"github.com/slok/sbx/internal/capacity"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// RecoverCommand recovers the sandboxes killed while running by a host reboot.
+type RecoverCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	admission string
+	timeouts  model.StartTimeouts
+}
+
+// NewRecoverCommand returns the recover command.
+func NewRecoverCommand(rootCmd *RootCommand, app *kingpin.Application) *RecoverCommand {
+	c := &RecoverCommand{rootCmd: rootCmd}
+
+	c.Cmd = app.Command("recover", "Flag as stopped the running sandboxes whose VM is dead (e.g. after a host reboot) and start the persistent ones again. Run it when the host boots, use --namespace '*' for all the namespaces.")
+	c.Cmd.Flag("admission", "Check the host capacity (see 'sbx capacity') before starting the persistent sandboxes: off, warn or enforce (refuse the sandbox).").Default(string(model.AdmissionModeOff)).EnumVar(&c.admission, string(model.AdmissionModeOff), string(model.AdmissionModeWarn), string(model.AdmissionModeEnforce))
+	c.Cmd.Flag("boot-timeout", "Maximum time to wait for the guest to boot and accept SSH connections (default 60s).").DurationVar(&c.timeouts.Boot)
+
+	return c
+}
+
+func (c RecoverCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c RecoverCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	planner, err := newCapacityPlanner(repo, c.rootCmd.VMsDir, model.AdmissionMode(c.admission), logger)
+	if err != nil {
+		return err
+	}
+
+	sandboxes, err := repo.ListSandboxes(ctx)
+	if err != nil {
+		return fmt.Errorf("could not list sandboxes: %w", err)
+	}
+
+	// A sandbox that can't be recovered doesn't stop the others.
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	var errs []error
+	recovered := 0
+	for _, sb := range sandboxes {
+		if sb.Status != model.SandboxStatusRunning {
+			continue
+		}
+
+		result, err := c.recover(ctx, repo, planner, sb)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sandbox %s: %w", sb.Name, err))
+			continue
+		}
+
+		var msg string
+		switch result.Action {
+		case model.RecoveryActionStopped:
+			msg = fmt.Sprintf("Sandbox %s VM is dead, flagged as stopped", result.Name)
+		case model.RecoveryActionRestarted:
+			msg = fmt.Sprintf("Started persistent sandbox %s again", result.Name)
+		case model.RecoveryActionFailed:
+			msg = fmt.Sprintf("Could not start persistent sandbox %s again, left stopped: %s", result.Name, result.Error)
+			errs = append(errs, fmt.Errorf("sandbox %s: could not start it again", result.Name))
+		default:
+			continue
+		}
+		recovered++
+		if err := p.PrintMessage(msg); err != nil {
+			return fmt.Errorf("could not print message: %w", err)
+		}
+	}
+
+	if recovered == 0 && len(errs) == 0 {
+		if err := p.PrintMessage("No sandboxes to recover"); err != nil {
+			return fmt.Errorf("could not print message: %w", err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c RecoverCommand) recover(ctx context.Context, repo storage.Repository, planner *capacity.Planner, sb model.Sandbox) (*model.RecoveryResult, error) {
+	logger := c.rootCmd.Logger
+
+	eng, err := newEngineFromConfig(sb.Config, repo, c.rootCmd.VMsDir, logger)
+	if err != nil {
+		return nil, fmt.Errorf("could not create engine: %w", err)
+	}
+
+	svc, err := recovery.NewService(recovery.ServiceConfig{
+		Engine:     eng,
+		Repository: repo,
+		Admission:  planner,
+		Logger:     logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	result, err := svc.Run(ctx, recovery.Request{NameOrID: sb.ID, Timeouts: c.timeouts})
+	if err != nil {
+		return nil, fmt.Errorf("could not recover sandbox: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,177 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// remoteLocalArgs are the arguments of a command that are paths or ports of this
+// host, in remote mode they would be the ones of the remote host.
+type remoteLocalArgs struct {
+	// all is true when the command arguments are local paths or ports.
+	all bool
+	// flags are the flags (long and short forms) whose values are local paths.
+	flags []string
+	// stdio is true when the flags accept "-" (stdin or stdout), streamed by ssh.
+	stdio bool
+}
+
+// remoteLocalCommands are the commands using local paths or ports.
+var remoteLocalCommands = map[string]remoteLocalArgs{
+	"cp":              {all: true},
+	"sync":            {all: true},
+	"forward":         {all: true},
+	"expose":          {all: true},
+	"clip drop":       {all: true},
+	"clip pickup":     {all: true},
+	"image import":    {all: true},
+	"image export":    {all: true},
+	"image add":       {all: true},
+	"image from-oci":  {all: true},
+	"support-bundle":  {all: true},
+	"create":          {flags: []string{"--user-data"}},
+	"start":           {flags: []string{"--file", "-f"}},
+	"exec":            {flags: []string{"--file", "-f"}},
+	"shell":           {flags: []string{"--file", "-f"}},
+	"run":             {flags: []string{"--file", "-f"}},
+	"egress test":     {flags: []string{"--file", "-f"}},
+	"policy create":   {flags: []string{"--file", "-f"}},
+	"policy update":   {flags: []string{"--file", "-f"}},
+	"task register":   {flags: []string{"--file", "-f"}},
+	"template create": {flags: []string{"--file", "-f", "--user-data"}},
+	"image pull":      {flags: []string{"--customize-file"}},
+	"pcap":            {flags: []string{"--file", "-f"}, stdio: true},
+}
+
+// checkRemoteLocalArgs returns an error when the command uses local paths or ports,
+// they are not supported in remote mode.
+func checkRemoteLocalArgs(cmdName string, args []string) error {
+	local, ok := remoteLocalCommands[cmdName]
+	if !ok {
+		return nil
+	}
+	if local.all {
+		return fmt.Errorf("%q command uses local paths or ports and is not supported in remote mode: %w", cmdName, model.ErrNotValid)
+	}
+
+	for _, v := range flagValues(args, local.flags) {
+		if v == "" || (local.stdio && v == "-") {
+			continue
+		}
+		msg := fmt.Sprintf("%q command %s flags are local paths and are not supported in remote mode", cmdName, strings.Join(local.flags, "/"))
+		if local.stdio {
+			msg += ", use '-' to stream them over ssh"
+		}
+		return fmt.Errorf("%s: %w", msg, model.ErrNotValid)
+	}
+
+	return nil
+}
+
+// RunRemote runs the command with sbx on the remote host using the ssh binary, so
+// the sandboxes of a Linux host can be managed from machines that can't run
+// Firecracker (e.g. macOS). The ssh configuration of the user (keys, agent, jump
+// hosts) is used. The process exits with the remote exit code, like exec does. The
+// remote sbx checks the features against the capabilities of its engines.
+func RunRemote(ctx context.Context, rootCmd RootCommand, cmdName string, args []string) error {
+	if err := checkRemoteLocalArgs(cmdName, args); err != nil {
+		return err
+	}
+
+	remoteArgs := []string{shellQuote(rootCmd.RemoteBin)}
+	for _, arg := range stripRemoteFlags(args) {
+		remoteArgs = append(remoteArgs, shellQuote(arg))
+	}
+
+	var sshArgs []string
+	// Interactive commands (shell, repl, exec --tty) need a remote terminal.
+	if f, ok := rootCmd.Stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		sshArgs = append(sshArgs, "-t")
+	}
+	sshArgs = append(sshArgs, "--", rootCmd.Remote, strings.Join(remoteArgs, " "))
+
+	rootCmd.Logger.Debugf("Running on remote host: ssh %v", sshArgs)
+
+	cmd := exec.CommandContext(ctx, "ssh", sshArgs...)
+	cmd.Stdin = rootCmd.Stdin
+	cmd.Stdout = rootCmd.Stdout
+	cmd.Stderr = rootCmd.Stderr
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && ctx.Err() == nil {
+		// The remote sbx (or ssh) already printed the error.
+		os.Exit(exitErr.ExitCode())
+	}
+	if err != nil {
+		return fmt.Errorf("could not run remote command: %w", err)
+	}
+
+	return nil
+}
+
+// stripRemoteFlags removes the remote mode flags from the command line arguments,
+// the remote sbx must run the command locally. The arguments after "--" belong to
+// the sandbox command and are kept.
+func stripRemoteFlags(args []string) []string {
+	var out []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return append(out, args[i:]...)
+		}
+		switch {
+		case arg == "--remote" || arg == "--remote-bin":
+			i++ // Skip the value.
+		case strings.HasPrefix(arg, "--remote=") || strings.HasPrefix(arg, "--remote-bin="):
+		default:
+			out = append(out, arg)
+		}
+	}
+	return out
+}
+
+// flagValues returns the values of the flags (long "--name" or short "-n" forms) in
+// the command line arguments. The arguments after "--" belong to the sandbox command
+// and are ignored.
+func flagValues(args []string, flags []string) []string {
+	var values []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return values
+		}
+		for _, flag := range flags {
+			if arg == flag {
+				if i+1 < len(args) {
+					i++
+					values = append(values, args[i])
+				}
+				break
+			}
+			// The long flags are joined to their values with "=", the short ones without.
+			prefix := flag
+			if strings.HasPrefix(flag, "--") {
+				prefix += "="
+			}
+			if v, ok := strings.CutPrefix(arg, prefix); ok {
+				values = append(values, v)
+				break
+			}
+		}
+	}
+	return values
+}
+
+// shellQuote quotes the argument for the remote shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
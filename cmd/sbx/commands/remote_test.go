@@ -0,0 +1,213 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestStripRemoteFlags(t *testing.T) {
+	tests := map[string]struct {
+		args    []string
+		expArgs []string
+	}{
+		"Args without remote flags should be kept.": {
+			args:    []string{"list", "-o", "json"},
+			expArgs: []string{"list", "-o", "json"},
+		},
+
+		"The remote flags with separated values should be removed.": {
+			args:    []string{"--remote", "me@host", "status", "--remote-bin", "/opt/sbx", "dev"},
+			expArgs: []string{"status", "dev"},
+		},
+
+		"The remote flags with joined values should be removed.": {
+			args:    []string{"--remote=me@host", "--remote-bin=/opt/sbx", "status", "dev"},
+			expArgs: []string{"status", "dev"},
+		},
+
+		"The args after '--' should be kept.": {
+			args:    []string{"exec", "--remote", "me@host", "dev", "--", "tool", "--remote", "x"},
+			expArgs: []string{"exec", "dev", "--", "tool", "--remote", "x"},
+		},
+
+		"A remote flag without value should be removed.": {
+			args:    []string{"list", "--remote"},
+			expArgs: []string{"list"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expArgs, stripRemoteFlags(test.args))
+		})
+	}
+}
+
+func TestFlagValues(t *testing.T) {
+	tests := map[string]struct {
+		args      []string
+		flags     []string
+		expValues []string
+	}{
+		"Args without the flags should not return values.": {
+			args:  []string{"start", "dev", "--timeout", "1m"},
+			flags: []string{"--file", "-f"},
+		},
+
+		"The long flag with separated value should return its value.": {
+			args:      []string{"start", "dev", "--file", "session.yaml"},
+			flags:     []string{"--file", "-f"},
+			expValues: []string{"session.yaml"},
+		},
+
+		"The long flag with joined value should return its value.": {
+			args:      []string{"start", "dev", "--file=session.yaml"},
+			flags:     []string{"--file", "-f"},
+			expValues: []string{"session.yaml"},
+		},
+
+		"The short flag with separated value should return its value.": {
+			args:      []string{"exec", "-f", "a.txt", "dev", "--", "ls"},
+			flags:     []string{"--file", "-f"},
+			expValues: []string{"a.txt"},
+		},
+
+		"The short flag with joined value should return its value.": {
+			args:      []string{"exec", "-fa.txt", "dev", "--", "ls"},
+			flags:     []string{"--file", "-f"},
+			expValues: []string{"a.txt"},
+		},
+
+		"Repeated flags should return all their values in order.": {
+			args:      []string{"exec", "-f", "a.txt", "--file=b.txt", "dev", "--", "ls"},
+			flags:     []string{"--file", "-f"},
+			expValues: []string{"a.txt", "b.txt"},
+		},
+
+		"The flags after '--' should be ignored.": {
+			args:  []string{"exec", "dev", "--", "tail", "-f", "/var/log/syslog"},
+			flags: []string{"--file", "-f"},
+		},
+
+		"A flag with a longer name should not match.": {
+			args:  []string{"template", "create", "--file-mode", "x", "--user-data-x=y"},
+			flags: []string{"--file", "--user-data"},
+		},
+
+		"A flag without value should not return values.": {
+			args:  []string{"start", "dev", "--file"},
+			flags: []string{"--file", "-f"},
+		},
+
+		"The '-' value should be returned.": {
+			args:      []string{"pcap", "dev", "-f", "-"},
+			flags:     []string{"--file", "-f"},
+			expValues: []string{"-"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expValues, flagValues(test.args, test.flags))
+		})
+	}
+}
+
+func TestCheckRemoteLocalArgs(t *testing.T) {
+	tests := map[string]struct {
+		cmdName string
+		args    []string
+		expErr  bool
+	}{
+		"A command without local paths should be allowed.": {
+			cmdName: "status",
+			args:    []string{"status", "dev"},
+		},
+
+		"A command using local paths as arguments should fail.": {
+			cmdName: "cp",
+			args:    []string{"cp", "./file", "dev:/tmp/file"},
+			expErr:  true,
+		},
+
+		"A command without its local path flags should be allowed.": {
+			cmdName: "start",
+			args:    []string{"start", "dev"},
+		},
+
+		"A command with a local path flag should fail.": {
+			cmdName: "start",
+			args:    []string{"start", "dev", "-f", "session.yaml"},
+			expErr:  true,
+		},
+
+		"A command with a second local path flag should fail.": {
+			cmdName: "template create",
+			args:    []string{"template", "create", "web", "--user-data=init.sh"},
+			expErr:  true,
+		},
+
+		"A flag of a sandbox command should be allowed.": {
+			cmdName: "exec",
+			args:    []string{"exec", "dev", "--", "tail", "-f", "/var/log/syslog"},
+		},
+
+		"A stdio flag streamed over ssh should be allowed.": {
+			cmdName: "pcap",
+			args:    []string{"pcap", "dev", "--file", "-"},
+		},
+
+		"A stdio flag with a local path should fail.": {
+			cmdName: "pcap",
+			args:    []string{"pcap", "dev", "--file", "out.pcap"},
+			expErr:  true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := checkRemoteLocalArgs(test.cmdName, test.args)
+			if test.expErr {
+				assert.ErrorIs(t, err, model.ErrNotValid)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]struct {
+		s   string
+		exp string
+	}{
+		"A plain word should be quoted.": {
+			s:   "status",
+			exp: `'status'`,
+		},
+
+		"An empty string should be an empty quoted argument.": {
+			s:   "",
+			exp: `''`,
+		},
+
+		"Spaces and shell characters should be kept literally.": {
+			s:   `echo $HOME; rm -rf "x" | cat`,
+			exp: `'echo $HOME; rm -rf "x" | cat'`,
+		},
+
+		"Single quotes should be escaped.": {
+			s:   "it's",
+			exp: `'it'"'"'s'`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.exp, shellQuote(test.s))
+		})
+	}
+}
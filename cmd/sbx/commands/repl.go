@@ -0,0 +1,731 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"golang.org/x/term"
+
+	"github.com/slok/sbx/internal/app/copy"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/pkg/lib"
+)
+
+const replWatchInterval = time.Second
+
+type ReplCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+}
+
+// NewReplCommand returns the repl command.
+func NewReplCommand(rootCmd *RootCommand, app *kingpin.Application) *ReplCommand {
+	c := &ReplCommand{rootCmd: rootCmd}
+
+	c.Cmd = app.Command("repl", "Start an interactive session to manage sandboxes.")
+
+	return c
+}
+
+func (c ReplCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c ReplCommand) Run(ctx context.Context) error {
+	client, err := lib.New(ctx, lib.Config{
+		DBPath: c.rootCmd.DBPath,
+		Logger: c.rootCmd.Logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create client: %w", err)
+	}
+	defer client.Close()
+
+	s := newReplSession(ctx, client, c.rootCmd.Stdout, c.rootCmd.Stderr)
+	defer s.close()
+
+	reader := s.newLineReader(c.rootCmd.Stdin)
+	for {
+		line, err := reader.ReadLine()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				fmt.Fprintln(s.stdout)
+				return nil
+			}
+			return fmt.Errorf("could not read input: %w", err)
+		}
+
+		exit, err := s.eval(ctx, line)
+		if err != nil {
+			fmt.Fprintf(s.stderr, "Error: %s\n", err)
+		}
+		if exit {
+			return nil
+		}
+	}
+}
+
+// replHandler runs a REPL command with its already split arguments.
+type replHandler func(ctx context.Context, args []string) error
+
+type replCommandInfo struct {
+	usage string
+	help  string
+	// sandboxArg marks commands whose first argument is a sandbox name (used for completion).
+	sandboxArg bool
+	handler    replHandler
+}
+
+// replSession holds the state of an interactive session: the SDK client, the
+// selected sandbox and the background forwards and watchers.
+type replSession struct {
+	ctx      context.Context
+	client   *lib.Client
+	stdout   io.Writer
+	stderr   io.Writer
+	commands map[string]replCommandInfo
+
+	mu        sync.Mutex
+	current   string
+	forwards  map[string]*replTask
+	watchers  map[string]*replTask
+	wg        sync.WaitGroup
+	notify    io.Writer
+	setPrompt func(string)
+}
+
+// replTask is a background operation of the session (forward or watcher).
+type replTask struct {
+	cancel context.CancelFunc
+}
+
+func newReplSession(ctx context.Context, client *lib.Client, stdout, stderr io.Writer) *replSession {
+	s := &replSession{
+		ctx:       ctx,
+		client:    client,
+		stdout:    stdout,
+		stderr:    stderr,
+		notify:    stdout,
+		forwards:  map[string]*replTask{},
+		watchers:  map[string]*replTask{},
+		setPrompt: func(string) {},
+	}
+
+	s.commands = map[string]replCommandInfo{
+		"help":     {usage: "help", help: "Show this help.", handler: s.cmdHelp},
+		"list":     {usage: "list", help: "List sandboxes.", handler: s.cmdList},
+		"status":   {usage: "status [NAME]", help: "Show sandbox status.", sandboxArg: true, handler: s.cmdStatus},
+		"start":    {usage: "start [NAME]", help: "Start a sandbox.", sandboxArg: true, handler: s.cmdStart},
+		"stop":     {usage: "stop [NAME]", help: "Stop a sandbox.", sandboxArg: true, handler: s.cmdStop},
+		"use":      {usage: "use NAME", help: "Select the sandbox used by commands without NAME and by '!'.", sandboxArg: true, handler: s.cmdUse},
+		"exec":     {usage: "exec NAME CMD...", help: "Execute a command in a sandbox.", sandboxArg: true, handler: s.cmdExec},
+		"cp":       {usage: "cp SRC DST", help: "Copy files (use NAME:/path for the sandbox side).", handler: s.cmdCp},
+		"forward":  {usage: "forward NAME [PORTS...]", help: "Forward ports in the background, without ports stops the forward.", sandboxArg: true, handler: s.cmdForward},
+		"forwards": {usage: "forwards", help: "List active forwards.", handler: s.cmdForwards},
+		"watch":    {usage: "watch NAME", help: "Toggle printing status changes of a sandbox in the background.", sandboxArg: true, handler: s.cmdWatch},
+	}
+
+	return s
+}
+
+// lineReader reads REPL input lines.
+type lineReader interface {
+	ReadLine() (string, error)
+}
+
+// newLineReader returns a terminal line editor with history and completion when
+// stdin is a terminal, otherwise a plain line scanner (for piped scripts).
+func (s *replSession) newLineReader(stdin io.Reader) lineReader {
+	f, ok := stdin.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return scannerLineReader{scanner: bufio.NewScanner(stdin)}
+	}
+
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{f, s.stdout}, replPrompt(""))
+	t.AutoCompleteCallback = s.complete
+
+	s.mu.Lock()
+	s.notify = t
+	s.setPrompt = t.SetPrompt
+	s.mu.Unlock()
+
+	return terminalLineReader{fd: int(f.Fd()), t: t}
+}
+
+type scannerLineReader struct {
+	scanner *bufio.Scanner
+}
+
+func (r scannerLineReader) ReadLine() (string, error) {
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+type terminalLineReader struct {
+	fd int
+	t  *term.Terminal
+}
+
+// ReadLine sets the terminal in raw mode only while reading, so command output
+// is written with the regular terminal settings.
+func (r terminalLineReader) ReadLine() (string, error) {
+	state, err := term.MakeRaw(r.fd)
+	if err != nil {
+		return "", fmt.Errorf("could not set terminal raw mode: %w", err)
+	}
+	defer func() { _ = term.Restore(r.fd, state) }()
+
+	return r.t.ReadLine()
+}
+
+func replPrompt(current string) string {
+	if current == "" {
+		return "sbx> "
+	}
+	return fmt.Sprintf("sbx(%s)> ", current)
+}
+
+// replInput is a parsed REPL input line.
+type replInput struct {
+	// inlineExec is set for the '!' lines, the args are the command executed in the
+	// selected sandbox.
+	inlineExec bool
+	// exit is set for the exit and quit commands.
+	exit bool
+	args []string
+}
+
+// parseReplLine parses an input line, nil for empty and comment lines.
+func parseReplLine(line string) (*replInput, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil, nil
+	}
+
+	// Inline exec on the selected sandbox.
+	if strings.HasPrefix(line, "!") {
+		args, err := splitReplArgs(strings.TrimPrefix(line, "!"))
+		if err != nil {
+			return nil, err
+		}
+		return &replInput{inlineExec: true, args: args}, nil
+	}
+
+	args, err := splitReplArgs(line)
+	if err != nil {
+		return nil, err
+	}
+
+	switch args[0] {
+	case "exit", "quit":
+		return &replInput{exit: true}, nil
+	}
+	return &replInput{args: args}, nil
+}
+
+// eval runs a single input line. It returns true when the session should end.
+func (s *replSession) eval(ctx context.Context, line string) (bool, error) {
+	in, err := parseReplLine(line)
+	if err != nil || in == nil {
+		return false, err
+	}
+
+	switch {
+	case in.exit:
+		return true, nil
+	case in.inlineExec:
+		name, err := s.sandboxOrCurrent(nil)
+		if err != nil {
+			return false, err
+		}
+		return false, s.exec(ctx, name, in.args)
+	}
+
+	cmd, ok := s.commands[in.args[0]]
+	if !ok {
+		return false, fmt.Errorf("unknown command %q (use 'help')", in.args[0])
+	}
+
+	return false, cmd.handler(ctx, in.args[1:])
+}
+
+// close stops all background forwards and watchers and waits for them.
+func (s *replSession) close() {
+	s.mu.Lock()
+	for _, t := range s.forwards {
+		t.cancel()
+	}
+	for _, t := range s.watchers {
+		t.cancel()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *replSession) printNotice(format string, args ...any) {
+	s.mu.Lock()
+	w := s.notify
+	s.mu.Unlock()
+
+	fmt.Fprintf(w, format+"\n", args...)
+}
+
+// sandboxOrCurrent returns the sandbox name from the args or the selected one.
+func (s *replSession) sandboxOrCurrent(args []string) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current == "" {
+		return "", fmt.Errorf("no sandbox selected, pass a name or select one with 'use NAME'")
+	}
+	return s.current, nil
+}
+
+func (s *replSession) cmdHelp(_ context.Context, _ []string) error {
+	names := make([]string, 0, len(s.commands))
+	for name := range s.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(s.stdout, 0, 0, 3, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\t%s\n", s.commands[name].usage, s.commands[name].help)
+	}
+	fmt.Fprintf(w, "  %s\t%s\n", "! CMD...", "Execute a command in the selected sandbox.")
+	fmt.Fprintf(w, "  %s\t%s\n", "exit", "Exit the session.")
+	return w.Flush()
+}
+
+func (s *replSession) cmdList(ctx context.Context, _ []string) error {
+	sandboxes, err := s.client.ListSandboxes(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(s.stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSTATUS\tID")
+	for _, sb := range sandboxes {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", sb.Name, sb.Status, sb.ID)
+	}
+	return w.Flush()
+}
+
+func (s *replSession) cmdStatus(ctx context.Context, args []string) error {
+	name, err := s.sandboxOrCurrent(args)
+	if err != nil {
+		return err
+	}
+
+	sb, err := s.client.GetSandbox(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(s.stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", sb.Name)
+	fmt.Fprintf(w, "ID:\t%s\n", sb.ID)
+	fmt.Fprintf(w, "Status:\t%s\n", sb.Status)
+	fmt.Fprintf(w, "Resources:\t%g vCPU, %d MB, %d GB\n", sb.Config.Resources.VCPUs, sb.Config.Resources.MemoryMB, sb.Config.Resources.DiskGB)
+	fmt.Fprintf(w, "Created:\t%s\n", sb.CreatedAt.Format(time.RFC3339))
+	if sb.StartedAt != nil {
+		fmt.Fprintf(w, "Started:\t%s\n", sb.StartedAt.Format(time.RFC3339))
+	}
+	return w.Flush()
+}
+
+func (s *replSession) cmdStart(ctx context.Context, args []string) error {
+	name, err := s.sandboxOrCurrent(args)
+	if err != nil {
+		return err
+	}
+
+	sb, err := s.client.StartSandbox(ctx, name, nil)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.stdout, "Started %s\n", sb.Name)
+	return nil
+}
+
+func (s *replSession) cmdStop(ctx context.Context, args []string) error {
+	name, err := s.sandboxOrCurrent(args)
+	if err != nil {
+		return err
+	}
+
+	sb, err := s.client.StopSandbox(ctx, name, nil)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.stdout, "Stopped %s\n", sb.Name)
+	return nil
+}
+
+func (s *replSession) cmdUse(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: use NAME")
+	}
+
+	sb, err := s.client.GetSandbox(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.current = sb.Name
+	setPrompt := s.setPrompt
+	s.mu.Unlock()
+
+	setPrompt(replPrompt(sb.Name))
+	return nil
+}
+
+func (s *replSession) cmdExec(ctx context.Context, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: exec NAME CMD...")
+	}
+	return s.exec(ctx, args[0], args[1:])
+}
+
+func (s *replSession) exec(ctx context.Context, name string, command []string) error {
+	res, err := s.client.Exec(ctx, name, command, &lib.ExecOpts{
+		Stdout: s.stdout,
+		Stderr: s.stderr,
+		Caller: "repl",
+	})
+	if err != nil {
+		return err
+	}
+	if res.ExitCode != 0 {
+		fmt.Fprintf(s.stderr, "exit code %d\n", res.ExitCode)
+	}
+	return nil
+}
+
+func (s *replSession) cmdCp(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: cp SRC DST")
+	}
+
+	parsed, err := copy.ParseCopyArgs(args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	if parsed.ToSandbox {
+		err = s.client.CopyTo(ctx, parsed.SandboxRef, parsed.LocalPath, parsed.RemotePath, nil)
+	} else {
+		err = s.client.CopyFrom(ctx, parsed.SandboxRef, parsed.RemotePath, parsed.LocalPath, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(s.stdout, "Copied %s -> %s\n", args[0], args[1])
+	return nil
+}
+
+func (s *replSession) cmdForward(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: forward NAME [PORTS...]")
+	}
+	name := args[0]
+
+	var namedPorts map[string]int
+	if len(args) > 1 {
+		sb, err := s.client.GetSandbox(ctx, name)
+		if err != nil {
+			return err
+		}
+		namedPorts = sb.Config.Ports
+	}
+
+	ports := make([]lib.PortMapping, 0, len(args)-1)
+	for _, p := range args[1:] {
+		pm, err := model.ParseNamedPortMapping(p, namedPorts)
+		if err != nil {
+			return fmt.Errorf("invalid port mapping %q: %w", p, err)
+		}
+		ports = append(ports, lib.PortMapping{LocalPort: pm.LocalPort, RemotePort: pm.RemotePort})
+	}
+
+	// Any active forward for the sandbox is stopped, either to toggle it off or to replace it.
+	s.mu.Lock()
+	active, ok := s.forwards[name]
+	delete(s.forwards, name)
+	s.mu.Unlock()
+	if ok {
+		active.cancel()
+	}
+
+	if len(ports) == 0 {
+		if !ok {
+			return fmt.Errorf("no active forward for %s", name)
+		}
+		return nil
+	}
+
+	// Forwards outlive the command that started them, so they use the session context.
+	fwdCtx, fwdCancel := context.WithCancel(s.ctx)
+	task := &replTask{cancel: fwdCancel}
+	s.mu.Lock()
+	s.forwards[name] = task
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer fwdCancel()
+
+		err := s.client.ForwardWithInfo(fwdCtx, name, ports, func(info lib.ForwardInfo) {
+			for _, p := range info.Ports {
+				s.printNotice("Forwarding localhost:%d -> %s:%d", p.LocalPort, name, p.RemotePort)
+			}
+		})
+
+		s.mu.Lock()
+		if s.forwards[name] == task {
+			delete(s.forwards, name)
+		}
+		s.mu.Unlock()
+
+		if err != nil && !errors.Is(err, context.Canceled) {
+			s.printNotice("[forward] %s: stopped: %s", name, err)
+			return
+		}
+		s.printNotice("[forward] %s: stopped", name)
+	}()
+
+	return nil
+}
+
+func (s *replSession) cmdForwards(_ context.Context, _ []string) error {
+	s.mu.Lock()
+	names := make([]string, 0, len(s.forwards))
+	for name := range s.forwards {
+		names = append(names, name)
+	}
+	s.mu.Unlock()
+
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintln(s.stdout, name)
+	}
+	return nil
+}
+
+func (s *replSession) cmdWatch(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: watch NAME")
+	}
+	name := args[0]
+
+	s.mu.Lock()
+	active, ok := s.watchers[name]
+	delete(s.watchers, name)
+	s.mu.Unlock()
+
+	if ok {
+		active.cancel()
+		fmt.Fprintf(s.stdout, "Stopped watching %s\n", name)
+		return nil
+	}
+
+	sb, err := s.client.GetSandbox(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	watchCtx, watchCancel := context.WithCancel(s.ctx)
+	s.mu.Lock()
+	s.watchers[name] = &replTask{cancel: watchCancel}
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer watchCancel()
+
+		last := sb.Status
+		ticker := time.NewTicker(replWatchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-watchCtx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			current, err := s.client.GetSandbox(watchCtx, name)
+			if err != nil {
+				if watchCtx.Err() == nil {
+					s.printNotice("[watch] %s: %s", name, err)
+				}
+				continue
+			}
+			if current.Status != last {
+				s.printNotice("[watch] %s: %s -> %s", name, last, current.Status)
+				last = current.Status
+			}
+		}
+	}()
+
+	fmt.Fprintf(s.stdout, "Watching %s (status: %s)\n", name, sb.Status)
+	return nil
+}
+
+// complete is the terminal autocomplete callback, see completeReplLine.
+func (s *replSession) complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+
+	return completeReplLine(line, pos, s.commands, func() ([]string, error) {
+		sandboxes, err := s.client.ListSandboxes(s.ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(sandboxes))
+		for _, sb := range sandboxes {
+			names = append(names, sb.Name)
+		}
+		return names, nil
+	})
+}
+
+// completeReplLine completes the word before the cursor position of the line: the
+// command names on the first word and the sandbox names (only listed when needed)
+// on the first argument of the sandbox commands. It returns the new line and
+// cursor position, false when there is nothing to complete.
+func completeReplLine(line string, pos int, commands map[string]replCommandInfo, sandboxNames func() ([]string, error)) (string, int, bool) {
+	prefix := line[:pos]
+	words := strings.Fields(prefix)
+	if strings.HasSuffix(prefix, " ") || len(words) == 0 {
+		words = append(words, "")
+	}
+	word := words[len(words)-1]
+
+	var candidates []string
+	switch len(words) {
+	case 1:
+		for name := range commands {
+			candidates = append(candidates, name)
+		}
+	case 2:
+		cmd, ok := commands[words[0]]
+		if !ok || !cmd.sandboxArg {
+			return "", 0, false
+		}
+		names, err := sandboxNames()
+		if err != nil {
+			return "", 0, false
+		}
+		candidates = names
+	default:
+		return "", 0, false
+	}
+
+	completed, ok := completeWord(word, candidates)
+	if !ok {
+		return "", 0, false
+	}
+
+	newPrefix := prefix[:len(prefix)-len(word)] + completed
+	return newPrefix + line[pos:], len(newPrefix), true
+}
+
+// completeWord completes word with the candidates that share its prefix. A single
+// match is completed with a trailing space, multiple matches with their longest
+// common prefix.
+func completeWord(word string, candidates []string) (string, bool) {
+	var matches []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, word) {
+			matches = append(matches, c)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", false
+	case 1:
+		return matches[0] + " ", true
+	}
+
+	common := matches[0]
+	for _, m := range matches[1:] {
+		for !strings.HasPrefix(m, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	if common == word {
+		return "", false
+	}
+	return common, true
+}
+
+// splitReplArgs splits a line into arguments, supporting single and double quotes.
+func splitReplArgs(line string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inArg   bool
+		quote   rune
+	)
+
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+				continue
+			}
+			current.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", line)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("missing command")
+	}
+
+	return args, nil
+}
@@ -0,0 +1,249 @@
+package commands
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitReplArgs(t *testing.T) {
+	tests := map[string]struct {
+		line    string
+		expArgs []string
+		expErr  bool
+	}{
+		"Words should be split by spaces and tabs.": {
+			line:    "exec  dev\tuname -a",
+			expArgs: []string{"exec", "dev", "uname", "-a"},
+		},
+
+		"Quoted arguments should keep their spaces.": {
+			line:    `exec dev sh -c "echo hello world"`,
+			expArgs: []string{"exec", "dev", "sh", "-c", "echo hello world"},
+		},
+
+		"Single quotes should keep the double quotes.": {
+			line:    `exec dev echo '"quoted"'`,
+			expArgs: []string{"exec", "dev", "echo", `"quoted"`},
+		},
+
+		"Quotes in the middle of a word should be joined to it.": {
+			line:    `cp dev:"/tmp/my file" ./out`,
+			expArgs: []string{"cp", "dev:/tmp/my file", "./out"},
+		},
+
+		"Empty quotes should be an empty argument.": {
+			line:    `exec dev echo ""`,
+			expArgs: []string{"exec", "dev", "echo", ""},
+		},
+
+		"An unterminated quote should fail.": {
+			line:   `exec dev echo "hello`,
+			expErr: true,
+		},
+
+		"An empty line should fail.": {
+			line:   "   ",
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args, err := splitReplArgs(test.line)
+			if test.expErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expArgs, args)
+		})
+	}
+}
+
+func TestParseReplLine(t *testing.T) {
+	tests := map[string]struct {
+		line     string
+		expInput *replInput
+		expErr   bool
+	}{
+		"An empty line should be ignored.": {
+			line: "  ",
+		},
+
+		"A comment should be ignored.": {
+			line: "# start the sandbox",
+		},
+
+		"A command should return its args.": {
+			line:     "  status dev ",
+			expInput: &replInput{args: []string{"status", "dev"}},
+		},
+
+		"Exit should end the session.": {
+			line:     "exit",
+			expInput: &replInput{exit: true},
+		},
+
+		"Quit should end the session.": {
+			line:     "quit now",
+			expInput: &replInput{exit: true},
+		},
+
+		"A '!' line should be an inline exec.": {
+			line:     `!ls -la "/tmp/my dir"`,
+			expInput: &replInput{inlineExec: true, args: []string{"ls", "-la", "/tmp/my dir"}},
+		},
+
+		"A '!' line without command should fail.": {
+			line:   "!",
+			expErr: true,
+		},
+
+		"An unterminated quote should fail.": {
+			line:   "exec dev 'echo",
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			in, err := parseReplLine(test.line)
+			if test.expErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expInput, in)
+		})
+	}
+}
+
+func TestCompleteWord(t *testing.T) {
+	tests := map[string]struct {
+		word          string
+		candidates    []string
+		expCompletion string
+		expOK         bool
+	}{
+		"A single match should be completed with a trailing space.": {
+			word:          "sta",
+			candidates:    []string{"status", "stop", "list"},
+			expCompletion: "status ",
+			expOK:         true,
+		},
+
+		"Multiple matches should be completed with their common prefix.": {
+			word:          "s",
+			candidates:    []string{"status", "start", "list"},
+			expCompletion: "sta",
+			expOK:         true,
+		},
+
+		"Multiple matches without a longer common prefix should not complete.": {
+			word:       "st",
+			candidates: []string{"status", "stop"},
+		},
+
+		"No matches should not complete.": {
+			word:       "x",
+			candidates: []string{"status", "stop"},
+		},
+
+		"An empty word should complete the common prefix of all the candidates.": {
+			word:          "",
+			candidates:    []string{"forward", "forwards"},
+			expCompletion: "forward",
+			expOK:         true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			completion, ok := completeWord(test.word, test.candidates)
+			assert.Equal(t, test.expOK, ok)
+			assert.Equal(t, test.expCompletion, completion)
+		})
+	}
+}
+
+func TestCompleteReplLine(t *testing.T) {
+	commands := map[string]replCommandInfo{
+		"list":   {},
+		"status": {sandboxArg: true},
+		"stop":   {sandboxArg: true},
+		"cp":     {},
+	}
+
+	tests := map[string]struct {
+		line         string
+		pos          int
+		sandboxes    []string
+		sandboxesErr error
+		expLine      string
+		expPos       int
+		expOK        bool
+	}{
+		"The first word should complete the command names.": {
+			line:    "li",
+			pos:     2,
+			expLine: "list ",
+			expPos:  5,
+			expOK:   true,
+		},
+
+		"The first argument of a sandbox command should complete the sandbox names.": {
+			line:      "status de",
+			pos:       9,
+			sandboxes: []string{"dev", "prod"},
+			expLine:   "status dev ",
+			expPos:    11,
+			expOK:     true,
+		},
+
+		"The text after the cursor should be kept.": {
+			line:      "stop pr --force",
+			pos:       7,
+			sandboxes: []string{"dev", "prod"},
+			expLine:   "stop prod  --force",
+			expPos:    10,
+			expOK:     true,
+		},
+
+		"The first argument of a command without sandbox arg should not complete.": {
+			line:      "cp de",
+			pos:       5,
+			sandboxes: []string{"dev"},
+		},
+
+		"An unknown command should not complete its arguments.": {
+			line:      "foo de",
+			pos:       6,
+			sandboxes: []string{"dev"},
+		},
+
+		"The second argument should not complete.": {
+			line:      "status dev pr",
+			pos:       13,
+			sandboxes: []string{"prod"},
+		},
+
+		"A sandbox listing error should not complete.": {
+			line:         "status de",
+			pos:          9,
+			sandboxesErr: fmt.Errorf("something"),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			line, pos, ok := completeReplLine(test.line, test.pos, commands, func() ([]string, error) {
+				return test.sandboxes, test.sandboxesErr
+			})
+			assert.Equal(t, test.expOK, ok)
+			assert.Equal(t, test.expLine, line)
+			assert.Equal(t, test.expPos, pos)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/alecthomas/kingpin/v2"
+	"k8s.io/client-go/util/homedir"
+
+	"github.com/slok/sbx/internal/app/reservations"
+	"github.com/slok/sbx/internal/conventions"
+	"github.com/slok/sbx/internal/reservation"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// ReservationsCommand shows the resources reserved by the sandboxes and syncs their
+// reservation manifests.
+type ReservationsCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+}
+
+// NewReservationsCommand returns the reservations command.
+func NewReservationsCommand(rootCmd *RootCommand, app *kingpin.Application) *ReservationsCommand {
+	c := &ReservationsCommand{rootCmd: rootCmd}
+
+	c.Cmd = app.Command("reservations", "Show the resources reserved by the sandboxes of all the namespaces and sync their reservation manifests for the external schedulers.")
+
+	return c
+}
+
+func (c ReservationsCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c ReservationsCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	svc, err := reservations.NewService(reservations.ServiceConfig{
+		Repository: repo,
+		Store:      reservation.NewStore(defaultReservationsDir()),
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	rs, err := svc.Run(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get reservations: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	if err := p.PrintReservations(rs); err != nil {
+		return fmt.Errorf("could not print reservations: %w", err)
+	}
+
+	return nil
+}
+
+// defaultReservationsDir returns the directory of the reservation manifests.
+func defaultReservationsDir() string {
+	return conventions.ReservationsPath(filepath.Join(homedir.HomeDir(), conventions.DefaultDataDir))
+}
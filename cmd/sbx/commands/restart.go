@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/restart"
+	"github.com/slok/sbx/internal/capacity"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+type RestartCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	nameOrID     string
+	gracePeriod  time.Duration
+	preStopHooks []string
+	admission    string
+	timeouts     model.StartTimeouts
+	yes          bool
+	quiet        bool
+}
+
+// NewRestartCommand returns the restart command.
+func NewRestartCommand(rootCmd *RootCommand, app *kingpin.Application) *RestartCommand {
+	c := &RestartCommand{rootCmd: rootCmd}
+
+	c.Cmd = app.Command("restart", "Restart a running sandbox in place with the session of its previous start (env, egress policy), or the sandboxes matching a name pattern.")
+	c.Cmd.Arg("name-or-id", sandboxSelectionHelp).Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+	c.Cmd.Flag("grace-period", "Time the sandbox has to run the pre-stop hooks and shut down before being killed (default 10s).").DurationVar(&c.gracePeriod)
+	c.Cmd.Flag("pre-stop", "Shell command run in the guest before it's shut down (e.g. flush caches, notify the agent). Can be repeated.").StringsVar(&c.preStopHooks)
+	c.Cmd.Flag("admission", "Check the host capacity (see 'sbx capacity'): off, warn or enforce (refuse the sandbox).").Default(string(model.AdmissionModeOff)).EnumVar(&c.admission, string(model.AdmissionModeOff), string(model.AdmissionModeWarn), string(model.AdmissionModeEnforce))
+	c.Cmd.Flag("boot-timeout", "Maximum time to wait for the guest to boot and accept SSH connections (default 60s).").DurationVar(&c.timeouts.Boot)
+	c.Cmd.Flag("ssh-dial-timeout", "Timeout of each guest SSH connection attempt while waiting for the boot (default 2s).").DurationVar(&c.timeouts.SSHDial)
+	c.Cmd.Flag("ssh-retries", "Maximum guest SSH connection attempts while waiting for the boot (default 0, retry until the boot timeout).").IntVar(&c.timeouts.SSHRetries)
+	c.Cmd.Flag("fs-expand-timeout", "Timeout of the guest filesystem expansion after the boot (default 0, no timeout).").DurationVar(&c.timeouts.FilesystemExpand)
+	c.Cmd.Flag("yes", "Don't ask for confirmation when a name pattern selects the sandboxes.").Short('y').BoolVar(&c.yes)
+	c.Cmd.Flag("quiet", "Only print the restarted sandbox IDs on stdout, the messages go to stderr.").Short('q').BoolVar(&c.quiet)
+
+	return c
+}
+
+func (c RestartCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c RestartCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	// Initialize storage (SQLite).
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	planner, err := newCapacityPlanner(repo, c.rootCmd.VMsDir, model.AdmissionMode(c.admission), logger)
+	if err != nil {
+		return err
+	}
+
+	sel, err := selectSandboxes(ctx, c.rootCmd, repo, c.nameOrID, "restart", c.yes)
+	if err != nil {
+		return err
+	}
+
+	return runOnSelection(c.rootCmd, sel, c.quiet, func(nameOrID string) (*model.Sandbox, string, error) {
+		sandbox, err := c.restart(ctx, repo, planner, nameOrID)
+		if err != nil {
+			return nil, "", err
+		}
+		var bootTime time.Duration
+		for _, p := range sandbox.BootPhases {
+			bootTime += p.Duration
+		}
+		return sandbox, fmt.Sprintf("Restarted sandbox: %s (booted in %s)", sandbox.Name, bootTime.Round(time.Millisecond)), nil
+	})
+}
+
+func (c RestartCommand) restart(ctx context.Context, repo storage.Repository, planner *capacity.Planner, nameOrID string) (*model.Sandbox, error) {
+	logger := c.rootCmd.Logger
+
+	// Get sandbox to determine which engine to use.
+	sandbox, err := repo.GetSandboxByName(ctx, nameOrID)
+	if err != nil {
+		// Try by ID if name lookup failed
+		sandbox, err = repo.GetSandbox(ctx, nameOrID)
+		if err != nil {
+			return nil, fmt.Errorf("could not find sandbox: %w", err)
+		}
+	}
+
+	// Initialize engine based on sandbox configuration.
+	eng, err := newEngineFromConfig(sandbox.Config, repo, c.rootCmd.VMsDir, logger)
+	if err != nil {
+		return nil, fmt.Errorf("could not create engine: %w", err)
+	}
+
+	hooksRunner, err := newHooksRunner(c.rootCmd, logger)
+	if err != nil {
+		return nil, fmt.Errorf("could not create hooks runner: %w", err)
+	}
+
+	// Create restart service.
+	svc, err := restart.NewService(restart.ServiceConfig{
+		Engine:     eng,
+		Repository: repo,
+		Admission:  planner,
+		Hooks:      hooksRunner,
+		Logger:     logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	// Execute restart.
+	progress, finishProgress := newProgress(c.rootCmd)
+	sandbox, err = svc.Run(ctx, restart.Request{
+		NameOrID:     nameOrID,
+		GracePeriod:  c.gracePeriod,
+		PreStopHooks: c.preStopHooks,
+		Timeouts:     c.timeouts,
+		Progress:     progress,
+	})
+	finishProgress(err)
+	if err != nil {
+		return nil, fmt.Errorf("could not restart sandbox: %w", err)
+	}
+
+	return sandbox, nil
+}
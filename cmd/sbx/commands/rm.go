@@ -7,7 +7,8 @@ import (
 	"github.com/alecthomas/kingpin/v2"
 
 	"github.com/slok/sbx/internal/app/remove"
-	"github.com/slok/sbx/internal/printer"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
 	"github.com/slok/sbx/internal/storage/sqlite"
 )
 
@@ -17,15 +18,17 @@ type RemoveCommand struct {
 
 	nameOrID string
 	force    bool
+	yes      bool
 }
 
 // NewRemoveCommand returns the remove command.
 func NewRemoveCommand(rootCmd *RootCommand, app *kingpin.Application) *RemoveCommand {
 	c := &RemoveCommand{rootCmd: rootCmd}
 
-	c.Cmd = app.Command("rm", "Remove a sandbox.")
-	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().StringVar(&c.nameOrID)
+	c.Cmd = app.Command("rm", "Remove a sandbox, or the sandboxes matching a name pattern.")
+	c.Cmd.Arg("name-or-id", sandboxSelectionHelp).Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
 	c.Cmd.Flag("force", "Force removal of a running sandbox.").BoolVar(&c.force)
+	c.Cmd.Flag("yes", "Don't ask for confirmation when a name pattern selects the sandboxes.").Short('y').BoolVar(&c.yes)
 
 	return c
 }
@@ -37,57 +40,75 @@ func (c RemoveCommand) Run(ctx context.Context) error {
 
 	// Initialize storage (SQLite).
 	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
-		DBPath: c.rootCmd.DBPath,
-		Logger: logger,
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
 	})
 	if err != nil {
 		return fmt.Errorf("could not create repository: %w", err)
 	}
 
+	sel, err := selectSandboxes(ctx, c.rootCmd, repo, c.nameOrID, "remove", c.yes)
+	if err != nil {
+		return err
+	}
+
+	return runOnSelection(c.rootCmd, sel, false, func(nameOrID string) (*model.Sandbox, string, error) {
+		sandbox, err := c.remove(ctx, repo, nameOrID)
+		if err != nil {
+			return nil, "", err
+		}
+		msg := fmt.Sprintf("Removed sandbox: %s", sandbox.Name)
+		if c.force && sandbox.Status == "running" {
+			msg = fmt.Sprintf("Stopped and removed sandbox: %s", sandbox.Name)
+		}
+		return sandbox, msg, nil
+	})
+}
+
+func (c RemoveCommand) remove(ctx context.Context, repo storage.Repository, nameOrID string) (*model.Sandbox, error) {
+	logger := c.rootCmd.Logger
+
 	// Get sandbox to determine which engine to use.
-	sandbox, err := repo.GetSandboxByName(ctx, c.nameOrID)
+	sandbox, err := repo.GetSandboxByName(ctx, nameOrID)
 	if err != nil {
 		// Try by ID if name lookup failed
-		sandbox, err = repo.GetSandbox(ctx, c.nameOrID)
+		sandbox, err = repo.GetSandbox(ctx, nameOrID)
 		if err != nil {
-			return fmt.Errorf("could not find sandbox: %w", err)
+			return nil, fmt.Errorf("could not find sandbox: %w", err)
 		}
 	}
 
 	// Initialize engine based on sandbox configuration.
-	eng, err := newEngineFromConfig(sandbox.Config, repo, logger)
+	eng, err := newEngineFromConfig(sandbox.Config, repo, c.rootCmd.VMsDir, logger)
+	if err != nil {
+		return nil, fmt.Errorf("could not create engine: %w", err)
+	}
+
+	hooksRunner, err := newHooksRunner(c.rootCmd, logger)
 	if err != nil {
-		return fmt.Errorf("could not create engine: %w", err)
+		return nil, fmt.Errorf("could not create hooks runner: %w", err)
 	}
 
 	// Create remove service.
 	svc, err := remove.NewService(remove.ServiceConfig{
 		Engine:     eng,
 		Repository: repo,
+		Hooks:      hooksRunner,
 		Logger:     logger,
 	})
 	if err != nil {
-		return fmt.Errorf("could not create service: %w", err)
+		return nil, fmt.Errorf("could not create service: %w", err)
 	}
 
 	// Execute remove.
 	sandbox, err = svc.Run(ctx, remove.Request{
-		NameOrID: c.nameOrID,
+		NameOrID: nameOrID,
 		Force:    c.force,
 	})
 	if err != nil {
-		return fmt.Errorf("could not remove sandbox: %w", err)
-	}
-
-	// Print success message.
-	p := printer.NewTablePrinter(c.rootCmd.Stdout)
-	msg := fmt.Sprintf("Removed sandbox: %s", sandbox.Name)
-	if c.force && sandbox.Status == "running" {
-		msg = fmt.Sprintf("Stopped and removed sandbox: %s", sandbox.Name)
-	}
-	if err := p.PrintMessage(msg); err != nil {
-		return fmt.Errorf("could not print message: %w", err)
+		return nil, fmt.Errorf("could not remove sandbox: %w", err)
 	}
 
-	return nil
+	return sandbox, nil
 }
@@ -0,0 +1,131 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/exec"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/sqlite"
+	utilsenv "github.com/slok/sbx/internal/utils/env"
+)
+
+// RunCommand runs a task in a running sandbox.
+type RunCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	nameOrID   string
+	task       string
+	args       []string
+	workingDir string
+	envSpecs   []string
+	tty        bool
+	files      []string
+	timeout    time.Duration
+}
+
+// NewRunCommand returns the run command.
+func NewRunCommand(rootCmd *RootCommand, app *kingpin.Application) *RunCommand {
+	c := &RunCommand{rootCmd: rootCmd}
+
+	c.Cmd = app.Command("run", "Run a task (of the sandbox or a global one) in a running sandbox.")
+	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+	c.Cmd.Arg("task", "Task name.").Required().StringVar(&c.task)
+	c.Cmd.Arg("args", "Arguments appended to the task command (use -- before the arguments).").StringsVar(&c.args)
+	c.Cmd.Flag("workdir", "Working directory, overrides the task one.").Short('w').StringVar(&c.workingDir)
+	c.Cmd.Flag("env", "Environment variables (KEY=VALUE or KEY from current environment), override the task ones. Can be repeated.").Short('e').StringsVar(&c.envSpecs)
+	c.Cmd.Flag("tty", "Allocate a pseudo-TTY.").Short('t').BoolVar(&c.tty)
+	c.Cmd.Flag("file", "Upload local file to sandbox before running the task (into workdir). Can be repeated.").Short('f').StringsVar(&c.files)
+	c.Cmd.Flag("timeout", "Kill the task when it runs longer, overrides the task one.").DurationVar(&c.timeout)
+
+	return c
+}
+
+func (c RunCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c RunCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	cmdEnv, err := utilsenv.ParseSpecs(c.envSpecs)
+	if err != nil {
+		return fmt.Errorf("invalid --env value: %w", err)
+	}
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	sandbox, err := repo.GetSandboxByName(ctx, c.nameOrID)
+	if err != nil {
+		sandbox, err = repo.GetSandbox(ctx, c.nameOrID)
+		if err != nil {
+			return fmt.Errorf("could not find sandbox: %w", err)
+		}
+	}
+
+	eng, err := newEngineFromConfig(sandbox.Config, repo, c.rootCmd.VMsDir, logger)
+	if err != nil {
+		return fmt.Errorf("could not create engine: %w", err)
+	}
+
+	svc, err := exec.NewService(exec.ServiceConfig{
+		Engine:     eng,
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	structured := c.rootCmd.structuredOutput("")
+	if structured && c.tty {
+		return fmt.Errorf("--tty can't be used with %s output: %w", c.rootCmd.outputFormat(""), model.ErrNotValid)
+	}
+
+	opts := model.ExecOpts{
+		WorkingDir: c.workingDir,
+		Env:        cmdEnv,
+		Stdin:      os.Stdin,
+		Stdout:     os.Stdout,
+		Stderr:     os.Stderr,
+		Tty:        c.tty,
+	}
+	if structured {
+		opts.Stdout = nil
+		opts.Stderr = nil
+	}
+
+	result, err := svc.Run(ctx, exec.Request{
+		NameOrID:      c.nameOrID,
+		Task:          c.task,
+		Command:       c.args,
+		Files:         c.files,
+		Opts:          opts,
+		Timeout:       c.timeout,
+		CaptureOutput: structured,
+		Caller:        "cli",
+	})
+	if err != nil {
+		return fmt.Errorf("could not run task: %w", err)
+	}
+
+	if structured {
+		p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+		if err := p.PrintExecResult(*result); err != nil {
+			return fmt.Errorf("could not print result: %w", err)
+		}
+	}
+
+	os.Exit(result.ExitCode)
+	return nil
+}
@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/scale"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+type ScaleCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	nameOrID string
+	cpu      float64
+	mem      int
+}
+
+// NewScaleCommand returns the scale command.
+func NewScaleCommand(rootCmd *RootCommand, app *kingpin.Application) *ScaleCommand {
+	c := &ScaleCommand{rootCmd: rootCmd}
+
+	c.Cmd = app.Command("scale", "Change the VCPUs and memory of a running sandbox, up to its --max-cpu and --max-mem.")
+	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+	c.Cmd.Flag("cpu", "Number of VCPUs (default: current).").Float64Var(&c.cpu)
+	c.Cmd.Flag("mem", "Memory in MB (default: current).").IntVar(&c.mem)
+
+	return c
+}
+
+func (c ScaleCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c ScaleCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	// Initialize storage (SQLite).
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	// Get sandbox to determine which engine to use.
+	sandbox, err := repo.GetSandboxByName(ctx, c.nameOrID)
+	if err != nil {
+		// Try by ID if name lookup failed
+		sandbox, err = repo.GetSandbox(ctx, c.nameOrID)
+		if err != nil {
+			return fmt.Errorf("could not find sandbox: %w", err)
+		}
+	}
+
+	// Initialize engine based on sandbox configuration.
+	eng, err := newEngineFromConfig(sandbox.Config, repo, c.rootCmd.VMsDir, logger)
+	if err != nil {
+		return fmt.Errorf("could not create engine: %w", err)
+	}
+
+	// Create scale service.
+	svc, err := scale.NewService(scale.ServiceConfig{
+		Engine:     eng,
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	// Execute scale.
+	sandbox, err = svc.Run(ctx, scale.Request{
+		NameOrID: c.nameOrID,
+		VCPUs:    c.cpu,
+		MemoryMB: c.mem,
+	})
+	if err != nil {
+		return fmt.Errorf("could not scale sandbox: %w", err)
+	}
+
+	// Print success message.
+	res := sandbox.Config.Resources
+	msg := fmt.Sprintf("Sandbox %s scaled to %g VCPUs (max %g) and %dMB (max %dMB)", sandbox.Name, res.VCPUs, res.MaxVCPUs, res.MemoryMB, res.MaxMemoryMB)
+	if err := printSandboxResult(c.rootCmd, *sandbox, msg); err != nil {
+		return fmt.Errorf("could not print message: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/slok/sbx/internal/app/list"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// sandboxSelectionHelp is the help of the name-or-id argument of the commands
+// that accept sandbox name patterns.
+const sandboxSelectionHelp = "Sandbox name or ID, or a name pattern: a glob ('ci-*') or a regular expression prefixed with 're:' ('re:^ci-[0-9]+$')."
+
+// sandboxSelection is the result of resolving the name-or-id argument of the
+// commands that accept sandbox name patterns.
+type sandboxSelection struct {
+	// Names are the selected sandbox names (or the name or ID as it was passed).
+	Names []string
+	// Pattern is true when the names come from a pattern.
+	Pattern bool
+}
+
+// selectSandboxes resolves the name-or-id argument. An existing sandbox name or ID
+// always wins over a pattern, so sandboxes with pattern characters in their name
+// are still addressable. The sandboxes selected by a pattern need an interactive
+// confirmation unless yes is set.
+func selectSandboxes(ctx context.Context, rootCmd *RootCommand, repo storage.Repository, nameOrID, verb string, yes bool) (*sandboxSelection, error) {
+	if !model.IsSandboxNamePattern(nameOrID) {
+		return &sandboxSelection{Names: []string{nameOrID}}, nil
+	}
+	if _, err := repo.GetSandboxByName(ctx, nameOrID); err == nil {
+		return &sandboxSelection{Names: []string{nameOrID}}, nil
+	}
+
+	svc, err := list.NewService(list.ServiceConfig{
+		Repository: repo,
+		Logger:     rootCmd.Logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	sandboxes, err := svc.Run(ctx, list.Request{NamePattern: nameOrID})
+	if err != nil {
+		return nil, fmt.Errorf("could not select sandboxes: %w", err)
+	}
+	if len(sandboxes) == 0 {
+		return nil, fmt.Errorf("no sandboxes match %q: %w", nameOrID, model.ErrNotFound)
+	}
+
+	names := make([]string, 0, len(sandboxes))
+	for _, sb := range sandboxes {
+		names = append(names, sb.Name)
+	}
+
+	if !yes {
+		ok, err := confirmSelection(rootCmd, nameOrID, verb, sandboxes)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return &sandboxSelection{Pattern: true}, nil
+		}
+	}
+
+	return &sandboxSelection{Names: names, Pattern: true}, nil
+}
+
+// confirmSelection asks the user on the terminal to confirm the selected sandboxes.
+// Without a terminal there is no one to ask, so it fails instead of acting on a
+// selection nobody reviewed.
+func confirmSelection(rootCmd *RootCommand, pattern, verb string, sandboxes []model.Sandbox) (bool, error) {
+	f, ok := rootCmd.Stdin.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return false, fmt.Errorf("%q matches %d sandboxes, use --yes to %s them without a terminal confirmation: %w", pattern, len(sandboxes), verb, model.ErrNotValid)
+	}
+
+	fmt.Fprintf(rootCmd.Stderr, "Sandboxes matching %q:\n", pattern)
+	for _, sb := range sandboxes {
+		fmt.Fprintf(rootCmd.Stderr, "  %s (%s)\n", sb.Name, sb.Status)
+	}
+	fmt.Fprintf(rootCmd.Stderr, "%s %d sandboxes? [y/N]: ", strings.ToUpper(verb[:1])+verb[1:], len(sandboxes))
+
+	answer, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && answer == "" {
+		return false, fmt.Errorf("could not read the confirmation: %w", err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// runOnSelection runs the operation on each selected sandbox. With a pattern it
+// continues on errors and prints the sandboxes as a list with the structured
+// output formats, otherwise it behaves like the single sandbox commands. With
+// quiet only the IDs of the changed sandboxes are printed on stdout.
+func runOnSelection(rootCmd *RootCommand, sel *sandboxSelection, quiet bool, op func(nameOrID string) (*model.Sandbox, string, error)) error {
+	if !sel.Pattern {
+		sandbox, msg, err := op(sel.Names[0])
+		if err != nil {
+			return err
+		}
+		if quiet {
+			err = printQuietResult(rootCmd, sandbox.ID, msg)
+		} else {
+			err = printSandboxResult(rootCmd, *sandbox, msg)
+		}
+		if err != nil {
+			return fmt.Errorf("could not print message: %w", err)
+		}
+		return nil
+	}
+
+	p := newPrinter(rootCmd.outputFormat(""), rootCmd.Stdout)
+	if quiet {
+		p = newPrinter(OutputFormatTable, rootCmd.Stderr)
+	}
+	if len(sel.Names) == 0 {
+		return p.PrintMessage("Canceled, no sandbox has been changed")
+	}
+
+	var errs []error
+	sandboxes := make([]model.Sandbox, 0, len(sel.Names))
+	for _, name := range sel.Names {
+		sandbox, msg, err := op(name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		sandboxes = append(sandboxes, *sandbox)
+		switch {
+		case quiet:
+			if err := printQuietResult(rootCmd, sandbox.ID, msg); err != nil {
+				return fmt.Errorf("could not print message: %w", err)
+			}
+		case !rootCmd.structuredOutput(""):
+			if err := p.PrintMessage(msg); err != nil {
+				return fmt.Errorf("could not print message: %w", err)
+			}
+		}
+	}
+
+	if !quiet && rootCmd.structuredOutput("") {
+		if err := p.PrintList(sandboxes); err != nil {
+			return fmt.Errorf("could not print sandboxes: %w", err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%d of %d sandboxes failed: %w", len(errs), len(sel.Names), errors.Join(errs...))
+	}
+
+	return nil
+}
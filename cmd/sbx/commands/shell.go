@@ -27,7 +27,7 @@ func NewShellCommand(rootCmd *RootCommand, app *kingpin.Application) *ShellComma
 	c := &ShellCommand{rootCmd: rootCmd}
 
 	c.Cmd = app.Command("shell", "Open an interactive shell in a running sandbox.")
-	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().StringVar(&c.nameOrID)
+	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
 	c.Cmd.Flag("env", "Environment variables (KEY=VALUE or KEY from current environment). Can be repeated.").Short('e').StringsVar(&c.envSpecs)
 	c.Cmd.Flag("file", "Upload local file to sandbox before shell (into /). Can be repeated.").Short('f').StringsVar(&c.files)
 
@@ -46,8 +46,9 @@ func (c ShellCommand) Run(ctx context.Context) error {
 
 	// Initialize storage (SQLite).
 	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
-		DBPath: c.rootCmd.DBPath,
-		Logger: logger,
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
 	})
 	if err != nil {
 		return fmt.Errorf("could not create repository: %w", err)
@@ -64,7 +65,7 @@ func (c ShellCommand) Run(ctx context.Context) error {
 	}
 
 	// Initialize engine based on sandbox configuration.
-	eng, err := newEngineFromConfig(sandbox.Config, repo, logger)
+	eng, err := newEngineFromConfig(sandbox.Config, repo, c.rootCmd.VMsDir, logger)
 	if err != nil {
 		return fmt.Errorf("could not create engine: %w", err)
 	}
@@ -79,11 +80,16 @@ func (c ShellCommand) Run(ctx context.Context) error {
 		return fmt.Errorf("could not create service: %w", err)
 	}
 
-	// Execute /bin/sh with TTY for interactive shell.
+	// Execute the exec profile shell (or /bin/sh) with TTY for interactive shell.
+	shell := "/bin/sh"
+	if p := sandbox.Config.ExecProfile; p != nil && p.Shell != "" {
+		shell = p.Shell
+	}
 	result, err := svc.Run(ctx, exec.Request{
 		NameOrID: c.nameOrID,
-		Command:  []string{"/bin/sh"},
+		Command:  []string{shell},
 		Files:    c.files,
+		Caller:   "cli",
 		Opts: model.ExecOpts{
 			Stdin:  os.Stdin,
 			Stdout: os.Stdout,
@@ -1,33 +1,31 @@
 package commands
 
 import (
-	"context"
 	"fmt"
+	"net/url"
 	"path/filepath"
 
 	"github.com/alecthomas/kingpin/v2"
+	"github.com/alecthomas/units"
 	"k8s.io/client-go/util/homedir"
 
-	"github.com/slok/sbx/internal/app/snapshotcreate"
 	"github.com/slok/sbx/internal/image"
-	"github.com/slok/sbx/internal/storage/sqlite"
+	"github.com/slok/sbx/internal/model"
 )
 
+// SnapshotCommand is the parent command for snapshot subcommands, creating a
+// snapshot is the default subcommand.
 type SnapshotCommand struct {
-	Cmd     *kingpin.CmdClause
-	rootCmd *RootCommand
+	Cmd *kingpin.CmdClause
 
-	sandboxNameOrID string
-	imageName       string
-	imagesDir       string
+	imagesDir string
 }
 
-func NewSnapshotCommand(rootCmd *RootCommand, app *kingpin.Application) *SnapshotCommand {
-	c := &SnapshotCommand{rootCmd: rootCmd}
+// NewSnapshotCommand returns the snapshot parent command.
+func NewSnapshotCommand(app *kingpin.Application) *SnapshotCommand {
+	c := &SnapshotCommand{}
 
-	c.Cmd = app.Command("snapshot", "Create a snapshot image from a sandbox.")
-	c.Cmd.Arg("sandbox", "Name or ID of the sandbox to snapshot.").Required().StringVar(&c.sandboxNameOrID)
-	c.Cmd.Flag("name", "Name for the snapshot image. Auto-generated if not provided.").StringVar(&c.imageName)
+	c.Cmd = app.Command("snapshot", "Create, inspect and share snapshot images.")
 
 	defaultImagesDir := filepath.Join(homedir.HomeDir(), image.DefaultImagesDir)
 	c.Cmd.Flag("images-dir", "Local directory for images.").Default(defaultImagesDir).StringVar(&c.imagesDir)
@@ -35,61 +33,38 @@ func NewSnapshotCommand(rootCmd *RootCommand, app *kingpin.Application) *Snapsho
 	return c
 }
 
-func (c SnapshotCommand) Name() string { return c.Cmd.FullCommand() }
-
-func (c SnapshotCommand) Run(ctx context.Context) error {
-	logger := c.rootCmd.Logger
-
-	// Initialize storage.
-	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
-		DBPath: c.rootCmd.DBPath,
-		Logger: logger,
-	})
-	if err != nil {
-		return fmt.Errorf("could not create repository: %w", err)
+// newSnapshotStore returns the snapshot store of the --snapshot-store URL, its query
+// options configure the store (e.g. s3://snaps/team?endpoint=http://minio:9000&sse=AES256).
+func newSnapshotStore(rootCmd *RootCommand) (image.SnapshotStore, error) {
+	if rootCmd.SnapshotStore == "" {
+		return nil, fmt.Errorf("no snapshot store configured, set --snapshot-store or SBX_SNAPSHOT_STORE: %w", model.ErrNotValid)
 	}
 
-	// Initialize local image manager (for Exists check).
-	imgMgr, err := image.NewLocalImageManager(image.LocalImageManagerConfig{
-		ImagesDir: c.imagesDir,
-		Logger:    logger,
-	})
+	u, err := url.Parse(rootCmd.SnapshotStore)
 	if err != nil {
-		return fmt.Errorf("could not create image manager: %w", err)
+		return nil, fmt.Errorf("invalid snapshot store url: %w", model.ErrNotValid)
 	}
-
-	// Initialize snapshot creator.
-	snapCrt, err := image.NewLocalSnapshotCreator(image.LocalSnapshotCreatorConfig{
-		ImagesDir: c.imagesDir,
-		Logger:    logger,
-	})
-	if err != nil {
-		return fmt.Errorf("could not create snapshot creator: %w", err)
+	q := u.Query()
+	u.RawQuery = ""
+	cfg := image.S3SnapshotStoreConfig{
+		URL:        u.String(),
+		Endpoint:   q.Get("endpoint"),
+		Region:     q.Get("region"),
+		Encryption: model.SnapshotStoreEncryption(q.Get("sse")),
+		KMSKeyID:   q.Get("sse_kms_key_id"),
+		Logger:     rootCmd.Logger,
 	}
-
-	// Determine data dir from images dir (go up one level: ~/.sbx/images -> ~/.sbx).
-	dataDir := filepath.Dir(c.imagesDir)
-
-	svc, err := snapshotcreate.NewService(snapshotcreate.ServiceConfig{
-		ImageManager:    imgMgr,
-		SnapshotCreator: snapCrt,
-		Repository:      repo,
-		Logger:          logger,
-		DataDir:         dataDir,
-	})
-	if err != nil {
-		return fmt.Errorf("could not create service: %w", err)
+	if v := q.Get("part_size"); v != "" {
+		size, err := units.ParseBase2Bytes(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid snapshot store part size %q: %w", v, model.ErrNotValid)
+		}
+		cfg.PartSize = int64(size)
 	}
 
-	imgName, err := svc.Run(ctx, snapshotcreate.Request{
-		NameOrID:  c.sandboxNameOrID,
-		ImageName: c.imageName,
-	})
+	store, err := image.NewS3SnapshotStore(cfg)
 	if err != nil {
-		return fmt.Errorf("could not create snapshot image: %w", err)
+		return nil, fmt.Errorf("could not create snapshot store: %w: %w", err, model.ErrNotValid)
 	}
-
-	fmt.Fprintf(c.rootCmd.Stdout, "Snapshot image created: %s\n", imgName)
-	fmt.Fprintf(c.rootCmd.Stdout, "  Use 'sbx create --from-image %s' to create a sandbox from this image.\n", imgName)
-	return nil
+	return store, nil
 }
@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/snapshotcreate"
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// SnapshotCreateCommand creates a snapshot image from a sandbox.
+type SnapshotCreateCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+	snapCmd *SnapshotCommand
+
+	sandboxNameOrID string
+	imageName       string
+	quiet           bool
+	push            bool
+}
+
+// NewSnapshotCreateCommand returns the snapshot create command, the default
+// subcommand of snapshot (sbx snapshot SANDBOX).
+func NewSnapshotCreateCommand(rootCmd *RootCommand, snapCmd *SnapshotCommand) *SnapshotCreateCommand {
+	c := &SnapshotCreateCommand{rootCmd: rootCmd, snapCmd: snapCmd}
+
+	c.Cmd = snapCmd.Cmd.Command("create", "Create a snapshot image from a sandbox, running sandboxes are paused briefly to capture their disk and memory.").Default()
+	c.Cmd.Arg("sandbox", "Name or ID of the sandbox to snapshot.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.sandboxNameOrID)
+	c.Cmd.Flag("name", "Name for the snapshot image. Auto-generated if not provided.").StringVar(&c.imageName)
+	c.Cmd.Flag("push", "Upload the snapshot image to the snapshot store once created (see --snapshot-store).").BoolVar(&c.push)
+	c.Cmd.Flag("quiet", "Only print the snapshot image name on stdout, the messages go to stderr.").Short('q').BoolVar(&c.quiet)
+
+	return c
+}
+
+func (c SnapshotCreateCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c SnapshotCreateCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	// Check the snapshot store before creating a snapshot that can't be pushed.
+	if c.push {
+		if _, err := newSnapshotStore(c.rootCmd); err != nil {
+			return err
+		}
+	}
+
+	// Initialize storage.
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	// Get sandbox to determine which engine to use (required by running sandboxes).
+	sandbox, err := repo.GetSandboxByName(ctx, c.sandboxNameOrID)
+	if err != nil {
+		// Try by ID if name lookup failed
+		sandbox, err = repo.GetSandbox(ctx, c.sandboxNameOrID)
+		if err != nil {
+			return fmt.Errorf("could not find sandbox '%s': %w", c.sandboxNameOrID, err)
+		}
+	}
+
+	eng, err := newEngineFromConfig(sandbox.Config, repo, c.rootCmd.VMsDir, logger)
+	if err != nil {
+		return fmt.Errorf("could not create engine: %w", err)
+	}
+
+	// Initialize local image manager (for Exists check).
+	imgMgr, err := image.NewLocalImageManager(image.LocalImageManagerConfig{
+		ImagesDir:    c.snapCmd.imagesDir,
+		SnapshotsDir: c.rootCmd.SnapshotsDir,
+		Logger:       logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create image manager: %w", err)
+	}
+
+	// Initialize snapshot creator.
+	snapCrt, err := image.NewLocalSnapshotCreator(image.LocalSnapshotCreatorConfig{
+		ImagesDir: cmp.Or(c.rootCmd.SnapshotsDir, c.snapCmd.imagesDir),
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create snapshot creator: %w", err)
+	}
+
+	svc, err := snapshotcreate.NewService(snapshotcreate.ServiceConfig{
+		ImageManager:    imgMgr,
+		SnapshotCreator: snapCrt,
+		Repository:      repo,
+		Engine:          eng,
+		Logger:          logger,
+		VMsDir:          c.rootCmd.VMsDir,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	progress, finishProgress := newProgress(c.rootCmd)
+	imgName, err := svc.Run(ctx, snapshotcreate.Request{
+		NameOrID:  c.sandboxNameOrID,
+		ImageName: c.imageName,
+		Progress:  progress,
+	})
+	finishProgress(err)
+	if err != nil {
+		return fmt.Errorf("could not create snapshot image: %w", err)
+	}
+
+	msg := fmt.Sprintf("Snapshot image created: %s", imgName)
+	if c.push {
+		snap, err := pushSnapshot(ctx, c.rootCmd, c.snapCmd.imagesDir, imgName)
+		if err != nil {
+			return fmt.Errorf("snapshot image %s created locally: %w", imgName, err)
+		}
+		msg += "\n" + snapshotPushedMessage(snap)
+	}
+
+	if c.quiet {
+		return printQuietResult(c.rootCmd, imgName, msg)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	if c.rootCmd.structuredOutput("") {
+		return p.PrintMessage(msg)
+	}
+	return p.PrintMessage(fmt.Sprintf("%s\n  Use 'sbx create --from-image %s' to create a sandbox from this image.", msg, imgName))
+}
@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/snapshotdiff"
+	"github.com/slok/sbx/internal/image"
+)
+
+// SnapshotDiffCommand reports the files changed between snapshot images.
+type SnapshotDiffCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+	snapCmd *SnapshotCommand
+
+	snapA string
+	snapB string
+}
+
+// NewSnapshotDiffCommand returns the snapshot diff command.
+func NewSnapshotDiffCommand(rootCmd *RootCommand, snapCmd *SnapshotCommand) *SnapshotDiffCommand {
+	c := &SnapshotDiffCommand{rootCmd: rootCmd, snapCmd: snapCmd}
+
+	c.Cmd = snapCmd.Cmd.Command("diff", "Show the rootfs files changed by a snapshot against its base image, or from an image to another (requires root).")
+	c.Cmd.Arg("snapshot", "Snapshot image, or the older image when a second one is given.").Required().StringVar(&c.snapA)
+	c.Cmd.Arg("other", "Newer image compared with the first one (default: the first one against its base image).").StringVar(&c.snapB)
+
+	return c
+}
+
+func (c SnapshotDiffCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c SnapshotDiffCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	mgr, err := image.NewLocalImageManager(image.LocalImageManagerConfig{
+		ImagesDir:    c.snapCmd.imagesDir,
+		SnapshotsDir: c.rootCmd.SnapshotsDir,
+		Logger:       logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create image manager: %w", err)
+	}
+
+	differ, err := image.NewLocalImageDiffer(image.LocalImageDifferConfig{
+		ImagesDir:    c.snapCmd.imagesDir,
+		SnapshotsDir: c.rootCmd.SnapshotsDir,
+		Logger:       logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create image differ: %w", err)
+	}
+
+	svc, err := snapshotdiff.NewService(snapshotdiff.ServiceConfig{
+		Manager: mgr,
+		Differ:  differ,
+		Logger:  logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	req := snapshotdiff.Request{Snapshot: c.snapA}
+	if c.snapB != "" {
+		req = snapshotdiff.Request{Snapshot: c.snapB, Against: c.snapA}
+	}
+	diff, err := svc.Run(ctx, req)
+	if err != nil {
+		return fmt.Errorf("could not diff snapshot: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	if err := p.PrintImageDiff(*diff); err != nil {
+		return fmt.Errorf("could not print snapshot diff: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/snapshotfetch"
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/model"
+)
+
+// SnapshotFetchCommand installs a snapshot image from the snapshot store.
+type SnapshotFetchCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+	snapCmd *SnapshotCommand
+
+	name  string
+	force bool
+}
+
+// NewSnapshotFetchCommand returns the snapshot fetch command.
+func NewSnapshotFetchCommand(rootCmd *RootCommand, snapCmd *SnapshotCommand) *SnapshotFetchCommand {
+	c := &SnapshotFetchCommand{rootCmd: rootCmd, snapCmd: snapCmd}
+
+	c.Cmd = snapCmd.Cmd.Command("fetch", "Install a snapshot image from the snapshot store (see --snapshot-store), 'sbx create --from-image' fetches the missing ones automatically.")
+	c.Cmd.Arg("name", "Snapshot image name.").Required().StringVar(&c.name)
+	c.Cmd.Flag("force", "Replace the image if already installed.").BoolVar(&c.force)
+
+	return c
+}
+
+func (c SnapshotFetchCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c SnapshotFetchCommand) Run(ctx context.Context) error {
+	if err := fetchSnapshot(ctx, c.rootCmd, c.snapCmd.imagesDir, c.name, c.force); err != nil {
+		return err
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	return p.PrintMessage(fmt.Sprintf("Snapshot image fetched: %s", c.name))
+}
+
+// fetchSnapshot installs a snapshot image from the snapshot store.
+func fetchSnapshot(ctx context.Context, rootCmd *RootCommand, imagesDir, name string, force bool) error {
+	store, err := newSnapshotStore(rootCmd)
+	if err != nil {
+		return err
+	}
+
+	mgr, err := image.NewLocalImageManager(image.LocalImageManagerConfig{
+		ImagesDir:    imagesDir,
+		SnapshotsDir: rootCmd.SnapshotsDir,
+		Logger:       rootCmd.Logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create image manager: %w", err)
+	}
+
+	bundler, err := image.NewLocalImageBundler(image.LocalImageBundlerConfig{
+		ImagesDir:    imagesDir,
+		SnapshotsDir: rootCmd.SnapshotsDir,
+		Logger:       rootCmd.Logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create image bundler: %w", err)
+	}
+
+	svc, err := snapshotfetch.NewService(snapshotfetch.ServiceConfig{
+		ImageManager: mgr,
+		Bundler:      bundler,
+		Store:        store,
+		Logger:       rootCmd.Logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	if err := svc.Run(ctx, snapshotfetch.Request{Name: name, Force: force}); err != nil {
+		return fmt.Errorf("could not fetch snapshot: %w", err)
+	}
+	return nil
+}
+
+// fetchMissingSnapshot fetches an image that is not installed from the snapshot
+// store when there is one, and returns if it was fetched.
+func fetchMissingSnapshot(ctx context.Context, rootCmd *RootCommand, imagesDir, name string) (bool, error) {
+	if rootCmd.SnapshotStore == "" {
+		return false, nil
+	}
+
+	rootCmd.Logger.Infof("Image %s is not installed, fetching it from the snapshot store", name)
+	err := fetchSnapshot(ctx, rootCmd, imagesDir, name, false)
+	if errors.Is(err, model.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
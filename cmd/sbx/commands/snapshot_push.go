@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/snapshotpush"
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/printer"
+)
+
+// SnapshotPushCommand uploads a snapshot image to the snapshot store.
+type SnapshotPushCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+	snapCmd *SnapshotCommand
+
+	name string
+}
+
+// NewSnapshotPushCommand returns the snapshot push command.
+func NewSnapshotPushCommand(rootCmd *RootCommand, snapCmd *SnapshotCommand) *SnapshotPushCommand {
+	c := &SnapshotPushCommand{rootCmd: rootCmd, snapCmd: snapCmd}
+
+	c.Cmd = snapCmd.Cmd.Command("push", "Upload a snapshot image to the snapshot store (see --snapshot-store), so other hosts can create sandboxes from it.")
+	c.Cmd.Arg("name", "Snapshot image name.").Required().HintAction(imageNameHints(rootCmd, &snapCmd.imagesDir)).StringVar(&c.name)
+
+	return c
+}
+
+func (c SnapshotPushCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c SnapshotPushCommand) Run(ctx context.Context) error {
+	snap, err := pushSnapshot(ctx, c.rootCmd, c.snapCmd.imagesDir, c.name)
+	if err != nil {
+		return err
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	return p.PrintMessage(snapshotPushedMessage(snap))
+}
+
+// pushSnapshot uploads an installed image to the snapshot store.
+func pushSnapshot(ctx context.Context, rootCmd *RootCommand, imagesDir, name string) (*model.RemoteSnapshot, error) {
+	store, err := newSnapshotStore(rootCmd)
+	if err != nil {
+		return nil, err
+	}
+
+	bundler, err := image.NewLocalImageBundler(image.LocalImageBundlerConfig{
+		ImagesDir:    imagesDir,
+		SnapshotsDir: rootCmd.SnapshotsDir,
+		Logger:       rootCmd.Logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create image bundler: %w", err)
+	}
+
+	svc, err := snapshotpush.NewService(snapshotpush.ServiceConfig{
+		Bundler: bundler,
+		Store:   store,
+		Logger:  rootCmd.Logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	snap, err := svc.Run(ctx, snapshotpush.Request{Name: name})
+	if err != nil {
+		return nil, fmt.Errorf("could not push snapshot: %w", err)
+	}
+	return snap, nil
+}
+
+func snapshotPushedMessage(snap *model.RemoteSnapshot) string {
+	return fmt.Sprintf("Snapshot %s pushed to %s (%s)", snap.Name, snap.URL, printer.FormatBytes(snap.SizeBytes))
+}
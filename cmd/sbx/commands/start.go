@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/alecthomas/kingpin/v2"
 
 	"github.com/slok/sbx/internal/app/start"
+	"github.com/slok/sbx/internal/capacity"
 	"github.com/slok/sbx/internal/model"
-	"github.com/slok/sbx/internal/printer"
+	"github.com/slok/sbx/internal/storage"
 	"github.com/slok/sbx/internal/storage/io"
 	"github.com/slok/sbx/internal/storage/sqlite"
 	utilsenv "github.com/slok/sbx/internal/utils/env"
@@ -20,45 +22,71 @@ type StartCommand struct {
 	Cmd     *kingpin.CmdClause
 	rootCmd *RootCommand
 
-	nameOrID   string
-	configFile string
-	envSpecs   []string
+	nameOrID       string
+	configFile     string
+	egressPolicy   string
+	envSpecs       []string
+	integrityPaths []string
+	resetSession   bool
+	admission      string
+	timeouts       model.StartTimeouts
+	yes            bool
+	quiet          bool
 }
 
 // NewStartCommand returns the start command.
 func NewStartCommand(rootCmd *RootCommand, app *kingpin.Application) *StartCommand {
 	c := &StartCommand{rootCmd: rootCmd}
 
-	c.Cmd = app.Command("start", "Start a created or stopped sandbox.")
-	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().StringVar(&c.nameOrID)
+	c.Cmd = app.Command("start", "Start a created or stopped sandbox, or the sandboxes matching a name pattern.")
+	c.Cmd.Arg("name-or-id", sandboxSelectionHelp).Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
 	c.Cmd.Flag("file", "Path to a session configuration YAML file.").Short('f').StringVar(&c.configFile)
+	c.Cmd.Flag("egress-policy", "Name of a stored egress policy (see 'sbx policy'), overrides the session file one.").StringVar(&c.egressPolicy)
 	c.Cmd.Flag("env", "Environment variables (KEY=VALUE or KEY from current environment). Can be repeated.").Short('e').StringsVar(&c.envSpecs)
+	c.Cmd.Flag("integrity-path", "Guest path whose files are hashed after the start as the baseline of 'sbx verify', overrides the session file ones. Can be repeated.").StringsVar(&c.integrityPaths)
+	c.Cmd.Flag("reset-session", "Don't apply the session of the previous start (env, egress policy, integrity paths) when no session is given.").BoolVar(&c.resetSession)
+	c.Cmd.Flag("admission", "Check the host capacity (see 'sbx capacity'): off, warn or enforce (refuse the sandbox).").Default(string(model.AdmissionModeOff)).EnumVar(&c.admission, string(model.AdmissionModeOff), string(model.AdmissionModeWarn), string(model.AdmissionModeEnforce))
+	c.Cmd.Flag("boot-timeout", "Maximum time to wait for the guest to boot and accept SSH connections (default 60s).").DurationVar(&c.timeouts.Boot)
+	c.Cmd.Flag("ssh-dial-timeout", "Timeout of each guest SSH connection attempt while waiting for the boot (default 2s).").DurationVar(&c.timeouts.SSHDial)
+	c.Cmd.Flag("ssh-retries", "Maximum guest SSH connection attempts while waiting for the boot (default 0, retry until the boot timeout).").IntVar(&c.timeouts.SSHRetries)
+	c.Cmd.Flag("fs-expand-timeout", "Timeout of the guest filesystem expansion after the boot (default 0, no timeout).").DurationVar(&c.timeouts.FilesystemExpand)
+	c.Cmd.Flag("yes", "Don't ask for confirmation when a name pattern selects the sandboxes.").Short('y').BoolVar(&c.yes)
+	c.Cmd.Flag("quiet", "Only print the started sandbox IDs on stdout, the messages go to stderr.").Short('q').BoolVar(&c.quiet)
 
 	return c
 }
 
 func (c StartCommand) Name() string { return c.Cmd.FullCommand() }
 
+// loadSessionConfig loads a session configuration YAML file.
+func loadSessionConfig(ctx context.Context, path string) (model.SessionConfig, error) {
+	if !filepath.IsAbs(path) {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return model.SessionConfig{}, fmt.Errorf("could not resolve session config path: %w", err)
+		}
+		path = absPath
+	}
+
+	configRepo := io.NewSessionYAMLRepository(os.DirFS("/"))
+	cfg, err := configRepo.GetSessionConfig(ctx, path[1:])
+	if err != nil {
+		return model.SessionConfig{}, fmt.Errorf("could not load session config: %w", err)
+	}
+
+	return cfg, nil
+}
+
 func (c StartCommand) Run(ctx context.Context) error {
 	logger := c.rootCmd.Logger
 
 	// Load session config from YAML if provided.
 	var sessionCfg model.SessionConfig
 	if c.configFile != "" {
-		configPath := c.configFile
-		if !filepath.IsAbs(configPath) {
-			absPath, err := filepath.Abs(configPath)
-			if err != nil {
-				return fmt.Errorf("could not resolve session config path: %w", err)
-			}
-			configPath = absPath
-		}
-
-		configRepo := io.NewSessionYAMLRepository(os.DirFS("/"))
 		var err error
-		sessionCfg, err = configRepo.GetSessionConfig(ctx, configPath[1:])
+		sessionCfg, err = loadSessionConfig(ctx, c.configFile)
 		if err != nil {
-			return fmt.Errorf("could not load session config: %w", err)
+			return err
 		}
 	}
 
@@ -67,56 +95,98 @@ func (c StartCommand) Run(ctx context.Context) error {
 		return fmt.Errorf("invalid --env value: %w", err)
 	}
 	sessionCfg.Env = utilsenv.MergeMaps(sessionCfg.Env, cliEnv)
+	if c.egressPolicy != "" {
+		sessionCfg.EgressPolicyName = c.egressPolicy
+	}
+	if len(c.integrityPaths) > 0 {
+		sessionCfg.IntegrityPaths = c.integrityPaths
+		if err := sessionCfg.Validate(); err != nil {
+			return fmt.Errorf("invalid --integrity-path value: %w", err)
+		}
+	}
 
 	// Initialize storage (SQLite).
 	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
-		DBPath: c.rootCmd.DBPath,
-		Logger: logger,
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
 	})
 	if err != nil {
 		return fmt.Errorf("could not create repository: %w", err)
 	}
 
+	planner, err := newCapacityPlanner(repo, c.rootCmd.VMsDir, model.AdmissionMode(c.admission), logger)
+	if err != nil {
+		return err
+	}
+
+	sel, err := selectSandboxes(ctx, c.rootCmd, repo, c.nameOrID, "start", c.yes)
+	if err != nil {
+		return err
+	}
+
+	return runOnSelection(c.rootCmd, sel, c.quiet, func(nameOrID string) (*model.Sandbox, string, error) {
+		sandbox, err := c.start(ctx, repo, planner, sessionCfg, nameOrID)
+		if err != nil {
+			return nil, "", err
+		}
+		var bootTime time.Duration
+		for _, p := range sandbox.BootPhases {
+			bootTime += p.Duration
+		}
+		return sandbox, fmt.Sprintf("Started sandbox: %s (in %s)", sandbox.Name, bootTime.Round(time.Millisecond)), nil
+	})
+}
+
+func (c StartCommand) start(ctx context.Context, repo storage.Repository, planner *capacity.Planner, sessionCfg model.SessionConfig, nameOrID string) (*model.Sandbox, error) {
+	logger := c.rootCmd.Logger
+
 	// Get sandbox to determine which engine to use.
-	sandbox, err := repo.GetSandboxByName(ctx, c.nameOrID)
+	sandbox, err := repo.GetSandboxByName(ctx, nameOrID)
 	if err != nil {
 		// Try by ID if name lookup failed
-		sandbox, err = repo.GetSandbox(ctx, c.nameOrID)
+		sandbox, err = repo.GetSandbox(ctx, nameOrID)
 		if err != nil {
-			return fmt.Errorf("could not find sandbox: %w", err)
+			return nil, fmt.Errorf("could not find sandbox: %w", err)
 		}
 	}
 
 	// Initialize engine based on sandbox configuration.
-	eng, err := newEngineFromConfig(sandbox.Config, repo, logger)
+	eng, err := newEngineFromConfig(sandbox.Config, repo, c.rootCmd.VMsDir, logger)
+	if err != nil {
+		return nil, fmt.Errorf("could not create engine: %w", err)
+	}
+
+	hooksRunner, err := newHooksRunner(c.rootCmd, logger)
 	if err != nil {
-		return fmt.Errorf("could not create engine: %w", err)
+		return nil, fmt.Errorf("could not create hooks runner: %w", err)
 	}
 
 	// Create start service.
 	svc, err := start.NewService(start.ServiceConfig{
 		Engine:     eng,
 		Repository: repo,
+		Admission:  planner,
+		Hooks:      hooksRunner,
 		Logger:     logger,
 	})
 	if err != nil {
-		return fmt.Errorf("could not create service: %w", err)
+		return nil, fmt.Errorf("could not create service: %w", err)
 	}
 
 	// Execute start.
+	progress, finishProgress := newProgress(c.rootCmd)
 	sandbox, err = svc.Run(ctx, start.Request{
-		NameOrID:      c.nameOrID,
+		NameOrID:      nameOrID,
 		SessionConfig: sessionCfg,
+		ResetSession:  c.resetSession,
+		Timeouts:      c.timeouts,
+		Progress:      progress,
 	})
+	finishProgress(err)
 	if err != nil {
-		return fmt.Errorf("could not start sandbox: %w", err)
-	}
-
-	// Print success message.
-	p := printer.NewTablePrinter(c.rootCmd.Stdout)
-	if err := p.PrintMessage(fmt.Sprintf("Started sandbox: %s", sandbox.Name)); err != nil {
-		return fmt.Errorf("could not print message: %w", err)
+		return nil, fmt.Errorf("could not start sandbox: %w", err)
 	}
 
-	return nil
+	return sandbox, nil
 }
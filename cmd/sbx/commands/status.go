@@ -3,11 +3,14 @@ package commands
 import (
 	"context"
 	"fmt"
+	"io"
+	"time"
 
 	"github.com/alecthomas/kingpin/v2"
 
 	"github.com/slok/sbx/internal/app/status"
-	"github.com/slok/sbx/internal/printer"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
 	"github.com/slok/sbx/internal/storage/sqlite"
 )
 
@@ -17,6 +20,8 @@ type StatusCommand struct {
 
 	nameOrID string
 	format   string
+	watch    bool
+	interval time.Duration
 }
 
 // NewStatusCommand returns the status command.
@@ -24,8 +29,10 @@ func NewStatusCommand(rootCmd *RootCommand, app *kingpin.Application) *StatusCom
 	c := &StatusCommand{rootCmd: rootCmd}
 
 	c.Cmd = app.Command("status", "Get detailed status of a sandbox.")
-	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().StringVar(&c.nameOrID)
-	c.Cmd.Flag("format", "Output format (table, json).").Default("table").EnumVar(&c.format, "table", "json")
+	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+	c.Cmd.Flag("format", "Output format (table, json, yaml). Deprecated: use the global --output flag.").EnumVar(&c.format, OutputFormatTable, OutputFormatJSON, OutputFormatYAML)
+	c.Cmd.Flag("watch", "Keep the status updated on the sandbox changes until Ctrl+C, highlighting the changed lines (requires a terminal and the table output).").Short('w').BoolVar(&c.watch)
+	c.Cmd.Flag("interval", "Time between the refreshes of the watched status without changes.").Default("2s").DurationVar(&c.interval)
 
 	return c
 }
@@ -35,44 +42,64 @@ func (c StatusCommand) Name() string { return c.Cmd.FullCommand() }
 func (c StatusCommand) Run(ctx context.Context) error {
 	logger := c.rootCmd.Logger
 
+	if c.interval <= 0 {
+		return fmt.Errorf("interval must be greater than 0: %w", model.ErrNotValid)
+	}
+
 	// Initialize storage (SQLite).
 	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
-		DBPath: c.rootCmd.DBPath,
-		Logger: logger,
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
 	})
 	if err != nil {
 		return fmt.Errorf("could not create repository: %w", err)
 	}
 
+	// Get sandbox to determine which engine to use, the engine adds the runtime status.
+	sb, err := repo.GetSandboxByName(ctx, c.nameOrID)
+	if err != nil {
+		// Try by ID if name lookup failed
+		sb, err = repo.GetSandbox(ctx, c.nameOrID)
+	}
+	var eng sandbox.Engine
+	if err == nil {
+		eng, err = newEngineFromConfig(sb.Config, repo, c.rootCmd.VMsDir, logger)
+		if err != nil {
+			return fmt.Errorf("could not create engine: %w", err)
+		}
+	}
+
 	// Create status service.
 	svc, err := status.NewService(status.ServiceConfig{
 		Repository: repo,
+		Engine:     eng,
 		Logger:     logger,
 	})
 	if err != nil {
 		return fmt.Errorf("could not create service: %w", err)
 	}
 
-	// Execute status.
-	sandbox, err := svc.Run(ctx, status.Request{
-		NameOrID: c.nameOrID,
-	})
-	if err != nil {
-		return fmt.Errorf("could not get sandbox status: %w", err)
-	}
-
-	// Print output.
-	var p printer.Printer
-	switch c.format {
-	case "json":
-		p = printer.NewJSONPrinter(c.rootCmd.Stdout)
-	default: // table
-		p = printer.NewTablePrinter(c.rootCmd.Stdout)
+	printStatus := func(w io.Writer, format string) error {
+		sandbox, err := svc.Run(ctx, status.Request{
+			NameOrID: c.nameOrID,
+		})
+		if err != nil {
+			return fmt.Errorf("could not get sandbox status: %w", err)
+		}
+		if err := newPrinter(format, w).PrintStatus(*sandbox); err != nil {
+			return fmt.Errorf("could not print status: %w", err)
+		}
+		return nil
 	}
 
-	if err := p.PrintStatus(*sandbox); err != nil {
-		return fmt.Errorf("could not print status: %w", err)
+	// The watched status is refreshed with the same service and engine, driven by the
+	// stored changes.
+	if out, ok := c.rootCmd.watchTerminal(c.format); c.watch && ok {
+		return watchView(ctx, out, repo, c.interval, func(w io.Writer) error {
+			return printStatus(w, OutputFormatTable)
+		})
 	}
 
-	return nil
+	return printStatus(c.rootCmd.Stdout, c.rootCmd.outputFormat(c.format))
 }
@@ -3,11 +3,13 @@ package commands
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/alecthomas/kingpin/v2"
 
 	"github.com/slok/sbx/internal/app/stop"
-	"github.com/slok/sbx/internal/printer"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
 	"github.com/slok/sbx/internal/storage/sqlite"
 )
 
@@ -15,15 +17,21 @@ type StopCommand struct {
 	Cmd     *kingpin.CmdClause
 	rootCmd *RootCommand
 
-	nameOrID string
+	nameOrID     string
+	yes          bool
+	gracePeriod  time.Duration
+	preStopHooks []string
 }
 
 // NewStopCommand returns the stop command.
 func NewStopCommand(rootCmd *RootCommand, app *kingpin.Application) *StopCommand {
 	c := &StopCommand{rootCmd: rootCmd}
 
-	c.Cmd = app.Command("stop", "Stop a running sandbox.")
-	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().StringVar(&c.nameOrID)
+	c.Cmd = app.Command("stop", "Stop a running sandbox, or the sandboxes matching a name pattern.")
+	c.Cmd.Arg("name-or-id", sandboxSelectionHelp).Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+	c.Cmd.Flag("yes", "Don't ask for confirmation when a name pattern selects the sandboxes.").Short('y').BoolVar(&c.yes)
+	c.Cmd.Flag("grace-period", "Time the sandbox has to run the pre-stop hooks and shut down before being killed (default 10s).").DurationVar(&c.gracePeriod)
+	c.Cmd.Flag("pre-stop", "Shell command run in the guest before it's shut down (e.g. flush caches, notify the agent). Can be repeated.").StringsVar(&c.preStopHooks)
 
 	return c
 }
@@ -35,52 +43,72 @@ func (c StopCommand) Run(ctx context.Context) error {
 
 	// Initialize storage (SQLite).
 	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
-		DBPath: c.rootCmd.DBPath,
-		Logger: logger,
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
 	})
 	if err != nil {
 		return fmt.Errorf("could not create repository: %w", err)
 	}
 
+	sel, err := selectSandboxes(ctx, c.rootCmd, repo, c.nameOrID, "stop", c.yes)
+	if err != nil {
+		return err
+	}
+
+	return runOnSelection(c.rootCmd, sel, false, func(nameOrID string) (*model.Sandbox, string, error) {
+		sandbox, err := c.stop(ctx, repo, nameOrID)
+		if err != nil {
+			return nil, "", err
+		}
+		return sandbox, fmt.Sprintf("Stopped sandbox: %s", sandbox.Name), nil
+	})
+}
+
+func (c StopCommand) stop(ctx context.Context, repo storage.Repository, nameOrID string) (*model.Sandbox, error) {
+	logger := c.rootCmd.Logger
+
 	// Get sandbox to determine which engine to use.
-	sandbox, err := repo.GetSandboxByName(ctx, c.nameOrID)
+	sandbox, err := repo.GetSandboxByName(ctx, nameOrID)
 	if err != nil {
 		// Try by ID if name lookup failed
-		sandbox, err = repo.GetSandbox(ctx, c.nameOrID)
+		sandbox, err = repo.GetSandbox(ctx, nameOrID)
 		if err != nil {
-			return fmt.Errorf("could not find sandbox: %w", err)
+			return nil, fmt.Errorf("could not find sandbox: %w", err)
 		}
 	}
 
 	// Initialize engine based on sandbox configuration.
-	eng, err := newEngineFromConfig(sandbox.Config, repo, logger)
+	eng, err := newEngineFromConfig(sandbox.Config, repo, c.rootCmd.VMsDir, logger)
 	if err != nil {
-		return fmt.Errorf("could not create engine: %w", err)
+		return nil, fmt.Errorf("could not create engine: %w", err)
+	}
+
+	hooksRunner, err := newHooksRunner(c.rootCmd, logger)
+	if err != nil {
+		return nil, fmt.Errorf("could not create hooks runner: %w", err)
 	}
 
 	// Create stop service.
 	svc, err := stop.NewService(stop.ServiceConfig{
 		Engine:     eng,
 		Repository: repo,
+		Hooks:      hooksRunner,
 		Logger:     logger,
 	})
 	if err != nil {
-		return fmt.Errorf("could not create service: %w", err)
+		return nil, fmt.Errorf("could not create service: %w", err)
 	}
 
 	// Execute stop.
 	sandbox, err = svc.Run(ctx, stop.Request{
-		NameOrID: c.nameOrID,
+		NameOrID:     nameOrID,
+		GracePeriod:  c.gracePeriod,
+		PreStopHooks: c.preStopHooks,
 	})
 	if err != nil {
-		return fmt.Errorf("could not stop sandbox: %w", err)
-	}
-
-	// Print success message.
-	p := printer.NewTablePrinter(c.rootCmd.Stdout)
-	if err := p.PrintMessage(fmt.Sprintf("Stopped sandbox: %s", sandbox.Name)); err != nil {
-		return fmt.Errorf("could not print message: %w", err)
+		return nil, fmt.Errorf("could not stop sandbox: %w", err)
 	}
 
-	return nil
+	return sandbox, nil
 }
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/diagnostics"
+	"github.com/slok/sbx/internal/sandbox/firecracker"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// SupportBundleCommand collects a diagnostics bundle to attach to bug reports.
+type SupportBundleCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	sandbox     string
+	file        string
+	maxLogBytes int64
+}
+
+// NewSupportBundleCommand returns the support-bundle command.
+func NewSupportBundleCommand(rootCmd *RootCommand, app *kingpin.Application) *SupportBundleCommand {
+	c := &SupportBundleCommand{rootCmd: rootCmd}
+
+	c.Cmd = app.Command("support-bundle", "Collect a sanitized diagnostics bundle (tar.gz) to attach to bug reports.")
+	c.Cmd.Flag("sandbox", "Sandbox name or ID whose config and logs are included.").HintAction(sandboxNameHints(rootCmd)).StringVar(&c.sandbox)
+	// The -o short flag is the global output format.
+	c.Cmd.Flag("file", "Bundle file path (default: sbx-support-<time>.tar.gz).").Short('f').StringVar(&c.file)
+	c.Cmd.Flag("max-log-bytes", "Size of the log tails included in the bundle.").Default(fmt.Sprint(diagnostics.DefaultMaxLogBytes)).Int64Var(&c.maxLogBytes)
+
+	return c
+}
+
+func (c SupportBundleCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c SupportBundleCommand) Run(ctx context.Context) (err error) {
+	logger := c.rootCmd.Logger
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	// The doctor checks are the firecracker engine ones.
+	eng, err := firecracker.NewEngine(firecracker.EngineConfig{
+		VMsDir:     c.rootCmd.VMsDir,
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create firecracker engine: %w", err)
+	}
+
+	svc, err := diagnostics.NewService(diagnostics.ServiceConfig{
+		Engine:     eng,
+		Repository: repo,
+		Logger:     logger,
+		VMsDir:     c.rootCmd.VMsDir,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	file := c.file
+	if file == "" {
+		file = fmt.Sprintf("sbx-support-%s.tar.gz", time.Now().UTC().Format("20060102T150405Z"))
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return fmt.Errorf("could not create bundle file: %w", err)
+	}
+	defer func() {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("could not write bundle file: %w", cerr)
+		}
+		// Don't leave broken bundles around.
+		if err != nil {
+			os.Remove(file)
+		}
+	}()
+
+	err = svc.Run(ctx, diagnostics.Request{
+		NameOrID:    c.sandbox,
+		Version:     c.rootCmd.Version,
+		MaxLogBytes: c.maxLogBytes,
+		Output:      f,
+	})
+	if err != nil {
+		return fmt.Errorf("could not collect diagnostics: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	return p.PrintMessage(fmt.Sprintf("Wrote support bundle to %s, review it before sharing", file))
+}
@@ -0,0 +1,157 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/copy"
+	"github.com/slok/sbx/internal/app/filesync"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/printer"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+type SyncCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	source      string
+	destination string
+	delete      bool
+	exclude     []string
+	excludeFrom string
+	watch       bool
+	debounce    time.Duration
+}
+
+// NewSyncCommand returns the sync command.
+func NewSyncCommand(rootCmd *RootCommand, app *kingpin.Application) *SyncCommand {
+	c := &SyncCommand{rootCmd: rootCmd}
+
+	c.Cmd = app.Command("sync", "Sync a host directory into a sandbox, uploading only the changed files.")
+	c.Cmd.Arg("source", "Local directory.").Required().StringVar(&c.source)
+	c.Cmd.Arg("destination", "Sandbox directory (sandbox:/path).").Required().StringVar(&c.destination)
+	c.Cmd.Flag("delete", "Remove the sandbox files missing in the local directory.").BoolVar(&c.delete)
+	c.Cmd.Flag("exclude", "Pattern of the paths not synced, matches names (e.g. .git, *.pyc) or relative paths with a slash (e.g. build/out). Repeatable.").StringsVar(&c.exclude)
+	c.Cmd.Flag("exclude-from", "File with exclude patterns, one per line (# comments), e.g. .sbxignore.").StringVar(&c.excludeFrom)
+	c.Cmd.Flag("watch", "Keep syncing the local changes until Ctrl+C.").BoolVar(&c.watch)
+	c.Cmd.Flag("debounce", "Quiet time after a change before syncing in watch mode.").Default("300ms").DurationVar(&c.debounce)
+
+	return c
+}
+
+func (c SyncCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c SyncCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	// Parse arguments to determine sandbox reference.
+	parsed, err := copy.ParseCopyArgs(c.source, c.destination)
+	if err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	if !parsed.ToSandbox {
+		return fmt.Errorf("invalid arguments: sync only supports host to sandbox, the destination must be sandbox:/path: %w", model.ErrNotValid)
+	}
+
+	// Initialize storage (SQLite).
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	// Get sandbox to determine which engine to use.
+	sandbox, err := repo.GetSandboxByName(ctx, parsed.SandboxRef)
+	if err != nil {
+		// Try by ID if name lookup failed
+		sandbox, err = repo.GetSandbox(ctx, parsed.SandboxRef)
+		if err != nil {
+			return fmt.Errorf("could not find sandbox '%s': %w", parsed.SandboxRef, err)
+		}
+	}
+
+	// Initialize engine based on sandbox configuration.
+	eng, err := newEngineFromConfig(sandbox.Config, repo, c.rootCmd.VMsDir, logger)
+	if err != nil {
+		return fmt.Errorf("could not create engine: %w", err)
+	}
+
+	// Create sync service.
+	svc, err := filesync.NewService(filesync.ServiceConfig{
+		Engine:     eng,
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	exclude := c.exclude
+	if c.excludeFrom != "" {
+		patterns, err := readExcludeFile(c.excludeFrom)
+		if err != nil {
+			return err
+		}
+		exclude = append(exclude, patterns...)
+	}
+
+	req := filesync.Request{
+		NameOrID:  parsed.SandboxRef,
+		LocalDir:  parsed.LocalPath,
+		RemoteDir: parsed.RemotePath,
+		Opts: model.SyncOpts{
+			Delete:  c.delete,
+			Exclude: exclude,
+		},
+	}
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	syncMsg := func(res *model.SyncResult) string {
+		return fmt.Sprintf("Synced %s to %s:%s (%d uploaded, %s, %d deleted, %d unchanged)",
+			parsed.LocalPath, sandbox.Name, parsed.RemotePath, len(res.Uploaded), printer.FormatBytes(res.UploadedBytes), len(res.Deleted), res.Unchanged)
+	}
+
+	if c.watch {
+		// Each sync is reported, the failed ones are retried on the next change.
+		err := svc.Watch(ctx, filesync.WatchRequest{
+			Request:  req,
+			Debounce: c.debounce,
+			OnSync: func(res *model.SyncResult, err error) {
+				if err != nil {
+					fmt.Fprintf(c.rootCmd.Stderr, "Sync failed: %v\n", err)
+					return
+				}
+				_ = p.PrintMessage(syncMsg(res))
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("could not watch: %w", err)
+		}
+		return nil
+	}
+
+	// Execute sync.
+	res, err := svc.Run(ctx, req)
+	if err != nil {
+		return fmt.Errorf("could not sync: %w", err)
+	}
+
+	return p.PrintMessage(syncMsg(res))
+}
+
+func readExcludeFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open exclude file: %w", err)
+	}
+	defer f.Close()
+
+	return model.ReadExcludePatterns(f)
+}
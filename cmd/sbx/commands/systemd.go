@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/bootunit"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/storage"
+	"github.com/slok/sbx/internal/systemd"
+)
+
+// SystemdCommand is the parent command for systemd subcommands.
+type SystemdCommand struct {
+	Cmd *kingpin.CmdClause
+}
+
+// NewSystemdCommand returns the systemd parent command.
+func NewSystemdCommand(app *kingpin.Application) *SystemdCommand {
+	c := &SystemdCommand{}
+
+	c.Cmd = app.Command("systemd", "Manage the systemd units that start the sandboxes when the host boots.")
+
+	return c
+}
+
+// newBootUnitService returns the boot unit service of the units in unitDir.
+func newBootUnitService(repo storage.Repository, unitDir string, logger log.Logger) (*bootunit.Service, error) {
+	manager, err := systemd.NewManager(systemd.ManagerConfig{UnitDir: unitDir, Logger: logger})
+	if err != nil {
+		return nil, fmt.Errorf("could not create systemd manager: %w", err)
+	}
+
+	svc, err := bootunit.NewService(bootunit.ServiceConfig{
+		Repository: repo,
+		Manager:    manager,
+		Logger:     logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+	return svc, nil
+}
+
+// unitArgs returns the global flags of the sbx commands run by the units, with
+// absolute paths so they don't depend on the unit working directory.
+func unitArgs(rootCmd *RootCommand) ([]string, error) {
+	dbPath, err := filepath.Abs(rootCmd.DBPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid db path: %w", err)
+	}
+	vmsDir, err := filepath.Abs(rootCmd.VMsDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vms dir: %w", err)
+	}
+	return []string{"--db-path", dbPath, "--vms-dir", vmsDir}, nil
+}
+
+// sbxBinary returns the absolute path of the running sbx binary.
+func sbxBinary() (string, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("could not get the sbx binary, set --binary: %w", err)
+	}
+	return filepath.EvalSymlinks(path)
+}
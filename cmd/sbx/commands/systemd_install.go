@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/bootunit"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/sqlite"
+	"github.com/slok/sbx/internal/systemd"
+)
+
+// SystemdInstallCommand installs the systemd unit that starts a sandbox on the host boot.
+type SystemdInstallCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	nameOrID string
+	restart  string
+	unitDir  string
+	binary   string
+}
+
+// NewSystemdInstallCommand returns the systemd install command.
+func NewSystemdInstallCommand(rootCmd *RootCommand, systemdCmd *SystemdCommand) *SystemdInstallCommand {
+	c := &SystemdInstallCommand{rootCmd: rootCmd}
+
+	c.Cmd = systemdCmd.Cmd.Command("install", "Install and enable a systemd unit (sbx-<ID>.service) that starts the sandbox when the host boots, replacing the previous one. Usually requires root.")
+	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+	c.Cmd.Flag("restart", "What systemd does when the start fails: on-failure (retry every 10s) or no.").Default(string(model.BootRestartPolicyOnFailure)).EnumVar(&c.restart, string(model.BootRestartPolicyOnFailure), string(model.BootRestartPolicyNo))
+	c.Cmd.Flag("unit-dir", "Directory of the systemd units.").Default(systemd.DefaultUnitDir).StringVar(&c.unitDir)
+	c.Cmd.Flag("binary", "Absolute path of the sbx binary run by the unit (default the running one).").StringVar(&c.binary)
+
+	return c
+}
+
+func (c SystemdInstallCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c SystemdInstallCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	binary := c.binary
+	if binary == "" {
+		binary, err = sbxBinary()
+		if err != nil {
+			return err
+		}
+	}
+
+	args, err := unitArgs(c.rootCmd)
+	if err != nil {
+		return err
+	}
+
+	svc, err := newBootUnitService(repo, c.unitDir, logger)
+	if err != nil {
+		return err
+	}
+
+	unit, err := svc.Run(ctx, bootunit.Request{
+		NameOrID: c.nameOrID,
+		Binary:   binary,
+		Args:     args,
+		Restart:  model.BootRestartPolicy(c.restart),
+	})
+	if err != nil {
+		return fmt.Errorf("could not install systemd unit: %w", err)
+	}
+
+	msg := fmt.Sprintf("Sandbox %s starts on boot: %s (restart %s)", unit.SandboxName, unit.Path, unit.Restart)
+	if err := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout).PrintMessage(msg); err != nil {
+		return fmt.Errorf("could not print message: %w", err)
+	}
+
+	return nil
+}
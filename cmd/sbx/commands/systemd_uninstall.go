@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/bootunit"
+	"github.com/slok/sbx/internal/storage/sqlite"
+	"github.com/slok/sbx/internal/systemd"
+)
+
+// SystemdUninstallCommand removes the systemd unit of a sandbox.
+type SystemdUninstallCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	nameOrID string
+	unitDir  string
+}
+
+// NewSystemdUninstallCommand returns the systemd uninstall command.
+func NewSystemdUninstallCommand(rootCmd *RootCommand, systemdCmd *SystemdCommand) *SystemdUninstallCommand {
+	c := &SystemdUninstallCommand{rootCmd: rootCmd}
+
+	c.Cmd = systemdCmd.Cmd.Command("uninstall", "Disable and remove the systemd unit of a sandbox, it no longer starts when the host boots. The sandbox is not stopped.")
+	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+	c.Cmd.Flag("unit-dir", "Directory of the systemd units.").Default(systemd.DefaultUnitDir).StringVar(&c.unitDir)
+
+	return c
+}
+
+func (c SystemdUninstallCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c SystemdUninstallCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	svc, err := newBootUnitService(repo, c.unitDir, logger)
+	if err != nil {
+		return err
+	}
+
+	if _, err := svc.Run(ctx, bootunit.Request{NameOrID: c.nameOrID, Disable: true}); err != nil {
+		return fmt.Errorf("could not uninstall systemd unit: %w", err)
+	}
+
+	msg := fmt.Sprintf("Sandbox %s no longer starts on boot", c.nameOrID)
+	if err := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout).PrintMessage(msg); err != nil {
+		return fmt.Errorf("could not print message: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/io"
+)
+
+// TaskCommand is the parent command for task subcommands.
+type TaskCommand struct {
+	Cmd *kingpin.CmdClause
+}
+
+// NewTaskCommand returns the task parent command.
+func NewTaskCommand(app *kingpin.Application) *TaskCommand {
+	c := &TaskCommand{}
+
+	c.Cmd = app.Command("task", "Manage the named commands run with sbx run, global or of a sandbox.")
+
+	return c
+}
+
+// loadTasks loads the tasks of a YAML file.
+func loadTasks(ctx context.Context, path string) ([]model.Task, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve tasks file path: %w", err)
+	}
+
+	tasks, err := io.NewTaskYAMLRepository(os.DirFS("/")).ListTasks(ctx, absPath[1:])
+	if err != nil {
+		return nil, fmt.Errorf("could not load tasks: %w", err)
+	}
+
+	return tasks, nil
+}
@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/tasklist"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// TaskListCommand lists the tasks.
+type TaskListCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	sandbox string
+}
+
+// NewTaskListCommand returns the task list command.
+func NewTaskListCommand(rootCmd *RootCommand, taskCmd *TaskCommand) *TaskListCommand {
+	c := &TaskListCommand{rootCmd: rootCmd}
+
+	c.Cmd = taskCmd.Cmd.Command("list", "List the global tasks, or the tasks available to a sandbox.")
+	c.Cmd.Flag("sandbox", "Sandbox name or ID, lists its tasks and the global ones it doesn't override.").HintAction(sandboxNameHints(rootCmd)).StringVar(&c.sandbox)
+
+	return c
+}
+
+func (c TaskListCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c TaskListCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	svc, err := tasklist.NewService(tasklist.ServiceConfig{
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	tasks, err := svc.Run(ctx, tasklist.Request{NameOrID: c.sandbox})
+	if err != nil {
+		return fmt.Errorf("could not list tasks: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	if err := p.PrintTaskList(tasks); err != nil {
+		return fmt.Errorf("could not print tasks: %w", err)
+	}
+
+	return nil
+}
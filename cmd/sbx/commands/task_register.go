@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/taskregister"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// TaskRegisterCommand registers the tasks of a YAML file.
+type TaskRegisterCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	file    string
+	sandbox string
+}
+
+// NewTaskRegisterCommand returns the task register command.
+func NewTaskRegisterCommand(rootCmd *RootCommand, taskCmd *TaskCommand) *TaskRegisterCommand {
+	c := &TaskRegisterCommand{rootCmd: rootCmd}
+
+	c.Cmd = taskCmd.Cmd.Command("register", "Register (or replace) the tasks of a YAML file.")
+	c.Cmd.Flag("file", "Tasks YAML file.").Short('f').Required().StringVar(&c.file)
+	c.Cmd.Flag("sandbox", "Sandbox name or ID of the tasks (default: global tasks).").HintAction(sandboxNameHints(rootCmd)).StringVar(&c.sandbox)
+
+	return c
+}
+
+func (c TaskRegisterCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c TaskRegisterCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	tasks, err := loadTasks(ctx, c.file)
+	if err != nil {
+		return err
+	}
+	if len(tasks) == 0 {
+		return fmt.Errorf("tasks file %q has no tasks: %w", c.file, model.ErrNotValid)
+	}
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	svc, err := taskregister.NewService(taskregister.ServiceConfig{
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	for _, t := range tasks {
+		if _, err := svc.Run(ctx, taskregister.Request{NameOrID: c.sandbox, Task: t}); err != nil {
+			return fmt.Errorf("could not register task %q: %w", t.Name, err)
+		}
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	return p.PrintMessage(fmt.Sprintf("Registered %d tasks", len(tasks)))
+}
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/taskrm"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// TaskRmCommand removes a task.
+type TaskRmCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	name    string
+	sandbox string
+}
+
+// NewTaskRmCommand returns the task rm command.
+func NewTaskRmCommand(rootCmd *RootCommand, taskCmd *TaskCommand) *TaskRmCommand {
+	c := &TaskRmCommand{rootCmd: rootCmd}
+
+	c.Cmd = taskCmd.Cmd.Command("rm", "Remove a task.")
+	c.Cmd.Arg("name", "Task name.").Required().StringVar(&c.name)
+	c.Cmd.Flag("sandbox", "Sandbox name or ID of the task (default: global task).").HintAction(sandboxNameHints(rootCmd)).StringVar(&c.sandbox)
+
+	return c
+}
+
+func (c TaskRmCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c TaskRmCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	svc, err := taskrm.NewService(taskrm.ServiceConfig{
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	if err := svc.Run(ctx, taskrm.Request{NameOrID: c.sandbox, Name: c.name}); err != nil {
+		return fmt.Errorf("could not remove task: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	return p.PrintMessage(fmt.Sprintf("Removed task %s", c.name))
+}
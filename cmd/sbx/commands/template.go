@@ -0,0 +1,19 @@
+package commands
+
+import (
+	"github.com/alecthomas/kingpin/v2"
+)
+
+// TemplateCommand is the parent command for sandbox template subcommands.
+type TemplateCommand struct {
+	Cmd *kingpin.CmdClause
+}
+
+// NewTemplateCommand returns the template parent command.
+func NewTemplateCommand(app *kingpin.Application) *TemplateCommand {
+	c := &TemplateCommand{}
+
+	c.Cmd = app.Command("template", "Manage the sandbox templates used by 'sbx create --template'.")
+
+	return c
+}
@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/templatecreate"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/sqlite"
+	utilsenv "github.com/slok/sbx/internal/utils/env"
+)
+
+// TemplateCreateCommand creates a sandbox template.
+type TemplateCreateCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	name         string
+	description  string
+	engine       string
+	fromImage    string
+	profile      string
+	cpu          float64
+	mem          int
+	disk         int
+	maxCPU       float64
+	maxMem       int
+	userDataFile string
+	ports        []string
+	execPaths    []string
+	execLocale   string
+	execUmask    string
+	execUlimit   []string
+	execShell    string
+	configFile   string
+	egressPolicy string
+	envSpecs     []string
+}
+
+// NewTemplateCreateCommand returns the template create command.
+func NewTemplateCreateCommand(rootCmd *RootCommand, templateCmd *TemplateCommand) *TemplateCreateCommand {
+	c := &TemplateCreateCommand{rootCmd: rootCmd}
+
+	c.Cmd = templateCmd.Cmd.Command("create", "Create a sandbox template with the spec of the sandboxes created from it.")
+	c.Cmd.Arg("name", "Template name.").Required().StringVar(&c.name)
+	c.Cmd.Flag("description", "Description of the template.").StringVar(&c.description)
+	c.Cmd.Flag("engine", "Engine type (firecracker, fake).").Default(model.TemplateEngineFirecracker).EnumVar(&c.engine, model.TemplateEngineFirecracker, model.TemplateEngineFake)
+	c.Cmd.Flag("from-image", "Image version of the sandboxes (e.g. v0.1.0), required with the firecracker engine.").StringVar(&c.fromImage)
+	c.Cmd.Flag("profile", "Kernel profile of the sandboxes: fast-boot, debug, hardened or one shipped by the image.").StringVar(&c.profile)
+	c.Cmd.Flag("cpu", "Number of VCPUs (can be fractional, e.g., 0.5, 1.5).").Default("2").Float64Var(&c.cpu)
+	c.Cmd.Flag("mem", "Memory in MB.").Default("2048").IntVar(&c.mem)
+	c.Cmd.Flag("disk", "Disk in GB.").Default("10").IntVar(&c.disk)
+	c.Cmd.Flag("max-cpu", "Number of VCPUs the running sandboxes can be scaled up to (default: --cpu).").Float64Var(&c.maxCPU)
+	c.Cmd.Flag("max-mem", "Memory in MB the running sandboxes can be scaled up to (default: --mem).").IntVar(&c.maxMem)
+	c.Cmd.Flag("user-data", "Path to a shell script or cloud-config file executed in the guest on the first boot.").StringVar(&c.userDataFile)
+	c.Cmd.Flag("port", "Named port of a sandbox service 'NAME=PORT' (e.g. web=3000). Repeatable.").StringsVar(&c.ports)
+	c.Cmd.Flag("exec-path", "Guest directory added in front of the PATH of every exec and shell. Repeatable, in order.").StringsVar(&c.execPaths)
+	c.Cmd.Flag("exec-locale", "Locale (LANG and LC_ALL) of every exec and shell (e.g. C.UTF-8).").StringVar(&c.execLocale)
+	c.Cmd.Flag("exec-umask", "Octal umask of every exec and shell (e.g. 027).").StringVar(&c.execUmask)
+	c.Cmd.Flag("exec-ulimit", "Resource limit 'NAME=VALUE' of every exec and shell (e.g. nofile=65536). Repeatable.").StringsVar(&c.execUlimit)
+	c.Cmd.Flag("exec-shell", "Interactive shell of 'sbx shell' (e.g. /bin/bash).").StringVar(&c.execShell)
+	c.Cmd.Flag("file", "Path to a session configuration YAML file with the environment and egress of the sandboxes created with --start.").Short('f').StringVar(&c.configFile)
+	c.Cmd.Flag("egress-policy", "Name of a stored egress policy (see 'sbx policy'), overrides the session file one.").StringVar(&c.egressPolicy)
+	c.Cmd.Flag("env", "Session environment variables (KEY=VALUE or KEY from current environment), override the session file ones. Can be repeated.").Short('e').StringsVar(&c.envSpecs)
+
+	return c
+}
+
+func (c TemplateCreateCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c TemplateCreateCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	var sessionCfg model.SessionConfig
+	if c.configFile != "" {
+		var err error
+		sessionCfg, err = loadSessionConfig(ctx, c.configFile)
+		if err != nil {
+			return err
+		}
+	}
+	cliEnv, err := utilsenv.ParseSpecs(c.envSpecs)
+	if err != nil {
+		return fmt.Errorf("invalid --env value: %w", err)
+	}
+	if len(sessionCfg.Env) > 0 || len(cliEnv) > 0 {
+		sessionCfg.Env = utilsenv.MergeMaps(sessionCfg.Env, cliEnv)
+	}
+	if c.egressPolicy != "" {
+		sessionCfg.Egress, sessionCfg.EgressPolicyName = nil, c.egressPolicy
+	}
+
+	ports, err := parseNamedPorts(c.ports)
+	if err != nil {
+		return err
+	}
+
+	execProfile, err := parseExecProfile(c.execPaths, c.execLocale, c.execUmask, c.execUlimit, c.execShell)
+	if err != nil {
+		return err
+	}
+
+	var userData string
+	if c.userDataFile != "" {
+		data, err := os.ReadFile(c.userDataFile)
+		if err != nil {
+			return fmt.Errorf("could not read user data: %w", err)
+		}
+		userData = string(data)
+	}
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	svc, err := templatecreate.NewService(templatecreate.ServiceConfig{
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	_, err = svc.Run(ctx, templatecreate.Request{Template: model.SandboxTemplate{
+		Name:        c.name,
+		Description: c.description,
+		Engine:      c.engine,
+		Image:       c.fromImage,
+		Profile:     c.profile,
+		Resources: model.Resources{
+			VCPUs:       c.cpu,
+			MemoryMB:    c.mem,
+			DiskGB:      c.disk,
+			MaxVCPUs:    c.maxCPU,
+			MaxMemoryMB: c.maxMem,
+		},
+		UserData:         userData,
+		Ports:            ports,
+		ExecProfile:      execProfile,
+		Env:              sessionCfg.Env,
+		Egress:           sessionCfg.Egress,
+		EgressPolicyName: sessionCfg.EgressPolicyName,
+	}})
+	if err != nil {
+		return fmt.Errorf("could not create template: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	return p.PrintMessage(fmt.Sprintf("Created template %s", c.name))
+}
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/templatelist"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// TemplateListCommand lists the sandbox templates.
+type TemplateListCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+}
+
+// NewTemplateListCommand returns the template list command.
+func NewTemplateListCommand(rootCmd *RootCommand, templateCmd *TemplateCommand) *TemplateListCommand {
+	c := &TemplateListCommand{rootCmd: rootCmd}
+
+	c.Cmd = templateCmd.Cmd.Command("list", "List the sandbox templates.")
+
+	return c
+}
+
+func (c TemplateListCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c TemplateListCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	svc, err := templatelist.NewService(templatelist.ServiceConfig{
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	templates, err := svc.Run(ctx)
+	if err != nil {
+		return fmt.Errorf("could not list templates: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	if err := p.PrintTemplateList(templates); err != nil {
+		return fmt.Errorf("could not print templates: %w", err)
+	}
+
+	return nil
+}
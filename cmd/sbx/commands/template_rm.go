@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/templaterm"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// TemplateRmCommand removes a sandbox template.
+type TemplateRmCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	name string
+}
+
+// NewTemplateRmCommand returns the template rm command.
+func NewTemplateRmCommand(rootCmd *RootCommand, templateCmd *TemplateCommand) *TemplateRmCommand {
+	c := &TemplateRmCommand{rootCmd: rootCmd}
+
+	c.Cmd = templateCmd.Cmd.Command("rm", "Remove a sandbox template.")
+	c.Cmd.Arg("name", "Template name.").Required().StringVar(&c.name)
+
+	return c
+}
+
+func (c TemplateRmCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c TemplateRmCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	svc, err := templaterm.NewService(templaterm.ServiceConfig{
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	if err := svc.Run(ctx, templaterm.Request{Name: c.name}); err != nil {
+		return fmt.Errorf("could not remove template: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	return p.PrintMessage(fmt.Sprintf("Removed template %s", c.name))
+}
@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/templateget"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// TemplateShowCommand shows a sandbox template.
+type TemplateShowCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	name string
+}
+
+// NewTemplateShowCommand returns the template show command.
+func NewTemplateShowCommand(rootCmd *RootCommand, templateCmd *TemplateCommand) *TemplateShowCommand {
+	c := &TemplateShowCommand{rootCmd: rootCmd}
+
+	c.Cmd = templateCmd.Cmd.Command("show", "Show the spec of a sandbox template.")
+	c.Cmd.Arg("name", "Template name.").Required().StringVar(&c.name)
+
+	return c
+}
+
+func (c TemplateShowCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c TemplateShowCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	svc, err := templateget.NewService(templateget.ServiceConfig{
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	template, err := svc.Run(ctx, templateget.Request{Name: c.name})
+	if err != nil {
+		return fmt.Errorf("could not show template: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	if err := p.PrintTemplate(*template); err != nil {
+		return fmt.Errorf("could not print template: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"golang.org/x/term"
+
+	"github.com/slok/sbx/internal/app/top"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// topHeaderLines are the lines of the process table before the processes.
+const topHeaderLines = 5
+
+// TopCommand shows the processes running in a sandbox guest.
+type TopCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	nameOrID string
+	interval time.Duration
+	once     bool
+	limit    int
+}
+
+// NewTopCommand returns the top command.
+func NewTopCommand(rootCmd *RootCommand, app *kingpin.Application) *TopCommand {
+	c := &TopCommand{rootCmd: rootCmd}
+
+	c.Cmd = app.Command("top", "Show the processes running in a sandbox with their CPU and memory usage, refreshed until Ctrl+C.")
+	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+	c.Cmd.Flag("interval", "Time between the refreshes.").Default("2s").DurationVar(&c.interval)
+	c.Cmd.Flag("once", "Print the processes once instead of refreshing them (the default without a terminal or with structured output).").BoolVar(&c.once)
+	c.Cmd.Flag("limit", "Maximum number of processes shown, the highest CPU usage first (0 fits the terminal when refreshing, all of them otherwise).").Short('n').IntVar(&c.limit)
+
+	return c
+}
+
+func (c TopCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c TopCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	if c.interval <= 0 {
+		return fmt.Errorf("interval must be greater than 0: %w", model.ErrNotValid)
+	}
+	if c.limit < 0 {
+		return fmt.Errorf("limit must be 0 or greater: %w", model.ErrNotValid)
+	}
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	// Get sandbox to determine which engine to use.
+	sandbox, err := repo.GetSandboxByName(ctx, c.nameOrID)
+	if err != nil {
+		// Try by ID if name lookup failed
+		sandbox, err = repo.GetSandbox(ctx, c.nameOrID)
+		if err != nil {
+			return fmt.Errorf("could not find sandbox: %w", err)
+		}
+	}
+
+	eng, err := newEngineFromConfig(sandbox.Config, repo, c.rootCmd.VMsDir, logger)
+	if err != nil {
+		return fmt.Errorf("could not create engine: %w", err)
+	}
+
+	svc, err := top.NewService(top.ServiceConfig{
+		Engine:     eng,
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	out, ok := c.rootCmd.Stdout.(*os.File)
+	if c.once || !ok || !term.IsTerminal(int(out.Fd())) || c.rootCmd.structuredOutput("") {
+		table, err := svc.Run(ctx, top.Request{NameOrID: sandbox.ID})
+		if err != nil {
+			return fmt.Errorf("could not read sandbox processes: %w", err)
+		}
+		table.Processes = limitProcesses(table.Processes, c.limit)
+		if err := p.PrintProcessTable(*table); err != nil {
+			return fmt.Errorf("could not print processes: %w", err)
+		}
+		return nil
+	}
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	var prev *model.ProcessTable
+	for {
+		table, err := svc.Run(ctx, top.Request{NameOrID: sandbox.ID, Previous: prev})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("could not read sandbox processes: %w", err)
+		}
+		prev = table
+
+		limit := c.limit
+		if _, height, err := term.GetSize(int(out.Fd())); limit == 0 && err == nil {
+			limit = max(height-topHeaderLines-1, 1)
+		}
+		view := *table
+		view.Processes = limitProcesses(table.Processes, limit)
+
+		// The view is rendered before clearing the screen to avoid flickering.
+		var buf bytes.Buffer
+		buf.WriteString("\033[H\033[2J")
+		if err := newPrinter(OutputFormatTable, &buf).PrintProcessTable(view); err != nil {
+			return fmt.Errorf("could not print processes: %w", err)
+		}
+		if _, err := buf.WriteTo(out); err != nil {
+			return fmt.Errorf("could not print processes: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// limitProcesses returns the first processes up to a limit, all of them without one.
+func limitProcesses(ps []model.Process, limit int) []model.Process {
+	if limit > 0 && len(ps) > limit {
+		return ps[:limit]
+	}
+	return ps
+}
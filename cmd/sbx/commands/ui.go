@@ -0,0 +1,797 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/alecthomas/kingpin/v2"
+	"golang.org/x/term"
+
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/printer"
+	"github.com/slok/sbx/pkg/lib"
+)
+
+const (
+	// uiDenials is the number of recent egress denials shown of the selected sandbox.
+	uiDenials = 5
+	// uiProcesses is the number of processes shown of the selected sandbox.
+	uiProcesses = 3
+	// uiInputPoll is how long the input reader waits for keys before checking if
+	// it's paused (e.g. while a shell owns the terminal).
+	uiInputPoll = 100 * time.Millisecond
+)
+
+// UICommand shows a terminal dashboard of the sandboxes.
+type UICommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	interval time.Duration
+}
+
+// NewUICommand returns the ui command.
+func NewUICommand(rootCmd *RootCommand, app *kingpin.Application) *UICommand {
+	c := &UICommand{rootCmd: rootCmd}
+
+	c.Cmd = app.Command("ui", "Open a terminal dashboard of the sandboxes with their usage, egress denials and logs, to start, stop, shell into and forward them.")
+	c.Cmd.Flag("interval", "Time between the refreshes of the selected sandbox usage, denials and logs.").Default("2s").DurationVar(&c.interval)
+
+	return c
+}
+
+func (c UICommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c UICommand) Run(ctx context.Context) error {
+	if c.interval <= 0 {
+		return fmt.Errorf("interval must be greater than 0: %w", model.ErrNotValid)
+	}
+
+	in, inOK := c.rootCmd.Stdin.(*os.File)
+	out, outOK := c.rootCmd.Stdout.(*os.File)
+	if !inOK || !outOK || !term.IsTerminal(int(in.Fd())) || !term.IsTerminal(int(out.Fd())) {
+		return fmt.Errorf("the dashboard requires a terminal: %w", model.ErrNotValid)
+	}
+
+	// The client logs would break the dashboard, the errors are shown in it.
+	client, err := lib.New(ctx, lib.Config{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		VMsDir:    c.rootCmd.VMsDir,
+		HooksDir:  c.rootCmd.HooksDir,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create client: %w", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	updates, err := client.WatchSandboxes(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not watch sandboxes: %w", err)
+	}
+
+	d := newDashboard(ctx, client, in, out, c.interval)
+	return d.run(ctx, updates)
+}
+
+// uiDetails are the details of the selected sandbox, refreshed periodically.
+type uiDetails struct {
+	sandboxID string
+	processes *lib.ProcessTable
+	denials   []lib.DNSEvent
+	logs      []lib.SandboxLog
+	err       error
+}
+
+// uiPrompt is a line typed in the dashboard footer, e.g. the ports to forward.
+type uiPrompt struct {
+	label  string
+	input  string
+	submit func(input string)
+}
+
+// dashboard is the state of the terminal dashboard. The state is owned by the run
+// loop, the background operations (actions, forwards, details) report to it with
+// channels.
+type dashboard struct {
+	ctx      context.Context
+	client   *lib.Client
+	in       *os.File
+	out      *os.File
+	interval time.Duration
+
+	sandboxes []lib.Sandbox
+	selected  string
+	offset    int
+	details   uiDetails
+	prompt    *uiPrompt
+	message   string
+
+	keys     chan string
+	notices  chan string
+	detailsC chan uiDetails
+	loading  bool
+
+	// inputMu is held by the input reader while it reads, the shell takes it to
+	// pause the reader and own the terminal input.
+	inputMu     sync.Mutex
+	inputPaused bool
+
+	forwardsMu sync.Mutex
+	forwards   map[string]*uiForward
+	wg         sync.WaitGroup
+}
+
+// uiForward is a port forward running in the background.
+type uiForward struct {
+	cancel context.CancelFunc
+	ports  []lib.PortMapping
+}
+
+func newDashboard(ctx context.Context, client *lib.Client, in, out *os.File, interval time.Duration) *dashboard {
+	return &dashboard{
+		ctx:      ctx,
+		client:   client,
+		in:       in,
+		out:      out,
+		interval: interval,
+		keys:     make(chan string, 16),
+		notices:  make(chan string, 16),
+		detailsC: make(chan uiDetails, 1),
+		forwards: map[string]*uiForward{},
+	}
+}
+
+func (d *dashboard) run(ctx context.Context, updates <-chan []lib.Sandbox) error {
+	fd := int(d.in.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("could not set terminal raw mode: %w", err)
+	}
+	// Alternate screen without cursor, restored on exit.
+	fmt.Fprint(d.out, "\033[?1049h\033[?25l")
+	defer func() {
+		fmt.Fprint(d.out, "\033[?25h\033[?1049l")
+		_ = term.Restore(fd, state)
+	}()
+
+	go d.readInput(ctx)
+	defer d.stopForwards()
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	resize := time.NewTicker(500 * time.Millisecond)
+	defer resize.Stop()
+	width, height := d.size()
+
+	for {
+		d.render()
+
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case sandboxes, ok := <-updates:
+			if !ok {
+				return nil
+			}
+			prev := d.selected
+			d.setSandboxes(sandboxes)
+			if d.selected != prev {
+				d.refreshDetails()
+			}
+
+		case det := <-d.detailsC:
+			d.loading = false
+			if det.sandboxID == d.selected {
+				d.details = det
+			}
+
+		case msg := <-d.notices:
+			d.message = msg
+
+		case <-ticker.C:
+			d.refreshDetails()
+
+		case <-resize.C:
+			// The terminal size is polled, only rendered again when it changes.
+			w, h := d.size()
+			if w == width && h == height {
+				continue
+			}
+			width, height = w, h
+
+		case key := <-d.keys:
+			quit, err := d.handleKey(key, state)
+			if err != nil {
+				return err
+			}
+			if quit {
+				return nil
+			}
+		}
+	}
+}
+
+// readInput sends the terminal keys until the context is done. It only reads while
+// not paused, so a shell can own the terminal input.
+func (d *dashboard) readInput(ctx context.Context) {
+	fd := int(d.in.Fd())
+	buf := make([]byte, 64)
+	for ctx.Err() == nil {
+		d.inputMu.Lock()
+		if d.inputPaused {
+			d.inputMu.Unlock()
+			time.Sleep(uiInputPoll)
+			continue
+		}
+
+		ready, err := waitInput(fd, uiInputPoll)
+		var n int
+		if err == nil && ready {
+			n, err = d.in.Read(buf)
+		}
+		d.inputMu.Unlock()
+		if err != nil {
+			return
+		}
+
+		for _, key := range decodeKeys(buf[:n]) {
+			select {
+			case d.keys <- key:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// decodeKeys splits the terminal input into keys, the arrow keys are named "up",
+// "down", "left" and "right", a lone escape is "esc".
+func decodeKeys(data []byte) []string {
+	arrows := map[byte]string{'A': "up", 'B': "down", 'C': "right", 'D': "left"}
+
+	var keys []string
+	for len(data) > 0 {
+		switch {
+		case len(data) >= 3 && data[0] == 0x1b && (data[1] == '[' || data[1] == 'O'):
+			if name, ok := arrows[data[2]]; ok {
+				keys = append(keys, name)
+			}
+			data = data[3:]
+		case data[0] == 0x1b:
+			keys = append(keys, "esc")
+			data = data[1:]
+		case data[0] == '\r' || data[0] == '\n':
+			keys = append(keys, "enter")
+			data = data[1:]
+		case data[0] == 0x7f || data[0] == 0x08:
+			keys = append(keys, "backspace")
+			data = data[1:]
+		case data[0] == 0x03:
+			keys = append(keys, "ctrl+c")
+			data = data[1:]
+		default:
+			r, size := utf8.DecodeRune(data)
+			keys = append(keys, string(r))
+			data = data[size:]
+		}
+	}
+	return keys
+}
+
+// handleKey runs the action of a key, it returns true when the dashboard should quit.
+func (d *dashboard) handleKey(key string, state *term.State) (bool, error) {
+	if key == "ctrl+c" {
+		return true, nil
+	}
+
+	if d.prompt != nil {
+		switch key {
+		case "esc":
+			d.prompt = nil
+		case "enter":
+			p := d.prompt
+			d.prompt = nil
+			p.submit(p.input)
+		case "backspace":
+			if d.prompt.input != "" {
+				_, size := utf8.DecodeLastRuneInString(d.prompt.input)
+				d.prompt.input = d.prompt.input[:len(d.prompt.input)-size]
+			}
+		default:
+			if utf8.RuneCountInString(key) == 1 {
+				d.prompt.input += key
+			}
+		}
+		return false, nil
+	}
+
+	switch key {
+	case "q":
+		return true, nil
+	case "up", "k":
+		d.move(-1)
+	case "down", "j":
+		d.move(1)
+	case "r":
+		d.refreshDetails()
+	case "s":
+		d.startSelected()
+	case "x":
+		d.stopSelected()
+	case "e":
+		return false, d.shellSelected(state)
+	case "f":
+		d.promptForward()
+	case "F":
+		d.stopForward(d.selected)
+	}
+	return false, nil
+}
+
+// setSandboxes replaces the listed sandboxes keeping the selected one when it still
+// exists.
+func (d *dashboard) setSandboxes(sandboxes []lib.Sandbox) {
+	d.sandboxes = sandboxes
+	if len(sandboxes) == 0 {
+		d.selected = ""
+		d.details = uiDetails{}
+		return
+	}
+	if slices.IndexFunc(sandboxes, func(sb lib.Sandbox) bool { return sb.ID == d.selected }) < 0 {
+		d.selected = sandboxes[0].ID
+	}
+}
+
+func (d *dashboard) move(delta int) {
+	if len(d.sandboxes) == 0 {
+		return
+	}
+	i := slices.IndexFunc(d.sandboxes, func(sb lib.Sandbox) bool { return sb.ID == d.selected })
+	i = min(max(i+delta, 0), len(d.sandboxes)-1)
+	if d.sandboxes[i].ID != d.selected {
+		d.selected = d.sandboxes[i].ID
+		d.details = uiDetails{}
+		d.refreshDetails()
+	}
+}
+
+func (d *dashboard) selectedSandbox() (lib.Sandbox, bool) {
+	i := slices.IndexFunc(d.sandboxes, func(sb lib.Sandbox) bool { return sb.ID == d.selected })
+	if i < 0 {
+		return lib.Sandbox{}, false
+	}
+	return d.sandboxes[i], true
+}
+
+// refreshDetails reads the details of the selected sandbox in the background, unless
+// they are already being read.
+func (d *dashboard) refreshDetails() {
+	sb, ok := d.selectedSandbox()
+	if !ok || d.loading {
+		return
+	}
+	d.loading = true
+
+	go func() {
+		ctx, cancel := context.WithTimeout(d.ctx, d.interval+5*time.Second)
+		defer cancel()
+
+		det := uiDetails{sandboxID: sb.ID}
+		var errs []error
+		if sb.Status == lib.SandboxStatusRunning {
+			det.processes, det.err = d.client.Top(ctx, sb.ID)
+			if det.err != nil {
+				errs = append(errs, fmt.Errorf("usage: %w", det.err))
+			}
+		}
+
+		events, err := d.client.DNSEvents(ctx, sb.ID, nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("denials: %w", err))
+		}
+		for _, ev := range events {
+			if ev.Action == lib.EgressActionDeny {
+				det.denials = append(det.denials, ev)
+			}
+		}
+		if len(det.denials) > uiDenials {
+			det.denials = det.denials[len(det.denials)-uiDenials:]
+		}
+
+		det.logs, err = d.client.Logs(ctx, sb.ID, &lib.LogsOpts{Lines: 20})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("logs: %w", err))
+		}
+		det.err = errors.Join(errs...)
+
+		select {
+		case d.detailsC <- det:
+		case <-d.ctx.Done():
+		}
+	}()
+}
+
+// notify shows a message in the footer from a background operation.
+func (d *dashboard) notify(format string, args ...any) {
+	select {
+	case d.notices <- fmt.Sprintf(format, args...):
+	case <-d.ctx.Done():
+	}
+}
+
+func (d *dashboard) startSelected() {
+	sb, ok := d.selectedSandbox()
+	if !ok {
+		return
+	}
+	d.message = fmt.Sprintf("Starting %s...", sb.Name)
+	go func() {
+		if _, err := d.client.StartSandbox(d.ctx, sb.ID, nil); err != nil {
+			d.notify("Could not start %s: %s", sb.Name, err)
+			return
+		}
+		d.notify("Started %s", sb.Name)
+	}()
+}
+
+func (d *dashboard) stopSelected() {
+	sb, ok := d.selectedSandbox()
+	if !ok {
+		return
+	}
+	d.message = fmt.Sprintf("Stopping %s...", sb.Name)
+	go func() {
+		if _, err := d.client.StopSandbox(d.ctx, sb.ID, nil); err != nil {
+			d.notify("Could not stop %s: %s", sb.Name, err)
+			return
+		}
+		d.notify("Stopped %s", sb.Name)
+	}()
+}
+
+// shellSelected opens a shell in the selected sandbox with the terminal, the
+// dashboard is shown again when the shell exits.
+func (d *dashboard) shellSelected(state *term.State) error {
+	sb, ok := d.selectedSandbox()
+	if !ok {
+		return nil
+	}
+	if sb.Status != lib.SandboxStatusRunning {
+		d.message = fmt.Sprintf("%s is not running", sb.Name)
+		return nil
+	}
+
+	d.inputMu.Lock()
+	d.inputPaused = true
+	d.inputMu.Unlock()
+	defer func() {
+		d.inputMu.Lock()
+		d.inputPaused = false
+		d.inputMu.Unlock()
+	}()
+
+	fd := int(d.in.Fd())
+	fmt.Fprint(d.out, "\033[?25h\033[?1049l")
+	if err := term.Restore(fd, state); err != nil {
+		return fmt.Errorf("could not restore terminal: %w", err)
+	}
+
+	shell := "/bin/sh"
+	if p := sb.Config.ExecProfile; p != nil && p.Shell != "" {
+		shell = p.Shell
+	}
+	res, err := d.client.Exec(d.ctx, sb.ID, []string{shell}, &lib.ExecOpts{
+		Stdin:  d.in,
+		Stdout: d.out,
+		Stderr: d.out,
+		Tty:    true,
+		Caller: "ui",
+	})
+	switch {
+	case err != nil:
+		d.message = fmt.Sprintf("Could not open a shell in %s: %s", sb.Name, err)
+	case res.ExitCode != 0:
+		d.message = fmt.Sprintf("Shell of %s exited with code %d", sb.Name, res.ExitCode)
+	default:
+		d.message = fmt.Sprintf("Shell of %s closed", sb.Name)
+	}
+
+	if _, err := term.MakeRaw(fd); err != nil {
+		return fmt.Errorf("could not set terminal raw mode: %w", err)
+	}
+	fmt.Fprint(d.out, "\033[?1049h\033[?25l")
+	return nil
+}
+
+// promptForward asks for the ports to forward of the selected sandbox.
+func (d *dashboard) promptForward() {
+	sb, ok := d.selectedSandbox()
+	if !ok {
+		return
+	}
+	if sb.Status != lib.SandboxStatusRunning {
+		d.message = fmt.Sprintf("%s is not running", sb.Name)
+		return
+	}
+
+	d.prompt = &uiPrompt{
+		label: fmt.Sprintf("Forward ports of %s (e.g. 8080:80 web): ", sb.Name),
+		submit: func(input string) {
+			var ports []lib.PortMapping
+			for _, p := range strings.Fields(input) {
+				pm, err := model.ParseNamedPortMapping(p, sb.Config.Ports)
+				if err != nil {
+					d.message = fmt.Sprintf("Invalid port mapping %q: %s", p, err)
+					return
+				}
+				ports = append(ports, lib.PortMapping{LocalPort: pm.LocalPort, RemotePort: pm.RemotePort})
+			}
+			if len(ports) > 0 {
+				d.startForward(sb, ports)
+			}
+		},
+	}
+}
+
+// startForward forwards the ports of a sandbox in the background until stopped or the
+// dashboard exits, replacing its active forward.
+func (d *dashboard) startForward(sb lib.Sandbox, ports []lib.PortMapping) {
+	d.stopForward(sb.ID)
+
+	ctx, cancel := context.WithCancel(d.ctx)
+	fwd := &uiForward{cancel: cancel, ports: ports}
+	d.forwardsMu.Lock()
+	d.forwards[sb.ID] = fwd
+	d.forwardsMu.Unlock()
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		defer cancel()
+
+		err := d.client.ForwardWithInfo(ctx, sb.ID, ports, func(info lib.ForwardInfo) {
+			d.forwardsMu.Lock()
+			fwd.ports = info.Ports
+			d.forwardsMu.Unlock()
+			d.notify("Forwarding %s", formatForwardPorts(info.Ports))
+		})
+
+		d.forwardsMu.Lock()
+		if d.forwards[sb.ID] == fwd {
+			delete(d.forwards, sb.ID)
+		}
+		d.forwardsMu.Unlock()
+
+		if err != nil && !errors.Is(err, context.Canceled) {
+			d.notify("Forward of %s stopped: %s", sb.Name, err)
+		}
+	}()
+}
+
+func (d *dashboard) stopForward(id string) {
+	d.forwardsMu.Lock()
+	fwd, ok := d.forwards[id]
+	delete(d.forwards, id)
+	d.forwardsMu.Unlock()
+	if ok {
+		fwd.cancel()
+	}
+}
+
+// stopForwards stops all the forwards and waits for them.
+func (d *dashboard) stopForwards() {
+	d.forwardsMu.Lock()
+	for _, fwd := range d.forwards {
+		fwd.cancel()
+	}
+	d.forwardsMu.Unlock()
+	d.wg.Wait()
+}
+
+func (d *dashboard) forwardPorts(id string) string {
+	d.forwardsMu.Lock()
+	defer d.forwardsMu.Unlock()
+	fwd, ok := d.forwards[id]
+	if !ok {
+		return ""
+	}
+	return formatForwardPorts(fwd.ports)
+}
+
+func formatForwardPorts(ports []lib.PortMapping) string {
+	fs := make([]string, 0, len(ports))
+	for _, p := range ports {
+		fs = append(fs, fmt.Sprintf("%d->%d", p.LocalPort, p.RemotePort))
+	}
+	return strings.Join(fs, ",")
+}
+
+func (d *dashboard) size() (int, int) {
+	w, h, err := term.GetSize(int(d.out.Fd()))
+	if err != nil {
+		return 80, 24
+	}
+	return w, h
+}
+
+// render draws the dashboard: the sandboxes table, the details of the selected
+// sandbox and the footer with the keys, the message or the prompt.
+func (d *dashboard) render() {
+	width, height := d.size()
+	var lines []string
+	add := func(format string, args ...any) { lines = append(lines, fmt.Sprintf(format, args...)) }
+	section := func(title string) {
+		add("\033[1m── %s %s\033[0m", title, strings.Repeat("─", max(width-len(title)-4, 0)))
+	}
+
+	running := 0
+	for _, sb := range d.sandboxes {
+		if sb.Status == lib.SandboxStatusRunning {
+			running++
+		}
+	}
+	add("\033[1msbx\033[0m  namespace: %s  sandboxes: %d (%d running)", d.client.Namespace(), len(d.sandboxes), running)
+
+	// The sandboxes table uses a third of the screen, scrolled to the selected one.
+	rows := max(min(len(d.sandboxes), height/3), 1)
+	selected := slices.IndexFunc(d.sandboxes, func(sb lib.Sandbox) bool { return sb.ID == d.selected })
+	if selected < d.offset {
+		d.offset = selected
+	}
+	if selected >= d.offset+rows {
+		d.offset = selected - rows + 1
+	}
+	d.offset = max(min(d.offset, len(d.sandboxes)-rows), 0)
+
+	add("\033[1m  %-24s %-12s %-6s %-10s %-16s %s\033[0m", "NAME", "STATUS", "VCPUS", "MEMORY", "CREATED", "FORWARDS")
+	if len(d.sandboxes) == 0 {
+		add("  No sandboxes, create one with 'sbx create'.")
+	}
+	for i := d.offset; i < min(d.offset+rows, len(d.sandboxes)); i++ {
+		sb := d.sandboxes[i]
+		row := fmt.Sprintf("  %-24s %s%-12s\033[0m %-6g %-10s %-16s %s",
+			truncate(sb.Name, 24), statusColor(sb.Status), sb.Status,
+			sb.Config.Resources.VCPUs, fmt.Sprintf("%d MB", sb.Config.Resources.MemoryMB),
+			printer.TimeAgo(sb.CreatedAt), d.forwardPorts(sb.ID))
+		if sb.ID == d.selected {
+			row = "\033[7m>" + row[1:] + "\033[0m"
+		}
+		lines = append(lines, row)
+	}
+
+	if sb, ok := d.selectedSandbox(); ok {
+		add("")
+		section(sb.Name)
+		d.renderUsage(sb, add)
+		section("Egress denials")
+		if len(d.details.denials) == 0 {
+			add("  None")
+		}
+		for _, ev := range d.details.denials {
+			add("  %s  %-6s %s (%s)", ev.Time.Local().Format("15:04:05"), ev.QType, ev.Domain, ev.Reason)
+		}
+		section("Logs")
+		// The logs use the rest of the screen, the latest lines of all of them.
+		var logLines []string
+		for _, l := range d.details.logs {
+			for _, line := range l.Lines {
+				logLines = append(logLines, fmt.Sprintf("  [%s] %s", l.Source, line))
+			}
+		}
+		free := max(height-len(lines)-1, 0)
+		if len(logLines) > free {
+			logLines = logLines[len(logLines)-free:]
+		}
+		lines = append(lines, logLines...)
+	}
+
+	// The footer is on the last line.
+	for len(lines) < height-1 {
+		lines = append(lines, "")
+	}
+	lines = lines[:max(height-1, 0)]
+	footer := "\033[7m s \033[0m start  \033[7m x \033[0m stop  \033[7m e \033[0m shell  \033[7m f \033[0m forward  \033[7m F \033[0m stop forward  \033[7m r \033[0m refresh  \033[7m q \033[0m quit  " + d.message
+	if d.prompt != nil {
+		footer = d.prompt.label + d.prompt.input + "\033[7m \033[0m"
+	}
+	lines = append(lines, footer)
+
+	var buf strings.Builder
+	buf.WriteString("\033[H")
+	for i, line := range lines {
+		if i > 0 {
+			buf.WriteString("\r\n")
+		}
+		buf.WriteString(fitLine(line, width))
+		buf.WriteString("\033[K")
+	}
+	buf.WriteString("\033[J")
+	fmt.Fprint(d.out, buf.String())
+}
+
+// renderUsage adds the resource usage lines of the selected sandbox.
+func (d *dashboard) renderUsage(sb lib.Sandbox, add func(format string, args ...any)) {
+	if d.details.err != nil {
+		add("  \033[31m%s\033[0m", d.details.err)
+	}
+	t := d.details.processes
+	if sb.Status != lib.SandboxStatusRunning || t == nil {
+		add("  Status: %s", sb.Status)
+		return
+	}
+
+	used := t.MemoryTotalBytes - t.MemoryAvailableBytes
+	memPct := 0.0
+	if t.MemoryTotalBytes > 0 {
+		memPct = float64(used) / float64(t.MemoryTotalBytes) * 100
+	}
+	add("  Uptime: %s  Load: %.2f %.2f %.2f  Memory: %s / %s (%.0f%%)  Processes: %d",
+		t.Uptime.Truncate(time.Second), t.Load1, t.Load5, t.Load15,
+		printer.FormatBytes(int64(used)), printer.FormatBytes(int64(t.MemoryTotalBytes)), memPct, len(t.Processes))
+	for _, p := range t.Processes[:min(len(t.Processes), uiProcesses)] {
+		add("  %7d %-10s %5.1f%% CPU %8s  %s", p.PID, truncate(p.User, 10), p.CPUPercent, printer.FormatBytes(int64(p.RSSBytes)), p.Command)
+	}
+}
+
+func statusColor(s lib.SandboxStatus) string {
+	switch s {
+	case lib.SandboxStatusRunning:
+		return "\033[32m"
+	case lib.SandboxStatusPending:
+		return "\033[33m"
+	case lib.SandboxStatusFailed:
+		return "\033[31m"
+	}
+	return ""
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:n-1]) + "…"
+}
+
+// fitLine cuts a line with escape sequences to the terminal width, the escape
+// sequences don't use columns.
+func fitLine(line string, width int) string {
+	var b strings.Builder
+	cols := 0
+	for i := 0; i < len(line); {
+		if line[i] == 0x1b {
+			end := strings.IndexAny(line[i:], "mKJHh")
+			if end < 0 {
+				break
+			}
+			b.WriteString(line[i : i+end+1])
+			i += end + 1
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(line[i:])
+		if r == '\t' {
+			r = ' '
+		}
+		if cols < width {
+			b.WriteRune(r)
+		}
+		cols++
+		i += size
+	}
+	if cols > width {
+		b.WriteString("\033[0m")
+	}
+	return b.String()
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package commands
+
+import "time"
+
+// waitInput can't wait for the console input on Windows, the reads block until a
+// key is pressed.
+func waitInput(fd int, timeout time.Duration) (bool, error) {
+	return true, nil
+}
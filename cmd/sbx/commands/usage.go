@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/usage"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/printer"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// UsageCommand reports the resources used by the sandboxes.
+type UsageCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	since       string
+	until       string
+	namePattern string
+	annotations []string
+	groupBy     string
+	csv         bool
+}
+
+// NewUsageCommand returns the usage command.
+func NewUsageCommand(rootCmd *RootCommand, app *kingpin.Application) *UsageCommand {
+	c := &UsageCommand{rootCmd: rootCmd}
+
+	c.Cmd = app.Command("usage", "Report the CPU, memory and disk used by the sandboxes, including the removed ones.")
+	c.Cmd.Flag("since", "Start of the report period, a duration ago (e.g. 720h) or a time (RFC3339 or YYYY-MM-DD). Defaults to the first record.").StringVar(&c.since)
+	c.Cmd.Flag("until", "End of the report period, a duration ago (e.g. 24h) or a time (RFC3339 or YYYY-MM-DD). Defaults to now.").StringVar(&c.until)
+	c.Cmd.Flag("name", "Only report the sandboxes whose name matches the pattern (glob, or regex with re: prefix).").StringVar(&c.namePattern)
+	c.Cmd.Flag("annotation", "Only report the sandboxes with this annotation (KEY=VALUE, repeatable).").StringsVar(&c.annotations)
+	c.Cmd.Flag("group-by", "Group the sandboxes by the value of this annotation key.").StringVar(&c.groupBy)
+	c.Cmd.Flag("csv", "Print the report entries in CSV format, takes precedence over --output.").BoolVar(&c.csv)
+
+	return c
+}
+
+func (c UsageCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c UsageCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	now := time.Now()
+	since, err := parseReportTime(c.since, now)
+	if err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	until, err := parseReportTime(c.until, now)
+	if err != nil {
+		return fmt.Errorf("invalid --until: %w", err)
+	}
+
+	req := usage.Request{
+		Since:       since,
+		Until:       until,
+		NamePattern: c.namePattern,
+		GroupBy:     c.groupBy,
+	}
+	for _, a := range c.annotations {
+		k, v, ok := strings.Cut(a, "=")
+		if !ok {
+			return fmt.Errorf("invalid annotation %q, use KEY=VALUE: %w", a, model.ErrNotValid)
+		}
+		if req.Annotations == nil {
+			req.Annotations = map[string]string{}
+		}
+		req.Annotations[k] = v
+	}
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	svc, err := usage.NewService(usage.ServiceConfig{
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	report, err := svc.Run(ctx, req)
+	if err != nil {
+		return fmt.Errorf("could not get usage report: %w", err)
+	}
+
+	if c.csv {
+		err = printer.PrintUsageReportCSV(c.rootCmd.Stdout, *report)
+	} else {
+		err = newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout).PrintUsageReport(*report)
+	}
+	if err != nil {
+		return fmt.Errorf("could not print usage report: %w", err)
+	}
+
+	return nil
+}
+
+// parseReportTime parses a report time flag: a duration before now, an RFC3339
+// time or a UTC date. An empty value is the zero time.
+func parseReportTime(v string, now time.Time) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	if d, err := time.ParseDuration(v); err == nil {
+		if d < 0 {
+			return time.Time{}, fmt.Errorf("duration can't be negative: %w", model.ErrNotValid)
+		}
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("%q is not a duration, an RFC3339 time or a YYYY-MM-DD date: %w", v, model.ErrNotValid)
+}
@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/integrityverify"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+// VerifyCommand verifies the guest files of a sandbox against its integrity baseline.
+type VerifyCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	nameOrID     string
+	failOnChange bool
+}
+
+// NewVerifyCommand returns the verify command.
+func NewVerifyCommand(rootCmd *RootCommand, app *kingpin.Application) *VerifyCommand {
+	c := &VerifyCommand{rootCmd: rootCmd}
+
+	c.Cmd = app.Command("verify", "Report the sandbox files modified, added or removed since the integrity baseline captured on start (see 'sbx start --integrity-path').")
+	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+	c.Cmd.Flag("fail-on-change", "Exit with an error when a file changed.").BoolVar(&c.failOnChange)
+
+	return c
+}
+
+func (c VerifyCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c VerifyCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	// Get sandbox to determine which engine to use.
+	sandbox, err := repo.GetSandboxByName(ctx, c.nameOrID)
+	if err != nil {
+		// Try by ID if name lookup failed
+		sandbox, err = repo.GetSandbox(ctx, c.nameOrID)
+		if err != nil {
+			return fmt.Errorf("could not find sandbox: %w", err)
+		}
+	}
+
+	eng, err := newEngineFromConfig(sandbox.Config, repo, c.rootCmd.VMsDir, logger)
+	if err != nil {
+		return fmt.Errorf("could not create engine: %w", err)
+	}
+
+	svc, err := integrityverify.NewService(integrityverify.ServiceConfig{
+		Engine:     eng,
+		Repository: repo,
+		Logger:     logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	report, err := svc.Run(ctx, integrityverify.Request{NameOrID: c.nameOrID})
+	if err != nil {
+		return fmt.Errorf("could not verify sandbox integrity: %w", err)
+	}
+
+	p := newPrinter(c.rootCmd.outputFormat(""), c.rootCmd.Stdout)
+	if err := p.PrintIntegrityReport(*report); err != nil {
+		return fmt.Errorf("could not print integrity report: %w", err)
+	}
+
+	if c.failOnChange && !report.Intact() {
+		return fmt.Errorf("%d files changed since the integrity baseline: %w", len(report.Changes), model.ErrConflict)
+	}
+
+	return nil
+}
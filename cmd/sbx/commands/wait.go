@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/slok/sbx/internal/app/wait"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+type WaitCommand struct {
+	Cmd     *kingpin.CmdClause
+	rootCmd *RootCommand
+
+	nameOrID  string
+	condition string
+	timeout   time.Duration
+	interval  time.Duration
+}
+
+// NewWaitCommand returns the wait command.
+func NewWaitCommand(rootCmd *RootCommand, app *kingpin.Application) *WaitCommand {
+	c := &WaitCommand{rootCmd: rootCmd}
+
+	c.Cmd = app.Command("wait", "Wait for a sandbox to reach a condition.")
+	c.Cmd.Arg("name-or-id", "Sandbox name or ID.").Required().HintAction(sandboxNameHints(rootCmd)).StringVar(&c.nameOrID)
+	c.Cmd.Flag("for", "Condition to wait for (running, stopped, ssh-ready, healthy).").Default(string(model.WaitConditionRunning)).EnumVar(&c.condition,
+		string(model.WaitConditionRunning),
+		string(model.WaitConditionStopped),
+		string(model.WaitConditionSSHReady),
+		string(model.WaitConditionHealthy),
+	)
+	c.Cmd.Flag("timeout", "Maximum time to wait (0 waits forever).").Default("60s").DurationVar(&c.timeout)
+	c.Cmd.Flag("interval", "Interval between checks.").Default("1s").DurationVar(&c.interval)
+
+	return c
+}
+
+func (c WaitCommand) Name() string { return c.Cmd.FullCommand() }
+
+func (c WaitCommand) Run(ctx context.Context) error {
+	logger := c.rootCmd.Logger
+
+	// Initialize storage (SQLite).
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
+		DBPath:    c.rootCmd.DBPath,
+		Namespace: c.rootCmd.Namespace,
+		Logger:    logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create repository: %w", err)
+	}
+
+	// Get sandbox to determine which engine to use.
+	sandbox, err := repo.GetSandboxByName(ctx, c.nameOrID)
+	if err != nil {
+		// Try by ID if name lookup failed
+		sandbox, err = repo.GetSandbox(ctx, c.nameOrID)
+		if err != nil {
+			return fmt.Errorf("could not find sandbox: %w", err)
+		}
+	}
+
+	// Initialize engine based on sandbox configuration.
+	eng, err := newEngineFromConfig(sandbox.Config, repo, c.rootCmd.VMsDir, logger)
+	if err != nil {
+		return fmt.Errorf("could not create engine: %w", err)
+	}
+
+	// Create wait service.
+	svc, err := wait.NewService(wait.ServiceConfig{
+		Engine:       eng,
+		Repository:   repo,
+		Logger:       logger,
+		PollInterval: c.interval,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	// Execute wait.
+	sandbox, err = svc.Run(ctx, wait.Request{
+		NameOrID:  c.nameOrID,
+		Condition: model.WaitCondition(c.condition),
+		Timeout:   c.timeout,
+	})
+	if err != nil {
+		return fmt.Errorf("could not wait for sandbox: %w", err)
+	}
+
+	// Print success message.
+	if err := printSandboxResult(c.rootCmd, *sandbox, fmt.Sprintf("Sandbox %s is %s", sandbox.Name, c.condition)); err != nil {
+		return fmt.Errorf("could not print message: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/slok/sbx/internal/storage"
+)
+
+// watchDebounce is the quiet time after a stored change before rendering the view,
+// so the writes of an operation (e.g. a start) are rendered once.
+const watchDebounce = 100 * time.Millisecond
+
+// watchTerminal returns the terminal the watched views are rendered on, false when
+// the output is not a terminal or is structured.
+func (r RootCommand) watchTerminal(cmdFormat string) (*os.File, bool) {
+	out, ok := r.Stdout.(*os.File)
+	if !ok || !term.IsTerminal(int(out.Fd())) || r.structuredOutput(cmdFormat) {
+		return nil, false
+	}
+	return out, true
+}
+
+// watchView renders a view on the terminal until the context is done. It's rendered
+// again after the stored sandboxes change (including the changes of other processes)
+// and on every interval, so the relative times and runtime status stay current. The
+// lines that changed since the previous render are highlighted.
+func watchView(ctx context.Context, out io.Writer, watcher storage.ChangeWatcher, interval time.Duration, render func(w io.Writer) error) error {
+	changes, err := watcher.WatchChanges(ctx)
+	if err != nil {
+		return fmt.Errorf("could not watch sandbox changes: %w", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	debounce := time.NewTimer(watchDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	var prev map[string]bool
+	for {
+		var view bytes.Buffer
+		if err := render(&view); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		// The view is rendered before clearing the screen to avoid flickering.
+		var buf bytes.Buffer
+		buf.WriteString("\033[H\033[2J")
+		prev = highlightChangedLines(&buf, view.String(), prev)
+		if _, err := buf.WriteTo(out); err != nil {
+			return fmt.Errorf("could not print view: %w", err)
+		}
+
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case _, ok := <-changes:
+				if !ok {
+					return nil
+				}
+				debounce.Reset(watchDebounce)
+			case <-debounce.C:
+				break wait
+			case <-ticker.C:
+				break wait
+			}
+		}
+	}
+}
+
+// highlightChangedLines writes the lines of a view, highlighting the ones that are not
+// in the previous view lines (none on the first render). It returns the view lines.
+func highlightChangedLines(w io.Writer, view string, prev map[string]bool) map[string]bool {
+	lines := map[string]bool{}
+	for _, line := range strings.SplitAfter(view, "\n") {
+		if line == "" {
+			continue
+		}
+		text := strings.TrimSuffix(line, "\n")
+		lines[text] = true
+		if prev == nil || prev[text] || strings.TrimSpace(text) == "" {
+			io.WriteString(w, line)
+			continue
+		}
+		fmt.Fprintf(w, "\033[1;7m%s\033[0m%s", text, line[len(text):])
+	}
+	return lines
+}
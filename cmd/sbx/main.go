@@ -15,6 +15,7 @@ import (
 	"github.com/slok/sbx/cmd/sbx/commands"
 	"github.com/slok/sbx/internal/log"
 	loglogrus "github.com/slok/sbx/internal/log/logrus"
+	"github.com/slok/sbx/internal/model"
 )
 
 const (
@@ -34,64 +35,251 @@ func Run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.
 	statusCmd := commands.NewStatusCommand(rootCmd, app)
 	stopCmd := commands.NewStopCommand(rootCmd, app)
 	startCmd := commands.NewStartCommand(rootCmd, app)
+	restartCmd := commands.NewRestartCommand(rootCmd, app)
 	removeCmd := commands.NewRemoveCommand(rootCmd, app)
+	cloneCmd := commands.NewCloneCommand(rootCmd, app)
+	rebaseCmd := commands.NewRebaseCommand(rootCmd, app)
 	execCmd := commands.NewExecCommand(rootCmd, app)
+	runCmd := commands.NewRunCommand(rootCmd, app)
 	shellCmd := commands.NewShellCommand(rootCmd, app)
 	doctorCmd := commands.NewDoctorCommand(rootCmd, app)
+	supportBundleCmd := commands.NewSupportBundleCommand(rootCmd, app)
 	cpCmd := commands.NewCpCommand(rootCmd, app)
+	syncCmd := commands.NewSyncCommand(rootCmd, app)
 	forwardCmd := commands.NewForwardCommand(rootCmd, app)
+	exposeCmd := commands.NewExposeCommand(rootCmd, app)
+	waitCmd := commands.NewWaitCommand(rootCmd, app)
+	historyCmd := commands.NewHistoryCommand(rootCmd, app)
+	capacityCmd := commands.NewCapacityCommand(rootCmd, app)
+	reservationsCmd := commands.NewReservationsCommand(rootCmd, app)
+	memoryCmd := commands.NewMemoryCommand(rootCmd, app)
+	scaleCmd := commands.NewScaleCommand(rootCmd, app)
+	annotateCmd := commands.NewAnnotateCommand(rootCmd, app)
+	usageCmd := commands.NewUsageCommand(rootCmd, app)
+	verifyCmd := commands.NewVerifyCommand(rootCmd, app)
+	topCmd := commands.NewTopCommand(rootCmd, app)
+	pcapCmd := commands.NewPcapCommand(rootCmd, app)
+	connectionsCmd := commands.NewConnectionsCommand(rootCmd, app)
+	quarantineCmd := commands.NewQuarantineCommand(rootCmd, app)
+	persistCmd := commands.NewPersistCommand(rootCmd, app)
+	recoverCmd := commands.NewRecoverCommand(rootCmd, app)
+	replCmd := commands.NewReplCommand(rootCmd, app)
+	uiCmd := commands.NewUICommand(rootCmd, app)
+	completionCmd := commands.NewCompletionCommand(rootCmd, app)
+	completeCmd := commands.NewCompleteCommand(rootCmd, app)
 
-	snapshotCmd := commands.NewSnapshotCommand(rootCmd, app)
+	// Snapshot subcommands share a parent command, creating is the default one.
+	snapshotCmd := commands.NewSnapshotCommand(app)
+	snapshotCreateCmd := commands.NewSnapshotCreateCommand(rootCmd, snapshotCmd)
+	snapshotDiffCmd := commands.NewSnapshotDiffCommand(rootCmd, snapshotCmd)
+	snapshotPushCmd := commands.NewSnapshotPushCommand(rootCmd, snapshotCmd)
+	snapshotFetchCmd := commands.NewSnapshotFetchCommand(rootCmd, snapshotCmd)
 	proxyCmd := commands.NewProxyCommand(rootCmd, app)
+	proxySupervisorCmd := commands.NewProxySupervisorCommand(rootCmd, app)
 
 	// Image subcommands share a parent command.
-	imgCmd := commands.NewImageCommand(app)
+	imgCmd := commands.NewImageCommand(rootCmd, app)
 	imageListCmd := commands.NewImageListCommand(rootCmd, imgCmd)
 	imagePullCmd := commands.NewImagePullCommand(rootCmd, imgCmd)
 	imageRmCmd := commands.NewImageRmCommand(rootCmd, imgCmd)
+	imagePruneCmd := commands.NewImagePruneCommand(rootCmd, imgCmd)
 	imageInspectCmd := commands.NewImageInspectCommand(rootCmd, imgCmd)
+	imageDuCmd := commands.NewImageDuCommand(rootCmd, imgCmd)
+	imageExportCmd := commands.NewImageExportCommand(rootCmd, imgCmd)
+	imageImportCmd := commands.NewImageImportCommand(rootCmd, imgCmd)
+	imageAddCmd := commands.NewImageAddCommand(rootCmd, imgCmd)
+	imageFromOCICmd := commands.NewImageFromOCICommand(rootCmd, imgCmd)
 
-	cmds := map[string]commands.Command{
-		createCmd.Name():       createCmd,
-		listCmd.Name():         listCmd,
-		statusCmd.Name():       statusCmd,
-		stopCmd.Name():         stopCmd,
-		startCmd.Name():        startCmd,
-		removeCmd.Name():       removeCmd,
-		execCmd.Name():         execCmd,
-		shellCmd.Name():        shellCmd,
-		doctorCmd.Name():       doctorCmd,
-		cpCmd.Name():           cpCmd,
-		forwardCmd.Name():      forwardCmd,
-		snapshotCmd.Name():     snapshotCmd,
-		imageListCmd.Name():    imageListCmd,
-		imagePullCmd.Name():    imagePullCmd,
-		imageRmCmd.Name():      imageRmCmd,
-		imageInspectCmd.Name(): imageInspectCmd,
-		proxyCmd.Name():        proxyCmd,
-	}
+	// Egress subcommands share a parent command.
+	egressCmd := commands.NewEgressCommand(app)
+	egressTestCmd := commands.NewEgressTestCommand(rootCmd, egressCmd)
 
-	// Parse command.
-	cmdName, err := app.Parse(args[1:])
-	if err != nil {
-		return fmt.Errorf("invalid command configuration: %w", err)
+	// Policy subcommands share a parent command.
+	policyCmd := commands.NewPolicyCommand(app)
+	policyCreateCmd := commands.NewPolicyCreateCommand(rootCmd, policyCmd)
+	policyUpdateCmd := commands.NewPolicyUpdateCommand(rootCmd, policyCmd)
+	policyListCmd := commands.NewPolicyListCommand(rootCmd, policyCmd)
+	policyRmCmd := commands.NewPolicyRmCommand(rootCmd, policyCmd)
+
+	// Template subcommands share a parent command.
+	templateCmd := commands.NewTemplateCommand(app)
+	templateCreateCmd := commands.NewTemplateCreateCommand(rootCmd, templateCmd)
+	templateListCmd := commands.NewTemplateListCommand(rootCmd, templateCmd)
+	templateShowCmd := commands.NewTemplateShowCommand(rootCmd, templateCmd)
+	templateRmCmd := commands.NewTemplateRmCommand(rootCmd, templateCmd)
+
+	// Idle subcommands share a parent command.
+	idleCmd := commands.NewIdleCommand(app)
+	idleSetCmd := commands.NewIdleSetCommand(rootCmd, idleCmd)
+	idleRmCmd := commands.NewIdleRmCommand(rootCmd, idleCmd)
+	idleSuspendCmd := commands.NewIdleSuspendCommand(rootCmd, idleCmd)
+
+	// Task subcommands share a parent command.
+	taskCmd := commands.NewTaskCommand(app)
+	taskRegisterCmd := commands.NewTaskRegisterCommand(rootCmd, taskCmd)
+	taskListCmd := commands.NewTaskListCommand(rootCmd, taskCmd)
+	taskRmCmd := commands.NewTaskRmCommand(rootCmd, taskCmd)
+
+	// Clip subcommands share a parent command.
+	clipCmd := commands.NewClipCommand(app)
+	clipPushCmd := commands.NewClipPushCommand(rootCmd, clipCmd)
+	clipPullCmd := commands.NewClipPullCommand(rootCmd, clipCmd)
+	clipDropCmd := commands.NewClipDropCommand(rootCmd, clipCmd)
+	clipPickupCmd := commands.NewClipPickupCommand(rootCmd, clipCmd)
+
+	// DB subcommands share a parent command.
+	dbCmd := commands.NewDBCommand(app)
+	dbCompactCmd := commands.NewDBCompactCommand(rootCmd, dbCmd)
+
+	// Data subcommands share a parent command.
+	dataCmd := commands.NewDataCommand(app)
+	dataMigrateCmd := commands.NewDataMigrateCommand(rootCmd, dataCmd)
+
+	// Namespace subcommands share a parent command.
+	namespaceCmd := commands.NewNamespaceCommand(app)
+	namespaceListCmd := commands.NewNamespaceListCommand(rootCmd, namespaceCmd)
+
+	// Systemd subcommands share a parent command.
+	systemdCmd := commands.NewSystemdCommand(app)
+	systemdInstallCmd := commands.NewSystemdInstallCommand(rootCmd, systemdCmd)
+	systemdUninstallCmd := commands.NewSystemdUninstallCommand(rootCmd, systemdCmd)
+
+	// DNS subcommands share a parent command.
+	dnsCmd := commands.NewDNSCommand(app)
+	dnsEventsCmd := commands.NewDNSEventsCommand(rootCmd, dnsCmd)
+	dnsStatsCmd := commands.NewDNSStatsCommand(rootCmd, dnsCmd)
+
+	cmds := map[string]commands.Command{
+		createCmd.Name():           createCmd,
+		listCmd.Name():             listCmd,
+		statusCmd.Name():           statusCmd,
+		stopCmd.Name():             stopCmd,
+		startCmd.Name():            startCmd,
+		restartCmd.Name():          restartCmd,
+		removeCmd.Name():           removeCmd,
+		cloneCmd.Name():            cloneCmd,
+		rebaseCmd.Name():           rebaseCmd,
+		execCmd.Name():             execCmd,
+		runCmd.Name():              runCmd,
+		shellCmd.Name():            shellCmd,
+		doctorCmd.Name():           doctorCmd,
+		supportBundleCmd.Name():    supportBundleCmd,
+		cpCmd.Name():               cpCmd,
+		syncCmd.Name():             syncCmd,
+		forwardCmd.Name():          forwardCmd,
+		exposeCmd.Name():           exposeCmd,
+		waitCmd.Name():             waitCmd,
+		historyCmd.Name():          historyCmd,
+		capacityCmd.Name():         capacityCmd,
+		reservationsCmd.Name():     reservationsCmd,
+		memoryCmd.Name():           memoryCmd,
+		scaleCmd.Name():            scaleCmd,
+		annotateCmd.Name():         annotateCmd,
+		usageCmd.Name():            usageCmd,
+		verifyCmd.Name():           verifyCmd,
+		topCmd.Name():              topCmd,
+		pcapCmd.Name():             pcapCmd,
+		connectionsCmd.Name():      connectionsCmd,
+		quarantineCmd.Name():       quarantineCmd,
+		persistCmd.Name():          persistCmd,
+		recoverCmd.Name():          recoverCmd,
+		replCmd.Name():             replCmd,
+		uiCmd.Name():               uiCmd,
+		completionCmd.Name():       completionCmd,
+		completeCmd.Name():         completeCmd,
+		snapshotCreateCmd.Name():   snapshotCreateCmd,
+		snapshotDiffCmd.Name():     snapshotDiffCmd,
+		snapshotPushCmd.Name():     snapshotPushCmd,
+		snapshotFetchCmd.Name():    snapshotFetchCmd,
+		imageListCmd.Name():        imageListCmd,
+		imagePullCmd.Name():        imagePullCmd,
+		imageRmCmd.Name():          imageRmCmd,
+		imagePruneCmd.Name():       imagePruneCmd,
+		imageInspectCmd.Name():     imageInspectCmd,
+		imageDuCmd.Name():          imageDuCmd,
+		imageExportCmd.Name():      imageExportCmd,
+		imageImportCmd.Name():      imageImportCmd,
+		imageAddCmd.Name():         imageAddCmd,
+		imageFromOCICmd.Name():     imageFromOCICmd,
+		egressTestCmd.Name():       egressTestCmd,
+		policyCreateCmd.Name():     policyCreateCmd,
+		policyUpdateCmd.Name():     policyUpdateCmd,
+		policyListCmd.Name():       policyListCmd,
+		policyRmCmd.Name():         policyRmCmd,
+		templateCreateCmd.Name():   templateCreateCmd,
+		templateListCmd.Name():     templateListCmd,
+		templateShowCmd.Name():     templateShowCmd,
+		templateRmCmd.Name():       templateRmCmd,
+		idleSetCmd.Name():          idleSetCmd,
+		idleRmCmd.Name():           idleRmCmd,
+		idleSuspendCmd.Name():      idleSuspendCmd,
+		taskRegisterCmd.Name():     taskRegisterCmd,
+		taskListCmd.Name():         taskListCmd,
+		taskRmCmd.Name():           taskRmCmd,
+		clipPushCmd.Name():         clipPushCmd,
+		clipPullCmd.Name():         clipPullCmd,
+		clipDropCmd.Name():         clipDropCmd,
+		clipPickupCmd.Name():       clipPickupCmd,
+		dbCompactCmd.Name():        dbCompactCmd,
+		dataMigrateCmd.Name():      dataMigrateCmd,
+		namespaceListCmd.Name():    namespaceListCmd,
+		systemdInstallCmd.Name():   systemdInstallCmd,
+		systemdUninstallCmd.Name(): systemdUninstallCmd,
+		dnsEventsCmd.Name():        dnsEventsCmd,
+		dnsStatsCmd.Name():         dnsStatsCmd,
+		proxyCmd.Name():            proxyCmd,
+		proxySupervisorCmd.Name():  proxySupervisorCmd,
 	}
 
 	// Set standard input/output.
 	rootCmd.Stdin = stdin
 	rootCmd.Stdout = stdout
 	rootCmd.Stderr = stderr
+	rootCmd.Version = Version
+
+	// Errors are printed here so they honor the selected output format.
+	defer func() {
+		commands.PrintError(*rootCmd, err)
+	}()
 
-	// Auto-suppress logging for commands that produce structured output (table/JSON)
-	// to prevent log noise from mixing with printer output in the terminal.
+	// Parse command.
+	cmdName, err := app.Parse(args[1:])
+	if err != nil {
+		return fmt.Errorf("invalid command configuration: %w: %w", err, model.ErrNotValid)
+	}
+
+	// Auto-suppress logging for commands that produce structured output (table/JSON),
+	// and for every command when a machine readable output is selected, to prevent log
+	// noise from mixing with printer output in the terminal.
 	// Users can still enable logging with --debug.
 	printerCommands := map[string]bool{
-		"list":          true,
-		"status":        true,
-		"image list":    true,
-		"image inspect": true,
+		"list":           true,
+		"status":         true,
+		"history":        true,
+		"capacity":       true,
+		"reservations":   true,
+		"image list":     true,
+		"image inspect":  true,
+		"image du":       true,
+		"snapshot diff":  true,
+		"verify":         true,
+		"top":            true,
+		"connections":    true,
+		"doctor":         true,
+		"egress test":    true,
+		"policy list":    true,
+		"template list":  true,
+		"template show":  true,
+		"task list":      true,
+		"namespace list": true,
+		"dns events":     true,
+		"dns stats":      true,
+		"clip pull":      true,
+		"repl":           true,
+		"completion":     true,
+		"__complete":     true,
 	}
-	if printerCommands[cmdName] && !rootCmd.Debug {
+	if (printerCommands[cmdName] || rootCmd.Output != commands.OutputFormatTable) && !rootCmd.Debug {
 		rootCmd.NoLog = true
 	}
 
@@ -124,7 +312,13 @@ func Run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.
 
 		g.Add(
 			func() error {
-				err := cmds[cmdName].Run(ctx)
+				var err error
+				// The shell completion script is the same, everything else runs on the remote host.
+				if rootCmd.Remote != "" && cmdName != completionCmd.Name() {
+					err = commands.RunRemote(ctx, *rootCmd, cmdName, args[1:])
+				} else {
+					err = cmds[cmdName].Run(ctx)
+				}
 				if err != nil {
 					return fmt.Errorf("%q command failed: %w", cmdName, err)
 				}
@@ -178,7 +372,6 @@ func main() {
 	ctx := context.Background()
 	err := Run(ctx, os.Args, os.Stdin, os.Stdout, os.Stderr)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
-		os.Exit(1)
+		os.Exit(commands.ExitCode(err))
 	}
 }
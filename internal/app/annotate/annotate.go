@@ -0,0 +1,118 @@
+package annotate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the annotate service.
+type ServiceConfig struct {
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.Annotate"})
+
+	return nil
+}
+
+// Service sets and removes the annotations of sandboxes.
+type Service struct {
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new annotate service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the annotate request parameters.
+type Request struct {
+	// NameOrID is the sandbox name or ID.
+	NameOrID string
+	// Set are the annotations to add or replace.
+	Set map[string]string
+	// Remove are the keys of the annotations to remove, missing keys are ignored.
+	Remove []string
+}
+
+// Run updates the annotations of a sandbox by name or ID, in any status, and
+// returns the sandbox with its new annotations.
+func (s *Service) Run(ctx context.Context, req Request) (*model.Sandbox, error) {
+	for _, k := range req.Remove {
+		if _, ok := req.Set[k]; ok {
+			return nil, fmt.Errorf("annotation %q can't be set and removed at the same time: %w", k, model.ErrNotValid)
+		}
+	}
+
+	// Lookup sandbox by name first, then by ID if it looks like a ULID.
+	sandbox, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
+	if errors.Is(err, model.ErrNotFound) && looksLikeULID(req.NameOrID) {
+		sandbox, err = s.repo.GetSandbox(ctx, req.NameOrID)
+	}
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("sandbox not found: %s: %w", req.NameOrID, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not get sandbox: %w", err)
+	}
+
+	annotations := maps.Clone(sandbox.Annotations)
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	maps.Copy(annotations, req.Set)
+	for _, k := range req.Remove {
+		delete(annotations, k)
+	}
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+
+	if err := model.ValidateAnnotations(annotations); err != nil {
+		return nil, fmt.Errorf("invalid annotations: %w", err)
+	}
+
+	if err := s.repo.UpdateSandboxAnnotations(ctx, sandbox.ID, annotations); err != nil {
+		return nil, fmt.Errorf("could not store annotations: %w", err)
+	}
+	sandbox.Annotations = annotations
+
+	s.logger.Infof("updated sandbox %s annotations", sandbox.Name)
+	return sandbox, nil
+}
+
+// looksLikeULID checks if a string looks like a ULID (26 characters, alphanumeric uppercase).
+func looksLikeULID(s string) bool {
+	if len(s) != 26 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,128 @@
+package annotate_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/annotate"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestService_Run(t *testing.T) {
+	sandbox := func() *model.Sandbox {
+		return &model.Sandbox{
+			ID:          "01H2QWERTYASDFGZXCVBNMLKJH",
+			Name:        "my-sandbox",
+			Status:      model.SandboxStatusRunning,
+			Annotations: map[string]string{"owner": "alice@example.com", "ticket": "https://issues.example.com/1"},
+		}
+	}
+
+	tests := map[string]struct {
+		mockRepo       func(m *storagemock.MockRepository)
+		req            annotate.Request
+		expAnnotations map[string]string
+		expErrIs       error
+		expErr         bool
+	}{
+		"setting annotations should add and replace them": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(sandbox(), nil)
+				m.On("UpdateSandboxAnnotations", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", map[string]string{
+					"owner":  "bob@example.com",
+					"ticket": "https://issues.example.com/1",
+					"exp":    "cache-warm",
+				}).Once().Return(nil)
+			},
+			req: annotate.Request{NameOrID: "my-sandbox", Set: map[string]string{"owner": "bob@example.com", "exp": "cache-warm"}},
+			expAnnotations: map[string]string{
+				"owner":  "bob@example.com",
+				"ticket": "https://issues.example.com/1",
+				"exp":    "cache-warm",
+			},
+		},
+		"removing annotations should ignore the missing keys": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(sandbox(), nil)
+				m.On("UpdateSandboxAnnotations", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", map[string]string{"owner": "alice@example.com"}).Once().Return(nil)
+			},
+			req:            annotate.Request{NameOrID: "my-sandbox", Remove: []string{"ticket", "missing"}},
+			expAnnotations: map[string]string{"owner": "alice@example.com"},
+		},
+		"removing all the annotations should leave the sandbox without annotations": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH").Once().Return(nil, model.ErrNotFound)
+				m.On("GetSandbox", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH").Once().Return(sandbox(), nil)
+				m.On("UpdateSandboxAnnotations", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", map[string]string(nil)).Once().Return(nil)
+			},
+			req: annotate.Request{NameOrID: "01H2QWERTYASDFGZXCVBNMLKJH", Remove: []string{"owner", "ticket"}},
+		},
+		"setting and removing the same annotation should fail": {
+			mockRepo: func(m *storagemock.MockRepository) {},
+			req:      annotate.Request{NameOrID: "my-sandbox", Set: map[string]string{"owner": "bob"}, Remove: []string{"owner"}},
+			expErrIs: model.ErrNotValid,
+		},
+		"an invalid annotation key should fail": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(sandbox(), nil)
+			},
+			req:      annotate.Request{NameOrID: "my-sandbox", Set: map[string]string{"my owner": "bob"}},
+			expErrIs: model.ErrNotValid,
+		},
+		"too big annotations should fail": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(sandbox(), nil)
+			},
+			req:      annotate.Request{NameOrID: "my-sandbox", Set: map[string]string{"notes": strings.Repeat("a", model.AnnotationsMaxSize)}},
+			expErrIs: model.ErrNotValid,
+		},
+		"a missing sandbox should fail": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "nonexistent").Once().Return(nil, model.ErrNotFound)
+			},
+			req:      annotate.Request{NameOrID: "nonexistent", Set: map[string]string{"owner": "bob"}},
+			expErrIs: model.ErrNotFound,
+		},
+		"a repository error should propagate": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(sandbox(), nil)
+				m.On("UpdateSandboxAnnotations", mock.Anything, mock.Anything, mock.Anything).Once().Return(fmt.Errorf("db error"))
+			},
+			req:    annotate.Request{NameOrID: "my-sandbox", Set: map[string]string{"owner": "bob"}},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mRepo := &storagemock.MockRepository{}
+			test.mockRepo(mRepo)
+
+			svc, err := annotate.NewService(annotate.ServiceConfig{Repository: mRepo})
+			require.NoError(err)
+
+			sb, err := svc.Run(context.Background(), test.req)
+
+			if test.expErr || test.expErrIs != nil {
+				assert.Error(err)
+				if test.expErrIs != nil {
+					assert.ErrorIs(err, test.expErrIs)
+				}
+			} else if assert.NoError(err) {
+				assert.Equal(test.expAnnotations, sb.Annotations)
+			}
+
+			mRepo.AssertExpectations(t)
+		})
+	}
+}
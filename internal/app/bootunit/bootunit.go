@@ -0,0 +1,111 @@
+package bootunit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
+	"github.com/slok/sbx/internal/systemd"
+)
+
+// ServiceConfig is the configuration for the boot unit service.
+type ServiceConfig struct {
+	Repository storage.Repository
+	Manager    *systemd.Manager
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Manager == nil {
+		return fmt.Errorf("manager is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.BootUnit"})
+
+	return nil
+}
+
+// Service enables and disables the systemd units that start the sandboxes on the
+// host boot.
+type Service struct {
+	repo    storage.Repository
+	manager *systemd.Manager
+	logger  log.Logger
+}
+
+// NewService creates a new boot unit service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		repo:    cfg.Repository,
+		manager: cfg.Manager,
+		logger:  cfg.Logger,
+	}, nil
+}
+
+// Request represents the boot unit request parameters.
+type Request struct {
+	// NameOrID is the sandbox name or ID.
+	NameOrID string
+	// Disable removes the unit instead of installing it.
+	Disable bool
+	// Binary is the absolute path of the sbx binary run by the unit.
+	Binary string
+	// Args are the global flags of the sbx commands run by the unit.
+	Args []string
+	// Restart is the restart policy of the unit (default on-failure).
+	Restart model.BootRestartPolicy
+}
+
+// Run installs the boot unit of a sandbox by name or ID, replacing the previous
+// one, or removes it. Removing the unit returns nil, the sandbox is not stopped.
+func (s *Service) Run(ctx context.Context, req Request) (*model.BootUnit, error) {
+	sb, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
+	if errors.Is(err, model.ErrNotFound) {
+		sb, err = s.repo.GetSandbox(ctx, req.NameOrID)
+	}
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("sandbox not found: %s: %w", req.NameOrID, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not get sandbox: %w", err)
+	}
+
+	if req.Disable {
+		if err := s.manager.Disable(ctx, sb.ID); err != nil {
+			return nil, fmt.Errorf("could not disable boot unit: %w", err)
+		}
+		s.logger.Infof("Sandbox %s boot unit removed", sb.Name)
+		return nil, nil
+	}
+
+	if req.Restart == "" {
+		req.Restart = model.BootRestartPolicyOnFailure
+	}
+
+	unit, err := s.manager.Enable(ctx, systemd.UnitSpec{
+		Sandbox: *sb,
+		Binary:  req.Binary,
+		Args:    req.Args,
+		Restart: req.Restart,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not enable boot unit: %w", err)
+	}
+	s.logger.Infof("Sandbox %s boot unit installed: %s", sb.Name, unit.Path)
+
+	return unit, nil
+}
@@ -0,0 +1,118 @@
+package bootunit_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/bootunit"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/memory"
+	"github.com/slok/sbx/internal/systemd"
+)
+
+const testSandboxID = "01H2QWERTYASDFGZXCVBNMLKJH"
+
+func TestServiceRun(t *testing.T) {
+	unitName := "sbx-" + testSandboxID + ".service"
+
+	tests := map[string]struct {
+		installed  bool
+		req        bootunit.Request
+		expUnit    *model.BootUnit
+		expRestart string
+		expErr     error
+	}{
+		"Enabling should install the unit restarted on failure by default.": {
+			req:        bootunit.Request{NameOrID: "my-sandbox", Binary: "/usr/local/bin/sbx"},
+			expUnit:    &model.BootUnit{Name: unitName, SandboxID: testSandboxID, SandboxName: "my-sandbox", Restart: model.BootRestartPolicyOnFailure},
+			expRestart: "Restart=on-failure",
+		},
+
+		"Enabling by ID with a restart policy should install the unit with it.": {
+			installed:  true,
+			req:        bootunit.Request{NameOrID: testSandboxID, Binary: "/usr/local/bin/sbx", Restart: model.BootRestartPolicyNo},
+			expUnit:    &model.BootUnit{Name: unitName, SandboxID: testSandboxID, SandboxName: "my-sandbox", Restart: model.BootRestartPolicyNo},
+			expRestart: "Restart=no",
+		},
+
+		"Disabling should remove the unit.": {
+			installed: true,
+			req:       bootunit.Request{NameOrID: "my-sandbox", Disable: true},
+		},
+
+		"Disabling without unit should do nothing.": {
+			req: bootunit.Request{NameOrID: "my-sandbox", Disable: true},
+		},
+
+		"An invalid restart policy should fail.": {
+			req:    bootunit.Request{NameOrID: "my-sandbox", Binary: "/usr/local/bin/sbx", Restart: "always"},
+			expErr: model.ErrNotValid,
+		},
+
+		"A missing sandbox should fail.": {
+			req:    bootunit.Request{NameOrID: "missing", Binary: "/usr/local/bin/sbx"},
+			expErr: model.ErrNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			ctx := context.Background()
+
+			repo, err := memory.NewRepository(memory.RepositoryConfig{})
+			require.NoError(err)
+			require.NoError(repo.CreateSandbox(ctx, model.Sandbox{
+				ID:        testSandboxID,
+				Name:      "my-sandbox",
+				Status:    model.SandboxStatusStopped,
+				CreatedAt: time.Now(),
+				Config:    model.SandboxConfig{Name: "my-sandbox", FirecrackerEngine: &model.FirecrackerEngineConfig{}},
+			}))
+
+			unitDir := t.TempDir()
+			manager, err := systemd.NewManager(systemd.ManagerConfig{
+				UnitDir:   unitDir,
+				Systemctl: func(ctx context.Context, args ...string) error { return nil },
+			})
+			require.NoError(err)
+			if test.installed {
+				_, err := manager.Enable(ctx, systemd.UnitSpec{Sandbox: model.Sandbox{ID: testSandboxID}, Binary: "/usr/local/bin/sbx", Restart: model.BootRestartPolicyOnFailure})
+				require.NoError(err)
+			}
+
+			svc, err := bootunit.NewService(bootunit.ServiceConfig{Repository: repo, Manager: manager, Logger: log.Noop})
+			require.NoError(err)
+
+			unit, err := svc.Run(ctx, test.req)
+
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+				return
+			}
+			require.NoError(err)
+
+			unitPath := filepath.Join(unitDir, unitName)
+			if test.expUnit == nil {
+				assert.Nil(unit)
+				assert.NoFileExists(unitPath)
+				return
+			}
+
+			exp := *test.expUnit
+			exp.Path = unitPath
+			assert.Equal(&exp, unit)
+			data, err := os.ReadFile(unitPath)
+			require.NoError(err)
+			assert.Contains(string(data), test.expRestart+"\n")
+		})
+	}
+}
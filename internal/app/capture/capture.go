@@ -0,0 +1,105 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the capture service.
+type ServiceConfig struct {
+	Engine     sandbox.Engine
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Engine == nil {
+		return fmt.Errorf("engine is required")
+	}
+
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.Capture"})
+
+	return nil
+}
+
+// Service captures the network traffic of the sandboxes.
+type Service struct {
+	engine sandbox.Engine
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new capture service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		engine: cfg.Engine,
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the capture request parameters.
+type Request struct {
+	// NameOrID is the sandbox name or ID.
+	NameOrID string
+	// Output receives the pcap stream.
+	Output io.Writer
+	// Opts are the capture interface, filter and limits.
+	Opts model.CaptureOpts
+}
+
+func (r Request) validate() error {
+	if r.Output == nil {
+		return fmt.Errorf("output is required: %w", model.ErrNotValid)
+	}
+	return r.Opts.Validate()
+}
+
+// Run captures the packets of a running sandbox network interface until the context
+// ends or the capture limits are reached.
+func (s *Service) Run(ctx context.Context, req Request) (*model.CaptureResult, error) {
+	if err := req.validate(); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	sb, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
+	if errors.Is(err, model.ErrNotFound) {
+		sb, err = s.repo.GetSandbox(ctx, req.NameOrID)
+	}
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("sandbox not found: %s: %w", req.NameOrID, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not get sandbox: %w", err)
+	}
+
+	if sb.Status != model.SandboxStatusRunning {
+		return nil, fmt.Errorf("sandbox %s is not running (current status: %s): %w", sb.Name, sb.Status, model.ErrNotValid)
+	}
+
+	result, err := s.engine.CaptureTraffic(ctx, sb.ID, req.Output, req.Opts)
+	if err != nil {
+		return nil, fmt.Errorf("could not capture sandbox traffic: %w", err)
+	}
+
+	s.logger.Infof("Captured %d packets of sandbox %s %s", result.Packets, sb.Name, result.Interface)
+	return result, nil
+}
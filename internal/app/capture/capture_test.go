@@ -0,0 +1,116 @@
+package capture_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/capture"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox/sandboxmock"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+const testSandboxID = "01H2QWERTYASDFGZXCVBNMLKJH"
+
+var testRunning = &model.Sandbox{
+	ID:     testSandboxID,
+	Name:   "my-sandbox",
+	Status: model.SandboxStatusRunning,
+}
+
+func TestServiceRun(t *testing.T) {
+	tests := map[string]struct {
+		mockRepo   func(m *storagemock.MockRepository)
+		mockEngine func(m *sandboxmock.MockEngine)
+		req        capture.Request
+		expResult  *model.CaptureResult
+		expErrIs   error
+		expErr     bool
+	}{
+		"Capturing should capture the sandbox traffic with the options.": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(testRunning, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				opts := model.CaptureOpts{Interface: "eth1", Filter: "tcp port 443", MaxPackets: 10}
+				m.On("CaptureTraffic", mock.Anything, testSandboxID, mock.Anything, opts).Once().Return(&model.CaptureResult{Interface: "eth1", Packets: 10, LimitReached: true}, nil)
+			},
+			req:       capture.Request{NameOrID: "my-sandbox", Opts: model.CaptureOpts{Interface: "eth1", Filter: "tcp port 443", MaxPackets: 10}},
+			expResult: &model.CaptureResult{Interface: "eth1", Packets: 10, LimitReached: true},
+		},
+		"A failing capture should fail.": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(testRunning, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("CaptureTraffic", mock.Anything, testSandboxID, mock.Anything, mock.Anything).Once().Return(nil, fmt.Errorf("something"))
+			},
+			req:    capture.Request{NameOrID: "my-sandbox"},
+			expErr: true,
+		},
+		"A filter starting with a dash should fail.": {
+			mockRepo:   func(m *storagemock.MockRepository) {},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        capture.Request{NameOrID: "my-sandbox", Opts: model.CaptureOpts{Filter: "-w /etc/passwd"}},
+			expErrIs:   model.ErrNotValid,
+		},
+		"An invalid interface should fail.": {
+			mockRepo:   func(m *storagemock.MockRepository) {},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        capture.Request{NameOrID: "my-sandbox", Opts: model.CaptureOpts{Interface: "sbx-a3f2"}},
+			expErrIs:   model.ErrNotValid,
+		},
+		"A stopped sandbox should fail.": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:     testSandboxID,
+					Name:   "my-sandbox",
+					Status: model.SandboxStatusStopped,
+				}, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        capture.Request{NameOrID: "my-sandbox"},
+			expErrIs:   model.ErrNotValid,
+		},
+		"A missing sandbox should fail.": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "missing").Once().Return(nil, model.ErrNotFound)
+				m.On("GetSandbox", mock.Anything, "missing").Once().Return(nil, model.ErrNotFound)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        capture.Request{NameOrID: "missing"},
+			expErrIs:   model.ErrNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mRepo := storagemock.NewMockRepository(t)
+			mEngine := sandboxmock.NewMockEngine(t)
+			test.mockRepo(mRepo)
+			test.mockEngine(mEngine)
+			svc, err := capture.NewService(capture.ServiceConfig{Engine: mEngine, Repository: mRepo, Logger: log.Noop})
+			require.NoError(t, err)
+
+			test.req.Output = &bytes.Buffer{}
+			result, err := svc.Run(context.TODO(), test.req)
+
+			switch {
+			case test.expErrIs != nil:
+				assert.ErrorIs(t, err, test.expErrIs)
+			case test.expErr:
+				assert.Error(t, err)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, test.expResult, result)
+			}
+		})
+	}
+}
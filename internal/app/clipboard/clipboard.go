@@ -0,0 +1,242 @@
+package clipboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/slok/sbx/internal/conventions"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the clipboard service.
+type ServiceConfig struct {
+	Engine     sandbox.Engine
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Engine == nil {
+		return fmt.Errorf("engine is required")
+	}
+
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.Clipboard"})
+
+	return nil
+}
+
+// Service moves small snippets and files between the host and running sandboxes
+// through well known sandbox locations: a clipboard file and a drop directory.
+type Service struct {
+	engine sandbox.Engine
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new clipboard service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		engine: cfg.Engine,
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// PushRequest represents the clipboard push parameters.
+type PushRequest struct {
+	// NameOrID is the sandbox name or ID.
+	NameOrID string
+	// Data is the new clipboard content.
+	Data []byte
+}
+
+// Push replaces the sandbox clipboard content.
+func (s *Service) Push(ctx context.Context, req PushRequest) error {
+	sb, err := s.runningSandbox(ctx, req.NameOrID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.ensureDir(ctx, sb.ID, path.Dir(conventions.GuestClipboardPath)); err != nil {
+		return err
+	}
+
+	if err := s.engine.WriteFile(ctx, sb.ID, conventions.GuestClipboardPath, req.Data, 0600); err != nil {
+		return fmt.Errorf("could not write clipboard: %w", err)
+	}
+
+	s.logger.Debugf("pushed %d bytes to sandbox %s clipboard", len(req.Data), sb.Name)
+	return nil
+}
+
+// Pull returns the sandbox clipboard content, empty when nothing was pushed.
+func (s *Service) Pull(ctx context.Context, nameOrID string) ([]byte, error) {
+	sb, err := s.runningSandbox(ctx, nameOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.engine.ReadFile(ctx, sb.ID, conventions.GuestClipboardPath)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return []byte{}, nil
+		}
+		return nil, fmt.Errorf("could not read clipboard: %w", err)
+	}
+
+	return data, nil
+}
+
+// DropRequest represents the drop parameters.
+type DropRequest struct {
+	// NameOrID is the sandbox name or ID.
+	NameOrID string
+	// Paths are the local files or directories dropped.
+	Paths []string
+}
+
+// Drop copies local files or directories into the sandbox drop directory, keeping
+// their names, and returns their sandbox paths.
+func (s *Service) Drop(ctx context.Context, req DropRequest) ([]string, error) {
+	if len(req.Paths) == 0 {
+		return nil, fmt.Errorf("at least one path is required: %w", model.ErrNotValid)
+	}
+	for _, p := range req.Paths {
+		if _, err := os.Stat(p); err != nil {
+			return nil, fmt.Errorf("source path does not exist: %s: %w", p, model.ErrNotValid)
+		}
+	}
+
+	sb, err := s.runningSandbox(ctx, req.NameOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.ensureDir(ctx, sb.ID, conventions.GuestDropDir); err != nil {
+		return nil, err
+	}
+
+	dropped := make([]string, 0, len(req.Paths))
+	for _, p := range req.Paths {
+		dst := path.Join(conventions.GuestDropDir, filepath.Base(filepath.Clean(p)))
+		if err := s.engine.CopyTo(ctx, sb.ID, p, dst, model.CopyOpts{}); err != nil {
+			return nil, fmt.Errorf("could not drop %s: %w", p, err)
+		}
+		dropped = append(dropped, dst)
+	}
+
+	s.logger.Debugf("dropped %d paths in sandbox %s", len(dropped), sb.Name)
+	return dropped, nil
+}
+
+// PickupRequest represents the pickup parameters.
+type PickupRequest struct {
+	// NameOrID is the sandbox name or ID.
+	NameOrID string
+	// LocalDir is the local directory the files are copied to.
+	LocalDir string
+}
+
+// Pickup copies the entries of the sandbox drop directory to a local directory and
+// returns their local paths, none when the drop directory is empty or missing.
+func (s *Service) Pickup(ctx context.Context, req PickupRequest) ([]string, error) {
+	if req.LocalDir == "" {
+		return nil, fmt.Errorf("local directory is required: %w", model.ErrNotValid)
+	}
+
+	sb, err := s.runningSandbox(ctx, req.NameOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := s.engine.ListDir(ctx, sb.ID, conventions.GuestDropDir)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("could not list drop directory: %w", err)
+	}
+
+	if err := os.MkdirAll(req.LocalDir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create local directory: %w", err)
+	}
+
+	picked := make([]string, 0, len(files))
+	for _, f := range files {
+		dst := filepath.Join(req.LocalDir, f.Name)
+		if err := s.engine.CopyFrom(ctx, sb.ID, f.Path, dst, model.CopyOpts{}); err != nil {
+			return nil, fmt.Errorf("could not pick up %s: %w", f.Name, err)
+		}
+		picked = append(picked, dst)
+	}
+
+	return picked, nil
+}
+
+// ensureDir creates a sandbox directory and its parents.
+func (s *Service) ensureDir(ctx context.Context, id, dir string) error {
+	res, err := s.engine.Exec(ctx, id, []string{"mkdir", "-p", dir}, model.ExecOpts{})
+	if err != nil {
+		return fmt.Errorf("could not create sandbox directory %s: %w", dir, err)
+	}
+	if res.ExitCode != 0 {
+		return fmt.Errorf("could not create sandbox directory %s: mkdir exited with code %d", dir, res.ExitCode)
+	}
+	return nil
+}
+
+// runningSandbox returns the sandbox of the name or ID, that must be running.
+func (s *Service) runningSandbox(ctx context.Context, nameOrID string) (*model.Sandbox, error) {
+	if nameOrID == "" {
+		return nil, fmt.Errorf("sandbox name or ID is required: %w", model.ErrNotValid)
+	}
+
+	// Lookup sandbox by name first, then by ID if it looks like a ULID.
+	sandbox, err := s.repo.GetSandboxByName(ctx, nameOrID)
+	if errors.Is(err, model.ErrNotFound) && looksLikeULID(nameOrID) {
+		sandbox, err = s.repo.GetSandbox(ctx, nameOrID)
+	}
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("sandbox not found: %s: %w", nameOrID, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not get sandbox: %w", err)
+	}
+
+	if sandbox.Status != model.SandboxStatusRunning {
+		return nil, fmt.Errorf("cannot access clipboard: sandbox not running (current status: %s): %w", sandbox.Status, model.ErrNotValid)
+	}
+
+	return sandbox, nil
+}
+
+// looksLikeULID checks if a string looks like a ULID (26 characters, alphanumeric uppercase).
+func looksLikeULID(s string) bool {
+	if len(s) != 26 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return true
+}
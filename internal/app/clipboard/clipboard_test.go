@@ -0,0 +1,245 @@
+package clipboard_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/clipboard"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox/sandboxmock"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+const testSandboxID = "01H2QWERTYASDFGZXCVBNMLKJH"
+
+var testRunning = &model.Sandbox{
+	ID:     testSandboxID,
+	Name:   "my-sandbox",
+	Status: model.SandboxStatusRunning,
+}
+
+func newService(t *testing.T, mockRepo func(m *storagemock.MockRepository), mockEngine func(m *sandboxmock.MockEngine)) *clipboard.Service {
+	mRepo := storagemock.NewMockRepository(t)
+	mEngine := sandboxmock.NewMockEngine(t)
+	mockRepo(mRepo)
+	mockEngine(mEngine)
+
+	svc, err := clipboard.NewService(clipboard.ServiceConfig{
+		Engine:     mEngine,
+		Repository: mRepo,
+		Logger:     log.Noop,
+	})
+	require.NoError(t, err)
+	return svc
+}
+
+func TestServicePush(t *testing.T) {
+	tests := map[string]struct {
+		mockRepo   func(m *storagemock.MockRepository)
+		mockEngine func(m *sandboxmock.MockEngine)
+		req        clipboard.PushRequest
+		expErrIs   error
+		expErr     bool
+	}{
+		"pushing should create the clipboard directory and write the clipboard": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(testRunning, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Exec", mock.Anything, testSandboxID, []string{"mkdir", "-p", "/tmp/sbx"}, mock.Anything).Once().Return(&model.ExecResult{}, nil)
+				m.On("WriteFile", mock.Anything, testSandboxID, "/tmp/sbx/clipboard", []byte("hello"), os.FileMode(0600)).Once().Return(nil)
+			},
+			req: clipboard.PushRequest{NameOrID: "my-sandbox", Data: []byte("hello")},
+		},
+		"a failing directory creation should fail": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(testRunning, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Exec", mock.Anything, testSandboxID, mock.Anything, mock.Anything).Once().Return(&model.ExecResult{ExitCode: 1}, nil)
+			},
+			req:    clipboard.PushRequest{NameOrID: "my-sandbox", Data: []byte("hello")},
+			expErr: true,
+		},
+		"a stopped sandbox should fail": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:     testSandboxID,
+					Name:   "my-sandbox",
+					Status: model.SandboxStatusStopped,
+				}, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        clipboard.PushRequest{NameOrID: "my-sandbox"},
+			expErrIs:   model.ErrNotValid,
+		},
+		"a missing sandbox should fail": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "missing").Once().Return(nil, model.ErrNotFound)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        clipboard.PushRequest{NameOrID: "missing"},
+			expErrIs:   model.ErrNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			svc := newService(t, test.mockRepo, test.mockEngine)
+
+			err := svc.Push(context.TODO(), test.req)
+
+			switch {
+			case test.expErrIs != nil:
+				assert.ErrorIs(t, err, test.expErrIs)
+			case test.expErr:
+				assert.Error(t, err)
+			default:
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestServicePull(t *testing.T) {
+	tests := map[string]struct {
+		mockEngine func(m *sandboxmock.MockEngine)
+		expData    []byte
+		expErr     bool
+	}{
+		"pulling should read the clipboard": {
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("ReadFile", mock.Anything, testSandboxID, "/tmp/sbx/clipboard").Once().Return([]byte("hello"), nil)
+			},
+			expData: []byte("hello"),
+		},
+		"a missing clipboard should be empty": {
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("ReadFile", mock.Anything, testSandboxID, "/tmp/sbx/clipboard").Once().Return(nil, model.ErrNotFound)
+			},
+			expData: []byte{},
+		},
+		"a failing read should fail": {
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("ReadFile", mock.Anything, testSandboxID, "/tmp/sbx/clipboard").Once().Return(nil, fmt.Errorf("something"))
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			svc := newService(t, func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(testRunning, nil)
+			}, test.mockEngine)
+
+			data, err := svc.Pull(context.TODO(), "my-sandbox")
+
+			if test.expErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expData, data)
+		})
+	}
+}
+
+func TestServiceDrop(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "notes.txt")
+	require.NoError(t, os.WriteFile(file, []byte("x"), 0644))
+
+	tests := map[string]struct {
+		mockRepo   func(m *storagemock.MockRepository)
+		mockEngine func(m *sandboxmock.MockEngine)
+		paths      []string
+		expPaths   []string
+		expErrIs   error
+	}{
+		"dropping should copy the paths into the drop directory": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(testRunning, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Exec", mock.Anything, testSandboxID, []string{"mkdir", "-p", "/tmp/sbx/drop"}, mock.Anything).Once().Return(&model.ExecResult{}, nil)
+				m.On("CopyTo", mock.Anything, testSandboxID, file, "/tmp/sbx/drop/notes.txt", model.CopyOpts{}).Once().Return(nil)
+				m.On("CopyTo", mock.Anything, testSandboxID, dir+"/", "/tmp/sbx/drop/"+filepath.Base(dir), model.CopyOpts{}).Once().Return(nil)
+			},
+			paths:    []string{file, dir + "/"},
+			expPaths: []string{"/tmp/sbx/drop/notes.txt", "/tmp/sbx/drop/" + filepath.Base(dir)},
+		},
+		"a missing local path should fail": {
+			mockRepo:   func(m *storagemock.MockRepository) {},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			paths:      []string{filepath.Join(dir, "missing")},
+			expErrIs:   model.ErrNotValid,
+		},
+		"no paths should fail": {
+			mockRepo:   func(m *storagemock.MockRepository) {},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			expErrIs:   model.ErrNotValid,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			svc := newService(t, test.mockRepo, test.mockEngine)
+
+			paths, err := svc.Drop(context.TODO(), clipboard.DropRequest{NameOrID: "my-sandbox", Paths: test.paths})
+
+			if test.expErrIs != nil {
+				assert.ErrorIs(t, err, test.expErrIs)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expPaths, paths)
+		})
+	}
+}
+
+func TestServicePickup(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+
+	tests := map[string]struct {
+		mockEngine func(m *sandboxmock.MockEngine)
+		expPaths   []string
+	}{
+		"picking up should copy the drop directory entries": {
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("ListDir", mock.Anything, testSandboxID, "/tmp/sbx/drop").Once().Return([]model.FileInfo{
+					{Name: "report.txt", Path: "/tmp/sbx/drop/report.txt"},
+				}, nil)
+				m.On("CopyFrom", mock.Anything, testSandboxID, "/tmp/sbx/drop/report.txt", filepath.Join(dir, "report.txt"), model.CopyOpts{}).Once().Return(nil)
+			},
+			expPaths: []string{filepath.Join(dir, "report.txt")},
+		},
+		"a missing drop directory should pick up nothing": {
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("ListDir", mock.Anything, testSandboxID, "/tmp/sbx/drop").Once().Return(nil, model.ErrNotFound)
+			},
+			expPaths: []string{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			svc := newService(t, func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(testRunning, nil)
+			}, test.mockEngine)
+
+			paths, err := svc.Pickup(context.TODO(), clipboard.PickupRequest{NameOrID: "my-sandbox", LocalDir: dir})
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expPaths, paths)
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package clone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/slok/sbx/internal/conventions"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the clone service.
+type ServiceConfig struct {
+	Engine     sandbox.Engine
+	Repository storage.Repository
+	Logger     log.Logger
+	// VMsDir is the sandbox VMs directory (default: ~/.sbx/vms).
+	VMsDir string
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Engine == nil {
+		return fmt.Errorf("engine is required")
+	}
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	if c.VMsDir == "" {
+		return fmt.Errorf("vms dir is required")
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.Clone"})
+	return nil
+}
+
+// Service clones stopped sandboxes.
+type Service struct {
+	engine sandbox.Engine
+	repo   storage.Repository
+	logger log.Logger
+	vmsDir string
+}
+
+// NewService creates a new clone service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &Service{
+		engine: cfg.Engine,
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+		vmsDir: cfg.VMsDir,
+	}, nil
+}
+
+// Request represents a clone request.
+type Request struct {
+	// NameOrID is the source sandbox name or ID.
+	NameOrID string
+	// Name is the name of the new sandbox.
+	Name string
+	// Resources overrides the source sandbox resources. Zero fields are inherited.
+	Resources model.Resources
+}
+
+// Run creates a new sandbox with a copy of the source sandbox rootfs (including the
+// changes made while it was running) and configuration.
+func (s *Service) Run(ctx context.Context, req Request) (*model.Sandbox, error) {
+	// 1. Resolve source sandbox.
+	src, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
+	if err != nil {
+		// Try by ID if name lookup failed
+		if errors.Is(err, model.ErrNotFound) {
+			src, err = s.repo.GetSandbox(ctx, req.NameOrID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not find sandbox: %w", err)
+		}
+	}
+
+	if src.Status != model.SandboxStatusStopped {
+		return nil, fmt.Errorf("cannot clone sandbox in status %q (must be stopped): %w", src.Status, model.ErrNotValid)
+	}
+	if src.Config.FirecrackerEngine == nil {
+		return nil, fmt.Errorf("sandbox has no engine configuration: %w", model.ErrNotValid)
+	}
+
+	// 2. Build the clone config from the source one.
+	cfg := src.Config
+	cfg.Name = req.Name
+	fcCfg := *src.Config.FirecrackerEngine
+	cfg.FirecrackerEngine = &fcCfg
+	// The source disk is already provisioned, user data must not run again on the clone.
+	if src.StartedAt != nil {
+		cfg.UserData = ""
+	}
+	if req.Resources.VCPUs > 0 {
+		cfg.Resources.VCPUs = req.Resources.VCPUs
+	}
+	if req.Resources.MemoryMB > 0 {
+		cfg.Resources.MemoryMB = req.Resources.MemoryMB
+	}
+	if req.Resources.DiskGB > 0 {
+		cfg.Resources.DiskGB = req.Resources.DiskGB
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if cfg.Resources.DiskGB < src.Config.Resources.DiskGB {
+		return nil, fmt.Errorf("clone disk (%d GB) can't be smaller than the source disk (%d GB): %w", cfg.Resources.DiskGB, src.Config.Resources.DiskGB, model.ErrNotValid)
+	}
+
+	// 3. Check name uniqueness.
+	_, err = s.repo.GetSandboxByName(ctx, cfg.Name)
+	if err == nil {
+		return nil, fmt.Errorf("sandbox with name %q already exists: %w", cfg.Name, model.ErrAlreadyExists)
+	}
+	if !errors.Is(err, model.ErrNotFound) {
+		return nil, fmt.Errorf("could not check name uniqueness: %w", err)
+	}
+
+	// 4. Create via engine using the source VM rootfs as base image.
+	createCfg := cfg
+	createFCCfg := fcCfg
+	createFCCfg.RootFS = conventions.VMFilePath(s.vmsDir, src.ID, conventions.RootFSFile)
+	createCfg.FirecrackerEngine = &createFCCfg
+
+	sb, err := s.engine.Create(ctx, createCfg, sandbox.CreateOpts{})
+	if err != nil {
+		return nil, fmt.Errorf("could not create sandbox: %w", err)
+	}
+
+	// The clone keeps the source configuration (base image) instead of the source VM rootfs,
+	// so it doesn't depend on the source sandbox after being created. The network addresses
+	// are the ones assigned by the engine to the clone.
+	if sb.Config.FirecrackerEngine != nil {
+		fcCfg.Networks = sb.Config.FirecrackerEngine.Networks
+	}
+	sb.Config = cfg
+	sb.Namespace = src.Namespace
+
+	// 5. Save to repository.
+	if err := s.repo.CreateSandbox(ctx, *sb); err != nil {
+		return nil, fmt.Errorf("could not save sandbox: %w", err)
+	}
+
+	s.logger.Infof("Cloned sandbox %s (%s) into %s (%s)", src.Name, src.ID, sb.Name, sb.ID)
+	return sb, nil
+}
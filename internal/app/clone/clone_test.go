@@ -0,0 +1,272 @@
+package clone_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/clone"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/sandbox/sandboxmock"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestNewService(t *testing.T) {
+	tests := map[string]struct {
+		config clone.ServiceConfig
+		expErr bool
+	}{
+		"valid config should create service": {
+			config: clone.ServiceConfig{
+				Engine:     &sandboxmock.MockEngine{},
+				Repository: &storagemock.MockRepository{},
+				VMsDir:     "/data/vms",
+			},
+		},
+		"missing engine should fail": {
+			config: clone.ServiceConfig{
+				Repository: &storagemock.MockRepository{},
+				VMsDir:     "/data/vms",
+			},
+			expErr: true,
+		},
+		"missing repository should fail": {
+			config: clone.ServiceConfig{
+				Engine: &sandboxmock.MockEngine{},
+				VMsDir: "/data/vms",
+			},
+			expErr: true,
+		},
+		"missing data dir should fail": {
+			config: clone.ServiceConfig{
+				Engine:     &sandboxmock.MockEngine{},
+				Repository: &storagemock.MockRepository{},
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			svc, err := clone.NewService(test.config)
+
+			if test.expErr {
+				require.Error(err)
+				require.Nil(svc)
+			} else {
+				require.NoError(err)
+				require.NotNil(svc)
+			}
+		})
+	}
+}
+
+func TestServiceRun(t *testing.T) {
+	errTest := errors.New("whatever")
+	createdAt := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+
+	srcSandbox := func(status model.SandboxStatus) *model.Sandbox {
+		return &model.Sandbox{
+			ID:     "01SRC000000000000000000000",
+			Name:   "src",
+			Status: status,
+			Config: model.SandboxConfig{
+				Name: "src",
+				FirecrackerEngine: &model.FirecrackerEngineConfig{
+					RootFS:      "/images/rootfs.ext4",
+					KernelImage: "/images/vmlinux",
+				},
+				Resources: model.Resources{VCPUs: 2, MemoryMB: 1024, DiskGB: 10},
+			},
+		}
+	}
+
+	tests := map[string]struct {
+		mock      func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine)
+		req       clone.Request
+		expConfig model.SandboxConfig
+		expErr    error
+	}{
+		"Cloning a stopped sandbox should create a sandbox from the source VM rootfs.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "src").Once().Return(srcSandbox(model.SandboxStatusStopped), nil)
+				mr.On("GetSandboxByName", mock.Anything, "dst").Once().Return(nil, model.ErrNotFound)
+				me.On("Create", mock.Anything, model.SandboxConfig{
+					Name: "dst",
+					FirecrackerEngine: &model.FirecrackerEngineConfig{
+						RootFS:      "/data/vms/01SRC000000000000000000000/rootfs.ext4",
+						KernelImage: "/images/vmlinux",
+					},
+					Resources: model.Resources{VCPUs: 2, MemoryMB: 1024, DiskGB: 10},
+				}, sandbox.CreateOpts{}).Once().Return(func(_ context.Context, cfg model.SandboxConfig, _ sandbox.CreateOpts) (*model.Sandbox, error) {
+					return &model.Sandbox{ID: "01DST000000000000000000000", Name: cfg.Name, Status: model.SandboxStatusStopped, Config: cfg, CreatedAt: createdAt}, nil
+				})
+				mr.On("CreateSandbox", mock.Anything, mock.Anything).Once().Return(nil)
+			},
+			req: clone.Request{NameOrID: "src", Name: "dst"},
+			expConfig: model.SandboxConfig{
+				Name: "dst",
+				FirecrackerEngine: &model.FirecrackerEngineConfig{
+					RootFS:      "/images/rootfs.ext4",
+					KernelImage: "/images/vmlinux",
+				},
+				Resources: model.Resources{VCPUs: 2, MemoryMB: 1024, DiskGB: 10},
+			},
+		},
+
+		"Cloning with resource overrides should use them.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "src").Once().Return(srcSandbox(model.SandboxStatusStopped), nil)
+				mr.On("GetSandboxByName", mock.Anything, "dst").Once().Return(nil, model.ErrNotFound)
+				me.On("Create", mock.Anything, mock.Anything, mock.Anything).Once().Return(func(_ context.Context, cfg model.SandboxConfig, _ sandbox.CreateOpts) (*model.Sandbox, error) {
+					return &model.Sandbox{ID: "01DST000000000000000000000", Name: cfg.Name, Status: model.SandboxStatusStopped, Config: cfg, CreatedAt: createdAt}, nil
+				})
+				mr.On("CreateSandbox", mock.Anything, mock.Anything).Once().Return(nil)
+			},
+			req: clone.Request{NameOrID: "src", Name: "dst", Resources: model.Resources{MemoryMB: 4096, DiskGB: 20}},
+			expConfig: model.SandboxConfig{
+				Name: "dst",
+				FirecrackerEngine: &model.FirecrackerEngineConfig{
+					RootFS:      "/images/rootfs.ext4",
+					KernelImage: "/images/vmlinux",
+				},
+				Resources: model.Resources{VCPUs: 2, MemoryMB: 4096, DiskGB: 20},
+			},
+		},
+
+		"Cloning a booted sandbox should not run its user data again.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				src := srcSandbox(model.SandboxStatusStopped)
+				src.Config.UserData = "apk add git\n"
+				src.StartedAt = &createdAt
+				mr.On("GetSandboxByName", mock.Anything, "src").Once().Return(src, nil)
+				mr.On("GetSandboxByName", mock.Anything, "dst").Once().Return(nil, model.ErrNotFound)
+				me.On("Create", mock.Anything, mock.Anything, mock.Anything).Once().Return(func(_ context.Context, cfg model.SandboxConfig, _ sandbox.CreateOpts) (*model.Sandbox, error) {
+					return &model.Sandbox{ID: "01DST000000000000000000000", Name: cfg.Name, Status: model.SandboxStatusStopped, Config: cfg, CreatedAt: createdAt}, nil
+				})
+				mr.On("CreateSandbox", mock.Anything, mock.Anything).Once().Return(nil)
+			},
+			req: clone.Request{NameOrID: "src", Name: "dst"},
+			expConfig: model.SandboxConfig{
+				Name: "dst",
+				FirecrackerEngine: &model.FirecrackerEngineConfig{
+					RootFS:      "/images/rootfs.ext4",
+					KernelImage: "/images/vmlinux",
+				},
+				Resources: model.Resources{VCPUs: 2, MemoryMB: 1024, DiskGB: 10},
+			},
+		},
+
+		"Cloning a never booted sandbox should keep its user data.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				src := srcSandbox(model.SandboxStatusStopped)
+				src.Config.UserData = "apk add git\n"
+				mr.On("GetSandboxByName", mock.Anything, "src").Once().Return(src, nil)
+				mr.On("GetSandboxByName", mock.Anything, "dst").Once().Return(nil, model.ErrNotFound)
+				me.On("Create", mock.Anything, mock.Anything, mock.Anything).Once().Return(func(_ context.Context, cfg model.SandboxConfig, _ sandbox.CreateOpts) (*model.Sandbox, error) {
+					return &model.Sandbox{ID: "01DST000000000000000000000", Name: cfg.Name, Status: model.SandboxStatusStopped, Config: cfg, CreatedAt: createdAt}, nil
+				})
+				mr.On("CreateSandbox", mock.Anything, mock.Anything).Once().Return(nil)
+			},
+			req: clone.Request{NameOrID: "src", Name: "dst"},
+			expConfig: model.SandboxConfig{
+				Name: "dst",
+				FirecrackerEngine: &model.FirecrackerEngineConfig{
+					RootFS:      "/images/rootfs.ext4",
+					KernelImage: "/images/vmlinux",
+				},
+				Resources: model.Resources{VCPUs: 2, MemoryMB: 1024, DiskGB: 10},
+				UserData:  "apk add git\n",
+			},
+		},
+
+		"Cloning with a smaller disk should fail.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "src").Once().Return(srcSandbox(model.SandboxStatusStopped), nil)
+			},
+			req:    clone.Request{NameOrID: "src", Name: "dst", Resources: model.Resources{DiskGB: 5}},
+			expErr: model.ErrNotValid,
+		},
+
+		"Cloning a running sandbox should fail.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "src").Once().Return(srcSandbox(model.SandboxStatusRunning), nil)
+			},
+			req:    clone.Request{NameOrID: "src", Name: "dst"},
+			expErr: model.ErrNotValid,
+		},
+
+		"Cloning without a name should fail.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "src").Once().Return(srcSandbox(model.SandboxStatusStopped), nil)
+			},
+			req:    clone.Request{NameOrID: "src"},
+			expErr: model.ErrNotValid,
+		},
+
+		"Cloning into an existing name should fail.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "src").Once().Return(srcSandbox(model.SandboxStatusStopped), nil)
+				mr.On("GetSandboxByName", mock.Anything, "dst").Once().Return(&model.Sandbox{Name: "dst"}, nil)
+			},
+			req:    clone.Request{NameOrID: "src", Name: "dst"},
+			expErr: model.ErrAlreadyExists,
+		},
+
+		"Cloning a missing sandbox should fail.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "src").Once().Return(nil, model.ErrNotFound)
+				mr.On("GetSandbox", mock.Anything, "src").Once().Return(nil, model.ErrNotFound)
+			},
+			req:    clone.Request{NameOrID: "src", Name: "dst"},
+			expErr: model.ErrNotFound,
+		},
+
+		"An engine error should fail.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "src").Once().Return(srcSandbox(model.SandboxStatusStopped), nil)
+				mr.On("GetSandboxByName", mock.Anything, "dst").Once().Return(nil, model.ErrNotFound)
+				me.On("Create", mock.Anything, mock.Anything, mock.Anything).Once().Return(nil, errTest)
+			},
+			req:    clone.Request{NameOrID: "src", Name: "dst"},
+			expErr: errTest,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mr := &storagemock.MockRepository{}
+			me := &sandboxmock.MockEngine{}
+			test.mock(mr, me)
+
+			svc, err := clone.NewService(clone.ServiceConfig{
+				Engine:     me,
+				Repository: mr,
+				VMsDir:     "/data/vms",
+			})
+			require.NoError(err)
+
+			sb, err := svc.Run(context.TODO(), test.req)
+
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expConfig, sb.Config)
+			}
+
+			mr.AssertExpectations(t)
+			me.AssertExpectations(t)
+		})
+	}
+}
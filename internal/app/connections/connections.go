@@ -0,0 +1,88 @@
+package connections
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the connections service.
+type ServiceConfig struct {
+	Engine     sandbox.Engine
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Engine == nil {
+		return fmt.Errorf("engine is required")
+	}
+
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.Connections"})
+
+	return nil
+}
+
+// Service lists the active network connections of the sandboxes.
+type Service struct {
+	engine sandbox.Engine
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new connections service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		engine: cfg.Engine,
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the connections request parameters.
+type Request struct {
+	// NameOrID is the sandbox name or ID.
+	NameOrID string
+}
+
+// Run returns the active network connections of a running sandbox.
+func (s *Service) Run(ctx context.Context, req Request) ([]model.Connection, error) {
+	sb, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
+	if errors.Is(err, model.ErrNotFound) {
+		sb, err = s.repo.GetSandbox(ctx, req.NameOrID)
+	}
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("sandbox not found: %s: %w", req.NameOrID, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not get sandbox: %w", err)
+	}
+
+	if sb.Status != model.SandboxStatusRunning {
+		return nil, fmt.Errorf("sandbox %s is not running (current status: %s): %w", sb.Name, sb.Status, model.ErrNotValid)
+	}
+
+	conns, err := s.engine.Connections(ctx, sb.ID)
+	if err != nil {
+		return nil, fmt.Errorf("could not list sandbox connections: %w", err)
+	}
+
+	s.logger.Debugf("Listed %d connections of sandbox %s", len(conns), sb.Name)
+	return conns, nil
+}
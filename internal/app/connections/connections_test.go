@@ -0,0 +1,116 @@
+package connections_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/connections"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox/sandboxmock"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+const testSandboxID = "01H2QWERTYASDFGZXCVBNMLKJH"
+
+var testRunning = &model.Sandbox{
+	ID:     testSandboxID,
+	Name:   "my-sandbox",
+	Status: model.SandboxStatusRunning,
+}
+
+func TestServiceRun(t *testing.T) {
+	testConns := []model.Connection{
+		{Protocol: "tcp", Direction: model.ConnectionDirectionEgress, Interface: "eth0", Source: "10.68.40.2:40000", Destination: "140.82.121.4:443", Domain: "github.com", State: "ESTABLISHED", Proxy: "tls"},
+	}
+
+	tests := map[string]struct {
+		mockRepo   func(m *storagemock.MockRepository)
+		mockEngine func(m *sandboxmock.MockEngine)
+		req        connections.Request
+		expConns   []model.Connection
+		expErrIs   error
+		expErr     bool
+	}{
+		"Listing should return the sandbox connections.": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(testRunning, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Connections", mock.Anything, testSandboxID).Once().Return(testConns, nil)
+			},
+			req:      connections.Request{NameOrID: "my-sandbox"},
+			expConns: testConns,
+		},
+		"Listing by ID should return the sandbox connections.": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, testSandboxID).Once().Return(nil, model.ErrNotFound)
+				m.On("GetSandbox", mock.Anything, testSandboxID).Once().Return(testRunning, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Connections", mock.Anything, testSandboxID).Once().Return([]model.Connection{}, nil)
+			},
+			req:      connections.Request{NameOrID: testSandboxID},
+			expConns: []model.Connection{},
+		},
+		"A failing engine should fail.": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(testRunning, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Connections", mock.Anything, testSandboxID).Once().Return(nil, fmt.Errorf("something"))
+			},
+			req:    connections.Request{NameOrID: "my-sandbox"},
+			expErr: true,
+		},
+		"A stopped sandbox should fail.": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:     testSandboxID,
+					Name:   "my-sandbox",
+					Status: model.SandboxStatusStopped,
+				}, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        connections.Request{NameOrID: "my-sandbox"},
+			expErrIs:   model.ErrNotValid,
+		},
+		"A missing sandbox should fail.": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "missing").Once().Return(nil, model.ErrNotFound)
+				m.On("GetSandbox", mock.Anything, "missing").Once().Return(nil, model.ErrNotFound)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        connections.Request{NameOrID: "missing"},
+			expErrIs:   model.ErrNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mRepo := storagemock.NewMockRepository(t)
+			mEngine := sandboxmock.NewMockEngine(t)
+			test.mockRepo(mRepo)
+			test.mockEngine(mEngine)
+			svc, err := connections.NewService(connections.ServiceConfig{Engine: mEngine, Repository: mRepo, Logger: log.Noop})
+			require.NoError(t, err)
+
+			conns, err := svc.Run(context.TODO(), test.req)
+
+			switch {
+			case test.expErrIs != nil:
+				assert.ErrorIs(t, err, test.expErrIs)
+			case test.expErr:
+				assert.Error(t, err)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, test.expConns, conns)
+			}
+		})
+	}
+}
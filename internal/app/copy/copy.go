@@ -58,6 +58,16 @@ func NewService(cfg ServiceConfig) (*Service, error) {
 type Request struct {
 	Source      string // Source path (with optional sandbox: prefix)
 	Destination string // Destination path (with optional sandbox: prefix)
+	// Compress sends the files compressed.
+	Compress bool
+	// Progress is called with the copy progress (optional).
+	Progress func(model.CopyProgress)
+	// BandwidthLimit limits the copy rate in bytes per second (0 is unlimited).
+	BandwidthLimit int64
+	// Retries is the number of times the copy is resumed when the connection breaks.
+	Retries int
+	// Checksum verifies the copied files checksum.
+	Checksum bool
 }
 
 // ParsedCopy contains the parsed copy operation details.
@@ -141,14 +151,21 @@ func (s *Service) Run(ctx context.Context, req Request) error {
 	}
 
 	// 5. Execute copy operation
+	opts := model.CopyOpts{
+		Compress:       req.Compress,
+		Progress:       req.Progress,
+		BandwidthLimit: req.BandwidthLimit,
+		Retries:        req.Retries,
+		Checksum:       req.Checksum,
+	}
 	if parsed.ToSandbox {
 		s.logger.Debugf("Copying %s to %s:%s", parsed.LocalPath, sbx.Name, parsed.RemotePath)
-		if err := s.engine.CopyTo(ctx, sbx.ID, parsed.LocalPath, parsed.RemotePath); err != nil {
+		if err := s.engine.CopyTo(ctx, sbx.ID, parsed.LocalPath, parsed.RemotePath, opts); err != nil {
 			return fmt.Errorf("could not copy to sandbox: %w", err)
 		}
 	} else {
 		s.logger.Debugf("Copying %s:%s to %s", sbx.Name, parsed.RemotePath, parsed.LocalPath)
-		if err := s.engine.CopyFrom(ctx, sbx.ID, parsed.RemotePath, parsed.LocalPath); err != nil {
+		if err := s.engine.CopyFrom(ctx, sbx.ID, parsed.RemotePath, parsed.LocalPath, opts); err != nil {
 			return fmt.Errorf("could not copy from sandbox: %w", err)
 		}
 	}
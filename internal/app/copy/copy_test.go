@@ -223,7 +223,27 @@ func TestServiceRun(t *testing.T) {
 					Status: model.SandboxStatusRunning,
 				}
 				mRepo.On("GetSandboxByName", mock.Anything, "test-sandbox").Once().Return(sandbox, nil)
-				mEngine.On("CopyTo", mock.Anything, "test-id", existingFile, "/workspace/").Once().Return(nil)
+				mEngine.On("CopyTo", mock.Anything, "test-id", existingFile, "/workspace/", model.CopyOpts{}).Once().Return(nil)
+			},
+			expErr: false,
+		},
+
+		"CopyTo with compression and progress should pass the options to the engine": {
+			req: Request{
+				Source:      existingFile,
+				Destination: "test-sandbox:/workspace/",
+				Compress:    true,
+				Progress:    func(model.CopyProgress) {},
+			},
+			mock: func(mEngine *sandboxmock.MockEngine, mRepo *storagemock.MockRepository) {
+				sandbox := &model.Sandbox{
+					ID:     "test-id",
+					Name:   "test-sandbox",
+					Status: model.SandboxStatusRunning,
+				}
+				mRepo.On("GetSandboxByName", mock.Anything, "test-sandbox").Once().Return(sandbox, nil)
+				expOpts := mock.MatchedBy(func(opts model.CopyOpts) bool { return opts.Compress && opts.Progress != nil })
+				mEngine.On("CopyTo", mock.Anything, "test-id", existingFile, "/workspace/", expOpts).Once().Return(nil)
 			},
 			expErr: false,
 		},
@@ -240,7 +260,7 @@ func TestServiceRun(t *testing.T) {
 					Status: model.SandboxStatusRunning,
 				}
 				mRepo.On("GetSandboxByName", mock.Anything, "test-sandbox").Once().Return(sandbox, nil)
-				mEngine.On("CopyFrom", mock.Anything, "test-id", "/workspace/file.txt", tempDir).Once().Return(nil)
+				mEngine.On("CopyFrom", mock.Anything, "test-id", "/workspace/file.txt", tempDir, model.CopyOpts{}).Once().Return(nil)
 			},
 			expErr: false,
 		},
@@ -261,7 +281,7 @@ func TestServiceRun(t *testing.T) {
 					Status: model.SandboxStatusRunning,
 				}
 				mRepo.On("GetSandbox", mock.Anything, "TEST-ID").Once().Return(sandbox, nil)
-				mEngine.On("CopyTo", mock.Anything, "TEST-ID", existingFile, "/workspace/").Once().Return(nil)
+				mEngine.On("CopyTo", mock.Anything, "TEST-ID", existingFile, "/workspace/", model.CopyOpts{}).Once().Return(nil)
 			},
 			expErr: false,
 		},
@@ -317,7 +337,7 @@ func TestServiceRun(t *testing.T) {
 					Status: model.SandboxStatusRunning,
 				}
 				mRepo.On("GetSandboxByName", mock.Anything, "test-sandbox").Once().Return(sandbox, nil)
-				mEngine.On("CopyTo", mock.Anything, "test-id", existingFile, "/workspace/").Once().Return(model.ErrNotFound)
+				mEngine.On("CopyTo", mock.Anything, "test-id", existingFile, "/workspace/", model.CopyOpts{}).Once().Return(model.ErrNotFound)
 			},
 			expErr: true,
 		},
@@ -334,7 +354,7 @@ func TestServiceRun(t *testing.T) {
 					Status: model.SandboxStatusRunning,
 				}
 				mRepo.On("GetSandboxByName", mock.Anything, "test-sandbox").Once().Return(sandbox, nil)
-				mEngine.On("CopyFrom", mock.Anything, "test-id", "/workspace/file.txt", tempDir).Once().Return(model.ErrNotFound)
+				mEngine.On("CopyFrom", mock.Anything, "test-id", "/workspace/file.txt", tempDir, model.CopyOpts{}).Once().Return(model.ErrNotFound)
 			},
 			expErr: true,
 		},
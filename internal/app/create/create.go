@@ -5,17 +5,24 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/slok/sbx/internal/capacity"
+	"github.com/slok/sbx/internal/hooks"
 	"github.com/slok/sbx/internal/log"
 	"github.com/slok/sbx/internal/model"
 	"github.com/slok/sbx/internal/sandbox"
 	"github.com/slok/sbx/internal/storage"
+	"github.com/slok/sbx/internal/userdata"
 )
 
 // ServiceConfig is the configuration for the create service.
 type ServiceConfig struct {
 	Engine     sandbox.Engine
 	Repository storage.Repository
-	Logger     log.Logger
+	// Admission checks the host capacity before creating the sandbox (optional).
+	Admission *capacity.Planner
+	// Hooks runs the pre-create host hooks (optional).
+	Hooks  hooks.Runner
+	Logger log.Logger
 }
 
 func (c *ServiceConfig) defaults() error {
@@ -25,6 +32,9 @@ func (c *ServiceConfig) defaults() error {
 	if c.Repository == nil {
 		return fmt.Errorf("repository is required")
 	}
+	if c.Hooks == nil {
+		c.Hooks = hooks.Noop
+	}
 	if c.Logger == nil {
 		c.Logger = log.Noop
 	}
@@ -34,9 +44,11 @@ func (c *ServiceConfig) defaults() error {
 
 // Service handles sandbox creation business logic.
 type Service struct {
-	engine sandbox.Engine
-	repo   storage.Repository
-	logger log.Logger
+	engine    sandbox.Engine
+	repo      storage.Repository
+	admission *capacity.Planner
+	hooks     hooks.Runner
+	logger    log.Logger
 }
 
 // NewService creates a new create service.
@@ -45,15 +57,30 @@ func NewService(cfg ServiceConfig) (*Service, error) {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 	return &Service{
-		engine: cfg.Engine,
-		repo:   cfg.Repository,
-		logger: cfg.Logger,
+		engine:    cfg.Engine,
+		repo:      cfg.Repository,
+		admission: cfg.Admission,
+		hooks:     cfg.Hooks,
+		logger:    cfg.Logger,
 	}, nil
 }
 
 // CreateOptions are the options for creating a sandbox.
 type CreateOptions struct {
 	Config model.SandboxConfig
+	// Namespace is the namespace of the sandbox, empty uses the repository namespace.
+	Namespace string
+	// IfNotExists returns the existing sandbox with the same name when its spec matches
+	// the config and webhooks instead of failing. A sandbox with a different spec fails
+	// with a model.SpecMismatchError.
+	IfNotExists bool
+	// Webhooks are notified of the sandbox lifecycle events (optional).
+	Webhooks []model.Webhook
+	// Persistent starts the sandbox again when the host reboot kills it while
+	// running (see the recovery service).
+	Persistent bool
+	// Progress receives the create steps (optional).
+	Progress model.ProgressFunc
 }
 
 // Create creates a new sandbox.
@@ -62,23 +89,68 @@ func (s *Service) Create(ctx context.Context, opts CreateOptions) (*model.Sandbo
 	if err := opts.Config.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
+	if opts.Config.UserData != "" {
+		if err := userdata.Validate(opts.Config.UserData); err != nil {
+			return nil, fmt.Errorf("invalid user data: %w", err)
+		}
+	}
+	if opts.Namespace != "" {
+		if err := model.ValidateNamespace(opts.Namespace); err != nil {
+			return nil, fmt.Errorf("invalid namespace: %w", err)
+		}
+	}
+	for _, w := range opts.Webhooks {
+		if err := w.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid webhook: %w", err)
+		}
+	}
 
 	// 2. Check name uniqueness
-	_, err := s.repo.GetSandboxByName(ctx, opts.Config.Name)
+	existing, err := s.repo.GetSandboxByName(ctx, opts.Config.Name)
 	if err == nil {
-		return nil, fmt.Errorf("sandbox with name %q already exists: %w", opts.Config.Name, model.ErrAlreadyExists)
+		if !opts.IfNotExists {
+			return nil, fmt.Errorf("sandbox with name %q already exists: %w", opts.Config.Name, model.ErrAlreadyExists)
+		}
+		if diff := model.SandboxSpecDiff(*existing, opts.Config, opts.Webhooks); len(diff) > 0 {
+			return nil, &model.SpecMismatchError{Name: opts.Config.Name, Fields: diff}
+		}
+		s.logger.Debugf("Sandbox %s (%s) already exists with the same spec", existing.Name, existing.ID)
+		return existing, nil
 	}
 	if !errors.Is(err, model.ErrNotFound) {
 		return nil, fmt.Errorf("could not check name uniqueness: %w", err)
 	}
 
-	// 3. Create via engine
-	sandbox, err := s.engine.Create(ctx, opts.Config)
+	// 3. Check host capacity
+	if s.admission != nil {
+		if err := s.admission.AdmitCreate(ctx, opts.Config.Resources); err != nil {
+			return nil, fmt.Errorf("sandbox not admitted: %w", err)
+		}
+	}
+
+	// 4. Run the pre-create hooks, a failed one aborts the creation
+	hookSandbox := model.Sandbox{
+		Name:       opts.Config.Name,
+		Namespace:  opts.Namespace,
+		Config:     opts.Config,
+		Status:     model.SandboxStatusPending,
+		Webhooks:   opts.Webhooks,
+		Persistent: opts.Persistent,
+	}
+	if err := s.hooks.Run(ctx, model.HookEventPreCreate, hookSandbox); err != nil {
+		return nil, fmt.Errorf("sandbox creation aborted: %w", err)
+	}
+
+	// 5. Create via engine
+	sandbox, err := s.engine.Create(ctx, opts.Config, sandbox.CreateOpts{Progress: opts.Progress})
 	if err != nil {
 		return nil, fmt.Errorf("could not create sandbox: %w", err)
 	}
 
-	// 4. Save to repository
+	// 6. Save to repository
+	sandbox.Namespace = opts.Namespace
+	sandbox.Webhooks = opts.Webhooks
+	sandbox.Persistent = opts.Persistent
 	if err := s.repo.CreateSandbox(ctx, *sandbox); err != nil {
 		return nil, fmt.Errorf("could not save sandbox: %w", err)
 	}
@@ -10,6 +10,8 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/slok/sbx/internal/app/create"
+	"github.com/slok/sbx/internal/capacity"
+	"github.com/slok/sbx/internal/hooks"
 	"github.com/slok/sbx/internal/log"
 	"github.com/slok/sbx/internal/model"
 	"github.com/slok/sbx/internal/sandbox/sandboxmock"
@@ -33,7 +35,7 @@ func TestCreateService(t *testing.T) {
 		repo := storagemock.NewMockRepository(t)
 
 		repo.On("GetSandboxByName", mock.Anything, "test-sandbox").Return((*model.Sandbox)(nil), model.ErrNotFound)
-		eng.On("Create", mock.Anything, mock.Anything).Return(&model.Sandbox{ID: "01", Name: "test-sandbox", Status: model.SandboxStatusStopped, Config: validConfig()}, nil)
+		eng.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(&model.Sandbox{ID: "01", Name: "test-sandbox", Status: model.SandboxStatusStopped, Config: validConfig()}, nil)
 		repo.On("CreateSandbox", mock.Anything, mock.Anything).Return(nil)
 
 		svc, err := create.NewService(create.ServiceConfig{Engine: eng, Repository: repo, Logger: log.Noop})
@@ -59,6 +61,99 @@ func TestCreateService(t *testing.T) {
 		assert.Nil(t, sb)
 	})
 
+	t.Run("invalid user data", func(t *testing.T) {
+		eng := sandboxmock.NewMockEngine(t)
+		repo := storagemock.NewMockRepository(t)
+		svc, err := create.NewService(create.ServiceConfig{Engine: eng, Repository: repo, Logger: log.Noop})
+		require.NoError(t, err)
+
+		cfg := validConfig()
+		cfg.UserData = "#cloud-config\nunknown_module: true\n"
+
+		sb, err := svc.Create(context.Background(), create.CreateOptions{Config: cfg})
+		assert.ErrorIs(t, err, model.ErrNotValid)
+		assert.Nil(t, sb)
+	})
+
+	t.Run("with namespace", func(t *testing.T) {
+		eng := sandboxmock.NewMockEngine(t)
+		repo := storagemock.NewMockRepository(t)
+
+		repo.On("GetSandboxByName", mock.Anything, "test-sandbox").Return((*model.Sandbox)(nil), model.ErrNotFound)
+		eng.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(&model.Sandbox{ID: "01", Name: "test-sandbox", Status: model.SandboxStatusStopped, Config: validConfig()}, nil)
+		repo.On("CreateSandbox", mock.Anything, mock.MatchedBy(func(s model.Sandbox) bool { return s.Namespace == "team-a" })).Return(nil)
+
+		svc, err := create.NewService(create.ServiceConfig{Engine: eng, Repository: repo, Logger: log.Noop})
+		require.NoError(t, err)
+
+		sb, err := svc.Create(context.Background(), create.CreateOptions{Config: validConfig(), Namespace: "team-a"})
+		require.NoError(t, err)
+		assert.Equal(t, "team-a", sb.Namespace)
+	})
+
+	t.Run("invalid namespace", func(t *testing.T) {
+		eng := sandboxmock.NewMockEngine(t)
+		repo := storagemock.NewMockRepository(t)
+		svc, err := create.NewService(create.ServiceConfig{Engine: eng, Repository: repo, Logger: log.Noop})
+		require.NoError(t, err)
+
+		sb, err := svc.Create(context.Background(), create.CreateOptions{Config: validConfig(), Namespace: model.AllNamespaces})
+		assert.ErrorIs(t, err, model.ErrNotValid)
+		assert.Nil(t, sb)
+	})
+
+	t.Run("with webhooks", func(t *testing.T) {
+		eng := sandboxmock.NewMockEngine(t)
+		repo := storagemock.NewMockRepository(t)
+
+		webhooks := []model.Webhook{{URL: "https://hooks.example.com/sbx", Secret: "s3cret", Events: []model.EventType{model.EventTypeSandboxStarted}}}
+		repo.On("GetSandboxByName", mock.Anything, "test-sandbox").Return((*model.Sandbox)(nil), model.ErrNotFound)
+		eng.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(&model.Sandbox{ID: "01", Name: "test-sandbox", Status: model.SandboxStatusStopped, Config: validConfig()}, nil)
+		repo.On("CreateSandbox", mock.Anything, mock.MatchedBy(func(s model.Sandbox) bool { return len(s.Webhooks) == 1 })).Return(nil)
+
+		svc, err := create.NewService(create.ServiceConfig{Engine: eng, Repository: repo, Logger: log.Noop})
+		require.NoError(t, err)
+
+		sb, err := svc.Create(context.Background(), create.CreateOptions{Config: validConfig(), Webhooks: webhooks})
+		require.NoError(t, err)
+		assert.Equal(t, webhooks, sb.Webhooks)
+	})
+
+	t.Run("invalid webhook", func(t *testing.T) {
+		eng := sandboxmock.NewMockEngine(t)
+		repo := storagemock.NewMockRepository(t)
+		svc, err := create.NewService(create.ServiceConfig{Engine: eng, Repository: repo, Logger: log.Noop})
+		require.NoError(t, err)
+
+		sb, err := svc.Create(context.Background(), create.CreateOptions{Config: validConfig(), Webhooks: []model.Webhook{{URL: "hooks.example.com"}}})
+		assert.ErrorIs(t, err, model.ErrNotValid)
+		assert.Nil(t, sb)
+	})
+
+	t.Run("failed pre-create hook aborts the create", func(t *testing.T) {
+		eng := sandboxmock.NewMockEngine(t)
+		repo := storagemock.NewMockRepository(t)
+		repo.On("GetSandboxByName", mock.Anything, "test-sandbox").Return((*model.Sandbox)(nil), model.ErrNotFound)
+
+		var got model.Sandbox
+		runner, err := hooks.NewRunner(hooks.RunnerConfig{Hooks: []model.HostHook{{
+			Event: model.HookEventPreCreate,
+			Func: func(_ context.Context, _ model.HookEvent, sb model.Sandbox) error {
+				got = sb
+				return errors.New("quota exceeded")
+			},
+		}}})
+		require.NoError(t, err)
+
+		svc, err := create.NewService(create.ServiceConfig{Engine: eng, Repository: repo, Hooks: runner, Logger: log.Noop})
+		require.NoError(t, err)
+
+		sb, err := svc.Create(context.Background(), create.CreateOptions{Config: validConfig(), Namespace: "team-a"})
+		assert.ErrorContains(t, err, "quota exceeded")
+		assert.Nil(t, sb)
+		assert.Equal(t, model.Sandbox{Name: "test-sandbox", Namespace: "team-a", Config: validConfig(), Status: model.SandboxStatusPending}, got)
+	})
+
 	t.Run("name conflict", func(t *testing.T) {
 		eng := sandboxmock.NewMockEngine(t)
 		repo := storagemock.NewMockRepository(t)
@@ -72,11 +167,92 @@ func TestCreateService(t *testing.T) {
 		assert.Nil(t, sb)
 	})
 
+	t.Run("if not exists with the same spec", func(t *testing.T) {
+		eng := sandboxmock.NewMockEngine(t)
+		repo := storagemock.NewMockRepository(t)
+
+		// Engine assigned fields should be ignored.
+		existingCfg := validConfig()
+		existingCfg.FirecrackerEngine.Networks = []model.NetworkInterface{{Mode: model.NetworkModeIsolated, Network: "backend", Address: "172.20.3.7"}}
+		repo.On("GetSandboxByName", mock.Anything, "test-sandbox").Return(&model.Sandbox{ID: "existing", Name: "test-sandbox", Config: existingCfg}, nil)
+
+		svc, err := create.NewService(create.ServiceConfig{Engine: eng, Repository: repo, Logger: log.Noop})
+		require.NoError(t, err)
+
+		cfg := validConfig()
+		cfg.FirecrackerEngine.Networks = []model.NetworkInterface{{Mode: model.NetworkModeIsolated, Network: "backend"}}
+		sb, err := svc.Create(context.Background(), create.CreateOptions{Config: cfg, IfNotExists: true})
+		require.NoError(t, err)
+		assert.Equal(t, "existing", sb.ID)
+	})
+
+	t.Run("if not exists with a different spec", func(t *testing.T) {
+		eng := sandboxmock.NewMockEngine(t)
+		repo := storagemock.NewMockRepository(t)
+		repo.On("GetSandboxByName", mock.Anything, "test-sandbox").Return(&model.Sandbox{ID: "existing", Name: "test-sandbox", Config: validConfig()}, nil)
+
+		svc, err := create.NewService(create.ServiceConfig{Engine: eng, Repository: repo, Logger: log.Noop})
+		require.NoError(t, err)
+
+		cfg := validConfig()
+		cfg.Resources.MemoryMB = 4096
+		cfg.FirecrackerEngine.KernelArgs = []string{"quiet"}
+		sb, err := svc.Create(context.Background(), create.CreateOptions{Config: cfg, IfNotExists: true})
+		assert.ErrorIs(t, err, model.ErrAlreadyExists)
+		assert.ErrorContains(t, err, "resources, kernel_args")
+		var mismatchErr *model.SpecMismatchError
+		if assert.ErrorAs(t, err, &mismatchErr) {
+			assert.Equal(t, "test-sandbox", mismatchErr.Name)
+			assert.Equal(t, []model.SpecField{model.SpecFieldResources, model.SpecFieldKernelArgs}, mismatchErr.Fields)
+		}
+		assert.Nil(t, sb)
+	})
+
+	t.Run("if not exists with different webhooks", func(t *testing.T) {
+		eng := sandboxmock.NewMockEngine(t)
+		repo := storagemock.NewMockRepository(t)
+		repo.On("GetSandboxByName", mock.Anything, "test-sandbox").Return(&model.Sandbox{ID: "existing", Name: "test-sandbox", Config: validConfig()}, nil)
+
+		svc, err := create.NewService(create.ServiceConfig{Engine: eng, Repository: repo, Logger: log.Noop})
+		require.NoError(t, err)
+
+		webhooks := []model.Webhook{{URL: "https://hooks.test/sbx"}}
+		sb, err := svc.Create(context.Background(), create.CreateOptions{Config: validConfig(), Webhooks: webhooks, IfNotExists: true})
+		var mismatchErr *model.SpecMismatchError
+		if assert.ErrorAs(t, err, &mismatchErr) {
+			assert.Equal(t, []model.SpecField{model.SpecFieldWebhooks}, mismatchErr.Fields)
+		}
+		assert.Nil(t, sb)
+	})
+
+	t.Run("not admitted", func(t *testing.T) {
+		eng := sandboxmock.NewMockEngine(t)
+		repo := storagemock.NewMockRepository(t)
+		repo.On("GetSandboxByName", mock.Anything, "test-sandbox").Return((*model.Sandbox)(nil), model.ErrNotFound)
+		repo.On("ListAllSandboxes", mock.Anything).Return([]model.Sandbox{}, nil)
+
+		planner, err := capacity.NewPlanner(capacity.PlannerConfig{
+			Repository: repo,
+			HostResources: func(ctx context.Context) (model.Resources, error) {
+				return model.Resources{VCPUs: 4, MemoryMB: 8192, DiskGB: 5}, nil
+			},
+			Mode: model.AdmissionModeEnforce,
+		})
+		require.NoError(t, err)
+
+		svc, err := create.NewService(create.ServiceConfig{Engine: eng, Repository: repo, Admission: planner, Logger: log.Noop})
+		require.NoError(t, err)
+
+		sb, err := svc.Create(context.Background(), create.CreateOptions{Config: validConfig()})
+		assert.ErrorIs(t, err, model.ErrNotValid)
+		assert.Nil(t, sb)
+	})
+
 	t.Run("engine failure", func(t *testing.T) {
 		eng := sandboxmock.NewMockEngine(t)
 		repo := storagemock.NewMockRepository(t)
 		repo.On("GetSandboxByName", mock.Anything, "test-sandbox").Return((*model.Sandbox)(nil), model.ErrNotFound)
-		eng.On("Create", mock.Anything, mock.Anything).Return((*model.Sandbox)(nil), errors.New("boom"))
+		eng.On("Create", mock.Anything, mock.Anything, mock.Anything).Return((*model.Sandbox)(nil), errors.New("boom"))
 
 		svc, err := create.NewService(create.ServiceConfig{Engine: eng, Repository: repo, Logger: log.Noop})
 		require.NoError(t, err)
@@ -0,0 +1,170 @@
+package createstart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/slok/sbx/internal/app/create"
+	"github.com/slok/sbx/internal/app/start"
+	"github.com/slok/sbx/internal/capacity"
+	"github.com/slok/sbx/internal/hooks"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the create and start service.
+type ServiceConfig struct {
+	Engine     sandbox.Engine
+	Repository storage.Repository
+	// Admission checks the host capacity before creating and starting the sandbox (optional).
+	Admission *capacity.Planner
+	// Hooks runs the pre-create and post-start host hooks (optional).
+	Hooks  hooks.Runner
+	Logger log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Engine == nil {
+		return fmt.Errorf("engine is required")
+	}
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.CreateStart"})
+	return nil
+}
+
+// Service creates and starts a sandbox as a single operation, a sandbox created by
+// a failed or canceled operation is removed so no half created sandbox is left.
+type Service struct {
+	engine sandbox.Engine
+	repo   storage.Repository
+	create *create.Service
+	start  *start.Service
+	logger log.Logger
+}
+
+// NewService creates a new create and start service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	createSvc, err := create.NewService(create.ServiceConfig{
+		Engine:     cfg.Engine,
+		Repository: cfg.Repository,
+		Admission:  cfg.Admission,
+		Hooks:      cfg.Hooks,
+		Logger:     cfg.Logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create create service: %w", err)
+	}
+
+	startSvc, err := start.NewService(start.ServiceConfig{
+		Engine:     cfg.Engine,
+		Repository: cfg.Repository,
+		Admission:  cfg.Admission,
+		Hooks:      cfg.Hooks,
+		Logger:     cfg.Logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create start service: %w", err)
+	}
+
+	return &Service{
+		engine: cfg.Engine,
+		repo:   cfg.Repository,
+		create: createSvc,
+		start:  startSvc,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the create and start parameters.
+type Request struct {
+	// Create are the create options, their progress also receives the start steps
+	// unless StartProgress is set.
+	Create create.CreateOptions
+	// SessionConfig is the optional session configuration applied at start time.
+	SessionConfig model.SessionConfig
+	// Timeouts are the optional start step timeouts, the unset ones use the engine defaults.
+	Timeouts model.StartTimeouts
+	// StartProgress receives the start steps (optional).
+	StartProgress model.ProgressFunc
+}
+
+// Result is the result of a create and start.
+type Result struct {
+	// Sandbox is the running sandbox.
+	Sandbox *model.Sandbox
+	// Created is true when the sandbox was created, false when an existing one was
+	// used (create IfNotExists).
+	Created bool
+}
+
+// Run creates and starts a sandbox. When the start fails, or the context is
+// canceled, after the sandbox was created, the sandbox is removed from the engine
+// and the repository. An existing sandbox (create IfNotExists) is started if it's
+// not running and never removed.
+func (s *Service) Run(ctx context.Context, req Request) (*Result, error) {
+	createStart := time.Now()
+	sb, err := s.create.Create(ctx, req.Create)
+	if err != nil {
+		return nil, err
+	}
+	// An existing sandbox returned by IfNotExists was not created now.
+	created := !req.Create.IfNotExists || !sb.CreatedAt.Before(createStart)
+
+	if !created && sb.Status == model.SandboxStatusRunning {
+		return &Result{Sandbox: sb}, nil
+	}
+
+	// The create could finish right before the cancellation.
+	if err := ctx.Err(); err != nil {
+		return nil, s.rollback(ctx, *sb, created, fmt.Errorf("could not start sandbox: %w", err))
+	}
+
+	progress := req.StartProgress
+	if progress == nil {
+		progress = req.Create.Progress
+	}
+	started, err := s.start.Run(ctx, start.Request{
+		NameOrID:      sb.ID,
+		SessionConfig: req.SessionConfig,
+		Timeouts:      req.Timeouts,
+		Progress:      progress,
+	})
+	if err != nil {
+		return nil, s.rollback(ctx, *sb, created, err)
+	}
+
+	return &Result{Sandbox: started, Created: created}, nil
+}
+
+// rollback removes the sandbox created by the failed operation. It returns the
+// operation error with the rollback errors, if any.
+func (s *Service) rollback(ctx context.Context, sb model.Sandbox, created bool, err error) error {
+	if !created {
+		return err
+	}
+	s.logger.Warningf("start of the created sandbox %s failed, removing it: %v", sb.Name, err)
+
+	// The rollback runs even if the operation was canceled.
+	ctx = context.WithoutCancel(ctx)
+	if rmErr := s.engine.Remove(ctx, sb.ID); rmErr != nil {
+		return errors.Join(err, fmt.Errorf("could not remove the created sandbox %s: %w", sb.Name, rmErr))
+	}
+	if delErr := s.repo.DeleteSandbox(ctx, sb.ID); delErr != nil {
+		return errors.Join(err, fmt.Errorf("could not delete the created sandbox %s: %w", sb.Name, delErr))
+	}
+
+	return err
+}
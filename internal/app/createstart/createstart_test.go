@@ -0,0 +1,125 @@
+package createstart_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/create"
+	"github.com/slok/sbx/internal/app/createstart"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox/fake"
+	"github.com/slok/sbx/internal/sandbox/sandboxmock"
+	"github.com/slok/sbx/internal/storage/memory"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+const testSandboxID = "01H2QWERTYASDFGZXCVBNMLKJH"
+
+func validConfig() model.SandboxConfig {
+	return model.SandboxConfig{
+		Name: "test-sandbox",
+		FirecrackerEngine: &model.FirecrackerEngineConfig{
+			RootFS:      "/images/rootfs.ext4",
+			KernelImage: "/images/vmlinux",
+		},
+		Resources: model.Resources{VCPUs: 2, MemoryMB: 2048, DiskGB: 10},
+	}
+}
+
+func TestServiceRun(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	eng, err := fake.NewEngine(fake.EngineConfig{})
+	require.NoError(err)
+	repo, err := memory.NewRepository(memory.RepositoryConfig{})
+	require.NoError(err)
+	svc, err := createstart.NewService(createstart.ServiceConfig{Engine: eng, Repository: repo, Logger: log.Noop})
+	require.NoError(err)
+
+	res, err := svc.Run(ctx, createstart.Request{Create: create.CreateOptions{Config: validConfig()}})
+	require.NoError(err)
+	assert.True(res.Created)
+	assert.Equal(model.SandboxStatusRunning, res.Sandbox.Status)
+
+	// An existing running sandbox is returned as is.
+	res, err = svc.Run(ctx, createstart.Request{Create: create.CreateOptions{Config: validConfig(), IfNotExists: true}})
+	require.NoError(err)
+	assert.False(res.Created)
+	assert.Equal(model.SandboxStatusRunning, res.Sandbox.Status)
+
+	// Without IfNotExists the name is taken.
+	_, err = svc.Run(ctx, createstart.Request{Create: create.CreateOptions{Config: validConfig()}})
+	assert.ErrorIs(err, model.ErrAlreadyExists)
+}
+
+func TestServiceRunRollback(t *testing.T) {
+	created := &model.Sandbox{ID: testSandboxID, Name: "test-sandbox", Status: model.SandboxStatusStopped, Config: validConfig()}
+
+	tests := map[string]struct {
+		mock   func(eng *sandboxmock.MockEngine, repo *storagemock.MockRepository)
+		ctx    func() context.Context
+		expErr error
+	}{
+		"A failed start should remove the created sandbox.": {
+			mock: func(eng *sandboxmock.MockEngine, repo *storagemock.MockRepository) {
+				repo.On("GetSandboxByName", mock.Anything, "test-sandbox").Once().Return(nil, model.ErrNotFound)
+				eng.On("Create", mock.Anything, mock.Anything, mock.Anything).Once().Return(created, nil)
+				repo.On("CreateSandbox", mock.Anything, mock.Anything).Once().Return(nil)
+				repo.On("GetSandboxByName", mock.Anything, testSandboxID).Once().Return(nil, model.ErrNotFound)
+				repo.On("GetSandbox", mock.Anything, testSandboxID).Once().Return(created, nil)
+				eng.On("Start", mock.Anything, testSandboxID, mock.Anything).Once().Return(nil, fmt.Errorf("boot failed"))
+				repo.On("UpdateSandbox", mock.Anything, mock.Anything).Once().Return(nil)
+				eng.On("Remove", mock.Anything, testSandboxID).Once().Return(nil)
+				repo.On("DeleteSandbox", mock.Anything, testSandboxID).Once().Return(nil)
+			},
+			ctx: context.Background,
+		},
+		"A canceled operation should remove the created sandbox.": {
+			mock: func(eng *sandboxmock.MockEngine, repo *storagemock.MockRepository) {
+				repo.On("GetSandboxByName", mock.Anything, "test-sandbox").Once().Return(nil, model.ErrNotFound)
+				eng.On("Create", mock.Anything, mock.Anything, mock.Anything).Once().Return(created, nil)
+				repo.On("CreateSandbox", mock.Anything, mock.Anything).Once().Return(nil)
+				eng.On("Remove", mock.Anything, testSandboxID).Once().Return(nil)
+				repo.On("DeleteSandbox", mock.Anything, testSandboxID).Once().Return(nil)
+			},
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx
+			},
+			expErr: context.Canceled,
+		},
+		"A failed create should not remove anything.": {
+			mock: func(eng *sandboxmock.MockEngine, repo *storagemock.MockRepository) {
+				repo.On("GetSandboxByName", mock.Anything, "test-sandbox").Once().Return(nil, model.ErrNotFound)
+				eng.On("Create", mock.Anything, mock.Anything, mock.Anything).Once().Return(nil, fmt.Errorf("something"))
+			},
+			ctx: context.Background,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			eng := sandboxmock.NewMockEngine(t)
+			repo := storagemock.NewMockRepository(t)
+			test.mock(eng, repo)
+
+			svc, err := createstart.NewService(createstart.ServiceConfig{Engine: eng, Repository: repo, Logger: log.Noop})
+			require.NoError(t, err)
+
+			_, err = svc.Run(test.ctx(), createstart.Request{Create: create.CreateOptions{Config: validConfig()}})
+			assert.Error(t, err)
+			if test.expErr != nil {
+				assert.ErrorIs(t, err, test.expErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package datamigrate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/slok/sbx/internal/conventions"
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
+	fileutil "github.com/slok/sbx/internal/utils/file"
+)
+
+// ServiceConfig is the configuration for the data layout migration service.
+type ServiceConfig struct {
+	Repository storage.Repository
+	// ImageManager lists the images of the source layout.
+	ImageManager image.ImageManager
+	// ImageMover moves the images of the source layout.
+	ImageMover image.ImageMover
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+	if c.ImageManager == nil {
+		return fmt.Errorf("image manager is required")
+	}
+	if c.ImageMover == nil {
+		return fmt.Errorf("image mover is required")
+	}
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.DataMigrate"})
+	return nil
+}
+
+// Service moves the sandbox VMs, images and snapshots between data layouts.
+type Service struct {
+	repo   storage.Repository
+	imgMgr image.ImageManager
+	mover  image.ImageMover
+	logger log.Logger
+}
+
+// NewService creates a new data layout migration service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &Service{
+		repo:   cfg.Repository,
+		imgMgr: cfg.ImageManager,
+		mover:  cfg.ImageMover,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the data layout migration request parameters.
+type Request struct {
+	// From is the current layout.
+	From model.DataLayout
+	// To is the new layout.
+	To model.DataLayout
+	// DryRun only reports the moves.
+	DryRun bool
+}
+
+// Run moves the VM directories of the sandboxes and the installed images to the new
+// layout and rewrites the sandbox paths pointing to them. The sandboxes must be
+// stopped, the repository must be scoped to all the namespaces to see every VM.
+func (s *Service) Run(ctx context.Context, req Request) ([]model.DataMove, error) {
+	if err := req.From.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid source layout: %w", err)
+	}
+	if err := req.To.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid destination layout: %w", err)
+	}
+
+	sandboxes, err := s.repo.ListSandboxes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not list sandboxes: %w", err)
+	}
+	for _, sb := range sandboxes {
+		if sb.Status == model.SandboxStatusRunning || sb.Status == model.SandboxStatusPending {
+			return nil, fmt.Errorf("sandbox %s is %s, stop it before migrating the data: %w", sb.Name, sb.Status, model.ErrNotValid)
+		}
+	}
+
+	moves := []model.DataMove{}
+
+	// Sandbox VMs.
+	if filepath.Clean(req.From.VMsDir) != filepath.Clean(req.To.VMsDir) {
+		for _, sb := range sandboxes {
+			from := conventions.VMDir(req.From.VMsDir, sb.ID)
+			if _, err := os.Stat(from); err != nil {
+				continue
+			}
+			to := conventions.VMDir(req.To.VMsDir, sb.ID)
+			if !req.DryRun {
+				if err := fileutil.MoveDir(ctx, from, to); err != nil {
+					return moves, fmt.Errorf("could not move sandbox %s VM: %w", sb.Name, err)
+				}
+			}
+			moves = append(moves, model.DataMove{Kind: model.DataMoveKindVM, Name: sb.Name, From: from, To: to})
+		}
+	}
+
+	// Images and snapshots.
+	images, err := s.imgMgr.List(ctx)
+	if err != nil {
+		return moves, fmt.Errorf("could not list images: %w", err)
+	}
+	for _, img := range images {
+		kind, toDir := model.DataMoveKindImage, req.To.ImagesDir
+		if img.Source == model.ImageSourceSnapshot {
+			kind, toDir = model.DataMoveKindSnapshot, req.To.SnapshotsDir
+		}
+		fromDir, err := s.mover.Move(ctx, img.Version, toDir, req.DryRun)
+		if err != nil {
+			return moves, fmt.Errorf("could not move image %s: %w", img.Version, err)
+		}
+		if filepath.Clean(fromDir) == filepath.Clean(toDir) {
+			continue
+		}
+		moves = append(moves, model.DataMove{Kind: kind, Name: img.Version, From: filepath.Join(fromDir, img.Version), To: filepath.Join(toDir, img.Version)})
+	}
+
+	if req.DryRun {
+		return moves, nil
+	}
+
+	// Sandbox paths pointing to the moved directories (e.g. the image kernel).
+	for _, sb := range sandboxes {
+		fc := sb.Config.FirecrackerEngine
+		if fc == nil {
+			continue
+		}
+		rootFS, kernel := relocate(fc.RootFS, moves), relocate(fc.KernelImage, moves)
+		if rootFS == fc.RootFS && kernel == fc.KernelImage {
+			continue
+		}
+		updated := *fc
+		updated.RootFS, updated.KernelImage = rootFS, kernel
+		sb.Config.FirecrackerEngine = &updated
+		if err := s.repo.UpdateSandbox(ctx, sb); err != nil {
+			return moves, fmt.Errorf("could not update sandbox %s paths: %w", sb.Name, err)
+		}
+	}
+
+	s.logger.Infof("Migrated %d sandbox data directories", len(moves))
+	return moves, nil
+}
+
+// relocate returns the path in its moved directory, unchanged when it wasn't moved.
+func relocate(path string, moves []model.DataMove) string {
+	for _, m := range moves {
+		if path == m.From {
+			return m.To
+		}
+		if rel, ok := strings.CutPrefix(path, m.From+string(filepath.Separator)); ok {
+			return filepath.Join(m.To, rel)
+		}
+	}
+	return path
+}
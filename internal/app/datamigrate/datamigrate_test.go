@@ -0,0 +1,128 @@
+package datamigrate_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/datamigrate"
+	"github.com/slok/sbx/internal/image/imagemock"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestServiceRun(t *testing.T) {
+	errTest := errors.New("whatever")
+	stopped := model.Sandbox{
+		ID:     "sb-id",
+		Name:   "my-sandbox",
+		Status: model.SandboxStatusStopped,
+		Config: model.SandboxConfig{FirecrackerEngine: &model.FirecrackerEngineConfig{
+			RootFS:      "/old/images/v0.1.0/rootfs-x86_64.ext4",
+			KernelImage: "/old/images/v0.1.0/vmlinux-x86_64",
+		}},
+	}
+	images := []model.ImageRelease{
+		{Version: "v0.1.0", Source: model.ImageSourceRelease},
+		{Version: "my-snap", Source: model.ImageSourceSnapshot},
+	}
+
+	tests := map[string]struct {
+		mock     func(mr *storagemock.MockRepository, mm *imagemock.MockImageManager, mv *imagemock.MockImageMover)
+		dryRun   bool
+		expMoves func(vmsDir, toVMsDir string) []model.DataMove
+		expVMs   bool
+		expErr   error
+	}{
+		"Migrating should move the VMs and images and rewrite the sandbox paths.": {
+			mock: func(mr *storagemock.MockRepository, mm *imagemock.MockImageManager, mv *imagemock.MockImageMover) {
+				mr.On("ListSandboxes", mock.Anything).Once().Return([]model.Sandbox{stopped}, nil)
+				mm.On("List", mock.Anything).Once().Return(images, nil)
+				mv.On("Move", mock.Anything, "v0.1.0", "/new/images", false).Once().Return("/old/images", nil)
+				mv.On("Move", mock.Anything, "my-snap", "/new/snapshots", false).Once().Return("/old/images", nil)
+				mr.On("UpdateSandbox", mock.Anything, mock.MatchedBy(func(sb model.Sandbox) bool {
+					fc := sb.Config.FirecrackerEngine
+					return fc.RootFS == "/new/images/v0.1.0/rootfs-x86_64.ext4" && fc.KernelImage == "/new/images/v0.1.0/vmlinux-x86_64"
+				})).Once().Return(nil)
+			},
+			expMoves: func(vmsDir, toVMsDir string) []model.DataMove {
+				return []model.DataMove{
+					{Kind: model.DataMoveKindVM, Name: "my-sandbox", From: filepath.Join(vmsDir, "sb-id"), To: filepath.Join(toVMsDir, "sb-id")},
+					{Kind: model.DataMoveKindImage, Name: "v0.1.0", From: "/old/images/v0.1.0", To: "/new/images/v0.1.0"},
+					{Kind: model.DataMoveKindSnapshot, Name: "my-snap", From: "/old/images/my-snap", To: "/new/snapshots/my-snap"},
+				}
+			},
+			expVMs: true,
+		},
+
+		"A dry run should only report the moves.": {
+			mock: func(mr *storagemock.MockRepository, mm *imagemock.MockImageManager, mv *imagemock.MockImageMover) {
+				mr.On("ListSandboxes", mock.Anything).Once().Return([]model.Sandbox{stopped}, nil)
+				mm.On("List", mock.Anything).Once().Return(images[:1], nil)
+				mv.On("Move", mock.Anything, "v0.1.0", "/new/images", true).Once().Return("/new/images", nil)
+			},
+			dryRun: true,
+			expMoves: func(vmsDir, toVMsDir string) []model.DataMove {
+				return []model.DataMove{
+					{Kind: model.DataMoveKindVM, Name: "my-sandbox", From: filepath.Join(vmsDir, "sb-id"), To: filepath.Join(toVMsDir, "sb-id")},
+				}
+			},
+		},
+
+		"A running sandbox should fail.": {
+			mock: func(mr *storagemock.MockRepository, mm *imagemock.MockImageManager, mv *imagemock.MockImageMover) {
+				mr.On("ListSandboxes", mock.Anything).Once().Return([]model.Sandbox{{ID: "sb-id", Name: "my-sandbox", Status: model.SandboxStatusRunning}}, nil)
+			},
+			expErr: model.ErrNotValid,
+		},
+
+		"An error moving an image should fail.": {
+			mock: func(mr *storagemock.MockRepository, mm *imagemock.MockImageManager, mv *imagemock.MockImageMover) {
+				mr.On("ListSandboxes", mock.Anything).Once().Return([]model.Sandbox{}, nil)
+				mm.On("List", mock.Anything).Once().Return(images[:1], nil)
+				mv.On("Move", mock.Anything, "v0.1.0", "/new/images", false).Once().Return("", errTest)
+			},
+			expErr: errTest,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mr := storagemock.NewMockRepository(t)
+			mm := imagemock.NewMockImageManager(t)
+			mv := imagemock.NewMockImageMover(t)
+			test.mock(mr, mm, mv)
+
+			vmsDir, toVMsDir := t.TempDir(), filepath.Join(t.TempDir(), "vms")
+			require.NoError(t, os.MkdirAll(filepath.Join(vmsDir, "sb-id"), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(vmsDir, "sb-id", "rootfs.ext4"), []byte("rootfs"), 0o644))
+
+			svc, err := datamigrate.NewService(datamigrate.ServiceConfig{Repository: mr, ImageManager: mm, ImageMover: mv})
+			require.NoError(t, err)
+
+			moves, err := svc.Run(context.Background(), datamigrate.Request{
+				From:   model.DataLayout{VMsDir: vmsDir, ImagesDir: "/old/images", SnapshotsDir: "/old/images"},
+				To:     model.DataLayout{VMsDir: toVMsDir, ImagesDir: "/new/images", SnapshotsDir: "/new/snapshots"},
+				DryRun: test.dryRun,
+			})
+			if test.expErr != nil {
+				assert.ErrorIs(t, err, test.expErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expMoves(vmsDir, toVMsDir), moves)
+			if test.expVMs {
+				assert.FileExists(t, filepath.Join(toVMsDir, "sb-id", "rootfs.ext4"))
+				assert.NoDirExists(t, filepath.Join(vmsDir, "sb-id"))
+			} else {
+				assert.DirExists(t, filepath.Join(vmsDir, "sb-id"))
+			}
+		})
+	}
+}
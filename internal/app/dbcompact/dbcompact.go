@@ -0,0 +1,65 @@
+package dbcompact
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the database compaction service.
+type ServiceConfig struct {
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.DBCompact"})
+	return nil
+}
+
+// Service handles the database maintenance.
+type Service struct {
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new database compaction service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &Service{
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Run checkpoints, checks and compacts the database.
+func (s *Service) Run(ctx context.Context) (*model.DBCompactResult, error) {
+	m, ok := s.repo.(storage.Maintainer)
+	if !ok {
+		return nil, fmt.Errorf("the repository doesn't support maintenance: %w", model.ErrNotValid)
+	}
+
+	res, err := m.Compact(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not compact database: %w", err)
+	}
+
+	if len(res.IntegrityErrors) > 0 {
+		s.logger.Warningf("Database integrity check found %d problems, the database was not compacted", len(res.IntegrityErrors))
+	} else {
+		s.logger.Infof("Database compacted from %d to %d bytes in %s", res.SizeBefore, res.SizeAfter, res.Duration)
+	}
+
+	return res, nil
+}
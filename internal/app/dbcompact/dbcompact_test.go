@@ -0,0 +1,62 @@
+package dbcompact_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/dbcompact"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
+	"github.com/slok/sbx/internal/storage/memory"
+	"github.com/slok/sbx/internal/storage/sqlite"
+)
+
+func TestServiceRun(t *testing.T) {
+	tests := map[string]struct {
+		repo     func(t *testing.T) storage.Repository
+		expErrIs error
+	}{
+		"Compacting a SQLite database should check and compact it.": {
+			repo: func(t *testing.T) storage.Repository {
+				repo, err := sqlite.NewRepository(context.Background(), sqlite.RepositoryConfig{
+					DBPath: filepath.Join(t.TempDir(), "test.db"),
+				})
+				require.NoError(t, err)
+				t.Cleanup(func() { _ = repo.Close() })
+				return repo
+			},
+		},
+		"Compacting a repository without maintenance should fail.": {
+			repo: func(t *testing.T) storage.Repository {
+				repo, err := memory.NewRepository(memory.RepositoryConfig{})
+				require.NoError(t, err)
+				return repo
+			},
+			expErrIs: model.ErrNotValid,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			svc, err := dbcompact.NewService(dbcompact.ServiceConfig{Repository: test.repo(t), Logger: log.Noop})
+			require.NoError(err)
+
+			res, err := svc.Run(context.Background())
+			if test.expErrIs != nil {
+				assert.ErrorIs(err, test.expErrIs)
+				return
+			}
+			require.NoError(err)
+			assert.Empty(res.IntegrityErrors)
+			assert.Positive(res.SizeAfter)
+		})
+	}
+}
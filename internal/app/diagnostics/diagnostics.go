@@ -0,0 +1,422 @@
+package diagnostics
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/slok/sbx/internal/conventions"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// DefaultMaxLogBytes is the default size of the log tails included in the bundle.
+const DefaultMaxLogBytes = 1 << 20
+
+// bundleDir is the directory of the bundle files in the tarball.
+const bundleDir = "sbx-diagnostics"
+
+// ServiceConfig is the configuration for the diagnostics service.
+type ServiceConfig struct {
+	// Engine runs the preflight checks (doctor).
+	Engine     sandbox.Engine
+	Repository storage.Repository
+	Logger     log.Logger
+	// VMsDir is the sandbox VMs directory (default: ~/.sbx/vms).
+	VMsDir string
+	// Ruleset returns the host firewall ruleset (default: nft list ruleset).
+	Ruleset func(ctx context.Context) ([]byte, error)
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Engine == nil {
+		return fmt.Errorf("engine is required")
+	}
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+	if c.VMsDir == "" {
+		return fmt.Errorf("vms dir is required")
+	}
+	if c.Ruleset == nil {
+		c.Ruleset = nftRuleset
+	}
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.Diagnostics"})
+	return nil
+}
+
+// Service collects the diagnostics bundles attached to bug reports.
+type Service struct {
+	engine  sandbox.Engine
+	repo    storage.Repository
+	logger  log.Logger
+	vmsDir  string
+	ruleset func(ctx context.Context) ([]byte, error)
+}
+
+// NewService creates a new diagnostics service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &Service{
+		engine:  cfg.Engine,
+		repo:    cfg.Repository,
+		logger:  cfg.Logger,
+		vmsDir:  cfg.VMsDir,
+		ruleset: cfg.Ruleset,
+	}, nil
+}
+
+// Request represents a diagnostics request.
+type Request struct {
+	// NameOrID is the sandbox whose logs and configuration are included (optional).
+	NameOrID string
+	// Version is the sbx version, empty uses the sbx module version of the binary.
+	Version string
+	// MaxLogBytes is the size of the log tails (default: DefaultMaxLogBytes).
+	MaxLogBytes int64
+	// Output receives the bundle (gzip compressed tarball).
+	Output io.Writer
+}
+
+// info is the host and sbx information of the bundle.
+type info struct {
+	Version   string    `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	OS        string    `json:"os"`
+	Arch      string    `json:"arch"`
+	Kernel    string    `json:"kernel,omitempty"`
+	Sandbox   string    `json:"sandbox,omitempty"`
+}
+
+// Run writes the diagnostics bundle to the request output: the sbx and host
+// information, the doctor checks, the sandbox list, the host nftables ruleset and,
+// for the requested sandbox, its configuration and the tails of its engine and
+// egress proxy logs. The parts that can't be collected are listed in errors.txt
+// instead of failing. The bundle is sanitized: the home directory, the hostname,
+// the user data and the secret looking values are redacted.
+func (s *Service) Run(ctx context.Context, req Request) error {
+	if req.Output == nil {
+		return fmt.Errorf("output is required: %w", model.ErrNotValid)
+	}
+	if req.MaxLogBytes < 0 {
+		return fmt.Errorf("max log bytes can't be negative: %w", model.ErrNotValid)
+	}
+	if req.MaxLogBytes == 0 {
+		req.MaxLogBytes = DefaultMaxLogBytes
+	}
+
+	var sb *model.Sandbox
+	if req.NameOrID != "" {
+		var err error
+		sb, err = s.repo.GetSandboxByName(ctx, req.NameOrID)
+		if errors.Is(err, model.ErrNotFound) {
+			sb, err = s.repo.GetSandbox(ctx, req.NameOrID)
+		}
+		if err != nil {
+			return fmt.Errorf("could not find sandbox: %w", err)
+		}
+	}
+
+	b := newBundle(req.Output, newSanitizer())
+	var collectErrs []string
+	collectErr := func(part string, err error) {
+		s.logger.Debugf("could not collect %s: %s", part, err)
+		collectErrs = append(collectErrs, fmt.Sprintf("%s: %s", part, err))
+	}
+
+	inf := info{
+		Version:   req.Version,
+		CreatedAt: time.Now().UTC(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		Kernel:    kernelRelease(),
+	}
+	if inf.Version == "" {
+		inf.Version = moduleVersion()
+	}
+	if sb != nil {
+		inf.Sandbox = sb.Name
+	}
+	if err := b.addJSON("info.json", inf); err != nil {
+		return err
+	}
+
+	if err := b.addJSON("doctor.json", s.engine.Check(ctx)); err != nil {
+		return err
+	}
+
+	sandboxes, err := s.repo.ListSandboxes(ctx)
+	if err != nil {
+		collectErr("sandboxes.json", err)
+	} else {
+		for i := range sandboxes {
+			redactSandbox(&sandboxes[i])
+		}
+		if err := b.addJSON("sandboxes.json", sandboxes); err != nil {
+			return err
+		}
+	}
+
+	ruleset, err := s.ruleset(ctx)
+	if err != nil {
+		collectErr("nftables.txt", err)
+	} else if err := b.add("nftables.txt", ruleset); err != nil {
+		return err
+	}
+
+	if sb != nil {
+		redacted := *sb
+		redactSandbox(&redacted)
+		if err := b.addJSON("sandbox/sandbox.json", redacted); err != nil {
+			return err
+		}
+		if err := b.addJSON("sandbox/config.json", redacted.Config); err != nil {
+			return err
+		}
+
+		files, err := sandboxFiles(conventions.VMDir(s.vmsDir, sb.ID))
+		if err != nil {
+			collectErr("sandbox files", err)
+		}
+		for _, f := range files {
+			data, err := tailFile(f, req.MaxLogBytes)
+			if err != nil {
+				collectErr(path.Join("sandbox", filepath.Base(f)), err)
+				continue
+			}
+			if err := b.add(path.Join("sandbox", filepath.Base(f)), data); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(collectErrs) > 0 {
+		if err := b.add("errors.txt", []byte(strings.Join(collectErrs, "\n")+"\n")); err != nil {
+			return err
+		}
+	}
+
+	if err := b.close(); err != nil {
+		return err
+	}
+
+	s.logger.Debugf("collected diagnostics bundle with %d errors", len(collectErrs))
+	return nil
+}
+
+// redactSandbox removes the sandbox data that can have secrets.
+func redactSandbox(sb *model.Sandbox) {
+	if sb.Config.UserData != "" {
+		sb.Config.UserData = "<redacted>"
+	}
+	if sb.Session != nil {
+		session := sb.Session.Redacted()
+		sb.Session = &session
+	}
+}
+
+// sandboxFiles returns the engine and egress proxy logs and state files of the VM
+// directory, including the ones of every network interface.
+func sandboxFiles(vmDir string) ([]string, error) {
+	if _, err := os.Stat(vmDir); err != nil {
+		return nil, err
+	}
+
+	set := map[string]struct{}{}
+	for _, base := range []string{
+		conventions.LogFile,
+		conventions.ProxyLogFile,
+		conventions.ProxyPortFile,
+		conventions.ProxyStateFile,
+		conventions.DNSEventsFile,
+		conventions.ProxySessionsFile,
+	} {
+		ext := filepath.Ext(base)
+		matches, err := filepath.Glob(filepath.Join(vmDir, strings.TrimSuffix(base, ext)+"*"+ext))
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			set[m] = struct{}{}
+		}
+	}
+
+	files := make([]string, 0, len(set))
+	for f := range set {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// tailFile returns the last max bytes of the file, starting on a full line when
+// the file is bigger.
+func tailFile(p string, max int64) ([]byte, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() <= max {
+		return io.ReadAll(f)
+	}
+
+	if _, err := f.Seek(info.Size()-max, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		data = data[i+1:]
+	}
+	return data, nil
+}
+
+func nftRuleset(ctx context.Context) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, "nft", "list", "ruleset").CombinedOutput()
+	if err != nil {
+		if msg := bytes.TrimSpace(out); len(msg) > 0 {
+			return nil, fmt.Errorf("nft list ruleset: %w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("nft list ruleset: %w", err)
+	}
+	return out, nil
+}
+
+func kernelRelease() string {
+	data, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// moduleVersion returns the sbx module version of the running binary, also when
+// sbx is used as a library.
+func moduleVersion() string {
+	const modPath = "github.com/slok/sbx"
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if bi.Main.Path == modPath {
+		return bi.Main.Version
+	}
+	for _, dep := range bi.Deps {
+		if dep.Path == modPath {
+			return dep.Version
+		}
+	}
+	return "unknown"
+}
+
+// secretRegexp matches the values of the secret looking keys (e.g. TOKEN=x, "password": "x").
+var secretRegexp = regexp.MustCompile(`(?i)((?:token|secret|password|passwd|api[_-]?key|authorization)[A-Za-z0-9_-]*"?\s*[:=]\s*"?)[^\s",]+`)
+
+// sanitizer redacts the host identifying information and secrets of the bundle files.
+type sanitizer struct {
+	home *regexp.Regexp
+	host *regexp.Regexp
+}
+
+func newSanitizer() sanitizer {
+	var s sanitizer
+	// Only the full paths and words are replaced (e.g. /root but not /rootfs).
+	if home, err := os.UserHomeDir(); err == nil && home != "" && home != "/" {
+		s.home = regexp.MustCompile(`(^|[^\w./-])` + regexp.QuoteMeta(home) + `(/|$|[^\w.-])`)
+	}
+	if host, err := os.Hostname(); err == nil && host != "" && host != "localhost" {
+		s.host = regexp.MustCompile(`\b` + regexp.QuoteMeta(host) + `\b`)
+	}
+	return s
+}
+
+func (s sanitizer) sanitize(data []byte) []byte {
+	if s.home != nil {
+		data = s.home.ReplaceAll(data, []byte("${1}~${2}"))
+	}
+	if s.host != nil {
+		data = s.host.ReplaceAll(data, []byte("<hostname>"))
+	}
+	return secretRegexp.ReplaceAll(data, []byte("${1}<redacted>"))
+}
+
+// bundle is a gzip compressed tarball with sanitized files.
+type bundle struct {
+	gz        *gzip.Writer
+	tw        *tar.Writer
+	sanitizer sanitizer
+	modTime   time.Time
+}
+
+func newBundle(w io.Writer, s sanitizer) *bundle {
+	gz := gzip.NewWriter(w)
+	return &bundle{gz: gz, tw: tar.NewWriter(gz), sanitizer: s, modTime: time.Now()}
+}
+
+func (b *bundle) add(name string, data []byte) error {
+	data = b.sanitizer.sanitize(data)
+	hdr := &tar.Header{
+		Name:    path.Join(bundleDir, name),
+		Mode:    0o644,
+		Size:    int64(len(data)),
+		ModTime: b.modTime,
+	}
+	if err := b.tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("could not write %s: %w", name, err)
+	}
+	if _, err := b.tw.Write(data); err != nil {
+		return fmt.Errorf("could not write %s: %w", name, err)
+	}
+	return nil
+}
+
+func (b *bundle) addJSON(name string, v any) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("could not marshal %s: %w", name, err)
+	}
+	return b.add(name, buf.Bytes())
+}
+
+func (b *bundle) close() error {
+	if err := b.tw.Close(); err != nil {
+		return fmt.Errorf("could not write bundle: %w", err)
+	}
+	if err := b.gz.Close(); err != nil {
+		return fmt.Errorf("could not write bundle: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package diagnostics_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/diagnostics"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox/sandboxmock"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestNewService(t *testing.T) {
+	tests := map[string]struct {
+		config diagnostics.ServiceConfig
+		expErr bool
+	}{
+		"valid config should create service": {
+			config: diagnostics.ServiceConfig{
+				Engine:     &sandboxmock.MockEngine{},
+				Repository: &storagemock.MockRepository{},
+				VMsDir:     "/data/vms",
+			},
+		},
+		"missing engine should fail": {
+			config: diagnostics.ServiceConfig{
+				Repository: &storagemock.MockRepository{},
+				VMsDir:     "/data/vms",
+			},
+			expErr: true,
+		},
+		"missing repository should fail": {
+			config: diagnostics.ServiceConfig{
+				Engine: &sandboxmock.MockEngine{},
+				VMsDir: "/data/vms",
+			},
+			expErr: true,
+		},
+		"missing data dir should fail": {
+			config: diagnostics.ServiceConfig{
+				Engine:     &sandboxmock.MockEngine{},
+				Repository: &storagemock.MockRepository{},
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			svc, err := diagnostics.NewService(test.config)
+
+			if test.expErr {
+				require.Error(err)
+				require.Nil(svc)
+			} else {
+				require.NoError(err)
+				require.NotNil(svc)
+			}
+		})
+	}
+}
+
+// readBundle returns the files of a bundle by their path in the bundle directory.
+func readBundle(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	files := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[strings.TrimPrefix(hdr.Name, "sbx-diagnostics/")] = string(content)
+	}
+	return files
+}
+
+func TestServiceRun(t *testing.T) {
+	sb := model.Sandbox{
+		ID:     "01SB0000000000000000000000",
+		Name:   "sb",
+		Status: model.SandboxStatusRunning,
+		Config: model.SandboxConfig{
+			Name: "sb",
+			FirecrackerEngine: &model.FirecrackerEngineConfig{
+				RootFS:      "/images/rootfs.ext4",
+				KernelImage: "/images/vmlinux",
+			},
+			Resources: model.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+			UserData:  "export GITHUB_TOKEN=ghp_secret\n",
+		},
+	}
+
+	// writeVMFiles writes the files of the sandbox VM directory.
+	writeVMFiles := func(t *testing.T, vmsDir string) {
+		vmDir := filepath.Join(vmsDir, sb.ID)
+		require.NoError(t, os.MkdirAll(vmDir, 0o755))
+		files := map[string]string{
+			"firecracker.log":       "old line\nboot ok\n",
+			"proxy.log":             "api_key=abc\n",
+			"proxy-eth1.log":        "proxy eth1 started\n",
+			"proxy-state.json":      `{"restarts": 1}`,
+			"dns-events.jsonl":      `{"domain":"example.com"}` + "\n",
+			"id_ed25519":            "PRIVATE KEY",
+			"rootfs.ext4":           "disk",
+			"proxy-state.json.tmp":  "partial",
+			"firecracker.sock":      "",
+			"firecracker.pid":       "1234",
+			"proxy.pid":             "1235",
+			"unrelated-notes.jsonl": "{}",
+		}
+		for name, content := range files {
+			require.NoError(t, os.WriteFile(filepath.Join(vmDir, name), []byte(content), 0o644))
+		}
+	}
+
+	tests := map[string]struct {
+		mock        func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine)
+		req         diagnostics.Request
+		ruleset     func(ctx context.Context) ([]byte, error)
+		expFiles    []string
+		expContains map[string][]string
+		expMissing  map[string][]string
+		expErr      error
+	}{
+		"A bundle without sandbox should have the host diagnostics.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				me.On("Check", mock.Anything).Once().Return([]model.CheckResult{{ID: "kvm", Message: "KVM is available", Status: model.CheckStatusOK}})
+				mr.On("ListSandboxes", mock.Anything).Once().Return([]model.Sandbox{sb}, nil)
+			},
+			req: diagnostics.Request{Version: "v1.2.3"},
+			ruleset: func(ctx context.Context) ([]byte, error) {
+				return []byte("table ip sbx {\n}\n"), nil
+			},
+			expFiles: []string{"info.json", "doctor.json", "sandboxes.json", "nftables.txt"},
+			expContains: map[string][]string{
+				"info.json":      {`"version": "v1.2.3"`},
+				"doctor.json":    {"KVM is available"},
+				"sandboxes.json": {sb.ID, `"UserData": "<redacted>"`},
+				"nftables.txt":   {"table ip sbx"},
+			},
+			expMissing: map[string][]string{
+				"sandboxes.json": {"ghp_secret"},
+			},
+		},
+
+		"A bundle with a sandbox should have its config and logs.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "sb").Once().Return(&sb, nil)
+				me.On("Check", mock.Anything).Once().Return([]model.CheckResult{})
+				mr.On("ListSandboxes", mock.Anything).Once().Return([]model.Sandbox{sb}, nil)
+			},
+			req: diagnostics.Request{NameOrID: "sb", Version: "v1.2.3", MaxLogBytes: 12},
+			ruleset: func(ctx context.Context) ([]byte, error) {
+				return []byte("table ip sbx {\n}\n"), nil
+			},
+			expFiles: []string{
+				"info.json", "doctor.json", "sandboxes.json", "nftables.txt",
+				"sandbox/sandbox.json", "sandbox/config.json",
+				"sandbox/dns-events.jsonl", "sandbox/firecracker.log", "sandbox/proxy-eth1.log",
+				"sandbox/proxy-state.json", "sandbox/proxy.log",
+			},
+			expContains: map[string][]string{
+				"info.json":               {`"sandbox": "sb"`},
+				"sandbox/config.json":     {"/images/rootfs.ext4"},
+				"sandbox/firecracker.log": {"boot ok"},
+				"sandbox/proxy.log":       {"api_key=<redacted>"},
+			},
+			expMissing: map[string][]string{
+				"sandbox/firecracker.log": {"old line"},
+				"sandbox/proxy.log":       {"abc"},
+				"sandbox/config.json":     {"ghp_secret"},
+			},
+		},
+
+		"The parts that can't be collected should be listed as errors.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				me.On("Check", mock.Anything).Once().Return([]model.CheckResult{})
+				mr.On("ListSandboxes", mock.Anything).Once().Return(nil, errors.New("db locked"))
+			},
+			ruleset: func(ctx context.Context) ([]byte, error) {
+				return nil, errors.New("nft not found")
+			},
+			expFiles: []string{"info.json", "doctor.json", "errors.txt"},
+			expContains: map[string][]string{
+				"errors.txt": {"sandboxes.json: db locked", "nftables.txt: nft not found"},
+			},
+		},
+
+		"A missing sandbox should fail.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "sb").Once().Return(nil, model.ErrNotFound)
+				mr.On("GetSandbox", mock.Anything, "sb").Once().Return(nil, model.ErrNotFound)
+			},
+			req:    diagnostics.Request{NameOrID: "sb"},
+			expErr: model.ErrNotFound,
+		},
+
+		"A negative log size should fail.": {
+			mock:   func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {},
+			req:    diagnostics.Request{MaxLogBytes: -1},
+			expErr: model.ErrNotValid,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			vmsDir := t.TempDir()
+			writeVMFiles(t, vmsDir)
+
+			mr := &storagemock.MockRepository{}
+			me := &sandboxmock.MockEngine{}
+			test.mock(mr, me)
+
+			svc, err := diagnostics.NewService(diagnostics.ServiceConfig{
+				Engine:     me,
+				Repository: mr,
+				VMsDir:     vmsDir,
+				Ruleset:    test.ruleset,
+			})
+			require.NoError(err)
+
+			var out bytes.Buffer
+			test.req.Output = &out
+			err = svc.Run(context.TODO(), test.req)
+
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+			} else if assert.NoError(err) {
+				files := readBundle(t, out.Bytes())
+				var names []string
+				for name := range files {
+					names = append(names, name)
+				}
+				assert.ElementsMatch(test.expFiles, names)
+				for name, subs := range test.expContains {
+					for _, sub := range subs {
+						assert.Contains(files[name], sub, name)
+					}
+				}
+				for name, subs := range test.expMissing {
+					for _, sub := range subs {
+						assert.NotContains(files[name], sub, name)
+					}
+				}
+			}
+
+			mr.AssertExpectations(t)
+			me.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package dnsevents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the DNS events service.
+type ServiceConfig struct {
+	Engine     sandbox.Engine
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Engine == nil {
+		return fmt.Errorf("engine is required")
+	}
+
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.DNSEvents"})
+
+	return nil
+}
+
+// Service returns the DNS queries made by sandboxes.
+type Service struct {
+	engine sandbox.Engine
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new DNS events service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		engine: cfg.Engine,
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the DNS events request parameters.
+type Request struct {
+	// NameOrID is the sandbox name or ID.
+	NameOrID string
+	Filter   model.DNSEventFilter
+}
+
+// Run returns the DNS queries of a sandbox, oldest first.
+func (s *Service) Run(ctx context.Context, req Request) ([]model.DNSEvent, error) {
+	if req.Filter.Limit < 0 {
+		return nil, fmt.Errorf("limit can't be negative: %w", model.ErrNotValid)
+	}
+
+	sb, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
+	if errors.Is(err, model.ErrNotFound) {
+		sb, err = s.repo.GetSandbox(ctx, req.NameOrID)
+	}
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("sandbox not found: %s: %w", req.NameOrID, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not get sandbox: %w", err)
+	}
+
+	events, err := s.engine.DNSEvents(ctx, sb.ID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get DNS events: %w", err)
+	}
+
+	events = filterEvents(events, req.Filter)
+
+	s.logger.Debugf("found %d DNS events for sandbox %s", len(events), sb.ID)
+	return events, nil
+}
+
+// filterEvents returns the events matching the filter, keeping the latest ones
+// when limited.
+func filterEvents(events []model.DNSEvent, filter model.DNSEventFilter) []model.DNSEvent {
+	domain := strings.ToLower(strings.TrimSuffix(filter.Domain, "."))
+
+	filtered := []model.DNSEvent{}
+	for _, ev := range events {
+		if !filter.Since.IsZero() && ev.Time.Before(filter.Since) {
+			continue
+		}
+		if domain != "" && ev.Domain != domain {
+			continue
+		}
+		filtered = append(filtered, ev)
+	}
+
+	if filter.Limit > 0 && len(filtered) > filter.Limit {
+		filtered = filtered[len(filtered)-filter.Limit:]
+	}
+
+	return filtered
+}
@@ -0,0 +1,128 @@
+package dnsevents_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/dnsevents"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox/sandboxmock"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestServiceRun(t *testing.T) {
+	errTest := errors.New("whatever")
+	t0 := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	events := []model.DNSEvent{
+		{Time: t0, Domain: "github.com", QType: "A", Action: model.EgressActionAllow, Rcode: "NOERROR"},
+		{Time: t0.Add(time.Second), Domain: "evil.com", QType: "A", Action: model.EgressActionDeny, Reason: model.DNSEventReasonRuleMatch, Rcode: "REFUSED"},
+		{Time: t0.Add(2 * time.Second), Domain: "github.com", QType: "AAAA", Action: model.EgressActionAllow, Rcode: "NOERROR"},
+	}
+
+	tests := map[string]struct {
+		mock      func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine)
+		req       dnsevents.Request
+		expEvents []model.DNSEvent
+		expErr    error
+	}{
+		"Listing by name should return all the sandbox events.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{ID: "sb-id"}, nil)
+				me.On("DNSEvents", mock.Anything, "sb-id").Once().Return(events, nil)
+			},
+			req:       dnsevents.Request{NameOrID: "my-sandbox"},
+			expEvents: events,
+		},
+
+		"Listing by ID should return the sandbox events.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "sb-id").Once().Return(nil, model.ErrNotFound)
+				mr.On("GetSandbox", mock.Anything, "sb-id").Once().Return(&model.Sandbox{ID: "sb-id"}, nil)
+				me.On("DNSEvents", mock.Anything, "sb-id").Once().Return(events, nil)
+			},
+			req:       dnsevents.Request{NameOrID: "sb-id"},
+			expEvents: events,
+		},
+
+		"Filtering by time should return the events since that time.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{ID: "sb-id"}, nil)
+				me.On("DNSEvents", mock.Anything, "sb-id").Once().Return(events, nil)
+			},
+			req:       dnsevents.Request{NameOrID: "my-sandbox", Filter: model.DNSEventFilter{Since: t0.Add(time.Second)}},
+			expEvents: events[1:],
+		},
+
+		"Filtering by domain should ignore the case and the trailing dot.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{ID: "sb-id"}, nil)
+				me.On("DNSEvents", mock.Anything, "sb-id").Once().Return(events, nil)
+			},
+			req:       dnsevents.Request{NameOrID: "my-sandbox", Filter: model.DNSEventFilter{Domain: "GitHub.com."}},
+			expEvents: []model.DNSEvent{events[0], events[2]},
+		},
+
+		"Limiting should return the latest events.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{ID: "sb-id"}, nil)
+				me.On("DNSEvents", mock.Anything, "sb-id").Once().Return(events, nil)
+			},
+			req:       dnsevents.Request{NameOrID: "my-sandbox", Filter: model.DNSEventFilter{Limit: 2}},
+			expEvents: events[1:],
+		},
+
+		"A missing sandbox should fail.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "missing").Once().Return(nil, model.ErrNotFound)
+				mr.On("GetSandbox", mock.Anything, "missing").Once().Return(nil, model.ErrNotFound)
+			},
+			req:    dnsevents.Request{NameOrID: "missing"},
+			expErr: model.ErrNotFound,
+		},
+
+		"An engine error should fail.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{ID: "sb-id"}, nil)
+				me.On("DNSEvents", mock.Anything, "sb-id").Once().Return(nil, errTest)
+			},
+			req:    dnsevents.Request{NameOrID: "my-sandbox"},
+			expErr: errTest,
+		},
+
+		"A negative limit should fail.": {
+			mock:   func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {},
+			req:    dnsevents.Request{NameOrID: "my-sandbox", Filter: model.DNSEventFilter{Limit: -1}},
+			expErr: model.ErrNotValid,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mRepo := &storagemock.MockRepository{}
+			mEngine := &sandboxmock.MockEngine{}
+			test.mock(mRepo, mEngine)
+
+			svc, err := dnsevents.NewService(dnsevents.ServiceConfig{Engine: mEngine, Repository: mRepo})
+			require.NoError(err)
+
+			got, err := svc.Run(context.Background(), test.req)
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expEvents, got)
+			}
+
+			mRepo.AssertExpectations(t)
+			mEngine.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package dnsstats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the DNS stats service.
+type ServiceConfig struct {
+	Engine     sandbox.Engine
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Engine == nil {
+		return fmt.Errorf("engine is required")
+	}
+
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.DNSStats"})
+
+	return nil
+}
+
+// Service aggregates the DNS queries made by sandboxes per domain.
+type Service struct {
+	engine sandbox.Engine
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new DNS stats service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		engine: cfg.Engine,
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the DNS stats request parameters.
+type Request struct {
+	// NameOrID is the sandbox name or ID.
+	NameOrID string
+	// Since aggregates the queries made at or after this time (optional).
+	Since time.Time
+}
+
+// Run returns the aggregated DNS queries of a sandbox.
+func (s *Service) Run(ctx context.Context, req Request) (*model.DNSStats, error) {
+	sb, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
+	if errors.Is(err, model.ErrNotFound) {
+		sb, err = s.repo.GetSandbox(ctx, req.NameOrID)
+	}
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("sandbox not found: %s: %w", req.NameOrID, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not get sandbox: %w", err)
+	}
+
+	events, err := s.engine.DNSEvents(ctx, sb.ID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get DNS events: %w", err)
+	}
+
+	stats := aggregate(events, req.Since)
+
+	s.logger.Debugf("aggregated %d DNS queries of %d domains for sandbox %s", stats.Queries, len(stats.Domains), sb.ID)
+	return stats, nil
+}
+
+// aggregate returns the stats of the events made at or after since.
+func aggregate(events []model.DNSEvent, since time.Time) *model.DNSStats {
+	stats := &model.DNSStats{Domains: []model.DNSDomainStats{}}
+	domains := map[string]*model.DNSDomainStats{}
+	forwarded := map[string]int{}
+	latencies := map[string]time.Duration{}
+
+	for _, ev := range events {
+		if !since.IsZero() && ev.Time.Before(since) {
+			continue
+		}
+
+		ds, ok := domains[ev.Domain]
+		if !ok {
+			ds = &model.DNSDomainStats{Domain: ev.Domain}
+			domains[ev.Domain] = ds
+		}
+
+		stats.Queries++
+		ds.Queries++
+		switch {
+		case ev.Action == model.EgressActionDeny:
+			stats.Denied++
+			ds.Denied++
+		case ev.Failed():
+			stats.Failed++
+			ds.Failed++
+		default:
+			stats.Allowed++
+			ds.Allowed++
+		}
+
+		if ev.UpstreamLatency > 0 {
+			forwarded[ev.Domain]++
+			latencies[ev.Domain] += ev.UpstreamLatency
+			ds.MaxUpstreamLatency = max(ds.MaxUpstreamLatency, ev.UpstreamLatency)
+		}
+		if ev.Time.After(ds.LastQueryAt) {
+			ds.LastQueryAt = ev.Time
+		}
+	}
+
+	for domain, ds := range domains {
+		if n := forwarded[domain]; n > 0 {
+			ds.AvgUpstreamLatency = latencies[domain] / time.Duration(n)
+		}
+		stats.Domains = append(stats.Domains, *ds)
+	}
+	sort.Slice(stats.Domains, func(i, j int) bool {
+		if stats.Domains[i].Queries != stats.Domains[j].Queries {
+			return stats.Domains[i].Queries > stats.Domains[j].Queries
+		}
+		return stats.Domains[i].Domain < stats.Domains[j].Domain
+	})
+
+	return stats
+}
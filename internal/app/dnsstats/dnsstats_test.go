@@ -0,0 +1,158 @@
+package dnsstats_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/dnsstats"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox/sandboxmock"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestServiceRun(t *testing.T) {
+	errTest := errors.New("whatever")
+	t0 := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	events := []model.DNSEvent{
+		{Time: t0, Domain: "github.com", Action: model.EgressActionAllow, Rcode: "NOERROR", UpstreamLatency: 10 * time.Millisecond},
+		{Time: t0.Add(time.Second), Domain: "evil.com", Action: model.EgressActionDeny, Reason: model.DNSEventReasonRuleMatch, Rcode: "REFUSED"},
+		{Time: t0.Add(2 * time.Second), Domain: "github.com", Action: model.EgressActionAllow, Rcode: "NOERROR", UpstreamLatency: 30 * time.Millisecond},
+		{Time: t0.Add(3 * time.Second), Domain: "github.com", Action: model.EgressActionAllow, Reason: model.DNSEventReasonUpstreamError, Rcode: "SERVFAIL", UpstreamLatency: 2 * time.Second},
+		{Time: t0.Add(4 * time.Second), Domain: "api.example.com", Action: model.EgressActionDeny, Reason: model.DNSEventReasonFailClosed, Rcode: "REFUSED", UpstreamLatency: 20 * time.Millisecond},
+	}
+
+	tests := map[string]struct {
+		mock     func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine)
+		req      dnsstats.Request
+		expStats *model.DNSStats
+		expErr   error
+	}{
+		"The queries should be aggregated per domain, most queried first.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{ID: "sb-id"}, nil)
+				me.On("DNSEvents", mock.Anything, "sb-id").Once().Return(events, nil)
+			},
+			req: dnsstats.Request{NameOrID: "my-sandbox"},
+			expStats: &model.DNSStats{
+				Queries: 5,
+				Allowed: 2,
+				Denied:  2,
+				Failed:  1,
+				Domains: []model.DNSDomainStats{
+					{
+						Domain:             "github.com",
+						Queries:            3,
+						Allowed:            2,
+						Failed:             1,
+						AvgUpstreamLatency: 680 * time.Millisecond,
+						MaxUpstreamLatency: 2 * time.Second,
+						LastQueryAt:        t0.Add(3 * time.Second),
+					},
+					{
+						Domain:             "api.example.com",
+						Queries:            1,
+						Denied:             1,
+						AvgUpstreamLatency: 20 * time.Millisecond,
+						MaxUpstreamLatency: 20 * time.Millisecond,
+						LastQueryAt:        t0.Add(4 * time.Second),
+					},
+					{
+						Domain:      "evil.com",
+						Queries:     1,
+						Denied:      1,
+						LastQueryAt: t0.Add(time.Second),
+					},
+				},
+			},
+		},
+
+		"Aggregating since a time should ignore the previous queries.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "sb-id").Once().Return(nil, model.ErrNotFound)
+				mr.On("GetSandbox", mock.Anything, "sb-id").Once().Return(&model.Sandbox{ID: "sb-id"}, nil)
+				me.On("DNSEvents", mock.Anything, "sb-id").Once().Return(events, nil)
+			},
+			req: dnsstats.Request{NameOrID: "sb-id", Since: t0.Add(3 * time.Second)},
+			expStats: &model.DNSStats{
+				Queries: 2,
+				Denied:  1,
+				Failed:  1,
+				Domains: []model.DNSDomainStats{
+					{
+						Domain:             "api.example.com",
+						Queries:            1,
+						Denied:             1,
+						AvgUpstreamLatency: 20 * time.Millisecond,
+						MaxUpstreamLatency: 20 * time.Millisecond,
+						LastQueryAt:        t0.Add(4 * time.Second),
+					},
+					{
+						Domain:             "github.com",
+						Queries:            1,
+						Failed:             1,
+						AvgUpstreamLatency: 2 * time.Second,
+						MaxUpstreamLatency: 2 * time.Second,
+						LastQueryAt:        t0.Add(3 * time.Second),
+					},
+				},
+			},
+		},
+
+		"A sandbox without queries should have empty stats.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{ID: "sb-id"}, nil)
+				me.On("DNSEvents", mock.Anything, "sb-id").Once().Return([]model.DNSEvent{}, nil)
+			},
+			req:      dnsstats.Request{NameOrID: "my-sandbox"},
+			expStats: &model.DNSStats{Domains: []model.DNSDomainStats{}},
+		},
+
+		"A missing sandbox should fail.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "missing").Once().Return(nil, model.ErrNotFound)
+				mr.On("GetSandbox", mock.Anything, "missing").Once().Return(nil, model.ErrNotFound)
+			},
+			req:    dnsstats.Request{NameOrID: "missing"},
+			expErr: model.ErrNotFound,
+		},
+
+		"An engine error should fail.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{ID: "sb-id"}, nil)
+				me.On("DNSEvents", mock.Anything, "sb-id").Once().Return(nil, errTest)
+			},
+			req:    dnsstats.Request{NameOrID: "my-sandbox"},
+			expErr: errTest,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mRepo := &storagemock.MockRepository{}
+			mEngine := &sandboxmock.MockEngine{}
+			test.mock(mRepo, mEngine)
+
+			svc, err := dnsstats.NewService(dnsstats.ServiceConfig{Engine: mEngine, Repository: mRepo})
+			require.NoError(err)
+
+			got, err := svc.Run(context.Background(), test.req)
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expStats, got)
+			}
+
+			mRepo.AssertExpectations(t)
+			mEngine.AssertExpectations(t)
+		})
+	}
+}
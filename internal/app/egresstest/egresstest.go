@@ -0,0 +1,151 @@
+package egresstest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/proxy"
+)
+
+// ServiceConfig is the configuration for the egress test service.
+type ServiceConfig struct {
+	Logger log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	return nil
+}
+
+// Service evaluates targets against an egress policy offline, without a sandbox.
+type Service struct {
+	logger log.Logger
+}
+
+// NewService creates a new egress test service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &Service{
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the egress test request parameters.
+type Request struct {
+	// Policy is the egress policy to evaluate.
+	Policy model.EgressPolicy
+	// Targets are URLs (e.g. "https://api.github.com/repos"), hosts with an optional
+	// port (e.g. "github.com:443") or IP addresses.
+	Targets []string
+}
+
+func (r Request) validate() error {
+	if err := r.Policy.Validate(); err != nil {
+		return err
+	}
+	if len(r.Targets) == 0 {
+		return fmt.Errorf("at least one target is required: %w", model.ErrNotValid)
+	}
+	return nil
+}
+
+// Run evaluates the targets against the policy the same way the egress proxies do,
+// and returns the resulting action of each target in order.
+//
+// The targets are not resolved, so the fail-closed checks of the resolved addresses
+// are not evaluated.
+func (s *Service) Run(ctx context.Context, req Request) ([]model.EgressTestResult, error) {
+	if err := req.validate(); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	rules := make([]proxy.Rule, 0, len(req.Policy.Rules))
+	for _, r := range req.Policy.Rules {
+		rules = append(rules, proxy.Rule{Action: proxy.Action(r.Action), Domain: r.Domain, ICMP: r.ICMP})
+	}
+	// The built-in rules of the system services are evaluated after the policy ones.
+	systemSvcs := make([]string, 0, len(req.Policy.AllowSystemServices))
+	for _, s := range req.Policy.AllowSystemServices {
+		systemSvcs = append(systemSvcs, string(s))
+	}
+	systemRules, err := proxy.SystemServiceRules(systemSvcs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid system service: %w", model.ErrNotValid)
+	}
+	rules = append(rules, systemRules...)
+
+	matcher, err := proxy.NewRuleMatcher(proxy.Action(req.Policy.Default), rules)
+	if err != nil {
+		return nil, fmt.Errorf("could not create rule matcher: %w", err)
+	}
+
+	results := make([]model.EgressTestResult, 0, len(req.Targets))
+	for _, target := range req.Targets {
+		host, err := targetHost(target)
+		if err != nil {
+			return nil, err
+		}
+
+		res := model.EgressTestResult{Target: target, RuleIndex: -1}
+
+		// The proxies deny the IP addresses, the rules can't be evaluated without a domain.
+		res.Domain = proxy.ExtractDomain(host)
+		if res.Domain == "" {
+			res.Action = model.EgressActionDeny
+			res.Reason = model.EgressTestReasonIPAddress
+			results = append(results, res)
+			continue
+		}
+
+		action, idx := matcher.MatchRule(res.Domain)
+		res.Action = model.EgressAction(action)
+		res.Reason = model.EgressTestReasonDefault
+		switch {
+		case idx >= len(req.Policy.Rules):
+			res.Reason = model.EgressTestReasonSystemService
+			res.Rule = &model.EgressRule{Domain: rules[idx].Domain, Action: model.EgressAction(rules[idx].Action)}
+		case idx >= 0:
+			rule := req.Policy.Rules[idx]
+			res.Reason = model.EgressTestReasonRule
+			res.RuleIndex = idx
+			res.Rule = &rule
+		}
+		s.logger.Debugf("egress test target %q: %s (%s)", target, res.Action, res.Reason)
+
+		results = append(results, res)
+	}
+
+	return results, nil
+}
+
+// targetHost returns the host (with the port, if any) of a URL, host or IP target.
+func targetHost(target string) (string, error) {
+	t := strings.TrimSpace(target)
+	if t == "" {
+		return "", fmt.Errorf("empty target: %w", model.ErrNotValid)
+	}
+
+	// Bare IPv6 addresses are not valid URL hosts.
+	if net.ParseIP(t) != nil {
+		return t, nil
+	}
+
+	if !strings.Contains(t, "://") {
+		t = "//" + t
+	}
+	u, err := url.Parse(t)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid target %q: %w", target, model.ErrNotValid)
+	}
+
+	return u.Host, nil
+}
@@ -0,0 +1,112 @@
+package egresstest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/egresstest"
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestServiceRun(t *testing.T) {
+	policy := model.EgressPolicy{
+		Default: model.EgressActionDeny,
+		Rules: []model.EgressRule{
+			{Domain: "evil.github.com", Action: model.EgressActionDeny},
+			{Domain: "*.github.com", Action: model.EgressActionAllow},
+			{Domain: "github.com", Action: model.EgressActionAllow},
+		},
+	}
+
+	tests := map[string]struct {
+		req        egresstest.Request
+		expResults []model.EgressTestResult
+		expErr     error
+	}{
+		"A domain should match the first matching rule.": {
+			req: egresstest.Request{Policy: policy, Targets: []string{"evil.github.com", "api.github.com"}},
+			expResults: []model.EgressTestResult{
+				{Target: "evil.github.com", Domain: "evil.github.com", Action: model.EgressActionDeny, Reason: model.EgressTestReasonRule, RuleIndex: 0, Rule: &policy.Rules[0]},
+				{Target: "api.github.com", Domain: "api.github.com", Action: model.EgressActionAllow, Reason: model.EgressTestReasonRule, RuleIndex: 1, Rule: &policy.Rules[1]},
+			},
+		},
+
+		"URLs and hosts with ports should be matched by their domain.": {
+			req: egresstest.Request{Policy: policy, Targets: []string{"https://GitHub.com./repos?x=1", "github.com:443"}},
+			expResults: []model.EgressTestResult{
+				{Target: "https://GitHub.com./repos?x=1", Domain: "github.com", Action: model.EgressActionAllow, Reason: model.EgressTestReasonRule, RuleIndex: 2, Rule: &policy.Rules[2]},
+				{Target: "github.com:443", Domain: "github.com", Action: model.EgressActionAllow, Reason: model.EgressTestReasonRule, RuleIndex: 2, Rule: &policy.Rules[2]},
+			},
+		},
+
+		"A domain without matching rules should get the default action.": {
+			req: egresstest.Request{Policy: policy, Targets: []string{"example.com"}},
+			expResults: []model.EgressTestResult{
+				{Target: "example.com", Domain: "example.com", Action: model.EgressActionDeny, Reason: model.EgressTestReasonDefault, RuleIndex: -1},
+			},
+		},
+
+		"A system service domain should match its built-in rule after the policy rules.": {
+			req: egresstest.Request{
+				Policy: model.EgressPolicy{
+					Default:             model.EgressActionDeny,
+					Rules:               []model.EgressRule{{Domain: "security.debian.org", Action: model.EgressActionDeny}},
+					AllowSystemServices: []model.EgressSystemService{model.EgressSystemServicePackages},
+				},
+				Targets: []string{"deb.debian.org", "security.debian.org"},
+			},
+			expResults: []model.EgressTestResult{
+				{Target: "deb.debian.org", Domain: "deb.debian.org", Action: model.EgressActionAllow, Reason: model.EgressTestReasonSystemService, RuleIndex: -1, Rule: &model.EgressRule{Domain: "deb.debian.org", Action: model.EgressActionAllow}},
+				{Target: "security.debian.org", Domain: "security.debian.org", Action: model.EgressActionDeny, Reason: model.EgressTestReasonRule, RuleIndex: 0, Rule: &model.EgressRule{Domain: "security.debian.org", Action: model.EgressActionDeny}},
+			},
+		},
+
+		"IP addresses should be denied even with an allow catch-all rule.": {
+			req: egresstest.Request{
+				Policy:  model.EgressPolicy{Default: model.EgressActionAllow, Rules: []model.EgressRule{{Domain: "*", Action: model.EgressActionAllow}}},
+				Targets: []string{"1.1.1.1", "http://1.1.1.1:8080/", "::1"},
+			},
+			expResults: []model.EgressTestResult{
+				{Target: "1.1.1.1", Action: model.EgressActionDeny, Reason: model.EgressTestReasonIPAddress, RuleIndex: -1},
+				{Target: "http://1.1.1.1:8080/", Action: model.EgressActionDeny, Reason: model.EgressTestReasonIPAddress, RuleIndex: -1},
+				{Target: "::1", Action: model.EgressActionDeny, Reason: model.EgressTestReasonIPAddress, RuleIndex: -1},
+			},
+		},
+
+		"An invalid policy should fail.": {
+			req:    egresstest.Request{Policy: model.EgressPolicy{Default: "maybe"}, Targets: []string{"github.com"}},
+			expErr: model.ErrNotValid,
+		},
+
+		"A request without targets should fail.": {
+			req:    egresstest.Request{Policy: policy},
+			expErr: model.ErrNotValid,
+		},
+
+		"An invalid target should fail.": {
+			req:    egresstest.Request{Policy: policy, Targets: []string{"https://"}},
+			expErr: model.ErrNotValid,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			svc, err := egresstest.NewService(egresstest.ServiceConfig{})
+			require.NoError(err)
+
+			results, err := svc.Run(context.Background(), test.req)
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(test.expResults, results)
+		})
+	}
+}
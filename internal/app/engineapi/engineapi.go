@@ -0,0 +1,112 @@
+package engineapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the engine API service.
+type ServiceConfig struct {
+	Engine     sandbox.Engine
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Engine == nil {
+		return fmt.Errorf("engine is required")
+	}
+
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.EngineAPI"})
+
+	return nil
+}
+
+// Service connects to the VMM machine API of the sandboxes.
+type Service struct {
+	engine sandbox.Engine
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new engine API service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		engine: cfg.Engine,
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the engine API request parameters.
+type Request struct {
+	// NameOrID is the sandbox name or ID.
+	NameOrID string
+}
+
+// Run returns a connection to the VMM machine API of a running sandbox.
+func (s *Service) Run(ctx context.Context, req Request) (net.Conn, error) {
+	sb, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
+	if errors.Is(err, model.ErrNotFound) {
+		sb, err = s.repo.GetSandbox(ctx, req.NameOrID)
+	}
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("sandbox not found: %s: %w", req.NameOrID, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not get sandbox: %w", err)
+	}
+
+	if sb.Status != model.SandboxStatusRunning {
+		return nil, fmt.Errorf("sandbox %s is not running (current status: %s): %w", sb.Name, sb.Status, model.ErrNotValid)
+	}
+
+	conn, err := s.engine.DialAPI(ctx, sb.ID)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to sandbox engine API: %w", err)
+	}
+
+	s.logger.Debugf("Connected to the engine API of sandbox %s", sb.Name)
+	return conn, nil
+}
+
+// managedResources are the machine API resources sbx configures and tracks (the
+// boot, disks, network and machine configuration), changing them would leave the
+// sandbox out of sync with sbx.
+var managedResources = []string{"/boot-source", "/drives", "/network-interfaces", "/machine-config", "/snapshot/load"}
+
+// ValidateRequest checks an engine API request doesn't change the resources managed
+// by sbx. The reads and the other resources (e.g. metrics, entropy, balloon) are
+// allowed.
+func ValidateRequest(method, path string) error {
+	if method == http.MethodGet || method == http.MethodHead {
+		return nil
+	}
+
+	for _, r := range managedResources {
+		if path == r || strings.HasPrefix(path, r+"/") {
+			return fmt.Errorf("%s %s changes a resource managed by sbx: %w", method, path, model.ErrNotValid)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package engineapi_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/engineapi"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox/sandboxmock"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+const testSandboxID = "01H2QWERTYASDFGZXCVBNMLKJH"
+
+var testRunning = &model.Sandbox{
+	ID:     testSandboxID,
+	Name:   "my-sandbox",
+	Status: model.SandboxStatusRunning,
+}
+
+func TestServiceRun(t *testing.T) {
+	testConn, _ := net.Pipe()
+
+	tests := map[string]struct {
+		mockRepo   func(m *storagemock.MockRepository)
+		mockEngine func(m *sandboxmock.MockEngine)
+		req        engineapi.Request
+		expConn    net.Conn
+		expErrIs   error
+		expErr     bool
+	}{
+		"Connecting should return the sandbox engine API connection.": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(testRunning, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("DialAPI", mock.Anything, testSandboxID).Once().Return(testConn, nil)
+			},
+			req:     engineapi.Request{NameOrID: "my-sandbox"},
+			expConn: testConn,
+		},
+
+		"Connecting by ID should return the sandbox engine API connection.": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, testSandboxID).Once().Return(nil, model.ErrNotFound)
+				m.On("GetSandbox", mock.Anything, testSandboxID).Once().Return(testRunning, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("DialAPI", mock.Anything, testSandboxID).Once().Return(testConn, nil)
+			},
+			req:     engineapi.Request{NameOrID: testSandboxID},
+			expConn: testConn,
+		},
+
+		"A missing sandbox should fail with not found.": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "ghost").Once().Return(nil, model.ErrNotFound)
+				m.On("GetSandbox", mock.Anything, "ghost").Once().Return(nil, model.ErrNotFound)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        engineapi.Request{NameOrID: "ghost"},
+			expErr:     true,
+			expErrIs:   model.ErrNotFound,
+		},
+
+		"A stopped sandbox should fail with not valid.": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{ID: testSandboxID, Name: "my-sandbox", Status: model.SandboxStatusStopped}, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        engineapi.Request{NameOrID: "my-sandbox"},
+			expErr:     true,
+			expErrIs:   model.ErrNotValid,
+		},
+
+		"An engine error should fail.": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(testRunning, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("DialAPI", mock.Anything, testSandboxID).Once().Return(nil, fmt.Errorf("something"))
+			},
+			req:    engineapi.Request{NameOrID: "my-sandbox"},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mRepo := storagemock.NewMockRepository(t)
+			mEngine := sandboxmock.NewMockEngine(t)
+			test.mockRepo(mRepo)
+			test.mockEngine(mEngine)
+
+			svc, err := engineapi.NewService(engineapi.ServiceConfig{
+				Engine:     mEngine,
+				Repository: mRepo,
+				Logger:     log.Noop,
+			})
+			require.NoError(err)
+
+			conn, err := svc.Run(context.Background(), test.req)
+			if test.expErr {
+				require.Error(err)
+				if test.expErrIs != nil {
+					assert.ErrorIs(err, test.expErrIs)
+				}
+				return
+			}
+			require.NoError(err)
+			assert.Equal(test.expConn, conn)
+		})
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	tests := map[string]struct {
+		method string
+		path   string
+		expErr bool
+	}{
+		"Reading a managed resource should be allowed.":   {method: http.MethodGet, path: "/machine-config"},
+		"Changing the balloon should be allowed.":         {method: http.MethodPatch, path: "/balloon"},
+		"Flushing the metrics should be allowed.":         {method: http.MethodPut, path: "/actions"},
+		"Changing a drive should be refused.":             {method: http.MethodPatch, path: "/drives/rootfs", expErr: true},
+		"Changing a network interface should be refused.": {method: http.MethodPatch, path: "/network-interfaces/eth0", expErr: true},
+		"Changing the machine config should be refused.":  {method: http.MethodPut, path: "/machine-config", expErr: true},
+		"Loading a snapshot should be refused.":           {method: http.MethodPut, path: "/snapshot/load", expErr: true},
+		"A similar resource name should be allowed.":      {method: http.MethodPut, path: "/drives-other"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := engineapi.ValidateRequest(test.method, test.path)
+			if test.expErr {
+				assert.ErrorIs(t, err, model.ErrNotValid)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -2,10 +2,17 @@ package exec
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"os/user"
 	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/oklog/ulid/v2"
 
 	"github.com/slok/sbx/internal/log"
 	"github.com/slok/sbx/internal/model"
@@ -18,6 +25,9 @@ type ServiceConfig struct {
 	Engine     sandbox.Engine
 	Repository storage.Repository
 	Logger     log.Logger
+	// User is the host user recorded in the exec audit records, defaults to the
+	// current OS user.
+	User string
 }
 
 func (c *ServiceConfig) defaults() error {
@@ -31,6 +41,9 @@ func (c *ServiceConfig) defaults() error {
 		c.Logger = log.Noop
 	}
 	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.Exec"})
+	if c.User == "" {
+		c.User = currentUser()
+	}
 	return nil
 }
 
@@ -39,6 +52,7 @@ type Service struct {
 	engine sandbox.Engine
 	repo   storage.Repository
 	logger log.Logger
+	user   string
 }
 
 // NewService creates a new exec service.
@@ -51,6 +65,7 @@ func NewService(cfg ServiceConfig) (*Service, error) {
 		engine: cfg.Engine,
 		repo:   cfg.Repository,
 		logger: cfg.Logger,
+		user:   cfg.User,
 	}, nil
 }
 
@@ -62,14 +77,43 @@ type Request struct {
 	// Files are local file paths to upload into the sandbox before executing.
 	// Files are uploaded to the working directory (Opts.WorkingDir) or "/" if unset.
 	Files []string
+	// CaptureOutput captures stdout and stderr into the result, in addition to
+	// writing them to Opts.Stdout and Opts.Stderr.
+	CaptureOutput bool
+	// CaptureMaxBytes is the per-stream capture limit. Output beyond this limit is
+	// still written to the streams but not captured. Defaults to DefaultCaptureMaxBytes.
+	CaptureMaxBytes int
+	// MaxOutputBytes limits the output of the command, stdout and stderr combined,
+	// written to the streams and captured (optional, 0 is unlimited).
+	MaxOutputBytes int64
+	// OutputLimitPolicy is what happens when the output exceeds MaxOutputBytes,
+	// defaults to model.ExecOutputLimitTruncate.
+	OutputLimitPolicy model.ExecOutputLimitPolicy
+	// Caller identifies the client in the exec audit record (e.g. "cli", "sdk").
+	Caller string
+	// Task runs a task of the sandbox, or a global one, instead of a command (optional).
+	// Command are arguments appended to the task command, the working dir, env,
+	// files and timeout of the request override or extend the task ones.
+	Task string
+	// Timeout kills the command when it runs longer (optional).
+	Timeout time.Duration
 }
 
 // Run executes a command in a sandbox.
 func (s *Service) Run(ctx context.Context, req Request) (*model.ExecResult, error) {
 	// 1. Validate command
-	if len(req.Command) == 0 {
+	if len(req.Command) == 0 && req.Task == "" {
 		return nil, fmt.Errorf("command cannot be empty: %w", model.ErrNotValid)
 	}
+	if req.Timeout < 0 {
+		return nil, fmt.Errorf("timeout can't be negative: %w", model.ErrNotValid)
+	}
+	if req.MaxOutputBytes < 0 {
+		return nil, fmt.Errorf("max output bytes can't be negative: %w", model.ErrNotValid)
+	}
+	if err := req.OutputLimitPolicy.Validate(); err != nil {
+		return nil, err
+	}
 
 	// 2. Get sandbox from storage (by name or ID)
 	sandbox, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
@@ -87,6 +131,17 @@ func (s *Service) Run(ctx context.Context, req Request) (*model.ExecResult, erro
 	if sandbox.Status != model.SandboxStatusRunning {
 		return nil, fmt.Errorf("sandbox %s is not running (status: %s): %w", sandbox.Name, sandbox.Status, model.ErrNotValid)
 	}
+	if req.Opts.Tty && !s.engine.Capabilities().Tty {
+		return nil, fmt.Errorf("the sandbox engine doesn't support tty commands: %w", model.ErrNotValid)
+	}
+
+	if req.Task != "" {
+		task, err := s.getTask(ctx, sandbox.ID, req.Task)
+		if err != nil {
+			return nil, err
+		}
+		req = withTask(req, *task)
+	}
 
 	// 4. Upload files before exec (if any).
 	if len(req.Files) > 0 {
@@ -111,19 +166,159 @@ func (s *Service) Run(ctx context.Context, req Request) (*model.ExecResult, erro
 			remotePath := filepath.Join(destDir, filepath.Base(f))
 			s.logger.Debugf("Uploading %s to %s:%s", f, sandbox.Name, remotePath)
 
-			if err := s.engine.CopyTo(ctx, sandbox.ID, f, remotePath); err != nil {
+			if err := s.engine.CopyTo(ctx, sandbox.ID, f, remotePath, model.CopyOpts{}); err != nil {
 				return nil, fmt.Errorf("could not upload file %q: %w", f, err)
 			}
 		}
 	}
 
-	// 5. Execute command via engine.
-	result, err := s.engine.Exec(ctx, sandbox.ID, req.Command, req.Opts)
+	// 5. Execute command via engine, recording output and timing.
+	captureMaxBytes := req.CaptureMaxBytes
+	if captureMaxBytes <= 0 {
+		captureMaxBytes = DefaultCaptureMaxBytes
+	}
+	execCtx, cancelExec := context.WithCancel(ctx)
+	defer cancelExec()
+	if req.Timeout > 0 {
+		var cancel context.CancelFunc
+		execCtx, cancel = context.WithTimeout(execCtx, req.Timeout)
+		defer cancel()
+	}
+
+	// The abort policy kills the command cancelling its context.
+	var limit *outputLimit
+	if req.MaxOutputBytes > 0 {
+		var onExceed func()
+		if req.OutputLimitPolicy == model.ExecOutputLimitAbort {
+			onExceed = cancelExec
+		}
+		limit = newOutputLimit(req.MaxOutputBytes, onExceed)
+	}
+
+	stdout := newOutputRecorder(req.Opts.Stdout, req.CaptureOutput, captureMaxBytes, limit)
+	stderr := newOutputRecorder(req.Opts.Stderr, req.CaptureOutput, captureMaxBytes, limit)
+
+	opts := req.Opts
+	opts.Stdout = stdout
+	opts.Stderr = stderr
+
+	// The activity is recorded on both ends so the long running commands (e.g. shell
+	// sessions) don't make the sandbox idle.
+	startedAt := time.Now().UTC()
+	s.recordActivity(ctx, sandbox.ID, startedAt)
+	result, err := s.engine.Exec(execCtx, sandbox.ID, req.Command, opts)
+	finishedAt := time.Now().UTC()
+	s.recordActivity(context.WithoutCancel(ctx), sandbox.ID, finishedAt)
+	if err != nil && ctx.Err() == nil && errors.Is(execCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("command timed out after %s: %w", req.Timeout, context.DeadlineExceeded)
+	}
+	limitExceeded := limit != nil && limit.Exceeded()
+	aborted := limitExceeded && req.OutputLimitPolicy == model.ExecOutputLimitAbort && err != nil && ctx.Err() == nil
+	if aborted {
+		// The aborted command is a result with the limit indicator, not an error.
+		result, err = &model.ExecResult{ExitCode: -1}, nil
+	}
+
+	// 6. Record the execution in the audit trail, even if it failed.
+	rec := model.ExecRecord{
+		ID:           ulid.MustNew(ulid.Timestamp(startedAt), rand.Reader).String(),
+		SandboxID:    sandbox.ID,
+		Command:      req.Command,
+		WorkingDir:   req.Opts.WorkingDir,
+		User:         s.user,
+		Caller:       req.Caller,
+		ExitCode:     -1,
+		StartedAt:    startedAt,
+		Duration:     finishedAt.Sub(startedAt),
+		StdoutBytes:  stdout.written,
+		StderrBytes:  stderr.written,
+		StdoutSHA256: stdout.SHA256(),
+		StderrSHA256: stderr.SHA256(),
+	}
+	switch {
+	case err != nil:
+		rec.Error = err.Error()
+	case aborted:
+		rec.Error = fmt.Sprintf("command aborted, its output exceeded the %d bytes limit", req.MaxOutputBytes)
+	default:
+		rec.ExitCode = result.ExitCode
+	}
+	s.recordExec(context.WithoutCancel(ctx), rec)
+
 	if err != nil {
 		return nil, fmt.Errorf("could not execute command: %w", err)
 	}
 
+	result.StartedAt = startedAt
+	result.FinishedAt = finishedAt
+	result.Duration = finishedAt.Sub(startedAt)
+	result.StdoutBytes = stdout.written
+	result.StderrBytes = stderr.written
+	result.OutputLimitExceeded = limitExceeded
+	if req.CaptureOutput {
+		result.Stdout = stdout.String()
+		result.Stderr = stderr.String()
+		result.StdoutTruncated = stdout.truncated
+		result.StderrTruncated = stderr.truncated
+	}
+
 	s.logger.Debugf("executed command in sandbox %s (%s): exit code %d", sandbox.Name, sandbox.ID, result.ExitCode)
 
 	return result, nil
 }
+
+// getTask returns the task of a sandbox, or the global one when the sandbox doesn't
+// have it.
+func (s *Service) getTask(ctx context.Context, sandboxID, name string) (*model.Task, error) {
+	task, err := s.repo.GetTask(ctx, sandboxID, name)
+	if errors.Is(err, model.ErrNotFound) {
+		task, err = s.repo.GetTask(ctx, "", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get task: %w", err)
+	}
+	return task, nil
+}
+
+// withTask returns the request running a task, the request options override or
+// extend the task ones.
+func withTask(req Request, task model.Task) Request {
+	req.Command = append(slices.Clone(task.Command), req.Command...)
+	if req.Opts.WorkingDir == "" {
+		req.Opts.WorkingDir = task.WorkingDir
+	}
+	if len(task.Env) > 0 {
+		env := maps.Clone(task.Env)
+		maps.Copy(env, req.Opts.Env)
+		req.Opts.Env = env
+	}
+	req.Files = append(slices.Clone(task.Files), req.Files...)
+	if req.Timeout == 0 {
+		req.Timeout = task.Timeout
+	}
+	return req
+}
+
+// recordExec stores the exec audit record. The command already ran, so failing to
+// record it doesn't fail the exec.
+func (s *Service) recordExec(ctx context.Context, rec model.ExecRecord) {
+	if err := s.repo.CreateExecRecord(ctx, rec); err != nil {
+		s.logger.Warningf("could not record exec in sandbox %s: %v", rec.SandboxID, err)
+	}
+}
+
+// recordActivity sets the sandbox last activity, used to detect the idle sandboxes.
+// Failing to record it doesn't fail the exec.
+func (s *Service) recordActivity(ctx context.Context, sandboxID string, at time.Time) {
+	if err := s.repo.UpdateSandboxLastActivity(ctx, sandboxID, at); err != nil {
+		s.logger.Warningf("could not record activity of sandbox %s: %v", sandboxID, err)
+	}
+}
+
+func currentUser() string {
+	u, err := user.Current()
+	if err != nil {
+		return ""
+	}
+	return u.Username
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -19,6 +20,22 @@ import (
 	"github.com/slok/sbx/internal/storage/storagemock"
 )
 
+// assertExecResult checks that timing was recorded and the rest of the result matches.
+func assertExecResult(t *testing.T, exp, got *model.ExecResult) {
+	t.Helper()
+	require.NotNil(t, got)
+
+	assert.False(t, got.StartedAt.IsZero())
+	assert.False(t, got.FinishedAt.Before(got.StartedAt))
+	assert.Equal(t, got.FinishedAt.Sub(got.StartedAt), got.Duration)
+
+	normalized := *got
+	normalized.StartedAt = time.Time{}
+	normalized.FinishedAt = time.Time{}
+	normalized.Duration = 0
+	assert.Equal(t, exp, &normalized)
+}
+
 func TestNewService(t *testing.T) {
 	tests := map[string]struct {
 		cfg    ServiceConfig
@@ -269,6 +286,45 @@ func TestServiceRun(t *testing.T) {
 			expErr: true,
 		},
 
+		"A tty command should fail when the engine doesn't support tty": {
+			req: Request{
+				NameOrID: "test-sandbox",
+				Command:  []string{"sh"},
+				Opts:     model.ExecOpts{Tty: true},
+			},
+			mock: func(mEngine *sandboxmock.MockEngine, mRepo *storagemock.MockRepository) {
+				sandbox := &model.Sandbox{
+					ID:     "test-id",
+					Name:   "test-sandbox",
+					Status: model.SandboxStatusRunning,
+				}
+				mRepo.On("GetSandboxByName", mock.Anything, "test-sandbox").Once().Return(sandbox, nil)
+				mEngine.On("Capabilities").Once().Return(model.EngineCapabilities{})
+			},
+			expErr: true,
+		},
+
+		"A tty command should run when the engine supports tty": {
+			req: Request{
+				NameOrID: "test-sandbox",
+				Command:  []string{"sh"},
+				Opts:     model.ExecOpts{Tty: true},
+			},
+			mock: func(mEngine *sandboxmock.MockEngine, mRepo *storagemock.MockRepository) {
+				sandbox := &model.Sandbox{
+					ID:     "test-id",
+					Name:   "test-sandbox",
+					Status: model.SandboxStatusRunning,
+				}
+				mRepo.On("GetSandboxByName", mock.Anything, "test-sandbox").Once().Return(sandbox, nil)
+				mEngine.On("Capabilities").Once().Return(model.EngineCapabilities{Tty: true})
+
+				result := &model.ExecResult{ExitCode: 0}
+				mEngine.On("Exec", mock.Anything, "test-id", []string{"sh"}, mock.Anything).Once().Return(result, nil)
+			},
+			expRes: &model.ExecResult{ExitCode: 0},
+		},
+
 		"Engine exec error should fail": {
 			req: Request{
 				NameOrID: "test-sandbox",
@@ -287,6 +343,87 @@ func TestServiceRun(t *testing.T) {
 			},
 			expErr: true,
 		},
+
+		"Running a sandbox task should run its command with its options": {
+			req: Request{
+				NameOrID: "test-sandbox",
+				Task:     "test",
+				Command:  []string{"-run", "TestX"},
+				Opts:     model.ExecOpts{Env: map[string]string{"GOFLAGS": "-count=1"}},
+			},
+			mock: func(mEngine *sandboxmock.MockEngine, mRepo *storagemock.MockRepository) {
+				sandbox := &model.Sandbox{ID: "test-id", Name: "test-sandbox", Status: model.SandboxStatusRunning}
+				mRepo.On("GetSandboxByName", mock.Anything, "test-sandbox").Once().Return(sandbox, nil)
+				task := &model.Task{
+					Name:       "test",
+					SandboxID:  "test-id",
+					Command:    []string{"go", "test", "./..."},
+					WorkingDir: "/src",
+					Env:        map[string]string{"CGO_ENABLED": "0", "GOFLAGS": "-race"},
+					Timeout:    time.Minute,
+				}
+				mRepo.On("GetTask", mock.Anything, "test-id", "test").Once().Return(task, nil)
+
+				result := &model.ExecResult{ExitCode: 0}
+				mEngine.On("Exec", mock.MatchedBy(func(ctx context.Context) bool {
+					_, ok := ctx.Deadline()
+					return ok
+				}), "test-id", []string{"go", "test", "./...", "-run", "TestX"}, mock.MatchedBy(func(opts model.ExecOpts) bool {
+					return opts.WorkingDir == "/src" && opts.Env["CGO_ENABLED"] == "0" && opts.Env["GOFLAGS"] == "-count=1"
+				})).Once().Return(result, nil)
+			},
+			expRes: &model.ExecResult{ExitCode: 0},
+		},
+
+		"Running a task missing in the sandbox should run the global one": {
+			req: Request{
+				NameOrID: "test-sandbox",
+				Task:     "lint",
+				Opts:     model.ExecOpts{WorkingDir: "/app"},
+			},
+			mock: func(mEngine *sandboxmock.MockEngine, mRepo *storagemock.MockRepository) {
+				sandbox := &model.Sandbox{ID: "test-id", Name: "test-sandbox", Status: model.SandboxStatusRunning}
+				mRepo.On("GetSandboxByName", mock.Anything, "test-sandbox").Once().Return(sandbox, nil)
+				mRepo.On("GetTask", mock.Anything, "test-id", "lint").Once().Return(nil, model.ErrNotFound)
+				mRepo.On("GetTask", mock.Anything, "", "lint").Once().Return(&model.Task{Name: "lint", Command: []string{"golangci-lint", "run"}, WorkingDir: "/src"}, nil)
+
+				result := &model.ExecResult{ExitCode: 0}
+				mEngine.On("Exec", mock.Anything, "test-id", []string{"golangci-lint", "run"}, mock.MatchedBy(func(opts model.ExecOpts) bool {
+					return opts.WorkingDir == "/app"
+				})).Once().Return(result, nil)
+			},
+			expRes: &model.ExecResult{ExitCode: 0},
+		},
+
+		"Running a missing task should fail": {
+			req: Request{
+				NameOrID: "test-sandbox",
+				Task:     "missing",
+			},
+			mock: func(mEngine *sandboxmock.MockEngine, mRepo *storagemock.MockRepository) {
+				sandbox := &model.Sandbox{ID: "test-id", Name: "test-sandbox", Status: model.SandboxStatusRunning}
+				mRepo.On("GetSandboxByName", mock.Anything, "test-sandbox").Once().Return(sandbox, nil)
+				mRepo.On("GetTask", mock.Anything, mock.Anything, "missing").Return(nil, model.ErrNotFound)
+			},
+			expErr: true,
+		},
+
+		"A command exceeding the timeout should fail": {
+			req: Request{
+				NameOrID: "test-sandbox",
+				Command:  []string{"sleep", "10"},
+				Timeout:  time.Millisecond,
+			},
+			mock: func(mEngine *sandboxmock.MockEngine, mRepo *storagemock.MockRepository) {
+				sandbox := &model.Sandbox{ID: "test-id", Name: "test-sandbox", Status: model.SandboxStatusRunning}
+				mRepo.On("GetSandboxByName", mock.Anything, "test-sandbox").Once().Return(sandbox, nil)
+				mEngine.On("Exec", mock.Anything, "test-id", []string{"sleep", "10"}, mock.Anything).Once().Return(func(ctx context.Context, _ string, _ []string, _ model.ExecOpts) (*model.ExecResult, error) {
+					<-ctx.Done()
+					return nil, ctx.Err()
+				})
+			},
+			expErr: true,
+		},
 	}
 
 	for name, test := range tests {
@@ -296,6 +433,8 @@ func TestServiceRun(t *testing.T) {
 
 			mEngine := &sandboxmock.MockEngine{}
 			mRepo := &storagemock.MockRepository{}
+			mRepo.On("CreateExecRecord", mock.Anything, mock.Anything).Maybe().Return(nil)
+			mRepo.On("UpdateSandboxLastActivity", mock.Anything, mock.Anything, mock.Anything).Maybe().Return(nil)
 			test.mock(mEngine, mRepo)
 
 			svc, err := NewService(ServiceConfig{
@@ -311,7 +450,7 @@ func TestServiceRun(t *testing.T) {
 				assert.Error(err)
 			} else {
 				assert.NoError(err)
-				assert.Equal(test.expRes, result)
+				assertExecResult(t, test.expRes, result)
 			}
 
 			mEngine.AssertExpectations(t)
@@ -327,6 +466,8 @@ func TestServiceRunWithOutput(t *testing.T) {
 
 	mEngine := &sandboxmock.MockEngine{}
 	mRepo := &storagemock.MockRepository{}
+	mRepo.On("CreateExecRecord", mock.Anything, mock.Anything).Maybe().Return(nil)
+	mRepo.On("UpdateSandboxLastActivity", mock.Anything, mock.Anything, mock.Anything).Maybe().Return(nil)
 
 	sandbox := &model.Sandbox{
 		ID:     "test-id",
@@ -367,6 +508,182 @@ func TestServiceRunWithOutput(t *testing.T) {
 	mRepo.AssertExpectations(t)
 }
 
+func TestServiceRunCaptureOutput(t *testing.T) {
+	tests := map[string]struct {
+		stdout          string
+		stderr          string
+		captureOutput   bool
+		captureMaxBytes int
+		expRes          *model.ExecResult
+	}{
+		"Without capture only byte counts should be recorded.": {
+			stdout: "hello\n",
+			stderr: "oops\n",
+			expRes: &model.ExecResult{StdoutBytes: 6, StderrBytes: 5},
+		},
+
+		"With capture the output should be returned in the result.": {
+			stdout:        "hello\n",
+			stderr:        "oops\n",
+			captureOutput: true,
+			expRes: &model.ExecResult{
+				StdoutBytes: 6,
+				StderrBytes: 5,
+				Stdout:      "hello\n",
+				Stderr:      "oops\n",
+			},
+		},
+
+		"With capture over the limit the output should be truncated.": {
+			stdout:          "0123456789",
+			stderr:          "abc",
+			captureOutput:   true,
+			captureMaxBytes: 4,
+			expRes: &model.ExecResult{
+				StdoutBytes:     10,
+				StderrBytes:     3,
+				Stdout:          "0123",
+				Stderr:          "abc",
+				StdoutTruncated: true,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mEngine := &sandboxmock.MockEngine{}
+			mRepo := &storagemock.MockRepository{}
+			mRepo.On("CreateExecRecord", mock.Anything, mock.Anything).Maybe().Return(nil)
+			mRepo.On("UpdateSandboxLastActivity", mock.Anything, mock.Anything, mock.Anything).Maybe().Return(nil)
+
+			sandbox := &model.Sandbox{ID: "test-id", Name: "test-sandbox", Status: model.SandboxStatusRunning}
+			mRepo.On("GetSandboxByName", mock.Anything, "test-sandbox").Once().Return(sandbox, nil)
+			mEngine.On("Exec", mock.Anything, "test-id", []string{"run"}, mock.Anything).Once().
+				Run(func(args mock.Arguments) {
+					opts := args.Get(3).(model.ExecOpts)
+					_, _ = io.WriteString(opts.Stdout, test.stdout)
+					_, _ = io.WriteString(opts.Stderr, test.stderr)
+				}).
+				Return(&model.ExecResult{ExitCode: 0}, nil)
+
+			svc, err := NewService(ServiceConfig{Engine: mEngine, Repository: mRepo, Logger: log.Noop})
+			require.NoError(err)
+
+			stdout := &bytes.Buffer{}
+			result, err := svc.Run(context.TODO(), Request{
+				NameOrID:        "test-sandbox",
+				Command:         []string{"run"},
+				Opts:            model.ExecOpts{Stdout: stdout},
+				CaptureOutput:   test.captureOutput,
+				CaptureMaxBytes: test.captureMaxBytes,
+			})
+			require.NoError(err)
+
+			assertExecResult(t, test.expRes, result)
+			assert.Equal(test.stdout, stdout.String())
+
+			mEngine.AssertExpectations(t)
+			mRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestServiceRunOutputLimit(t *testing.T) {
+	tests := map[string]struct {
+		maxOutputBytes int64
+		policy         model.ExecOutputLimitPolicy
+		execErr        bool
+		expStdout      string
+		expStderr      string
+		expRes         *model.ExecResult
+		expErr         bool
+	}{
+		"Output under the limit should be written.": {
+			maxOutputBytes: 100,
+			expStdout:      "0123456789",
+			expStderr:      "abc",
+			expRes:         &model.ExecResult{StdoutBytes: 10, StderrBytes: 3, Stdout: "0123456789", Stderr: "abc"},
+		},
+
+		"Output over the limit should be truncated with the truncate policy.": {
+			maxOutputBytes: 8,
+			policy:         model.ExecOutputLimitTruncate,
+			expStdout:      "01234567",
+			expRes:         &model.ExecResult{StdoutBytes: 10, StderrBytes: 3, Stdout: "01234567", OutputLimitExceeded: true},
+		},
+
+		"Output over the limit should abort the command with the abort policy.": {
+			maxOutputBytes: 8,
+			policy:         model.ExecOutputLimitAbort,
+			execErr:        true,
+			expStdout:      "01234567",
+			expRes:         &model.ExecResult{ExitCode: -1, StdoutBytes: 10, StderrBytes: 3, Stdout: "01234567", OutputLimitExceeded: true},
+		},
+
+		"A negative limit should fail.": {
+			maxOutputBytes: -1,
+			expErr:         true,
+		},
+
+		"An unknown policy should fail.": {
+			maxOutputBytes: 8,
+			policy:         "drop",
+			expErr:         true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mEngine := &sandboxmock.MockEngine{}
+			mRepo := &storagemock.MockRepository{}
+			mRepo.On("CreateExecRecord", mock.Anything, mock.Anything).Maybe().Return(nil)
+			mRepo.On("UpdateSandboxLastActivity", mock.Anything, mock.Anything, mock.Anything).Maybe().Return(nil)
+
+			sandbox := &model.Sandbox{ID: "test-id", Name: "test-sandbox", Status: model.SandboxStatusRunning}
+			mRepo.On("GetSandboxByName", mock.Anything, "test-sandbox").Maybe().Return(sandbox, nil)
+			mEngine.On("Exec", mock.Anything, "test-id", []string{"run"}, mock.Anything).Maybe().
+				Return(func(ctx context.Context, _ string, _ []string, opts model.ExecOpts) (*model.ExecResult, error) {
+					_, _ = io.WriteString(opts.Stdout, "0123456789")
+					_, _ = io.WriteString(opts.Stderr, "abc")
+					if test.execErr {
+						// The killed command returns when its context is cancelled.
+						<-ctx.Done()
+						return nil, ctx.Err()
+					}
+					return &model.ExecResult{ExitCode: 0}, nil
+				})
+
+			svc, err := NewService(ServiceConfig{Engine: mEngine, Repository: mRepo, Logger: log.Noop})
+			require.NoError(err)
+
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			result, err := svc.Run(context.TODO(), Request{
+				NameOrID:          "test-sandbox",
+				Command:           []string{"run"},
+				Opts:              model.ExecOpts{Stdout: stdout, Stderr: stderr},
+				CaptureOutput:     true,
+				MaxOutputBytes:    test.maxOutputBytes,
+				OutputLimitPolicy: test.policy,
+			})
+			if test.expErr {
+				assert.ErrorIs(err, model.ErrNotValid)
+				return
+			}
+			require.NoError(err)
+
+			assertExecResult(t, test.expRes, result)
+			assert.Equal(test.expStdout, stdout.String())
+			assert.Equal(test.expStderr, stderr.String())
+		})
+	}
+}
+
 func TestServiceRunWithFiles(t *testing.T) {
 	// Helper to create a temp file that exists on disk.
 	createTempFile := func(t *testing.T, name string) string {
@@ -399,7 +716,7 @@ func TestServiceRunWithFiles(t *testing.T) {
 
 				// Expect CopyTo with workdir destination.
 				expectedRemote := filepath.Join("/app", filepath.Base(tmpFile))
-				mEngine.On("CopyTo", mock.Anything, "test-id", tmpFile, expectedRemote).Once().Return(nil)
+				mEngine.On("CopyTo", mock.Anything, "test-id", tmpFile, expectedRemote, model.CopyOpts{}).Once().Return(nil)
 
 				// Then the actual exec.
 				result := &model.ExecResult{ExitCode: 0}
@@ -432,7 +749,7 @@ func TestServiceRunWithFiles(t *testing.T) {
 
 				// Expect CopyTo with "/" destination.
 				expectedRemote := filepath.Join("/", filepath.Base(tmpFile))
-				mEngine.On("CopyTo", mock.Anything, "test-id", tmpFile, expectedRemote).Once().Return(nil)
+				mEngine.On("CopyTo", mock.Anything, "test-id", tmpFile, expectedRemote, model.CopyOpts{}).Once().Return(nil)
 
 				result := &model.ExecResult{ExitCode: 0}
 				mEngine.On("Exec", mock.Anything, "test-id", []string{"cat", "data.txt"}, mock.Anything).Once().Return(result, nil)
@@ -464,8 +781,8 @@ func TestServiceRunWithFiles(t *testing.T) {
 				mEngine.On("Exec", mock.Anything, "test-id", []string{"mkdir", "-p", "/tmp"}, mock.Anything).Once().Return(mkdirResult, nil)
 
 				// Both files uploaded to /tmp.
-				mEngine.On("CopyTo", mock.Anything, "test-id", tmpFile1, filepath.Join("/tmp", filepath.Base(tmpFile1))).Once().Return(nil)
-				mEngine.On("CopyTo", mock.Anything, "test-id", tmpFile2, filepath.Join("/tmp", filepath.Base(tmpFile2))).Once().Return(nil)
+				mEngine.On("CopyTo", mock.Anything, "test-id", tmpFile1, filepath.Join("/tmp", filepath.Base(tmpFile1)), model.CopyOpts{}).Once().Return(nil)
+				mEngine.On("CopyTo", mock.Anything, "test-id", tmpFile2, filepath.Join("/tmp", filepath.Base(tmpFile2)), model.CopyOpts{}).Once().Return(nil)
 
 				result := &model.ExecResult{ExitCode: 0}
 				mEngine.On("Exec", mock.Anything, "test-id", []string{"ls"}, mock.Anything).Once().Return(result, nil)
@@ -496,7 +813,7 @@ func TestServiceRunWithFiles(t *testing.T) {
 				mEngine.On("Exec", mock.Anything, "test-id", []string{"mkdir", "-p", "/app"}, mock.Anything).Once().Return(mkdirResult, nil)
 
 				// CopyTo fails.
-				mEngine.On("CopyTo", mock.Anything, "test-id", tmpFile, mock.Anything).Once().Return(fmt.Errorf("scp failed"))
+				mEngine.On("CopyTo", mock.Anything, "test-id", tmpFile, mock.Anything, model.CopyOpts{}).Once().Return(fmt.Errorf("scp failed"))
 
 				// User exec should NOT be called.
 
@@ -589,6 +906,8 @@ func TestServiceRunWithFiles(t *testing.T) {
 
 			mEngine := &sandboxmock.MockEngine{}
 			mRepo := &storagemock.MockRepository{}
+			mRepo.On("CreateExecRecord", mock.Anything, mock.Anything).Maybe().Return(nil)
+			mRepo.On("UpdateSandboxLastActivity", mock.Anything, mock.Anything, mock.Anything).Maybe().Return(nil)
 			req := test.mock(t, mEngine, mRepo)
 
 			svc, err := NewService(ServiceConfig{
@@ -604,9 +923,104 @@ func TestServiceRunWithFiles(t *testing.T) {
 				assert.Error(err)
 			} else {
 				assert.NoError(err)
-				assert.Equal(test.expRes, result)
+				assertExecResult(t, test.expRes, result)
+			}
+
+			mEngine.AssertExpectations(t)
+			mRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestServiceRunExecRecord(t *testing.T) {
+	tests := map[string]struct {
+		execErr   error
+		expErr    bool
+		expRecord model.ExecRecord
+	}{
+		"A finished command should be recorded with its exit code and output hashes.": {
+			expRecord: model.ExecRecord{
+				SandboxID:    "test-id",
+				Command:      []string{"run"},
+				WorkingDir:   "/app",
+				User:         "dev",
+				Caller:       "cli",
+				ExitCode:     3,
+				StdoutBytes:  6,
+				StderrBytes:  5,
+				StdoutSHA256: "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03", // "hello\n".
+				StderrSHA256: "fe19778cf1ce280658154f2b9c01ffbccd825a23460141dcf3794e7a2c0eb629", // "oops\n".
+			},
+		},
+
+		"A command that couldn't be executed should be recorded with the error.": {
+			execErr: fmt.Errorf("connection refused"),
+			expErr:  true,
+			expRecord: model.ExecRecord{
+				SandboxID:    "test-id",
+				Command:      []string{"run"},
+				WorkingDir:   "/app",
+				User:         "dev",
+				Caller:       "cli",
+				ExitCode:     -1,
+				Error:        "connection refused",
+				StdoutSHA256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+				StderrSHA256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mEngine := &sandboxmock.MockEngine{}
+			mRepo := &storagemock.MockRepository{}
+
+			sandbox := &model.Sandbox{ID: "test-id", Name: "test-sandbox", Status: model.SandboxStatusRunning}
+			mRepo.On("GetSandboxByName", mock.Anything, "test-sandbox").Once().Return(sandbox, nil)
+			if test.execErr != nil {
+				mEngine.On("Exec", mock.Anything, "test-id", []string{"run"}, mock.Anything).Once().Return(nil, test.execErr)
+			} else {
+				mEngine.On("Exec", mock.Anything, "test-id", []string{"run"}, mock.Anything).Once().
+					Run(func(args mock.Arguments) {
+						opts := args.Get(3).(model.ExecOpts)
+						_, _ = io.WriteString(opts.Stdout, "hello\n")
+						_, _ = io.WriteString(opts.Stderr, "oops\n")
+					}).
+					Return(&model.ExecResult{ExitCode: 3}, nil)
+			}
+
+			// The activity should be recorded when the command starts and finishes.
+			mRepo.On("UpdateSandboxLastActivity", mock.Anything, "test-id", mock.Anything).Twice().Return(nil)
+			var gotRecord model.ExecRecord
+			mRepo.On("CreateExecRecord", mock.Anything, mock.Anything).Once().
+				Run(func(args mock.Arguments) { gotRecord = args.Get(1).(model.ExecRecord) }).
+				Return(nil)
+
+			svc, err := NewService(ServiceConfig{Engine: mEngine, Repository: mRepo, Logger: log.Noop, User: "dev"})
+			require.NoError(err)
+
+			_, err = svc.Run(context.TODO(), Request{
+				NameOrID: "test-sandbox",
+				Command:  []string{"run"},
+				Opts:     model.ExecOpts{WorkingDir: "/app"},
+				Caller:   "cli",
+			})
+			if test.expErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
 			}
 
+			assert.NotEmpty(gotRecord.ID)
+			assert.False(gotRecord.StartedAt.IsZero())
+			gotRecord.ID = ""
+			gotRecord.StartedAt = time.Time{}
+			gotRecord.Duration = 0
+			assert.Equal(test.expRecord, gotRecord)
+
 			mEngine.AssertExpectations(t)
 			mRepo.AssertExpectations(t)
 		})
@@ -0,0 +1,125 @@
+package exec
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"sync"
+)
+
+// DefaultCaptureMaxBytes is the default per-stream limit for captured output.
+const DefaultCaptureMaxBytes = 1024 * 1024
+
+// outputRecorder is an io.Writer that forwards writes to an optional destination,
+// counts and hashes the written bytes and optionally captures them up to a limit.
+type outputRecorder struct {
+	dst       io.Writer
+	capture   bool
+	maxBytes  int
+	limit     *outputLimit
+	buf       bytes.Buffer
+	written   int64
+	truncated bool
+	hash      hash.Hash
+}
+
+func newOutputRecorder(dst io.Writer, capture bool, maxBytes int, limit *outputLimit) *outputRecorder {
+	return &outputRecorder{
+		dst:      dst,
+		capture:  capture,
+		maxBytes: maxBytes,
+		limit:    limit,
+		hash:     sha256.New(),
+	}
+}
+
+func (o *outputRecorder) Write(p []byte) (int, error) {
+	// The output beyond the limit is discarded, but still counted and hashed.
+	allowed := len(p)
+	if o.limit != nil {
+		allowed = o.limit.take(len(p))
+	}
+
+	n, err := len(p), error(nil)
+	if o.dst != nil && allowed > 0 {
+		n, err = o.dst.Write(p[:allowed])
+		if err == nil {
+			n = len(p)
+		}
+	}
+
+	o.written += int64(n)
+	o.hash.Write(p[:n])
+	o.record(p[:min(n, allowed)])
+
+	return n, err
+}
+
+func (o *outputRecorder) record(p []byte) {
+	if !o.capture {
+		return
+	}
+
+	remaining := o.maxBytes - o.buf.Len()
+	if remaining <= 0 {
+		if len(p) > 0 {
+			o.truncated = true
+		}
+		return
+	}
+
+	if len(p) > remaining {
+		p = p[:remaining]
+		o.truncated = true
+	}
+	o.buf.Write(p)
+}
+
+// String returns the captured output.
+func (o *outputRecorder) String() string { return o.buf.String() }
+
+// SHA256 returns the hex SHA-256 of all the written output.
+func (o *outputRecorder) SHA256() string { return hex.EncodeToString(o.hash.Sum(nil)) }
+
+// outputLimit is the output limit shared by the stdout and stderr of a command.
+type outputLimit struct {
+	mu        sync.Mutex
+	remaining int64
+	exceeded  bool
+	// onExceed is called once, when the limit is exceeded (optional).
+	onExceed func()
+}
+
+func newOutputLimit(maxBytes int64, onExceed func()) *outputLimit {
+	return &outputLimit{remaining: maxBytes, onExceed: onExceed}
+}
+
+// take takes n bytes from the limit and returns how many of them fit in it.
+func (l *outputLimit) take(n int) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if int64(n) <= l.remaining {
+		l.remaining -= int64(n)
+		return n
+	}
+
+	allowed := int(l.remaining)
+	l.remaining = 0
+	if !l.exceeded {
+		l.exceeded = true
+		if l.onExceed != nil {
+			l.onExceed()
+		}
+	}
+	return allowed
+}
+
+// Exceeded returns true when the output exceeded the limit.
+func (l *outputLimit) Exceeded() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.exceeded
+}
@@ -0,0 +1,85 @@
+package exechistory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the exec history service.
+type ServiceConfig struct {
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.ExecHistory"})
+
+	return nil
+}
+
+// Service returns the exec audit trail of sandboxes.
+type Service struct {
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new exec history service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the exec history request parameters.
+type Request struct {
+	// NameOrID is the sandbox name or ID.
+	NameOrID string
+	// Since returns the commands started at or after this time (optional).
+	Since time.Time
+	// Limit returns only the latest N commands, 0 means all.
+	Limit int
+}
+
+// Run returns the commands executed in a sandbox, oldest first.
+func (s *Service) Run(ctx context.Context, req Request) ([]model.ExecRecord, error) {
+	if req.Limit < 0 {
+		return nil, fmt.Errorf("limit can't be negative: %w", model.ErrNotValid)
+	}
+
+	sb, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
+	if errors.Is(err, model.ErrNotFound) {
+		sb, err = s.repo.GetSandbox(ctx, req.NameOrID)
+	}
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("sandbox not found: %s: %w", req.NameOrID, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not get sandbox: %w", err)
+	}
+
+	records, err := s.repo.ListExecRecords(ctx, sb.ID, model.ExecRecordFilter{Since: req.Since, Limit: req.Limit})
+	if err != nil {
+		return nil, fmt.Errorf("could not list exec records: %w", err)
+	}
+
+	s.logger.Debugf("found %d exec records for sandbox %s", len(records), sb.ID)
+	return records, nil
+}
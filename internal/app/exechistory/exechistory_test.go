@@ -0,0 +1,86 @@
+package exechistory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/exechistory"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestServiceRun(t *testing.T) {
+	since := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	records := []model.ExecRecord{
+		{ID: "rec-1", SandboxID: "sb-id", Command: []string{"ls"}},
+		{ID: "rec-2", SandboxID: "sb-id", Command: []string{"pwd"}},
+	}
+
+	tests := map[string]struct {
+		mock       func(m *storagemock.MockRepository)
+		req        exechistory.Request
+		expRecords []model.ExecRecord
+		expErr     error
+	}{
+		"Listing by name should return the sandbox records.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{ID: "sb-id"}, nil)
+				m.On("ListExecRecords", mock.Anything, "sb-id", model.ExecRecordFilter{Since: since, Limit: 2}).Once().Return(records, nil)
+			},
+			req:        exechistory.Request{NameOrID: "my-sandbox", Since: since, Limit: 2},
+			expRecords: records,
+		},
+
+		"Listing by ID should return the sandbox records.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "sb-id").Once().Return(nil, model.ErrNotFound)
+				m.On("GetSandbox", mock.Anything, "sb-id").Once().Return(&model.Sandbox{ID: "sb-id"}, nil)
+				m.On("ListExecRecords", mock.Anything, "sb-id", model.ExecRecordFilter{}).Once().Return(records, nil)
+			},
+			req:        exechistory.Request{NameOrID: "sb-id"},
+			expRecords: records,
+		},
+
+		"A missing sandbox should fail.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "missing").Once().Return(nil, model.ErrNotFound)
+				m.On("GetSandbox", mock.Anything, "missing").Once().Return(nil, model.ErrNotFound)
+			},
+			req:    exechistory.Request{NameOrID: "missing"},
+			expErr: model.ErrNotFound,
+		},
+
+		"A negative limit should fail.": {
+			mock:   func(m *storagemock.MockRepository) {},
+			req:    exechistory.Request{NameOrID: "my-sandbox", Limit: -1},
+			expErr: model.ErrNotValid,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mRepo := &storagemock.MockRepository{}
+			test.mock(mRepo)
+
+			svc, err := exechistory.NewService(exechistory.ServiceConfig{Repository: mRepo})
+			require.NoError(err)
+
+			got, err := svc.Run(context.Background(), test.req)
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expRecords, got)
+			}
+
+			mRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package fileops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the file operations service.
+type ServiceConfig struct {
+	Engine     sandbox.Engine
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Engine == nil {
+		return fmt.Errorf("engine is required")
+	}
+
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.FileOps"})
+
+	return nil
+}
+
+// Service reads, writes, lists and stats the files of running sandboxes without
+// executing commands in them.
+type Service struct {
+	engine sandbox.Engine
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new file operations service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		engine: cfg.Engine,
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the parameters of the file operations.
+type Request struct {
+	// NameOrID is the sandbox name or ID.
+	NameOrID string
+	// Path is the absolute path of the file or directory in the sandbox.
+	Path string
+}
+
+// WriteRequest represents the write file parameters.
+type WriteRequest struct {
+	Request
+	// Data is the new content of the file.
+	Data []byte
+	// Perm are the file permissions (default: 0644).
+	Perm fs.FileMode
+}
+
+// ReadFile returns the content of a sandbox file.
+func (s *Service) ReadFile(ctx context.Context, req Request) ([]byte, error) {
+	sb, err := s.runningSandbox(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.engine.ReadFile(ctx, sb.ID, req.Path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read file: %w", err)
+	}
+
+	return data, nil
+}
+
+// WriteFile writes a sandbox file, creating or truncating it.
+func (s *Service) WriteFile(ctx context.Context, req WriteRequest) error {
+	sb, err := s.runningSandbox(ctx, req.Request)
+	if err != nil {
+		return err
+	}
+
+	perm := req.Perm
+	if perm == 0 {
+		perm = 0644
+	}
+	if perm&^fs.ModePerm != 0 {
+		return fmt.Errorf("permissions %s are not valid, only permission bits are allowed: %w", perm, model.ErrNotValid)
+	}
+
+	if err := s.engine.WriteFile(ctx, sb.ID, req.Path, req.Data, perm); err != nil {
+		return fmt.Errorf("could not write file: %w", err)
+	}
+
+	s.logger.Infof("wrote %d bytes to sandbox %s:%s", len(req.Data), sb.Name, req.Path)
+	return nil
+}
+
+// ListDir returns the entries of a sandbox directory sorted by name.
+func (s *Service) ListDir(ctx context.Context, req Request) ([]model.FileInfo, error) {
+	sb, err := s.runningSandbox(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := s.engine.ListDir(ctx, sb.ID, req.Path)
+	if err != nil {
+		return nil, fmt.Errorf("could not list directory: %w", err)
+	}
+
+	return files, nil
+}
+
+// StatFile returns the information of a sandbox file, following symlinks.
+func (s *Service) StatFile(ctx context.Context, req Request) (*model.FileInfo, error) {
+	sb, err := s.runningSandbox(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := s.engine.StatFile(ctx, sb.ID, req.Path)
+	if err != nil {
+		return nil, fmt.Errorf("could not stat file: %w", err)
+	}
+
+	return info, nil
+}
+
+// runningSandbox validates the request and returns its sandbox, that must be running.
+func (s *Service) runningSandbox(ctx context.Context, req Request) (*model.Sandbox, error) {
+	if req.NameOrID == "" {
+		return nil, fmt.Errorf("sandbox name or ID is required: %w", model.ErrNotValid)
+	}
+	if !path.IsAbs(req.Path) {
+		return nil, fmt.Errorf("path '%s' must be absolute: %w", req.Path, model.ErrNotValid)
+	}
+
+	// Lookup sandbox by name first, then by ID if it looks like a ULID.
+	sandbox, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
+	if errors.Is(err, model.ErrNotFound) && looksLikeULID(req.NameOrID) {
+		sandbox, err = s.repo.GetSandbox(ctx, req.NameOrID)
+	}
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("sandbox not found: %s: %w", req.NameOrID, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not get sandbox: %w", err)
+	}
+
+	if sandbox.Status != model.SandboxStatusRunning {
+		return nil, fmt.Errorf("cannot access files: sandbox not running (current status: %s): %w", sandbox.Status, model.ErrNotValid)
+	}
+
+	return sandbox, nil
+}
+
+// looksLikeULID checks if a string looks like a ULID (26 characters, alphanumeric uppercase).
+func looksLikeULID(s string) bool {
+	if len(s) != 26 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return true
+}
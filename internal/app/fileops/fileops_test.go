@@ -0,0 +1,273 @@
+package fileops_test
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/fileops"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox/sandboxmock"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+const testSandboxID = "01H2QWERTYASDFGZXCVBNMLKJH"
+
+var testRunning = &model.Sandbox{
+	ID:     testSandboxID,
+	Name:   "my-sandbox",
+	Status: model.SandboxStatusRunning,
+}
+
+func TestNewService(t *testing.T) {
+	tests := map[string]struct {
+		config fileops.ServiceConfig
+		expErr bool
+	}{
+		"valid config should create service": {
+			config: fileops.ServiceConfig{
+				Engine:     &sandboxmock.MockEngine{},
+				Repository: &storagemock.MockRepository{},
+				Logger:     log.Noop,
+			},
+		},
+		"missing engine should fail": {
+			config: fileops.ServiceConfig{
+				Repository: &storagemock.MockRepository{},
+			},
+			expErr: true,
+		},
+		"missing repository should fail": {
+			config: fileops.ServiceConfig{
+				Engine: &sandboxmock.MockEngine{},
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			svc, err := fileops.NewService(test.config)
+
+			if test.expErr {
+				require.Error(err)
+				require.Nil(svc)
+			} else {
+				require.NoError(err)
+				require.NotNil(svc)
+			}
+		})
+	}
+}
+
+func TestService_ReadFile(t *testing.T) {
+	tests := map[string]struct {
+		mockRepo   func(m *storagemock.MockRepository)
+		mockEngine func(m *sandboxmock.MockEngine)
+		req        fileops.Request
+		expData    []byte
+		expErrIs   error
+		expErr     bool
+	}{
+		"reading a file of a running sandbox should read it with the engine": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(testRunning, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("ReadFile", mock.Anything, testSandboxID, "/etc/hostname").Once().Return([]byte("my-sandbox\n"), nil)
+			},
+			req:     fileops.Request{NameOrID: "my-sandbox", Path: "/etc/hostname"},
+			expData: []byte("my-sandbox\n"),
+		},
+		"reading by ID should lookup the sandbox by ID": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, testSandboxID).Once().Return(nil, model.ErrNotFound)
+				m.On("GetSandbox", mock.Anything, testSandboxID).Once().Return(testRunning, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("ReadFile", mock.Anything, testSandboxID, "/etc/hostname").Once().Return([]byte("x"), nil)
+			},
+			req:     fileops.Request{NameOrID: testSandboxID, Path: "/etc/hostname"},
+			expData: []byte("x"),
+		},
+		"a relative path should fail": {
+			mockRepo:   func(m *storagemock.MockRepository) {},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        fileops.Request{NameOrID: "my-sandbox", Path: "etc/hostname"},
+			expErrIs:   model.ErrNotValid,
+		},
+		"a missing sandbox name should fail": {
+			mockRepo:   func(m *storagemock.MockRepository) {},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        fileops.Request{Path: "/etc/hostname"},
+			expErrIs:   model.ErrNotValid,
+		},
+		"a stopped sandbox should fail": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:     testSandboxID,
+					Name:   "my-sandbox",
+					Status: model.SandboxStatusStopped,
+				}, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        fileops.Request{NameOrID: "my-sandbox", Path: "/etc/hostname"},
+			expErrIs:   model.ErrNotValid,
+		},
+		"a missing sandbox should fail": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "nonexistent").Once().Return(nil, model.ErrNotFound)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        fileops.Request{NameOrID: "nonexistent", Path: "/etc/hostname"},
+			expErrIs:   model.ErrNotFound,
+		},
+		"a missing file should fail with not found": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(testRunning, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("ReadFile", mock.Anything, testSandboxID, "/missing").Once().Return(nil, fmt.Errorf("missing: %w", model.ErrNotFound))
+			},
+			req:      fileops.Request{NameOrID: "my-sandbox", Path: "/missing"},
+			expErrIs: model.ErrNotFound,
+		},
+		"engine error should propagate": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(testRunning, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("ReadFile", mock.Anything, testSandboxID, "/etc/hostname").Once().Return(nil, fmt.Errorf("engine error"))
+			},
+			req:    fileops.Request{NameOrID: "my-sandbox", Path: "/etc/hostname"},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			svc, mRepo, mEngine := newTestService(t, test.mockRepo, test.mockEngine)
+
+			data, err := svc.ReadFile(context.Background(), test.req)
+
+			if test.expErr || test.expErrIs != nil {
+				assert.Error(err)
+				if test.expErrIs != nil {
+					assert.ErrorIs(err, test.expErrIs)
+				}
+			} else if assert.NoError(err) {
+				assert.Equal(test.expData, data)
+			}
+
+			mRepo.AssertExpectations(t)
+			mEngine.AssertExpectations(t)
+		})
+	}
+}
+
+func TestService_WriteFile(t *testing.T) {
+	tests := map[string]struct {
+		mockEngine func(m *sandboxmock.MockEngine)
+		req        fileops.WriteRequest
+		expErrIs   error
+	}{
+		"writing without permissions should use the default ones": {
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("WriteFile", mock.Anything, testSandboxID, "/tmp/f", []byte("data"), fs.FileMode(0644)).Once().Return(nil)
+			},
+			req: fileops.WriteRequest{Request: fileops.Request{NameOrID: "my-sandbox", Path: "/tmp/f"}, Data: []byte("data")},
+		},
+		"writing with permissions should use them": {
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("WriteFile", mock.Anything, testSandboxID, "/tmp/run.sh", []byte("#!/bin/sh"), fs.FileMode(0755)).Once().Return(nil)
+			},
+			req: fileops.WriteRequest{Request: fileops.Request{NameOrID: "my-sandbox", Path: "/tmp/run.sh"}, Data: []byte("#!/bin/sh"), Perm: 0755},
+		},
+		"writing with file type bits should fail": {
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        fileops.WriteRequest{Request: fileops.Request{NameOrID: "my-sandbox", Path: "/tmp/d"}, Perm: fs.ModeDir | 0755},
+			expErrIs:   model.ErrNotValid,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			mockRepo := func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(testRunning, nil)
+			}
+			svc, _, mEngine := newTestService(t, mockRepo, test.mockEngine)
+
+			err := svc.WriteFile(context.Background(), test.req)
+
+			if test.expErrIs != nil {
+				assert.ErrorIs(err, test.expErrIs)
+			} else {
+				assert.NoError(err)
+			}
+
+			mEngine.AssertExpectations(t)
+		})
+	}
+}
+
+func TestService_ListDirAndStatFile(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	modTime := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	file := model.FileInfo{Name: "main.go", Path: "/workspace/main.go", Size: 12, Mode: 0644, ModTime: modTime}
+	dir := model.FileInfo{Name: "workspace", Path: "/workspace", Mode: fs.ModeDir | 0755, ModTime: modTime, IsDir: true}
+
+	mockRepo := func(m *storagemock.MockRepository) {
+		m.On("GetSandboxByName", mock.Anything, "my-sandbox").Twice().Return(testRunning, nil)
+	}
+	mockEngine := func(m *sandboxmock.MockEngine) {
+		m.On("ListDir", mock.Anything, testSandboxID, "/workspace").Once().Return([]model.FileInfo{file}, nil)
+		m.On("StatFile", mock.Anything, testSandboxID, "/workspace").Once().Return(&dir, nil)
+	}
+	svc, mRepo, mEngine := newTestService(t, mockRepo, mockEngine)
+
+	files, err := svc.ListDir(context.Background(), fileops.Request{NameOrID: "my-sandbox", Path: "/workspace"})
+	require.NoError(err)
+	assert.Equal([]model.FileInfo{file}, files)
+
+	info, err := svc.StatFile(context.Background(), fileops.Request{NameOrID: "my-sandbox", Path: "/workspace"})
+	require.NoError(err)
+	assert.Equal(&dir, info)
+
+	_, err = svc.ListDir(context.Background(), fileops.Request{NameOrID: "my-sandbox", Path: "workspace"})
+	assert.ErrorIs(err, model.ErrNotValid)
+
+	mRepo.AssertExpectations(t)
+	mEngine.AssertExpectations(t)
+}
+
+func newTestService(t *testing.T, mockRepo func(m *storagemock.MockRepository), mockEngine func(m *sandboxmock.MockEngine)) (*fileops.Service, *storagemock.MockRepository, *sandboxmock.MockEngine) {
+	t.Helper()
+
+	mRepo := &storagemock.MockRepository{}
+	mEngine := &sandboxmock.MockEngine{}
+	mockRepo(mRepo)
+	mockEngine(mEngine)
+
+	svc, err := fileops.NewService(fileops.ServiceConfig{
+		Engine:     mEngine,
+		Repository: mRepo,
+	})
+	require.NoError(t, err)
+
+	return svc, mRepo, mEngine
+}
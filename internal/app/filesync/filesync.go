@@ -0,0 +1,147 @@
+package filesync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the sync service.
+type ServiceConfig struct {
+	Engine     sandbox.Engine
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Engine == nil {
+		return fmt.Errorf("engine is required")
+	}
+
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.FileSync"})
+
+	return nil
+}
+
+// Service syncs host directories into running sandboxes, transferring only the
+// changed files.
+type Service struct {
+	engine sandbox.Engine
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new sync service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		engine: cfg.Engine,
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the sync request parameters.
+type Request struct {
+	// NameOrID is the sandbox name or ID.
+	NameOrID string
+	// LocalDir is the host directory to sync.
+	LocalDir string
+	// RemoteDir is the sandbox directory that mirrors LocalDir.
+	RemoteDir string
+	// Opts are the sync options.
+	Opts model.SyncOpts
+}
+
+// Run syncs the local directory into the sandbox directory.
+func (s *Service) Run(ctx context.Context, req Request) (*model.SyncResult, error) {
+	if err := s.validate(req); err != nil {
+		return nil, err
+	}
+
+	// Lookup sandbox by name first, then by ID if it looks like a ULID.
+	sandbox, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
+	if errors.Is(err, model.ErrNotFound) && looksLikeULID(req.NameOrID) {
+		sandbox, err = s.repo.GetSandbox(ctx, req.NameOrID)
+	}
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("sandbox not found: %s: %w", req.NameOrID, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not get sandbox: %w", err)
+	}
+
+	if sandbox.Status != model.SandboxStatusRunning {
+		return nil, fmt.Errorf("cannot sync: sandbox not running (current status: %s): %w", sandbox.Status, model.ErrNotValid)
+	}
+
+	start := time.Now()
+	res, err := s.engine.Sync(ctx, sandbox.ID, req.LocalDir, req.RemoteDir, req.Opts)
+	if err != nil {
+		return nil, fmt.Errorf("could not sync: %w", err)
+	}
+
+	s.logger.Infof("synced %s to sandbox %s:%s in %s (%d uploaded, %d deleted, %d unchanged)",
+		req.LocalDir, sandbox.Name, req.RemoteDir, time.Since(start).Round(time.Millisecond), len(res.Uploaded), len(res.Deleted), res.Unchanged)
+	return res, nil
+}
+
+func (s *Service) validate(req Request) error {
+	if req.LocalDir == "" {
+		return fmt.Errorf("local directory is required: %w", model.ErrNotValid)
+	}
+	if req.RemoteDir == "" {
+		return fmt.Errorf("remote directory is required: %w", model.ErrNotValid)
+	}
+	// Deleting the missing files of the sandbox root would wipe the sandbox.
+	if req.Opts.Delete && path.Clean(req.RemoteDir) == "/" {
+		return fmt.Errorf("can't sync with delete into the sandbox root directory: %w", model.ErrNotValid)
+	}
+	if err := req.Opts.Validate(); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(req.LocalDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("local directory '%s' does not exist: %w", req.LocalDir, model.ErrNotValid)
+		}
+		return fmt.Errorf("could not stat local directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("local path '%s' is not a directory: %w", req.LocalDir, model.ErrNotValid)
+	}
+
+	return nil
+}
+
+// looksLikeULID checks if a string looks like a ULID (26 characters, alphanumeric uppercase).
+func looksLikeULID(s string) bool {
+	if len(s) != 26 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return true
+}
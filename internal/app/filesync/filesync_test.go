@@ -0,0 +1,201 @@
+package filesync_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/filesync"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox/sandboxmock"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestNewService(t *testing.T) {
+	tests := map[string]struct {
+		config filesync.ServiceConfig
+		expErr bool
+	}{
+		"valid config should create service": {
+			config: filesync.ServiceConfig{
+				Engine:     &sandboxmock.MockEngine{},
+				Repository: &storagemock.MockRepository{},
+				Logger:     log.Noop,
+			},
+		},
+		"missing engine should fail": {
+			config: filesync.ServiceConfig{
+				Repository: &storagemock.MockRepository{},
+			},
+			expErr: true,
+		},
+		"missing repository should fail": {
+			config: filesync.ServiceConfig{
+				Engine: &sandboxmock.MockEngine{},
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			svc, err := filesync.NewService(test.config)
+
+			if test.expErr {
+				require.Error(err)
+				require.Nil(svc)
+			} else {
+				require.NoError(err)
+				require.NotNil(svc)
+			}
+		})
+	}
+}
+
+func TestService_Run(t *testing.T) {
+	localDir := t.TempDir()
+	localFile := filepath.Join(localDir, "file.txt")
+	require.NoError(t, os.WriteFile(localFile, []byte("data"), 0644))
+
+	running := &model.Sandbox{
+		ID:     "01H2QWERTYASDFGZXCVBNMLKJH",
+		Name:   "my-sandbox",
+		Status: model.SandboxStatusRunning,
+	}
+	syncOpts := model.SyncOpts{Delete: true, Exclude: []string{".git"}}
+
+	tests := map[string]struct {
+		mockRepo   func(m *storagemock.MockRepository)
+		mockEngine func(m *sandboxmock.MockEngine)
+		req        filesync.Request
+		expResult  *model.SyncResult
+		expErrIs   error
+		expErr     bool
+	}{
+		"syncing into a running sandbox should sync with the engine": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(running, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Sync", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", localDir, "/workspace", syncOpts).Once().
+					Return(&model.SyncResult{Uploaded: []string{"file.txt"}, UploadedBytes: 4}, nil)
+			},
+			req:       filesync.Request{NameOrID: "my-sandbox", LocalDir: localDir, RemoteDir: "/workspace", Opts: syncOpts},
+			expResult: &model.SyncResult{Uploaded: []string{"file.txt"}, UploadedBytes: 4},
+		},
+		"syncing by ID should lookup the sandbox by ID": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH").Once().Return(nil, model.ErrNotFound)
+				m.On("GetSandbox", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH").Once().Return(running, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Sync", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", localDir, "/workspace", model.SyncOpts{}).Once().
+					Return(&model.SyncResult{Unchanged: 1}, nil)
+			},
+			req:       filesync.Request{NameOrID: "01H2QWERTYASDFGZXCVBNMLKJH", LocalDir: localDir, RemoteDir: "/workspace"},
+			expResult: &model.SyncResult{Unchanged: 1},
+		},
+		"a missing local directory should fail": {
+			mockRepo:   func(m *storagemock.MockRepository) {},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        filesync.Request{NameOrID: "my-sandbox", LocalDir: "/nonexistent/dir", RemoteDir: "/workspace"},
+			expErrIs:   model.ErrNotValid,
+		},
+		"a local file should fail": {
+			mockRepo:   func(m *storagemock.MockRepository) {},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        filesync.Request{NameOrID: "my-sandbox", LocalDir: localFile, RemoteDir: "/workspace"},
+			expErrIs:   model.ErrNotValid,
+		},
+		"a missing remote directory should fail": {
+			mockRepo:   func(m *storagemock.MockRepository) {},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        filesync.Request{NameOrID: "my-sandbox", LocalDir: localDir},
+			expErrIs:   model.ErrNotValid,
+		},
+		"deleting into the sandbox root should fail": {
+			mockRepo:   func(m *storagemock.MockRepository) {},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        filesync.Request{NameOrID: "my-sandbox", LocalDir: localDir, RemoteDir: "//", Opts: model.SyncOpts{Delete: true}},
+			expErrIs:   model.ErrNotValid,
+		},
+		"an invalid exclude pattern should fail": {
+			mockRepo:   func(m *storagemock.MockRepository) {},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        filesync.Request{NameOrID: "my-sandbox", LocalDir: localDir, RemoteDir: "/workspace", Opts: model.SyncOpts{Exclude: []string{"[a-"}}},
+			expErrIs:   model.ErrNotValid,
+		},
+		"a stopped sandbox should fail": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:     "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:   "my-sandbox",
+					Status: model.SandboxStatusStopped,
+				}, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        filesync.Request{NameOrID: "my-sandbox", LocalDir: localDir, RemoteDir: "/workspace"},
+			expErrIs:   model.ErrNotValid,
+		},
+		"a missing sandbox should fail": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "nonexistent").Once().Return(nil, model.ErrNotFound)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        filesync.Request{NameOrID: "nonexistent", LocalDir: localDir, RemoteDir: "/workspace"},
+			expErrIs:   model.ErrNotFound,
+		},
+		"engine error should propagate": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(running, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Sync", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", localDir, "/workspace", model.SyncOpts{}).Once().
+					Return(nil, fmt.Errorf("engine error"))
+			},
+			req:    filesync.Request{NameOrID: "my-sandbox", LocalDir: localDir, RemoteDir: "/workspace"},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mRepo := &storagemock.MockRepository{}
+			mEngine := &sandboxmock.MockEngine{}
+			test.mockRepo(mRepo)
+			test.mockEngine(mEngine)
+
+			svc, err := filesync.NewService(filesync.ServiceConfig{
+				Engine:     mEngine,
+				Repository: mRepo,
+			})
+			require.NoError(err)
+
+			res, err := svc.Run(context.Background(), test.req)
+
+			if test.expErr || test.expErrIs != nil {
+				assert.Error(err)
+				if test.expErrIs != nil {
+					assert.ErrorIs(err, test.expErrIs)
+				}
+			} else if assert.NoError(err) {
+				assert.Equal(test.expResult, res)
+			}
+
+			mRepo.AssertExpectations(t)
+			mEngine.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package filesync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+// DefaultWatchDebounce is the default quiet time after a change before syncing.
+const DefaultWatchDebounce = 300 * time.Millisecond
+
+// WatchRequest represents the sync watch request parameters.
+type WatchRequest struct {
+	// Request is the sync that is repeated on each change.
+	Request
+	// Debounce is the quiet time after a change before syncing, so a burst of
+	// changes (e.g. a git checkout or a build) is synced once (default: 300ms).
+	Debounce time.Duration
+	// OnSync is called after each sync with its result or error (optional).
+	OnSync func(*model.SyncResult, error)
+}
+
+// Watch syncs the local directory into the sandbox and keeps syncing it on each
+// change until the context is cancelled. The failed syncs (e.g. the sandbox is
+// restarting) are reported to OnSync and retried on the next change.
+//
+// Returns nil when the context is cancelled.
+func (s *Service) Watch(ctx context.Context, req WatchRequest) error {
+	if req.Debounce <= 0 {
+		req.Debounce = DefaultWatchDebounce
+	}
+	onSync := req.OnSync
+	if onSync == nil {
+		onSync = func(*model.SyncResult, error) {}
+	}
+
+	if err := s.validate(req.Request); err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("could not create file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := s.watchDir(watcher, req.LocalDir, req.LocalDir, req.Opts); err != nil {
+		return err
+	}
+
+	// The first sync validates the sandbox, its errors are not retried.
+	res, err := s.Run(ctx, req.Request)
+	if err != nil {
+		return err
+	}
+	onSync(res, nil)
+
+	logger := s.logger.WithValues(log.Kv{"dir": req.LocalDir})
+	timer := time.NewTimer(req.Debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return fmt.Errorf("file watcher closed")
+			}
+			if !s.relevantEvent(watcher, req, event) {
+				continue
+			}
+			timer.Reset(req.Debounce)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return fmt.Errorf("file watcher closed")
+			}
+			// Overflowed events are lost, syncing compares the whole tree anyway.
+			logger.Warningf("File watcher error: %v", err)
+			timer.Reset(req.Debounce)
+
+		case <-timer.C:
+			res, err := s.Run(ctx, req.Request)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				logger.Warningf("Sync failed: %v", err)
+			}
+			onSync(res, err)
+		}
+	}
+}
+
+// relevantEvent returns true when the event changes a synced path, new
+// directories are watched.
+func (s *Service) relevantEvent(watcher *fsnotify.Watcher, req WatchRequest, event fsnotify.Event) bool {
+	if event.Op == fsnotify.Chmod {
+		// Access time updates are reported as chmod too.
+		return false
+	}
+
+	rel, err := filepath.Rel(req.LocalDir, event.Name)
+	if err != nil || req.Opts.Excluded(filepath.ToSlash(rel)) {
+		return false
+	}
+
+	if event.Has(fsnotify.Create) {
+		if err := s.watchDir(watcher, req.LocalDir, event.Name, req.Opts); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			s.logger.Warningf("Could not watch %s: %v", event.Name, err)
+		}
+	}
+
+	return true
+}
+
+// watchDir watches a directory of the synced root and its subdirectories, except
+// the excluded ones. Paths that are not directories are ignored.
+func (s *Service) watchDir(watcher *fsnotify.Watcher, root, dir string, opts model.SyncOpts) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+
+		if rel, err := filepath.Rel(root, p); err == nil && opts.Excluded(filepath.ToSlash(rel)) {
+			return filepath.SkipDir
+		}
+
+		if err := watcher.Add(p); err != nil {
+			return fmt.Errorf("could not watch %s: %w", p, err)
+		}
+		return nil
+	})
+}
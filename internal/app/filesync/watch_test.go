@@ -0,0 +1,123 @@
+package filesync_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/filesync"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox/sandboxmock"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestService_Watch(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	localDir := t.TempDir()
+	require.NoError(os.MkdirAll(filepath.Join(localDir, ".git"), 0755))
+
+	running := &model.Sandbox{
+		ID:     "01H2QWERTYASDFGZXCVBNMLKJH",
+		Name:   "my-sandbox",
+		Status: model.SandboxStatusRunning,
+	}
+	opts := model.SyncOpts{Exclude: []string{".git"}}
+
+	mRepo := &storagemock.MockRepository{}
+	mRepo.On("GetSandboxByName", mock.Anything, "my-sandbox").Return(running, nil)
+	mEngine := &sandboxmock.MockEngine{}
+	mEngine.On("Sync", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", localDir, "/workspace", opts).Return(&model.SyncResult{}, nil)
+
+	svc, err := filesync.NewService(filesync.ServiceConfig{
+		Engine:     mEngine,
+		Repository: mRepo,
+	})
+	require.NoError(err)
+
+	synced := make(chan struct{}, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- svc.Watch(ctx, filesync.WatchRequest{
+			Request:  filesync.Request{NameOrID: "my-sandbox", LocalDir: localDir, RemoteDir: "/workspace", Opts: opts},
+			Debounce: 50 * time.Millisecond,
+			OnSync: func(res *model.SyncResult, err error) {
+				assert.NoError(err)
+				synced <- struct{}{}
+			},
+		})
+	}()
+
+	waitSync := func(msg string) {
+		t.Helper()
+		select {
+		case <-synced:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for sync: %s", msg)
+		}
+	}
+	noSync := func(msg string) {
+		t.Helper()
+		select {
+		case <-synced:
+			t.Fatalf("unexpected sync: %s", msg)
+		case <-time.After(300 * time.Millisecond):
+		}
+	}
+
+	waitSync("initial sync")
+
+	// A burst of changes should be synced once.
+	for i := range 5 {
+		require.NoError(os.WriteFile(filepath.Join(localDir, "main.go"), []byte{byte(i)}, 0644))
+	}
+	waitSync("file change")
+	noSync("burst of changes")
+
+	// Excluded paths should not trigger a sync.
+	require.NoError(os.WriteFile(filepath.Join(localDir, ".git", "HEAD"), []byte("ref"), 0644))
+	noSync("excluded change")
+
+	// New directories should be watched.
+	require.NoError(os.MkdirAll(filepath.Join(localDir, "pkg"), 0755))
+	waitSync("new directory")
+	require.NoError(os.WriteFile(filepath.Join(localDir, "pkg", "lib.go"), []byte("package pkg"), 0644))
+	waitSync("new directory file")
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for watch to end")
+	}
+}
+
+func TestService_WatchInitialSyncError(t *testing.T) {
+	mRepo := &storagemock.MockRepository{}
+	mRepo.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+		ID:     "01H2QWERTYASDFGZXCVBNMLKJH",
+		Name:   "my-sandbox",
+		Status: model.SandboxStatusStopped,
+	}, nil)
+
+	svc, err := filesync.NewService(filesync.ServiceConfig{
+		Engine:     &sandboxmock.MockEngine{},
+		Repository: mRepo,
+	})
+	require.NoError(t, err)
+
+	err = svc.Watch(context.Background(), filesync.WatchRequest{
+		Request: filesync.Request{NameOrID: "my-sandbox", LocalDir: t.TempDir(), RemoteDir: "/workspace"},
+	})
+	assert.ErrorIs(t, err, model.ErrNotValid)
+	mRepo.AssertExpectations(t)
+}
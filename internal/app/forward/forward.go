@@ -4,11 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/slok/sbx/internal/log"
 	"github.com/slok/sbx/internal/model"
 	"github.com/slok/sbx/internal/sandbox"
 	"github.com/slok/sbx/internal/storage"
+	"github.com/slok/sbx/internal/tlscert"
 )
 
 // ServiceConfig is the configuration for the forward service.
@@ -16,6 +18,12 @@ type ServiceConfig struct {
 	Engine     sandbox.Engine
 	Repository storage.Repository
 	Logger     log.Logger
+	// TLSDir is the directory of the generated localhost certificate, used by the
+	// TLS ports without certificate files (optional).
+	TLSDir string
+	// QuarantineCheckInterval is how often the forwarded sandbox is checked, the
+	// forwarding stops when it's quarantined (default: 2s).
+	QuarantineCheckInterval time.Duration
 }
 
 func (c *ServiceConfig) defaults() error {
@@ -25,6 +33,9 @@ func (c *ServiceConfig) defaults() error {
 	if c.Repository == nil {
 		return fmt.Errorf("repository is required")
 	}
+	if c.QuarantineCheckInterval <= 0 {
+		c.QuarantineCheckInterval = 2 * time.Second
+	}
 	if c.Logger == nil {
 		c.Logger = log.Noop
 	}
@@ -34,9 +45,11 @@ func (c *ServiceConfig) defaults() error {
 
 // Service handles port forwarding to sandboxes.
 type Service struct {
-	engine sandbox.Engine
-	repo   storage.Repository
-	logger log.Logger
+	engine        sandbox.Engine
+	repo          storage.Repository
+	logger        log.Logger
+	tlsDir        string
+	checkInterval time.Duration
 }
 
 // NewService creates a new forward service.
@@ -46,9 +59,11 @@ func NewService(cfg ServiceConfig) (*Service, error) {
 	}
 
 	return &Service{
-		engine: cfg.Engine,
-		repo:   cfg.Repository,
-		logger: cfg.Logger,
+		engine:        cfg.Engine,
+		repo:          cfg.Repository,
+		logger:        cfg.Logger,
+		tlsDir:        cfg.TLSDir,
+		checkInterval: cfg.QuarantineCheckInterval,
 	}, nil
 }
 
@@ -56,15 +71,22 @@ func NewService(cfg ServiceConfig) (*Service, error) {
 type Request struct {
 	NameOrID string
 	Ports    []model.PortMapping
+	// Ready is called once the local ports listen, with the bound local ports of
+	// the free (0) ones (optional).
+	Ready func(ports []model.PortMapping)
 }
 
 // Run starts port forwarding to a sandbox.
-// Blocks until context is cancelled or connection drops.
+// Blocks until context is cancelled, connection drops or the sandbox is quarantined.
 func (s *Service) Run(ctx context.Context, req Request) error {
 	// 1. Validate ports
 	if len(req.Ports) == 0 {
 		return fmt.Errorf("at least one port mapping is required: %w", model.ErrNotValid)
 	}
+	ports, err := s.resolveTLS(req.Ports)
+	if err != nil {
+		return err
+	}
 
 	// 2. Get sandbox from storage (by name or ID)
 	sbx, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
@@ -82,14 +104,25 @@ func (s *Service) Run(ctx context.Context, req Request) error {
 	if sbx.Status != model.SandboxStatusRunning {
 		return fmt.Errorf("sandbox %s is not running (status: %s): %w", sbx.Name, sbx.Status, model.ErrNotValid)
 	}
+	if sbx.QuarantinedAt != nil {
+		return fmt.Errorf("sandbox %s is quarantined: %w", sbx.Name, model.ErrNotValid)
+	}
 
 	s.logger.Debugf("Starting port forwarding to sandbox %s (%s)", sbx.Name, sbx.ID)
-	for _, pm := range req.Ports {
+	for _, pm := range ports {
 		s.logger.Debugf("  localhost:%d -> sandbox:%d", pm.LocalPort, pm.RemotePort)
 	}
 
-	// 4. Forward ports via engine (blocks until context cancelled)
-	if err := s.engine.Forward(ctx, sbx.ID, req.Ports); err != nil {
+	// 4. Forward ports via engine (blocks until context cancelled), the quarantine of
+	// the sandbox revokes the forwarding.
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go s.watchQuarantine(ctx, sbx.ID, cancel)
+
+	if err := s.engine.Forward(ctx, sbx.ID, ports, sandbox.ForwardOpts{Ready: req.Ready}); err != nil {
+		if errors.Is(context.Cause(ctx), ErrQuarantined) {
+			return fmt.Errorf("port forwarding stopped: %s: %w: %w", sbx.Name, ErrQuarantined, model.ErrNotValid)
+		}
 		// Context cancellation is expected behavior
 		if errors.Is(err, context.Canceled) {
 			s.logger.Debugf("Port forwarding stopped")
@@ -100,3 +133,50 @@ func (s *Service) Run(ctx context.Context, req Request) error {
 
 	return nil
 }
+
+// ErrQuarantined is returned when the forwarding stops because the sandbox was
+// quarantined.
+var ErrQuarantined = errors.New("sandbox was quarantined")
+
+// watchQuarantine cancels the forwarding with ErrQuarantined once the sandbox is
+// quarantined. The quarantine is set by other processes, so the sandbox is polled.
+func (s *Service) watchQuarantine(ctx context.Context, id string, cancel context.CancelCauseFunc) {
+	t := time.NewTicker(s.checkInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		sb, err := s.repo.GetSandbox(ctx, id)
+		if err != nil {
+			s.logger.Debugf("could not check sandbox %s quarantine: %v", id, err)
+			continue
+		}
+		if sb.QuarantinedAt != nil {
+			s.logger.Warningf("sandbox %s was quarantined, stopping port forwarding", sb.Name)
+			cancel(ErrQuarantined)
+			return
+		}
+	}
+}
+
+// resolveTLS sets the generated localhost certificate on the TLS ports without
+// certificate files.
+func (s *Service) resolveTLS(ports []model.PortMapping) ([]model.PortMapping, error) {
+	resolved := make([]model.PortMapping, 0, len(ports))
+	for _, pm := range ports {
+		if pm.TLS != nil {
+			t, err := tlscert.Resolve(*pm.TLS, s.tlsDir)
+			if err != nil {
+				return nil, err
+			}
+			pm.TLS = &t
+		}
+		resolved = append(resolved, pm)
+	}
+	return resolved, nil
+}
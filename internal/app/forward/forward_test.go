@@ -3,7 +3,9 @@ package forward_test
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -12,6 +14,7 @@ import (
 	"github.com/slok/sbx/internal/app/forward"
 	"github.com/slok/sbx/internal/log"
 	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
 	"github.com/slok/sbx/internal/sandbox/sandboxmock"
 	"github.com/slok/sbx/internal/storage/storagemock"
 )
@@ -95,6 +98,21 @@ func TestServiceRun(t *testing.T) {
 			},
 			expErr: true,
 		},
+		"Quarantined sandbox should fail.": {
+			mock: func(mRepo *storagemock.MockRepository, mEngine *sandboxmock.MockEngine) {
+				mRepo.On("GetSandboxByName", mock.Anything, "test-sandbox").Return(&model.Sandbox{
+					ID:            "test-id",
+					Name:          "test-sandbox",
+					Status:        model.SandboxStatusRunning,
+					QuarantinedAt: &time.Time{},
+				}, nil)
+			},
+			req: forward.Request{
+				NameOrID: "test-sandbox",
+				Ports:    []model.PortMapping{{LocalPort: 8080, RemotePort: 8080}},
+			},
+			expErr: true,
+		},
 		"Engine Forward error should fail.": {
 			mock: func(mRepo *storagemock.MockRepository, mEngine *sandboxmock.MockEngine) {
 				mRepo.On("GetSandboxByName", mock.Anything, "test-sandbox").Return(&model.Sandbox{
@@ -102,7 +120,7 @@ func TestServiceRun(t *testing.T) {
 					Name:   "test-sandbox",
 					Status: model.SandboxStatusRunning,
 				}, nil)
-				mEngine.On("Forward", mock.Anything, "test-id", []model.PortMapping{{LocalPort: 8080, RemotePort: 8080}}).
+				mEngine.On("Forward", mock.Anything, "test-id", []model.PortMapping{{LocalPort: 8080, RemotePort: 8080}}, sandbox.ForwardOpts{}).
 					Return(fmt.Errorf("forward error"))
 			},
 			req: forward.Request{
@@ -118,7 +136,7 @@ func TestServiceRun(t *testing.T) {
 					Name:   "test-sandbox",
 					Status: model.SandboxStatusRunning,
 				}, nil)
-				mEngine.On("Forward", mock.Anything, "test-id", []model.PortMapping{{LocalPort: 8080, RemotePort: 8080}}).
+				mEngine.On("Forward", mock.Anything, "test-id", []model.PortMapping{{LocalPort: 8080, RemotePort: 8080}}, sandbox.ForwardOpts{}).
 					Return(context.Canceled)
 			},
 			req: forward.Request{
@@ -134,7 +152,7 @@ func TestServiceRun(t *testing.T) {
 					Name:   "test-sandbox",
 					Status: model.SandboxStatusRunning,
 				}, nil)
-				mEngine.On("Forward", mock.Anything, "test-id", []model.PortMapping{{LocalPort: 8080, RemotePort: 8080}}).
+				mEngine.On("Forward", mock.Anything, "test-id", []model.PortMapping{{LocalPort: 8080, RemotePort: 8080}}, sandbox.ForwardOpts{}).
 					Return(nil)
 			},
 			req: forward.Request{
@@ -155,7 +173,7 @@ func TestServiceRun(t *testing.T) {
 					{LocalPort: 3000, RemotePort: 3000},
 					{LocalPort: 9000, RemotePort: 5432},
 				}
-				mEngine.On("Forward", mock.Anything, "test-id", ports).Return(nil)
+				mEngine.On("Forward", mock.Anything, "test-id", ports, sandbox.ForwardOpts{}).Return(nil)
 			},
 			req: forward.Request{
 				NameOrID: "test-sandbox",
@@ -175,7 +193,7 @@ func TestServiceRun(t *testing.T) {
 					Name:   "test-sandbox",
 					Status: model.SandboxStatusRunning,
 				}, nil)
-				mEngine.On("Forward", mock.Anything, "01ABC123", []model.PortMapping{{LocalPort: 8080, RemotePort: 8080}}).
+				mEngine.On("Forward", mock.Anything, "01ABC123", []model.PortMapping{{LocalPort: 8080, RemotePort: 8080}}, sandbox.ForwardOpts{}).
 					Return(nil)
 			},
 			req: forward.Request{
@@ -215,3 +233,113 @@ func TestServiceRun(t *testing.T) {
 		})
 	}
 }
+
+func TestServiceRunQuarantine(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	running := &model.Sandbox{ID: "test-id", Name: "test-sandbox", Status: model.SandboxStatusRunning}
+	quarantined := *running
+	quarantined.QuarantinedAt = &time.Time{}
+
+	mRepo := storagemock.NewMockRepository(t)
+	mEngine := sandboxmock.NewMockEngine(t)
+	mRepo.On("GetSandboxByName", mock.Anything, "test-sandbox").Once().Return(running, nil)
+	mRepo.On("GetSandbox", mock.Anything, "test-id").Once().Return(running, nil)
+	mRepo.On("GetSandbox", mock.Anything, "test-id").Return(&quarantined, nil)
+	// The forwarding blocks until it's cancelled.
+	mEngine.On("Forward", mock.Anything, "test-id", mock.Anything, mock.Anything).Once().
+		Return(func(ctx context.Context, _ string, _ []model.PortMapping, _ sandbox.ForwardOpts) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+	svc, err := forward.NewService(forward.ServiceConfig{
+		Engine:                  mEngine,
+		Repository:              mRepo,
+		QuarantineCheckInterval: time.Millisecond,
+	})
+	require.NoError(err)
+
+	err = svc.Run(context.Background(), forward.Request{
+		NameOrID: "test-sandbox",
+		Ports:    []model.PortMapping{{LocalPort: 8080, RemotePort: 8080}},
+	})
+	assert.ErrorIs(err, forward.ErrQuarantined)
+	assert.ErrorIs(err, model.ErrNotValid)
+}
+
+func TestServiceRunTLS(t *testing.T) {
+	running := &model.Sandbox{ID: "test-id", Name: "test-sandbox", Status: model.SandboxStatusRunning}
+	tlsDir := t.TempDir()
+
+	tests := map[string]struct {
+		tlsDir string
+		tls    *model.ForwardTLS
+		mock   func(mRepo *storagemock.MockRepository, mEngine *sandboxmock.MockEngine)
+		expErr bool
+	}{
+		"TLS without certificate files should use the generated certificate.": {
+			tlsDir: tlsDir,
+			tls:    &model.ForwardTLS{},
+			mock: func(mRepo *storagemock.MockRepository, mEngine *sandboxmock.MockEngine) {
+				mRepo.On("GetSandboxByName", mock.Anything, "test-sandbox").Return(running, nil)
+				exp := []model.PortMapping{{LocalPort: 8443, RemotePort: 8080, TLS: &model.ForwardTLS{
+					CertFile: filepath.Join(tlsDir, "localhost.crt"),
+					KeyFile:  filepath.Join(tlsDir, "localhost.key"),
+				}}}
+				mEngine.On("Forward", mock.Anything, "test-id", exp, sandbox.ForwardOpts{}).Return(nil)
+			},
+		},
+		"TLS with certificate files should use them.": {
+			tls: &model.ForwardTLS{CertFile: "/tmp/my.crt", KeyFile: "/tmp/my.key"},
+			mock: func(mRepo *storagemock.MockRepository, mEngine *sandboxmock.MockEngine) {
+				mRepo.On("GetSandboxByName", mock.Anything, "test-sandbox").Return(running, nil)
+				exp := []model.PortMapping{{LocalPort: 8443, RemotePort: 8080, TLS: &model.ForwardTLS{CertFile: "/tmp/my.crt", KeyFile: "/tmp/my.key"}}}
+				mEngine.On("Forward", mock.Anything, "test-id", exp, sandbox.ForwardOpts{}).Return(nil)
+			},
+		},
+		"TLS with a certificate without key should fail.": {
+			tlsDir: tlsDir,
+			tls:    &model.ForwardTLS{CertFile: "/tmp/my.crt"},
+			mock:   func(mRepo *storagemock.MockRepository, mEngine *sandboxmock.MockEngine) {},
+			expErr: true,
+		},
+		"TLS without certificate files nor TLS directory should fail.": {
+			tls:    &model.ForwardTLS{},
+			mock:   func(mRepo *storagemock.MockRepository, mEngine *sandboxmock.MockEngine) {},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mRepo := &storagemock.MockRepository{}
+			mEngine := &sandboxmock.MockEngine{}
+			test.mock(mRepo, mEngine)
+
+			svc, err := forward.NewService(forward.ServiceConfig{
+				Engine:     mEngine,
+				Repository: mRepo,
+				TLSDir:     test.tlsDir,
+			})
+			require.NoError(err)
+
+			err = svc.Run(context.Background(), forward.Request{
+				NameOrID: "test-sandbox",
+				Ports:    []model.PortMapping{{LocalPort: 8443, RemotePort: 8080, TLS: test.tls}},
+			})
+			if test.expErr {
+				assert.ErrorIs(err, model.ErrNotValid)
+			} else {
+				assert.NoError(err)
+			}
+
+			mRepo.AssertExpectations(t)
+			mEngine.AssertExpectations(t)
+		})
+	}
+}
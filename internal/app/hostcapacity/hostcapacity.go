@@ -0,0 +1,53 @@
+package hostcapacity
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/capacity"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+// ServiceConfig is the configuration for the host capacity service.
+type ServiceConfig struct {
+	Planner *capacity.Planner
+	Logger  log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Planner == nil {
+		return fmt.Errorf("capacity planner is required")
+	}
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	return nil
+}
+
+// Service handles reporting the host capacity.
+type Service struct {
+	planner *capacity.Planner
+	logger  log.Logger
+}
+
+// NewService creates a new host capacity service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &Service{
+		planner: cfg.Planner,
+		logger:  cfg.Logger,
+	}, nil
+}
+
+// Run returns the host capacity and the resources allocated to the sandboxes.
+func (s *Service) Run(ctx context.Context) (*model.HostCapacity, error) {
+	c, err := s.planner.Capacity(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting host capacity: %w", err)
+	}
+
+	return c, nil
+}
@@ -0,0 +1,72 @@
+package hostcapacity_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/hostcapacity"
+	"github.com/slok/sbx/internal/capacity"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestServiceRun(t *testing.T) {
+	host := model.Resources{VCPUs: 8, MemoryMB: 16384, DiskGB: 100}
+
+	tests := map[string]struct {
+		mock        func(m *storagemock.MockRepository)
+		expCapacity *model.HostCapacity
+		expErr      bool
+	}{
+		"Getting the host capacity should return the sandboxes allocation.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("ListAllSandboxes", mock.Anything).Return([]model.Sandbox{
+					{Status: model.SandboxStatusRunning, Config: model.SandboxConfig{Resources: model.Resources{VCPUs: 2, MemoryMB: 2048, DiskGB: 10}}},
+					{Status: model.SandboxStatusStopped, Config: model.SandboxConfig{Resources: model.Resources{VCPUs: 1, MemoryMB: 1024, DiskGB: 5}}},
+				}, nil)
+			},
+			expCapacity: &model.HostCapacity{
+				Total:            host,
+				Allocated:        model.Resources{VCPUs: 2, MemoryMB: 2048, DiskGB: 15},
+				Overcommit:       capacity.DefaultOvercommit,
+				Sandboxes:        2,
+				RunningSandboxes: 1,
+			},
+		},
+
+		"An error from the repository should propagate.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("ListAllSandboxes", mock.Anything).Return(nil, fmt.Errorf("db error"))
+			},
+			expErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			repo := storagemock.NewMockRepository(t)
+			tc.mock(repo)
+
+			planner, err := capacity.NewPlanner(capacity.PlannerConfig{
+				Repository:    repo,
+				HostResources: func(ctx context.Context) (model.Resources, error) { return host, nil },
+			})
+			require.NoError(t, err)
+
+			svc, err := hostcapacity.NewService(hostcapacity.ServiceConfig{Planner: planner})
+			require.NoError(t, err)
+
+			got, err := svc.Run(context.Background())
+			if tc.expErr {
+				assert.Error(t, err)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tc.expCapacity, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package idlecheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the idle check service.
+type ServiceConfig struct {
+	Engine     sandbox.Engine
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Engine == nil {
+		return fmt.Errorf("engine is required")
+	}
+
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.IdleCheck"})
+
+	return nil
+}
+
+// Service checks if the running sandboxes are idle longer than their idle policy timeout.
+type Service struct {
+	engine sandbox.Engine
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new idle check service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		engine: cfg.Engine,
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the idle check request parameters.
+type Request struct {
+	// NameOrID is the sandbox name or ID.
+	NameOrID string
+}
+
+// Run checks the sandbox activity and returns its idle status. The network traffic
+// since the previous check counts as activity, so the checks need to run more often
+// than the idle policy timeout to detect it. Sandboxes that are not running or
+// without idle policy are never idle. The idle sandboxes are not suspended, that's
+// up to the caller.
+func (s *Service) Run(ctx context.Context, req Request) (*model.IdleStatus, error) {
+	sb, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
+	if errors.Is(err, model.ErrNotFound) {
+		sb, err = s.repo.GetSandbox(ctx, req.NameOrID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not find sandbox: %w", err)
+	}
+
+	status := &model.IdleStatus{Policy: sb.IdlePolicy}
+	if sb.Status != model.SandboxStatusRunning || sb.IdlePolicy == nil {
+		return status, nil
+	}
+
+	now := time.Now().UTC()
+	bytes, err := s.engine.NetworkBytes(ctx, sb.ID)
+	switch {
+	case err != nil:
+		// Without traffic counters the commands are the only activity.
+		s.logger.Warningf("could not get sandbox %s network bytes: %v", sb.Name, err)
+	case bytes != sb.Activity.NetworkBytes:
+		if err := s.repo.UpdateSandboxNetworkBytes(ctx, sb.ID, bytes); err != nil {
+			return nil, fmt.Errorf("could not store network bytes: %w", err)
+		}
+		if err := s.repo.UpdateSandboxLastActivity(ctx, sb.ID, now); err != nil {
+			return nil, fmt.Errorf("could not store last activity: %w", err)
+		}
+		sb.Activity = model.SandboxActivity{LastActivityAt: now, NetworkBytes: bytes}
+	}
+
+	status.IdleFor = max(now.Sub(sb.IdleSince()), 0)
+	status.Idle = status.IdleFor >= sb.IdlePolicy.Timeout
+	s.logger.Debugf("sandbox %s idle for %s (timeout %s)", sb.Name, status.IdleFor, sb.IdlePolicy.Timeout)
+
+	return status, nil
+}
@@ -0,0 +1,116 @@
+package idlecheck_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/idlecheck"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox/sandboxmock"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestServiceRun(t *testing.T) {
+	policy := &model.IdlePolicy{Timeout: 30 * time.Minute, Action: model.IdleActionStop}
+	ago := func(d time.Duration) *time.Time {
+		t := time.Now().UTC().Add(-d)
+		return &t
+	}
+
+	tests := map[string]struct {
+		sandbox    *model.Sandbox
+		mock       func(me *sandboxmock.MockEngine, mr *storagemock.MockRepository)
+		expIdle    bool
+		expIdleFor time.Duration
+		expErr     bool
+	}{
+		"A sandbox without idle policy should not be idle.": {
+			sandbox: &model.Sandbox{ID: "01SB", Name: "sb", Status: model.SandboxStatusRunning, StartedAt: ago(time.Hour)},
+			mock:    func(me *sandboxmock.MockEngine, mr *storagemock.MockRepository) {},
+		},
+
+		"A stopped sandbox should not be idle.": {
+			sandbox: &model.Sandbox{ID: "01SB", Name: "sb", Status: model.SandboxStatusStopped, StartedAt: ago(time.Hour), IdlePolicy: policy},
+			mock:    func(me *sandboxmock.MockEngine, mr *storagemock.MockRepository) {},
+		},
+
+		"A sandbox without activity since its start longer than the timeout should be idle.": {
+			sandbox: &model.Sandbox{ID: "01SB", Name: "sb", Status: model.SandboxStatusRunning, StartedAt: ago(time.Hour), IdlePolicy: policy},
+			mock: func(me *sandboxmock.MockEngine, mr *storagemock.MockRepository) {
+				me.On("NetworkBytes", mock.Anything, "01SB").Once().Return(uint64(0), nil)
+			},
+			expIdle:    true,
+			expIdleFor: time.Hour,
+		},
+
+		"A sandbox with a recent command should not be idle.": {
+			sandbox: &model.Sandbox{ID: "01SB", Name: "sb", Status: model.SandboxStatusRunning, StartedAt: ago(time.Hour), IdlePolicy: policy,
+				Activity: model.SandboxActivity{LastActivityAt: *ago(10 * time.Minute), NetworkBytes: 42}},
+			mock: func(me *sandboxmock.MockEngine, mr *storagemock.MockRepository) {
+				me.On("NetworkBytes", mock.Anything, "01SB").Once().Return(uint64(42), nil)
+			},
+			expIdleFor: 10 * time.Minute,
+		},
+
+		"A sandbox with network traffic since the last check should not be idle.": {
+			sandbox: &model.Sandbox{ID: "01SB", Name: "sb", Status: model.SandboxStatusRunning, StartedAt: ago(time.Hour), IdlePolicy: policy,
+				Activity: model.SandboxActivity{NetworkBytes: 42}},
+			mock: func(me *sandboxmock.MockEngine, mr *storagemock.MockRepository) {
+				me.On("NetworkBytes", mock.Anything, "01SB").Once().Return(uint64(1042), nil)
+				mr.On("UpdateSandboxNetworkBytes", mock.Anything, "01SB", uint64(1042)).Once().Return(nil)
+				mr.On("UpdateSandboxLastActivity", mock.Anything, "01SB", mock.Anything).Once().Return(nil)
+			},
+		},
+
+		"Failing to get the network bytes should only use the commands activity.": {
+			sandbox: &model.Sandbox{ID: "01SB", Name: "sb", Status: model.SandboxStatusRunning, StartedAt: ago(time.Hour), IdlePolicy: policy},
+			mock: func(me *sandboxmock.MockEngine, mr *storagemock.MockRepository) {
+				me.On("NetworkBytes", mock.Anything, "01SB").Once().Return(uint64(0), fmt.Errorf("something"))
+			},
+			expIdle:    true,
+			expIdleFor: time.Hour,
+		},
+
+		"Failing to store the activity should fail.": {
+			sandbox: &model.Sandbox{ID: "01SB", Name: "sb", Status: model.SandboxStatusRunning, StartedAt: ago(time.Hour), IdlePolicy: policy},
+			mock: func(me *sandboxmock.MockEngine, mr *storagemock.MockRepository) {
+				me.On("NetworkBytes", mock.Anything, "01SB").Once().Return(uint64(1042), nil)
+				mr.On("UpdateSandboxNetworkBytes", mock.Anything, "01SB", uint64(1042)).Once().Return(fmt.Errorf("something"))
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mEngine := &sandboxmock.MockEngine{}
+			mRepo := &storagemock.MockRepository{}
+			mRepo.On("GetSandboxByName", mock.Anything, "sb").Once().Return(test.sandbox, nil)
+			test.mock(mEngine, mRepo)
+
+			svc, err := idlecheck.NewService(idlecheck.ServiceConfig{Engine: mEngine, Repository: mRepo})
+			require.NoError(err)
+
+			got, err := svc.Run(context.Background(), idlecheck.Request{NameOrID: "sb"})
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expIdle, got.Idle)
+				assert.InDelta(test.expIdleFor, got.IdleFor, float64(time.Minute))
+				assert.Equal(test.sandbox.IdlePolicy, got.Policy)
+			}
+
+			mEngine.AssertExpectations(t)
+			mRepo.AssertExpectations(t)
+		})
+	}
+}
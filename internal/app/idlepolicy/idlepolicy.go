@@ -0,0 +1,93 @@
+package idlepolicy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the idle policy service.
+type ServiceConfig struct {
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.IdlePolicy"})
+
+	return nil
+}
+
+// Service sets and removes the idle policies of sandboxes.
+type Service struct {
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new idle policy service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the idle policy request parameters.
+type Request struct {
+	// NameOrID is the sandbox name or ID.
+	NameOrID string
+	// Policy is the idle policy of the sandbox, nil removes it. The action defaults
+	// to stop.
+	Policy *model.IdlePolicy
+}
+
+// Run sets the idle policy of a sandbox, in any status, and returns the sandbox with
+// its new policy.
+func (s *Service) Run(ctx context.Context, req Request) (*model.Sandbox, error) {
+	var policy *model.IdlePolicy
+	if req.Policy != nil {
+		p := *req.Policy
+		if p.Action == "" {
+			p.Action = model.IdleActionStop
+		}
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid idle policy: %w", err)
+		}
+		policy = &p
+	}
+
+	sandbox, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
+	if errors.Is(err, model.ErrNotFound) {
+		sandbox, err = s.repo.GetSandbox(ctx, req.NameOrID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not find sandbox: %w", err)
+	}
+
+	if err := s.repo.UpdateSandboxIdlePolicy(ctx, sandbox.ID, policy); err != nil {
+		return nil, fmt.Errorf("could not store idle policy: %w", err)
+	}
+	sandbox.IdlePolicy = policy
+
+	if policy != nil {
+		s.logger.Infof("set sandbox %s idle policy: %s after %s", sandbox.Name, policy.Action, policy.Timeout)
+	} else {
+		s.logger.Infof("removed sandbox %s idle policy", sandbox.Name)
+	}
+	return sandbox, nil
+}
@@ -0,0 +1,83 @@
+package idlepolicy_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/idlepolicy"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestServiceRun(t *testing.T) {
+	sandbox := func() *model.Sandbox {
+		return &model.Sandbox{ID: "01SB", Name: "sb", Status: model.SandboxStatusRunning}
+	}
+
+	tests := map[string]struct {
+		mock      func(m *storagemock.MockRepository)
+		req       idlepolicy.Request
+		expPolicy *model.IdlePolicy
+		expErr    error
+	}{
+		"Setting a policy without action should stop the idle sandbox.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "sb").Once().Return(sandbox(), nil)
+				m.On("UpdateSandboxIdlePolicy", mock.Anything, "01SB", &model.IdlePolicy{Timeout: time.Hour, Action: model.IdleActionStop}).Once().Return(nil)
+			},
+			req:       idlepolicy.Request{NameOrID: "sb", Policy: &model.IdlePolicy{Timeout: time.Hour}},
+			expPolicy: &model.IdlePolicy{Timeout: time.Hour, Action: model.IdleActionStop},
+		},
+
+		"Removing the policy should store no policy.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "01SB").Once().Return(nil, model.ErrNotFound)
+				m.On("GetSandbox", mock.Anything, "01SB").Once().Return(sandbox(), nil)
+				m.On("UpdateSandboxIdlePolicy", mock.Anything, "01SB", (*model.IdlePolicy)(nil)).Once().Return(nil)
+			},
+			req: idlepolicy.Request{NameOrID: "01SB"},
+		},
+
+		"An invalid policy should fail.": {
+			mock:   func(m *storagemock.MockRepository) {},
+			req:    idlepolicy.Request{NameOrID: "sb", Policy: &model.IdlePolicy{Timeout: time.Hour, Action: "pause"}},
+			expErr: model.ErrNotValid,
+		},
+
+		"A missing sandbox should fail.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "sb").Once().Return(nil, model.ErrNotFound)
+				m.On("GetSandbox", mock.Anything, "sb").Once().Return(nil, model.ErrNotFound)
+			},
+			req:    idlepolicy.Request{NameOrID: "sb", Policy: &model.IdlePolicy{Timeout: time.Hour}},
+			expErr: model.ErrNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mRepo := &storagemock.MockRepository{}
+			test.mock(mRepo)
+
+			svc, err := idlepolicy.NewService(idlepolicy.ServiceConfig{Repository: mRepo})
+			require.NoError(err)
+
+			got, err := svc.Run(context.Background(), test.req)
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expPolicy, got.IdlePolicy)
+			}
+
+			mRepo.AssertExpectations(t)
+		})
+	}
+}
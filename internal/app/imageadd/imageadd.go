@@ -0,0 +1,76 @@
+package imageadd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+// ServiceConfig is the configuration for the image add service.
+type ServiceConfig struct {
+	Adder   image.ImageAdder
+	Manager image.ImageManager
+	Logger  log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Adder == nil {
+		return fmt.Errorf("image adder is required")
+	}
+	if c.Manager == nil {
+		return fmt.Errorf("image manager is required")
+	}
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	return nil
+}
+
+// Service handles registering user built artifacts as local images.
+type Service struct {
+	adder   image.ImageAdder
+	manager image.ImageManager
+	logger  log.Logger
+}
+
+// NewService creates a new image add service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &Service{
+		adder:   cfg.Adder,
+		manager: cfg.Manager,
+		logger:  cfg.Logger,
+	}, nil
+}
+
+// Request is the add request parameters.
+type Request struct {
+	// Options are the image name, artifacts and manifest metadata.
+	Options image.AddImageOptions
+}
+
+// Run installs the image and returns its generated manifest.
+func (s *Service) Run(ctx context.Context, req Request) (*model.ImageManifest, error) {
+	if req.Options.Name == "" {
+		return nil, fmt.Errorf("image name is required: %w", model.ErrNotValid)
+	}
+	if req.Options.KernelSrc == "" || req.Options.RootFSSrc == "" {
+		return nil, fmt.Errorf("kernel and rootfs are required: %w", model.ErrNotValid)
+	}
+
+	if err := s.adder.Add(ctx, req.Options); err != nil {
+		return nil, fmt.Errorf("adding image: %w", err)
+	}
+
+	manifest, err := s.manager.GetManifest(ctx, req.Options.Name)
+	if err != nil {
+		return nil, fmt.Errorf("reading added image manifest: %w", err)
+	}
+
+	return manifest, nil
+}
@@ -0,0 +1,74 @@
+package imageadd_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/imageadd"
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/image/imagemock"
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestServiceRun(t *testing.T) {
+	opts := image.AddImageOptions{Name: "my-image", KernelSrc: "/tmp/vmlinux", RootFSSrc: "/tmp/rootfs.ext4"}
+
+	tests := map[string]struct {
+		mock        func(a *imagemock.MockImageAdder, m *imagemock.MockImageManager)
+		req         imageadd.Request
+		expManifest *model.ImageManifest
+		expErr      error
+	}{
+		"Adding an image should return its manifest.": {
+			mock: func(a *imagemock.MockImageAdder, m *imagemock.MockImageManager) {
+				a.On("Add", mock.Anything, opts).Once().Return(nil)
+				m.On("GetManifest", mock.Anything, "my-image").Once().Return(&model.ImageManifest{Version: "my-image"}, nil)
+			},
+			req:         imageadd.Request{Options: opts},
+			expManifest: &model.ImageManifest{Version: "my-image"},
+		},
+
+		"Adding an already installed image should fail.": {
+			mock: func(a *imagemock.MockImageAdder, m *imagemock.MockImageManager) {
+				a.On("Add", mock.Anything, opts).Once().Return(model.ErrAlreadyExists)
+			},
+			req:    imageadd.Request{Options: opts},
+			expErr: model.ErrAlreadyExists,
+		},
+
+		"Adding an image without rootfs should fail.": {
+			mock:   func(a *imagemock.MockImageAdder, m *imagemock.MockImageManager) {},
+			req:    imageadd.Request{Options: image.AddImageOptions{Name: "my-image", KernelSrc: "/tmp/vmlinux"}},
+			expErr: model.ErrNotValid,
+		},
+
+		"Adding an image without name should fail.": {
+			mock:   func(a *imagemock.MockImageAdder, m *imagemock.MockImageManager) {},
+			req:    imageadd.Request{Options: image.AddImageOptions{KernelSrc: "/tmp/vmlinux", RootFSSrc: "/tmp/rootfs.ext4"}},
+			expErr: model.ErrNotValid,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			a := imagemock.NewMockImageAdder(t)
+			m := imagemock.NewMockImageManager(t)
+			tc.mock(a, m)
+
+			svc, err := imageadd.NewService(imageadd.ServiceConfig{Adder: a, Manager: m})
+			require.NoError(t, err)
+
+			got, err := svc.Run(context.Background(), tc.req)
+			if tc.expErr != nil {
+				assert.ErrorIs(t, err, tc.expErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expManifest, got)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package imagedu
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+// ServiceConfig is the configuration for the image disk usage service.
+type ServiceConfig struct {
+	Manager image.ImageManager
+	Logger  log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Manager == nil {
+		return fmt.Errorf("image manager is required")
+	}
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	return nil
+}
+
+// Service handles reporting the images disk usage.
+type Service struct {
+	manager image.ImageManager
+	logger  log.Logger
+}
+
+// NewService creates a new image disk usage service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &Service{
+		manager: cfg.Manager,
+		logger:  cfg.Logger,
+	}, nil
+}
+
+// Run returns the disk usage of the installed images, accounting the artifacts
+// shared between images only once.
+func (s *Service) Run(ctx context.Context) (*model.ImageDiskUsage, error) {
+	usage, err := s.manager.DiskUsage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting images disk usage: %w", err)
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,69 @@
+package imagedu_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/imagedu"
+	"github.com/slok/sbx/internal/image/imagemock"
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestServiceRun(t *testing.T) {
+	tests := map[string]struct {
+		mock     func(m *imagemock.MockImageManager)
+		expUsage *model.ImageDiskUsage
+		expErr   bool
+	}{
+		"Getting the disk usage should return the manager usage.": {
+			mock: func(m *imagemock.MockImageManager) {
+				m.On("DiskUsage", mock.Anything).Return(&model.ImageDiskUsage{
+					Images: []model.ImageUsage{
+						{Name: "v0.1.0", SizeBytes: 300, SharedBytes: 100},
+						{Name: "v0.2.0", SizeBytes: 400, SharedBytes: 100},
+					},
+					TotalBytes: 700,
+					DiskBytes:  600,
+				}, nil)
+			},
+			expUsage: &model.ImageDiskUsage{
+				Images: []model.ImageUsage{
+					{Name: "v0.1.0", SizeBytes: 300, SharedBytes: 100},
+					{Name: "v0.2.0", SizeBytes: 400, SharedBytes: 100},
+				},
+				TotalBytes: 700,
+				DiskBytes:  600,
+			},
+		},
+
+		"An error from the image manager should propagate.": {
+			mock: func(m *imagemock.MockImageManager) {
+				m.On("DiskUsage", mock.Anything).Return(nil, fmt.Errorf("disk error"))
+			},
+			expErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mgr := imagemock.NewMockImageManager(t)
+			tc.mock(mgr)
+
+			svc, err := imagedu.NewService(imagedu.ServiceConfig{Manager: mgr})
+			require.NoError(t, err)
+
+			got, err := svc.Run(context.Background())
+			if tc.expErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expUsage, got)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package imageexport
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+// ServiceConfig is the configuration for the image export service.
+type ServiceConfig struct {
+	Bundler image.ImageBundler
+	Logger  log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Bundler == nil {
+		return fmt.Errorf("image bundler is required")
+	}
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	return nil
+}
+
+// Service handles exporting installed images as bundles.
+type Service struct {
+	bundler image.ImageBundler
+	logger  log.Logger
+}
+
+// NewService creates a new image export service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &Service{
+		bundler: cfg.Bundler,
+		logger:  cfg.Logger,
+	}, nil
+}
+
+// Request is the export request parameters.
+type Request struct {
+	// Version is the installed image to export (release or snapshot).
+	Version string
+	// Output receives the bundle.
+	Output io.Writer
+}
+
+// Run writes the bundle of an installed image to the request output.
+func (s *Service) Run(ctx context.Context, req Request) error {
+	if req.Version == "" {
+		return fmt.Errorf("image version is required: %w", model.ErrNotValid)
+	}
+	if req.Output == nil {
+		return fmt.Errorf("output is required: %w", model.ErrNotValid)
+	}
+
+	if err := s.bundler.Export(ctx, req.Version, req.Output); err != nil {
+		return fmt.Errorf("exporting image %s: %w", req.Version, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package imageexport_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/imageexport"
+	"github.com/slok/sbx/internal/image/imagemock"
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestServiceRun(t *testing.T) {
+	tests := map[string]struct {
+		mock   func(m *imagemock.MockImageBundler)
+		req    func(out *bytes.Buffer) imageexport.Request
+		expErr error
+	}{
+		"Exporting an image should write the bundle to the output.": {
+			mock: func(m *imagemock.MockImageBundler) {
+				m.On("Export", mock.Anything, "v0.1.0", mock.Anything).Once().Return(nil)
+			},
+			req: func(out *bytes.Buffer) imageexport.Request {
+				return imageexport.Request{Version: "v0.1.0", Output: out}
+			},
+		},
+
+		"Exporting a missing image should fail.": {
+			mock: func(m *imagemock.MockImageBundler) {
+				m.On("Export", mock.Anything, "v0.1.0", mock.Anything).Once().Return(model.ErrNotFound)
+			},
+			req: func(out *bytes.Buffer) imageexport.Request {
+				return imageexport.Request{Version: "v0.1.0", Output: out}
+			},
+			expErr: model.ErrNotFound,
+		},
+
+		"Exporting without version should fail.": {
+			mock: func(m *imagemock.MockImageBundler) {},
+			req: func(out *bytes.Buffer) imageexport.Request {
+				return imageexport.Request{Output: out}
+			},
+			expErr: model.ErrNotValid,
+		},
+
+		"Exporting without output should fail.": {
+			mock: func(m *imagemock.MockImageBundler) {},
+			req: func(out *bytes.Buffer) imageexport.Request {
+				return imageexport.Request{Version: "v0.1.0"}
+			},
+			expErr: model.ErrNotValid,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			b := imagemock.NewMockImageBundler(t)
+			tc.mock(b)
+
+			svc, err := imageexport.NewService(imageexport.ServiceConfig{Bundler: b})
+			require.NoError(t, err)
+
+			var out bytes.Buffer
+			err = svc.Run(context.Background(), tc.req(&out))
+			if tc.expErr != nil {
+				assert.ErrorIs(t, err, tc.expErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package imagefromoci
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+// ServiceConfig is the configuration for the image from OCI service.
+type ServiceConfig struct {
+	Builder image.RootFSBuilder
+	Adder   image.ImageAdder
+	Manager image.ImageManager
+	// TmpDir is the directory for the built rootfs until it's installed (default: os temp dir).
+	TmpDir string
+	Logger log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Builder == nil {
+		return fmt.Errorf("rootfs builder is required")
+	}
+	if c.Adder == nil {
+		return fmt.Errorf("image adder is required")
+	}
+	if c.Manager == nil {
+		return fmt.Errorf("image manager is required")
+	}
+	if c.TmpDir == "" {
+		c.TmpDir = os.TempDir()
+	}
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.ImageFromOCI"})
+	return nil
+}
+
+// Service converts OCI/Docker images into installed sbx images, reusing the
+// kernel and guest init of an installed base image.
+type Service struct {
+	builder image.RootFSBuilder
+	adder   image.ImageAdder
+	manager image.ImageManager
+	tmpDir  string
+	logger  log.Logger
+}
+
+// NewService creates a new image from OCI service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &Service{
+		builder: cfg.Builder,
+		adder:   cfg.Adder,
+		manager: cfg.Manager,
+		tmpDir:  cfg.TmpDir,
+		logger:  cfg.Logger,
+	}, nil
+}
+
+// Request is the image from OCI request parameters.
+type Request struct {
+	// Name is the installed image name.
+	Name string
+	// Source is the OCI image: an OCI layout or docker save archive path, or a
+	// reference exported with the docker or podman CLI.
+	Source string
+	// BaseImage is the installed image the kernel, guest init and firecracker
+	// binary are taken from (default: the most recently installed release).
+	BaseImage string
+	// KernelPath overrides the base image kernel (optional).
+	KernelPath string
+	// InitPath overrides the base image guest init binary (optional).
+	InitPath string
+	// SizeMB is the rootfs size (default: the image content with some room).
+	SizeMB int
+	// Force replaces the image if already installed.
+	Force bool
+}
+
+// Run builds the rootfs of the OCI image and installs it as an image, returning
+// its manifest.
+func (s *Service) Run(ctx context.Context, req Request) (*model.ImageManifest, error) {
+	if err := model.ValidateImageName(req.Name); err != nil {
+		return nil, fmt.Errorf("invalid image name: %w", err)
+	}
+	if req.Source == "" {
+		return nil, fmt.Errorf("OCI image is required: %w", model.ErrNotValid)
+	}
+	if req.SizeMB < 0 {
+		return nil, fmt.Errorf("rootfs size can't be negative: %w", model.ErrNotValid)
+	}
+
+	// Fail before the long build.
+	if !req.Force {
+		exists, err := s.manager.Exists(ctx, req.Name)
+		if err != nil {
+			return nil, fmt.Errorf("checking image: %w", err)
+		}
+		if exists {
+			return nil, fmt.Errorf("image %q already exists: %w", req.Name, model.ErrAlreadyExists)
+		}
+	}
+
+	addOpts := image.AddImageOptions{Name: req.Name, KernelSrc: req.KernelPath, Force: req.Force}
+	buildOpts := image.OCIBuildOptions{Source: req.Source, InitSrc: req.InitPath, SizeMB: req.SizeMB}
+	if req.KernelPath == "" || req.InitPath == "" {
+		base, err := s.baseImage(ctx, req.BaseImage)
+		if err != nil {
+			return nil, err
+		}
+		s.logger.Infof("Using base image %s", base)
+
+		if addOpts.KernelSrc == "" {
+			addOpts.KernelSrc = s.manager.KernelPath(base)
+			if manifest, err := s.manager.GetManifest(ctx, base); err == nil {
+				if a, err := manifest.ArtifactsFor(image.HostArch()); err == nil {
+					addOpts.KernelVersion = a.Kernel.Version
+				}
+			}
+		}
+		if buildOpts.InitSrc == "" {
+			buildOpts.InitFromRootFS = s.manager.RootFSPath(base)
+		}
+		if fc := s.manager.FirecrackerPath(base); fileExists(fc) {
+			addOpts.FirecrackerSrc = fc
+		}
+	}
+
+	if err := os.MkdirAll(s.tmpDir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating work directory: %w", err)
+	}
+	workDir, err := os.MkdirTemp(s.tmpDir, "sbx-oci-rootfs-")
+	if err != nil {
+		return nil, fmt.Errorf("creating work directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+	buildOpts.OutputPath = filepath.Join(workDir, "rootfs.ext4")
+
+	res, err := s.builder.BuildFromOCI(ctx, buildOpts)
+	if err != nil {
+		return nil, fmt.Errorf("building rootfs from %s: %w", req.Source, err)
+	}
+
+	addOpts.RootFSSrc = buildOpts.OutputPath
+	addOpts.Distro = res.Distro
+	addOpts.DistroVersion = res.DistroVersion
+	addOpts.Profile = "oci"
+	if err := s.adder.Add(ctx, addOpts); err != nil {
+		return nil, fmt.Errorf("adding image: %w", err)
+	}
+
+	manifest, err := s.manager.GetManifest(ctx, req.Name)
+	if err != nil {
+		return nil, fmt.Errorf("reading added image manifest: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// baseImage returns the requested base image, or the most recently installed
+// release when not set.
+func (s *Service) baseImage(ctx context.Context, name string) (string, error) {
+	if name != "" {
+		exists, err := s.manager.Exists(ctx, name)
+		if err != nil {
+			return "", fmt.Errorf("checking base image: %w", err)
+		}
+		if !exists {
+			return "", fmt.Errorf("base image %s is not installed: %w", name, model.ErrNotFound)
+		}
+		return name, nil
+	}
+
+	images, err := s.manager.List(ctx)
+	if err != nil {
+		return "", fmt.Errorf("listing images: %w", err)
+	}
+	var latest *model.ImageRelease
+	for i, img := range images {
+		if img.Source != model.ImageSourceRelease {
+			continue
+		}
+		if latest == nil || img.InstalledAt.After(latest.InstalledAt) {
+			latest = &images[i]
+		}
+	}
+	if latest == nil {
+		return "", fmt.Errorf("no release image installed to take the kernel and guest init from, pull one or set the kernel and init paths: %w", model.ErrNotValid)
+	}
+
+	return latest.Version, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
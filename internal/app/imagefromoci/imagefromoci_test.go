@@ -0,0 +1,126 @@
+package imagefromoci_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/imagefromoci"
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/image/imagemock"
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestServiceRun(t *testing.T) {
+	now := time.Now()
+
+	tests := map[string]struct {
+		mock   func(b *imagemock.MockRootFSBuilder, a *imagemock.MockImageAdder, m *imagemock.MockImageManager)
+		req    imagefromoci.Request
+		expErr error
+	}{
+		"Converting an image should use the latest installed release as base.": {
+			mock: func(b *imagemock.MockRootFSBuilder, a *imagemock.MockImageAdder, m *imagemock.MockImageManager) {
+				m.On("Exists", mock.Anything, "my-app").Once().Return(false, nil)
+				m.On("List", mock.Anything).Once().Return([]model.ImageRelease{
+					{Version: "v0.1.0", Source: model.ImageSourceRelease, InstalledAt: now.Add(-time.Hour)},
+					{Version: "v0.2.0", Source: model.ImageSourceRelease, InstalledAt: now},
+					{Version: "snap", Source: model.ImageSourceSnapshot, InstalledAt: now.Add(time.Hour)},
+				}, nil)
+				m.On("KernelPath", "v0.2.0").Once().Return("/images/v0.2.0/vmlinux")
+				m.On("RootFSPath", "v0.2.0").Once().Return("/images/v0.2.0/rootfs.ext4")
+				m.On("FirecrackerPath", "v0.2.0").Once().Return("/images/v0.2.0/firecracker")
+				m.On("GetManifest", mock.Anything, "v0.2.0").Once().Return(&model.ImageManifest{Artifacts: map[string]model.ArchArtifacts{
+					image.HostArch(): {Kernel: model.KernelInfo{Version: "6.1.155"}},
+				}}, nil)
+				b.On("BuildFromOCI", mock.Anything, mock.MatchedBy(func(o image.OCIBuildOptions) bool {
+					return o.Source == "debian:12" && o.InitFromRootFS == "/images/v0.2.0/rootfs.ext4" && o.OutputPath != ""
+				})).Once().Return(&image.OCIBuildResult{Distro: "debian", DistroVersion: "12"}, nil)
+				a.On("Add", mock.Anything, mock.MatchedBy(func(o image.AddImageOptions) bool {
+					return o.Name == "my-app" && o.KernelSrc == "/images/v0.2.0/vmlinux" && o.KernelVersion == "6.1.155" &&
+						o.Distro == "debian" && o.DistroVersion == "12" && o.RootFSSrc != ""
+				})).Once().Return(nil)
+				m.On("GetManifest", mock.Anything, "my-app").Once().Return(&model.ImageManifest{Version: "my-app"}, nil)
+			},
+			req: imagefromoci.Request{Name: "my-app", Source: "debian:12"},
+		},
+
+		"Converting an image with the kernel and init set should not need a base image.": {
+			mock: func(b *imagemock.MockRootFSBuilder, a *imagemock.MockImageAdder, m *imagemock.MockImageManager) {
+				m.On("Exists", mock.Anything, "my-app").Once().Return(false, nil)
+				b.On("BuildFromOCI", mock.Anything, mock.MatchedBy(func(o image.OCIBuildOptions) bool {
+					return o.InitSrc == "/tmp/sbx-init" && o.InitFromRootFS == ""
+				})).Once().Return(&image.OCIBuildResult{}, nil)
+				a.On("Add", mock.Anything, mock.MatchedBy(func(o image.AddImageOptions) bool {
+					return o.KernelSrc == "/tmp/vmlinux"
+				})).Once().Return(nil)
+				m.On("GetManifest", mock.Anything, "my-app").Once().Return(&model.ImageManifest{Version: "my-app"}, nil)
+			},
+			req: imagefromoci.Request{Name: "my-app", Source: "app.tar", KernelPath: "/tmp/vmlinux", InitPath: "/tmp/sbx-init"},
+		},
+
+		"Converting an image without installed releases should fail.": {
+			mock: func(b *imagemock.MockRootFSBuilder, a *imagemock.MockImageAdder, m *imagemock.MockImageManager) {
+				m.On("Exists", mock.Anything, "my-app").Once().Return(false, nil)
+				m.On("List", mock.Anything).Once().Return(nil, nil)
+			},
+			req:    imagefromoci.Request{Name: "my-app", Source: "debian:12"},
+			expErr: model.ErrNotValid,
+		},
+
+		"Converting an image with a missing base image should fail.": {
+			mock: func(b *imagemock.MockRootFSBuilder, a *imagemock.MockImageAdder, m *imagemock.MockImageManager) {
+				m.On("Exists", mock.Anything, "my-app").Once().Return(false, nil)
+				m.On("Exists", mock.Anything, "v9").Once().Return(false, nil)
+			},
+			req:    imagefromoci.Request{Name: "my-app", Source: "debian:12", BaseImage: "v9"},
+			expErr: model.ErrNotFound,
+		},
+
+		"Converting to an installed image should fail before building.": {
+			mock: func(b *imagemock.MockRootFSBuilder, a *imagemock.MockImageAdder, m *imagemock.MockImageManager) {
+				m.On("Exists", mock.Anything, "my-app").Once().Return(true, nil)
+			},
+			req:    imagefromoci.Request{Name: "my-app", Source: "debian:12"},
+			expErr: model.ErrAlreadyExists,
+		},
+
+		"A failed build should fail.": {
+			mock: func(b *imagemock.MockRootFSBuilder, a *imagemock.MockImageAdder, m *imagemock.MockImageManager) {
+				b.On("BuildFromOCI", mock.Anything, mock.Anything).Once().Return(nil, fmt.Errorf("no sshd: %w", model.ErrNotValid))
+			},
+			req:    imagefromoci.Request{Name: "my-app", Source: "app.tar", KernelPath: "/tmp/vmlinux", InitPath: "/tmp/sbx-init", Force: true},
+			expErr: model.ErrNotValid,
+		},
+
+		"An invalid name should fail.": {
+			mock:   func(b *imagemock.MockRootFSBuilder, a *imagemock.MockImageAdder, m *imagemock.MockImageManager) {},
+			req:    imagefromoci.Request{Name: "../x", Source: "debian:12"},
+			expErr: model.ErrNotValid,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			b := imagemock.NewMockRootFSBuilder(t)
+			a := imagemock.NewMockImageAdder(t)
+			m := imagemock.NewMockImageManager(t)
+			tc.mock(b, a, m)
+
+			svc, err := imagefromoci.NewService(imagefromoci.ServiceConfig{Builder: b, Adder: a, Manager: m, TmpDir: t.TempDir()})
+			require.NoError(t, err)
+
+			_, err = svc.Run(context.Background(), tc.req)
+			if tc.expErr != nil {
+				assert.ErrorIs(t, err, tc.expErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package imageimport
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+// ServiceConfig is the configuration for the image import service.
+type ServiceConfig struct {
+	Bundler image.ImageBundler
+	Logger  log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Bundler == nil {
+		return fmt.Errorf("image bundler is required")
+	}
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	return nil
+}
+
+// Service handles importing image bundles.
+type Service struct {
+	bundler image.ImageBundler
+	logger  log.Logger
+}
+
+// NewService creates a new image import service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &Service{
+		bundler: cfg.Bundler,
+		logger:  cfg.Logger,
+	}, nil
+}
+
+// Request is the import request parameters.
+type Request struct {
+	// Input is the bundle (plain or gzip compressed tar).
+	Input io.Reader
+	// Force replaces the image if already installed.
+	Force bool
+}
+
+// Run installs the image of a bundle and returns the imported image name.
+func (s *Service) Run(ctx context.Context, req Request) (string, error) {
+	if req.Input == nil {
+		return "", fmt.Errorf("input is required: %w", model.ErrNotValid)
+	}
+
+	name, err := s.bundler.Import(ctx, req.Input, image.ImportOptions{Force: req.Force})
+	if err != nil {
+		return "", fmt.Errorf("importing image: %w", err)
+	}
+
+	return name, nil
+}
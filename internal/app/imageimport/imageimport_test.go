@@ -0,0 +1,73 @@
+package imageimport_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/imageimport"
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/image/imagemock"
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestServiceRun(t *testing.T) {
+	tests := map[string]struct {
+		mock    func(m *imagemock.MockImageBundler)
+		req     imageimport.Request
+		expName string
+		expErr  error
+	}{
+		"Importing a bundle should return the imported image.": {
+			mock: func(m *imagemock.MockImageBundler) {
+				m.On("Import", mock.Anything, mock.Anything, image.ImportOptions{}).Once().Return("v0.1.0", nil)
+			},
+			req:     imageimport.Request{Input: strings.NewReader("bundle")},
+			expName: "v0.1.0",
+		},
+
+		"Importing a bundle with force should replace the image.": {
+			mock: func(m *imagemock.MockImageBundler) {
+				m.On("Import", mock.Anything, mock.Anything, image.ImportOptions{Force: true}).Once().Return("v0.1.0", nil)
+			},
+			req:     imageimport.Request{Input: strings.NewReader("bundle"), Force: true},
+			expName: "v0.1.0",
+		},
+
+		"Importing an already installed image should fail.": {
+			mock: func(m *imagemock.MockImageBundler) {
+				m.On("Import", mock.Anything, mock.Anything, image.ImportOptions{}).Once().Return("", model.ErrAlreadyExists)
+			},
+			req:    imageimport.Request{Input: strings.NewReader("bundle")},
+			expErr: model.ErrAlreadyExists,
+		},
+
+		"Importing without input should fail.": {
+			mock:   func(m *imagemock.MockImageBundler) {},
+			req:    imageimport.Request{},
+			expErr: model.ErrNotValid,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			b := imagemock.NewMockImageBundler(t)
+			tc.mock(b)
+
+			svc, err := imageimport.NewService(imageimport.ServiceConfig{Bundler: b})
+			require.NoError(t, err)
+
+			got, err := svc.Run(context.Background(), tc.req)
+			if tc.expErr != nil {
+				assert.ErrorIs(t, err, tc.expErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expName, got)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package imageprune
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the image prune service.
+type ServiceConfig struct {
+	Manager    image.ImageManager
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Manager == nil {
+		return fmt.Errorf("image manager is required")
+	}
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.ImagePrune"})
+	return nil
+}
+
+// Service removes installed images that no sandbox depends on.
+type Service struct {
+	manager image.ImageManager
+	repo    storage.Repository
+	logger  log.Logger
+}
+
+// NewService creates a new image prune service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &Service{
+		manager: cfg.Manager,
+		repo:    cfg.Repository,
+		logger:  cfg.Logger,
+	}, nil
+}
+
+// Request is the prune request parameters.
+type Request struct {
+	// OlderThan only prunes images installed before this duration ago (0 prunes all unused images).
+	OlderThan time.Duration
+	// DryRun returns the images that would be pruned without removing them.
+	DryRun bool
+}
+
+// Run removes the installed images with zero sandbox references that are older than
+// the requested threshold. It returns the pruned image names.
+func (s *Service) Run(ctx context.Context, req Request) ([]string, error) {
+	if req.OlderThan < 0 {
+		return nil, fmt.Errorf("older than duration can't be negative")
+	}
+
+	images, err := s.manager.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not list images: %w", err)
+	}
+
+	sandboxes, err := s.repo.ListAllSandboxes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not list sandboxes: %w", err)
+	}
+
+	threshold := time.Now().UTC().Add(-req.OlderThan)
+	pruned := []string{}
+	for _, img := range images {
+		if img.InstalledAt.After(threshold) {
+			continue
+		}
+
+		if users := image.SandboxesUsingImage(s.manager, img.Version, sandboxes); len(users) > 0 {
+			s.logger.Debugf("Image %s is used by %d sandboxes, skipping", img.Version, len(users))
+			continue
+		}
+
+		if !req.DryRun {
+			if err := s.manager.Remove(ctx, img.Version); err != nil {
+				return pruned, fmt.Errorf("removing image %s: %w", img.Version, err)
+			}
+			s.logger.Infof("Pruned image %s", img.Version)
+		}
+		pruned = append(pruned, img.Version)
+	}
+
+	return pruned, nil
+}
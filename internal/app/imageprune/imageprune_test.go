@@ -0,0 +1,96 @@
+package imageprune_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/imageprune"
+	"github.com/slok/sbx/internal/image/imagemock"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestServiceRun(t *testing.T) {
+	old := time.Now().UTC().Add(-48 * time.Hour)
+	recent := time.Now().UTC().Add(-1 * time.Hour)
+
+	images := []model.ImageRelease{
+		{Version: "v0.1.0", Installed: true, Source: model.ImageSourceRelease, InstalledAt: old},
+		{Version: "v0.2.0", Installed: true, Source: model.ImageSourceRelease, InstalledAt: recent},
+		{Version: "my-snap", Installed: true, Source: model.ImageSourceSnapshot, InstalledAt: old},
+	}
+
+	tests := map[string]struct {
+		req       imageprune.Request
+		sandboxes []model.Sandbox
+		mock      func(mgr *imagemock.MockImageManager)
+		expPruned []string
+		expErr    bool
+	}{
+		"Pruning without threshold should remove all unused images.": {
+			req: imageprune.Request{},
+			sandboxes: []model.Sandbox{
+				{Name: "sb1", Config: model.SandboxConfig{Image: "v0.2.0"}},
+			},
+			mock: func(mgr *imagemock.MockImageManager) {
+				mgr.On("Remove", mock.Anything, "v0.1.0").Once().Return(nil)
+				mgr.On("Remove", mock.Anything, "my-snap").Once().Return(nil)
+			},
+			expPruned: []string{"v0.1.0", "my-snap"},
+		},
+
+		"Pruning with a threshold should only remove old unused images.": {
+			req: imageprune.Request{OlderThan: 24 * time.Hour},
+			sandboxes: []model.Sandbox{
+				{Name: "sb1", Config: model.SandboxConfig{Image: "my-snap"}},
+			},
+			mock: func(mgr *imagemock.MockImageManager) {
+				mgr.On("Remove", mock.Anything, "v0.1.0").Once().Return(nil)
+			},
+			expPruned: []string{"v0.1.0"},
+		},
+
+		"Pruning in dry run mode should not remove images.": {
+			req:       imageprune.Request{OlderThan: 24 * time.Hour, DryRun: true},
+			mock:      func(mgr *imagemock.MockImageManager) {},
+			expPruned: []string{"v0.1.0", "my-snap"},
+		},
+
+		"An error removing an image should fail.": {
+			req: imageprune.Request{OlderThan: 24 * time.Hour},
+			mock: func(mgr *imagemock.MockImageManager) {
+				mgr.On("Remove", mock.Anything, "v0.1.0").Once().Return(fmt.Errorf("something"))
+			},
+			expErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mgr := imagemock.NewMockImageManager(t)
+			mgr.On("List", mock.Anything).Return(images, nil)
+			mgr.On("KernelPath", mock.Anything).Maybe().Return(func(name string) string { return "/images/" + name + "/vmlinux-x86_64" })
+			tc.mock(mgr)
+
+			repo := &storagemock.MockRepository{}
+			repo.On("ListAllSandboxes", mock.Anything).Return(tc.sandboxes, nil)
+
+			svc, err := imageprune.NewService(imageprune.ServiceConfig{Manager: mgr, Repository: repo})
+			require.NoError(t, err)
+
+			pruned, err := svc.Run(context.Background(), tc.req)
+			if tc.expErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expPruned, pruned)
+		})
+	}
+}
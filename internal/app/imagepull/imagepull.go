@@ -7,12 +7,16 @@ import (
 
 	"github.com/slok/sbx/internal/image"
 	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
 )
 
 // ServiceConfig is the configuration for the image pull service.
 type ServiceConfig struct {
 	Puller image.ImagePuller
-	Logger log.Logger
+	// Customizer applies the request customizations (optional, required only
+	// when customizing).
+	Customizer image.ImageCustomizer
+	Logger     log.Logger
 }
 
 func (c *ServiceConfig) defaults() error {
@@ -27,8 +31,9 @@ func (c *ServiceConfig) defaults() error {
 
 // Service handles pulling image releases.
 type Service struct {
-	puller image.ImagePuller
-	logger log.Logger
+	puller     image.ImagePuller
+	customizer image.ImageCustomizer
+	logger     log.Logger
 }
 
 // NewService creates a new image pull service.
@@ -36,7 +41,7 @@ func NewService(cfg ServiceConfig) (*Service, error) {
 	if err := cfg.defaults(); err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
-	return &Service{puller: cfg.Puller, logger: cfg.Logger}, nil
+	return &Service{puller: cfg.Puller, customizer: cfg.Customizer, logger: cfg.Logger}, nil
 }
 
 // Request is the pull request parameters.
@@ -44,16 +49,50 @@ type Request struct {
 	Version      string
 	Force        bool
 	StatusWriter io.Writer
+	// OnProgress receives the download progress of each artifact (optional).
+	OnProgress func(image.PullProgress)
+	// Concurrency is the number of parallel chunk downloads per artifact (0 uses the default).
+	Concurrency int
+	// BandwidthLimit limits the download rate in bytes per second (0 is unlimited).
+	BandwidthLimit int64
+	// Customize is applied to the pulled image rootfs, producing a derived image
+	// (optional). The derived image is reused if already installed, unless Force.
+	Customize *model.ImageCustomization
 }
 
-// Run pulls an image release.
+// Run pulls an image release and applies the customization, if any.
 func (s *Service) Run(ctx context.Context, req Request) (*image.PullResult, error) {
+	if req.Customize != nil {
+		if s.customizer == nil {
+			return nil, fmt.Errorf("image customizer is required to customize images")
+		}
+		if err := req.Customize.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid customization: %w", err)
+		}
+	}
+
 	result, err := s.puller.Pull(ctx, req.Version, image.PullOptions{
-		Force:        req.Force,
-		StatusWriter: req.StatusWriter,
+		Force:          req.Force,
+		StatusWriter:   req.StatusWriter,
+		OnProgress:     req.OnProgress,
+		Concurrency:    req.Concurrency,
+		BandwidthLimit: req.BandwidthLimit,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("pulling image %s: %w", req.Version, err)
 	}
+
+	if req.Customize != nil {
+		custom, err := s.customizer.Customize(ctx, image.CustomizeOptions{
+			BaseImage:     result.Version,
+			Customization: *req.Customize,
+			Force:         req.Force,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("customizing image %s: %w", result.Version, err)
+		}
+		result.Customized = custom
+	}
+
 	return result, nil
 }
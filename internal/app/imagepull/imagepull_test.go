@@ -12,6 +12,7 @@ import (
 	"github.com/slok/sbx/internal/app/imagepull"
 	"github.com/slok/sbx/internal/image"
 	"github.com/slok/sbx/internal/image/imagemock"
+	"github.com/slok/sbx/internal/model"
 )
 
 func TestServiceRun(t *testing.T) {
@@ -70,3 +71,66 @@ func TestServiceRun(t *testing.T) {
 		})
 	}
 }
+
+func TestServiceRunCustomize(t *testing.T) {
+	cust := &model.ImageCustomization{Commands: []string{"apk add curl"}}
+
+	tests := map[string]struct {
+		req       imagepull.Request
+		mock      func(p *imagemock.MockImagePuller, c *imagemock.MockImageCustomizer)
+		expResult *image.PullResult
+		expErr    error
+	}{
+		"A customization should be applied to the pulled image.": {
+			req: imagepull.Request{Version: "v0.1.0", Customize: cust},
+			mock: func(p *imagemock.MockImagePuller, c *imagemock.MockImageCustomizer) {
+				p.On("Pull", mock.Anything, "v0.1.0", mock.Anything).Once().Return(&image.PullResult{Version: "v0.1.0"}, nil)
+				c.On("Customize", mock.Anything, image.CustomizeOptions{BaseImage: "v0.1.0", Customization: *cust}).Once().Return(&image.CustomizeResult{Name: "v0.1.0-custom-abc"}, nil)
+			},
+			expResult: &image.PullResult{Version: "v0.1.0", Customized: &image.CustomizeResult{Name: "v0.1.0-custom-abc"}},
+		},
+
+		"A customization should be applied to an already installed image.": {
+			req: imagepull.Request{Version: "v0.1.0", Customize: cust, Force: true},
+			mock: func(p *imagemock.MockImagePuller, c *imagemock.MockImageCustomizer) {
+				p.On("Pull", mock.Anything, "v0.1.0", mock.Anything).Once().Return(&image.PullResult{Version: "v0.1.0", Skipped: true}, nil)
+				c.On("Customize", mock.Anything, image.CustomizeOptions{BaseImage: "v0.1.0", Customization: *cust, Force: true}).Once().Return(&image.CustomizeResult{Name: "v0.1.0-custom-abc", Skipped: true}, nil)
+			},
+			expResult: &image.PullResult{Version: "v0.1.0", Skipped: true, Customized: &image.CustomizeResult{Name: "v0.1.0-custom-abc", Skipped: true}},
+		},
+
+		"An invalid customization should fail before pulling.": {
+			req:    imagepull.Request{Version: "v0.1.0", Customize: &model.ImageCustomization{}},
+			mock:   func(p *imagemock.MockImagePuller, c *imagemock.MockImageCustomizer) {},
+			expErr: model.ErrNotValid,
+		},
+
+		"A customization error should fail.": {
+			req: imagepull.Request{Version: "v0.1.0", Customize: cust},
+			mock: func(p *imagemock.MockImagePuller, c *imagemock.MockImageCustomizer) {
+				p.On("Pull", mock.Anything, "v0.1.0", mock.Anything).Once().Return(&image.PullResult{Version: "v0.1.0"}, nil)
+				c.On("Customize", mock.Anything, mock.Anything).Once().Return(nil, fmt.Errorf("something: %w", model.ErrNotValid))
+			},
+			expErr: model.ErrNotValid,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			puller := imagemock.NewMockImagePuller(t)
+			customizer := imagemock.NewMockImageCustomizer(t)
+			tc.mock(puller, customizer)
+
+			svc, err := imagepull.NewService(imagepull.ServiceConfig{Puller: puller, Customizer: customizer})
+			require.NoError(t, err)
+
+			got, err := svc.Run(context.Background(), tc.req)
+			if tc.expErr != nil {
+				assert.ErrorIs(t, err, tc.expErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expResult, got)
+		})
+	}
+}
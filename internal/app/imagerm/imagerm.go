@@ -3,21 +3,28 @@ package imagerm
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/slok/sbx/internal/image"
 	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
 )
 
 // ServiceConfig is the configuration for the image remove service.
 type ServiceConfig struct {
-	Manager image.ImageManager
-	Logger  log.Logger
+	Manager    image.ImageManager
+	Repository storage.Repository
+	Logger     log.Logger
 }
 
 func (c *ServiceConfig) defaults() error {
 	if c.Manager == nil {
 		return fmt.Errorf("image manager is required")
 	}
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
 	if c.Logger == nil {
 		c.Logger = log.Noop
 	}
@@ -27,6 +34,7 @@ func (c *ServiceConfig) defaults() error {
 // Service handles removing installed images.
 type Service struct {
 	manager image.ImageManager
+	repo    storage.Repository
 	logger  log.Logger
 }
 
@@ -37,6 +45,7 @@ func NewService(cfg ServiceConfig) (*Service, error) {
 	}
 	return &Service{
 		manager: cfg.Manager,
+		repo:    cfg.Repository,
 		logger:  cfg.Logger,
 	}, nil
 }
@@ -44,10 +53,28 @@ func NewService(cfg ServiceConfig) (*Service, error) {
 // Request is the remove request parameters.
 type Request struct {
 	Version string
+	// Force removes the image even if sandboxes still depend on it.
+	Force bool
 }
 
-// Run removes a locally installed image.
+// Run removes a locally installed image. Images used by sandboxes are not removed
+// unless forced.
 func (s *Service) Run(ctx context.Context, req Request) error {
+	if !req.Force {
+		sandboxes, err := s.repo.ListAllSandboxes(ctx)
+		if err != nil {
+			return fmt.Errorf("could not list sandboxes: %w", err)
+		}
+
+		if users := image.SandboxesUsingImage(s.manager, req.Version, sandboxes); len(users) > 0 {
+			names := make([]string, 0, len(users))
+			for _, sb := range users {
+				names = append(names, sb.Name)
+			}
+			return fmt.Errorf("image %s is used by sandboxes: %s: %w", req.Version, strings.Join(names, ", "), model.ErrNotValid)
+		}
+	}
+
 	if err := s.manager.Remove(ctx, req.Version); err != nil {
 		return fmt.Errorf("removing image %s: %w", req.Version, err)
 	}
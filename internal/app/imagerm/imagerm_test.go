@@ -11,38 +11,72 @@ import (
 
 	"github.com/slok/sbx/internal/app/imagerm"
 	"github.com/slok/sbx/internal/image/imagemock"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/storagemock"
 )
 
 func TestServiceRun(t *testing.T) {
 	tests := map[string]struct {
-		version string
-		mockErr error
-		expErr  bool
+		req       imagerm.Request
+		sandboxes []model.Sandbox
+		mock      func(mgr *imagemock.MockImageManager)
+		expErr    bool
 	}{
 		"Removing an installed image should succeed.": {
-			version: "v0.1.0",
+			req: imagerm.Request{Version: "v0.1.0"},
+			mock: func(mgr *imagemock.MockImageManager) {
+				mgr.On("Remove", mock.Anything, "v0.1.0").Return(nil)
+			},
 		},
 
 		"Removing a snapshot image should succeed.": {
-			version: "my-snap",
+			req: imagerm.Request{Version: "my-snap"},
+			mock: func(mgr *imagemock.MockImageManager) {
+				mgr.On("Remove", mock.Anything, "my-snap").Return(nil)
+			},
+		},
+
+		"Removing an image used by a sandbox should fail.": {
+			req: imagerm.Request{Version: "v0.1.0"},
+			sandboxes: []model.Sandbox{
+				{Name: "sb1", Config: model.SandboxConfig{Image: "v0.1.0"}},
+			},
+			mock:   func(mgr *imagemock.MockImageManager) {},
+			expErr: true,
+		},
+
+		"Removing an image used by a sandbox with force should succeed.": {
+			req: imagerm.Request{Version: "v0.1.0", Force: true},
+			sandboxes: []model.Sandbox{
+				{Name: "sb1", Config: model.SandboxConfig{Image: "v0.1.0"}},
+			},
+			mock: func(mgr *imagemock.MockImageManager) {
+				mgr.On("Remove", mock.Anything, "v0.1.0").Return(nil)
+			},
 		},
 
 		"An error from the image manager should propagate.": {
-			version: "v0.1.0",
-			mockErr: fmt.Errorf("not installed"),
-			expErr:  true,
+			req: imagerm.Request{Version: "v0.1.0"},
+			mock: func(mgr *imagemock.MockImageManager) {
+				mgr.On("Remove", mock.Anything, "v0.1.0").Return(fmt.Errorf("not installed"))
+			},
+			expErr: true,
 		},
 	}
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
 			mgr := imagemock.NewMockImageManager(t)
-			mgr.On("Remove", mock.Anything, tc.version).Return(tc.mockErr)
+			mgr.On("KernelPath", mock.Anything).Maybe().Return("/images/v0.1.0/vmlinux-x86_64")
+			tc.mock(mgr)
+
+			repo := &storagemock.MockRepository{}
+			repo.On("ListAllSandboxes", mock.Anything).Maybe().Return(tc.sandboxes, nil)
 
-			svc, err := imagerm.NewService(imagerm.ServiceConfig{Manager: mgr})
+			svc, err := imagerm.NewService(imagerm.ServiceConfig{Manager: mgr, Repository: repo})
 			require.NoError(t, err)
 
-			err = svc.Run(context.Background(), imagerm.Request{Version: tc.version})
+			err = svc.Run(context.Background(), tc.req)
 			if tc.expErr {
 				assert.Error(t, err)
 				return
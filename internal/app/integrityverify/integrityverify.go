@@ -0,0 +1,110 @@
+package integrityverify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/slok/sbx/internal/integrity"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the integrity verify service.
+type ServiceConfig struct {
+	Engine     sandbox.Engine
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Engine == nil {
+		return fmt.Errorf("engine is required")
+	}
+
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.IntegrityVerify"})
+
+	return nil
+}
+
+// Service verifies the guest files of a sandbox against the integrity baseline
+// captured when it started.
+type Service struct {
+	engine sandbox.Engine
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new integrity verify service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		engine: cfg.Engine,
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the integrity verify request parameters.
+type Request struct {
+	// NameOrID is the sandbox name or ID.
+	NameOrID string
+}
+
+// Run hashes the files under the baseline paths of a running sandbox and returns
+// the files modified, added or removed since the baseline.
+func (s *Service) Run(ctx context.Context, req Request) (*model.IntegrityReport, error) {
+	sb, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
+	if errors.Is(err, model.ErrNotFound) {
+		sb, err = s.repo.GetSandbox(ctx, req.NameOrID)
+	}
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("sandbox not found: %s: %w", req.NameOrID, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not get sandbox: %w", err)
+	}
+
+	if sb.Status != model.SandboxStatusRunning {
+		return nil, fmt.Errorf("sandbox %s is not running (current status: %s): %w", sb.Name, sb.Status, model.ErrNotValid)
+	}
+
+	baseline, err := s.repo.GetIntegrityBaseline(ctx, sb.ID)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("sandbox %s has no integrity baseline, start it with integrity paths: %w", sb.Name, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not get integrity baseline: %w", err)
+	}
+
+	files, err := integrity.Collect(ctx, s.engine, sb.ID, baseline.Paths)
+	if err != nil {
+		return nil, fmt.Errorf("could not hash sandbox files: %w", err)
+	}
+
+	report := &model.IntegrityReport{
+		SandboxID:   sb.ID,
+		SandboxName: sb.Name,
+		Paths:       baseline.Paths,
+		Files:       len(files),
+		BaselineAt:  baseline.CreatedAt,
+		CheckedAt:   time.Now().UTC(),
+		Changes:     integrity.Compare(baseline.Files, files),
+	}
+
+	s.logger.Debugf("verified %d files of sandbox %s: %d changes", report.Files, sb.ID, len(report.Changes))
+	return report, nil
+}
@@ -0,0 +1,132 @@
+package integrityverify_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/integrityverify"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox/sandboxmock"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestServiceRun(t *testing.T) {
+	errTest := errors.New("whatever")
+	t0 := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	shaA, shaB := strings.Repeat("a", 64), strings.Repeat("b", 64)
+	running := &model.Sandbox{ID: "sb-id", Name: "my-sandbox", Status: model.SandboxStatusRunning}
+	baseline := &model.IntegrityBaseline{
+		SandboxID: "sb-id",
+		Paths:     []string{"/etc", "/app"},
+		Files:     map[string]string{"/etc/motd": shaA, "/etc/hosts": shaA, "/app/bin": shaB},
+		CreatedAt: t0,
+	}
+	hashed := func(out string) func(args mock.Arguments) {
+		return func(args mock.Arguments) {
+			_, _ = args.Get(3).(model.ExecOpts).Stdout.Write([]byte(out))
+		}
+	}
+
+	tests := map[string]struct {
+		mock       func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine)
+		req        integrityverify.Request
+		expChanges []model.IntegrityChange
+		expFiles   int
+		expErr     error
+	}{
+		"The changed files should be reported.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(running, nil)
+				mr.On("GetIntegrityBaseline", mock.Anything, "sb-id").Once().Return(baseline, nil)
+				me.On("Exec", mock.Anything, "sb-id", mock.MatchedBy(func(cmd []string) bool {
+					return len(cmd) == 3 && strings.Contains(cmd[2], "for p in '/etc' '/app'")
+				}), mock.Anything).Once().Run(hashed(shaB+"  /etc/motd\n"+shaA+"  /etc/hosts\n"+shaA+"  /etc/new\n")).Return(&model.ExecResult{}, nil)
+			},
+			req: integrityverify.Request{NameOrID: "my-sandbox"},
+			expChanges: []model.IntegrityChange{
+				{Path: "/app/bin", Kind: model.FileChangeRemoved, OldSHA256: shaB},
+				{Path: "/etc/motd", Kind: model.FileChangeModified, OldSHA256: shaA, NewSHA256: shaB},
+				{Path: "/etc/new", Kind: model.FileChangeAdded, NewSHA256: shaA},
+			},
+			expFiles: 3,
+		},
+
+		"The unchanged files should not be reported.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "sb-id").Once().Return(nil, model.ErrNotFound)
+				mr.On("GetSandbox", mock.Anything, "sb-id").Once().Return(running, nil)
+				mr.On("GetIntegrityBaseline", mock.Anything, "sb-id").Once().Return(baseline, nil)
+				me.On("Exec", mock.Anything, "sb-id", mock.Anything, mock.Anything).Once().Run(hashed(shaA+"  /etc/motd\n"+shaA+"  /etc/hosts\n"+shaB+"  /app/bin\n")).Return(&model.ExecResult{}, nil)
+			},
+			req:        integrityverify.Request{NameOrID: "sb-id"},
+			expChanges: []model.IntegrityChange{},
+			expFiles:   3,
+		},
+
+		"A stopped sandbox should fail.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{ID: "sb-id", Status: model.SandboxStatusStopped}, nil)
+			},
+			req:    integrityverify.Request{NameOrID: "my-sandbox"},
+			expErr: model.ErrNotValid,
+		},
+
+		"A sandbox without baseline should fail.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(running, nil)
+				mr.On("GetIntegrityBaseline", mock.Anything, "sb-id").Once().Return(nil, model.ErrNotFound)
+			},
+			req:    integrityverify.Request{NameOrID: "my-sandbox"},
+			expErr: model.ErrNotFound,
+		},
+
+		"A missing sandbox should fail.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "missing").Once().Return(nil, model.ErrNotFound)
+				mr.On("GetSandbox", mock.Anything, "missing").Once().Return(nil, model.ErrNotFound)
+			},
+			req:    integrityverify.Request{NameOrID: "missing"},
+			expErr: model.ErrNotFound,
+		},
+
+		"An error hashing the files should fail.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(running, nil)
+				mr.On("GetIntegrityBaseline", mock.Anything, "sb-id").Once().Return(baseline, nil)
+				me.On("Exec", mock.Anything, "sb-id", mock.Anything, mock.Anything).Once().Return(nil, errTest)
+			},
+			req:    integrityverify.Request{NameOrID: "my-sandbox"},
+			expErr: errTest,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mr := storagemock.NewMockRepository(t)
+			me := sandboxmock.NewMockEngine(t)
+			test.mock(mr, me)
+
+			svc, err := integrityverify.NewService(integrityverify.ServiceConfig{Engine: me, Repository: mr})
+			require.NoError(t, err)
+
+			report, err := svc.Run(context.Background(), test.req)
+			if test.expErr != nil {
+				assert.ErrorIs(t, err, test.expErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "sb-id", report.SandboxID)
+			assert.Equal(t, baseline.Paths, report.Paths)
+			assert.Equal(t, t0, report.BaselineAt)
+			assert.Equal(t, test.expFiles, report.Files)
+			assert.Equal(t, test.expChanges, report.Changes)
+		})
+	}
+}
@@ -49,12 +49,27 @@ func NewService(cfg ServiceConfig) (*Service, error) {
 type Request struct {
 	// StatusFilter is an optional filter to only show sandboxes with this status.
 	StatusFilter *model.SandboxStatus
+	// NamePattern is an optional sandbox name pattern (see [model.SandboxNamePattern])
+	// to only show the sandboxes whose name matches.
+	NamePattern string
+	// Namespace is an optional filter to only show the sandboxes of this namespace,
+	// for the repositories scoped to all the namespaces.
+	Namespace string
 }
 
-// Run lists all sandboxes, optionally filtered by status.
+// Run lists all sandboxes, optionally filtered by status and name pattern.
 func (s *Service) Run(ctx context.Context, req Request) ([]model.Sandbox, error) {
 	s.logger.Debugf("listing sandboxes with filter: %v", req.StatusFilter)
 
+	var pattern *model.SandboxNamePattern
+	if req.NamePattern != "" {
+		p, err := model.NewSandboxNamePattern(req.NamePattern)
+		if err != nil {
+			return nil, err
+		}
+		pattern = p
+	}
+
 	// Get all sandboxes from repository
 	sandboxes, err := s.repo.ListSandboxes(ctx)
 	if err != nil {
@@ -72,6 +87,28 @@ func (s *Service) Run(ctx context.Context, req Request) ([]model.Sandbox, error)
 		sandboxes = filtered
 	}
 
+	// Apply namespace filter if provided
+	if req.Namespace != "" {
+		filtered := make([]model.Sandbox, 0, len(sandboxes))
+		for _, sb := range sandboxes {
+			if sb.Namespace == req.Namespace {
+				filtered = append(filtered, sb)
+			}
+		}
+		sandboxes = filtered
+	}
+
+	// Apply name pattern filter if provided
+	if pattern != nil {
+		filtered := make([]model.Sandbox, 0, len(sandboxes))
+		for _, sb := range sandboxes {
+			if pattern.Match(sb.Name) {
+				filtered = append(filtered, sb)
+			}
+		}
+		sandboxes = filtered
+	}
+
 	s.logger.Debugf("found %d sandboxes", len(sandboxes))
 	return sandboxes, nil
 }
@@ -120,6 +120,59 @@ func TestService_Run(t *testing.T) {
 			},
 			expErr: false,
 		},
+		"filter by name pattern": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("ListSandboxes", mock.Anything).Once().Return([]model.Sandbox{
+					{ID: "id1", Name: "ci-1", Status: model.SandboxStatusRunning, CreatedAt: createdAt},
+					{ID: "id2", Name: "dev-1", Status: model.SandboxStatusStopped, CreatedAt: createdAt},
+					{ID: "id3", Name: "ci-2", Status: model.SandboxStatusStopped, CreatedAt: createdAt},
+				}, nil)
+			},
+			req: list.Request{NamePattern: "ci-*"},
+			expResult: func() []model.Sandbox {
+				return []model.Sandbox{
+					{ID: "id1", Name: "ci-1", Status: model.SandboxStatusRunning, CreatedAt: createdAt},
+					{ID: "id3", Name: "ci-2", Status: model.SandboxStatusStopped, CreatedAt: createdAt},
+				}
+			},
+			expErr: false,
+		},
+		"filter by namespace": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("ListSandboxes", mock.Anything).Once().Return([]model.Sandbox{
+					{ID: "id1", Namespace: "team-a", Name: "ci-1", Status: model.SandboxStatusRunning, CreatedAt: createdAt},
+					{ID: "id2", Namespace: "team-b", Name: "ci-1", Status: model.SandboxStatusStopped, CreatedAt: createdAt},
+				}, nil)
+			},
+			req: list.Request{Namespace: "team-b"},
+			expResult: func() []model.Sandbox {
+				return []model.Sandbox{
+					{ID: "id2", Namespace: "team-b", Name: "ci-1", Status: model.SandboxStatusStopped, CreatedAt: createdAt},
+				}
+			},
+			expErr: false,
+		},
+		"filter by status and name pattern": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("ListSandboxes", mock.Anything).Once().Return([]model.Sandbox{
+					{ID: "id1", Name: "ci-1", Status: model.SandboxStatusRunning, CreatedAt: createdAt},
+					{ID: "id2", Name: "dev-1", Status: model.SandboxStatusStopped, CreatedAt: createdAt},
+					{ID: "id3", Name: "ci-2", Status: model.SandboxStatusStopped, CreatedAt: createdAt},
+				}, nil)
+			},
+			req: list.Request{StatusFilter: &stopped, NamePattern: "re:^ci-"},
+			expResult: func() []model.Sandbox {
+				return []model.Sandbox{
+					{ID: "id3", Name: "ci-2", Status: model.SandboxStatusStopped, CreatedAt: createdAt},
+				}
+			},
+			expErr: false,
+		},
+		"invalid name pattern should fail": {
+			mock:   func(m *storagemock.MockRepository) {},
+			req:    list.Request{NamePattern: "ci-["},
+			expErr: true,
+		},
 		"filter with no matches returns empty list": {
 			mock: func(m *storagemock.MockRepository) {
 				m.On("ListSandboxes", mock.Anything).Once().Return([]model.Sandbox{
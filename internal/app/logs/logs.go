@@ -0,0 +1,96 @@
+package logs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// DefaultLines is the default number of lines returned of each log.
+const DefaultLines = 100
+
+// ServiceConfig is the configuration for the logs service.
+type ServiceConfig struct {
+	Engine     sandbox.Engine
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Engine == nil {
+		return fmt.Errorf("engine is required")
+	}
+
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.Logs"})
+
+	return nil
+}
+
+// Service returns the engine logs of sandboxes.
+type Service struct {
+	engine sandbox.Engine
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new logs service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		engine: cfg.Engine,
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the logs request parameters.
+type Request struct {
+	// NameOrID is the sandbox name or ID.
+	NameOrID string
+	// Lines is the number of last lines of each log (default: DefaultLines).
+	Lines int
+}
+
+// Run returns the last lines of the engine logs of a sandbox.
+func (s *Service) Run(ctx context.Context, req Request) ([]model.SandboxLog, error) {
+	if req.Lines < 0 {
+		return nil, fmt.Errorf("lines can't be negative: %w", model.ErrNotValid)
+	}
+	if req.Lines == 0 {
+		req.Lines = DefaultLines
+	}
+
+	sb, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
+	if errors.Is(err, model.ErrNotFound) {
+		sb, err = s.repo.GetSandbox(ctx, req.NameOrID)
+	}
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("sandbox not found: %s: %w", req.NameOrID, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not get sandbox: %w", err)
+	}
+
+	logs, err := s.engine.Logs(ctx, sb.ID, req.Lines)
+	if err != nil {
+		return nil, fmt.Errorf("could not get sandbox logs: %w", err)
+	}
+
+	s.logger.Debugf("found %d logs for sandbox %s", len(logs), sb.ID)
+	return logs, nil
+}
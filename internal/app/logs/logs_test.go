@@ -0,0 +1,98 @@
+package logs_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/logs"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox/sandboxmock"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestServiceRun(t *testing.T) {
+	errTest := errors.New("whatever")
+	sbLogs := []model.SandboxLog{
+		{Source: "firecracker", Lines: []string{"boot"}},
+		{Source: "proxy", Lines: []string{"listening"}},
+	}
+
+	tests := map[string]struct {
+		mock    func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine)
+		req     logs.Request
+		expLogs []model.SandboxLog
+		expErr  error
+	}{
+		"Getting by name should return the default lines of the sandbox logs.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{ID: "sb-id"}, nil)
+				me.On("Logs", mock.Anything, "sb-id", logs.DefaultLines).Once().Return(sbLogs, nil)
+			},
+			req:     logs.Request{NameOrID: "my-sandbox"},
+			expLogs: sbLogs,
+		},
+
+		"Getting by ID with lines should return the requested lines of the sandbox logs.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "sb-id").Once().Return(nil, model.ErrNotFound)
+				mr.On("GetSandbox", mock.Anything, "sb-id").Once().Return(&model.Sandbox{ID: "sb-id"}, nil)
+				me.On("Logs", mock.Anything, "sb-id", 5).Once().Return(sbLogs, nil)
+			},
+			req:     logs.Request{NameOrID: "sb-id", Lines: 5},
+			expLogs: sbLogs,
+		},
+
+		"A missing sandbox should fail.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "missing").Once().Return(nil, model.ErrNotFound)
+				mr.On("GetSandbox", mock.Anything, "missing").Once().Return(nil, model.ErrNotFound)
+			},
+			req:    logs.Request{NameOrID: "missing"},
+			expErr: model.ErrNotFound,
+		},
+
+		"An engine error should fail.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{ID: "sb-id"}, nil)
+				me.On("Logs", mock.Anything, "sb-id", logs.DefaultLines).Once().Return(nil, errTest)
+			},
+			req:    logs.Request{NameOrID: "my-sandbox"},
+			expErr: errTest,
+		},
+
+		"Negative lines should fail.": {
+			mock:   func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {},
+			req:    logs.Request{NameOrID: "my-sandbox", Lines: -1},
+			expErr: model.ErrNotValid,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mRepo := &storagemock.MockRepository{}
+			mEngine := &sandboxmock.MockEngine{}
+			test.mock(mRepo, mEngine)
+
+			svc, err := logs.NewService(logs.ServiceConfig{Engine: mEngine, Repository: mRepo})
+			require.NoError(err)
+
+			got, err := svc.Run(context.Background(), test.req)
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expLogs, got)
+			}
+
+			mRepo.AssertExpectations(t)
+			mEngine.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package memorytarget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the memory target service.
+type ServiceConfig struct {
+	Engine     sandbox.Engine
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Engine == nil {
+		return fmt.Errorf("engine is required")
+	}
+
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.MemoryTarget"})
+
+	return nil
+}
+
+// Service sets the memory a running sandbox keeps, reclaiming the rest for the host.
+type Service struct {
+	engine sandbox.Engine
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new memory target service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		engine: cfg.Engine,
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the memory target request parameters.
+type Request struct {
+	// NameOrID is the sandbox name or ID.
+	NameOrID string
+	// TargetMB is the memory in MB the sandbox keeps, 0 gives back all the
+	// sandbox memory.
+	TargetMB int
+}
+
+// Run sets the memory target of a running sandbox by name or ID.
+func (s *Service) Run(ctx context.Context, req Request) (*model.Sandbox, error) {
+	if req.TargetMB < 0 {
+		return nil, fmt.Errorf("memory target can't be negative: %w", model.ErrNotValid)
+	}
+
+	// Lookup sandbox by name first, then by ID if it looks like a ULID.
+	sandbox, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
+	if errors.Is(err, model.ErrNotFound) && looksLikeULID(req.NameOrID) {
+		sandbox, err = s.repo.GetSandbox(ctx, req.NameOrID)
+	}
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("sandbox not found: %s: %w", req.NameOrID, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not get sandbox: %w", err)
+	}
+
+	if sandbox.Status != model.SandboxStatusRunning {
+		return nil, fmt.Errorf("cannot set memory target: sandbox not running (current status: %s): %w", sandbox.Status, model.ErrNotValid)
+	}
+
+	memoryMB := sandbox.Config.Resources.MemoryMB
+	targetMB := req.TargetMB
+	if targetMB == 0 {
+		targetMB = memoryMB
+	}
+	if targetMB > memoryMB {
+		return nil, fmt.Errorf("memory target %dMB is greater than the sandbox memory (%dMB): %w", targetMB, memoryMB, model.ErrNotValid)
+	}
+
+	if err := s.engine.SetMemoryTarget(ctx, sandbox.ID, targetMB); err != nil {
+		return nil, fmt.Errorf("could not set memory target: %w", err)
+	}
+
+	s.logger.Infof("set sandbox %s memory target to %dMB of %dMB", sandbox.Name, targetMB, memoryMB)
+	return sandbox, nil
+}
+
+// looksLikeULID checks if a string looks like a ULID (26 characters, alphanumeric uppercase).
+func looksLikeULID(s string) bool {
+	if len(s) != 26 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,182 @@
+package memorytarget_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/memorytarget"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox/sandboxmock"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestNewService(t *testing.T) {
+	tests := map[string]struct {
+		config memorytarget.ServiceConfig
+		expErr bool
+	}{
+		"valid config should create service": {
+			config: memorytarget.ServiceConfig{
+				Engine:     &sandboxmock.MockEngine{},
+				Repository: &storagemock.MockRepository{},
+				Logger:     log.Noop,
+			},
+		},
+		"missing engine should fail": {
+			config: memorytarget.ServiceConfig{
+				Repository: &storagemock.MockRepository{},
+			},
+			expErr: true,
+		},
+		"missing repository should fail": {
+			config: memorytarget.ServiceConfig{
+				Engine: &sandboxmock.MockEngine{},
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			svc, err := memorytarget.NewService(test.config)
+
+			if test.expErr {
+				require.Error(err)
+				require.Nil(svc)
+			} else {
+				require.NoError(err)
+				require.NotNil(svc)
+			}
+		})
+	}
+}
+
+func TestService_Run(t *testing.T) {
+	running := &model.Sandbox{
+		ID:     "01H2QWERTYASDFGZXCVBNMLKJH",
+		Name:   "my-sandbox",
+		Status: model.SandboxStatusRunning,
+		Config: model.SandboxConfig{Resources: model.Resources{MemoryMB: 1024}},
+	}
+
+	tests := map[string]struct {
+		mockRepo   func(m *storagemock.MockRepository)
+		mockEngine func(m *sandboxmock.MockEngine)
+		req        memorytarget.Request
+		expErrIs   error
+		expErr     bool
+	}{
+		"setting a memory target on a running sandbox should reclaim memory": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(running, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("SetMemoryTarget", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", 256).Once().Return(nil)
+			},
+			req: memorytarget.Request{NameOrID: "my-sandbox", TargetMB: 256},
+		},
+		"setting a memory target by ID should lookup the sandbox by ID": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH").Once().Return(nil, model.ErrNotFound)
+				m.On("GetSandbox", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH").Once().Return(running, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("SetMemoryTarget", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", 512).Once().Return(nil)
+			},
+			req: memorytarget.Request{NameOrID: "01H2QWERTYASDFGZXCVBNMLKJH", TargetMB: 512},
+		},
+		"a zero memory target should give back all the sandbox memory": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(running, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("SetMemoryTarget", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", 1024).Once().Return(nil)
+			},
+			req: memorytarget.Request{NameOrID: "my-sandbox"},
+		},
+		"a memory target greater than the sandbox memory should fail": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(running, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        memorytarget.Request{NameOrID: "my-sandbox", TargetMB: 2048},
+			expErrIs:   model.ErrNotValid,
+		},
+		"a negative memory target should fail": {
+			mockRepo:   func(m *storagemock.MockRepository) {},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        memorytarget.Request{NameOrID: "my-sandbox", TargetMB: -1},
+			expErrIs:   model.ErrNotValid,
+		},
+		"a stopped sandbox should fail": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:     "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:   "my-sandbox",
+					Status: model.SandboxStatusStopped,
+				}, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        memorytarget.Request{NameOrID: "my-sandbox", TargetMB: 256},
+			expErrIs:   model.ErrNotValid,
+		},
+		"a missing sandbox should fail": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "nonexistent").Once().Return(nil, model.ErrNotFound)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        memorytarget.Request{NameOrID: "nonexistent", TargetMB: 256},
+			expErrIs:   model.ErrNotFound,
+		},
+		"engine error should propagate": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(running, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("SetMemoryTarget", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", 256).Once().Return(fmt.Errorf("engine error"))
+			},
+			req:    memorytarget.Request{NameOrID: "my-sandbox", TargetMB: 256},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mRepo := &storagemock.MockRepository{}
+			mEngine := &sandboxmock.MockEngine{}
+			test.mockRepo(mRepo)
+			test.mockEngine(mEngine)
+
+			svc, err := memorytarget.NewService(memorytarget.ServiceConfig{
+				Engine:     mEngine,
+				Repository: mRepo,
+			})
+			require.NoError(err)
+
+			sb, err := svc.Run(context.Background(), test.req)
+
+			if test.expErr || test.expErrIs != nil {
+				assert.Error(err)
+				if test.expErrIs != nil {
+					assert.ErrorIs(err, test.expErrIs)
+				}
+			} else if assert.NoError(err) {
+				assert.Equal("my-sandbox", sb.Name)
+			}
+
+			mRepo.AssertExpectations(t)
+			mEngine.AssertExpectations(t)
+		})
+	}
+}
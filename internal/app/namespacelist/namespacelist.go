@@ -0,0 +1,80 @@
+package namespacelist
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the namespace list service.
+type ServiceConfig struct {
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.NamespaceList"})
+
+	return nil
+}
+
+// Service lists the namespaces with sandboxes.
+type Service struct {
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new namespace list service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Run lists the namespaces of the sandboxes in the repository scope, sorted by
+// name. The namespaces only exist while they have sandboxes, a repository scoped
+// to a single namespace only returns that one.
+func (s *Service) Run(ctx context.Context) ([]model.NamespaceUsage, error) {
+	sandboxes, err := s.repo.ListSandboxes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not list sandboxes: %w", err)
+	}
+
+	byName := map[string]*model.NamespaceUsage{}
+	for _, sb := range sandboxes {
+		ns, ok := byName[sb.Namespace]
+		if !ok {
+			ns = &model.NamespaceUsage{Name: sb.Namespace}
+			byName[sb.Namespace] = ns
+		}
+		ns.Sandboxes++
+		if sb.Status == model.SandboxStatusRunning {
+			ns.RunningSandboxes++
+		}
+	}
+
+	namespaces := make([]model.NamespaceUsage, 0, len(byName))
+	for _, ns := range byName {
+		namespaces = append(namespaces, *ns)
+	}
+	sort.Slice(namespaces, func(i, j int) bool { return namespaces[i].Name < namespaces[j].Name })
+
+	s.logger.Debugf("listed %d namespaces", len(namespaces))
+	return namespaces, nil
+}
@@ -0,0 +1,107 @@
+package namespacelist_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/namespacelist"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestNewService(t *testing.T) {
+	tests := map[string]struct {
+		config namespacelist.ServiceConfig
+		expErr bool
+	}{
+		"valid config should create service": {
+			config: namespacelist.ServiceConfig{
+				Repository: &storagemock.MockRepository{},
+				Logger:     log.Noop,
+			},
+		},
+		"missing repository should fail": {
+			config: namespacelist.ServiceConfig{Logger: log.Noop},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			svc, err := namespacelist.NewService(test.config)
+
+			if test.expErr {
+				require.Error(err)
+				require.Nil(svc)
+			} else {
+				require.NoError(err)
+				require.NotNil(svc)
+			}
+		})
+	}
+}
+
+func TestService_Run(t *testing.T) {
+	tests := map[string]struct {
+		mock          func(m *storagemock.MockRepository)
+		expNamespaces []model.NamespaceUsage
+		expErr        bool
+	}{
+		"namespaces should be counted and sorted by name": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("ListSandboxes", mock.Anything).Once().Return([]model.Sandbox{
+					{ID: "id-1", Namespace: "team-b", Status: model.SandboxStatusRunning},
+					{ID: "id-2", Namespace: "default", Status: model.SandboxStatusStopped},
+					{ID: "id-3", Namespace: "team-b", Status: model.SandboxStatusStopped},
+				}, nil)
+			},
+			expNamespaces: []model.NamespaceUsage{
+				{Name: "default", Sandboxes: 1},
+				{Name: "team-b", Sandboxes: 2, RunningSandboxes: 1},
+			},
+		},
+		"no sandboxes should return no namespaces": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("ListSandboxes", mock.Anything).Once().Return([]model.Sandbox{}, nil)
+			},
+			expNamespaces: []model.NamespaceUsage{},
+		},
+		"repository error should propagate": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("ListSandboxes", mock.Anything).Once().Return(nil, fmt.Errorf("db error"))
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+			assert := assert.New(t)
+
+			mRepo := &storagemock.MockRepository{}
+			test.mock(mRepo)
+
+			svc, err := namespacelist.NewService(namespacelist.ServiceConfig{Repository: mRepo, Logger: log.Noop})
+			require.NoError(err)
+
+			namespaces, err := svc.Run(context.Background())
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expNamespaces, namespaces)
+			}
+
+			mRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package netcheck
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// DefaultTargets are the addresses pinged when the request has no targets.
+var DefaultTargets = []string{"1.1.1.1"}
+
+// pingTimeoutSeconds is how long a ping waits for the echo reply.
+const pingTimeoutSeconds = "2"
+
+// ServiceConfig is the configuration for the connectivity check service.
+type ServiceConfig struct {
+	Engine     sandbox.Engine
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Engine == nil {
+		return fmt.Errorf("engine is required")
+	}
+
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.NetCheck"})
+
+	return nil
+}
+
+// Service checks the network connectivity of a running sandbox from inside its VM,
+// the results are doctor checks.
+type Service struct {
+	engine sandbox.Engine
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new connectivity check service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		engine: cfg.Engine,
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents a connectivity check request.
+type Request struct {
+	// NameOrID is the running sandbox the checks run in.
+	NameOrID string
+	// Targets are the addresses or domains pinged (default: DefaultTargets).
+	Targets []string
+}
+
+// Run pings every target once from inside the sandbox and returns a check result
+// per target. A failed ping is an error check, a sandbox without ping is a warning.
+func (s *Service) Run(ctx context.Context, req Request) ([]model.CheckResult, error) {
+	targets := req.Targets
+	if len(targets) == 0 {
+		targets = DefaultTargets
+	}
+	for _, t := range targets {
+		if t == "" || strings.HasPrefix(t, "-") || strings.ContainsAny(t, " \t\n") {
+			return nil, fmt.Errorf("invalid ping target %q: %w", t, model.ErrNotValid)
+		}
+	}
+
+	sb, err := s.runningSandbox(ctx, req.NameOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]model.CheckResult, 0, len(targets))
+	for _, t := range targets {
+		results = append(results, s.ping(ctx, sb, t))
+	}
+
+	return results, nil
+}
+
+// ping pings a target from the sandbox.
+func (s *Service) ping(ctx context.Context, sb *model.Sandbox, target string) model.CheckResult {
+	var out bytes.Buffer
+	res, err := s.engine.Exec(ctx, sb.ID, []string{"ping", "-c", "1", "-W", pingTimeoutSeconds, target}, model.ExecOpts{Stdout: &out, Stderr: &out})
+	if err != nil {
+		return model.CheckResult{
+			ID:          "connectivity_ping",
+			Message:     fmt.Sprintf("Could not ping %s from sandbox %s: %s", target, sb.Name, err),
+			Status:      model.CheckStatusError,
+			Remediation: "Check the sandbox is reachable with 'sbx exec'",
+		}
+	}
+
+	switch res.ExitCode {
+	case 0:
+		return model.CheckResult{
+			ID:      "connectivity_ping",
+			Message: fmt.Sprintf("Sandbox %s can ping %s", sb.Name, target),
+			Status:  model.CheckStatusOK,
+		}
+	case 126, 127:
+		return model.CheckResult{
+			ID:          "connectivity_ping",
+			Message:     fmt.Sprintf("Sandbox %s has no ping command", sb.Name),
+			Status:      model.CheckStatusWarning,
+			Remediation: "Install ping (iputils or busybox) in the sandbox image to check the connectivity",
+		}
+	}
+
+	s.logger.Debugf("ping %s from sandbox %s failed with code %d: %s", target, sb.Name, res.ExitCode, out.String())
+	return model.CheckResult{
+		ID:          "connectivity_ping",
+		Message:     fmt.Sprintf("Sandbox %s can't ping %s (%s)", sb.Name, target, lastLine(out.String())),
+		Status:      model.CheckStatusError,
+		Remediation: remediation(sb),
+	}
+}
+
+// remediation returns how to fix the failed pings of a sandbox.
+func remediation(sb *model.Sandbox) string {
+	if sb.Session != nil && (sb.Session.Egress != nil || sb.Session.EgressPolicyName != "") {
+		return "The sandbox has an egress policy: it can only ping the addresses of the domains of allow rules with 'icmp: true', resolved through the sandbox DNS first"
+	}
+	return "Check the host IP forwarding (sysctl net.ipv4.ip_forward=1), the nftables sbx table NAT rules and the route of the egress interface"
+}
+
+// lastLine returns the last non empty line of an output.
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if l := strings.TrimSpace(lines[len(lines)-1]); l != "" {
+		return l
+	}
+	return "no output"
+}
+
+// runningSandbox returns the sandbox of the name or ID, that must be running.
+func (s *Service) runningSandbox(ctx context.Context, nameOrID string) (*model.Sandbox, error) {
+	if nameOrID == "" {
+		return nil, fmt.Errorf("sandbox name or ID is required: %w", model.ErrNotValid)
+	}
+
+	sb, err := s.repo.GetSandboxByName(ctx, nameOrID)
+	if errors.Is(err, model.ErrNotFound) {
+		sb, err = s.repo.GetSandbox(ctx, nameOrID)
+	}
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("sandbox not found: %s: %w", nameOrID, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not get sandbox: %w", err)
+	}
+
+	if sb.Status != model.SandboxStatusRunning {
+		return nil, fmt.Errorf("cannot check connectivity: sandbox not running (current status: %s): %w", sb.Status, model.ErrNotValid)
+	}
+
+	return sb, nil
+}
@@ -0,0 +1,160 @@
+package netcheck_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/netcheck"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox/sandboxmock"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+const testSandboxID = "01H2QWERTYASDFGZXCVBNMLKJH"
+
+func TestServiceRun(t *testing.T) {
+	running := &model.Sandbox{ID: testSandboxID, Name: "my-sandbox", Status: model.SandboxStatusRunning}
+	egress := &model.Sandbox{
+		ID:      testSandboxID,
+		Name:    "my-sandbox",
+		Status:  model.SandboxStatusRunning,
+		Session: &model.SessionConfig{EgressPolicyName: "ci"},
+	}
+
+	tests := map[string]struct {
+		mockRepo   func(m *storagemock.MockRepository)
+		mockEngine func(m *sandboxmock.MockEngine)
+		req        netcheck.Request
+		expResults []model.CheckResult
+		expErrIs   error
+		expErr     bool
+	}{
+		"a reachable default target should pass": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(running, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Exec", mock.Anything, testSandboxID, []string{"ping", "-c", "1", "-W", "2", "1.1.1.1"}, mock.Anything).Once().Return(&model.ExecResult{}, nil)
+			},
+			req: netcheck.Request{NameOrID: "my-sandbox"},
+			expResults: []model.CheckResult{
+				{ID: "connectivity_ping", Message: "Sandbox my-sandbox can ping 1.1.1.1", Status: model.CheckStatusOK},
+			},
+		},
+		"a failed ping should error with the last output line and the host remediation": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(running, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Exec", mock.Anything, testSandboxID, mock.Anything, mock.Anything).Once().
+					Run(func(args mock.Arguments) {
+						fmt.Fprint(args.Get(3).(model.ExecOpts).Stdout, "PING 8.8.8.8\n1 packets transmitted, 0 received, 100% packet loss\n")
+					}).
+					Return(&model.ExecResult{ExitCode: 1}, nil)
+			},
+			req: netcheck.Request{NameOrID: "my-sandbox", Targets: []string{"8.8.8.8"}},
+			expResults: []model.CheckResult{
+				{
+					ID:          "connectivity_ping",
+					Message:     "Sandbox my-sandbox can't ping 8.8.8.8 (1 packets transmitted, 0 received, 100% packet loss)",
+					Status:      model.CheckStatusError,
+					Remediation: "Check the host IP forwarding (sysctl net.ipv4.ip_forward=1), the nftables sbx table NAT rules and the route of the egress interface",
+				},
+			},
+		},
+		"a failed ping of a sandbox with an egress policy should explain the ICMP rules": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(egress, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Exec", mock.Anything, testSandboxID, mock.Anything, mock.Anything).Once().Return(&model.ExecResult{ExitCode: 1}, nil)
+			},
+			req: netcheck.Request{NameOrID: "my-sandbox", Targets: []string{"github.com"}},
+			expResults: []model.CheckResult{
+				{
+					ID:          "connectivity_ping",
+					Message:     "Sandbox my-sandbox can't ping github.com (no output)",
+					Status:      model.CheckStatusError,
+					Remediation: "The sandbox has an egress policy: it can only ping the addresses of the domains of allow rules with 'icmp: true', resolved through the sandbox DNS first",
+				},
+			},
+		},
+		"a sandbox without ping should warn": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(running, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Exec", mock.Anything, testSandboxID, mock.Anything, mock.Anything).Once().Return(&model.ExecResult{ExitCode: 127}, nil)
+			},
+			req: netcheck.Request{NameOrID: "my-sandbox"},
+			expResults: []model.CheckResult{
+				{
+					ID:          "connectivity_ping",
+					Message:     "Sandbox my-sandbox has no ping command",
+					Status:      model.CheckStatusWarning,
+					Remediation: "Install ping (iputils or busybox) in the sandbox image to check the connectivity",
+				},
+			},
+		},
+		"a flag like target should fail": {
+			mockRepo:   func(m *storagemock.MockRepository) {},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        netcheck.Request{NameOrID: "my-sandbox", Targets: []string{"-f"}},
+			expErrIs:   model.ErrNotValid,
+		},
+		"a stopped sandbox should fail": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{ID: testSandboxID, Name: "my-sandbox", Status: model.SandboxStatusStopped}, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        netcheck.Request{NameOrID: "my-sandbox"},
+			expErrIs:   model.ErrNotValid,
+		},
+		"a missing sandbox should fail": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "missing").Once().Return(nil, model.ErrNotFound)
+				m.On("GetSandbox", mock.Anything, "missing").Once().Return(nil, model.ErrNotFound)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        netcheck.Request{NameOrID: "missing"},
+			expErrIs:   model.ErrNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mRepo := storagemock.NewMockRepository(t)
+			mEngine := sandboxmock.NewMockEngine(t)
+			test.mockRepo(mRepo)
+			test.mockEngine(mEngine)
+
+			svc, err := netcheck.NewService(netcheck.ServiceConfig{
+				Engine:     mEngine,
+				Repository: mRepo,
+				Logger:     log.Noop,
+			})
+			require.NoError(err)
+
+			results, err := svc.Run(context.Background(), test.req)
+
+			switch {
+			case test.expErrIs != nil:
+				assert.ErrorIs(err, test.expErrIs)
+			case test.expErr:
+				assert.Error(err)
+			default:
+				require.NoError(err)
+				assert.Equal(test.expResults, results)
+			}
+		})
+	}
+}
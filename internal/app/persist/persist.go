@@ -0,0 +1,83 @@
+package persist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the persist service.
+type ServiceConfig struct {
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.Persist"})
+
+	return nil
+}
+
+// Service flags the sandboxes started again by the recovery after a host reboot.
+type Service struct {
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new persist service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the persist request parameters.
+type Request struct {
+	// NameOrID is the sandbox name or ID.
+	NameOrID string
+	// Persistent is if the sandbox is started again by the recovery.
+	Persistent bool
+}
+
+// Run sets if a sandbox by name or ID is persistent. It doesn't change the sandbox
+// status.
+func (s *Service) Run(ctx context.Context, req Request) (*model.Sandbox, error) {
+	sb, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
+	if errors.Is(err, model.ErrNotFound) {
+		sb, err = s.repo.GetSandbox(ctx, req.NameOrID)
+	}
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("sandbox not found: %s: %w", req.NameOrID, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not get sandbox: %w", err)
+	}
+
+	if sb.Persistent == req.Persistent {
+		return sb, nil
+	}
+
+	if err := s.repo.UpdateSandboxPersistent(ctx, sb.ID, req.Persistent); err != nil {
+		return nil, fmt.Errorf("could not update sandbox: %w", err)
+	}
+	sb.Persistent = req.Persistent
+	s.logger.Infof("Sandbox %s persistent: %t", sb.Name, sb.Persistent)
+
+	return sb, nil
+}
@@ -0,0 +1,90 @@
+package persist_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/persist"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestServiceRun(t *testing.T) {
+	tests := map[string]struct {
+		mock          func(m *storagemock.MockRepository)
+		req           persist.Request
+		expPersistent bool
+		expErr        error
+	}{
+		"A sandbox should be flagged as persistent.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "sb-1").Once().Return(&model.Sandbox{ID: "id-1", Name: "sb-1"}, nil)
+				m.On("UpdateSandboxPersistent", mock.Anything, "id-1", true).Once().Return(nil)
+			},
+			req:           persist.Request{NameOrID: "sb-1", Persistent: true},
+			expPersistent: true,
+		},
+
+		"A persistent sandbox should be unflagged by ID.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "id-1").Once().Return(nil, model.ErrNotFound)
+				m.On("GetSandbox", mock.Anything, "id-1").Once().Return(&model.Sandbox{ID: "id-1", Name: "sb-1", Persistent: true}, nil)
+				m.On("UpdateSandboxPersistent", mock.Anything, "id-1", false).Once().Return(nil)
+			},
+			req: persist.Request{NameOrID: "id-1"},
+		},
+
+		"Flagging a persistent sandbox should do nothing.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "sb-1").Once().Return(&model.Sandbox{ID: "id-1", Name: "sb-1", Persistent: true}, nil)
+			},
+			req:           persist.Request{NameOrID: "sb-1", Persistent: true},
+			expPersistent: true,
+		},
+
+		"A missing sandbox should fail.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "missing").Once().Return(nil, model.ErrNotFound)
+				m.On("GetSandbox", mock.Anything, "missing").Once().Return(nil, model.ErrNotFound)
+			},
+			req:    persist.Request{NameOrID: "missing", Persistent: true},
+			expErr: model.ErrNotFound,
+		},
+
+		"A repository error should fail.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "sb-1").Once().Return(&model.Sandbox{ID: "id-1", Name: "sb-1"}, nil)
+				m.On("UpdateSandboxPersistent", mock.Anything, "id-1", true).Once().Return(fmt.Errorf("db error: %w", model.ErrConflict))
+			},
+			req:    persist.Request{NameOrID: "sb-1", Persistent: true},
+			expErr: model.ErrConflict,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mRepo := storagemock.NewMockRepository(t)
+			test.mock(mRepo)
+
+			svc, err := persist.NewService(persist.ServiceConfig{Repository: mRepo, Logger: log.Noop})
+			require.NoError(err)
+
+			sb, err := svc.Run(context.Background(), test.req)
+
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expPersistent, sb.Persistent)
+			}
+		})
+	}
+}
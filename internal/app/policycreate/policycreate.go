@@ -0,0 +1,79 @@
+package policycreate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the policy create service.
+type ServiceConfig struct {
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.PolicyCreate"})
+
+	return nil
+}
+
+// Service stores named egress policies.
+type Service struct {
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new policy create service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the policy create request parameters.
+type Request struct {
+	// Name is the policy name used by the sandboxes to reference it.
+	Name string
+	// Policy is the egress policy.
+	Policy model.EgressPolicy
+}
+
+// Run stores a new named egress policy, it fails if a policy with the same name
+// already exists.
+func (s *Service) Run(ctx context.Context, req Request) (*model.NamedEgressPolicy, error) {
+	// The times are stored with second precision.
+	now := time.Now().UTC().Truncate(time.Second)
+	p := model.NamedEgressPolicy{
+		Name:      req.Name,
+		Policy:    req.Policy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy: %w", err)
+	}
+
+	if err := s.repo.CreateEgressPolicy(ctx, p); err != nil {
+		return nil, fmt.Errorf("could not store policy: %w", err)
+	}
+
+	s.logger.Infof("created egress policy: %s", p.Name)
+	return &p, nil
+}
@@ -0,0 +1,79 @@
+package policycreate_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/policycreate"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestServiceRun(t *testing.T) {
+	policy := model.EgressPolicy{
+		Default: model.EgressActionDeny,
+		Rules:   []model.EgressRule{{Domain: "*.github.com", Action: model.EgressActionAllow}},
+	}
+
+	tests := map[string]struct {
+		mock   func(m *storagemock.MockRepository)
+		req    policycreate.Request
+		expErr error
+	}{
+		"Creating a valid policy should store it.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("CreateEgressPolicy", mock.Anything, mock.MatchedBy(func(p model.NamedEgressPolicy) bool {
+					return p.Name == "github" && assert.ObjectsAreEqual(policy, p.Policy) && !p.CreatedAt.IsZero() && p.CreatedAt.Equal(p.UpdatedAt)
+				})).Once().Return(nil)
+			},
+			req: policycreate.Request{Name: "github", Policy: policy},
+		},
+
+		"An existing policy should fail.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("CreateEgressPolicy", mock.Anything, mock.Anything).Once().Return(model.ErrAlreadyExists)
+			},
+			req:    policycreate.Request{Name: "github", Policy: policy},
+			expErr: model.ErrAlreadyExists,
+		},
+
+		"An invalid name should fail.": {
+			mock:   func(m *storagemock.MockRepository) {},
+			req:    policycreate.Request{Name: "git hub", Policy: policy},
+			expErr: model.ErrNotValid,
+		},
+
+		"An invalid policy should fail.": {
+			mock:   func(m *storagemock.MockRepository) {},
+			req:    policycreate.Request{Name: "github", Policy: model.EgressPolicy{Default: "maybe"}},
+			expErr: model.ErrNotValid,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mRepo := &storagemock.MockRepository{}
+			test.mock(mRepo)
+
+			svc, err := policycreate.NewService(policycreate.ServiceConfig{Repository: mRepo})
+			require.NoError(err)
+
+			got, err := svc.Run(context.Background(), test.req)
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+			} else if assert.NoError(err) {
+				assert.Equal(test.req.Name, got.Name)
+				assert.Equal(test.req.Policy, got.Policy)
+			}
+
+			mRepo.AssertExpectations(t)
+		})
+	}
+}
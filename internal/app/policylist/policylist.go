@@ -0,0 +1,58 @@
+package policylist
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the policy list service.
+type ServiceConfig struct {
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.PolicyList"})
+
+	return nil
+}
+
+// Service lists the named egress policies.
+type Service struct {
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new policy list service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Run returns the named egress policies sorted by name.
+func (s *Service) Run(ctx context.Context) ([]model.NamedEgressPolicy, error) {
+	policies, err := s.repo.ListEgressPolicies(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not list egress policies: %w", err)
+	}
+
+	s.logger.Debugf("found %d egress policies", len(policies))
+	return policies, nil
+}
@@ -0,0 +1,64 @@
+package policylist_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/policylist"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestServiceRun(t *testing.T) {
+	policies := []model.NamedEgressPolicy{
+		{Name: "allow-all", Policy: model.EgressPolicy{Default: model.EgressActionAllow}},
+		{Name: "github", Policy: model.EgressPolicy{Default: model.EgressActionDeny}},
+	}
+
+	tests := map[string]struct {
+		mock        func(m *storagemock.MockRepository)
+		expPolicies []model.NamedEgressPolicy
+		expErr      bool
+	}{
+		"Listing should return the stored policies.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("ListEgressPolicies", mock.Anything).Once().Return(policies, nil)
+			},
+			expPolicies: policies,
+		},
+
+		"A repository error should fail.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("ListEgressPolicies", mock.Anything).Once().Return(nil, fmt.Errorf("something"))
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mRepo := &storagemock.MockRepository{}
+			test.mock(mRepo)
+
+			svc, err := policylist.NewService(policylist.ServiceConfig{Repository: mRepo})
+			require.NoError(err)
+
+			got, err := svc.Run(context.Background())
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expPolicies, got)
+			}
+
+			mRepo.AssertExpectations(t)
+		})
+	}
+}
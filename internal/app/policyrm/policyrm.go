@@ -0,0 +1,63 @@
+package policyrm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the policy remove service.
+type ServiceConfig struct {
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.PolicyRm"})
+
+	return nil
+}
+
+// Service removes named egress policies.
+type Service struct {
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new policy remove service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the policy remove request parameters.
+type Request struct {
+	// Name is the name of the policy to remove.
+	Name string
+}
+
+// Run removes a named egress policy. The running sandboxes that use it keep their
+// policy, their next start fails until they use another one.
+func (s *Service) Run(ctx context.Context, req Request) error {
+	if err := s.repo.DeleteEgressPolicy(ctx, req.Name); err != nil {
+		return fmt.Errorf("could not delete policy: %w", err)
+	}
+
+	s.logger.Infof("removed egress policy: %s", req.Name)
+	return nil
+}
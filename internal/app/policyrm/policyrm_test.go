@@ -0,0 +1,59 @@
+package policyrm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/policyrm"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestServiceRun(t *testing.T) {
+	tests := map[string]struct {
+		mock   func(m *storagemock.MockRepository)
+		req    policyrm.Request
+		expErr error
+	}{
+		"Removing a policy should delete it.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("DeleteEgressPolicy", mock.Anything, "github").Once().Return(nil)
+			},
+			req: policyrm.Request{Name: "github"},
+		},
+
+		"A missing policy should fail.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("DeleteEgressPolicy", mock.Anything, "github").Once().Return(model.ErrNotFound)
+			},
+			req:    policyrm.Request{Name: "github"},
+			expErr: model.ErrNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mRepo := &storagemock.MockRepository{}
+			test.mock(mRepo)
+
+			svc, err := policyrm.NewService(policyrm.ServiceConfig{Repository: mRepo})
+			require.NoError(err)
+
+			err = svc.Run(context.Background(), test.req)
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+			} else {
+				assert.NoError(err)
+			}
+
+			mRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package policyupdate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the policy update service.
+type ServiceConfig struct {
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.PolicyUpdate"})
+
+	return nil
+}
+
+// Service replaces the rules of named egress policies.
+type Service struct {
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new policy update service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the policy update request parameters.
+type Request struct {
+	// Name is the name of the policy to update.
+	Name string
+	// Policy is the new egress policy.
+	Policy model.EgressPolicy
+}
+
+// Run replaces the egress policy of a named policy. The sandboxes using it get
+// the new policy on their next start.
+func (s *Service) Run(ctx context.Context, req Request) (*model.NamedEgressPolicy, error) {
+	p := model.NamedEgressPolicy{Name: req.Name, Policy: req.Policy}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy: %w", err)
+	}
+
+	stored, err := s.repo.GetEgressPolicy(ctx, req.Name)
+	if err != nil {
+		return nil, fmt.Errorf("could not get policy: %w", err)
+	}
+
+	stored.Policy = req.Policy
+	stored.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+	if err := s.repo.UpdateEgressPolicy(ctx, *stored); err != nil {
+		return nil, fmt.Errorf("could not store policy: %w", err)
+	}
+
+	s.logger.Infof("updated egress policy: %s", stored.Name)
+	return stored, nil
+}
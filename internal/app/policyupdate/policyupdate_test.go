@@ -0,0 +1,81 @@
+package policyupdate_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/policyupdate"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestServiceRun(t *testing.T) {
+	createdAt := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	policy := model.EgressPolicy{
+		Default: model.EgressActionDeny,
+		Rules:   []model.EgressRule{{Domain: "*.github.com", Action: model.EgressActionAllow}},
+	}
+
+	tests := map[string]struct {
+		mock   func(m *storagemock.MockRepository)
+		req    policyupdate.Request
+		expErr error
+	}{
+		"Updating a policy should replace its rules and keep its creation time.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("GetEgressPolicy", mock.Anything, "github").Once().Return(&model.NamedEgressPolicy{
+					Name:      "github",
+					Policy:    model.EgressPolicy{Default: model.EgressActionAllow},
+					CreatedAt: createdAt,
+					UpdatedAt: createdAt,
+				}, nil)
+				m.On("UpdateEgressPolicy", mock.Anything, mock.MatchedBy(func(p model.NamedEgressPolicy) bool {
+					return p.Name == "github" && assert.ObjectsAreEqual(policy, p.Policy) && p.CreatedAt.Equal(createdAt) && !p.UpdatedAt.Before(createdAt)
+				})).Once().Return(nil)
+			},
+			req: policyupdate.Request{Name: "github", Policy: policy},
+		},
+
+		"A missing policy should fail.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("GetEgressPolicy", mock.Anything, "github").Once().Return(nil, model.ErrNotFound)
+			},
+			req:    policyupdate.Request{Name: "github", Policy: policy},
+			expErr: model.ErrNotFound,
+		},
+
+		"An invalid policy should fail.": {
+			mock:   func(m *storagemock.MockRepository) {},
+			req:    policyupdate.Request{Name: "github", Policy: model.EgressPolicy{Default: model.EgressActionDeny, Rules: []model.EgressRule{{Action: model.EgressActionAllow}}}},
+			expErr: model.ErrNotValid,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mRepo := &storagemock.MockRepository{}
+			test.mock(mRepo)
+
+			svc, err := policyupdate.NewService(policyupdate.ServiceConfig{Repository: mRepo})
+			require.NoError(err)
+
+			got, err := svc.Run(context.Background(), test.req)
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+			} else if assert.NoError(err) {
+				assert.Equal(test.req.Policy, got.Policy)
+				assert.Equal(createdAt, got.CreatedAt)
+			}
+
+			mRepo.AssertExpectations(t)
+		})
+	}
+}
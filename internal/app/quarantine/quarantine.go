@@ -0,0 +1,137 @@
+package quarantine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the quarantine service.
+type ServiceConfig struct {
+	Engine     sandbox.Engine
+	Repository storage.Repository
+	// Locker serializes the sandbox operations. Defaults to the repository when it
+	// implements it, otherwise the operations are not serialized.
+	Locker storage.SandboxLocker
+	Logger log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Engine == nil {
+		return fmt.Errorf("engine is required")
+	}
+
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Locker == nil {
+		c.Locker, _ = c.Repository.(storage.SandboxLocker)
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.Quarantine"})
+
+	return nil
+}
+
+// Service quarantines sandboxes: their network access and port forwards are cut
+// without stopping them, so their live state can be investigated.
+type Service struct {
+	engine sandbox.Engine
+	repo   storage.Repository
+	locker storage.SandboxLocker
+	logger log.Logger
+}
+
+// NewService creates a new quarantine service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		engine: cfg.Engine,
+		repo:   cfg.Repository,
+		locker: cfg.Locker,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the quarantine request parameters.
+type Request struct {
+	// NameOrID is the sandbox name or ID.
+	NameOrID string
+	// Release lifts the quarantine instead of applying it.
+	Release bool
+}
+
+// Run quarantines a sandbox by name or ID, or lifts its quarantine. A running
+// sandbox is isolated right away, a stopped one when it starts. Quarantining a
+// quarantined sandbox (or releasing a released one) does nothing.
+func (s *Service) Run(ctx context.Context, req Request) (*model.Sandbox, error) {
+	sb, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
+	if errors.Is(err, model.ErrNotFound) {
+		sb, err = s.repo.GetSandbox(ctx, req.NameOrID)
+	}
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("sandbox not found: %s: %w", req.NameOrID, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not get sandbox: %w", err)
+	}
+
+	// Serialize the operations on the sandbox, the sandbox is read again once locked
+	// so a concurrent start sees the quarantine.
+	if s.locker != nil {
+		unlock, err := s.locker.LockSandbox(ctx, sb.ID)
+		if err != nil {
+			return nil, fmt.Errorf("could not lock sandbox: %w", err)
+		}
+		defer unlock()
+
+		sb, err = s.repo.GetSandbox(ctx, sb.ID)
+		if err != nil {
+			return nil, fmt.Errorf("could not get sandbox: %w", err)
+		}
+	}
+
+	quarantined := sb.QuarantinedAt != nil
+	if quarantined == !req.Release {
+		return sb, nil
+	}
+
+	// The network is cut before the sandbox is flagged, so a quarantined sandbox is
+	// never reachable, the port forwards stop once they see the flag.
+	if sb.Status == model.SandboxStatusRunning {
+		if err := s.engine.Quarantine(ctx, sb.ID, !req.Release); err != nil {
+			return nil, fmt.Errorf("could not update sandbox network: %w", err)
+		}
+	}
+
+	var at *time.Time
+	if !req.Release {
+		// The repository stores the time in seconds.
+		now := time.Now().UTC().Truncate(time.Second)
+		at = &now
+	}
+	if err := s.repo.UpdateSandboxQuarantine(ctx, sb.ID, at); err != nil {
+		return nil, fmt.Errorf("could not update sandbox quarantine: %w", err)
+	}
+	sb.QuarantinedAt = at
+
+	if req.Release {
+		s.logger.Infof("released sandbox %s (ID: %s) from quarantine", sb.Name, sb.ID)
+	} else {
+		s.logger.Infof("quarantined sandbox %s (ID: %s)", sb.Name, sb.ID)
+	}
+	return sb, nil
+}
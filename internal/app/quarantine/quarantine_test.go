@@ -0,0 +1,118 @@
+package quarantine_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/quarantine"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox/sandboxmock"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestServiceRun(t *testing.T) {
+	errTest := errors.New("whatever")
+	t0 := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	running := func() *model.Sandbox {
+		return &model.Sandbox{ID: "sb-id", Name: "my-sandbox", Status: model.SandboxStatusRunning}
+	}
+	quarantined := func() *model.Sandbox {
+		sb := running()
+		sb.QuarantinedAt = &t0
+		return sb
+	}
+
+	tests := map[string]struct {
+		mock           func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine)
+		req            quarantine.Request
+		expQuarantined bool
+		expErr         error
+	}{
+		"A running sandbox should be isolated and flagged.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(running(), nil)
+				me.On("Quarantine", mock.Anything, "sb-id", true).Once().Return(nil)
+				mr.On("UpdateSandboxQuarantine", mock.Anything, "sb-id", mock.MatchedBy(func(at *time.Time) bool { return at != nil })).Once().Return(nil)
+			},
+			req:            quarantine.Request{NameOrID: "my-sandbox"},
+			expQuarantined: true,
+		},
+
+		"A stopped sandbox should only be flagged.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "sb-id").Once().Return(nil, model.ErrNotFound)
+				mr.On("GetSandbox", mock.Anything, "sb-id").Once().Return(&model.Sandbox{ID: "sb-id", Status: model.SandboxStatusStopped}, nil)
+				mr.On("UpdateSandboxQuarantine", mock.Anything, "sb-id", mock.Anything).Once().Return(nil)
+			},
+			req:            quarantine.Request{NameOrID: "sb-id"},
+			expQuarantined: true,
+		},
+
+		"A quarantined sandbox should not be quarantined again.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(quarantined(), nil)
+			},
+			req:            quarantine.Request{NameOrID: "my-sandbox"},
+			expQuarantined: true,
+		},
+
+		"Releasing a quarantined sandbox should restore its network and clear the flag.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(quarantined(), nil)
+				me.On("Quarantine", mock.Anything, "sb-id", false).Once().Return(nil)
+				mr.On("UpdateSandboxQuarantine", mock.Anything, "sb-id", (*time.Time)(nil)).Once().Return(nil)
+			},
+			req: quarantine.Request{NameOrID: "my-sandbox", Release: true},
+		},
+
+		"Releasing a sandbox not quarantined should do nothing.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(running(), nil)
+			},
+			req: quarantine.Request{NameOrID: "my-sandbox", Release: true},
+		},
+
+		"An engine error should fail without flagging the sandbox.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(running(), nil)
+				me.On("Quarantine", mock.Anything, "sb-id", true).Once().Return(errTest)
+			},
+			req:    quarantine.Request{NameOrID: "my-sandbox"},
+			expErr: errTest,
+		},
+
+		"A missing sandbox should fail.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "missing").Once().Return(nil, model.ErrNotFound)
+				mr.On("GetSandbox", mock.Anything, "missing").Once().Return(nil, model.ErrNotFound)
+			},
+			req:    quarantine.Request{NameOrID: "missing"},
+			expErr: model.ErrNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mr := storagemock.NewMockRepository(t)
+			me := sandboxmock.NewMockEngine(t)
+			test.mock(mr, me)
+
+			svc, err := quarantine.NewService(quarantine.ServiceConfig{Engine: me, Repository: mr})
+			require.NoError(t, err)
+
+			sb, err := svc.Run(context.Background(), test.req)
+			if test.expErr != nil {
+				assert.ErrorIs(t, err, test.expErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expQuarantined, sb.QuarantinedAt != nil)
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package rebase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the rebase service.
+type ServiceConfig struct {
+	Engine     sandbox.Engine
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Engine == nil {
+		return fmt.Errorf("engine is required")
+	}
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.Rebase"})
+	return nil
+}
+
+// Service moves stopped sandboxes to a new base image.
+type Service struct {
+	engine sandbox.Engine
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new rebase service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &Service{
+		engine: cfg.Engine,
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents a rebase request.
+type Request struct {
+	// NameOrID is the sandbox name or ID.
+	NameOrID string
+	// Image is the new image name, empty when the paths are not from an image.
+	Image string
+	// RootFS is the new base rootfs path.
+	RootFS string
+	// KernelImage is the new kernel path, empty keeps the current kernel.
+	KernelImage string
+	// PreservePaths are the absolute paths copied from the sandbox rootfs into the
+	// new one (e.g. /root, /home).
+	PreservePaths []string
+	// Progress receives the rebase steps (optional).
+	Progress model.ProgressFunc
+}
+
+// Run replaces the rootfs of a stopped sandbox with the new base rootfs, keeping the
+// preserved paths, and stores the new image in the sandbox configuration. The user
+// data is not executed again, the sandbox has already been provisioned.
+func (s *Service) Run(ctx context.Context, req Request) (*model.Sandbox, error) {
+	if req.RootFS == "" {
+		return nil, fmt.Errorf("rootfs is required: %w", model.ErrNotValid)
+	}
+	preserve, err := cleanPreservePaths(req.PreservePaths)
+	if err != nil {
+		return nil, err
+	}
+
+	sb, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
+	if err != nil {
+		// Try by ID if name lookup failed
+		if errors.Is(err, model.ErrNotFound) {
+			sb, err = s.repo.GetSandbox(ctx, req.NameOrID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not find sandbox: %w", err)
+		}
+	}
+
+	if sb.Status != model.SandboxStatusStopped {
+		return nil, fmt.Errorf("cannot rebase sandbox in status %q (must be stopped): %w", sb.Status, model.ErrNotValid)
+	}
+	if sb.Config.FirecrackerEngine == nil {
+		return nil, fmt.Errorf("sandbox has no engine configuration: %w", model.ErrNotValid)
+	}
+
+	cfg := sb.Config
+	fcCfg := *sb.Config.FirecrackerEngine
+	fcCfg.RootFS = req.RootFS
+	if req.KernelImage != "" {
+		fcCfg.KernelImage = req.KernelImage
+	}
+	cfg.FirecrackerEngine = &fcCfg
+	cfg.Image = req.Image
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	err = s.engine.Rebase(ctx, sb.ID, cfg, sandbox.RebaseOpts{
+		PreservePaths: preserve,
+		Progress:      req.Progress,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not rebase sandbox: %w", err)
+	}
+
+	sb.Config = cfg
+	if err := s.repo.UpdateSandbox(ctx, *sb); err != nil {
+		return nil, fmt.Errorf("could not update sandbox: %w", err)
+	}
+
+	s.logger.Infof("Rebased sandbox %s (%s) on %s", sb.Name, sb.ID, req.RootFS)
+	return sb, nil
+}
+
+// cleanPreservePaths validates the preserved paths and returns them cleaned, without
+// duplicates and without the paths inside another preserved path.
+func cleanPreservePaths(paths []string) ([]string, error) {
+	cleaned := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if !path.IsAbs(p) {
+			return nil, fmt.Errorf("preserved path %q must be absolute: %w", p, model.ErrNotValid)
+		}
+		if strings.ContainsAny(p, "\"\n") {
+			return nil, fmt.Errorf("preserved path %q has invalid characters: %w", p, model.ErrNotValid)
+		}
+		p = path.Clean(p)
+		if p == "/" {
+			return nil, fmt.Errorf("the root directory can't be preserved, rebase would keep the whole rootfs: %w", model.ErrNotValid)
+		}
+		cleaned = append(cleaned, p)
+	}
+
+	var res []string
+	for i, p := range cleaned {
+		nested := false
+		for j, o := range cleaned {
+			if (i != j && strings.HasPrefix(p, o+"/")) || (j < i && p == o) {
+				nested = true
+				break
+			}
+		}
+		if !nested {
+			res = append(res, p)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,233 @@
+package rebase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/rebase"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/sandbox/sandboxmock"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestNewService(t *testing.T) {
+	tests := map[string]struct {
+		config rebase.ServiceConfig
+		expErr bool
+	}{
+		"valid config should create service": {
+			config: rebase.ServiceConfig{
+				Engine:     &sandboxmock.MockEngine{},
+				Repository: &storagemock.MockRepository{},
+			},
+		},
+		"missing engine should fail": {
+			config: rebase.ServiceConfig{
+				Repository: &storagemock.MockRepository{},
+			},
+			expErr: true,
+		},
+		"missing repository should fail": {
+			config: rebase.ServiceConfig{
+				Engine: &sandboxmock.MockEngine{},
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			svc, err := rebase.NewService(test.config)
+
+			if test.expErr {
+				require.Error(err)
+				require.Nil(svc)
+			} else {
+				require.NoError(err)
+				require.NotNil(svc)
+			}
+		})
+	}
+}
+
+func TestServiceRun(t *testing.T) {
+	errTest := errors.New("whatever")
+
+	sb := func(status model.SandboxStatus) *model.Sandbox {
+		return &model.Sandbox{
+			ID:     "01SB0000000000000000000000",
+			Name:   "sb",
+			Status: status,
+			Config: model.SandboxConfig{
+				Name:  "sb",
+				Image: "v0.1.0",
+				FirecrackerEngine: &model.FirecrackerEngineConfig{
+					RootFS:      "/images/v0.1.0/rootfs.ext4",
+					KernelImage: "/images/v0.1.0/vmlinux",
+					KernelArgs:  []string{"quiet"},
+				},
+				Resources: model.Resources{VCPUs: 2, MemoryMB: 1024, DiskGB: 10},
+				UserData:  "apk add git\n",
+			},
+		}
+	}
+	rebasedConfig := model.SandboxConfig{
+		Name:  "sb",
+		Image: "v0.2.0",
+		FirecrackerEngine: &model.FirecrackerEngineConfig{
+			RootFS:      "/images/v0.2.0/rootfs.ext4",
+			KernelImage: "/images/v0.2.0/vmlinux",
+			KernelArgs:  []string{"quiet"},
+		},
+		Resources: model.Resources{VCPUs: 2, MemoryMB: 1024, DiskGB: 10},
+		UserData:  "apk add git\n",
+	}
+	imageReq := rebase.Request{
+		NameOrID:    "sb",
+		Image:       "v0.2.0",
+		RootFS:      "/images/v0.2.0/rootfs.ext4",
+		KernelImage: "/images/v0.2.0/vmlinux",
+	}
+
+	tests := map[string]struct {
+		mock      func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine)
+		req       rebase.Request
+		expConfig model.SandboxConfig
+		expErr    error
+	}{
+		"Rebasing a stopped sandbox should replace its image and keep the rest of the config.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "sb").Once().Return(sb(model.SandboxStatusStopped), nil)
+				me.On("Rebase", mock.Anything, "01SB0000000000000000000000", rebasedConfig, sandbox.RebaseOpts{}).Once().Return(nil)
+				mr.On("UpdateSandbox", mock.Anything, mock.MatchedBy(func(s model.Sandbox) bool {
+					return s.Config.Image == "v0.2.0"
+				})).Once().Return(nil)
+			},
+			req:       imageReq,
+			expConfig: rebasedConfig,
+		},
+
+		"Rebasing without a kernel should keep the current kernel.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "sb").Once().Return(sb(model.SandboxStatusStopped), nil)
+				me.On("Rebase", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Once().Return(nil)
+				mr.On("UpdateSandbox", mock.Anything, mock.Anything).Once().Return(nil)
+			},
+			req: rebase.Request{NameOrID: "sb", RootFS: "/tmp/rootfs.ext4"},
+			expConfig: model.SandboxConfig{
+				Name: "sb",
+				FirecrackerEngine: &model.FirecrackerEngineConfig{
+					RootFS:      "/tmp/rootfs.ext4",
+					KernelImage: "/images/v0.1.0/vmlinux",
+					KernelArgs:  []string{"quiet"},
+				},
+				Resources: model.Resources{VCPUs: 2, MemoryMB: 1024, DiskGB: 10},
+				UserData:  "apk add git\n",
+			},
+		},
+
+		"Rebasing with preserved paths should clean them and drop the nested ones.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "sb").Once().Return(sb(model.SandboxStatusStopped), nil)
+				me.On("Rebase", mock.Anything, mock.Anything, mock.Anything, sandbox.RebaseOpts{PreservePaths: []string{"/root", "/home"}}).Once().Return(nil)
+				mr.On("UpdateSandbox", mock.Anything, mock.Anything).Once().Return(nil)
+			},
+			req: rebase.Request{
+				NameOrID:      "sb",
+				Image:         "v0.2.0",
+				RootFS:        "/images/v0.2.0/rootfs.ext4",
+				KernelImage:   "/images/v0.2.0/vmlinux",
+				PreservePaths: []string{"/root/", "/home", "/home/user/.cache", "/root"},
+			},
+			expConfig: rebasedConfig,
+		},
+
+		"Rebasing with a relative preserved path should fail.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {},
+			req: rebase.Request{
+				NameOrID:      "sb",
+				RootFS:        "/images/v0.2.0/rootfs.ext4",
+				PreservePaths: []string{"root"},
+			},
+			expErr: model.ErrNotValid,
+		},
+
+		"Rebasing preserving the root directory should fail.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {},
+			req: rebase.Request{
+				NameOrID:      "sb",
+				RootFS:        "/images/v0.2.0/rootfs.ext4",
+				PreservePaths: []string{"/root/.."},
+			},
+			expErr: model.ErrNotValid,
+		},
+
+		"Rebasing without a rootfs should fail.": {
+			mock:   func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {},
+			req:    rebase.Request{NameOrID: "sb"},
+			expErr: model.ErrNotValid,
+		},
+
+		"Rebasing a running sandbox should fail.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "sb").Once().Return(sb(model.SandboxStatusRunning), nil)
+			},
+			req:    imageReq,
+			expErr: model.ErrNotValid,
+		},
+
+		"Rebasing a missing sandbox should fail.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "sb").Once().Return(nil, model.ErrNotFound)
+				mr.On("GetSandbox", mock.Anything, "sb").Once().Return(nil, model.ErrNotFound)
+			},
+			req:    imageReq,
+			expErr: model.ErrNotFound,
+		},
+
+		"An engine error should fail without updating the sandbox.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "sb").Once().Return(sb(model.SandboxStatusStopped), nil)
+				me.On("Rebase", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Once().Return(errTest)
+			},
+			req:    imageReq,
+			expErr: errTest,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mr := &storagemock.MockRepository{}
+			me := &sandboxmock.MockEngine{}
+			test.mock(mr, me)
+
+			svc, err := rebase.NewService(rebase.ServiceConfig{
+				Engine:     me,
+				Repository: mr,
+			})
+			require.NoError(err)
+
+			sb, err := svc.Run(context.TODO(), test.req)
+
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expConfig, sb.Config)
+			}
+
+			mr.AssertExpectations(t)
+			me.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package recovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/slok/sbx/internal/app/start"
+	"github.com/slok/sbx/internal/capacity"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the recovery service.
+type ServiceConfig struct {
+	Engine     sandbox.Engine
+	Repository storage.Repository
+	// Locker serializes the sandbox operations. Defaults to the repository when it
+	// implements it, otherwise the operations are not serialized.
+	Locker storage.SandboxLocker
+	// Admission checks the host capacity before starting the persistent sandboxes
+	// again (optional).
+	Admission *capacity.Planner
+	Logger    log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Engine == nil {
+		return fmt.Errorf("engine is required")
+	}
+
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Locker == nil {
+		c.Locker, _ = c.Repository.(storage.SandboxLocker)
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.Recovery"})
+
+	return nil
+}
+
+// Service recovers the sandboxes whose VM died while running (e.g. the host
+// rebooted): they are flagged as stopped and the persistent ones are started again.
+type Service struct {
+	engine sandbox.Engine
+	repo   storage.Repository
+	locker storage.SandboxLocker
+	start  *start.Service
+	logger log.Logger
+}
+
+// NewService creates a new recovery service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	// The recovery holds the sandbox lock for the start, the lock is not reentrant
+	// so the start doesn't take it again.
+	startSvc, err := start.NewService(start.ServiceConfig{
+		Engine:     cfg.Engine,
+		Repository: cfg.Repository,
+		Locker:     heldLocker{},
+		Admission:  cfg.Admission,
+		Logger:     cfg.Logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create start service: %w", err)
+	}
+
+	return &Service{
+		engine: cfg.Engine,
+		repo:   cfg.Repository,
+		locker: cfg.Locker,
+		start:  startSvc,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the recovery request parameters.
+type Request struct {
+	// NameOrID is the sandbox name or ID.
+	NameOrID string
+	// Timeouts are the optional start step timeouts, the unset ones use the engine defaults.
+	Timeouts model.StartTimeouts
+	// Progress receives the start steps (optional).
+	Progress model.ProgressFunc
+}
+
+// Run recovers a sandbox by name or ID. A sandbox flagged as running whose VM is
+// not running anymore is flagged as stopped, and a persistent one is started again
+// with the session of its previous start, recreating its network. The sandboxes
+// that are alive or not running are left as they are.
+//
+// A failed start is not an error, the sandbox is left stopped and the result has
+// the start error.
+func (s *Service) Run(ctx context.Context, req Request) (*model.RecoveryResult, error) {
+	if err := req.Timeouts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid start timeouts: %w", err)
+	}
+
+	sb, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
+	if errors.Is(err, model.ErrNotFound) {
+		sb, err = s.repo.GetSandbox(ctx, req.NameOrID)
+	}
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("sandbox not found: %s: %w", req.NameOrID, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not get sandbox: %w", err)
+	}
+
+	// Serialize the operations on the sandbox, the sandbox is read again once locked
+	// so a sandbox stopped meanwhile is not started again.
+	if s.locker != nil {
+		unlock, err := s.locker.LockSandbox(ctx, sb.ID)
+		if err != nil {
+			return nil, fmt.Errorf("could not lock sandbox: %w", err)
+		}
+		defer unlock()
+
+		sb, err = s.repo.GetSandbox(ctx, sb.ID)
+		if err != nil {
+			return nil, fmt.Errorf("could not get sandbox: %w", err)
+		}
+	}
+
+	result := &model.RecoveryResult{
+		SandboxID:  sb.ID,
+		Name:       sb.Name,
+		Namespace:  sb.Namespace,
+		Persistent: sb.Persistent,
+		Action:     model.RecoveryActionNone,
+	}
+	if sb.Status != model.SandboxStatusRunning {
+		return result, nil
+	}
+
+	alive, err := s.alive(ctx, sb.ID)
+	if err != nil {
+		return nil, err
+	}
+	if alive {
+		return result, nil
+	}
+
+	// The VM is gone with its network, flag the sandbox as stopped like a stop does.
+	// The run is accounted until now, the time the VM died is unknown.
+	s.logger.Warningf("Sandbox %s was running but its VM is dead, flagging it as stopped", sb.Name)
+	now := time.Now().UTC()
+	usage := model.SandboxUsageRecords(*sb, now)
+	sb.Status = model.SandboxStatusStopped
+	sb.StoppedAt = &now
+	if err := s.repo.UpdateSandbox(ctx, *sb); err != nil {
+		return nil, fmt.Errorf("could not update sandbox: %w", err)
+	}
+	for _, rec := range usage {
+		if rec.Kind != model.UsageKindRun {
+			continue
+		}
+		if err := s.repo.CreateUsageRecord(ctx, rec); err != nil {
+			s.logger.Warningf("could not store sandbox %s usage: %v", sb.ID, err)
+		}
+	}
+	result.Action = model.RecoveryActionStopped
+
+	if !sb.Persistent {
+		return result, nil
+	}
+
+	// The start recreates the sandbox network (TAP device, firewall rules) and
+	// applies the session of the previous start.
+	if _, err := s.start.Run(ctx, start.Request{
+		NameOrID: sb.ID,
+		Timeouts: req.Timeouts,
+		Progress: req.Progress,
+	}); err != nil {
+		s.logger.Errorf("Could not start persistent sandbox %s again: %v", sb.Name, err)
+		result.Action = model.RecoveryActionFailed
+		result.Error = err.Error()
+		return result, nil
+	}
+	result.Action = model.RecoveryActionRestarted
+	s.logger.Infof("Persistent sandbox %s started again", sb.Name)
+
+	return result, nil
+}
+
+// alive returns if the VM of a sandbox flagged as running is running, a sandbox
+// unknown to the engine is dead.
+func (s *Service) alive(ctx context.Context, id string) (bool, error) {
+	status, err := s.engine.Status(ctx, id)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("could not get sandbox engine status: %w", err)
+	}
+	return status.Status == model.SandboxStatusRunning, nil
+}
+
+// heldLocker is the locker of the operations run while the recovery holds the
+// sandbox lock.
+type heldLocker struct{}
+
+func (heldLocker) LockSandbox(ctx context.Context, id string) (func(), error) {
+	return func() {}, nil
+}
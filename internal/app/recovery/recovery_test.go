@@ -0,0 +1,153 @@
+package recovery_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/recovery"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/sandbox/sandboxmock"
+	"github.com/slok/sbx/internal/storage/memory"
+)
+
+const testSandboxID = "01H2QWERTYASDFGZXCVBNMLKJH"
+
+var errEngine = errors.New("invalid PID file")
+
+func TestServiceRun(t *testing.T) {
+	session := &model.SessionConfig{
+		Env:    map[string]string{"FOO": "bar"},
+		Egress: &model.EgressPolicy{Default: model.EgressActionDeny, Rules: []model.EgressRule{{Domain: "github.com", Action: model.EgressActionAllow}}},
+	}
+
+	tests := map[string]struct {
+		sandbox    model.Sandbox
+		mockEngine func(m *sandboxmock.MockEngine)
+		req        recovery.Request
+		expAction  model.RecoveryAction
+		expError   string
+		expStatus  model.SandboxStatus
+		expErr     error
+	}{
+		"A running sandbox with its VM alive should be left as it is.": {
+			sandbox: model.Sandbox{Status: model.SandboxStatusRunning, Persistent: true},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Status", mock.Anything, testSandboxID).Once().Return(&model.Sandbox{Status: model.SandboxStatusRunning}, nil)
+			},
+			req:       recovery.Request{NameOrID: "my-sandbox"},
+			expAction: model.RecoveryActionNone,
+			expStatus: model.SandboxStatusRunning,
+		},
+
+		"A stopped persistent sandbox should be left as it is.": {
+			sandbox:    model.Sandbox{Status: model.SandboxStatusStopped, Persistent: true},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        recovery.Request{NameOrID: "my-sandbox"},
+			expAction:  model.RecoveryActionNone,
+			expStatus:  model.SandboxStatusStopped,
+		},
+
+		"A running sandbox with its VM dead should be flagged as stopped.": {
+			sandbox: model.Sandbox{Status: model.SandboxStatusRunning},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Status", mock.Anything, testSandboxID).Once().Return(&model.Sandbox{Status: model.SandboxStatusStopped}, nil)
+			},
+			req:       recovery.Request{NameOrID: "my-sandbox"},
+			expAction: model.RecoveryActionStopped,
+			expStatus: model.SandboxStatusStopped,
+		},
+
+		"A persistent sandbox with its VM dead should be started again with its previous session.": {
+			sandbox: model.Sandbox{Status: model.SandboxStatusRunning, Persistent: true, Session: session},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Status", mock.Anything, testSandboxID).Once().Return(nil, fmt.Errorf("sandbox %s: %w", testSandboxID, model.ErrNotFound))
+				m.On("Start", mock.Anything, testSandboxID, sandbox.StartOpts{Egress: session.Egress}).Once().Return(nil, nil)
+				m.On("Exec", mock.Anything, testSandboxID, mock.Anything, mock.Anything).Return(&model.ExecResult{}, nil)
+				m.On("CopyTo", mock.Anything, testSandboxID, mock.Anything, mock.Anything, model.CopyOpts{}).Return(nil)
+			},
+			req:       recovery.Request{NameOrID: testSandboxID},
+			expAction: model.RecoveryActionRestarted,
+			expStatus: model.SandboxStatusRunning,
+		},
+
+		"A persistent sandbox failing to start again should be left stopped with the error.": {
+			sandbox: model.Sandbox{Status: model.SandboxStatusRunning, Persistent: true},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Status", mock.Anything, testSandboxID).Once().Return(&model.Sandbox{Status: model.SandboxStatusStopped}, nil)
+				m.On("Start", mock.Anything, testSandboxID, mock.Anything).Once().Return(nil, fmt.Errorf("kernel not found"))
+			},
+			req:       recovery.Request{NameOrID: "my-sandbox"},
+			expAction: model.RecoveryActionFailed,
+			expError:  "could not start sandbox: kernel not found",
+			expStatus: model.SandboxStatusStopped,
+		},
+
+		"An engine status error should fail.": {
+			sandbox: model.Sandbox{Status: model.SandboxStatusRunning},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Status", mock.Anything, testSandboxID).Once().Return(nil, errEngine)
+			},
+			req:       recovery.Request{NameOrID: "my-sandbox"},
+			expStatus: model.SandboxStatusRunning,
+			expErr:    errEngine,
+		},
+
+		"A missing sandbox should fail.": {
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        recovery.Request{NameOrID: "missing"},
+			expErr:     model.ErrNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			ctx := context.Background()
+
+			repo, err := memory.NewRepository(memory.RepositoryConfig{})
+			require.NoError(err)
+			if test.sandbox.Status != "" {
+				sb := test.sandbox
+				sb.ID = testSandboxID
+				sb.Name = "my-sandbox"
+				sb.CreatedAt = time.Now()
+				started := time.Now().Add(-time.Hour)
+				sb.StartedAt = &started
+				sb.Config = model.SandboxConfig{Name: "my-sandbox", FirecrackerEngine: &model.FirecrackerEngineConfig{}}
+				require.NoError(repo.CreateSandbox(ctx, sb))
+			}
+
+			mEngine := sandboxmock.NewMockEngine(t)
+			test.mockEngine(mEngine)
+
+			svc, err := recovery.NewService(recovery.ServiceConfig{Engine: mEngine, Repository: repo, Logger: log.Noop})
+			require.NoError(err)
+
+			result, err := svc.Run(ctx, test.req)
+
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expAction, result.Action)
+				assert.Equal(test.expError, result.Error)
+				assert.Equal(test.sandbox.Persistent, result.Persistent)
+			}
+
+			if test.sandbox.Status != "" {
+				got, err := repo.GetSandbox(ctx, testSandboxID)
+				require.NoError(err)
+				assert.Equal(test.expStatus, got.Status)
+			}
+		})
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/slok/sbx/internal/hooks"
 	"github.com/slok/sbx/internal/log"
 	"github.com/slok/sbx/internal/model"
 	"github.com/slok/sbx/internal/sandbox"
@@ -15,7 +17,13 @@ import (
 type ServiceConfig struct {
 	Engine     sandbox.Engine
 	Repository storage.Repository
-	Logger     log.Logger
+	// Locker serializes the sandbox operations. Defaults to the repository when it
+	// implements it, otherwise the operations are not serialized.
+	Locker storage.SandboxLocker
+	// Hooks runs the pre-stop hooks of the forced stops and the post-remove host
+	// hooks (optional).
+	Hooks  hooks.Runner
+	Logger log.Logger
 }
 
 func (c *ServiceConfig) defaults() error {
@@ -27,6 +35,14 @@ func (c *ServiceConfig) defaults() error {
 		return fmt.Errorf("repository is required")
 	}
 
+	if c.Locker == nil {
+		c.Locker, _ = c.Repository.(storage.SandboxLocker)
+	}
+
+	if c.Hooks == nil {
+		c.Hooks = hooks.Noop
+	}
+
 	if c.Logger == nil {
 		c.Logger = log.Noop
 	}
@@ -38,6 +54,8 @@ func (c *ServiceConfig) defaults() error {
 type Service struct {
 	engine sandbox.Engine
 	repo   storage.Repository
+	locker storage.SandboxLocker
+	hooks  hooks.Runner
 	logger log.Logger
 }
 
@@ -50,6 +68,8 @@ func NewService(cfg ServiceConfig) (*Service, error) {
 	return &Service{
 		engine: cfg.Engine,
 		repo:   cfg.Repository,
+		locker: cfg.Locker,
+		hooks:  cfg.Hooks,
 		logger: cfg.Logger,
 	}, nil
 }
@@ -68,6 +88,9 @@ type Request struct {
 func (s *Service) Run(ctx context.Context, req Request) (*model.Sandbox, error) {
 	s.logger.Debugf("removing sandbox: %s (force: %v)", req.NameOrID, req.Force)
 
+	// The forced stop uses the engine default grace period, without pre-stop hooks.
+	var stopOpts sandbox.StopOpts
+
 	// Lookup sandbox by name first, then by ID if it looks like a ULID.
 	sandbox, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
 	if errors.Is(err, model.ErrNotFound) && looksLikeULID(req.NameOrID) {
@@ -80,15 +103,34 @@ func (s *Service) Run(ctx context.Context, req Request) (*model.Sandbox, error)
 		return nil, fmt.Errorf("could not get sandbox: %w", err)
 	}
 
+	// Serialize the operations on the sandbox, the sandbox is read again once locked
+	// so the checks use its latest state.
+	if s.locker != nil {
+		unlock, err := s.locker.LockSandbox(ctx, sandbox.ID)
+		if err != nil {
+			return nil, fmt.Errorf("could not lock sandbox: %w", err)
+		}
+		defer unlock()
+
+		sandbox, err = s.repo.GetSandbox(ctx, sandbox.ID)
+		if err != nil {
+			return nil, fmt.Errorf("could not get sandbox: %w", err)
+		}
+	}
+
 	// Check if sandbox is running.
 	if sandbox.Status == model.SandboxStatusRunning {
 		if !req.Force {
 			return nil, fmt.Errorf("cannot remove running sandbox without --force: %w", model.ErrNotValid)
 		}
 
-		// Stop the sandbox first (ignore errors, best effort).
+		// Stop the sandbox first (ignore errors, best effort), the pre-stop host hooks
+		// can't abort a forced removal.
 		s.logger.Infof("force removing running sandbox, stopping first: %s", sandbox.ID)
-		_ = s.engine.Stop(ctx, sandbox.ID)
+		if err := s.hooks.Run(ctx, model.HookEventPreStop, *sandbox); err != nil {
+			s.logger.Warningf("pre-stop hooks of sandbox %s failed: %v", sandbox.Name, err)
+		}
+		_ = s.engine.Stop(ctx, sandbox.ID, stopOpts)
 	}
 
 	// Remove the sandbox via engine.
@@ -97,10 +139,23 @@ func (s *Service) Run(ctx context.Context, req Request) (*model.Sandbox, error)
 	}
 
 	// Delete from repository.
+	usage := model.SandboxUsageRecords(*sandbox, time.Now().UTC())
 	if err := s.repo.DeleteSandbox(ctx, sandbox.ID); err != nil {
 		return nil, fmt.Errorf("could not delete sandbox from repository: %w", err)
 	}
 
+	// Store the disk (and the run of a force removed sandbox) for the usage accounting.
+	for _, rec := range usage {
+		if err := s.repo.CreateUsageRecord(ctx, rec); err != nil {
+			s.logger.Warningf("could not store sandbox %s usage: %v", sandbox.ID, err)
+		}
+	}
+
+	// The sandbox is already gone, failed post-remove hooks don't fail the removal.
+	if err := s.hooks.Run(ctx, model.HookEventPostRemove, *sandbox); err != nil {
+		s.logger.Warningf("post-remove hooks of sandbox %s failed: %v", sandbox.Name, err)
+	}
+
 	s.logger.Infof("removed sandbox: %s (ID: %s)", sandbox.Name, sandbox.ID)
 	return sandbox, nil
 }
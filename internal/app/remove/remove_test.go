@@ -13,6 +13,7 @@ import (
 	"github.com/slok/sbx/internal/app/remove"
 	"github.com/slok/sbx/internal/log"
 	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
 	"github.com/slok/sbx/internal/sandbox/sandboxmock"
 	"github.com/slok/sbx/internal/storage/storagemock"
 )
@@ -72,6 +73,9 @@ func TestService_Run(t *testing.T) {
 					StoppedAt: &stoppedAt,
 				}, nil)
 				m.On("DeleteSandbox", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH").Once().Return(nil)
+				m.On("CreateUsageRecord", mock.Anything, mock.MatchedBy(func(r model.UsageRecord) bool {
+					return r.SandboxID == "01H2QWERTYASDFGZXCVBNMLKJH" && r.Kind == model.UsageKindDisk && r.StartedAt.Equal(createdAt)
+				})).Once().Return(nil)
 			},
 			mockEngine: func(m *sandboxmock.MockEngine) {
 				m.On("Remove", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH").Once().Return(nil)
@@ -103,9 +107,15 @@ func TestService_Run(t *testing.T) {
 					StartedAt: &startedAt,
 				}, nil)
 				m.On("DeleteSandbox", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH").Once().Return(nil)
+				m.On("CreateUsageRecord", mock.Anything, mock.MatchedBy(func(r model.UsageRecord) bool {
+					return r.Kind == model.UsageKindDisk && r.StartedAt.Equal(createdAt)
+				})).Once().Return(nil)
+				m.On("CreateUsageRecord", mock.Anything, mock.MatchedBy(func(r model.UsageRecord) bool {
+					return r.Kind == model.UsageKindRun && r.StartedAt.Equal(startedAt)
+				})).Once().Return(nil)
 			},
 			mockEngine: func(m *sandboxmock.MockEngine) {
-				m.On("Stop", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH").Once().Return(nil)
+				m.On("Stop", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", sandbox.StopOpts{}).Once().Return(nil)
 				m.On("Remove", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH").Once().Return(nil)
 			},
 			req:    remove.Request{NameOrID: "my-sandbox", Force: true},
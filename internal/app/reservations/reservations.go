@@ -0,0 +1,87 @@
+package reservations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/reservation"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the reservations service.
+type ServiceConfig struct {
+	Repository storage.Repository
+	Store      *reservation.Store
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Store == nil {
+		return fmt.Errorf("reservation store is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.Reservations"})
+
+	return nil
+}
+
+// Service reports the resource reservations of the sandboxes.
+type Service struct {
+	repo   storage.Repository
+	store  *reservation.Store
+	logger log.Logger
+}
+
+// NewService creates a new reservations service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		repo:   cfg.Repository,
+		store:  cfg.Store,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Run returns the reservations of the sandboxes of all the namespaces, the host
+// resources are shared by them, sorted by namespace and name. The manifests are
+// synced with them, so the changes made by other clients of the data directory
+// are written too.
+func (s *Service) Run(ctx context.Context) ([]model.Reservation, error) {
+	sandboxes, err := s.repo.ListAllSandboxes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not list sandboxes: %w", err)
+	}
+
+	now := time.Now().UTC()
+	rs := make([]model.Reservation, 0, len(sandboxes))
+	for _, sb := range sandboxes {
+		rs = append(rs, model.NewReservation(sb, now))
+	}
+	sort.Slice(rs, func(i, j int) bool {
+		if rs[i].Namespace != rs[j].Namespace {
+			return rs[i].Namespace < rs[j].Namespace
+		}
+		return rs[i].Name < rs[j].Name
+	})
+
+	if err := s.store.Sync(rs); err != nil {
+		return nil, fmt.Errorf("could not write reservations: %w", err)
+	}
+
+	s.logger.Debugf("synced %d reservations", len(rs))
+	return rs, nil
+}
@@ -0,0 +1,97 @@
+package reservations_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/reservations"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/reservation"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestServiceRun(t *testing.T) {
+	res := model.Resources{VCPUs: 2, MemoryMB: 2048, DiskGB: 10}
+
+	tests := map[string]struct {
+		mock     func(m *storagemock.MockRepository)
+		stale    []string
+		expNames []string
+		expFiles []string
+		expErr   bool
+	}{
+		"The reservations of all the namespaces should be returned and written.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("ListAllSandboxes", mock.Anything).Once().Return([]model.Sandbox{
+					{ID: "id-1", Name: "sb-b", Namespace: "team-a", Status: model.SandboxStatusRunning, Config: model.SandboxConfig{Resources: res}},
+					{ID: "id-2", Name: "sb-a", Status: model.SandboxStatusStopped, Config: model.SandboxConfig{Resources: res}},
+				}, nil)
+			},
+			stale:    []string{"id-3"},
+			expNames: []string{"sb-a", "sb-b"},
+			expFiles: []string{"id-1.json", "id-2.json"},
+		},
+
+		"Without sandboxes the manifests should be removed.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("ListAllSandboxes", mock.Anything).Once().Return(nil, nil)
+			},
+			stale:    []string{"id-1"},
+			expNames: []string{},
+		},
+
+		"A repository error should fail.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("ListAllSandboxes", mock.Anything).Once().Return(nil, fmt.Errorf("db error"))
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			dir := t.TempDir()
+			store := reservation.NewStore(dir)
+			for _, id := range test.stale {
+				require.NoError(store.Write(model.Reservation{SandboxID: id}))
+			}
+
+			mRepo := storagemock.NewMockRepository(t)
+			test.mock(mRepo)
+
+			svc, err := reservations.NewService(reservations.ServiceConfig{Repository: mRepo, Store: store, Logger: log.Noop})
+			require.NoError(err)
+
+			rs, err := svc.Run(context.Background())
+			if test.expErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			names := []string{}
+			for _, r := range rs {
+				names = append(names, r.Name)
+			}
+			assert.Equal(test.expNames, names)
+
+			files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+			require.NoError(err)
+			var gotFiles []string
+			for _, f := range files {
+				gotFiles = append(gotFiles, filepath.Base(f))
+			}
+			assert.Equal(test.expFiles, gotFiles)
+		})
+	}
+}
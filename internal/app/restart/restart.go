@@ -0,0 +1,206 @@
+package restart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/slok/sbx/internal/app/start"
+	"github.com/slok/sbx/internal/app/stop"
+	"github.com/slok/sbx/internal/capacity"
+	"github.com/slok/sbx/internal/hooks"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the restart service.
+type ServiceConfig struct {
+	Engine     sandbox.Engine
+	Repository storage.Repository
+	// Locker serializes the sandbox operations. Defaults to the repository when it
+	// implements it, otherwise the operations are not serialized.
+	Locker storage.SandboxLocker
+	// Admission checks the host capacity before starting the sandbox again (optional).
+	Admission *capacity.Planner
+	// Hooks runs the pre-stop and post-start host hooks (optional).
+	Hooks  hooks.Runner
+	Logger log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Engine == nil {
+		return fmt.Errorf("engine is required")
+	}
+
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Locker == nil {
+		c.Locker, _ = c.Repository.(storage.SandboxLocker)
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+
+	return nil
+}
+
+// Service restarts a running sandbox in place, with the session configuration of
+// its previous start.
+type Service struct {
+	repo   storage.Repository
+	locker storage.SandboxLocker
+	stop   *stop.Service
+	start  *start.Service
+	logger log.Logger
+}
+
+// NewService creates a new restart service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	// The restart holds the sandbox lock for the stop and the start, the lock is not
+	// reentrant so they don't take it again.
+	stopSvc, err := stop.NewService(stop.ServiceConfig{
+		Engine:     cfg.Engine,
+		Repository: cfg.Repository,
+		Locker:     heldLocker{},
+		Hooks:      cfg.Hooks,
+		Logger:     cfg.Logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create stop service: %w", err)
+	}
+
+	startSvc, err := start.NewService(start.ServiceConfig{
+		Engine:     cfg.Engine,
+		Repository: cfg.Repository,
+		Locker:     heldLocker{},
+		Admission:  cfg.Admission,
+		Hooks:      cfg.Hooks,
+		Logger:     cfg.Logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create start service: %w", err)
+	}
+
+	return &Service{
+		repo:   cfg.Repository,
+		locker: cfg.Locker,
+		stop:   stopSvc,
+		start:  startSvc,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the restart request parameters.
+type Request struct {
+	// NameOrID is the sandbox name or ID to restart.
+	NameOrID string
+	// GracePeriod is the time the sandbox has to run the pre-stop hooks and shut
+	// down before being killed, zero uses the engine default.
+	GracePeriod time.Duration
+	// PreStopHooks are the shell commands run in the guest before it's shut down.
+	PreStopHooks []string
+	// Timeouts are the optional start step timeouts, the unset ones use the engine defaults.
+	Timeouts model.StartTimeouts
+	// Progress receives the start steps (optional).
+	Progress model.ProgressFunc
+}
+
+// Run stops and starts a running sandbox by name or ID holding its lock, so no
+// other operation runs in between. The start applies the session configuration
+// (env, egress and integrity paths) of the previous start. If the start fails the
+// sandbox is left stopped.
+func (s *Service) Run(ctx context.Context, req Request) (*model.Sandbox, error) {
+	s.logger.Debugf("restarting sandbox: %s", req.NameOrID)
+
+	if err := req.Timeouts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid start timeouts: %w", err)
+	}
+
+	// Lookup sandbox by name first, then by ID if it looks like a ULID.
+	sb, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
+	if errors.Is(err, model.ErrNotFound) && looksLikeULID(req.NameOrID) {
+		sb, err = s.repo.GetSandbox(ctx, req.NameOrID)
+	}
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("sandbox not found: %s: %w", req.NameOrID, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not get sandbox: %w", err)
+	}
+
+	// Serialize the operations on the sandbox for the whole restart, the sandbox is
+	// read again once locked so the checks use its latest state.
+	if s.locker != nil {
+		unlock, err := s.locker.LockSandbox(ctx, sb.ID)
+		if err != nil {
+			return nil, fmt.Errorf("could not lock sandbox: %w", err)
+		}
+		defer unlock()
+
+		sb, err = s.repo.GetSandbox(ctx, sb.ID)
+		if err != nil {
+			return nil, fmt.Errorf("could not get sandbox: %w", err)
+		}
+	}
+
+	if sb.Status != model.SandboxStatusRunning {
+		return nil, fmt.Errorf("cannot restart sandbox: not running (current status: %s): %w", sb.Status, model.ErrNotValid)
+	}
+
+	var session model.SessionConfig
+	if sb.Session != nil {
+		session = *sb.Session
+	}
+
+	if _, err := s.stop.Run(ctx, stop.Request{
+		NameOrID:     sb.ID,
+		GracePeriod:  req.GracePeriod,
+		PreStopHooks: req.PreStopHooks,
+	}); err != nil {
+		return nil, err
+	}
+
+	started, err := s.start.Run(ctx, start.Request{
+		NameOrID:      sb.ID,
+		SessionConfig: session,
+		Timeouts:      req.Timeouts,
+		Progress:      req.Progress,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sandbox stopped but could not be started again: %w", err)
+	}
+
+	s.logger.Infof("restarted sandbox: %s (ID: %s)", started.Name, started.ID)
+	return started, nil
+}
+
+// heldLocker is the locker of the operations run while the restart holds the
+// sandbox lock.
+type heldLocker struct{}
+
+func (heldLocker) LockSandbox(ctx context.Context, id string) (func(), error) {
+	return func() {}, nil
+}
+
+// looksLikeULID checks if a string looks like a ULID (26 characters, alphanumeric uppercase).
+func looksLikeULID(s string) bool {
+	if len(s) != 26 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,149 @@
+package restart_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/restart"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/sandbox/sandboxmock"
+	"github.com/slok/sbx/internal/storage/memory"
+)
+
+const testSandboxID = "01H2QWERTYASDFGZXCVBNMLKJH"
+
+func TestServiceRun(t *testing.T) {
+	session := &model.SessionConfig{
+		Env:    map[string]string{"FOO": "bar"},
+		Egress: &model.EgressPolicy{Default: model.EgressActionDeny, Rules: []model.EgressRule{{Domain: "github.com", Action: model.EgressActionAllow}}},
+	}
+
+	tests := map[string]struct {
+		sandbox    model.Sandbox
+		lock       bool
+		mockEngine func(m *sandboxmock.MockEngine)
+		req        restart.Request
+		expSession *model.SessionConfig
+		expStatus  model.SandboxStatus
+		expErr     error
+	}{
+		"A running sandbox should be restarted with the session of its previous start.": {
+			sandbox: model.Sandbox{Status: model.SandboxStatusRunning, Session: session},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Stop", mock.Anything, testSandboxID, sandbox.StopOpts{GracePeriod: time.Second, PreStopHooks: []string{"sync"}}).Once().Return(nil)
+				m.On("Start", mock.Anything, testSandboxID, sandbox.StartOpts{Egress: session.Egress}).Once().Return(nil, nil)
+				m.On("Exec", mock.Anything, testSandboxID, mock.Anything, mock.Anything).Return(&model.ExecResult{}, nil)
+				m.On("CopyTo", mock.Anything, testSandboxID, mock.Anything, mock.Anything, model.CopyOpts{}).Return(nil)
+			},
+			req:        restart.Request{NameOrID: "my-sandbox", GracePeriod: time.Second, PreStopHooks: []string{"sync"}},
+			expSession: session,
+			expStatus:  model.SandboxStatusRunning,
+		},
+
+		"A running sandbox without a previous session should be restarted without session.": {
+			sandbox: model.Sandbox{Status: model.SandboxStatusRunning},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Stop", mock.Anything, testSandboxID, sandbox.StopOpts{}).Once().Return(nil)
+				m.On("Start", mock.Anything, testSandboxID, sandbox.StartOpts{}).Once().Return(nil, nil)
+				m.On("Exec", mock.Anything, testSandboxID, mock.Anything, mock.Anything).Return(&model.ExecResult{}, nil)
+				m.On("CopyTo", mock.Anything, testSandboxID, mock.Anything, mock.Anything, model.CopyOpts{}).Return(nil)
+			},
+			req:        restart.Request{NameOrID: testSandboxID},
+			expSession: &model.SessionConfig{Env: map[string]string{}},
+			expStatus:  model.SandboxStatusRunning,
+		},
+
+		"A failed start should leave the sandbox stopped.": {
+			sandbox: model.Sandbox{Status: model.SandboxStatusRunning, Session: session},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Stop", mock.Anything, testSandboxID, sandbox.StopOpts{}).Once().Return(nil)
+				m.On("Start", mock.Anything, testSandboxID, mock.Anything).Once().Return(nil, assert.AnError)
+			},
+			req:        restart.Request{NameOrID: "my-sandbox"},
+			expSession: session,
+			expStatus:  model.SandboxStatusStopped,
+			expErr:     assert.AnError,
+		},
+
+		"A stopped sandbox should fail.": {
+			sandbox:    model.Sandbox{Status: model.SandboxStatusStopped},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        restart.Request{NameOrID: "my-sandbox"},
+			expStatus:  model.SandboxStatusStopped,
+			expErr:     model.ErrNotValid,
+		},
+
+		"A sandbox locked by another operation should fail.": {
+			sandbox:    model.Sandbox{Status: model.SandboxStatusRunning},
+			lock:       true,
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        restart.Request{NameOrID: "my-sandbox"},
+			expStatus:  model.SandboxStatusRunning,
+			expErr:     model.ErrConflict,
+		},
+
+		"A missing sandbox should fail.": {
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        restart.Request{NameOrID: "missing"},
+			expErr:     model.ErrNotFound,
+		},
+
+		"Negative start timeouts should fail.": {
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        restart.Request{NameOrID: "my-sandbox", Timeouts: model.StartTimeouts{Boot: -time.Second}},
+			expErr:     model.ErrNotValid,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			ctx := context.Background()
+
+			repo, err := memory.NewRepository(memory.RepositoryConfig{})
+			require.NoError(err)
+			if test.sandbox.Status != "" {
+				sb := test.sandbox
+				sb.ID = testSandboxID
+				sb.Name = "my-sandbox"
+				sb.CreatedAt = time.Now()
+				sb.Config = model.SandboxConfig{Name: "my-sandbox", FirecrackerEngine: &model.FirecrackerEngineConfig{}}
+				require.NoError(repo.CreateSandbox(ctx, sb))
+			}
+			if test.lock {
+				unlock, err := repo.LockSandbox(ctx, testSandboxID)
+				require.NoError(err)
+				defer unlock()
+			}
+
+			mEngine := sandboxmock.NewMockEngine(t)
+			test.mockEngine(mEngine)
+
+			svc, err := restart.NewService(restart.ServiceConfig{Engine: mEngine, Repository: repo, Logger: log.Noop})
+			require.NoError(err)
+
+			result, err := svc.Run(ctx, test.req)
+
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expStatus, result.Status)
+			}
+
+			if test.sandbox.Status != "" {
+				got, err := repo.GetSandbox(ctx, testSandboxID)
+				require.NoError(err)
+				assert.Equal(test.expStatus, got.Status)
+				assert.Equal(test.expSession, got.Session)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package scale
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the scale service.
+type ServiceConfig struct {
+	Engine     sandbox.Engine
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Engine == nil {
+		return fmt.Errorf("engine is required")
+	}
+
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.Scale"})
+
+	return nil
+}
+
+// Service changes the VCPUs and memory of running sandboxes.
+type Service struct {
+	engine sandbox.Engine
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new scale service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		engine: cfg.Engine,
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the scale request parameters.
+type Request struct {
+	// NameOrID is the sandbox name or ID.
+	NameOrID string
+	// VCPUs is the new number of VCPUs, 0 keeps the current ones.
+	VCPUs float64
+	// MemoryMB is the new memory in MB, 0 keeps the current one.
+	MemoryMB int
+}
+
+// Run scales a running sandbox by name or ID inside the resource ceilings it booted
+// with, and stores the new resources. The ceilings are stored too, so a sandbox
+// booted without them can scale back up to its booted resources.
+func (s *Service) Run(ctx context.Context, req Request) (*model.Sandbox, error) {
+	if req.VCPUs < 0 || req.MemoryMB < 0 {
+		return nil, fmt.Errorf("vcpus and memory can't be negative: %w", model.ErrNotValid)
+	}
+
+	// Lookup sandbox by name first, then by ID if it looks like a ULID.
+	sb, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
+	if errors.Is(err, model.ErrNotFound) && looksLikeULID(req.NameOrID) {
+		sb, err = s.repo.GetSandbox(ctx, req.NameOrID)
+	}
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("sandbox not found: %s: %w", req.NameOrID, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not get sandbox: %w", err)
+	}
+
+	if sb.Status != model.SandboxStatusRunning {
+		return nil, fmt.Errorf("cannot scale: sandbox not running (current status: %s): %w", sb.Status, model.ErrNotValid)
+	}
+
+	current := sb.Config.Resources
+	resources := model.Resources{
+		VCPUs:       current.VCPUs,
+		MemoryMB:    current.MemoryMB,
+		DiskGB:      current.DiskGB,
+		MaxVCPUs:    current.BootVCPUs(),
+		MaxMemoryMB: current.BootMemoryMB(),
+	}
+	if req.VCPUs > 0 {
+		resources.VCPUs = req.VCPUs
+	}
+	if req.MemoryMB > 0 {
+		resources.MemoryMB = req.MemoryMB
+	}
+	if resources.VCPUs > resources.MaxVCPUs {
+		return nil, fmt.Errorf("vcpus %g are greater than the sandbox max vcpus (%g), recreate the sandbox with a higher max: %w", resources.VCPUs, resources.MaxVCPUs, model.ErrNotValid)
+	}
+	if resources.MemoryMB > resources.MaxMemoryMB {
+		return nil, fmt.Errorf("memory %dMB is greater than the sandbox max memory (%dMB), recreate the sandbox with a higher max: %w", resources.MemoryMB, resources.MaxMemoryMB, model.ErrNotValid)
+	}
+
+	if err := s.engine.Scale(ctx, sb.ID, resources); err != nil {
+		return nil, fmt.Errorf("could not scale sandbox: %w", err)
+	}
+
+	sb.Config.Resources = resources
+	if err := s.repo.UpdateSandbox(ctx, *sb); err != nil {
+		return nil, fmt.Errorf("could not update sandbox: %w", err)
+	}
+
+	s.logger.Infof("scaled sandbox %s to %g vcpus and %dMB", sb.Name, resources.VCPUs, resources.MemoryMB)
+	return sb, nil
+}
+
+// looksLikeULID checks if a string looks like a ULID (26 characters, alphanumeric uppercase).
+func looksLikeULID(s string) bool {
+	if len(s) != 26 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return true
+}
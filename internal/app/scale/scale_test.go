@@ -0,0 +1,198 @@
+package scale_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/scale"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox/sandboxmock"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestNewService(t *testing.T) {
+	tests := map[string]struct {
+		config scale.ServiceConfig
+		expErr bool
+	}{
+		"valid config should create service": {
+			config: scale.ServiceConfig{
+				Engine:     &sandboxmock.MockEngine{},
+				Repository: &storagemock.MockRepository{},
+				Logger:     log.Noop,
+			},
+		},
+		"missing engine should fail": {
+			config: scale.ServiceConfig{
+				Repository: &storagemock.MockRepository{},
+			},
+			expErr: true,
+		},
+		"missing repository should fail": {
+			config: scale.ServiceConfig{
+				Engine: &sandboxmock.MockEngine{},
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			svc, err := scale.NewService(test.config)
+
+			if test.expErr {
+				require.Error(err)
+				require.Nil(svc)
+			} else {
+				require.NoError(err)
+				require.NotNil(svc)
+			}
+		})
+	}
+}
+
+func TestService_Run(t *testing.T) {
+	newSandbox := func(status model.SandboxStatus, resources model.Resources) *model.Sandbox {
+		return &model.Sandbox{
+			ID:     "01H2QWERTYASDFGZXCVBNMLKJH",
+			Name:   "my-sandbox",
+			Status: status,
+			Config: model.SandboxConfig{Resources: resources},
+		}
+	}
+	withCeilings := model.Resources{VCPUs: 1, MemoryMB: 1024, DiskGB: 10, MaxVCPUs: 4, MaxMemoryMB: 4096}
+
+	tests := map[string]struct {
+		mockRepo     func(m *storagemock.MockRepository)
+		mockEngine   func(m *sandboxmock.MockEngine)
+		req          scale.Request
+		expResources model.Resources
+		expErrIs     error
+		expErr       bool
+	}{
+		"scaling up a running sandbox inside its ceilings should scale and store the resources": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(newSandbox(model.SandboxStatusRunning, withCeilings), nil)
+				m.On("UpdateSandbox", mock.Anything, mock.MatchedBy(func(sb model.Sandbox) bool {
+					return sb.Config.Resources == model.Resources{VCPUs: 2, MemoryMB: 2048, DiskGB: 10, MaxVCPUs: 4, MaxMemoryMB: 4096}
+				})).Once().Return(nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Scale", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", model.Resources{VCPUs: 2, MemoryMB: 2048, DiskGB: 10, MaxVCPUs: 4, MaxMemoryMB: 4096}).Once().Return(nil)
+			},
+			req:          scale.Request{NameOrID: "my-sandbox", VCPUs: 2, MemoryMB: 2048},
+			expResources: model.Resources{VCPUs: 2, MemoryMB: 2048, DiskGB: 10, MaxVCPUs: 4, MaxMemoryMB: 4096},
+		},
+		"scaling down a sandbox without ceilings should keep the booted resources as ceilings": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(newSandbox(model.SandboxStatusRunning, model.Resources{VCPUs: 2, MemoryMB: 2048, DiskGB: 10}), nil)
+				m.On("UpdateSandbox", mock.Anything, mock.Anything).Once().Return(nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Scale", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything).Once().Return(nil)
+			},
+			req:          scale.Request{NameOrID: "my-sandbox", MemoryMB: 512},
+			expResources: model.Resources{VCPUs: 2, MemoryMB: 512, DiskGB: 10, MaxVCPUs: 2, MaxMemoryMB: 2048},
+		},
+		"scaling by ID should lookup the sandbox by ID": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH").Once().Return(nil, model.ErrNotFound)
+				m.On("GetSandbox", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH").Once().Return(newSandbox(model.SandboxStatusRunning, withCeilings), nil)
+				m.On("UpdateSandbox", mock.Anything, mock.Anything).Once().Return(nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Scale", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything).Once().Return(nil)
+			},
+			req:          scale.Request{NameOrID: "01H2QWERTYASDFGZXCVBNMLKJH", VCPUs: 4},
+			expResources: model.Resources{VCPUs: 4, MemoryMB: 1024, DiskGB: 10, MaxVCPUs: 4, MaxMemoryMB: 4096},
+		},
+		"scaling above the vcpus ceiling should fail": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(newSandbox(model.SandboxStatusRunning, withCeilings), nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        scale.Request{NameOrID: "my-sandbox", VCPUs: 8},
+			expErrIs:   model.ErrNotValid,
+		},
+		"scaling above the memory ceiling should fail": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(newSandbox(model.SandboxStatusRunning, withCeilings), nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        scale.Request{NameOrID: "my-sandbox", MemoryMB: 8192},
+			expErrIs:   model.ErrNotValid,
+		},
+		"negative resources should fail": {
+			mockRepo:   func(m *storagemock.MockRepository) {},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        scale.Request{NameOrID: "my-sandbox", MemoryMB: -1},
+			expErrIs:   model.ErrNotValid,
+		},
+		"a stopped sandbox should fail": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(newSandbox(model.SandboxStatusStopped, withCeilings), nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        scale.Request{NameOrID: "my-sandbox", VCPUs: 2},
+			expErrIs:   model.ErrNotValid,
+		},
+		"a missing sandbox should fail": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "nonexistent").Once().Return(nil, model.ErrNotFound)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        scale.Request{NameOrID: "nonexistent", VCPUs: 2},
+			expErrIs:   model.ErrNotFound,
+		},
+		"engine error should propagate and not store the resources": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(newSandbox(model.SandboxStatusRunning, withCeilings), nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Scale", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything).Once().Return(fmt.Errorf("engine error"))
+			},
+			req:    scale.Request{NameOrID: "my-sandbox", VCPUs: 2},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mRepo := &storagemock.MockRepository{}
+			mEngine := &sandboxmock.MockEngine{}
+			test.mockRepo(mRepo)
+			test.mockEngine(mEngine)
+
+			svc, err := scale.NewService(scale.ServiceConfig{
+				Engine:     mEngine,
+				Repository: mRepo,
+			})
+			require.NoError(err)
+
+			sb, err := svc.Run(context.Background(), test.req)
+
+			if test.expErr || test.expErrIs != nil {
+				assert.Error(err)
+				if test.expErrIs != nil {
+					assert.ErrorIs(err, test.expErrIs)
+				}
+			} else if assert.NoError(err) {
+				assert.Equal(test.expResources, sb.Config.Resources)
+			}
+
+			mRepo.AssertExpectations(t)
+			mEngine.AssertExpectations(t)
+		})
+	}
+}
@@ -4,13 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/slok/sbx/internal/conventions"
 	"github.com/slok/sbx/internal/image"
 	"github.com/slok/sbx/internal/log"
 	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
 	"github.com/slok/sbx/internal/storage"
 )
 
@@ -19,9 +22,12 @@ type ServiceConfig struct {
 	ImageManager    image.ImageManager
 	SnapshotCreator image.SnapshotCreator
 	Repository      storage.Repository
-	Logger          log.Logger
-	// DataDir is the base sbx data directory (default: ~/.sbx).
-	DataDir string
+	// Engine is the engine of the sandbox, only required to snapshot running
+	// sandboxes.
+	Engine sandbox.Engine
+	Logger log.Logger
+	// VMsDir is the sandbox VMs directory (default: ~/.sbx/vms).
+	VMsDir string
 }
 
 func (c *ServiceConfig) defaults() error {
@@ -37,8 +43,8 @@ func (c *ServiceConfig) defaults() error {
 	if c.Logger == nil {
 		c.Logger = log.Noop
 	}
-	if c.DataDir == "" {
-		return fmt.Errorf("data dir is required")
+	if c.VMsDir == "" {
+		return fmt.Errorf("vms dir is required")
 	}
 	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.SnapshotCreate"})
 	return nil
@@ -49,8 +55,9 @@ type Service struct {
 	imgMgr  image.ImageManager
 	snapCrt image.SnapshotCreator
 	repo    storage.Repository
+	engine  sandbox.Engine
 	logger  log.Logger
-	dataDir string
+	vmsDir  string
 }
 
 // NewService creates a new snapshot create service.
@@ -62,8 +69,9 @@ func NewService(cfg ServiceConfig) (*Service, error) {
 		imgMgr:  cfg.ImageManager,
 		snapCrt: cfg.SnapshotCreator,
 		repo:    cfg.Repository,
+		engine:  cfg.Engine,
 		logger:  cfg.Logger,
-		dataDir: cfg.DataDir,
+		vmsDir:  cfg.VMsDir,
 	}, nil
 }
 
@@ -71,9 +79,14 @@ func NewService(cfg ServiceConfig) (*Service, error) {
 type Request struct {
 	NameOrID  string
 	ImageName string
+	// Progress receives the snapshot steps (optional).
+	Progress model.ProgressFunc
 }
 
 // Run creates a local snapshot image from an existing sandbox.
+//
+// Running sandboxes are snapshotted live with an engine checkpoint: they are paused
+// briefly and the image records their memory and VM state besides the disk.
 func (s *Service) Run(ctx context.Context, req Request) (string, error) {
 	if req.ImageName != "" {
 		if err := model.ValidateImageName(req.ImageName); err != nil {
@@ -93,8 +106,12 @@ func (s *Service) Run(ctx context.Context, req Request) (string, error) {
 		return "", fmt.Errorf("could not get sandbox: %w", err)
 	}
 
-	if sb.Status != model.SandboxStatusStopped {
-		return "", fmt.Errorf("cannot snapshot sandbox in status %q (must be stopped): %w", sb.Status, model.ErrNotValid)
+	live := sb.Status == model.SandboxStatusRunning
+	if sb.Status != model.SandboxStatusStopped && !live {
+		return "", fmt.Errorf("cannot snapshot sandbox in status %q (must be stopped or running): %w", sb.Status, model.ErrNotValid)
+	}
+	if live && s.engine == nil {
+		return "", fmt.Errorf("cannot snapshot running sandbox without an engine: %w", model.ErrNotValid)
 	}
 
 	// Resolve image name.
@@ -104,7 +121,7 @@ func (s *Service) Run(ctx context.Context, req Request) (string, error) {
 	}
 
 	// Determine rootfs path (the actual VM rootfs, not base image).
-	rootfsPath := filepath.Join(s.dataDir, "vms", sb.ID, "rootfs.ext4")
+	rootfsPath := conventions.VMFilePath(s.vmsDir, sb.ID, conventions.RootFSFile)
 
 	// Determine kernel path from sandbox config.
 	var kernelPath string
@@ -116,7 +133,12 @@ func (s *Service) Run(ctx context.Context, req Request) (string, error) {
 	}
 
 	// Try to detect source image from kernel path.
-	sourceImage := detectSourceImage(kernelPath)
+	// The images dir can be anywhere, the kernel path is only a fallback for the
+	// sandboxes created without an image name.
+	sourceImage := sb.Config.Image
+	if sourceImage == "" {
+		sourceImage = detectSourceImage(kernelPath)
+	}
 
 	// Read source image manifest if available (to inherit metadata).
 	var sourceManifest *model.ImageManifest
@@ -130,11 +152,31 @@ func (s *Service) Run(ctx context.Context, req Request) (string, error) {
 		}
 	}
 
+	// Capture the running sandbox state, the snapshot is created from the checkpoint files.
+	var memoryPath, vmStatePath string
+	steps := 1
+	if live {
+		steps++
+		req.Progress.ReportStep(1, steps, "checkpoint", "Checkpointing the running sandbox")
+		dir := conventions.VMFilePath(s.vmsDir, sb.ID, "checkpoint")
+		defer os.RemoveAll(dir)
+
+		cp, err := s.engine.Checkpoint(ctx, sb.ID, dir)
+		if err != nil {
+			return "", fmt.Errorf("could not checkpoint sandbox: %w", err)
+		}
+		s.logger.Debugf("Sandbox %s paused %s for the checkpoint", sb.Name, cp.Paused)
+		rootfsPath, memoryPath, vmStatePath = cp.RootFSPath, cp.MemoryPath, cp.VMStatePath
+	}
+
+	req.Progress.ReportStep(steps, steps, "create_image", fmt.Sprintf("Creating image %s", imgName))
 	if err := s.snapCrt.Create(ctx, image.CreateSnapshotOptions{
 		Name:              imgName,
 		KernelSrc:         kernelPath,
 		RootFSSrc:         rootfsPath,
 		FirecrackerSrc:    firecrackerSrc,
+		MemorySrc:         memoryPath,
+		VMStateSrc:        vmStatePath,
 		SourceSandboxID:   sb.ID,
 		SourceSandboxName: sb.Name,
 		SourceImage:       sourceImage,
@@ -13,6 +13,7 @@ import (
 	"github.com/slok/sbx/internal/image"
 	"github.com/slok/sbx/internal/image/imagemock"
 	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox/sandboxmock"
 	"github.com/slok/sbx/internal/storage/storagemock"
 )
 
@@ -26,7 +27,7 @@ func TestNewService(t *testing.T) {
 				ImageManager:    &imagemock.MockImageManager{},
 				SnapshotCreator: &imagemock.MockSnapshotCreator{},
 				Repository:      &storagemock.MockRepository{},
-				DataDir:         "/tmp/sbx",
+				VMsDir:          "/tmp/sbx/vms",
 			},
 		},
 
@@ -34,7 +35,7 @@ func TestNewService(t *testing.T) {
 			config: snapshotcreate.ServiceConfig{
 				SnapshotCreator: &imagemock.MockSnapshotCreator{},
 				Repository:      &storagemock.MockRepository{},
-				DataDir:         "/tmp/sbx",
+				VMsDir:          "/tmp/sbx/vms",
 			},
 			expErr: true,
 		},
@@ -43,7 +44,7 @@ func TestNewService(t *testing.T) {
 			config: snapshotcreate.ServiceConfig{
 				ImageManager: &imagemock.MockImageManager{},
 				Repository:   &storagemock.MockRepository{},
-				DataDir:      "/tmp/sbx",
+				VMsDir:       "/tmp/sbx/vms",
 			},
 			expErr: true,
 		},
@@ -52,7 +53,7 @@ func TestNewService(t *testing.T) {
 			config: snapshotcreate.ServiceConfig{
 				ImageManager:    &imagemock.MockImageManager{},
 				SnapshotCreator: &imagemock.MockSnapshotCreator{},
-				DataDir:         "/tmp/sbx",
+				VMsDir:          "/tmp/sbx/vms",
 			},
 			expErr: true,
 		},
@@ -83,7 +84,7 @@ func TestNewService(t *testing.T) {
 
 func TestServiceRun(t *testing.T) {
 	const (
-		dataDir    = "/home/user/.sbx"
+		vmsDir     = "/home/user/.sbx/vms"
 		sandboxID  = "01JKQWERTYASDFGZXCVBNMLKJH"
 		sbxName    = "my-sandbox"
 		kernelPath = "/home/user/.sbx/images/v0.1.0/vmlinux-x86_64"
@@ -117,6 +118,7 @@ func TestServiceRun(t *testing.T) {
 		mockRepo   func(m *storagemock.MockRepository)
 		mockImgMgr func(m *imagemock.MockImageManager)
 		mockSnapC  func(m *imagemock.MockSnapshotCreator)
+		mockEngine func(m *sandboxmock.MockEngine)
 		req        snapshotcreate.Request
 		expName    string
 		expErr     bool
@@ -134,7 +136,7 @@ func TestServiceRun(t *testing.T) {
 				m.On("Create", mock.Anything, mock.MatchedBy(func(opts image.CreateSnapshotOptions) bool {
 					return opts.Name == "my-snap" &&
 						opts.KernelSrc == kernelPath &&
-						opts.RootFSSrc == dataDir+"/vms/"+sandboxID+"/rootfs.ext4" &&
+						opts.RootFSSrc == vmsDir+"/"+sandboxID+"/rootfs.ext4" &&
 						opts.FirecrackerSrc == "/home/user/.sbx/images/v0.1.0/firecracker" &&
 						opts.SourceSandboxID == sandboxID &&
 						opts.SourceSandboxName == sbxName &&
@@ -210,7 +212,7 @@ func TestServiceRun(t *testing.T) {
 			expErr:     true,
 		},
 
-		"Running sandbox should fail with not valid error.": {
+		"Running sandbox without an engine should fail with not valid error.": {
 			mockRepo: func(m *storagemock.MockRepository) {
 				runningSandbox := *stoppedSandbox
 				runningSandbox.Status = model.SandboxStatusRunning
@@ -222,6 +224,52 @@ func TestServiceRun(t *testing.T) {
 			expErr:     true,
 		},
 
+		"Running sandbox should be snapshotted from an engine checkpoint.": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				runningSandbox := *stoppedSandbox
+				runningSandbox.Status = model.SandboxStatusRunning
+				m.On("GetSandboxByName", mock.Anything, sbxName).Once().Return(&runningSandbox, nil)
+			},
+			mockImgMgr: func(m *imagemock.MockImageManager) {
+				m.On("Exists", mock.Anything, "my-snap").Once().Return(false, nil)
+				m.On("GetManifest", mock.Anything, "v0.1.0").Once().Return(sourceManifest, nil)
+				m.On("FirecrackerPath", "v0.1.0").Once().Return("/home/user/.sbx/images/v0.1.0/firecracker")
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Checkpoint", mock.Anything, sandboxID, "/home/user/.sbx/vms/"+sandboxID+"/checkpoint").Once().Return(&model.Checkpoint{
+					RootFSPath:  "/cp/rootfs.ext4",
+					MemoryPath:  "/cp/memory",
+					VMStatePath: "/cp/vmstate",
+				}, nil)
+			},
+			mockSnapC: func(m *imagemock.MockSnapshotCreator) {
+				m.On("Create", mock.Anything, mock.MatchedBy(func(opts image.CreateSnapshotOptions) bool {
+					return opts.RootFSSrc == "/cp/rootfs.ext4" && opts.MemorySrc == "/cp/memory" && opts.VMStateSrc == "/cp/vmstate"
+				})).Once().Return(nil)
+			},
+			req:     snapshotcreate.Request{NameOrID: sbxName, ImageName: "my-snap"},
+			expName: "my-snap",
+		},
+
+		"A failed checkpoint should fail without creating the image.": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				runningSandbox := *stoppedSandbox
+				runningSandbox.Status = model.SandboxStatusRunning
+				m.On("GetSandboxByName", mock.Anything, sbxName).Once().Return(&runningSandbox, nil)
+			},
+			mockImgMgr: func(m *imagemock.MockImageManager) {
+				m.On("Exists", mock.Anything, "my-snap").Once().Return(false, nil)
+				m.On("GetManifest", mock.Anything, "v0.1.0").Once().Return(sourceManifest, nil)
+				m.On("FirecrackerPath", "v0.1.0").Once().Return("/home/user/.sbx/images/v0.1.0/firecracker")
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Checkpoint", mock.Anything, sandboxID, mock.Anything).Once().Return(nil, fmt.Errorf("pause failed"))
+			},
+			mockSnapC: func(m *imagemock.MockSnapshotCreator) {},
+			req:       snapshotcreate.Request{NameOrID: sbxName, ImageName: "my-snap"},
+			expErr:    true,
+		},
+
 		"Stopped sandbox (freshly created) should succeed.": {
 			mockRepo: func(m *storagemock.MockRepository) {
 				m.On("GetSandboxByName", mock.Anything, sbxName).Once().Return(stoppedSandbox, nil)
@@ -353,13 +401,19 @@ func TestServiceRun(t *testing.T) {
 			tc.mockRepo(mRepo)
 			tc.mockImgMgr(mImgMgr)
 			tc.mockSnapC(mSnapC)
-
-			svc, err := snapshotcreate.NewService(snapshotcreate.ServiceConfig{
+			cfg := snapshotcreate.ServiceConfig{
 				ImageManager:    mImgMgr,
 				SnapshotCreator: mSnapC,
 				Repository:      mRepo,
-				DataDir:         dataDir,
-			})
+				VMsDir:          vmsDir,
+			}
+			mEngine := &sandboxmock.MockEngine{}
+			if tc.mockEngine != nil {
+				tc.mockEngine(mEngine)
+				cfg.Engine = mEngine
+			}
+
+			svc, err := snapshotcreate.NewService(cfg)
 			require.NoError(t, err)
 
 			result, err := svc.Run(context.Background(), tc.req)
@@ -378,6 +432,7 @@ func TestServiceRun(t *testing.T) {
 			mRepo.AssertExpectations(t)
 			mImgMgr.AssertExpectations(t)
 			mSnapC.AssertExpectations(t)
+			mEngine.AssertExpectations(t)
 		})
 	}
 }
@@ -0,0 +1,107 @@
+package snapshotdiff
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+// ServiceConfig is the configuration for the snapshot diff service.
+type ServiceConfig struct {
+	Manager image.ImageManager
+	Differ  image.ImageDiffer
+	Logger  log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Manager == nil {
+		return fmt.Errorf("image manager is required")
+	}
+	if c.Differ == nil {
+		return fmt.Errorf("image differ is required")
+	}
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.SnapshotDiff"})
+	return nil
+}
+
+// Service reports the files changed between snapshot images.
+type Service struct {
+	manager image.ImageManager
+	differ  image.ImageDiffer
+	logger  log.Logger
+}
+
+// NewService creates a new snapshot diff service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &Service{
+		manager: cfg.Manager,
+		differ:  cfg.Differ,
+		logger:  cfg.Logger,
+	}, nil
+}
+
+// Request is the diff request parameters.
+type Request struct {
+	// Snapshot is the snapshot image whose changes are reported.
+	Snapshot string
+	// Against is the image the snapshot is compared with. Empty uses the base of
+	// the snapshot: its parent snapshot or the image of its source sandbox.
+	Against string
+}
+
+// Run returns the files changed from the compared image to the snapshot.
+func (s *Service) Run(ctx context.Context, req Request) (*model.ImageDiff, error) {
+	if req.Snapshot == "" {
+		return nil, fmt.Errorf("snapshot is required: %w", model.ErrNotValid)
+	}
+	if err := s.checkInstalled(ctx, req.Snapshot); err != nil {
+		return nil, err
+	}
+
+	against := req.Against
+	if against == "" {
+		manifest, err := s.manager.GetManifest(ctx, req.Snapshot)
+		if err != nil {
+			return nil, fmt.Errorf("reading image %s: %w", req.Snapshot, err)
+		}
+		if manifest.Snapshot == nil {
+			return nil, fmt.Errorf("image %q is not a snapshot, set the image to compare it with: %w", req.Snapshot, model.ErrNotValid)
+		}
+		against = manifest.Snapshot.ParentSnapshot
+		if against == "" {
+			against = manifest.Snapshot.SourceImage
+		}
+		if against == "" {
+			return nil, fmt.Errorf("snapshot %q has no base image (its sandbox wasn't created from an image), set the image to compare it with: %w", req.Snapshot, model.ErrNotValid)
+		}
+	}
+	if err := s.checkInstalled(ctx, against); err != nil {
+		return nil, err
+	}
+
+	diff, err := s.differ.Diff(ctx, against, req.Snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("diffing %s against %s: %w", req.Snapshot, against, err)
+	}
+	return diff, nil
+}
+
+func (s *Service) checkInstalled(ctx context.Context, name string) error {
+	exists, err := s.manager.Exists(ctx, name)
+	if err != nil {
+		return fmt.Errorf("checking image %s: %w", name, err)
+	}
+	if !exists {
+		return fmt.Errorf("image %q is not installed: %w", name, model.ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package snapshotdiff_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/snapshotdiff"
+	"github.com/slok/sbx/internal/image/imagemock"
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestServiceRun(t *testing.T) {
+	diff := &model.ImageDiff{From: "v0.1.0", To: "snap", Changes: []model.FileChange{{Path: "/etc/motd", Kind: model.FileChangeAdded}}}
+
+	tests := map[string]struct {
+		req     snapshotdiff.Request
+		mock    func(m *imagemock.MockImageManager, d *imagemock.MockImageDiffer)
+		expDiff *model.ImageDiff
+		expErr  error
+	}{
+		"Without image to compare, the snapshot should be compared with its source image.": {
+			req: snapshotdiff.Request{Snapshot: "snap"},
+			mock: func(m *imagemock.MockImageManager, d *imagemock.MockImageDiffer) {
+				m.On("Exists", mock.Anything, "snap").Once().Return(true, nil)
+				m.On("GetManifest", mock.Anything, "snap").Once().Return(&model.ImageManifest{Snapshot: &model.SnapshotInfo{SourceImage: "v0.1.0"}}, nil)
+				m.On("Exists", mock.Anything, "v0.1.0").Once().Return(true, nil)
+				d.On("Diff", mock.Anything, "v0.1.0", "snap").Once().Return(diff, nil)
+			},
+			expDiff: diff,
+		},
+
+		"Without image to compare, the snapshot should be compared with its parent snapshot.": {
+			req: snapshotdiff.Request{Snapshot: "snap"},
+			mock: func(m *imagemock.MockImageManager, d *imagemock.MockImageDiffer) {
+				m.On("Exists", mock.Anything, "snap").Once().Return(true, nil)
+				m.On("GetManifest", mock.Anything, "snap").Once().Return(&model.ImageManifest{Snapshot: &model.SnapshotInfo{SourceImage: "v0.1.0", ParentSnapshot: "parent"}}, nil)
+				m.On("Exists", mock.Anything, "parent").Once().Return(true, nil)
+				d.On("Diff", mock.Anything, "parent", "snap").Once().Return(diff, nil)
+			},
+			expDiff: diff,
+		},
+
+		"An image to compare should be used.": {
+			req: snapshotdiff.Request{Snapshot: "snap", Against: "other"},
+			mock: func(m *imagemock.MockImageManager, d *imagemock.MockImageDiffer) {
+				m.On("Exists", mock.Anything, "snap").Once().Return(true, nil)
+				m.On("Exists", mock.Anything, "other").Once().Return(true, nil)
+				d.On("Diff", mock.Anything, "other", "snap").Once().Return(diff, nil)
+			},
+			expDiff: diff,
+		},
+
+		"A missing snapshot should fail.": {
+			req: snapshotdiff.Request{Snapshot: "snap"},
+			mock: func(m *imagemock.MockImageManager, d *imagemock.MockImageDiffer) {
+				m.On("Exists", mock.Anything, "snap").Once().Return(false, nil)
+			},
+			expErr: model.ErrNotFound,
+		},
+
+		"A missing base image should fail.": {
+			req: snapshotdiff.Request{Snapshot: "snap"},
+			mock: func(m *imagemock.MockImageManager, d *imagemock.MockImageDiffer) {
+				m.On("Exists", mock.Anything, "snap").Once().Return(true, nil)
+				m.On("GetManifest", mock.Anything, "snap").Once().Return(&model.ImageManifest{Snapshot: &model.SnapshotInfo{SourceImage: "v0.1.0"}}, nil)
+				m.On("Exists", mock.Anything, "v0.1.0").Once().Return(false, nil)
+			},
+			expErr: model.ErrNotFound,
+		},
+
+		"A release image without image to compare should fail.": {
+			req: snapshotdiff.Request{Snapshot: "v0.1.0"},
+			mock: func(m *imagemock.MockImageManager, d *imagemock.MockImageDiffer) {
+				m.On("Exists", mock.Anything, "v0.1.0").Once().Return(true, nil)
+				m.On("GetManifest", mock.Anything, "v0.1.0").Once().Return(&model.ImageManifest{}, nil)
+			},
+			expErr: model.ErrNotValid,
+		},
+
+		"A snapshot without base image should fail.": {
+			req: snapshotdiff.Request{Snapshot: "snap"},
+			mock: func(m *imagemock.MockImageManager, d *imagemock.MockImageDiffer) {
+				m.On("Exists", mock.Anything, "snap").Once().Return(true, nil)
+				m.On("GetManifest", mock.Anything, "snap").Once().Return(&model.ImageManifest{Snapshot: &model.SnapshotInfo{}}, nil)
+			},
+			expErr: model.ErrNotValid,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mgr := imagemock.NewMockImageManager(t)
+			differ := imagemock.NewMockImageDiffer(t)
+			tc.mock(mgr, differ)
+
+			svc, err := snapshotdiff.NewService(snapshotdiff.ServiceConfig{Manager: mgr, Differ: differ})
+			require.NoError(t, err)
+
+			got, err := svc.Run(context.Background(), tc.req)
+			if tc.expErr != nil {
+				assert.ErrorIs(t, err, tc.expErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expDiff, got)
+		})
+	}
+}
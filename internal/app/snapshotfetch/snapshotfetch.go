@@ -0,0 +1,112 @@
+package snapshotfetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+// ServiceConfig is the configuration for the snapshot fetch service.
+type ServiceConfig struct {
+	ImageManager image.ImageManager
+	Bundler      image.ImageBundler
+	Store        image.SnapshotStore
+	Logger       log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.ImageManager == nil {
+		return fmt.Errorf("image manager is required")
+	}
+	if c.Bundler == nil {
+		return fmt.Errorf("image bundler is required")
+	}
+	if c.Store == nil {
+		return fmt.Errorf("snapshot store is required")
+	}
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.SnapshotFetch"})
+	return nil
+}
+
+// errImportStopped stops the download when the import doesn't read the bundle anymore.
+var errImportStopped = errors.New("import stopped")
+
+// Service installs snapshot images from the snapshot store.
+type Service struct {
+	manager image.ImageManager
+	bundler image.ImageBundler
+	store   image.SnapshotStore
+	logger  log.Logger
+}
+
+// NewService creates a new snapshot fetch service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &Service{
+		manager: cfg.ImageManager,
+		bundler: cfg.Bundler,
+		store:   cfg.Store,
+		logger:  cfg.Logger,
+	}, nil
+}
+
+// Request is the fetch request parameters.
+type Request struct {
+	// Name is the snapshot image to fetch.
+	Name string
+	// Force replaces the image if already installed.
+	Force bool
+}
+
+// Run streams the bundle of a snapshot from the snapshot store into the local
+// images, the bundle artifacts are verified against its manifest checksums.
+func (s *Service) Run(ctx context.Context, req Request) error {
+	if err := model.ValidateImageName(req.Name); err != nil {
+		return err
+	}
+
+	// Fail before downloading the bundle.
+	if !req.Force {
+		exists, err := s.manager.Exists(ctx, req.Name)
+		if err != nil {
+			return fmt.Errorf("checking image %s: %w", req.Name, err)
+		}
+		if exists {
+			return fmt.Errorf("image %q already exists: %w", req.Name, model.ErrAlreadyExists)
+		}
+	}
+
+	pr, pw := io.Pipe()
+	downloadErr := make(chan error, 1)
+	go func() {
+		err := s.store.Download(ctx, req.Name, pw)
+		pw.CloseWithError(err)
+		downloadErr <- err
+	}()
+
+	name, err := s.bundler.Import(ctx, pr, image.ImportOptions{Force: req.Force})
+	// Unblock the download when the import stopped reading.
+	pr.CloseWithError(errImportStopped)
+	if dErr := <-downloadErr; dErr != nil && !errors.Is(dErr, errImportStopped) {
+		return fmt.Errorf("fetching snapshot %s: %w", req.Name, dErr)
+	}
+	if err != nil {
+		return fmt.Errorf("fetching snapshot %s: %w", req.Name, err)
+	}
+	if name != req.Name {
+		return fmt.Errorf("fetched bundle of snapshot %s installed image %s: %w", req.Name, name, model.ErrNotValid)
+	}
+
+	s.logger.Infof("Fetched snapshot %s", req.Name)
+	return nil
+}
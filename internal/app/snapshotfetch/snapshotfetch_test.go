@@ -0,0 +1,145 @@
+package snapshotfetch_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/snapshotfetch"
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/image/imagemock"
+	"github.com/slok/sbx/internal/model"
+)
+
+var errUnexpectedCall = errors.New("unexpected call")
+
+// fakeBundler and fakeStore are hand-written instead of mocks: the mocks format
+// their arguments, the pipes, while the service closes them.
+type fakeBundler struct {
+	importBundle func(r io.Reader, opts image.ImportOptions) (string, error)
+}
+
+func (f fakeBundler) Export(context.Context, string, io.Writer) error {
+	return errUnexpectedCall
+}
+
+func (f fakeBundler) Import(_ context.Context, r io.Reader, opts image.ImportOptions) (string, error) {
+	if f.importBundle == nil {
+		return "", errUnexpectedCall
+	}
+	return f.importBundle(r, opts)
+}
+
+type fakeStore struct {
+	download func(name string, w io.Writer) error
+}
+
+func (f fakeStore) Upload(context.Context, string, io.Reader) (*model.RemoteSnapshot, error) {
+	return nil, errUnexpectedCall
+}
+
+func (f fakeStore) Download(_ context.Context, name string, w io.Writer) error {
+	if f.download == nil {
+		return errUnexpectedCall
+	}
+	return f.download(name, w)
+}
+
+func TestServiceRun(t *testing.T) {
+	errTest := errors.New("whatever")
+	downloadBundle := func(err error) func(name string, w io.Writer) error {
+		return func(name string, w io.Writer) error {
+			assert.Equal(t, "my-snap", name)
+			if err != nil {
+				return err
+			}
+			_, _ = w.Write([]byte("bundle"))
+			return nil
+		}
+	}
+	importBundle := func(expOpts image.ImportOptions, imported string) func(r io.Reader, opts image.ImportOptions) (string, error) {
+		return func(r io.Reader, opts image.ImportOptions) (string, error) {
+			assert.Equal(t, expOpts, opts)
+			data, _ := io.ReadAll(r)
+			assert.Equal(t, "bundle", string(data))
+			return imported, nil
+		}
+	}
+
+	tests := map[string]struct {
+		mock    func(mm *imagemock.MockImageManager)
+		bundler fakeBundler
+		store   fakeStore
+		req     snapshotfetch.Request
+		expErr  error
+	}{
+		"Fetching a snapshot should import its bundle from the store.": {
+			mock: func(mm *imagemock.MockImageManager) {
+				mm.On("Exists", mock.Anything, "my-snap").Once().Return(false, nil)
+			},
+			bundler: fakeBundler{importBundle: importBundle(image.ImportOptions{}, "my-snap")},
+			store:   fakeStore{download: downloadBundle(nil)},
+			req:     snapshotfetch.Request{Name: "my-snap"},
+		},
+
+		"Fetching an installed snapshot should fail before downloading it.": {
+			mock: func(mm *imagemock.MockImageManager) {
+				mm.On("Exists", mock.Anything, "my-snap").Once().Return(true, nil)
+			},
+			req:    snapshotfetch.Request{Name: "my-snap"},
+			expErr: model.ErrAlreadyExists,
+		},
+
+		"Force fetching an installed snapshot should replace it.": {
+			mock:    func(mm *imagemock.MockImageManager) {},
+			bundler: fakeBundler{importBundle: importBundle(image.ImportOptions{Force: true}, "my-snap")},
+			store:   fakeStore{download: downloadBundle(nil)},
+			req:     snapshotfetch.Request{Name: "my-snap", Force: true},
+		},
+
+		"Fetching a missing snapshot should fail with the download error.": {
+			mock: func(mm *imagemock.MockImageManager) {
+				mm.On("Exists", mock.Anything, "my-snap").Once().Return(false, nil)
+			},
+			bundler: fakeBundler{importBundle: func(r io.Reader, _ image.ImportOptions) (string, error) {
+				_, _ = io.ReadAll(r)
+				return "", errTest
+			}},
+			store:  fakeStore{download: downloadBundle(model.ErrNotFound)},
+			req:    snapshotfetch.Request{Name: "my-snap"},
+			expErr: model.ErrNotFound,
+		},
+
+		"A bundle of another image should fail.": {
+			mock: func(mm *imagemock.MockImageManager) {
+				mm.On("Exists", mock.Anything, "my-snap").Once().Return(false, nil)
+			},
+			bundler: fakeBundler{importBundle: importBundle(image.ImportOptions{}, "other")},
+			store:   fakeStore{download: downloadBundle(nil)},
+			req:     snapshotfetch.Request{Name: "my-snap"},
+			expErr:  model.ErrNotValid,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mm := imagemock.NewMockImageManager(t)
+			test.mock(mm)
+
+			svc, err := snapshotfetch.NewService(snapshotfetch.ServiceConfig{ImageManager: mm, Bundler: test.bundler, Store: test.store})
+			require.NoError(t, err)
+
+			err = svc.Run(context.Background(), test.req)
+			if test.expErr != nil {
+				assert.ErrorIs(t, err, test.expErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package snapshotpush
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+// ServiceConfig is the configuration for the snapshot push service.
+type ServiceConfig struct {
+	Bundler image.ImageBundler
+	Store   image.SnapshotStore
+	Logger  log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Bundler == nil {
+		return fmt.Errorf("image bundler is required")
+	}
+	if c.Store == nil {
+		return fmt.Errorf("snapshot store is required")
+	}
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.SnapshotPush"})
+	return nil
+}
+
+// errUploadStopped stops the export when the upload doesn't read the bundle anymore.
+var errUploadStopped = errors.New("upload stopped")
+
+// Service uploads installed snapshot images to the snapshot store.
+type Service struct {
+	bundler image.ImageBundler
+	store   image.SnapshotStore
+	logger  log.Logger
+}
+
+// NewService creates a new snapshot push service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &Service{
+		bundler: cfg.Bundler,
+		store:   cfg.Store,
+		logger:  cfg.Logger,
+	}, nil
+}
+
+// Request is the push request parameters.
+type Request struct {
+	// Name is the installed image to upload (usually a snapshot).
+	Name string
+}
+
+// Run streams the bundle of an installed image to the snapshot store, the bundle
+// is never written to the local disk.
+func (s *Service) Run(ctx context.Context, req Request) (*model.RemoteSnapshot, error) {
+	if err := model.ValidateImageName(req.Name); err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	exportErr := make(chan error, 1)
+	go func() {
+		err := s.bundler.Export(ctx, req.Name, pw)
+		pw.CloseWithError(err)
+		exportErr <- err
+	}()
+
+	snap, err := s.store.Upload(ctx, req.Name, pr)
+	// Unblock the export when the upload stopped reading.
+	pr.CloseWithError(errUploadStopped)
+	if eErr := <-exportErr; eErr != nil && !errors.Is(eErr, errUploadStopped) {
+		return nil, fmt.Errorf("pushing snapshot %s: %w", req.Name, eErr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("pushing snapshot %s: %w", req.Name, err)
+	}
+
+	s.logger.Infof("Pushed snapshot %s to %s", req.Name, snap.URL)
+	return snap, nil
+}
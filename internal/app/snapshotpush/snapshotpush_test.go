@@ -0,0 +1,122 @@
+package snapshotpush_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/snapshotpush"
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/model"
+)
+
+var errUnexpectedCall = errors.New("unexpected call")
+
+// fakeBundler and fakeStore are hand-written instead of mocks: the mocks format
+// their arguments, the pipes, while the service closes them.
+type fakeBundler struct {
+	export func(name string, w io.Writer) error
+}
+
+func (f fakeBundler) Export(_ context.Context, name string, w io.Writer) error {
+	if f.export == nil {
+		return errUnexpectedCall
+	}
+	return f.export(name, w)
+}
+
+func (f fakeBundler) Import(context.Context, io.Reader, image.ImportOptions) (string, error) {
+	return "", errUnexpectedCall
+}
+
+type fakeStore struct {
+	upload func(name string, r io.Reader) (*model.RemoteSnapshot, error)
+}
+
+func (f fakeStore) Upload(_ context.Context, name string, r io.Reader) (*model.RemoteSnapshot, error) {
+	if f.upload == nil {
+		return nil, errUnexpectedCall
+	}
+	return f.upload(name, r)
+}
+
+func (f fakeStore) Download(context.Context, string, io.Writer) error {
+	return errUnexpectedCall
+}
+
+func TestServiceRun(t *testing.T) {
+	errTest := errors.New("whatever")
+	exportBundle := func(err error) func(name string, w io.Writer) error {
+		return func(name string, w io.Writer) error {
+			assert.Equal(t, "my-snap", name)
+			if err != nil {
+				return err
+			}
+			_, _ = w.Write([]byte("bundle"))
+			return nil
+		}
+	}
+	tests := map[string]struct {
+		bundler fakeBundler
+		store   fakeStore
+		req     snapshotpush.Request
+		expSnap *model.RemoteSnapshot
+		expErr  error
+	}{
+		"Pushing a snapshot should stream its bundle to the store.": {
+			bundler: fakeBundler{export: exportBundle(nil)},
+			store: fakeStore{upload: func(name string, r io.Reader) (*model.RemoteSnapshot, error) {
+				assert.Equal(t, "my-snap", name)
+				data, _ := io.ReadAll(r)
+				assert.Equal(t, "bundle", string(data))
+				return &model.RemoteSnapshot{Name: "my-snap", URL: "s3://b/my-snap.tar", SizeBytes: 6, Parts: 1}, nil
+			}},
+			req:     snapshotpush.Request{Name: "my-snap"},
+			expSnap: &model.RemoteSnapshot{Name: "my-snap", URL: "s3://b/my-snap.tar", SizeBytes: 6, Parts: 1},
+		},
+
+		"Pushing a missing image should fail with the export error.": {
+			bundler: fakeBundler{export: exportBundle(model.ErrNotFound)},
+			store: fakeStore{upload: func(name string, r io.Reader) (*model.RemoteSnapshot, error) {
+				data, _ := io.ReadAll(r)
+				assert.Empty(t, data)
+				return nil, errTest
+			}},
+			req:    snapshotpush.Request{Name: "my-snap"},
+			expErr: model.ErrNotFound,
+		},
+
+		"An upload error should fail.": {
+			bundler: fakeBundler{export: exportBundle(nil)},
+			store: fakeStore{upload: func(string, io.Reader) (*model.RemoteSnapshot, error) {
+				return nil, errTest
+			}},
+			req:    snapshotpush.Request{Name: "my-snap"},
+			expErr: errTest,
+		},
+
+		"An invalid name should fail.": {
+			req:    snapshotpush.Request{Name: "../snap"},
+			expErr: model.ErrNotValid,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			svc, err := snapshotpush.NewService(snapshotpush.ServiceConfig{Bundler: test.bundler, Store: test.store})
+			require.NoError(t, err)
+
+			snap, err := svc.Run(context.Background(), test.req)
+			if test.expErr != nil {
+				assert.ErrorIs(t, err, test.expErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expSnap, snap)
+		})
+	}
+}
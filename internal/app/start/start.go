@@ -1,25 +1,39 @@
 package start
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/slok/sbx/internal/capacity"
+	"github.com/slok/sbx/internal/hooks"
+	"github.com/slok/sbx/internal/integrity"
 	"github.com/slok/sbx/internal/log"
 	"github.com/slok/sbx/internal/model"
 	"github.com/slok/sbx/internal/sandbox"
 	"github.com/slok/sbx/internal/storage"
+	"github.com/slok/sbx/internal/userdata"
 )
 
 // ServiceConfig is the configuration for the start service.
 type ServiceConfig struct {
 	Engine     sandbox.Engine
 	Repository storage.Repository
-	Logger     log.Logger
+	// Locker serializes the sandbox operations. Defaults to the repository when it
+	// implements it, otherwise the operations are not serialized.
+	Locker storage.SandboxLocker
+	// Admission checks the host capacity before starting the sandbox (optional).
+	Admission *capacity.Planner
+	// Hooks runs the post-start host hooks (optional).
+	Hooks  hooks.Runner
+	Logger log.Logger
 }
 
 func (c *ServiceConfig) defaults() error {
@@ -31,6 +45,14 @@ func (c *ServiceConfig) defaults() error {
 		return fmt.Errorf("repository is required")
 	}
 
+	if c.Locker == nil {
+		c.Locker, _ = c.Repository.(storage.SandboxLocker)
+	}
+
+	if c.Hooks == nil {
+		c.Hooks = hooks.Noop
+	}
+
 	if c.Logger == nil {
 		c.Logger = log.Noop
 	}
@@ -40,9 +62,12 @@ func (c *ServiceConfig) defaults() error {
 
 // Service starts a stopped sandbox.
 type Service struct {
-	engine sandbox.Engine
-	repo   storage.Repository
-	logger log.Logger
+	engine    sandbox.Engine
+	repo      storage.Repository
+	locker    storage.SandboxLocker
+	admission *capacity.Planner
+	hooks     hooks.Runner
+	logger    log.Logger
 }
 
 // NewService creates a new start service.
@@ -52,9 +77,12 @@ func NewService(cfg ServiceConfig) (*Service, error) {
 	}
 
 	return &Service{
-		engine: cfg.Engine,
-		repo:   cfg.Repository,
-		logger: cfg.Logger,
+		engine:    cfg.Engine,
+		repo:      cfg.Repository,
+		locker:    cfg.Locker,
+		admission: cfg.Admission,
+		hooks:     cfg.Hooks,
+		logger:    cfg.Logger,
 	}, nil
 }
 
@@ -63,14 +91,34 @@ type Request struct {
 	// NameOrID is the sandbox name or ID to start.
 	NameOrID string
 	// SessionConfig is the optional session configuration applied at start time.
+	// Without it the session of the previous start is applied again, so a sandbox
+	// stopped by a host reboot keeps its env and egress policy.
 	SessionConfig model.SessionConfig
+	// ResetSession starts the sandbox without the session of the previous start
+	// when SessionConfig is empty.
+	ResetSession bool
+	// Timeouts are the optional start step timeouts, the unset ones use the engine defaults.
+	Timeouts model.StartTimeouts
+	// Progress receives the start steps (optional).
+	Progress model.ProgressFunc
 }
 
 // Run starts a sandbox by name or ID.
 // It validates the sandbox is created or stopped before attempting to start it.
+// If the start fails once the engine started the sandbox (e.g. running the user
+// data), the sandbox is stopped, its previous state is restored and a
+// [model.StartError] with the failed step is returned.
 func (s *Service) Run(ctx context.Context, req Request) (*model.Sandbox, error) {
 	s.logger.Debugf("starting sandbox: %s", req.NameOrID)
 
+	if err := req.Timeouts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid start timeouts: %w", err)
+	}
+
+	if err := req.SessionConfig.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid session config: %w", err)
+	}
+
 	// Lookup sandbox by name first, then by ID if it looks like a ULID.
 	sb, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
 	if errors.Is(err, model.ErrNotFound) && looksLikeULID(req.NameOrID) {
@@ -83,26 +131,112 @@ func (s *Service) Run(ctx context.Context, req Request) (*model.Sandbox, error)
 		return nil, fmt.Errorf("could not get sandbox: %w", err)
 	}
 
+	// Serialize the operations on the sandbox, the sandbox is read again once locked
+	// so the checks use its latest state.
+	if s.locker != nil {
+		unlock, err := s.locker.LockSandbox(ctx, sb.ID)
+		if err != nil {
+			return nil, fmt.Errorf("could not lock sandbox: %w", err)
+		}
+		defer unlock()
+
+		sb, err = s.repo.GetSandbox(ctx, sb.ID)
+		if err != nil {
+			return nil, fmt.Errorf("could not get sandbox: %w", err)
+		}
+	}
+
 	// Validate sandbox is in a startable state (stopped).
 	if sb.Status != model.SandboxStatusStopped {
 		return nil, fmt.Errorf("cannot start sandbox: not in startable state (current status: %s): %w", sb.Status, model.ErrNotValid)
 	}
 
-	sessionCfg := normalizeSessionConfig(req.SessionConfig)
+	// Check host capacity.
+	if s.admission != nil {
+		if err := s.admission.AdmitStart(ctx, *sb); err != nil {
+			return nil, fmt.Errorf("sandbox not admitted: %w", err)
+		}
+	}
+
+	reqSession := req.SessionConfig
+	if reqSession.IsZero() && !req.ResetSession && sb.Session != nil {
+		s.logger.Debugf("applying the session of the previous start to sandbox: %s", sb.ID)
+		reqSession = *sb.Session
+	}
+	sessionCfg := normalizeSessionConfig(reqSession)
+	egress, err := s.resolveEgress(ctx, sessionCfg)
+	if err != nil {
+		return nil, err
+	}
 
 	// Start the sandbox via engine.
 	startOpts := sandbox.StartOpts{
-		Egress: sessionCfg.Egress,
+		Egress:     egress,
+		Timeouts:   req.Timeouts,
+		Progress:   req.Progress,
+		Quarantine: sb.QuarantinedAt != nil,
+	}
+	// The sandbox state before the start is restored if the start fails after the
+	// engine started it, the engine rolls back its own failed start steps.
+	prev := *sb
+	phaseStart := time.Now()
+	phases, err := s.engine.Start(ctx, sb.ID, startOpts)
+	if err != nil {
+		err = fmt.Errorf("could not start sandbox: %w", err)
+		s.recordFailedStart(ctx, prev, engineFailedPhases(err, time.Since(phaseStart)), err)
+		return nil, err
+	}
+
+	// Engines without phases report the whole start as the boot.
+	if len(phases) == 0 {
+		phases = []model.BootPhase{{Name: "boot", Duration: time.Since(phaseStart)}}
+	}
+
+	// The guest setup phases are measured after the engine ones.
+	phaseStart = time.Now()
+	endPhase := func(name string) {
+		now := time.Now()
+		phases = append(phases, model.BootPhase{Name: name, Duration: now.Sub(phaseStart)})
+		phaseStart = now
+	}
+	failPhase := func(step string, err error) error {
+		phases = append(phases, model.BootPhase{Name: step, Duration: time.Since(phaseStart), Error: err.Error()})
+		return s.rollbackStart(ctx, prev, phases, step, err)
+	}
+
+	// The clock is set before anything else runs in the guest.
+	if sb.Config.Clock != nil {
+		req.Progress.Report(model.ProgressEvent{Step: "clock", Percent: -1, Message: "Setting the guest clock"})
+		if err := s.applyClock(ctx, sb.ID, *sb.Config.Clock); err != nil {
+			return nil, failPhase("clock", fmt.Errorf("could not set sandbox clock: %w", err))
+		}
+		endPhase("clock")
+	}
+
+	req.Progress.Report(model.ProgressEvent{Step: "session_env", Percent: -1, Message: "Applying the session environment"})
+	if err := s.applySessionEnvToSandbox(ctx, sb.ID, sessionCfg.Env, sb.Config.ExecProfile); err != nil {
+		return nil, failPhase("session_env", fmt.Errorf("could not apply session environment: %w", err))
 	}
-	if err := s.engine.Start(ctx, sb.ID, startOpts); err != nil {
-		return nil, fmt.Errorf("could not start sandbox: %w", err)
+	endPhase("session_env")
+
+	// User data only runs on the first boot, if it fails the sandbox is stopped and
+	// it runs again on the next start.
+	if sb.StartedAt == nil && sb.Config.UserData != "" {
+		req.Progress.Report(model.ProgressEvent{Step: "user_data", Percent: -1, Message: "Running the user data"})
+		if err := s.runUserData(ctx, sb.ID, sb.Config.UserData); err != nil {
+			return nil, failPhase("user_data", fmt.Errorf("could not run user data: %w", err))
+		}
+		endPhase("user_data")
 	}
 
-	if err := s.applySessionEnvToSandbox(ctx, sb.ID, sessionCfg.Env); err != nil {
-		if stopErr := s.engine.Stop(ctx, sb.ID); stopErr != nil {
-			s.logger.Warningf("could not stop sandbox after env setup failure: %v", stopErr)
+	// The integrity baseline is captured once the guest is provisioned, replacing the
+	// one of the previous start.
+	if len(sessionCfg.IntegrityPaths) > 0 {
+		req.Progress.Report(model.ProgressEvent{Step: "integrity", Percent: -1, Message: "Capturing the integrity baseline"})
+		if err := s.captureIntegrityBaseline(ctx, sb.ID, sessionCfg.IntegrityPaths); err != nil {
+			return nil, failPhase("integrity", fmt.Errorf("could not capture integrity baseline: %w", err))
 		}
-		return nil, fmt.Errorf("could not apply session environment: %w", err)
+		endPhase("integrity")
 	}
 
 	// Update sandbox state in repository.
@@ -110,20 +244,104 @@ func (s *Service) Run(ctx context.Context, req Request) (*model.Sandbox, error)
 	sb.Status = model.SandboxStatusRunning
 	sb.StartedAt = &now
 	sb.StoppedAt = nil
+	sb.LastBootPhases = phases
+	sb.LastError = ""
+	// The session is kept so the restarts apply it again, the egress policy names are
+	// resolved again on each start.
+	sb.Session = &sessionCfg
 
 	if err := s.repo.UpdateSandbox(ctx, *sb); err != nil {
-		return nil, fmt.Errorf("could not update sandbox: %w", err)
+		return nil, failPhase("update", fmt.Errorf("could not update sandbox: %w", err))
+	}
+
+	// The sandbox is already running, failed post-start hooks don't fail the start.
+	if err := s.hooks.Run(ctx, model.HookEventPostStart, *sb); err != nil {
+		s.logger.Warningf("post-start hooks of sandbox %s failed: %v", sb.Name, err)
 	}
 
-	s.logger.Infof("started sandbox: %s (ID: %s)", sb.Name, sb.ID)
+	sb.BootPhases = phases
+	s.logger.Infof("started sandbox: %s (ID: %s) in %s", sb.Name, sb.ID, totalDuration(phases).Round(time.Millisecond))
 	return sb, nil
 }
 
+// rollbackStart stops the sandbox started by the engine and restores its previous
+// state in the repository, with the failed start phases, so a failed start leaves
+// the sandbox as it was. It returns the start error of the failed step with the
+// rollback errors, if any.
+func (s *Service) rollbackStart(ctx context.Context, prev model.Sandbox, phases []model.BootPhase, step string, err error) error {
+	s.logger.Warningf("start failed on %s step, rolling back: %v", step, err)
+	startErr := &model.StartError{Step: step, Err: err, Phases: phases}
+	prev.LastBootPhases = phases
+	prev.LastError = startErr.Error()
+
+	// The rollback runs even if the start was canceled.
+	ctx = context.WithoutCancel(ctx)
+	var rbErrs []error
+	if stopErr := s.engine.Stop(ctx, prev.ID, sandbox.StopOpts{}); stopErr != nil {
+		rbErrs = append(rbErrs, fmt.Errorf("could not stop sandbox: %w", stopErr))
+	}
+	if updateErr := s.repo.UpdateSandbox(ctx, prev); updateErr != nil {
+		rbErrs = append(rbErrs, fmt.Errorf("could not restore sandbox state: %w", updateErr))
+	}
+
+	startErr.RollbackErr = errors.Join(rbErrs...)
+	return startErr
+}
+
+// recordFailedStart stores the phases and the error of a start failed in the engine,
+// the engine already rolled it back so the sandbox state is kept.
+func (s *Service) recordFailedStart(ctx context.Context, prev model.Sandbox, phases []model.BootPhase, err error) {
+	prev.LastBootPhases = phases
+	prev.LastError = err.Error()
+	if updateErr := s.repo.UpdateSandbox(context.WithoutCancel(ctx), prev); updateErr != nil {
+		s.logger.Warningf("could not store sandbox %s failed start: %v", prev.ID, updateErr)
+	}
+}
+
+// engineFailedPhases returns the phases of a start failed in the engine, the engines
+// without phases report the whole start as the failed boot.
+func engineFailedPhases(err error, elapsed time.Duration) []model.BootPhase {
+	var startErr *model.StartError
+	if errors.As(err, &startErr) && len(startErr.Phases) > 0 {
+		return startErr.Phases
+	}
+	return []model.BootPhase{{Name: "boot", Duration: elapsed, Error: err.Error()}}
+}
+
+// totalDuration returns the sum of the phases duration.
+func totalDuration(phases []model.BootPhase) time.Duration {
+	var total time.Duration
+	for _, p := range phases {
+		total += p.Duration
+	}
+	return total
+}
+
+// resolveEgress returns the egress policy of the session, the named ones are read
+// from the repository so their latest version is used on every start.
+func (s *Service) resolveEgress(ctx context.Context, cfg model.SessionConfig) (*model.EgressPolicy, error) {
+	if cfg.EgressPolicyName == "" {
+		return cfg.Egress, nil
+	}
+
+	p, err := s.repo.GetEgressPolicy(ctx, cfg.EgressPolicyName)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("egress policy not found: %s: %w", cfg.EgressPolicyName, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not get egress policy: %w", err)
+	}
+
+	return &p.Policy, nil
+}
+
 func normalizeSessionConfig(cfg model.SessionConfig) model.SessionConfig {
 	normalized := model.SessionConfig{
-		Name:   cfg.Name,
-		Env:    map[string]string{},
-		Egress: cfg.Egress,
+		Name:             cfg.Name,
+		Env:              map[string]string{},
+		Egress:           cfg.Egress,
+		EgressPolicyName: cfg.EgressPolicyName,
+		IntegrityPaths:   cfg.IntegrityPaths,
 	}
 
 	for k, v := range cfg.Env {
@@ -133,7 +351,9 @@ func normalizeSessionConfig(cfg model.SessionConfig) model.SessionConfig {
 	return normalized
 }
 
-func (s *Service) applySessionEnvToSandbox(ctx context.Context, sandboxID string, env map[string]string) error {
+// applySessionEnvToSandbox writes the session env script sourced by every exec and
+// shell, with the sandbox exec profile after the env.
+func (s *Service) applySessionEnvToSandbox(ctx context.Context, sandboxID string, env map[string]string, profile *model.ExecProfile) error {
 	if _, err := s.engine.Exec(ctx, sandboxID, []string{"mkdir", "-p", "/etc/sbx", "/etc/profile.d", "/root/.ssh"}, model.ExecOpts{}); err != nil {
 		return fmt.Errorf("could not create session env directories: %w", err)
 	}
@@ -145,7 +365,7 @@ func (s *Service) applySessionEnvToSandbox(ctx context.Context, sandboxID string
 	tmpSessionPath := tmpSessionFile.Name()
 	defer os.Remove(tmpSessionPath)
 
-	if _, err := tmpSessionFile.WriteString(renderSessionEnvScript(env)); err != nil {
+	if _, err := tmpSessionFile.WriteString(renderSessionEnvScript(env, profile)); err != nil {
 		tmpSessionFile.Close()
 		return fmt.Errorf("could not write temporary session env file: %w", err)
 	}
@@ -183,15 +403,15 @@ func (s *Service) applySessionEnvToSandbox(ctx context.Context, sandboxID string
 		return fmt.Errorf("could not close temporary ssh rc file: %w", err)
 	}
 
-	if err := s.engine.CopyTo(ctx, sandboxID, tmpSessionPath, "/etc/sbx/session-env.sh"); err != nil {
+	if err := s.engine.CopyTo(ctx, sandboxID, tmpSessionPath, "/etc/sbx/session-env.sh", model.CopyOpts{}); err != nil {
 		return fmt.Errorf("could not copy session env script: %w", err)
 	}
 
-	if err := s.engine.CopyTo(ctx, sandboxID, tmpProfileHookPath, "/etc/profile.d/sbx-session-env.sh"); err != nil {
+	if err := s.engine.CopyTo(ctx, sandboxID, tmpProfileHookPath, "/etc/profile.d/sbx-session-env.sh", model.CopyOpts{}); err != nil {
 		return fmt.Errorf("could not copy profile hook script: %w", err)
 	}
 
-	if err := s.engine.CopyTo(ctx, sandboxID, tmpSSHRCPath, "/root/.ssh/rc"); err != nil {
+	if err := s.engine.CopyTo(ctx, sandboxID, tmpSSHRCPath, "/root/.ssh/rc", model.CopyOpts{}); err != nil {
 		return fmt.Errorf("could not copy ssh rc script: %w", err)
 	}
 
@@ -206,7 +426,111 @@ func (s *Service) applySessionEnvToSandbox(ctx context.Context, sandboxID string
 	return nil
 }
 
-func renderSessionEnvScript(env map[string]string) string {
+func (s *Service) applyClock(ctx context.Context, sandboxID string, clock model.ClockConfig) error {
+	t := clock.Time(time.Now())
+
+	var out bytes.Buffer
+	result, err := s.engine.Exec(ctx, sandboxID, []string{"sh", "-c", renderClockScript(t)}, model.ExecOpts{Stdout: &out, Stderr: &out})
+	if err != nil {
+		return fmt.Errorf("could not execute clock script: %w", err)
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("clock script failed with exit code %d: %s", result.ExitCode, lastLines(out.String(), 10))
+	}
+
+	s.logger.Debugf("Set sandbox clock to %s", t.UTC().Format(time.RFC3339))
+	return nil
+}
+
+// renderClockScript renders the script that stops the guest time sync daemons, so
+// they don't set the host time back, and sets the guest clock to t.
+func renderClockScript(t time.Time) string {
+	var b strings.Builder
+	b.WriteString("for svc in systemd-timesyncd chronyd chrony ntpd ntp openntpd; do\n")
+	b.WriteString("  if command -v systemctl >/dev/null 2>&1; then systemctl disable --now \"$svc\" >/dev/null 2>&1 || true; fi\n")
+	b.WriteString("  if command -v rc-service >/dev/null 2>&1; then rc-service \"$svc\" stop >/dev/null 2>&1 || true; fi\n")
+	b.WriteString("done\n")
+	b.WriteString("pkill -x chronyd >/dev/null 2>&1 || true\n")
+	b.WriteString("pkill -x ntpd >/dev/null 2>&1 || true\n")
+	fmt.Fprintf(&b, "date -u -s @%d >/dev/null\n", t.Unix())
+	return b.String()
+}
+
+func (s *Service) captureIntegrityBaseline(ctx context.Context, sandboxID string, paths []string) error {
+	files, err := integrity.Collect(ctx, s.engine, sandboxID, paths)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.SaveIntegrityBaseline(ctx, model.IntegrityBaseline{
+		SandboxID: sandboxID,
+		Paths:     paths,
+		Files:     files,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("could not save integrity baseline: %w", err)
+	}
+
+	s.logger.Debugf("Captured integrity baseline of %d files", len(files))
+	return nil
+}
+
+// userDataPath is the guest path of the user data script.
+const userDataPath = "/etc/sbx/user-data"
+
+func (s *Service) runUserData(ctx context.Context, sandboxID string, data string) error {
+	script, err := userdata.Script(data)
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := os.CreateTemp("", "sbx-user-data-*")
+	if err != nil {
+		return fmt.Errorf("could not create temporary user data file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmpFile.WriteString(script); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("could not write temporary user data file: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("could not close temporary user data file: %w", err)
+	}
+
+	if err := s.engine.CopyTo(ctx, sandboxID, tmpPath, userDataPath, model.CopyOpts{}); err != nil {
+		return fmt.Errorf("could not copy user data script: %w", err)
+	}
+
+	if _, err := s.engine.Exec(ctx, sandboxID, []string{"chmod", "700", userDataPath}, model.ExecOpts{}); err != nil {
+		return fmt.Errorf("could not set user data script permissions: %w", err)
+	}
+
+	s.logger.Infof("Running user data on sandbox first boot")
+	var out bytes.Buffer
+	result, err := s.engine.Exec(ctx, sandboxID, []string{userDataPath}, model.ExecOpts{Stdout: &out, Stderr: &out})
+	if err != nil {
+		return fmt.Errorf("could not execute user data script: %w", err)
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("user data script failed with exit code %d: %s", result.ExitCode, lastLines(out.String(), 10))
+	}
+
+	return nil
+}
+
+// lastLines returns the last n lines of s.
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func renderSessionEnvScript(env map[string]string, profile *model.ExecProfile) string {
 	keys := make([]string, 0, len(env))
 	for k := range env {
 		keys = append(keys, k)
@@ -225,6 +549,34 @@ func renderSessionEnvScript(env map[string]string) string {
 		b.WriteString("'\n")
 	}
 
+	if profile != nil {
+		b.WriteString(renderExecProfileScript(*profile))
+	}
+
+	return b.String()
+}
+
+// renderExecProfileScript renders the exec profile setup. The ulimits the guest
+// doesn't allow are ignored, a failing limit must not break every exec.
+func renderExecProfileScript(p model.ExecProfile) string {
+	var b strings.Builder
+	b.WriteString("# Exec profile.\n")
+	if len(p.PathPrepend) > 0 {
+		fmt.Fprintf(&b, "export PATH='%s':\"$PATH\"\n", escapeShellSingleQuoted(strings.Join(p.PathPrepend, ":")))
+	}
+	if p.Locale != "" {
+		fmt.Fprintf(&b, "export LANG='%s' LC_ALL='%s'\n", p.Locale, p.Locale)
+	}
+	if p.Umask != "" {
+		fmt.Fprintf(&b, "umask %s\n", p.Umask)
+	}
+	for _, name := range slices.Sorted(maps.Keys(p.Ulimits)) {
+		fmt.Fprintf(&b, "ulimit %s %s 2>/dev/null\n", model.UlimitFlags[name], p.Ulimits[name])
+	}
+	if p.Shell != "" {
+		fmt.Fprintf(&b, "export SHELL='%s'\n", escapeShellSingleQuoted(p.Shell))
+	}
+
 	return b.String()
 }
 
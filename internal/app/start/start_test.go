@@ -3,6 +3,8 @@ package start_test
 import (
 	"context"
 	"fmt"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,6 +15,7 @@ import (
 	"github.com/slok/sbx/internal/app/start"
 	"github.com/slok/sbx/internal/log"
 	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
 	"github.com/slok/sbx/internal/sandbox/sandboxmock"
 	"github.com/slok/sbx/internal/storage/storagemock"
 )
@@ -64,10 +67,13 @@ func TestService_Run(t *testing.T) {
 	stoppedAt := time.Date(2026, 1, 30, 12, 0, 0, 0, time.UTC)
 
 	tests := map[string]struct {
-		mockRepo   func(m *storagemock.MockRepository)
-		mockEngine func(m *sandboxmock.MockEngine)
-		req        start.Request
-		expErr     bool
+		mockRepo       func(m *storagemock.MockRepository)
+		mockEngine     func(m *sandboxmock.MockEngine)
+		req            start.Request
+		expPhases      []string
+		expErrStep     string
+		expRollbackErr bool
+		expErr         bool
 	}{
 		"start stopped sandbox": {
 			mockRepo: func(m *storagemock.MockRepository) {
@@ -84,16 +90,54 @@ func TestService_Run(t *testing.T) {
 				})).Once().Return(nil)
 			},
 			mockEngine: func(m *sandboxmock.MockEngine) {
-				m.On("Start", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything).Once().Return(nil)
+				m.On("Start", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything).Once().Return([]model.BootPhase{{Name: "boot", Duration: time.Second}, {Name: "ssh", Duration: time.Second}}, nil)
 				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", []string{"mkdir", "-p", "/etc/sbx", "/etc/profile.d", "/root/.ssh"}, mock.Anything).Once().Return(&model.ExecResult{}, nil)
-				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/etc/sbx/session-env.sh").Once().Return(nil)
-				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/etc/profile.d/sbx-session-env.sh").Once().Return(nil)
-				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/root/.ssh/rc").Once().Return(nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/etc/sbx/session-env.sh", model.CopyOpts{}).Once().Return(nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/etc/profile.d/sbx-session-env.sh", model.CopyOpts{}).Once().Return(nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/root/.ssh/rc", model.CopyOpts{}).Once().Return(nil)
 				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", []string{"chmod", "644", "/etc/sbx/session-env.sh", "/etc/profile.d/sbx-session-env.sh"}, mock.Anything).Once().Return(&model.ExecResult{}, nil)
 				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", []string{"chmod", "700", "/root/.ssh/rc"}, mock.Anything).Once().Return(&model.ExecResult{}, nil)
 			},
-			req:    start.Request{NameOrID: "my-sandbox"},
-			expErr: false,
+			req:       start.Request{NameOrID: "my-sandbox"},
+			expPhases: []string{"boot", "ssh", "session_env"},
+			expErr:    false,
+		},
+		"start sandbox with exec profile writes it in the session env": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:     "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:   "my-sandbox",
+					Status: model.SandboxStatusStopped,
+					Config: model.SandboxConfig{ExecProfile: &model.ExecProfile{
+						PathPrepend: []string{"/opt/tools/bin", "/root/.local/bin"},
+						Locale:      "C.UTF-8",
+						Umask:       "027",
+						Ulimits:     map[string]string{"nofile": "65536", "core": "unlimited"},
+						Shell:       "/bin/bash",
+					}},
+					CreatedAt: createdAt,
+					StartedAt: &startedAt,
+				}, nil)
+				m.On("UpdateSandbox", mock.Anything, mock.Anything).Once().Return(nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Start", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything).Once().Return(nil, nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/etc/sbx/session-env.sh", model.CopyOpts{}).Once().Run(func(args mock.Arguments) {
+					data, err := os.ReadFile(args.String(2))
+					require.NoError(t, err)
+					assert.Contains(t, string(data), "export PATH='/opt/tools/bin:/root/.local/bin':\"$PATH\"\n"+
+						"export LANG='C.UTF-8' LC_ALL='C.UTF-8'\n"+
+						"umask 027\n"+
+						"ulimit -c unlimited 2>/dev/null\n"+
+						"ulimit -n 65536 2>/dev/null\n"+
+						"export SHELL='/bin/bash'\n")
+				}).Return(nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, mock.Anything, model.CopyOpts{}).Return(nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, mock.Anything).Return(&model.ExecResult{}, nil)
+			},
+			req:       start.Request{NameOrID: "my-sandbox"},
+			expPhases: []string{"boot", "session_env"},
+			expErr:    false,
 		},
 		"cannot start already running sandbox": {
 			mockRepo: func(m *storagemock.MockRepository) {
@@ -122,17 +166,275 @@ func TestService_Run(t *testing.T) {
 				})).Once().Return(nil)
 			},
 			mockEngine: func(m *sandboxmock.MockEngine) {
-				m.On("Start", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything).Once().Return(nil)
+				m.On("Start", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything).Once().Return(nil, nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", []string{"mkdir", "-p", "/etc/sbx", "/etc/profile.d", "/root/.ssh"}, mock.Anything).Once().Return(&model.ExecResult{}, nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/etc/sbx/session-env.sh", model.CopyOpts{}).Once().Return(nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/etc/profile.d/sbx-session-env.sh", model.CopyOpts{}).Once().Return(nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/root/.ssh/rc", model.CopyOpts{}).Once().Return(nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", []string{"chmod", "644", "/etc/sbx/session-env.sh", "/etc/profile.d/sbx-session-env.sh"}, mock.Anything).Once().Return(&model.ExecResult{}, nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", []string{"chmod", "700", "/root/.ssh/rc"}, mock.Anything).Once().Return(&model.ExecResult{}, nil)
+			},
+			req:    start.Request{NameOrID: "my-sandbox"},
+			expErr: false,
+		},
+		"start freshly created sandbox with user data runs the user data": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:        "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:      "my-sandbox",
+					Status:    model.SandboxStatusStopped,
+					Config:    model.SandboxConfig{UserData: "apk add git\n"},
+					CreatedAt: createdAt,
+				}, nil)
+				m.On("UpdateSandbox", mock.Anything, mock.MatchedBy(func(s model.Sandbox) bool {
+					return s.Status == model.SandboxStatusRunning && s.StartedAt != nil
+				})).Once().Return(nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Start", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything).Once().Return(nil, nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", []string{"mkdir", "-p", "/etc/sbx", "/etc/profile.d", "/root/.ssh"}, mock.Anything).Once().Return(&model.ExecResult{}, nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/etc/sbx/session-env.sh", model.CopyOpts{}).Once().Return(nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/etc/profile.d/sbx-session-env.sh", model.CopyOpts{}).Once().Return(nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/root/.ssh/rc", model.CopyOpts{}).Once().Return(nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", []string{"chmod", "644", "/etc/sbx/session-env.sh", "/etc/profile.d/sbx-session-env.sh"}, mock.Anything).Once().Return(&model.ExecResult{}, nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", []string{"chmod", "700", "/root/.ssh/rc"}, mock.Anything).Once().Return(&model.ExecResult{}, nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/etc/sbx/user-data", model.CopyOpts{}).Once().Return(nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", []string{"chmod", "700", "/etc/sbx/user-data"}, mock.Anything).Once().Return(&model.ExecResult{}, nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", []string{"/etc/sbx/user-data"}, mock.Anything).Once().Return(&model.ExecResult{}, nil)
+			},
+			req:    start.Request{NameOrID: "my-sandbox"},
+			expErr: false,
+		},
+		"start already booted sandbox with user data doesn't run the user data": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:        "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:      "my-sandbox",
+					Status:    model.SandboxStatusStopped,
+					Config:    model.SandboxConfig{UserData: "apk add git\n"},
+					CreatedAt: createdAt,
+					StartedAt: &createdAt,
+					StoppedAt: &stoppedAt,
+				}, nil)
+				m.On("UpdateSandbox", mock.Anything, mock.Anything).Once().Return(nil, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Start", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything).Once().Return(nil, nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", []string{"mkdir", "-p", "/etc/sbx", "/etc/profile.d", "/root/.ssh"}, mock.Anything).Once().Return(&model.ExecResult{}, nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/etc/sbx/session-env.sh", model.CopyOpts{}).Once().Return(nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/etc/profile.d/sbx-session-env.sh", model.CopyOpts{}).Once().Return(nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/root/.ssh/rc", model.CopyOpts{}).Once().Return(nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", []string{"chmod", "644", "/etc/sbx/session-env.sh", "/etc/profile.d/sbx-session-env.sh"}, mock.Anything).Once().Return(&model.ExecResult{}, nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", []string{"chmod", "700", "/root/.ssh/rc"}, mock.Anything).Once().Return(&model.ExecResult{}, nil)
+			},
+			req:    start.Request{NameOrID: "my-sandbox"},
+			expErr: false,
+		},
+		"failing user data stops the sandbox": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:        "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:      "my-sandbox",
+					Status:    model.SandboxStatusStopped,
+					Config:    model.SandboxConfig{UserData: "exit 1\n"},
+					CreatedAt: createdAt,
+				}, nil)
+				m.On("UpdateSandbox", mock.Anything, mock.MatchedBy(func(s model.Sandbox) bool {
+					return s.Status == model.SandboxStatusStopped && s.StartedAt == nil
+				})).Once().Return(nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Start", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything).Once().Return(nil, nil)
 				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", []string{"mkdir", "-p", "/etc/sbx", "/etc/profile.d", "/root/.ssh"}, mock.Anything).Once().Return(&model.ExecResult{}, nil)
-				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/etc/sbx/session-env.sh").Once().Return(nil)
-				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/etc/profile.d/sbx-session-env.sh").Once().Return(nil)
-				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/root/.ssh/rc").Once().Return(nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/etc/sbx/session-env.sh", model.CopyOpts{}).Once().Return(nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/etc/profile.d/sbx-session-env.sh", model.CopyOpts{}).Once().Return(nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/root/.ssh/rc", model.CopyOpts{}).Once().Return(nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", []string{"chmod", "644", "/etc/sbx/session-env.sh", "/etc/profile.d/sbx-session-env.sh"}, mock.Anything).Once().Return(&model.ExecResult{}, nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", []string{"chmod", "700", "/root/.ssh/rc"}, mock.Anything).Once().Return(&model.ExecResult{}, nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/etc/sbx/user-data", model.CopyOpts{}).Once().Return(nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", []string{"chmod", "700", "/etc/sbx/user-data"}, mock.Anything).Once().Return(&model.ExecResult{}, nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", []string{"/etc/sbx/user-data"}, mock.Anything).Once().Return(&model.ExecResult{ExitCode: 1}, nil)
+				m.On("Stop", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", sandbox.StopOpts{}).Once().Return(nil)
+			},
+			req:        start.Request{NameOrID: "my-sandbox"},
+			expErrStep: "user_data",
+			expErr:     true,
+		},
+		"start sandbox with clock sets the guest clock before the session env": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:        "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:      "my-sandbox",
+					Status:    model.SandboxStatusStopped,
+					Config:    model.SandboxConfig{Clock: &model.ClockConfig{BootTime: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}},
+					CreatedAt: createdAt,
+					StartedAt: &startedAt,
+					StoppedAt: &stoppedAt,
+				}, nil)
+				m.On("UpdateSandbox", mock.Anything, mock.Anything).Once().Return(nil, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Start", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything).Once().Return(nil, nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.MatchedBy(func(cmd []string) bool {
+					return len(cmd) == 3 && cmd[0] == "sh" && strings.Contains(cmd[2], "systemd-timesyncd") && strings.Contains(cmd[2], "date -u -s @1893456000")
+				}), mock.Anything).Once().Return(&model.ExecResult{}, nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", []string{"mkdir", "-p", "/etc/sbx", "/etc/profile.d", "/root/.ssh"}, mock.Anything).Once().Return(&model.ExecResult{}, nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/etc/sbx/session-env.sh", model.CopyOpts{}).Once().Return(nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/etc/profile.d/sbx-session-env.sh", model.CopyOpts{}).Once().Return(nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/root/.ssh/rc", model.CopyOpts{}).Once().Return(nil)
 				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", []string{"chmod", "644", "/etc/sbx/session-env.sh", "/etc/profile.d/sbx-session-env.sh"}, mock.Anything).Once().Return(&model.ExecResult{}, nil)
 				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", []string{"chmod", "700", "/root/.ssh/rc"}, mock.Anything).Once().Return(&model.ExecResult{}, nil)
 			},
 			req:    start.Request{NameOrID: "my-sandbox"},
 			expErr: false,
 		},
+		"start sandbox with integrity paths captures the integrity baseline": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:        "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:      "my-sandbox",
+					Status:    model.SandboxStatusStopped,
+					CreatedAt: createdAt,
+					StartedAt: &startedAt,
+					StoppedAt: &stoppedAt,
+				}, nil)
+				m.On("SaveIntegrityBaseline", mock.Anything, mock.MatchedBy(func(b model.IntegrityBaseline) bool {
+					return b.SandboxID == "01H2QWERTYASDFGZXCVBNMLKJH" &&
+						assert.ObjectsAreEqual([]string{"/etc"}, b.Paths) &&
+						assert.ObjectsAreEqual(map[string]string{"/etc/motd": strings.Repeat("a", 64)}, b.Files)
+				})).Once().Return(nil)
+				m.On("UpdateSandbox", mock.Anything, mock.Anything).Once().Return(nil, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Start", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything).Once().Return(nil, nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.MatchedBy(func(cmd []string) bool {
+					return len(cmd) == 3 && cmd[0] == "sh" && strings.Contains(cmd[2], "sha256sum")
+				}), mock.Anything).Once().Run(func(args mock.Arguments) {
+					_, _ = args.Get(3).(model.ExecOpts).Stdout.Write([]byte(strings.Repeat("a", 64) + "  /etc/motd\n"))
+				}).Return(&model.ExecResult{}, nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, mock.Anything).Return(&model.ExecResult{}, nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, mock.Anything, model.CopyOpts{}).Return(nil)
+			},
+			req:       start.Request{NameOrID: "my-sandbox", SessionConfig: model.SessionConfig{IntegrityPaths: []string{"/etc"}}},
+			expPhases: []string{"boot", "session_env", "integrity"},
+			expErr:    false,
+		},
+		"failing integrity baseline stops the sandbox": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:        "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:      "my-sandbox",
+					Status:    model.SandboxStatusStopped,
+					CreatedAt: createdAt,
+				}, nil)
+				m.On("UpdateSandbox", mock.Anything, mock.MatchedBy(func(s model.Sandbox) bool {
+					return s.Status == model.SandboxStatusStopped && s.StartedAt == nil
+				})).Once().Return(nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Start", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything).Once().Return(nil, nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.MatchedBy(func(cmd []string) bool {
+					return len(cmd) == 3 && cmd[0] == "sh" && strings.Contains(cmd[2], "sha256sum")
+				}), mock.Anything).Once().Return(&model.ExecResult{ExitCode: 1}, nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, mock.Anything).Return(&model.ExecResult{}, nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, mock.Anything, model.CopyOpts{}).Return(nil)
+				m.On("Stop", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", sandbox.StopOpts{}).Once().Return(nil)
+			},
+			req:        start.Request{NameOrID: "my-sandbox", SessionConfig: model.SessionConfig{IntegrityPaths: []string{"/etc"}}},
+			expErrStep: "integrity",
+			expErr:     true,
+		},
+		"failing clock setup stops the sandbox": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:        "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:      "my-sandbox",
+					Status:    model.SandboxStatusStopped,
+					Config:    model.SandboxConfig{Clock: &model.ClockConfig{Offset: time.Hour}},
+					CreatedAt: createdAt,
+				}, nil)
+				m.On("UpdateSandbox", mock.Anything, mock.MatchedBy(func(s model.Sandbox) bool {
+					return s.Status == model.SandboxStatusStopped && s.StartedAt == nil
+				})).Once().Return(nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Start", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything).Once().Return(nil, nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, mock.Anything).Once().Return(&model.ExecResult{ExitCode: 1}, nil)
+				m.On("Stop", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", sandbox.StopOpts{}).Once().Return(nil)
+			},
+			req:        start.Request{NameOrID: "my-sandbox"},
+			expErrStep: "clock",
+			expErr:     true,
+		},
+		"failing session env stops the sandbox and restores its state": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:        "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:      "my-sandbox",
+					Status:    model.SandboxStatusStopped,
+					CreatedAt: createdAt,
+					StartedAt: &startedAt,
+					StoppedAt: &stoppedAt,
+				}, nil)
+				m.On("UpdateSandbox", mock.Anything, mock.MatchedBy(func(s model.Sandbox) bool {
+					return s.Status == model.SandboxStatusStopped && s.StartedAt.Equal(startedAt) && s.StoppedAt.Equal(stoppedAt)
+				})).Once().Return(nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Start", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything).Once().Return(nil, nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", []string{"mkdir", "-p", "/etc/sbx", "/etc/profile.d", "/root/.ssh"}, mock.Anything).Once().Return(nil, fmt.Errorf("ssh error"))
+				m.On("Stop", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", sandbox.StopOpts{}).Once().Return(nil)
+			},
+			req:        start.Request{NameOrID: "my-sandbox"},
+			expErrStep: "session_env",
+			expErr:     true,
+		},
+		"failing sandbox update stops the sandbox": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:        "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:      "my-sandbox",
+					Status:    model.SandboxStatusStopped,
+					CreatedAt: createdAt,
+				}, nil)
+				m.On("UpdateSandbox", mock.Anything, mock.MatchedBy(func(s model.Sandbox) bool {
+					return s.Status == model.SandboxStatusRunning
+				})).Once().Return(fmt.Errorf("storage error"))
+				m.On("UpdateSandbox", mock.Anything, mock.MatchedBy(func(s model.Sandbox) bool {
+					return s.Status == model.SandboxStatusStopped && strings.HasPrefix(s.LastError, `start step "update" failed`)
+				})).Once().Return(nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Start", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything).Once().Return(nil, nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, mock.Anything).Return(&model.ExecResult{}, nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, mock.Anything, model.CopyOpts{}).Return(nil)
+				m.On("Stop", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", sandbox.StopOpts{}).Once().Return(nil)
+			},
+			req:        start.Request{NameOrID: "my-sandbox"},
+			expErrStep: "update",
+			expErr:     true,
+		},
+		"failing rollback returns the rollback error with the step error": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:        "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:      "my-sandbox",
+					Status:    model.SandboxStatusStopped,
+					Config:    model.SandboxConfig{Clock: &model.ClockConfig{Offset: time.Hour}},
+					CreatedAt: createdAt,
+				}, nil)
+				m.On("UpdateSandbox", mock.Anything, mock.Anything).Once().Return(fmt.Errorf("storage error"))
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Start", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything).Once().Return(nil, nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, mock.Anything).Once().Return(&model.ExecResult{ExitCode: 1}, nil)
+				m.On("Stop", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", sandbox.StopOpts{}).Once().Return(fmt.Errorf("engine error"))
+			},
+			req:            start.Request{NameOrID: "my-sandbox"},
+			expErrStep:     "clock",
+			expRollbackErr: true,
+			expErr:         true,
+		},
 		"cannot start pending sandbox": {
 			mockRepo: func(m *storagemock.MockRepository) {
 				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
@@ -154,6 +456,167 @@ func TestService_Run(t *testing.T) {
 			req:        start.Request{NameOrID: "nonexistent"},
 			expErr:     true,
 		},
+		"start timeouts are passed to the engine": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:        "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:      "my-sandbox",
+					Status:    model.SandboxStatusStopped,
+					CreatedAt: createdAt,
+				}, nil)
+				m.On("UpdateSandbox", mock.Anything, mock.Anything).Once().Return(nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				expOpts := sandbox.StartOpts{Timeouts: model.StartTimeouts{Boot: 5 * time.Minute, SSHRetries: 100}}
+				m.On("Start", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", expOpts).Once().Return(nil, nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, mock.Anything).Return(&model.ExecResult{}, nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, mock.Anything, model.CopyOpts{}).Return(nil)
+			},
+			req: start.Request{
+				NameOrID: "my-sandbox",
+				Timeouts: model.StartTimeouts{Boot: 5 * time.Minute, SSHRetries: 100},
+			},
+			expErr: false,
+		},
+		"a quarantined sandbox should start quarantined": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:            "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:          "my-sandbox",
+					Status:        model.SandboxStatusStopped,
+					CreatedAt:     createdAt,
+					QuarantinedAt: &createdAt,
+				}, nil)
+				m.On("UpdateSandbox", mock.Anything, mock.Anything).Once().Return(nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				expOpts := sandbox.StartOpts{Quarantine: true}
+				m.On("Start", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", expOpts).Once().Return(nil, nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, mock.Anything).Return(&model.ExecResult{}, nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, mock.Anything, model.CopyOpts{}).Return(nil)
+			},
+			req:    start.Request{NameOrID: "my-sandbox"},
+			expErr: false,
+		},
+		"start with an egress policy name uses the stored policy": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:        "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:      "my-sandbox",
+					Status:    model.SandboxStatusStopped,
+					CreatedAt: createdAt,
+				}, nil)
+				m.On("GetEgressPolicy", mock.Anything, "github").Once().Return(&model.NamedEgressPolicy{
+					Name:   "github",
+					Policy: model.EgressPolicy{Default: model.EgressActionDeny, Rules: []model.EgressRule{{Domain: "github.com", Action: model.EgressActionAllow}}},
+				}, nil)
+				m.On("UpdateSandbox", mock.Anything, mock.MatchedBy(func(s model.Sandbox) bool {
+					return s.Session != nil && s.Session.EgressPolicyName == "github" && s.Session.Egress == nil
+				})).Once().Return(nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				expOpts := sandbox.StartOpts{Egress: &model.EgressPolicy{Default: model.EgressActionDeny, Rules: []model.EgressRule{{Domain: "github.com", Action: model.EgressActionAllow}}}}
+				m.On("Start", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", expOpts).Once().Return(nil, nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, mock.Anything).Return(&model.ExecResult{}, nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, mock.Anything, model.CopyOpts{}).Return(nil)
+			},
+			req: start.Request{
+				NameOrID:      "my-sandbox",
+				SessionConfig: model.SessionConfig{EgressPolicyName: "github"},
+			},
+			expErr: false,
+		},
+		"start without session should apply the session of the previous start": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:        "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:      "my-sandbox",
+					Status:    model.SandboxStatusStopped,
+					CreatedAt: createdAt,
+					StartedAt: &startedAt,
+					Session: &model.SessionConfig{
+						Env:    map[string]string{"FOO": "bar"},
+						Egress: &model.EgressPolicy{Default: model.EgressActionDeny},
+					},
+				}, nil)
+				m.On("UpdateSandbox", mock.Anything, mock.MatchedBy(func(s model.Sandbox) bool {
+					return s.Session != nil && s.Session.Env["FOO"] == "bar" && s.Session.Egress != nil
+				})).Once().Return(nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				expOpts := sandbox.StartOpts{Egress: &model.EgressPolicy{Default: model.EgressActionDeny}}
+				m.On("Start", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", expOpts).Once().Return(nil, nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, mock.Anything).Return(&model.ExecResult{}, nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, "/etc/sbx/session-env.sh", model.CopyOpts{}).Once().Run(func(args mock.Arguments) {
+					data, err := os.ReadFile(args.String(2))
+					require.NoError(t, err)
+					assert.Contains(t, string(data), "export FOO='bar'\n")
+				}).Return(nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, mock.Anything, model.CopyOpts{}).Return(nil)
+			},
+			req:    start.Request{NameOrID: "my-sandbox"},
+			expErr: false,
+		},
+		"start with a reset session should not apply the session of the previous start": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:        "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:      "my-sandbox",
+					Status:    model.SandboxStatusStopped,
+					CreatedAt: createdAt,
+					StartedAt: &startedAt,
+					Session:   &model.SessionConfig{Egress: &model.EgressPolicy{Default: model.EgressActionDeny}},
+				}, nil)
+				m.On("UpdateSandbox", mock.Anything, mock.MatchedBy(func(s model.Sandbox) bool {
+					return s.Session != nil && s.Session.IsZero()
+				})).Once().Return(nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Start", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", sandbox.StartOpts{}).Once().Return(nil, nil)
+				m.On("Exec", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, mock.Anything).Return(&model.ExecResult{}, nil)
+				m.On("CopyTo", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything, mock.Anything, model.CopyOpts{}).Return(nil)
+			},
+			req:    start.Request{NameOrID: "my-sandbox", ResetSession: true},
+			expErr: false,
+		},
+		"a missing egress policy name should fail": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:        "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:      "my-sandbox",
+					Status:    model.SandboxStatusStopped,
+					CreatedAt: createdAt,
+				}, nil)
+				m.On("GetEgressPolicy", mock.Anything, "missing").Once().Return(nil, model.ErrNotFound)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req: start.Request{
+				NameOrID:      "my-sandbox",
+				SessionConfig: model.SessionConfig{EgressPolicyName: "missing"},
+			},
+			expErr: true,
+		},
+		"an egress policy with an egress policy name should fail": {
+			mockRepo:   func(m *storagemock.MockRepository) {},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req: start.Request{
+				NameOrID: "my-sandbox",
+				SessionConfig: model.SessionConfig{
+					Egress:           &model.EgressPolicy{Default: model.EgressActionAllow},
+					EgressPolicyName: "github",
+				},
+			},
+			expErr: true,
+		},
+		"negative start timeouts should fail": {
+			mockRepo:   func(m *storagemock.MockRepository) {},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req: start.Request{
+				NameOrID: "my-sandbox",
+				Timeouts: model.StartTimeouts{SSHDial: -time.Second},
+			},
+			expErr: true,
+		},
 		"engine error propagates": {
 			mockRepo: func(m *storagemock.MockRepository) {
 				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
@@ -163,9 +626,14 @@ func TestService_Run(t *testing.T) {
 					CreatedAt: createdAt,
 					StoppedAt: &stoppedAt,
 				}, nil)
+				m.On("UpdateSandbox", mock.Anything, mock.MatchedBy(func(sb model.Sandbox) bool {
+					return sb.Status == model.SandboxStatusStopped &&
+						sb.LastError == "could not start sandbox: engine error" &&
+						len(sb.LastBootPhases) == 1 && sb.LastBootPhases[0].Error == sb.LastError
+				})).Once().Return(nil)
 			},
 			mockEngine: func(m *sandboxmock.MockEngine) {
-				m.On("Start", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything).Once().Return(fmt.Errorf("engine error"))
+				m.On("Start", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", mock.Anything).Once().Return(nil, fmt.Errorf("engine error"))
 			},
 			req:    start.Request{NameOrID: "my-sandbox"},
 			expErr: true,
@@ -193,10 +661,28 @@ func TestService_Run(t *testing.T) {
 
 			if test.expErr {
 				assert.Error(err)
+				if test.expErrStep != "" {
+					var startErr *model.StartError
+					require.ErrorAs(err, &startErr)
+					assert.Equal(test.expErrStep, startErr.Step)
+					assert.Equal(test.expRollbackErr, startErr.RollbackErr != nil)
+					if assert.NotEmpty(startErr.Phases) {
+						last := startErr.Phases[len(startErr.Phases)-1]
+						assert.Equal(test.expErrStep, last.Name)
+						assert.NotEmpty(last.Error)
+					}
+				}
 			} else {
 				assert.NoError(err)
 				assert.NotNil(result)
 				assert.Equal(model.SandboxStatusRunning, result.Status)
+				if test.expPhases != nil {
+					var gotPhases []string
+					for _, p := range result.BootPhases {
+						gotPhases = append(gotPhases, p.Name)
+					}
+					assert.Equal(test.expPhases, gotPhases)
+				}
 			}
 
 			mRepo.AssertExpectations(t)
@@ -7,13 +7,17 @@ import (
 
 	"github.com/slok/sbx/internal/log"
 	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
 	"github.com/slok/sbx/internal/storage"
 )
 
 // ServiceConfig is the configuration for the status service.
 type ServiceConfig struct {
 	Repository storage.Repository
-	Logger     log.Logger
+	// Engine is optional, when set the runtime status of running sandboxes (e.g. the
+	// egress proxy health) is added.
+	Engine sandbox.Engine
+	Logger log.Logger
 }
 
 func (c *ServiceConfig) defaults() error {
@@ -31,6 +35,7 @@ func (c *ServiceConfig) defaults() error {
 // Service retrieves detailed sandbox status.
 type Service struct {
 	repo   storage.Repository
+	engine sandbox.Engine
 	logger log.Logger
 }
 
@@ -42,6 +47,7 @@ func NewService(cfg ServiceConfig) (*Service, error) {
 
 	return &Service{
 		repo:   cfg.Repository,
+		engine: cfg.Engine,
 		logger: cfg.Logger,
 	}, nil
 }
@@ -50,6 +56,8 @@ func NewService(cfg ServiceConfig) (*Service, error) {
 type Request struct {
 	// NameOrID is the sandbox name or ID to query.
 	NameOrID string
+	// ByID only looks up NameOrID as an ID, the names are not resolved.
+	ByID bool
 }
 
 // Run retrieves the status of a sandbox by name or ID.
@@ -57,21 +65,32 @@ type Request struct {
 func (s *Service) Run(ctx context.Context, req Request) (*model.Sandbox, error) {
 	s.logger.Debugf("getting status for sandbox: %s", req.NameOrID)
 
+	if req.ByID {
+		sb, err := s.repo.GetSandbox(ctx, req.NameOrID)
+		if err != nil {
+			if errors.Is(err, model.ErrNotFound) {
+				return nil, fmt.Errorf("sandbox not found: %s: %w", req.NameOrID, model.ErrNotFound)
+			}
+			return nil, fmt.Errorf("could not get sandbox status: %w", err)
+		}
+		return s.withRuntimeStatus(ctx, sb), nil
+	}
+
 	// Try lookup by name first.
-	sandbox, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
+	sb, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
 	if err == nil {
-		s.logger.Debugf("found sandbox by name: %s", sandbox.ID)
-		return sandbox, nil
+		s.logger.Debugf("found sandbox by name: %s", sb.ID)
+		return s.withRuntimeStatus(ctx, sb), nil
 	}
 
 	// If not found by name and the input looks like a ULID (26 chars, alphanumeric),
 	// try lookup by ID.
 	if errors.Is(err, model.ErrNotFound) && looksLikeULID(req.NameOrID) {
 		s.logger.Debugf("name lookup failed, trying ID lookup")
-		sandbox, err = s.repo.GetSandbox(ctx, req.NameOrID)
+		sb, err = s.repo.GetSandbox(ctx, req.NameOrID)
 		if err == nil {
-			s.logger.Debugf("found sandbox by ID: %s", sandbox.ID)
-			return sandbox, nil
+			s.logger.Debugf("found sandbox by ID: %s", sb.ID)
+			return s.withRuntimeStatus(ctx, sb), nil
 		}
 	}
 
@@ -83,6 +102,24 @@ func (s *Service) Run(ctx context.Context, req Request) (*model.Sandbox, error)
 	return nil, fmt.Errorf("could not get sandbox status: %w", err)
 }
 
+// withRuntimeStatus adds the engine runtime status to a running sandbox. The stored
+// status is still returned if the engine can't get it.
+func (s *Service) withRuntimeStatus(ctx context.Context, sb *model.Sandbox) *model.Sandbox {
+	if s.engine == nil || sb.Status != model.SandboxStatusRunning {
+		return sb
+	}
+
+	engSb, err := s.engine.Status(ctx, sb.ID)
+	if err != nil {
+		s.logger.Warningf("could not get sandbox runtime status: %v", err)
+		return sb
+	}
+	sb.Egress = engSb.Egress
+	sb.Seccomp = engSb.Seccomp
+
+	return sb
+}
+
 // looksLikeULID checks if a string looks like a ULID (26 characters, alphanumeric uppercase).
 func looksLikeULID(s string) bool {
 	if len(s) != 26 {
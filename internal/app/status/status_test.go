@@ -13,6 +13,7 @@ import (
 	"github.com/slok/sbx/internal/app/status"
 	"github.com/slok/sbx/internal/log"
 	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox/sandboxmock"
 	"github.com/slok/sbx/internal/storage/storagemock"
 )
 
@@ -64,10 +65,11 @@ func TestService_Run(t *testing.T) {
 	startedAt := time.Date(2026, 1, 30, 10, 0, 5, 0, time.UTC)
 
 	tests := map[string]struct {
-		mock      func(m *storagemock.MockRepository)
-		req       status.Request
-		expResult func() *model.Sandbox
-		expErr    bool
+		mock       func(m *storagemock.MockRepository)
+		mockEngine func(m *sandboxmock.MockEngine)
+		req        status.Request
+		expResult  func() *model.Sandbox
+		expErr     bool
 	}{
 		"get sandbox by name": {
 			mock: func(m *storagemock.MockRepository) {
@@ -131,6 +133,99 @@ func TestService_Run(t *testing.T) {
 			expResult: nil,
 			expErr:    true,
 		},
+		"get sandbox by ID only should not resolve the names": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("GetSandbox", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH").Once().Return(&model.Sandbox{
+					ID:        "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:      "my-sandbox",
+					Status:    model.SandboxStatusStopped,
+					CreatedAt: createdAt,
+				}, nil)
+			},
+			req: status.Request{NameOrID: "01H2QWERTYASDFGZXCVBNMLKJH", ByID: true},
+			expResult: func() *model.Sandbox {
+				return &model.Sandbox{
+					ID:        "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:      "my-sandbox",
+					Status:    model.SandboxStatusStopped,
+					CreatedAt: createdAt,
+				}
+			},
+			expErr: false,
+		},
+		"sandbox not found by ID only": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("GetSandbox", mock.Anything, "my-sandbox").Once().Return(nil, model.ErrNotFound)
+			},
+			req:       status.Request{NameOrID: "my-sandbox", ByID: true},
+			expResult: nil,
+			expErr:    true,
+		},
+		"a running sandbox should have the engine egress status when an engine is set": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:     "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:   "my-sandbox",
+					Status: model.SandboxStatusRunning,
+				}, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Status", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH").Once().Return(&model.Sandbox{
+					ID:      "01H2QWERTYASDFGZXCVBNMLKJH",
+					Status:  model.SandboxStatusRunning,
+					Egress:  &model.EgressStatus{Health: model.EgressHealthDegraded, Restarts: 2, LastError: "proxy exited"},
+					Seccomp: &model.SeccompStatus{Enforced: true, Filters: 1},
+				}, nil)
+			},
+			req: status.Request{NameOrID: "my-sandbox"},
+			expResult: func() *model.Sandbox {
+				return &model.Sandbox{
+					ID:      "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:    "my-sandbox",
+					Status:  model.SandboxStatusRunning,
+					Egress:  &model.EgressStatus{Health: model.EgressHealthDegraded, Restarts: 2, LastError: "proxy exited"},
+					Seccomp: &model.SeccompStatus{Enforced: true, Filters: 1},
+				}
+			},
+		},
+		"a stopped sandbox should not get the engine status": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:     "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:   "my-sandbox",
+					Status: model.SandboxStatusStopped,
+				}, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        status.Request{NameOrID: "my-sandbox"},
+			expResult: func() *model.Sandbox {
+				return &model.Sandbox{
+					ID:     "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:   "my-sandbox",
+					Status: model.SandboxStatusStopped,
+				}
+			},
+		},
+		"an engine status error should return the stored status": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:     "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:   "my-sandbox",
+					Status: model.SandboxStatusRunning,
+				}, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				m.On("Status", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH").Once().Return(nil, fmt.Errorf("engine error"))
+			},
+			req: status.Request{NameOrID: "my-sandbox"},
+			expResult: func() *model.Sandbox {
+				return &model.Sandbox{
+					ID:     "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:   "my-sandbox",
+					Status: model.SandboxStatusRunning,
+				}
+			},
+		},
 		"repository error should propagate": {
 			mock: func(m *storagemock.MockRepository) {
 				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(nil, fmt.Errorf("database error"))
@@ -150,10 +245,17 @@ func TestService_Run(t *testing.T) {
 			m := &storagemock.MockRepository{}
 			test.mock(m)
 
-			svc, err := status.NewService(status.ServiceConfig{
+			cfg := status.ServiceConfig{
 				Repository: m,
 				Logger:     log.Noop,
-			})
+			}
+			me := &sandboxmock.MockEngine{}
+			if test.mockEngine != nil {
+				test.mockEngine(me)
+				cfg.Engine = me
+			}
+
+			svc, err := status.NewService(cfg)
 			require.NoError(err)
 
 			// Execute
@@ -170,6 +272,7 @@ func TestService_Run(t *testing.T) {
 			}
 
 			m.AssertExpectations(t)
+			me.AssertExpectations(t)
 		})
 	}
 }
@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/slok/sbx/internal/hooks"
 	"github.com/slok/sbx/internal/log"
 	"github.com/slok/sbx/internal/model"
 	"github.com/slok/sbx/internal/sandbox"
@@ -16,7 +18,12 @@ import (
 type ServiceConfig struct {
 	Engine     sandbox.Engine
 	Repository storage.Repository
-	Logger     log.Logger
+	// Locker serializes the sandbox operations. Defaults to the repository when it
+	// implements it, otherwise the operations are not serialized.
+	Locker storage.SandboxLocker
+	// Hooks runs the pre-stop host hooks (optional).
+	Hooks  hooks.Runner
+	Logger log.Logger
 }
 
 func (c *ServiceConfig) defaults() error {
@@ -28,6 +35,14 @@ func (c *ServiceConfig) defaults() error {
 		return fmt.Errorf("repository is required")
 	}
 
+	if c.Locker == nil {
+		c.Locker, _ = c.Repository.(storage.SandboxLocker)
+	}
+
+	if c.Hooks == nil {
+		c.Hooks = hooks.Noop
+	}
+
 	if c.Logger == nil {
 		c.Logger = log.Noop
 	}
@@ -39,6 +54,8 @@ func (c *ServiceConfig) defaults() error {
 type Service struct {
 	engine sandbox.Engine
 	repo   storage.Repository
+	locker storage.SandboxLocker
+	hooks  hooks.Runner
 	logger log.Logger
 }
 
@@ -51,6 +68,8 @@ func NewService(cfg ServiceConfig) (*Service, error) {
 	return &Service{
 		engine: cfg.Engine,
 		repo:   cfg.Repository,
+		locker: cfg.Locker,
+		hooks:  cfg.Hooks,
 		logger: cfg.Logger,
 	}, nil
 }
@@ -59,6 +78,11 @@ func NewService(cfg ServiceConfig) (*Service, error) {
 type Request struct {
 	// NameOrID is the sandbox name or ID to stop.
 	NameOrID string
+	// GracePeriod is the time the sandbox has to run the pre-stop hooks and shut
+	// down before being killed, zero uses the engine default.
+	GracePeriod time.Duration
+	// PreStopHooks are the shell commands run in the guest before it's shut down.
+	PreStopHooks []string
 }
 
 // Run stops a sandbox by name or ID.
@@ -66,6 +90,16 @@ type Request struct {
 func (s *Service) Run(ctx context.Context, req Request) (*model.Sandbox, error) {
 	s.logger.Debugf("stopping sandbox: %s", req.NameOrID)
 
+	if req.GracePeriod < 0 {
+		return nil, fmt.Errorf("grace period can't be negative: %w", model.ErrNotValid)
+	}
+	for _, hook := range req.PreStopHooks {
+		if strings.TrimSpace(hook) == "" {
+			return nil, fmt.Errorf("pre-stop hooks can't be empty: %w", model.ErrNotValid)
+		}
+	}
+	stopOpts := sandbox.StopOpts{GracePeriod: req.GracePeriod, PreStopHooks: req.PreStopHooks}
+
 	// Lookup sandbox by name first, then by ID if it looks like a ULID.
 	sandbox, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
 	if errors.Is(err, model.ErrNotFound) && looksLikeULID(req.NameOrID) {
@@ -78,18 +112,39 @@ func (s *Service) Run(ctx context.Context, req Request) (*model.Sandbox, error)
 		return nil, fmt.Errorf("could not get sandbox: %w", err)
 	}
 
+	// Serialize the operations on the sandbox, the sandbox is read again once locked
+	// so the checks use its latest state.
+	if s.locker != nil {
+		unlock, err := s.locker.LockSandbox(ctx, sandbox.ID)
+		if err != nil {
+			return nil, fmt.Errorf("could not lock sandbox: %w", err)
+		}
+		defer unlock()
+
+		sandbox, err = s.repo.GetSandbox(ctx, sandbox.ID)
+		if err != nil {
+			return nil, fmt.Errorf("could not get sandbox: %w", err)
+		}
+	}
+
 	// Validate sandbox is running.
 	if sandbox.Status != model.SandboxStatusRunning {
 		return nil, fmt.Errorf("cannot stop sandbox: not running (current status: %s): %w", sandbox.Status, model.ErrNotValid)
 	}
 
+	// Run the pre-stop host hooks, a failed one aborts the stop.
+	if err := s.hooks.Run(ctx, model.HookEventPreStop, *sandbox); err != nil {
+		return nil, fmt.Errorf("sandbox stop aborted: %w", err)
+	}
+
 	// Stop the sandbox via engine.
-	if err := s.engine.Stop(ctx, sandbox.ID); err != nil {
+	if err := s.engine.Stop(ctx, sandbox.ID, stopOpts); err != nil {
 		return nil, fmt.Errorf("could not stop sandbox: %w", err)
 	}
 
 	// Update sandbox state in repository.
 	now := time.Now().UTC()
+	usage := model.SandboxUsageRecords(*sandbox, now)
 	sandbox.Status = model.SandboxStatusStopped
 	sandbox.StoppedAt = &now
 
@@ -97,6 +152,16 @@ func (s *Service) Run(ctx context.Context, req Request) (*model.Sandbox, error)
 		return nil, fmt.Errorf("could not update sandbox: %w", err)
 	}
 
+	// Store the finished run for the usage accounting, the disk is stored on removal.
+	for _, rec := range usage {
+		if rec.Kind != model.UsageKindRun {
+			continue
+		}
+		if err := s.repo.CreateUsageRecord(ctx, rec); err != nil {
+			s.logger.Warningf("could not store sandbox %s usage: %v", sandbox.ID, err)
+		}
+	}
+
 	s.logger.Infof("stopped sandbox: %s (ID: %s)", sandbox.Name, sandbox.ID)
 	return sandbox, nil
 }
@@ -11,9 +11,12 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/slok/sbx/internal/app/stop"
+	"github.com/slok/sbx/internal/hooks"
 	"github.com/slok/sbx/internal/log"
 	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
 	"github.com/slok/sbx/internal/sandbox/sandboxmock"
+	"github.com/slok/sbx/internal/storage/memory"
 	"github.com/slok/sbx/internal/storage/storagemock"
 )
 
@@ -77,6 +80,7 @@ func TestService_Run(t *testing.T) {
 	tests := map[string]struct {
 		mockRepo   func(m *storagemock.MockRepository)
 		mockEngine func(m *sandboxmock.MockEngine)
+		hooks      []model.HostHook
 		req        stop.Request
 		expErr     bool
 	}{
@@ -94,13 +98,65 @@ func TestService_Run(t *testing.T) {
 						s.Status == model.SandboxStatusStopped &&
 						s.StoppedAt != nil
 				})).Once().Return(nil)
+				m.On("CreateUsageRecord", mock.Anything, mock.MatchedBy(func(r model.UsageRecord) bool {
+					return r.SandboxID == "01H2QWERTYASDFGZXCVBNMLKJH" && r.Kind == model.UsageKindRun && r.StartedAt.Equal(startedAt)
+				})).Once().Return(nil)
 			},
 			mockEngine: func(m *sandboxmock.MockEngine) {
-				m.On("Stop", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH").Once().Return(nil)
+				m.On("Stop", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", sandbox.StopOpts{}).Once().Return(nil)
 			},
 			req:    stop.Request{NameOrID: "my-sandbox"},
 			expErr: false,
 		},
+		"stop running sandbox with grace period and pre-stop hooks": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:        "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:      "my-sandbox",
+					Status:    model.SandboxStatusRunning,
+					CreatedAt: createdAt,
+					StartedAt: &startedAt,
+				}, nil)
+				m.On("UpdateSandbox", mock.Anything, mock.Anything).Once().Return(nil)
+				m.On("CreateUsageRecord", mock.Anything, mock.Anything).Once().Return(nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				exp := sandbox.StopOpts{GracePeriod: 30 * time.Second, PreStopHooks: []string{"sync", "systemctl stop agent"}}
+				m.On("Stop", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", exp).Once().Return(nil)
+			},
+			req:    stop.Request{NameOrID: "my-sandbox", GracePeriod: 30 * time.Second, PreStopHooks: []string{"sync", "systemctl stop agent"}},
+			expErr: false,
+		},
+		"a failed host pre-stop hook should abort the stop": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:     "01H2QWERTYASDFGZXCVBNMLKJH",
+					Name:   "my-sandbox",
+					Status: model.SandboxStatusRunning,
+				}, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			hooks: []model.HostHook{{
+				Event: model.HookEventPreStop,
+				Func: func(context.Context, model.HookEvent, model.Sandbox) error {
+					return fmt.Errorf("sandbox still registered")
+				},
+			}},
+			req:    stop.Request{NameOrID: "my-sandbox"},
+			expErr: true,
+		},
+		"a negative grace period should fail": {
+			mockRepo:   func(m *storagemock.MockRepository) {},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        stop.Request{NameOrID: "my-sandbox", GracePeriod: -time.Second},
+			expErr:     true,
+		},
+		"an empty pre-stop hook should fail": {
+			mockRepo:   func(m *storagemock.MockRepository) {},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        stop.Request{NameOrID: "my-sandbox", PreStopHooks: []string{"sync", " "}},
+			expErr:     true,
+		},
 		"stop running sandbox by ID": {
 			mockRepo: func(m *storagemock.MockRepository) {
 				m.On("GetSandboxByName", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH").Once().Return(nil, model.ErrNotFound)
@@ -114,9 +170,10 @@ func TestService_Run(t *testing.T) {
 				m.On("UpdateSandbox", mock.Anything, mock.MatchedBy(func(s model.Sandbox) bool {
 					return s.Status == model.SandboxStatusStopped
 				})).Once().Return(nil)
+				m.On("CreateUsageRecord", mock.Anything, mock.Anything).Once().Return(nil)
 			},
 			mockEngine: func(m *sandboxmock.MockEngine) {
-				m.On("Stop", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH").Once().Return(nil)
+				m.On("Stop", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", sandbox.StopOpts{}).Once().Return(nil)
 			},
 			req:    stop.Request{NameOrID: "01H2QWERTYASDFGZXCVBNMLKJH"},
 			expErr: false,
@@ -190,7 +247,7 @@ func TestService_Run(t *testing.T) {
 				}, nil)
 			},
 			mockEngine: func(m *sandboxmock.MockEngine) {
-				m.On("Stop", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH").Once().Return(fmt.Errorf("engine error"))
+				m.On("Stop", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", sandbox.StopOpts{}).Once().Return(fmt.Errorf("engine error"))
 			},
 			req:    stop.Request{NameOrID: "my-sandbox"},
 			expErr: true,
@@ -207,7 +264,7 @@ func TestService_Run(t *testing.T) {
 				m.On("UpdateSandbox", mock.Anything, mock.Anything).Once().Return(fmt.Errorf("database error"))
 			},
 			mockEngine: func(m *sandboxmock.MockEngine) {
-				m.On("Stop", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH").Once().Return(nil)
+				m.On("Stop", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", sandbox.StopOpts{}).Once().Return(nil)
 			},
 			req:    stop.Request{NameOrID: "my-sandbox"},
 			expErr: true,
@@ -225,9 +282,13 @@ func TestService_Run(t *testing.T) {
 			test.mockRepo(mRepo)
 			test.mockEngine(mEngine)
 
+			runner, err := hooks.NewRunner(hooks.RunnerConfig{Hooks: test.hooks})
+			require.NoError(err)
+
 			svc, err := stop.NewService(stop.ServiceConfig{
 				Engine:     mEngine,
 				Repository: mRepo,
+				Hooks:      runner,
 				Logger:     log.Noop,
 			})
 			require.NoError(err)
@@ -250,3 +311,41 @@ func TestService_Run(t *testing.T) {
 		})
 	}
 }
+
+func TestServiceRunLocking(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	repo, err := memory.NewRepository(memory.RepositoryConfig{})
+	require.NoError(err)
+	require.NoError(repo.CreateSandbox(ctx, model.Sandbox{
+		ID:        "01H2QWERTYASDFGZXCVBNMLKJH",
+		Name:      "my-sandbox",
+		Status:    model.SandboxStatusRunning,
+		CreatedAt: time.Now(),
+		Config:    model.SandboxConfig{Name: "my-sandbox", FirecrackerEngine: &model.FirecrackerEngineConfig{}},
+	}))
+
+	mEngine := sandboxmock.NewMockEngine(t)
+	mEngine.On("Stop", mock.Anything, "01H2QWERTYASDFGZXCVBNMLKJH", sandbox.StopOpts{}).Once().Return(nil)
+
+	svc, err := stop.NewService(stop.ServiceConfig{Engine: mEngine, Repository: repo, Logger: log.Noop})
+	require.NoError(err)
+
+	// A sandbox locked by another operation should fail with a conflict.
+	unlock, err := repo.LockSandbox(ctx, "01H2QWERTYASDFGZXCVBNMLKJH")
+	require.NoError(err)
+	_, err = svc.Run(ctx, stop.Request{NameOrID: "my-sandbox"})
+	assert.ErrorIs(err, model.ErrConflict)
+
+	// Once released it should stop, and the lock should be released after the operation.
+	unlock()
+	sb, err := svc.Run(ctx, stop.Request{NameOrID: "my-sandbox"})
+	require.NoError(err)
+	assert.Equal(model.SandboxStatusStopped, sb.Status)
+
+	// The stopped state should be read once locked.
+	_, err = svc.Run(ctx, stop.Request{NameOrID: "my-sandbox"})
+	assert.ErrorIs(err, model.ErrNotValid)
+}
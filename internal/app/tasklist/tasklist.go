@@ -0,0 +1,105 @@
+package tasklist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the task list service.
+type ServiceConfig struct {
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.TaskList"})
+
+	return nil
+}
+
+// Service lists the tasks available to the sandboxes.
+type Service struct {
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new task list service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the task list request parameters.
+type Request struct {
+	// NameOrID lists the tasks available to a sandbox: its tasks and the global ones
+	// it doesn't override. Empty lists the global tasks.
+	NameOrID string
+}
+
+// Run returns the tasks sorted by name.
+func (s *Service) Run(ctx context.Context, req Request) ([]model.Task, error) {
+	sandboxID, err := s.sandboxID(ctx, req.NameOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := s.repo.ListTasks(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("could not list tasks: %w", err)
+	}
+	if sandboxID == "" {
+		return tasks, nil
+	}
+
+	sbTasks, err := s.repo.ListTasks(ctx, sandboxID)
+	if err != nil {
+		return nil, fmt.Errorf("could not list sandbox tasks: %w", err)
+	}
+	overridden := map[string]bool{}
+	for _, t := range sbTasks {
+		overridden[t.Name] = true
+	}
+	for _, t := range tasks {
+		if !overridden[t.Name] {
+			sbTasks = append(sbTasks, t)
+		}
+	}
+	sort.SliceStable(sbTasks, func(i, j int) bool { return sbTasks[i].Name < sbTasks[j].Name })
+
+	return sbTasks, nil
+}
+
+// sandboxID returns the ID of a sandbox by name or ID, empty without sandbox.
+func (s *Service) sandboxID(ctx context.Context, nameOrID string) (string, error) {
+	if nameOrID == "" {
+		return "", nil
+	}
+
+	sb, err := s.repo.GetSandboxByName(ctx, nameOrID)
+	if errors.Is(err, model.ErrNotFound) {
+		sb, err = s.repo.GetSandbox(ctx, nameOrID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not find sandbox: %w", err)
+	}
+	return sb.ID, nil
+}
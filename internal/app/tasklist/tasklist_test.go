@@ -0,0 +1,79 @@
+package tasklist_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/tasklist"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestServiceRun(t *testing.T) {
+	global := []model.Task{
+		{Name: "lint", Command: []string{"golangci-lint", "run"}},
+		{Name: "test", Command: []string{"go", "test", "./..."}},
+	}
+	sbTasks := []model.Task{
+		{Name: "deploy", SandboxID: "01SB", Command: []string{"make", "deploy"}},
+		{Name: "test", SandboxID: "01SB", Command: []string{"make", "test"}},
+	}
+
+	tests := map[string]struct {
+		mock     func(m *storagemock.MockRepository)
+		req      tasklist.Request
+		expTasks []model.Task
+		expErr   bool
+	}{
+		"Listing without sandbox should return the global tasks.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("ListTasks", mock.Anything, "").Once().Return(global, nil)
+			},
+			expTasks: global,
+		},
+
+		"Listing the tasks of a sandbox should return its tasks and the global ones it doesn't override.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "sb").Once().Return(&model.Sandbox{ID: "01SB", Name: "sb"}, nil)
+				m.On("ListTasks", mock.Anything, "").Once().Return(global, nil)
+				m.On("ListTasks", mock.Anything, "01SB").Once().Return(sbTasks, nil)
+			},
+			req:      tasklist.Request{NameOrID: "sb"},
+			expTasks: []model.Task{sbTasks[0], global[0], sbTasks[1]},
+		},
+
+		"A repository error should fail.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("ListTasks", mock.Anything, "").Once().Return(nil, fmt.Errorf("something"))
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mRepo := &storagemock.MockRepository{}
+			test.mock(mRepo)
+
+			svc, err := tasklist.NewService(tasklist.ServiceConfig{Repository: mRepo})
+			require.NoError(err)
+
+			got, err := svc.Run(context.Background(), test.req)
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expTasks, got)
+			}
+
+			mRepo.AssertExpectations(t)
+		})
+	}
+}
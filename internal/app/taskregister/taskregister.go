@@ -0,0 +1,99 @@
+package taskregister
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the task register service.
+type ServiceConfig struct {
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.TaskRegister"})
+
+	return nil
+}
+
+// Service stores the tasks run by name in the sandboxes.
+type Service struct {
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new task register service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the task register request parameters.
+type Request struct {
+	// NameOrID is the sandbox of the task, empty registers a global task.
+	NameOrID string
+	// Task is the task, its sandbox and times are set by the service.
+	Task model.Task
+}
+
+// Run registers a task, replacing the task of the sandbox (or the global one) with
+// the same name.
+func (s *Service) Run(ctx context.Context, req Request) (*model.Task, error) {
+	sandboxID, err := s.sandboxID(ctx, req.NameOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The times are stored with second precision.
+	now := time.Now().UTC().Truncate(time.Second)
+	t := req.Task
+	t.SandboxID = sandboxID
+	t.CreatedAt = now
+	t.UpdatedAt = now
+	if err := t.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid task: %w", err)
+	}
+
+	if err := s.repo.SaveTask(ctx, t); err != nil {
+		return nil, fmt.Errorf("could not store task: %w", err)
+	}
+
+	s.logger.Infof("registered task: %s", t.Name)
+	return &t, nil
+}
+
+// sandboxID returns the ID of a sandbox by name or ID, empty without sandbox.
+func (s *Service) sandboxID(ctx context.Context, nameOrID string) (string, error) {
+	if nameOrID == "" {
+		return "", nil
+	}
+
+	sb, err := s.repo.GetSandboxByName(ctx, nameOrID)
+	if errors.Is(err, model.ErrNotFound) {
+		sb, err = s.repo.GetSandbox(ctx, nameOrID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not find sandbox: %w", err)
+	}
+	return sb.ID, nil
+}
@@ -0,0 +1,82 @@
+package taskregister_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/taskregister"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestServiceRun(t *testing.T) {
+	task := model.Task{Name: "test", Command: []string{"go", "test", "./..."}}
+
+	tests := map[string]struct {
+		mock         func(m *storagemock.MockRepository)
+		req          taskregister.Request
+		expSandboxID string
+		expErr       error
+	}{
+		"Registering a global task should store it.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("SaveTask", mock.Anything, mock.MatchedBy(func(t model.Task) bool {
+					return t.Name == "test" && t.SandboxID == "" && !t.CreatedAt.IsZero()
+				})).Once().Return(nil)
+			},
+			req: taskregister.Request{Task: task},
+		},
+
+		"Registering a sandbox task should store it with the sandbox ID.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "sb").Once().Return(nil, model.ErrNotFound)
+				m.On("GetSandbox", mock.Anything, "sb").Once().Return(&model.Sandbox{ID: "01SB", Name: "sb"}, nil)
+				m.On("SaveTask", mock.Anything, mock.MatchedBy(func(t model.Task) bool { return t.SandboxID == "01SB" })).Once().Return(nil)
+			},
+			req:          taskregister.Request{NameOrID: "sb", Task: task},
+			expSandboxID: "01SB",
+		},
+
+		"Registering a task of a missing sandbox should fail.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "sb").Once().Return(nil, model.ErrNotFound)
+				m.On("GetSandbox", mock.Anything, "sb").Once().Return(nil, model.ErrNotFound)
+			},
+			req:    taskregister.Request{NameOrID: "sb", Task: task},
+			expErr: model.ErrNotFound,
+		},
+
+		"Registering an invalid task should fail.": {
+			mock:   func(m *storagemock.MockRepository) {},
+			req:    taskregister.Request{Task: model.Task{Name: "test"}},
+			expErr: model.ErrNotValid,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mRepo := &storagemock.MockRepository{}
+			test.mock(mRepo)
+
+			svc, err := taskregister.NewService(taskregister.ServiceConfig{Repository: mRepo})
+			require.NoError(err)
+
+			got, err := svc.Run(context.Background(), test.req)
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expSandboxID, got.SandboxID)
+				assert.Equal(test.req.Task.Command, got.Command)
+			}
+
+			mRepo.AssertExpectations(t)
+		})
+	}
+}
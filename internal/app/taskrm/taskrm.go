@@ -0,0 +1,87 @@
+package taskrm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the task remove service.
+type ServiceConfig struct {
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.TaskRm"})
+
+	return nil
+}
+
+// Service removes tasks.
+type Service struct {
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new task remove service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the task remove request parameters.
+type Request struct {
+	// NameOrID is the sandbox of the task, empty removes a global task.
+	NameOrID string
+	// Name is the name of the task to remove.
+	Name string
+}
+
+// Run removes a task.
+func (s *Service) Run(ctx context.Context, req Request) error {
+	sandboxID, err := s.sandboxID(ctx, req.NameOrID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteTask(ctx, sandboxID, req.Name); err != nil {
+		return fmt.Errorf("could not delete task: %w", err)
+	}
+
+	s.logger.Infof("removed task: %s", req.Name)
+	return nil
+}
+
+// sandboxID returns the ID of a sandbox by name or ID, empty without sandbox.
+func (s *Service) sandboxID(ctx context.Context, nameOrID string) (string, error) {
+	if nameOrID == "" {
+		return "", nil
+	}
+
+	sb, err := s.repo.GetSandboxByName(ctx, nameOrID)
+	if errors.Is(err, model.ErrNotFound) {
+		sb, err = s.repo.GetSandbox(ctx, nameOrID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not find sandbox: %w", err)
+	}
+	return sb.ID, nil
+}
@@ -0,0 +1,66 @@
+package taskrm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/taskrm"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestServiceRun(t *testing.T) {
+	tests := map[string]struct {
+		mock   func(m *storagemock.MockRepository)
+		req    taskrm.Request
+		expErr error
+	}{
+		"Removing a global task should delete it.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("DeleteTask", mock.Anything, "", "test").Once().Return(nil)
+			},
+			req: taskrm.Request{Name: "test"},
+		},
+
+		"Removing a sandbox task should delete it.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "sb").Once().Return(&model.Sandbox{ID: "01SB", Name: "sb"}, nil)
+				m.On("DeleteTask", mock.Anything, "01SB", "test").Once().Return(nil)
+			},
+			req: taskrm.Request{NameOrID: "sb", Name: "test"},
+		},
+
+		"Removing a missing task should fail.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("DeleteTask", mock.Anything, "", "test").Once().Return(model.ErrNotFound)
+			},
+			req:    taskrm.Request{Name: "test"},
+			expErr: model.ErrNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			mRepo := &storagemock.MockRepository{}
+			test.mock(mRepo)
+
+			svc, err := taskrm.NewService(taskrm.ServiceConfig{Repository: mRepo})
+			require.NoError(err)
+
+			err = svc.Run(context.Background(), test.req)
+			if test.expErr != nil {
+				assert.ErrorIs(t, err, test.expErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			mRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package templatecreate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the template create service.
+type ServiceConfig struct {
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.TemplateCreate"})
+
+	return nil
+}
+
+// Service stores sandbox templates.
+type Service struct {
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new template create service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the template create request parameters.
+type Request struct {
+	// Template is the sandbox template, its times are set by the service.
+	Template model.SandboxTemplate
+}
+
+// Run stores a new sandbox template, it fails if a template with the same name
+// already exists.
+func (s *Service) Run(ctx context.Context, req Request) (*model.SandboxTemplate, error) {
+	// The times are stored with second precision.
+	now := time.Now().UTC().Truncate(time.Second)
+	t := req.Template
+	t.CreatedAt = now
+	t.UpdatedAt = now
+	if err := t.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	if err := s.repo.CreateSandboxTemplate(ctx, t); err != nil {
+		return nil, fmt.Errorf("could not store template: %w", err)
+	}
+
+	s.logger.Infof("created sandbox template: %s", t.Name)
+	return &t, nil
+}
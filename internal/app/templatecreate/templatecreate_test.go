@@ -0,0 +1,80 @@
+package templatecreate_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/templatecreate"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestServiceRun(t *testing.T) {
+	template := model.SandboxTemplate{
+		Name:      "go-dev",
+		Engine:    model.TemplateEngineFirecracker,
+		Image:     "v0.1.0",
+		Resources: model.Resources{VCPUs: 2, MemoryMB: 2048, DiskGB: 10},
+		Env:       map[string]string{"GOFLAGS": "-mod=mod"},
+	}
+
+	tests := map[string]struct {
+		mock   func(m *storagemock.MockRepository)
+		req    templatecreate.Request
+		expErr error
+	}{
+		"Creating a valid template should store it.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("CreateSandboxTemplate", mock.Anything, mock.MatchedBy(func(t model.SandboxTemplate) bool {
+					return t.Name == "go-dev" && t.Image == "v0.1.0" && !t.CreatedAt.IsZero() && t.CreatedAt.Equal(t.UpdatedAt)
+				})).Once().Return(nil)
+			},
+			req: templatecreate.Request{Template: template},
+		},
+
+		"An existing template should fail.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("CreateSandboxTemplate", mock.Anything, mock.Anything).Once().Return(model.ErrAlreadyExists)
+			},
+			req:    templatecreate.Request{Template: template},
+			expErr: model.ErrAlreadyExists,
+		},
+
+		"An invalid template should fail.": {
+			mock: func(m *storagemock.MockRepository) {},
+			req: templatecreate.Request{Template: model.SandboxTemplate{
+				Name:      "go-dev",
+				Engine:    model.TemplateEngineFirecracker,
+				Resources: model.Resources{VCPUs: 2, MemoryMB: 2048, DiskGB: 10},
+			}},
+			expErr: model.ErrNotValid,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mRepo := &storagemock.MockRepository{}
+			test.mock(mRepo)
+
+			svc, err := templatecreate.NewService(templatecreate.ServiceConfig{Repository: mRepo})
+			require.NoError(err)
+
+			got, err := svc.Run(context.Background(), test.req)
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+			} else if assert.NoError(err) {
+				assert.Equal(test.req.Template.Name, got.Name)
+				assert.Equal(test.req.Template.Env, got.Env)
+			}
+
+			mRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package templateget
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the template get service.
+type ServiceConfig struct {
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.TemplateGet"})
+
+	return nil
+}
+
+// Service returns sandbox templates.
+type Service struct {
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new template get service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the template get request parameters.
+type Request struct {
+	// Name is the template name.
+	Name string
+}
+
+// Run returns a sandbox template.
+func (s *Service) Run(ctx context.Context, req Request) (*model.SandboxTemplate, error) {
+	t, err := s.repo.GetSandboxTemplate(ctx, req.Name)
+	if err != nil {
+		return nil, fmt.Errorf("could not get template: %w", err)
+	}
+
+	return t, nil
+}
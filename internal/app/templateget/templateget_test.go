@@ -0,0 +1,63 @@
+package templateget_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/templateget"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestServiceRun(t *testing.T) {
+	template := &model.SandboxTemplate{Name: "go-dev", Engine: model.TemplateEngineFake}
+
+	tests := map[string]struct {
+		mock        func(m *storagemock.MockRepository)
+		req         templateget.Request
+		expTemplate *model.SandboxTemplate
+		expErr      error
+	}{
+		"Getting a template should return the stored one.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxTemplate", mock.Anything, "go-dev").Once().Return(template, nil)
+			},
+			req:         templateget.Request{Name: "go-dev"},
+			expTemplate: template,
+		},
+
+		"A missing template should fail.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxTemplate", mock.Anything, "go-dev").Once().Return(nil, model.ErrNotFound)
+			},
+			req:    templateget.Request{Name: "go-dev"},
+			expErr: model.ErrNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mRepo := &storagemock.MockRepository{}
+			test.mock(mRepo)
+
+			svc, err := templateget.NewService(templateget.ServiceConfig{Repository: mRepo})
+			require.NoError(err)
+
+			got, err := svc.Run(context.Background(), test.req)
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expTemplate, got)
+			}
+
+			mRepo.AssertExpectations(t)
+		})
+	}
+}
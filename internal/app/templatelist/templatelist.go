@@ -0,0 +1,58 @@
+package templatelist
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the template list service.
+type ServiceConfig struct {
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.TemplateList"})
+
+	return nil
+}
+
+// Service lists the sandbox templates.
+type Service struct {
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new template list service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Run returns the sandbox templates sorted by name.
+func (s *Service) Run(ctx context.Context) ([]model.SandboxTemplate, error) {
+	templates, err := s.repo.ListSandboxTemplates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not list templates: %w", err)
+	}
+
+	s.logger.Debugf("found %d sandbox templates", len(templates))
+	return templates, nil
+}
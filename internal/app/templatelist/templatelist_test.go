@@ -0,0 +1,64 @@
+package templatelist_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/templatelist"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestServiceRun(t *testing.T) {
+	templates := []model.SandboxTemplate{
+		{Name: "fake", Engine: model.TemplateEngineFake},
+		{Name: "go-dev", Engine: model.TemplateEngineFirecracker, Image: "v0.1.0"},
+	}
+
+	tests := map[string]struct {
+		mock         func(m *storagemock.MockRepository)
+		expTemplates []model.SandboxTemplate
+		expErr       bool
+	}{
+		"Listing should return the stored templates.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("ListSandboxTemplates", mock.Anything).Once().Return(templates, nil)
+			},
+			expTemplates: templates,
+		},
+
+		"A repository error should fail.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("ListSandboxTemplates", mock.Anything).Once().Return(nil, fmt.Errorf("something"))
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mRepo := &storagemock.MockRepository{}
+			test.mock(mRepo)
+
+			svc, err := templatelist.NewService(templatelist.ServiceConfig{Repository: mRepo})
+			require.NoError(err)
+
+			got, err := svc.Run(context.Background())
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expTemplates, got)
+			}
+
+			mRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package templaterm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the template remove service.
+type ServiceConfig struct {
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.TemplateRm"})
+
+	return nil
+}
+
+// Service removes sandbox templates.
+type Service struct {
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new template remove service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the template remove request parameters.
+type Request struct {
+	// Name is the name of the template to remove.
+	Name string
+}
+
+// Run removes a sandbox template. The sandboxes created from it keep their spec.
+func (s *Service) Run(ctx context.Context, req Request) error {
+	if err := s.repo.DeleteSandboxTemplate(ctx, req.Name); err != nil {
+		return fmt.Errorf("could not delete template: %w", err)
+	}
+
+	s.logger.Infof("removed sandbox template: %s", req.Name)
+	return nil
+}
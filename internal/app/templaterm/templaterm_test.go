@@ -0,0 +1,59 @@
+package templaterm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/templaterm"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestServiceRun(t *testing.T) {
+	tests := map[string]struct {
+		mock   func(m *storagemock.MockRepository)
+		req    templaterm.Request
+		expErr error
+	}{
+		"Removing a template should delete it.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("DeleteSandboxTemplate", mock.Anything, "go-dev").Once().Return(nil)
+			},
+			req: templaterm.Request{Name: "go-dev"},
+		},
+
+		"A missing template should fail.": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("DeleteSandboxTemplate", mock.Anything, "go-dev").Once().Return(model.ErrNotFound)
+			},
+			req:    templaterm.Request{Name: "go-dev"},
+			expErr: model.ErrNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mRepo := &storagemock.MockRepository{}
+			test.mock(mRepo)
+
+			svc, err := templaterm.NewService(templaterm.ServiceConfig{Repository: mRepo})
+			require.NoError(err)
+
+			err = svc.Run(context.Background(), test.req)
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+			} else {
+				assert.NoError(err)
+			}
+
+			mRepo.AssertExpectations(t)
+		})
+	}
+}
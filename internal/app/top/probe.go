@@ -0,0 +1,214 @@
+package top
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// clockTicks is the procfs clock ticks per second (USER_HZ), 100 on all the Linux
+// architectures.
+const clockTicks = 100
+
+// probeScript prints the guest procfs data of the process table: the probe shell
+// PIDs, the uptime, load and memory, the users and a line per process with its UID,
+// resident memory (kB), command line and stat. It only needs a POSIX shell, cat,
+// grep and tr (available in the busybox based images).
+const probeScript = `echo "self $$ $PPID"
+echo "uptime $(cat /proc/uptime)"
+echo "loadavg $(cat /proc/loadavg)"
+grep -E '^(MemTotal|MemAvailable):' /proc/meminfo
+echo "passwd"
+cat /etc/passwd 2>/dev/null
+echo "procs"
+for d in /proc/[0-9]*; do
+  read -r stat 2>/dev/null < "$d/stat" || continue
+  uid= rss=0
+  while read -r k v _; do
+    case "$k" in
+    Uid:) uid=$v ;;
+    VmRSS:) rss=$v ;;
+    esac
+  done 2>/dev/null < "$d/status"
+  cmd=$(tr '\0\t\n' '   ' 2>/dev/null < "$d/cmdline")
+  printf '%s\t%s\t%s\t%s\n' "$uid" "$rss" "$cmd" "$stat"
+done
+`
+
+// execWrapperMark is in the command line of the guest shell running the sbx execs.
+const execWrapperMark = "/etc/sbx/session-env.sh"
+
+// parseProbe parses the probe script output into the process table collected at a
+// time.
+func parseProbe(out string, collectedAt time.Time) (*model.ProcessTable, error) {
+	table := &model.ProcessTable{CollectedAt: collectedAt, Processes: []model.Process{}}
+	users := map[string]string{}
+	var self, parent int
+	section := ""
+
+	for _, line := range strings.Split(out, "\n") {
+		if line == "" {
+			continue
+		}
+		switch line {
+		case "passwd", "procs":
+			section = line
+			continue
+		}
+
+		switch section {
+		case "":
+			key, value, _ := strings.Cut(line, " ")
+			fields := strings.Fields(value)
+			switch key {
+			case "self":
+				if len(fields) != 2 {
+					return nil, fmt.Errorf("unexpected probe pids: %q", line)
+				}
+				self, _ = strconv.Atoi(fields[0])
+				parent, _ = strconv.Atoi(fields[1])
+			case "uptime":
+				if len(fields) < 1 {
+					return nil, fmt.Errorf("unexpected uptime: %q", line)
+				}
+				secs, err := strconv.ParseFloat(fields[0], 64)
+				if err != nil {
+					return nil, fmt.Errorf("unexpected uptime: %q", line)
+				}
+				table.Uptime = time.Duration(secs * float64(time.Second))
+			case "loadavg":
+				if len(fields) < 3 {
+					return nil, fmt.Errorf("unexpected loadavg: %q", line)
+				}
+				table.Load1, _ = strconv.ParseFloat(fields[0], 64)
+				table.Load5, _ = strconv.ParseFloat(fields[1], 64)
+				table.Load15, _ = strconv.ParseFloat(fields[2], 64)
+			case "MemTotal:":
+				table.MemoryTotalBytes = parseKB(fields)
+			case "MemAvailable:":
+				table.MemoryAvailableBytes = parseKB(fields)
+			}
+		case "passwd":
+			parts := strings.Split(line, ":")
+			if len(parts) >= 3 {
+				users[parts[2]] = parts[0]
+			}
+		case "procs":
+			p, err := parseProcess(line, table, users)
+			if err != nil {
+				return nil, err
+			}
+			table.Processes = append(table.Processes, p)
+		}
+	}
+
+	// The probe shell, its children and the sbx exec shell running it are not listed.
+	table.Processes = slices.DeleteFunc(table.Processes, func(p model.Process) bool {
+		return p.PID == self || p.PPID == self || (p.PID == parent && strings.Contains(p.Command, execWrapperMark))
+	})
+
+	return table, nil
+}
+
+// parseProcess parses a probe process line.
+func parseProcess(line string, table *model.ProcessTable, users map[string]string) (model.Process, error) {
+	parts := strings.SplitN(line, "\t", 4)
+	if len(parts) != 4 {
+		return model.Process{}, fmt.Errorf("unexpected process line: %q", line)
+	}
+	uid, rssKB, cmdline, stat := parts[0], parts[1], strings.TrimSpace(parts[2]), parts[3]
+
+	// The name is between parentheses and can have spaces, the fields after it
+	// start with the state.
+	open, closing := strings.Index(stat, "("), strings.LastIndex(stat, ")")
+	if open < 0 || closing < open {
+		return model.Process{}, fmt.Errorf("unexpected process stat: %q", stat)
+	}
+	fields := strings.Fields(stat[closing+1:])
+	if len(fields) < 20 {
+		return model.Process{}, fmt.Errorf("unexpected process stat: %q", stat)
+	}
+
+	p := model.Process{State: fields[0], Command: cmdline}
+	p.PID, _ = strconv.Atoi(strings.TrimSpace(stat[:open]))
+	p.PPID, _ = strconv.Atoi(fields[1])
+	p.Threads, _ = strconv.Atoi(fields[17])
+	if p.Command == "" {
+		p.Command = "[" + stat[open+1:closing] + "]"
+	}
+
+	p.User = uid
+	if name, ok := users[uid]; ok {
+		p.User = name
+	}
+
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	p.CPUTime = ticks(utime + stime)
+	start, _ := strconv.ParseUint(fields[19], 10, 64)
+	p.StartedAt = table.CollectedAt.Add(ticks(start) - table.Uptime)
+	if running := table.Uptime - ticks(start); running > 0 {
+		p.CPUPercent = 100 * p.CPUTime.Seconds() / running.Seconds()
+	}
+
+	kb, _ := strconv.ParseUint(rssKB, 10, 64)
+	p.RSSBytes = kb * 1024
+	if table.MemoryTotalBytes > 0 {
+		p.MemoryPercent = 100 * float64(p.RSSBytes) / float64(table.MemoryTotalBytes)
+	}
+
+	return p, nil
+}
+
+// intervalCPU sets the CPU usage of the processes in the interval since the previous
+// table, the processes started after it keep their average since they started.
+func intervalCPU(table, prev *model.ProcessTable) {
+	interval := table.Uptime - prev.Uptime
+	if interval <= 0 {
+		return
+	}
+
+	prevCPU := map[int]model.Process{}
+	for _, p := range prev.Processes {
+		prevCPU[p.PID] = p
+	}
+	for i, p := range table.Processes {
+		old, ok := prevCPU[p.PID]
+		// A PID reused by another process has a different start time, the start times
+		// of the tables drift a bit as they are relative to the collection time.
+		if !ok || old.StartedAt.Sub(p.StartedAt).Abs() > time.Second || p.CPUTime < old.CPUTime {
+			continue
+		}
+		table.Processes[i].CPUPercent = 100 * (p.CPUTime - old.CPUTime).Seconds() / interval.Seconds()
+	}
+}
+
+// sortProcesses sorts the processes by CPU usage, then memory and PID.
+func sortProcesses(ps []model.Process) {
+	slices.SortStableFunc(ps, func(a, b model.Process) int {
+		if c := cmp.Compare(b.CPUPercent, a.CPUPercent); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.RSSBytes, a.RSSBytes); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.PID, b.PID)
+	})
+}
+
+func parseKB(fields []string) uint64 {
+	if len(fields) == 0 {
+		return 0
+	}
+	kb, _ := strconv.ParseUint(fields[0], 10, 64)
+	return kb * 1024
+}
+
+func ticks(n uint64) time.Duration {
+	return time.Duration(n) * time.Second / clockTicks
+}
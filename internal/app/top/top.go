@@ -0,0 +1,111 @@
+package top
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the top service.
+type ServiceConfig struct {
+	Engine     sandbox.Engine
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Engine == nil {
+		return fmt.Errorf("engine is required")
+	}
+
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.Top"})
+
+	return nil
+}
+
+// Service returns the process table of the sandbox guests.
+type Service struct {
+	engine sandbox.Engine
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new top service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		engine: cfg.Engine,
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the top request parameters.
+type Request struct {
+	// NameOrID is the sandbox name or ID.
+	NameOrID string
+	// Previous is the previous table of the sandbox (optional), the CPU usage is
+	// the one in the interval since it instead of the average since the processes
+	// started.
+	Previous *model.ProcessTable
+}
+
+// Run reads the process table of a running sandbox with a probe script executed in
+// the guest, the probe processes are not listed.
+func (s *Service) Run(ctx context.Context, req Request) (*model.ProcessTable, error) {
+	sb, err := s.repo.GetSandboxByName(ctx, req.NameOrID)
+	if errors.Is(err, model.ErrNotFound) {
+		sb, err = s.repo.GetSandbox(ctx, req.NameOrID)
+	}
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("sandbox not found: %s: %w", req.NameOrID, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not get sandbox: %w", err)
+	}
+
+	if sb.Status != model.SandboxStatusRunning {
+		return nil, fmt.Errorf("sandbox %s is not running (current status: %s): %w", sb.Name, sb.Status, model.ErrNotValid)
+	}
+
+	var stdout, stderr bytes.Buffer
+	result, err := s.engine.Exec(ctx, sb.ID, []string{"sh", "-c", probeScript}, model.ExecOpts{Stdout: &stdout, Stderr: &stderr})
+	if err != nil {
+		return nil, fmt.Errorf("could not execute process probe: %w", err)
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("process probe failed with exit code %d: %s", result.ExitCode, strings.TrimSpace(stderr.String()))
+	}
+
+	table, err := parseProbe(stdout.String(), time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("could not parse process probe: %w", err)
+	}
+	table.SandboxID = sb.ID
+	table.SandboxName = sb.Name
+	if req.Previous != nil && req.Previous.SandboxID == sb.ID {
+		intervalCPU(table, req.Previous)
+	}
+	sortProcesses(table.Processes)
+
+	s.logger.Debugf("read %d processes of sandbox %s", len(table.Processes), sb.ID)
+	return table, nil
+}
@@ -0,0 +1,185 @@
+package top_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/top"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox/sandboxmock"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+const testSandboxID = "01H2QWERTYASDFGZXCVBNMLKJH"
+
+var testRunning = &model.Sandbox{
+	ID:     testSandboxID,
+	Name:   "my-sandbox",
+	Status: model.SandboxStatusRunning,
+}
+
+// procStat returns a procfs stat line with the fields the process table uses.
+func procStat(pid, ppid int, comm, state string, utime, stime, threads, start int) string {
+	return fmt.Sprintf("%d (%s) %s %d 1 1 0 -1 4194560 100 0 0 0 %d %d 0 0 20 0 %d 0 %d 10000000 250", pid, comm, state, ppid, utime, stime, threads, start)
+}
+
+// testProbeOutput has the init, a kernel thread, an app and the probe processes (the
+// exec shell, the probe shell and a probe child) of a guest up for 100s.
+func testProbeOutput(appUtime int) string {
+	return "self 101 100\n" +
+		"uptime 100.00 180.00\n" +
+		"loadavg 0.50 0.25 0.10 2/80 102\n" +
+		"MemTotal:        1000000 kB\n" +
+		"MemAvailable:     800000 kB\n" +
+		"passwd\n" +
+		"root:x:0:0:root:/root:/bin/sh\n" +
+		"procs\n" +
+		"0\t1000\t/sbin/init \t" + procStat(1, 0, "init", "S", 50, 50, 1, 0) + "\n" +
+		"0\t0\t\t" + procStat(2, 0, "kthreadd", "S", 0, 0, 1, 0) + "\n" +
+		"1000\t20000\tpython3 app.py \t" + procStat(42, 1, "python3", "R", appUtime, 1000, 4, 1000) + "\n" +
+		"0\t500\tsh -c [ -f /etc/sbx/session-env.sh ] && . /etc/sbx/session-env.sh; sh -c probe \t" + procStat(100, 90, "sh", "S", 0, 0, 1, 9900) + "\n" +
+		"0\t500\tsh -c probe \t" + procStat(101, 100, "sh", "S", 0, 0, 1, 9900) + "\n" +
+		"0\t100\ttr  \t" + procStat(102, 101, "tr", "R", 0, 0, 1, 9990) + "\n"
+}
+
+func mockProbe(m *sandboxmock.MockEngine, out string, exitCode int) {
+	m.On("Exec", mock.Anything, testSandboxID, mock.Anything, mock.Anything).Once().Return(func(ctx context.Context, id string, cmd []string, opts model.ExecOpts) (*model.ExecResult, error) {
+		_, _ = fmt.Fprint(opts.Stdout, out)
+		return &model.ExecResult{ExitCode: exitCode}, nil
+	})
+}
+
+func TestServiceRun(t *testing.T) {
+	tests := map[string]struct {
+		mockRepo   func(m *storagemock.MockRepository)
+		mockEngine func(m *sandboxmock.MockEngine)
+		req        top.Request
+		expProcs   []model.Process
+		expErrIs   error
+		expErr     bool
+	}{
+		"Reading the process table should list the guest processes without the probe ones.": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(testRunning, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				mockProbe(m, testProbeOutput(4000), 0)
+			},
+			req: top.Request{NameOrID: "my-sandbox"},
+			expProcs: []model.Process{
+				{PID: 42, PPID: 1, User: "1000", State: "R", Command: "python3 app.py", CPUTime: 50 * time.Second, CPUPercent: 100 * 50.0 / 90.0, RSSBytes: 20000 * 1024, MemoryPercent: 2, Threads: 4, StartedAt: time.Time{}.Add(10 * time.Second)},
+				{PID: 1, PPID: 0, User: "root", State: "S", Command: "/sbin/init", CPUTime: time.Second, CPUPercent: 1, RSSBytes: 1000 * 1024, MemoryPercent: 0.1, Threads: 1},
+				{PID: 2, PPID: 0, User: "root", State: "S", Command: "[kthreadd]", Threads: 1},
+			},
+		},
+		"Reading the process table with a previous one should use the CPU usage in the interval.": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(testRunning, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				mockProbe(m, testProbeOutput(4000), 0)
+			},
+			req: top.Request{NameOrID: "my-sandbox", Previous: &model.ProcessTable{
+				SandboxID: testSandboxID,
+				Uptime:    98 * time.Second,
+				Processes: []model.Process{
+					{PID: 42, CPUTime: 49 * time.Second, StartedAt: time.Now().UTC().Add(-90 * time.Second)},
+				},
+			}},
+			expProcs: []model.Process{
+				{PID: 42, PPID: 1, User: "1000", State: "R", Command: "python3 app.py", CPUTime: 50 * time.Second, CPUPercent: 50, RSSBytes: 20000 * 1024, MemoryPercent: 2, Threads: 4, StartedAt: time.Time{}.Add(10 * time.Second)},
+				{PID: 1, PPID: 0, User: "root", State: "S", Command: "/sbin/init", CPUTime: time.Second, CPUPercent: 1, RSSBytes: 1000 * 1024, MemoryPercent: 0.1, Threads: 1},
+				{PID: 2, PPID: 0, User: "root", State: "S", Command: "[kthreadd]", Threads: 1},
+			},
+		},
+		"A failing probe should fail.": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(testRunning, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				mockProbe(m, "", 127)
+			},
+			req:    top.Request{NameOrID: "my-sandbox"},
+			expErr: true,
+		},
+		"An unexpected probe output should fail.": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(testRunning, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {
+				mockProbe(m, "uptime 100\nprocs\n1 (init) S\n", 0)
+			},
+			req:    top.Request{NameOrID: "my-sandbox"},
+			expErr: true,
+		},
+		"A stopped sandbox should fail.": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(&model.Sandbox{
+					ID:     testSandboxID,
+					Name:   "my-sandbox",
+					Status: model.SandboxStatusStopped,
+				}, nil)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        top.Request{NameOrID: "my-sandbox"},
+			expErrIs:   model.ErrNotValid,
+		},
+		"A missing sandbox should fail.": {
+			mockRepo: func(m *storagemock.MockRepository) {
+				m.On("GetSandboxByName", mock.Anything, "missing").Once().Return(nil, model.ErrNotFound)
+				m.On("GetSandbox", mock.Anything, "missing").Once().Return(nil, model.ErrNotFound)
+			},
+			mockEngine: func(m *sandboxmock.MockEngine) {},
+			req:        top.Request{NameOrID: "missing"},
+			expErrIs:   model.ErrNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mRepo := storagemock.NewMockRepository(t)
+			mEngine := sandboxmock.NewMockEngine(t)
+			test.mockRepo(mRepo)
+			test.mockEngine(mEngine)
+			svc, err := top.NewService(top.ServiceConfig{Engine: mEngine, Repository: mRepo, Logger: log.Noop})
+			require.NoError(t, err)
+
+			table, err := svc.Run(context.TODO(), test.req)
+
+			switch {
+			case test.expErrIs != nil:
+				assert.ErrorIs(t, err, test.expErrIs)
+			case test.expErr:
+				assert.Error(t, err)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, testSandboxID, table.SandboxID)
+				assert.Equal(t, "my-sandbox", table.SandboxName)
+				assert.Equal(t, 100*time.Second, table.Uptime)
+				assert.Equal(t, 0.5, table.Load1)
+				assert.Equal(t, uint64(1000000*1024), table.MemoryTotalBytes)
+				assert.Equal(t, uint64(800000*1024), table.MemoryAvailableBytes)
+
+				// The start times are relative to the collection time.
+				require.Len(t, table.Processes, len(test.expProcs))
+				boot := table.CollectedAt.Add(-100 * time.Second)
+				for i := range table.Processes {
+					assert.Equal(t, boot.Add(test.expProcs[i].StartedAt.Sub(time.Time{})), table.Processes[i].StartedAt)
+					table.Processes[i].StartedAt = test.expProcs[i].StartedAt
+					assert.InDelta(t, test.expProcs[i].CPUPercent, table.Processes[i].CPUPercent, 0.001)
+					assert.InDelta(t, test.expProcs[i].MemoryPercent, table.Processes[i].MemoryPercent, 0.001)
+					table.Processes[i].CPUPercent = test.expProcs[i].CPUPercent
+					table.Processes[i].MemoryPercent = test.expProcs[i].MemoryPercent
+				}
+				assert.Equal(t, test.expProcs, table.Processes)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// ServiceConfig is the configuration for the usage service.
+type ServiceConfig struct {
+	Repository storage.Repository
+	Logger     log.Logger
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.Usage"})
+
+	return nil
+}
+
+// Service reports the resources used by the sandboxes.
+type Service struct {
+	repo   storage.Repository
+	logger log.Logger
+}
+
+// NewService creates a new usage service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		repo:   cfg.Repository,
+		logger: cfg.Logger,
+	}, nil
+}
+
+// Request represents the usage request parameters.
+type Request struct {
+	// Since is the start of the report time range, zero means since the first record.
+	Since time.Time
+	// Until is the end of the report time range, zero means now.
+	Until time.Time
+	// NamePattern is an optional sandbox name pattern (see [model.SandboxNamePattern])
+	// to only report the sandboxes whose name matches.
+	NamePattern string
+	// Annotations only reports the sandboxes with all these annotations.
+	Annotations map[string]string
+	// GroupBy is an optional annotation key to group the sandboxes by its value.
+	GroupBy string
+}
+
+// Run returns the usage report of the stored usage records and the current usage
+// of the existing sandboxes. The annotations of the removed sandboxes (and of the
+// finished periods) are the ones they had when the period ended.
+func (s *Service) Run(ctx context.Context, req Request) (*model.UsageReport, error) {
+	now := time.Now().UTC()
+	if req.Until.IsZero() {
+		req.Until = now
+	}
+	if !req.Since.IsZero() && !req.Since.Before(req.Until) {
+		return nil, fmt.Errorf("since (%s) must be before until (%s): %w", req.Since.Format(time.RFC3339), req.Until.Format(time.RFC3339), model.ErrNotValid)
+	}
+
+	var pattern *model.SandboxNamePattern
+	if req.NamePattern != "" {
+		p, err := model.NewSandboxNamePattern(req.NamePattern)
+		if err != nil {
+			return nil, err
+		}
+		pattern = p
+	}
+
+	records, err := s.repo.ListUsageRecords(ctx, model.UsageRecordFilter{Since: req.Since, Until: req.Until})
+	if err != nil {
+		return nil, fmt.Errorf("could not list usage records: %w", err)
+	}
+
+	sandboxes, err := s.repo.ListSandboxes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not list sandboxes: %w", err)
+	}
+	existing := map[string]model.Sandbox{}
+	for _, sb := range sandboxes {
+		existing[sb.ID] = sb
+		records = append(records, model.SandboxUsageRecords(sb, now)...)
+	}
+
+	report := &model.UsageReport{
+		Since:   req.Since,
+		Until:   req.Until,
+		GroupBy: req.GroupBy,
+		Entries: []model.UsageReportEntry{},
+	}
+	entries := map[string]*model.UsageReportEntry{}
+	groupSandboxes := map[string]map[string]struct{}{}
+	for _, rec := range records {
+		if !rec.EndedAt.After(req.Since) || !rec.StartedAt.Before(req.Until) {
+			continue
+		}
+
+		// The existing sandboxes are reported with their current name and annotations.
+		if sb, ok := existing[rec.SandboxID]; ok {
+			rec.SandboxName = sb.Name
+			rec.Annotations = sb.Annotations
+		}
+		if pattern != nil && !pattern.Match(rec.SandboxName) {
+			continue
+		}
+		if !matchAnnotations(rec.Annotations, req.Annotations) {
+			continue
+		}
+
+		key := rec.SandboxID
+		if req.GroupBy != "" {
+			key = rec.Annotations[req.GroupBy]
+		}
+		e, ok := entries[key]
+		if !ok {
+			e = &model.UsageReportEntry{Key: key}
+			if req.GroupBy == "" {
+				_, exists := existing[rec.SandboxID]
+				e.Key = rec.SandboxName
+				e.SandboxID = rec.SandboxID
+				e.Removed = !exists
+			}
+			entries[key] = e
+			groupSandboxes[key] = map[string]struct{}{}
+		}
+		groupSandboxes[key][rec.SandboxID] = struct{}{}
+
+		u := rec.Usage(req.Since, req.Until)
+		e.Usage = e.Usage.Add(u)
+		report.Total = report.Total.Add(u)
+	}
+
+	for key, e := range entries {
+		e.Sandboxes = len(groupSandboxes[key])
+		report.Entries = append(report.Entries, *e)
+	}
+	sort.Slice(report.Entries, func(i, j int) bool {
+		a, b := report.Entries[i], report.Entries[j]
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		return a.SandboxID < b.SandboxID
+	})
+
+	s.logger.Debugf("reported usage of %d entries", len(report.Entries))
+	return report, nil
+}
+
+func matchAnnotations(annotations, selector map[string]string) bool {
+	for k, v := range selector {
+		got, ok := annotations[k]
+		if !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
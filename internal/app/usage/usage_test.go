@@ -0,0 +1,182 @@
+package usage_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/usage"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestNewService(t *testing.T) {
+	tests := map[string]struct {
+		config usage.ServiceConfig
+		expErr bool
+	}{
+		"valid config should create service": {
+			config: usage.ServiceConfig{
+				Repository: &storagemock.MockRepository{},
+				Logger:     log.Noop,
+			},
+		},
+		"missing repository should fail": {
+			config: usage.ServiceConfig{Logger: log.Noop},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			svc, err := usage.NewService(test.config)
+
+			if test.expErr {
+				require.Error(err)
+				require.Nil(svc)
+			} else {
+				require.NoError(err)
+				require.NotNil(svc)
+			}
+		})
+	}
+}
+
+func TestService_Run(t *testing.T) {
+	t0 := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	res := model.Resources{VCPUs: 2, MemoryMB: 1024, DiskGB: 10}
+
+	// A removed sandbox that ran one hour and existed two hours.
+	removed := []model.UsageRecord{
+		{SandboxID: "id-1", SandboxName: "sb-1", Annotations: map[string]string{"team": "infra"}, Kind: model.UsageKindRun, Resources: res, StartedAt: t0, EndedAt: t0.Add(time.Hour)},
+		{SandboxID: "id-1", SandboxName: "sb-1", Annotations: map[string]string{"team": "infra"}, Kind: model.UsageKindDisk, Resources: res, StartedAt: t0, EndedAt: t0.Add(2 * time.Hour)},
+	}
+	// An existing sandbox that ran one hour and is stopped.
+	existingRun := model.UsageRecord{SandboxID: "id-2", SandboxName: "sb-2", Kind: model.UsageKindRun, Resources: res, StartedAt: t0, EndedAt: t0.Add(time.Hour)}
+	existing := model.Sandbox{
+		ID:          "id-2",
+		Name:        "sb-2",
+		Annotations: map[string]string{"team": "web"},
+		Status:      model.SandboxStatusStopped,
+		CreatedAt:   t0,
+		Config:      model.SandboxConfig{Resources: res},
+	}
+	until := t0.Add(4 * time.Hour)
+
+	tests := map[string]struct {
+		mock      func(m *storagemock.MockRepository)
+		req       usage.Request
+		expReport *model.UsageReport
+		expErr    bool
+	}{
+		"report per sandbox should include the removed and existing sandboxes": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("ListUsageRecords", mock.Anything, model.UsageRecordFilter{Until: until}).Once().Return(append(removed, existingRun), nil)
+				m.On("ListSandboxes", mock.Anything).Once().Return([]model.Sandbox{existing}, nil)
+			},
+			req: usage.Request{Until: until},
+			expReport: &model.UsageReport{
+				Until: until,
+				Entries: []model.UsageReportEntry{
+					{Key: "sb-1", SandboxID: "id-1", Removed: true, Sandboxes: 1, Usage: model.Usage{CPUSeconds: 7200, MemoryMBHours: 1024, DiskGBHours: 20}},
+					{Key: "sb-2", SandboxID: "id-2", Sandboxes: 1, Usage: model.Usage{CPUSeconds: 7200, MemoryMBHours: 1024, DiskGBHours: 40}},
+				},
+				Total: model.Usage{CPUSeconds: 14400, MemoryMBHours: 2048, DiskGBHours: 60},
+			},
+		},
+		"report grouped by annotation should use the current annotations of the existing sandboxes": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("ListUsageRecords", mock.Anything, model.UsageRecordFilter{Until: until}).Once().Return(append(removed, existingRun), nil)
+				m.On("ListSandboxes", mock.Anything).Once().Return([]model.Sandbox{existing}, nil)
+			},
+			req: usage.Request{Until: until, GroupBy: "team"},
+			expReport: &model.UsageReport{
+				Until:   until,
+				GroupBy: "team",
+				Entries: []model.UsageReportEntry{
+					{Key: "infra", Sandboxes: 1, Usage: model.Usage{CPUSeconds: 7200, MemoryMBHours: 1024, DiskGBHours: 20}},
+					{Key: "web", Sandboxes: 1, Usage: model.Usage{CPUSeconds: 7200, MemoryMBHours: 1024, DiskGBHours: 40}},
+				},
+				Total: model.Usage{CPUSeconds: 14400, MemoryMBHours: 2048, DiskGBHours: 60},
+			},
+		},
+		"report with a time range should clip the usage": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("ListUsageRecords", mock.Anything, model.UsageRecordFilter{Since: t0.Add(90 * time.Minute), Until: until}).Once().Return(removed[1:], nil)
+				m.On("ListSandboxes", mock.Anything).Once().Return([]model.Sandbox{existing}, nil)
+			},
+			req: usage.Request{Since: t0.Add(90 * time.Minute), Until: until},
+			expReport: &model.UsageReport{
+				Since: t0.Add(90 * time.Minute),
+				Until: until,
+				Entries: []model.UsageReportEntry{
+					{Key: "sb-1", SandboxID: "id-1", Removed: true, Sandboxes: 1, Usage: model.Usage{DiskGBHours: 5}},
+					{Key: "sb-2", SandboxID: "id-2", Sandboxes: 1, Usage: model.Usage{DiskGBHours: 25}},
+				},
+				Total: model.Usage{DiskGBHours: 30},
+			},
+		},
+		"report filtered by name and annotations should only include the matching sandboxes": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("ListUsageRecords", mock.Anything, model.UsageRecordFilter{Until: until}).Once().Return(append(removed, existingRun), nil)
+				m.On("ListSandboxes", mock.Anything).Once().Return([]model.Sandbox{existing}, nil)
+			},
+			req: usage.Request{Until: until, NamePattern: "sb-*", Annotations: map[string]string{"team": "web"}},
+			expReport: &model.UsageReport{
+				Until: until,
+				Entries: []model.UsageReportEntry{
+					{Key: "sb-2", SandboxID: "id-2", Sandboxes: 1, Usage: model.Usage{CPUSeconds: 7200, MemoryMBHours: 1024, DiskGBHours: 40}},
+				},
+				Total: model.Usage{CPUSeconds: 7200, MemoryMBHours: 1024, DiskGBHours: 40},
+			},
+		},
+		"since after until should fail": {
+			mock:   func(m *storagemock.MockRepository) {},
+			req:    usage.Request{Since: until, Until: t0},
+			expErr: true,
+		},
+		"invalid name pattern should fail": {
+			mock:   func(m *storagemock.MockRepository) {},
+			req:    usage.Request{Until: until, NamePattern: "re:("},
+			expErr: true,
+		},
+		"repository error should propagate": {
+			mock: func(m *storagemock.MockRepository) {
+				m.On("ListUsageRecords", mock.Anything, mock.Anything).Once().Return(nil, fmt.Errorf("db error"))
+			},
+			req:    usage.Request{Until: until},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+			assert := assert.New(t)
+
+			mRepo := &storagemock.MockRepository{}
+			test.mock(mRepo)
+
+			svc, err := usage.NewService(usage.ServiceConfig{Repository: mRepo, Logger: log.Noop})
+			require.NoError(err)
+
+			report, err := svc.Run(context.Background(), test.req)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expReport, report)
+			}
+
+			mRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package wait
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/storage"
+)
+
+const (
+	// DefaultPollInterval is the default interval between condition checks.
+	DefaultPollInterval = time.Second
+	// DefaultProbeTimeout is the default timeout of a single readiness probe.
+	DefaultProbeTimeout = 5 * time.Second
+)
+
+// probeCommand is the command executed in the sandbox to check it accepts commands.
+var probeCommand = []string{"true"}
+
+// ServiceConfig is the configuration for the wait service.
+type ServiceConfig struct {
+	Engine       sandbox.Engine
+	Repository   storage.Repository
+	Logger       log.Logger
+	PollInterval time.Duration
+	ProbeTimeout time.Duration
+}
+
+func (c *ServiceConfig) defaults() error {
+	if c.Engine == nil {
+		return fmt.Errorf("engine is required")
+	}
+
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "app.Wait"})
+
+	if c.PollInterval <= 0 {
+		c.PollInterval = DefaultPollInterval
+	}
+
+	if c.ProbeTimeout <= 0 {
+		c.ProbeTimeout = DefaultProbeTimeout
+	}
+
+	return nil
+}
+
+// Service waits for a sandbox to reach a condition.
+type Service struct {
+	engine       sandbox.Engine
+	repo         storage.Repository
+	logger       log.Logger
+	pollInterval time.Duration
+	probeTimeout time.Duration
+}
+
+// NewService creates a new wait service.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Service{
+		engine:       cfg.Engine,
+		repo:         cfg.Repository,
+		logger:       cfg.Logger,
+		pollInterval: cfg.PollInterval,
+		probeTimeout: cfg.ProbeTimeout,
+	}, nil
+}
+
+// Request represents the wait request parameters.
+type Request struct {
+	// NameOrID is the sandbox name or ID to wait for.
+	NameOrID string
+	// Condition is the condition to wait for.
+	Condition model.WaitCondition
+	// Timeout is the maximum time to wait. Zero means no timeout (until context cancellation).
+	Timeout time.Duration
+}
+
+// Run blocks until the sandbox reaches the requested condition and returns the sandbox.
+//
+// It fails fast when the condition can't be reached anymore: the sandbox doesn't exist
+// or it has failed. When the timeout is reached the error wraps [context.DeadlineExceeded].
+func (s *Service) Run(ctx context.Context, req Request) (*model.Sandbox, error) {
+	if err := req.Condition.Validate(); err != nil {
+		return nil, err
+	}
+
+	if req.Timeout < 0 {
+		return nil, fmt.Errorf("timeout can't be negative: %w", model.ErrNotValid)
+	}
+
+	if req.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, req.Timeout)
+		defer cancel()
+	}
+
+	for {
+		sb, ok, err := s.check(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			s.logger.Debugf("sandbox %s (%s) is %s", sb.Name, sb.ID, req.Condition)
+			return sb, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, fmt.Errorf("timed out waiting for sandbox %s to be %s: %w", req.NameOrID, req.Condition, context.DeadlineExceeded)
+			}
+			return nil, ctx.Err()
+		case <-time.After(s.pollInterval):
+		}
+	}
+}
+
+// check returns true when the condition is met. Errors are only returned when the
+// condition can't be reached anymore.
+func (s *Service) check(ctx context.Context, req Request) (*model.Sandbox, bool, error) {
+	sb, err := s.getSandbox(ctx, req.NameOrID)
+	if err != nil {
+		// A timeout while reading the sandbox is not terminal, the wait loop handles it.
+		if ctx.Err() != nil {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	if req.Condition == model.WaitConditionStopped {
+		switch sb.Status {
+		case model.SandboxStatusStopped:
+			return sb, true, nil
+		case model.SandboxStatusFailed:
+			return nil, false, fmt.Errorf("sandbox %s failed and will not be stopped: %w", sb.Name, model.ErrNotValid)
+		}
+		return sb, false, nil
+	}
+
+	// Remaining conditions require the sandbox to be running.
+	switch sb.Status {
+	case model.SandboxStatusFailed:
+		return nil, false, fmt.Errorf("sandbox %s failed and will not be %s: %w", sb.Name, req.Condition, model.ErrNotValid)
+	case model.SandboxStatusRunning:
+	default:
+		return sb, false, nil
+	}
+
+	if req.Condition == model.WaitConditionRunning {
+		return sb, true, nil
+	}
+
+	if req.Condition == model.WaitConditionHealthy && !s.engineAlive(ctx, sb) {
+		return sb, false, nil
+	}
+
+	return sb, s.probe(ctx, sb), nil
+}
+
+// probe returns true when the sandbox accepts commands.
+func (s *Service) probe(ctx context.Context, sb *model.Sandbox) bool {
+	ctx, cancel := context.WithTimeout(ctx, s.probeTimeout)
+	defer cancel()
+
+	res, err := s.engine.Exec(ctx, sb.ID, probeCommand, model.ExecOpts{})
+	if err != nil {
+		s.logger.Debugf("sandbox %s probe failed: %s", sb.Name, err)
+		return false
+	}
+
+	return res.ExitCode == 0
+}
+
+// engineAlive returns true when the engine reports the sandbox VM as running. Engines
+// that don't track the sandbox (not found) rely on the probe only.
+func (s *Service) engineAlive(ctx context.Context, sb *model.Sandbox) bool {
+	esb, err := s.engine.Status(ctx, sb.ID)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return true
+		}
+		s.logger.Debugf("sandbox %s engine status failed: %s", sb.Name, err)
+		return false
+	}
+
+	return esb.Status == model.SandboxStatusRunning
+}
+
+func (s *Service) getSandbox(ctx context.Context, nameOrID string) (*model.Sandbox, error) {
+	sb, err := s.repo.GetSandboxByName(ctx, nameOrID)
+	if err != nil {
+		// Try by ID if name lookup failed.
+		if errors.Is(err, model.ErrNotFound) {
+			sb, err = s.repo.GetSandbox(ctx, nameOrID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not find sandbox: %w", err)
+		}
+	}
+
+	return sb, nil
+}
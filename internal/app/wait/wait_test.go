@@ -0,0 +1,179 @@
+package wait_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/app/wait"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox/sandboxmock"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+func TestNewService(t *testing.T) {
+	tests := map[string]struct {
+		config wait.ServiceConfig
+		expErr bool
+	}{
+		"valid config should create service": {
+			config: wait.ServiceConfig{
+				Engine:     &sandboxmock.MockEngine{},
+				Repository: &storagemock.MockRepository{},
+				Logger:     log.Noop,
+			},
+		},
+		"missing engine should fail": {
+			config: wait.ServiceConfig{
+				Repository: &storagemock.MockRepository{},
+			},
+			expErr: true,
+		},
+		"missing repository should fail": {
+			config: wait.ServiceConfig{
+				Engine: &sandboxmock.MockEngine{},
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			svc, err := wait.NewService(test.config)
+
+			if test.expErr {
+				require.Error(err)
+				require.Nil(svc)
+			} else {
+				require.NoError(err)
+				require.NotNil(svc)
+			}
+		})
+	}
+}
+
+func sandboxWithStatus(status model.SandboxStatus) *model.Sandbox {
+	return &model.Sandbox{
+		ID:     "01H2QWERTYASDFGZXCVBNMLKJH",
+		Name:   "my-sandbox",
+		Status: status,
+	}
+}
+
+func TestServiceRun(t *testing.T) {
+	const id = "01H2QWERTYASDFGZXCVBNMLKJH"
+
+	tests := map[string]struct {
+		mock   func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine)
+		req    wait.Request
+		expSb  *model.Sandbox
+		expErr error
+	}{
+		"Waiting for an invalid condition should fail.": {
+			mock:   func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {},
+			req:    wait.Request{NameOrID: "my-sandbox", Condition: "sleeping"},
+			expErr: model.ErrNotValid,
+		},
+
+		"Waiting for a missing sandbox should fail.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Return(nil, model.ErrNotFound)
+				mr.On("GetSandbox", mock.Anything, "my-sandbox").Return(nil, model.ErrNotFound)
+			},
+			req:    wait.Request{NameOrID: "my-sandbox", Condition: model.WaitConditionRunning},
+			expErr: model.ErrNotFound,
+		},
+
+		"Waiting for running should poll until the sandbox is running.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Twice().Return(sandboxWithStatus(model.SandboxStatusPending), nil)
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(sandboxWithStatus(model.SandboxStatusRunning), nil)
+			},
+			req:   wait.Request{NameOrID: "my-sandbox", Condition: model.WaitConditionRunning},
+			expSb: sandboxWithStatus(model.SandboxStatusRunning),
+		},
+
+		"Waiting for running on a failed sandbox should fail.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(sandboxWithStatus(model.SandboxStatusFailed), nil)
+			},
+			req:    wait.Request{NameOrID: "my-sandbox", Condition: model.WaitConditionRunning},
+			expErr: model.ErrNotValid,
+		},
+
+		"Waiting for stopped should return the stopped sandbox.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(sandboxWithStatus(model.SandboxStatusRunning), nil)
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Once().Return(sandboxWithStatus(model.SandboxStatusStopped), nil)
+			},
+			req:   wait.Request{NameOrID: "my-sandbox", Condition: model.WaitConditionStopped},
+			expSb: sandboxWithStatus(model.SandboxStatusStopped),
+		},
+
+		"Waiting for SSH ready should probe until the command succeeds.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Return(sandboxWithStatus(model.SandboxStatusRunning), nil)
+				me.On("Exec", mock.Anything, id, []string{"true"}, model.ExecOpts{}).Once().Return(nil, fmt.Errorf("connection refused"))
+				me.On("Exec", mock.Anything, id, []string{"true"}, model.ExecOpts{}).Once().Return(&model.ExecResult{ExitCode: 0}, nil)
+			},
+			req:   wait.Request{NameOrID: "my-sandbox", Condition: model.WaitConditionSSHReady},
+			expSb: sandboxWithStatus(model.SandboxStatusRunning),
+		},
+
+		"Waiting for healthy should check the engine status and probe.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Return(sandboxWithStatus(model.SandboxStatusRunning), nil)
+				me.On("Status", mock.Anything, id).Once().Return(sandboxWithStatus(model.SandboxStatusStopped), nil)
+				me.On("Status", mock.Anything, id).Once().Return(sandboxWithStatus(model.SandboxStatusRunning), nil)
+				me.On("Exec", mock.Anything, id, []string{"true"}, model.ExecOpts{}).Once().Return(&model.ExecResult{ExitCode: 0}, nil)
+			},
+			req:   wait.Request{NameOrID: "my-sandbox", Condition: model.WaitConditionHealthy},
+			expSb: sandboxWithStatus(model.SandboxStatusRunning),
+		},
+
+		"Waiting past the timeout should fail with a deadline error.": {
+			mock: func(mr *storagemock.MockRepository, me *sandboxmock.MockEngine) {
+				mr.On("GetSandboxByName", mock.Anything, "my-sandbox").Return(sandboxWithStatus(model.SandboxStatusStopped), nil)
+			},
+			req:    wait.Request{NameOrID: "my-sandbox", Condition: model.WaitConditionRunning, Timeout: 20 * time.Millisecond},
+			expErr: context.DeadlineExceeded,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mr := &storagemock.MockRepository{}
+			me := &sandboxmock.MockEngine{}
+			test.mock(mr, me)
+
+			svc, err := wait.NewService(wait.ServiceConfig{
+				Engine:       me,
+				Repository:   mr,
+				PollInterval: time.Millisecond,
+			})
+			require.NoError(err)
+
+			sb, err := svc.Run(context.TODO(), test.req)
+
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expSb, sb)
+			}
+
+			mr.AssertExpectations(t)
+			me.AssertExpectations(t)
+		})
+	}
+}
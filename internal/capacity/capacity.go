@@ -0,0 +1,186 @@
+package capacity
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// DefaultOvercommit are the default overcommit ratios. Sandboxes are mostly idle so
+// the VCPUs are overcommitted, memory and disk are not.
+var DefaultOvercommit = model.OvercommitRatios{VCPUs: 4, Memory: 1, Disk: 1}
+
+// HostResourcesFunc returns the host resources.
+type HostResourcesFunc func(ctx context.Context) (model.Resources, error)
+
+// PlannerConfig is the configuration for the capacity planner.
+type PlannerConfig struct {
+	Repository storage.Repository
+	// HostResources returns the host resources. Defaults to the resources of this host,
+	// using the DataDir filesystem for the disk.
+	HostResources HostResourcesFunc
+	// DataDir is the directory where the sandboxes are stored. Required when
+	// HostResources is not set.
+	DataDir string
+	// Overcommit are the overcommit ratios, the zero ones use the default.
+	Overcommit model.OvercommitRatios
+	// Mode is the admission mode. Defaults to off.
+	Mode   model.AdmissionMode
+	Logger log.Logger
+}
+
+func (c *PlannerConfig) defaults() error {
+	if c.Repository == nil {
+		return fmt.Errorf("repository is required")
+	}
+
+	if c.HostResources == nil {
+		if c.DataDir == "" {
+			return fmt.Errorf("data dir is required")
+		}
+		dataDir := c.DataDir
+		c.HostResources = func(ctx context.Context) (model.Resources, error) { return HostResources(dataDir) }
+	}
+
+	if c.Overcommit.VCPUs == 0 {
+		c.Overcommit.VCPUs = DefaultOvercommit.VCPUs
+	}
+	if c.Overcommit.Memory == 0 {
+		c.Overcommit.Memory = DefaultOvercommit.Memory
+	}
+	if c.Overcommit.Disk == 0 {
+		c.Overcommit.Disk = DefaultOvercommit.Disk
+	}
+	if err := c.Overcommit.Validate(); err != nil {
+		return err
+	}
+
+	switch c.Mode {
+	case "":
+		c.Mode = model.AdmissionModeOff
+	case model.AdmissionModeOff, model.AdmissionModeWarn, model.AdmissionModeEnforce:
+	default:
+		return fmt.Errorf("admission mode must be %q, %q or %q, got %q: %w", model.AdmissionModeOff, model.AdmissionModeWarn, model.AdmissionModeEnforce, c.Mode, model.ErrNotValid)
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "capacity.Planner"})
+
+	return nil
+}
+
+// Planner reports the host capacity and admits the sandboxes based on it.
+type Planner struct {
+	repo          storage.Repository
+	hostResources HostResourcesFunc
+	overcommit    model.OvercommitRatios
+	mode          model.AdmissionMode
+	logger        log.Logger
+}
+
+// NewPlanner returns a new capacity planner.
+func NewPlanner(cfg PlannerConfig) (*Planner, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Planner{
+		repo:          cfg.Repository,
+		hostResources: cfg.HostResources,
+		overcommit:    cfg.Overcommit,
+		mode:          cfg.Mode,
+		logger:        cfg.Logger,
+	}, nil
+}
+
+// Capacity returns the host capacity and its current allocation.
+func (p *Planner) Capacity(ctx context.Context) (*model.HostCapacity, error) {
+	total, err := p.hostResources(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get host resources: %w", err)
+	}
+
+	sbs, err := p.repo.ListAllSandboxes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not list sandboxes: %w", err)
+	}
+
+	c := &model.HostCapacity{
+		Total:      total,
+		Overcommit: p.overcommit,
+		Sandboxes:  len(sbs),
+	}
+	for _, sb := range sbs {
+		c.Allocated.DiskGB += sb.Config.Resources.DiskGB
+		if sb.Status == model.SandboxStatusRunning {
+			c.RunningSandboxes++
+			c.Allocated.VCPUs += sb.Config.Resources.VCPUs
+			c.Allocated.MemoryMB += sb.Config.Resources.MemoryMB
+		}
+	}
+
+	return c, nil
+}
+
+// AdmitCreate checks there is disk capacity for a new sandbox.
+func (p *Planner) AdmitCreate(ctx context.Context, res model.Resources) error {
+	if p.mode == model.AdmissionModeOff {
+		return nil
+	}
+
+	c, err := p.Capacity(ctx)
+	if err != nil {
+		return err
+	}
+
+	var exceeded []string
+	if free := c.Free(); res.DiskGB > free.DiskGB {
+		exceeded = append(exceeded, fmt.Sprintf("disk %dGB requested, %dGB free", res.DiskGB, max(free.DiskGB, 0)))
+	}
+
+	return p.admit("create", exceeded)
+}
+
+// AdmitStart checks there is VCPU and memory capacity to start the sandbox.
+func (p *Planner) AdmitStart(ctx context.Context, sb model.Sandbox) error {
+	if p.mode == model.AdmissionModeOff {
+		return nil
+	}
+
+	c, err := p.Capacity(ctx)
+	if err != nil {
+		return err
+	}
+
+	res := sb.Config.Resources
+	free := c.Free()
+	var exceeded []string
+	if res.VCPUs > free.VCPUs {
+		exceeded = append(exceeded, fmt.Sprintf("%g VCPUs requested, %g free", res.VCPUs, max(free.VCPUs, 0)))
+	}
+	if res.MemoryMB > free.MemoryMB {
+		exceeded = append(exceeded, fmt.Sprintf("memory %dMB requested, %dMB free", res.MemoryMB, max(free.MemoryMB, 0)))
+	}
+
+	return p.admit("start", exceeded)
+}
+
+func (p *Planner) admit(op string, exceeded []string) error {
+	if len(exceeded) == 0 {
+		return nil
+	}
+
+	msg := fmt.Sprintf("not enough host capacity to %s the sandbox: %s", op, strings.Join(exceeded, ", "))
+	if p.mode == model.AdmissionModeWarn {
+		p.logger.Warningf("%s", msg)
+		return nil
+	}
+
+	return fmt.Errorf("%s: %w", msg, model.ErrNotValid)
+}
@@ -0,0 +1,190 @@
+package capacity_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/capacity"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/memory"
+)
+
+func newRepo(t *testing.T, sbs ...model.Sandbox) *memory.Repository {
+	t.Helper()
+	repo, err := memory.NewRepository(memory.RepositoryConfig{})
+	require.NoError(t, err)
+	for i, sb := range sbs {
+		sb.ID = fmt.Sprintf("sb-%d", i)
+		sb.Name = sb.ID
+		sb.CreatedAt = time.Now()
+		require.NoError(t, repo.CreateSandbox(context.Background(), sb))
+	}
+	return repo
+}
+
+func sandbox(status model.SandboxStatus, vcpus float64, memoryMB, diskGB int) model.Sandbox {
+	return model.Sandbox{
+		Status: status,
+		Config: model.SandboxConfig{Resources: model.Resources{VCPUs: vcpus, MemoryMB: memoryMB, DiskGB: diskGB}},
+	}
+}
+
+func hostResources(r model.Resources) capacity.HostResourcesFunc {
+	return func(ctx context.Context) (model.Resources, error) { return r, nil }
+}
+
+func TestPlannerCapacity(t *testing.T) {
+	repo := newRepo(t,
+		sandbox(model.SandboxStatusRunning, 2, 2048, 10),
+		sandbox(model.SandboxStatusRunning, 0.5, 512, 5),
+		sandbox(model.SandboxStatusStopped, 4, 4096, 20),
+	)
+
+	p, err := capacity.NewPlanner(capacity.PlannerConfig{
+		Repository:    repo,
+		HostResources: hostResources(model.Resources{VCPUs: 8, MemoryMB: 16384, DiskGB: 100}),
+		Overcommit:    model.OvercommitRatios{Memory: 1.5},
+	})
+	require.NoError(t, err)
+
+	got, err := p.Capacity(context.Background())
+	require.NoError(t, err)
+
+	exp := &model.HostCapacity{
+		Total:            model.Resources{VCPUs: 8, MemoryMB: 16384, DiskGB: 100},
+		Allocated:        model.Resources{VCPUs: 2.5, MemoryMB: 2560, DiskGB: 35},
+		Overcommit:       model.OvercommitRatios{VCPUs: 4, Memory: 1.5, Disk: 1},
+		Sandboxes:        3,
+		RunningSandboxes: 2,
+	}
+	assert.Equal(t, exp, got)
+	assert.Equal(t, model.Resources{VCPUs: 32, MemoryMB: 24576, DiskGB: 100}, got.Allocatable())
+	assert.Equal(t, model.Resources{VCPUs: 29.5, MemoryMB: 22016, DiskGB: 65}, got.Free())
+}
+
+func TestPlannerAdmit(t *testing.T) {
+	host := model.Resources{VCPUs: 2, MemoryMB: 4096, DiskGB: 20}
+	overcommit := model.OvercommitRatios{VCPUs: 1, Memory: 1, Disk: 1}
+
+	tests := map[string]struct {
+		mode   model.AdmissionMode
+		admit  func(ctx context.Context, p *capacity.Planner) error
+		expErr bool
+	}{
+		"Creating a sandbox that fits should be admitted.": {
+			mode: model.AdmissionModeEnforce,
+			admit: func(ctx context.Context, p *capacity.Planner) error {
+				return p.AdmitCreate(ctx, model.Resources{VCPUs: 8, MemoryMB: 8192, DiskGB: 10})
+			},
+		},
+
+		"Creating a sandbox without disk capacity should be refused.": {
+			mode: model.AdmissionModeEnforce,
+			admit: func(ctx context.Context, p *capacity.Planner) error {
+				return p.AdmitCreate(ctx, model.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 11})
+			},
+			expErr: true,
+		},
+
+		"Starting a sandbox that fits should be admitted.": {
+			mode: model.AdmissionModeEnforce,
+			admit: func(ctx context.Context, p *capacity.Planner) error {
+				return p.AdmitStart(ctx, sandbox(model.SandboxStatusStopped, 1, 2048, 5))
+			},
+		},
+
+		"Starting a sandbox without VCPU capacity should be refused.": {
+			mode: model.AdmissionModeEnforce,
+			admit: func(ctx context.Context, p *capacity.Planner) error {
+				return p.AdmitStart(ctx, sandbox(model.SandboxStatusStopped, 1.5, 512, 5))
+			},
+			expErr: true,
+		},
+
+		"Starting a sandbox without memory capacity should be refused.": {
+			mode: model.AdmissionModeEnforce,
+			admit: func(ctx context.Context, p *capacity.Planner) error {
+				return p.AdmitStart(ctx, sandbox(model.SandboxStatusStopped, 1, 2049, 5))
+			},
+			expErr: true,
+		},
+
+		"Exceeding the capacity in warn mode should be admitted.": {
+			mode: model.AdmissionModeWarn,
+			admit: func(ctx context.Context, p *capacity.Planner) error {
+				return p.AdmitStart(ctx, sandbox(model.SandboxStatusStopped, 4, 8192, 5))
+			},
+		},
+
+		"Exceeding the capacity with admission off should be admitted.": {
+			mode: model.AdmissionModeOff,
+			admit: func(ctx context.Context, p *capacity.Planner) error {
+				return p.AdmitCreate(ctx, model.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 1000})
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// A running sandbox uses 1 VCPU, 2048MB and 10GB.
+			repo := newRepo(t, sandbox(model.SandboxStatusRunning, 1, 2048, 10))
+			p, err := capacity.NewPlanner(capacity.PlannerConfig{
+				Repository:    repo,
+				HostResources: hostResources(host),
+				Overcommit:    overcommit,
+				Mode:          test.mode,
+			})
+			require.NoError(t, err)
+
+			err = test.admit(context.Background(), p)
+			if test.expErr {
+				assert.ErrorIs(t, err, model.ErrNotValid)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNewPlanner(t *testing.T) {
+	tests := map[string]struct {
+		cfg    capacity.PlannerConfig
+		expErr bool
+	}{
+		"A repository and the data dir should be valid.": {
+			cfg: capacity.PlannerConfig{DataDir: "/tmp"},
+		},
+
+		"A missing data dir without host resources should fail.": {
+			cfg:    capacity.PlannerConfig{},
+			expErr: true,
+		},
+
+		"Negative overcommit ratios should fail.": {
+			cfg:    capacity.PlannerConfig{DataDir: "/tmp", Overcommit: model.OvercommitRatios{VCPUs: -1}},
+			expErr: true,
+		},
+
+		"An unknown admission mode should fail.": {
+			cfg:    capacity.PlannerConfig{DataDir: "/tmp", Mode: "strict"},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test.cfg.Repository = newRepo(t)
+			_, err := capacity.NewPlanner(test.cfg)
+			if test.expErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
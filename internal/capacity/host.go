@@ -0,0 +1,69 @@
+package capacity
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// HostResources returns the resources of this host: the CPUs, the total memory and the
+// size of the filesystem of the data dir (or its closest existing parent).
+func HostResources(dataDir string) (model.Resources, error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return model.Resources{}, fmt.Errorf("could not open meminfo: %w", err)
+	}
+	defer f.Close()
+
+	memoryMB, err := parseMemTotal(f)
+	if err != nil {
+		return model.Resources{}, err
+	}
+
+	var diskBytes uint64
+	for dir := filepath.Clean(dataDir); ; dir = filepath.Dir(dir) {
+		diskBytes, err = filesystemSize(dir)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, fs.ErrNotExist) || dir == filepath.Dir(dir) {
+			return model.Resources{}, fmt.Errorf("could not stat %s filesystem: %w", dir, err)
+		}
+	}
+
+	return model.Resources{
+		VCPUs:    float64(runtime.NumCPU()),
+		MemoryMB: memoryMB,
+		DiskGB:   int(diskBytes / (1 << 30)),
+	}, nil
+}
+
+// parseMemTotal returns the total memory in MB from a /proc/meminfo content.
+func parseMemTotal(r io.Reader) (int, error) {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		// Format: "MemTotal:       16318480 kB".
+		fields := strings.Fields(s.Text())
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+		kb, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid MemTotal %q: %w", fields[1], err)
+		}
+		return kb / 1024, nil
+	}
+	if err := s.Err(); err != nil {
+		return 0, fmt.Errorf("could not read meminfo: %w", err)
+	}
+	return 0, fmt.Errorf("MemTotal not found in meminfo")
+}
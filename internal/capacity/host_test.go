@@ -0,0 +1,52 @@
+package capacity
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMemTotal(t *testing.T) {
+	tests := map[string]struct {
+		meminfo   string
+		expMemory int
+		expErr    bool
+	}{
+		"The MemTotal should be returned in MB.": {
+			meminfo:   "MemTotal:       16318480 kB\nMemFree:         1029440 kB\n",
+			expMemory: 15936,
+		},
+
+		"A meminfo without MemTotal should fail.": {
+			meminfo: "MemFree:         1029440 kB\n",
+			expErr:  true,
+		},
+
+		"An invalid MemTotal should fail.": {
+			meminfo: "MemTotal:       lots kB\n",
+			expErr:  true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseMemTotal(strings.NewReader(test.meminfo))
+			if test.expErr {
+				assert.Error(t, err)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, test.expMemory, got)
+			}
+		})
+	}
+}
+
+func TestHostResources(t *testing.T) {
+	// A missing data dir should use its closest existing parent.
+	got, err := HostResources(filepath.Join(t.TempDir(), "missing", "dir"))
+	if assert.NoError(t, err) {
+		assert.Positive(t, got.VCPUs)
+		assert.Positive(t, got.MemoryMB)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package capacity
+
+import "golang.org/x/sys/unix"
+
+// filesystemSize returns the size in bytes of the filesystem of the path.
+func filesystemSize(path string) (uint64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return st.Blocks * uint64(st.Bsize), nil
+}
@@ -0,0 +1,17 @@
+package capacity
+
+import "golang.org/x/sys/windows"
+
+// filesystemSize returns the size in bytes of the filesystem of the path.
+func filesystemSize(path string) (uint64, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var free, total, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &free, &total, &totalFree); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
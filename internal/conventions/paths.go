@@ -9,6 +9,15 @@ const (
 	VMsDir = "vms"
 	// ImagesDir is the subdirectory for images.
 	ImagesDir = "images"
+	// HostLocksDir is the subdirectory for the locks of the host resources shared by
+	// the clients of a data directory (VM directories, TAP devices, nftables).
+	HostLocksDir = "host-locks"
+	// ReservationsDir is the subdirectory for the resource reservation manifests of
+	// the sandboxes, read by the external schedulers sharing the host.
+	ReservationsDir = "reservations"
+	// HooksDir is the subdirectory for the host hook executables of the sandbox
+	// lifecycle events, in a subdirectory per event.
+	HooksDir = "hooks"
 
 	// VM-level files.
 
@@ -29,6 +38,12 @@ const (
 	ProxyLogFile = "proxy.log"
 	// ProxyPortFile is the JSON file storing allocated proxy ports.
 	ProxyPortFile = "proxy.json"
+	// ProxyStateFile is the JSON file storing the proxy health reported by its supervisor.
+	ProxyStateFile = "proxy-state.json"
+	// DNSEventsFile is the JSON lines file storing the DNS queries answered by the proxy.
+	DNSEventsFile = "dns-events.jsonl"
+	// ProxySessionsFile is the JSON file storing the connections forwarded by the proxy.
+	ProxySessionsFile = "proxy-sessions.json"
 
 	// SSH key files.
 
@@ -38,24 +53,68 @@ const (
 	SSHPublicKeyFile = "id_ed25519.pub"
 	// AuthorizedKeysPath is the path inside the rootfs for SSH authorized keys.
 	AuthorizedKeysPath = "/root/.ssh/authorized_keys"
+
+	// Guest clipboard files.
+
+	// GuestClipboardPath is the path inside the sandbox of the clipboard shared
+	// with the host.
+	GuestClipboardPath = "/tmp/sbx/clipboard"
+	// GuestDropDir is the directory inside the sandbox the host drops files into
+	// and picks files up from.
+	GuestDropDir = "/tmp/sbx/drop"
+
+	// TLS files.
+
+	// TLSDir is the subdirectory for the generated TLS certificate of the port
+	// forwards and the ingress.
+	TLSDir = "tls"
+	// TLSCertFile is the filename of the generated localhost TLS certificate.
+	TLSCertFile = "localhost.crt"
+	// TLSKeyFile is the filename of the generated localhost TLS key.
+	TLSKeyFile = "localhost.key"
 )
 
-// VMDir returns the directory for a specific sandbox VM.
-func VMDir(dataDir, sandboxID string) string {
-	return filepath.Join(dataDir, VMsDir, sandboxID)
+// VMsPath returns the default directory of the sandbox VMs inside a data directory.
+func VMsPath(dataDir string) string {
+	return filepath.Join(dataDir, VMsDir)
+}
+
+// ImagesPath returns the default directory of the images inside a data directory.
+func ImagesPath(dataDir string) string {
+	return filepath.Join(dataDir, ImagesDir)
+}
+
+// HostLocksPath returns the directory of the host resource locks inside a data directory.
+func HostLocksPath(dataDir string) string {
+	return filepath.Join(dataDir, HostLocksDir)
+}
+
+// HooksPath returns the directory of the host hooks inside a data directory.
+func HooksPath(dataDir string) string {
+	return filepath.Join(dataDir, HooksDir)
+}
+
+// ReservationsPath returns the directory of the reservation manifests inside a data directory.
+func ReservationsPath(dataDir string) string {
+	return filepath.Join(dataDir, ReservationsDir)
+}
+
+// VMDir returns the directory for a specific sandbox VM inside the VMs directory.
+func VMDir(vmsDir, sandboxID string) string {
+	return filepath.Join(vmsDir, sandboxID)
 }
 
 // VMFilePath returns the full path to a file inside a sandbox VM directory.
-func VMFilePath(dataDir, sandboxID, filename string) string {
-	return filepath.Join(VMDir(dataDir, sandboxID), filename)
+func VMFilePath(vmsDir, sandboxID, filename string) string {
+	return filepath.Join(VMDir(vmsDir, sandboxID), filename)
 }
 
 // SSHPrivateKeyPath returns the path to a sandbox's SSH private key.
-func SSHPrivateKeyPath(dataDir, sandboxID string) string {
-	return VMFilePath(dataDir, sandboxID, SSHPrivateKeyFile)
+func SSHPrivateKeyPath(vmsDir, sandboxID string) string {
+	return VMFilePath(vmsDir, sandboxID, SSHPrivateKeyFile)
 }
 
 // SSHPublicKeyPath returns the path to a sandbox's SSH public key.
-func SSHPublicKeyPath(dataDir, sandboxID string) string {
-	return VMFilePath(dataDir, sandboxID, SSHPublicKeyFile)
+func SSHPublicKeyPath(vmsDir, sandboxID string) string {
+	return VMFilePath(vmsDir, sandboxID, SSHPublicKeyFile)
 }
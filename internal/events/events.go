@@ -0,0 +1,243 @@
+// Package events sends the structured sbx lifecycle and security events to the
+// configured sinks (JSON lines files, the systemd journal, webhooks), so the SIEM
+// pipelines can ingest the sbx activity and the users get notified of the sandbox
+// state transitions.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+// SinksEnv is the environment variable the event sinks are passed to the proxy
+// processes with, it keeps the webhook secrets out of their command line.
+const SinksEnv = "SBX_EVENT_SINKS"
+
+// queueSize is the number of events waiting to be sent, the newer ones are dropped
+// when the sinks can't keep up.
+const queueSize = 1024
+
+// Sink sends events to a destination.
+type Sink interface {
+	Send(ctx context.Context, ev model.Event) error
+}
+
+// NewSink returns the sink of a sink configuration.
+func NewSink(cfg model.EventSinkConfig) (Sink, error) {
+	switch cfg.Type {
+	case model.EventSinkTypeFile:
+		return NewFileSink(cfg.Path)
+	case model.EventSinkTypeJournald:
+		return NewJournaldSink(""), nil
+	case model.EventSinkTypeWebhook:
+		return NewWebhookSink(WebhookSinkConfig{URL: cfg.URL, Secret: cfg.Secret})
+	default:
+		return nil, fmt.Errorf("unknown event sink type %q: %w", cfg.Type, model.ErrNotValid)
+	}
+}
+
+// jsonEvent is the JSON format of the events.
+type jsonEvent struct {
+	Time        time.Time         `json:"time"`
+	Type        model.EventType   `json:"type"`
+	SandboxID   string            `json:"sandbox_id,omitempty"`
+	SandboxName string            `json:"sandbox_name,omitempty"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+}
+
+// MarshalEvent returns the JSON format of an event, the one written by the file
+// sink and sent by the webhook sink.
+func MarshalEvent(ev model.Event) ([]byte, error) {
+	return json.Marshal(jsonEvent(ev))
+}
+
+// UnmarshalEvent parses an event in JSON format.
+func UnmarshalEvent(data []byte) (model.Event, error) {
+	var ev jsonEvent
+	if err := json.Unmarshal(data, &ev); err != nil {
+		return model.Event{}, fmt.Errorf("could not parse event: %w", err)
+	}
+	return model.Event(ev), nil
+}
+
+// jsonSinkConfig is the JSON format of the sink configurations, used to pass them
+// to the proxy processes.
+type jsonSinkConfig struct {
+	Type   model.EventSinkType `json:"type"`
+	Path   string              `json:"path,omitempty"`
+	URL    string              `json:"url,omitempty"`
+	Secret string              `json:"secret,omitempty"`
+	Events []model.EventType   `json:"events,omitempty"`
+}
+
+// MarshalSinkConfigs returns the JSON format of sink configurations.
+func MarshalSinkConfigs(cfgs []model.EventSinkConfig) string {
+	js := make([]jsonSinkConfig, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		js = append(js, jsonSinkConfig(cfg))
+	}
+	data, _ := json.Marshal(js)
+	return string(data)
+}
+
+// ParseSinkConfigs parses sink configurations in JSON format and validates them.
+// An empty string has no sinks.
+func ParseSinkConfigs(raw string) ([]model.EventSinkConfig, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	var js []jsonSinkConfig
+	if err := json.Unmarshal([]byte(raw), &js); err != nil {
+		return nil, fmt.Errorf("could not parse event sinks: %w", model.ErrNotValid)
+	}
+
+	cfgs := make([]model.EventSinkConfig, 0, len(js))
+	for _, j := range js {
+		cfg := model.EventSinkConfig(j)
+		if _, err := NewSink(cfg); err != nil {
+			return nil, err
+		}
+		cfgs = append(cfgs, cfg)
+	}
+	return cfgs, nil
+}
+
+// filteredSink is a sink with the event types it receives.
+type filteredSink struct {
+	sink Sink
+	cfg  model.EventSinkConfig
+}
+
+// delivery is an event waiting to be sent to its sinks.
+type delivery struct {
+	ev    model.Event
+	sinks []filteredSink
+}
+
+// Emitter sends the events to its sinks in the background, in the order they were
+// emitted. The events are best effort, a sink failure is logged and never fails the
+// operation that emitted the event.
+type Emitter struct {
+	sinks  []filteredSink
+	logger log.Logger
+
+	mu     sync.Mutex
+	closed bool
+	queue  chan delivery
+	done   chan struct{}
+}
+
+// NewEmitter returns an emitter of the sink configurations, it must be closed to
+// send the pending events.
+func NewEmitter(cfgs []model.EventSinkConfig, logger log.Logger) (*Emitter, error) {
+	if logger == nil {
+		logger = log.Noop
+	}
+
+	sinks, err := newFilteredSinks(cfgs)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Emitter{
+		sinks:  sinks,
+		logger: logger,
+		queue:  make(chan delivery, queueSize),
+		done:   make(chan struct{}),
+	}
+	go e.run()
+
+	return e, nil
+}
+
+func newFilteredSinks(cfgs []model.EventSinkConfig) ([]filteredSink, error) {
+	sinks := make([]filteredSink, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		s, err := NewSink(cfg)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, filteredSink{sink: s, cfg: cfg})
+	}
+	return sinks, nil
+}
+
+// Enabled returns true if the emitter has sinks. A nil emitter has none.
+func (e *Emitter) Enabled() bool { return e != nil && len(e.sinks) > 0 }
+
+// Emit queues an event for the emitter sinks and the extra sinks of this event (e.g.
+// the webhooks of a sandbox). A nil emitter discards the events.
+func (e *Emitter) Emit(ev model.Event, extra ...model.EventSinkConfig) {
+	if e == nil {
+		return
+	}
+
+	extraSinks, err := newFilteredSinks(extra)
+	if err != nil {
+		e.logger.Warningf("Could not send %s event: %v", ev.Type, err)
+	}
+
+	var sinks []filteredSink
+	for _, s := range slices.Concat(e.sinks, extraSinks) {
+		if s.cfg.Accepts(ev.Type) {
+			sinks = append(sinks, s)
+		}
+	}
+	if len(sinks) == 0 {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return
+	}
+	select {
+	case e.queue <- delivery{ev: ev, sinks: sinks}:
+	default:
+		e.logger.Warningf("Dropped %s event, the event sinks can't keep up", ev.Type)
+	}
+}
+
+func (e *Emitter) run() {
+	defer close(e.done)
+
+	for d := range e.queue {
+		for _, s := range d.sinks {
+			if err := s.sink.Send(context.Background(), d.ev); err != nil {
+				e.logger.Warningf("Could not send %s event: %v", d.ev.Type, err)
+			}
+		}
+	}
+}
+
+// Close stops accepting events and waits until the pending ones are sent or the
+// context is done. A nil emitter has nothing to close.
+func (e *Emitter) Close(ctx context.Context) error {
+	if e == nil {
+		return nil
+	}
+
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.queue)
+	}
+	e.mu.Unlock()
+
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("pending events not sent: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,319 @@
+package events_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/events"
+	"github.com/slok/sbx/internal/model"
+)
+
+func testEvent() model.Event {
+	return model.Event{
+		Time:        time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Type:        model.EventTypeEgressDenied,
+		SandboxID:   "01SB0000000000000000000000",
+		SandboxName: "sb",
+		Namespace:   "team-a",
+		Attributes:  map[string]string{"domain": "evil.test", "protocol": "tls"},
+	}
+}
+
+func TestNewSink(t *testing.T) {
+	tests := map[string]struct {
+		cfg    model.EventSinkConfig
+		expErr bool
+	}{
+		"A file sink should be valid.": {
+			cfg: model.EventSinkConfig{Type: model.EventSinkTypeFile, Path: "/var/log/sbx/events.jsonl"},
+		},
+		"A file sink without path should fail.": {
+			cfg:    model.EventSinkConfig{Type: model.EventSinkTypeFile},
+			expErr: true,
+		},
+		"A file sink with a relative path should fail.": {
+			cfg:    model.EventSinkConfig{Type: model.EventSinkTypeFile, Path: "events.jsonl"},
+			expErr: true,
+		},
+		"A journald sink should be valid.": {
+			cfg: model.EventSinkConfig{Type: model.EventSinkTypeJournald},
+		},
+		"A webhook sink should be valid.": {
+			cfg: model.EventSinkConfig{Type: model.EventSinkTypeWebhook, URL: "https://siem.example.com/ingest"},
+		},
+		"A webhook sink without an HTTP URL should fail.": {
+			cfg:    model.EventSinkConfig{Type: model.EventSinkTypeWebhook, URL: "ftp://siem.example.com"},
+			expErr: true,
+		},
+		"An unknown sink should fail.": {
+			cfg:    model.EventSinkConfig{Type: "kafka"},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := events.NewSink(test.cfg)
+
+			if test.expErr {
+				assert.ErrorIs(t, err, model.ErrNotValid)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSinkConfigsRoundTrip(t *testing.T) {
+	require := require.New(t)
+
+	cfgs := []model.EventSinkConfig{
+		{Type: model.EventSinkTypeWebhook, URL: "https://siem.example.com/ingest", Secret: "s3cret", Events: []model.EventType{model.EventTypeEgressDenied}},
+		{Type: model.EventSinkTypeJournald},
+	}
+	got, err := events.ParseSinkConfigs(events.MarshalSinkConfigs(cfgs))
+	require.NoError(err)
+	assert.Equal(t, cfgs, got)
+
+	got, err = events.ParseSinkConfigs("")
+	require.NoError(err)
+	assert.Nil(t, got)
+
+	_, err = events.ParseSinkConfigs(`[{"type":"file"}]`)
+	assert.ErrorIs(t, err, model.ErrNotValid)
+
+	_, err = events.ParseSinkConfigs(`{"type":"journald"}`)
+	assert.ErrorIs(t, err, model.ErrNotValid)
+}
+
+func TestFileSink(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "logs", "events.jsonl")
+	sink, err := events.NewFileSink(path)
+	require.NoError(err)
+
+	ev := testEvent()
+	require.NoError(sink.Send(context.TODO(), ev))
+	require.NoError(sink.Send(context.TODO(), ev))
+
+	data, err := os.ReadFile(path)
+	require.NoError(err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(lines, 2)
+	assert.Equal(`{"time":"2026-01-02T03:04:05Z","type":"egress.denied","sandbox_id":"01SB0000000000000000000000","sandbox_name":"sb","namespace":"team-a","attributes":{"domain":"evil.test","protocol":"tls"}}`, lines[0])
+
+	got, err := events.UnmarshalEvent([]byte(lines[1]))
+	require.NoError(err)
+	assert.Equal(ev, got)
+}
+
+func TestWebhookSink(t *testing.T) {
+	tests := map[string]struct {
+		secret      string
+		statuses    []int
+		expRequests int
+		expErr      bool
+	}{
+		"A 2xx response should send the event.": {
+			statuses:    []int{http.StatusAccepted},
+			expRequests: 1,
+		},
+		"A secret should sign the event.": {
+			secret:      "s3cret",
+			statuses:    []int{http.StatusOK},
+			expRequests: 1,
+		},
+		"A 5xx response should be retried.": {
+			statuses:    []int{http.StatusBadGateway, http.StatusTooManyRequests, http.StatusOK},
+			expRequests: 3,
+		},
+		"A 4xx response should fail without retries.": {
+			statuses:    []int{http.StatusBadRequest, http.StatusOK},
+			expRequests: 1,
+			expErr:      true,
+		},
+		"A failing receiver should fail after the retries.": {
+			statuses:    []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError},
+			expRequests: 3,
+			expErr:      true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+			assert := assert.New(t)
+
+			var gotBody []byte
+			var gotHeaders []http.Header
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotBody, _ = io.ReadAll(r.Body)
+				gotHeaders = append(gotHeaders, r.Header.Clone())
+				w.WriteHeader(test.statuses[len(gotHeaders)-1])
+			}))
+			defer srv.Close()
+
+			sink, err := events.NewWebhookSink(events.WebhookSinkConfig{
+				URL:        srv.URL,
+				Secret:     test.secret,
+				MaxRetries: 2,
+				MinBackoff: time.Millisecond,
+			})
+			require.NoError(err)
+
+			err = sink.Send(context.TODO(), testEvent())
+
+			require.Len(gotHeaders, test.expRequests)
+			if test.expErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			h := gotHeaders[len(gotHeaders)-1]
+			assert.Equal("application/json", h.Get("Content-Type"))
+			assert.Equal("egress.denied", h.Get(events.WebhookEventHeader))
+			// The retries should be the same delivery.
+			for _, retry := range gotHeaders {
+				assert.Equal(gotHeaders[0].Get(events.WebhookDeliveryHeader), retry.Get(events.WebhookDeliveryHeader))
+			}
+			if test.secret != "" {
+				assert.Equal(events.Sign(test.secret, gotBody), h.Get(events.WebhookSignatureHeader))
+			} else {
+				assert.Empty(h.Get(events.WebhookSignatureHeader))
+			}
+			got, err := events.UnmarshalEvent(gotBody)
+			require.NoError(err)
+			assert.Equal(testEvent(), got)
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '{"a":1}' | openssl dgst -sha256 -hmac s3cret
+	assert.Equal(t, "sha256=5910e62016ef5034272c926c27071992a465c2335cecf41851bda071577f4f6d", events.Sign("s3cret", []byte(`{"a":1}`)))
+}
+
+// readJournalFields parses the fields of a journal native protocol datagram.
+func readJournalFields(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+
+	fields := map[string]string{}
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		require.GreaterOrEqual(t, i, 0)
+		line := string(data[:i])
+		data = data[i+1:]
+
+		if name, value, ok := strings.Cut(line, "="); ok {
+			fields[name] = value
+			continue
+		}
+		size := binary.LittleEndian.Uint64(data[:8])
+		fields[line] = string(data[8 : 8+size])
+		data = data[8+size+1:]
+	}
+	return fields
+}
+
+func TestJournaldSink(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	// Unix socket paths are short, the test temp dir may be too long.
+	dir, err := os.MkdirTemp("", "sbx-journal")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "socket")
+	conn, err := net.ListenPacket("unixgram", socket)
+	require.NoError(err)
+	defer conn.Close()
+
+	ev := testEvent()
+	ev.Attributes["error"] = "line1\nline2"
+	require.NoError(events.NewJournaldSink(socket).Send(context.TODO(), ev))
+
+	buf := make([]byte, 64*1024)
+	require.NoError(conn.SetReadDeadline(time.Now().Add(5 * time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(err)
+
+	fields := readJournalFields(t, buf[:n])
+	assert.Equal("4", fields["PRIORITY"])
+	assert.Equal("sbx", fields["SYSLOG_IDENTIFIER"])
+	assert.Equal("egress.denied", fields["SBX_EVENT_TYPE"])
+	assert.Equal("01SB0000000000000000000000", fields["SBX_SANDBOX_ID"])
+	assert.Equal("team-a", fields["SBX_NAMESPACE"])
+	assert.Equal("evil.test", fields["SBX_ATTR_DOMAIN"])
+	assert.Equal("line1\nline2", fields["SBX_ATTR_ERROR"])
+	assert.Contains(fields["MESSAGE"], "egress.denied sandbox=sb domain=evil.test")
+	assert.Contains(fields["SBX_EVENT"], `"type":"egress.denied"`)
+}
+
+func TestEmitter(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	allPath := filepath.Join(dir, "all.jsonl")
+	deniedPath := filepath.Join(dir, "denied.jsonl")
+	extraPath := filepath.Join(dir, "extra.jsonl")
+	emitter, err := events.NewEmitter([]model.EventSinkConfig{
+		// A directory can't be written as a file.
+		{Type: model.EventSinkTypeFile, Path: dir},
+		{Type: model.EventSinkTypeFile, Path: allPath},
+		{Type: model.EventSinkTypeFile, Path: deniedPath, Events: []model.EventType{model.EventTypeEgressDenied}},
+	}, nil)
+	require.NoError(err)
+	require.True(emitter.Enabled())
+
+	// A failing sink should not prevent the others from receiving the events, and
+	// the sinks should only receive their event types.
+	started := testEvent()
+	started.Type = model.EventTypeSandboxStarted
+	emitter.Emit(started, model.EventSinkConfig{Type: model.EventSinkTypeFile, Path: extraPath})
+	emitter.Emit(testEvent())
+	require.NoError(emitter.Close(context.TODO()))
+
+	// Closed emitters should discard the events.
+	emitter.Emit(testEvent())
+
+	readTypes := func(path string) []string {
+		data, err := os.ReadFile(path)
+		require.NoError(err)
+		var types []string
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			ev, err := events.UnmarshalEvent([]byte(line))
+			require.NoError(err)
+			types = append(types, string(ev.Type))
+		}
+		return types
+	}
+	assert.Equal([]string{"sandbox.started", "egress.denied"}, readTypes(allPath))
+	assert.Equal([]string{"egress.denied"}, readTypes(deniedPath))
+	assert.Equal([]string{"sandbox.started"}, readTypes(extraPath))
+
+	// A nil emitter should discard the events.
+	var nilEmitter *events.Emitter
+	assert.False(nilEmitter.Enabled())
+	nilEmitter.Emit(testEvent())
+	assert.NoError(nilEmitter.Close(context.TODO()))
+
+	_, err = events.NewEmitter([]model.EventSinkConfig{{Type: "kafka"}}, nil)
+	assert.ErrorIs(err, model.ErrNotValid)
+}
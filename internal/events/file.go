@@ -0,0 +1,61 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// FileSink appends the events as JSON lines to a file.
+//
+// The file is opened on every event in append mode, so several processes (e.g. the
+// SDK and the egress proxies) can share the file and it can be rotated externally
+// (e.g. logrotate) without signaling them.
+type FileSink struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileSink returns a file sink.
+func NewFileSink(path string) (*FileSink, error) {
+	if path == "" {
+		return nil, fmt.Errorf("the file event sink path is required: %w", model.ErrNotValid)
+	}
+	if !filepath.IsAbs(path) {
+		return nil, fmt.Errorf("the file event sink path must be absolute: %w", model.ErrNotValid)
+	}
+
+	return &FileSink{path: path}, nil
+}
+
+// Send appends an event to the file.
+func (s *FileSink) Send(_ context.Context, ev model.Event) error {
+	data, err := MarshalEvent(ev)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("could not create events directory: %w", err)
+	}
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("could not open events file: %w", err)
+	}
+	defer f.Close()
+
+	// A single write keeps the lines of concurrent writers whole.
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("could not write event: %w", err)
+	}
+
+	return nil
+}
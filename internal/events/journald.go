@@ -0,0 +1,122 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+const (
+	// DefaultJournaldSocket is the socket of the systemd journal native protocol.
+	DefaultJournaldSocket = "/run/systemd/journal/socket"
+
+	// Syslog priorities of the events.
+	journaldPriorityWarning = "4"
+	journaldPriorityInfo    = "6"
+)
+
+// JournaldSink sends the events to the systemd journal using its native protocol,
+// the event fields are journal fields (SBX_EVENT_TYPE, SBX_SANDBOX_ID...) so they
+// can be matched with journalctl (e.g. journalctl SBX_EVENT_TYPE=egress.denied).
+type JournaldSink struct {
+	socket string
+}
+
+// NewJournaldSink returns a journald sink, an empty socket uses the default one.
+func NewJournaldSink(socket string) *JournaldSink {
+	if socket == "" {
+		socket = DefaultJournaldSocket
+	}
+	return &JournaldSink{socket: socket}
+}
+
+// Send sends an event to the journal.
+func (s *JournaldSink) Send(ctx context.Context, ev model.Event) error {
+	raw, err := MarshalEvent(ev)
+	if err != nil {
+		return err
+	}
+
+	priority := journaldPriorityInfo
+	if ev.Type.Security() {
+		priority = journaldPriorityWarning
+	}
+
+	msg := string(ev.Type)
+	if ev.SandboxName != "" {
+		msg += " sandbox=" + ev.SandboxName
+	}
+	attrKeys := make([]string, 0, len(ev.Attributes))
+	for k := range ev.Attributes {
+		attrKeys = append(attrKeys, k)
+	}
+	sort.Strings(attrKeys)
+	for _, k := range attrKeys {
+		msg += fmt.Sprintf(" %s=%s", k, ev.Attributes[k])
+	}
+
+	var buf bytes.Buffer
+	writeJournalField(&buf, "MESSAGE", msg)
+	writeJournalField(&buf, "PRIORITY", priority)
+	writeJournalField(&buf, "SYSLOG_IDENTIFIER", "sbx")
+	writeJournalField(&buf, "SBX_EVENT_TYPE", string(ev.Type))
+	writeJournalField(&buf, "SBX_SANDBOX_ID", ev.SandboxID)
+	writeJournalField(&buf, "SBX_SANDBOX_NAME", ev.SandboxName)
+	writeJournalField(&buf, "SBX_NAMESPACE", ev.Namespace)
+	for _, k := range attrKeys {
+		writeJournalField(&buf, "SBX_ATTR_"+journalFieldName(k), ev.Attributes[k])
+	}
+	writeJournalField(&buf, "SBX_EVENT", string(raw))
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unixgram", s.socket)
+	if err != nil {
+		return fmt.Errorf("could not connect to the journal: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("could not send event to the journal: %w", err)
+	}
+
+	return nil
+}
+
+// writeJournalField writes a field in the journal native format, the values with
+// new lines are written with their size instead of the NAME=value form.
+func writeJournalField(buf *bytes.Buffer, name, value string) {
+	if value == "" {
+		return
+	}
+
+	if !strings.Contains(value, "\n") {
+		fmt.Fprintf(buf, "%s=%s\n", name, value)
+		return
+	}
+
+	buf.WriteString(name)
+	buf.WriteByte('\n')
+	_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// journalFieldName returns a valid journal field name ([A-Z0-9_]) of an attribute key.
+func journalFieldName(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+}
@@ -0,0 +1,152 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// Headers of the webhook requests.
+const (
+	// WebhookEventHeader is the event type.
+	WebhookEventHeader = "X-Sbx-Event"
+	// WebhookDeliveryHeader is the delivery ID, the same on the retries of an event
+	// so the receivers can drop the duplicates.
+	WebhookDeliveryHeader = "X-Sbx-Delivery"
+	// WebhookSignatureHeader is the payload HMAC-SHA256 with the webhook secret, in
+	// the sha256=<hex> format. Only set with a secret.
+	WebhookSignatureHeader = "X-Sbx-Signature-256"
+)
+
+// WebhookSinkConfig is the configuration of the webhook sink.
+type WebhookSinkConfig struct {
+	URL string
+	// Secret signs the payloads, empty doesn't sign them.
+	Secret string
+	// MaxRetries is the number of retries of the failed requests (network errors,
+	// 429 and 5xx responses), negative doesn't retry.
+	MaxRetries int
+	// MinBackoff and MaxBackoff bound the wait between retries, it doubles on every
+	// retry.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Timeout is the maximum time of a request.
+	Timeout time.Duration
+}
+
+func (c *WebhookSinkConfig) defaults() error {
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("the webhook event sink URL must be an http:// or https:// URL: %w", model.ErrNotValid)
+	}
+	if c.MaxRetries == 0 {
+		c.MaxRetries = 3
+	}
+	if c.MaxRetries < 0 {
+		c.MaxRetries = 0
+	}
+	if c.MinBackoff <= 0 {
+		c.MinBackoff = time.Second
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = 30 * time.Second
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 5 * time.Second
+	}
+	return nil
+}
+
+// WebhookSink POSTs the events as JSON to a URL, signed with its secret and retried
+// with backoff when the receiver fails.
+type WebhookSink struct {
+	cfg    WebhookSinkConfig
+	client *http.Client
+}
+
+// NewWebhookSink returns a webhook sink.
+func NewWebhookSink(cfg WebhookSinkConfig) (*WebhookSink, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, err
+	}
+
+	return &WebhookSink{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// Sign returns the signature of a payload with a webhook secret, the value of the
+// signature header.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Send POSTs an event, retrying the failed requests. Any non 2xx response is an error.
+func (s *WebhookSink) Send(ctx context.Context, ev model.Event) error {
+	data, err := MarshalEvent(ev)
+	if err != nil {
+		return err
+	}
+	delivery := ulid.MustNew(ulid.Timestamp(time.Now()), rand.Reader).String()
+
+	backoff := s.cfg.MinBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := s.post(ctx, ev, delivery, data)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= s.cfg.MaxRetries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, s.cfg.MaxBackoff)
+	}
+}
+
+// post sends the event once, returning if a failure can be retried.
+func (s *WebhookSink) post(ctx context.Context, ev model.Event, delivery string, data []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL, bytes.NewReader(data))
+	if err != nil {
+		return false, fmt.Errorf("could not create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, string(ev.Type))
+	req.Header.Set(WebhookDeliveryHeader, delivery)
+	if s.cfg.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, Sign(s.cfg.Secret, data))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("could not send webhook request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retry, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	return false, nil
+}
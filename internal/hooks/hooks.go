@@ -0,0 +1,269 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+// DefaultTimeout is the time a hook can run before it's cancelled.
+const DefaultTimeout = 30 * time.Second
+
+// maxOutputBytes is the size of the hook output included in its error.
+const maxOutputBytes = 4096
+
+// Runner runs the host hooks of the sandbox lifecycle events.
+type Runner interface {
+	// Run runs the hooks of the event in order. The hooks of the pre events stop on
+	// the first failure, the post ones all run and their failures are joined.
+	Run(ctx context.Context, event model.HookEvent, sb model.Sandbox) error
+}
+
+// Noop is a runner without hooks.
+var Noop Runner = noop{}
+
+type noop struct{}
+
+func (noop) Run(context.Context, model.HookEvent, model.Sandbox) error { return nil }
+
+// RunnerConfig is the configuration of the hooks runner.
+type RunnerConfig struct {
+	// Dir is the hooks directory: the executables of its event subdirectories (e.g.
+	// hooks/post-start/10-register-dns) run in name order before the Hooks of the
+	// event. Empty or missing directories have no hooks.
+	Dir string
+	// Hooks are the configured hooks, run in order.
+	Hooks []model.HostHook
+	// Timeout is the default timeout of the hooks (default: DefaultTimeout).
+	Timeout time.Duration
+	Logger  log.Logger
+}
+
+func (c *RunnerConfig) defaults() error {
+	for i, h := range c.Hooks {
+		if err := h.Validate(); err != nil {
+			return fmt.Errorf("invalid hook %d: %w", i, err)
+		}
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "hooks.Runner"})
+	return nil
+}
+
+// NewRunner returns a runner of the hooks directory and the configured hooks.
+func NewRunner(cfg RunnerConfig) (Runner, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if cfg.Dir == "" && len(cfg.Hooks) == 0 {
+		return Noop, nil
+	}
+	return &runner{dir: cfg.Dir, hooks: cfg.Hooks, timeout: cfg.Timeout, logger: cfg.Logger}, nil
+}
+
+type runner struct {
+	dir     string
+	hooks   []model.HostHook
+	timeout time.Duration
+	logger  log.Logger
+}
+
+func (r *runner) Run(ctx context.Context, event model.HookEvent, sb model.Sandbox) error {
+	hooks, err := r.eventHooks(event)
+	if err != nil {
+		return err
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(newPayload(event, sb))
+	if err != nil {
+		return fmt.Errorf("could not marshal hook payload: %w", err)
+	}
+
+	var errs []error
+	for _, h := range hooks {
+		start := time.Now()
+		err := r.runHook(ctx, h, sb, payload)
+		logger := r.logger.WithValues(log.Kv{"event": event, "hook": hookName(h), "sandbox": sb.Name, "duration": time.Since(start)})
+		if err == nil {
+			logger.Debugf("hook succeeded")
+			continue
+		}
+
+		err = fmt.Errorf("%s hook %s failed: %w", event, hookName(h), err)
+		if event.Pre() {
+			return err
+		}
+		logger.Warningf("hook failed: %v", err)
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// eventHooks returns the hooks of an event: the executables of the event directory
+// in name order, then the configured ones.
+func (r *runner) eventHooks(event model.HookEvent) ([]model.HostHook, error) {
+	var hooks []model.HostHook
+	if r.dir != "" {
+		dir := filepath.Join(r.dir, string(event))
+		entries, err := os.ReadDir(dir)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("could not read hooks directory: %w", err)
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+		for _, e := range entries {
+			// Hidden files (e.g. editor backups) and non executable files are skipped.
+			if strings.HasPrefix(e.Name(), ".") || e.IsDir() {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil || info.Mode().Perm()&0111 == 0 {
+				r.logger.Debugf("skipping non executable hook %s", e.Name())
+				continue
+			}
+			hooks = append(hooks, model.HostHook{Event: event, Command: []string{filepath.Join(dir, e.Name())}})
+		}
+	}
+
+	for _, h := range r.hooks {
+		if h.Event == event {
+			hooks = append(hooks, h)
+		}
+	}
+
+	return hooks, nil
+}
+
+// runHook runs a hook with its timeout.
+func (r *runner) runHook(ctx context.Context, h model.HostHook, sb model.Sandbox, payload []byte) error {
+	timeout := h.Timeout
+	if timeout == 0 {
+		timeout = r.timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if h.Func != nil {
+		return h.Func(ctx, h.Event, sb)
+	}
+
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, h.Command[0], h.Command[1:]...)
+	cmd.Env = append(os.Environ(), hookEnv(h.Event, sb)...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	setProcessGroup(cmd)
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("timed out after %s", timeout)
+		}
+		if output := lastOutput(out.String()); output != "" {
+			return fmt.Errorf("%w: %s", err, output)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// hookName returns the name of a hook in the logs and errors.
+func hookName(h model.HostHook) string {
+	if len(h.Command) > 0 {
+		return filepath.Base(h.Command[0])
+	}
+	return "func"
+}
+
+// hookEnv returns the SBX_* environment variables of a hook.
+func hookEnv(event model.HookEvent, sb model.Sandbox) []string {
+	return []string{
+		"SBX_HOOK_EVENT=" + string(event),
+		"SBX_SANDBOX_ID=" + sb.ID,
+		"SBX_SANDBOX_NAME=" + sb.Name,
+		"SBX_SANDBOX_NAMESPACE=" + sb.Namespace,
+		"SBX_SANDBOX_STATUS=" + string(sb.Status),
+		"SBX_SANDBOX_IMAGE=" + sb.Config.Image,
+		"SBX_SANDBOX_IP=" + sb.InternalIP,
+	}
+}
+
+// lastOutput returns the tail of a hook output.
+func lastOutput(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > maxOutputBytes {
+		s = "..." + s[len(s)-maxOutputBytes:]
+	}
+	return s
+}
+
+// payload is the JSON a command hook gets in its standard input.
+type payload struct {
+	Event   model.HookEvent `json:"event"`
+	Time    time.Time       `json:"time"`
+	Sandbox sandboxPayload  `json:"sandbox"`
+}
+
+type sandboxPayload struct {
+	ID          string            `json:"id,omitempty"`
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	Status      string            `json:"status,omitempty"`
+	Image       string            `json:"image,omitempty"`
+	VCPUs       float64           `json:"vcpus"`
+	MemoryMB    int               `json:"memory_mb"`
+	DiskGB      int               `json:"disk_gb"`
+	IP          string            `json:"ip,omitempty"`
+	TapDevice   string            `json:"tap_device,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Persistent  bool              `json:"persistent,omitempty"`
+	CreatedAt   *time.Time        `json:"created_at,omitempty"`
+	StartedAt   *time.Time        `json:"started_at,omitempty"`
+}
+
+func newPayload(event model.HookEvent, sb model.Sandbox) payload {
+	p := payload{
+		Event: event,
+		Time:  time.Now().UTC(),
+		Sandbox: sandboxPayload{
+			ID:          sb.ID,
+			Name:        sb.Name,
+			Namespace:   sb.Namespace,
+			Status:      string(sb.Status),
+			Image:       sb.Config.Image,
+			VCPUs:       sb.Config.Resources.VCPUs,
+			MemoryMB:    sb.Config.Resources.MemoryMB,
+			DiskGB:      sb.Config.Resources.DiskGB,
+			IP:          sb.InternalIP,
+			TapDevice:   sb.TapDevice,
+			Annotations: sb.Annotations,
+			Persistent:  sb.Persistent,
+			StartedAt:   sb.StartedAt,
+		},
+	}
+	if !sb.CreatedAt.IsZero() {
+		createdAt := sb.CreatedAt.UTC()
+		p.Sandbox.CreatedAt = &createdAt
+	}
+	return p
+}
@@ -0,0 +1,174 @@
+package hooks_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/hooks"
+	"github.com/slok/sbx/internal/model"
+)
+
+func testSandbox() model.Sandbox {
+	return model.Sandbox{
+		ID:         "01SB0000000000000000000000",
+		Name:       "sb",
+		Namespace:  "team-a",
+		Status:     model.SandboxStatusRunning,
+		InternalIP: "10.163.0.2",
+		Config: model.SandboxConfig{
+			Name:      "sb",
+			Image:     "ubuntu-24.04",
+			Resources: model.Resources{VCPUs: 2, MemoryMB: 1024, DiskGB: 10},
+		},
+		Annotations: map[string]string{"owner": "ci"},
+	}
+}
+
+// writeHook writes an executable shell hook into the event directory of the hooks dir.
+func writeHook(t *testing.T, dir string, event model.HookEvent, name, script string, mode os.FileMode) {
+	t.Helper()
+	eventDir := filepath.Join(dir, string(event))
+	require.NoError(t, os.MkdirAll(eventDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(eventDir, name), []byte("#!/bin/sh\n"+script), mode))
+}
+
+func TestRunnerDirHooks(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+
+	// The hooks run in name order, skipping the hidden and non executable files.
+	writeHook(t, dir, model.HookEventPostStart, "20-env", `echo "$SBX_HOOK_EVENT $SBX_SANDBOX_ID $SBX_SANDBOX_NAME $SBX_SANDBOX_NAMESPACE $SBX_SANDBOX_IP" >> `+out+"\n", 0o755)
+	writeHook(t, dir, model.HookEventPostStart, "10-json", `cat > `+out+".json\n", 0o755)
+	writeHook(t, dir, model.HookEventPostStart, "30-disabled", "echo disabled >> "+out+"\n", 0o644)
+	writeHook(t, dir, model.HookEventPostStart, ".backup", "echo hidden >> "+out+"\n", 0o755)
+	writeHook(t, dir, model.HookEventPreStop, "10-other", "echo other >> "+out+"\n", 0o755)
+
+	r, err := hooks.NewRunner(hooks.RunnerConfig{Dir: dir})
+	require.NoError(t, err)
+
+	require.NoError(t, r.Run(context.Background(), model.HookEventPostStart, testSandbox()))
+
+	got, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "post-start 01SB0000000000000000000000 sb team-a 10.163.0.2\n", string(got))
+
+	var payload struct {
+		Event   string `json:"event"`
+		Sandbox struct {
+			ID          string            `json:"id"`
+			Name        string            `json:"name"`
+			Image       string            `json:"image"`
+			VCPUs       float64           `json:"vcpus"`
+			MemoryMB    int               `json:"memory_mb"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"sandbox"`
+	}
+	gotJSON, err := os.ReadFile(out + ".json")
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(gotJSON, &payload))
+	assert.Equal(t, "post-start", payload.Event)
+	assert.Equal(t, "01SB0000000000000000000000", payload.Sandbox.ID)
+	assert.Equal(t, "sb", payload.Sandbox.Name)
+	assert.Equal(t, "ubuntu-24.04", payload.Sandbox.Image)
+	assert.Equal(t, 2.0, payload.Sandbox.VCPUs)
+	assert.Equal(t, 1024, payload.Sandbox.MemoryMB)
+	assert.Equal(t, map[string]string{"owner": "ci"}, payload.Sandbox.Annotations)
+}
+
+func TestRunnerFailures(t *testing.T) {
+	tests := map[string]struct {
+		event     model.HookEvent
+		expCalls  int
+		expErrMsg string
+	}{
+		"A failed pre hook should stop the next hooks and return its error.": {
+			event:     model.HookEventPreCreate,
+			expCalls:  0,
+			expErrMsg: "pre-create hook 10-fail failed: exit status 3: no dns",
+		},
+
+		"A failed post hook should run the next hooks and return its error.": {
+			event:     model.HookEventPostRemove,
+			expCalls:  1,
+			expErrMsg: "post-remove hook 10-fail failed: exit status 3: no dns",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeHook(t, dir, test.event, "10-fail", "echo no dns >&2\nexit 3\n", 0o755)
+
+			calls := 0
+			r, err := hooks.NewRunner(hooks.RunnerConfig{
+				Dir: dir,
+				Hooks: []model.HostHook{{
+					Event: test.event,
+					Func: func(_ context.Context, event model.HookEvent, sb model.Sandbox) error {
+						calls++
+						return nil
+					},
+				}},
+			})
+			require.NoError(t, err)
+
+			err = r.Run(context.Background(), test.event, testSandbox())
+			assert.EqualError(t, err, test.expErrMsg)
+			assert.Equal(t, test.expCalls, calls)
+		})
+	}
+}
+
+func TestRunnerFuncHooks(t *testing.T) {
+	var got []string
+	hook := func(name string, err error) model.HookFunc {
+		return func(_ context.Context, event model.HookEvent, sb model.Sandbox) error {
+			got = append(got, name+":"+string(event)+":"+sb.Name)
+			return err
+		}
+	}
+
+	r, err := hooks.NewRunner(hooks.RunnerConfig{
+		Hooks: []model.HostHook{
+			{Event: model.HookEventPreStop, Func: hook("first", nil)},
+			{Event: model.HookEventPostStart, Func: hook("other", nil)},
+			{Event: model.HookEventPreStop, Func: hook("second", errors.New("inventory down"))},
+		},
+	})
+	require.NoError(t, err)
+
+	err = r.Run(context.Background(), model.HookEventPreStop, testSandbox())
+	assert.EqualError(t, err, "pre-stop hook func failed: inventory down")
+	assert.Equal(t, []string{"first:pre-stop:sb", "second:pre-stop:sb"}, got)
+}
+
+func TestRunnerTimeout(t *testing.T) {
+	dir := t.TempDir()
+	writeHook(t, dir, model.HookEventPreStop, "10-slow", "sleep 5\n", 0o755)
+
+	r, err := hooks.NewRunner(hooks.RunnerConfig{Dir: dir, Timeout: 100 * time.Millisecond})
+	require.NoError(t, err)
+
+	err = r.Run(context.Background(), model.HookEventPreStop, testSandbox())
+	assert.EqualError(t, err, "pre-stop hook 10-slow failed: timed out after 100ms")
+}
+
+func TestNewRunnerInvalidHook(t *testing.T) {
+	_, err := hooks.NewRunner(hooks.RunnerConfig{Hooks: []model.HostHook{{Event: "post-create", Command: []string{"true"}}}})
+	assert.ErrorIs(t, err, model.ErrNotValid)
+}
+
+func TestRunnerMissingDir(t *testing.T) {
+	r, err := hooks.NewRunner(hooks.RunnerConfig{Dir: filepath.Join(t.TempDir(), "missing")})
+	require.NoError(t, err)
+
+	assert.NoError(t, r.Run(context.Background(), model.HookEventPostStart, testSandbox()))
+}
@@ -0,0 +1,9 @@
+package hooks
+
+import "os/exec"
+
+// setProcessGroup kills the hook process on timeout, the processes it started are
+// not killed on Windows.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.Cancel = func() error { return cmd.Process.Kill() }
+}
@@ -0,0 +1,123 @@
+// Package hostlock coordinates the clients (processes with their own database) that
+// share a data directory, so they don't change the same host resources (VM
+// directories, TAP devices, nftables) at the same time.
+//
+// The locks are advisory file locks, released by the kernel if the process dies. The
+// lock files have the holder of the lock, so the busy errors can tell who has it.
+package hostlock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// ErrLocked is returned when the file is locked by another process.
+var ErrLocked = errors.New("file is locked")
+
+// retryInterval is the time between the tries of a waiting lock.
+const retryInterval = 50 * time.Millisecond
+
+// Manager takes the host resource locks of a data directory.
+type Manager struct {
+	dir    string
+	dbPath string
+}
+
+// NewManager returns a lock manager with the lock files in dir. The database path
+// identifies the client in the holder of its locks (optional).
+func NewManager(dir, dbPath string) *Manager {
+	return &Manager{dir: dir, dbPath: dbPath}
+}
+
+// Dir returns the directory of the lock files.
+func (m *Manager) Dir() string { return m.dir }
+
+// TryLock takes the lock of a resource without waiting, it returns a
+// model.BusyError when another client holds it. The returned func releases it.
+func (m *Manager) TryLock(resource, operation string) (unlock func(), err error) {
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create host locks directory: %w", err)
+	}
+
+	path := m.lockPath(resource)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open lock file: %w", err)
+	}
+
+	if err := TryLockFile(f); err != nil {
+		f.Close()
+		if errors.Is(err, ErrLocked) {
+			return nil, &model.BusyError{Resource: resource, Holder: readHolder(path)}
+		}
+		return nil, fmt.Errorf("could not lock %s: %w", resource, err)
+	}
+
+	// The holder is informative, the lock is held even if it can't be written.
+	hostname, _ := os.Hostname()
+	data, _ := json.Marshal(model.LockHolder{
+		PID:       os.Getpid(),
+		Hostname:  hostname,
+		DBPath:    m.dbPath,
+		Operation: operation,
+		Since:     time.Now().UTC(),
+	})
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt(data, 0)
+	}
+
+	return func() {
+		// Closing the file releases the lock.
+		_ = f.Truncate(0)
+		_ = f.Close()
+	}, nil
+}
+
+// Lock takes the lock of a resource, waiting for the other clients to release it
+// until the context is done. Then it returns a model.BusyError with the holder.
+func (m *Manager) Lock(ctx context.Context, resource, operation string) (unlock func(), err error) {
+	ticker := time.NewTicker(retryInterval)
+	defer ticker.Stop()
+
+	for {
+		unlock, err := m.TryLock(resource, operation)
+		var busyErr *model.BusyError
+		if !errors.As(err, &busyErr) {
+			return unlock, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, busyErr
+		case <-ticker.C:
+		}
+	}
+}
+
+// lockPath returns the lock file of a resource, the resource path separators
+// (e.g. vm/01J...) are flattened.
+func (m *Manager) lockPath(resource string) string {
+	return filepath.Join(m.dir, strings.ReplaceAll(resource, "/", "-")+".lock")
+}
+
+// readHolder returns the holder written in a lock file, nil if it can't be read
+// (e.g. the holder has not written it yet).
+func readHolder(path string) *model.LockHolder {
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	var h model.LockHolder
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil
+	}
+	return &h
+}
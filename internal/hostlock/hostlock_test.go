@@ -0,0 +1,73 @@
+package hostlock_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/hostlock"
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestManagerTryLock(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	// Two managers on the same directory act like two clients with their own database.
+	dir := t.TempDir()
+	m1 := hostlock.NewManager(dir, "/data/a.db")
+	m2 := hostlock.NewManager(dir, "/data/b.db")
+
+	unlock, err := m1.TryLock("vm/sb-1", "start")
+	require.NoError(err)
+
+	// The locked resource should be busy with the holder, other resources should be lockable.
+	_, err = m2.TryLock("vm/sb-1", "remove")
+	assert.ErrorIs(err, model.ErrBusy)
+	var busyErr *model.BusyError
+	require.True(errors.As(err, &busyErr))
+	assert.Equal("vm/sb-1", busyErr.Resource)
+	require.NotNil(busyErr.Holder)
+	assert.Equal(os.Getpid(), busyErr.Holder.PID)
+	assert.Equal("/data/a.db", busyErr.Holder.DBPath)
+	assert.Equal("start", busyErr.Holder.Operation)
+
+	unlock2, err := m2.TryLock("vm/sb-2", "start")
+	require.NoError(err)
+	unlock2()
+
+	// Once released it should be lockable again.
+	unlock()
+	unlock, err = m2.TryLock("vm/sb-1", "remove")
+	require.NoError(err)
+	unlock()
+}
+
+func TestManagerLock(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	m1 := hostlock.NewManager(dir, "")
+	m2 := hostlock.NewManager(dir, "")
+
+	unlock, err := m1.TryLock("nftables", "start")
+	require.NoError(err)
+
+	// While held, the wait should end with the context.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = m2.Lock(ctx, "nftables", "stop")
+	assert.ErrorIs(err, model.ErrBusy)
+
+	// The wait should take the lock once released.
+	time.AfterFunc(100*time.Millisecond, unlock)
+	unlock, err = m2.Lock(context.Background(), "nftables", "stop")
+	require.NoError(err)
+	unlock()
+}
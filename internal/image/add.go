@@ -0,0 +1,254 @@
+package image
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+// LocalImageAdderConfig configures the local image adder.
+type LocalImageAdderConfig struct {
+	// ImagesDir is the local directory for storing images.
+	ImagesDir string
+	// Logger for logging.
+	Logger log.Logger
+}
+
+func (c *LocalImageAdderConfig) defaults() error {
+	if c.ImagesDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("could not get user home dir: %w", err)
+		}
+		c.ImagesDir = filepath.Join(home, DefaultImagesDir)
+	}
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	return nil
+}
+
+// LocalImageAdder implements ImageAdder using the local filesystem. The added
+// artifacts are copied into the image directory with the same layout as the
+// pulled releases, and a manifest is generated for them.
+type LocalImageAdder struct {
+	imagesDir string
+	logger    log.Logger
+}
+
+// NewLocalImageAdder creates a new local image adder.
+func NewLocalImageAdder(cfg LocalImageAdderConfig) (*LocalImageAdder, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &LocalImageAdder{
+		imagesDir: cfg.ImagesDir,
+		logger:    cfg.Logger,
+	}, nil
+}
+
+func (a *LocalImageAdder) Add(ctx context.Context, opts AddImageOptions) error {
+	if err := model.ValidateImageName(opts.Name); err != nil {
+		return fmt.Errorf("invalid image name: %w", err)
+	}
+	if err := checkArtifact(opts.KernelSrc, "kernel"); err != nil {
+		return err
+	}
+	if err := checkArtifact(opts.RootFSSrc, "rootfs"); err != nil {
+		return err
+	}
+	if err := checkExt4(opts.RootFSSrc); err != nil {
+		return err
+	}
+	if opts.FirecrackerSrc != "" {
+		if err := checkArtifact(opts.FirecrackerSrc, "firecracker binary"); err != nil {
+			return err
+		}
+	}
+
+	versionDir := filepath.Join(a.imagesDir, opts.Name)
+	if _, err := os.Stat(versionDir); err == nil && !opts.Force {
+		return fmt.Errorf("image %q already exists: %w", opts.Name, model.ErrAlreadyExists)
+	}
+
+	// Stage the image so a failed add never leaves a half installed image.
+	partial := filepath.Join(a.imagesDir, partialDir)
+	if err := os.MkdirAll(partial, 0o755); err != nil {
+		return fmt.Errorf("creating staging directory: %w", err)
+	}
+	stagingDir, err := os.MkdirTemp(partial, "add-")
+	if err != nil {
+		return fmt.Errorf("creating staging directory: %w", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	arch := HostArch()
+	kernelFile := fmt.Sprintf("vmlinux-%s", arch)
+	rootfsFile := fmt.Sprintf("rootfs-%s.ext4", arch)
+
+	if err := copyFile(opts.KernelSrc, filepath.Join(stagingDir, kernelFile)); err != nil {
+		return fmt.Errorf("copying kernel: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := copyFile(opts.RootFSSrc, filepath.Join(stagingDir, rootfsFile)); err != nil {
+		return fmt.Errorf("copying rootfs: %w", err)
+	}
+	if opts.FirecrackerSrc != "" {
+		fcDst := filepath.Join(stagingDir, "firecracker")
+		if err := copyFile(opts.FirecrackerSrc, fcDst); err != nil {
+			return fmt.Errorf("copying firecracker binary: %w", err)
+		}
+		if err := os.Chmod(fcDst, 0o755); err != nil {
+			return fmt.Errorf("chmod firecracker binary: %w", err)
+		}
+	}
+
+	kernelMeta, err := artifactMeta(filepath.Join(stagingDir, kernelFile))
+	if err != nil {
+		return fmt.Errorf("kernel: %w", err)
+	}
+	rootfsMeta, err := artifactMeta(filepath.Join(stagingDir, rootfsFile))
+	if err != nil {
+		return fmt.Errorf("rootfs: %w", err)
+	}
+
+	kernelSrc, _ := filepath.Abs(opts.KernelSrc)
+	rootfsSrc, _ := filepath.Abs(opts.RootFSSrc)
+	mj := manifestJSON{
+		SchemaVersion: model.CurrentSchemaVersion,
+		Version:       opts.Name,
+		Artifacts: map[string]archArtifactsJSON{
+			arch: {
+				Kernel: kernelJSON{
+					File:      kernelFile,
+					Version:   opts.KernelVersion,
+					Source:    "local",
+					SizeBytes: kernelMeta.size,
+					SHA256:    kernelMeta.digest,
+				},
+				Rootfs: rootfsJSON{
+					File:          rootfsFile,
+					Distro:        opts.Distro,
+					DistroVersion: opts.DistroVersion,
+					Profile:       opts.Profile,
+					SizeBytes:     rootfsMeta.size,
+					SHA256:        rootfsMeta.digest,
+				},
+			},
+		},
+		Build: buildJSON{
+			Date: time.Now().UTC().Format(time.RFC3339),
+		},
+		Local: &localInfoJSON{
+			KernelSource: kernelSrc,
+			RootFSSource: rootfsSrc,
+			AddedAt:      time.Now().UTC().Format(time.RFC3339),
+		},
+	}
+
+	manifestData, err := json.MarshalIndent(mj, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(stagingDir, "manifest.json"), manifestData, 0o644); err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+
+	if _, err := os.Stat(versionDir); err == nil {
+		if err := os.RemoveAll(versionDir); err != nil {
+			return fmt.Errorf("removing existing image: %w", err)
+		}
+	}
+	if err := os.Rename(stagingDir, versionDir); err != nil {
+		return fmt.Errorf("installing image: %w", err)
+	}
+	if err := os.Chmod(versionDir, 0o755); err != nil {
+		return fmt.Errorf("setting image directory permissions: %w", err)
+	}
+
+	// Deduplicate the artifacts shared with other images (e.g. a released kernel).
+	digests := map[string]string{kernelFile: kernelMeta.digest, rootfsFile: rootfsMeta.digest, "firecracker": ""}
+	for file, digest := range digests {
+		path := filepath.Join(versionDir, file)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if err := storeBlob(a.imagesDir, path, digest, a.logger); err != nil {
+			a.logger.Warningf("Could not deduplicate %s: %v", file, err)
+		}
+	}
+	if err := gcBlobs(a.imagesDir, a.logger); err != nil {
+		a.logger.Warningf("Could not remove unused blobs: %v", err)
+	}
+
+	a.logger.Infof("Added local image %s", opts.Name)
+	return nil
+}
+
+type fileMeta struct {
+	size   int64
+	digest string
+}
+
+// artifactMeta returns the size and SHA256 hex digest of an artifact.
+func artifactMeta(path string) (fileMeta, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileMeta{}, fmt.Errorf("stat: %w", err)
+	}
+	digest, err := fileDigest(path)
+	if err != nil {
+		return fileMeta{}, err
+	}
+	return fileMeta{size: info.Size(), digest: digest}, nil
+}
+
+// checkArtifact checks that an artifact source is a non empty regular file.
+func checkArtifact(path, kind string) error {
+	if path == "" {
+		return fmt.Errorf("%s path is required: %w", kind, model.ErrNotValid)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("%s %s does not exist: %w", kind, path, model.ErrNotValid)
+	}
+	if !info.Mode().IsRegular() || info.Size() == 0 {
+		return fmt.Errorf("%s %s is not a non empty regular file: %w", kind, path, model.ErrNotValid)
+	}
+	return nil
+}
+
+// ext4 superblock magic, at offset 0x38 of the superblock that starts at byte 1024.
+const (
+	ext4MagicOffset = 1024 + 0x38
+	ext4Magic       = 0xEF53
+)
+
+// checkExt4 checks that the rootfs is an ext2/3/4 filesystem image, the format
+// Firecracker boots from.
+func checkExt4(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening rootfs: %w", err)
+	}
+	defer f.Close()
+
+	magic := make([]byte, 2)
+	if _, err := f.ReadAt(magic, ext4MagicOffset); err != nil && err != io.EOF {
+		return fmt.Errorf("reading rootfs: %w", err)
+	}
+	if binary.LittleEndian.Uint16(magic) != ext4Magic {
+		return fmt.Errorf("rootfs %s is not an ext4 filesystem image: %w", path, model.ErrNotValid)
+	}
+	return nil
+}
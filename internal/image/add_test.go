@@ -0,0 +1,126 @@
+package image_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/model"
+)
+
+// writeTestExt4 creates a fake rootfs with the ext4 superblock magic.
+func writeTestExt4(t *testing.T, dir string) string {
+	t.Helper()
+	data := make([]byte, 2048)
+	data[1024+0x38] = 0x53
+	data[1024+0x39] = 0xEF
+	path := filepath.Join(dir, "rootfs.ext4")
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+	return path
+}
+
+func TestLocalImageAdderAdd(t *testing.T) {
+	srcDir := t.TempDir()
+	kernelSrc := filepath.Join(srcDir, "vmlinux")
+	require.NoError(t, os.WriteFile(kernelSrc, []byte("fake-kernel-data"), 0o644))
+	rootfsSrc := writeTestExt4(t, srcDir)
+	notExt4 := filepath.Join(srcDir, "rootfs.img")
+	require.NoError(t, os.WriteFile(notExt4, []byte("fake-rootfs-data"), 0o644))
+
+	tests := map[string]struct {
+		setup      func(t *testing.T, imagesDir string)
+		opts       image.AddImageOptions
+		expErr     error
+		assertions func(t *testing.T, imagesDir string)
+	}{
+		"Adding an image should install the artifacts with a generated manifest.": {
+			opts: image.AddImageOptions{Name: "my-image", KernelSrc: kernelSrc, RootFSSrc: rootfsSrc, KernelVersion: "6.1.0", Distro: "debian"},
+			assertions: func(t *testing.T, imagesDir string) {
+				mgr, err := image.NewLocalImageManager(image.LocalImageManagerConfig{ImagesDir: imagesDir})
+				require.NoError(t, err)
+
+				manifest, err := mgr.GetManifest(context.Background(), "my-image")
+				require.NoError(t, err)
+				a, err := manifest.ArtifactsFor(image.HostArch())
+				require.NoError(t, err)
+				assert.Equal(t, "6.1.0", a.Kernel.Version)
+				assert.Equal(t, int64(16), a.Kernel.SizeBytes)
+				assert.NotEmpty(t, a.Kernel.SHA256)
+				assert.Equal(t, "debian", a.Rootfs.Distro)
+				require.NotNil(t, manifest.Local)
+				assert.Equal(t, kernelSrc, manifest.Local.KernelSource)
+				assert.Equal(t, rootfsSrc, manifest.Local.RootFSSource)
+
+				data, err := os.ReadFile(mgr.KernelPath("my-image"))
+				require.NoError(t, err)
+				assert.Equal(t, "fake-kernel-data", string(data))
+
+				imgs, err := mgr.List(context.Background())
+				require.NoError(t, err)
+				require.Len(t, imgs, 1)
+				assert.Equal(t, model.ImageSourceLocal, imgs[0].Source)
+			},
+		},
+
+		"Adding an existing image should fail.": {
+			setup: func(t *testing.T, imagesDir string) {
+				require.NoError(t, os.MkdirAll(filepath.Join(imagesDir, "my-image"), 0o755))
+			},
+			opts:   image.AddImageOptions{Name: "my-image", KernelSrc: kernelSrc, RootFSSrc: rootfsSrc},
+			expErr: model.ErrAlreadyExists,
+		},
+
+		"Adding an existing image with force should replace it.": {
+			setup: func(t *testing.T, imagesDir string) {
+				require.NoError(t, os.MkdirAll(filepath.Join(imagesDir, "my-image"), 0o755))
+				require.NoError(t, os.WriteFile(filepath.Join(imagesDir, "my-image", "old"), []byte("x"), 0o644))
+			},
+			opts: image.AddImageOptions{Name: "my-image", KernelSrc: kernelSrc, RootFSSrc: rootfsSrc, Force: true},
+			assertions: func(t *testing.T, imagesDir string) {
+				assertFileExists(t, filepath.Join(imagesDir, "my-image", "manifest.json"))
+				_, err := os.Stat(filepath.Join(imagesDir, "my-image", "old"))
+				assert.True(t, os.IsNotExist(err))
+			},
+		},
+
+		"Adding a rootfs that is not ext4 should fail.": {
+			opts:   image.AddImageOptions{Name: "my-image", KernelSrc: kernelSrc, RootFSSrc: notExt4},
+			expErr: model.ErrNotValid,
+		},
+
+		"Adding a missing kernel should fail.": {
+			opts:   image.AddImageOptions{Name: "my-image", KernelSrc: filepath.Join(srcDir, "missing"), RootFSSrc: rootfsSrc},
+			expErr: model.ErrNotValid,
+		},
+
+		"Adding an image with an invalid name should fail.": {
+			opts:   image.AddImageOptions{Name: "../bad", KernelSrc: kernelSrc, RootFSSrc: rootfsSrc},
+			expErr: model.ErrNotValid,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			imagesDir := t.TempDir()
+			if tc.setup != nil {
+				tc.setup(t, imagesDir)
+			}
+
+			a, err := image.NewLocalImageAdder(image.LocalImageAdderConfig{ImagesDir: imagesDir})
+			require.NoError(t, err)
+
+			err = a.Add(context.Background(), tc.opts)
+			if tc.expErr != nil {
+				assert.ErrorIs(t, err, tc.expErr)
+				return
+			}
+			require.NoError(t, err)
+			tc.assertions(t, imagesDir)
+		})
+	}
+}
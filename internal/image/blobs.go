@@ -0,0 +1,157 @@
+package image
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/slok/sbx/internal/log"
+	fileutil "github.com/slok/sbx/internal/utils/file"
+)
+
+// blobsDir is the images dir subdirectory holding the content-addressed artifacts.
+// Image directories reference blobs with hard links, so artifacts shared by multiple
+// images (e.g. the same kernel) are stored once, while the image artifact paths keep
+// working as regular files.
+const blobsDir = ".blobs"
+
+// blobPath returns the path of a blob by its SHA256 hex digest.
+func blobPath(imagesDir, digest string) string {
+	return filepath.Join(imagesDir, blobsDir, "sha256", digest)
+}
+
+// storeBlob moves an image artifact into the blob store and links it back to its
+// path, reusing the existing blob when the same content is already stored. digest
+// is the known SHA256 hex digest of the artifact (computed when empty).
+//
+// Artifacts that can't be linked (e.g. images dir spanning filesystems) are kept
+// as regular files.
+func storeBlob(imagesDir, path, digest string, logger log.Logger) error {
+	if digest == "" {
+		d, err := fileDigest(path)
+		if err != nil {
+			return err
+		}
+		digest = d
+	}
+	digest = strings.TrimPrefix(strings.ToLower(digest), "sha256:")
+
+	blob := blobPath(imagesDir, digest)
+	if err := os.MkdirAll(filepath.Dir(blob), 0o755); err != nil {
+		return fmt.Errorf("creating blobs directory: %w", err)
+	}
+
+	// New content, the artifact becomes the blob.
+	if _, err := os.Stat(blob); os.IsNotExist(err) {
+		if err := os.Link(path, blob); err != nil {
+			logger.Warningf("Could not store %s as a blob, keeping it as a regular file: %v", path, err)
+		}
+		return nil
+	}
+
+	// Known content, replace the artifact with a link to the stored blob.
+	tmp := path + ".link"
+	os.Remove(tmp)
+	if err := os.Link(blob, tmp); err != nil {
+		logger.Warningf("Could not link %s to its blob, keeping it as a regular file: %v", path, err)
+		return nil
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("linking %s to blob: %w", path, err)
+	}
+
+	return nil
+}
+
+// gcBlobs removes the blobs that no image references anymore.
+func gcBlobs(imagesDir string, logger log.Logger) error {
+	blobs, err := filepath.Glob(filepath.Join(imagesDir, blobsDir, "sha256", "*"))
+	if err != nil || len(blobs) == 0 {
+		return err
+	}
+
+	files, err := imageFiles(imagesDir)
+	if err != nil {
+		return err
+	}
+
+	for _, blob := range blobs {
+		info, err := os.Stat(blob)
+		if err != nil {
+			continue
+		}
+
+		referenced := false
+		for _, f := range files {
+			if os.SameFile(info, f.info) {
+				referenced = true
+				break
+			}
+		}
+		if referenced {
+			continue
+		}
+
+		if err := os.Remove(blob); err != nil {
+			return fmt.Errorf("removing unreferenced blob %s: %w", blob, err)
+		}
+		logger.Debugf("Removed unreferenced blob %s", filepath.Base(blob))
+	}
+
+	return nil
+}
+
+type imageFile struct {
+	image string
+	path  string
+	info  os.FileInfo
+}
+
+// imageFiles returns the regular files of all the installed images.
+func imageFiles(imagesDir string) ([]imageFile, error) {
+	entries, err := os.ReadDir(imagesDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading images directory: %w", err)
+	}
+
+	var files []imageFile
+	for _, entry := range entries {
+		// Skip internal directories (blobs, partial pulls...).
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		dir := filepath.Join(imagesDir, entry.Name())
+		fileEntries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("reading image directory %s: %w", dir, err)
+		}
+		for _, fe := range fileEntries {
+			if !fe.Type().IsRegular() {
+				continue
+			}
+			path := filepath.Join(dir, fe.Name())
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil, fmt.Errorf("stat %s: %w", path, err)
+			}
+			files = append(files, imageFile{image: entry.Name(), path: path, info: info})
+		}
+	}
+
+	return files, nil
+}
+
+// allocatedSize returns the real disk usage of a file (excluding sparse holes).
+func allocatedSize(path string) int64 {
+	_, allocated, err := fileutil.SizeStats(path)
+	if err != nil {
+		return 0
+	}
+	return allocated
+}
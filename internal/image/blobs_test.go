@@ -0,0 +1,109 @@
+package image_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/image"
+)
+
+func TestImageBlobsDeduplication(t *testing.T) {
+	kernelData := []byte("shared-kernel-binary-data")
+	fcBinaryData := []byte("shared-firecracker-binary")
+	rootfsData := map[string][]byte{
+		"v0.1.0": []byte("rootfs-v0.1.0-data"),
+		"v0.2.0": []byte("rootfs-v0.2.0-data-bigger"),
+	}
+
+	manifest := func(version string) map[string]any {
+		return map[string]any{
+			"schema_version": 1,
+			"version":        version,
+			"artifacts": map[string]any{
+				"x86_64": map[string]any{
+					"kernel": map[string]any{"file": "vmlinux-x86_64", "version": "6.1", "source": "test", "size_bytes": len(kernelData)},
+					"rootfs": map[string]any{"file": "rootfs-x86_64.ext4", "distro": "alpine", "distro_version": "3.23", "profile": "balanced", "size_bytes": len(rootfsData[version])},
+				},
+			},
+			"firecracker": map[string]any{"version": "v1.14.1", "source": "test"},
+			"build":       map[string]any{"date": "2026-01-01", "commit": "abc"},
+		}
+	}
+
+	fcTgz := buildFakeFCTgz(t, "v1.14.1", "x86_64", fcBinaryData)
+
+	downloadHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for version := range rootfsData {
+			switch r.URL.Path {
+			case "/test/images/releases/download/" + version + "/manifest.json":
+				_ = json.NewEncoder(w).Encode(manifest(version))
+				return
+			case "/test/images/releases/download/" + version + "/vmlinux-x86_64":
+				_, _ = w.Write(kernelData)
+				return
+			case "/test/images/releases/download/" + version + "/rootfs-x86_64.ext4":
+				_, _ = w.Write(rootfsData[version])
+				return
+			}
+		}
+		if r.URL.Path == "/firecracker-microvm/firecracker/releases/download/v1.14.1/firecracker-v1.14.1-x86_64.tgz" {
+			_, _ = w.Write(fcTgz)
+			return
+		}
+		http.NotFound(w, r)
+	})
+
+	p, imagesDir := newTestPuller(t, http.NotFoundHandler(), downloadHandler)
+	m, err := image.NewLocalImageManager(image.LocalImageManagerConfig{ImagesDir: imagesDir})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	_, err = p.Pull(ctx, "v0.1.0", image.PullOptions{})
+	require.NoError(t, err)
+	_, err = p.Pull(ctx, "v0.2.0", image.PullOptions{})
+	require.NoError(t, err)
+
+	stat := func(version, file string) os.FileInfo {
+		info, err := os.Stat(filepath.Join(imagesDir, version, file))
+		require.NoError(t, err)
+		return info
+	}
+
+	// Shared artifacts should be the same file, different ones shouldn't.
+	assert.True(t, os.SameFile(stat("v0.1.0", "vmlinux-x86_64"), stat("v0.2.0", "vmlinux-x86_64")))
+	assert.True(t, os.SameFile(stat("v0.1.0", "firecracker"), stat("v0.2.0", "firecracker")))
+	assert.False(t, os.SameFile(stat("v0.1.0", "rootfs-x86_64.ext4"), stat("v0.2.0", "rootfs-x86_64.ext4")))
+
+	// Disk usage should report the shared artifacts.
+	usage, err := m.DiskUsage(ctx)
+	require.NoError(t, err)
+	require.Len(t, usage.Images, 2)
+	for _, img := range usage.Images {
+		assert.Positive(t, img.SharedBytes, img.Name)
+		assert.Less(t, img.SharedBytes, img.SizeBytes, img.Name)
+	}
+	assert.Greater(t, usage.TotalBytes, usage.DiskBytes)
+
+	// Removing an image should keep the blobs still used by others.
+	require.NoError(t, m.Remove(ctx, "v0.1.0"))
+	blobs, err := filepath.Glob(filepath.Join(imagesDir, ".blobs", "sha256", "*"))
+	require.NoError(t, err)
+	assert.Len(t, blobs, 3)
+
+	gotKernel, err := os.ReadFile(filepath.Join(imagesDir, "v0.2.0", "vmlinux-x86_64"))
+	require.NoError(t, err)
+	assert.Equal(t, kernelData, gotKernel)
+
+	// Removing the last image should remove all the blobs.
+	require.NoError(t, m.Remove(ctx, "v0.2.0"))
+	blobs, err = filepath.Glob(filepath.Join(imagesDir, ".blobs", "sha256", "*"))
+	require.NoError(t, err)
+	assert.Empty(t, blobs)
+}
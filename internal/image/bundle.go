@@ -0,0 +1,327 @@
+package image
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+// LocalImageBundlerConfig configures the local image bundler.
+type LocalImageBundlerConfig struct {
+	// ImagesDir is the local directory for storing images.
+	ImagesDir string
+	// SnapshotsDir is the local directory of the snapshot images (default: ImagesDir).
+	SnapshotsDir string
+	// Logger for logging.
+	Logger log.Logger
+}
+
+func (c *LocalImageBundlerConfig) defaults() error {
+	if c.ImagesDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("could not get user home dir: %w", err)
+		}
+		c.ImagesDir = filepath.Join(home, DefaultImagesDir)
+	}
+	if c.SnapshotsDir == "" {
+		c.SnapshotsDir = c.ImagesDir
+	}
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	return nil
+}
+
+// LocalImageBundler implements ImageBundler using the local filesystem.
+//
+// Bundles are tar archives with all the image files (manifest, kernel, rootfs and
+// firecracker binary) inside a directory named as the image, e.g:
+//
+//	v0.1.0/manifest.json
+//	v0.1.0/vmlinux-x86_64
+//	v0.1.0/rootfs-x86_64.ext4
+//	v0.1.0/firecracker
+//
+// Gzip compressed bundles are also accepted on import.
+type LocalImageBundler struct {
+	imagesDir    string
+	snapshotsDir string
+	logger       log.Logger
+}
+
+// NewLocalImageBundler creates a new local image bundler.
+func NewLocalImageBundler(cfg LocalImageBundlerConfig) (*LocalImageBundler, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &LocalImageBundler{
+		imagesDir:    cfg.ImagesDir,
+		snapshotsDir: cfg.SnapshotsDir,
+		logger:       cfg.Logger,
+	}, nil
+}
+
+func (b *LocalImageBundler) Export(ctx context.Context, name string, w io.Writer) error {
+	dir := lookupImageDir(name, b.imagesDir, b.snapshotsDir)
+	versionDir := filepath.Join(dir, name)
+	if _, err := readLocalManifest(dir, name); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("image %s is not installed: %w", name, model.ErrNotFound)
+		}
+		return fmt.Errorf("reading manifest for %s: %w", name, err)
+	}
+
+	entries, err := os.ReadDir(versionDir)
+	if err != nil {
+		return fmt.Errorf("reading image directory: %w", err)
+	}
+
+	// Manifest goes first so importers can validate the bundle early.
+	files := []string{"manifest.json"}
+	for _, e := range entries {
+		if e.Type().IsRegular() && e.Name() != "manifest.json" {
+			files = append(files, e.Name())
+		}
+	}
+	sort.Strings(files[1:])
+
+	tw := tar.NewWriter(w)
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := addTarFile(tw, filepath.Join(versionDir, file), path.Join(name, file)); err != nil {
+			return err
+		}
+		b.logger.Debugf("Exported %s", file)
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("closing bundle: %w", err)
+	}
+
+	return nil
+}
+
+func addTarFile(tw *tar.Writer, src, name string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", src, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", src, err)
+	}
+
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return fmt.Errorf("creating header for %s: %w", src, err)
+	}
+	hdr.Name = name
+	hdr.Uname, hdr.Gname, hdr.Uid, hdr.Gid = "", "", 0, 0
+
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("writing header for %s: %w", name, err)
+	}
+	if _, err := io.Copy(tw, f); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func (b *LocalImageBundler) Import(ctx context.Context, r io.Reader, opts ImportOptions) (string, error) {
+	// Stage the bundle files so a broken bundle never leaves a half installed image.
+	partial := filepath.Join(b.imagesDir, partialDir)
+	if err := os.MkdirAll(partial, 0o755); err != nil {
+		return "", fmt.Errorf("creating staging directory: %w", err)
+	}
+	stagingDir, err := os.MkdirTemp(partial, "import-")
+	if err != nil {
+		return "", fmt.Errorf("creating staging directory: %w", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	name, err := extractBundle(ctx, r, stagingDir)
+	if err != nil {
+		return "", err
+	}
+
+	if err := model.ValidateImageName(name); err != nil {
+		return "", fmt.Errorf("invalid bundle: %w", err)
+	}
+
+	mj, err := readLocalManifest(filepath.Dir(stagingDir), filepath.Base(stagingDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("invalid bundle: missing manifest.json: %w", model.ErrNotValid)
+		}
+		return "", fmt.Errorf("invalid bundle: %w: %w", err, model.ErrNotValid)
+	}
+	if mj.SchemaVersion == 0 {
+		mj.SchemaVersion = 1
+	}
+	if mj.SchemaVersion != model.CurrentSchemaVersion {
+		return "", fmt.Errorf("unsupported manifest schema version %d for %s (supported: %d), try updating sbx: %w",
+			mj.SchemaVersion, name, model.CurrentSchemaVersion, model.ErrNotValid)
+	}
+	if mj.Version != name {
+		return "", fmt.Errorf("invalid bundle: manifest version %q doesn't match bundle image %q: %w", mj.Version, name, model.ErrNotValid)
+	}
+
+	// The host architecture artifacts are required, the ones present are verified.
+	arch := HostArch()
+	archArtifacts, ok := mj.Artifacts[arch]
+	if !ok {
+		return "", fmt.Errorf("invalid bundle: image %s has no %s artifacts: %w", name, arch, model.ErrNotValid)
+	}
+	digests := map[string]string{}
+	for _, a := range mj.Artifacts {
+		digests[a.Kernel.File] = a.Kernel.SHA256
+		digests[a.Rootfs.File] = a.Rootfs.SHA256
+	}
+	for _, required := range []string{archArtifacts.Kernel.File, archArtifacts.Rootfs.File} {
+		if _, err := os.Stat(filepath.Join(stagingDir, required)); err != nil {
+			return "", fmt.Errorf("invalid bundle: missing %s: %w", required, model.ErrNotValid)
+		}
+	}
+	for file, digest := range digests {
+		p := filepath.Join(stagingDir, file)
+		if _, err := os.Stat(p); err != nil || digest == "" {
+			continue
+		}
+		if err := verifyFileDigest(p, digest); err != nil {
+			return "", fmt.Errorf("invalid bundle: %w: %w", err, model.ErrNotValid)
+		}
+	}
+
+	versionDir := filepath.Join(b.imagesDir, name)
+	if _, err := os.Stat(versionDir); err == nil {
+		if !opts.Force {
+			return "", fmt.Errorf("image %q already exists: %w", name, model.ErrAlreadyExists)
+		}
+		if err := os.RemoveAll(versionDir); err != nil {
+			return "", fmt.Errorf("removing existing image: %w", err)
+		}
+	}
+
+	if err := os.Rename(stagingDir, versionDir); err != nil {
+		return "", fmt.Errorf("installing image: %w", err)
+	}
+	if err := os.Chmod(versionDir, 0o755); err != nil {
+		return "", fmt.Errorf("setting image directory permissions: %w", err)
+	}
+
+	// Deduplicate the artifacts shared with other images.
+	entries, err := os.ReadDir(versionDir)
+	if err != nil {
+		return "", fmt.Errorf("reading image directory: %w", err)
+	}
+	for _, e := range entries {
+		if e.Name() == "manifest.json" {
+			continue
+		}
+		if err := storeBlob(b.imagesDir, filepath.Join(versionDir, e.Name()), digests[e.Name()], b.logger); err != nil {
+			b.logger.Warningf("Could not deduplicate %s: %v", e.Name(), err)
+		}
+	}
+	if err := gcBlobs(b.imagesDir, b.logger); err != nil {
+		b.logger.Warningf("Could not remove unused blobs: %v", err)
+	}
+
+	return name, nil
+}
+
+// extractBundle extracts the bundle image files into dstDir and returns the
+// bundle image name.
+func extractBundle(ctx context.Context, r io.Reader, dstDir string) (string, error) {
+	br := bufio.NewReader(r)
+	var src io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return "", fmt.Errorf("opening gzip bundle: %w", err)
+		}
+		defer gz.Close()
+		src = gz
+	}
+
+	name := ""
+	tr := tar.NewReader(src)
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("reading bundle: %w: %w", err, model.ErrNotValid)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			continue
+		case tar.TypeReg:
+		default:
+			return "", fmt.Errorf("invalid bundle: unsupported entry %s: %w", hdr.Name, model.ErrNotValid)
+		}
+
+		// Only "<image>/<file>" entries are allowed.
+		parts := strings.Split(path.Clean(strings.TrimPrefix(hdr.Name, "./")), "/")
+		if len(parts) != 2 || parts[0] == ".." || parts[1] == ".." || strings.HasPrefix(parts[1], ".") {
+			return "", fmt.Errorf("invalid bundle: unexpected entry %s: %w", hdr.Name, model.ErrNotValid)
+		}
+		if name == "" {
+			name = parts[0]
+		}
+		if parts[0] != name {
+			return "", fmt.Errorf("invalid bundle: multiple images (%s, %s): %w", name, parts[0], model.ErrNotValid)
+		}
+
+		mode := os.FileMode(0o644)
+		if hdr.FileInfo().Mode()&0o111 != 0 {
+			mode = 0o755
+		}
+		if err := writeBundleFile(tr, filepath.Join(dstDir, parts[1]), mode); err != nil {
+			return "", err
+		}
+	}
+
+	if name == "" {
+		return "", fmt.Errorf("invalid bundle: no image files: %w", model.ErrNotValid)
+	}
+
+	return name, nil
+}
+
+func writeBundleFile(r io.Reader, dst string, mode os.FileMode) error {
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", dst, err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		return fmt.Errorf("writing %s: %w", dst, err)
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,199 @@
+package image_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/model"
+)
+
+func newTestBundler(t *testing.T) (*image.LocalImageBundler, string) {
+	t.Helper()
+	imagesDir := t.TempDir()
+	b, err := image.NewLocalImageBundler(image.LocalImageBundlerConfig{
+		ImagesDir: imagesDir,
+	})
+	require.NoError(t, err)
+	return b, imagesDir
+}
+
+// writeTestImage writes an installed image with the host arch artifacts.
+func writeTestImage(t *testing.T, imagesDir, name string) {
+	t.Helper()
+	arch := image.HostArch()
+	writeTestManifestArch(t, imagesDir, name, arch)
+	vDir := filepath.Join(imagesDir, name)
+	require.NoError(t, os.WriteFile(filepath.Join(vDir, "vmlinux-"+arch), []byte("kernel-"+name), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(vDir, "rootfs-"+arch+".ext4"), []byte("rootfs-"+name), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(vDir, "firecracker"), []byte("fc"), 0o755))
+}
+
+func writeTestManifestArch(t *testing.T, imagesDir, name, arch string) {
+	t.Helper()
+	vDir := filepath.Join(imagesDir, name)
+	require.NoError(t, os.MkdirAll(vDir, 0o755))
+	manifest := fmt.Sprintf(`{
+  "schema_version": 1,
+  "version": %q,
+  "artifacts": {
+    %q: {
+      "kernel": {"file": "vmlinux-%s", "version": "6.1", "source": "test", "size_bytes": 10},
+      "rootfs": {"file": "rootfs-%s.ext4", "distro": "alpine", "distro_version": "3.23", "profile": "balanced", "size_bytes": 10}
+    }
+  },
+  "firecracker": {"version": "v1.14.1", "source": "test"},
+  "build": {"date": "2026-01-01", "commit": "abc"}
+}`, name, arch, arch, arch)
+	require.NoError(t, os.WriteFile(filepath.Join(vDir, "manifest.json"), []byte(manifest), 0o644))
+}
+
+func TestLocalImageBundlerExportImport(t *testing.T) {
+	src, srcDir := newTestBundler(t)
+	writeTestImage(t, srcDir, "v0.1.0")
+
+	var bundle bytes.Buffer
+	require.NoError(t, src.Export(context.Background(), "v0.1.0", &bundle))
+
+	dst, dstDir := newTestBundler(t)
+	name, err := dst.Import(context.Background(), bytes.NewReader(bundle.Bytes()), image.ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "v0.1.0", name)
+
+	// All the image files should be installed.
+	for _, file := range []string{"manifest.json", "vmlinux-" + image.HostArch(), "rootfs-" + image.HostArch() + ".ext4", "firecracker"} {
+		exp, err := os.ReadFile(filepath.Join(srcDir, "v0.1.0", file))
+		require.NoError(t, err)
+		got, err := os.ReadFile(filepath.Join(dstDir, "v0.1.0", file))
+		require.NoError(t, err)
+		assert.Equal(t, exp, got, file)
+	}
+	info, err := os.Stat(filepath.Join(dstDir, "v0.1.0", "firecracker"))
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode()&0o111, "firecracker should be executable")
+
+	// The imported image should be usable by the image manager.
+	m, err := image.NewLocalImageManager(image.LocalImageManagerConfig{ImagesDir: dstDir})
+	require.NoError(t, err)
+	manifest, err := m.GetManifest(context.Background(), "v0.1.0")
+	require.NoError(t, err)
+	assert.Equal(t, "v0.1.0", manifest.Version)
+
+	// Importing again should fail unless forced.
+	_, err = dst.Import(context.Background(), bytes.NewReader(bundle.Bytes()), image.ImportOptions{})
+	assert.ErrorIs(t, err, model.ErrAlreadyExists)
+	_, err = dst.Import(context.Background(), bytes.NewReader(bundle.Bytes()), image.ImportOptions{Force: true})
+	assert.NoError(t, err)
+
+	// No staging leftovers.
+	staged, err := filepath.Glob(filepath.Join(dstDir, ".partial", "*"))
+	require.NoError(t, err)
+	assert.Empty(t, staged)
+}
+
+func TestLocalImageBundlerExportMissing(t *testing.T) {
+	b, _ := newTestBundler(t)
+	err := b.Export(context.Background(), "v9.9.9", &bytes.Buffer{})
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestLocalImageBundlerImport(t *testing.T) {
+	arch := image.HostArch()
+
+	type entry struct {
+		name string
+		data string
+	}
+	manifest := func(version string) string {
+		return fmt.Sprintf(`{"schema_version": 1, "version": %q, "artifacts": {%q: {"kernel": {"file": "vmlinux-%s"}, "rootfs": {"file": "rootfs-%s.ext4"}}}}`,
+			version, arch, arch, arch)
+	}
+	validEntries := []entry{
+		{name: "v0.1.0/manifest.json", data: manifest("v0.1.0")},
+		{name: "v0.1.0/vmlinux-" + arch, data: "kernel"},
+		{name: "v0.1.0/rootfs-" + arch + ".ext4", data: "rootfs"},
+	}
+
+	tests := map[string]struct {
+		entries []entry
+		gzip    bool
+		expName string
+		expErr  error
+	}{
+		"A valid bundle should be imported.": {
+			entries: validEntries,
+			expName: "v0.1.0",
+		},
+		"A gzip compressed bundle should be imported.": {
+			entries: validEntries,
+			gzip:    true,
+			expName: "v0.1.0",
+		},
+		"A bundle without manifest should fail.": {
+			entries: validEntries[1:],
+			expErr:  model.ErrNotValid,
+		},
+		"A bundle without the rootfs should fail.": {
+			entries: validEntries[:2],
+			expErr:  model.ErrNotValid,
+		},
+		"A bundle with a manifest of another image should fail.": {
+			entries: append([]entry{{name: "v0.1.0/manifest.json", data: manifest("v0.2.0")}}, validEntries[1:]...),
+			expErr:  model.ErrNotValid,
+		},
+		"A bundle with paths escaping the image should fail.": {
+			entries: append([]entry{{name: "v0.1.0/../../evil", data: "x"}}, validEntries...),
+			expErr:  model.ErrNotValid,
+		},
+		"A bundle with multiple images should fail.": {
+			entries: append([]entry{{name: "v0.2.0/vmlinux-" + arch, data: "x"}}, validEntries...),
+			expErr:  model.ErrNotValid,
+		},
+		"An empty bundle should fail.": {
+			expErr: model.ErrNotValid,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			var tw *tar.Writer
+			var gz *gzip.Writer
+			if tc.gzip {
+				gz = gzip.NewWriter(&buf)
+				tw = tar.NewWriter(gz)
+			} else {
+				tw = tar.NewWriter(&buf)
+			}
+			for _, e := range tc.entries {
+				require.NoError(t, tw.WriteHeader(&tar.Header{Name: e.name, Mode: 0o644, Size: int64(len(e.data)), Typeflag: tar.TypeReg}))
+				_, err := tw.Write([]byte(e.data))
+				require.NoError(t, err)
+			}
+			require.NoError(t, tw.Close())
+			if gz != nil {
+				require.NoError(t, gz.Close())
+			}
+
+			b, imagesDir := newTestBundler(t)
+			got, err := b.Import(context.Background(), &buf, image.ImportOptions{})
+			if tc.expErr != nil {
+				assert.ErrorIs(t, err, tc.expErr)
+				_, statErr := os.Stat(filepath.Join(imagesDir, "v0.1.0"))
+				assert.True(t, os.IsNotExist(statErr), "failed imports shouldn't install the image")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expName, got)
+		})
+	}
+}
@@ -0,0 +1,372 @@
+package image
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+// customizeEnv is the environment of the customization commands, the host
+// environment is not leaked into the rootfs.
+var customizeEnv = []string{
+	"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
+	"HOME=/root",
+	"DEBIAN_FRONTEND=noninteractive",
+}
+
+// LocalImageCustomizerConfig configures the local image customizer.
+type LocalImageCustomizerConfig struct {
+	// ImagesDir is the local directory for storing images.
+	ImagesDir string
+	// SnapshotsDir is the local directory of the snapshot images (default: ImagesDir).
+	SnapshotsDir string
+	// Logger for logging.
+	Logger log.Logger
+}
+
+func (c *LocalImageCustomizerConfig) defaults() error {
+	if c.ImagesDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("could not get user home dir: %w", err)
+		}
+		c.ImagesDir = filepath.Join(home, DefaultImagesDir)
+	}
+	if c.SnapshotsDir == "" {
+		c.SnapshotsDir = c.ImagesDir
+	}
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	return nil
+}
+
+// LocalImageCustomizer implements ImageCustomizer by loop mounting a copy of the
+// base image rootfs. The files are copied into the mounted rootfs and the commands
+// run chrooted in it, so they must be runnable on the host architecture. It
+// requires root.
+//
+// The derived image is named after the base image and the customization digest
+// (<base>-custom-<digest>), so the same customization is applied only once.
+type LocalImageCustomizer struct {
+	imagesDir    string
+	snapshotsDir string
+	logger       log.Logger
+}
+
+// NewLocalImageCustomizer creates a new local image customizer.
+func NewLocalImageCustomizer(cfg LocalImageCustomizerConfig) (*LocalImageCustomizer, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &LocalImageCustomizer{
+		imagesDir:    cfg.ImagesDir,
+		snapshotsDir: cfg.SnapshotsDir,
+		logger:       cfg.Logger,
+	}, nil
+}
+
+func (c *LocalImageCustomizer) Customize(ctx context.Context, opts CustomizeOptions) (*CustomizeResult, error) {
+	if err := opts.Customization.Validate(); err != nil {
+		return nil, err
+	}
+
+	baseImagesDir := lookupImageDir(opts.BaseImage, c.imagesDir, c.snapshotsDir)
+	base, err := readLocalManifest(baseImagesDir, opts.BaseImage)
+	if err != nil {
+		return nil, fmt.Errorf("image %q is not installed: %w", opts.BaseImage, model.ErrNotFound)
+	}
+	arch := HostArch()
+	artifacts, ok := base.Artifacts[arch]
+	if !ok {
+		return nil, fmt.Errorf("image %q has no %s artifacts: %w", opts.BaseImage, arch, model.ErrNotValid)
+	}
+
+	digest, err := customizationDigest(artifacts.Rootfs.SHA256, opts.Customization)
+	if err != nil {
+		return nil, err
+	}
+	name := CustomizedImageName(opts.BaseImage, digest)
+	if err := model.ValidateImageName(name); err != nil {
+		return nil, fmt.Errorf("invalid derived image name: %w", err)
+	}
+
+	versionDir := filepath.Join(c.imagesDir, name)
+	if _, err := os.Stat(filepath.Join(versionDir, "manifest.json")); err == nil && !opts.Force {
+		c.logger.Infof("Customized image %s already installed", name)
+		return &CustomizeResult{Name: name, Skipped: true}, nil
+	}
+
+	if os.Geteuid() != 0 {
+		return nil, fmt.Errorf("customizing images requires root to loop mount the rootfs: %w", model.ErrNotValid)
+	}
+
+	// Stage the image so a failed customization never leaves a half installed image.
+	partial := filepath.Join(c.imagesDir, partialDir)
+	if err := os.MkdirAll(partial, 0o755); err != nil {
+		return nil, fmt.Errorf("creating staging directory: %w", err)
+	}
+	stagingDir, err := os.MkdirTemp(partial, "customize-")
+	if err != nil {
+		return nil, fmt.Errorf("creating staging directory: %w", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	baseDir := filepath.Join(baseImagesDir, opts.BaseImage)
+	for _, file := range []string{artifacts.Kernel.File, artifacts.Rootfs.File, "firecracker"} {
+		src := filepath.Join(baseDir, file)
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+		if err := copyFile(src, filepath.Join(stagingDir, file)); err != nil {
+			return nil, fmt.Errorf("copying %s: %w", file, err)
+		}
+	}
+	if info, err := os.Stat(filepath.Join(stagingDir, "firecracker")); err == nil {
+		if err := os.Chmod(filepath.Join(stagingDir, "firecracker"), info.Mode()|0o755); err != nil {
+			return nil, fmt.Errorf("chmod firecracker binary: %w", err)
+		}
+	}
+
+	rootfs := filepath.Join(stagingDir, artifacts.Rootfs.File)
+	if err := c.apply(ctx, rootfs, filepath.Join(stagingDir, "mnt"), opts.Customization); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(filepath.Join(stagingDir, "mnt")); err != nil {
+		return nil, fmt.Errorf("removing mount point: %w", err)
+	}
+
+	rootfsMeta, err := artifactMeta(rootfs)
+	if err != nil {
+		return nil, fmt.Errorf("rootfs: %w", err)
+	}
+
+	files := make([]string, 0, len(opts.Customization.Files))
+	for _, f := range opts.Customization.Files {
+		files = append(files, f.Dst)
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	artifacts.Rootfs.SizeBytes = rootfsMeta.size
+	artifacts.Rootfs.SHA256 = rootfsMeta.digest
+	mj := manifestJSON{
+		SchemaVersion: base.SchemaVersion,
+		Version:       name,
+		Artifacts:     map[string]archArtifactsJSON{arch: artifacts},
+		FC:            base.FC,
+		Build:         buildJSON{Date: now, Commit: base.Build.Commit},
+		Profiles:      base.Profiles,
+		Customized: &customizedInfoJSON{
+			BaseImage: opts.BaseImage,
+			Commands:  opts.Customization.Commands,
+			Files:     files,
+			Digest:    digest,
+			CreatedAt: now,
+		},
+	}
+	manifestData, err := json.MarshalIndent(mj, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshaling manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(stagingDir, "manifest.json"), manifestData, 0o644); err != nil {
+		return nil, fmt.Errorf("writing manifest: %w", err)
+	}
+
+	if _, err := os.Stat(versionDir); err == nil {
+		if err := os.RemoveAll(versionDir); err != nil {
+			return nil, fmt.Errorf("removing existing image: %w", err)
+		}
+	}
+	if err := os.Rename(stagingDir, versionDir); err != nil {
+		return nil, fmt.Errorf("installing image: %w", err)
+	}
+	if err := os.Chmod(versionDir, 0o755); err != nil {
+		return nil, fmt.Errorf("setting image directory permissions: %w", err)
+	}
+
+	// The kernel and firecracker binary are the base image ones, deduplicate them.
+	digests := map[string]string{artifacts.Kernel.File: artifacts.Kernel.SHA256, artifacts.Rootfs.File: rootfsMeta.digest, "firecracker": ""}
+	for file, d := range digests {
+		path := filepath.Join(versionDir, file)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if err := storeBlob(c.imagesDir, path, d, c.logger); err != nil {
+			c.logger.Warningf("Could not deduplicate %s: %v", file, err)
+		}
+	}
+	if err := gcBlobs(c.imagesDir, c.logger); err != nil {
+		c.logger.Warningf("Could not remove unused blobs: %v", err)
+	}
+
+	c.logger.Infof("Customized image %s created from %s", name, opts.BaseImage)
+	return &CustomizeResult{Name: name}, nil
+}
+
+// apply loop mounts the rootfs and applies the customization to it.
+func (c *LocalImageCustomizer) apply(ctx context.Context, rootfs, mountDir string, cust model.ImageCustomization) (err error) {
+	if err := os.MkdirAll(mountDir, 0o755); err != nil {
+		return fmt.Errorf("creating mount point: %w", err)
+	}
+
+	// The mounts are always undone, even when the context is cancelled.
+	var mounts []string
+	defer func() {
+		for i := len(mounts) - 1; i >= 0; i-- {
+			if out, uerr := exec.Command("umount", mounts[i]).CombinedOutput(); uerr != nil && err == nil {
+				err = fmt.Errorf("unmounting %s: %w: %s", mounts[i], uerr, strings.TrimSpace(string(out)))
+			}
+		}
+	}()
+	mount := func(args ...string) error {
+		target := args[len(args)-1]
+		if out, err := exec.CommandContext(ctx, "mount", args...).CombinedOutput(); err != nil {
+			return fmt.Errorf("mounting %s: %w: %s", target, err, strings.TrimSpace(string(out)))
+		}
+		mounts = append(mounts, target)
+		return nil
+	}
+
+	if err := mount("-o", "loop", rootfs, mountDir); err != nil {
+		return err
+	}
+	if !rootHasFile(mountDir, "/bin/sh") && len(cust.Commands) > 0 {
+		return fmt.Errorf("the image has no /bin/sh to run the commands: %w", model.ErrNotValid)
+	}
+
+	for _, f := range cust.Files {
+		if err := copyIntoRoot(mountDir, f); err != nil {
+			return err
+		}
+	}
+	if len(cust.Commands) == 0 {
+		return nil
+	}
+
+	// The commands usually need the devices, the processes and DNS resolution
+	// (e.g. package installs).
+	for _, m := range []struct{ args []string }{
+		{[]string{"--bind", "/dev", rootPath(mountDir, "/dev")}},
+		{[]string{"-t", "proc", "proc", rootPath(mountDir, "/proc")}},
+	} {
+		target := m.args[len(m.args)-1]
+		if err := os.MkdirAll(target, 0o755); err != nil {
+			return fmt.Errorf("creating %s: %w", target, err)
+		}
+		if err := mount(m.args...); err != nil {
+			return err
+		}
+	}
+	restoreResolv := hostResolvConf(mountDir, c.logger)
+	defer restoreResolv()
+
+	for _, cmd := range cust.Commands {
+		c.logger.Infof("Running customization command: %s", cmd)
+		run := exec.CommandContext(ctx, "chroot", mountDir, "/bin/sh", "-c", cmd)
+		run.Env = customizeEnv
+		out, err := run.CombinedOutput()
+		c.logger.Debugf("Customization command output: %s", out)
+		if err != nil {
+			return fmt.Errorf("customization command %q failed: %w: %s", cmd, err, strings.TrimSpace(string(out)))
+		}
+	}
+
+	return nil
+}
+
+// copyIntoRoot copies a customization file into the mounted rootfs, keeping the
+// source permissions.
+func copyIntoRoot(rootDir string, f model.ImageCustomizationFile) error {
+	info, err := os.Stat(f.Src)
+	if err != nil {
+		return fmt.Errorf("customization file %s does not exist: %w", f.Src, model.ErrNotValid)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("customization file %s is not a regular file: %w", f.Src, model.ErrNotValid)
+	}
+
+	dst := rootPath(rootDir, f.Dst)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("creating %s parent directory: %w", f.Dst, err)
+	}
+	os.Remove(dst)
+	if err := copyFile(f.Src, dst); err != nil {
+		return fmt.Errorf("copying %s: %w", f.Dst, err)
+	}
+	if err := os.Chmod(dst, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("chmod %s: %w", f.Dst, err)
+	}
+	return nil
+}
+
+// hostResolvConf sets the host DNS configuration in the rootfs while the commands
+// run, and returns the func that restores the rootfs one.
+func hostResolvConf(rootDir string, logger log.Logger) func() {
+	host, err := os.ReadFile("/etc/resolv.conf")
+	if err != nil {
+		return func() {}
+	}
+	dst := rootPath(rootDir, "/etc/resolv.conf")
+	info, err := os.Lstat(dst)
+	switch {
+	case err == nil && !info.Mode().IsRegular():
+		// Symlinks (e.g. systemd-resolved) would be written outside the rootfs.
+		return func() {}
+	case err == nil:
+		orig, err := os.ReadFile(dst)
+		if err != nil {
+			return func() {}
+		}
+		if err := os.WriteFile(dst, host, info.Mode().Perm()); err != nil {
+			logger.Warningf("Could not set the host DNS configuration: %v", err)
+			return func() {}
+		}
+		return func() { _ = os.WriteFile(dst, orig, info.Mode().Perm()) }
+	default:
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return func() {}
+		}
+		if err := os.WriteFile(dst, host, 0o644); err != nil {
+			logger.Warningf("Could not set the host DNS configuration: %v", err)
+			return func() {}
+		}
+		return func() { _ = os.Remove(dst) }
+	}
+}
+
+// CustomizedImageName returns the name of the image derived from a base image
+// by the customization with the digest.
+func CustomizedImageName(baseImage, digest string) string {
+	return fmt.Sprintf("%s-custom-%s", baseImage, digest[:12])
+}
+
+// customizationDigest returns the SHA256 hex digest of a customization applied to
+// the base rootfs with the digest, covering the commands and the file contents.
+func customizationDigest(baseRootFSDigest string, cust model.ImageCustomization) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "rootfs\x00%s\x00", baseRootFSDigest)
+	for _, f := range cust.Files {
+		info, err := os.Stat(f.Src)
+		if err != nil {
+			return "", fmt.Errorf("customization file %s does not exist: %w", f.Src, model.ErrNotValid)
+		}
+		d, err := fileDigest(f.Src)
+		if err != nil {
+			return "", fmt.Errorf("customization file %s: %w", f.Src, err)
+		}
+		fmt.Fprintf(h, "file\x00%s\x00%s\x00%o\x00", f.Dst, d, info.Mode().Perm())
+	}
+	for _, cmd := range cust.Commands {
+		fmt.Fprintf(h, "cmd\x00%s\x00", cmd)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,181 @@
+package image_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/model"
+)
+
+// installCustomizableImage installs a base image whose rootfs has the host shell
+// and its libraries, so the customization commands can run chrooted in it.
+func installCustomizableImage(t *testing.T, imagesDir, name string) {
+	t.Helper()
+	if os.Geteuid() != 0 {
+		t.Skip("customizing images requires root")
+	}
+	if _, err := exec.LookPath("mkfs.ext4"); err != nil {
+		t.Skip("mkfs.ext4 not available")
+	}
+
+	rootDir := t.TempDir()
+	shell, err := filepath.EvalSymlinks("/bin/sh")
+	require.NoError(t, err)
+	files := []string{shell}
+	out, err := exec.Command("ldd", shell).Output()
+	if err != nil {
+		t.Skip("ldd not available")
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		for _, f := range strings.Fields(line) {
+			if strings.HasPrefix(f, "/") {
+				files = append(files, f)
+			}
+		}
+	}
+	for _, f := range files {
+		dst := filepath.Join(rootDir, f)
+		require.NoError(t, os.MkdirAll(filepath.Dir(dst), 0o755))
+		data, err := os.ReadFile(f)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(dst, data, 0o755))
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(rootDir, "bin"), 0o755))
+	if shell != filepath.Join(rootDir, "bin", "sh") {
+		require.NoError(t, os.Symlink(shell, filepath.Join(rootDir, "bin", "sh")))
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(rootDir, "etc"), 0o755))
+
+	arch := image.HostArch()
+	versionDir := filepath.Join(imagesDir, name)
+	require.NoError(t, os.MkdirAll(versionDir, 0o755))
+	rootfs := filepath.Join(versionDir, "rootfs-"+arch+".ext4")
+	if out, err := exec.Command("mkfs.ext4", "-q", "-d", rootDir, rootfs, "32M").CombinedOutput(); err != nil {
+		t.Skipf("mkfs.ext4 failed: %s", out)
+	}
+	mnt := t.TempDir()
+	if err := exec.Command("mount", "-o", "loop,ro", rootfs, mnt).Run(); err != nil {
+		t.Skip("loop mounts not available")
+	}
+	require.NoError(t, exec.Command("umount", mnt).Run())
+	require.NoError(t, os.WriteFile(filepath.Join(versionDir, "vmlinux-"+arch), []byte("kernel"), 0o644))
+
+	manifest, err := json.Marshal(map[string]any{
+		"schema_version": 1,
+		"version":        name,
+		"artifacts": map[string]any{arch: map[string]any{
+			"kernel": map[string]any{"file": "vmlinux-" + arch, "version": "6.1.0"},
+			"rootfs": map[string]any{"file": "rootfs-" + arch + ".ext4", "distro": "test"},
+		}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(versionDir, "manifest.json"), manifest, 0o644))
+}
+
+func TestLocalImageCustomizerCustomize(t *testing.T) {
+	srcDir := t.TempDir()
+	motd := filepath.Join(srcDir, "motd")
+	require.NoError(t, os.WriteFile(motd, []byte("welcome"), 0o600))
+
+	cust := model.ImageCustomization{
+		Commands: []string{"echo customized > /etc/greeting"},
+		Files:    []model.ImageCustomizationFile{{Src: motd, Dst: "/etc/sbx/motd"}},
+	}
+
+	tests := map[string]struct {
+		opts       image.CustomizeOptions
+		expErr     error
+		expFail    bool
+		assertions func(t *testing.T, imagesDir string, res *image.CustomizeResult)
+	}{
+		"Customizing an image should install the derived image with the changes.": {
+			opts: image.CustomizeOptions{BaseImage: "v0.1.0", Customization: cust},
+			assertions: func(t *testing.T, imagesDir string, res *image.CustomizeResult) {
+				assert.True(t, strings.HasPrefix(res.Name, "v0.1.0-custom-"))
+				assert.False(t, res.Skipped)
+
+				mgr, err := image.NewLocalImageManager(image.LocalImageManagerConfig{ImagesDir: imagesDir})
+				require.NoError(t, err)
+				manifest, err := mgr.GetManifest(context.Background(), res.Name)
+				require.NoError(t, err)
+				require.NotNil(t, manifest.Customized)
+				assert.Equal(t, "v0.1.0", manifest.Customized.BaseImage)
+				assert.Equal(t, cust.Commands, manifest.Customized.Commands)
+				assert.Equal(t, []string{"/etc/sbx/motd"}, manifest.Customized.Files)
+
+				imgs, err := mgr.List(context.Background())
+				require.NoError(t, err)
+				sources := map[string]model.ImageSource{}
+				for _, img := range imgs {
+					sources[img.Version] = img.Source
+				}
+				assert.Equal(t, model.ImageSourceCustomized, sources[res.Name])
+
+				rootfs := mgr.RootFSPath(res.Name)
+				assert.Equal(t, "customized\n", debugfsCat(t, rootfs, "/etc/greeting"))
+				assert.Equal(t, "welcome", debugfsCat(t, rootfs, "/etc/sbx/motd"))
+
+				// The base image is untouched.
+				assert.Empty(t, debugfsCat(t, mgr.RootFSPath("v0.1.0"), "/etc/greeting"))
+
+				// The same customization reuses the derived image.
+				c, err := image.NewLocalImageCustomizer(image.LocalImageCustomizerConfig{ImagesDir: imagesDir})
+				require.NoError(t, err)
+				again, err := c.Customize(context.Background(), image.CustomizeOptions{BaseImage: "v0.1.0", Customization: cust})
+				require.NoError(t, err)
+				assert.Equal(t, &image.CustomizeResult{Name: res.Name, Skipped: true}, again)
+			},
+		},
+
+		"A failing command should fail without installing the image.": {
+			opts:    image.CustomizeOptions{BaseImage: "v0.1.0", Customization: model.ImageCustomization{Commands: []string{"exit 3"}}},
+			expFail: true,
+			assertions: func(t *testing.T, imagesDir string, _ *image.CustomizeResult) {
+				entries, err := filepath.Glob(filepath.Join(imagesDir, "v0.1.0-custom-*"))
+				require.NoError(t, err)
+				assert.Empty(t, entries)
+			},
+		},
+
+		"A missing base image should fail.": {
+			opts:   image.CustomizeOptions{BaseImage: "v9.9.9", Customization: cust},
+			expErr: model.ErrNotFound,
+		},
+
+		"An empty customization should fail.": {
+			opts:   image.CustomizeOptions{BaseImage: "v0.1.0"},
+			expErr: model.ErrNotValid,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			imagesDir := t.TempDir()
+			installCustomizableImage(t, imagesDir, "v0.1.0")
+
+			c, err := image.NewLocalImageCustomizer(image.LocalImageCustomizerConfig{ImagesDir: imagesDir})
+			require.NoError(t, err)
+
+			res, err := c.Customize(context.Background(), tc.opts)
+			switch {
+			case tc.expErr != nil:
+				assert.ErrorIs(t, err, tc.expErr)
+			case tc.expFail:
+				require.Error(t, err)
+				tc.assertions(t, imagesDir, nil)
+			default:
+				require.NoError(t, err)
+				tc.assertions(t, imagesDir, res)
+			}
+		})
+	}
+}
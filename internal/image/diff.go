@@ -0,0 +1,292 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+// LocalImageDifferConfig configures the local image differ.
+type LocalImageDifferConfig struct {
+	// ImagesDir is the local directory for storing images.
+	ImagesDir string
+	// SnapshotsDir is the local directory of the snapshot images (default: ImagesDir).
+	SnapshotsDir string
+	// Logger for logging.
+	Logger log.Logger
+}
+
+func (c *LocalImageDifferConfig) defaults() error {
+	if c.ImagesDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("could not get user home dir: %w", err)
+		}
+		c.ImagesDir = filepath.Join(home, DefaultImagesDir)
+	}
+	if c.SnapshotsDir == "" {
+		c.SnapshotsDir = c.ImagesDir
+	}
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	return nil
+}
+
+// LocalImageDiffer implements ImageDiffer by loop mounting the image rootfs files
+// read-only and walking them. It requires root.
+//
+// Regular files with the same size and modification time are considered
+// unchanged without reading them, like rsync does, the rest are compared by
+// their SHA256 digest.
+type LocalImageDiffer struct {
+	imagesDir    string
+	snapshotsDir string
+	logger       log.Logger
+}
+
+// NewLocalImageDiffer creates a new local image differ.
+func NewLocalImageDiffer(cfg LocalImageDifferConfig) (*LocalImageDiffer, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &LocalImageDiffer{
+		imagesDir:    cfg.ImagesDir,
+		snapshotsDir: cfg.SnapshotsDir,
+		logger:       cfg.Logger,
+	}, nil
+}
+
+func (d *LocalImageDiffer) Diff(ctx context.Context, from, to string) (*model.ImageDiff, error) {
+	fromRootFS, err := d.rootFS(from)
+	if err != nil {
+		return nil, err
+	}
+	toRootFS, err := d.rootFS(to)
+	if err != nil {
+		return nil, err
+	}
+	if os.Geteuid() != 0 {
+		return nil, fmt.Errorf("diffing images requires root to loop mount the rootfs: %w", model.ErrNotValid)
+	}
+
+	partial := filepath.Join(d.imagesDir, partialDir)
+	if err := os.MkdirAll(partial, 0o755); err != nil {
+		return nil, fmt.Errorf("creating staging directory: %w", err)
+	}
+	workDir, err := os.MkdirTemp(partial, "diff-")
+	if err != nil {
+		return nil, fmt.Errorf("creating staging directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	fromDir, toDir := filepath.Join(workDir, "from"), filepath.Join(workDir, "to")
+	unmountFrom, err := mountReadOnly(ctx, fromRootFS, fromDir)
+	if err != nil {
+		return nil, err
+	}
+	defer unmountFrom()
+	unmountTo, err := mountReadOnly(ctx, toRootFS, toDir)
+	if err != nil {
+		return nil, err
+	}
+	defer unmountTo()
+
+	oldFiles, err := scanRoot(ctx, fromDir)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s rootfs: %w", from, err)
+	}
+	newFiles, err := scanRoot(ctx, toDir)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s rootfs: %w", to, err)
+	}
+
+	paths := make([]string, 0, len(oldFiles)+len(newFiles))
+	for p := range oldFiles {
+		paths = append(paths, p)
+	}
+	for p := range newFiles {
+		if _, ok := oldFiles[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	slices.Sort(paths)
+
+	diff := &model.ImageDiff{From: from, To: to, Changes: []model.FileChange{}}
+	for _, p := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		oldFile, inOld := oldFiles[p]
+		newFile, inNew := newFiles[p]
+		var change *model.FileChange
+		switch {
+		case !inNew:
+			change = &model.FileChange{Path: p, Kind: model.FileChangeRemoved}
+		case !inOld:
+			change = &model.FileChange{Path: p, Kind: model.FileChangeAdded}
+		default:
+			changed, err := fileChanged(oldFile, newFile)
+			if err != nil {
+				return nil, err
+			}
+			if !changed {
+				continue
+			}
+			change = &model.FileChange{Path: p, Kind: model.FileChangeModified}
+		}
+
+		if inOld {
+			if err := oldFile.hash(); err != nil {
+				return nil, err
+			}
+			change.Old = &oldFile.state
+		}
+		if inNew {
+			if err := newFile.hash(); err != nil {
+				return nil, err
+			}
+			change.New = &newFile.state
+		}
+		diff.Changes = append(diff.Changes, *change)
+	}
+
+	d.logger.Infof("Diffed image %s against %s: %d changes", to, from, len(diff.Changes))
+	return diff, nil
+}
+
+// rootFS returns the rootfs path of an installed image for the host architecture.
+func (d *LocalImageDiffer) rootFS(name string) (string, error) {
+	dir := lookupImageDir(name, d.imagesDir, d.snapshotsDir)
+	mj, err := readLocalManifest(dir, name)
+	if err != nil {
+		return "", fmt.Errorf("image %q is not installed: %w", name, model.ErrNotFound)
+	}
+	artifacts, ok := mj.Artifacts[HostArch()]
+	if !ok {
+		return "", fmt.Errorf("image %q has no %s artifacts: %w", name, HostArch(), model.ErrNotValid)
+	}
+	return filepath.Join(dir, name, artifacts.Rootfs.File), nil
+}
+
+// mountReadOnly loop mounts an ext4 image read-only without replaying its journal,
+// so the image is never written. It returns the func that unmounts it.
+func mountReadOnly(ctx context.Context, img, dir string) (func(), error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating mount point: %w", err)
+	}
+	if out, err := exec.CommandContext(ctx, "mount", "-o", "loop,ro,noload", img, dir).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("mounting %s: %w: %s", img, err, strings.TrimSpace(string(out)))
+	}
+	return func() { _ = exec.Command("umount", dir).Run() }, nil
+}
+
+// scannedFile is a rootfs file found by scanRoot, the digest is computed on demand.
+type scannedFile struct {
+	hostPath string
+	modTime  time.Time
+	state    model.FileState
+}
+
+func (f *scannedFile) hash() error {
+	if f.state.Type != model.FileTypeRegular || f.state.SHA256 != "" {
+		return nil
+	}
+	digest, err := fileDigest(f.hostPath)
+	if err != nil {
+		return err
+	}
+	f.state.SHA256 = digest
+	return nil
+}
+
+// scanRoot returns the files of a mounted rootfs keyed by their guest path.
+func scanRoot(ctx context.Context, rootDir string) (map[string]*scannedFile, error) {
+	files := map[string]*scannedFile{}
+	err := filepath.WalkDir(rootDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(rootDir, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		guestPath := "/" + filepath.ToSlash(rel)
+		if guestPath == "/lost+found" {
+			return filepath.SkipDir
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		f := &scannedFile{
+			hostPath: p,
+			modTime:  info.ModTime(),
+			state:    model.FileState{Mode: info.Mode() & (fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky)},
+		}
+		if uid, gid, ok := fileOwner(info); ok {
+			f.state.UID, f.state.GID = uid, gid
+		}
+		switch {
+		case info.Mode().IsRegular():
+			f.state.Type = model.FileTypeRegular
+			f.state.SizeBytes = info.Size()
+		case info.IsDir():
+			f.state.Type = model.FileTypeDir
+		case info.Mode()&fs.ModeSymlink != 0:
+			f.state.Type = model.FileTypeSymlink
+			if f.state.LinkTarget, err = os.Readlink(p); err != nil {
+				return err
+			}
+		default:
+			f.state.Type = model.FileTypeOther
+		}
+		files[guestPath] = f
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// fileChanged reports if a file changed between two images.
+func fileChanged(oldFile, newFile *scannedFile) (bool, error) {
+	o, n := oldFile.state, newFile.state
+	if o.Type != n.Type || o.Mode != n.Mode || o.UID != n.UID || o.GID != n.GID || o.LinkTarget != n.LinkTarget {
+		return true, nil
+	}
+	if o.Type != model.FileTypeRegular {
+		return false, nil
+	}
+	if o.SizeBytes != n.SizeBytes {
+		return true, nil
+	}
+	if oldFile.modTime.Equal(newFile.modTime) {
+		return false, nil
+	}
+	if err := oldFile.hash(); err != nil {
+		return false, err
+	}
+	if err := newFile.hash(); err != nil {
+		return false, err
+	}
+	return oldFile.state.SHA256 != newFile.state.SHA256, nil
+}
@@ -0,0 +1,147 @@
+package image_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/model"
+)
+
+// installRootFSImage installs an image whose rootfs has the files (path to content,
+// an empty content is a directory).
+func installRootFSImage(t *testing.T, imagesDir, name string, files map[string]string, modTime time.Time) {
+	t.Helper()
+	if os.Geteuid() != 0 {
+		t.Skip("diffing images requires root")
+	}
+	if _, err := exec.LookPath("mkfs.ext4"); err != nil {
+		t.Skip("mkfs.ext4 not available")
+	}
+
+	rootDir := t.TempDir()
+	for p, content := range files {
+		dst := filepath.Join(rootDir, p)
+		if content == "" {
+			require.NoError(t, os.MkdirAll(dst, 0o755))
+			continue
+		}
+		require.NoError(t, os.MkdirAll(filepath.Dir(dst), 0o755))
+		require.NoError(t, os.WriteFile(dst, []byte(content), 0o644))
+		require.NoError(t, os.Chtimes(dst, modTime, modTime))
+	}
+
+	arch := image.HostArch()
+	versionDir := filepath.Join(imagesDir, name)
+	require.NoError(t, os.MkdirAll(versionDir, 0o755))
+	rootfs := filepath.Join(versionDir, "rootfs-"+arch+".ext4")
+	if out, err := exec.Command("mkfs.ext4", "-q", "-d", rootDir, rootfs, "8M").CombinedOutput(); err != nil {
+		t.Skipf("mkfs.ext4 failed: %s", out)
+	}
+	mnt := t.TempDir()
+	if err := exec.Command("mount", "-o", "loop,ro", rootfs, mnt).Run(); err != nil {
+		t.Skip("loop mounts not available")
+	}
+	require.NoError(t, exec.Command("umount", mnt).Run())
+
+	manifest, err := json.Marshal(map[string]any{
+		"schema_version": 1,
+		"version":        name,
+		"artifacts": map[string]any{arch: map[string]any{
+			"kernel": map[string]any{"file": "vmlinux-" + arch},
+			"rootfs": map[string]any{"file": "rootfs-" + arch + ".ext4"},
+		}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(versionDir, "manifest.json"), manifest, 0o644))
+}
+
+func TestLocalImageDifferDiff(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	base := map[string]string{
+		"etc/hostname": "base",
+		"etc/motd":     "hello",
+		"etc/removed":  "bye",
+		"var/log":      "",
+	}
+
+	tests := map[string]struct {
+		files      map[string]string
+		modTime    time.Time
+		from, to   string
+		expChanges []model.FileChange
+		expErr     error
+	}{
+		"Changed files should be reported with their states.": {
+			files: map[string]string{
+				"etc/hostname":    "base",
+				"etc/motd":        "HELLO",
+				"var/log":         "",
+				"var/log/app.log": "line",
+			},
+			modTime: t0.Add(time.Hour),
+			from:    "base",
+			to:      "snap",
+			expChanges: []model.FileChange{
+				{
+					Path: "/etc/motd", Kind: model.FileChangeModified,
+					Old: &model.FileState{Type: model.FileTypeRegular, Mode: 0o644, SizeBytes: 5, SHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+					New: &model.FileState{Type: model.FileTypeRegular, Mode: 0o644, SizeBytes: 5, SHA256: "3733cd977ff8eb18b987357e22ced99f46097f31ecb239e878ae63760e83e4d5"},
+				},
+				{
+					Path: "/etc/removed", Kind: model.FileChangeRemoved,
+					Old: &model.FileState{Type: model.FileTypeRegular, Mode: 0o644, SizeBytes: 3, SHA256: "b49f425a7e1f9cff3856329ada223f2f9d368f15a00cf48df16ca95986137fe8"},
+				},
+				{
+					Path: "/var/log/app.log", Kind: model.FileChangeAdded,
+					New: &model.FileState{Type: model.FileTypeRegular, Mode: 0o644, SizeBytes: 4, SHA256: "38a9c1e721584da284b90ceea4ed7eb8db15ac4ea86c2c75ec925d5f8799fcf0"},
+				},
+			},
+		},
+
+		"The same files should not be reported.": {
+			files:      base,
+			modTime:    t0,
+			from:       "base",
+			to:         "snap",
+			expChanges: []model.FileChange{},
+		},
+
+		"A missing image should fail.": {
+			files:   base,
+			modTime: t0,
+			from:    "base",
+			to:      "missing",
+			expErr:  model.ErrNotFound,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			imagesDir := t.TempDir()
+			installRootFSImage(t, imagesDir, "base", base, t0)
+			installRootFSImage(t, imagesDir, "snap", tc.files, tc.modTime)
+
+			d, err := image.NewLocalImageDiffer(image.LocalImageDifferConfig{ImagesDir: imagesDir})
+			require.NoError(t, err)
+
+			diff, err := d.Diff(context.Background(), tc.from, tc.to)
+			if tc.expErr != nil {
+				assert.ErrorIs(t, err, tc.expErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.from, diff.From)
+			assert.Equal(t, tc.to, diff.To)
+			assert.Equal(t, tc.expChanges, diff.Changes)
+		})
+	}
+}
@@ -0,0 +1,8 @@
+package image
+
+import "io/fs"
+
+// fileOwner returns no ownership, the Windows files have no UID and GID.
+func fileOwner(fs.FileInfo) (uid, gid int, ok bool) {
+	return 0, 0, false
+}
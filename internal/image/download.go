@@ -0,0 +1,445 @@
+package image
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+const (
+	// DefaultPullConcurrency is the default number of parallel chunk downloads per artifact.
+	DefaultPullConcurrency = 4
+	// DefaultPullChunkSize is the default size of each ranged download chunk.
+	DefaultPullChunkSize int64 = 16 << 20
+
+	// downloadStateSuffix is the suffix of the file that tracks the downloaded chunks of
+	// an artifact, so interrupted downloads can be resumed.
+	downloadStateSuffix = ".state"
+)
+
+// PullProgress reports the download progress of an image artifact.
+type PullProgress struct {
+	// Artifact is the artifact being downloaded (kernel, rootfs or firecracker).
+	Artifact string
+	// Downloaded is the number of bytes of the artifact already downloaded (including resumed bytes).
+	Downloaded int64
+	// Total is the artifact size in bytes (0 if unknown).
+	Total int64
+}
+
+// ProgressEvent returns the download progress as the "download_<artifact>" step
+// of a long operation, the percent is the completion of the artifact.
+func (p PullProgress) ProgressEvent() model.ProgressEvent {
+	percent := -1.0
+	if p.Total > 0 {
+		percent = float64(p.Downloaded) / float64(p.Total) * 100
+	}
+	return model.ProgressEvent{
+		Step:    "download_" + p.Artifact,
+		Percent: percent,
+		Message: fmt.Sprintf("Downloading %s", p.Artifact),
+	}
+}
+
+// errRangeNotSupported is returned when the server ignores ranged requests.
+var errRangeNotSupported = errors.New("server doesn't support ranged requests")
+
+// downloadState is the on-disk state of a chunked download.
+type downloadState struct {
+	URL       string `json:"url"`
+	Size      int64  `json:"size"`
+	ChunkSize int64  `json:"chunk_size"`
+	Done      []bool `json:"done"`
+}
+
+// chunkedDownloader downloads files in parallel ranged chunks, resuming the chunks
+// already downloaded by previous interrupted attempts.
+type chunkedDownloader struct {
+	httpClient  *http.Client
+	concurrency int
+	chunkSize   int64
+	limiter     *rateLimiter
+	logger      log.Logger
+}
+
+// download downloads url into dstPath. size is the expected file size (chunked
+// download is only used when known), and digest the expected SHA256 hex digest
+// (verification is skipped when empty). onProgress receives the downloaded bytes.
+func (d *chunkedDownloader) download(ctx context.Context, url, dstPath string, size int64, digest string, onProgress func(downloaded int64)) error {
+	if onProgress == nil {
+		onProgress = func(int64) {}
+	}
+
+	err := errRangeNotSupported
+	if size > 0 {
+		err = d.downloadChunked(ctx, url, dstPath, size, onProgress)
+	}
+	if errors.Is(err, errRangeNotSupported) {
+		d.logger.Debugf("Ranged download not available for %s, downloading as a single stream", url)
+		err = d.downloadStream(ctx, url, dstPath, onProgress)
+	}
+	if err != nil {
+		return err
+	}
+
+	if digest != "" {
+		if err := verifyFileDigest(dstPath, digest); err != nil {
+			// Corrupted data can't be resumed, start from zero on the next attempt.
+			os.Remove(dstPath)
+			os.Remove(dstPath + downloadStateSuffix)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *chunkedDownloader) downloadChunked(ctx context.Context, url, dstPath string, size int64, onProgress func(int64)) error {
+	state := d.loadState(dstPath, url, size)
+
+	// A fresh download checks first if the server supports ranges.
+	resuming := false
+	for _, done := range state.Done {
+		resuming = resuming || done
+	}
+	if !resuming {
+		if err := d.probeRanges(ctx, url); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(dstPath, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("creating file %s: %w", dstPath, err)
+	}
+	defer f.Close()
+
+	if err := f.Truncate(size); err != nil {
+		return fmt.Errorf("allocating file %s: %w", dstPath, err)
+	}
+
+	// Account already downloaded chunks.
+	var downloaded atomic.Int64
+	pending := make(chan int, len(state.Done))
+	for i, done := range state.Done {
+		if done {
+			downloaded.Add(chunkLength(i, state.ChunkSize, size))
+			continue
+		}
+		pending <- i
+	}
+	close(pending)
+	if resuming {
+		d.logger.Infof("Resuming download of %s (%d/%d bytes)", dstPath, downloaded.Load(), size)
+	}
+	onProgress(downloaded.Load())
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		stateMu  sync.Mutex
+		errOnce  sync.Once
+		firstErr error
+	)
+	for range d.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range pending {
+				start := int64(i) * state.ChunkSize
+				length := chunkLength(i, state.ChunkSize, size)
+				err := d.downloadChunk(ctx, url, f, start, length, func(n int64) {
+					onProgress(downloaded.Add(n))
+				})
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					return
+				}
+
+				stateMu.Lock()
+				state.Done[i] = true
+				err = saveState(dstPath, state)
+				stateMu.Unlock()
+				if err != nil {
+					d.logger.Warningf("Could not save download state: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("syncing file %s: %w", dstPath, err)
+	}
+	os.Remove(dstPath + downloadStateSuffix)
+
+	return nil
+}
+
+func (d *chunkedDownloader) probeRanges(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Range", "bytes=0-0")
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		return nil
+	case http.StatusOK:
+		return errRangeNotSupported
+	default:
+		return fmt.Errorf("HTTP %d from %s", resp.StatusCode, url)
+	}
+}
+
+func (d *chunkedDownloader) downloadChunk(ctx context.Context, url string, f *os.File, start, length int64, onWrite func(n int64)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, start+length-1))
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("HTTP %d from %s (range %d-%d)", resp.StatusCode, url, start, start+length-1)
+	}
+
+	w := &callbackWriter{w: io.NewOffsetWriter(f, start), onWrite: onWrite}
+	n, err := io.Copy(w, d.limiter.reader(ctx, io.LimitReader(resp.Body, length)))
+	if err != nil {
+		return fmt.Errorf("downloading range %d-%d: %w", start, start+length-1, err)
+	}
+	if n != length {
+		return fmt.Errorf("short read on range %d-%d: got %d bytes", start, start+length-1, n)
+	}
+
+	return nil
+}
+
+func (d *chunkedDownloader) downloadStream(ctx context.Context, url, dstPath string, onProgress func(int64)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d from %s", resp.StatusCode, url)
+	}
+
+	f, err := os.Create(dstPath)
+	if err != nil {
+		return fmt.Errorf("creating file %s: %w", dstPath, err)
+	}
+	defer f.Close()
+
+	var downloaded int64
+	w := &callbackWriter{w: f, onWrite: func(n int64) {
+		downloaded += n
+		onProgress(downloaded)
+	}}
+	if _, err := io.Copy(w, d.limiter.reader(ctx, resp.Body)); err != nil {
+		os.Remove(dstPath)
+		return fmt.Errorf("writing file %s: %w", dstPath, err)
+	}
+
+	return nil
+}
+
+// loadState returns the saved download state for dstPath if it matches the current
+// download, otherwise a fresh state.
+func (d *chunkedDownloader) loadState(dstPath, url string, size int64) *downloadState {
+	fresh := &downloadState{
+		URL:       url,
+		Size:      size,
+		ChunkSize: d.chunkSize,
+		Done:      make([]bool, (size+d.chunkSize-1)/d.chunkSize),
+	}
+
+	data, err := os.ReadFile(dstPath + downloadStateSuffix)
+	if err != nil {
+		return fresh
+	}
+
+	var state downloadState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fresh
+	}
+	if state.URL != url || state.Size != size || state.ChunkSize <= 0 || int64(len(state.Done)) != (size+state.ChunkSize-1)/state.ChunkSize {
+		return fresh
+	}
+
+	return &state
+}
+
+func saveState(dstPath string, state *downloadState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	tmp := dstPath + downloadStateSuffix + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dstPath+downloadStateSuffix)
+}
+
+func chunkLength(i int, chunkSize, size int64) int64 {
+	start := int64(i) * chunkSize
+	return min(chunkSize, size-start)
+}
+
+// verifyFileDigest checks the SHA256 digest of a file.
+func verifyFileDigest(path, digest string) error {
+	got, err := fileDigest(path)
+	if err != nil {
+		return err
+	}
+
+	want := strings.TrimPrefix(strings.ToLower(digest), "sha256:")
+	if got != want {
+		return fmt.Errorf("checksum mismatch for %s: expected sha256 %s, got %s", path, want, got)
+	}
+
+	return nil
+}
+
+// fileDigest returns the SHA256 hex digest of a file.
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hashing %s: %w", path, err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// callbackWriter calls onWrite with the number of bytes of each write.
+type callbackWriter struct {
+	w       io.Writer
+	onWrite func(n int64)
+}
+
+func (c *callbackWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	if n > 0 {
+		c.onWrite(int64(n))
+	}
+	return n, err
+}
+
+// rateLimiter limits the throughput of all the readers it wraps to a shared
+// bytes per second rate. A nil limiter doesn't limit.
+type rateLimiter struct {
+	bytesPerSec int64
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func newRateLimiter(bytesPerSec int64) *rateLimiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return &rateLimiter{bytesPerSec: bytesPerSec}
+}
+
+// wait blocks until n bytes can be consumed without exceeding the rate.
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	at := l.next
+	l.next = l.next.Add(time.Duration(float64(n) / float64(l.bytesPerSec) * float64(time.Second)))
+	l.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func (l *rateLimiter) reader(ctx context.Context, r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &limitedReader{ctx: ctx, r: r, limiter: l}
+}
+
+type limitedReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *rateLimiter
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	// Small reads keep the rate smooth.
+	if int64(len(p)) > l.limiter.bytesPerSec {
+		p = p[:max(1, l.limiter.bytesPerSec)]
+	}
+
+	n, err := l.r.Read(p)
+	if n > 0 {
+		if werr := l.limiter.wait(l.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
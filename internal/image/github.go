@@ -11,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/slok/sbx/internal/log"
@@ -27,6 +28,9 @@ const (
 	defaultGitHubDownloadBase   = "https://github.com"
 	defaultFirecrackerRepoOwner = "firecracker-microvm"
 	defaultFirecrackerRepoName  = "firecracker"
+
+	// partialDir is the images dir subdirectory holding the partially downloaded images.
+	partialDir = ".partial"
 )
 
 // GitHubImagePullerConfig configures the GitHub-backed image puller.
@@ -112,6 +116,9 @@ type manifestJSON struct {
 	FC            firecrackerJSON              `json:"firecracker"`
 	Build         buildJSON                    `json:"build"`
 	Snapshot      *snapshotInfoJSON            `json:"snapshot,omitempty"`
+	Local         *localInfoJSON               `json:"local,omitempty"`
+	Customized    *customizedInfoJSON          `json:"customized,omitempty"`
+	Profiles      []kernelProfileJSON          `json:"profiles,omitempty"`
 }
 
 type archArtifactsJSON struct {
@@ -124,6 +131,7 @@ type kernelJSON struct {
 	Version   string `json:"version"`
 	Source    string `json:"source"`
 	SizeBytes int64  `json:"size_bytes"`
+	SHA256    string `json:"sha256,omitempty"`
 }
 
 type rootfsJSON struct {
@@ -132,6 +140,7 @@ type rootfsJSON struct {
 	DistroVersion string `json:"distro_version"`
 	Profile       string `json:"profile"`
 	SizeBytes     int64  `json:"size_bytes"`
+	SHA256        string `json:"sha256,omitempty"`
 }
 
 type firecrackerJSON struct {
@@ -149,9 +158,45 @@ type snapshotInfoJSON struct {
 	SourceSandboxName string `json:"source_sandbox_name"`
 	SourceImage       string `json:"source_image"`
 	ParentSnapshot    string `json:"parent_snapshot"`
+	MemoryFile        string `json:"memory_file,omitempty"`
+	VMStateFile       string `json:"vmstate_file,omitempty"`
 	CreatedAt         string `json:"created_at"`
 }
 
+type localInfoJSON struct {
+	KernelSource string `json:"kernel_source"`
+	RootFSSource string `json:"rootfs_source"`
+	AddedAt      string `json:"added_at"`
+}
+
+type customizedInfoJSON struct {
+	BaseImage string   `json:"base_image"`
+	Commands  []string `json:"commands,omitempty"`
+	Files     []string `json:"files,omitempty"`
+	Digest    string   `json:"digest"`
+	CreatedAt string   `json:"created_at"`
+}
+
+type kernelProfileJSON struct {
+	Name           string   `json:"name"`
+	Description    string   `json:"description,omitempty"`
+	KernelArgs     []string `json:"kernel_args,omitempty"`
+	DisableConsole bool     `json:"disable_console,omitempty"`
+}
+
+func kernelProfilesJSON(profiles []model.KernelProfile) []kernelProfileJSON {
+	res := make([]kernelProfileJSON, 0, len(profiles))
+	for _, p := range profiles {
+		res = append(res, kernelProfileJSON{
+			Name:           p.Name,
+			Description:    p.Description,
+			KernelArgs:     p.KernelArgs,
+			DisableConsole: p.DisableConsole,
+		})
+	}
+	return res
+}
+
 func (m *manifestJSON) toModel() *model.ImageManifest {
 	artifacts := make(map[string]model.ArchArtifacts, len(m.Artifacts))
 	for arch, a := range m.Artifacts {
@@ -161,6 +206,7 @@ func (m *manifestJSON) toModel() *model.ImageManifest {
 				Version:   a.Kernel.Version,
 				Source:    a.Kernel.Source,
 				SizeBytes: a.Kernel.SizeBytes,
+				SHA256:    a.Kernel.SHA256,
 			},
 			Rootfs: model.RootfsInfo{
 				File:          a.Rootfs.File,
@@ -168,6 +214,7 @@ func (m *manifestJSON) toModel() *model.ImageManifest {
 				DistroVersion: a.Rootfs.DistroVersion,
 				Profile:       a.Rootfs.Profile,
 				SizeBytes:     a.Rootfs.SizeBytes,
+				SHA256:        a.Rootfs.SHA256,
 			},
 		}
 	}
@@ -193,10 +240,41 @@ func (m *manifestJSON) toModel() *model.ImageManifest {
 			SourceSandboxName: m.Snapshot.SourceSandboxName,
 			SourceImage:       m.Snapshot.SourceImage,
 			ParentSnapshot:    m.Snapshot.ParentSnapshot,
+			MemoryFile:        m.Snapshot.MemoryFile,
+			VMStateFile:       m.Snapshot.VMStateFile,
 			CreatedAt:         createdAt,
 		}
 	}
 
+	if m.Local != nil {
+		addedAt, _ := time.Parse(time.RFC3339, m.Local.AddedAt)
+		manifest.Local = &model.LocalImageInfo{
+			KernelSource: m.Local.KernelSource,
+			RootFSSource: m.Local.RootFSSource,
+			AddedAt:      addedAt,
+		}
+	}
+
+	if m.Customized != nil {
+		createdAt, _ := time.Parse(time.RFC3339, m.Customized.CreatedAt)
+		manifest.Customized = &model.CustomizedImageInfo{
+			BaseImage: m.Customized.BaseImage,
+			Commands:  m.Customized.Commands,
+			Files:     m.Customized.Files,
+			Digest:    m.Customized.Digest,
+			CreatedAt: createdAt,
+		}
+	}
+
+	for _, p := range m.Profiles {
+		manifest.Profiles = append(manifest.Profiles, model.KernelProfile{
+			Name:           p.Name,
+			Description:    p.Description,
+			KernelArgs:     p.KernelArgs,
+			DisableConsole: p.DisableConsole,
+		})
+	}
+
 	return manifest
 }
 
@@ -223,16 +301,21 @@ func (g *GitHubImagePuller) ListRemote(ctx context.Context) ([]model.ImageReleas
 func (g *GitHubImagePuller) Pull(ctx context.Context, version string, opts PullOptions) (*PullResult, error) {
 	arch := HostArch()
 
-	// Check if already installed (directory exists with a valid manifest).
+	// Check if already installed (directory exists with a valid manifest). Images
+	// installed without the host architecture artifacts (e.g. an images directory
+	// copied from a host of another architecture) are pulled again.
 	if !opts.Force {
-		if _, err := readLocalManifest(g.imagesDir, version); err == nil {
-			return &PullResult{
-				Version:         version,
-				Skipped:         true,
-				KernelPath:      filepath.Join(g.imagesDir, version, fmt.Sprintf("vmlinux-%s", arch)),
-				RootFSPath:      filepath.Join(g.imagesDir, version, fmt.Sprintf("rootfs-%s.ext4", arch)),
-				FirecrackerPath: filepath.Join(g.imagesDir, version, "firecracker"),
-			}, nil
+		if mj, err := readLocalManifest(g.imagesDir, version); err == nil {
+			if _, ok := mj.Artifacts[arch]; ok {
+				return &PullResult{
+					Version:         version,
+					Skipped:         true,
+					KernelPath:      filepath.Join(g.imagesDir, version, fmt.Sprintf("vmlinux-%s", arch)),
+					RootFSPath:      filepath.Join(g.imagesDir, version, fmt.Sprintf("rootfs-%s.ext4", arch)),
+					FirecrackerPath: filepath.Join(g.imagesDir, version, "firecracker"),
+				}, nil
+			}
+			g.logger.Infof("Image %s is installed without %s artifacts, pulling it again", version, arch)
 		}
 	}
 
@@ -242,60 +325,84 @@ func (g *GitHubImagePuller) Pull(ctx context.Context, version string, opts PullO
 		return nil, fmt.Errorf("getting manifest: %w", err)
 	}
 
-	archArtifacts, ok := manifest.Artifacts[arch]
-	if !ok {
-		return nil, fmt.Errorf("no artifacts for architecture %q in release %s", arch, version)
+	archArtifacts, err := manifest.ArtifactsFor(arch)
+	if err != nil {
+		return nil, fmt.Errorf("release %s doesn't support this host: %w", version, err)
 	}
 
-	// Create version directory.
-	versionDir := filepath.Join(g.imagesDir, version)
-	if err := os.MkdirAll(versionDir, 0o755); err != nil {
-		return nil, fmt.Errorf("creating version directory: %w", err)
+	// Download into a staging directory that is kept on failure, so an interrupted
+	// pull resumes the already downloaded chunks on the next attempt.
+	opts.defaults()
+	stagingDir := filepath.Join(g.imagesDir, partialDir, version)
+	if err := os.MkdirAll(stagingDir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating staging directory: %w", err)
 	}
 
-	// Cleanup on error.
-	success := false
-	defer func() {
-		if !success {
-			os.RemoveAll(versionDir)
-		}
-	}()
+	dl := &chunkedDownloader{
+		httpClient:  g.httpClient,
+		concurrency: opts.Concurrency,
+		chunkSize:   opts.ChunkSize,
+		limiter:     newRateLimiter(opts.BandwidthLimit),
+		logger:      g.logger,
+	}
 
 	// Download kernel.
 	kernelURL := fmt.Sprintf("%s/%s/releases/download/%s/%s", g.downloadBaseURL, g.repo, version, archArtifacts.Kernel.File)
-	kernelPath := filepath.Join(versionDir, archArtifacts.Kernel.File)
 	g.logger.Infof("Downloading kernel: %s", archArtifacts.Kernel.File)
-	if err := g.downloadFile(ctx, kernelURL, kernelPath, archArtifacts.Kernel.SizeBytes, opts.StatusWriter); err != nil {
+	err = g.downloadArtifact(ctx, dl, "kernel", kernelURL, filepath.Join(stagingDir, archArtifacts.Kernel.File), archArtifacts.Kernel.SizeBytes, archArtifacts.Kernel.SHA256, opts)
+	if err != nil {
 		return nil, fmt.Errorf("downloading kernel: %w", err)
 	}
 
 	// Download rootfs.
 	rootfsURL := fmt.Sprintf("%s/%s/releases/download/%s/%s", g.downloadBaseURL, g.repo, version, archArtifacts.Rootfs.File)
-	rootfsPath := filepath.Join(versionDir, archArtifacts.Rootfs.File)
 	g.logger.Infof("Downloading rootfs: %s", archArtifacts.Rootfs.File)
-	if err := g.downloadFile(ctx, rootfsURL, rootfsPath, archArtifacts.Rootfs.SizeBytes, opts.StatusWriter); err != nil {
+	err = g.downloadArtifact(ctx, dl, "rootfs", rootfsURL, filepath.Join(stagingDir, archArtifacts.Rootfs.File), archArtifacts.Rootfs.SizeBytes, archArtifacts.Rootfs.SHA256, opts)
+	if err != nil {
 		return nil, fmt.Errorf("downloading rootfs: %w", err)
 	}
 
 	// Download and extract firecracker binary.
-	fcPath := filepath.Join(versionDir, "firecracker")
 	g.logger.Infof("Downloading Firecracker %s", manifest.Firecracker.Version)
-	if err := g.downloadFirecracker(ctx, manifest.Firecracker.Version, arch, fcPath, opts.StatusWriter); err != nil {
+	if err := g.downloadFirecracker(ctx, dl, manifest.Firecracker.Version, arch, filepath.Join(stagingDir, "firecracker"), opts); err != nil {
 		return nil, fmt.Errorf("downloading firecracker: %w", err)
 	}
 
 	// Write manifest locally so LocalImageManager can read it.
-	if err := os.WriteFile(filepath.Join(versionDir, "manifest.json"), manifestRaw, 0o644); err != nil {
+	if err := os.WriteFile(filepath.Join(stagingDir, "manifest.json"), manifestRaw, 0o644); err != nil {
 		return nil, fmt.Errorf("writing local manifest: %w", err)
 	}
 
-	success = true
+	// Install the image (replacing the previous one when forced).
+	versionDir := filepath.Join(g.imagesDir, version)
+	if err := os.RemoveAll(versionDir); err != nil {
+		return nil, fmt.Errorf("removing previous image: %w", err)
+	}
+	if err := os.Rename(stagingDir, versionDir); err != nil {
+		return nil, fmt.Errorf("installing image: %w", err)
+	}
+
+	// Deduplicate the artifacts shared with other images.
+	blobs := map[string]string{
+		archArtifacts.Kernel.File: archArtifacts.Kernel.SHA256,
+		archArtifacts.Rootfs.File: archArtifacts.Rootfs.SHA256,
+		"firecracker":             "",
+	}
+	for file, digest := range blobs {
+		if err := storeBlob(g.imagesDir, filepath.Join(versionDir, file), digest, g.logger); err != nil {
+			g.logger.Warningf("Could not deduplicate %s: %v", file, err)
+		}
+	}
+	if err := gcBlobs(g.imagesDir, g.logger); err != nil {
+		g.logger.Warningf("Could not remove unused blobs: %v", err)
+	}
+
 	return &PullResult{
 		Version:         version,
 		Skipped:         false,
-		KernelPath:      kernelPath,
-		RootFSPath:      rootfsPath,
-		FirecrackerPath: fcPath,
+		KernelPath:      filepath.Join(versionDir, archArtifacts.Kernel.File),
+		RootFSPath:      filepath.Join(versionDir, archArtifacts.Rootfs.File),
+		FirecrackerPath: filepath.Join(versionDir, "firecracker"),
 	}, nil
 }
 
@@ -373,44 +480,27 @@ func (g *GitHubImagePuller) httpGet(ctx context.Context, url string) ([]byte, er
 	return io.ReadAll(resp.Body)
 }
 
-func (g *GitHubImagePuller) downloadFile(ctx context.Context, url, dstPath string, totalSize int64, statusWriter io.Writer) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return fmt.Errorf("creating request: %w", err)
-	}
-
-	resp, err := g.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("executing request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("HTTP %d from %s", resp.StatusCode, url)
-	}
-
-	f, err := os.Create(dstPath)
-	if err != nil {
-		return fmt.Errorf("creating file %s: %w", dstPath, err)
-	}
-	defer f.Close()
-
-	var dst io.Writer = f
-	if statusWriter != nil {
-		pw := NewProgressWriter(f, statusWriter, totalSize)
+// downloadArtifact downloads an image artifact reporting the progress to the pull
+// options status writer and progress callback.
+func (g *GitHubImagePuller) downloadArtifact(ctx context.Context, dl *chunkedDownloader, artifact, url, dstPath string, size int64, digest string, opts PullOptions) error {
+	var pw *ProgressWriter
+	if opts.StatusWriter != nil {
+		pw = NewProgressWriter(io.Discard, opts.StatusWriter, size)
 		defer pw.Finish()
-		dst = pw
-	}
-
-	if _, err := io.Copy(dst, resp.Body); err != nil {
-		os.Remove(dstPath)
-		return fmt.Errorf("writing file %s: %w", dstPath, err)
 	}
 
-	return nil
+	var last int64
+	return dl.download(ctx, url, dstPath, size, digest, func(downloaded int64) {
+		if pw != nil {
+			pw.Add(downloaded - atomic.SwapInt64(&last, downloaded))
+		}
+		if opts.OnProgress != nil {
+			opts.OnProgress(PullProgress{Artifact: artifact, Downloaded: downloaded, Total: size})
+		}
+	})
 }
 
-func (g *GitHubImagePuller) downloadFirecracker(ctx context.Context, fcVersion, arch, dstPath string, statusWriter io.Writer) error {
+func (g *GitHubImagePuller) downloadFirecracker(ctx context.Context, dl *chunkedDownloader, fcVersion, arch, dstPath string, opts PullOptions) error {
 	// Download tgz from upstream Firecracker releases.
 	tgzURL := fmt.Sprintf("%s/%s/%s/releases/download/%s/firecracker-%s-%s.tgz",
 		g.downloadBaseURL, defaultFirecrackerRepoOwner, defaultFirecrackerRepoName, fcVersion, fcVersion, arch)
@@ -430,12 +520,19 @@ func (g *GitHubImagePuller) downloadFirecracker(ctx context.Context, fcVersion,
 		return fmt.Errorf("HTTP %d from %s", resp.StatusCode, tgzURL)
 	}
 
-	var reader io.Reader = resp.Body
-	if statusWriter != nil {
-		pw := NewProgressWriter(io.Discard, statusWriter, resp.ContentLength)
-		reader = io.TeeReader(resp.Body, pw)
+	var progress io.Writer = io.Discard
+	if opts.StatusWriter != nil {
+		pw := NewProgressWriter(io.Discard, opts.StatusWriter, resp.ContentLength)
 		defer pw.Finish()
+		progress = pw
 	}
+	var downloaded int64
+	reader := io.TeeReader(dl.limiter.reader(ctx, resp.Body), &callbackWriter{w: progress, onWrite: func(n int64) {
+		downloaded += n
+		if opts.OnProgress != nil {
+			opts.OnProgress(PullProgress{Artifact: "firecracker", Downloaded: downloaded, Total: max(resp.ContentLength, 0)})
+		}
+	}})
 
 	// Extract the firecracker binary from the tgz.
 	// Expected path: release-{version}-{arch}/firecracker-{version}-{arch}
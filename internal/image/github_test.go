@@ -5,17 +5,23 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/model"
 )
 
 // newTestPuller creates a GitHubImagePuller backed by httptest servers.
@@ -144,13 +150,189 @@ func TestGitHubImagePullerPullSkipsIfInstalled(t *testing.T) {
 	p, imagesDir := newTestPuller(t, http.NotFoundHandler(), http.NotFoundHandler())
 
 	// Pre-create the version directory with a valid manifest.
-	writeTestManifest(t, imagesDir, "v0.1.0", false)
+	writeTestManifestArch(t, imagesDir, "v0.1.0", image.HostArch())
 
 	result, err := p.Pull(context.Background(), "v0.1.0", image.PullOptions{})
 	require.NoError(t, err)
 	assert.True(t, result.Skipped)
 }
 
+func TestGitHubImagePullerPullInstalledWithoutHostArch(t *testing.T) {
+	p, imagesDir := newTestPuller(t, http.NotFoundHandler(), http.NotFoundHandler())
+
+	// An image installed for another architecture should be pulled again.
+	writeTestManifestArch(t, imagesDir, "v0.1.0", "riscv64")
+
+	_, err := p.Pull(context.Background(), "v0.1.0", image.PullOptions{})
+	assert.Error(t, err)
+}
+
+func TestGitHubImagePullerPullWithoutHostArch(t *testing.T) {
+	manifest := map[string]any{
+		"schema_version": 1,
+		"version":        "v0.1.0",
+		"artifacts": map[string]any{
+			"riscv64": map[string]any{
+				"kernel": map[string]any{"file": "vmlinux-riscv64"},
+				"rootfs": map[string]any{"file": "rootfs-riscv64.ext4"},
+			},
+		},
+	}
+	downloadHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/test/images/releases/download/v0.1.0/manifest.json" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(manifest)
+	})
+
+	p, imagesDir := newTestPuller(t, http.NotFoundHandler(), downloadHandler)
+
+	_, err := p.Pull(context.Background(), "v0.1.0", image.PullOptions{})
+	assert.ErrorIs(t, err, model.ErrNotValid)
+	_, err = os.Stat(filepath.Join(imagesDir, "v0.1.0"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestGitHubImagePullerPullChunked(t *testing.T) {
+	kernelData := bytes.Repeat([]byte("k"), 1000)
+	rootfsData := bytes.Repeat([]byte("0123456789"), 1000)
+	fcTgz := buildFakeFCTgz(t, "v1.14.1", "x86_64", []byte("fake-firecracker-binary"))
+
+	digest := func(data []byte) string {
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:])
+	}
+
+	tests := map[string]struct {
+		rootfsDigest string
+		setup        func(t *testing.T, imagesDir, rootfsURL string)
+		opts         image.PullOptions
+		expErr       bool
+		expRequests  int // Rootfs ranged requests (0 skips the check).
+	}{
+		"Pulling should download artifacts in ranged chunks.": {
+			rootfsDigest: digest(rootfsData),
+			opts:         image.PullOptions{ChunkSize: 1000, Concurrency: 3},
+			expRequests:  11, // Range probe + 10 chunks.
+		},
+
+		"Pulling after an interrupted pull should only download the missing chunks.": {
+			rootfsDigest: digest(rootfsData),
+			setup: func(t *testing.T, imagesDir, rootfsURL string) {
+				dir := filepath.Join(imagesDir, ".partial", "v0.1.0")
+				require.NoError(t, os.MkdirAll(dir, 0o755))
+
+				// First 8 chunks already downloaded.
+				partial := make([]byte, len(rootfsData))
+				copy(partial, rootfsData[:8000])
+				require.NoError(t, os.WriteFile(filepath.Join(dir, "rootfs-x86_64.ext4"), partial, 0o644))
+				state := map[string]any{
+					"url":        rootfsURL,
+					"size":       len(rootfsData),
+					"chunk_size": 1000,
+					"done":       []bool{true, true, true, true, true, true, true, true, false, false},
+				}
+				data, err := json.Marshal(state)
+				require.NoError(t, err)
+				require.NoError(t, os.WriteFile(filepath.Join(dir, "rootfs-x86_64.ext4.state"), data, 0o644))
+			},
+			opts:        image.PullOptions{ChunkSize: 1000, Concurrency: 2},
+			expRequests: 2,
+		},
+
+		"A checksum mismatch should fail the pull.": {
+			rootfsDigest: digest([]byte("other")),
+			opts:         image.PullOptions{ChunkSize: 1000},
+			expErr:       true,
+		},
+
+		"Pulling with a bandwidth limit should download the artifacts.": {
+			rootfsDigest: digest(rootfsData),
+			opts:         image.PullOptions{ChunkSize: 4000, BandwidthLimit: 1 << 20},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var rootfsRequests atomic.Int64
+
+			manifest := map[string]any{
+				"schema_version": 1,
+				"version":        "v0.1.0",
+				"artifacts": map[string]any{
+					"x86_64": map[string]any{
+						"kernel": map[string]any{"file": "vmlinux-x86_64", "size_bytes": len(kernelData), "sha256": digest(kernelData)},
+						"rootfs": map[string]any{"file": "rootfs-x86_64.ext4", "size_bytes": len(rootfsData), "sha256": tc.rootfsDigest},
+					},
+				},
+				"firecracker": map[string]any{"version": "v1.14.1"},
+			}
+
+			downloadHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/test/images/releases/download/v0.1.0/manifest.json":
+					_ = json.NewEncoder(w).Encode(manifest)
+				case "/test/images/releases/download/v0.1.0/vmlinux-x86_64":
+					http.ServeContent(w, r, "kernel", time.Time{}, bytes.NewReader(kernelData))
+				case "/test/images/releases/download/v0.1.0/rootfs-x86_64.ext4":
+					rootfsRequests.Add(1)
+					http.ServeContent(w, r, "rootfs", time.Time{}, bytes.NewReader(rootfsData))
+				case "/firecracker-microvm/firecracker/releases/download/v1.14.1/firecracker-v1.14.1-x86_64.tgz":
+					_, _ = w.Write(fcTgz)
+				default:
+					http.NotFound(w, r)
+				}
+			})
+
+			downloadServer := httptest.NewServer(downloadHandler)
+			t.Cleanup(downloadServer.Close)
+			serverURL := downloadServer.URL
+
+			imagesDir := t.TempDir()
+			p, err := image.NewGitHubImagePullerWithBaseURL(image.GitHubImagePullerConfig{
+				Repo:      "test/images",
+				ImagesDir: imagesDir,
+			}, serverURL, serverURL)
+			require.NoError(t, err)
+
+			if tc.setup != nil {
+				tc.setup(t, imagesDir, serverURL+"/test/images/releases/download/v0.1.0/rootfs-x86_64.ext4")
+			}
+
+			var lastRootfs image.PullProgress
+			var mu sync.Mutex
+			tc.opts.OnProgress = func(p image.PullProgress) {
+				mu.Lock()
+				defer mu.Unlock()
+				if p.Artifact == "rootfs" {
+					lastRootfs = p
+				}
+			}
+
+			_, err = p.Pull(context.Background(), "v0.1.0", tc.opts)
+			if tc.expErr {
+				require.Error(t, err)
+				assert.NoDirExists(t, filepath.Join(imagesDir, "v0.1.0"))
+				return
+			}
+			require.NoError(t, err)
+
+			gotRootfs, err := os.ReadFile(filepath.Join(imagesDir, "v0.1.0", "rootfs-x86_64.ext4"))
+			require.NoError(t, err)
+			assert.Equal(t, rootfsData, gotRootfs)
+			assert.NoFileExists(t, filepath.Join(imagesDir, "v0.1.0", "rootfs-x86_64.ext4.state"))
+			assert.NoDirExists(t, filepath.Join(imagesDir, ".partial", "v0.1.0"))
+
+			assert.Equal(t, int64(len(rootfsData)), lastRootfs.Downloaded)
+			assert.Equal(t, int64(len(rootfsData)), lastRootfs.Total)
+			if tc.expRequests > 0 {
+				assert.Equal(t, int64(tc.expRequests), rootfsRequests.Load())
+			}
+		})
+	}
+}
+
 // buildFakeFCTgz creates a gzipped tar archive with a fake firecracker binary.
 func buildFakeFCTgz(t *testing.T, version, arch string, binaryData []byte) []byte {
 	t.Helper()
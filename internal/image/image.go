@@ -25,6 +25,8 @@ type ImageManager interface {
 	RootFSPath(name string) string
 	// FirecrackerPath returns the local firecracker binary path for an installed image.
 	FirecrackerPath(name string) string
+	// DiskUsage returns the real disk usage of the installed images.
+	DiskUsage(ctx context.Context) (*model.ImageDiskUsage, error)
 }
 
 // ImagePuller downloads remote images to local storage.
@@ -41,12 +43,84 @@ type SnapshotCreator interface {
 	Create(ctx context.Context, opts CreateSnapshotOptions) error
 }
 
+// ImageAdder registers user built artifacts as installed images.
+type ImageAdder interface {
+	// Add installs an image from a local kernel and rootfs, generating its manifest.
+	Add(ctx context.Context, opts AddImageOptions) error
+}
+
+// RootFSBuilder builds bootable rootfs images from other image formats.
+type RootFSBuilder interface {
+	// BuildFromOCI converts an OCI/Docker image into a bootable ext4 rootfs.
+	BuildFromOCI(ctx context.Context, opts OCIBuildOptions) (*OCIBuildResult, error)
+}
+
+// ImageCustomizer derives images by applying a customization to the rootfs of
+// an installed image.
+type ImageCustomizer interface {
+	// Customize creates the derived image of a base image and customization, the
+	// derived image is reused when it already exists.
+	Customize(ctx context.Context, opts CustomizeOptions) (*CustomizeResult, error)
+}
+
+// ImageDiffer compares the rootfs files of installed images.
+type ImageDiffer interface {
+	// Diff returns the files changed from the from image to the to image.
+	Diff(ctx context.Context, from, to string) (*model.ImageDiff, error)
+}
+
+// ImageMover moves installed images between local images directories, used to
+// relocate the images and snapshots to other filesystems.
+type ImageMover interface {
+	// Move moves an installed image to another images directory and returns the
+	// directory it was in. The image is left in place when it's already in the
+	// directory or dryRun is set.
+	Move(ctx context.Context, name, toDir string, dryRun bool) (fromDir string, err error)
+}
+
+// ImageBundler exports and imports installed images as self-contained archives,
+// used to move images to offline hosts.
+type ImageBundler interface {
+	// Export writes the bundle of an installed image.
+	Export(ctx context.Context, name string, w io.Writer) error
+	// Import installs the image of a bundle and returns its name.
+	Import(ctx context.Context, r io.Reader, opts ImportOptions) (string, error)
+}
+
+// SnapshotStore stores snapshot image bundles (see ImageBundler) in a remote object
+// storage, so the snapshots are shared by the hosts.
+type SnapshotStore interface {
+	// Upload streams the bundle of the name snapshot to the store.
+	Upload(ctx context.Context, name string, r io.Reader) (*model.RemoteSnapshot, error)
+	// Download streams the bundle of the name snapshot from the store, returns
+	// model.ErrNotFound if it's not stored.
+	Download(ctx context.Context, name string, w io.Writer) error
+}
+
 // PullOptions configures the pull operation.
 type PullOptions struct {
 	// Force re-downloads even if already installed.
 	Force bool
 	// StatusWriter receives progress output during downloads.
 	StatusWriter io.Writer
+	// OnProgress is called with the download progress of each artifact (optional).
+	OnProgress func(PullProgress)
+	// Concurrency is the number of parallel ranged chunk downloads per artifact
+	// (default: DefaultPullConcurrency).
+	Concurrency int
+	// ChunkSize is the size of each ranged chunk (default: DefaultPullChunkSize).
+	ChunkSize int64
+	// BandwidthLimit limits the download rate in bytes per second (0 is unlimited).
+	BandwidthLimit int64
+}
+
+func (o *PullOptions) defaults() {
+	if o.Concurrency <= 0 {
+		o.Concurrency = DefaultPullConcurrency
+	}
+	if o.ChunkSize <= 0 {
+		o.ChunkSize = DefaultPullChunkSize
+	}
 }
 
 // PullResult contains the result of a pull operation.
@@ -56,6 +130,81 @@ type PullResult struct {
 	KernelPath      string
 	RootFSPath      string
 	FirecrackerPath string
+	// Customized is the result of the customization applied after the pull (nil
+	// when there is no customization).
+	Customized *CustomizeResult
+}
+
+// ImportOptions configures the bundle import operation.
+type ImportOptions struct {
+	// Force replaces the image if already installed.
+	Force bool
+}
+
+// AddImageOptions configures the local image add operation.
+type AddImageOptions struct {
+	// Name is the image name.
+	Name string
+	// KernelSrc is the path to the kernel binary.
+	KernelSrc string
+	// RootFSSrc is the path to the rootfs ext4 image.
+	RootFSSrc string
+	// FirecrackerSrc is the path to the firecracker binary (optional, copied if set).
+	FirecrackerSrc string
+	// KernelVersion is the kernel version recorded in the manifest (optional).
+	KernelVersion string
+	// Distro is the rootfs distribution recorded in the manifest (optional).
+	Distro string
+	// DistroVersion is the rootfs distribution version recorded in the manifest (optional).
+	DistroVersion string
+	// Profile is the rootfs profile recorded in the manifest (optional).
+	Profile string
+	// Force replaces the image if already installed.
+	Force bool
+}
+
+// CustomizeOptions configures the image customization.
+type CustomizeOptions struct {
+	// BaseImage is the installed image the customization is applied to.
+	BaseImage string
+	// Customization are the changes applied to the base image rootfs.
+	Customization model.ImageCustomization
+	// Force recreates the derived image even if already installed.
+	Force bool
+}
+
+// CustomizeResult contains the result of a customization.
+type CustomizeResult struct {
+	// Name is the derived image name.
+	Name string
+	// Skipped is true if the derived image was already installed and Force was false.
+	Skipped bool
+}
+
+// OCIBuildOptions configures the rootfs build from an OCI image.
+type OCIBuildOptions struct {
+	// Source is an OCI image layout or docker save archive path, or an image
+	// reference exported with the docker or podman CLI.
+	Source string
+	// OutputPath is the path of the ext4 image created.
+	OutputPath string
+	// InitSrc is the path of the sbx guest init binary injected in the rootfs.
+	InitSrc string
+	// InitFromRootFS is an ext4 rootfs the sbx guest init is taken from when
+	// InitSrc is not set (e.g. an installed release rootfs).
+	InitFromRootFS string
+	// SizeMB is the ext4 image size (default: the image content with some room).
+	SizeMB int
+}
+
+// OCIBuildResult is the result of a rootfs build from an OCI image.
+type OCIBuildResult struct {
+	// Distro is the distribution ID of the image os-release (if any).
+	Distro string
+	// DistroVersion is the distribution version of the image os-release (if any).
+	DistroVersion string
+	// SizeBytes is the ext4 image size.
+	SizeBytes int64
 }
 
 // CreateSnapshotOptions configures snapshot image creation.
@@ -68,6 +217,11 @@ type CreateSnapshotOptions struct {
 	RootFSSrc string
 	// FirecrackerSrc is the path to the source firecracker binary (optional, copied if set).
 	FirecrackerSrc string
+	// MemorySrc is the path to the guest memory file of a live snapshot (optional).
+	MemorySrc string
+	// VMStateSrc is the path to the microVM state file of a live snapshot (required
+	// with MemorySrc).
+	VMStateSrc string
 	// SourceSandboxID is the ULID of the source sandbox.
 	SourceSandboxID string
 	// SourceSandboxName is the name of the source sandbox.
@@ -85,10 +239,23 @@ type CreateSnapshotOptions struct {
 func HostArch() string {
 	switch runtime.GOARCH {
 	case "amd64":
-		return "x86_64"
+		return model.ArchX86_64
 	case "arm64":
-		return "aarch64"
+		return model.ArchAarch64
 	default:
 		return runtime.GOARCH
 	}
 }
+
+// CheckHostArch checks that an installed image has the artifacts to boot on the
+// host architecture.
+func CheckHostArch(ctx context.Context, mgr ImageManager, name string) error {
+	manifest, err := mgr.GetManifest(ctx, name)
+	if err != nil {
+		return err
+	}
+	if _, err := manifest.ArtifactsFor(HostArch()); err != nil {
+		return err
+	}
+	return nil
+}
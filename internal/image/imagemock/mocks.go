@@ -6,6 +6,7 @@ package imagemock
 
 import (
 	"context"
+	"io"
 
 	"github.com/slok/sbx/internal/image"
 	"github.com/slok/sbx/internal/model"
@@ -39,6 +40,68 @@ func (_m *MockImageManager) EXPECT() *MockImageManager_Expecter {
 	return &MockImageManager_Expecter{mock: &_m.Mock}
 }
 
+// DiskUsage provides a mock function for the type MockImageManager
+func (_mock *MockImageManager) DiskUsage(ctx context.Context) (*model.ImageDiskUsage, error) {
+	ret := _mock.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DiskUsage")
+	}
+
+	var r0 *model.ImageDiskUsage
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context) (*model.ImageDiskUsage, error)); ok {
+		return returnFunc(ctx)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context) *model.ImageDiskUsage); ok {
+		r0 = returnFunc(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.ImageDiskUsage)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = returnFunc(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockImageManager_DiskUsage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DiskUsage'
+type MockImageManager_DiskUsage_Call struct {
+	*mock.Call
+}
+
+// DiskUsage is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockImageManager_Expecter) DiskUsage(ctx interface{}) *MockImageManager_DiskUsage_Call {
+	return &MockImageManager_DiskUsage_Call{Call: _e.mock.On("DiskUsage", ctx)}
+}
+
+func (_c *MockImageManager_DiskUsage_Call) Run(run func(ctx context.Context)) *MockImageManager_DiskUsage_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockImageManager_DiskUsage_Call) Return(imageDiskUsage *model.ImageDiskUsage, err error) *MockImageManager_DiskUsage_Call {
+	_c.Call.Return(imageDiskUsage, err)
+	return _c
+}
+
+func (_c *MockImageManager_DiskUsage_Call) RunAndReturn(run func(ctx context.Context) (*model.ImageDiskUsage, error)) *MockImageManager_DiskUsage_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Exists provides a mock function for the type MockImageManager
 func (_mock *MockImageManager) Exists(ctx context.Context, name string) (bool, error) {
 	ret := _mock.Called(ctx, name)
@@ -691,3 +754,809 @@ func (_c *MockSnapshotCreator_Create_Call) RunAndReturn(run func(ctx context.Con
 	_c.Call.Return(run)
 	return _c
 }
+
+// NewMockImageBundler creates a new instance of MockImageBundler. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockImageBundler(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockImageBundler {
+	mock := &MockImageBundler{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockImageBundler is an autogenerated mock type for the ImageBundler type
+type MockImageBundler struct {
+	mock.Mock
+}
+
+type MockImageBundler_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockImageBundler) EXPECT() *MockImageBundler_Expecter {
+	return &MockImageBundler_Expecter{mock: &_m.Mock}
+}
+
+// Export provides a mock function for the type MockImageBundler
+func (_mock *MockImageBundler) Export(ctx context.Context, name string, w io.Writer) error {
+	ret := _mock.Called(ctx, name, w)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Export")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, io.Writer) error); ok {
+		r0 = returnFunc(ctx, name, w)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockImageBundler_Export_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Export'
+type MockImageBundler_Export_Call struct {
+	*mock.Call
+}
+
+// Export is a helper method to define mock.On call
+//   - ctx context.Context
+//   - name string
+//   - w io.Writer
+func (_e *MockImageBundler_Expecter) Export(ctx interface{}, name interface{}, w interface{}) *MockImageBundler_Export_Call {
+	return &MockImageBundler_Export_Call{Call: _e.mock.On("Export", ctx, name, w)}
+}
+
+func (_c *MockImageBundler_Export_Call) Run(run func(ctx context.Context, name string, w io.Writer)) *MockImageBundler_Export_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 io.Writer
+		if args[2] != nil {
+			arg2 = args[2].(io.Writer)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockImageBundler_Export_Call) Return(err error) *MockImageBundler_Export_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockImageBundler_Export_Call) RunAndReturn(run func(ctx context.Context, name string, w io.Writer) error) *MockImageBundler_Export_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Import provides a mock function for the type MockImageBundler
+func (_mock *MockImageBundler) Import(ctx context.Context, r io.Reader, opts image.ImportOptions) (string, error) {
+	ret := _mock.Called(ctx, r, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Import")
+	}
+
+	var r0 string
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, io.Reader, image.ImportOptions) (string, error)); ok {
+		return returnFunc(ctx, r, opts)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, io.Reader, image.ImportOptions) string); ok {
+		r0 = returnFunc(ctx, r, opts)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, io.Reader, image.ImportOptions) error); ok {
+		r1 = returnFunc(ctx, r, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockImageBundler_Import_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Import'
+type MockImageBundler_Import_Call struct {
+	*mock.Call
+}
+
+// Import is a helper method to define mock.On call
+//   - ctx context.Context
+//   - r io.Reader
+//   - opts image.ImportOptions
+func (_e *MockImageBundler_Expecter) Import(ctx interface{}, r interface{}, opts interface{}) *MockImageBundler_Import_Call {
+	return &MockImageBundler_Import_Call{Call: _e.mock.On("Import", ctx, r, opts)}
+}
+
+func (_c *MockImageBundler_Import_Call) Run(run func(ctx context.Context, r io.Reader, opts image.ImportOptions)) *MockImageBundler_Import_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 io.Reader
+		if args[1] != nil {
+			arg1 = args[1].(io.Reader)
+		}
+		var arg2 image.ImportOptions
+		if args[2] != nil {
+			arg2 = args[2].(image.ImportOptions)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockImageBundler_Import_Call) Return(s string, err error) *MockImageBundler_Import_Call {
+	_c.Call.Return(s, err)
+	return _c
+}
+
+func (_c *MockImageBundler_Import_Call) RunAndReturn(run func(ctx context.Context, r io.Reader, opts image.ImportOptions) (string, error)) *MockImageBundler_Import_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockImageAdder creates a new instance of MockImageAdder. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockImageAdder(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockImageAdder {
+	mock := &MockImageAdder{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockImageAdder is an autogenerated mock type for the ImageAdder type
+type MockImageAdder struct {
+	mock.Mock
+}
+
+type MockImageAdder_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockImageAdder) EXPECT() *MockImageAdder_Expecter {
+	return &MockImageAdder_Expecter{mock: &_m.Mock}
+}
+
+// Add provides a mock function for the type MockImageAdder
+func (_mock *MockImageAdder) Add(ctx context.Context, opts image.AddImageOptions) error {
+	ret := _mock.Called(ctx, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Add")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, image.AddImageOptions) error); ok {
+		r0 = returnFunc(ctx, opts)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockImageAdder_Add_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Add'
+type MockImageAdder_Add_Call struct {
+	*mock.Call
+}
+
+// Add is a helper method to define mock.On call
+//   - ctx context.Context
+//   - opts image.AddImageOptions
+func (_e *MockImageAdder_Expecter) Add(ctx interface{}, opts interface{}) *MockImageAdder_Add_Call {
+	return &MockImageAdder_Add_Call{Call: _e.mock.On("Add", ctx, opts)}
+}
+
+func (_c *MockImageAdder_Add_Call) Run(run func(ctx context.Context, opts image.AddImageOptions)) *MockImageAdder_Add_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 image.AddImageOptions
+		if args[1] != nil {
+			arg1 = args[1].(image.AddImageOptions)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockImageAdder_Add_Call) Return(err error) *MockImageAdder_Add_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockImageAdder_Add_Call) RunAndReturn(run func(ctx context.Context, opts image.AddImageOptions) error) *MockImageAdder_Add_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockRootFSBuilder creates a new instance of MockRootFSBuilder. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockRootFSBuilder(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockRootFSBuilder {
+	mock := &MockRootFSBuilder{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockRootFSBuilder is an autogenerated mock type for the RootFSBuilder type
+type MockRootFSBuilder struct {
+	mock.Mock
+}
+
+type MockRootFSBuilder_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockRootFSBuilder) EXPECT() *MockRootFSBuilder_Expecter {
+	return &MockRootFSBuilder_Expecter{mock: &_m.Mock}
+}
+
+// BuildFromOCI provides a mock function for the type MockRootFSBuilder
+func (_mock *MockRootFSBuilder) BuildFromOCI(ctx context.Context, opts image.OCIBuildOptions) (*image.OCIBuildResult, error) {
+	ret := _mock.Called(ctx, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for BuildFromOCI")
+	}
+
+	var r0 *image.OCIBuildResult
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, image.OCIBuildOptions) (*image.OCIBuildResult, error)); ok {
+		return returnFunc(ctx, opts)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, image.OCIBuildOptions) *image.OCIBuildResult); ok {
+		r0 = returnFunc(ctx, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*image.OCIBuildResult)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, image.OCIBuildOptions) error); ok {
+		r1 = returnFunc(ctx, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockRootFSBuilder_BuildFromOCI_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'BuildFromOCI'
+type MockRootFSBuilder_BuildFromOCI_Call struct {
+	*mock.Call
+}
+
+// BuildFromOCI is a helper method to define mock.On call
+//   - ctx context.Context
+//   - opts image.OCIBuildOptions
+func (_e *MockRootFSBuilder_Expecter) BuildFromOCI(ctx interface{}, opts interface{}) *MockRootFSBuilder_BuildFromOCI_Call {
+	return &MockRootFSBuilder_BuildFromOCI_Call{Call: _e.mock.On("BuildFromOCI", ctx, opts)}
+}
+
+func (_c *MockRootFSBuilder_BuildFromOCI_Call) Run(run func(ctx context.Context, opts image.OCIBuildOptions)) *MockRootFSBuilder_BuildFromOCI_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 image.OCIBuildOptions
+		if args[1] != nil {
+			arg1 = args[1].(image.OCIBuildOptions)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRootFSBuilder_BuildFromOCI_Call) Return(oCIBuildResult *image.OCIBuildResult, err error) *MockRootFSBuilder_BuildFromOCI_Call {
+	_c.Call.Return(oCIBuildResult, err)
+	return _c
+}
+
+func (_c *MockRootFSBuilder_BuildFromOCI_Call) RunAndReturn(run func(ctx context.Context, opts image.OCIBuildOptions) (*image.OCIBuildResult, error)) *MockRootFSBuilder_BuildFromOCI_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockImageCustomizer creates a new instance of MockImageCustomizer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockImageCustomizer(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockImageCustomizer {
+	mock := &MockImageCustomizer{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockImageCustomizer is an autogenerated mock type for the ImageCustomizer type
+type MockImageCustomizer struct {
+	mock.Mock
+}
+
+type MockImageCustomizer_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockImageCustomizer) EXPECT() *MockImageCustomizer_Expecter {
+	return &MockImageCustomizer_Expecter{mock: &_m.Mock}
+}
+
+// Customize provides a mock function for the type MockImageCustomizer
+func (_mock *MockImageCustomizer) Customize(ctx context.Context, opts image.CustomizeOptions) (*image.CustomizeResult, error) {
+	ret := _mock.Called(ctx, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Customize")
+	}
+
+	var r0 *image.CustomizeResult
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, image.CustomizeOptions) (*image.CustomizeResult, error)); ok {
+		return returnFunc(ctx, opts)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, image.CustomizeOptions) *image.CustomizeResult); ok {
+		r0 = returnFunc(ctx, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*image.CustomizeResult)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, image.CustomizeOptions) error); ok {
+		r1 = returnFunc(ctx, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockImageCustomizer_Customize_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Customize'
+type MockImageCustomizer_Customize_Call struct {
+	*mock.Call
+}
+
+// Customize is a helper method to define mock.On call
+//   - ctx context.Context
+//   - opts image.CustomizeOptions
+func (_e *MockImageCustomizer_Expecter) Customize(ctx interface{}, opts interface{}) *MockImageCustomizer_Customize_Call {
+	return &MockImageCustomizer_Customize_Call{Call: _e.mock.On("Customize", ctx, opts)}
+}
+
+func (_c *MockImageCustomizer_Customize_Call) Run(run func(ctx context.Context, opts image.CustomizeOptions)) *MockImageCustomizer_Customize_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 image.CustomizeOptions
+		if args[1] != nil {
+			arg1 = args[1].(image.CustomizeOptions)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockImageCustomizer_Customize_Call) Return(oCIBuildResult *image.CustomizeResult, err error) *MockImageCustomizer_Customize_Call {
+	_c.Call.Return(oCIBuildResult, err)
+	return _c
+}
+
+func (_c *MockImageCustomizer_Customize_Call) RunAndReturn(run func(ctx context.Context, opts image.CustomizeOptions) (*image.CustomizeResult, error)) *MockImageCustomizer_Customize_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockImageDiffer creates a new instance of MockImageDiffer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockImageDiffer(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockImageDiffer {
+	mock := &MockImageDiffer{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockImageDiffer is an autogenerated mock type for the ImageDiffer type
+type MockImageDiffer struct {
+	mock.Mock
+}
+
+type MockImageDiffer_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockImageDiffer) EXPECT() *MockImageDiffer_Expecter {
+	return &MockImageDiffer_Expecter{mock: &_m.Mock}
+}
+
+// Diff provides a mock function for the type MockImageDiffer
+func (_mock *MockImageDiffer) Diff(ctx context.Context, from string, to string) (*model.ImageDiff, error) {
+	ret := _mock.Called(ctx, from, to)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Diff")
+	}
+
+	var r0 *model.ImageDiff
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) (*model.ImageDiff, error)); ok {
+		return returnFunc(ctx, from, to)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) *model.ImageDiff); ok {
+		r0 = returnFunc(ctx, from, to)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.ImageDiff)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = returnFunc(ctx, from, to)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockImageDiffer_Diff_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Diff'
+type MockImageDiffer_Diff_Call struct {
+	*mock.Call
+}
+
+// Diff is a helper method to define mock.On call
+//   - ctx context.Context
+//   - from string
+//   - to string
+func (_e *MockImageDiffer_Expecter) Diff(ctx interface{}, from interface{}, to interface{}) *MockImageDiffer_Diff_Call {
+	return &MockImageDiffer_Diff_Call{Call: _e.mock.On("Diff", ctx, from, to)}
+}
+
+func (_c *MockImageDiffer_Diff_Call) Run(run func(ctx context.Context, from string, to string)) *MockImageDiffer_Diff_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockImageDiffer_Diff_Call) Return(imageDiff *model.ImageDiff, err error) *MockImageDiffer_Diff_Call {
+	_c.Call.Return(imageDiff, err)
+	return _c
+}
+
+func (_c *MockImageDiffer_Diff_Call) RunAndReturn(run func(ctx context.Context, from string, to string) (*model.ImageDiff, error)) *MockImageDiffer_Diff_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockImageMover creates a new instance of MockImageMover. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockImageMover(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockImageMover {
+	mock := &MockImageMover{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockImageMover is an autogenerated mock type for the ImageMover type
+type MockImageMover struct {
+	mock.Mock
+}
+
+type MockImageMover_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockImageMover) EXPECT() *MockImageMover_Expecter {
+	return &MockImageMover_Expecter{mock: &_m.Mock}
+}
+
+// Move provides a mock function for the type MockImageMover
+func (_mock *MockImageMover) Move(ctx context.Context, name string, toDir string, dryRun bool) (string, error) {
+	ret := _mock.Called(ctx, name, toDir, dryRun)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Move")
+	}
+
+	var r0 string
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, bool) (string, error)); ok {
+		return returnFunc(ctx, name, toDir, dryRun)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, bool) string); ok {
+		r0 = returnFunc(ctx, name, toDir, dryRun)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string, bool) error); ok {
+		r1 = returnFunc(ctx, name, toDir, dryRun)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockImageMover_Move_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Move'
+type MockImageMover_Move_Call struct {
+	*mock.Call
+}
+
+// Move is a helper method to define mock.On call
+//   - ctx context.Context
+//   - name string
+//   - toDir string
+//   - dryRun bool
+func (_e *MockImageMover_Expecter) Move(ctx interface{}, name interface{}, toDir interface{}, dryRun interface{}) *MockImageMover_Move_Call {
+	return &MockImageMover_Move_Call{Call: _e.mock.On("Move", ctx, name, toDir, dryRun)}
+}
+
+func (_c *MockImageMover_Move_Call) Run(run func(ctx context.Context, name string, toDir string, dryRun bool)) *MockImageMover_Move_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		var arg3 bool
+		if args[3] != nil {
+			arg3 = args[3].(bool)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+		)
+	})
+	return _c
+}
+
+func (_c *MockImageMover_Move_Call) Return(fromDir string, err error) *MockImageMover_Move_Call {
+	_c.Call.Return(fromDir, err)
+	return _c
+}
+
+func (_c *MockImageMover_Move_Call) RunAndReturn(run func(ctx context.Context, name string, toDir string, dryRun bool) (string, error)) *MockImageMover_Move_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockSnapshotStore creates a new instance of MockSnapshotStore. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockSnapshotStore(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockSnapshotStore {
+	mock := &MockSnapshotStore{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockSnapshotStore is an autogenerated mock type for the SnapshotStore type
+type MockSnapshotStore struct {
+	mock.Mock
+}
+
+type MockSnapshotStore_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockSnapshotStore) EXPECT() *MockSnapshotStore_Expecter {
+	return &MockSnapshotStore_Expecter{mock: &_m.Mock}
+}
+
+// Download provides a mock function for the type MockSnapshotStore
+func (_mock *MockSnapshotStore) Download(ctx context.Context, name string, w io.Writer) error {
+	ret := _mock.Called(ctx, name, w)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Download")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, io.Writer) error); ok {
+		r0 = returnFunc(ctx, name, w)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockSnapshotStore_Download_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Download'
+type MockSnapshotStore_Download_Call struct {
+	*mock.Call
+}
+
+// Download is a helper method to define mock.On call
+//   - ctx context.Context
+//   - name string
+//   - w io.Writer
+func (_e *MockSnapshotStore_Expecter) Download(ctx interface{}, name interface{}, w interface{}) *MockSnapshotStore_Download_Call {
+	return &MockSnapshotStore_Download_Call{Call: _e.mock.On("Download", ctx, name, w)}
+}
+
+func (_c *MockSnapshotStore_Download_Call) Run(run func(ctx context.Context, name string, w io.Writer)) *MockSnapshotStore_Download_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 io.Writer
+		if args[2] != nil {
+			arg2 = args[2].(io.Writer)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockSnapshotStore_Download_Call) Return(err error) *MockSnapshotStore_Download_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockSnapshotStore_Download_Call) RunAndReturn(run func(ctx context.Context, name string, w io.Writer) error) *MockSnapshotStore_Download_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Upload provides a mock function for the type MockSnapshotStore
+func (_mock *MockSnapshotStore) Upload(ctx context.Context, name string, r io.Reader) (*model.RemoteSnapshot, error) {
+	ret := _mock.Called(ctx, name, r)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Upload")
+	}
+
+	var r0 *model.RemoteSnapshot
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, io.Reader) (*model.RemoteSnapshot, error)); ok {
+		return returnFunc(ctx, name, r)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, io.Reader) *model.RemoteSnapshot); ok {
+		r0 = returnFunc(ctx, name, r)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.RemoteSnapshot)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, io.Reader) error); ok {
+		r1 = returnFunc(ctx, name, r)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockSnapshotStore_Upload_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Upload'
+type MockSnapshotStore_Upload_Call struct {
+	*mock.Call
+}
+
+// Upload is a helper method to define mock.On call
+//   - ctx context.Context
+//   - name string
+//   - r io.Reader
+func (_e *MockSnapshotStore_Expecter) Upload(ctx interface{}, name interface{}, r interface{}) *MockSnapshotStore_Upload_Call {
+	return &MockSnapshotStore_Upload_Call{Call: _e.mock.On("Upload", ctx, name, r)}
+}
+
+func (_c *MockSnapshotStore_Upload_Call) Run(run func(ctx context.Context, name string, r io.Reader)) *MockSnapshotStore_Upload_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 io.Reader
+		if args[2] != nil {
+			arg2 = args[2].(io.Reader)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockSnapshotStore_Upload_Call) Return(remoteSnapshot *model.RemoteSnapshot, err error) *MockSnapshotStore_Upload_Call {
+	_c.Call.Return(remoteSnapshot, err)
+	return _c
+}
+
+func (_c *MockSnapshotStore_Upload_Call) RunAndReturn(run func(ctx context.Context, name string, r io.Reader) (*model.RemoteSnapshot, error)) *MockSnapshotStore_Upload_Call {
+	_c.Call.Return(run)
+	return _c
+}
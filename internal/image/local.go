@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/slok/sbx/internal/log"
 	"github.com/slok/sbx/internal/model"
@@ -17,6 +18,8 @@ import (
 type LocalImageManagerConfig struct {
 	// ImagesDir is the local directory for storing images.
 	ImagesDir string
+	// SnapshotsDir is the local directory of the snapshot images (default: ImagesDir).
+	SnapshotsDir string
 	// Logger for logging.
 	Logger log.Logger
 }
@@ -29,6 +32,9 @@ func (c *LocalImageManagerConfig) defaults() error {
 		}
 		c.ImagesDir = filepath.Join(home, DefaultImagesDir)
 	}
+	if c.SnapshotsDir == "" {
+		c.SnapshotsDir = c.ImagesDir
+	}
 	if c.Logger == nil {
 		c.Logger = log.Noop
 	}
@@ -38,8 +44,8 @@ func (c *LocalImageManagerConfig) defaults() error {
 // LocalImageManager implements ImageManager using the local filesystem.
 // It handles all locally installed images uniformly (both releases and snapshots).
 type LocalImageManager struct {
-	imagesDir string
-	logger    log.Logger
+	dirs   []string
+	logger log.Logger
 }
 
 // NewLocalImageManager creates a new local image manager.
@@ -48,50 +54,67 @@ func NewLocalImageManager(cfg LocalImageManagerConfig) (*LocalImageManager, erro
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 	return &LocalImageManager{
-		imagesDir: cfg.ImagesDir,
-		logger:    cfg.Logger,
+		dirs:   imageDirs(cfg.ImagesDir, cfg.SnapshotsDir),
+		logger: cfg.Logger,
 	}, nil
 }
 
 func (m *LocalImageManager) List(_ context.Context) ([]model.ImageRelease, error) {
-	entries, err := os.ReadDir(m.imagesDir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("reading images directory: %w", err)
-	}
-
 	var result []model.ImageRelease
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-		name := entry.Name()
-
-		// Only include dirs with a valid manifest.
-		mj, err := readLocalManifest(m.imagesDir, name)
+	seen := map[string]bool{}
+	for _, dir := range m.dirs {
+		entries, err := os.ReadDir(dir)
 		if err != nil {
-			continue
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("reading images directory: %w", err)
 		}
 
-		source := model.ImageSourceRelease
-		if mj.Snapshot != nil {
-			source = model.ImageSourceSnapshot
-		}
+		for _, entry := range entries {
+			if !entry.IsDir() || seen[entry.Name()] {
+				continue
+			}
+			name := entry.Name()
+
+			// Only include dirs with a valid manifest.
+			mj, err := readLocalManifest(dir, name)
+			if err != nil {
+				continue
+			}
+			seen[name] = true
 
-		result = append(result, model.ImageRelease{
-			Version:   name,
-			Installed: true,
-			Source:    source,
-		})
+			source := model.ImageSourceRelease
+			switch {
+			case mj.Snapshot != nil:
+				source = model.ImageSourceSnapshot
+			case mj.Local != nil:
+				source = model.ImageSourceLocal
+			case mj.Customized != nil:
+				source = model.ImageSourceCustomized
+			}
+
+			// The manifest is written last on pull, add, customization and snapshot creation, so its
+			// modification time is the install time.
+			var installedAt time.Time
+			if info, err := os.Stat(filepath.Join(dir, name, "manifest.json")); err == nil {
+				installedAt = info.ModTime().UTC()
+			}
+
+			result = append(result, model.ImageRelease{
+				Version:     name,
+				Installed:   true,
+				Source:      source,
+				InstalledAt: installedAt,
+			})
+		}
 	}
 
 	return result, nil
 }
 
 func (m *LocalImageManager) GetManifest(_ context.Context, name string) (*model.ImageManifest, error) {
-	mj, err := readLocalManifest(m.imagesDir, name)
+	mj, err := readLocalManifest(lookupImageDir(name, m.dirs...), name)
 	if err != nil {
 		return nil, fmt.Errorf("reading manifest for %s: %w", name, err)
 	}
@@ -108,18 +131,25 @@ func (m *LocalImageManager) GetManifest(_ context.Context, name string) (*model.
 }
 
 func (m *LocalImageManager) Remove(_ context.Context, name string) error {
-	versionDir := filepath.Join(m.imagesDir, name)
+	dir := lookupImageDir(name, m.dirs...)
+	versionDir := filepath.Join(dir, name)
 	if _, err := os.Stat(versionDir); os.IsNotExist(err) {
 		return fmt.Errorf("image %s is not installed", name)
 	}
 	if err := os.RemoveAll(versionDir); err != nil {
 		return fmt.Errorf("removing image %s: %w", name, err)
 	}
+
+	// Artifacts shared with other images are kept.
+	if err := gcBlobs(dir, m.logger); err != nil {
+		return fmt.Errorf("removing unused blobs: %w", err)
+	}
+
 	return nil
 }
 
 func (m *LocalImageManager) Exists(_ context.Context, name string) (bool, error) {
-	versionDir := filepath.Join(m.imagesDir, name)
+	versionDir := filepath.Join(lookupImageDir(name, m.dirs...), name)
 	info, err := os.Stat(versionDir)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -131,19 +161,135 @@ func (m *LocalImageManager) Exists(_ context.Context, name string) (bool, error)
 }
 
 func (m *LocalImageManager) KernelPath(name string) string {
-	return filepath.Join(m.imagesDir, name, fmt.Sprintf("vmlinux-%s", HostArch()))
+	return filepath.Join(lookupImageDir(name, m.dirs...), name, fmt.Sprintf("vmlinux-%s", HostArch()))
 }
 
 func (m *LocalImageManager) RootFSPath(name string) string {
-	return filepath.Join(m.imagesDir, name, fmt.Sprintf("rootfs-%s.ext4", HostArch()))
+	return filepath.Join(lookupImageDir(name, m.dirs...), name, fmt.Sprintf("rootfs-%s.ext4", HostArch()))
 }
 
 func (m *LocalImageManager) FirecrackerPath(name string) string {
-	return filepath.Join(m.imagesDir, name, "firecracker")
+	return filepath.Join(lookupImageDir(name, m.dirs...), name, "firecracker")
+}
+
+func (m *LocalImageManager) Move(ctx context.Context, name, toDir string, dryRun bool) (string, error) {
+	fromDir := lookupImageDir(name, m.dirs...)
+	if _, err := readLocalManifest(fromDir, name); err != nil {
+		return "", fmt.Errorf("image %s is not installed: %w", name, model.ErrNotFound)
+	}
+	if dryRun || filepath.Clean(fromDir) == filepath.Clean(toDir) {
+		return fromDir, nil
+	}
+
+	versionDir := filepath.Join(toDir, name)
+	if _, err := os.Stat(versionDir); err == nil {
+		return "", fmt.Errorf("image %s already exists in %s: %w", name, toDir, model.ErrAlreadyExists)
+	}
+	if err := fileutil.MoveDir(ctx, filepath.Join(fromDir, name), versionDir); err != nil {
+		return "", fmt.Errorf("moving image %s: %w", name, err)
+	}
+
+	// The moved artifacts are stored in the destination blobs, releasing the source ones.
+	entries, err := os.ReadDir(versionDir)
+	if err != nil {
+		return "", fmt.Errorf("reading image directory: %w", err)
+	}
+	for _, e := range entries {
+		if !e.Type().IsRegular() || e.Name() == "manifest.json" {
+			continue
+		}
+		if err := storeBlob(toDir, filepath.Join(versionDir, e.Name()), "", m.logger); err != nil {
+			return "", fmt.Errorf("storing %s: %w", e.Name(), err)
+		}
+	}
+	if err := gcBlobs(fromDir, m.logger); err != nil {
+		return "", fmt.Errorf("removing unused blobs: %w", err)
+	}
+
+	m.logger.Infof("Moved image %s from %s to %s", name, fromDir, toDir)
+	return fromDir, nil
+}
+
+func (m *LocalImageManager) DiskUsage(_ context.Context) (*model.ImageDiskUsage, error) {
+	var files []imageFile
+	for _, dir := range m.dirs {
+		dirFiles, err := imageFiles(dir)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, dirFiles...)
+	}
+
+	usage := &model.ImageDiskUsage{}
+	var names []string
+	byImage := map[string]*model.ImageUsage{}
+	var counted []os.FileInfo
+	for _, f := range files {
+		iu, ok := byImage[f.image]
+		if !ok {
+			iu = &model.ImageUsage{Name: f.image}
+			byImage[f.image] = iu
+			names = append(names, f.image)
+		}
+
+		size := allocatedSize(f.path)
+		iu.SizeBytes += size
+		usage.TotalBytes += size
+
+		// Files linked from multiple images are the same blob.
+		shared := false
+		for _, other := range files {
+			if other.image != f.image && os.SameFile(f.info, other.info) {
+				shared = true
+				break
+			}
+		}
+		if shared {
+			iu.SharedBytes += size
+		}
+
+		seen := false
+		for _, c := range counted {
+			if os.SameFile(f.info, c) {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			counted = append(counted, f.info)
+			usage.DiskBytes += size
+		}
+	}
+
+	for _, name := range names {
+		usage.Images = append(usage.Images, *byImage[name])
+	}
+
+	return usage, nil
 }
 
 // --- Shared helpers (used by LocalImageManager, LocalSnapshotCreator, GitHubImagePuller) ---
 
+// imageDirs returns the distinct local directories holding images, the images
+// directory first.
+func imageDirs(imagesDir, snapshotsDir string) []string {
+	if snapshotsDir == "" || filepath.Clean(snapshotsDir) == filepath.Clean(imagesDir) {
+		return []string{imagesDir}
+	}
+	return []string{imagesDir, snapshotsDir}
+}
+
+// lookupImageDir returns the first directory holding the named image, the first
+// directory when none does.
+func lookupImageDir(name string, dirs ...string) string {
+	for _, dir := range dirs {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil && info.IsDir() {
+			return dir
+		}
+	}
+	return dirs[0]
+}
+
 // readLocalManifest reads and parses a manifest.json from a local version directory.
 func readLocalManifest(imagesDir, version string) (*manifestJSON, error) {
 	manifestPath := filepath.Join(imagesDir, version, "manifest.json")
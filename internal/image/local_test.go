@@ -98,6 +98,7 @@ func TestLocalImageManagerList(t *testing.T) {
 			got := make(map[string]model.ImageSource, len(releases))
 			for _, r := range releases {
 				assert.True(t, r.Installed)
+				assert.False(t, r.InstalledAt.IsZero())
 				got[r.Version] = r.Source
 			}
 			assert.Equal(t, tc.expVersions, got)
@@ -217,3 +218,62 @@ func TestLocalImageManagerPaths(t *testing.T) {
 	assert.Equal(t, filepath.Join(imagesDir, "v0.1.0", "rootfs-x86_64.ext4"), m.RootFSPath("v0.1.0"))
 	assert.Equal(t, filepath.Join(imagesDir, "v0.1.0", "firecracker"), m.FirecrackerPath("v0.1.0"))
 }
+
+func TestLocalImageManagerSnapshotsDir(t *testing.T) {
+	imagesDir, snapshotsDir := t.TempDir(), t.TempDir()
+	m, err := image.NewLocalImageManager(image.LocalImageManagerConfig{
+		ImagesDir:    imagesDir,
+		SnapshotsDir: snapshotsDir,
+	})
+	require.NoError(t, err)
+
+	writeTestManifest(t, imagesDir, "v0.1.0", false)
+	writeTestManifest(t, snapshotsDir, "my-snap", true)
+
+	releases, err := m.List(context.Background())
+	require.NoError(t, err)
+	got := map[string]model.ImageSource{}
+	for _, r := range releases {
+		got[r.Version] = r.Source
+	}
+	assert.Equal(t, map[string]model.ImageSource{"v0.1.0": model.ImageSourceRelease, "my-snap": model.ImageSourceSnapshot}, got)
+
+	exists, err := m.Exists(context.Background(), "my-snap")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	_, err = m.GetManifest(context.Background(), "my-snap")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(snapshotsDir, "my-snap", "rootfs-x86_64.ext4"), m.RootFSPath("my-snap"))
+	assert.Equal(t, filepath.Join(imagesDir, "v0.1.0", "firecracker"), m.FirecrackerPath("v0.1.0"))
+
+	require.NoError(t, m.Remove(context.Background(), "my-snap"))
+	_, err = os.Stat(filepath.Join(snapshotsDir, "my-snap"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestLocalImageManagerMove(t *testing.T) {
+	m, imagesDir := newTestLocalManager(t)
+	toDir := t.TempDir()
+	writeTestManifest(t, imagesDir, "v0.1.0", false)
+	require.NoError(t, os.WriteFile(filepath.Join(imagesDir, "v0.1.0", "vmlinux-x86_64"), []byte("kernel"), 0o644))
+
+	// A dry run reports the source without moving.
+	fromDir, err := m.Move(context.Background(), "v0.1.0", toDir, true)
+	require.NoError(t, err)
+	assert.Equal(t, imagesDir, fromDir)
+	assert.DirExists(t, filepath.Join(imagesDir, "v0.1.0"))
+
+	fromDir, err = m.Move(context.Background(), "v0.1.0", toDir, false)
+	require.NoError(t, err)
+	assert.Equal(t, imagesDir, fromDir)
+	assert.NoDirExists(t, filepath.Join(imagesDir, "v0.1.0"))
+	data, err := os.ReadFile(filepath.Join(toDir, "v0.1.0", "vmlinux-x86_64"))
+	require.NoError(t, err)
+	assert.Equal(t, "kernel", string(data))
+	blobs, err := filepath.Glob(filepath.Join(toDir, ".blobs", "sha256", "*"))
+	require.NoError(t, err)
+	assert.Len(t, blobs, 1)
+
+	_, err = m.Move(context.Background(), "missing", toDir, false)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
@@ -0,0 +1,606 @@
+package image
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+// GuestInitPath is the rootfs path of the sbx guest init binary.
+const GuestInitPath = "/usr/sbin/sbx-init"
+
+// sshdPaths are the rootfs paths where the OpenSSH server is looked up.
+var sshdPaths = []string{"/usr/sbin/sshd", "/sbin/sshd", "/usr/bin/sshd"}
+
+// LocalRootFSBuilderConfig configures the local rootfs builder.
+type LocalRootFSBuilderConfig struct {
+	// TmpDir is the directory for the build staging files (default: os temp dir).
+	TmpDir string
+	// Logger for logging.
+	Logger log.Logger
+}
+
+func (c *LocalRootFSBuilderConfig) defaults() error {
+	if c.TmpDir == "" {
+		c.TmpDir = os.TempDir()
+	}
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	return nil
+}
+
+// LocalRootFSBuilder implements RootFSBuilder with the local filesystem and the
+// e2fsprogs tools. The OCI image layers are flattened in a staging directory
+// (applying the whiteouts), the sbx init is injected and the ext4 image is created
+// from the directory with mkfs.ext4.
+//
+// Images that aren't archive files are exported with the docker (or podman) CLI.
+type LocalRootFSBuilder struct {
+	tmpDir string
+	logger log.Logger
+}
+
+// NewLocalRootFSBuilder creates a new local rootfs builder.
+func NewLocalRootFSBuilder(cfg LocalRootFSBuilderConfig) (*LocalRootFSBuilder, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &LocalRootFSBuilder{
+		tmpDir: cfg.TmpDir,
+		logger: cfg.Logger,
+	}, nil
+}
+
+func (b *LocalRootFSBuilder) BuildFromOCI(ctx context.Context, opts OCIBuildOptions) (*OCIBuildResult, error) {
+	if opts.Source == "" {
+		return nil, fmt.Errorf("image is required: %w", model.ErrNotValid)
+	}
+	if opts.OutputPath == "" {
+		return nil, fmt.Errorf("output path is required: %w", model.ErrNotValid)
+	}
+	if opts.InitSrc == "" && opts.InitFromRootFS == "" {
+		return nil, fmt.Errorf("an init binary or a rootfs to take it from is required: %w", model.ErrNotValid)
+	}
+	if _, err := exec.LookPath("mkfs.ext4"); err != nil {
+		return nil, fmt.Errorf("mkfs.ext4 not found (install e2fsprogs): %w", err)
+	}
+
+	if err := os.MkdirAll(b.tmpDir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating work directory: %w", err)
+	}
+	workDir, err := os.MkdirTemp(b.tmpDir, "sbx-oci-")
+	if err != nil {
+		return nil, fmt.Errorf("creating work directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	archive, err := b.archive(ctx, opts.Source, workDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unpack the archive to read its manifest and layer blobs.
+	layoutDir := filepath.Join(workDir, "layout")
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, fmt.Errorf("opening image archive: %w", err)
+	}
+	err = untar(ctx, f, layoutDir, false)
+	f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("unpacking image archive: %w", err)
+	}
+
+	layers, err := ociLayers(layoutDir)
+	if err != nil {
+		return nil, err
+	}
+
+	rootDir := filepath.Join(workDir, "rootfs")
+	if err := os.MkdirAll(rootDir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating rootfs directory: %w", err)
+	}
+	for i, layer := range layers {
+		b.logger.Debugf("Applying layer %d/%d", i+1, len(layers))
+		if err := applyLayer(ctx, layer, rootDir); err != nil {
+			return nil, fmt.Errorf("applying layer %d: %w", i+1, err)
+		}
+	}
+	// The layer blobs aren't needed anymore, free the space before creating the image.
+	os.RemoveAll(layoutDir)
+	os.Remove(filepath.Join(workDir, "image.tar"))
+
+	if err := b.prepareRootFS(ctx, rootDir, workDir, opts); err != nil {
+		return nil, err
+	}
+
+	size, err := dirSize(rootDir)
+	if err != nil {
+		return nil, fmt.Errorf("computing rootfs size: %w", err)
+	}
+	sizeMB := opts.SizeMB
+	contentMB := int(size>>20) + 1
+	if sizeMB == 0 {
+		// Room for the filesystem metadata and the first writes, the disk is grown
+		// to the sandbox disk size on start.
+		sizeMB = contentMB + contentMB/4 + 128
+	}
+	if sizeMB < contentMB {
+		return nil, fmt.Errorf("rootfs size %dMB is smaller than the image content (%dMB): %w", sizeMB, contentMB, model.ErrNotValid)
+	}
+
+	out, err := exec.CommandContext(ctx, "mkfs.ext4", "-q", "-F", "-L", "rootfs", "-E", "root_owner=0:0", "-d", rootDir, opts.OutputPath, fmt.Sprintf("%dM", sizeMB)).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("creating ext4 image: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	distro, distroVersion := osRelease(rootDir)
+	return &OCIBuildResult{
+		Distro:        distro,
+		DistroVersion: distroVersion,
+		SizeBytes:     int64(sizeMB) << 20,
+	}, nil
+}
+
+// archive returns the path of the image archive, exporting the image with the
+// docker or podman CLI when the source isn't an archive file.
+func (b *LocalRootFSBuilder) archive(ctx context.Context, source, workDir string) (string, error) {
+	if info, err := os.Stat(source); err == nil && info.Mode().IsRegular() {
+		return source, nil
+	}
+
+	var cli string
+	for _, c := range []string{"docker", "podman"} {
+		if _, err := exec.LookPath(c); err == nil {
+			cli = c
+			break
+		}
+	}
+	if cli == "" {
+		return "", fmt.Errorf("%s is not an image archive file and docker or podman is not available to export it: %w", source, model.ErrNotValid)
+	}
+
+	dst := filepath.Join(workDir, "image.tar")
+	b.logger.Infof("Exporting %s with %s", source, cli)
+	out, err := exec.CommandContext(ctx, cli, "save", "-o", dst, source).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("exporting image with %s: %w: %s", cli, err, strings.TrimSpace(string(out)))
+	}
+	return dst, nil
+}
+
+// prepareRootFS makes the flattened container filesystem bootable by the sbx
+// Firecracker engine: it injects the sbx init and checks the SSH server the
+// engine connects to.
+func (b *LocalRootFSBuilder) prepareRootFS(ctx context.Context, rootDir, workDir string, opts OCIBuildOptions) error {
+	if !rootHasFile(rootDir, "/bin/sh") {
+		return fmt.Errorf("the image has no /bin/sh: %w", model.ErrNotValid)
+	}
+	hasSSHD := false
+	for _, p := range sshdPaths {
+		if rootHasFile(rootDir, p) {
+			hasSSHD = true
+			break
+		}
+	}
+	if !hasSSHD {
+		return fmt.Errorf("the image has no sshd, install the OpenSSH server in it (e.g. openssh-server): %w", model.ErrNotValid)
+	}
+
+	// Mount points and directories the guest boot expects.
+	for _, d := range []struct {
+		path string
+		mode os.FileMode
+	}{
+		{"/dev", 0o755}, {"/proc", 0o555}, {"/sys", 0o555}, {"/run", 0o755},
+		{"/tmp", 0o1777}, {"/etc/ssh", 0o755}, {"/root/.ssh", 0o700}, {"/usr/sbin", 0o755},
+	} {
+		p := rootPath(rootDir, d.path)
+		if info, err := os.Lstat(p); err == nil && !info.IsDir() {
+			continue
+		}
+		if err := os.MkdirAll(p, d.mode); err != nil {
+			return fmt.Errorf("creating %s: %w", d.path, err)
+		}
+		if err := os.Chmod(p, d.mode); err != nil {
+			return fmt.Errorf("setting %s permissions: %w", d.path, err)
+		}
+	}
+
+	// Inject the init.
+	initSrc := opts.InitSrc
+	if initSrc == "" {
+		if _, err := exec.LookPath("debugfs"); err != nil {
+			return fmt.Errorf("debugfs not found (install e2fsprogs): %w", err)
+		}
+		initSrc = filepath.Join(workDir, "sbx-init")
+		out, err := exec.CommandContext(ctx, "debugfs", "-R", fmt.Sprintf("dump %s %s", GuestInitPath, initSrc), opts.InitFromRootFS).CombinedOutput()
+		if _, statErr := os.Stat(initSrc); err != nil || statErr != nil {
+			return fmt.Errorf("could not take %s from %s: %s: %w", GuestInitPath, opts.InitFromRootFS, strings.TrimSpace(string(out)), model.ErrNotValid)
+		}
+	}
+	initDst := rootPath(rootDir, GuestInitPath)
+	os.Remove(initDst)
+	if err := copyFile(initSrc, initDst); err != nil {
+		return fmt.Errorf("injecting init: %w", err)
+	}
+	if err := os.Chmod(initDst, 0o755); err != nil {
+		return fmt.Errorf("chmod init: %w", err)
+	}
+
+	// Generate the missing SSH host keys, container images usually ship without them.
+	keys, _ := filepath.Glob(filepath.Join(rootPath(rootDir, "/etc/ssh"), "ssh_host_*_key"))
+	if len(keys) == 0 {
+		if _, err := exec.LookPath("ssh-keygen"); err != nil {
+			b.logger.Warningf("ssh-keygen not found, the image has no SSH host keys and sshd must generate them on boot")
+		} else if out, err := exec.CommandContext(ctx, "ssh-keygen", "-A", "-f", rootDir).CombinedOutput(); err != nil {
+			return fmt.Errorf("generating SSH host keys: %w: %s", err, strings.TrimSpace(string(out)))
+		}
+	}
+
+	if os.Geteuid() != 0 {
+		b.logger.Warningf("Not running as root, the rootfs files are owned by the current user instead of the image owners")
+	}
+
+	return nil
+}
+
+// ociLayers returns the layer blob paths, from the lowest to the topmost, of an
+// unpacked OCI image layout or docker save archive.
+func ociLayers(layoutDir string) ([]string, error) {
+	// docker save archives, also written by recent docker versions next to index.json.
+	if data, err := os.ReadFile(filepath.Join(layoutDir, "manifest.json")); err == nil {
+		var manifests []struct {
+			Layers []string `json:"Layers"`
+		}
+		if err := json.Unmarshal(data, &manifests); err != nil {
+			return nil, fmt.Errorf("invalid image archive manifest.json: %w: %w", err, model.ErrNotValid)
+		}
+		if len(manifests) != 1 {
+			return nil, fmt.Errorf("image archive has %d images, one is required: %w", len(manifests), model.ErrNotValid)
+		}
+		layers := make([]string, 0, len(manifests[0].Layers))
+		for _, l := range manifests[0].Layers {
+			layers = append(layers, filepath.Join(layoutDir, filepath.FromSlash(path.Clean("/"+l))))
+		}
+		return layers, nil
+	}
+
+	// OCI image layout.
+	data, err := os.ReadFile(filepath.Join(layoutDir, "index.json"))
+	if err != nil {
+		return nil, fmt.Errorf("image archive has no manifest.json or index.json: %w", model.ErrNotValid)
+	}
+	var desc ociDescriptor
+	if err := resolveOCIManifest(layoutDir, data, &desc); err != nil {
+		return nil, err
+	}
+	data, err = os.ReadFile(ociBlobPath(layoutDir, desc.Digest))
+	if err != nil {
+		return nil, fmt.Errorf("reading image manifest: %w: %w", err, model.ErrNotValid)
+	}
+	var manifest struct {
+		Layers []ociDescriptor `json:"layers"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid image manifest: %w: %w", err, model.ErrNotValid)
+	}
+	layers := make([]string, 0, len(manifest.Layers))
+	for _, l := range manifest.Layers {
+		layers = append(layers, ociBlobPath(layoutDir, l.Digest))
+	}
+	return layers, nil
+}
+
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Platform  *struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+	} `json:"platform,omitempty"`
+}
+
+// resolveOCIManifest resolves an image index to the image manifest of the host
+// platform, following nested indexes.
+func resolveOCIManifest(layoutDir string, index []byte, desc *ociDescriptor) error {
+	var idx struct {
+		Manifests []ociDescriptor `json:"manifests"`
+	}
+	if err := json.Unmarshal(index, &idx); err != nil {
+		return fmt.Errorf("invalid image index: %w: %w", err, model.ErrNotValid)
+	}
+
+	var found *ociDescriptor
+	for i, m := range idx.Manifests {
+		if m.Platform != nil && (m.Platform.OS != "linux" || m.Platform.Architecture != runtime.GOARCH) {
+			continue
+		}
+		found = &idx.Manifests[i]
+		break
+	}
+	if found == nil {
+		return fmt.Errorf("image has no linux/%s manifest: %w", runtime.GOARCH, model.ErrNotValid)
+	}
+
+	if strings.Contains(found.MediaType, "index") || strings.Contains(found.MediaType, "manifest.list") {
+		data, err := os.ReadFile(ociBlobPath(layoutDir, found.Digest))
+		if err != nil {
+			return fmt.Errorf("reading image index: %w: %w", err, model.ErrNotValid)
+		}
+		return resolveOCIManifest(layoutDir, data, desc)
+	}
+
+	*desc = *found
+	return nil
+}
+
+// ociBlobPath returns the path of a blob of an OCI image layout.
+func ociBlobPath(layoutDir, digest string) string {
+	alg, hex, _ := strings.Cut(digest, ":")
+	return filepath.Join(layoutDir, "blobs", path.Base(alg), path.Base(hex))
+}
+
+// applyLayer extracts a layer blob (plain or gzip compressed tar) over the rootfs
+// directory, applying the whiteouts to the lower layers.
+func applyLayer(ctx context.Context, layerPath, rootDir string) error {
+	f, err := os.Open(layerPath)
+	if err != nil {
+		return fmt.Errorf("opening layer: %w", err)
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	if magic, err := br.Peek(4); err == nil {
+		switch {
+		case magic[0] == 0x1f && magic[1] == 0x8b:
+			gz, err := gzip.NewReader(br)
+			if err != nil {
+				return fmt.Errorf("opening gzip layer: %w", err)
+			}
+			defer gz.Close()
+			r = gz
+		case magic[0] == 0x28 && magic[1] == 0xb5 && magic[2] == 0x2f && magic[3] == 0xfd:
+			return fmt.Errorf("zstd compressed layers are not supported: %w", model.ErrNotValid)
+		}
+	}
+
+	return untar(ctx, r, rootDir, true)
+}
+
+type dirTime struct {
+	path    string
+	modTime time.Time
+}
+
+// untar extracts a tar stream into dstDir. With layer set, the OCI whiteout
+// files remove the entries of the lower layers, and the ownership is kept when
+// running as root.
+func untar(ctx context.Context, r io.Reader, dstDir string, layer bool) error {
+	if err := os.MkdirAll(dstDir, 0o755); err != nil {
+		return err
+	}
+
+	var dirTimes []dirTime
+	tr := tar.NewReader(r)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("reading tar: %w: %w", err, model.ErrNotValid)
+		}
+
+		// Rooted clean paths can't escape the destination.
+		name := path.Clean("/" + hdr.Name)
+		if name == "/" {
+			continue
+		}
+		dst := filepath.Join(dstDir, filepath.FromSlash(name))
+		if layer {
+			dst = rootPath(dstDir, name)
+		}
+
+		if layer {
+			base := path.Base(name)
+			if base == ".wh..wh..opq" {
+				entries, _ := os.ReadDir(filepath.Dir(dst))
+				for _, e := range entries {
+					os.RemoveAll(filepath.Join(filepath.Dir(dst), e.Name()))
+				}
+				continue
+			}
+			if strings.HasPrefix(base, ".wh.") {
+				os.RemoveAll(filepath.Join(filepath.Dir(dst), strings.TrimPrefix(base, ".wh.")))
+				continue
+			}
+		}
+
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return err
+		}
+
+		mode := hdr.FileInfo().Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if info, err := os.Lstat(dst); err == nil && !info.IsDir() {
+				os.RemoveAll(dst)
+			}
+			if err := os.MkdirAll(dst, 0o755); err != nil {
+				return err
+			}
+			if err := os.Chmod(dst, mode); err != nil {
+				return err
+			}
+			dirTimes = append(dirTimes, dirTime{path: dst, modTime: hdr.ModTime})
+		case tar.TypeReg:
+			os.RemoveAll(dst)
+			out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(out, tr)
+			out.Close()
+			if err != nil {
+				return fmt.Errorf("writing %s: %w", name, err)
+			}
+			if err := os.Chmod(dst, mode); err != nil {
+				return err
+			}
+			_ = os.Chtimes(dst, hdr.ModTime, hdr.ModTime)
+		case tar.TypeSymlink:
+			os.RemoveAll(dst)
+			if err := os.Symlink(hdr.Linkname, dst); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			target := rootPath(dstDir, hdr.Linkname)
+			os.RemoveAll(dst)
+			if err := os.Link(target, dst); err != nil {
+				return fmt.Errorf("linking %s: %w", name, err)
+			}
+		default:
+			// Devices and fifos are created by the guest kernel (devtmpfs) and init.
+			continue
+		}
+
+		if layer && os.Geteuid() == 0 {
+			_ = os.Lchown(dst, hdr.Uid, hdr.Gid)
+		}
+	}
+
+	// Directory times are set last, their content changes them.
+	for _, d := range dirTimes {
+		_ = os.Chtimes(d.path, time.Now(), d.modTime)
+	}
+
+	return nil
+}
+
+// rootHasFile reports if a rootfs path exists, following the symlinks inside the
+// rootfs (e.g. /bin -> usr/bin).
+func rootHasFile(rootDir, p string) bool {
+	for range 40 {
+		dst := rootPath(rootDir, p)
+		info, err := os.Lstat(dst)
+		if err != nil {
+			return false
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			return true
+		}
+		target, err := os.Readlink(dst)
+		if err != nil {
+			return false
+		}
+		if !path.IsAbs(target) {
+			target = path.Join(path.Dir(path.Clean("/"+p)), target)
+		}
+		p = target
+	}
+	return false
+}
+
+// rootPath returns the host path of a rootfs path, resolving the symlinks of its
+// parent directories inside the rootfs, so the absolute symlinks of the image
+// (e.g. /lib -> /usr/lib) never point to the host files. The last element is not
+// resolved.
+func rootPath(rootDir, p string) string {
+	comps := strings.Split(strings.TrimPrefix(path.Clean("/"+p), "/"), "/")
+	cur := "/"
+	for links := 0; len(comps) > 0; {
+		part := comps[0]
+		comps = comps[1:]
+		next := path.Join(cur, part)
+		if len(comps) == 0 {
+			cur = next
+			break
+		}
+
+		host := filepath.Join(rootDir, filepath.FromSlash(next))
+		info, err := os.Lstat(host)
+		if err == nil && info.Mode()&fs.ModeSymlink != 0 && links < 40 {
+			target, err := os.Readlink(host)
+			if err == nil {
+				links++
+				if !path.IsAbs(target) {
+					target = path.Join(cur, target)
+				}
+				target = strings.TrimPrefix(path.Clean("/"+target), "/")
+				if target != "" {
+					comps = append(strings.Split(target, "/"), comps...)
+				}
+				cur = "/"
+				continue
+			}
+		}
+		cur = next
+	}
+	return filepath.Join(rootDir, filepath.FromSlash(cur))
+}
+
+// osRelease returns the distribution ID and version of a rootfs from its os-release file.
+func osRelease(rootDir string) (id, version string) {
+	for _, p := range []string{"/etc/os-release", "/usr/lib/os-release"} {
+		data, err := os.ReadFile(filepath.Join(rootDir, filepath.FromSlash(p)))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			k, v, ok := strings.Cut(strings.TrimSpace(line), "=")
+			if !ok {
+				continue
+			}
+			v = strings.Trim(v, `"'`)
+			switch k {
+			case "ID":
+				id = v
+			case "VERSION_ID":
+				version = v
+			}
+		}
+		return id, version
+	}
+	return "", ""
+}
+
+// dirSize returns the size of the regular files of a directory.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
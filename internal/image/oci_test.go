@@ -0,0 +1,214 @@
+package image_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/model"
+)
+
+type tarEntry struct {
+	name     string
+	body     string
+	linkname string
+	typ      byte
+}
+
+func tarBytes(t *testing.T, entries []tarEntry, compress bool) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var gz *gzip.Writer
+	tw := tar.NewWriter(&buf)
+	if compress {
+		gz = gzip.NewWriter(&buf)
+		tw = tar.NewWriter(gz)
+	}
+	for _, e := range entries {
+		typ := e.typ
+		if typ == 0 {
+			typ = tar.TypeReg
+		}
+		hdr := &tar.Header{Name: e.name, Typeflag: typ, Mode: 0o755, Size: int64(len(e.body)), Linkname: e.linkname}
+		if typ != tar.TypeReg {
+			hdr.Size = 0
+		}
+		require.NoError(t, tw.WriteHeader(hdr))
+		if typ == tar.TypeReg {
+			_, err := tw.Write([]byte(e.body))
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, tw.Close())
+	if gz != nil {
+		require.NoError(t, gz.Close())
+	}
+	return buf.Bytes()
+}
+
+var (
+	testBaseLayer = []tarEntry{
+		{name: "bin/sh", body: "sh"},
+		{name: "usr/sbin/sshd", body: "sshd"},
+		{name: "usr/lib/", typ: tar.TypeDir},
+		{name: "lib", typ: tar.TypeSymlink, linkname: "/usr/lib"},
+		{name: "etc/os-release", body: "ID=debian\nVERSION_ID=\"12\"\n"},
+		{name: "etc/ssh/ssh_host_ed25519_key", body: "key"},
+		{name: "removed.txt", body: "x"},
+		{name: "opq/a.txt", body: "a"},
+	}
+	testTopLayer = []tarEntry{
+		{name: ".wh.removed.txt"},
+		{name: "opq/.wh..wh..opq"},
+		{name: "opq/b.txt", body: "b"},
+		{name: "lib/libc.so", body: "libc"},
+	}
+)
+
+// dockerArchive writes a docker save archive with the layers.
+func dockerArchive(t *testing.T, dir string, layers ...[]byte) string {
+	t.Helper()
+	var entries []tarEntry
+	var names []string
+	for i, l := range layers {
+		name := filepath.Join("layer"+string(rune('0'+i)), "layer.tar")
+		names = append(names, name)
+		entries = append(entries, tarEntry{name: name, body: string(l)})
+	}
+	manifest, err := json.Marshal([]map[string]any{{"Config": "config.json", "Layers": names}})
+	require.NoError(t, err)
+	entries = append(entries, tarEntry{name: "manifest.json", body: string(manifest)})
+
+	path := filepath.Join(dir, "image.tar")
+	require.NoError(t, os.WriteFile(path, tarBytes(t, entries, false), 0o644))
+	return path
+}
+
+// ociArchive writes an OCI image layout archive with the layers.
+func ociArchive(t *testing.T, dir string, layers ...[]byte) string {
+	t.Helper()
+	blob := func(data []byte) (string, tarEntry) {
+		sum := sha256.Sum256(data)
+		h := hex.EncodeToString(sum[:])
+		return "sha256:" + h, tarEntry{name: "blobs/sha256/" + h, body: string(data)}
+	}
+
+	var entries []tarEntry
+	var descs []map[string]string
+	for _, l := range layers {
+		d, e := blob(l)
+		entries = append(entries, e)
+		descs = append(descs, map[string]string{"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip", "digest": d})
+	}
+	manifest, err := json.Marshal(map[string]any{"layers": descs})
+	require.NoError(t, err)
+	md, me := blob(manifest)
+	entries = append(entries, me)
+	index, err := json.Marshal(map[string]any{"manifests": []map[string]any{
+		{"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:0000", "platform": map[string]string{"os": "linux", "architecture": "s390x"}},
+		{"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": md, "platform": map[string]string{"os": "linux", "architecture": runtime.GOARCH}},
+	}})
+	require.NoError(t, err)
+	entries = append(entries, tarEntry{name: "index.json", body: string(index)})
+
+	path := filepath.Join(dir, "image-oci.tar")
+	require.NoError(t, os.WriteFile(path, tarBytes(t, entries, false), 0o644))
+	return path
+}
+
+// debugfsCat returns the content of a file of an ext4 image.
+func debugfsCat(t *testing.T, img, path string) string {
+	t.Helper()
+	out, err := exec.Command("debugfs", "-R", "cat "+path, img).Output()
+	require.NoError(t, err)
+	return string(out)
+}
+
+func TestLocalRootFSBuilderBuildFromOCI(t *testing.T) {
+	for _, tool := range []string{"mkfs.ext4", "debugfs"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not available", tool)
+		}
+	}
+
+	srcDir := t.TempDir()
+	initSrc := filepath.Join(srcDir, "sbx-init")
+	require.NoError(t, os.WriteFile(initSrc, []byte("init"), 0o755))
+
+	tests := map[string]struct {
+		archive func(t *testing.T, dir string) string
+		expErr  error
+	}{
+		"A docker save archive should be flattened into an ext4 rootfs.": {
+			archive: func(t *testing.T, dir string) string {
+				return dockerArchive(t, dir, tarBytes(t, testBaseLayer, false), tarBytes(t, testTopLayer, true))
+			},
+		},
+
+		"An OCI image layout archive should be flattened into an ext4 rootfs.": {
+			archive: func(t *testing.T, dir string) string {
+				return ociArchive(t, dir, tarBytes(t, testBaseLayer, true), tarBytes(t, testTopLayer, true))
+			},
+		},
+
+		"An image without sshd should fail.": {
+			archive: func(t *testing.T, dir string) string {
+				return dockerArchive(t, dir, tarBytes(t, []tarEntry{{name: "bin/sh", body: "sh"}}, false))
+			},
+			expErr: model.ErrNotValid,
+		},
+
+		"An archive without manifest should fail.": {
+			archive: func(t *testing.T, dir string) string {
+				path := filepath.Join(dir, "bad.tar")
+				require.NoError(t, os.WriteFile(path, tarBytes(t, []tarEntry{{name: "x", body: "x"}}, false), 0o644))
+				return path
+			},
+			expErr: model.ErrNotValid,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			b, err := image.NewLocalRootFSBuilder(image.LocalRootFSBuilderConfig{TmpDir: dir})
+			require.NoError(t, err)
+
+			out := filepath.Join(dir, "rootfs.ext4")
+			res, err := b.BuildFromOCI(context.Background(), image.OCIBuildOptions{
+				Source:     tc.archive(t, dir),
+				OutputPath: out,
+				InitSrc:    initSrc,
+			})
+			if tc.expErr != nil {
+				assert.ErrorIs(t, err, tc.expErr)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, "debian", res.Distro)
+			assert.Equal(t, "12", res.DistroVersion)
+			assert.Equal(t, "sshd", debugfsCat(t, out, "/usr/sbin/sshd"))
+			assert.Equal(t, "init", debugfsCat(t, out, image.GuestInitPath))
+			assert.Equal(t, "b", debugfsCat(t, out, "/opq/b.txt"))
+			// Files written through absolute symlinks stay in the rootfs.
+			assert.Equal(t, "libc", debugfsCat(t, out, "/usr/lib/libc.so"))
+			// Whiteouts remove the lower layer files.
+			assert.Empty(t, debugfsCat(t, out, "/removed.txt"))
+			assert.Empty(t, debugfsCat(t, out, "/opq/a.txt"))
+		})
+	}
+}
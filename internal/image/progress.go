@@ -29,13 +29,16 @@ func NewProgressWriter(dst io.Writer, statusWriter io.Writer, total int64) *Prog
 
 func (pw *ProgressWriter) Write(p []byte) (int, error) {
 	n, err := pw.dst.Write(p)
+	pw.Add(int64(n))
+	return n, err
+}
 
+// Add accounts n bytes written outside the writer (e.g. parallel or resumed downloads).
+func (pw *ProgressWriter) Add(n int64) {
 	pw.mu.Lock()
-	pw.written += int64(n)
+	pw.written += n
 	pw.printProgress()
 	pw.mu.Unlock()
-
-	return n, err
 }
 
 // Finish prints the final progress line with a newline.
@@ -0,0 +1,28 @@
+package image
+
+import (
+	"github.com/slok/sbx/internal/model"
+)
+
+// SandboxesUsingImage returns the sandboxes that depend on a locally installed image.
+//
+// A sandbox depends on the image it was created from, as it boots from the image
+// kernel. Sandboxes created before the image was tracked are matched by their
+// kernel path.
+func SandboxesUsingImage(mgr ImageManager, name string, sandboxes []model.Sandbox) []model.Sandbox {
+	kernelPath := mgr.KernelPath(name)
+
+	var result []model.Sandbox
+	for _, sb := range sandboxes {
+		if sb.Config.Image == name {
+			result = append(result, sb)
+			continue
+		}
+
+		if sb.Config.Image == "" && sb.Config.FirecrackerEngine != nil && sb.Config.FirecrackerEngine.KernelImage == kernelPath {
+			result = append(result, sb)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,65 @@
+package image_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestSandboxesUsingImage(t *testing.T) {
+	m, _ := newTestLocalManager(t)
+
+	sandbox := func(name, img, kernel string) model.Sandbox {
+		return model.Sandbox{
+			Name: name,
+			Config: model.SandboxConfig{
+				Name:              name,
+				Image:             img,
+				FirecrackerEngine: &model.FirecrackerEngineConfig{KernelImage: kernel},
+			},
+		}
+	}
+
+	tests := map[string]struct {
+		sandboxes []model.Sandbox
+		image     string
+		expNames  []string
+	}{
+		"Sandboxes created from the image should be returned.": {
+			sandboxes: []model.Sandbox{
+				sandbox("sb1", "v0.1.0", m.KernelPath("v0.1.0")),
+				sandbox("sb2", "my-snap", m.KernelPath("my-snap")),
+				sandbox("sb3", "v0.1.0", m.KernelPath("v0.1.0")),
+			},
+			image:    "v0.1.0",
+			expNames: []string{"sb1", "sb3"},
+		},
+		"Untracked sandboxes should be matched by the image kernel path.": {
+			sandboxes: []model.Sandbox{
+				sandbox("sb1", "", m.KernelPath("v0.1.0")),
+				sandbox("sb2", "", "/custom/vmlinux"),
+			},
+			image:    "v0.1.0",
+			expNames: []string{"sb1"},
+		},
+		"An unused image should not return sandboxes.": {
+			sandboxes: []model.Sandbox{
+				sandbox("sb1", "v0.1.0", m.KernelPath("v0.1.0")),
+			},
+			image: "v0.2.0",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var gotNames []string
+			for _, sb := range image.SandboxesUsingImage(m, tc.image, tc.sandboxes) {
+				gotNames = append(gotNames, sb.Name)
+			}
+			assert.Equal(t, tc.expNames, gotNames)
+		})
+	}
+}
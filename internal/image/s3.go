@@ -0,0 +1,512 @@
+package image
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+const (
+	// DefaultSnapshotStorePartSize is the default size of the snapshot bundle
+	// multipart upload parts.
+	DefaultSnapshotStorePartSize = 16 * 1024 * 1024
+	// MinSnapshotStorePartSize is the minimum size of the multipart upload parts
+	// accepted by S3 (except the last one).
+	MinSnapshotStorePartSize = 5 * 1024 * 1024
+
+	// maxSnapshotStoreParts is the maximum number of parts of a multipart upload.
+	maxSnapshotStoreParts = 10000
+
+	defaultS3Region  = "us-east-1"
+	defaultGCSRegion = "auto"
+	gcsEndpoint      = "https://storage.googleapis.com"
+)
+
+// S3SnapshotStoreConfig configures the S3 compatible snapshot store.
+type S3SnapshotStoreConfig struct {
+	// URL is the bucket and key prefix of the snapshot bundles: s3://BUCKET[/PREFIX]
+	// or gs://BUCKET[/PREFIX] (Google Cloud Storage XML API with HMAC keys).
+	URL string
+	// Endpoint overrides the object storage endpoint (e.g. http://localhost:9000 for
+	// MinIO), the buckets are addressed in the path.
+	// Default: the AWS S3 regional endpoint or the Google Cloud Storage one.
+	Endpoint string
+	// Region is the bucket region used to sign the requests.
+	// Default: AWS_REGION env var, us-east-1 (auto for Google Cloud Storage).
+	Region string
+	// AccessKeyID, SecretAccessKey and SessionToken are the credentials, the
+	// requests are anonymous without them (public buckets).
+	// Default: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN env vars.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Encryption is the server-side encryption of the uploaded bundles.
+	Encryption model.SnapshotStoreEncryption
+	// KMSKeyID is the KMS key of model.SnapshotStoreEncryptionKMS (optional).
+	KMSKeyID string
+	// PartSize is the size of the multipart upload parts, each part is buffered in
+	// memory (default: DefaultSnapshotStorePartSize).
+	PartSize int64
+	// HTTPClient is the HTTP client for the object storage requests.
+	HTTPClient *http.Client
+	// Logger for logging.
+	Logger log.Logger
+}
+
+func (c *S3SnapshotStoreConfig) defaults() error {
+	if c.URL == "" {
+		return fmt.Errorf("snapshot store url is required")
+	}
+	if _, _, _, err := model.ParseSnapshotStoreURL(c.URL); err != nil {
+		return err
+	}
+	if err := c.Encryption.Validate(); err != nil {
+		return err
+	}
+	if c.KMSKeyID != "" && c.Encryption != model.SnapshotStoreEncryptionKMS {
+		return fmt.Errorf("kms key id requires the %s encryption", model.SnapshotStoreEncryptionKMS)
+	}
+	if c.PartSize == 0 {
+		c.PartSize = DefaultSnapshotStorePartSize
+	}
+	if c.PartSize < MinSnapshotStorePartSize {
+		return fmt.Errorf("part size must be at least %d bytes", MinSnapshotStorePartSize)
+	}
+	if c.AccessKeyID == "" && c.SecretAccessKey == "" {
+		c.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		c.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		c.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	if (c.AccessKeyID == "") != (c.SecretAccessKey == "") {
+		return fmt.Errorf("access key id and secret access key must be set together")
+	}
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	return nil
+}
+
+// S3SnapshotStore implements SnapshotStore with the S3 API: AWS S3, MinIO, Google
+// Cloud Storage (XML API) and the other S3 compatible object storages.
+//
+// The bundles are stored as PREFIX/NAME.tar objects. They are streamed: the
+// bundles bigger than a part are uploaded with a multipart upload, only buffering
+// the part being uploaded, and aborted on failure so no partial object is left.
+// The requests are signed with AWS Signature Version 4.
+type S3SnapshotStore struct {
+	scheme          string
+	bucket          string
+	prefix          string
+	endpoint        *url.URL
+	virtualHost     bool
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	encryption      model.SnapshotStoreEncryption
+	kmsKeyID        string
+	partSize        int64
+	httpClient      *http.Client
+	logger          log.Logger
+}
+
+// NewS3SnapshotStore creates a new S3 compatible snapshot store.
+func NewS3SnapshotStore(cfg S3SnapshotStoreConfig) (*S3SnapshotStore, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	scheme, bucket, prefix, _ := model.ParseSnapshotStoreURL(cfg.URL)
+	region := cfg.Region
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		region = defaultS3Region
+		if scheme == "gs" {
+			region = defaultGCSRegion
+		}
+	}
+
+	// The AWS endpoints use virtual-hosted buckets, the path-style addressing isn't
+	// available for the new buckets.
+	rawEndpoint, virtualHost := cfg.Endpoint, false
+	if rawEndpoint == "" {
+		rawEndpoint = gcsEndpoint
+		if scheme == "s3" {
+			rawEndpoint, virtualHost = fmt.Sprintf("https://s3.%s.amazonaws.com", region), true
+		}
+	}
+	endpoint, err := url.Parse(rawEndpoint)
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("invalid config: invalid endpoint %q", cfg.Endpoint)
+	}
+
+	return &S3SnapshotStore{
+		scheme:          scheme,
+		bucket:          bucket,
+		prefix:          prefix,
+		endpoint:        endpoint,
+		virtualHost:     virtualHost,
+		region:          region,
+		accessKeyID:     cfg.AccessKeyID,
+		secretAccessKey: cfg.SecretAccessKey,
+		sessionToken:    cfg.SessionToken,
+		encryption:      cfg.Encryption,
+		kmsKeyID:        cfg.KMSKeyID,
+		partSize:        cfg.PartSize,
+		httpClient:      cfg.HTTPClient,
+		logger:          cfg.Logger,
+	}, nil
+}
+
+func (s *S3SnapshotStore) Upload(ctx context.Context, name string, r io.Reader) (*model.RemoteSnapshot, error) {
+	if err := model.ValidateImageName(name); err != nil {
+		return nil, err
+	}
+	key := s.key(name)
+	snap := &model.RemoteSnapshot{Name: name, URL: s.objectName(key)}
+
+	buf := make([]byte, s.partSize)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("reading snapshot bundle: %w", err)
+	}
+
+	// Bundles fitting in a part are uploaded with a single request.
+	if err != nil {
+		resp, err := s.do(ctx, http.MethodPut, key, nil, buf[:n], s.encryptionHeader())
+		if err != nil {
+			return nil, fmt.Errorf("uploading snapshot %s: %w", name, err)
+		}
+		resp.Body.Close()
+		snap.SizeBytes, snap.Parts = int64(n), 1
+		s.logger.Debugf("Uploaded snapshot %s to %s (%d bytes)", name, snap.URL, snap.SizeBytes)
+		return snap, nil
+	}
+
+	uploadID, err := s.createMultipartUpload(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("uploading snapshot %s: %w", name, err)
+	}
+
+	var parts []s3CompletedPart
+	err = func() error {
+		for n > 0 {
+			if len(parts) == maxSnapshotStoreParts {
+				return fmt.Errorf("bundle exceeds %d parts, use a bigger part size: %w", maxSnapshotStoreParts, model.ErrNotValid)
+			}
+			etag, err := s.uploadPart(ctx, key, uploadID, len(parts)+1, buf[:n])
+			if err != nil {
+				return err
+			}
+			parts = append(parts, s3CompletedPart{PartNumber: len(parts) + 1, ETag: etag})
+			snap.SizeBytes += int64(n)
+
+			n, err = io.ReadFull(r, buf)
+			if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+				return fmt.Errorf("reading snapshot bundle: %w", err)
+			}
+		}
+		return s.completeMultipartUpload(ctx, key, uploadID, parts)
+	}()
+	if err != nil {
+		// Abort even when the context is cancelled, the storage keeps the parts otherwise.
+		if abortErr := s.abortMultipartUpload(context.WithoutCancel(ctx), key, uploadID); abortErr != nil {
+			s.logger.Warningf("Could not abort the multipart upload of snapshot %s: %v", name, abortErr)
+		}
+		return nil, fmt.Errorf("uploading snapshot %s: %w", name, err)
+	}
+
+	snap.Parts = len(parts)
+	s.logger.Debugf("Uploaded snapshot %s to %s (%d bytes in %d parts)", name, snap.URL, snap.SizeBytes, snap.Parts)
+	return snap, nil
+}
+
+func (s *S3SnapshotStore) Download(ctx context.Context, name string, w io.Writer) error {
+	if err := model.ValidateImageName(name); err != nil {
+		return err
+	}
+	key := s.key(name)
+
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil, nil)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return fmt.Errorf("snapshot %s not found in %s: %w", name, s.objectName(key), model.ErrNotFound)
+		}
+		return fmt.Errorf("downloading snapshot %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return fmt.Errorf("downloading snapshot %s: %w", name, err)
+	}
+	s.logger.Debugf("Downloaded snapshot %s from %s (%d bytes)", name, s.objectName(key), n)
+	return nil
+}
+
+// key returns the object key of a snapshot bundle.
+func (s *S3SnapshotStore) key(name string) string {
+	return path.Join(s.prefix, name+".tar")
+}
+
+// objectName returns the store URL of an object, e.g. s3://bucket/prefix/name.tar.
+func (s *S3SnapshotStore) objectName(key string) string {
+	return s.scheme + "://" + s.bucket + "/" + key
+}
+
+func (s *S3SnapshotStore) encryptionHeader() http.Header {
+	h := http.Header{}
+	if s.encryption != model.SnapshotStoreEncryptionDefault {
+		h.Set("x-amz-server-side-encryption", string(s.encryption))
+	}
+	if s.kmsKeyID != "" {
+		h.Set("x-amz-server-side-encryption-aws-kms-key-id", s.kmsKeyID)
+	}
+	return h
+}
+
+type s3InitiateMultipartUploadResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type s3CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type s3CompleteMultipartUpload struct {
+	XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+	Parts   []s3CompletedPart `xml:"Part"`
+}
+
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func (s *S3SnapshotStore) createMultipartUpload(ctx context.Context, key string) (string, error) {
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil, s.encryptionHeader())
+	if err != nil {
+		return "", fmt.Errorf("creating multipart upload: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result s3InitiateMultipartUploadResult
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil || result.UploadID == "" {
+		return "", fmt.Errorf("creating multipart upload: invalid response")
+	}
+	return result.UploadID, nil
+}
+
+func (s *S3SnapshotStore) uploadPart(ctx context.Context, key, uploadID string, number int, data []byte) (string, error) {
+	query := url.Values{"partNumber": {fmt.Sprint(number)}, "uploadId": {uploadID}}
+	resp, err := s.do(ctx, http.MethodPut, key, query, data, nil)
+	if err != nil {
+		return "", fmt.Errorf("uploading part %d: %w", number, err)
+	}
+	resp.Body.Close()
+
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		return "", fmt.Errorf("uploading part %d: missing ETag", number)
+	}
+	return etag, nil
+}
+
+func (s *S3SnapshotStore) completeMultipartUpload(ctx context.Context, key, uploadID string, parts []s3CompletedPart) error {
+	body, err := xml.Marshal(s3CompleteMultipartUpload{Parts: parts})
+	if err != nil {
+		return fmt.Errorf("completing multipart upload: %w", err)
+	}
+	h := http.Header{}
+	h.Set("Content-Type", "application/xml")
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, body, h)
+	if err != nil {
+		return fmt.Errorf("completing multipart upload: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// The completion can fail after the 200 status, with an error document.
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return fmt.Errorf("completing multipart upload: %w", err)
+	}
+	var e s3Error
+	if xml.Unmarshal(data, &e) == nil && e.Code != "" && bytes.Contains(data, []byte("<Error>")) {
+		return fmt.Errorf("completing multipart upload: %s: %s", e.Code, e.Message)
+	}
+	return nil
+}
+
+func (s *S3SnapshotStore) abortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do sends a signed object request, the responses with an error status are
+// returned as errors.
+func (s *S3SnapshotStore) do(ctx context.Context, method, key string, query url.Values, body []byte, header http.Header) (*http.Response, error) {
+	u := *s.endpoint
+	objectPath := "/" + s.bucket + "/" + key
+	if s.virtualHost {
+		u.Host = s.bucket + "." + u.Host
+		objectPath = "/" + key
+	}
+	u.Path, u.RawPath = objectPath, awsURIEscape(objectPath, true)
+	u.RawQuery = awsQueryEncode(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	s.sign(req, body, time.Now().UTC())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	var e s3Error
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	_ = xml.Unmarshal(data, &e)
+	msg := cmp.Or(e.Message, e.Code, http.StatusText(resp.StatusCode))
+	switch {
+	case resp.StatusCode == http.StatusNotFound && e.Code != "NoSuchBucket":
+		return nil, fmt.Errorf("%s (status %d): %w", msg, resp.StatusCode, model.ErrNotFound)
+	default:
+		return nil, fmt.Errorf("%s (status %d)", msg, resp.StatusCode)
+	}
+}
+
+// sign signs the request with AWS Signature Version 4, the requests are anonymous
+// without credentials.
+func (s *S3SnapshotStore) sign(req *http.Request, body []byte, now time.Time) {
+	if s.accessKeyID == "" {
+		return
+	}
+
+	payloadHash := sha256Hex(body)
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	if s.sessionToken != "" {
+		req.Header.Set("x-amz-security-token", s.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		lk := strings.ToLower(k)
+		if strings.HasPrefix(lk, "x-amz-") || lk == "content-type" {
+			headers[lk] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.secretAccessKey), date)
+	for _, v := range []string{s.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, v)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKeyID, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// awsURIEscape escapes everything but the unreserved characters (RFC 3986) as
+// required by the signature, keeping the slashes of paths.
+func awsURIEscape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && keepSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// awsQueryEncode encodes the query sorted by key, as required by the signature.
+func awsQueryEncode(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, awsURIEscape(k, false)+"="+awsURIEscape(v, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
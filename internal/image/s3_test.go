@@ -0,0 +1,192 @@
+package image_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/image"
+	"github.com/slok/sbx/internal/model"
+)
+
+// fakeS3 is an in memory S3 object storage with the single and multipart uploads.
+type fakeS3 struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	uploads  map[string]map[int][]byte
+	requests []*http.Request
+	aborted  int
+	failPart int
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: map[string][]byte{}, uploads: map[string]map[int][]byte{}}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r)
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	key := r.URL.Path
+	body, _ := io.ReadAll(r.Body)
+	q := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		id := fmt.Sprintf("upload-%d", len(f.uploads)+1)
+		f.uploads[id] = map[int][]byte{}
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", id)
+	case r.Method == http.MethodPut && q.Has("uploadId"):
+		var n int
+		_, _ = fmt.Sscan(q.Get("partNumber"), &n)
+		if n == f.failPart {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		f.uploads[q.Get("uploadId")][n] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%d"`, n))
+	case r.Method == http.MethodPost && q.Has("uploadId"):
+		parts := f.uploads[q.Get("uploadId")]
+		numbers := make([]int, 0, len(parts))
+		for n := range parts {
+			numbers = append(numbers, n)
+		}
+		sort.Ints(numbers)
+		var data []byte
+		for _, n := range numbers {
+			data = append(data, parts[n]...)
+		}
+		f.objects[key] = data
+		delete(f.uploads, q.Get("uploadId"))
+		fmt.Fprint(w, "<CompleteMultipartUploadResult></CompleteMultipartUploadResult>")
+	case r.Method == http.MethodDelete && q.Has("uploadId"):
+		delete(f.uploads, q.Get("uploadId"))
+		f.aborted++
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		f.objects[key] = body
+	case r.Method == http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, "<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>")
+			return
+		}
+		_, _ = w.Write(data)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newTestS3Store(t *testing.T, srv *httptest.Server, enc model.SnapshotStoreEncryption) *image.S3SnapshotStore {
+	t.Helper()
+	store, err := image.NewS3SnapshotStore(image.S3SnapshotStoreConfig{
+		URL:             "s3://snapshots/team-a",
+		Endpoint:        srv.URL,
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		Encryption:      enc,
+		PartSize:        image.MinSnapshotStorePartSize,
+		HTTPClient:      srv.Client(),
+	})
+	require.NoError(t, err)
+	return store
+}
+
+func TestS3SnapshotStoreUploadSingle(t *testing.T) {
+	fake := newFakeS3()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	store := newTestS3Store(t, srv, model.SnapshotStoreEncryptionAES256)
+
+	snap, err := store.Upload(context.Background(), "my-snap", strings.NewReader("small bundle"))
+	require.NoError(t, err)
+	assert.Equal(t, &model.RemoteSnapshot{Name: "my-snap", URL: "s3://snapshots/team-a/my-snap.tar", SizeBytes: 12, Parts: 1}, snap)
+	assert.Equal(t, []byte("small bundle"), fake.objects["/snapshots/team-a/my-snap.tar"])
+
+	require.Len(t, fake.requests, 1)
+	req := fake.requests[0]
+	assert.Equal(t, "AES256", req.Header.Get("x-amz-server-side-encryption"))
+	assert.Contains(t, req.Header.Get("Authorization"), "SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-server-side-encryption,")
+
+	var buf bytes.Buffer
+	require.NoError(t, store.Download(context.Background(), "my-snap", &buf))
+	assert.Equal(t, "small bundle", buf.String())
+}
+
+func TestS3SnapshotStoreUploadMultipart(t *testing.T) {
+	fake := newFakeS3()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	store := newTestS3Store(t, srv, model.SnapshotStoreEncryptionKMS)
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), (2*image.MinSnapshotStorePartSize+1024)/16)
+	snap, err := store.Upload(context.Background(), "big-snap", bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 3, snap.Parts)
+	assert.Equal(t, int64(len(data)), snap.SizeBytes)
+	assert.Equal(t, data, fake.objects["/snapshots/team-a/big-snap.tar"])
+
+	// The encryption is set on the upload creation.
+	assert.Equal(t, "aws:kms", fake.requests[0].Header.Get("x-amz-server-side-encryption"))
+	assert.Empty(t, fake.requests[1].Header.Get("x-amz-server-side-encryption"))
+}
+
+func TestS3SnapshotStoreUploadAbort(t *testing.T) {
+	fake := newFakeS3()
+	fake.failPart = 2
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	store := newTestS3Store(t, srv, model.SnapshotStoreEncryptionDefault)
+
+	data := make([]byte, 2*image.MinSnapshotStorePartSize+1)
+	_, err := store.Upload(context.Background(), "big-snap", bytes.NewReader(data))
+	require.Error(t, err)
+	assert.Equal(t, 1, fake.aborted)
+	assert.Empty(t, fake.uploads)
+	assert.Empty(t, fake.objects)
+}
+
+func TestS3SnapshotStoreDownloadMissing(t *testing.T) {
+	srv := httptest.NewServer(newFakeS3())
+	defer srv.Close()
+	store := newTestS3Store(t, srv, model.SnapshotStoreEncryptionDefault)
+
+	err := store.Download(context.Background(), "missing", io.Discard)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestNewS3SnapshotStoreInvalid(t *testing.T) {
+	tests := map[string]image.S3SnapshotStoreConfig{
+		"A missing url should fail.":           {},
+		"An unknown scheme should fail.":       {URL: "ftp://bucket"},
+		"A url without bucket should fail.":    {URL: "s3:///prefix"},
+		"An unknown encryption should fail.":   {URL: "s3://bucket", Encryption: "rot13"},
+		"A kms key without kms should fail.":   {URL: "s3://bucket", KMSKeyID: "key"},
+		"A part size too small should fail.":   {URL: "s3://bucket", PartSize: 1024},
+		"A key id without secret should fail.": {URL: "s3://bucket", AccessKeyID: "AKID"},
+		"An invalid endpoint should fail.":     {URL: "gs://bucket", Endpoint: "localhost:9000"},
+	}
+
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := image.NewS3SnapshotStore(cfg)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -92,6 +92,19 @@ func (m *LocalSnapshotCreator) Create(_ context.Context, opts CreateSnapshotOpti
 		return fmt.Errorf("copying rootfs: %w", err)
 	}
 
+	// Copy the memory and microVM state of live snapshots.
+	var memoryFile, vmStateFile string
+	if opts.MemorySrc != "" {
+		memoryFile = fmt.Sprintf("memory-%s", arch)
+		vmStateFile = fmt.Sprintf("vmstate-%s", arch)
+		if err := copyFile(opts.MemorySrc, filepath.Join(versionDir, memoryFile)); err != nil {
+			return fmt.Errorf("copying memory: %w", err)
+		}
+		if err := copyFile(opts.VMStateSrc, filepath.Join(versionDir, vmStateFile)); err != nil {
+			return fmt.Errorf("copying VM state: %w", err)
+		}
+	}
+
 	// Copy firecracker binary if available.
 	if opts.FirecrackerSrc != "" {
 		fcDst := filepath.Join(versionDir, "firecracker")
@@ -125,6 +138,7 @@ func (m *LocalSnapshotCreator) Create(_ context.Context, opts CreateSnapshotOpti
 	}
 	fcMeta := firecrackerJSON{}
 	buildMeta := buildJSON{}
+	var profiles []kernelProfileJSON
 
 	if src := opts.SourceManifest; src != nil {
 		if archInfo, ok := src.Artifacts[arch]; ok {
@@ -138,6 +152,10 @@ func (m *LocalSnapshotCreator) Create(_ context.Context, opts CreateSnapshotOpti
 		fcMeta.Source = src.Firecracker.Source
 		buildMeta.Date = src.Build.Date
 		buildMeta.Commit = src.Build.Commit
+		// The snapshot boots the source kernel, it keeps the source kernel profiles.
+		if len(src.Profiles) > 0 {
+			profiles = kernelProfilesJSON(src.Profiles)
+		}
 	}
 
 	mj := manifestJSON{
@@ -149,13 +167,16 @@ func (m *LocalSnapshotCreator) Create(_ context.Context, opts CreateSnapshotOpti
 				Rootfs: rootfsMeta,
 			},
 		},
-		FC:    fcMeta,
-		Build: buildMeta,
+		FC:       fcMeta,
+		Build:    buildMeta,
+		Profiles: profiles,
 		Snapshot: &snapshotInfoJSON{
 			SourceSandboxID:   opts.SourceSandboxID,
 			SourceSandboxName: opts.SourceSandboxName,
 			SourceImage:       opts.SourceImage,
 			ParentSnapshot:    opts.ParentSnapshot,
+			MemoryFile:        memoryFile,
+			VMStateFile:       vmStateFile,
 			CreatedAt:         time.Now().UTC().Format(time.RFC3339),
 		},
 	}
@@ -170,6 +191,17 @@ func (m *LocalSnapshotCreator) Create(_ context.Context, opts CreateSnapshotOpti
 		return fmt.Errorf("writing manifest: %w", err)
 	}
 
+	// Deduplicate the artifacts shared with other images (e.g. the source image kernel).
+	for _, file := range []string{kernelFile, rootfsFile, "firecracker"} {
+		path := filepath.Join(versionDir, file)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if err := storeBlob(m.imagesDir, path, "", m.logger); err != nil {
+			m.logger.Warningf("Could not deduplicate %s: %v", file, err)
+		}
+	}
+
 	success = true
 	return nil
 }
@@ -72,6 +72,7 @@ func TestLocalSnapshotCreatorCreate(t *testing.T) {
 						},
 						Firecracker: model.FirecrackerInfo{Version: "1.10.1", Source: "github"},
 						Build:       model.BuildInfo{Date: "2026-01-01", Commit: "abc123"},
+						Profiles:    []model.KernelProfile{{Name: "tracing", KernelArgs: []string{"ftrace=function"}}},
 					},
 				}
 			},
@@ -127,6 +128,10 @@ func TestLocalSnapshotCreatorCreate(t *testing.T) {
 				fc, ok := mj["firecracker"].(map[string]any)
 				require.True(t, ok)
 				assert.Equal(t, "1.10.1", fc["version"])
+
+				profiles, ok := mj["profiles"].([]any)
+				require.True(t, ok)
+				assert.Equal(t, []any{map[string]any{"name": "tracing", "kernel_args": []any{"ftrace=function"}}}, profiles)
 			},
 		},
 
@@ -166,6 +171,57 @@ func TestLocalSnapshotCreatorCreate(t *testing.T) {
 			},
 		},
 
+		"Live snapshots should copy the memory and VM state and record them in the manifest.": {
+			opts: func(imagesDir string) image.CreateSnapshotOptions {
+				return image.CreateSnapshotOptions{
+					Name:              "live-snap",
+					KernelSrc:         kernelSrc,
+					RootFSSrc:         rootfsSrc,
+					MemorySrc:         kernelSrc,
+					VMStateSrc:        fcSrc,
+					SourceSandboxID:   "01JKQWERTYASDFGZXCVBNMLKJH",
+					SourceSandboxName: "test-sb",
+				}
+			},
+			assertions: func(t *testing.T, imagesDir string) {
+				vDir := filepath.Join(imagesDir, "live-snap")
+				arch := image.HostArch()
+
+				data, err := os.ReadFile(filepath.Join(vDir, "memory-"+arch))
+				require.NoError(t, err)
+				assert.Equal(t, "fake-kernel-data", string(data))
+				data, err = os.ReadFile(filepath.Join(vDir, "vmstate-"+arch))
+				require.NoError(t, err)
+				assert.Equal(t, "fake-fc-binary", string(data))
+
+				mgr, err := image.NewLocalImageManager(image.LocalImageManagerConfig{ImagesDir: imagesDir})
+				require.NoError(t, err)
+				manifest, err := mgr.GetManifest(context.Background(), "live-snap")
+				require.NoError(t, err)
+				require.NotNil(t, manifest.Snapshot)
+				assert.Equal(t, "memory-"+arch, manifest.Snapshot.MemoryFile)
+				assert.Equal(t, "vmstate-"+arch, manifest.Snapshot.VMStateFile)
+			},
+		},
+
+		"Missing VM state source of a live snapshot should fail and clean up.": {
+			opts: func(imagesDir string) image.CreateSnapshotOptions {
+				return image.CreateSnapshotOptions{
+					Name:       "fail-live",
+					KernelSrc:  kernelSrc,
+					RootFSSrc:  rootfsSrc,
+					MemorySrc:  kernelSrc,
+					VMStateSrc: "/nonexistent/vmstate",
+				}
+			},
+			expErr:    true,
+			expErrMsg: "copying VM state",
+			assertions: func(t *testing.T, imagesDir string) {
+				_, err := os.Stat(filepath.Join(imagesDir, "fail-live"))
+				assert.True(t, os.IsNotExist(err), "snapshot dir should be cleaned up on failure")
+			},
+		},
+
 		"Invalid image name should fail.": {
 			opts: func(imagesDir string) image.CreateSnapshotOptions {
 				return image.CreateSnapshotOptions{
@@ -0,0 +1,199 @@
+// Package ingress routes the HTTP requests of the host to the sandbox services by
+// hostname, http://<sandbox-name>.localhost:8080 reaches the service the sandbox
+// exposes, so many sandboxes can be reached without managing port numbers.
+package ingress
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+// DefaultAddress is the default ingress listen address.
+const DefaultAddress = "localhost:8080"
+
+// DefaultDomain is the default domain of the ingress hostnames, the *.localhost
+// names resolve to the loopback without DNS configuration.
+const DefaultDomain = "localhost"
+
+// hostLabelRegexp matches the DNS labels the sandbox names must be to be routed.
+var hostLabelRegexp = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// ServerConfig is the configuration of the ingress server.
+type ServerConfig struct {
+	// Address is the listen address (default: localhost:8080).
+	Address string
+	// Domain is the domain of the hostnames (default: localhost).
+	Domain string
+	// TLS serves https with the configuration, the sandboxes get plain HTTP
+	// (optional).
+	TLS    *tls.Config
+	Logger log.Logger
+}
+
+func (c *ServerConfig) defaults() error {
+	if c.Address == "" {
+		c.Address = DefaultAddress
+	}
+	if c.Domain == "" {
+		c.Domain = DefaultDomain
+	}
+	c.Domain = strings.ToLower(strings.Trim(c.Domain, "."))
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "ingress.Server"})
+	return nil
+}
+
+// Server is an HTTP reverse proxy that routes the requests by the hostname
+// subdomain to the route targets (the local end of the sandbox port forwards).
+type Server struct {
+	server   *http.Server
+	listener net.Listener
+	domain   string
+	scheme   string
+	logger   log.Logger
+
+	mu     sync.RWMutex
+	routes map[string]*httputil.ReverseProxy
+}
+
+// NewServer listens on the ingress address and serves the routes in the
+// background until closed.
+func NewServer(cfg ServerConfig) (*Server, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	l, err := net.Listen("tcp", cfg.Address)
+	if err != nil {
+		return nil, fmt.Errorf("could not listen on %s: %w", cfg.Address, err)
+	}
+
+	scheme := "http"
+	if cfg.TLS != nil {
+		l = tls.NewListener(l, cfg.TLS)
+		scheme = "https"
+	}
+
+	s := &Server{
+		listener: l,
+		domain:   cfg.Domain,
+		scheme:   scheme,
+		logger:   cfg.Logger,
+		routes:   map[string]*httputil.ReverseProxy{},
+	}
+	s.server = &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+
+	go func() {
+		if err := s.server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Errorf("Ingress server failed: %v", err)
+		}
+	}()
+
+	return s, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() net.Addr { return s.listener.Addr() }
+
+// URL returns the ingress URL of a route name.
+func (s *Server) URL(name string) string {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return fmt.Sprintf("%s://%s.%s:%s", s.scheme, name, s.domain, port)
+}
+
+// AddRoute routes the requests of the name hostname (name.domain) to the target
+// address (host:port). The name must be a DNS label.
+func (s *Server) AddRoute(name, target string) error {
+	if !hostLabelRegexp.MatchString(name) {
+		return fmt.Errorf("%q is not a valid hostname label, use up to 63 lowercase alphanumeric characters and hyphens: %w", name, model.ErrNotValid)
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(&url.URL{Scheme: "http", Host: target})
+			r.SetXForwarded()
+			// The services see the ingress hostname, like behind a regular ingress.
+			r.Out.Host = r.In.Host
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			s.logger.Warningf("Could not proxy %s request to %s: %v", name, target, err)
+			http.Error(w, fmt.Sprintf("sandbox %s is not reachable", name), http.StatusBadGateway)
+		},
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.routes[name]; ok {
+		return fmt.Errorf("%s is already exposed on the ingress: %w", name, model.ErrAlreadyExists)
+	}
+	s.routes[name] = proxy
+	s.logger.Debugf("Routing %s to %s", s.URL(name), target)
+
+	return nil
+}
+
+// RemoveRoute stops routing the name hostname.
+func (s *Server) RemoveRoute(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.routes, name)
+}
+
+// ServeHTTP proxies the request to the route of its hostname.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, ok := s.routeName(r.Host)
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown host %q, use %s://<sandbox-name>.%s", r.Host, s.scheme, s.domain), http.StatusNotFound)
+		return
+	}
+
+	s.mu.RLock()
+	proxy, ok := s.routes[name]
+	s.mu.RUnlock()
+	if !ok {
+		http.Error(w, fmt.Sprintf("sandbox %s is not exposed", name), http.StatusNotFound)
+		return
+	}
+
+	proxy.ServeHTTP(w, r)
+}
+
+// routeName returns the route name of a request host (name.domain[:port]).
+func (s *Server) routeName(host string) (string, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	name, ok := strings.CutSuffix(host, "."+s.domain)
+	if !ok || name == "" || strings.Contains(name, ".") {
+		return "", false
+	}
+	return name, true
+}
+
+// Close stops the server, closing the listener and the active connections.
+func (s *Server) Close(ctx context.Context) error {
+	if err := s.server.Shutdown(ctx); err != nil {
+		return s.server.Close()
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package ingress_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/ingress"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/tlscert"
+)
+
+func TestServerRouting(t *testing.T) {
+	backend := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s %s %s %s", name, r.Host, r.URL.Path, r.Header.Get("X-Forwarded-Host"))
+		}))
+	}
+	web := backend("web")
+	defer web.Close()
+	api := backend("api")
+	defer api.Close()
+
+	srv, err := ingress.NewServer(ingress.ServerConfig{Address: "127.0.0.1:0"})
+	require.NoError(t, err)
+	defer srv.Close(context.Background())
+
+	require.NoError(t, srv.AddRoute("web", strings.TrimPrefix(web.URL, "http://")))
+	require.NoError(t, srv.AddRoute("api-1", strings.TrimPrefix(api.URL, "http://")))
+
+	get := func(host, path string) (int, string) {
+		req, err := http.NewRequest(http.MethodGet, "http://"+srv.Addr().String()+path, nil)
+		require.NoError(t, err)
+		req.Host = host
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	tests := map[string]struct {
+		host      string
+		expStatus int
+		expBody   string
+	}{
+		"A sandbox hostname should be routed to its target.": {
+			host:      "web.localhost:8080",
+			expStatus: http.StatusOK,
+			expBody:   "web web.localhost:8080 /index.html web.localhost:8080",
+		},
+		"Hostnames should be case insensitive.": {
+			host:      "API-1.localhost",
+			expStatus: http.StatusOK,
+			expBody:   "api API-1.localhost /index.html API-1.localhost",
+		},
+		"A not exposed sandbox should not be found.": {
+			host:      "db.localhost",
+			expStatus: http.StatusNotFound,
+		},
+		"A host of another domain should not be found.": {
+			host:      "web.example.com",
+			expStatus: http.StatusNotFound,
+		},
+		"A nested subdomain should not be found.": {
+			host:      "a.web.localhost",
+			expStatus: http.StatusNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			status, body := get(test.host, "/index.html")
+			assert.Equal(t, test.expStatus, status)
+			if test.expBody != "" {
+				assert.Equal(t, test.expBody, body)
+			}
+		})
+	}
+
+	t.Run("A removed route should not be found.", func(t *testing.T) {
+		srv.RemoveRoute("web")
+		status, _ := get("web.localhost", "/")
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+}
+
+func TestServerAddRoute(t *testing.T) {
+	srv, err := ingress.NewServer(ingress.ServerConfig{Address: "127.0.0.1:0"})
+	require.NoError(t, err)
+	defer srv.Close(context.Background())
+
+	require.NoError(t, srv.AddRoute("web", "127.0.0.1:3000"))
+	assert.ErrorIs(t, srv.AddRoute("web", "127.0.0.1:3001"), model.ErrAlreadyExists)
+	assert.ErrorIs(t, srv.AddRoute("my_sandbox", "127.0.0.1:3000"), model.ErrNotValid)
+	assert.ErrorIs(t, srv.AddRoute("-web", "127.0.0.1:3000"), model.ErrNotValid)
+
+	_, port, _ := strings.Cut(srv.Addr().String(), ":")
+	assert.Equal(t, "http://web.localhost:"+port, srv.URL("web"))
+}
+
+func TestServerUnreachableTarget(t *testing.T) {
+	srv, err := ingress.NewServer(ingress.ServerConfig{Address: "127.0.0.1:0"})
+	require.NoError(t, err)
+	defer srv.Close(context.Background())
+
+	// A closed server address.
+	closed := httptest.NewServer(http.NotFoundHandler())
+	target := strings.TrimPrefix(closed.URL, "http://")
+	closed.Close()
+	require.NoError(t, srv.AddRoute("web", target))
+
+	req, err := http.NewRequest(http.MethodGet, "http://"+srv.Addr().String(), nil)
+	require.NoError(t, err)
+	req.Host = "web.localhost"
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+}
+
+func TestServerTLS(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Host, r.Header.Get("X-Forwarded-Proto"))
+	}))
+	defer backend.Close()
+
+	certFile, keyFile, err := tlscert.EnsureSelfSigned(t.TempDir())
+	require.NoError(err)
+	tlsConfig, err := tlscert.ServerConfig(certFile, keyFile)
+	require.NoError(err)
+
+	srv, err := ingress.NewServer(ingress.ServerConfig{Address: "127.0.0.1:0", TLS: tlsConfig})
+	require.NoError(err)
+	defer srv.Close(context.Background())
+	require.NoError(srv.AddRoute("web", strings.TrimPrefix(backend.URL, "http://")))
+	assert.True(strings.HasPrefix(srv.URL("web"), "https://web.localhost:"))
+
+	// The clients trusting the generated certificate verify the sandbox hostnames.
+	certPEM, err := os.ReadFile(certFile)
+	require.NoError(err)
+	roots := x509.NewCertPool()
+	require.True(roots.AppendCertsFromPEM(certPEM))
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: roots},
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, srv.Addr().String())
+		},
+	}}
+
+	resp, err := client.Get(srv.URL("web"))
+	require.NoError(err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal(strings.TrimPrefix(srv.URL("web"), "https://")+" https", string(body))
+}
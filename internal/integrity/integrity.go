@@ -0,0 +1,98 @@
+// Package integrity hashes the guest files of a sandbox and compares them with a
+// previous baseline, to detect the files tampered by the sandbox workload.
+//
+// The files are hashed in the guest with find and sha256sum (available in the
+// busybox based images), only the regular files are hashed, the symlinks are not
+// followed and the other filesystems mounted under the paths are not crossed.
+package integrity
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+)
+
+// Collect returns the SHA256 digests of the regular files under the guest paths,
+// keyed by their guest path. The missing paths are skipped.
+func Collect(ctx context.Context, eng sandbox.Engine, sandboxID string, paths []string) (map[string]string, error) {
+	var stdout, stderr bytes.Buffer
+	result, err := eng.Exec(ctx, sandboxID, []string{"sh", "-c", Script(paths)}, model.ExecOpts{Stdout: &stdout, Stderr: &stderr})
+	if err != nil {
+		return nil, fmt.Errorf("could not execute integrity script: %w", err)
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("integrity script failed with exit code %d: %s", result.ExitCode, strings.TrimSpace(stderr.String()))
+	}
+
+	return Parse(stdout.String())
+}
+
+// Script returns the guest script printing the sha256sum lines of the regular
+// files under the paths.
+func Script(paths []string) string {
+	var b strings.Builder
+	b.WriteString("set -e\n")
+	b.WriteString("for p in")
+	for _, p := range paths {
+		b.WriteString(" '")
+		b.WriteString(strings.ReplaceAll(p, "'", `'"'"'`))
+		b.WriteString("'")
+	}
+	b.WriteString("; do\n")
+	b.WriteString("  [ -e \"$p\" ] || continue\n")
+	b.WriteString("  find \"$p\" -xdev -type f -exec sha256sum {} +\n")
+	b.WriteString("done\n")
+	return b.String()
+}
+
+// Parse parses the sha256sum output lines into the digests keyed by path. The
+// escaped lines (paths with a backslash or a new line) are unescaped like
+// sha256sum does.
+func Parse(out string) (map[string]string, error) {
+	files := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		if line == "" {
+			continue
+		}
+		escaped := strings.HasPrefix(line, `\`)
+		if escaped {
+			line = line[1:]
+		}
+		digest, p, ok := strings.Cut(line, "  ")
+		if !ok || len(digest) != 64 || p == "" {
+			return nil, fmt.Errorf("unexpected sha256sum output line: %q", line)
+		}
+		if escaped {
+			p = strings.NewReplacer(`\\`, `\`, `\n`, "\n").Replace(p)
+		}
+		files[p] = digest
+	}
+	return files, nil
+}
+
+// Compare returns the files changed from the baseline to the current digests,
+// sorted by path.
+func Compare(baseline, current map[string]string) []model.IntegrityChange {
+	changes := []model.IntegrityChange{}
+	for p, old := range baseline {
+		now, ok := current[p]
+		switch {
+		case !ok:
+			changes = append(changes, model.IntegrityChange{Path: p, Kind: model.FileChangeRemoved, OldSHA256: old})
+		case now != old:
+			changes = append(changes, model.IntegrityChange{Path: p, Kind: model.FileChangeModified, OldSHA256: old, NewSHA256: now})
+		}
+	}
+	for p, now := range current {
+		if _, ok := baseline[p]; !ok {
+			changes = append(changes, model.IntegrityChange{Path: p, Kind: model.FileChangeAdded, NewSHA256: now})
+		}
+	}
+	slices.SortFunc(changes, func(a, b model.IntegrityChange) int { return strings.Compare(a.Path, b.Path) })
+	return changes
+}
@@ -0,0 +1,92 @@
+package integrity_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/integrity"
+	"github.com/slok/sbx/internal/model"
+)
+
+const (
+	helloSHA = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	byeSHA   = "b49f425a7e1f9cff3856329ada223f2f9d368f15a00cf48df16ca95986137fe8"
+)
+
+func TestScript(t *testing.T) {
+	if _, err := exec.LookPath("sha256sum"); err != nil {
+		t.Skip("sha256sum not available")
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "etc", "it's"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "etc", "motd"), []byte("hello"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "etc", "it's", "conf"), []byte("bye"), 0o644))
+	require.NoError(t, os.Symlink("motd", filepath.Join(dir, "etc", "link")))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app"), []byte("hello"), 0o755))
+
+	paths := []string{filepath.Join(dir, "etc"), filepath.Join(dir, "app"), filepath.Join(dir, "missing")}
+	out, err := exec.Command("sh", "-c", integrity.Script(paths)).Output()
+	require.NoError(t, err)
+
+	files, err := integrity.Parse(string(out))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		filepath.Join(dir, "etc", "motd"):         helloSHA,
+		filepath.Join(dir, "etc", "it's", "conf"): byeSHA,
+		filepath.Join(dir, "app"):                 helloSHA,
+	}, files)
+}
+
+func TestParse(t *testing.T) {
+	tests := map[string]struct {
+		out      string
+		expFiles map[string]string
+		expErr   bool
+	}{
+		"Empty output should have no files.": {
+			expFiles: map[string]string{},
+		},
+		"Lines should be parsed by path.": {
+			out:      helloSHA + "  /etc/motd\n" + byeSHA + "  /etc/with  spaces\n",
+			expFiles: map[string]string{"/etc/motd": helloSHA, "/etc/with  spaces": byeSHA},
+		},
+		"Escaped lines should be unescaped.": {
+			out:      `\` + helloSHA + `  /etc/new\nline\\x` + "\n",
+			expFiles: map[string]string{"/etc/new\nline\\x": helloSHA},
+		},
+		"An unexpected line should fail.": {
+			out:    "sha256sum: /etc/shadow: Permission denied\n",
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, err := integrity.Parse(test.out)
+			if test.expErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expFiles, files)
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	baseline := map[string]string{"/etc/motd": helloSHA, "/etc/removed": byeSHA, "/etc/same": byeSHA}
+	current := map[string]string{"/etc/motd": byeSHA, "/etc/added": helloSHA, "/etc/same": byeSHA}
+
+	assert.Equal(t, []model.IntegrityChange{
+		{Path: "/etc/added", Kind: model.FileChangeAdded, NewSHA256: helloSHA},
+		{Path: "/etc/motd", Kind: model.FileChangeModified, OldSHA256: helloSHA, NewSHA256: byeSHA},
+		{Path: "/etc/removed", Kind: model.FileChangeRemoved, OldSHA256: byeSHA},
+	}, integrity.Compare(baseline, current))
+	assert.Equal(t, []model.IntegrityChange{}, integrity.Compare(baseline, baseline))
+}
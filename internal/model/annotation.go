@@ -0,0 +1,46 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+)
+
+const (
+	// AnnotationKeyMaxLen is the maximum length of an annotation key.
+	AnnotationKeyMaxLen = 128
+	// AnnotationsMaxSize is the maximum size of all the annotations of a sandbox
+	// (keys and values).
+	AnnotationsMaxSize = 256 * 1024
+)
+
+var annotationKeyRegexp = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9._/-]*[a-zA-Z0-9])?$`)
+
+// ValidateAnnotations validates the annotations of a sandbox. Annotations are free-form
+// metadata (ticket URLs, owners, experiment IDs...), the values can be any text.
+func ValidateAnnotations(annotations map[string]string) error {
+	size := 0
+	for k, v := range annotations {
+		if err := ValidateAnnotationKey(k); err != nil {
+			return err
+		}
+		size += len(k) + len(v)
+	}
+	if size > AnnotationsMaxSize {
+		return fmt.Errorf("annotations are %d bytes, the maximum is %d: %w", size, AnnotationsMaxSize, ErrNotValid)
+	}
+
+	return nil
+}
+
+// ValidateAnnotationKey validates an annotation key: up to 128 alphanumeric, '.', '_',
+// '/' or '-' characters, starting and ending with an alphanumeric character.
+func ValidateAnnotationKey(key string) error {
+	if len(key) > AnnotationKeyMaxLen {
+		return fmt.Errorf("annotation key %q is longer than %d characters: %w", key, AnnotationKeyMaxLen, ErrNotValid)
+	}
+	if !annotationKeyRegexp.MatchString(key) {
+		return fmt.Errorf("invalid annotation key %q, must use [a-zA-Z0-9._/-] and start and end with an alphanumeric character: %w", key, ErrNotValid)
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestValidateAnnotations(t *testing.T) {
+	tests := map[string]struct {
+		annotations map[string]string
+		expErr      bool
+	}{
+		"No annotations should be valid.": {},
+
+		"Annotations with free-form values should be valid.": {
+			annotations: map[string]string{
+				"owner":                 "alice@example.com",
+				"ticket":                "https://issues.example.com/42",
+				"example.com/exp-id_v2": "run 7: warm cache\nsecond line",
+				"empty":                 "",
+			},
+		},
+
+		"An empty key should fail.": {
+			annotations: map[string]string{"": "value"},
+			expErr:      true,
+		},
+
+		"A key with spaces should fail.": {
+			annotations: map[string]string{"my key": "value"},
+			expErr:      true,
+		},
+
+		"A key not ending with an alphanumeric character should fail.": {
+			annotations: map[string]string{"owner-": "value"},
+			expErr:      true,
+		},
+
+		"A too long key should fail.": {
+			annotations: map[string]string{strings.Repeat("a", 129): "value"},
+			expErr:      true,
+		},
+
+		"Too big annotations should fail.": {
+			annotations: map[string]string{"notes": strings.Repeat("a", model.AnnotationsMaxSize)},
+			expErr:      true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := model.ValidateAnnotations(test.annotations)
+			if test.expErr {
+				assert.ErrorIs(t, err, model.ErrNotValid)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
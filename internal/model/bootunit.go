@@ -0,0 +1,34 @@
+package model
+
+import "fmt"
+
+// BootRestartPolicy is what systemd does when the start of a sandbox boot unit fails.
+type BootRestartPolicy string
+
+const (
+	// BootRestartPolicyNo leaves the sandbox stopped when its start fails.
+	BootRestartPolicyNo BootRestartPolicy = "no"
+	// BootRestartPolicyOnFailure retries the failed starts (e.g. the host network
+	// is not ready yet), up to the systemd start limit.
+	BootRestartPolicyOnFailure BootRestartPolicy = "on-failure"
+)
+
+// Validate validates the restart policy.
+func (p BootRestartPolicy) Validate() error {
+	switch p {
+	case BootRestartPolicyNo, BootRestartPolicyOnFailure:
+		return nil
+	}
+	return fmt.Errorf("boot restart policy must be %q or %q, got %q: %w", BootRestartPolicyNo, BootRestartPolicyOnFailure, p, ErrNotValid)
+}
+
+// BootUnit is the systemd unit that starts a sandbox on the host boot.
+type BootUnit struct {
+	// Name is the unit name (e.g. sbx-01H2QWERTYASDFGZXCVBNMLKJH.service).
+	Name string
+	// Path is the unit file path.
+	Path        string
+	SandboxID   string
+	SandboxName string
+	Restart     BootRestartPolicy
+}
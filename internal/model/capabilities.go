@@ -0,0 +1,35 @@
+package model
+
+// EngineCapabilities describes the optional features an engine supports.
+type EngineCapabilities struct {
+	// Snapshots is true when stopped sandboxes can be snapshotted into images.
+	Snapshots bool
+	// LiveSnapshots is true when running sandboxes can be snapshotted without
+	// stopping them, recording their disk and memory.
+	LiveSnapshots bool
+	// PauseResume is true when running sandboxes can be paused and resumed keeping
+	// their memory.
+	PauseResume bool
+	// Egress is true when the sandbox network egress can be filtered.
+	Egress bool
+	// Networks is true when sandboxes can have additional network interfaces.
+	Networks bool
+	// PortForward is true when host ports can be forwarded to sandboxes.
+	PortForward bool
+	// MemoryBalloon is true when the memory of running sandboxes can be reclaimed.
+	MemoryBalloon bool
+	// Scaling is true when the vCPUs and memory of running sandboxes can be scaled
+	// up to their resource ceilings.
+	Scaling bool
+	// EngineAPI is true when the VMM machine API of running sandboxes can be used
+	// directly.
+	EngineAPI bool
+	// Tty is true when commands can be executed with an interactive terminal.
+	Tty bool
+	// Mounts is true when host directories can be mounted in sandboxes.
+	Mounts bool
+	// Vsock is true when the host and the sandboxes can talk over vsock.
+	Vsock bool
+	// GPU is true when GPUs can be attached to sandboxes.
+	GPU bool
+}
@@ -0,0 +1,104 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// HostCapacity is the host compute capacity and its allocation to the sandboxes.
+type HostCapacity struct {
+	// Total are the host resources.
+	Total Resources
+	// Allocated are the resources allocated to the sandboxes. VCPUs and memory are
+	// allocated by the running sandboxes, disk by all of them.
+	Allocated Resources
+	// Overcommit are the ratios applied to the host resources to get the allocatable ones.
+	Overcommit OvercommitRatios
+	// Sandboxes is the number of sandboxes.
+	Sandboxes int
+	// RunningSandboxes is the number of running sandboxes.
+	RunningSandboxes int
+}
+
+// Allocatable returns the host resources that can be allocated with the overcommit ratios.
+func (c HostCapacity) Allocatable() Resources {
+	return Resources{
+		VCPUs:    c.Total.VCPUs * c.Overcommit.VCPUs,
+		MemoryMB: int(math.Floor(float64(c.Total.MemoryMB) * c.Overcommit.Memory)),
+		DiskGB:   int(math.Floor(float64(c.Total.DiskGB) * c.Overcommit.Disk)),
+	}
+}
+
+// Free returns the allocatable resources not allocated yet, they can be negative when
+// the host is overcommitted beyond the ratios.
+func (c HostCapacity) Free() Resources {
+	a := c.Allocatable()
+	return Resources{
+		VCPUs:    a.VCPUs - c.Allocated.VCPUs,
+		MemoryMB: a.MemoryMB - c.Allocated.MemoryMB,
+		DiskGB:   a.DiskGB - c.Allocated.DiskGB,
+	}
+}
+
+// Reservation are the host resources reserved by a sandbox, for the external
+// schedulers placing other workloads on the same host.
+type Reservation struct {
+	SandboxID string
+	Name      string
+	Namespace string
+	Status    SandboxStatus
+	// Resources are the resources configured in the sandbox.
+	Resources Resources
+	// Reserved are the resources the sandbox holds now, allocated like the host
+	// capacity ones: the disk always, the VCPUs and memory while it runs.
+	Reserved  Resources
+	UpdatedAt time.Time
+}
+
+// NewReservation returns the reservation of a sandbox.
+func NewReservation(sb Sandbox, now time.Time) Reservation {
+	res := sb.Config.Resources
+	r := Reservation{
+		SandboxID: sb.ID,
+		Name:      sb.Name,
+		Namespace: sb.Namespace,
+		Status:    sb.Status,
+		Resources: Resources{VCPUs: res.VCPUs, MemoryMB: res.MemoryMB, DiskGB: res.DiskGB},
+		Reserved:  Resources{DiskGB: res.DiskGB},
+		UpdatedAt: now,
+	}
+	if sb.Status == SandboxStatusRunning {
+		r.Reserved.VCPUs = res.VCPUs
+		r.Reserved.MemoryMB = res.MemoryMB
+	}
+	return r
+}
+
+// OvercommitRatios are the ratios of the host resources that can be allocated to the
+// sandboxes, e.g. 2 allows allocating twice the host VCPUs.
+type OvercommitRatios struct {
+	VCPUs  float64
+	Memory float64
+	Disk   float64
+}
+
+// Validate validates the overcommit ratios.
+func (o OvercommitRatios) Validate() error {
+	if o.VCPUs <= 0 || o.Memory <= 0 || o.Disk <= 0 {
+		return fmt.Errorf("overcommit ratios must be positive: %w", ErrNotValid)
+	}
+	return nil
+}
+
+// AdmissionMode is how sandboxes exceeding the host capacity are handled on create and start.
+type AdmissionMode string
+
+const (
+	// AdmissionModeOff doesn't check the host capacity.
+	AdmissionModeOff AdmissionMode = "off"
+	// AdmissionModeWarn logs a warning when the host capacity is exceeded.
+	AdmissionModeWarn AdmissionMode = "warn"
+	// AdmissionModeEnforce refuses the sandboxes that exceed the host capacity.
+	AdmissionModeEnforce AdmissionMode = "enforce"
+)
@@ -0,0 +1,46 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestNewReservation(t *testing.T) {
+	now := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	res := model.Resources{VCPUs: 2, MemoryMB: 2048, DiskGB: 10, MaxVCPUs: 4}
+
+	tests := map[string]struct {
+		status      model.SandboxStatus
+		expReserved model.Resources
+	}{
+		"A running sandbox should reserve all its resources.": {
+			status:      model.SandboxStatusRunning,
+			expReserved: model.Resources{VCPUs: 2, MemoryMB: 2048, DiskGB: 10},
+		},
+		"A stopped sandbox should only reserve its disk.": {
+			status:      model.SandboxStatusStopped,
+			expReserved: model.Resources{DiskGB: 10},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			sb := model.Sandbox{ID: "id-1", Name: "sb-1", Namespace: "team-a", Status: test.status, Config: model.SandboxConfig{Resources: res}}
+
+			r := model.NewReservation(sb, now)
+			assert.Equal(t, model.Reservation{
+				SandboxID: "id-1",
+				Name:      "sb-1",
+				Namespace: "team-a",
+				Status:    test.status,
+				Resources: model.Resources{VCPUs: 2, MemoryMB: 2048, DiskGB: 10},
+				Reserved:  test.expReserved,
+				UpdatedAt: now,
+			}, r)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultCaptureSnapLen is the bytes captured of each packet by default, enough for
+// the whole packets of the sandbox interfaces.
+const DefaultCaptureSnapLen = 262144
+
+var captureInterfaceRegexp = regexp.MustCompile(`^eth[0-9]$`)
+
+// CaptureOpts contains options for capturing the network traffic of a sandbox.
+type CaptureOpts struct {
+	// Interface is the guest network interface captured (eth0, eth1...), empty is
+	// the primary one (eth0).
+	Interface string
+	// Filter is a BPF filter expression with the pcap-filter syntax (e.g. "tcp port
+	// 443"), empty captures all the packets.
+	Filter string
+	// Duration stops the capture after it (optional).
+	Duration time.Duration
+	// MaxBytes stops the capture before the pcap stream exceeds it (optional).
+	MaxBytes int64
+	// MaxPackets stops the capture after these packets (optional).
+	MaxPackets int
+	// SnapLen is the bytes captured of each packet, 0 is DefaultCaptureSnapLen.
+	SnapLen int
+}
+
+// Validate validates the capture options.
+func (o CaptureOpts) Validate() error {
+	if o.Interface != "" && !captureInterfaceRegexp.MatchString(o.Interface) {
+		return fmt.Errorf("interface %q is not valid (eth0, eth1...): %w", o.Interface, ErrNotValid)
+	}
+	// The filter is a capture tool argument, it can't be taken as an option.
+	if strings.HasPrefix(strings.TrimSpace(o.Filter), "-") {
+		return fmt.Errorf("filter %q can't start with '-': %w", o.Filter, ErrNotValid)
+	}
+	if o.Duration < 0 {
+		return fmt.Errorf("duration can't be negative: %w", ErrNotValid)
+	}
+	if o.MaxBytes < 0 {
+		return fmt.Errorf("max bytes can't be negative: %w", ErrNotValid)
+	}
+	if o.MaxPackets < 0 {
+		return fmt.Errorf("max packets can't be negative: %w", ErrNotValid)
+	}
+	if o.SnapLen < 0 || o.SnapLen > DefaultCaptureSnapLen {
+		return fmt.Errorf("snap length must be between 0 and %d: %w", DefaultCaptureSnapLen, ErrNotValid)
+	}
+	return nil
+}
+
+// CaptureResult is the result of a sandbox network traffic capture.
+type CaptureResult struct {
+	// Interface is the captured guest network interface.
+	Interface string
+	// Device is the captured host TAP device of the interface.
+	Device string
+	// Packets is the number of packets written.
+	Packets int
+	// Bytes is the size of the written pcap stream.
+	Bytes int64
+	// StartedAt is when the capture started.
+	StartedAt time.Time
+	// Duration is how long the capture ran.
+	Duration time.Duration
+	// LimitReached is true when the capture stopped on the bytes or packets limit.
+	LimitReached bool
+}
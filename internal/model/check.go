@@ -12,11 +12,37 @@ const (
 	CheckStatusError CheckStatus = "error"
 )
 
+// CheckSeverity is the machine-readable severity of a preflight check status, used
+// by scripts gating on the results: only the fail severity is a hard failure.
+type CheckSeverity string
+
+const (
+	// CheckSeverityPass is the severity of the passed checks.
+	CheckSeverityPass CheckSeverity = "pass"
+	// CheckSeverityWarn is the severity of the checks passed with a warning.
+	CheckSeverityWarn CheckSeverity = "warn"
+	// CheckSeverityFail is the severity of the failed checks.
+	CheckSeverityFail CheckSeverity = "fail"
+)
+
+// Severity returns the severity of the check status.
+func (s CheckStatus) Severity() CheckSeverity {
+	switch s {
+	case CheckStatusOK:
+		return CheckSeverityPass
+	case CheckStatusWarning:
+		return CheckSeverityWarn
+	default:
+		return CheckSeverityFail
+	}
+}
+
 // CheckResult represents the result of a single preflight check.
 type CheckResult struct {
-	ID      string      // Unique identifier for the check (e.g., "kvm_available").
-	Message string      // Human-readable description of the result.
-	Status  CheckStatus // Status of the check.
+	ID          string      // Unique identifier for the check (e.g., "kvm_available").
+	Message     string      // Human-readable description of the result.
+	Status      CheckStatus // Status of the check.
+	Remediation string      // How to fix a warning or error (optional).
 }
 
 // HasErrors returns true if any check result has an error status.
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// Checkpoint is the state of a running sandbox captured without stopping it.
+type Checkpoint struct {
+	// RootFSPath is the copy of the sandbox disk.
+	RootFSPath string
+	// MemoryPath is the guest memory file.
+	MemoryPath string
+	// VMStatePath is the engine microVM state file (devices, vCPUs...).
+	VMStatePath string
+	// Paused is how long the sandbox was paused to capture the state.
+	Paused time.Duration
+}
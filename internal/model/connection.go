@@ -0,0 +1,40 @@
+package model
+
+// ConnectionDirection is the side of a sandbox connection that opened it.
+type ConnectionDirection string
+
+const (
+	// ConnectionDirectionEgress is a connection opened by the sandbox.
+	ConnectionDirectionEgress ConnectionDirection = "egress"
+	// ConnectionDirectionIngress is a connection opened to the sandbox (e.g. the
+	// engine control connections, the port forwards).
+	ConnectionDirectionIngress ConnectionDirection = "ingress"
+)
+
+// Connection is an active network flow of a sandbox, tracked by the host.
+type Connection struct {
+	// Protocol is the transport protocol (e.g. tcp, udp, icmp).
+	Protocol  string
+	Direction ConnectionDirection
+	// Interface is the sandbox network interface of the flow (e.g. eth0).
+	Interface string
+	// Source is the address that opened the connection (ip:port).
+	Source string
+	// Destination is the address the connection was opened to (ip:port), before
+	// the egress proxy redirection.
+	Destination string
+	// Domain is the destination domain when known, from the egress proxy or the
+	// DNS answers of the sandbox.
+	Domain string
+	// State is the connection tracking state (e.g. ESTABLISHED, TIME_WAIT), empty
+	// for the connectionless protocols.
+	State string
+	// Proxy is the egress proxy forwarding the connection (http, http-connect or
+	// tls), empty when the connection is not proxied.
+	Proxy string
+	// BytesSent and BytesReceived are the bytes sent and received by the connection
+	// source, zero when the host doesn't account the flow traffic and the connection
+	// is not proxied.
+	BytesSent     uint64
+	BytesReceived uint64
+}
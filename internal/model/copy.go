@@ -0,0 +1,26 @@
+package model
+
+// CopyOpts contains options for copying files between the host and a sandbox.
+type CopyOpts struct {
+	// Compress sends the files compressed, faster for compressible files (e.g. source
+	// trees) on slow links. The sandbox needs tar with gzip support.
+	Compress bool
+	// Progress is called with the copy progress (optional).
+	Progress func(CopyProgress)
+	// BandwidthLimit limits the copy rate in bytes per second (0 is unlimited).
+	BandwidthLimit int64
+	// Retries is the number of times the copy is resumed on a new connection when
+	// the SSH connection breaks (0 doesn't retry).
+	Retries int
+	// Checksum verifies the SHA-256 of the copied files against the source. The
+	// sandbox needs sha256sum.
+	Checksum bool
+}
+
+// CopyProgress is the progress of a copy.
+type CopyProgress struct {
+	// Bytes are the file bytes copied so far.
+	Bytes int64
+	// TotalBytes are the file bytes to copy, 0 when unknown.
+	TotalBytes int64
+}
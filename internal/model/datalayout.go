@@ -0,0 +1,51 @@
+package model
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// DataLayout are the host directories holding the sandbox data, they can live on
+// different filesystems (e.g. the VMs on a fast disk and the images on a large one).
+type DataLayout struct {
+	// VMsDir is the directory of the sandbox VMs (rootfs copies, SSH keys and runtime files).
+	VMsDir string
+	// ImagesDir is the directory of the installed images.
+	ImagesDir string
+	// SnapshotsDir is the directory of the snapshot images.
+	SnapshotsDir string
+}
+
+// Validate validates the data layout.
+func (l DataLayout) Validate() error {
+	for name, dir := range map[string]string{"vms": l.VMsDir, "images": l.ImagesDir, "snapshots": l.SnapshotsDir} {
+		if dir == "" {
+			return fmt.Errorf("%s dir is required: %w", name, ErrNotValid)
+		}
+		if !filepath.IsAbs(dir) {
+			return fmt.Errorf("%s dir %q must be absolute: %w", name, dir, ErrNotValid)
+		}
+	}
+	return nil
+}
+
+// DataMoveKind is the kind of data moved by a data layout migration.
+type DataMoveKind string
+
+const (
+	// DataMoveKindVM is the directory of a sandbox VM.
+	DataMoveKindVM DataMoveKind = "vm"
+	// DataMoveKindImage is an installed image.
+	DataMoveKindImage DataMoveKind = "image"
+	// DataMoveKindSnapshot is a snapshot image.
+	DataMoveKindSnapshot DataMoveKind = "snapshot"
+)
+
+// DataMove is a directory moved by a data layout migration.
+type DataMove struct {
+	Kind DataMoveKind
+	// Name is the sandbox name of the VMs or the image name.
+	Name string
+	From string
+	To   string
+}
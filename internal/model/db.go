@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// DBCompactResult is the result of a database compaction.
+type DBCompactResult struct {
+	// SizeBefore is the size in bytes of the database and its write-ahead log
+	// before the compaction.
+	SizeBefore int64
+	// SizeAfter is the size in bytes of the database and its write-ahead log
+	// after the compaction.
+	SizeAfter int64
+	// IntegrityErrors are the problems found by the integrity check, empty when
+	// the database is healthy. A database with problems is not rebuilt.
+	IntegrityErrors []string
+	// Duration is the time the compaction took.
+	Duration time.Duration
+}
@@ -0,0 +1,70 @@
+package model
+
+import "time"
+
+// DNSEventReason is why a DNS query didn't get the upstream answer.
+type DNSEventReason string
+
+const (
+	// DNSEventReasonRuleMatch means the query was denied by the egress rules.
+	DNSEventReasonRuleMatch DNSEventReason = "rule-match"
+	// DNSEventReasonFailClosed means the query was denied because the answer had
+	// non public addresses.
+	DNSEventReasonFailClosed DNSEventReason = "fail-closed"
+	// DNSEventReasonUpstreamError means the upstream resolver failed.
+	DNSEventReasonUpstreamError DNSEventReason = "upstream-error"
+)
+
+// DNSEvent is a DNS query of a sandbox answered by its egress DNS proxy.
+type DNSEvent struct {
+	Time   time.Time
+	Domain string
+	// QType is the query type (e.g. A, AAAA, TXT).
+	QType  string
+	Action EgressAction
+	// Reason is empty when the query got the upstream answer.
+	Reason DNSEventReason
+	// Rcode is the response code sent to the sandbox (e.g. NOERROR, NXDOMAIN, REFUSED).
+	Rcode string
+	// UpstreamLatency is zero when the query was not forwarded upstream.
+	UpstreamLatency time.Duration
+	// Answers are the addresses answered to the sandbox (A and AAAA records).
+	Answers []string
+}
+
+// Failed returns true if the query couldn't be answered by the upstream resolver.
+func (e DNSEvent) Failed() bool { return e.Reason == DNSEventReasonUpstreamError }
+
+// DNSEventFilter filters the DNS events of a sandbox.
+type DNSEventFilter struct {
+	// Since returns the events at or after this time (optional).
+	Since time.Time
+	// Domain returns only the events of this domain (optional).
+	Domain string
+	// Limit returns only the latest N events, 0 means all.
+	Limit int
+}
+
+// DNSDomainStats are the aggregated DNS queries of a domain.
+type DNSDomainStats struct {
+	Domain  string
+	Queries int
+	Allowed int
+	Denied  int
+	// Failed are the queries allowed by the egress policy that the upstream
+	// resolver couldn't answer, they are not counted as allowed.
+	Failed             int
+	AvgUpstreamLatency time.Duration
+	MaxUpstreamLatency time.Duration
+	LastQueryAt        time.Time
+}
+
+// DNSStats are the aggregated DNS queries of a sandbox.
+type DNSStats struct {
+	Queries int
+	Allowed int
+	Denied  int
+	Failed  int
+	// Domains are sorted by queries, most queried first.
+	Domains []DNSDomainStats
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// NamedEgressPolicy is an egress policy stored with a name, so multiple sandboxes
+// can use it by name. Its changes apply on the next start of the sandboxes.
+type NamedEgressPolicy struct {
+	Name      string
+	Policy    EgressPolicy
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+var egressPolicyNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// Validate validates the policy name and rules.
+func (p *NamedEgressPolicy) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("egress policy name is required: %w", ErrNotValid)
+	}
+
+	if !egressPolicyNameRegexp.MatchString(p.Name) {
+		return fmt.Errorf("egress policy name %q is invalid (allowed: [a-zA-Z0-9._-]): %w", p.Name, ErrNotValid)
+	}
+
+	if err := p.Policy.Validate(); err != nil {
+		return fmt.Errorf("invalid egress policy %q: %w", p.Name, err)
+	}
+
+	return nil
+}
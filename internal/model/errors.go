@@ -1,6 +1,11 @@
 package model
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
 
 var (
 	// ErrNotFound is returned when a resource is not found.
@@ -9,4 +14,86 @@ var (
 	ErrAlreadyExists = errors.New("already exists")
 	// ErrNotValid is returned when a resource is not valid.
 	ErrNotValid = errors.New("not valid")
+	// ErrConflict is returned when a resource is being used by another operation.
+	ErrConflict = errors.New("conflict")
+	// ErrBusy is returned when a host resource is locked by another client.
+	ErrBusy = errors.New("busy")
 )
+
+// StartError is returned when a sandbox start step fails. The steps that already
+// ran are rolled back before returning it.
+type StartError struct {
+	// Step is the name of the start step that failed (e.g. network, boot, ssh).
+	Step string
+	// Err is the error of the failed step.
+	Err error
+	// RollbackErr is the error of rolling back the already run steps, nil when the
+	// rollback succeeded.
+	RollbackErr error
+	// Phases are the start phases run until the failure, the failed one last with
+	// its error.
+	Phases []BootPhase
+}
+
+func (e *StartError) Error() string {
+	if e.RollbackErr != nil {
+		return fmt.Sprintf("start step %q failed: %v (rollback failed: %v)", e.Step, e.Err, e.RollbackErr)
+	}
+	return fmt.Sprintf("start step %q failed: %v", e.Step, e.Err)
+}
+
+func (e *StartError) Unwrap() error { return e.Err }
+
+// SpecMismatchError is returned when a sandbox with the name already exists with a
+// different spec (e.g. an if not exists create), so the callers can tell it from a
+// plain name clash.
+type SpecMismatchError struct {
+	// Name is the name of the existing sandbox.
+	Name string
+	// Fields are the spec fields that differ, in SpecDiff order.
+	Fields []SpecField
+}
+
+func (e *SpecMismatchError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		fields = append(fields, string(f))
+	}
+	return fmt.Sprintf("sandbox with name %q already exists with a different spec (%s): %v", e.Name, strings.Join(fields, ", "), ErrAlreadyExists)
+}
+
+func (e *SpecMismatchError) Unwrap() error { return ErrAlreadyExists }
+
+// LockHolder identifies the client holding a host resource lock.
+type LockHolder struct {
+	PID      int    `json:"pid"`
+	Hostname string `json:"hostname"`
+	// DBPath is the database of the client, empty when unknown.
+	DBPath string `json:"db_path,omitempty"`
+	// Operation is what the client is doing with the resource (e.g. start, remove).
+	Operation string    `json:"operation"`
+	Since     time.Time `json:"since"`
+}
+
+// BusyError is returned when a host resource is locked by another client, it
+// matches ErrBusy.
+type BusyError struct {
+	// Resource is the locked host resource (e.g. vm/01J..., tap/sbx-1a2b, nftables).
+	Resource string
+	// Holder is the client holding the lock, nil when it couldn't be read.
+	Holder *LockHolder
+}
+
+func (e *BusyError) Error() string {
+	if e.Holder == nil {
+		return fmt.Sprintf("%s is locked by another client: %v", e.Resource, ErrBusy)
+	}
+	h := e.Holder
+	msg := fmt.Sprintf("%s is locked by pid %d on %s (%s since %s", e.Resource, h.PID, h.Hostname, h.Operation, h.Since.Format(time.RFC3339))
+	if h.DBPath != "" {
+		msg += ", database " + h.DBPath
+	}
+	return fmt.Sprintf("%s): %v", msg, ErrBusy)
+}
+
+func (e *BusyError) Unwrap() error { return ErrBusy }
@@ -0,0 +1,131 @@
+package model
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// EventType is the type of a structured sbx event.
+type EventType string
+
+const (
+	// EventTypeSandboxCreated is emitted when a sandbox is created.
+	EventTypeSandboxCreated EventType = "sandbox.created"
+	// EventTypeSandboxStarted is emitted when a sandbox is started.
+	EventTypeSandboxStarted EventType = "sandbox.started"
+	// EventTypeSandboxStopped is emitted when a sandbox is stopped.
+	EventTypeSandboxStopped EventType = "sandbox.stopped"
+	// EventTypeSandboxIdleStopped is emitted when a sandbox idle longer than its idle
+	// policy timeout is stopped.
+	EventTypeSandboxIdleStopped EventType = "sandbox.idle_stopped"
+	// EventTypeSandboxRemoved is emitted when a sandbox is removed.
+	EventTypeSandboxRemoved EventType = "sandbox.removed"
+	// EventTypeSnapshotCreated is emitted when a snapshot image is created from a sandbox.
+	EventTypeSnapshotCreated EventType = "snapshot.created"
+	// EventTypeEgressDenied is emitted when the egress proxy denies a request,
+	// connection or DNS query of a sandbox.
+	EventTypeEgressDenied EventType = "egress.denied"
+	// EventTypeProxyCrashed is emitted when the egress proxy of a sandbox exits
+	// unexpectedly, its supervisor restarts it.
+	EventTypeProxyCrashed EventType = "proxy.crashed"
+	// EventTypeSandboxQuarantined is emitted when a sandbox is quarantined.
+	EventTypeSandboxQuarantined EventType = "sandbox.quarantined"
+	// EventTypeSandboxQuarantineReleased is emitted when the quarantine of a sandbox is lifted.
+	EventTypeSandboxQuarantineReleased EventType = "sandbox.quarantine_released"
+)
+
+// Security returns true if the event is a security event (as opposed to a lifecycle one).
+func (t EventType) Security() bool {
+	switch t {
+	case EventTypeEgressDenied, EventTypeProxyCrashed, EventTypeSandboxQuarantined, EventTypeSandboxQuarantineReleased:
+		return true
+	}
+	return false
+}
+
+// Event is a structured lifecycle or security event of a sandbox.
+type Event struct {
+	Time        time.Time
+	Type        EventType
+	SandboxID   string
+	SandboxName string
+	Namespace   string
+	// Attributes are the details of the event type (e.g. the denied domain).
+	Attributes map[string]string
+}
+
+// NewSandboxEvent returns an event of a sandbox emitted now.
+func NewSandboxEvent(t EventType, sb Sandbox, attrs map[string]string) Event {
+	return Event{
+		Time:        time.Now().UTC(),
+		Type:        t,
+		SandboxID:   sb.ID,
+		SandboxName: sb.Name,
+		Namespace:   sb.Namespace,
+		Attributes:  attrs,
+	}
+}
+
+// EventSinkType is the destination type of the events.
+type EventSinkType string
+
+const (
+	// EventSinkTypeFile appends the events as JSON lines to a file.
+	EventSinkTypeFile EventSinkType = "file"
+	// EventSinkTypeJournald sends the events to the systemd journal.
+	EventSinkTypeJournald EventSinkType = "journald"
+	// EventSinkTypeWebhook POSTs the events as JSON to a URL.
+	EventSinkTypeWebhook EventSinkType = "webhook"
+)
+
+// EventSinkConfig is the configuration of an event destination.
+type EventSinkConfig struct {
+	Type EventSinkType
+	// Path is the file of the file sink.
+	Path string
+	// URL is the endpoint of the webhook sink.
+	URL string
+	// Secret signs the webhook sink payloads (HMAC-SHA256), empty doesn't sign them.
+	Secret string
+	// Events are the event types sent to the sink, empty sends all of them.
+	Events []EventType
+}
+
+// Accepts returns true if the sink receives the events of a type.
+func (c EventSinkConfig) Accepts(t EventType) bool {
+	return len(c.Events) == 0 || slices.Contains(c.Events, t)
+}
+
+// Webhook is a webhook notified of the lifecycle events of a sandbox.
+type Webhook struct {
+	URL string
+	// Secret signs the payloads (HMAC-SHA256), empty doesn't sign them.
+	Secret string
+	// Events are the event types notified, empty notifies all of them.
+	Events []EventType
+}
+
+// Validate checks the webhook URL.
+func (w Webhook) Validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook URL %q must be an http:// or https:// URL: %w", w.URL, ErrNotValid)
+	}
+	return nil
+}
+
+// SinkConfig returns the event sink configuration of the webhook.
+func (w Webhook) SinkConfig() EventSinkConfig {
+	return EventSinkConfig{Type: EventSinkTypeWebhook, URL: w.URL, Secret: w.Secret, Events: w.Events}
+}
+
+// WebhookSinkConfigs returns the event sink configurations of webhooks.
+func WebhookSinkConfigs(webhooks []Webhook) []EventSinkConfig {
+	sinks := make([]EventSinkConfig, 0, len(webhooks))
+	for _, w := range webhooks {
+		sinks = append(sinks, w.SinkConfig())
+	}
+	return sinks
+}
@@ -1,6 +1,16 @@
 package model
 
-import "io"
+import (
+	"fmt"
+	"io"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // ExecOpts contains options for executing a command in a sandbox.
 type ExecOpts struct {
@@ -8,7 +18,8 @@ type ExecOpts struct {
 	WorkingDir string
 	// Env contains additional environment variables for this exec.
 	Env map[string]string
-	// Stdin is the input stream for the command (optional).
+	// Stdin is the input stream for the command (optional). It is streamed until
+	// EOF, then the command input is closed, the command can exit before it ends.
 	Stdin io.Reader
 	// Stdout is the output stream for the command (optional, defaults to discard).
 	Stdout io.Writer
@@ -22,4 +33,167 @@ type ExecOpts struct {
 type ExecResult struct {
 	// ExitCode is the exit code of the executed command.
 	ExitCode int
+	// StartedAt is when the command execution started.
+	StartedAt time.Time
+	// FinishedAt is when the command execution finished.
+	FinishedAt time.Time
+	// Duration is the wall-clock time of the command execution.
+	Duration time.Duration
+	// StdoutBytes is the number of bytes the command wrote to stdout.
+	StdoutBytes int64
+	// StderrBytes is the number of bytes the command wrote to stderr.
+	StderrBytes int64
+	// Stdout is the captured standard output (only set when output capture is enabled).
+	Stdout string
+	// Stderr is the captured standard error (only set when output capture is enabled).
+	Stderr string
+	// StdoutTruncated is true when the captured stdout exceeded the capture limit.
+	StdoutTruncated bool
+	// StderrTruncated is true when the captured stderr exceeded the capture limit.
+	StderrTruncated bool
+	// OutputLimitExceeded is true when the output exceeded the exec output limit,
+	// the output beyond it was discarded or the command was aborted.
+	OutputLimitExceeded bool
+}
+
+// ExecOutputLimitPolicy is what happens when the output of a command exceeds the
+// exec output limit.
+type ExecOutputLimitPolicy string
+
+const (
+	// ExecOutputLimitTruncate discards the output beyond the limit and lets the
+	// command finish.
+	ExecOutputLimitTruncate ExecOutputLimitPolicy = "truncate"
+	// ExecOutputLimitAbort kills the command once its output exceeds the limit.
+	ExecOutputLimitAbort ExecOutputLimitPolicy = "abort"
+)
+
+// Validate validates the output limit policy, empty is ExecOutputLimitTruncate.
+func (p ExecOutputLimitPolicy) Validate() error {
+	switch p {
+	case "", ExecOutputLimitTruncate, ExecOutputLimitAbort:
+		return nil
+	default:
+		return fmt.Errorf("unknown output limit policy %q (allowed: truncate, abort): %w", p, ErrNotValid)
+	}
+}
+
+// ExecRecord is the audit record of a command executed in a sandbox.
+type ExecRecord struct {
+	ID        string
+	SandboxID string
+	Command   []string
+	// WorkingDir is the directory the command ran in, empty means the user home.
+	WorkingDir string
+	// User is the host user that executed the command.
+	User string
+	// Caller identifies the client that executed the command (e.g. "cli", "sdk").
+	Caller   string
+	ExitCode int
+	// Error is set when the command could not be executed (e.g. connection errors),
+	// in that case the exit code is -1.
+	Error       string
+	StartedAt   time.Time
+	Duration    time.Duration
+	StdoutBytes int64
+	StderrBytes int64
+	// StdoutSHA256 and StderrSHA256 are the SHA-256 (hex) of the full command output,
+	// the output itself is not stored.
+	StdoutSHA256 string
+	StderrSHA256 string
+}
+
+// ExecRecordFilter filters the exec records of a sandbox.
+type ExecRecordFilter struct {
+	// Since returns the records started at or after this time. Zero means all.
+	Since time.Time
+	// Limit returns only the latest N records. Zero means all.
+	Limit int
+}
+
+// ExecProfile is the environment setup of a sandbox applied to every exec and
+// shell, so the callers don't need to prefix the commands with it.
+type ExecProfile struct {
+	// PathPrepend are the directories added in front of the PATH, in order.
+	PathPrepend []string
+	// Locale is set as LANG and LC_ALL (e.g. C.UTF-8). Empty keeps the guest default.
+	Locale string
+	// Umask is the octal file mode creation mask (e.g. 027). Empty keeps the guest default.
+	Umask string
+	// Ulimits are the resource limits by name (e.g. nofile: 65536), the values are
+	// numbers or "unlimited".
+	Ulimits map[string]string
+	// Shell is the interactive shell of 'sbx shell'. Empty uses /bin/sh.
+	Shell string
+}
+
+// UlimitFlags are the ulimit flags of the exec profile resource limit names.
+var UlimitFlags = map[string]string{
+	"core":    "-c",
+	"cpu":     "-t",
+	"data":    "-d",
+	"fsize":   "-f",
+	"memlock": "-l",
+	"nofile":  "-n",
+	"nproc":   "-u",
+	"stack":   "-s",
+	"as":      "-v",
+}
+
+var (
+	localeRegexp = regexp.MustCompile(`^[A-Za-z0-9_.@-]+$`)
+	umaskRegexp  = regexp.MustCompile(`^0?[0-7]{3}$`)
+)
+
+// Validate validates the exec profile.
+func (p ExecProfile) Validate() error {
+	for _, dir := range p.PathPrepend {
+		if !path.IsAbs(dir) || strings.ContainsAny(dir, ":\n") {
+			return fmt.Errorf("invalid exec profile path %q, must be an absolute guest directory without colons: %w", dir, ErrNotValid)
+		}
+	}
+
+	if p.Locale != "" && !localeRegexp.MatchString(p.Locale) {
+		return fmt.Errorf("invalid exec profile locale %q: %w", p.Locale, ErrNotValid)
+	}
+
+	if p.Umask != "" && !umaskRegexp.MatchString(p.Umask) {
+		return fmt.Errorf("invalid exec profile umask %q, must be octal (e.g. 022): %w", p.Umask, ErrNotValid)
+	}
+
+	for name, value := range p.Ulimits {
+		if _, ok := UlimitFlags[name]; !ok {
+			return fmt.Errorf("unknown exec profile ulimit %q (allowed: %s): %w", name, strings.Join(slices.Sorted(maps.Keys(UlimitFlags)), ", "), ErrNotValid)
+		}
+		if value == "unlimited" {
+			continue
+		}
+		if _, err := strconv.ParseUint(value, 10, 64); err != nil {
+			return fmt.Errorf("invalid exec profile ulimit %s value %q, must be a number or unlimited: %w", name, value, ErrNotValid)
+		}
+	}
+
+	if p.Shell != "" && (!path.IsAbs(p.Shell) || strings.ContainsAny(p.Shell, " \t\n")) {
+		return fmt.Errorf("invalid exec profile shell %q, must be an absolute guest path: %w", p.Shell, ErrNotValid)
+	}
+
+	return nil
+}
+
+// IsZero returns true when the profile doesn't change the exec environment.
+func (p ExecProfile) IsZero() bool {
+	return len(p.PathPrepend) == 0 && p.Locale == "" && p.Umask == "" && len(p.Ulimits) == 0 && p.Shell == ""
+}
+
+// ParseUlimit parses a resource limit "NAME=VALUE" (e.g. nofile=65536).
+func ParseUlimit(s string) (name, value string, err error) {
+	name, value, ok := strings.Cut(strings.TrimSpace(s), "=")
+	if !ok {
+		return "", "", fmt.Errorf("invalid ulimit %q, expected 'name=value': %w", s, ErrNotValid)
+	}
+	name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+	if err := (ExecProfile{Ulimits: map[string]string{name: value}}).Validate(); err != nil {
+		return "", "", err
+	}
+	return name, value, nil
 }
@@ -0,0 +1,109 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestExecProfileValidate(t *testing.T) {
+	tests := map[string]struct {
+		profile model.ExecProfile
+		expErr  bool
+	}{
+		"An empty profile should be valid.": {
+			profile: model.ExecProfile{},
+		},
+		"A complete profile should be valid.": {
+			profile: model.ExecProfile{
+				PathPrepend: []string{"/opt/tools/bin", "/root/.local/bin"},
+				Locale:      "C.UTF-8",
+				Umask:       "027",
+				Ulimits:     map[string]string{"nofile": "65536", "core": "unlimited"},
+				Shell:       "/bin/bash",
+			},
+		},
+		"A relative path should fail.": {
+			profile: model.ExecProfile{PathPrepend: []string{"bin"}},
+			expErr:  true,
+		},
+		"A path with colons should fail.": {
+			profile: model.ExecProfile{PathPrepend: []string{"/opt/a:/opt/b"}},
+			expErr:  true,
+		},
+		"An invalid locale should fail.": {
+			profile: model.ExecProfile{Locale: "en US"},
+			expErr:  true,
+		},
+		"A non octal umask should fail.": {
+			profile: model.ExecProfile{Umask: "089"},
+			expErr:  true,
+		},
+		"An unknown ulimit should fail.": {
+			profile: model.ExecProfile{Ulimits: map[string]string{"files": "1024"}},
+			expErr:  true,
+		},
+		"A non numeric ulimit should fail.": {
+			profile: model.ExecProfile{Ulimits: map[string]string{"nofile": "many"}},
+			expErr:  true,
+		},
+		"A relative shell should fail.": {
+			profile: model.ExecProfile{Shell: "bash"},
+			expErr:  true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.profile.Validate()
+			if test.expErr {
+				assert.ErrorIs(t, err, model.ErrNotValid)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestParseUlimit(t *testing.T) {
+	tests := map[string]struct {
+		value    string
+		expName  string
+		expValue string
+		expErr   bool
+	}{
+		"A numeric limit should be parsed.": {
+			value:    "nofile=65536",
+			expName:  "nofile",
+			expValue: "65536",
+		},
+		"An unlimited limit should be parsed.": {
+			value:    " core = unlimited ",
+			expName:  "core",
+			expValue: "unlimited",
+		},
+		"A limit without value should fail.": {
+			value:  "nofile",
+			expErr: true,
+		},
+		"An unknown limit should fail.": {
+			value:  "open=10",
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotName, gotValue, err := model.ParseUlimit(test.value)
+			if test.expErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expName, gotName)
+			assert.Equal(t, test.expValue, gotValue)
+		})
+	}
+}
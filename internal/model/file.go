@@ -0,0 +1,22 @@
+package model
+
+import (
+	"io/fs"
+	"time"
+)
+
+// FileInfo is the information of a file inside a sandbox.
+type FileInfo struct {
+	// Name is the base name of the file.
+	Name string
+	// Path is the absolute path of the file in the sandbox.
+	Path string
+	// Size is the size in bytes.
+	Size int64
+	// Mode are the file mode and permission bits.
+	Mode fs.FileMode
+	// ModTime is the last modification time.
+	ModTime time.Time
+	// IsDir is true for directories.
+	IsDir bool
+}
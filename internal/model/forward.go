@@ -2,25 +2,45 @@ package model
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 )
 
 // PortMapping represents a port forwarding configuration.
-// LocalPort is the port on the host machine.
+// LocalPort is the port on the host machine, 0 binds a free port.
 // RemotePort is the port inside the sandbox.
 // BindAddress is the local address to listen on (e.g., "localhost", "0.0.0.0").
 // Defaults to "localhost" if empty.
+// TLS terminates TLS on the local port, nil forwards the connections as they are.
 type PortMapping struct {
 	BindAddress string
 	LocalPort   int
 	RemotePort  int
+	TLS         *ForwardTLS
+}
+
+// ForwardTLS terminates TLS on the host side of a port forward and tunnels the
+// plain connections into the sandbox, for the clients requiring https. Without
+// certificate and key files the generated localhost certificate is used.
+type ForwardTLS struct {
+	CertFile string
+	KeyFile  string
+}
+
+// Validate checks the certificate and key files are set together.
+func (t ForwardTLS) Validate() error {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("tls certificate and key files must be set together: %w", ErrNotValid)
+	}
+	return nil
 }
 
 // ParsePortMapping parses a port mapping string.
 // Supported formats:
 //   - "8080" -> {LocalPort: 8080, RemotePort: 8080}
 //   - "9000:8080" -> {LocalPort: 9000, RemotePort: 8080}
+//   - "0:8080" -> {LocalPort: 0, RemotePort: 8080} (a free local port)
 func ParsePortMapping(s string) (PortMapping, error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
@@ -39,7 +59,7 @@ func ParsePortMapping(s string) (PortMapping, error) {
 
 	case 2:
 		// Full form: "local:remote"
-		localPort, err := parsePort(parts[0])
+		localPort, err := parseLocalPort(parts[0])
 		if err != nil {
 			return PortMapping{}, fmt.Errorf("invalid local port: %w", err)
 		}
@@ -54,6 +74,79 @@ func ParsePortMapping(s string) (PortMapping, error) {
 	}
 }
 
+// ParseNamedPortMapping parses a port mapping like [ParsePortMapping], the remote port
+// can also be a named port of the sandbox:
+//   - "web" -> {LocalPort: ports["web"], RemotePort: ports["web"]}
+//   - "9000:web" -> {LocalPort: 9000, RemotePort: ports["web"]}
+//   - "0:web" -> {LocalPort: 0, RemotePort: ports["web"]} (a free local port)
+func ParseNamedPortMapping(s string, ports map[string]int) (PortMapping, error) {
+	local, remote, full := strings.Cut(strings.TrimSpace(s), ":")
+	if !full {
+		remote = local
+	}
+	remote = strings.TrimSpace(remote)
+	if !portNameRegexp.MatchString(remote) {
+		return ParsePortMapping(s)
+	}
+
+	remotePort, err := ResolveNamedPort(ports, remote)
+	if err != nil {
+		return PortMapping{}, err
+	}
+	if !full {
+		return PortMapping{LocalPort: remotePort, RemotePort: remotePort}, nil
+	}
+
+	localPort, err := parseLocalPort(local)
+	if err != nil {
+		return PortMapping{}, fmt.Errorf("invalid local port: %w", err)
+	}
+	return PortMapping{LocalPort: localPort, RemotePort: remotePort}, nil
+}
+
+// portNameRegexp matches the port names: up to 32 lowercase alphanumeric characters
+// and hyphens, starting with a letter and not ending with a hyphen (e.g. web, grpc-api).
+var portNameRegexp = regexp.MustCompile(`^[a-z]([a-z0-9-]{0,30}[a-z0-9])?$`)
+
+// ValidateNamedPorts validates the named ports of a sandbox.
+func ValidateNamedPorts(ports map[string]int) error {
+	for name, port := range ports {
+		if !portNameRegexp.MatchString(name) {
+			return fmt.Errorf("invalid port name %q, use up to 32 lowercase alphanumeric characters and hyphens starting with a letter: %w", name, ErrNotValid)
+		}
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("port %s %d out of range (1-65535): %w", name, port, ErrNotValid)
+		}
+	}
+	return nil
+}
+
+// ParseNamedPort parses a named port declaration "NAME=PORT" (e.g. web=3000).
+func ParseNamedPort(s string) (name string, port int, err error) {
+	name, value, ok := strings.Cut(strings.TrimSpace(s), "=")
+	if !ok {
+		return "", 0, fmt.Errorf("invalid named port %q, expected 'name=port': %w", s, ErrNotValid)
+	}
+	port, err = parsePort(value)
+	if err != nil {
+		return "", 0, err
+	}
+	name = strings.TrimSpace(name)
+	if err := ValidateNamedPorts(map[string]int{name: port}); err != nil {
+		return "", 0, err
+	}
+	return name, port, nil
+}
+
+// ResolveNamedPort returns the port of a named port of the sandbox.
+func ResolveNamedPort(ports map[string]int, name string) (int, error) {
+	port, ok := ports[name]
+	if !ok {
+		return 0, fmt.Errorf("sandbox has no port named %q: %w", name, ErrNotValid)
+	}
+	return port, nil
+}
+
 // parsePort parses and validates a single port number.
 func parsePort(s string) (int, error) {
 	s = strings.TrimSpace(s)
@@ -75,6 +168,14 @@ func (p PortMapping) String() string {
 	return fmt.Sprintf("%d:%d", p.LocalPort, p.RemotePort)
 }
 
+// parseLocalPort parses a local port number, 0 is valid and binds a free port.
+func parseLocalPort(s string) (int, error) {
+	if strings.TrimSpace(s) == "0" {
+		return 0, nil
+	}
+	return parsePort(s)
+}
+
 // ListenAddress returns the bind address for display, defaulting to "localhost".
 func (p PortMapping) ListenAddress() string {
 	if p.BindAddress == "" {
@@ -79,9 +79,9 @@ func TestParsePortMapping(t *testing.T) {
 			input:  "8080:abc",
 			expErr: true,
 		},
-		"Zero local port in full form should fail.": {
-			input:  "0:8080",
-			expErr: true,
+		"Zero local port in full form should pick a free port.": {
+			input:    "0:8080",
+			expected: model.PortMapping{LocalPort: 0, RemotePort: 8080},
 		},
 		"Zero remote port in full form should fail.": {
 			input:  "8080:0",
@@ -106,6 +106,102 @@ func TestParsePortMapping(t *testing.T) {
 	}
 }
 
+func TestParseNamedPortMapping(t *testing.T) {
+	ports := map[string]int{"web": 3000, "db": 5432}
+
+	tests := map[string]struct {
+		input    string
+		expected model.PortMapping
+		expErr   bool
+	}{
+		"A port number should be parsed as a port mapping.": {
+			input:    "9000:8080",
+			expected: model.PortMapping{LocalPort: 9000, RemotePort: 8080},
+		},
+		"A named port should use the same local port.": {
+			input:    "web",
+			expected: model.PortMapping{LocalPort: 3000, RemotePort: 3000},
+		},
+		"A named remote port should be resolved.": {
+			input:    " 15432 : db ",
+			expected: model.PortMapping{LocalPort: 15432, RemotePort: 5432},
+		},
+		"A missing named port should fail.": {
+			input:  "metrics",
+			expErr: true,
+		},
+		"A zero local port with a named port should pick a free port.": {
+			input:    "0:web",
+			expected: model.PortMapping{LocalPort: 0, RemotePort: 3000},
+		},
+		"A named local port should fail.": {
+			input:  "web:db",
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := model.ParseNamedPortMapping(test.input, ports)
+
+			if test.expErr {
+				assert.ErrorIs(t, err, model.ErrNotValid)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, test.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseNamedPort(t *testing.T) {
+	tests := map[string]struct {
+		input   string
+		expName string
+		expPort int
+		expErr  bool
+	}{
+		"A named port should be parsed.": {
+			input:   "web=3000",
+			expName: "web",
+			expPort: 3000,
+		},
+		"A named port with hyphens should be parsed.": {
+			input:   " grpc-api = 9090 ",
+			expName: "grpc-api",
+			expPort: 9090,
+		},
+		"A missing port should fail.": {
+			input:  "web",
+			expErr: true,
+		},
+		"An invalid port should fail.": {
+			input:  "web=70000",
+			expErr: true,
+		},
+		"An uppercase name should fail.": {
+			input:  "Web=3000",
+			expErr: true,
+		},
+		"A numeric name should fail.": {
+			input:  "3000=3000",
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotName, gotPort, err := model.ParseNamedPort(test.input)
+
+			if test.expErr {
+				assert.ErrorIs(t, err, model.ErrNotValid)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, test.expName, gotName)
+				assert.Equal(t, test.expPort, gotPort)
+			}
+		})
+	}
+}
+
 func TestPortMappingString(t *testing.T) {
 	tests := map[string]struct {
 		pm       model.PortMapping
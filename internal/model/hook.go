@@ -0,0 +1,67 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// HookEvent is a sandbox lifecycle event the host hooks run on.
+type HookEvent string
+
+const (
+	// HookEventPreCreate runs before a sandbox is created, a failing hook aborts
+	// the create. The sandbox has no ID yet.
+	HookEventPreCreate HookEvent = "pre-create"
+	// HookEventPostStart runs after a sandbox is started.
+	HookEventPostStart HookEvent = "post-start"
+	// HookEventPreStop runs before a running sandbox is stopped, a failing hook
+	// aborts the stop.
+	HookEventPreStop HookEvent = "pre-stop"
+	// HookEventPostRemove runs after a sandbox is removed.
+	HookEventPostRemove HookEvent = "post-remove"
+)
+
+// HookEvents are all the hook events.
+var HookEvents = []HookEvent{HookEventPreCreate, HookEventPostStart, HookEventPreStop, HookEventPostRemove}
+
+// Pre returns true when the hooks of the event run before the operation, their
+// failures abort it. The post hook failures are only logged.
+func (e HookEvent) Pre() bool {
+	return e == HookEventPreCreate || e == HookEventPreStop
+}
+
+// HookFunc is a Go host hook, called with the sandbox of the event.
+type HookFunc func(ctx context.Context, event HookEvent, sb Sandbox) error
+
+// HostHook runs on the host on a sandbox lifecycle event (not to be confused with
+// the pre-stop hooks run inside the sandbox), to integrate sbx with other systems
+// (e.g. DNS registration, inventories). It runs an executable or a Go function.
+type HostHook struct {
+	Event HookEvent
+	// Command is the executable and its arguments, it gets the sandbox in the SBX_*
+	// environment variables and as JSON in the standard input.
+	Command []string
+	// Func is called instead of running a command.
+	Func HookFunc
+	// Timeout cancels the hook, 0 uses the default.
+	Timeout time.Duration
+}
+
+// Validate validates the host hook.
+func (h HostHook) Validate() error {
+	if !slices.Contains(HookEvents, h.Event) {
+		return fmt.Errorf("hook event must be one of %v, got %q: %w", HookEvents, h.Event, ErrNotValid)
+	}
+	if (len(h.Command) == 0) == (h.Func == nil) {
+		return fmt.Errorf("hook requires a command or a func: %w", ErrNotValid)
+	}
+	if len(h.Command) > 0 && h.Command[0] == "" {
+		return fmt.Errorf("hook command executable is required: %w", ErrNotValid)
+	}
+	if h.Timeout < 0 {
+		return fmt.Errorf("hook timeout can't be negative: %w", ErrNotValid)
+	}
+	return nil
+}
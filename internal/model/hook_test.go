@@ -0,0 +1,65 @@
+package model_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestHostHookValidate(t *testing.T) {
+	noop := func(context.Context, model.HookEvent, model.Sandbox) error { return nil }
+
+	tests := map[string]struct {
+		hook   model.HostHook
+		expErr bool
+	}{
+		"A command hook should be valid.": {
+			hook: model.HostHook{Event: model.HookEventPostStart, Command: []string{"/usr/local/bin/register", "--dns"}},
+		},
+
+		"A func hook with a timeout should be valid.": {
+			hook: model.HostHook{Event: model.HookEventPreCreate, Func: noop, Timeout: time.Second},
+		},
+
+		"A hook with an unknown event should fail.": {
+			hook:   model.HostHook{Event: "post-create", Command: []string{"true"}},
+			expErr: true,
+		},
+
+		"A hook without command nor func should fail.": {
+			hook:   model.HostHook{Event: model.HookEventPreStop},
+			expErr: true,
+		},
+
+		"A hook with a command and a func should fail.": {
+			hook:   model.HostHook{Event: model.HookEventPreStop, Command: []string{"true"}, Func: noop},
+			expErr: true,
+		},
+
+		"A hook with an empty executable should fail.": {
+			hook:   model.HostHook{Event: model.HookEventPostRemove, Command: []string{""}},
+			expErr: true,
+		},
+
+		"A hook with a negative timeout should fail.": {
+			hook:   model.HostHook{Event: model.HookEventPostRemove, Command: []string{"true"}, Timeout: -time.Second},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.hook.Validate()
+
+			if test.expErr {
+				assert.ErrorIs(t, err, model.ErrNotValid)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// IdleAction is what happens to a sandbox idle longer than its idle policy timeout.
+type IdleAction string
+
+const (
+	// IdleActionStop stops the idle sandbox. There is no pause action, the engines
+	// can't pause and resume sandboxes.
+	IdleActionStop IdleAction = "stop"
+)
+
+// IdlePolicy suspends the running sandboxes without activity (commands, shell
+// sessions, forwarded connections or any other network traffic) for a while.
+type IdlePolicy struct {
+	// Timeout is how long a sandbox can be idle before it's suspended.
+	Timeout time.Duration
+	// Action is how the idle sandbox is suspended.
+	Action IdleAction
+}
+
+// Validate validates the idle policy timeout and action.
+func (p IdlePolicy) Validate() error {
+	if p.Timeout <= 0 {
+		return fmt.Errorf("idle timeout must be greater than 0: %w", ErrNotValid)
+	}
+
+	if p.Action != IdleActionStop {
+		return fmt.Errorf("idle action %q is invalid (allowed: %s): %w", p.Action, IdleActionStop, ErrNotValid)
+	}
+
+	return nil
+}
+
+// SandboxActivity is the last activity of a sandbox, used to detect the idle sandboxes.
+type SandboxActivity struct {
+	// LastActivityAt is the last time a command ran in the sandbox or its network
+	// traffic was seen, zero if never.
+	LastActivityAt time.Time
+	// NetworkBytes are the bytes received and sent by the sandbox network interfaces
+	// on the last idle check.
+	NetworkBytes uint64
+}
+
+// IdleSince returns when the sandbox activity stopped: the last activity, or the
+// start when the sandbox had no activity after it.
+func (s Sandbox) IdleSince() time.Time {
+	since := s.Activity.LastActivityAt
+	if s.StartedAt != nil && s.StartedAt.After(since) {
+		since = *s.StartedAt
+	}
+	return since
+}
+
+// IdleStatus is the result of an idle check of a sandbox.
+type IdleStatus struct {
+	// Idle is true when the sandbox has been idle longer than its idle policy timeout.
+	Idle bool
+	// IdleFor is how long the sandbox has been idle.
+	IdleFor time.Duration
+	// Policy is the idle policy of the sandbox, nil without one.
+	Policy *IdlePolicy
+}
@@ -0,0 +1,54 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestIdlePolicyValidate(t *testing.T) {
+	tests := map[string]struct {
+		policy model.IdlePolicy
+		expErr bool
+	}{
+		"A stop policy should be valid.": {
+			policy: model.IdlePolicy{Timeout: 30 * time.Minute, Action: model.IdleActionStop},
+		},
+
+		"A policy without timeout should fail.": {
+			policy: model.IdlePolicy{Action: model.IdleActionStop},
+			expErr: true,
+		},
+
+		"A policy with an unknown action should fail.": {
+			policy: model.IdlePolicy{Timeout: time.Minute, Action: "pause"},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.policy.Validate()
+
+			if test.expErr {
+				assert.ErrorIs(t, err, model.ErrNotValid)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSandboxIdleSince(t *testing.T) {
+	startedAt := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+
+	// Without activity after the start the sandbox is idle since it started.
+	sb := model.Sandbox{StartedAt: &startedAt, Activity: model.SandboxActivity{LastActivityAt: startedAt.Add(-time.Hour)}}
+	assert.Equal(t, startedAt, sb.IdleSince())
+
+	sb.Activity.LastActivityAt = startedAt.Add(time.Hour)
+	assert.Equal(t, startedAt.Add(time.Hour), sb.IdleSince())
+}
@@ -2,7 +2,12 @@ package model
 
 import (
 	"fmt"
+	"io/fs"
+	"maps"
+	"path"
 	"regexp"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -14,6 +19,11 @@ const (
 	ImageSourceRelease ImageSource = "release"
 	// ImageSourceSnapshot is a local image created from a sandbox snapshot.
 	ImageSourceSnapshot ImageSource = "snapshot"
+	// ImageSourceLocal is a local image added from user built artifacts.
+	ImageSourceLocal ImageSource = "local"
+	// ImageSourceCustomized is a local image derived from an installed image by
+	// applying a customization to its rootfs.
+	ImageSourceCustomized ImageSource = "customized"
 )
 
 // ImageRelease represents a release available in the image registry.
@@ -24,6 +34,8 @@ type ImageRelease struct {
 	Installed bool
 	// Source indicates where this image comes from (release or snapshot).
 	Source ImageSource
+	// InstalledAt is when the image was installed locally (zero if not installed).
+	InstalledAt time.Time
 }
 
 // CurrentSchemaVersion is the manifest schema version supported by this client.
@@ -39,6 +51,36 @@ type ImageManifest struct {
 	Build         BuildInfo
 	// Snapshot contains snapshot-specific metadata (nil for release images).
 	Snapshot *SnapshotInfo
+	// Local contains the metadata of images added from user built artifacts (nil
+	// for the rest).
+	Local *LocalImageInfo
+	// Customized contains the metadata of images derived by a rootfs customization
+	// (nil for the rest).
+	Customized *CustomizedImageInfo
+	// Profiles are the kernel profiles the image ships, besides the built-in ones
+	// (see KernelProfiles).
+	Profiles []KernelProfile
+}
+
+// Architectures supported by the images, using the Firecracker naming.
+const (
+	ArchX86_64  = "x86_64"
+	ArchAarch64 = "aarch64"
+)
+
+// ArtifactsFor returns the artifacts of the arch architecture, failing when
+// the image wasn't built for it.
+func (m ImageManifest) ArtifactsFor(arch string) (ArchArtifacts, error) {
+	a, ok := m.Artifacts[arch]
+	if !ok {
+		return ArchArtifacts{}, fmt.Errorf("image %s has no %s artifacts (available: %v): %w", m.Version, arch, m.Arches(), ErrNotValid)
+	}
+	return a, nil
+}
+
+// Arches returns the sorted architectures the image has artifacts for.
+func (m ImageManifest) Arches() []string {
+	return slices.Sorted(maps.Keys(m.Artifacts))
 }
 
 // ArchArtifacts contains per-architecture artifact metadata.
@@ -53,6 +95,8 @@ type KernelInfo struct {
 	Version   string
 	Source    string
 	SizeBytes int64
+	// SHA256 is the artifact hex digest (optional).
+	SHA256 string
 }
 
 // RootfsInfo describes the rootfs image artifact.
@@ -62,6 +106,8 @@ type RootfsInfo struct {
 	DistroVersion string
 	Profile       string
 	SizeBytes     int64
+	// SHA256 is the artifact hex digest (optional).
+	SHA256 string
 }
 
 // FirecrackerInfo describes the expected Firecracker version.
@@ -86,10 +132,163 @@ type SnapshotInfo struct {
 	SourceImage string
 	// ParentSnapshot is the snapshot image name this was derived from (for snapshot chains).
 	ParentSnapshot string
+	// MemoryFile is the guest memory file of live snapshots, taken while the sandbox
+	// was running (empty if it was stopped).
+	MemoryFile string
+	// VMStateFile is the microVM state file of live snapshots (empty if the sandbox
+	// was stopped).
+	VMStateFile string
 	// CreatedAt is when the snapshot was created.
 	CreatedAt time.Time
 }
 
+// LocalImageInfo contains metadata specific to images added from user built artifacts.
+type LocalImageInfo struct {
+	// KernelSource is the path the kernel was added from.
+	KernelSource string
+	// RootFSSource is the path the rootfs was added from.
+	RootFSSource string
+	// AddedAt is when the image was added.
+	AddedAt time.Time
+}
+
+// CustomizedImageInfo contains metadata specific to images derived from another
+// image by a rootfs customization.
+type CustomizedImageInfo struct {
+	// BaseImage is the image the customization was applied to.
+	BaseImage string
+	// Commands are the shell commands run in the rootfs, in order.
+	Commands []string
+	// Files are the guest paths of the files copied into the rootfs.
+	Files []string
+	// Digest identifies the customization (commands and file contents), the same
+	// customization of the same base image produces the same derived image.
+	Digest string
+	// CreatedAt is when the image was created.
+	CreatedAt time.Time
+}
+
+// ImageCustomization is a set of changes applied to an image rootfs. The files
+// are copied first and then the commands are run, in order.
+type ImageCustomization struct {
+	// Commands are shell commands run with /bin/sh inside the rootfs.
+	Commands []string
+	// Files are local files copied into the rootfs.
+	Files []ImageCustomizationFile
+}
+
+// ImageCustomizationFile is a local file copied into an image rootfs.
+type ImageCustomizationFile struct {
+	// Src is the local file path.
+	Src string
+	// Dst is the absolute guest path.
+	Dst string
+}
+
+// Validate checks the customization has changes, the commands aren't empty and
+// the file destinations are absolute paths.
+func (c ImageCustomization) Validate() error {
+	if len(c.Commands) == 0 && len(c.Files) == 0 {
+		return fmt.Errorf("customization has no commands or files: %w", ErrNotValid)
+	}
+	for _, cmd := range c.Commands {
+		if strings.TrimSpace(cmd) == "" {
+			return fmt.Errorf("customization command can't be empty: %w", ErrNotValid)
+		}
+	}
+	for _, f := range c.Files {
+		if f.Src == "" {
+			return fmt.Errorf("customization file source is required: %w", ErrNotValid)
+		}
+		if !path.IsAbs(f.Dst) || path.Clean(f.Dst) == "/" {
+			return fmt.Errorf("customization file destination %q must be an absolute file path: %w", f.Dst, ErrNotValid)
+		}
+	}
+	return nil
+}
+
+// FileChangeKind is the kind of change of a file between two images.
+type FileChangeKind string
+
+const (
+	// FileChangeAdded is a file that only exists in the newer image.
+	FileChangeAdded FileChangeKind = "added"
+	// FileChangeRemoved is a file that only exists in the older image.
+	FileChangeRemoved FileChangeKind = "removed"
+	// FileChangeModified is a file whose content, type, permissions or owner changed.
+	FileChangeModified FileChangeKind = "modified"
+)
+
+// FileType is the type of a rootfs file.
+type FileType string
+
+const (
+	// FileTypeRegular is a regular file.
+	FileTypeRegular FileType = "file"
+	// FileTypeDir is a directory.
+	FileTypeDir FileType = "dir"
+	// FileTypeSymlink is a symbolic link.
+	FileTypeSymlink FileType = "symlink"
+	// FileTypeOther are the devices, sockets and named pipes.
+	FileTypeOther FileType = "other"
+)
+
+// FileState is the state of a rootfs file in an image.
+type FileState struct {
+	Type FileType
+	// Mode are the permission bits (including setuid, setgid and sticky).
+	Mode fs.FileMode
+	UID  int
+	GID  int
+	// SizeBytes is the size of regular files.
+	SizeBytes int64
+	// SHA256 is the hex content digest of regular files.
+	SHA256 string
+	// LinkTarget is the target of symlinks.
+	LinkTarget string
+}
+
+// FileChange is a file changed between two images.
+type FileChange struct {
+	// Path is the absolute guest path.
+	Path string
+	Kind FileChangeKind
+	// Old is the file in the older image (nil when added).
+	Old *FileState
+	// New is the file in the newer image (nil when removed).
+	New *FileState
+}
+
+// ImageDiff are the rootfs file changes from an image to another.
+type ImageDiff struct {
+	// From is the older image (e.g. the base image of a snapshot).
+	From string
+	// To is the newer image.
+	To string
+	// Changes are the changed files sorted by path.
+	Changes []FileChange
+}
+
+// ImageDiskUsage is the disk usage of the locally installed images.
+type ImageDiskUsage struct {
+	// Images is the usage of each installed image.
+	Images []ImageUsage
+	// TotalBytes is the sum of all the image sizes, as if no artifact was shared.
+	TotalBytes int64
+	// DiskBytes is the real disk usage, counting shared artifacts once.
+	DiskBytes int64
+}
+
+// ImageUsage is the disk usage of a single installed image.
+type ImageUsage struct {
+	// Name is the image version or snapshot name.
+	Name string
+	// SizeBytes is the allocated size of the image artifacts.
+	SizeBytes int64
+	// SharedBytes is the part of SizeBytes stored once for multiple images.
+	SharedBytes int64
+}
+
 var imageNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
 
 // ValidateImageName validates an image name (used for snapshot-created images).
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/slok/sbx/internal/model"
 )
@@ -46,3 +47,68 @@ func TestImageManifestArtifactsAccess(t *testing.T) {
 	assert.Equal(t, "v1.14.1", manifest.Firecracker.Version)
 	assert.Equal(t, "adc9bc1", manifest.Build.Commit)
 }
+
+func TestImageManifestArtifactsFor(t *testing.T) {
+	manifest := model.ImageManifest{
+		Version: "v0.1.0",
+		Artifacts: map[string]model.ArchArtifacts{
+			model.ArchX86_64:  {Kernel: model.KernelInfo{File: "vmlinux-x86_64"}},
+			model.ArchAarch64: {Kernel: model.KernelInfo{File: "vmlinux-aarch64"}},
+		},
+	}
+
+	assert.Equal(t, []string{"aarch64", "x86_64"}, manifest.Arches())
+
+	a, err := manifest.ArtifactsFor(model.ArchAarch64)
+	require.NoError(t, err)
+	assert.Equal(t, "vmlinux-aarch64", a.Kernel.File)
+
+	delete(manifest.Artifacts, model.ArchAarch64)
+	_, err = manifest.ArtifactsFor(model.ArchAarch64)
+	assert.ErrorIs(t, err, model.ErrNotValid)
+}
+
+func TestImageCustomizationValidate(t *testing.T) {
+	tests := map[string]struct {
+		c      model.ImageCustomization
+		expErr bool
+	}{
+		"Commands and files should be valid.": {
+			c: model.ImageCustomization{
+				Commands: []string{"apk add curl"},
+				Files:    []model.ImageCustomizationFile{{Src: "./motd", Dst: "/etc/motd"}},
+			},
+		},
+		"An empty customization should fail.": {
+			c:      model.ImageCustomization{},
+			expErr: true,
+		},
+		"An empty command should fail.": {
+			c:      model.ImageCustomization{Commands: []string{" "}},
+			expErr: true,
+		},
+		"A relative file destination should fail.": {
+			c:      model.ImageCustomization{Files: []model.ImageCustomizationFile{{Src: "./motd", Dst: "etc/motd"}}},
+			expErr: true,
+		},
+		"The root as file destination should fail.": {
+			c:      model.ImageCustomization{Files: []model.ImageCustomizationFile{{Src: "./motd", Dst: "/"}}},
+			expErr: true,
+		},
+		"A file without source should fail.": {
+			c:      model.ImageCustomization{Files: []model.ImageCustomizationFile{{Dst: "/etc/motd"}}},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.c.Validate()
+			if test.expErr {
+				assert.ErrorIs(t, err, model.ErrNotValid)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"fmt"
+	"path"
+	"time"
+)
+
+// IntegrityBaseline is the digest of the guest files under the integrity paths of a
+// sandbox, captured when the sandbox starts so the files changed by its workload
+// can be detected before promoting them out of the sandbox.
+type IntegrityBaseline struct {
+	SandboxID string
+	// Paths are the absolute guest paths (files or directories) of the baseline.
+	Paths []string
+	// Files are the SHA256 digests of the regular files under the paths, keyed by
+	// their guest path.
+	Files     map[string]string
+	CreatedAt time.Time
+}
+
+// ValidateIntegrityPaths checks the integrity paths are absolute, clean and not
+// repeated.
+func ValidateIntegrityPaths(paths []string) error {
+	seen := map[string]bool{}
+	for _, p := range paths {
+		if !path.IsAbs(p) || path.Clean(p) != p {
+			return fmt.Errorf("integrity path %q must be a clean absolute path: %w", p, ErrNotValid)
+		}
+		if seen[p] {
+			return fmt.Errorf("integrity path %q is repeated: %w", p, ErrNotValid)
+		}
+		seen[p] = true
+	}
+	return nil
+}
+
+// IntegrityChange is a guest file changed since the integrity baseline.
+type IntegrityChange struct {
+	Path string
+	Kind FileChangeKind
+	// OldSHA256 is the baseline digest, empty for the added files.
+	OldSHA256 string
+	// NewSHA256 is the current digest, empty for the removed files.
+	NewSHA256 string
+}
+
+// IntegrityReport is the result of verifying the guest files of a sandbox against
+// its integrity baseline.
+type IntegrityReport struct {
+	SandboxID   string
+	SandboxName string
+	// Paths are the verified guest paths, the ones of the baseline.
+	Paths []string
+	// Files is the number of files currently under the paths.
+	Files      int
+	BaselineAt time.Time
+	CheckedAt  time.Time
+	// Changes are the changed files sorted by path.
+	Changes []IntegrityChange
+}
+
+// Intact returns true when no file changed since the baseline.
+func (r IntegrityReport) Intact() bool {
+	return len(r.Changes) == 0
+}
@@ -0,0 +1,44 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestValidateIntegrityPaths(t *testing.T) {
+	tests := map[string]struct {
+		paths  []string
+		expErr bool
+	}{
+		"No paths should be valid.": {},
+		"Absolute paths should be valid.": {
+			paths: []string{"/etc", "/usr/local/bin/app"},
+		},
+		"A relative path should fail.": {
+			paths:  []string{"etc"},
+			expErr: true,
+		},
+		"A path that is not clean should fail.": {
+			paths:  []string{"/etc/../root/"},
+			expErr: true,
+		},
+		"A repeated path should fail.": {
+			paths:  []string{"/etc", "/etc"},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := model.ValidateIntegrityPaths(test.paths)
+			if test.expErr {
+				assert.ErrorIs(t, err, model.ErrNotValid)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
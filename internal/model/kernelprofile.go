@@ -0,0 +1,138 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// KernelProfile is a named set of kernel and boot options for a common need (e.g.
+// a fast boot), so the users don't hand-craft the kernel args. The images ship the
+// built-in profiles (see DefaultKernelProfiles) and can add or replace them.
+type KernelProfile struct {
+	Name        string
+	Description string
+	// KernelArgs are the extra kernel command line arguments of the profile, the
+	// sandbox kernel args with the same key take precedence.
+	KernelArgs []string
+	// DisableConsole disables the guest serial console.
+	DisableConsole bool
+}
+
+// Kernel profile names of the built-in profiles.
+const (
+	KernelProfileFastBoot = "fast-boot"
+	KernelProfileDebug    = "debug"
+	KernelProfileHardened = "hardened"
+)
+
+// DefaultKernelProfiles are the built-in kernel profiles of all the images.
+var DefaultKernelProfiles = []KernelProfile{
+	{
+		Name:        KernelProfileFastBoot,
+		Description: "Minimal boot: no serial console, quiet kernel and no legacy keyboard controller probing",
+		KernelArgs: []string{
+			"quiet",
+			"loglevel=0",
+			"i8042.noaux",
+			"i8042.nomux",
+			"i8042.nopnp",
+			"i8042.dumbkbd",
+		},
+		DisableConsole: true,
+	},
+	{
+		Name:        KernelProfileDebug,
+		Description: "Verbose serial console: all the kernel messages and no reboot on panic",
+		KernelArgs: []string{
+			"loglevel=8",
+			"ignore_loglevel",
+			"printk.devkmsg=on",
+			"panic=0",
+		},
+	},
+	{
+		Name:        KernelProfileHardened,
+		Description: "Kernel self-protection: memory wiping, slab isolation, no vsyscall nor debugfs, panic on oops",
+		KernelArgs: []string{
+			"slab_nomerge",
+			"init_on_alloc=1",
+			"init_on_free=1",
+			"page_alloc.shuffle=1",
+			"randomize_kstack_offset=on",
+			"vsyscall=none",
+			"debugfs=off",
+			"oops=panic",
+		},
+	},
+}
+
+var kernelProfileNameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// Validate validates the kernel profile name and args.
+func (p KernelProfile) Validate() error {
+	if !kernelProfileNameRegexp.MatchString(p.Name) {
+		return fmt.Errorf("kernel profile name %q must be lowercase alphanumeric with dashes: %w", p.Name, ErrNotValid)
+	}
+	for _, arg := range p.KernelArgs {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'") {
+			return fmt.Errorf("kernel profile %s arg %q can't be empty or have spaces or quotes: %w", p.Name, arg, ErrNotValid)
+		}
+	}
+	return nil
+}
+
+// Apply sets the profile on a sandbox engine config: the profile kernel args go
+// before the config ones, skipping the ones the config overrides (same key), and
+// the console is disabled when the profile or the config disable it.
+func (p KernelProfile) Apply(cfg *FirecrackerEngineConfig) {
+	keys := make(map[string]bool, len(cfg.KernelArgs))
+	for _, arg := range cfg.KernelArgs {
+		keys[kernelArgKey(arg)] = true
+	}
+
+	args := make([]string, 0, len(p.KernelArgs)+len(cfg.KernelArgs))
+	for _, arg := range p.KernelArgs {
+		if !keys[kernelArgKey(arg)] {
+			args = append(args, arg)
+		}
+	}
+	cfg.KernelArgs = append(args, cfg.KernelArgs...)
+	cfg.Boot.Profile = p.Name
+	cfg.Boot.DisableConsole = cfg.Boot.DisableConsole || p.DisableConsole
+}
+
+// KernelProfiles returns the kernel profiles of the image: the built-in ones,
+// replaced by the image ones with the same name, and the rest of the image ones.
+func (m ImageManifest) KernelProfiles() []KernelProfile {
+	profiles := slices.Clone(DefaultKernelProfiles)
+	for _, p := range m.Profiles {
+		if i := slices.IndexFunc(profiles, func(d KernelProfile) bool { return d.Name == p.Name }); i >= 0 {
+			profiles[i] = p
+			continue
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles
+}
+
+// FindKernelProfile returns the profile with the name, an unknown name is not valid.
+func FindKernelProfile(profiles []KernelProfile, name string) (KernelProfile, error) {
+	for _, p := range profiles {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+
+	names := make([]string, 0, len(profiles))
+	for _, p := range profiles {
+		names = append(names, p.Name)
+	}
+	return KernelProfile{}, fmt.Errorf("kernel profile %q doesn't exist (available: %s): %w", name, strings.Join(names, ", "), ErrNotValid)
+}
+
+func kernelArgKey(arg string) string {
+	key, _, _ := strings.Cut(arg, "=")
+	return key
+}
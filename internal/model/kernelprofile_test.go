@@ -0,0 +1,134 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestKernelProfileApply(t *testing.T) {
+	tests := map[string]struct {
+		profile model.KernelProfile
+		cfg     model.FirecrackerEngineConfig
+		expCfg  model.FirecrackerEngineConfig
+	}{
+		"The profile args should go before the config ones.": {
+			profile: model.KernelProfile{Name: "debug", KernelArgs: []string{"loglevel=8", "panic=0"}},
+			cfg:     model.FirecrackerEngineConfig{KernelArgs: []string{"quiet"}},
+			expCfg: model.FirecrackerEngineConfig{
+				KernelArgs: []string{"loglevel=8", "panic=0", "quiet"},
+				Boot:       model.BootOptions{Profile: "debug"},
+			},
+		},
+
+		"The config args should override the profile ones with the same key.": {
+			profile: model.KernelProfile{Name: "debug", KernelArgs: []string{"loglevel=8", "panic=0"}},
+			cfg:     model.FirecrackerEngineConfig{KernelArgs: []string{"panic=5"}},
+			expCfg: model.FirecrackerEngineConfig{
+				KernelArgs: []string{"loglevel=8", "panic=5"},
+				Boot:       model.BootOptions{Profile: "debug"},
+			},
+		},
+
+		"The profile should disable the console.": {
+			profile: model.KernelProfile{Name: "fast-boot", KernelArgs: []string{"quiet"}, DisableConsole: true},
+			cfg:     model.FirecrackerEngineConfig{Boot: model.BootOptions{ReadOnlyRootFS: true}},
+			expCfg: model.FirecrackerEngineConfig{
+				KernelArgs: []string{"quiet"},
+				Boot:       model.BootOptions{Profile: "fast-boot", ReadOnlyRootFS: true, DisableConsole: true},
+			},
+		},
+
+		"A profile with the console should keep the config disabled console.": {
+			profile: model.KernelProfile{Name: "debug"},
+			cfg:     model.FirecrackerEngineConfig{Boot: model.BootOptions{DisableConsole: true}},
+			expCfg: model.FirecrackerEngineConfig{
+				KernelArgs: []string{},
+				Boot:       model.BootOptions{Profile: "debug", DisableConsole: true},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := test.cfg
+			test.profile.Apply(&cfg)
+			assert.Equal(t, test.expCfg, cfg)
+		})
+	}
+}
+
+func TestImageManifestKernelProfiles(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// Without image profiles there are the built-in ones.
+	names := func(ps []model.KernelProfile) []string {
+		res := []string{}
+		for _, p := range ps {
+			res = append(res, p.Name)
+		}
+		return res
+	}
+	assert.Equal([]string{"fast-boot", "debug", "hardened"}, names(model.ImageManifest{}.KernelProfiles()))
+
+	// The image profiles replace the built-in ones with the same name and add the rest.
+	m := model.ImageManifest{Profiles: []model.KernelProfile{
+		{Name: "tracing", KernelArgs: []string{"ftrace=function"}},
+		{Name: "debug", KernelArgs: []string{"loglevel=7"}},
+	}}
+	profiles := m.KernelProfiles()
+	assert.Equal([]string{"fast-boot", "debug", "hardened", "tracing"}, names(profiles))
+
+	debug, err := model.FindKernelProfile(profiles, "debug")
+	require.NoError(err)
+	assert.Equal([]string{"loglevel=7"}, debug.KernelArgs)
+
+	// The built-in profiles are not changed.
+	debug, err = model.FindKernelProfile(model.DefaultKernelProfiles, "debug")
+	require.NoError(err)
+	assert.Contains(debug.KernelArgs, "loglevel=8")
+
+	_, err = model.FindKernelProfile(profiles, "missing")
+	assert.ErrorIs(err, model.ErrNotValid)
+}
+
+func TestKernelProfileValidate(t *testing.T) {
+	tests := map[string]struct {
+		profile model.KernelProfile
+		expErr  bool
+	}{
+		"The built-in profiles should be valid.": {
+			profile: model.DefaultKernelProfiles[0],
+		},
+
+		"A profile without name should fail.": {
+			profile: model.KernelProfile{KernelArgs: []string{"quiet"}},
+			expErr:  true,
+		},
+
+		"A profile with an invalid name should fail.": {
+			profile: model.KernelProfile{Name: "Fast Boot"},
+			expErr:  true,
+		},
+
+		"A profile with an arg with spaces should fail.": {
+			profile: model.KernelProfile{Name: "custom", KernelArgs: []string{"a b"}},
+			expErr:  true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.profile.Validate()
+			if test.expErr {
+				assert.ErrorIs(t, err, model.ErrNotValid)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package model
+
+// SandboxLog is the tail of an engine log of a sandbox.
+type SandboxLog struct {
+	// Source is the process writing the log (e.g. firecracker, proxy, proxy-eth1).
+	Source string
+	// Lines are the last lines of the log, oldest first.
+	Lines []string
+}
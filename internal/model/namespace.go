@@ -0,0 +1,73 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+)
+
+const (
+	// DefaultNamespace is the namespace of the sandboxes when none is set, and of the
+	// sandboxes created before the namespaces existed.
+	DefaultNamespace = "default"
+	// AllNamespaces is the scope of the administration tools, it selects the
+	// sandboxes of every namespace. It's not a valid namespace for a sandbox.
+	AllNamespaces = "*"
+	// NamespaceMaxLen is the maximum length of a namespace.
+	NamespaceMaxLen = 63
+)
+
+var namespaceRegexp = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// ValidateNamespace validates a sandbox namespace. Namespaces isolate the sandboxes
+// of different teams or projects sharing a host, the sandbox names are unique in
+// their namespace.
+func ValidateNamespace(ns string) error {
+	if ns == "" {
+		return fmt.Errorf("namespace is required: %w", ErrNotValid)
+	}
+
+	if len(ns) > NamespaceMaxLen || !namespaceRegexp.MatchString(ns) {
+		return fmt.Errorf("namespace %q is invalid (up to %d [a-z0-9-] characters, starting and ending with an alphanumeric character): %w", ns, NamespaceMaxLen, ErrNotValid)
+	}
+
+	return nil
+}
+
+// NamespaceScope returns the namespace selected by a scope value: the default
+// namespace when empty, and all the namespaces with [AllNamespaces].
+func NamespaceScope(scope string) (string, error) {
+	switch scope {
+	case "":
+		return DefaultNamespace, nil
+	case AllNamespaces:
+		return AllNamespaces, nil
+	}
+
+	if err := ValidateNamespace(scope); err != nil {
+		return "", err
+	}
+	return scope, nil
+}
+
+// ValidateNamespaceInScope validates the namespace of a sandbox stored with a
+// namespace scope (see [NamespaceScope]), it must be the scope namespace unless the
+// scope selects all of them.
+func ValidateNamespaceInScope(scope, ns string) error {
+	if ns == AllNamespaces {
+		return fmt.Errorf("a namespace is required when all the namespaces are selected: %w", ErrNotValid)
+	}
+	if err := ValidateNamespace(ns); err != nil {
+		return err
+	}
+	if scope != AllNamespaces && ns != scope {
+		return fmt.Errorf("namespace %s is not the selected namespace %s: %w", ns, scope, ErrNotValid)
+	}
+	return nil
+}
+
+// NamespaceUsage is the number of sandboxes of a namespace.
+type NamespaceUsage struct {
+	Name             string
+	Sandboxes        int
+	RunningSandboxes int
+}
@@ -0,0 +1,67 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestNamespaceScope(t *testing.T) {
+	tests := map[string]struct {
+		scope    string
+		expScope string
+		expErr   bool
+	}{
+		"An empty scope should be the default namespace.": {
+			scope:    "",
+			expScope: model.DefaultNamespace,
+		},
+
+		"All the namespaces should be a valid scope.": {
+			scope:    model.AllNamespaces,
+			expScope: model.AllNamespaces,
+		},
+
+		"A namespace should be a valid scope.": {
+			scope:    "team-a",
+			expScope: "team-a",
+		},
+
+		"A namespace with uppercase characters should fail.": {
+			scope:  "Team-A",
+			expErr: true,
+		},
+
+		"A namespace ending with a dash should fail.": {
+			scope:  "team-",
+			expErr: true,
+		},
+
+		"A namespace too long should fail.": {
+			scope:  strings.Repeat("a", model.NamespaceMaxLen+1),
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			scope, err := model.NamespaceScope(test.scope)
+
+			if test.expErr {
+				assert.ErrorIs(t, err, model.ErrNotValid)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, test.expScope, scope)
+			}
+		})
+	}
+}
+
+func TestValidateNamespaceInScope(t *testing.T) {
+	assert.NoError(t, model.ValidateNamespaceInScope("team-a", "team-a"))
+	assert.NoError(t, model.ValidateNamespaceInScope(model.AllNamespaces, "team-b"))
+	assert.ErrorIs(t, model.ValidateNamespaceInScope("team-a", "team-b"), model.ErrNotValid)
+	assert.ErrorIs(t, model.ValidateNamespaceInScope(model.AllNamespaces, model.AllNamespaces), model.ErrNotValid)
+}
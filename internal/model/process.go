@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// ProcessTable is the process table of a sandbox guest, read from its procfs.
+type ProcessTable struct {
+	SandboxID   string
+	SandboxName string
+	CollectedAt time.Time
+	// Uptime is the guest uptime.
+	Uptime time.Duration
+	// Load1, Load5 and Load15 are the guest load averages.
+	Load1  float64
+	Load5  float64
+	Load15 float64
+	// MemoryTotalBytes and MemoryAvailableBytes are the guest memory.
+	MemoryTotalBytes     uint64
+	MemoryAvailableBytes uint64
+	// Processes are sorted by CPU usage, highest first.
+	Processes []Process
+}
+
+// Process is a process running in a sandbox guest.
+type Process struct {
+	PID  int
+	PPID int
+	// User is the process user name, or its UID when the guest doesn't know it.
+	User string
+	// State is the procfs state (e.g. R running, S sleeping, Z zombie).
+	State string
+	// Command is the process command line, or the name between brackets for the
+	// kernel threads.
+	Command string
+	// CPUTime is the CPU time used by the process (user and system).
+	CPUTime time.Duration
+	// CPUPercent is the CPU usage of a single CPU: the average since the process
+	// started, or in the interval since the previous table when it's refreshed.
+	CPUPercent float64
+	// RSSBytes is the resident memory of the process.
+	RSSBytes uint64
+	// MemoryPercent is the resident memory of the guest memory.
+	MemoryPercent float64
+	Threads       int
+	StartedAt     time.Time
+}
@@ -0,0 +1,29 @@
+package model
+
+// ProgressEvent is the progress of a long operation (e.g. create, start), sent
+// when a step starts and while it advances.
+type ProgressEvent struct {
+	// Step is the step name (e.g. "copy_rootfs", "boot").
+	Step string
+	// Percent is the operation completion from 0 to 100, -1 when unknown. The
+	// download steps report the completion of the downloaded artifact.
+	Percent float64
+	// Message is a human readable description of the step.
+	Message string
+}
+
+// ProgressFunc receives the progress of a long operation, the calls are serialized.
+type ProgressFunc func(ProgressEvent)
+
+// Report sends the event, it's a noop on a nil func so the optional progress
+// funcs can be used without checks.
+func (f ProgressFunc) Report(ev ProgressEvent) {
+	if f != nil {
+		f(ev)
+	}
+}
+
+// ReportStep sends the start of the step number n (starting at 1) of total steps.
+func (f ProgressFunc) ReportStep(n, total int, step, msg string) {
+	f.Report(ProgressEvent{Step: step, Percent: float64(n-1) / float64(total) * 100, Message: msg})
+}
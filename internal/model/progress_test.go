@@ -0,0 +1,26 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestProgressFuncReportStep(t *testing.T) {
+	var events []model.ProgressEvent
+	f := model.ProgressFunc(func(ev model.ProgressEvent) { events = append(events, ev) })
+
+	f.ReportStep(1, 4, "ssh_keys", "Generating SSH keys")
+	f.ReportStep(3, 4, "resize_rootfs", "Resizing rootfs")
+
+	assert.Equal(t, []model.ProgressEvent{
+		{Step: "ssh_keys", Percent: 0, Message: "Generating SSH keys"},
+		{Step: "resize_rootfs", Percent: 50, Message: "Resizing rootfs"},
+	}, events)
+
+	// A nil func should ignore the events.
+	var nilFunc model.ProgressFunc
+	assert.NotPanics(t, func() { nilFunc.ReportStep(1, 1, "boot", "Booting") })
+}
@@ -0,0 +1,28 @@
+package model
+
+// RecoveryAction is what the recovery did with a sandbox.
+type RecoveryAction string
+
+const (
+	// RecoveryActionNone leaves the sandbox as it is, it's alive or not running.
+	RecoveryActionNone RecoveryAction = "none"
+	// RecoveryActionStopped flags as stopped a sandbox that was running when its VM
+	// died (e.g. the host rebooted).
+	RecoveryActionStopped RecoveryAction = "stopped"
+	// RecoveryActionRestarted starts again a persistent sandbox whose VM died.
+	RecoveryActionRestarted RecoveryAction = "restarted"
+	// RecoveryActionFailed is a persistent sandbox whose VM died and couldn't be
+	// started again, it's left stopped.
+	RecoveryActionFailed RecoveryAction = "failed"
+)
+
+// RecoveryResult is the recovery of a sandbox.
+type RecoveryResult struct {
+	SandboxID  string
+	Name       string
+	Namespace  string
+	Persistent bool
+	Action     RecoveryAction
+	// Error is the start error of a failed recovery.
+	Error string
+}
@@ -0,0 +1,140 @@
+package model
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy is the retry policy of a transient failure: the number of attempts
+// and the backoff between them. The zero values use the engine defaults.
+type RetryPolicy struct {
+	// Attempts is the maximum number of attempts, zero keeps retrying until the
+	// operation deadline.
+	Attempts int
+	// InitialBackoff is the wait after the first failed attempt.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between attempts, unset caps it to an hour.
+	MaxBackoff time.Duration
+	// Multiplier grows the wait after each failed attempt, 1 keeps it fixed.
+	Multiplier float64
+	// Jitter randomizes each wait by up to this fraction of it (0-1), so the clients
+	// retrying the same resource don't do it in lockstep.
+	Jitter float64
+}
+
+// Validate checks the policy values are in range.
+func (p RetryPolicy) Validate() error {
+	if p.Attempts < 0 {
+		return fmt.Errorf("retry attempts can't be negative: %w", ErrNotValid)
+	}
+	if p.InitialBackoff < 0 || p.MaxBackoff < 0 {
+		return fmt.Errorf("retry backoff can't be negative: %w", ErrNotValid)
+	}
+	if p.MaxBackoff != 0 && p.InitialBackoff > p.MaxBackoff {
+		return fmt.Errorf("retry initial backoff can't be greater than the max backoff: %w", ErrNotValid)
+	}
+	if p.Multiplier != 0 && p.Multiplier < 1 {
+		return fmt.Errorf("retry multiplier can't be lower than 1: %w", ErrNotValid)
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return fmt.Errorf("retry jitter must be between 0 and 1: %w", ErrNotValid)
+	}
+	return nil
+}
+
+// Merge returns the policy with the non-zero values of override replacing them.
+func (p RetryPolicy) Merge(override RetryPolicy) RetryPolicy {
+	if override.Attempts != 0 {
+		p.Attempts = override.Attempts
+	}
+	if override.InitialBackoff != 0 {
+		p.InitialBackoff = override.InitialBackoff
+	}
+	if override.MaxBackoff != 0 {
+		p.MaxBackoff = override.MaxBackoff
+	}
+	if override.Multiplier != 0 {
+		p.Multiplier = override.Multiplier
+	}
+	if override.Jitter != 0 {
+		p.Jitter = override.Jitter
+	}
+	return p
+}
+
+// Backoff returns the wait after the failed attempt (starting at 1), without the
+// jitter.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	limit := float64(time.Hour)
+	if p.MaxBackoff > 0 {
+		limit = float64(p.MaxBackoff)
+	}
+
+	wait := float64(p.InitialBackoff)
+	for i := 1; i < attempt && p.Multiplier > 1 && wait < limit; i++ {
+		wait *= p.Multiplier
+	}
+	return time.Duration(min(wait, limit))
+}
+
+// JitteredBackoff returns the wait after the failed attempt (starting at 1) with
+// the jitter applied.
+func (p RetryPolicy) JitteredBackoff(attempt int) time.Duration {
+	wait := p.Backoff(attempt)
+	if p.Jitter <= 0 || wait <= 0 {
+		return wait
+	}
+	delta := float64(wait) * p.Jitter
+	return time.Duration(float64(wait) - delta + rand.Float64()*2*delta)
+}
+
+// RetryPolicies are the retry policies of the transient engine failures.
+type RetryPolicies struct {
+	// SSHDial retries the guest SSH connection while the guest boots.
+	SSHDial RetryPolicy
+	// FirecrackerAPI retries the connection to the Firecracker API socket while the
+	// Firecracker process starts.
+	FirecrackerAPI RetryPolicy
+	// Nftables retries the host nftables changes rejected as busy (EBUSY).
+	Nftables RetryPolicy
+}
+
+// Validate checks the policies.
+func (p RetryPolicies) Validate() error {
+	if err := p.SSHDial.Validate(); err != nil {
+		return fmt.Errorf("invalid SSH dial retry policy: %w", err)
+	}
+	if err := p.FirecrackerAPI.Validate(); err != nil {
+		return fmt.Errorf("invalid Firecracker API retry policy: %w", err)
+	}
+	if err := p.Nftables.Validate(); err != nil {
+		return fmt.Errorf("invalid nftables retry policy: %w", err)
+	}
+	return nil
+}
+
+// Merge returns the policies with the non-zero values of override replacing them.
+func (p RetryPolicies) Merge(override RetryPolicies) RetryPolicies {
+	return RetryPolicies{
+		SSHDial:        p.SSHDial.Merge(override.SSHDial),
+		FirecrackerAPI: p.FirecrackerAPI.Merge(override.FirecrackerAPI),
+		Nftables:       p.Nftables.Merge(override.Nftables),
+	}
+}
+
+// RetryError is returned when an operation retried on a transient failure gives up.
+type RetryError struct {
+	// Operation is the retried operation (e.g. guest SSH dial).
+	Operation string
+	// Attempts is the number of attempts made.
+	Attempts int
+	// Err is the error of the last attempt.
+	Err error
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("%s failed after %d attempts: %v", e.Operation, e.Attempts, e.Err)
+}
+
+func (e *RetryError) Unwrap() error { return e.Err }
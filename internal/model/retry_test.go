@@ -0,0 +1,124 @@
+package model_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestRetryPolicyValidate(t *testing.T) {
+	tests := map[string]struct {
+		policy model.RetryPolicy
+		expErr bool
+	}{
+		"An empty policy should be valid.": {},
+
+		"A full policy should be valid.": {
+			policy: model.RetryPolicy{Attempts: 5, InitialBackoff: 50 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2, Jitter: 0.2},
+		},
+
+		"Negative attempts should fail.": {
+			policy: model.RetryPolicy{Attempts: -1},
+			expErr: true,
+		},
+
+		"A negative backoff should fail.": {
+			policy: model.RetryPolicy{InitialBackoff: -time.Second},
+			expErr: true,
+		},
+
+		"An initial backoff greater than the max should fail.": {
+			policy: model.RetryPolicy{InitialBackoff: 2 * time.Second, MaxBackoff: time.Second},
+			expErr: true,
+		},
+
+		"A multiplier lower than 1 should fail.": {
+			policy: model.RetryPolicy{Multiplier: 0.5},
+			expErr: true,
+		},
+
+		"A jitter greater than 1 should fail.": {
+			policy: model.RetryPolicy{Jitter: 1.5},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.policy.Validate()
+
+			if test.expErr {
+				assert.ErrorIs(t, err, model.ErrNotValid)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	tests := map[string]struct {
+		policy     model.RetryPolicy
+		expBackoff []time.Duration
+	}{
+		"A fixed policy should wait the same after each attempt.": {
+			policy:     model.RetryPolicy{InitialBackoff: 100 * time.Millisecond, Multiplier: 1},
+			expBackoff: []time.Duration{100 * time.Millisecond, 100 * time.Millisecond, 100 * time.Millisecond},
+		},
+
+		"An exponential policy should grow the wait up to the max.": {
+			policy:     model.RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 500 * time.Millisecond, Multiplier: 2},
+			expBackoff: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 500 * time.Millisecond, 500 * time.Millisecond},
+		},
+
+		"An exponential policy without max should be capped to an hour.": {
+			policy:     model.RetryPolicy{InitialBackoff: time.Minute, Multiplier: 10},
+			expBackoff: []time.Duration{time.Minute, 10 * time.Minute, time.Hour, time.Hour},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := []time.Duration{}
+			for attempt := 1; attempt <= len(test.expBackoff); attempt++ {
+				got = append(got, test.policy.Backoff(attempt))
+			}
+			assert.Equal(t, test.expBackoff, got)
+		})
+	}
+}
+
+func TestRetryPolicyJitteredBackoff(t *testing.T) {
+	p := model.RetryPolicy{InitialBackoff: 100 * time.Millisecond, Multiplier: 1, Jitter: 0.2}
+	for range 100 {
+		got := p.JitteredBackoff(1)
+		assert.GreaterOrEqual(t, got, 80*time.Millisecond)
+		assert.LessOrEqual(t, got, 120*time.Millisecond)
+	}
+}
+
+func TestRetryPoliciesMerge(t *testing.T) {
+	base := model.RetryPolicies{
+		SSHDial:  model.RetryPolicy{InitialBackoff: 100 * time.Millisecond, Multiplier: 1},
+		Nftables: model.RetryPolicy{Attempts: 5, InitialBackoff: 50 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2},
+	}
+	got := base.Merge(model.RetryPolicies{Nftables: model.RetryPolicy{Attempts: 10, Jitter: 0.5}})
+
+	exp := model.RetryPolicies{
+		SSHDial:  model.RetryPolicy{InitialBackoff: 100 * time.Millisecond, Multiplier: 1},
+		Nftables: model.RetryPolicy{Attempts: 10, InitialBackoff: 50 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2, Jitter: 0.5},
+	}
+	assert.Equal(t, exp, got)
+}
+
+func TestRetryError(t *testing.T) {
+	errTest := errors.New("connection refused")
+	err := error(&model.RetryError{Operation: "guest SSH dial", Attempts: 3, Err: errTest})
+
+	assert.EqualError(t, err, "guest SSH dial failed after 3 attempts: connection refused")
+	assert.ErrorIs(t, err, errTest)
+}
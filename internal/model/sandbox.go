@@ -2,6 +2,11 @@ package model
 
 import (
 	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -21,8 +26,11 @@ const (
 
 // Sandbox represents a sandbox instance.
 type Sandbox struct {
-	ID        string
-	Name      string
+	ID   string
+	Name string
+	// Namespace isolates the sandboxes of different teams or projects sharing a
+	// host, the names are unique in their namespace.
+	Namespace string
 	Status    SandboxStatus
 	Config    SandboxConfig
 	CreatedAt time.Time
@@ -34,6 +42,100 @@ type Sandbox struct {
 	SocketPath string // API socket path (e.g., ~/.sbx/vms/<id>/firecracker.sock)
 	TapDevice  string // TAP device name (e.g., sbx-a3f2)
 	InternalIP string // VM's IP address (e.g., 10.163.242.2)
+
+	// RootFSStrategy is how the sandbox rootfs was provisioned from its base image on
+	// creation, empty for the sandboxes created before it was recorded.
+	RootFSStrategy RootFSStrategy
+
+	// Annotations are free-form metadata of the sandbox (e.g. ticket URLs, owners),
+	// they are not part of the sandbox spec.
+	Annotations map[string]string
+
+	// Webhooks are notified of the lifecycle events of the sandbox, they are set on
+	// creation and can't be changed (see SandboxSpecDiff).
+	Webhooks []Webhook
+
+	// IdlePolicy suspends the sandbox when it's idle (optional), it is not part of
+	// the sandbox spec.
+	IdlePolicy *IdlePolicy
+
+	// Activity is the last activity of the sandbox, set by the commands run in it
+	// and by the idle checks.
+	Activity SandboxActivity
+
+	// QuarantinedAt is when the sandbox was quarantined, nil if it isn't. A
+	// quarantined sandbox has no network access and no port forwards, it is not
+	// part of the sandbox spec.
+	QuarantinedAt *time.Time
+
+	// Persistent sandboxes are started again by the recovery when the host reboot
+	// (or a crash) killed them while running, it is not part of the sandbox spec.
+	Persistent bool
+
+	// Session is the session configuration of the last start, nil if the sandbox
+	// was never started. The restarts apply it again.
+	Session *SessionConfig
+
+	// BootPhases are the durations of the start phases, only set on the sandbox
+	// returned by a start.
+	BootPhases []BootPhase
+
+	// LastBootPhases are the phases of the last start, including the failed one
+	// with its error when the start failed.
+	LastBootPhases []BootPhase
+	// LastError is the error of the last start, empty when it succeeded.
+	LastError string
+
+	// Egress is the egress filtering status of a running sandbox started with an
+	// egress policy, nil otherwise. Only set when the engine status is requested.
+	Egress *EgressStatus
+
+	// Seccomp is the seccomp enforcement of a running firecracker sandbox, nil
+	// otherwise. Only set when the engine status is requested.
+	Seccomp *SeccompStatus
+}
+
+// RootFSStrategy is how the rootfs of a sandbox is provisioned from its base image.
+type RootFSStrategy string
+
+const (
+	// RootFSStrategyReflink is an instant copy-on-write clone sharing the image data
+	// blocks until they are written (btrfs, XFS with reflinks, ZFS block cloning).
+	RootFSStrategyReflink RootFSStrategy = "reflink"
+	// RootFSStrategySparse is a copy of the image data that keeps the holes.
+	RootFSStrategySparse RootFSStrategy = "sparse"
+	// RootFSStrategyCopy is a full copy of the image.
+	RootFSStrategyCopy RootFSStrategy = "copy"
+)
+
+// EgressHealth is the health of the sandbox egress filtering.
+type EgressHealth string
+
+const (
+	// EgressHealthHealthy indicates the egress proxies are running.
+	EgressHealthHealthy EgressHealth = "healthy"
+	// EgressHealthDegraded indicates an egress proxy is down (e.g. it crashed and is
+	// being restarted), the filtered egress traffic is blocked meanwhile.
+	EgressHealthDegraded EgressHealth = "degraded"
+)
+
+// EgressStatus is the status of the egress filtering proxies of a sandbox.
+type EgressStatus struct {
+	Health EgressHealth
+	// Restarts is the number of times the proxies have been restarted after a crash.
+	Restarts int
+	// LastError is the last proxy failure, empty if there wasn't any.
+	LastError string
+	// UpdatedAt is the last time the health changed.
+	UpdatedAt time.Time
+}
+
+// BootPhase is the duration of a sandbox start phase (e.g. "boot", "ssh").
+type BootPhase struct {
+	Name     string
+	Duration time.Duration
+	// Error is the error of the phase that failed the start, empty otherwise.
+	Error string
 }
 
 // SandboxConfig is the static configuration for creating a sandbox.
@@ -41,7 +143,39 @@ type Sandbox struct {
 type SandboxConfig struct {
 	Name              string
 	FirecrackerEngine *FirecrackerEngineConfig
-	Resources         Resources
+	// Image is the local image (release or snapshot) the sandbox was created from.
+	// Empty when created from explicit kernel and rootfs paths.
+	Image     string
+	Resources Resources
+	// UserData is the provisioning (shell script or cloud-config) executed in the
+	// guest on the first boot. Empty means no provisioning.
+	UserData string
+	// Clock is the guest clock configuration. nil means the guest uses the host time.
+	Clock *ClockConfig
+	// Ports are the named ports of the sandbox services (e.g. web: 3000), the
+	// port forwards can use the names instead of the numbers.
+	Ports map[string]int
+	// ExecProfile is the environment setup applied to every exec and shell. nil
+	// uses the guest defaults.
+	ExecProfile *ExecProfile
+}
+
+// ClockConfig isolates the guest clock from the host, the guest time sync (NTP) is
+// disabled and the clock is set on every boot.
+type ClockConfig struct {
+	// BootTime is the date set on every boot, the clock runs from there. Zero means
+	// the host time plus the offset.
+	BootTime time.Time
+	// Offset is added to the host time on every boot. Can't be used with the boot time.
+	Offset time.Duration
+}
+
+// Time returns the guest time for the host time now.
+func (c ClockConfig) Time(now time.Time) time.Time {
+	if !c.BootTime.IsZero() {
+		return c.BootTime
+	}
+	return now.Add(c.Offset)
 }
 
 // SessionConfig is the dynamic configuration applied when starting a sandbox.
@@ -50,6 +184,117 @@ type SessionConfig struct {
 	Name   string
 	Env    map[string]string
 	Egress *EgressPolicy // nil = no egress filtering.
+	// EgressPolicyName is the name of a stored egress policy, resolved when the
+	// sandbox starts. It can't be used with Egress.
+	EgressPolicyName string
+	// IntegrityPaths are the guest paths whose files are hashed once the sandbox
+	// started, as the baseline of the integrity verification (optional).
+	IntegrityPaths []string
+}
+
+// Validate checks the egress policy, that it's not set at the same time as the
+// egress policy name and the integrity paths.
+func (c SessionConfig) Validate() error {
+	if c.Egress != nil && c.EgressPolicyName != "" {
+		return fmt.Errorf("egress policy and egress policy name can't be used at the same time: %w", ErrNotValid)
+	}
+
+	if c.Egress != nil {
+		if err := c.Egress.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := ValidateIntegrityPaths(c.IntegrityPaths); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// IsZero returns true when the session has no configuration.
+func (c SessionConfig) IsZero() bool {
+	return c.Name == "" && len(c.Env) == 0 && c.Egress == nil && c.EgressPolicyName == "" && len(c.IntegrityPaths) == 0
+}
+
+// RedactedValue replaces the secret values shown to the users.
+const RedactedValue = "<redacted>"
+
+// secretEnvKeyRegexp matches the secret looking environment variable names (e.g.
+// GITHUB_TOKEN, DB_PASSWORD, AWS_SECRET_ACCESS_KEY).
+var secretEnvKeyRegexp = regexp.MustCompile(`(?i)(token|secret|password|passwd|pass$|pwd|api[_-]?key|private[_-]?key|access[_-]?key|credential|auth|cookie|dsn)`)
+
+// IsSecretEnvKey returns true when the environment variable name looks like it
+// holds a secret.
+func IsSecretEnvKey(key string) bool {
+	return secretEnvKeyRegexp.MatchString(key)
+}
+
+// Redacted returns a copy of the session with the values of the secret looking
+// environment variables replaced by [RedactedValue], to show it to the users.
+func (c SessionConfig) Redacted() SessionConfig {
+	if len(c.Env) == 0 {
+		return c
+	}
+
+	env := make(map[string]string, len(c.Env))
+	for k, v := range c.Env {
+		if IsSecretEnvKey(k) {
+			v = RedactedValue
+		}
+		env[k] = v
+	}
+	c.Env = env
+	return c
+}
+
+// StartTimeouts are the timeouts of the sandbox start steps. The zero values use
+// the engine defaults.
+type StartTimeouts struct {
+	// Boot is the maximum time to wait for the guest to boot and accept SSH connections.
+	Boot time.Duration
+	// SSHDial is the timeout of each guest SSH connection attempt.
+	SSHDial time.Duration
+	// SSHRetries is the maximum number of guest SSH connection attempts, zero keeps
+	// retrying until the boot timeout.
+	SSHRetries int
+	// FilesystemExpand is the timeout of the guest setup after the boot (filesystem
+	// expansion), zero means no timeout.
+	FilesystemExpand time.Duration
+}
+
+// Validate checks the timeouts are not negative.
+func (t StartTimeouts) Validate() error {
+	if t.Boot < 0 {
+		return fmt.Errorf("boot timeout can't be negative: %w", ErrNotValid)
+	}
+	if t.SSHDial < 0 {
+		return fmt.Errorf("SSH dial timeout can't be negative: %w", ErrNotValid)
+	}
+	if t.SSHRetries < 0 {
+		return fmt.Errorf("SSH retries can't be negative: %w", ErrNotValid)
+	}
+	if t.FilesystemExpand < 0 {
+		return fmt.Errorf("filesystem expand timeout can't be negative: %w", ErrNotValid)
+	}
+	return nil
+}
+
+// Merge returns the timeouts with the non-zero values of override replacing them.
+func (t StartTimeouts) Merge(override StartTimeouts) StartTimeouts {
+	if override.Boot != 0 {
+		t.Boot = override.Boot
+	}
+	if override.SSHDial != 0 {
+		t.SSHDial = override.SSHDial
+	}
+	if override.SSHRetries != 0 {
+		t.SSHRetries = override.SSHRetries
+	}
+	if override.FilesystemExpand != 0 {
+		t.FilesystemExpand = override.FilesystemExpand
+	}
+	return t
 }
 
 // EgressAction represents the action for an egress rule or default policy.
@@ -62,11 +307,37 @@ const (
 	EgressActionDeny EgressAction = "deny"
 )
 
+// EgressSystemService is a common infrastructure service the sandboxes need, allowed
+// by an egress policy with built-in rules.
+type EgressSystemService string
+
+const (
+	// EgressSystemServiceNTP allows the public NTP servers (UDP 123) to sync the clock.
+	EgressSystemServiceNTP EgressSystemService = "ntp"
+	// EgressSystemServicePackages allows the OS package mirrors (apt, apk, dnf, pacman).
+	EgressSystemServicePackages EgressSystemService = "packages"
+	// EgressSystemServiceRegistries allows the public container registries.
+	EgressSystemServiceRegistries EgressSystemService = "registries"
+)
+
+// EgressSystemServices are all the egress system services.
+var EgressSystemServices = []EgressSystemService{EgressSystemServiceNTP, EgressSystemServicePackages, EgressSystemServiceRegistries}
+
 // EgressPolicy defines network egress filtering rules for a sandbox.
 // When set, a proxy process is launched alongside the VM to enforce these rules.
 type EgressPolicy struct {
 	Default EgressAction // Default action when no rule matches.
 	Rules   []EgressRule // Evaluated in order, first match wins.
+	// FailClosed denies the allowed traffic whose destination can't be verified (it
+	// doesn't resolve or resolves to a non public address) instead of letting it through.
+	FailClosed bool
+	// DNSUpstreams are the resolvers the egress DNS proxy forwards the allowed queries
+	// to, tried in order: plain DNS ("IP[:port]"), DNS-over-TLS ("tls://host[:port]")
+	// or DNS-over-HTTPS ("https://host/path"). Empty uses the engine default.
+	DNSUpstreams []string
+	// AllowSystemServices allows the domains (and ports) of common infrastructure
+	// services with built-in rules, evaluated after Rules so they can still be denied.
+	AllowSystemServices []EgressSystemService
 }
 
 // Validate validates the egress policy.
@@ -82,6 +353,52 @@ func (p *EgressPolicy) Validate() error {
 		if r.Action != EgressActionAllow && r.Action != EgressActionDeny {
 			return fmt.Errorf("egress rule[%d]: action must be %q or %q, got %q: %w", i, EgressActionAllow, EgressActionDeny, r.Action, ErrNotValid)
 		}
+		if r.ICMP && r.Action != EgressActionAllow {
+			return fmt.Errorf("egress rule[%d]: icmp is only valid on allow rules: %w", i, ErrNotValid)
+		}
+	}
+
+	for i, u := range p.DNSUpstreams {
+		if err := ValidateDNSUpstream(u); err != nil {
+			return fmt.Errorf("egress dns upstream[%d]: %w", i, err)
+		}
+	}
+
+	for i, s := range p.AllowSystemServices {
+		if !slices.Contains(EgressSystemServices, s) {
+			return fmt.Errorf("egress system service[%d]: must be one of %v, got %q: %w", i, EgressSystemServices, s, ErrNotValid)
+		}
+	}
+
+	return nil
+}
+
+// ValidateDNSUpstream validates a DNS upstream resolver address, see
+// [EgressPolicy].DNSUpstreams. Plain DNS resolvers must be IPs, they can't be
+// resolved without a resolver.
+func ValidateDNSUpstream(upstream string) error {
+	switch {
+	case strings.HasPrefix(upstream, "tls://"):
+		host := strings.TrimPrefix(upstream, "tls://")
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if host == "" || strings.ContainsAny(host, "/?#@") {
+			return fmt.Errorf("invalid DNS-over-TLS resolver %q: %w", upstream, ErrNotValid)
+		}
+	case strings.HasPrefix(upstream, "https://"):
+		u, err := url.Parse(upstream)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("invalid DNS-over-HTTPS resolver %q: %w", upstream, ErrNotValid)
+		}
+	default:
+		host := upstream
+		if h, _, err := net.SplitHostPort(upstream); err == nil {
+			host = h
+		}
+		if net.ParseIP(host) == nil {
+			return fmt.Errorf("invalid DNS resolver %q, must be an IP, tls:// or https:// address: %w", upstream, ErrNotValid)
+		}
 	}
 
 	return nil
@@ -91,12 +408,184 @@ func (p *EgressPolicy) Validate() error {
 type EgressRule struct {
 	Domain string       // Domain pattern: "github.com", "*.github.com", or "*".
 	Action EgressAction // Allow or deny.
+	ICMP   bool         // Also allow ICMP echo (ping) to the IPs the matched domains resolve to (allow rules only).
+}
+
+// EgressTestReason is why an egress test target got its action.
+type EgressTestReason string
+
+const (
+	// EgressTestReasonRule means a rule matched the target domain.
+	EgressTestReasonRule EgressTestReason = "rule"
+	// EgressTestReasonDefault means no rule matched and the default action applies.
+	EgressTestReasonDefault EgressTestReason = "default"
+	// EgressTestReasonIPAddress means the target is an IP address, always denied
+	// because the rules can only be evaluated on domains.
+	EgressTestReasonIPAddress EgressTestReason = "ip-address"
+	// EgressTestReasonSystemService means a built-in rule of an allowed system
+	// service matched the target domain.
+	EgressTestReasonSystemService EgressTestReason = "system-service"
+)
+
+// EgressTestResult is the offline evaluation of a target against an egress policy.
+type EgressTestResult struct {
+	Target string       // Tested URL, host or IP.
+	Domain string       // Domain the rules were matched against, empty for IPs.
+	Action EgressAction // Resulting action.
+	Reason EgressTestReason
+	// RuleIndex is the index of the matched rule, -1 when no policy rule matched.
+	RuleIndex int
+	// Rule is the matched rule (a built-in one for system services), nil when no
+	// rule matched.
+	Rule *EgressRule
 }
 
 // FirecrackerEngineConfig contains Firecracker-specific engine configuration.
 type FirecrackerEngineConfig struct {
 	RootFS      string
 	KernelImage string
+	// KernelArgs are extra kernel command line arguments. The ones with the same key
+	// as a default argument (e.g. "panic=5") override it, the rest are appended.
+	KernelArgs []string
+	// Boot are the guest boot options.
+	Boot BootOptions
+	// Networks are the additional network interfaces (eth1, eth2...), eth0 is always
+	// the engine managed NAT interface.
+	Networks []NetworkInterface
+	// Seccomp is the syscall filtering of the Firecracker process.
+	Seccomp SeccompOptions
+	// EgressInterface is the host network interface the NAT traffic of the sandbox
+	// goes out through, empty uses the engine default (usually the default route).
+	EgressInterface string
+	// Network are the MTU and MSS clamping options of the sandbox interfaces.
+	Network NetworkOptions
+}
+
+// The MTU limits of the sandbox interfaces, the IPv4 minimum to keep the guest
+// reachable and the jumbo frames maximum.
+const (
+	MinMTU = 576
+	MaxMTU = 9000
+)
+
+// NetworkOptions are the MTU and TCP MSS clamping options of the sandbox network
+// interfaces, for hosts whose uplink has a lower MTU than the Ethernet default
+// (e.g. VPN tunnels).
+type NetworkOptions struct {
+	// MTU is the MTU of the host TAP devices and the guest interfaces, 0 uses the
+	// default (1500).
+	MTU int
+	// ClampMSS rewrites the MSS of the forwarded TCP SYN packets of the sandbox to
+	// the MTU of the route they go out through, so the connections don't hang on
+	// large transfers when a smaller MTU in the path drops the packets.
+	ClampMSS bool
+}
+
+// Validate validates the network options.
+func (o NetworkOptions) Validate() error {
+	if o.MTU != 0 && (o.MTU < MinMTU || o.MTU > MaxMTU) {
+		return fmt.Errorf("mtu must be between %d and %d: %w", MinMTU, MaxMTU, ErrNotValid)
+	}
+	return nil
+}
+
+// SeccompMode is the seccomp filtering mode of the sandbox VM process.
+type SeccompMode string
+
+const (
+	// SeccompModeDefault uses the seccomp filters built in Firecracker.
+	SeccompModeDefault SeccompMode = ""
+	// SeccompModeDisabled runs the VM process without seccomp filters.
+	SeccompModeDisabled SeccompMode = "disabled"
+	// SeccompModeCustom uses the custom seccomp filters of a file.
+	SeccompModeCustom SeccompMode = "custom"
+)
+
+// SeccompOptions are the seccomp options of the sandbox VM process.
+type SeccompOptions struct {
+	Mode SeccompMode
+	// Filter is the path of the compiled custom filters (e.g. by seccompiler-bin),
+	// required by the custom mode.
+	Filter string
+}
+
+// Validate validates the seccomp options.
+func (o SeccompOptions) Validate() error {
+	switch o.Mode {
+	case SeccompModeDefault, SeccompModeDisabled:
+		if o.Filter != "" {
+			return fmt.Errorf("seccomp filter can only be used with the custom mode: %w", ErrNotValid)
+		}
+	case SeccompModeCustom:
+		if o.Filter == "" {
+			return fmt.Errorf("seccomp custom mode requires a filter file: %w", ErrNotValid)
+		}
+	default:
+		return fmt.Errorf("unknown seccomp mode %q: %w", o.Mode, ErrNotValid)
+	}
+	return nil
+}
+
+// SeccompStatus is the seccomp enforcement of a running sandbox VM process.
+type SeccompStatus struct {
+	// Enforced is true when the process runs with seccomp filters.
+	Enforced bool
+	// Filters is the number of filters installed on the process, 0 when the host
+	// kernel doesn't report it.
+	Filters int
+}
+
+// NetworkMode is the mode of a sandbox network interface.
+type NetworkMode string
+
+const (
+	// NetworkModeNAT gives the interface outbound access through the host network (NAT).
+	NetworkModeNAT NetworkMode = "nat"
+	// NetworkModeIsolated connects the interface to a private network shared with the
+	// sandboxes attached to the same network, without outbound access.
+	NetworkModeIsolated NetworkMode = "isolated"
+)
+
+// NetworkInterface is an additional sandbox network interface.
+type NetworkInterface struct {
+	Mode NetworkMode
+	// Network is the isolated network name, required for isolated interfaces.
+	Network string
+	// Egress is the egress policy of NAT interfaces, nil means no egress filtering.
+	Egress *EgressPolicy
+	// Address is the interface guest IP, set by the engine on creation.
+	Address string
+}
+
+// hostInterfaceMaxLen is the maximum length of a Linux network interface name.
+const hostInterfaceMaxLen = 15
+
+// ValidateHostInterface validates the name of a host network interface (e.g. eth1,
+// wg0), it doesn't check the interface exists.
+func ValidateHostInterface(name string) error {
+	if name == "" {
+		return fmt.Errorf("network interface name is required: %w", ErrNotValid)
+	}
+	if len(name) > hostInterfaceMaxLen {
+		return fmt.Errorf("network interface name %q is longer than %d characters: %w", name, hostInterfaceMaxLen, ErrNotValid)
+	}
+	if name == "." || name == ".." || strings.ContainsAny(name, "/: \t\n") {
+		return fmt.Errorf("network interface name %q is invalid: %w", name, ErrNotValid)
+	}
+	return nil
+}
+
+// BootOptions are the guest boot options.
+type BootOptions struct {
+	// ReadOnlyRootFS attaches the rootfs as a read-only drive.
+	ReadOnlyRootFS bool
+	// DisableConsole disables the guest serial console, this speeds up the boot.
+	DisableConsole bool
+	// Init overrides the guest init binary path.
+	Init string
+	// Profile is the kernel profile the sandbox was created with (e.g. fast-boot),
+	// its options are already in the kernel args and boot options.
+	Profile string
 }
 
 // Resources defines the compute resources for a sandbox.
@@ -104,8 +593,19 @@ type Resources struct {
 	VCPUs    float64
 	MemoryMB int
 	DiskGB   int
+	// MaxVCPUs and MaxMemoryMB are the ceilings a running sandbox can be scaled up
+	// to without a restart, the VM boots with them and the guest only uses VCPUs and
+	// MemoryMB (0 means no scaling over VCPUs and MemoryMB).
+	MaxVCPUs    float64
+	MaxMemoryMB int
 }
 
+// BootVCPUs returns the vCPUs the sandbox VM boots with, its scaling ceiling.
+func (r Resources) BootVCPUs() float64 { return max(r.VCPUs, r.MaxVCPUs) }
+
+// BootMemoryMB returns the memory the sandbox VM boots with, its scaling ceiling.
+func (r Resources) BootMemoryMB() int { return max(r.MemoryMB, r.MaxMemoryMB) }
+
 // Validate validates the sandbox configuration.
 func (c *SandboxConfig) Validate() error {
 	if c.Name == "" {
@@ -123,6 +623,17 @@ func (c *SandboxConfig) Validate() error {
 	if c.FirecrackerEngine.KernelImage == "" {
 		return fmt.Errorf("firecracker engine kernel_image is required: %w", ErrNotValid)
 	}
+	if err := c.FirecrackerEngine.Seccomp.Validate(); err != nil {
+		return err
+	}
+	if c.FirecrackerEngine.EgressInterface != "" {
+		if err := ValidateHostInterface(c.FirecrackerEngine.EgressInterface); err != nil {
+			return err
+		}
+	}
+	if err := c.FirecrackerEngine.Network.Validate(); err != nil {
+		return err
+	}
 
 	// Validate resources
 	if c.Resources.VCPUs <= 0 {
@@ -134,5 +645,50 @@ func (c *SandboxConfig) Validate() error {
 	if c.Resources.DiskGB <= 0 {
 		return fmt.Errorf("disk_gb must be positive: %w", ErrNotValid)
 	}
+	if c.Resources.MaxVCPUs != 0 && c.Resources.MaxVCPUs < c.Resources.VCPUs {
+		return fmt.Errorf("max_vcpus can't be lower than vcpus: %w", ErrNotValid)
+	}
+	if c.Resources.MaxMemoryMB != 0 && c.Resources.MaxMemoryMB < c.Resources.MemoryMB {
+		return fmt.Errorf("max_memory_mb can't be lower than memory_mb: %w", ErrNotValid)
+	}
+
+	if c.Clock != nil && !c.Clock.BootTime.IsZero() && c.Clock.Offset != 0 {
+		return fmt.Errorf("clock boot time and offset can't be used together: %w", ErrNotValid)
+	}
+
+	if err := ValidateNamedPorts(c.Ports); err != nil {
+		return err
+	}
+
+	if c.ExecProfile != nil {
+		if err := c.ExecProfile.Validate(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// WaitCondition is a sandbox condition that can be waited for.
+type WaitCondition string
+
+const (
+	// WaitConditionRunning is met when the sandbox is running.
+	WaitConditionRunning WaitCondition = "running"
+	// WaitConditionStopped is met when the sandbox is stopped.
+	WaitConditionStopped WaitCondition = "stopped"
+	// WaitConditionSSHReady is met when the sandbox is running and accepts commands.
+	WaitConditionSSHReady WaitCondition = "ssh-ready"
+	// WaitConditionHealthy is met when the sandbox is SSH ready and the engine
+	// reports the VM process as alive.
+	WaitConditionHealthy WaitCondition = "healthy"
+)
+
+// Validate validates the wait condition.
+func (c WaitCondition) Validate() error {
+	switch c {
+	case WaitConditionRunning, WaitConditionStopped, WaitConditionSSHReady, WaitConditionHealthy:
+		return nil
+	default:
+		return fmt.Errorf("unknown wait condition %q: %w", c, ErrNotValid)
+	}
+}
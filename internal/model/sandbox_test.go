@@ -3,6 +3,7 @@ package model_test
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -48,6 +49,123 @@ func TestSandboxConfigValidate(t *testing.T) {
 			},
 			expErr: true,
 		},
+		"valid resource ceilings": {
+			cfg: model.SandboxConfig{
+				Name:              "test",
+				FirecrackerEngine: base.FirecrackerEngine,
+				Resources:         model.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 10, MaxVCPUs: 4, MaxMemoryMB: 4096},
+			},
+		},
+		"max vcpus lower than vcpus": {
+			cfg: model.SandboxConfig{
+				Name:              "test",
+				FirecrackerEngine: base.FirecrackerEngine,
+				Resources:         model.Resources{VCPUs: 2, MemoryMB: 512, DiskGB: 10, MaxVCPUs: 1},
+			},
+			expErr: true,
+		},
+		"max memory lower than memory": {
+			cfg: model.SandboxConfig{
+				Name:              "test",
+				FirecrackerEngine: base.FirecrackerEngine,
+				Resources:         model.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 10, MaxMemoryMB: 256},
+			},
+			expErr: true,
+		},
+		"valid clock": {
+			cfg: model.SandboxConfig{
+				Name:              "test",
+				FirecrackerEngine: base.FirecrackerEngine,
+				Resources:         base.Resources,
+				Clock:             &model.ClockConfig{Offset: -24 * time.Hour},
+			},
+		},
+		"clock with boot time and offset": {
+			cfg: model.SandboxConfig{
+				Name:              "test",
+				FirecrackerEngine: base.FirecrackerEngine,
+				Resources:         base.Resources,
+				Clock:             &model.ClockConfig{BootTime: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), Offset: time.Hour},
+			},
+			expErr: true,
+		},
+		"valid custom seccomp": {
+			cfg: model.SandboxConfig{
+				Name:              "test",
+				FirecrackerEngine: &model.FirecrackerEngineConfig{RootFS: "/images/rootfs.ext4", KernelImage: "/images/vmlinux", Seccomp: model.SeccompOptions{Mode: model.SeccompModeCustom, Filter: "/etc/sbx/filter.bpf"}},
+				Resources:         base.Resources,
+			},
+		},
+		"custom seccomp without filter": {
+			cfg: model.SandboxConfig{
+				Name:              "test",
+				FirecrackerEngine: &model.FirecrackerEngineConfig{RootFS: "/images/rootfs.ext4", KernelImage: "/images/vmlinux", Seccomp: model.SeccompOptions{Mode: model.SeccompModeCustom}},
+				Resources:         base.Resources,
+			},
+			expErr: true,
+		},
+		"seccomp filter without custom mode": {
+			cfg: model.SandboxConfig{
+				Name:              "test",
+				FirecrackerEngine: &model.FirecrackerEngineConfig{RootFS: "/images/rootfs.ext4", KernelImage: "/images/vmlinux", Seccomp: model.SeccompOptions{Mode: model.SeccompModeDisabled, Filter: "/etc/sbx/filter.bpf"}},
+				Resources:         base.Resources,
+			},
+			expErr: true,
+		},
+		"unknown seccomp mode": {
+			cfg: model.SandboxConfig{
+				Name:              "test",
+				FirecrackerEngine: &model.FirecrackerEngineConfig{RootFS: "/images/rootfs.ext4", KernelImage: "/images/vmlinux", Seccomp: model.SeccompOptions{Mode: "strict"}},
+				Resources:         base.Resources,
+			},
+			expErr: true,
+		},
+		"valid egress interface": {
+			cfg: model.SandboxConfig{
+				Name:              "test",
+				FirecrackerEngine: &model.FirecrackerEngineConfig{RootFS: "/images/rootfs.ext4", KernelImage: "/images/vmlinux", EgressInterface: "wg0"},
+				Resources:         base.Resources,
+			},
+		},
+		"egress interface name too long": {
+			cfg: model.SandboxConfig{
+				Name:              "test",
+				FirecrackerEngine: &model.FirecrackerEngineConfig{RootFS: "/images/rootfs.ext4", KernelImage: "/images/vmlinux", EgressInterface: "a-very-long-interface"},
+				Resources:         base.Resources,
+			},
+			expErr: true,
+		},
+		"invalid egress interface name": {
+			cfg: model.SandboxConfig{
+				Name:              "test",
+				FirecrackerEngine: &model.FirecrackerEngineConfig{RootFS: "/images/rootfs.ext4", KernelImage: "/images/vmlinux", EgressInterface: "eth0/1"},
+				Resources:         base.Resources,
+			},
+			expErr: true,
+		},
+		"valid network mtu": {
+			cfg: model.SandboxConfig{
+				Name:              "test",
+				FirecrackerEngine: &model.FirecrackerEngineConfig{RootFS: "/images/rootfs.ext4", KernelImage: "/images/vmlinux", Network: model.NetworkOptions{MTU: 1380, ClampMSS: true}},
+				Resources:         base.Resources,
+			},
+		},
+		"network mtu too low": {
+			cfg: model.SandboxConfig{
+				Name:              "test",
+				FirecrackerEngine: &model.FirecrackerEngineConfig{RootFS: "/images/rootfs.ext4", KernelImage: "/images/vmlinux", Network: model.NetworkOptions{MTU: 500}},
+				Resources:         base.Resources,
+			},
+			expErr: true,
+		},
+		"network mtu too high": {
+			cfg: model.SandboxConfig{
+				Name:              "test",
+				FirecrackerEngine: &model.FirecrackerEngineConfig{RootFS: "/images/rootfs.ext4", KernelImage: "/images/vmlinux", Network: model.NetworkOptions{MTU: 9001}},
+				Resources:         base.Resources,
+			},
+			expErr: true,
+		},
 	}
 
 	for name, tt := range tests {
@@ -62,3 +180,202 @@ func TestSandboxConfigValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestClockConfigTime(t *testing.T) {
+	now := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	bootTime := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		clock   model.ClockConfig
+		expTime time.Time
+	}{
+		"Without boot time and offset the host time should be used.": {
+			expTime: now,
+		},
+		"The offset should be added to the host time.": {
+			clock:   model.ClockConfig{Offset: -48 * time.Hour},
+			expTime: time.Date(2026, 1, 28, 10, 0, 0, 0, time.UTC),
+		},
+		"The boot time should be used as is.": {
+			clock:   model.ClockConfig{BootTime: bootTime},
+			expTime: bootTime,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expTime, test.clock.Time(now))
+		})
+	}
+}
+
+func TestStartTimeoutsMerge(t *testing.T) {
+	base := model.StartTimeouts{Boot: time.Minute, SSHDial: 2 * time.Second, SSHRetries: 10}
+
+	tests := map[string]struct {
+		override    model.StartTimeouts
+		expTimeouts model.StartTimeouts
+	}{
+		"Without override the base timeouts should be used.": {
+			expTimeouts: base,
+		},
+		"The override non-zero values should replace the base ones.": {
+			override:    model.StartTimeouts{Boot: 5 * time.Minute, FilesystemExpand: time.Minute},
+			expTimeouts: model.StartTimeouts{Boot: 5 * time.Minute, SSHDial: 2 * time.Second, SSHRetries: 10, FilesystemExpand: time.Minute},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expTimeouts, base.Merge(test.override))
+		})
+	}
+}
+
+func TestStartTimeoutsValidate(t *testing.T) {
+	tests := map[string]struct {
+		timeouts model.StartTimeouts
+		expErr   bool
+	}{
+		"Zero timeouts should be valid.": {},
+		"Positive timeouts should be valid.": {
+			timeouts: model.StartTimeouts{Boot: time.Minute, SSHDial: time.Second, SSHRetries: 3, FilesystemExpand: time.Minute},
+		},
+		"A negative boot timeout should fail.": {
+			timeouts: model.StartTimeouts{Boot: -time.Second},
+			expErr:   true,
+		},
+		"Negative SSH retries should fail.": {
+			timeouts: model.StartTimeouts{SSHRetries: -1},
+			expErr:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.timeouts.Validate()
+			if test.expErr {
+				assert.ErrorIs(t, err, model.ErrNotValid)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateDNSUpstream(t *testing.T) {
+	tests := map[string]struct {
+		upstream string
+		expErr   bool
+	}{
+		"An IP should be valid.":                            {upstream: "1.1.1.1"},
+		"An IP with port should be valid.":                  {upstream: "10.0.0.53:5353"},
+		"An IPv6 with port should be valid.":                {upstream: "[2606:4700:4700::1111]:53"},
+		"A DNS-over-TLS hostname should be valid.":          {upstream: "tls://dns.google"},
+		"A DNS-over-TLS address with port should be valid.": {upstream: "tls://1.1.1.1:853"},
+		"A DNS-over-HTTPS URL should be valid.":             {upstream: "https://cloudflare-dns.com/dns-query"},
+		"A plain hostname should fail.":                     {upstream: "dns.google", expErr: true},
+		"An empty DNS-over-TLS host should fail.":           {upstream: "tls://", expErr: true},
+		"A DNS-over-TLS path should fail.":                  {upstream: "tls://dns.google/dns-query", expErr: true},
+		"A DNS-over-HTTPS URL without host should fail.":    {upstream: "https:///dns-query", expErr: true},
+		"An unknown scheme should fail.":                    {upstream: "quic://dns.adguard.com", expErr: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := model.ValidateDNSUpstream(test.upstream)
+			if test.expErr {
+				assert.ErrorIs(t, err, model.ErrNotValid)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestEgressPolicyValidate(t *testing.T) {
+	tests := map[string]struct {
+		policy model.EgressPolicy
+		expErr bool
+	}{
+		"A policy with system services should be valid.": {
+			policy: model.EgressPolicy{
+				Default:             model.EgressActionDeny,
+				AllowSystemServices: []model.EgressSystemService{model.EgressSystemServiceNTP, model.EgressSystemServicePackages, model.EgressSystemServiceRegistries},
+			},
+		},
+		"An unknown system service should fail.": {
+			policy: model.EgressPolicy{
+				Default:             model.EgressActionDeny,
+				AllowSystemServices: []model.EgressSystemService{"smtp"},
+			},
+			expErr: true,
+		},
+		"An ICMP allow rule should be valid.": {
+			policy: model.EgressPolicy{
+				Default: model.EgressActionDeny,
+				Rules:   []model.EgressRule{{Domain: "github.com", Action: model.EgressActionAllow, ICMP: true}},
+			},
+		},
+		"An ICMP deny rule should fail.": {
+			policy: model.EgressPolicy{
+				Default: model.EgressActionAllow,
+				Rules:   []model.EgressRule{{Domain: "github.com", Action: model.EgressActionDeny, ICMP: true}},
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.policy.Validate()
+			if test.expErr {
+				assert.ErrorIs(t, err, model.ErrNotValid)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSessionConfigRedacted(t *testing.T) {
+	tests := map[string]struct {
+		session model.SessionConfig
+		exp     model.SessionConfig
+	}{
+		"A session without env should be returned as is.": {
+			session: model.SessionConfig{EgressPolicyName: "github"},
+			exp:     model.SessionConfig{EgressPolicyName: "github"},
+		},
+
+		"The secret looking env values should be redacted.": {
+			session: model.SessionConfig{Env: map[string]string{
+				"GITHUB_TOKEN":          "ghp_x",
+				"DB_PASSWORD":           "hunter2",
+				"AWS_SECRET_ACCESS_KEY": "abc",
+				"OPENAI_API_KEY":        "sk-x",
+				"GOFLAGS":               "-mod=mod",
+				"HOME":                  "/root",
+			}},
+			exp: model.SessionConfig{Env: map[string]string{
+				"GITHUB_TOKEN":          model.RedactedValue,
+				"DB_PASSWORD":           model.RedactedValue,
+				"AWS_SECRET_ACCESS_KEY": model.RedactedValue,
+				"OPENAI_API_KEY":        model.RedactedValue,
+				"GOFLAGS":               "-mod=mod",
+				"HOME":                  "/root",
+			}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.exp, test.session.Redacted())
+		})
+	}
+
+	// The original session should not be changed.
+	session := model.SessionConfig{Env: map[string]string{"API_KEY": "x"}}
+	_ = session.Redacted()
+	assert.Equal(t, "x", session.Env["API_KEY"])
+}
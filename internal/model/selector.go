@@ -0,0 +1,74 @@
+package model
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	// sandboxPatternRegexPrefix marks a sandbox name pattern as a regular expression.
+	sandboxPatternRegexPrefix = "re:"
+	// sandboxPatternMaxLen bounds the pattern size, regular expressions are
+	// evaluated in linear time but their compilation grows with the pattern.
+	sandboxPatternMaxLen = 256
+)
+
+// SandboxNamePattern selects sandboxes by name, it's a glob ("ci-*", "test-?",
+// "run-[0-9]*") or a regular expression prefixed with "re:" ("re:^ci-[0-9]+$").
+// Globs match the whole name, regular expressions match any part of it unless
+// they are anchored.
+type SandboxNamePattern struct {
+	raw  string
+	glob string
+	re   *regexp.Regexp
+}
+
+// IsSandboxNamePattern returns true if the value is a sandbox name pattern
+// instead of a plain sandbox name or ID.
+func IsSandboxNamePattern(s string) bool {
+	return strings.HasPrefix(s, sandboxPatternRegexPrefix) || strings.ContainsAny(s, "*?[")
+}
+
+// NewSandboxNamePattern parses a sandbox name pattern, see [SandboxNamePattern].
+func NewSandboxNamePattern(pattern string) (*SandboxNamePattern, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("sandbox name pattern is required: %w", ErrNotValid)
+	}
+
+	if len(pattern) > sandboxPatternMaxLen {
+		return nil, fmt.Errorf("sandbox name pattern is longer than %d characters: %w", sandboxPatternMaxLen, ErrNotValid)
+	}
+
+	if expr, ok := strings.CutPrefix(pattern, sandboxPatternRegexPrefix); ok {
+		if expr == "" {
+			return nil, fmt.Errorf("sandbox name pattern %q has an empty regular expression: %w", pattern, ErrNotValid)
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sandbox name pattern %q: %s: %w", pattern, err, ErrNotValid)
+		}
+		return &SandboxNamePattern{raw: pattern, re: re}, nil
+	}
+
+	// Match against an empty name only checks the pattern syntax.
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid sandbox name pattern %q: %s: %w", pattern, err, ErrNotValid)
+	}
+
+	return &SandboxNamePattern{raw: pattern, glob: pattern}, nil
+}
+
+// Match returns true if the sandbox name matches the pattern.
+func (p SandboxNamePattern) Match(name string) bool {
+	if p.re != nil {
+		return p.re.MatchString(name)
+	}
+
+	ok, _ := path.Match(p.glob, name)
+	return ok
+}
+
+// String returns the pattern as it was parsed.
+func (p SandboxNamePattern) String() string { return p.raw }
@@ -0,0 +1,103 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestSandboxNamePattern(t *testing.T) {
+	names := []string{"ci-1", "ci-22", "ci", "dev-ci-1", "test-a"}
+
+	tests := map[string]struct {
+		pattern  string
+		expNames []string
+		expErr   bool
+	}{
+		"A glob should match the whole name.": {
+			pattern:  "ci-*",
+			expNames: []string{"ci-1", "ci-22"},
+		},
+
+		"A glob with a single character wildcard should match one character.": {
+			pattern:  "ci-?",
+			expNames: []string{"ci-1"},
+		},
+
+		"A glob with a character class should match the class.": {
+			pattern:  "*-[a-z]",
+			expNames: []string{"test-a"},
+		},
+
+		"A regular expression should match any part of the name.": {
+			pattern:  "re:ci-[0-9]+",
+			expNames: []string{"ci-1", "ci-22", "dev-ci-1"},
+		},
+
+		"An anchored regular expression should match the whole name.": {
+			pattern:  "re:^ci-[0-9]{2}$",
+			expNames: []string{"ci-22"},
+		},
+
+		"An empty pattern should fail.": {
+			pattern: "",
+			expErr:  true,
+		},
+
+		"An invalid glob should fail.": {
+			pattern: "ci-[",
+			expErr:  true,
+		},
+
+		"An invalid regular expression should fail.": {
+			pattern: "re:ci-(",
+			expErr:  true,
+		},
+
+		"An empty regular expression should fail.": {
+			pattern: "re:",
+			expErr:  true,
+		},
+
+		"A too long pattern should fail.": {
+			pattern: "re:" + strings.Repeat("a", 300),
+			expErr:  true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			p, err := model.NewSandboxNamePattern(test.pattern)
+			if test.expErr {
+				assert.ErrorIs(err, model.ErrNotValid)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(test.pattern, p.String())
+
+			var gotNames []string
+			for _, n := range names {
+				if p.Match(n) {
+					gotNames = append(gotNames, n)
+				}
+			}
+			assert.Equal(test.expNames, gotNames)
+		})
+	}
+}
+
+func TestIsSandboxNamePattern(t *testing.T) {
+	assert.True(t, model.IsSandboxNamePattern("ci-*"))
+	assert.True(t, model.IsSandboxNamePattern("ci-?"))
+	assert.True(t, model.IsSandboxNamePattern("ci-[0-9]"))
+	assert.True(t, model.IsSandboxNamePattern("re:ci"))
+	assert.False(t, model.IsSandboxNamePattern("ci-1"))
+	assert.False(t, model.IsSandboxNamePattern("01JKXYZ"))
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// SnapshotStoreEncryption is the server-side encryption of the snapshot bundles
+// stored in an object storage.
+type SnapshotStoreEncryption string
+
+const (
+	// SnapshotStoreEncryptionDefault uses the bucket default encryption.
+	SnapshotStoreEncryptionDefault SnapshotStoreEncryption = ""
+	// SnapshotStoreEncryptionAES256 encrypts with the keys managed by the storage (SSE-S3).
+	SnapshotStoreEncryptionAES256 SnapshotStoreEncryption = "AES256"
+	// SnapshotStoreEncryptionKMS encrypts with a KMS key (SSE-KMS), the bucket default
+	// KMS key unless one is set.
+	SnapshotStoreEncryptionKMS SnapshotStoreEncryption = "aws:kms"
+)
+
+// Validate validates the snapshot store encryption.
+func (e SnapshotStoreEncryption) Validate() error {
+	switch e {
+	case SnapshotStoreEncryptionDefault, SnapshotStoreEncryptionAES256, SnapshotStoreEncryptionKMS:
+		return nil
+	default:
+		return fmt.Errorf("unknown snapshot store encryption %q (allowed: AES256, aws:kms): %w", e, ErrNotValid)
+	}
+}
+
+// ParseSnapshotStoreURL parses a snapshot store URL (s3://bucket/prefix or
+// gs://bucket/prefix) into its scheme, bucket and key prefix.
+func ParseSnapshotStoreURL(rawURL string) (scheme, bucket, prefix string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", "", fmt.Errorf("invalid snapshot store url %q: %w", rawURL, ErrNotValid)
+	}
+	if u.Scheme != "s3" && u.Scheme != "gs" {
+		return "", "", "", fmt.Errorf("invalid snapshot store url %q, use s3://BUCKET[/PREFIX] or gs://BUCKET[/PREFIX]: %w", rawURL, ErrNotValid)
+	}
+	if u.Host == "" {
+		return "", "", "", fmt.Errorf("snapshot store url %q has no bucket: %w", rawURL, ErrNotValid)
+	}
+	return u.Scheme, u.Host, strings.Trim(u.Path, "/"), nil
+}
+
+// RemoteSnapshot is a snapshot image bundle stored in an object storage.
+type RemoteSnapshot struct {
+	// Name is the snapshot image name.
+	Name string
+	// URL is the bundle object URL (e.g. s3://bucket/prefix/name.tar).
+	URL string
+	// SizeBytes is the bundle size.
+	SizeBytes int64
+	// Parts is the number of uploaded parts, 1 for the bundles uploaded in a single
+	// request.
+	Parts int
+}
@@ -0,0 +1,142 @@
+package model
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// SpecField is a field of the sandbox spec, the configuration set at creation that
+// can't be changed afterwards.
+type SpecField string
+
+// The sandbox spec fields, a sandbox must be replaced to change them.
+const (
+	SpecFieldName            SpecField = "name"
+	SpecFieldImage           SpecField = "image"
+	SpecFieldResources       SpecField = "resources"
+	SpecFieldUserData        SpecField = "user_data"
+	SpecFieldClock           SpecField = "clock"
+	SpecFieldPorts           SpecField = "ports"
+	SpecFieldExecProfile     SpecField = "exec_profile"
+	SpecFieldRootFS          SpecField = "rootfs"
+	SpecFieldKernelImage     SpecField = "kernel_image"
+	SpecFieldKernelArgs      SpecField = "kernel_args"
+	SpecFieldBoot            SpecField = "boot"
+	SpecFieldNetworks        SpecField = "networks"
+	SpecFieldSeccomp         SpecField = "seccomp"
+	SpecFieldEgressInterface SpecField = "egress_interface"
+	SpecFieldNetwork         SpecField = "network"
+	SpecFieldWebhooks        SpecField = "webhooks"
+)
+
+// SpecDiff returns the spec fields that differ between the existing sandbox config
+// and the requested one, in a stable order. Engine assigned fields (e.g. network
+// addresses) are ignored.
+func SpecDiff(existing, requested SandboxConfig) []SpecField {
+	var diff []SpecField
+	if existing.Name != requested.Name {
+		diff = append(diff, SpecFieldName)
+	}
+	if existing.Image != requested.Image {
+		diff = append(diff, SpecFieldImage)
+	}
+	if existing.Resources != requested.Resources {
+		diff = append(diff, SpecFieldResources)
+	}
+	if existing.UserData != requested.UserData {
+		diff = append(diff, SpecFieldUserData)
+	}
+	if !clockEqual(existing.Clock, requested.Clock) {
+		diff = append(diff, SpecFieldClock)
+	}
+	if !maps.Equal(existing.Ports, requested.Ports) {
+		diff = append(diff, SpecFieldPorts)
+	}
+	if !execProfileEqual(existing.ExecProfile, requested.ExecProfile) {
+		diff = append(diff, SpecFieldExecProfile)
+	}
+
+	var efc, rfc FirecrackerEngineConfig
+	if existing.FirecrackerEngine != nil {
+		efc = *existing.FirecrackerEngine
+	}
+	if requested.FirecrackerEngine != nil {
+		rfc = *requested.FirecrackerEngine
+	}
+	if efc.RootFS != rfc.RootFS {
+		diff = append(diff, SpecFieldRootFS)
+	}
+	if efc.KernelImage != rfc.KernelImage {
+		diff = append(diff, SpecFieldKernelImage)
+	}
+	if !slices.Equal(efc.KernelArgs, rfc.KernelArgs) {
+		diff = append(diff, SpecFieldKernelArgs)
+	}
+	if efc.Boot != rfc.Boot {
+		diff = append(diff, SpecFieldBoot)
+	}
+	if !slices.EqualFunc(efc.Networks, rfc.Networks, networkEqual) {
+		diff = append(diff, SpecFieldNetworks)
+	}
+	if efc.Seccomp != rfc.Seccomp {
+		diff = append(diff, SpecFieldSeccomp)
+	}
+	if efc.EgressInterface != rfc.EgressInterface {
+		diff = append(diff, SpecFieldEgressInterface)
+	}
+	if efc.Network != rfc.Network {
+		diff = append(diff, SpecFieldNetwork)
+	}
+
+	return diff
+}
+
+// SandboxSpecDiff returns the spec fields that differ between the existing sandbox
+// and the requested config and webhooks, the create-time fields stored out of the
+// config included (see SpecDiff).
+func SandboxSpecDiff(existing Sandbox, requested SandboxConfig, webhooks []Webhook) []SpecField {
+	diff := SpecDiff(existing.Config, requested)
+	if !slices.EqualFunc(existing.Webhooks, webhooks, webhookEqual) {
+		diff = append(diff, SpecFieldWebhooks)
+	}
+	return diff
+}
+
+func webhookEqual(a, b Webhook) bool {
+	return a.URL == b.URL && a.Secret == b.Secret && slices.Equal(a.Events, b.Events)
+}
+
+func clockEqual(a, b *ClockConfig) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	// The boot time is stored with second precision.
+	return a.Offset == b.Offset && a.BootTime.Truncate(time.Second).Equal(b.BootTime.Truncate(time.Second))
+}
+
+func networkEqual(a, b NetworkInterface) bool {
+	if a.Mode != b.Mode || a.Network != b.Network {
+		return false
+	}
+	if a.Egress == nil || b.Egress == nil {
+		return a.Egress == b.Egress
+	}
+	return egressPolicyEqual(*a.Egress, *b.Egress)
+}
+
+func egressPolicyEqual(a, b EgressPolicy) bool {
+	return a.Default == b.Default &&
+		slices.Equal(a.Rules, b.Rules) &&
+		a.FailClosed == b.FailClosed &&
+		slices.Equal(a.DNSUpstreams, b.DNSUpstreams) &&
+		slices.Equal(a.AllowSystemServices, b.AllowSystemServices)
+}
+
+func execProfileEqual(a, b *ExecProfile) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return slices.Equal(a.PathPrepend, b.PathPrepend) && a.Locale == b.Locale && a.Umask == b.Umask &&
+		maps.Equal(a.Ulimits, b.Ulimits) && a.Shell == b.Shell
+}
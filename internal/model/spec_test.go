@@ -0,0 +1,170 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestSpecDiff(t *testing.T) {
+	base := func() model.SandboxConfig {
+		return model.SandboxConfig{
+			Name: "test",
+			FirecrackerEngine: &model.FirecrackerEngineConfig{
+				RootFS:      "/images/rootfs.ext4",
+				KernelImage: "/images/vmlinux",
+				Networks:    []model.NetworkInterface{{Mode: model.NetworkModeIsolated, Network: "backend", Address: "172.20.3.7"}},
+			},
+			Resources: model.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 10},
+			Clock:     &model.ClockConfig{BootTime: time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)},
+			Ports:     map[string]int{"web": 3000},
+			ExecProfile: &model.ExecProfile{
+				PathPrepend: []string{"/opt/tools/bin"},
+				Ulimits:     map[string]string{"nofile": "65536"},
+			},
+		}
+	}
+
+	withEgress := func() model.SandboxConfig {
+		c := base()
+		c.FirecrackerEngine.Networks = append(c.FirecrackerEngine.Networks, model.NetworkInterface{
+			Mode: model.NetworkModeNAT,
+			Egress: &model.EgressPolicy{
+				Default:             model.EgressActionDeny,
+				Rules:               []model.EgressRule{{Domain: "github.com", Action: model.EgressActionAllow}},
+				FailClosed:          true,
+				DNSUpstreams:        []string{"1.1.1.1"},
+				AllowSystemServices: []model.EgressSystemService{model.EgressSystemServiceNTP, model.EgressSystemServiceRegistries},
+			},
+		})
+		return c
+	}
+
+	tests := map[string]struct {
+		existing  func() model.SandboxConfig
+		requested func() model.SandboxConfig
+		expDiff   []model.SpecField
+	}{
+		"same spec": {
+			requested: base,
+		},
+		"engine assigned addresses and sub-second boot times are ignored": {
+			requested: func() model.SandboxConfig {
+				c := base()
+				c.FirecrackerEngine.Networks = []model.NetworkInterface{{Mode: model.NetworkModeIsolated, Network: "backend"}}
+				c.Clock = &model.ClockConfig{BootTime: time.Date(2026, 1, 30, 10, 0, 0, 500, time.UTC)}
+				return c
+			},
+		},
+		"different fields are returned in order": {
+			requested: func() model.SandboxConfig {
+				c := base()
+				c.Name = "test-2"
+				c.Resources.MemoryMB = 1024
+				c.Clock = nil
+				c.Ports = map[string]int{"web": 8080}
+				c.ExecProfile = &model.ExecProfile{PathPrepend: []string{"/opt/tools/bin"}, Ulimits: map[string]string{"nofile": "1024"}}
+				c.FirecrackerEngine.KernelArgs = []string{"quiet"}
+				c.FirecrackerEngine.Networks = nil
+				c.FirecrackerEngine.Seccomp = model.SeccompOptions{Mode: model.SeccompModeDisabled}
+				c.FirecrackerEngine.EgressInterface = "wg0"
+				c.FirecrackerEngine.Network = model.NetworkOptions{MTU: 1400, ClampMSS: true}
+				return c
+			},
+			expDiff: []model.SpecField{model.SpecFieldName, model.SpecFieldResources, model.SpecFieldClock, model.SpecFieldPorts, model.SpecFieldExecProfile, model.SpecFieldKernelArgs, model.SpecFieldNetworks, model.SpecFieldSeccomp, model.SpecFieldEgressInterface, model.SpecFieldNetwork},
+		},
+		"different NIC egress default": {
+			requested: func() model.SandboxConfig {
+				c := base()
+				c.FirecrackerEngine.Networks[0].Egress = &model.EgressPolicy{Default: model.EgressActionDeny}
+				return c
+			},
+			expDiff: []model.SpecField{model.SpecFieldNetworks},
+		},
+		"different NIC egress fail closed": {
+			existing: withEgress,
+			requested: func() model.SandboxConfig {
+				c := withEgress()
+				c.FirecrackerEngine.Networks[1].Egress.FailClosed = false
+				return c
+			},
+			expDiff: []model.SpecField{model.SpecFieldNetworks},
+		},
+		"different NIC egress DNS upstreams": {
+			existing: withEgress,
+			requested: func() model.SandboxConfig {
+				c := withEgress()
+				c.FirecrackerEngine.Networks[1].Egress.DNSUpstreams = []string{"tls://dns.example.com"}
+				return c
+			},
+			expDiff: []model.SpecField{model.SpecFieldNetworks},
+		},
+		"different NIC egress system services": {
+			existing: withEgress,
+			requested: func() model.SandboxConfig {
+				c := withEgress()
+				c.FirecrackerEngine.Networks[1].Egress.AllowSystemServices = []model.EgressSystemService{model.EgressSystemServiceNTP}
+				return c
+			},
+			expDiff: []model.SpecField{model.SpecFieldNetworks},
+		},
+		"same NIC egress policy": {
+			existing:  withEgress,
+			requested: withEgress,
+		},
+		"missing engine config": {
+			requested: func() model.SandboxConfig {
+				c := base()
+				c.FirecrackerEngine = nil
+				return c
+			},
+			expDiff: []model.SpecField{model.SpecFieldRootFS, model.SpecFieldKernelImage, model.SpecFieldNetworks},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			existing := base
+			if test.existing != nil {
+				existing = test.existing
+			}
+			assert.Equal(t, test.expDiff, model.SpecDiff(existing(), test.requested()))
+		})
+	}
+}
+
+func TestSandboxSpecDiff(t *testing.T) {
+	existing := model.Sandbox{
+		Config:   model.SandboxConfig{Name: "test", Resources: model.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 10}},
+		Webhooks: []model.Webhook{{URL: "https://hooks.test/sbx", Secret: "s3cr3t", Events: []model.EventType{model.EventTypeSandboxStarted}}},
+	}
+
+	tests := map[string]struct {
+		requested model.SandboxConfig
+		webhooks  []model.Webhook
+		expDiff   []model.SpecField
+	}{
+		"same spec and webhooks": {
+			requested: existing.Config,
+			webhooks:  []model.Webhook{{URL: "https://hooks.test/sbx", Secret: "s3cr3t", Events: []model.EventType{model.EventTypeSandboxStarted}}},
+		},
+		"different webhooks": {
+			requested: existing.Config,
+			webhooks:  []model.Webhook{{URL: "https://hooks.test/sbx", Secret: "other"}},
+			expDiff:   []model.SpecField{model.SpecFieldWebhooks},
+		},
+		"missing webhooks are returned after the config fields": {
+			requested: model.SandboxConfig{Name: "test", Resources: model.Resources{VCPUs: 2, MemoryMB: 512, DiskGB: 10}},
+			expDiff:   []model.SpecField{model.SpecFieldResources, model.SpecFieldWebhooks},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expDiff, model.SandboxSpecDiff(existing, test.requested, test.webhooks))
+		})
+	}
+}
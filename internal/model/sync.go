@@ -0,0 +1,100 @@
+package model
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// SyncOpts contains options for syncing a host directory into a sandbox.
+type SyncOpts struct {
+	// Delete removes the sandbox files missing in the host directory. Excluded
+	// files are never removed.
+	Delete bool
+	// Exclude are the patterns of the paths not synced, see [SyncOpts.Excluded].
+	Exclude []string
+}
+
+// Validate checks the exclude patterns are valid.
+func (o SyncOpts) Validate() error {
+	for _, p := range o.Exclude {
+		if strings.TrimSpace(p) == "" {
+			return fmt.Errorf("exclude pattern can't be empty: %w", ErrNotValid)
+		}
+		if _, err := path.Match(cleanExcludePattern(p), ""); err != nil {
+			return fmt.Errorf("invalid exclude pattern %q: %w", p, ErrNotValid)
+		}
+	}
+	return nil
+}
+
+// Excluded returns true when the path (slash separated, relative to the synced
+// directory) matches an exclude pattern. The patterns use [path.Match] syntax:
+//   - Without a slash they match the name of any file or directory (e.g. ".git", "*.pyc").
+//   - With a slash they match the relative path (e.g. "build/out", "docs/*.md").
+//
+// Excluding a directory excludes all its content.
+func (o SyncOpts) Excluded(rel string) bool {
+	if len(o.Exclude) == 0 || rel == "" || rel == "." {
+		return false
+	}
+
+	parts := strings.Split(rel, "/")
+	for _, p := range o.Exclude {
+		p = cleanExcludePattern(p)
+		if !strings.Contains(p, "/") {
+			for _, name := range parts {
+				if ok, _ := path.Match(p, name); ok {
+					return true
+				}
+			}
+			continue
+		}
+
+		// Match the path and its parents so the directory content is excluded too.
+		for i := len(parts); i > 0; i-- {
+			if ok, _ := path.Match(p, strings.Join(parts[:i], "/")); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ReadExcludePatterns reads exclude patterns from an ignore file, one per line.
+// Blank lines and lines starting with "#" are ignored.
+func ReadExcludePatterns(r io.Reader) ([]string, error) {
+	var patterns []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read exclude patterns: %w", err)
+	}
+
+	return patterns, nil
+}
+
+func cleanExcludePattern(p string) string {
+	return strings.Trim(strings.TrimSpace(p), "/")
+}
+
+// SyncResult contains the result of a sync.
+type SyncResult struct {
+	// Uploaded are the files copied into the sandbox (relative paths).
+	Uploaded []string
+	// Deleted are the files and directories removed from the sandbox (relative paths).
+	Deleted []string
+	// Unchanged is the number of files already up to date.
+	Unchanged int
+	// UploadedBytes is the size of the uploaded files.
+	UploadedBytes int64
+}
@@ -0,0 +1,119 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestSyncOptsExcluded(t *testing.T) {
+	tests := map[string]struct {
+		exclude []string
+		rel     string
+		exp     bool
+	}{
+		"Without patterns nothing should be excluded.": {
+			rel: "main.go",
+			exp: false,
+		},
+		"A name pattern should match a file at any depth.": {
+			exclude: []string{"*.pyc"},
+			rel:     "app/lib/mod.pyc",
+			exp:     true,
+		},
+		"A name pattern should match a directory and its content.": {
+			exclude: []string{".git"},
+			rel:     ".git/objects/ab",
+			exp:     true,
+		},
+		"A name pattern should not match a partial name.": {
+			exclude: []string{".git"},
+			rel:     ".github/workflows/ci.yml",
+			exp:     false,
+		},
+		"A path pattern should match the relative path.": {
+			exclude: []string{"docs/*.md"},
+			rel:     "docs/README.md",
+			exp:     true,
+		},
+		"A path pattern should not match the same name in other directories.": {
+			exclude: []string{"build/out"},
+			rel:     "cmd/build/out",
+			exp:     false,
+		},
+		"A path pattern should match the directory content.": {
+			exclude: []string{"/build/out/"},
+			rel:     "build/out/bin/app",
+			exp:     true,
+		},
+		"The root should never be excluded.": {
+			exclude: []string{"*"},
+			rel:     ".",
+			exp:     false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts := model.SyncOpts{Exclude: test.exclude}
+			assert.Equal(t, test.exp, opts.Excluded(test.rel))
+		})
+	}
+}
+
+func TestSyncOptsValidate(t *testing.T) {
+	tests := map[string]struct {
+		exclude []string
+		expErr  bool
+	}{
+		"Valid patterns should pass.": {
+			exclude: []string{".git", "*.pyc", "build/[a-z]*"},
+		},
+		"An empty pattern should fail.": {
+			exclude: []string{" "},
+			expErr:  true,
+		},
+		"A malformed pattern should fail.": {
+			exclude: []string{"build/[a-z"},
+			expErr:  true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := model.SyncOpts{Exclude: test.exclude}.Validate()
+			if test.expErr {
+				assert.ErrorIs(t, err, model.ErrNotValid)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestReadExcludePatterns(t *testing.T) {
+	tests := map[string]struct {
+		content string
+		exp     []string
+	}{
+		"An empty file should not have patterns.": {
+			content: "",
+			exp:     nil,
+		},
+		"Comments and blank lines should be ignored.": {
+			content: "# VCS\n.git\n\n  node_modules  \n# Python\n*.pyc\n",
+			exp:     []string{".git", "node_modules", "*.pyc"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := model.ReadExcludePatterns(strings.NewReader(test.content))
+			assert.NoError(t, err)
+			assert.Equal(t, test.exp, got)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// Task is a named command with its execution defaults, so the repetitive exec
+// invocations can be run by name. The global tasks are available to all the
+// sandboxes, the tasks of a sandbox take precedence over the global ones with the
+// same name.
+type Task struct {
+	Name string
+	// SandboxID is the sandbox of the task, empty for a global task.
+	SandboxID string
+	// Command is the command run, the run arguments are appended to it.
+	Command []string
+	// WorkingDir is the directory the command runs in (optional).
+	WorkingDir string
+	// Env are the environment variables of the command (optional), the run ones
+	// override them.
+	Env map[string]string
+	// Files are the absolute host paths of the files uploaded to the working
+	// directory before running the command (optional).
+	Files []string
+	// Timeout kills the command when it runs longer (optional).
+	Timeout   time.Duration
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+var taskNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// Validate validates the task name, command, files and timeout.
+func (t *Task) Validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("task name is required: %w", ErrNotValid)
+	}
+
+	if !taskNameRegexp.MatchString(t.Name) {
+		return fmt.Errorf("task name %q is invalid (allowed: [a-zA-Z0-9._-]): %w", t.Name, ErrNotValid)
+	}
+
+	if len(t.Command) == 0 {
+		return fmt.Errorf("task %q command is required: %w", t.Name, ErrNotValid)
+	}
+
+	for _, f := range t.Files {
+		if !filepath.IsAbs(f) {
+			return fmt.Errorf("task %q file %q must be an absolute path: %w", t.Name, f, ErrNotValid)
+		}
+	}
+
+	if t.Timeout < 0 {
+		return fmt.Errorf("task %q timeout can't be negative: %w", t.Name, ErrNotValid)
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestTaskValidate(t *testing.T) {
+	tests := map[string]struct {
+		task   model.Task
+		expErr bool
+	}{
+		"A task with a command should be valid.": {
+			task: model.Task{Name: "test", Command: []string{"go", "test", "./..."}},
+		},
+
+		"A task with all the options should be valid.": {
+			task: model.Task{
+				Name:       "build.linux_amd64",
+				SandboxID:  "01SB",
+				Command:    []string{"make", "build"},
+				WorkingDir: "/src",
+				Env:        map[string]string{"GOOS": "linux"},
+				Files:      []string{"/home/user/build.env"},
+				Timeout:    10 * time.Minute,
+			},
+		},
+
+		"A task without name should fail.": {
+			task:   model.Task{Command: []string{"ls"}},
+			expErr: true,
+		},
+
+		"A task with an invalid name should fail.": {
+			task:   model.Task{Name: "run tests", Command: []string{"ls"}},
+			expErr: true,
+		},
+
+		"A task without command should fail.": {
+			task:   model.Task{Name: "test"},
+			expErr: true,
+		},
+
+		"A task with a relative file should fail.": {
+			task:   model.Task{Name: "test", Command: []string{"ls"}, Files: []string{"build.env"}},
+			expErr: true,
+		},
+
+		"A task with a negative timeout should fail.": {
+			task:   model.Task{Name: "test", Command: []string{"ls"}, Timeout: -time.Second},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.task.Validate()
+
+			if test.expErr {
+				assert.ErrorIs(t, err, model.ErrNotValid)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
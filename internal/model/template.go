@@ -0,0 +1,100 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Template engine types.
+const (
+	TemplateEngineFirecracker = "firecracker"
+	TemplateEngineFake        = "fake"
+)
+
+// SandboxTemplate is a reusable sandbox spec stored with a name, so the sandboxes
+// of the same shape are created by name instead of repeating their options. The
+// sandboxes keep the spec they were created with, the template changes don't
+// apply to them.
+type SandboxTemplate struct {
+	Name        string
+	Description string
+	// Engine is the engine type of the sandboxes (firecracker or fake).
+	Engine string
+	// Image is the image version of the sandboxes, required with firecracker.
+	Image string
+	// Profile is the kernel profile of the sandboxes (optional).
+	Profile     string
+	Resources   Resources
+	UserData    string
+	Ports       map[string]int
+	ExecProfile *ExecProfile
+	// Env, Egress and EgressPolicyName are the session of the start of the sandboxes
+	// created and started from the template.
+	Env              map[string]string
+	Egress           *EgressPolicy
+	EgressPolicyName string
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+var templateNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// Validate validates the template name and its sandbox and session specs.
+func (t *SandboxTemplate) Validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("template name is required: %w", ErrNotValid)
+	}
+
+	if !templateNameRegexp.MatchString(t.Name) {
+		return fmt.Errorf("template name %q is invalid (allowed: [a-zA-Z0-9._-]): %w", t.Name, ErrNotValid)
+	}
+
+	switch t.Engine {
+	case TemplateEngineFirecracker:
+		if t.Image == "" {
+			return fmt.Errorf("the image is required with the firecracker engine: %w", ErrNotValid)
+		}
+	case TemplateEngineFake:
+	default:
+		return fmt.Errorf("unknown engine %q (firecracker or fake): %w", t.Engine, ErrNotValid)
+	}
+
+	if t.Resources.VCPUs <= 0 {
+		return fmt.Errorf("vcpus must be positive: %w", ErrNotValid)
+	}
+	if t.Resources.MemoryMB <= 0 {
+		return fmt.Errorf("memory_mb must be positive: %w", ErrNotValid)
+	}
+	if t.Resources.DiskGB <= 0 {
+		return fmt.Errorf("disk_gb must be positive: %w", ErrNotValid)
+	}
+	if t.Resources.MaxVCPUs != 0 && t.Resources.MaxVCPUs < t.Resources.VCPUs {
+		return fmt.Errorf("max_vcpus can't be lower than vcpus: %w", ErrNotValid)
+	}
+	if t.Resources.MaxMemoryMB != 0 && t.Resources.MaxMemoryMB < t.Resources.MemoryMB {
+		return fmt.Errorf("max_memory_mb can't be lower than memory_mb: %w", ErrNotValid)
+	}
+
+	if err := ValidateNamedPorts(t.Ports); err != nil {
+		return err
+	}
+
+	if t.ExecProfile != nil {
+		if err := t.ExecProfile.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return t.SessionConfig().Validate()
+}
+
+// SessionConfig returns the session config of the start of the sandboxes created
+// from the template.
+func (t SandboxTemplate) SessionConfig() SessionConfig {
+	return SessionConfig{
+		Env:              t.Env,
+		Egress:           t.Egress,
+		EgressPolicyName: t.EgressPolicyName,
+	}
+}
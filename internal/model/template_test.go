@@ -0,0 +1,95 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestSandboxTemplateValidate(t *testing.T) {
+	resources := model.Resources{VCPUs: 2, MemoryMB: 2048, DiskGB: 10}
+
+	tests := map[string]struct {
+		template model.SandboxTemplate
+		expErr   bool
+	}{
+		"A firecracker template with an image should be valid.": {
+			template: model.SandboxTemplate{Name: "go-dev", Engine: model.TemplateEngineFirecracker, Image: "v0.1.0", Resources: resources},
+		},
+
+		"A template with all the options should be valid.": {
+			template: model.SandboxTemplate{
+				Name:        "go-dev.v2",
+				Description: "Go development sandbox",
+				Engine:      model.TemplateEngineFirecracker,
+				Image:       "v0.1.0",
+				Profile:     "fast-boot",
+				Resources:   model.Resources{VCPUs: 2, MemoryMB: 2048, DiskGB: 10, MaxVCPUs: 4, MaxMemoryMB: 4096},
+				UserData:    "#!/bin/sh\napk add go\n",
+				Ports:       map[string]int{"web": 8080},
+				ExecProfile: &model.ExecProfile{Locale: "C.UTF-8"},
+				Env:         map[string]string{"GOFLAGS": "-mod=mod"},
+				Egress:      &model.EgressPolicy{Default: model.EgressActionDeny, Rules: []model.EgressRule{{Domain: "proxy.golang.org", Action: model.EgressActionAllow}}},
+			},
+		},
+
+		"A fake template without image should be valid.": {
+			template: model.SandboxTemplate{Name: "test", Engine: model.TemplateEngineFake, Resources: resources},
+		},
+
+		"A template without name should fail.": {
+			template: model.SandboxTemplate{Engine: model.TemplateEngineFake, Resources: resources},
+			expErr:   true,
+		},
+
+		"A template with an invalid name should fail.": {
+			template: model.SandboxTemplate{Name: "go dev", Engine: model.TemplateEngineFake, Resources: resources},
+			expErr:   true,
+		},
+
+		"A template with an unknown engine should fail.": {
+			template: model.SandboxTemplate{Name: "test", Engine: "qemu", Resources: resources},
+			expErr:   true,
+		},
+
+		"A firecracker template without image should fail.": {
+			template: model.SandboxTemplate{Name: "test", Engine: model.TemplateEngineFirecracker, Resources: resources},
+			expErr:   true,
+		},
+
+		"A template without resources should fail.": {
+			template: model.SandboxTemplate{Name: "test", Engine: model.TemplateEngineFake},
+			expErr:   true,
+		},
+
+		"A template with an invalid port name should fail.": {
+			template: model.SandboxTemplate{Name: "test", Engine: model.TemplateEngineFake, Resources: resources, Ports: map[string]int{"Web": 8080}},
+			expErr:   true,
+		},
+
+		"A template with an egress policy and an egress policy name should fail.": {
+			template: model.SandboxTemplate{
+				Name:             "test",
+				Engine:           model.TemplateEngineFake,
+				Resources:        resources,
+				Egress:           &model.EgressPolicy{Default: model.EgressActionAllow},
+				EgressPolicyName: "github",
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.template.Validate()
+
+			if test.expErr {
+				assert.ErrorIs(t, err, model.ErrNotValid)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
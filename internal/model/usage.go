@@ -0,0 +1,139 @@
+package model
+
+import (
+	"time"
+)
+
+// UsageKind is the kind of resources of a usage record.
+type UsageKind string
+
+const (
+	// UsageKindRun is the usage of the VCPUs and memory while the sandbox runs.
+	UsageKindRun UsageKind = "run"
+	// UsageKindDisk is the usage of the disk while the sandbox exists.
+	UsageKindDisk UsageKind = "disk"
+)
+
+// UsageRecord is a period of resources allocated to a sandbox, used for the usage
+// accounting. The records are stored when the period ends (e.g. on stop) and kept
+// after the sandbox is removed.
+type UsageRecord struct {
+	SandboxID   string
+	SandboxName string
+	Namespace   string
+	// Annotations are the sandbox annotations when the period ended.
+	Annotations map[string]string
+	Kind        UsageKind
+	Resources   Resources
+	StartedAt   time.Time
+	EndedAt     time.Time
+}
+
+// UsageRecordFilter filters the usage records.
+type UsageRecordFilter struct {
+	// Since returns the records ended after this time. Zero means all.
+	Since time.Time
+	// Until returns the records started before this time. Zero means all.
+	Until time.Time
+}
+
+// Usage is the resources used by sandboxes over time, the allocated resources
+// count as used.
+type Usage struct {
+	// CPUSeconds are the VCPUs allocated to running sandboxes multiplied by the seconds.
+	CPUSeconds float64
+	// MemoryMBHours is the memory (MB) allocated to running sandboxes multiplied by the hours.
+	MemoryMBHours float64
+	// DiskGBHours is the disk (GB) of the existing sandboxes multiplied by the hours.
+	DiskGBHours float64
+}
+
+// Add returns the sum of both usages.
+func (u Usage) Add(o Usage) Usage {
+	return Usage{
+		CPUSeconds:    u.CPUSeconds + o.CPUSeconds,
+		MemoryMBHours: u.MemoryMBHours + o.MemoryMBHours,
+		DiskGBHours:   u.DiskGBHours + o.DiskGBHours,
+	}
+}
+
+// Usage returns the usage of the record in the [since, until) range, a zero time
+// doesn't limit the range.
+func (r UsageRecord) Usage(since, until time.Time) Usage {
+	start, end := r.StartedAt, r.EndedAt
+	if !since.IsZero() && start.Before(since) {
+		start = since
+	}
+	if !until.IsZero() && end.After(until) {
+		end = until
+	}
+	if !end.After(start) {
+		return Usage{}
+	}
+
+	d := end.Sub(start)
+	switch r.Kind {
+	case UsageKindRun:
+		return Usage{
+			CPUSeconds:    r.Resources.VCPUs * d.Seconds(),
+			MemoryMBHours: float64(r.Resources.MemoryMB) * d.Hours(),
+		}
+	case UsageKindDisk:
+		return Usage{DiskGBHours: float64(r.Resources.DiskGB) * d.Hours()}
+	default:
+		return Usage{}
+	}
+}
+
+// SandboxUsageRecords returns the usage periods of the sandbox not stored yet, ended
+// at the given time: the disk since its creation and, when it's running, the
+// current run.
+func SandboxUsageRecords(s Sandbox, endedAt time.Time) []UsageRecord {
+	rec := UsageRecord{
+		SandboxID:   s.ID,
+		SandboxName: s.Name,
+		Namespace:   s.Namespace,
+		Annotations: s.Annotations,
+		Resources:   s.Config.Resources,
+		EndedAt:     endedAt,
+	}
+
+	disk := rec
+	disk.Kind = UsageKindDisk
+	disk.StartedAt = s.CreatedAt
+	records := []UsageRecord{disk}
+
+	if s.Status == SandboxStatusRunning && s.StartedAt != nil {
+		run := rec
+		run.Kind = UsageKindRun
+		run.StartedAt = *s.StartedAt
+		records = append(records, run)
+	}
+
+	return records
+}
+
+// UsageReportEntry is the usage of a sandbox or of a group of sandboxes.
+type UsageReportEntry struct {
+	// Key is the sandbox name, or the annotation value of the group when the
+	// report is grouped (empty for the sandboxes without the annotation).
+	Key string
+	// SandboxID is the sandbox ID, empty when the report is grouped.
+	SandboxID string
+	// Removed is true when the sandbox has been removed.
+	Removed bool
+	// Sandboxes is the number of sandboxes of the entry.
+	Sandboxes int
+	Usage
+}
+
+// UsageReport is the resources used by the sandboxes in a time range.
+type UsageReport struct {
+	Since time.Time
+	Until time.Time
+	// GroupBy is the annotation key used to group the sandboxes, empty when the
+	// entries are the sandboxes.
+	GroupBy string
+	Entries []UsageReportEntry
+	Total   Usage
+}
@@ -0,0 +1,84 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestUsageRecordUsage(t *testing.T) {
+	t0 := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	res := model.Resources{VCPUs: 2, MemoryMB: 1024, DiskGB: 10}
+
+	tests := map[string]struct {
+		record   model.UsageRecord
+		since    time.Time
+		until    time.Time
+		expUsage model.Usage
+	}{
+		"A run record without range should use the VCPUs and memory of the whole period.": {
+			record:   model.UsageRecord{Kind: model.UsageKindRun, Resources: res, StartedAt: t0, EndedAt: t0.Add(2 * time.Hour)},
+			expUsage: model.Usage{CPUSeconds: 14400, MemoryMBHours: 2048},
+		},
+
+		"A disk record without range should use the disk of the whole period.": {
+			record:   model.UsageRecord{Kind: model.UsageKindDisk, Resources: res, StartedAt: t0, EndedAt: t0.Add(2 * time.Hour)},
+			expUsage: model.Usage{DiskGBHours: 20},
+		},
+
+		"A record should be clipped to the range.": {
+			record:   model.UsageRecord{Kind: model.UsageKindRun, Resources: res, StartedAt: t0, EndedAt: t0.Add(4 * time.Hour)},
+			since:    t0.Add(time.Hour),
+			until:    t0.Add(2 * time.Hour),
+			expUsage: model.Usage{CPUSeconds: 7200, MemoryMBHours: 1024},
+		},
+
+		"A record outside the range should not have usage.": {
+			record: model.UsageRecord{Kind: model.UsageKindDisk, Resources: res, StartedAt: t0, EndedAt: t0.Add(time.Hour)},
+			since:  t0.Add(2 * time.Hour),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expUsage, test.record.Usage(test.since, test.until))
+		})
+	}
+}
+
+func TestSandboxUsageRecords(t *testing.T) {
+	t0 := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	startedAt := t0.Add(time.Hour)
+	endedAt := t0.Add(3 * time.Hour)
+	sb := model.Sandbox{
+		ID:          "id-1",
+		Name:        "sb-1",
+		Annotations: map[string]string{"team": "infra"},
+		Status:      model.SandboxStatusStopped,
+		CreatedAt:   t0,
+		StartedAt:   &startedAt,
+		Config:      model.SandboxConfig{Resources: model.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5}},
+	}
+	disk := model.UsageRecord{
+		SandboxID:   "id-1",
+		SandboxName: "sb-1",
+		Annotations: map[string]string{"team": "infra"},
+		Kind:        model.UsageKindDisk,
+		Resources:   model.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+		StartedAt:   t0,
+		EndedAt:     endedAt,
+	}
+
+	// A stopped sandbox only uses its disk.
+	assert.Equal(t, []model.UsageRecord{disk}, model.SandboxUsageRecords(sb, endedAt))
+
+	// A running sandbox also uses the resources since its start.
+	sb.Status = model.SandboxStatusRunning
+	run := disk
+	run.Kind = model.UsageKindRun
+	run.StartedAt = startedAt
+	assert.Equal(t, []model.UsageRecord{disk, run}, model.SandboxUsageRecords(sb, endedAt))
+}
@@ -0,0 +1,41 @@
+package printer
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// PrintUsageReportCSV prints the usage report entries in CSV format, with a header
+// and without the total, to be loaded in spreadsheets and billing tools.
+func PrintUsageReportCSV(w io.Writer, report model.UsageReport) error {
+	cw := csv.NewWriter(w)
+
+	header := []string{"name", "id", "removed"}
+	if report.GroupBy != "" {
+		header = []string{report.GroupBy, "sandboxes"}
+	}
+	if err := cw.Write(append(header, "cpu_seconds", "memory_mb_hours", "disk_gb_hours")); err != nil {
+		return err
+	}
+
+	for _, e := range report.Entries {
+		row := []string{e.Key, e.SandboxID, strconv.FormatBool(e.Removed)}
+		if report.GroupBy != "" {
+			row = []string{e.Key, strconv.Itoa(e.Sandboxes)}
+		}
+		row = append(row,
+			strconv.FormatFloat(e.CPUSeconds, 'f', -1, 64),
+			strconv.FormatFloat(e.MemoryMBHours, 'f', -1, 64),
+			strconv.FormatFloat(e.DiskGBHours, 'f', -1, 64),
+		)
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
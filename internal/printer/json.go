@@ -2,7 +2,10 @@ package printer
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
+	"io/fs"
+	"math"
 	"time"
 
 	"github.com/slok/sbx/internal/model"
@@ -20,24 +23,89 @@ func NewJSONPrinter(w io.Writer) *JSONPrinter {
 
 // listItem represents a sandbox in the list output (subset of fields).
 type listItem struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"created_at"`
+	ID          string            `json:"id"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Name        string            `json:"name"`
+	Status      string            `json:"status"`
+	CreatedAt   time.Time         `json:"created_at"`
+	Annotations map[string]string `json:"annotations,omitempty"`
 }
 
 // statusOutput represents the full sandbox status output.
 type statusOutput struct {
 	ID        string        `json:"id"`
+	Namespace string        `json:"namespace,omitempty"`
 	Name      string        `json:"name"`
 	Status    string        `json:"status"`
 	Engine    *engineOutput `json:"engine,omitempty"`
 	VCPUs     float64       `json:"vcpus"`
 	MemoryMB  int           `json:"memory_mb"`
 	DiskGB    int           `json:"disk_gb"`
-	CreatedAt time.Time     `json:"created_at"`
-	StartedAt *time.Time    `json:"started_at"`
-	StoppedAt *time.Time    `json:"stopped_at"`
+	// MaxVCPUs and MaxMemoryMB are only set on sandboxes with resource ceilings.
+	MaxVCPUs    float64    `json:"max_vcpus,omitempty"`
+	MaxMemoryMB int        `json:"max_memory_mb,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	StartedAt   *time.Time `json:"started_at"`
+	StoppedAt   *time.Time `json:"stopped_at"`
+	// BootPhases are only set on the sandbox returned by a start.
+	BootPhases []bootPhaseOutput `json:"boot_phases,omitempty"`
+	// Egress is only set on running sandboxes with an egress policy.
+	Egress      *egressOutput      `json:"egress,omitempty"`
+	Annotations map[string]string  `json:"annotations,omitempty"`
+	Ports       map[string]int     `json:"ports,omitempty"`
+	ExecProfile *execProfileOutput `json:"exec_profile,omitempty"`
+	IdlePolicy  *idlePolicyOutput  `json:"idle_policy,omitempty"`
+	// LastActivityAt is only set when the sandbox had activity.
+	LastActivityAt *time.Time `json:"last_activity_at,omitempty"`
+	// QuarantinedAt is only set on quarantined sandboxes.
+	QuarantinedAt *time.Time `json:"quarantined_at,omitempty"`
+	// Persistent is only set on persistent sandboxes.
+	Persistent bool `json:"persistent,omitempty"`
+	// Session is only set on started sandboxes, with the secret looking env values
+	// redacted.
+	Session *sessionOutput `json:"session,omitempty"`
+	// LastBootPhases are the phases of the last start, the failed one with its error.
+	LastBootPhases []bootPhaseOutput `json:"last_boot_phases,omitempty"`
+	// LastError is only set when the last start failed.
+	LastError string `json:"last_error,omitempty"`
+}
+
+// sessionOutput represents the session configuration of the last start output.
+type sessionOutput struct {
+	Env              map[string]string     `json:"env,omitempty"`
+	Egress           *templateEgressOutput `json:"egress,omitempty"`
+	EgressPolicyName string                `json:"egress_policy,omitempty"`
+	IntegrityPaths   []string              `json:"integrity_paths,omitempty"`
+}
+
+// execProfileOutput represents the sandbox exec profile output.
+type execProfileOutput struct {
+	PathPrepend []string          `json:"path_prepend,omitempty"`
+	Locale      string            `json:"locale,omitempty"`
+	Umask       string            `json:"umask,omitempty"`
+	Ulimits     map[string]string `json:"ulimits,omitempty"`
+	Shell       string            `json:"shell,omitempty"`
+}
+
+// idlePolicyOutput represents the sandbox idle policy output.
+type idlePolicyOutput struct {
+	Timeout string `json:"timeout"`
+	Action  string `json:"action"`
+}
+
+// egressOutput represents the egress proxies status output.
+type egressOutput struct {
+	Health    string    `json:"health"`
+	Restarts  int       `json:"restarts"`
+	LastError string    `json:"last_error,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// bootPhaseOutput represents a sandbox start phase output.
+type bootPhaseOutput struct {
+	Name       string `json:"name"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
 }
 
 // engineOutput represents engine configuration output.
@@ -45,6 +113,24 @@ type engineOutput struct {
 	Type        string `json:"type"`
 	RootFS      string `json:"root_fs,omitempty"`
 	KernelImage string `json:"kernel_image,omitempty"`
+	// RootFSStrategy is empty for sandboxes created before it was recorded.
+	RootFSStrategy string `json:"rootfs_strategy,omitempty"`
+	// Seccomp is only set with a non default mode or on running sandboxes.
+	Seccomp *seccompOutput `json:"seccomp,omitempty"`
+	// EgressInterface is only set when the sandbox has its own.
+	EgressInterface string `json:"egress_interface,omitempty"`
+	// MTU and ClampMSS are only set when the sandbox has network options.
+	MTU      int  `json:"mtu,omitempty"`
+	ClampMSS bool `json:"clamp_mss,omitempty"`
+}
+
+// seccompOutput represents the VM process seccomp mode and enforcement output.
+type seccompOutput struct {
+	Mode   string `json:"mode"`
+	Filter string `json:"filter,omitempty"`
+	// Enforced and Filters are only set on running sandboxes.
+	Enforced *bool `json:"enforced,omitempty"`
+	Filters  int   `json:"filters,omitempty"`
 }
 
 // messageOutput represents a simple message output.
@@ -57,10 +143,12 @@ func (j *JSONPrinter) PrintList(sandboxes []model.Sandbox) error {
 	items := make([]listItem, len(sandboxes))
 	for i, s := range sandboxes {
 		items[i] = listItem{
-			ID:        s.ID,
-			Name:      s.Name,
-			Status:    string(s.Status),
-			CreatedAt: s.CreatedAt.UTC(),
+			ID:          s.ID,
+			Namespace:   s.Namespace,
+			Name:        s.Name,
+			Status:      string(s.Status),
+			CreatedAt:   s.CreatedAt.UTC(),
+			Annotations: s.Annotations,
 		}
 	}
 
@@ -72,23 +160,41 @@ func (j *JSONPrinter) PrintList(sandboxes []model.Sandbox) error {
 // PrintStatus prints detailed sandbox status in JSON format.
 func (j *JSONPrinter) PrintStatus(sandbox model.Sandbox) error {
 	output := statusOutput{
-		ID:        sandbox.ID,
-		Name:      sandbox.Name,
-		Status:    string(sandbox.Status),
-		VCPUs:     sandbox.Config.Resources.VCPUs,
-		MemoryMB:  sandbox.Config.Resources.MemoryMB,
-		DiskGB:    sandbox.Config.Resources.DiskGB,
-		CreatedAt: sandbox.CreatedAt.UTC(),
-		StartedAt: nil,
-		StoppedAt: nil,
+		ID:          sandbox.ID,
+		Namespace:   sandbox.Namespace,
+		Name:        sandbox.Name,
+		Status:      string(sandbox.Status),
+		VCPUs:       sandbox.Config.Resources.VCPUs,
+		MemoryMB:    sandbox.Config.Resources.MemoryMB,
+		DiskGB:      sandbox.Config.Resources.DiskGB,
+		MaxVCPUs:    sandbox.Config.Resources.MaxVCPUs,
+		MaxMemoryMB: sandbox.Config.Resources.MaxMemoryMB,
+		CreatedAt:   sandbox.CreatedAt.UTC(),
+		StartedAt:   nil,
+		StoppedAt:   nil,
+		Annotations: sandbox.Annotations,
+		Ports:       sandbox.Config.Ports,
 	}
 
 	// Add engine info
 	if sandbox.Config.FirecrackerEngine != nil {
 		output.Engine = &engineOutput{
-			Type:        "firecracker",
-			RootFS:      sandbox.Config.FirecrackerEngine.RootFS,
-			KernelImage: sandbox.Config.FirecrackerEngine.KernelImage,
+			Type:            "firecracker",
+			RootFS:          sandbox.Config.FirecrackerEngine.RootFS,
+			KernelImage:     sandbox.Config.FirecrackerEngine.KernelImage,
+			RootFSStrategy:  string(sandbox.RootFSStrategy),
+			EgressInterface: sandbox.Config.FirecrackerEngine.EgressInterface,
+			MTU:             sandbox.Config.FirecrackerEngine.Network.MTU,
+			ClampMSS:        sandbox.Config.FirecrackerEngine.Network.ClampMSS,
+		}
+
+		seccomp := sandbox.Config.FirecrackerEngine.Seccomp
+		if seccomp.Mode != model.SeccompModeDefault || sandbox.Seccomp != nil {
+			output.Engine.Seccomp = &seccompOutput{Mode: seccompModeName(seccomp.Mode), Filter: seccomp.Filter}
+			if sandbox.Seccomp != nil {
+				output.Engine.Seccomp.Enforced = &sandbox.Seccomp.Enforced
+				output.Engine.Seccomp.Filters = sandbox.Seccomp.Filters
+			}
 		}
 	}
 
@@ -102,11 +208,72 @@ func (j *JSONPrinter) PrintStatus(sandbox model.Sandbox) error {
 		output.StoppedAt = &utcTime
 	}
 
+	if p := sandbox.Config.ExecProfile; p != nil {
+		output.ExecProfile = &execProfileOutput{
+			PathPrepend: p.PathPrepend,
+			Locale:      p.Locale,
+			Umask:       p.Umask,
+			Ulimits:     p.Ulimits,
+			Shell:       p.Shell,
+		}
+	}
+
+	if sandbox.IdlePolicy != nil {
+		output.IdlePolicy = &idlePolicyOutput{
+			Timeout: sandbox.IdlePolicy.Timeout.String(),
+			Action:  string(sandbox.IdlePolicy.Action),
+		}
+	}
+
+	if !sandbox.Activity.LastActivityAt.IsZero() {
+		utcTime := sandbox.Activity.LastActivityAt.UTC()
+		output.LastActivityAt = &utcTime
+	}
+
+	if sandbox.QuarantinedAt != nil {
+		utcTime := sandbox.QuarantinedAt.UTC()
+		output.QuarantinedAt = &utcTime
+	}
+
+	output.Persistent = sandbox.Persistent
+	output.BootPhases = newBootPhasesOutput(sandbox.BootPhases)
+	output.LastBootPhases = newBootPhasesOutput(sandbox.LastBootPhases)
+	output.LastError = sandbox.LastError
+
+	if sandbox.Egress != nil {
+		output.Egress = &egressOutput{
+			Health:    string(sandbox.Egress.Health),
+			Restarts:  sandbox.Egress.Restarts,
+			LastError: sandbox.Egress.LastError,
+			UpdatedAt: sandbox.Egress.UpdatedAt.UTC(),
+		}
+	}
+
+	if sandbox.Session != nil {
+		session := sandbox.Session.Redacted()
+		output.Session = &sessionOutput{
+			Env:              session.Env,
+			Egress:           newEgressPolicyOutput(session.Egress),
+			EgressPolicyName: session.EgressPolicyName,
+			IntegrityPaths:   session.IntegrityPaths,
+		}
+	}
+
 	enc := json.NewEncoder(j.writer)
 	enc.SetIndent("", "  ")
+	// Keep the redacted values readable.
+	enc.SetEscapeHTML(false)
 	return enc.Encode(output)
 }
 
+func newBootPhasesOutput(phases []model.BootPhase) []bootPhaseOutput {
+	var out []bootPhaseOutput
+	for _, p := range phases {
+		out = append(out, bootPhaseOutput{Name: p.Name, DurationMS: p.Duration.Milliseconds(), Error: p.Error})
+	}
+	return out
+}
+
 // imageReleaseItem represents an image release in JSON output.
 type imageReleaseItem struct {
 	Version   string `json:"version"`
@@ -122,6 +289,16 @@ type imageManifestOutput struct {
 	Firecracker   firecrackerInfoOutput          `json:"firecracker"`
 	Build         buildInfoOutput                `json:"build"`
 	Snapshot      *snapshotInfoOutput            `json:"snapshot,omitempty"`
+	Local         *localImageInfoOutput          `json:"local,omitempty"`
+	Customized    *customizedImageInfoOutput     `json:"customized,omitempty"`
+	Profiles      []kernelProfileOutput          `json:"profiles"`
+}
+
+type kernelProfileOutput struct {
+	Name           string   `json:"name"`
+	Description    string   `json:"description,omitempty"`
+	KernelArgs     []string `json:"kernel_args,omitempty"`
+	DisableConsole bool     `json:"disable_console,omitempty"`
 }
 
 type snapshotInfoOutput struct {
@@ -129,9 +306,25 @@ type snapshotInfoOutput struct {
 	SourceSandboxName string `json:"source_sandbox_name"`
 	SourceImage       string `json:"source_image,omitempty"`
 	ParentSnapshot    string `json:"parent_snapshot,omitempty"`
+	MemoryFile        string `json:"memory_file,omitempty"`
+	VMStateFile       string `json:"vmstate_file,omitempty"`
 	CreatedAt         string `json:"created_at"`
 }
 
+type localImageInfoOutput struct {
+	KernelSource string `json:"kernel_source"`
+	RootFSSource string `json:"rootfs_source"`
+	AddedAt      string `json:"added_at"`
+}
+
+type customizedImageInfoOutput struct {
+	BaseImage string   `json:"base_image"`
+	Commands  []string `json:"commands,omitempty"`
+	Files     []string `json:"files,omitempty"`
+	Digest    string   `json:"digest"`
+	CreatedAt string   `json:"created_at"`
+}
+
 type archArtifactsOutput struct {
 	Kernel kernelInfoOutput `json:"kernel"`
 	Rootfs rootfsInfoOutput `json:"rootfs"`
@@ -219,10 +412,820 @@ func (j *JSONPrinter) PrintImageInspect(manifest model.ImageManifest) error {
 			SourceSandboxName: manifest.Snapshot.SourceSandboxName,
 			SourceImage:       manifest.Snapshot.SourceImage,
 			ParentSnapshot:    manifest.Snapshot.ParentSnapshot,
+			MemoryFile:        manifest.Snapshot.MemoryFile,
+			VMStateFile:       manifest.Snapshot.VMStateFile,
 			CreatedAt:         manifest.Snapshot.CreatedAt.UTC().Format(time.RFC3339),
 		}
 	}
 
+	if manifest.Local != nil {
+		output.Local = &localImageInfoOutput{
+			KernelSource: manifest.Local.KernelSource,
+			RootFSSource: manifest.Local.RootFSSource,
+			AddedAt:      manifest.Local.AddedAt.UTC().Format(time.RFC3339),
+		}
+	}
+
+	if manifest.Customized != nil {
+		output.Customized = &customizedImageInfoOutput{
+			BaseImage: manifest.Customized.BaseImage,
+			Commands:  manifest.Customized.Commands,
+			Files:     manifest.Customized.Files,
+			Digest:    manifest.Customized.Digest,
+			CreatedAt: manifest.Customized.CreatedAt.UTC().Format(time.RFC3339),
+		}
+	}
+
+	for _, p := range manifest.KernelProfiles() {
+		output.Profiles = append(output.Profiles, kernelProfileOutput{
+			Name:           p.Name,
+			Description:    p.Description,
+			KernelArgs:     p.KernelArgs,
+			DisableConsole: p.DisableConsole,
+		})
+	}
+
+	enc := json.NewEncoder(j.writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
+
+type imageDiskUsageOutput struct {
+	Images     []imageUsageItem `json:"images"`
+	TotalBytes int64            `json:"total_bytes"`
+	DiskBytes  int64            `json:"disk_bytes"`
+}
+
+type imageUsageItem struct {
+	Name        string `json:"name"`
+	SizeBytes   int64  `json:"size_bytes"`
+	SharedBytes int64  `json:"shared_bytes"`
+}
+
+// PrintImageDiskUsage prints the images disk usage in JSON format.
+func (j *JSONPrinter) PrintImageDiskUsage(usage model.ImageDiskUsage) error {
+	output := imageDiskUsageOutput{
+		Images:     make([]imageUsageItem, len(usage.Images)),
+		TotalBytes: usage.TotalBytes,
+		DiskBytes:  usage.DiskBytes,
+	}
+	for i, img := range usage.Images {
+		output.Images[i] = imageUsageItem{
+			Name:        img.Name,
+			SizeBytes:   img.SizeBytes,
+			SharedBytes: img.SharedBytes,
+		}
+	}
+
+	enc := json.NewEncoder(j.writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
+
+type imageDiffOutput struct {
+	From    string             `json:"from"`
+	To      string             `json:"to"`
+	Changes []fileChangeOutput `json:"changes"`
+}
+
+type fileChangeOutput struct {
+	Path string           `json:"path"`
+	Kind string           `json:"kind"`
+	Old  *fileStateOutput `json:"old,omitempty"`
+	New  *fileStateOutput `json:"new,omitempty"`
+}
+
+type fileStateOutput struct {
+	Type       string `json:"type"`
+	Mode       string `json:"mode"`
+	UID        int    `json:"uid"`
+	GID        int    `json:"gid"`
+	SizeBytes  int64  `json:"size_bytes,omitempty"`
+	SHA256     string `json:"sha256,omitempty"`
+	LinkTarget string `json:"link_target,omitempty"`
+}
+
+func toFileStateOutput(s *model.FileState) *fileStateOutput {
+	if s == nil {
+		return nil
+	}
+	return &fileStateOutput{
+		Type:       string(s.Type),
+		Mode:       fmt.Sprintf("%04o", uint32(s.Mode.Perm())|specialModeBits(s.Mode)),
+		UID:        s.UID,
+		GID:        s.GID,
+		SizeBytes:  s.SizeBytes,
+		SHA256:     s.SHA256,
+		LinkTarget: s.LinkTarget,
+	}
+}
+
+// specialModeBits returns the setuid, setgid and sticky bits in the octal notation.
+func specialModeBits(m fs.FileMode) uint32 {
+	var bits uint32
+	if m&fs.ModeSetuid != 0 {
+		bits |= 0o4000
+	}
+	if m&fs.ModeSetgid != 0 {
+		bits |= 0o2000
+	}
+	if m&fs.ModeSticky != 0 {
+		bits |= 0o1000
+	}
+	return bits
+}
+
+// PrintImageDiff prints the files changed between two images in JSON format.
+func (j *JSONPrinter) PrintImageDiff(diff model.ImageDiff) error {
+	output := imageDiffOutput{
+		From:    diff.From,
+		To:      diff.To,
+		Changes: make([]fileChangeOutput, len(diff.Changes)),
+	}
+	for i, c := range diff.Changes {
+		output.Changes[i] = fileChangeOutput{
+			Path: c.Path,
+			Kind: string(c.Kind),
+			Old:  toFileStateOutput(c.Old),
+			New:  toFileStateOutput(c.New),
+		}
+	}
+
+	enc := json.NewEncoder(j.writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
+
+// integrityReportOutput represents an integrity verification in JSON output.
+type integrityReportOutput struct {
+	SandboxID   string                  `json:"sandbox_id"`
+	SandboxName string                  `json:"sandbox_name"`
+	Paths       []string                `json:"paths"`
+	Files       int                     `json:"files"`
+	BaselineAt  string                  `json:"baseline_at"`
+	CheckedAt   string                  `json:"checked_at"`
+	Intact      bool                    `json:"intact"`
+	Changes     []integrityChangeOutput `json:"changes"`
+}
+
+// integrityChangeOutput represents a file changed since the integrity baseline in
+// JSON output.
+type integrityChangeOutput struct {
+	Path      string `json:"path"`
+	Kind      string `json:"kind"`
+	OldSHA256 string `json:"old_sha256,omitempty"`
+	NewSHA256 string `json:"new_sha256,omitempty"`
+}
+
+// PrintIntegrityReport prints the sandbox files changed since the integrity baseline
+// in JSON format.
+func (j *JSONPrinter) PrintIntegrityReport(r model.IntegrityReport) error {
+	output := integrityReportOutput{
+		SandboxID:   r.SandboxID,
+		SandboxName: r.SandboxName,
+		Paths:       r.Paths,
+		Files:       r.Files,
+		BaselineAt:  r.BaselineAt.UTC().Format(time.RFC3339),
+		CheckedAt:   r.CheckedAt.UTC().Format(time.RFC3339),
+		Intact:      r.Intact(),
+		Changes:     make([]integrityChangeOutput, len(r.Changes)),
+	}
+	for i, c := range r.Changes {
+		output.Changes[i] = integrityChangeOutput{
+			Path:      c.Path,
+			Kind:      string(c.Kind),
+			OldSHA256: c.OldSHA256,
+			NewSHA256: c.NewSHA256,
+		}
+	}
+
+	enc := json.NewEncoder(j.writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
+
+// resourcesOutput represents compute resources in JSON output.
+type resourcesOutput struct {
+	VCPUs    float64 `json:"vcpus"`
+	MemoryMB int     `json:"memory_mb"`
+	DiskGB   int     `json:"disk_gb"`
+}
+
+// hostCapacityOutput represents the host capacity in JSON output.
+type hostCapacityOutput struct {
+	Total            resourcesOutput  `json:"total"`
+	Overcommit       overcommitOutput `json:"overcommit"`
+	Allocatable      resourcesOutput  `json:"allocatable"`
+	Allocated        resourcesOutput  `json:"allocated"`
+	Free             resourcesOutput  `json:"free"`
+	Sandboxes        int              `json:"sandboxes"`
+	RunningSandboxes int              `json:"running_sandboxes"`
+}
+
+// overcommitOutput represents the overcommit ratios in JSON output.
+type overcommitOutput struct {
+	VCPUs  float64 `json:"vcpus"`
+	Memory float64 `json:"memory"`
+	Disk   float64 `json:"disk"`
+}
+
+func toResourcesOutput(r model.Resources) resourcesOutput {
+	return resourcesOutput{VCPUs: r.VCPUs, MemoryMB: r.MemoryMB, DiskGB: r.DiskGB}
+}
+
+// PrintHostCapacity prints the host capacity in JSON format.
+func (j *JSONPrinter) PrintHostCapacity(c model.HostCapacity) error {
+	output := hostCapacityOutput{
+		Total:            toResourcesOutput(c.Total),
+		Overcommit:       overcommitOutput{VCPUs: c.Overcommit.VCPUs, Memory: c.Overcommit.Memory, Disk: c.Overcommit.Disk},
+		Allocatable:      toResourcesOutput(c.Allocatable()),
+		Allocated:        toResourcesOutput(c.Allocated),
+		Free:             toResourcesOutput(c.Free()),
+		Sandboxes:        c.Sandboxes,
+		RunningSandboxes: c.RunningSandboxes,
+	}
+
+	enc := json.NewEncoder(j.writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
+
+// egressTestResultOutput represents an egress policy test result in JSON output.
+type egressTestResultOutput struct {
+	Target    string `json:"target"`
+	Domain    string `json:"domain,omitempty"`
+	Action    string `json:"action"`
+	Reason    string `json:"reason"`
+	RuleIndex *int   `json:"rule_index,omitempty"`
+	Rule      string `json:"rule,omitempty"`
+}
+
+// PrintEgressTest prints the egress policy test results in JSON format.
+func (j *JSONPrinter) PrintEgressTest(results []model.EgressTestResult) error {
+	output := make([]egressTestResultOutput, 0, len(results))
+	for _, r := range results {
+		o := egressTestResultOutput{
+			Target: r.Target,
+			Domain: r.Domain,
+			Action: string(r.Action),
+			Reason: string(r.Reason),
+		}
+		if r.Rule != nil {
+			o.Rule = r.Rule.Domain
+		}
+		if r.Rule != nil && r.RuleIndex >= 0 {
+			idx := r.RuleIndex
+			o.RuleIndex = &idx
+		}
+		output = append(output, o)
+	}
+
+	enc := json.NewEncoder(j.writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
+
+// policyOutput represents a named egress policy in JSON output.
+type policyOutput struct {
+	Name         string             `json:"name"`
+	Default      string             `json:"default"`
+	Rules        []policyRuleOutput `json:"rules"`
+	FailClosed   bool               `json:"fail_closed"`
+	DNSUpstreams []string           `json:"dns_upstreams,omitempty"`
+	SystemSvcs   []string           `json:"allow_system_services,omitempty"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+}
+
+// policyRuleOutput represents an egress rule in JSON output.
+type policyRuleOutput struct {
+	Domain string `json:"domain"`
+	Action string `json:"action"`
+	ICMP   bool   `json:"icmp,omitempty"`
+}
+
+// PrintPolicyList prints the named egress policies in JSON format.
+func (j *JSONPrinter) PrintPolicyList(policies []model.NamedEgressPolicy) error {
+	output := make([]policyOutput, 0, len(policies))
+	for _, p := range policies {
+		o := policyOutput{
+			Name:         p.Name,
+			Default:      string(p.Policy.Default),
+			Rules:        make([]policyRuleOutput, 0, len(p.Policy.Rules)),
+			FailClosed:   p.Policy.FailClosed,
+			DNSUpstreams: p.Policy.DNSUpstreams,
+			SystemSvcs:   systemServiceNames(p.Policy.AllowSystemServices),
+			CreatedAt:    p.CreatedAt.UTC(),
+			UpdatedAt:    p.UpdatedAt.UTC(),
+		}
+		for _, r := range p.Policy.Rules {
+			o.Rules = append(o.Rules, policyRuleOutput{Domain: r.Domain, Action: string(r.Action), ICMP: r.ICMP})
+		}
+		output = append(output, o)
+	}
+
+	enc := json.NewEncoder(j.writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
+
+// taskOutput represents a task in JSON output.
+type taskOutput struct {
+	Name       string            `json:"name"`
+	SandboxID  string            `json:"sandbox_id,omitempty"`
+	Command    []string          `json:"command"`
+	WorkingDir string            `json:"working_dir,omitempty"`
+	Env        map[string]string `json:"env,omitempty"`
+	Files      []string          `json:"files,omitempty"`
+	Timeout    string            `json:"timeout,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+// PrintTaskList prints the tasks in JSON format.
+func (j *JSONPrinter) PrintTaskList(tasks []model.Task) error {
+	output := make([]taskOutput, 0, len(tasks))
+	for _, t := range tasks {
+		o := taskOutput{
+			Name:       t.Name,
+			SandboxID:  t.SandboxID,
+			Command:    t.Command,
+			WorkingDir: t.WorkingDir,
+			Env:        t.Env,
+			Files:      t.Files,
+			CreatedAt:  t.CreatedAt.UTC(),
+			UpdatedAt:  t.UpdatedAt.UTC(),
+		}
+		if t.Timeout > 0 {
+			o.Timeout = t.Timeout.String()
+		}
+		output = append(output, o)
+	}
+
+	enc := json.NewEncoder(j.writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
+
+// templateOutput represents a sandbox template in JSON output.
+type templateOutput struct {
+	Name             string                `json:"name"`
+	Description      string                `json:"description,omitempty"`
+	Engine           string                `json:"engine"`
+	Image            string                `json:"image,omitempty"`
+	Profile          string                `json:"profile,omitempty"`
+	VCPUs            float64               `json:"vcpus"`
+	MemoryMB         int                   `json:"memory_mb"`
+	DiskGB           int                   `json:"disk_gb"`
+	MaxVCPUs         float64               `json:"max_vcpus,omitempty"`
+	MaxMemoryMB      int                   `json:"max_memory_mb,omitempty"`
+	UserData         bool                  `json:"user_data"`
+	Ports            map[string]int        `json:"ports,omitempty"`
+	ExecProfile      *execProfileOutput    `json:"exec_profile,omitempty"`
+	Env              map[string]string     `json:"env,omitempty"`
+	Egress           *templateEgressOutput `json:"egress,omitempty"`
+	EgressPolicyName string                `json:"egress_policy,omitempty"`
+	CreatedAt        time.Time             `json:"created_at"`
+	UpdatedAt        time.Time             `json:"updated_at"`
+}
+
+// templateEgressOutput represents the egress policy of a sandbox template or session in JSON output.
+type templateEgressOutput struct {
+	Default      string             `json:"default"`
+	Rules        []policyRuleOutput `json:"rules"`
+	FailClosed   bool               `json:"fail_closed"`
+	DNSUpstreams []string           `json:"dns_upstreams,omitempty"`
+	SystemSvcs   []string           `json:"allow_system_services,omitempty"`
+}
+
+func newTemplateOutput(t model.SandboxTemplate) templateOutput {
+	o := templateOutput{
+		Name:             t.Name,
+		Description:      t.Description,
+		Engine:           t.Engine,
+		Image:            t.Image,
+		Profile:          t.Profile,
+		VCPUs:            t.Resources.VCPUs,
+		MemoryMB:         t.Resources.MemoryMB,
+		DiskGB:           t.Resources.DiskGB,
+		MaxVCPUs:         t.Resources.MaxVCPUs,
+		MaxMemoryMB:      t.Resources.MaxMemoryMB,
+		UserData:         t.UserData != "",
+		Ports:            t.Ports,
+		Env:              t.Env,
+		EgressPolicyName: t.EgressPolicyName,
+		CreatedAt:        t.CreatedAt.UTC(),
+		UpdatedAt:        t.UpdatedAt.UTC(),
+	}
+	if p := t.ExecProfile; p != nil {
+		o.ExecProfile = &execProfileOutput{
+			PathPrepend: p.PathPrepend,
+			Locale:      p.Locale,
+			Umask:       p.Umask,
+			Ulimits:     p.Ulimits,
+			Shell:       p.Shell,
+		}
+	}
+	o.Egress = newEgressPolicyOutput(t.Egress)
+	return o
+}
+
+func newEgressPolicyOutput(e *model.EgressPolicy) *templateEgressOutput {
+	if e == nil {
+		return nil
+	}
+
+	o := &templateEgressOutput{
+		Default:      string(e.Default),
+		Rules:        make([]policyRuleOutput, 0, len(e.Rules)),
+		FailClosed:   e.FailClosed,
+		DNSUpstreams: e.DNSUpstreams,
+		SystemSvcs:   systemServiceNames(e.AllowSystemServices),
+	}
+	for _, r := range e.Rules {
+		o.Rules = append(o.Rules, policyRuleOutput{Domain: r.Domain, Action: string(r.Action), ICMP: r.ICMP})
+	}
+	return o
+}
+
+// systemServiceNames returns the names of the egress system services.
+func systemServiceNames(svcs []model.EgressSystemService) []string {
+	if len(svcs) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(svcs))
+	for _, s := range svcs {
+		names = append(names, string(s))
+	}
+	return names
+}
+
+// PrintTemplateList prints the sandbox templates in JSON format.
+func (j *JSONPrinter) PrintTemplateList(templates []model.SandboxTemplate) error {
+	output := make([]templateOutput, 0, len(templates))
+	for _, t := range templates {
+		output = append(output, newTemplateOutput(t))
+	}
+
+	enc := json.NewEncoder(j.writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
+
+// PrintTemplate prints a sandbox template in JSON format.
+func (j *JSONPrinter) PrintTemplate(template model.SandboxTemplate) error {
+	enc := json.NewEncoder(j.writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(newTemplateOutput(template))
+}
+
+// namespaceOutput represents a namespace in JSON output.
+type namespaceOutput struct {
+	Name             string `json:"name"`
+	Sandboxes        int    `json:"sandboxes"`
+	RunningSandboxes int    `json:"running_sandboxes"`
+}
+
+// reservationOutput represents a sandbox resource reservation in JSON output, the
+// same format as the reservation manifests.
+type reservationOutput struct {
+	SandboxID string          `json:"sandbox_id"`
+	Name      string          `json:"name"`
+	Namespace string          `json:"namespace,omitempty"`
+	Status    string          `json:"status"`
+	Resources resourcesOutput `json:"resources"`
+	Reserved  resourcesOutput `json:"reserved"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// PrintReservations prints the sandbox resource reservations in JSON format.
+func (j *JSONPrinter) PrintReservations(reservations []model.Reservation) error {
+	output := make([]reservationOutput, 0, len(reservations))
+	for _, r := range reservations {
+		output = append(output, reservationOutput{
+			SandboxID: r.SandboxID,
+			Name:      r.Name,
+			Namespace: r.Namespace,
+			Status:    string(r.Status),
+			Resources: toResourcesOutput(r.Resources),
+			Reserved:  toResourcesOutput(r.Reserved),
+			UpdatedAt: r.UpdatedAt.UTC(),
+		})
+	}
+
+	enc := json.NewEncoder(j.writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
+
+// PrintNamespaceList prints the namespaces in JSON format.
+func (j *JSONPrinter) PrintNamespaceList(namespaces []model.NamespaceUsage) error {
+	output := make([]namespaceOutput, 0, len(namespaces))
+	for _, ns := range namespaces {
+		output = append(output, namespaceOutput{Name: ns.Name, Sandboxes: ns.Sandboxes, RunningSandboxes: ns.RunningSandboxes})
+	}
+
+	enc := json.NewEncoder(j.writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
+
+// dataMoveOutput represents a data layout migration move in JSON output.
+type dataMoveOutput struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// PrintDataMoves prints the data layout migration moves in JSON format.
+func (j *JSONPrinter) PrintDataMoves(moves []model.DataMove) error {
+	output := make([]dataMoveOutput, 0, len(moves))
+	for _, m := range moves {
+		output = append(output, dataMoveOutput{Kind: string(m.Kind), Name: m.Name, From: m.From, To: m.To})
+	}
+
+	enc := json.NewEncoder(j.writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
+
+// dnsEventOutput represents a DNS query in JSON output.
+type dnsEventOutput struct {
+	Time              time.Time `json:"time"`
+	Domain            string    `json:"domain"`
+	QType             string    `json:"qtype"`
+	Action            string    `json:"action"`
+	Reason            string    `json:"reason,omitempty"`
+	Rcode             string    `json:"rcode"`
+	UpstreamLatencyMS float64   `json:"upstream_latency_ms"`
+}
+
+// PrintDNSEvents prints the DNS queries in JSON format.
+func (j *JSONPrinter) PrintDNSEvents(events []model.DNSEvent) error {
+	output := make([]dnsEventOutput, 0, len(events))
+	for _, e := range events {
+		output = append(output, dnsEventOutput{
+			Time:              e.Time.UTC(),
+			Domain:            e.Domain,
+			QType:             e.QType,
+			Action:            string(e.Action),
+			Reason:            string(e.Reason),
+			Rcode:             e.Rcode,
+			UpstreamLatencyMS: durationMS(e.UpstreamLatency),
+		})
+	}
+
+	enc := json.NewEncoder(j.writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
+
+// dnsStatsOutput represents the aggregated DNS queries in JSON output.
+type dnsStatsOutput struct {
+	Queries int                    `json:"queries"`
+	Allowed int                    `json:"allowed"`
+	Denied  int                    `json:"denied"`
+	Failed  int                    `json:"failed"`
+	Domains []dnsDomainStatsOutput `json:"domains"`
+}
+
+// dnsDomainStatsOutput represents the aggregated DNS queries of a domain in JSON output.
+type dnsDomainStatsOutput struct {
+	Domain               string    `json:"domain"`
+	Queries              int       `json:"queries"`
+	Allowed              int       `json:"allowed"`
+	Denied               int       `json:"denied"`
+	Failed               int       `json:"failed"`
+	AvgUpstreamLatencyMS float64   `json:"avg_upstream_latency_ms"`
+	MaxUpstreamLatencyMS float64   `json:"max_upstream_latency_ms"`
+	LastQueryAt          time.Time `json:"last_query_at"`
+}
+
+// PrintDNSStats prints the aggregated DNS queries in JSON format.
+func (j *JSONPrinter) PrintDNSStats(stats model.DNSStats) error {
+	output := dnsStatsOutput{
+		Queries: stats.Queries,
+		Allowed: stats.Allowed,
+		Denied:  stats.Denied,
+		Failed:  stats.Failed,
+		Domains: make([]dnsDomainStatsOutput, 0, len(stats.Domains)),
+	}
+	for _, d := range stats.Domains {
+		output.Domains = append(output.Domains, dnsDomainStatsOutput{
+			Domain:               d.Domain,
+			Queries:              d.Queries,
+			Allowed:              d.Allowed,
+			Denied:               d.Denied,
+			Failed:               d.Failed,
+			AvgUpstreamLatencyMS: durationMS(d.AvgUpstreamLatency),
+			MaxUpstreamLatencyMS: durationMS(d.MaxUpstreamLatency),
+			LastQueryAt:          d.LastQueryAt.UTC(),
+		})
+	}
+
+	enc := json.NewEncoder(j.writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
+
+// usageReportOutput represents a usage report in JSON output.
+type usageReportOutput struct {
+	Since   *time.Time         `json:"since,omitempty"`
+	Until   time.Time          `json:"until"`
+	GroupBy string             `json:"group_by,omitempty"`
+	Entries []usageEntryOutput `json:"entries"`
+	Total   usageOutput        `json:"total"`
+}
+
+// usageEntryOutput represents the usage of a sandbox or group in JSON output.
+type usageEntryOutput struct {
+	Name      string `json:"name,omitempty"`
+	ID        string `json:"id,omitempty"`
+	Removed   bool   `json:"removed,omitempty"`
+	Group     string `json:"group,omitempty"`
+	Sandboxes int    `json:"sandboxes"`
+	usageOutput
+}
+
+// usageOutput represents the used resources in JSON output.
+type usageOutput struct {
+	CPUSeconds    float64 `json:"cpu_seconds"`
+	MemoryMBHours float64 `json:"memory_mb_hours"`
+	DiskGBHours   float64 `json:"disk_gb_hours"`
+}
+
+func toUsageOutput(u model.Usage) usageOutput {
+	return usageOutput{CPUSeconds: u.CPUSeconds, MemoryMBHours: u.MemoryMBHours, DiskGBHours: u.DiskGBHours}
+}
+
+// PrintUsageReport prints the resources used by the sandboxes in JSON format.
+func (j *JSONPrinter) PrintUsageReport(report model.UsageReport) error {
+	output := usageReportOutput{
+		Until:   report.Until.UTC(),
+		GroupBy: report.GroupBy,
+		Entries: make([]usageEntryOutput, 0, len(report.Entries)),
+		Total:   toUsageOutput(report.Total),
+	}
+	if !report.Since.IsZero() {
+		since := report.Since.UTC()
+		output.Since = &since
+	}
+	for _, e := range report.Entries {
+		entry := usageEntryOutput{Sandboxes: e.Sandboxes, usageOutput: toUsageOutput(e.Usage)}
+		if report.GroupBy != "" {
+			entry.Group = e.Key
+		} else {
+			entry.Name, entry.ID, entry.Removed = e.Key, e.SandboxID, e.Removed
+		}
+		output.Entries = append(output.Entries, entry)
+	}
+
+	enc := json.NewEncoder(j.writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
+
+// processTableOutput represents a sandbox guest process table in JSON output.
+type processTableOutput struct {
+	SandboxID            string          `json:"sandbox_id"`
+	SandboxName          string          `json:"sandbox_name"`
+	CollectedAt          time.Time       `json:"collected_at"`
+	UptimeSeconds        float64         `json:"uptime_seconds"`
+	Load                 [3]float64      `json:"load"`
+	MemoryTotalBytes     uint64          `json:"memory_total_bytes"`
+	MemoryAvailableBytes uint64          `json:"memory_available_bytes"`
+	Processes            []processOutput `json:"processes"`
+}
+
+// processOutput represents a sandbox guest process in JSON output.
+type processOutput struct {
+	PID           int       `json:"pid"`
+	PPID          int       `json:"ppid"`
+	User          string    `json:"user"`
+	State         string    `json:"state"`
+	Command       string    `json:"command"`
+	CPUSeconds    float64   `json:"cpu_seconds"`
+	CPUPercent    float64   `json:"cpu_percent"`
+	RSSBytes      uint64    `json:"rss_bytes"`
+	MemoryPercent float64   `json:"memory_percent"`
+	Threads       int       `json:"threads"`
+	StartedAt     time.Time `json:"started_at"`
+}
+
+// PrintProcessTable prints the sandbox guest processes in JSON format.
+func (j *JSONPrinter) PrintProcessTable(table model.ProcessTable) error {
+	output := processTableOutput{
+		SandboxID:            table.SandboxID,
+		SandboxName:          table.SandboxName,
+		CollectedAt:          table.CollectedAt.UTC(),
+		UptimeSeconds:        table.Uptime.Seconds(),
+		Load:                 [3]float64{table.Load1, table.Load5, table.Load15},
+		MemoryTotalBytes:     table.MemoryTotalBytes,
+		MemoryAvailableBytes: table.MemoryAvailableBytes,
+		Processes:            make([]processOutput, 0, len(table.Processes)),
+	}
+	for _, p := range table.Processes {
+		output.Processes = append(output.Processes, processOutput{
+			PID:           p.PID,
+			PPID:          p.PPID,
+			User:          p.User,
+			State:         p.State,
+			Command:       p.Command,
+			CPUSeconds:    p.CPUTime.Seconds(),
+			CPUPercent:    math.Round(p.CPUPercent*100) / 100,
+			RSSBytes:      p.RSSBytes,
+			MemoryPercent: math.Round(p.MemoryPercent*100) / 100,
+			Threads:       p.Threads,
+			StartedAt:     p.StartedAt.UTC().Truncate(time.Second),
+		})
+	}
+
+	enc := json.NewEncoder(j.writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
+
+// connectionOutput represents a sandbox network connection in JSON output.
+type connectionOutput struct {
+	Protocol      string `json:"protocol"`
+	Direction     string `json:"direction"`
+	Interface     string `json:"interface"`
+	Source        string `json:"source"`
+	Destination   string `json:"destination"`
+	Domain        string `json:"domain,omitempty"`
+	State         string `json:"state,omitempty"`
+	Proxy         string `json:"proxy,omitempty"`
+	BytesSent     uint64 `json:"bytes_sent"`
+	BytesReceived uint64 `json:"bytes_received"`
+}
+
+// PrintConnections prints the sandbox network connections in JSON format.
+func (j *JSONPrinter) PrintConnections(conns []model.Connection) error {
+	output := make([]connectionOutput, 0, len(conns))
+	for _, c := range conns {
+		output = append(output, connectionOutput{
+			Protocol:      c.Protocol,
+			Direction:     string(c.Direction),
+			Interface:     c.Interface,
+			Source:        c.Source,
+			Destination:   c.Destination,
+			Domain:        c.Domain,
+			State:         c.State,
+			Proxy:         c.Proxy,
+			BytesSent:     c.BytesSent,
+			BytesReceived: c.BytesReceived,
+		})
+	}
+
+	enc := json.NewEncoder(j.writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
+
+// durationMS returns a duration in milliseconds with microsecond precision.
+func durationMS(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// execRecordOutput represents an exec history record in JSON output.
+type execRecordOutput struct {
+	ID           string    `json:"id"`
+	Command      []string  `json:"command"`
+	WorkingDir   string    `json:"working_dir,omitempty"`
+	User         string    `json:"user"`
+	Caller       string    `json:"caller"`
+	ExitCode     int       `json:"exit_code"`
+	Error        string    `json:"error,omitempty"`
+	StartedAt    time.Time `json:"started_at"`
+	DurationMS   int64     `json:"duration_ms"`
+	StdoutBytes  int64     `json:"stdout_bytes"`
+	StderrBytes  int64     `json:"stderr_bytes"`
+	StdoutSHA256 string    `json:"stdout_sha256"`
+	StderrSHA256 string    `json:"stderr_sha256"`
+}
+
+// PrintExecHistory prints the executed commands in JSON format.
+func (j *JSONPrinter) PrintExecHistory(records []model.ExecRecord) error {
+	output := make([]execRecordOutput, 0, len(records))
+	for _, r := range records {
+		output = append(output, execRecordOutput{
+			ID:           r.ID,
+			Command:      r.Command,
+			WorkingDir:   r.WorkingDir,
+			User:         r.User,
+			Caller:       r.Caller,
+			ExitCode:     r.ExitCode,
+			Error:        r.Error,
+			StartedAt:    r.StartedAt,
+			DurationMS:   r.Duration.Milliseconds(),
+			StdoutBytes:  r.StdoutBytes,
+			StderrBytes:  r.StderrBytes,
+			StdoutSHA256: r.StdoutSHA256,
+			StderrSHA256: r.StderrSHA256,
+		})
+	}
+
 	enc := json.NewEncoder(j.writer)
 	enc.SetIndent("", "  ")
 	return enc.Encode(output)
@@ -235,3 +1238,110 @@ func (j *JSONPrinter) PrintMessage(msg string) error {
 	enc.SetIndent("", "  ")
 	return enc.Encode(output)
 }
+
+// execResultOutput represents a command execution result in JSON output.
+type execResultOutput struct {
+	ExitCode        int       `json:"exit_code"`
+	StartedAt       time.Time `json:"started_at"`
+	FinishedAt      time.Time `json:"finished_at"`
+	DurationMS      int64     `json:"duration_ms"`
+	StdoutBytes     int64     `json:"stdout_bytes"`
+	StderrBytes     int64     `json:"stderr_bytes"`
+	Stdout          string    `json:"stdout"`
+	Stderr          string    `json:"stderr"`
+	StdoutTruncated bool      `json:"stdout_truncated"`
+	StderrTruncated bool      `json:"stderr_truncated"`
+	// OutputLimitExceeded is only set when the command output exceeded the exec limit.
+	OutputLimitExceeded bool `json:"output_limit_exceeded,omitempty"`
+}
+
+// engineChecksOutput represents the preflight checks of an engine in JSON output.
+type engineChecksOutput struct {
+	Engine   string              `json:"engine"`
+	Checks   []checkResultOutput `json:"checks"`
+	Errors   int                 `json:"errors"`
+	Warnings int                 `json:"warnings"`
+}
+
+type checkResultOutput struct {
+	ID       string `json:"id"`
+	Status   string `json:"status"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	// Remediation is only set on the warnings and errors that can be fixed.
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// errorOutput represents a command error in JSON output.
+type errorOutput struct {
+	Error    string `json:"error"`
+	Code     string `json:"code"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// PrintExecResult prints a command execution result in JSON format.
+func (j *JSONPrinter) PrintExecResult(result model.ExecResult) error {
+	output := execResultOutput{
+		ExitCode:            result.ExitCode,
+		StartedAt:           result.StartedAt.UTC(),
+		FinishedAt:          result.FinishedAt.UTC(),
+		DurationMS:          result.Duration.Milliseconds(),
+		StdoutBytes:         result.StdoutBytes,
+		StderrBytes:         result.StderrBytes,
+		Stdout:              result.Stdout,
+		Stderr:              result.Stderr,
+		StdoutTruncated:     result.StdoutTruncated,
+		StderrTruncated:     result.StderrTruncated,
+		OutputLimitExceeded: result.OutputLimitExceeded,
+	}
+
+	enc := json.NewEncoder(j.writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
+
+// PrintChecks prints preflight check results in JSON format.
+func (j *JSONPrinter) PrintChecks(checks []EngineChecks) error {
+	output := make([]engineChecksOutput, len(checks))
+	for i, ec := range checks {
+		out := engineChecksOutput{
+			Engine: ec.Engine,
+			Checks: make([]checkResultOutput, len(ec.Results)),
+		}
+		for k, r := range ec.Results {
+			out.Checks[k] = checkResultOutput{
+				ID:       r.ID,
+				Status:   string(r.Status),
+				Severity: string(r.Status.Severity()),
+				Message:  r.Message,
+			}
+			if r.Status != model.CheckStatusOK {
+				out.Checks[k].Remediation = r.Remediation
+			}
+			switch r.Status {
+			case model.CheckStatusError:
+				out.Errors++
+			case model.CheckStatusWarning:
+				out.Warnings++
+			}
+		}
+		output[i] = out
+	}
+
+	enc := json.NewEncoder(j.writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
+
+// PrintError prints a command error in JSON format.
+func (j *JSONPrinter) PrintError(err ErrorInfo) error {
+	output := errorOutput{
+		Error:    err.Message,
+		Code:     err.Code,
+		ExitCode: err.ExitCode,
+	}
+
+	enc := json.NewEncoder(j.writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
@@ -8,5 +8,42 @@ type Printer interface {
 	PrintStatus(sandbox model.Sandbox) error
 	PrintImageList(releases []model.ImageRelease) error
 	PrintImageInspect(manifest model.ImageManifest) error
+	PrintImageDiskUsage(usage model.ImageDiskUsage) error
+	PrintImageDiff(diff model.ImageDiff) error
+	PrintIntegrityReport(report model.IntegrityReport) error
 	PrintMessage(msg string) error
+	PrintExecResult(result model.ExecResult) error
+	PrintExecHistory(records []model.ExecRecord) error
+	PrintHostCapacity(capacity model.HostCapacity) error
+	PrintReservations(reservations []model.Reservation) error
+	PrintEgressTest(results []model.EgressTestResult) error
+	PrintPolicyList(policies []model.NamedEgressPolicy) error
+	PrintTaskList(tasks []model.Task) error
+	PrintTemplateList(templates []model.SandboxTemplate) error
+	PrintTemplate(template model.SandboxTemplate) error
+	PrintNamespaceList(namespaces []model.NamespaceUsage) error
+	PrintDataMoves(moves []model.DataMove) error
+	PrintDNSEvents(events []model.DNSEvent) error
+	PrintDNSStats(stats model.DNSStats) error
+	PrintUsageReport(report model.UsageReport) error
+	PrintProcessTable(table model.ProcessTable) error
+	PrintConnections(conns []model.Connection) error
+	PrintChecks(checks []EngineChecks) error
+	PrintError(err ErrorInfo) error
+}
+
+// EngineChecks are the preflight check results of an engine.
+type EngineChecks struct {
+	Engine  string
+	Results []model.CheckResult
+}
+
+// ErrorInfo describes a command failure.
+type ErrorInfo struct {
+	// Message is the error message.
+	Message string
+	// Code is a stable identifier of the error kind (e.g. "not_found").
+	Code string
+	// ExitCode is the process exit code used for the error.
+	ExitCode int
 }
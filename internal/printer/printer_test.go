@@ -2,6 +2,7 @@ package printer_test
 
 import (
 	"bytes"
+	"io/fs"
 	"strings"
 	"testing"
 	"time"
@@ -17,6 +18,7 @@ func sandboxFixture() model.Sandbox {
 	createdAt := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
 	return model.Sandbox{
 		ID:        "01234567890ABCDEFGHIJKLMNOP",
+		Namespace: "default",
 		Name:      "my-sandbox",
 		Status:    model.SandboxStatusRunning,
 		CreatedAt: createdAt,
@@ -55,6 +57,277 @@ func TestJSONPrinterPrintStatus(t *testing.T) {
 	assert.Contains(t, out, `"type": "firecracker"`)
 	assert.Contains(t, out, `"root_fs": "/images/rootfs.ext4"`)
 	assert.Contains(t, out, `"kernel_image": "/images/vmlinux"`)
+	assert.NotContains(t, out, `"boot_phases"`)
+}
+
+func TestJSONPrinterPrintStatusBootPhases(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewJSONPrinter(&buf)
+
+	sb := sandboxFixture()
+	sb.BootPhases = []model.BootPhase{
+		{Name: "boot", Duration: 120 * time.Millisecond},
+		{Name: "ssh", Duration: 850 * time.Millisecond},
+	}
+	err := p.PrintStatus(sb)
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, `"name": "boot"`)
+	assert.Contains(t, out, `"duration_ms": 850`)
+}
+
+func TestPrintStatusEgress(t *testing.T) {
+	sb := sandboxFixture()
+	sb.Egress = &model.EgressStatus{
+		Health:    model.EgressHealthDegraded,
+		Restarts:  3,
+		LastError: "proxy exited: signal: killed",
+		UpdatedAt: time.Date(2026, 1, 30, 10, 5, 0, 0, time.UTC),
+	}
+
+	var tableBuf bytes.Buffer
+	require.NoError(t, printer.NewTablePrinter(&tableBuf).PrintStatus(sb))
+	assert.Contains(t, tableBuf.String(), "Egress:     degraded (restarts: 3)")
+	assert.Contains(t, tableBuf.String(), "Egress err: proxy exited: signal: killed")
+
+	var jsonBuf bytes.Buffer
+	require.NoError(t, printer.NewJSONPrinter(&jsonBuf).PrintStatus(sb))
+	assert.Contains(t, jsonBuf.String(), `"health": "degraded"`)
+	assert.Contains(t, jsonBuf.String(), `"restarts": 3`)
+	assert.Contains(t, jsonBuf.String(), `"last_error": "proxy exited: signal: killed"`)
+}
+
+func TestPrintStatusResourceCeilings(t *testing.T) {
+	sb := sandboxFixture()
+	sb.Config.Resources.MaxVCPUs = 4
+	sb.Config.Resources.MaxMemoryMB = 8192
+
+	var tableBuf bytes.Buffer
+	require.NoError(t, printer.NewTablePrinter(&tableBuf).PrintStatus(sb))
+	assert.Contains(t, tableBuf.String(), "VCPUs:      2.00 (max 4.00)\nMemory:     2048 MB (max 8192 MB)\n")
+
+	var jsonBuf bytes.Buffer
+	require.NoError(t, printer.NewJSONPrinter(&jsonBuf).PrintStatus(sb))
+	assert.Contains(t, jsonBuf.String(), `"max_vcpus": 4`)
+	assert.Contains(t, jsonBuf.String(), `"max_memory_mb": 8192`)
+}
+
+func TestPrintStatusAnnotations(t *testing.T) {
+	sb := sandboxFixture()
+	sb.Annotations = map[string]string{"ticket": "https://issues.example.com/42", "owner": "alice@example.com"}
+
+	var tableBuf bytes.Buffer
+	require.NoError(t, printer.NewTablePrinter(&tableBuf).PrintStatus(sb))
+	assert.Contains(t, tableBuf.String(), "Annotations:\n  owner: alice@example.com\n  ticket: https://issues.example.com/42\n")
+
+	var jsonBuf bytes.Buffer
+	require.NoError(t, printer.NewJSONPrinter(&jsonBuf).PrintStatus(sb))
+	assert.Contains(t, jsonBuf.String(), `"owner": "alice@example.com"`)
+
+	var listBuf bytes.Buffer
+	require.NoError(t, printer.NewJSONPrinter(&listBuf).PrintList([]model.Sandbox{sb}))
+	assert.Contains(t, listBuf.String(), `"ticket": "https://issues.example.com/42"`)
+}
+
+func TestPrintStatusPorts(t *testing.T) {
+	sb := sandboxFixture()
+	sb.Config.Ports = map[string]int{"web": 3000, "db": 5432}
+
+	var tableBuf bytes.Buffer
+	require.NoError(t, printer.NewTablePrinter(&tableBuf).PrintStatus(sb))
+	assert.Contains(t, tableBuf.String(), "Ports:\n  db: 5432\n  web: 3000\n")
+
+	var jsonBuf bytes.Buffer
+	require.NoError(t, printer.NewJSONPrinter(&jsonBuf).PrintStatus(sb))
+	assert.Contains(t, jsonBuf.String(), `"web": 3000`)
+}
+
+func TestPrintStatusExecProfile(t *testing.T) {
+	sb := sandboxFixture()
+	sb.Config.ExecProfile = &model.ExecProfile{
+		PathPrepend: []string{"/opt/go/bin", "/root/.local/bin"},
+		Umask:       "027",
+		Ulimits:     map[string]string{"nproc": "512", "nofile": "65536"},
+		Shell:       "/bin/bash",
+	}
+
+	var tableBuf bytes.Buffer
+	require.NoError(t, printer.NewTablePrinter(&tableBuf).PrintStatus(sb))
+	assert.Contains(t, tableBuf.String(), "Exec profile:\n  path: /opt/go/bin:/root/.local/bin\n  umask: 027\n  ulimit nofile: 65536\n  ulimit nproc: 512\n  shell: /bin/bash\n")
+
+	var jsonBuf bytes.Buffer
+	require.NoError(t, printer.NewJSONPrinter(&jsonBuf).PrintStatus(sb))
+	assert.Contains(t, jsonBuf.String(), `"umask": "027"`)
+	assert.Contains(t, jsonBuf.String(), `"nofile": "65536"`)
+	assert.NotContains(t, jsonBuf.String(), `"locale"`)
+}
+
+func TestPrintStatusIdle(t *testing.T) {
+	sb := sandboxFixture()
+	sb.IdlePolicy = &model.IdlePolicy{Timeout: 30 * time.Minute, Action: model.IdleActionStop}
+	sb.Activity.LastActivityAt = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var tableBuf bytes.Buffer
+	require.NoError(t, printer.NewTablePrinter(&tableBuf).PrintStatus(sb))
+	assert.Contains(t, tableBuf.String(), "Idle:       stop after 30m0s\n")
+	assert.Contains(t, tableBuf.String(), "Activity:   ")
+
+	var jsonBuf bytes.Buffer
+	require.NoError(t, printer.NewJSONPrinter(&jsonBuf).PrintStatus(sb))
+	assert.Contains(t, jsonBuf.String(), `"timeout": "30m0s"`)
+	assert.Contains(t, jsonBuf.String(), `"last_activity_at": "2026-01-02T03:04:05Z"`)
+}
+
+func TestPrintStatusQuarantined(t *testing.T) {
+	sb := sandboxFixture()
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	sb.QuarantinedAt = &at
+
+	var tableBuf bytes.Buffer
+	require.NoError(t, printer.NewTablePrinter(&tableBuf).PrintStatus(sb))
+	assert.Contains(t, tableBuf.String(), "Quarantine: since ")
+
+	var jsonBuf bytes.Buffer
+	require.NoError(t, printer.NewJSONPrinter(&jsonBuf).PrintStatus(sb))
+	assert.Contains(t, jsonBuf.String(), `"quarantined_at": "2026-01-02T03:04:05Z"`)
+}
+
+func TestPrintStatusPersistent(t *testing.T) {
+	sb := sandboxFixture()
+	sb.Persistent = true
+
+	var tableBuf bytes.Buffer
+	require.NoError(t, printer.NewTablePrinter(&tableBuf).PrintStatus(sb))
+	assert.Contains(t, tableBuf.String(), "Persistent: yes")
+
+	var jsonBuf bytes.Buffer
+	require.NoError(t, printer.NewJSONPrinter(&jsonBuf).PrintStatus(sb))
+	assert.Contains(t, jsonBuf.String(), `"persistent": true`)
+}
+
+func TestPrintStatusEgressInterface(t *testing.T) {
+	sb := sandboxFixture()
+	sb.Config.FirecrackerEngine.EgressInterface = "wg0"
+
+	var tableBuf bytes.Buffer
+	require.NoError(t, printer.NewTablePrinter(&tableBuf).PrintStatus(sb))
+	assert.Contains(t, tableBuf.String(), "Uplink:     wg0\n")
+
+	var jsonBuf bytes.Buffer
+	require.NoError(t, printer.NewJSONPrinter(&jsonBuf).PrintStatus(sb))
+	assert.Contains(t, jsonBuf.String(), `"egress_interface": "wg0"`)
+}
+
+func TestPrintStatusNetworkOptions(t *testing.T) {
+	sb := sandboxFixture()
+	sb.Config.FirecrackerEngine.Network = model.NetworkOptions{MTU: 1380, ClampMSS: true}
+
+	var tableBuf bytes.Buffer
+	require.NoError(t, printer.NewTablePrinter(&tableBuf).PrintStatus(sb))
+	assert.Contains(t, tableBuf.String(), "Network:    mtu 1380, mss clamping\n")
+
+	var jsonBuf bytes.Buffer
+	require.NoError(t, printer.NewJSONPrinter(&jsonBuf).PrintStatus(sb))
+	assert.Contains(t, jsonBuf.String(), `"mtu": 1380`)
+	assert.Contains(t, jsonBuf.String(), `"clamp_mss": true`)
+}
+
+func TestPrintStatusSession(t *testing.T) {
+	sb := sandboxFixture()
+	sb.Session = &model.SessionConfig{
+		Env:              map[string]string{"GOFLAGS": "-mod=mod", "GITHUB_TOKEN": "ghp_secret"},
+		EgressPolicyName: "github",
+	}
+
+	var tableBuf bytes.Buffer
+	require.NoError(t, printer.NewTablePrinter(&tableBuf).PrintStatus(sb))
+	assert.Contains(t, tableBuf.String(), "Session:\n  env GITHUB_TOKEN: <redacted>\n  env GOFLAGS: -mod=mod\n  egress policy: github\n")
+	assert.NotContains(t, tableBuf.String(), "ghp_secret")
+
+	var jsonBuf bytes.Buffer
+	require.NoError(t, printer.NewJSONPrinter(&jsonBuf).PrintStatus(sb))
+	assert.Contains(t, jsonBuf.String(), `"GITHUB_TOKEN": "<redacted>"`)
+	assert.Contains(t, jsonBuf.String(), `"egress_policy": "github"`)
+	assert.NotContains(t, jsonBuf.String(), "ghp_secret")
+}
+
+func TestPrintStatusLastStart(t *testing.T) {
+	sb := sandboxFixture()
+	sb.LastBootPhases = []model.BootPhase{
+		{Name: "api_ready", Duration: 120 * time.Millisecond},
+		{Name: "guest_ready", Duration: 60 * time.Second, Error: "timeout waiting for guest SSH"},
+	}
+	sb.LastError = `start step "guest_ready" failed: timeout waiting for guest SSH`
+
+	var tableBuf bytes.Buffer
+	require.NoError(t, printer.NewTablePrinter(&tableBuf).PrintStatus(sb))
+	assert.Contains(t, tableBuf.String(), "Last boot:\n  api_ready: 120ms\n  guest_ready: 1m0s (failed: timeout waiting for guest SSH)\n")
+	assert.Contains(t, tableBuf.String(), `Last error: start step "guest_ready" failed: timeout waiting for guest SSH`)
+
+	var jsonBuf bytes.Buffer
+	require.NoError(t, printer.NewJSONPrinter(&jsonBuf).PrintStatus(sb))
+	assert.Contains(t, jsonBuf.String(), `"name": "guest_ready",
+      "duration_ms": 60000,
+      "error": "timeout waiting for guest SSH"`)
+	assert.Contains(t, jsonBuf.String(), `"last_error": "start step \"guest_ready\" failed: timeout waiting for guest SSH"`)
+}
+
+func TestPrintStatusRootFSStrategy(t *testing.T) {
+	sb := sandboxFixture()
+	sb.RootFSStrategy = model.RootFSStrategyReflink
+
+	var tableBuf bytes.Buffer
+	require.NoError(t, printer.NewTablePrinter(&tableBuf).PrintStatus(sb))
+	assert.Contains(t, tableBuf.String(), "Provision:  reflink\n")
+
+	var jsonBuf bytes.Buffer
+	require.NoError(t, printer.NewJSONPrinter(&jsonBuf).PrintStatus(sb))
+	assert.Contains(t, jsonBuf.String(), `"rootfs_strategy": "reflink"`)
+}
+
+func TestPrintStatusSeccomp(t *testing.T) {
+	tests := map[string]struct {
+		seccomp  model.SeccompOptions
+		status   *model.SeccompStatus
+		expTable string
+		expJSON  []string
+	}{
+		"A running sandbox should show the enforcement.": {
+			status:   &model.SeccompStatus{Enforced: true, Filters: 1},
+			expTable: "Seccomp:    default (enforced, filters: 1)",
+			expJSON:  []string{`"mode": "default"`, `"enforced": true`, `"filters": 1`},
+		},
+		"A running sandbox without filters should not be enforced.": {
+			seccomp:  model.SeccompOptions{Mode: model.SeccompModeDisabled},
+			status:   &model.SeccompStatus{},
+			expTable: "Seccomp:    disabled (not enforced)",
+			expJSON:  []string{`"mode": "disabled"`, `"enforced": false`},
+		},
+		"A stopped sandbox should only show the mode.": {
+			seccomp:  model.SeccompOptions{Mode: model.SeccompModeCustom, Filter: "/etc/sbx/filter.bpf"},
+			expTable: "Seccomp:    custom /etc/sbx/filter.bpf\n",
+			expJSON:  []string{`"mode": "custom"`, `"filter": "/etc/sbx/filter.bpf"`},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			sb := sandboxFixture()
+			sb.Config.FirecrackerEngine.Seccomp = test.seccomp
+			sb.Seccomp = test.status
+
+			var tableBuf bytes.Buffer
+			require.NoError(t, printer.NewTablePrinter(&tableBuf).PrintStatus(sb))
+			assert.Contains(t, tableBuf.String(), test.expTable)
+
+			var jsonBuf bytes.Buffer
+			require.NoError(t, printer.NewJSONPrinter(&jsonBuf).PrintStatus(sb))
+			for _, exp := range test.expJSON {
+				assert.Contains(t, jsonBuf.String(), exp)
+			}
+		})
+	}
 }
 
 func imageReleaseFixtures() []model.ImageRelease {
@@ -143,6 +416,9 @@ func TestTablePrinterPrintImageInspect(t *testing.T) {
 	assert.Contains(t, out, "alpine")
 	assert.Contains(t, out, "balanced")
 	assert.Contains(t, out, "adc9bc1")
+	assert.Contains(t, out, "Kernel profiles:")
+	assert.Contains(t, out, "fast-boot:")
+	assert.Contains(t, out, "slab_nomerge")
 }
 
 func TestJSONPrinterPrintImageList(t *testing.T) {
@@ -178,6 +454,153 @@ func TestJSONPrinterPrintImageInspect(t *testing.T) {
 	assert.Contains(t, out, `"profile": "balanced"`)
 	assert.Contains(t, out, `"version": "v1.14.1"`)
 	assert.Contains(t, out, `"commit": "adc9bc1"`)
+	assert.Contains(t, out, `"name": "debug"`)
+	assert.Contains(t, out, `"disable_console": true`)
+}
+
+func imageDiskUsageFixture() model.ImageDiskUsage {
+	return model.ImageDiskUsage{
+		Images: []model.ImageUsage{
+			{Name: "v0.1.0", SizeBytes: 3 * 1024 * 1024, SharedBytes: 1024 * 1024},
+			{Name: "v0.2.0", SizeBytes: 4 * 1024 * 1024, SharedBytes: 1024 * 1024},
+		},
+		TotalBytes: 7 * 1024 * 1024,
+		DiskBytes:  6 * 1024 * 1024,
+	}
+}
+
+func TestTablePrinterPrintImageDiskUsage(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewTablePrinter(&buf)
+
+	err := p.PrintImageDiskUsage(imageDiskUsageFixture())
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, "IMAGE")
+	assert.Contains(t, out, "SHARED")
+	assert.Contains(t, out, "v0.2.0")
+	assert.Contains(t, out, "Total:      7.0 MB")
+	assert.Contains(t, out, "On disk:    6.0 MB")
+	assert.Contains(t, out, "Reclaimed:  1.0 MB")
+}
+
+func TestJSONPrinterPrintImageDiskUsage(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewJSONPrinter(&buf)
+
+	err := p.PrintImageDiskUsage(imageDiskUsageFixture())
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, `"name": "v0.1.0"`)
+	assert.Contains(t, out, `"shared_bytes": 1048576`)
+	assert.Contains(t, out, `"total_bytes": 7340032`)
+	assert.Contains(t, out, `"disk_bytes": 6291456`)
+}
+
+func imageDiffFixture() model.ImageDiff {
+	return model.ImageDiff{
+		From: "v0.1.0",
+		To:   "snap",
+		Changes: []model.FileChange{
+			{
+				Path: "/etc/motd",
+				Kind: model.FileChangeModified,
+				Old:  &model.FileState{Type: model.FileTypeRegular, Mode: 0o644, SizeBytes: 5, SHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+				New:  &model.FileState{Type: model.FileTypeRegular, Mode: 0o644, SizeBytes: 2048, SHA256: "3733cd977ff8eb18b987357e22ced99f46097f31ecb239e878ae63760e83e4d5"},
+			},
+			{
+				Path: "/usr/bin/tool",
+				Kind: model.FileChangeAdded,
+				New:  &model.FileState{Type: model.FileTypeRegular, Mode: 0o755 | fs.ModeSetuid, SizeBytes: 10},
+			},
+			{
+				Path: "/etc/alt",
+				Kind: model.FileChangeRemoved,
+				Old:  &model.FileState{Type: model.FileTypeSymlink, Mode: 0o777, LinkTarget: "/etc/motd"},
+			},
+		},
+	}
+}
+
+func TestTablePrinterPrintImageDiff(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewTablePrinter(&buf)
+
+	require.NoError(t, p.PrintImageDiff(imageDiffFixture()))
+
+	out := buf.String()
+	assert.Contains(t, out, "CHANGE")
+	assert.Contains(t, out, "3733cd977ff8")
+	assert.Contains(t, out, "2.0 KB")
+	assert.Contains(t, out, "/etc/alt -> /etc/motd")
+	assert.Contains(t, out, "v0.1.0 -> snap: 1 added, 1 modified, 1 removed")
+
+	buf.Reset()
+	require.NoError(t, p.PrintImageDiff(model.ImageDiff{From: "v0.1.0", To: "snap"}))
+	assert.Equal(t, "No changes from v0.1.0 to snap\n", buf.String())
+}
+
+func TestJSONPrinterPrintImageDiff(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewJSONPrinter(&buf)
+
+	require.NoError(t, p.PrintImageDiff(imageDiffFixture()))
+
+	out := buf.String()
+	assert.Contains(t, out, `"from": "v0.1.0"`)
+	assert.Contains(t, out, `"kind": "modified"`)
+	assert.Contains(t, out, `"mode": "4755"`)
+	assert.Contains(t, out, `"link_target": "/etc/motd"`)
+	assert.Contains(t, out, `"size_bytes": 2048`)
+}
+
+func integrityReportFixture() model.IntegrityReport {
+	return model.IntegrityReport{
+		SandboxID:   "01234567890ABCDEFGHIJKLMNOP",
+		SandboxName: "my-sandbox",
+		Paths:       []string{"/etc"},
+		Files:       12,
+		BaselineAt:  time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC),
+		CheckedAt:   time.Date(2026, 1, 30, 11, 0, 0, 0, time.UTC),
+		Changes: []model.IntegrityChange{
+			{Path: "/etc/motd", Kind: model.FileChangeModified, OldSHA256: strings.Repeat("a", 64), NewSHA256: strings.Repeat("b", 64)},
+			{Path: "/etc/passwd", Kind: model.FileChangeRemoved, OldSHA256: strings.Repeat("c", 64)},
+		},
+	}
+}
+
+func TestTablePrinterPrintIntegrityReport(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewTablePrinter(&buf)
+
+	require.NoError(t, p.PrintIntegrityReport(integrityReportFixture()))
+
+	out := buf.String()
+	assert.Contains(t, out, "CHANGE")
+	assert.Contains(t, out, "modified  /etc/motd    bbbbbbbbbbbb")
+	assert.Contains(t, out, "removed   /etc/passwd  cccccccccccc")
+	assert.Contains(t, out, "my-sandbox: 0 added, 1 modified, 1 removed")
+
+	buf.Reset()
+	report := integrityReportFixture()
+	report.Changes = nil
+	require.NoError(t, p.PrintIntegrityReport(report))
+	assert.True(t, strings.HasPrefix(buf.String(), "No changes in 12 files of my-sandbox"))
+}
+
+func TestJSONPrinterPrintIntegrityReport(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewJSONPrinter(&buf)
+
+	require.NoError(t, p.PrintIntegrityReport(integrityReportFixture()))
+
+	out := buf.String()
+	assert.Contains(t, out, `"intact": false`)
+	assert.Contains(t, out, `"baseline_at": "2026-01-30T10:00:00Z"`)
+	assert.Contains(t, out, `"kind": "removed"`)
+	assert.NotContains(t, out, `"new_sha256": ""`)
 }
 
 func TestTablePrinterPrintMessage(t *testing.T) {
@@ -188,3 +611,781 @@ func TestTablePrinterPrintMessage(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "ok", strings.TrimSpace(buf.String()))
 }
+
+func TestYAMLPrinterPrintStatus(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewYAMLPrinter(&buf)
+
+	err := p.PrintStatus(sandboxFixture())
+	require.NoError(t, err)
+
+	expOut := `id: 01234567890ABCDEFGHIJKLMNOP
+namespace: default
+name: my-sandbox
+status: running
+engine:
+  type: firecracker
+  root_fs: /images/rootfs.ext4
+  kernel_image: /images/vmlinux
+vcpus: 2
+memory_mb: 2048
+disk_gb: 10
+created_at: "2026-01-30T10:00:00Z"
+started_at: null
+stopped_at: null
+`
+	assert.Equal(t, expOut, buf.String())
+}
+
+func TestYAMLPrinterPrintList(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewYAMLPrinter(&buf)
+
+	err := p.PrintList([]model.Sandbox{sandboxFixture()})
+	require.NoError(t, err)
+
+	expOut := `- id: 01234567890ABCDEFGHIJKLMNOP
+  namespace: default
+  name: my-sandbox
+  status: running
+  created_at: "2026-01-30T10:00:00Z"
+`
+	assert.Equal(t, expOut, buf.String())
+}
+
+func TestTablePrinterPrintListNamespaces(t *testing.T) {
+	sb1 := sandboxFixture()
+	sb2 := sandboxFixture()
+	sb2.Namespace = "team-a"
+
+	// A single namespace doesn't show the namespace column.
+	var buf bytes.Buffer
+	err := printer.NewTablePrinter(&buf).PrintList([]model.Sandbox{sb1, sb1})
+	require.NoError(t, err)
+	assert.NotContains(t, buf.String(), "NAMESPACE")
+
+	buf.Reset()
+	err = printer.NewTablePrinter(&buf).PrintList([]model.Sandbox{sb1, sb2})
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "NAMESPACE  NAME"))
+	assert.True(t, strings.HasPrefix(lines[1], "default    my-sandbox"))
+	assert.True(t, strings.HasPrefix(lines[2], "team-a     my-sandbox"))
+}
+
+func TestPrinterPrintDataMoves(t *testing.T) {
+	moves := []model.DataMove{
+		{Kind: model.DataMoveKindVM, Name: "dev", From: "/home/u/.sbx/vms/01AB", To: "/fast/vms/01AB"},
+		{Kind: model.DataMoveKindSnapshot, Name: "my-snap", From: "/home/u/.sbx/images/my-snap", To: "/big/snapshots/my-snap"},
+	}
+
+	var buf bytes.Buffer
+	err := printer.NewTablePrinter(&buf).PrintDataMoves(moves)
+	require.NoError(t, err)
+	expTable := `KIND      NAME     FROM                         TO
+vm        dev      /home/u/.sbx/vms/01AB        /fast/vms/01AB
+snapshot  my-snap  /home/u/.sbx/images/my-snap  /big/snapshots/my-snap
+`
+	assert.Equal(t, expTable, buf.String())
+
+	buf.Reset()
+	err = printer.NewJSONPrinter(&buf).PrintDataMoves(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "[]\n", buf.String())
+}
+
+func TestPrinterPrintNamespaceList(t *testing.T) {
+	namespaces := []model.NamespaceUsage{
+		{Name: "default", Sandboxes: 3, RunningSandboxes: 1},
+		{Name: "team-a", Sandboxes: 1},
+	}
+
+	var buf bytes.Buffer
+	err := printer.NewTablePrinter(&buf).PrintNamespaceList(namespaces)
+	require.NoError(t, err)
+	expTable := `NAME     SANDBOXES  RUNNING
+default  3          1
+team-a   1          0
+`
+	assert.Equal(t, expTable, buf.String())
+
+	buf.Reset()
+	err = printer.NewYAMLPrinter(&buf).PrintNamespaceList(namespaces)
+	require.NoError(t, err)
+	expYAML := `- name: default
+  sandboxes: 3
+  running_sandboxes: 1
+- name: team-a
+  sandboxes: 1
+  running_sandboxes: 0
+`
+	assert.Equal(t, expYAML, buf.String())
+}
+
+func TestPrinterPrintReservations(t *testing.T) {
+	updatedAt := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	reservations := []model.Reservation{
+		{SandboxID: "01H2QWERTYASDFGZXCVBNMLKJH", Name: "dev", Status: model.SandboxStatusRunning, Resources: model.Resources{VCPUs: 2, MemoryMB: 2048, DiskGB: 10}, Reserved: model.Resources{VCPUs: 2, MemoryMB: 2048, DiskGB: 10}, UpdatedAt: updatedAt},
+		{SandboxID: "01H2QWERTYASDFGZXCVBNMLKJK", Name: "ci", Namespace: "team-a", Status: model.SandboxStatusStopped, Resources: model.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5}, Reserved: model.Resources{DiskGB: 5}, UpdatedAt: updatedAt},
+	}
+
+	var buf bytes.Buffer
+	err := printer.NewTablePrinter(&buf).PrintReservations(reservations)
+	require.NoError(t, err)
+	expTable := `NAME  NAMESPACE  ID                          STATUS   VCPUS  MEMORY   DISK
+dev              01H2QWERTYASDFGZXCVBNMLKJH  running  2      2048 MB  10 GB
+ci    team-a     01H2QWERTYASDFGZXCVBNMLKJK  stopped  0      0 MB     5 GB
+`
+	assert.Equal(t, expTable, buf.String())
+
+	buf.Reset()
+	err = printer.NewJSONPrinter(&buf).PrintReservations(reservations[1:])
+	require.NoError(t, err)
+	expJSON := `[
+  {
+    "sandbox_id": "01H2QWERTYASDFGZXCVBNMLKJK",
+    "name": "ci",
+    "namespace": "team-a",
+    "status": "stopped",
+    "resources": {
+      "vcpus": 1,
+      "memory_mb": 512,
+      "disk_gb": 5
+    },
+    "reserved": {
+      "vcpus": 0,
+      "memory_mb": 0,
+      "disk_gb": 5
+    },
+    "updated_at": "2026-01-30T10:00:00Z"
+  }
+]
+`
+	assert.Equal(t, expJSON, buf.String())
+}
+
+func TestJSONPrinterPrintExecResult(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewJSONPrinter(&buf)
+
+	startedAt := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	err := p.PrintExecResult(model.ExecResult{
+		ExitCode:    3,
+		StartedAt:   startedAt,
+		FinishedAt:  startedAt.Add(1500 * time.Millisecond),
+		Duration:    1500 * time.Millisecond,
+		StdoutBytes: 6,
+		Stdout:      "hello\n",
+	})
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, `"exit_code": 3`)
+	assert.Contains(t, out, `"duration_ms": 1500`)
+	assert.Contains(t, out, `"stdout_bytes": 6`)
+	assert.Contains(t, out, `"stdout": "hello\n"`)
+	assert.Contains(t, out, `"stdout_truncated": false`)
+	assert.NotContains(t, out, `"output_limit_exceeded"`)
+
+	buf.Reset()
+	require.NoError(t, p.PrintExecResult(model.ExecResult{ExitCode: -1, OutputLimitExceeded: true}))
+	assert.Contains(t, buf.String(), `"output_limit_exceeded": true`)
+}
+
+func execHistoryFixture() []model.ExecRecord {
+	startedAt := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	return []model.ExecRecord{
+		{ID: "rec-1", Command: []string{"echo", "hello"}, User: "dev", Caller: "cli", ExitCode: 3, StartedAt: startedAt, Duration: 1500 * time.Millisecond, StdoutBytes: 6, StdoutSHA256: "abc"},
+		{ID: "rec-2", Command: []string{"ls"}, User: "dev", Caller: "sdk", ExitCode: -1, Error: "connection refused", StartedAt: startedAt.Add(time.Minute)},
+	}
+}
+
+func TestTablePrinterPrintExecHistory(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewTablePrinter(&buf)
+
+	err := p.PrintExecHistory(execHistoryFixture())
+	require.NoError(t, err)
+
+	expOut := `STARTED                  DURATION  EXIT   USER  CALLER  COMMAND
+2026-01-30 10:00:00 UTC  1.5s      3      dev   cli     echo hello
+2026-01-30 10:01:00 UTC  0s        error  dev   sdk     ls
+`
+	assert.Equal(t, expOut, buf.String())
+}
+
+func TestJSONPrinterPrintExecHistory(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewJSONPrinter(&buf)
+
+	err := p.PrintExecHistory(execHistoryFixture())
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, `"command": [`)
+	assert.Contains(t, out, `"exit_code": 3`)
+	assert.Contains(t, out, `"duration_ms": 1500`)
+	assert.Contains(t, out, `"started_at": "2026-01-30T10:00:00Z"`)
+	assert.Contains(t, out, `"stdout_sha256": "abc"`)
+	assert.Contains(t, out, `"error": "connection refused"`)
+}
+
+func TestPrinterPrintChecks(t *testing.T) {
+	checks := []printer.EngineChecks{
+		{
+			Engine: "firecracker",
+			Results: []model.CheckResult{
+				{ID: "kvm_available", Status: model.CheckStatusOK, Message: "KVM is available"},
+				{ID: "ip_forward", Status: model.CheckStatusWarning, Message: "IP forwarding is disabled"},
+				{ID: "iptables", Status: model.CheckStatusError, Message: "iptables not found", Remediation: "Install iptables"},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		newPrinter func(w *bytes.Buffer) printer.Printer
+		expOut     []string
+	}{
+		"Table printer should print the checks with a summary.": {
+			newPrinter: func(w *bytes.Buffer) printer.Printer { return printer.NewTablePrinter(w) },
+			expOut: []string{
+				"Checking firecracker engine...",
+				"OK kvm_available",
+				"!! ip_forward",
+				"XX iptables",
+				"fix: Install iptables",
+				"1 error(s), 1 warning(s)",
+			},
+		},
+
+		"JSON printer should print the checks with the counts.": {
+			newPrinter: func(w *bytes.Buffer) printer.Printer { return printer.NewJSONPrinter(w) },
+			expOut: []string{
+				`"engine": "firecracker"`,
+				`"id": "iptables"`,
+				`"status": "warning"`,
+				`"severity": "warn"`,
+				`"severity": "fail"`,
+				`"remediation": "Install iptables"`,
+				`"errors": 1`,
+				`"warnings": 1`,
+			},
+		},
+
+		"YAML printer should print the checks with the counts.": {
+			newPrinter: func(w *bytes.Buffer) printer.Printer { return printer.NewYAMLPrinter(w) },
+			expOut: []string{
+				"- engine: firecracker",
+				"id: iptables",
+				"status: warning",
+				"severity: pass",
+				"remediation: Install iptables",
+				"errors: 1",
+				"warnings: 1",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := test.newPrinter(&buf).PrintChecks(checks)
+			require.NoError(t, err)
+
+			for _, exp := range test.expOut {
+				assert.Contains(t, buf.String(), exp)
+			}
+		})
+	}
+}
+
+func TestPrinterPrintError(t *testing.T) {
+	errInfo := printer.ErrorInfo{Message: "sandbox not found", Code: "not_found", ExitCode: 3}
+
+	tests := map[string]struct {
+		newPrinter func(w *bytes.Buffer) printer.Printer
+		expOut     string
+	}{
+		"Table printer should print the error message.": {
+			newPrinter: func(w *bytes.Buffer) printer.Printer { return printer.NewTablePrinter(w) },
+			expOut:     "Error: sandbox not found\n",
+		},
+
+		"JSON printer should print the error with its code.": {
+			newPrinter: func(w *bytes.Buffer) printer.Printer { return printer.NewJSONPrinter(w) },
+			expOut:     "{\n  \"error\": \"sandbox not found\",\n  \"code\": \"not_found\",\n  \"exit_code\": 3\n}\n",
+		},
+
+		"YAML printer should print the error with its code.": {
+			newPrinter: func(w *bytes.Buffer) printer.Printer { return printer.NewYAMLPrinter(w) },
+			expOut:     "error: sandbox not found\ncode: not_found\nexit_code: 3\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := test.newPrinter(&buf).PrintError(errInfo)
+			require.NoError(t, err)
+			assert.Equal(t, test.expOut, buf.String())
+		})
+	}
+}
+
+func hostCapacityFixture() model.HostCapacity {
+	return model.HostCapacity{
+		Total:            model.Resources{VCPUs: 8, MemoryMB: 16384, DiskGB: 100},
+		Allocated:        model.Resources{VCPUs: 2.5, MemoryMB: 2560, DiskGB: 35},
+		Overcommit:       model.OvercommitRatios{VCPUs: 4, Memory: 1, Disk: 1},
+		Sandboxes:        3,
+		RunningSandboxes: 2,
+	}
+}
+
+func TestTablePrinterPrintHostCapacity(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewTablePrinter(&buf)
+
+	err := p.PrintHostCapacity(hostCapacityFixture())
+	require.NoError(t, err)
+
+	expOut := `RESOURCE  TOTAL     OVERCOMMIT  ALLOCATABLE  ALLOCATED  FREE
+VCPUs     8         4           32           2.5        29.5
+Memory    16384 MB  1           16384 MB     2560 MB    13824 MB
+Disk      100 GB    1           100 GB       35 GB      65 GB
+
+Sandboxes:  3 (2 running)
+`
+	assert.Equal(t, expOut, buf.String())
+}
+
+func TestJSONPrinterPrintHostCapacity(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewJSONPrinter(&buf)
+
+	err := p.PrintHostCapacity(hostCapacityFixture())
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, `"allocatable": {
+    "vcpus": 32,`)
+	assert.Contains(t, out, `"memory_mb": 13824`)
+	assert.Contains(t, out, `"running_sandboxes": 2`)
+}
+
+func egressTestFixture() []model.EgressTestResult {
+	return []model.EgressTestResult{
+		{Target: "https://api.github.com/repos", Domain: "api.github.com", Action: model.EgressActionAllow, Reason: model.EgressTestReasonRule, RuleIndex: 1, Rule: &model.EgressRule{Domain: "*.github.com", Action: model.EgressActionAllow}},
+		{Target: "example.com", Domain: "example.com", Action: model.EgressActionDeny, Reason: model.EgressTestReasonDefault, RuleIndex: -1},
+		{Target: "10.0.0.1", Action: model.EgressActionDeny, Reason: model.EgressTestReasonIPAddress, RuleIndex: -1},
+	}
+}
+
+func TestTablePrinterPrintEgressTest(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewTablePrinter(&buf)
+
+	err := p.PrintEgressTest(egressTestFixture())
+	require.NoError(t, err)
+
+	expOut := `TARGET                        DOMAIN          ACTION  MATCH
+https://api.github.com/repos  api.github.com  allow   rule[1] *.github.com
+example.com                   example.com     deny    default
+10.0.0.1                      -               deny    ip-address
+`
+	assert.Equal(t, expOut, buf.String())
+}
+
+func TestJSONPrinterPrintEgressTest(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewJSONPrinter(&buf)
+
+	err := p.PrintEgressTest(egressTestFixture())
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, `"reason": "rule",
+    "rule_index": 1,
+    "rule": "*.github.com"`)
+	assert.Contains(t, out, `"target": "10.0.0.1",
+    "action": "deny",
+    "reason": "ip-address"
+  }`)
+}
+
+func TestYAMLPrinterPrintPolicyList(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewYAMLPrinter(&buf)
+
+	at := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	err := p.PrintPolicyList([]model.NamedEgressPolicy{{
+		Name: "github",
+		Policy: model.EgressPolicy{
+			Default: model.EgressActionDeny,
+			Rules:   []model.EgressRule{{Domain: "*.github.com", Action: model.EgressActionAllow}},
+		},
+		CreatedAt: at,
+		UpdatedAt: at.Add(time.Hour),
+	}})
+	require.NoError(t, err)
+
+	expOut := `- name: github
+  default: deny
+  rules:
+    - domain: '*.github.com'
+      action: allow
+  fail_closed: false
+  created_at: "2026-01-30T10:00:00Z"
+  updated_at: "2026-01-30T11:00:00Z"
+`
+	assert.Equal(t, expOut, buf.String())
+}
+
+func TestPrinterPrintTaskList(t *testing.T) {
+	at := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	tasks := []model.Task{
+		{Name: "deploy", SandboxID: "01SB", Command: []string{"make", "deploy"}, Env: map[string]string{"TARGET": "staging"}, CreatedAt: at, UpdatedAt: at},
+		{Name: "test", Command: []string{"go", "test", "./..."}, WorkingDir: "/workspace", Timeout: 10 * time.Minute, CreatedAt: at, UpdatedAt: at},
+	}
+
+	var buf bytes.Buffer
+	err := printer.NewTablePrinter(&buf).PrintTaskList(tasks)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "NAME    SCOPE    COMMAND        TIMEOUT  UPDATED"))
+	assert.True(t, strings.HasPrefix(lines[1], "deploy  sandbox  make deploy    -"))
+	assert.True(t, strings.HasPrefix(lines[2], "test    global   go test ./...  10m0s"))
+
+	buf.Reset()
+	err = printer.NewYAMLPrinter(&buf).PrintTaskList(tasks)
+	require.NoError(t, err)
+	expYAML := `- name: deploy
+  sandbox_id: 01SB
+  command:
+    - make
+    - deploy
+  env:
+    TARGET: staging
+  created_at: "2026-01-30T10:00:00Z"
+  updated_at: "2026-01-30T10:00:00Z"
+- name: test
+  command:
+    - go
+    - test
+    - ./...
+  working_dir: /workspace
+  timeout: 10m0s
+  created_at: "2026-01-30T10:00:00Z"
+  updated_at: "2026-01-30T10:00:00Z"
+`
+	assert.Equal(t, expYAML, buf.String())
+}
+
+func TestPrinterPrintTemplate(t *testing.T) {
+	at := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	template := model.SandboxTemplate{
+		Name:        "go-dev",
+		Description: "Go development",
+		Engine:      model.TemplateEngineFirecracker,
+		Image:       "v0.1.0",
+		Resources:   model.Resources{VCPUs: 2, MemoryMB: 2048, DiskGB: 10},
+		Ports:       map[string]int{"web": 8080},
+		Env:         map[string]string{"GOFLAGS": "-mod=mod"},
+		Egress:      &model.EgressPolicy{Default: model.EgressActionDeny, Rules: []model.EgressRule{{Domain: "proxy.golang.org", Action: model.EgressActionAllow}}},
+		CreatedAt:   at,
+		UpdatedAt:   at,
+	}
+
+	var buf bytes.Buffer
+	err := printer.NewTablePrinter(&buf).PrintTemplateList([]model.SandboxTemplate{template})
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], "NAME    ENGINE       IMAGE   VCPUS  MEM      DISK   DESCRIPTION     UPDATED"))
+	assert.True(t, strings.HasPrefix(lines[1], "go-dev  firecracker  v0.1.0  2.00   2048 MB  10 GB  Go development"))
+
+	buf.Reset()
+	err = printer.NewTablePrinter(&buf).PrintTemplate(template)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "Egress:     default deny, 1 rules\n")
+	assert.Contains(t, buf.String(), "Env:\n  GOFLAGS=-mod=mod\n")
+
+	buf.Reset()
+	err = printer.NewYAMLPrinter(&buf).PrintTemplate(template)
+	require.NoError(t, err)
+	expYAML := `name: go-dev
+description: Go development
+engine: firecracker
+image: v0.1.0
+vcpus: 2
+memory_mb: 2048
+disk_gb: 10
+user_data: false
+ports:
+  web: 8080
+env:
+  GOFLAGS: -mod=mod
+egress:
+  default: deny
+  rules:
+    - domain: proxy.golang.org
+      action: allow
+  fail_closed: false
+created_at: "2026-01-30T10:00:00Z"
+updated_at: "2026-01-30T10:00:00Z"
+`
+	assert.Equal(t, expYAML, buf.String())
+}
+
+func dnsEventsFixture() []model.DNSEvent {
+	at := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	return []model.DNSEvent{
+		{Time: at, Domain: "github.com", QType: "A", Action: model.EgressActionAllow, Rcode: "NOERROR", UpstreamLatency: 12345 * time.Microsecond},
+		{Time: at.Add(time.Second), Domain: "evil.com", QType: "AAAA", Action: model.EgressActionDeny, Reason: model.DNSEventReasonRuleMatch, Rcode: "REFUSED"},
+	}
+}
+
+func TestTablePrinterPrintDNSEvents(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewTablePrinter(&buf)
+
+	err := p.PrintDNSEvents(dnsEventsFixture())
+	require.NoError(t, err)
+
+	expOut := `TIME                     DOMAIN      TYPE  ACTION  RCODE    LATENCY  REASON
+2026-01-30 10:00:00 UTC  github.com  A     allow   NOERROR  12.3ms   -
+2026-01-30 10:00:01 UTC  evil.com    AAAA  deny    REFUSED  -        rule-match
+`
+	assert.Equal(t, expOut, buf.String())
+}
+
+func TestJSONPrinterPrintDNSEvents(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewJSONPrinter(&buf)
+
+	err := p.PrintDNSEvents(dnsEventsFixture())
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, `"rcode": "NOERROR",
+    "upstream_latency_ms": 12.345
+  }`)
+	assert.Contains(t, out, `"action": "deny",
+    "reason": "rule-match",
+    "rcode": "REFUSED",
+    "upstream_latency_ms": 0`)
+}
+
+func TestYAMLPrinterPrintDNSStats(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewYAMLPrinter(&buf)
+
+	at := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	err := p.PrintDNSStats(model.DNSStats{
+		Queries: 3,
+		Allowed: 1,
+		Denied:  1,
+		Failed:  1,
+		Domains: []model.DNSDomainStats{{
+			Domain:             "github.com",
+			Queries:            3,
+			Allowed:            1,
+			Denied:             1,
+			Failed:             1,
+			AvgUpstreamLatency: 1500 * time.Microsecond,
+			MaxUpstreamLatency: 2 * time.Millisecond,
+			LastQueryAt:        at,
+		}},
+	})
+	require.NoError(t, err)
+
+	expOut := `queries: 3
+allowed: 1
+denied: 1
+failed: 1
+domains:
+  - domain: github.com
+    queries: 3
+    allowed: 1
+    denied: 1
+    failed: 1
+    avg_upstream_latency_ms: 1.5
+    max_upstream_latency_ms: 2
+    last_query_at: "2026-01-30T10:00:00Z"
+`
+	assert.Equal(t, expOut, buf.String())
+}
+
+func TestTablePrinterPrintDNSStatsEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewTablePrinter(&buf)
+
+	err := p.PrintDNSStats(model.DNSStats{})
+	require.NoError(t, err)
+	assert.Equal(t, "Queries:  0 (0 allowed, 0 denied, 0 failed)\n", buf.String())
+}
+
+func usageReportFixture() model.UsageReport {
+	return model.UsageReport{
+		Since: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Until: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+		Entries: []model.UsageReportEntry{
+			{Key: "ci-1", SandboxID: "id-1", Removed: true, Sandboxes: 1, Usage: model.Usage{CPUSeconds: 7200, MemoryMBHours: 1024, DiskGBHours: 20}},
+			{Key: "dev", SandboxID: "id-2", Sandboxes: 1, Usage: model.Usage{CPUSeconds: 3600, MemoryMBHours: 512.5, DiskGBHours: 10}},
+		},
+		Total: model.Usage{CPUSeconds: 10800, MemoryMBHours: 1536.5, DiskGBHours: 30},
+	}
+}
+
+func TestTablePrinterPrintUsageReport(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewTablePrinter(&buf)
+
+	err := p.PrintUsageReport(usageReportFixture())
+	require.NoError(t, err)
+
+	expOut := `NAME  ID    REMOVED  CPU SECONDS  MEMORY MB-HOURS  DISK GB-HOURS
+ci-1  id-1  true     7200         1024.00          20.00
+dev   id-2  false    3600         512.50           10.00
+
+Period:  2026-01-01 00:00:00 UTC - 2026-02-01 00:00:00 UTC
+Total:   10800 CPU seconds, 1536.50 memory MB-hours, 30.00 disk GB-hours
+`
+	assert.Equal(t, expOut, buf.String())
+}
+
+func TestJSONPrinterPrintUsageReport(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewJSONPrinter(&buf)
+
+	report := usageReportFixture()
+	report.GroupBy = "team"
+	report.Entries = []model.UsageReportEntry{{Key: "infra", Sandboxes: 2, Usage: report.Total}}
+	err := p.PrintUsageReport(report)
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, `"since": "2026-01-01T00:00:00Z"`)
+	assert.Contains(t, out, `"group_by": "team"`)
+	assert.Contains(t, out, `"group": "infra",
+      "sandboxes": 2,
+      "cpu_seconds": 10800,`)
+	assert.NotContains(t, out, `"name"`)
+}
+
+func TestPrintUsageReportCSV(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := printer.PrintUsageReportCSV(&buf, usageReportFixture())
+	require.NoError(t, err)
+
+	expOut := `name,id,removed,cpu_seconds,memory_mb_hours,disk_gb_hours
+ci-1,id-1,true,7200,1024,20
+dev,id-2,false,3600,512.5,10
+`
+	assert.Equal(t, expOut, buf.String())
+}
+
+func processTableFixture() model.ProcessTable {
+	return model.ProcessTable{
+		SandboxID:            "id-1",
+		SandboxName:          "dev",
+		CollectedAt:          time.Date(2026, 1, 1, 0, 1, 40, 0, time.UTC),
+		Uptime:               100500 * time.Millisecond,
+		Load1:                0.5,
+		Load5:                0.25,
+		Load15:               0.1,
+		MemoryTotalBytes:     1024 * 1024 * 1024,
+		MemoryAvailableBytes: 768 * 1024 * 1024,
+		Processes: []model.Process{
+			{PID: 42, PPID: 1, User: "app", State: "R", Command: "python3 app.py", CPUTime: 75250 * time.Millisecond, CPUPercent: 55.555, RSSBytes: 20 * 1024 * 1024, MemoryPercent: 1.953, Threads: 4, StartedAt: time.Date(2026, 1, 1, 0, 0, 10, 0, time.UTC)},
+			{PID: 2, PPID: 0, User: "root", State: "S", Command: "[kthreadd]", Threads: 1, StartedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		},
+	}
+}
+
+func TestTablePrinterPrintProcessTable(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewTablePrinter(&buf)
+
+	err := p.PrintProcessTable(processTableFixture())
+	require.NoError(t, err)
+
+	expOut := `Sandbox:  dev  up 1m40s  load average: 0.50, 0.25, 0.10
+Memory:   1.0 GB total, 768.0 MB available
+Tasks:    2
+
+PID  USER  STATE  %CPU  %MEM  RSS      THREADS  TIME     COMMAND
+42   app   R      55.6  2.0   20.0 MB  4        1:15.25  python3 app.py
+2    root  S      0.0   0.0   0 B      1        0:00.00  [kthreadd]
+`
+	assert.Equal(t, expOut, buf.String())
+}
+
+func TestJSONPrinterPrintProcessTable(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewJSONPrinter(&buf)
+
+	err := p.PrintProcessTable(processTableFixture())
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, `"sandbox_name": "dev"`)
+	assert.Contains(t, out, `"uptime_seconds": 100.5`)
+	assert.Contains(t, out, `"pid": 42,
+      "ppid": 1,
+      "user": "app",
+      "state": "R",
+      "command": "python3 app.py",
+      "cpu_seconds": 75.25,
+      "cpu_percent": 55.56,
+      "rss_bytes": 20971520,
+      "memory_percent": 1.95,
+      "threads": 4,
+      "started_at": "2026-01-01T00:00:10Z"`)
+}
+
+func connectionsFixture() []model.Connection {
+	return []model.Connection{
+		{Protocol: "tcp", Direction: model.ConnectionDirectionEgress, Interface: "eth0", Source: "10.68.40.2:40000", Destination: "140.82.121.4:443", Domain: "github.com", State: "ESTABLISHED", Proxy: "tls", BytesSent: 2048, BytesReceived: 5 * 1024 * 1024},
+		{Protocol: "icmp", Direction: model.ConnectionDirectionEgress, Interface: "eth0", Source: "10.68.40.2", Destination: "8.8.8.8", BytesSent: 84, BytesReceived: 84},
+	}
+}
+
+func TestTablePrinterPrintConnections(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewTablePrinter(&buf)
+
+	err := p.PrintConnections(connectionsFixture())
+	require.NoError(t, err)
+
+	expOut := `PROTO  DIRECTION  IFACE  SOURCE            DESTINATION       DOMAIN      STATE        PROXY  SENT    RECEIVED
+tcp    egress     eth0   10.68.40.2:40000  140.82.121.4:443  github.com  ESTABLISHED  tls    2.0 KB  5.0 MB
+icmp   egress     eth0   10.68.40.2        8.8.8.8           -           -            -      84 B    84 B
+`
+	assert.Equal(t, expOut, buf.String())
+}
+
+func TestJSONPrinterPrintConnections(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.NewJSONPrinter(&buf)
+
+	err := p.PrintConnections(connectionsFixture())
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, `"protocol": "tcp",
+    "direction": "egress",
+    "interface": "eth0",
+    "source": "10.68.40.2:40000",
+    "destination": "140.82.121.4:443",
+    "domain": "github.com",
+    "state": "ESTABLISHED",
+    "proxy": "tls",
+    "bytes_sent": 2048,
+    "bytes_received": 5242880`)
+	assert.Contains(t, out, `"destination": "8.8.8.8",
+    "bytes_sent": 84,`)
+}
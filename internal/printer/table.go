@@ -3,7 +3,12 @@ package printer
 import (
 	"fmt"
 	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/slok/sbx/internal/model"
 )
@@ -27,11 +32,20 @@ func (t *TablePrinter) PrintList(sandboxes []model.Sandbox) error {
 	tw := tabwriter.NewWriter(t.writer, 0, 0, 2, ' ', 0)
 	defer tw.Flush()
 
+	// The namespace is only shown when listing the sandboxes of several namespaces.
+	multiNamespace := slices.ContainsFunc(sandboxes, func(s model.Sandbox) bool { return s.Namespace != sandboxes[0].Namespace })
+
 	// Print header
+	if multiNamespace {
+		fmt.Fprint(tw, "NAMESPACE\t")
+	}
 	fmt.Fprintln(tw, "NAME\tSTATUS\tCREATED")
 
 	// Print rows
 	for _, s := range sandboxes {
+		if multiNamespace {
+			fmt.Fprintf(tw, "%s\t", s.Namespace)
+		}
 		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Name, s.Status, TimeAgo(s.CreatedAt))
 	}
 
@@ -42,17 +56,43 @@ func (t *TablePrinter) PrintList(sandboxes []model.Sandbox) error {
 func (t *TablePrinter) PrintStatus(sandbox model.Sandbox) error {
 	fmt.Fprintf(t.writer, "Name:       %s\n", sandbox.Name)
 	fmt.Fprintf(t.writer, "ID:         %s\n", sandbox.ID)
+	if sandbox.Namespace != "" {
+		fmt.Fprintf(t.writer, "Namespace:  %s\n", sandbox.Namespace)
+	}
 	fmt.Fprintf(t.writer, "Status:     %s\n", sandbox.Status)
+	if sandbox.QuarantinedAt != nil {
+		fmt.Fprintf(t.writer, "Quarantine: since %s\n", FormatTimestamp(*sandbox.QuarantinedAt))
+	}
+	if sandbox.Persistent {
+		fmt.Fprintf(t.writer, "Persistent: yes (started again by 'sbx recover')\n")
+	}
 
 	// Print engine-specific info
 	if sandbox.Config.FirecrackerEngine != nil {
 		fmt.Fprintf(t.writer, "Engine:     firecracker\n")
 		fmt.Fprintf(t.writer, "RootFS:     %s\n", sandbox.Config.FirecrackerEngine.RootFS)
+		if sandbox.RootFSStrategy != "" {
+			fmt.Fprintf(t.writer, "Provision:  %s\n", sandbox.RootFSStrategy)
+		}
 		fmt.Fprintf(t.writer, "Kernel:     %s\n", sandbox.Config.FirecrackerEngine.KernelImage)
+		fmt.Fprintf(t.writer, "Seccomp:    %s\n", seccompDescription(sandbox.Config.FirecrackerEngine.Seccomp, sandbox.Seccomp))
+		if sandbox.Config.FirecrackerEngine.EgressInterface != "" {
+			fmt.Fprintf(t.writer, "Uplink:     %s\n", sandbox.Config.FirecrackerEngine.EgressInterface)
+		}
+		if network := sandbox.Config.FirecrackerEngine.Network; network != (model.NetworkOptions{}) {
+			fmt.Fprintf(t.writer, "Network:    %s\n", networkOptionsDescription(network))
+		}
 	}
 
-	fmt.Fprintf(t.writer, "VCPUs:      %.2f\n", sandbox.Config.Resources.VCPUs)
-	fmt.Fprintf(t.writer, "Memory:     %d MB\n", sandbox.Config.Resources.MemoryMB)
+	fmt.Fprintf(t.writer, "VCPUs:      %.2f", sandbox.Config.Resources.VCPUs)
+	if sandbox.Config.Resources.MaxVCPUs > 0 {
+		fmt.Fprintf(t.writer, " (max %.2f)", sandbox.Config.Resources.MaxVCPUs)
+	}
+	fmt.Fprintf(t.writer, "\nMemory:     %d MB", sandbox.Config.Resources.MemoryMB)
+	if sandbox.Config.Resources.MaxMemoryMB > 0 {
+		fmt.Fprintf(t.writer, " (max %d MB)", sandbox.Config.Resources.MaxMemoryMB)
+	}
+	fmt.Fprintln(t.writer)
 	fmt.Fprintf(t.writer, "Disk:       %d GB\n", sandbox.Config.Resources.DiskGB)
 	fmt.Fprintf(t.writer, "Created:    %s\n", FormatTimestamp(sandbox.CreatedAt))
 
@@ -64,6 +104,85 @@ func (t *TablePrinter) PrintStatus(sandbox model.Sandbox) error {
 		fmt.Fprintf(t.writer, "Stopped:    %s\n", FormatTimestamp(*sandbox.StoppedAt))
 	}
 
+	if len(sandbox.Config.Ports) > 0 {
+		fmt.Fprintf(t.writer, "Ports:\n")
+		for _, name := range slices.Sorted(maps.Keys(sandbox.Config.Ports)) {
+			fmt.Fprintf(t.writer, "  %s: %d\n", name, sandbox.Config.Ports[name])
+		}
+	}
+
+	if p := sandbox.Config.ExecProfile; p != nil {
+		fmt.Fprintf(t.writer, "Exec profile:\n")
+		if len(p.PathPrepend) > 0 {
+			fmt.Fprintf(t.writer, "  path: %s\n", strings.Join(p.PathPrepend, ":"))
+		}
+		if p.Locale != "" {
+			fmt.Fprintf(t.writer, "  locale: %s\n", p.Locale)
+		}
+		if p.Umask != "" {
+			fmt.Fprintf(t.writer, "  umask: %s\n", p.Umask)
+		}
+		for _, name := range slices.Sorted(maps.Keys(p.Ulimits)) {
+			fmt.Fprintf(t.writer, "  ulimit %s: %s\n", name, p.Ulimits[name])
+		}
+		if p.Shell != "" {
+			fmt.Fprintf(t.writer, "  shell: %s\n", p.Shell)
+		}
+	}
+
+	if sandbox.IdlePolicy != nil {
+		fmt.Fprintf(t.writer, "Idle:       %s after %s\n", sandbox.IdlePolicy.Action, sandbox.IdlePolicy.Timeout)
+	}
+
+	if !sandbox.Activity.LastActivityAt.IsZero() {
+		fmt.Fprintf(t.writer, "Activity:   %s\n", FormatTimestamp(sandbox.Activity.LastActivityAt))
+	}
+
+	if sandbox.Egress != nil {
+		fmt.Fprintf(t.writer, "Egress:     %s (restarts: %d)\n", sandbox.Egress.Health, sandbox.Egress.Restarts)
+		if sandbox.Egress.LastError != "" {
+			fmt.Fprintf(t.writer, "Egress err: %s\n", sandbox.Egress.LastError)
+		}
+	}
+
+	if sandbox.Session != nil && !sandbox.Session.IsZero() {
+		session := sandbox.Session.Redacted()
+		fmt.Fprintf(t.writer, "Session:\n")
+		for _, k := range slices.Sorted(maps.Keys(session.Env)) {
+			fmt.Fprintf(t.writer, "  env %s: %s\n", k, session.Env[k])
+		}
+		if session.EgressPolicyName != "" {
+			fmt.Fprintf(t.writer, "  egress policy: %s\n", session.EgressPolicyName)
+		}
+		if e := session.Egress; e != nil {
+			fmt.Fprintf(t.writer, "  egress: default %s, %d rules\n", e.Default, len(e.Rules))
+		}
+		if len(session.IntegrityPaths) > 0 {
+			fmt.Fprintf(t.writer, "  integrity paths: %s\n", strings.Join(session.IntegrityPaths, ", "))
+		}
+	}
+
+	if len(sandbox.LastBootPhases) > 0 {
+		fmt.Fprintf(t.writer, "Last boot:\n")
+		for _, p := range sandbox.LastBootPhases {
+			fmt.Fprintf(t.writer, "  %s: %s", p.Name, p.Duration.Round(time.Millisecond))
+			if p.Error != "" {
+				fmt.Fprintf(t.writer, " (failed: %s)", p.Error)
+			}
+			fmt.Fprintln(t.writer)
+		}
+	}
+	if sandbox.LastError != "" {
+		fmt.Fprintf(t.writer, "Last error: %s\n", sandbox.LastError)
+	}
+
+	if len(sandbox.Annotations) > 0 {
+		fmt.Fprintf(t.writer, "Annotations:\n")
+		for _, k := range slices.Sorted(maps.Keys(sandbox.Annotations)) {
+			fmt.Fprintf(t.writer, "  %s: %s\n", k, sandbox.Annotations[k])
+		}
+	}
+
 	return nil
 }
 
@@ -119,14 +238,617 @@ func (t *TablePrinter) PrintImageInspect(manifest model.ImageManifest) error {
 		if manifest.Snapshot.ParentSnapshot != "" {
 			fmt.Fprintf(t.writer, "  Parent:     %s\n", manifest.Snapshot.ParentSnapshot)
 		}
+		if manifest.Snapshot.MemoryFile != "" {
+			fmt.Fprintf(t.writer, "  Memory:     %s (state: %s)\n", manifest.Snapshot.MemoryFile, manifest.Snapshot.VMStateFile)
+		}
 		fmt.Fprintf(t.writer, "  Created:    %s\n", FormatTimestamp(manifest.Snapshot.CreatedAt))
 	}
 
+	if manifest.Local != nil {
+		fmt.Fprintf(t.writer, "\nLocal:\n")
+		fmt.Fprintf(t.writer, "  Kernel:     %s\n", manifest.Local.KernelSource)
+		fmt.Fprintf(t.writer, "  Rootfs:     %s\n", manifest.Local.RootFSSource)
+		fmt.Fprintf(t.writer, "  Added:      %s\n", FormatTimestamp(manifest.Local.AddedAt))
+	}
+
+	if manifest.Customized != nil {
+		fmt.Fprintf(t.writer, "\nCustomized:\n")
+		fmt.Fprintf(t.writer, "  Base:       %s\n", manifest.Customized.BaseImage)
+		for _, f := range manifest.Customized.Files {
+			fmt.Fprintf(t.writer, "  File:       %s\n", f)
+		}
+		for _, cmd := range manifest.Customized.Commands {
+			fmt.Fprintf(t.writer, "  Command:    %s\n", cmd)
+		}
+		fmt.Fprintf(t.writer, "  Created:    %s\n", FormatTimestamp(manifest.Customized.CreatedAt))
+	}
+
+	fmt.Fprintf(t.writer, "\nKernel profiles:\n")
+	for _, p := range manifest.KernelProfiles() {
+		fmt.Fprintf(t.writer, "  %-11s %s\n", p.Name+":", p.Description)
+		if len(p.KernelArgs) > 0 {
+			fmt.Fprintf(t.writer, "  %-11s %s\n", "", strings.Join(p.KernelArgs, " "))
+		}
+	}
+
+	return nil
+}
+
+// PrintImageDiskUsage prints the images disk usage in a table format with the totals.
+func (t *TablePrinter) PrintImageDiskUsage(usage model.ImageDiskUsage) error {
+	if len(usage.Images) > 0 {
+		tw := tabwriter.NewWriter(t.writer, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "IMAGE\tSIZE\tSHARED")
+		for _, img := range usage.Images {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", img.Name, FormatBytes(img.SizeBytes), FormatBytes(img.SharedBytes))
+		}
+		tw.Flush()
+		fmt.Fprintln(t.writer)
+	}
+
+	fmt.Fprintf(t.writer, "Total:      %s\n", FormatBytes(usage.TotalBytes))
+	fmt.Fprintf(t.writer, "On disk:    %s\n", FormatBytes(usage.DiskBytes))
+	fmt.Fprintf(t.writer, "Reclaimed:  %s\n", FormatBytes(usage.TotalBytes-usage.DiskBytes))
+	return nil
+}
+
+// PrintImageDiff prints the files changed between two images in a table format
+// with the totals. The size and digest are the ones of the newer file, or of the
+// older one for the removed files.
+func (t *TablePrinter) PrintImageDiff(diff model.ImageDiff) error {
+	if len(diff.Changes) == 0 {
+		fmt.Fprintf(t.writer, "No changes from %s to %s\n", diff.From, diff.To)
+		return nil
+	}
+
+	counts := map[model.FileChangeKind]int{}
+	tw := tabwriter.NewWriter(t.writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHANGE\tPATH\tTYPE\tSIZE\tSHA256")
+	for _, c := range diff.Changes {
+		counts[c.Kind]++
+		state := c.New
+		if state == nil {
+			state = c.Old
+		}
+		size, digest := "-", "-"
+		if state.Type == model.FileTypeRegular {
+			size = FormatBytes(state.SizeBytes)
+			digest = state.SHA256
+			if len(digest) > 12 {
+				digest = digest[:12]
+			}
+		}
+		path := c.Path
+		if state.Type == model.FileTypeSymlink {
+			path += " -> " + state.LinkTarget
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", c.Kind, path, state.Type, size, digest)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(t.writer, "\n%s -> %s: %d added, %d modified, %d removed\n", diff.From, diff.To,
+		counts[model.FileChangeAdded], counts[model.FileChangeModified], counts[model.FileChangeRemoved])
+	return nil
+}
+
+// PrintIntegrityReport prints the sandbox files changed since the integrity baseline
+// in a table format.
+func (t *TablePrinter) PrintIntegrityReport(r model.IntegrityReport) error {
+	if r.Intact() {
+		fmt.Fprintf(t.writer, "No changes in %d files of %s, baseline captured %s\n", r.Files, r.SandboxName, TimeAgo(r.BaselineAt))
+		return nil
+	}
+
+	counts := map[model.FileChangeKind]int{}
+	tw := tabwriter.NewWriter(t.writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHANGE\tPATH\tSHA256")
+	for _, c := range r.Changes {
+		counts[c.Kind]++
+		digest := c.NewSHA256
+		if digest == "" {
+			digest = c.OldSHA256
+		}
+		if len(digest) > 12 {
+			digest = digest[:12]
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", c.Kind, c.Path, digest)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(t.writer, "\n%s: %d added, %d modified, %d removed, baseline captured %s\n", r.SandboxName,
+		counts[model.FileChangeAdded], counts[model.FileChangeModified], counts[model.FileChangeRemoved], TimeAgo(r.BaselineAt))
+	return nil
+}
+
+// PrintHostCapacity prints the host capacity and its allocation in a table format.
+func (t *TablePrinter) PrintHostCapacity(c model.HostCapacity) error {
+	allocatable, free := c.Allocatable(), c.Free()
+
+	tw := tabwriter.NewWriter(t.writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RESOURCE\tTOTAL\tOVERCOMMIT\tALLOCATABLE\tALLOCATED\tFREE")
+	fmt.Fprintf(tw, "VCPUs\t%g\t%g\t%g\t%g\t%g\n", c.Total.VCPUs, c.Overcommit.VCPUs, allocatable.VCPUs, c.Allocated.VCPUs, free.VCPUs)
+	fmt.Fprintf(tw, "Memory\t%d MB\t%g\t%d MB\t%d MB\t%d MB\n", c.Total.MemoryMB, c.Overcommit.Memory, allocatable.MemoryMB, c.Allocated.MemoryMB, free.MemoryMB)
+	fmt.Fprintf(tw, "Disk\t%d GB\t%g\t%d GB\t%d GB\t%d GB\n", c.Total.DiskGB, c.Overcommit.Disk, allocatable.DiskGB, c.Allocated.DiskGB, free.DiskGB)
+	tw.Flush()
+
+	fmt.Fprintf(t.writer, "\nSandboxes:  %d (%d running)\n", c.Sandboxes, c.RunningSandboxes)
+	return nil
+}
+
+// PrintEgressTest prints the egress policy test results in a table format.
+func (t *TablePrinter) PrintEgressTest(results []model.EgressTestResult) error {
+	tw := tabwriter.NewWriter(t.writer, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "TARGET\tDOMAIN\tACTION\tMATCH")
+	for _, r := range results {
+		domain := r.Domain
+		if domain == "" {
+			domain = "-"
+		}
+		match := string(r.Reason)
+		switch {
+		case r.Rule != nil && r.RuleIndex >= 0:
+			match = fmt.Sprintf("rule[%d] %s", r.RuleIndex, r.Rule.Domain)
+		case r.Rule != nil:
+			match = fmt.Sprintf("%s %s", r.Reason, r.Rule.Domain)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Target, domain, r.Action, match)
+	}
+
+	return nil
+}
+
+// PrintPolicyList prints the named egress policies in a table format.
+func (t *TablePrinter) PrintPolicyList(policies []model.NamedEgressPolicy) error {
+	if len(policies) == 0 {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(t.writer, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "NAME\tDEFAULT\tRULES\tFAIL-CLOSED\tUPDATED")
+	for _, p := range policies {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%t\t%s\n", p.Name, p.Policy.Default, len(p.Policy.Rules), p.Policy.FailClosed, TimeAgo(p.UpdatedAt))
+	}
+
+	return nil
+}
+
+// PrintTaskList prints the tasks in a table format.
+func (t *TablePrinter) PrintTaskList(tasks []model.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(t.writer, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "NAME\tSCOPE\tCOMMAND\tTIMEOUT\tUPDATED")
+	for _, task := range tasks {
+		scope := "global"
+		if task.SandboxID != "" {
+			scope = "sandbox"
+		}
+		timeout := "-"
+		if task.Timeout > 0 {
+			timeout = task.Timeout.String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", task.Name, scope, strings.Join(task.Command, " "), timeout, TimeAgo(task.UpdatedAt))
+	}
+
+	return nil
+}
+
+// PrintTemplateList prints the sandbox templates in a table format.
+func (t *TablePrinter) PrintTemplateList(templates []model.SandboxTemplate) error {
+	if len(templates) == 0 {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(t.writer, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "NAME\tENGINE\tIMAGE\tVCPUS\tMEM\tDISK\tDESCRIPTION\tUPDATED")
+	for _, tpl := range templates {
+		image := tpl.Image
+		if image == "" {
+			image = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%.2f\t%d MB\t%d GB\t%s\t%s\n", tpl.Name, tpl.Engine, image, tpl.Resources.VCPUs, tpl.Resources.MemoryMB, tpl.Resources.DiskGB, tpl.Description, TimeAgo(tpl.UpdatedAt))
+	}
+
+	return nil
+}
+
+// PrintTemplate prints a sandbox template in a table format.
+func (t *TablePrinter) PrintTemplate(template model.SandboxTemplate) error {
+	fmt.Fprintf(t.writer, "Name:       %s\n", template.Name)
+	if template.Description != "" {
+		fmt.Fprintf(t.writer, "Desc:       %s\n", template.Description)
+	}
+	fmt.Fprintf(t.writer, "Engine:     %s\n", template.Engine)
+	if template.Image != "" {
+		fmt.Fprintf(t.writer, "Image:      %s\n", template.Image)
+	}
+	if template.Profile != "" {
+		fmt.Fprintf(t.writer, "Profile:    %s\n", template.Profile)
+	}
+
+	fmt.Fprintf(t.writer, "VCPUs:      %.2f", template.Resources.VCPUs)
+	if template.Resources.MaxVCPUs > 0 {
+		fmt.Fprintf(t.writer, " (max %.2f)", template.Resources.MaxVCPUs)
+	}
+	fmt.Fprintf(t.writer, "\nMemory:     %d MB", template.Resources.MemoryMB)
+	if template.Resources.MaxMemoryMB > 0 {
+		fmt.Fprintf(t.writer, " (max %d MB)", template.Resources.MaxMemoryMB)
+	}
+	fmt.Fprintln(t.writer)
+	fmt.Fprintf(t.writer, "Disk:       %d GB\n", template.Resources.DiskGB)
+	if template.UserData != "" {
+		fmt.Fprintf(t.writer, "User data:  %d bytes\n", len(template.UserData))
+	}
+
+	switch {
+	case template.Egress != nil:
+		fmt.Fprintf(t.writer, "Egress:     default %s, %d rules\n", template.Egress.Default, len(template.Egress.Rules))
+	case template.EgressPolicyName != "":
+		fmt.Fprintf(t.writer, "Egress:     policy %s\n", template.EgressPolicyName)
+	}
+	fmt.Fprintf(t.writer, "Created:    %s\n", FormatTimestamp(template.CreatedAt))
+
+	if len(template.Ports) > 0 {
+		fmt.Fprintf(t.writer, "Ports:\n")
+		for _, name := range slices.Sorted(maps.Keys(template.Ports)) {
+			fmt.Fprintf(t.writer, "  %s: %d\n", name, template.Ports[name])
+		}
+	}
+
+	if p := template.ExecProfile; p != nil {
+		fmt.Fprintf(t.writer, "Exec profile:\n")
+		if len(p.PathPrepend) > 0 {
+			fmt.Fprintf(t.writer, "  path: %s\n", strings.Join(p.PathPrepend, ":"))
+		}
+		if p.Locale != "" {
+			fmt.Fprintf(t.writer, "  locale: %s\n", p.Locale)
+		}
+		if p.Umask != "" {
+			fmt.Fprintf(t.writer, "  umask: %s\n", p.Umask)
+		}
+		for _, name := range slices.Sorted(maps.Keys(p.Ulimits)) {
+			fmt.Fprintf(t.writer, "  ulimit %s: %s\n", name, p.Ulimits[name])
+		}
+		if p.Shell != "" {
+			fmt.Fprintf(t.writer, "  shell: %s\n", p.Shell)
+		}
+	}
+
+	if len(template.Env) > 0 {
+		fmt.Fprintf(t.writer, "Env:\n")
+		for _, k := range slices.Sorted(maps.Keys(template.Env)) {
+			fmt.Fprintf(t.writer, "  %s=%s\n", k, template.Env[k])
+		}
+	}
+
 	return nil
 }
 
+// PrintReservations prints the sandbox resource reservations in a table format.
+func (t *TablePrinter) PrintReservations(reservations []model.Reservation) error {
+	if len(reservations) == 0 {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(t.writer, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "NAME\tNAMESPACE\tID\tSTATUS\tVCPUS\tMEMORY\tDISK")
+	for _, r := range reservations {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%g\t%d MB\t%d GB\n", r.Name, r.Namespace, r.SandboxID, r.Status, r.Reserved.VCPUs, r.Reserved.MemoryMB, r.Reserved.DiskGB)
+	}
+
+	return nil
+}
+
+// PrintNamespaceList prints the namespaces in a table format.
+func (t *TablePrinter) PrintNamespaceList(namespaces []model.NamespaceUsage) error {
+	if len(namespaces) == 0 {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(t.writer, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "NAME\tSANDBOXES\tRUNNING")
+	for _, ns := range namespaces {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", ns.Name, ns.Sandboxes, ns.RunningSandboxes)
+	}
+
+	return nil
+}
+
+// PrintDataMoves prints the data layout migration moves in a table format.
+func (t *TablePrinter) PrintDataMoves(moves []model.DataMove) error {
+	if len(moves) == 0 {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(t.writer, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "KIND\tNAME\tFROM\tTO")
+	for _, m := range moves {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", m.Kind, m.Name, m.From, m.To)
+	}
+
+	return nil
+}
+
+// PrintDNSEvents prints the DNS queries in a table format.
+func (t *TablePrinter) PrintDNSEvents(events []model.DNSEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(t.writer, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "TIME\tDOMAIN\tTYPE\tACTION\tRCODE\tLATENCY\tREASON")
+	for _, e := range events {
+		reason := string(e.Reason)
+		if reason == "" {
+			reason = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			FormatTimestamp(e.Time), e.Domain, e.QType, e.Action, e.Rcode, formatLatency(e.UpstreamLatency), reason)
+	}
+
+	return nil
+}
+
+// PrintDNSStats prints the aggregated DNS queries in a table format.
+func (t *TablePrinter) PrintDNSStats(stats model.DNSStats) error {
+	if len(stats.Domains) > 0 {
+		tw := tabwriter.NewWriter(t.writer, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "DOMAIN\tQUERIES\tALLOWED\tDENIED\tFAILED\tAVG LATENCY\tMAX LATENCY\tLAST QUERY")
+		for _, d := range stats.Domains {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%s\t%s\t%s\n",
+				d.Domain, d.Queries, d.Allowed, d.Denied, d.Failed, formatLatency(d.AvgUpstreamLatency), formatLatency(d.MaxUpstreamLatency), TimeAgo(d.LastQueryAt))
+		}
+		tw.Flush()
+		fmt.Fprintln(t.writer)
+	}
+
+	fmt.Fprintf(t.writer, "Queries:  %d (%d allowed, %d denied, %d failed)\n", stats.Queries, stats.Allowed, stats.Denied, stats.Failed)
+	return nil
+}
+
+// PrintUsageReport prints the resources used by the sandboxes in a table format.
+func (t *TablePrinter) PrintUsageReport(report model.UsageReport) error {
+	if len(report.Entries) > 0 {
+		tw := tabwriter.NewWriter(t.writer, 0, 0, 2, ' ', 0)
+		if report.GroupBy != "" {
+			fmt.Fprintf(tw, "%s\tSANDBOXES\tCPU SECONDS\tMEMORY MB-HOURS\tDISK GB-HOURS\n", strings.ToUpper(report.GroupBy))
+			for _, e := range report.Entries {
+				key := e.Key
+				if key == "" {
+					key = "<none>"
+				}
+				fmt.Fprintf(tw, "%s\t%d\t%.0f\t%.2f\t%.2f\n", key, e.Sandboxes, e.CPUSeconds, e.MemoryMBHours, e.DiskGBHours)
+			}
+		} else {
+			fmt.Fprintln(tw, "NAME\tID\tREMOVED\tCPU SECONDS\tMEMORY MB-HOURS\tDISK GB-HOURS")
+			for _, e := range report.Entries {
+				fmt.Fprintf(tw, "%s\t%s\t%t\t%.0f\t%.2f\t%.2f\n", e.Key, e.SandboxID, e.Removed, e.CPUSeconds, e.MemoryMBHours, e.DiskGBHours)
+			}
+		}
+		tw.Flush()
+		fmt.Fprintln(t.writer)
+	}
+
+	period := "until " + FormatTimestamp(report.Until)
+	if !report.Since.IsZero() {
+		period = FormatTimestamp(report.Since) + " - " + FormatTimestamp(report.Until)
+	}
+	fmt.Fprintf(t.writer, "Period:  %s\n", period)
+	fmt.Fprintf(t.writer, "Total:   %.0f CPU seconds, %.2f memory MB-hours, %.2f disk GB-hours\n", report.Total.CPUSeconds, report.Total.MemoryMBHours, report.Total.DiskGBHours)
+	return nil
+}
+
+// PrintProcessTable prints the sandbox guest processes in a table format.
+func (t *TablePrinter) PrintProcessTable(table model.ProcessTable) error {
+	fmt.Fprintf(t.writer, "Sandbox:  %s  up %s  load average: %.2f, %.2f, %.2f\n", table.SandboxName, table.Uptime.Truncate(time.Second), table.Load1, table.Load5, table.Load15)
+	fmt.Fprintf(t.writer, "Memory:   %s total, %s available\n", FormatBytes(int64(table.MemoryTotalBytes)), FormatBytes(int64(table.MemoryAvailableBytes)))
+	fmt.Fprintf(t.writer, "Tasks:    %d\n\n", len(table.Processes))
+
+	tw := tabwriter.NewWriter(t.writer, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "PID\tUSER\tSTATE\t%CPU\t%MEM\tRSS\tTHREADS\tTIME\tCOMMAND")
+	for _, p := range table.Processes {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%.1f\t%.1f\t%s\t%d\t%s\t%s\n",
+			p.PID, p.User, p.State, p.CPUPercent, p.MemoryPercent, FormatBytes(int64(p.RSSBytes)), p.Threads, formatCPUTime(p.CPUTime), p.Command)
+	}
+
+	return nil
+}
+
+// PrintConnections prints the sandbox network connections in a table format.
+func (t *TablePrinter) PrintConnections(conns []model.Connection) error {
+	if len(conns) == 0 {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(t.writer, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "PROTO\tDIRECTION\tIFACE\tSOURCE\tDESTINATION\tDOMAIN\tSTATE\tPROXY\tSENT\tRECEIVED")
+	for _, c := range conns {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			c.Protocol, c.Direction, c.Interface, c.Source, c.Destination, orDash(c.Domain), orDash(c.State), orDash(c.Proxy),
+			FormatBytes(int64(c.BytesSent)), FormatBytes(int64(c.BytesReceived)))
+	}
+
+	return nil
+}
+
+// formatCPUTime formats a process CPU time like top (minutes:seconds.hundredths).
+func formatCPUTime(d time.Duration) string {
+	cs := d.Milliseconds() / 10
+	return fmt.Sprintf("%d:%02d.%02d", cs/6000, cs/100%60, cs%100)
+}
+
+// orDash returns "-" for an empty value.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// formatLatency formats an upstream latency, "-" when there isn't one.
+func formatLatency(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return d.Round(100 * time.Microsecond).String()
+}
+
+// seccompModeName returns the name of a seccomp mode, the default one has no value.
+func seccompModeName(mode model.SeccompMode) string {
+	if mode == model.SeccompModeDefault {
+		return "default"
+	}
+	return string(mode)
+}
+
+// seccompDescription describes the seccomp mode of a sandbox and its enforcement
+// when it's running (e.g. "default (enforced, filters: 1)").
+func seccompDescription(opts model.SeccompOptions, status *model.SeccompStatus) string {
+	desc := seccompModeName(opts.Mode)
+	if opts.Filter != "" {
+		desc += " " + opts.Filter
+	}
+	switch {
+	case status == nil:
+		return desc
+	case !status.Enforced:
+		return desc + " (not enforced)"
+	case status.Filters > 0:
+		return fmt.Sprintf("%s (enforced, filters: %d)", desc, status.Filters)
+	default:
+		return desc + " (enforced)"
+	}
+}
+
+// networkOptionsDescription describes the network options of a sandbox (e.g.
+// "mtu 1380, mss clamping").
+func networkOptionsDescription(opts model.NetworkOptions) string {
+	var parts []string
+	if opts.MTU > 0 {
+		parts = append(parts, fmt.Sprintf("mtu %d", opts.MTU))
+	}
+	if opts.ClampMSS {
+		parts = append(parts, "mss clamping")
+	}
+	return strings.Join(parts, ", ")
+}
+
 // PrintMessage prints a simple text message.
 func (t *TablePrinter) PrintMessage(msg string) error {
 	fmt.Fprintln(t.writer, msg)
 	return nil
 }
+
+// PrintExecResult prints a command execution summary. The command output is
+// not printed, in table mode it is streamed while the command runs.
+func (t *TablePrinter) PrintExecResult(result model.ExecResult) error {
+	fmt.Fprintf(t.writer, "Exit code:  %d\n", result.ExitCode)
+	fmt.Fprintf(t.writer, "Duration:   %s\n", result.Duration)
+	fmt.Fprintf(t.writer, "Stdout:     %s\n", FormatBytes(result.StdoutBytes))
+	fmt.Fprintf(t.writer, "Stderr:     %s\n", FormatBytes(result.StderrBytes))
+	return nil
+}
+
+// PrintExecHistory prints the executed commands in a table format.
+func (t *TablePrinter) PrintExecHistory(records []model.ExecRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(t.writer, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "STARTED\tDURATION\tEXIT\tUSER\tCALLER\tCOMMAND")
+	for _, r := range records {
+		exit := strconv.Itoa(r.ExitCode)
+		if r.Error != "" {
+			exit = "error"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			FormatTimestamp(r.StartedAt), r.Duration.Round(time.Millisecond), exit, r.User, r.Caller, strings.Join(r.Command, " "))
+	}
+
+	return nil
+}
+
+// PrintChecks prints preflight check results with a summary.
+func (t *TablePrinter) PrintChecks(checks []EngineChecks) error {
+	totalErrors := 0
+	totalWarnings := 0
+
+	for _, ec := range checks {
+		fmt.Fprintf(t.writer, "\nChecking %s engine...\n", ec.Engine)
+		for _, r := range ec.Results {
+			fmt.Fprintf(t.writer, "  %s %-20s %s\n", checkStatusIcon(r.Status), r.ID, r.Message)
+			if r.Status != model.CheckStatusOK && r.Remediation != "" {
+				fmt.Fprintf(t.writer, "     %-20s fix: %s\n", "", r.Remediation)
+			}
+
+			switch r.Status {
+			case model.CheckStatusError:
+				totalErrors++
+			case model.CheckStatusWarning:
+				totalWarnings++
+			}
+		}
+	}
+
+	// Summary.
+	fmt.Fprintln(t.writer)
+	if totalErrors == 0 && totalWarnings == 0 {
+		fmt.Fprintln(t.writer, "All checks passed!")
+		return nil
+	}
+
+	var summary []string
+	if totalErrors > 0 {
+		summary = append(summary, fmt.Sprintf("%d error(s)", totalErrors))
+	}
+	if totalWarnings > 0 {
+		summary = append(summary, fmt.Sprintf("%d warning(s)", totalWarnings))
+	}
+	fmt.Fprintln(t.writer, strings.Join(summary, ", "))
+
+	return nil
+}
+
+// PrintError prints a command error.
+func (t *TablePrinter) PrintError(err ErrorInfo) error {
+	fmt.Fprintf(t.writer, "Error: %s\n", err.Message)
+	return nil
+}
+
+func checkStatusIcon(status model.CheckStatus) string {
+	switch status {
+	case model.CheckStatusOK:
+		return "OK"
+	case model.CheckStatusWarning:
+		return "!!"
+	case model.CheckStatusError:
+		return "XX"
+	default:
+		return "??"
+	}
+}
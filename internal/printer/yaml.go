@@ -0,0 +1,184 @@
+package printer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// YAMLPrinter prints sandbox information in YAML format.
+//
+// The output has the same schema (field names and order) as the JSON printer,
+// the JSON documents are converted to YAML.
+type YAMLPrinter struct {
+	writer io.Writer
+}
+
+// NewYAMLPrinter creates a new YAML printer.
+func NewYAMLPrinter(w io.Writer) *YAMLPrinter {
+	return &YAMLPrinter{writer: w}
+}
+
+// PrintList prints sandboxes in YAML format with a subset of fields.
+func (y *YAMLPrinter) PrintList(sandboxes []model.Sandbox) error {
+	return y.print(func(p *JSONPrinter) error { return p.PrintList(sandboxes) })
+}
+
+// PrintStatus prints detailed sandbox status in YAML format.
+func (y *YAMLPrinter) PrintStatus(sandbox model.Sandbox) error {
+	return y.print(func(p *JSONPrinter) error { return p.PrintStatus(sandbox) })
+}
+
+// PrintImageList prints image releases in YAML format.
+func (y *YAMLPrinter) PrintImageList(releases []model.ImageRelease) error {
+	return y.print(func(p *JSONPrinter) error { return p.PrintImageList(releases) })
+}
+
+// PrintImageInspect prints detailed image manifest in YAML format.
+func (y *YAMLPrinter) PrintImageInspect(manifest model.ImageManifest) error {
+	return y.print(func(p *JSONPrinter) error { return p.PrintImageInspect(manifest) })
+}
+
+// PrintExecHistory prints the executed commands in YAML format.
+func (y *YAMLPrinter) PrintExecHistory(records []model.ExecRecord) error {
+	return y.print(func(p *JSONPrinter) error { return p.PrintExecHistory(records) })
+}
+
+// PrintEgressTest prints the egress policy test results in YAML format.
+func (y *YAMLPrinter) PrintEgressTest(results []model.EgressTestResult) error {
+	return y.print(func(p *JSONPrinter) error { return p.PrintEgressTest(results) })
+}
+
+// PrintPolicyList prints the named egress policies in YAML format.
+func (y *YAMLPrinter) PrintPolicyList(policies []model.NamedEgressPolicy) error {
+	return y.print(func(p *JSONPrinter) error { return p.PrintPolicyList(policies) })
+}
+
+// PrintTaskList prints the tasks in YAML format.
+func (y *YAMLPrinter) PrintTaskList(tasks []model.Task) error {
+	return y.print(func(p *JSONPrinter) error { return p.PrintTaskList(tasks) })
+}
+
+// PrintTemplateList prints the sandbox templates in YAML format.
+func (y *YAMLPrinter) PrintTemplateList(templates []model.SandboxTemplate) error {
+	return y.print(func(p *JSONPrinter) error { return p.PrintTemplateList(templates) })
+}
+
+// PrintTemplate prints a sandbox template in YAML format.
+func (y *YAMLPrinter) PrintTemplate(template model.SandboxTemplate) error {
+	return y.print(func(p *JSONPrinter) error { return p.PrintTemplate(template) })
+}
+
+// PrintReservations prints the sandbox resource reservations in YAML format.
+func (y *YAMLPrinter) PrintReservations(reservations []model.Reservation) error {
+	return y.print(func(p *JSONPrinter) error { return p.PrintReservations(reservations) })
+}
+
+// PrintNamespaceList prints the namespaces in YAML format.
+func (y *YAMLPrinter) PrintNamespaceList(namespaces []model.NamespaceUsage) error {
+	return y.print(func(p *JSONPrinter) error { return p.PrintNamespaceList(namespaces) })
+}
+
+// PrintDataMoves prints the data layout migration moves in YAML format.
+func (y *YAMLPrinter) PrintDataMoves(moves []model.DataMove) error {
+	return y.print(func(p *JSONPrinter) error { return p.PrintDataMoves(moves) })
+}
+
+// PrintDNSEvents prints the DNS queries in YAML format.
+func (y *YAMLPrinter) PrintDNSEvents(events []model.DNSEvent) error {
+	return y.print(func(p *JSONPrinter) error { return p.PrintDNSEvents(events) })
+}
+
+// PrintDNSStats prints the aggregated DNS queries in YAML format.
+func (y *YAMLPrinter) PrintDNSStats(stats model.DNSStats) error {
+	return y.print(func(p *JSONPrinter) error { return p.PrintDNSStats(stats) })
+}
+
+// PrintUsageReport prints the resources used by the sandboxes in YAML format.
+func (y *YAMLPrinter) PrintUsageReport(report model.UsageReport) error {
+	return y.print(func(p *JSONPrinter) error { return p.PrintUsageReport(report) })
+}
+
+// PrintProcessTable prints the sandbox guest processes in YAML format.
+func (y *YAMLPrinter) PrintProcessTable(table model.ProcessTable) error {
+	return y.print(func(p *JSONPrinter) error { return p.PrintProcessTable(table) })
+}
+
+// PrintConnections prints the sandbox network connections in YAML format.
+func (y *YAMLPrinter) PrintConnections(conns []model.Connection) error {
+	return y.print(func(p *JSONPrinter) error { return p.PrintConnections(conns) })
+}
+
+// PrintHostCapacity prints the host capacity in YAML format.
+func (y *YAMLPrinter) PrintHostCapacity(capacity model.HostCapacity) error {
+	return y.print(func(p *JSONPrinter) error { return p.PrintHostCapacity(capacity) })
+}
+
+// PrintImageDiskUsage prints the images disk usage in YAML format.
+func (y *YAMLPrinter) PrintImageDiskUsage(usage model.ImageDiskUsage) error {
+	return y.print(func(p *JSONPrinter) error { return p.PrintImageDiskUsage(usage) })
+}
+
+// PrintImageDiff prints the files changed between two images in YAML format.
+func (y *YAMLPrinter) PrintImageDiff(diff model.ImageDiff) error {
+	return y.print(func(p *JSONPrinter) error { return p.PrintImageDiff(diff) })
+}
+
+// PrintIntegrityReport prints the sandbox files changed since the integrity baseline
+// in YAML format.
+func (y *YAMLPrinter) PrintIntegrityReport(r model.IntegrityReport) error {
+	return y.print(func(p *JSONPrinter) error { return p.PrintIntegrityReport(r) })
+}
+
+// PrintMessage prints a simple message in YAML format.
+func (y *YAMLPrinter) PrintMessage(msg string) error {
+	return y.print(func(p *JSONPrinter) error { return p.PrintMessage(msg) })
+}
+
+// PrintExecResult prints a command execution result in YAML format.
+func (y *YAMLPrinter) PrintExecResult(result model.ExecResult) error {
+	return y.print(func(p *JSONPrinter) error { return p.PrintExecResult(result) })
+}
+
+// PrintChecks prints preflight check results in YAML format.
+func (y *YAMLPrinter) PrintChecks(checks []EngineChecks) error {
+	return y.print(func(p *JSONPrinter) error { return p.PrintChecks(checks) })
+}
+
+// PrintError prints a command error in YAML format.
+func (y *YAMLPrinter) PrintError(err ErrorInfo) error {
+	return y.print(func(p *JSONPrinter) error { return p.PrintError(err) })
+}
+
+func (y *YAMLPrinter) print(printJSON func(p *JSONPrinter) error) error {
+	var buf bytes.Buffer
+	if err := printJSON(NewJSONPrinter(&buf)); err != nil {
+		return err
+	}
+
+	// JSON is valid YAML, decoding into a node keeps the field order.
+	var node yaml.Node
+	if err := yaml.Unmarshal(buf.Bytes(), &node); err != nil {
+		return fmt.Errorf("could not convert output to YAML: %w", err)
+	}
+	resetNodeStyle(&node)
+
+	enc := yaml.NewEncoder(y.writer)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// resetNodeStyle removes the JSON flow and quoting styles so the output is block style YAML.
+func resetNodeStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		resetNodeStyle(c)
+	}
+}
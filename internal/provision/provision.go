@@ -48,7 +48,7 @@ func (a *sandboxAccessor) Exec(ctx context.Context, command []string, opts model
 }
 
 func (a *sandboxAccessor) CopyTo(ctx context.Context, srcLocal string, dstRemote string) error {
-	return a.engine.CopyTo(ctx, a.sandboxID, srcLocal, dstRemote)
+	return a.engine.CopyTo(ctx, a.sandboxID, srcLocal, dstRemote, model.CopyOpts{})
 }
 
 // NewProvisionerChain returns a Provisioner that runs all provisioners sequentially.
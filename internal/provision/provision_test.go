@@ -206,7 +206,7 @@ func TestSandboxAccessor(t *testing.T) {
 		"CopyTo should delegate to engine with the bound sandbox ID.": {
 			sandboxID: "sb-456",
 			mock: func(m *sandboxmock.MockEngine) {
-				m.On("CopyTo", mock.Anything, "sb-456", "/local/file.txt", "/remote/file.txt").
+				m.On("CopyTo", mock.Anything, "sb-456", "/local/file.txt", "/remote/file.txt", model.CopyOpts{}).
 					Once().Return(nil)
 			},
 			test: func(assert *assert.Assertions, require *require.Assertions, accessor provision.SandboxAccessor) {
@@ -230,7 +230,7 @@ func TestSandboxAccessor(t *testing.T) {
 		"CopyTo should propagate engine errors.": {
 			sandboxID: "sb-000",
 			mock: func(m *sandboxmock.MockEngine) {
-				m.On("CopyTo", mock.Anything, "sb-000", "/src", "/dst").
+				m.On("CopyTo", mock.Anything, "sb-000", "/src", "/dst", model.CopyOpts{}).
 					Once().Return(fmt.Errorf("copy failed"))
 			},
 			test: func(assert *assert.Assertions, _ *require.Assertions, accessor provision.SandboxAccessor) {
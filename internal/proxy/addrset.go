@@ -0,0 +1,28 @@
+package proxy
+
+import (
+	"net"
+	"time"
+)
+
+// addressMinTTL is the minimum time the traffic to an address is allowed after its
+// domain was resolved, the DNS answers can have very short TTLs.
+const addressMinTTL = time.Minute
+
+// AddressAllower allows the sandbox traffic the proxy can't see (e.g. pings or NTP)
+// to addresses for a while, the addresses of the DNS answers of the allowed domains.
+type AddressAllower interface {
+	Allow(ips []net.IP, ttl time.Duration) error
+}
+
+// NewNftablesAddressAllower returns an AddressAllower adding the addresses to an
+// IPv4 nftables set with timeouts, matched by the sandbox forward rules. Requires
+// Linux and CAP_NET_ADMIN.
+func NewNftablesAddressAllower(table, set string) AddressAllower {
+	return nftablesAddressAllower{table: table, set: set}
+}
+
+type nftablesAddressAllower struct {
+	table string
+	set   string
+}
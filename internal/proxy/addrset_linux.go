@@ -0,0 +1,43 @@
+//go:build linux
+
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/google/nftables"
+)
+
+// Allow adds the IPv4 addresses to the set for the TTL (at least addressMinTTL).
+func (a nftablesAddressAllower) Allow(ips []net.IP, ttl time.Duration) error {
+	ttl = max(ttl, addressMinTTL)
+	var elems []nftables.SetElement
+	for _, ip := range ips {
+		if ip4 := ip.To4(); ip4 != nil {
+			elems = append(elems, nftables.SetElement{Key: ip4, Timeout: ttl})
+		}
+	}
+	if len(elems) == 0 {
+		return nil
+	}
+
+	conn, err := nftables.New()
+	if err != nil {
+		return fmt.Errorf("could not connect to nftables: %w", err)
+	}
+	set := &nftables.Set{
+		Table:      &nftables.Table{Family: nftables.TableFamilyIPv4, Name: a.table},
+		Name:       a.set,
+		KeyType:    nftables.TypeIPAddr,
+		HasTimeout: true,
+	}
+	if err := conn.SetAddElements(set, elems); err != nil {
+		return fmt.Errorf("could not add addresses: %w", err)
+	}
+	if err := conn.Flush(); err != nil {
+		return fmt.Errorf("could not add addresses to set %s: %w", a.set, err)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// Allow fails, the nftables sets require Linux.
+func (a nftablesAddressAllower) Allow(_ []net.IP, _ time.Duration) error {
+	return fmt.Errorf("could not add addresses to set %s: requires a Linux host", a.set)
+}
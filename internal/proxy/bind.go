@@ -0,0 +1,14 @@
+package proxy
+
+import (
+	"net"
+	"time"
+)
+
+// InterfaceDialer returns a dialer whose connections go out through a host network
+// interface whatever the routing table says (SO_BINDTODEVICE), so the proxied
+// traffic leaves through the sandbox egress interface. Binding to an interface
+// requires Linux.
+func InterfaceDialer(iface string, timeout time.Duration) *net.Dialer {
+	return &net.Dialer{Timeout: timeout, Control: bindToDevice(iface)}
+}
@@ -0,0 +1,24 @@
+//go:build linux
+
+package proxy
+
+import (
+	"fmt"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// bindToDevice returns a dialer control binding the sockets to a network interface.
+func bindToDevice(iface string) func(network, address string, c syscall.RawConn) error {
+	return func(_, _ string, c syscall.RawConn) error {
+		var bindErr error
+		if err := c.Control(func(fd uintptr) { bindErr = unix.BindToDevice(int(fd), iface) }); err != nil {
+			return err
+		}
+		if bindErr != nil {
+			return fmt.Errorf("could not bind to interface %s: %w", iface, bindErr)
+		}
+		return nil
+	}
+}
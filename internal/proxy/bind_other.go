@@ -0,0 +1,16 @@
+//go:build !linux
+
+package proxy
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// bindToDevice returns a dialer control failing, binding the sockets to a network
+// interface requires Linux.
+func bindToDevice(iface string) func(network, address string, c syscall.RawConn) error {
+	return func(_, _ string, _ syscall.RawConn) error {
+		return fmt.Errorf("could not bind to interface %s: requires a Linux host", iface)
+	}
+}
@@ -0,0 +1,47 @@
+package proxy_test
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/proxy"
+)
+
+func TestInterfaceDialer(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(err)
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	ctx := context.Background()
+
+	// A missing interface should fail instead of using the routing table.
+	_, err = proxy.InterfaceDialer("sbx-missing0", time.Second).DialContext(ctx, "tcp", l.Addr().String())
+	assert.Error(err)
+
+	if runtime.GOOS != "linux" {
+		t.Skip("binding to an interface requires Linux")
+	}
+
+	// The loopback interface reaches the local listener.
+	conn, err := proxy.InterfaceDialer("lo", time.Second).DialContext(ctx, "tcp", l.Addr().String())
+	require.NoError(err)
+	conn.Close()
+}
@@ -0,0 +1,34 @@
+package proxy
+
+// Reasons of the denied requests, connections and DNS queries.
+const (
+	// DenialReasonRuleMatch means the domain was denied by the rules.
+	DenialReasonRuleMatch = "rule-match"
+	// DenialReasonIPAddress means the target was an IP address instead of a domain.
+	DenialReasonIPAddress = "ip-address"
+	// DenialReasonFailClosed means the destination couldn't be verified.
+	DenialReasonFailClosed = "fail-closed"
+)
+
+// Denial is a request, connection or DNS query denied by the proxies.
+type Denial struct {
+	// Protocol is http, http-connect, tls or dns.
+	Protocol string
+	// Domain is empty when the target was not a domain.
+	Domain string
+	// Target is the requested host (e.g. the request host, the TLS SNI).
+	Target string
+	Reason string
+}
+
+// DenialRecorder records the denials of the proxies.
+type DenialRecorder interface {
+	RecordDenial(d Denial)
+}
+
+// recordDenial records a denial if there is a recorder.
+func recordDenial(r DenialRecorder, d Denial) {
+	if r != nil {
+		r.RecordDenial(d)
+	}
+}
@@ -3,6 +3,7 @@ package proxy
 import (
 	"context"
 	"fmt"
+	"net"
 	"strings"
 	"time"
 
@@ -14,19 +15,44 @@ import (
 // DNSProxyConfig is the configuration for the DNS proxy server.
 type DNSProxyConfig struct {
 	ListenAddr string
-	Upstream   string
 	Matcher    *RuleMatcher
 	Logger     log.Logger
 	DNSClient  DNSClient
+	// Upstreams are the resolvers the allowed queries are forwarded to, tried in
+	// order until one answers. See ParseDNSUpstream for the formats.
+	Upstreams []string
+	// Dialer dials the upstreams of the default DNSClient (optional).
+	Dialer *net.Dialer
+	// FailClosed refuses the allowed queries answered with non public addresses.
+	FailClosed bool
+	// Events records the answered queries (optional).
+	Events DNSEventRecorder
+	// Denials records the denied queries (optional).
+	Denials DenialRecorder
+	// ICMP allows the pings to the answers of the domains allowed with ICMP by the
+	// rules (optional, without it the pings are never allowed).
+	ICMP AddressAllower
+	// NTP allows the NTP traffic to the answers of the domains allowed with NTP by
+	// the rules (optional, without it NTP is never allowed).
+	NTP AddressAllower
 }
 
 func (c *DNSProxyConfig) defaults() error {
 	if c.ListenAddr == "" {
 		c.ListenAddr = ":9667"
 	}
-	if c.Upstream == "" {
-		c.Upstream = "8.8.8.8:53"
+	if len(c.Upstreams) == 0 {
+		c.Upstreams = []string{"8.8.8.8:53"}
 	}
+	upstreams := make([]string, 0, len(c.Upstreams))
+	for _, raw := range c.Upstreams {
+		u, err := ParseDNSUpstream(raw)
+		if err != nil {
+			return err
+		}
+		upstreams = append(upstreams, u)
+	}
+	c.Upstreams = upstreams
 	if c.Matcher == nil {
 		return fmt.Errorf("matcher is required")
 	}
@@ -34,7 +60,7 @@ func (c *DNSProxyConfig) defaults() error {
 		c.Logger = log.Noop
 	}
 	if c.DNSClient == nil {
-		c.DNSClient = &dns.Client{Timeout: 5 * time.Second}
+		c.DNSClient = newUpstreamDNSClient(5*time.Second, c.Dialer)
 	}
 	return nil
 }
@@ -50,12 +76,17 @@ type DNSClient interface {
 // matcher, and either forwards the query to an upstream resolver (allow) or
 // returns a refused response (deny).
 type DNSProxy struct {
-	udpServer *dns.Server
-	tcpServer *dns.Server
-	upstream  string
-	matcher   *RuleMatcher
-	logger    log.Logger
-	client    DNSClient
+	udpServer  *dns.Server
+	tcpServer  *dns.Server
+	upstreams  []string
+	matcher    *RuleMatcher
+	logger     log.Logger
+	client     DNSClient
+	failClosed bool
+	events     DNSEventRecorder
+	denials    DenialRecorder
+	icmp       AddressAllower
+	ntp        AddressAllower
 }
 
 // NewDNSProxy creates a new DNS proxy server.
@@ -65,10 +96,15 @@ func NewDNSProxy(cfg DNSProxyConfig) (*DNSProxy, error) {
 	}
 
 	d := &DNSProxy{
-		upstream: cfg.Upstream,
-		matcher:  cfg.Matcher,
-		logger:   cfg.Logger,
-		client:   cfg.DNSClient,
+		upstreams:  cfg.Upstreams,
+		matcher:    cfg.Matcher,
+		logger:     cfg.Logger,
+		client:     cfg.DNSClient,
+		failClosed: cfg.FailClosed,
+		events:     cfg.Events,
+		denials:    cfg.Denials,
+		icmp:       cfg.ICMP,
+		ntp:        cfg.NTP,
 	}
 
 	mux := dns.NewServeMux()
@@ -129,6 +165,9 @@ func (d *DNSProxy) handleDNS(w dns.ResponseWriter, r *dns.Msg) {
 	// DNS names have a trailing dot (FQDN). Strip it for our matcher.
 	domain := strings.TrimSuffix(strings.ToLower(q.Name), ".")
 
+	ev := DNSEvent{Time: time.Now().UTC(), Domain: domain, QType: dns.TypeToString[q.Qtype]}
+	defer func() { d.recordEvent(ev) }()
+
 	action := d.matcher.Match(domain)
 
 	if action == ActionDeny {
@@ -140,6 +179,7 @@ func (d *DNSProxy) handleDNS(w dns.ResponseWriter, r *dns.Msg) {
 			"src":      w.RemoteAddr().String(),
 			"reason":   "rule-match",
 		}).Infof("denied request")
+		ev.Action, ev.Reason, ev.Rcode = ActionDeny, DNSReasonRuleMatch, dns.RcodeToString[dns.RcodeRefused]
 		d.refuseDNS(w, r)
 		return
 	}
@@ -151,24 +191,93 @@ func (d *DNSProxy) handleDNS(w dns.ResponseWriter, r *dns.Msg) {
 		"qtype":    dns.TypeToString[q.Qtype],
 		"src":      w.RemoteAddr().String(),
 	}).Infof("allowed request")
-	d.forwardDNS(w, r, domain)
+	ev.Action = ActionAllow
+	d.forwardDNS(w, r, &ev)
 }
 
-// forwardDNS forwards a DNS query to the upstream resolver and writes the response.
-func (d *DNSProxy) forwardDNS(w dns.ResponseWriter, r *dns.Msg, domain string) {
-	resp, _, err := d.client.ExchangeContext(context.Background(), r, d.upstream)
+// forwardDNS forwards a DNS query to the upstream resolvers and writes the response,
+// the result is set in the query event.
+func (d *DNSProxy) forwardDNS(w dns.ResponseWriter, r *dns.Msg, ev *DNSEvent) {
+	domain := ev.Domain
+	start := time.Now()
+	resp, err := d.exchange(r, domain)
+	ev.UpstreamLatency = time.Since(start)
 	if err != nil {
-		d.logger.Errorf("failed to forward DNS query for %q to %s: %v", domain, d.upstream, err)
+		ev.Reason, ev.Rcode = DNSReasonUpstreamError, dns.RcodeToString[dns.RcodeServerFailure]
 		d.serverFailDNS(w, r)
 		return
 	}
 
+	if d.failClosed {
+		if ip := nonPublicAnswer(resp); ip != nil {
+			d.logger.WithValues(log.Kv{
+				"action":   "deny",
+				"protocol": "dns",
+				"domain":   domain,
+				"answer":   ip.String(),
+				"src":      w.RemoteAddr().String(),
+				"reason":   "fail-closed",
+			}).Infof("denied request")
+			ev.Action, ev.Reason, ev.Rcode = ActionDeny, DNSReasonFailClosed, dns.RcodeToString[dns.RcodeRefused]
+			d.refuseDNS(w, r)
+			return
+		}
+	}
+
+	// The pings and NTP must be allowed before the VM gets the answer.
+	if d.icmp != nil && d.matcher.MatchICMP(domain) {
+		d.allowAnswers(d.icmp, "ICMP", domain, resp)
+	}
+	if d.ntp != nil && d.matcher.MatchNTP(domain) {
+		d.allowAnswers(d.ntp, "NTP", domain, resp)
+	}
+
+	ev.Rcode = dns.RcodeToString[resp.Rcode]
+	ev.Answers = answerAddresses(resp)
 	resp.Id = r.Id
 	if err := w.WriteMsg(resp); err != nil {
 		d.logger.Errorf("failed to write DNS response for %q: %v", domain, err)
 	}
 }
 
+// allowAnswers allows the traffic of a protocol to the addresses of an answer.
+func (d *DNSProxy) allowAnswers(allower AddressAllower, protocol, domain string, resp *dns.Msg) {
+	ips, ttl := answerIPs(resp)
+	if len(ips) == 0 {
+		return
+	}
+	if err := allower.Allow(ips, ttl); err != nil {
+		d.logger.Errorf("failed to allow %s for %q: %v", protocol, domain, err)
+	}
+}
+
+// exchange sends the query to the upstream resolvers in order, returning the first
+// answer.
+func (d *DNSProxy) exchange(r *dns.Msg, domain string) (*dns.Msg, error) {
+	var err error
+	for _, upstream := range d.upstreams {
+		var resp *dns.Msg
+		resp, _, err = d.client.ExchangeContext(context.Background(), r, upstream)
+		if err == nil {
+			return resp, nil
+		}
+		d.logger.Errorf("failed to forward DNS query for %q to %s: %v", domain, upstream, err)
+	}
+
+	return nil, err
+}
+
+// recordEvent records a query event if the proxy has a recorder, and the denial
+// of a denied query.
+func (d *DNSProxy) recordEvent(ev DNSEvent) {
+	if d.events != nil {
+		d.events.RecordDNSEvent(ev)
+	}
+	if ev.Action == ActionDeny {
+		recordDenial(d.denials, Denial{Protocol: "dns", Domain: ev.Domain, Target: ev.Domain, Reason: ev.Reason})
+	}
+}
+
 // refuseDNS sends a REFUSED response for denied queries.
 func (d *DNSProxy) refuseDNS(w dns.ResponseWriter, r *dns.Msg) {
 	resp := new(dns.Msg)
@@ -186,3 +295,55 @@ func (d *DNSProxy) serverFailDNS(w dns.ResponseWriter, r *dns.Msg) {
 		d.logger.Errorf("failed to write SERVFAIL DNS response: %v", err)
 	}
 }
+
+// answerAddresses returns the addresses of the A and AAAA answer records.
+func answerAddresses(resp *dns.Msg) []string {
+	var addrs []string
+	for _, rr := range resp.Answer {
+		switch rec := rr.(type) {
+		case *dns.A:
+			addrs = append(addrs, rec.A.String())
+		case *dns.AAAA:
+			addrs = append(addrs, rec.AAAA.String())
+		}
+	}
+	return addrs
+}
+
+// answerIPs returns the IPv4 addresses of the A answer records and their lowest TTL.
+func answerIPs(resp *dns.Msg) ([]net.IP, time.Duration) {
+	var ips []net.IP
+	var ttl time.Duration
+	for _, rr := range resp.Answer {
+		rec, ok := rr.(*dns.A)
+		if !ok {
+			continue
+		}
+		recTTL := time.Duration(rec.Hdr.Ttl) * time.Second
+		if len(ips) == 0 || recTTL < ttl {
+			ttl = recTTL
+		}
+		ips = append(ips, rec.A)
+	}
+	return ips, ttl
+}
+
+// nonPublicAnswer returns the first non public address of the A and AAAA answers, nil
+// if all of them are public.
+func nonPublicAnswer(resp *dns.Msg) net.IP {
+	for _, rr := range resp.Answer {
+		var ip net.IP
+		switch rec := rr.(type) {
+		case *dns.A:
+			ip = rec.A
+		case *dns.AAAA:
+			ip = rec.AAAA
+		default:
+			continue
+		}
+		if !isPublicIP(ip) {
+			return ip
+		}
+	}
+	return nil
+}
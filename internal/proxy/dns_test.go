@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"sync"
 	"testing"
 	"time"
 
@@ -29,7 +30,7 @@ func startDNSProxy(t *testing.T, matcher *proxy.RuleMatcher, dnsClient proxy.DNS
 
 	p, err := proxy.NewDNSProxy(proxy.DNSProxyConfig{
 		ListenAddr: addr,
-		Upstream:   "8.8.8.8:53", // Won't be used since we mock the client.
+		Upstreams:  []string{"8.8.8.8:53"}, // Won't be used since we mock the client.
 		Matcher:    matcher,
 		Logger:     log.Noop,
 		DNSClient:  dnsClient,
@@ -256,6 +257,97 @@ func TestDNSProxyUpstreamError(t *testing.T) {
 	assert.Equal(dns.RcodeServerFailure, resp.Rcode)
 }
 
+func TestDNSProxyUpstreams(t *testing.T) {
+	tests := map[string]struct {
+		upstreams        []string
+		failing          map[string]bool
+		expRcode         int
+		expUpstreamsUsed []string
+	}{
+		"The first upstream should answer the query.": {
+			upstreams:        []string{"10.0.0.1", "tls://dns.example.com"},
+			expRcode:         dns.RcodeSuccess,
+			expUpstreamsUsed: []string{"10.0.0.1:53"},
+		},
+
+		"A failing upstream should fall back to the next one.": {
+			upstreams:        []string{"10.0.0.1", "tls://dns.example.com", "https://dns.example.com/dns-query"},
+			failing:          map[string]bool{"10.0.0.1:53": true, "tls://dns.example.com:853": true},
+			expRcode:         dns.RcodeSuccess,
+			expUpstreamsUsed: []string{"10.0.0.1:53", "tls://dns.example.com:853", "https://dns.example.com/dns-query"},
+		},
+
+		"All the upstreams failing should return SERVFAIL.": {
+			upstreams:        []string{"10.0.0.1:5353", "10.0.0.2"},
+			failing:          map[string]bool{"10.0.0.1:5353": true, "10.0.0.2:53": true},
+			expRcode:         dns.RcodeServerFailure,
+			expUpstreamsUsed: []string{"10.0.0.1:5353", "10.0.0.2:53"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			matcher, err := proxy.NewRuleMatcher(proxy.ActionAllow, nil)
+			require.NoError(err)
+
+			pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+			require.NoError(err)
+			addr := pc.LocalAddr().String()
+			pc.Close()
+
+			var mu sync.Mutex
+			var gotUpstreams []string
+			answer := newFakeDNSClientA("93.184.216.34")
+			client := &addrDNSClient{exchange: func(m *dns.Msg, upstream string) (*dns.Msg, error) {
+				if m.Question[0].Name != "example.com." {
+					return answer.handler(m)
+				}
+				mu.Lock()
+				gotUpstreams = append(gotUpstreams, upstream)
+				mu.Unlock()
+				if test.failing[upstream] {
+					return nil, fmt.Errorf("upstream failure")
+				}
+				return answer.handler(m)
+			}}
+
+			p, err := proxy.NewDNSProxy(proxy.DNSProxyConfig{
+				ListenAddr: addr,
+				Upstreams:  test.upstreams,
+				Matcher:    matcher,
+				Logger:     log.Noop,
+				DNSClient:  client,
+			})
+			require.NoError(err)
+
+			ctx, ctxCancel := context.WithCancel(context.Background())
+			defer ctxCancel()
+			go func() { _ = p.Run(ctx) }()
+			waitForDNSPort(t, addr)
+
+			resp := dnsQuery(t, addr, "example.com", dns.TypeA)
+			assert.Equal(test.expRcode, resp.Rcode)
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(test.expUpstreamsUsed, gotUpstreams)
+		})
+	}
+}
+
+// addrDNSClient is a mock DNS client that answers based on the upstream address.
+type addrDNSClient struct {
+	exchange func(m *dns.Msg, upstream string) (*dns.Msg, error)
+}
+
+func (a *addrDNSClient) ExchangeContext(_ context.Context, m *dns.Msg, upstream string) (*dns.Msg, time.Duration, error) {
+	resp, err := a.exchange(m, upstream)
+	return resp, 0, err
+}
+
 func TestDNSProxyAAAAQuery(t *testing.T) {
 	assert := assert.New(t)
 	require := require.New(t)
@@ -326,6 +418,16 @@ func TestNewDNSProxyValidation(t *testing.T) {
 			cfg:    proxy.DNSProxyConfig{},
 			expErr: true,
 		},
+		"Invalid upstream should fail.": {
+			cfg: proxy.DNSProxyConfig{
+				Upstreams: []string{"dns.google"},
+				Matcher: func() *proxy.RuleMatcher {
+					m, _ := proxy.NewRuleMatcher(proxy.ActionAllow, nil)
+					return m
+				}(),
+			},
+			expErr: true,
+		},
 		"Valid config should succeed.": {
 			cfg: proxy.DNSProxyConfig{
 				Matcher: func() *proxy.RuleMatcher {
@@ -350,3 +452,338 @@ func TestNewDNSProxyValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestDNSProxyFailClosed(t *testing.T) {
+	tests := map[string]struct {
+		answerIP string
+		expRcode int
+	}{
+		"An answer with a public address should be forwarded.": {
+			answerIP: "93.184.216.34",
+			expRcode: dns.RcodeSuccess,
+		},
+
+		"An answer with a private address should be refused.": {
+			answerIP: "192.168.1.10",
+			expRcode: dns.RcodeRefused,
+		},
+
+		"An answer with a loopback address should be refused.": {
+			answerIP: "127.0.0.1",
+			expRcode: dns.RcodeRefused,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			matcher, err := proxy.NewRuleMatcher(proxy.ActionAllow, nil)
+			require.NoError(err)
+
+			pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+			require.NoError(err)
+			addr := pc.LocalAddr().String()
+			pc.Close()
+
+			p, err := proxy.NewDNSProxy(proxy.DNSProxyConfig{
+				ListenAddr: addr,
+				Matcher:    matcher,
+				Logger:     log.Noop,
+				DNSClient:  newFakeDNSClientA(test.answerIP),
+				FailClosed: true,
+			})
+			require.NoError(err)
+
+			ctx, ctxCancel := context.WithCancel(context.Background())
+			defer ctxCancel()
+			go func() { _ = p.Run(ctx) }()
+			waitForDNSPort(t, addr)
+
+			resp := dnsQuery(t, addr, "allowed.test", dns.TypeA)
+			assert.Equal(test.expRcode, resp.Rcode)
+		})
+	}
+}
+
+// dnsEventRecorder is a DNS event recorder that stores the events in memory.
+type dnsEventRecorder struct {
+	mu     sync.Mutex
+	events []proxy.DNSEvent
+}
+
+func (r *dnsEventRecorder) RecordDNSEvent(ev proxy.DNSEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, ev)
+}
+
+func (r *dnsEventRecorder) domainEvents(domain string) []proxy.DNSEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var evs []proxy.DNSEvent
+	for _, ev := range r.events {
+		if ev.Domain == domain {
+			evs = append(evs, ev)
+		}
+	}
+	return evs
+}
+
+type denialRecorder struct {
+	mu      sync.Mutex
+	denials []proxy.Denial
+}
+
+func (r *denialRecorder) RecordDenial(d proxy.Denial) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.denials = append(r.denials, d)
+}
+
+func (r *denialRecorder) all() []proxy.Denial {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]proxy.Denial(nil), r.denials...)
+}
+
+func (r *denialRecorder) domainDenials(domain string) []proxy.Denial {
+	var denials []proxy.Denial
+	for _, d := range r.all() {
+		if d.Domain == domain {
+			denials = append(denials, d)
+		}
+	}
+	return denials
+}
+
+func TestDNSProxyEvents(t *testing.T) {
+	tests := map[string]struct {
+		client     *fakeDNSClient
+		failClosed bool
+		domain     string
+		expEvent   proxy.DNSEvent
+		expDenials []proxy.Denial
+	}{
+		"An allowed query should record the upstream answer.": {
+			client:   newFakeDNSClientA("93.184.216.34"),
+			domain:   "allowed.test",
+			expEvent: proxy.DNSEvent{Domain: "allowed.test", QType: "A", Action: proxy.ActionAllow, Rcode: "NOERROR", Answers: []string{"93.184.216.34"}},
+		},
+
+		"A denied query should record the rule match.": {
+			client:   newFakeDNSClientA("93.184.216.34"),
+			domain:   "denied.test",
+			expEvent: proxy.DNSEvent{Domain: "denied.test", QType: "A", Action: proxy.ActionDeny, Reason: proxy.DNSReasonRuleMatch, Rcode: "REFUSED"},
+			expDenials: []proxy.Denial{
+				{Protocol: "dns", Domain: "denied.test", Target: "denied.test", Reason: proxy.DenialReasonRuleMatch},
+			},
+		},
+
+		"An upstream failure should record the upstream error.": {
+			client:   newFakeDNSClientError(),
+			domain:   "allowed.test",
+			expEvent: proxy.DNSEvent{Domain: "allowed.test", QType: "A", Action: proxy.ActionAllow, Reason: proxy.DNSReasonUpstreamError, Rcode: "SERVFAIL"},
+		},
+
+		"A fail-closed refusal should record the fail-closed deny.": {
+			client:     newFakeDNSClientA("10.0.0.1"),
+			failClosed: true,
+			domain:     "allowed.test",
+			expEvent:   proxy.DNSEvent{Domain: "allowed.test", QType: "A", Action: proxy.ActionDeny, Reason: proxy.DNSReasonFailClosed, Rcode: "REFUSED"},
+			expDenials: []proxy.Denial{
+				{Protocol: "dns", Domain: "allowed.test", Target: "allowed.test", Reason: proxy.DenialReasonFailClosed},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			matcher, err := proxy.NewRuleMatcher(proxy.ActionAllow, []proxy.Rule{
+				{Action: proxy.ActionDeny, Domain: "denied.test"},
+			})
+			require.NoError(err)
+
+			pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+			require.NoError(err)
+			addr := pc.LocalAddr().String()
+			pc.Close()
+
+			recorder := &dnsEventRecorder{}
+			denials := &denialRecorder{}
+			p, err := proxy.NewDNSProxy(proxy.DNSProxyConfig{
+				ListenAddr: addr,
+				Matcher:    matcher,
+				Logger:     log.Noop,
+				DNSClient:  test.client,
+				FailClosed: test.failClosed,
+				Events:     recorder,
+				Denials:    denials,
+			})
+			require.NoError(err)
+
+			ctx, ctxCancel := context.WithCancel(context.Background())
+			defer ctxCancel()
+			go func() { _ = p.Run(ctx) }()
+			waitForDNSPort(t, addr)
+
+			start := time.Now()
+			_ = dnsQuery(t, addr, test.domain, dns.TypeA)
+
+			evs := recorder.domainEvents(test.domain)
+			require.Len(evs, 1)
+			gotEvent := evs[0]
+			assert.False(gotEvent.Time.Before(start.Add(-time.Second)))
+			gotEvent.Time = time.Time{}
+			gotEvent.UpstreamLatency = 0
+			assert.Equal(test.expEvent, gotEvent)
+			assert.Equal(test.expDenials, denials.domainDenials(test.domain))
+		})
+	}
+}
+
+// addressAllower is an address allower that stores the allowed addresses in memory.
+type addressAllower struct {
+	mu  sync.Mutex
+	ips []string
+	ttl time.Duration
+}
+
+func (a *addressAllower) Allow(ips []net.IP, ttl time.Duration) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, ip := range ips {
+		a.ips = append(a.ips, ip.String())
+	}
+	a.ttl = ttl
+	return nil
+}
+
+func TestDNSProxyICMP(t *testing.T) {
+	tests := map[string]struct {
+		domain string
+		expIPs []string
+		expTTL time.Duration
+	}{
+		"The answers of a domain allowed with ICMP should be allowed to ping.": {
+			domain: "ping.test",
+			expIPs: []string{"93.184.216.34"},
+			expTTL: 60 * time.Second,
+		},
+
+		"The answers of a domain allowed without ICMP should not be allowed to ping.": {
+			domain: "noping.test",
+		},
+
+		"The answers of a denied domain should not be allowed to ping.": {
+			domain: "denied.test",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			matcher, err := proxy.NewRuleMatcher(proxy.ActionDeny, []proxy.Rule{
+				{Action: proxy.ActionAllow, Domain: "ping.test", ICMP: true},
+				{Action: proxy.ActionAllow, Domain: "noping.test"},
+			})
+			require.NoError(err)
+
+			pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+			require.NoError(err)
+			addr := pc.LocalAddr().String()
+			pc.Close()
+
+			icmp := &addressAllower{}
+			p, err := proxy.NewDNSProxy(proxy.DNSProxyConfig{
+				ListenAddr: addr,
+				Matcher:    matcher,
+				Logger:     log.Noop,
+				DNSClient:  newFakeDNSClientA("93.184.216.34"),
+				ICMP:       icmp,
+			})
+			require.NoError(err)
+
+			ctx, ctxCancel := context.WithCancel(context.Background())
+			defer ctxCancel()
+			go func() { _ = p.Run(ctx) }()
+			waitForDNSPort(t, addr)
+
+			_ = dnsQuery(t, addr, test.domain, dns.TypeA)
+
+			icmp.mu.Lock()
+			defer icmp.mu.Unlock()
+			assert.Equal(test.expIPs, icmp.ips)
+			assert.Equal(test.expTTL, icmp.ttl)
+		})
+	}
+}
+
+func TestDNSProxyNTP(t *testing.T) {
+	tests := map[string]struct {
+		domain string
+		expIPs []string
+		expTTL time.Duration
+	}{
+		"The answers of a domain allowed with NTP should be allowed to NTP.": {
+			domain: "ntp.test",
+			expIPs: []string{"93.184.216.34"},
+			expTTL: 60 * time.Second,
+		},
+
+		"The answers of a domain allowed without NTP should not be allowed to NTP.": {
+			domain: "nontp.test",
+		},
+
+		"The answers of a denied domain should not be allowed to NTP.": {
+			domain: "denied.test",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			matcher, err := proxy.NewRuleMatcher(proxy.ActionDeny, []proxy.Rule{
+				{Action: proxy.ActionAllow, Domain: "ntp.test", NTP: true},
+				{Action: proxy.ActionAllow, Domain: "nontp.test", ICMP: true},
+			})
+			require.NoError(err)
+
+			pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+			require.NoError(err)
+			addr := pc.LocalAddr().String()
+			pc.Close()
+
+			ntp := &addressAllower{}
+			p, err := proxy.NewDNSProxy(proxy.DNSProxyConfig{
+				ListenAddr: addr,
+				Matcher:    matcher,
+				Logger:     log.Noop,
+				DNSClient:  newFakeDNSClientA("93.184.216.34"),
+				ICMP:       &addressAllower{},
+				NTP:        ntp,
+			})
+			require.NoError(err)
+
+			ctx, ctxCancel := context.WithCancel(context.Background())
+			defer ctxCancel()
+			go func() { _ = p.Run(ctx) }()
+			waitForDNSPort(t, addr)
+
+			_ = dnsQuery(t, addr, test.domain, dns.TypeA)
+
+			ntp.mu.Lock()
+			defer ntp.mu.Unlock()
+			assert.Equal(test.expIPs, ntp.ips)
+			assert.Equal(test.expTTL, ntp.ttl)
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/slok/sbx/internal/log"
+)
+
+// Reasons of the DNS query events that didn't get an upstream answer.
+const (
+	// DNSReasonRuleMatch means the query was refused by the rules.
+	DNSReasonRuleMatch = "rule-match"
+	// DNSReasonFailClosed means the query was refused because the answer had non
+	// public addresses.
+	DNSReasonFailClosed = "fail-closed"
+	// DNSReasonUpstreamError means the upstream resolver failed.
+	DNSReasonUpstreamError = "upstream-error"
+)
+
+// DNSEvent is a DNS query answered by the DNS proxy.
+type DNSEvent struct {
+	Time   time.Time `json:"time"`
+	Domain string    `json:"domain"`
+	QType  string    `json:"qtype"`
+	Action Action    `json:"action"`
+	// Reason is set when the query didn't get the upstream answer.
+	Reason string `json:"reason,omitempty"`
+	// Rcode is the response code sent to the client (e.g. NOERROR, NXDOMAIN, REFUSED).
+	Rcode string `json:"rcode"`
+	// UpstreamLatency is the upstream resolver response time, zero when the
+	// query was not forwarded.
+	UpstreamLatency time.Duration `json:"upstream_latency_ns,omitempty"`
+	// Answers are the addresses of the A and AAAA records sent to the client.
+	Answers []string `json:"answers,omitempty"`
+}
+
+// DNSEventRecorder records the DNS proxy query events.
+type DNSEventRecorder interface {
+	RecordDNSEvent(ev DNSEvent)
+}
+
+// DNSEventLogConfig is the configuration of the DNS event log.
+type DNSEventLogConfig struct {
+	// Path is the file the events are appended to as JSON lines.
+	Path string
+	// MaxBytes is the size the file is rotated at, the previous events are kept in
+	// a single rotated file (Path + ".1").
+	MaxBytes int64
+	Logger   log.Logger
+}
+
+func (c *DNSEventLogConfig) defaults() error {
+	if c.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	if c.MaxBytes <= 0 {
+		c.MaxBytes = 10 * 1024 * 1024
+	}
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	return nil
+}
+
+// DNSEventLog is a DNSEventRecorder that appends the events to a size bounded
+// JSON lines file.
+type DNSEventLog struct {
+	path     string
+	maxBytes int64
+	logger   log.Logger
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// NewDNSEventLog opens (or creates) the event log file.
+func NewDNSEventLog(cfg DNSEventLogConfig) (*DNSEventLog, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid DNS event log config: %w", err)
+	}
+
+	l := &DNSEventLog{
+		path:     cfg.Path,
+		maxBytes: cfg.MaxBytes,
+		logger:   cfg.Logger,
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func (l *DNSEventLog) open() error {
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("could not open DNS event log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("could not stat DNS event log: %w", err)
+	}
+
+	l.f = f
+	l.size = info.Size()
+	return nil
+}
+
+// RecordDNSEvent appends the event to the log, rotating it when it's full. Errors
+// are logged, the DNS answers don't depend on the event log.
+func (l *DNSEventLog) RecordDNSEvent(ev DNSEvent) {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		l.logger.Errorf("could not marshal DNS event: %v", err)
+		return
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f == nil {
+		return
+	}
+
+	if l.size+int64(len(data)) > l.maxBytes && l.size > 0 {
+		if err := l.rotate(); err != nil {
+			l.logger.Errorf("could not rotate DNS event log: %v", err)
+			return
+		}
+	}
+
+	n, err := l.f.Write(data)
+	l.size += int64(n)
+	if err != nil {
+		l.logger.Errorf("could not write DNS event: %v", err)
+	}
+}
+
+func (l *DNSEventLog) rotate() error {
+	if err := l.f.Close(); err != nil {
+		return err
+	}
+	l.f = nil
+	if err := os.Rename(l.path, l.path+".1"); err != nil {
+		return err
+	}
+	return l.open()
+}
+
+// Close closes the event log file.
+func (l *DNSEventLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
+
+// ReadDNSEvents reads the events of an event log, including the rotated ones, oldest
+// first. A missing log has no events.
+func ReadDNSEvents(path string) ([]DNSEvent, error) {
+	var events []DNSEvent
+	for _, p := range []string{path + ".1", path} {
+		evs, err := readDNSEventFile(p)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, evs...)
+	}
+
+	return events, nil
+}
+
+func readDNSEventFile(path string) ([]DNSEvent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not open DNS event log: %w", err)
+	}
+	defer f.Close()
+
+	var events []DNSEvent
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var ev DNSEvent
+		// A partially written line (e.g. the proxy was killed) is skipped.
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			continue
+		}
+		events = append(events, ev)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("could not read DNS event log: %w", err)
+	}
+
+	return events, nil
+}
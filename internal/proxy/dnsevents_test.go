@@ -0,0 +1,67 @@
+package proxy_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/proxy"
+)
+
+func TestDNSEventLog(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "dns-events.jsonl")
+	t0 := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+
+	// A missing log doesn't have events.
+	evs, err := proxy.ReadDNSEvents(path)
+	require.NoError(err)
+	assert.Empty(evs)
+
+	// Small enough to rotate on every couple of events.
+	l, err := proxy.NewDNSEventLog(proxy.DNSEventLogConfig{Path: path, MaxBytes: 300})
+	require.NoError(err)
+
+	var exp []proxy.DNSEvent
+	for i := range 6 {
+		ev := proxy.DNSEvent{
+			Time:            t0.Add(time.Duration(i) * time.Second),
+			Domain:          "github.com",
+			QType:           "A",
+			Action:          proxy.ActionAllow,
+			Rcode:           "NOERROR",
+			UpstreamLatency: time.Duration(i) * time.Millisecond,
+		}
+		l.RecordDNSEvent(ev)
+		exp = append(exp, ev)
+	}
+	require.NoError(l.Close())
+
+	// The file doesn't grow over the limit.
+	info, err := os.Stat(path)
+	require.NoError(err)
+	assert.LessOrEqual(info.Size(), int64(300))
+
+	// The latest events are kept in order, the oldest were dropped by the rotation.
+	evs, err = proxy.ReadDNSEvents(path)
+	require.NoError(err)
+	require.NotEmpty(evs)
+	assert.Less(len(evs), len(exp))
+	assert.Equal(exp[len(exp)-len(evs):], evs)
+
+	// Reopening appends to the existing log.
+	l, err = proxy.NewDNSEventLog(proxy.DNSEventLogConfig{Path: path})
+	require.NoError(err)
+	l.RecordDNSEvent(exp[0])
+	require.NoError(l.Close())
+
+	evs2, err := proxy.ReadDNSEvents(path)
+	require.NoError(err)
+	assert.Equal(append(evs, exp[0]), evs2)
+}
@@ -0,0 +1,156 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Schemes of the encrypted DNS upstream resolvers.
+const (
+	dnsUpstreamTLSScheme   = "tls://"
+	dnsUpstreamHTTPSScheme = "https://"
+)
+
+// ParseDNSUpstream validates and normalizes a DNS upstream resolver address:
+//
+//   - Plain DNS: "IP[:port]", port 53 by default.
+//   - DNS-over-TLS: "tls://host[:port]", port 853 by default.
+//   - DNS-over-HTTPS: "https://host[:port]/path".
+//
+// The plain resolvers must be IPs, the encrypted ones are resolved with the host
+// resolver and their certificate is verified.
+func ParseDNSUpstream(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, dnsUpstreamTLSScheme):
+		host, port := strings.TrimPrefix(raw, dnsUpstreamTLSScheme), "853"
+		if h, p, err := net.SplitHostPort(host); err == nil {
+			host, port = h, p
+		}
+		if host == "" || strings.ContainsAny(host, "/?#@") {
+			return "", fmt.Errorf("invalid DNS-over-TLS resolver %q", raw)
+		}
+		return dnsUpstreamTLSScheme + net.JoinHostPort(host, port), nil
+
+	case strings.HasPrefix(raw, dnsUpstreamHTTPSScheme):
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" {
+			return "", fmt.Errorf("invalid DNS-over-HTTPS resolver %q", raw)
+		}
+		return u.String(), nil
+
+	default:
+		host, port := raw, "53"
+		if h, p, err := net.SplitHostPort(raw); err == nil {
+			host, port = h, p
+		}
+		if net.ParseIP(host) == nil {
+			return "", fmt.Errorf("invalid DNS resolver %q, must be an IP, tls:// or https:// address", raw)
+		}
+		return net.JoinHostPort(host, port), nil
+	}
+}
+
+// dohMaxResponseSize is the maximum DNS-over-HTTPS response size, the maximum DNS
+// message size.
+const dohMaxResponseSize = 65535
+
+// upstreamDNSClient is the default DNSClient, it sends the queries with the
+// protocol of the upstream address (see ParseDNSUpstream).
+type upstreamDNSClient struct {
+	udp  *dns.Client
+	tcp  *dns.Client
+	tls  *dns.Client
+	http *http.Client
+}
+
+// newUpstreamDNSClient returns the upstream client, its connections are dialed with
+// dialer when set (e.g. bound to an interface, see InterfaceDialer).
+func newUpstreamDNSClient(timeout time.Duration, dialer *net.Dialer) *upstreamDNSClient {
+	if dialer == nil {
+		return &upstreamDNSClient{
+			udp:  &dns.Client{Net: "udp", Timeout: timeout},
+			tcp:  &dns.Client{Net: "tcp", Timeout: timeout},
+			tls:  &dns.Client{Net: "tcp-tls", Timeout: timeout},
+			http: &http.Client{Timeout: timeout},
+		}
+	}
+
+	d := *dialer
+	d.Timeout = timeout
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = d.DialContext
+	return &upstreamDNSClient{
+		udp:  &dns.Client{Net: "udp", Timeout: timeout, Dialer: &d},
+		tcp:  &dns.Client{Net: "tcp", Timeout: timeout, Dialer: &d},
+		tls:  &dns.Client{Net: "tcp-tls", Timeout: timeout, Dialer: &d},
+		http: &http.Client{Timeout: timeout, Transport: transport},
+	}
+}
+
+func (c *upstreamDNSClient) ExchangeContext(ctx context.Context, m *dns.Msg, address string) (*dns.Msg, time.Duration, error) {
+	switch {
+	case strings.HasPrefix(address, dnsUpstreamTLSScheme):
+		return c.tls.ExchangeContext(ctx, m, strings.TrimPrefix(address, dnsUpstreamTLSScheme))
+	case strings.HasPrefix(address, dnsUpstreamHTTPSScheme):
+		return c.exchangeHTTPS(ctx, m, address)
+	default:
+		resp, rtt, err := c.udp.ExchangeContext(ctx, m, address)
+		// Truncated answers don't fit in UDP, retry over TCP.
+		if err == nil && resp.Truncated {
+			return c.tcp.ExchangeContext(ctx, m, address)
+		}
+		return resp, rtt, err
+	}
+}
+
+// exchangeHTTPS sends the query with DNS-over-HTTPS (RFC 8484).
+func (c *upstreamDNSClient) exchangeHTTPS(ctx context.Context, m *dns.Msg, address string) (*dns.Msg, time.Duration, error) {
+	// The ID is zero to make the responses cacheable by HTTP caches.
+	q := m.Copy()
+	q.Id = 0
+	body, err := q.Pack()
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not pack DNS query: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, address, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not create DNS-over-HTTPS request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	start := time.Now()
+	httpResp, err := c.http.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("DNS-over-HTTPS request failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("DNS-over-HTTPS resolver returned status %d", httpResp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(httpResp.Body, dohMaxResponseSize))
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not read DNS-over-HTTPS response: %w", err)
+	}
+	rtt := time.Since(start)
+
+	resp := new(dns.Msg)
+	if err := resp.Unpack(data); err != nil {
+		return nil, 0, fmt.Errorf("could not unpack DNS-over-HTTPS response: %w", err)
+	}
+	resp.Id = m.Id
+
+	return resp, rtt, nil
+}
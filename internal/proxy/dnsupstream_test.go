@@ -0,0 +1,131 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDNSUpstream(t *testing.T) {
+	tests := map[string]struct {
+		upstream    string
+		expUpstream string
+		expErr      bool
+	}{
+		"An IP should use the default DNS port.": {
+			upstream:    "1.1.1.1",
+			expUpstream: "1.1.1.1:53",
+		},
+
+		"An IP with port should be kept.": {
+			upstream:    "10.0.0.53:5353",
+			expUpstream: "10.0.0.53:5353",
+		},
+
+		"An IPv6 should use the default DNS port.": {
+			upstream:    "2606:4700:4700::1111",
+			expUpstream: "[2606:4700:4700::1111]:53",
+		},
+
+		"A DNS-over-TLS host should use the default DNS-over-TLS port.": {
+			upstream:    "tls://dns.google",
+			expUpstream: "tls://dns.google:853",
+		},
+
+		"A DNS-over-TLS address with port should be kept.": {
+			upstream:    "tls://1.1.1.1:8853",
+			expUpstream: "tls://1.1.1.1:8853",
+		},
+
+		"A DNS-over-HTTPS URL should be kept.": {
+			upstream:    "https://cloudflare-dns.com/dns-query",
+			expUpstream: "https://cloudflare-dns.com/dns-query",
+		},
+
+		"A plain hostname should fail.": {
+			upstream: "dns.google",
+			expErr:   true,
+		},
+
+		"A DNS-over-TLS path should fail.": {
+			upstream: "tls://dns.google/dns-query",
+			expErr:   true,
+		},
+
+		"A DNS-over-HTTPS URL without host should fail.": {
+			upstream: "https:///dns-query",
+			expErr:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			got, err := ParseDNSUpstream(test.upstream)
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expUpstream, got)
+			}
+		})
+	}
+}
+
+func TestUpstreamDNSClientHTTPS(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/dns-query" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/dns-message" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		q := new(dns.Msg)
+		if err := q.Unpack(body); err != nil || q.Id != 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		resp := new(dns.Msg)
+		resp.SetReply(q)
+		resp.Answer = append(resp.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: q.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   net.ParseIP("93.184.216.34"),
+		})
+		data, _ := resp.Pack()
+		w.Header().Set("Content-Type", "application/dns-message")
+		_, _ = w.Write(data)
+	}))
+	defer srv.Close()
+
+	c := newUpstreamDNSClient(2*time.Second, nil)
+	c.http = srv.Client()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.com.", dns.TypeA)
+	resp, _, err := c.ExchangeContext(context.Background(), m, srv.URL+"/dns-query")
+	require.NoError(err)
+
+	// The response should have the query ID, not the zero ID sent upstream.
+	assert.Equal(m.Id, resp.Id)
+	require.Len(resp.Answer, 1)
+	assert.Equal("93.184.216.34", resp.Answer[0].(*dns.A).A.String())
+
+	// A failing resolver should fail the query.
+	_, _, err = c.ExchangeContext(context.Background(), m, srv.URL+"/failing")
+	assert.ErrorContains(err, "returned status 500")
+}
@@ -0,0 +1,64 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ErrFailClosed is returned when a fail-closed proxy denies a connection it can't
+// verify, e.g. the domain doesn't resolve or resolves to a non public address.
+var ErrFailClosed = errors.New("denied by fail-closed policy")
+
+// dialContextFunc dials a network address.
+type dialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// lookupIPFunc resolves a host to its IP addresses.
+type lookupIPFunc func(ctx context.Context, network, host string) ([]net.IP, error)
+
+// failClosedDialContext returns a dial function that resolves the target host
+// before dialing and only dials the resolved addresses if all of them are public.
+// Any doubt (resolution failure, no addresses, a loopback or private address that
+// could reach the host or its networks) denies the connection with ErrFailClosed.
+//
+// Dialing the checked addresses instead of the host prevents the domain from
+// resolving to a different address between the check and the dial.
+func failClosedDialContext(lookup lookupIPFunc, dial dialContextFunc) dialContextFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q: %w", addr, ErrFailClosed)
+		}
+
+		ips, err := lookup(ctx, "ip", host)
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve %q: %v: %w", host, err, ErrFailClosed)
+		}
+		if len(ips) == 0 {
+			return nil, fmt.Errorf("%q has no addresses: %w", host, ErrFailClosed)
+		}
+		for _, ip := range ips {
+			if !isPublicIP(ip) {
+				return nil, fmt.Errorf("%q resolves to non public address %s: %w", host, ip, ErrFailClosed)
+			}
+		}
+
+		var dialErr error
+		for _, ip := range ips {
+			conn, err := dial(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			dialErr = errors.Join(dialErr, err)
+		}
+
+		return nil, dialErr
+	}
+}
+
+// isPublicIP returns true if the IP is a global unicast address that isn't private,
+// so it can't be used to reach the host, its networks or other sandboxes.
+func isPublicIP(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate()
+}
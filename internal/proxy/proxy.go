@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/slok/sbx/internal/log"
@@ -20,6 +21,14 @@ type ProxyConfig struct {
 	Matcher     *RuleMatcher
 	Logger      log.Logger
 	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+	// FailClosed denies the allowed requests whose target can't be verified, see
+	// failClosedDialContext.
+	FailClosed bool
+	LookupIP   func(ctx context.Context, network, host string) ([]net.IP, error)
+	// Denials records the denied requests (optional).
+	Denials DenialRecorder
+	// Sessions tracks the forwarded requests and tunnels (optional).
+	Sessions *SessionTable
 }
 
 func (c *ProxyConfig) defaults() error {
@@ -35,6 +44,12 @@ func (c *ProxyConfig) defaults() error {
 	if c.DialContext == nil {
 		c.DialContext = (&net.Dialer{Timeout: 10 * time.Second}).DialContext
 	}
+	if c.LookupIP == nil {
+		c.LookupIP = net.DefaultResolver.LookupIP
+	}
+	if c.FailClosed {
+		c.DialContext = failClosedDialContext(c.LookupIP, c.DialContext)
+	}
 	return nil
 }
 
@@ -46,6 +61,8 @@ type Proxy struct {
 	matcher     *RuleMatcher
 	logger      log.Logger
 	dialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+	denials     DenialRecorder
+	sessions    *SessionTable
 }
 
 // NewProxy creates a new proxy server.
@@ -58,6 +75,8 @@ func NewProxy(cfg ProxyConfig) (*Proxy, error) {
 		matcher:     cfg.Matcher,
 		logger:      cfg.Logger,
 		dialContext: cfg.DialContext,
+		denials:     cfg.Denials,
+		sessions:    cfg.Sessions,
 	}
 
 	p.server = &http.Server{
@@ -119,6 +138,7 @@ func (p *Proxy) handleHTTP(w http.ResponseWriter, r *http.Request) {
 			"src":      r.RemoteAddr,
 			"reason":   "ip-address",
 		}).Infof("denied request")
+		recordDenial(p.denials, Denial{Protocol: "http", Target: r.Host, Reason: DenialReasonIPAddress})
 		http.Error(w, fmt.Sprintf("blocked by proxy policy (IP addresses not allowed): %s", r.Host), http.StatusForbidden)
 		return
 	}
@@ -134,6 +154,7 @@ func (p *Proxy) handleHTTP(w http.ResponseWriter, r *http.Request) {
 			"src":      r.RemoteAddr,
 			"reason":   "rule-match",
 		}).Infof("denied request")
+		recordDenial(p.denials, Denial{Protocol: "http", Domain: domain, Target: r.Host, Reason: DenialReasonRuleMatch})
 		http.Error(w, fmt.Sprintf("blocked by proxy policy: %s", r.Host), http.StatusForbidden)
 		return
 	}
@@ -164,6 +185,7 @@ func (p *Proxy) handleConnect(w http.ResponseWriter, r *http.Request) {
 			"src":      r.RemoteAddr,
 			"reason":   "ip-address",
 		}).Infof("denied request")
+		recordDenial(p.denials, Denial{Protocol: "http-connect", Target: r.Host, Reason: DenialReasonIPAddress})
 		http.Error(w, fmt.Sprintf("blocked by proxy policy (IP addresses not allowed): %s", r.Host), http.StatusForbidden)
 		return
 	}
@@ -178,6 +200,7 @@ func (p *Proxy) handleConnect(w http.ResponseWriter, r *http.Request) {
 			"src":      r.RemoteAddr,
 			"reason":   "rule-match",
 		}).Infof("denied request")
+		recordDenial(p.denials, Denial{Protocol: "http-connect", Domain: domain, Target: r.Host, Reason: DenialReasonRuleMatch})
 		http.Error(w, fmt.Sprintf("blocked by proxy policy: %s", r.Host), http.StatusForbidden)
 		return
 	}
@@ -192,6 +215,19 @@ func (p *Proxy) handleConnect(w http.ResponseWriter, r *http.Request) {
 
 	// Dial the target.
 	targetConn, err := p.dialContext(r.Context(), "tcp", r.Host)
+	if errors.Is(err, ErrFailClosed) {
+		p.logger.WithValues(log.Kv{
+			"action":   "deny",
+			"protocol": "http-connect",
+			"domain":   domain,
+			"target":   r.Host,
+			"src":      r.RemoteAddr,
+			"reason":   "fail-closed",
+		}).Infof("denied request: %v", err)
+		recordDenial(p.denials, Denial{Protocol: "http-connect", Domain: domain, Target: r.Host, Reason: DenialReasonFailClosed})
+		http.Error(w, fmt.Sprintf("blocked by proxy policy (fail-closed): %s", r.Host), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		p.logger.Errorf("failed to connect to target %s: %v", r.Host, err)
 		http.Error(w, fmt.Sprintf("failed to connect to target: %v", err), http.StatusBadGateway)
@@ -222,7 +258,7 @@ func (p *Proxy) handleConnect(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Bidirectional copy.
-	p.tunnel(clientConn, targetConn)
+	p.tunnel(clientConn, targetConn, p.sessions.start(Session{Protocol: "http-connect", Source: r.RemoteAddr, Domain: domain, Target: r.Host}))
 }
 
 // forwardHTTP forwards a plain HTTP request to the target and writes the response back.
@@ -238,6 +274,16 @@ func (p *Proxy) forwardHTTP(w http.ResponseWriter, r *http.Request) {
 	// Remove hop-by-hop headers.
 	removeHopByHopHeaders(r.Header)
 
+	session := p.sessions.start(Session{Protocol: "http", Source: r.RemoteAddr, Domain: ExtractDomain(r.Host), Target: r.URL.Host})
+	defer session.done()
+	// The requests without body keep it as http.NoBody, so they are not sent chunked.
+	if r.Body != nil && r.Body != http.NoBody {
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{countingReader{r: r.Body, n: &session.sent}, r.Body}
+	}
+
 	// Create a transport and execute the request.
 	transport := &http.Transport{
 		DialContext:           p.dialContext,
@@ -245,6 +291,19 @@ func (p *Proxy) forwardHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	resp, err := transport.RoundTrip(r)
+	if errors.Is(err, ErrFailClosed) {
+		p.logger.WithValues(log.Kv{
+			"action":   "deny",
+			"protocol": "http",
+			"method":   r.Method,
+			"url":      r.URL.String(),
+			"src":      r.RemoteAddr,
+			"reason":   "fail-closed",
+		}).Infof("denied request: %v", err)
+		recordDenial(p.denials, Denial{Protocol: "http", Domain: ExtractDomain(r.Host), Target: r.Host, Reason: DenialReasonFailClosed})
+		http.Error(w, fmt.Sprintf("blocked by proxy policy (fail-closed): %s", r.Host), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		p.logger.Errorf("failed to forward request to %s: %v", r.URL.String(), err)
 		http.Error(w, fmt.Sprintf("proxy error: %v", err), http.StatusBadGateway)
@@ -261,25 +320,28 @@ func (p *Proxy) forwardHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.WriteHeader(resp.StatusCode)
-	_, _ = io.Copy(w, resp.Body)
+	_, _ = io.Copy(w, countingReader{r: resp.Body, n: &session.received})
 }
 
-// tunnel performs bidirectional data copy between two connections.
-func (p *Proxy) tunnel(client, target net.Conn) {
+// tunnel performs bidirectional data copy between two connections, counting the
+// session traffic.
+func (p *Proxy) tunnel(client, target net.Conn, session *activeSession) {
+	defer session.done()
+
 	var wg sync.WaitGroup
 	wg.Add(2)
 
-	copyConn := func(dst, src net.Conn) {
+	copyConn := func(dst, src net.Conn, n *atomic.Uint64) {
 		defer wg.Done()
-		_, _ = io.Copy(dst, src)
+		_, _ = io.Copy(dst, countingReader{r: src, n: n})
 		// Signal the other side that we're done by closing write.
 		if tc, ok := dst.(*net.TCPConn); ok {
 			_ = tc.CloseWrite()
 		}
 	}
 
-	go copyConn(target, client)
-	go copyConn(client, target)
+	go copyConn(target, client, &session.sent)
+	go copyConn(client, target, &session.received)
 
 	wg.Wait()
 	client.Close()
@@ -502,6 +502,96 @@ func TestProxyIPAddressUnidentifiable(t *testing.T) {
 	}
 }
 
+func TestProxyDenials(t *testing.T) {
+	tests := map[string]struct {
+		request    func(t *testing.T, proxyURL string)
+		expDenials []proxy.Denial
+	}{
+		"A request denied by the rules should record the denial.": {
+			request: func(t *testing.T, proxyURL string) {
+				resp, err := newProxyClient(proxyURL).Get("http://blocked.test/path")
+				require.NoError(t, err)
+				resp.Body.Close()
+			},
+			expDenials: []proxy.Denial{
+				{Protocol: "http", Domain: "blocked.test", Target: "blocked.test", Reason: proxy.DenialReasonRuleMatch},
+			},
+		},
+
+		"A request to an IP address should record the denial.": {
+			request: func(t *testing.T, proxyURL string) {
+				resp, err := newProxyClient(proxyURL).Get("http://127.0.0.1:1/")
+				require.NoError(t, err)
+				resp.Body.Close()
+			},
+			expDenials: []proxy.Denial{
+				{Protocol: "http", Target: "127.0.0.1:1", Reason: proxy.DenialReasonIPAddress},
+			},
+		},
+
+		"A CONNECT denied by the rules should record the denial.": {
+			request: func(t *testing.T, proxyURL string) {
+				pURL, _ := url.Parse(proxyURL)
+				conn, err := net.DialTimeout("tcp", pURL.Host, 2*time.Second)
+				require.NoError(t, err)
+				defer conn.Close()
+				_, err = fmt.Fprintf(conn, "CONNECT blocked.test:443 HTTP/1.1\r\nHost: blocked.test:443\r\n\r\n")
+				require.NoError(t, err)
+				_, _ = conn.Read(make([]byte, 4096))
+			},
+			expDenials: []proxy.Denial{
+				{Protocol: "http-connect", Domain: "blocked.test", Target: "blocked.test:443", Reason: proxy.DenialReasonRuleMatch},
+			},
+		},
+
+		"An allowed request should not record denials.": {
+			request: func(t *testing.T, proxyURL string) {
+				// The upstream doesn't exist, the request fails after being allowed.
+				resp, err := newProxyClient(proxyURL).Get("http://allowed.test/")
+				require.NoError(t, err)
+				resp.Body.Close()
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			matcher, err := proxy.NewRuleMatcher(proxy.ActionAllow, []proxy.Rule{
+				{Action: proxy.ActionDeny, Domain: "blocked.test"},
+			})
+			require.NoError(err)
+
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(err)
+			addr := listener.Addr().String()
+			listener.Close()
+
+			denials := &denialRecorder{}
+			p, err := proxy.NewProxy(proxy.ProxyConfig{
+				ListenAddr: addr,
+				Matcher:    matcher,
+				Logger:     log.Noop,
+				DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+					return nil, fmt.Errorf("no network in tests")
+				},
+				Denials: denials,
+			})
+			require.NoError(err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() { _ = p.Run(ctx) }()
+			waitForPort(t, addr)
+
+			test.request(t, "http://"+addr)
+
+			assert.Equal(t, test.expDenials, denials.all())
+		})
+	}
+}
+
 func TestProxyCONNECTIPAddressBlocked(t *testing.T) {
 	// CONNECT to an IP address (no domain) should always be blocked, regardless
 	// of default policy. This prevents attackers from bypassing domain-based TLS/SNI
@@ -685,3 +775,87 @@ func TestExtractDomain(t *testing.T) {
 		})
 	}
 }
+
+func TestProxyFailClosed(t *testing.T) {
+	tests := map[string]struct {
+		lookupIPs []string
+		lookupErr error
+		expStatus int
+		expDialed string
+	}{
+		"A domain resolving to public addresses should be dialed on the resolved address.": {
+			lookupIPs: []string{"93.184.216.34"},
+			expStatus: http.StatusOK,
+			expDialed: "93.184.216.34:80",
+		},
+
+		"A domain that doesn't resolve should be denied.": {
+			lookupErr: fmt.Errorf("no such host"),
+			expStatus: http.StatusForbidden,
+		},
+
+		"A domain resolving to a loopback address should be denied.": {
+			lookupIPs: []string{"127.0.0.1"},
+			expStatus: http.StatusForbidden,
+		},
+
+		"A domain resolving to a private address should be denied.": {
+			lookupIPs: []string{"93.184.216.34", "10.0.0.5"},
+			expStatus: http.StatusForbidden,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer upstream.Close()
+			upstreamAddr := upstream.Listener.Addr().String()
+
+			matcher, err := proxy.NewRuleMatcher(proxy.ActionAllow, nil)
+			require.NoError(err)
+
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(err)
+			proxyAddr := listener.Addr().String()
+			listener.Close()
+
+			var dialed string
+			p, err := proxy.NewProxy(proxy.ProxyConfig{
+				ListenAddr: proxyAddr,
+				Matcher:    matcher,
+				Logger:     log.Noop,
+				FailClosed: true,
+				LookupIP: func(ctx context.Context, network, host string) ([]net.IP, error) {
+					var ips []net.IP
+					for _, ip := range test.lookupIPs {
+						ips = append(ips, net.ParseIP(ip))
+					}
+					return ips, test.lookupErr
+				},
+				// Route the checked address to the local upstream.
+				DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+					dialed = addr
+					return (&net.Dialer{Timeout: 5 * time.Second}).DialContext(ctx, network, upstreamAddr)
+				},
+			})
+			require.NoError(err)
+
+			ctx, ctxCancel := context.WithCancel(context.Background())
+			defer ctxCancel()
+			go func() { _ = p.Run(ctx) }()
+			waitForPort(t, proxyAddr)
+
+			resp, err := newProxyClient("http://" + proxyAddr).Get("http://allowed.example.com/")
+			require.NoError(err)
+			defer resp.Body.Close()
+
+			assert.Equal(test.expStatus, resp.StatusCode)
+			assert.Equal(test.expDialed, dialed)
+		})
+	}
+}
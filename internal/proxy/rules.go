@@ -18,6 +18,10 @@ const (
 type Rule struct {
 	Action Action `json:"action"`
 	Domain string `json:"domain"`
+	// ICMP extends an allow rule to the ICMP echo (ping) of the domain addresses.
+	ICMP bool `json:"icmp,omitempty"`
+	// NTP extends an allow rule to the NTP traffic (UDP 123) of the domain addresses.
+	NTP bool `json:"ntp,omitempty"`
 }
 
 // ParseRule parses a JSON string into a Rule.
@@ -61,15 +65,38 @@ func NewRuleMatcher(defaultPolicy Action, rules []Rule) (*RuleMatcher, error) {
 // Match evaluates the domain against rules in order and returns the action.
 // First matching rule wins. If no rule matches, returns the default policy.
 func (m *RuleMatcher) Match(domain string) Action {
+	action, _ := m.MatchRule(domain)
+	return action
+}
+
+// MatchRule is like Match but also returns the index of the matched rule, -1 when
+// no rule matches and the default policy is returned.
+func (m *RuleMatcher) MatchRule(domain string) (Action, int) {
 	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
 
-	for _, r := range m.rules {
+	for i, r := range m.rules {
 		if matchDomain(r.Domain, domain) {
-			return r.Action
+			return r.Action, i
 		}
 	}
 
-	return m.defaultPolicy
+	return m.defaultPolicy, -1
+}
+
+// MatchICMP returns true when the pings to the addresses of the domain are allowed:
+// the first matching rule allows the domain with ICMP. The default policy never
+// allows them.
+func (m *RuleMatcher) MatchICMP(domain string) bool {
+	action, i := m.MatchRule(domain)
+	return i >= 0 && action == ActionAllow && m.rules[i].ICMP
+}
+
+// MatchNTP returns true when the NTP traffic to the addresses of the domain is
+// allowed: the first matching rule allows the domain with NTP. The default policy
+// never allows it.
+func (m *RuleMatcher) MatchNTP(domain string) bool {
+	action, i := m.MatchRule(domain)
+	return i >= 0 && action == ActionAllow && m.rules[i].NTP
 }
 
 // DefaultPolicy returns the default policy of the matcher.
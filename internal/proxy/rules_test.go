@@ -19,6 +19,10 @@ func TestParseRule(t *testing.T) {
 			raw:     `{"action":"allow","domain":"*.github.com"}`,
 			expRule: proxy.Rule{Action: proxy.ActionAllow, Domain: "*.github.com"},
 		},
+		"Valid allow rule with ICMP should parse correctly.": {
+			raw:     `{"action":"allow","domain":"github.com","icmp":true}`,
+			expRule: proxy.Rule{Action: proxy.ActionAllow, Domain: "github.com", ICMP: true},
+		},
 		"Valid deny rule should parse correctly.": {
 			raw:     `{"action":"deny","domain":"evil.com"}`,
 			expRule: proxy.Rule{Action: proxy.ActionDeny, Domain: "evil.com"},
@@ -248,3 +252,87 @@ func TestRuleMatcherMatch(t *testing.T) {
 		})
 	}
 }
+
+func TestRuleMatcherMatchRule(t *testing.T) {
+	rules := []proxy.Rule{
+		{Action: proxy.ActionDeny, Domain: "evil.github.com"},
+		{Action: proxy.ActionAllow, Domain: "*.github.com"},
+	}
+
+	tests := map[string]struct {
+		domain    string
+		expAction proxy.Action
+		expIndex  int
+	}{
+		"The first matching rule should be returned.": {
+			domain:    "evil.github.com",
+			expAction: proxy.ActionDeny,
+			expIndex:  0,
+		},
+		"A later matching rule should be returned when the previous ones don't match.": {
+			domain:    "api.github.com",
+			expAction: proxy.ActionAllow,
+			expIndex:  1,
+		},
+		"No matching rule should return the default policy without index.": {
+			domain:    "github.com",
+			expAction: proxy.ActionDeny,
+			expIndex:  -1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			matcher, err := proxy.NewRuleMatcher(proxy.ActionDeny, rules)
+			require.NoError(err)
+
+			action, idx := matcher.MatchRule(test.domain)
+			assert.Equal(test.expAction, action)
+			assert.Equal(test.expIndex, idx)
+		})
+	}
+}
+
+func TestRuleMatcherMatchICMP(t *testing.T) {
+	rules := []proxy.Rule{
+		{Action: proxy.ActionDeny, Domain: "evil.github.com", ICMP: true},
+		{Action: proxy.ActionAllow, Domain: "api.github.com"},
+		{Action: proxy.ActionAllow, Domain: "*.github.com", ICMP: true},
+	}
+
+	tests := map[string]struct {
+		defaultPolicy proxy.Action
+		domain        string
+		expICMP       bool
+	}{
+		"An allow rule with ICMP should allow the pings.": {
+			defaultPolicy: proxy.ActionDeny,
+			domain:        "www.github.com",
+			expICMP:       true,
+		},
+		"An allow rule without ICMP should deny the pings.": {
+			defaultPolicy: proxy.ActionDeny,
+			domain:        "api.github.com",
+		},
+		"A deny rule with ICMP should deny the pings.": {
+			defaultPolicy: proxy.ActionDeny,
+			domain:        "evil.github.com",
+		},
+		"The allow default policy should deny the pings.": {
+			defaultPolicy: proxy.ActionAllow,
+			domain:        "example.com",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			matcher, err := proxy.NewRuleMatcher(test.defaultPolicy, rules)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expICMP, matcher.MatchICMP(test.domain))
+		})
+	}
+}
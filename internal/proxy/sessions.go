@@ -0,0 +1,232 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/slok/sbx/internal/log"
+)
+
+// Session is a connection the proxies are forwarding to its destination.
+type Session struct {
+	// Protocol is http, http-connect or tls.
+	Protocol string `json:"protocol"`
+	// Source is the client address (ip:port).
+	Source string `json:"source"`
+	Domain string `json:"domain"`
+	// Target is the dialed address (e.g. the CONNECT host, the TLS SNI and port).
+	Target    string    `json:"target"`
+	StartedAt time.Time `json:"started_at"`
+	// BytesSent are the client bytes forwarded to the target.
+	BytesSent uint64 `json:"bytes_sent"`
+	// BytesReceived are the target bytes forwarded to the client.
+	BytesReceived uint64 `json:"bytes_received"`
+}
+
+// SessionTableConfig is the configuration of the session table.
+type SessionTableConfig struct {
+	// Path is the file the active sessions are written to as a JSON array.
+	Path string
+	// Interval is how often the file is updated while there are sessions.
+	Interval time.Duration
+	Logger   log.Logger
+}
+
+func (c *SessionTableConfig) defaults() error {
+	if c.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	if c.Interval <= 0 {
+		c.Interval = time.Second
+	}
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	return nil
+}
+
+// SessionTable tracks the active sessions of the proxies and writes them to a file,
+// so other processes can know the domain and traffic of the proxied connections.
+type SessionTable struct {
+	path     string
+	interval time.Duration
+	logger   log.Logger
+
+	mu      sync.Mutex
+	nextID  uint64
+	active  map[uint64]*activeSession
+	changed bool
+}
+
+// NewSessionTable returns a new session table.
+func NewSessionTable(cfg SessionTableConfig) (*SessionTable, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid session table config: %w", err)
+	}
+
+	return &SessionTable{
+		path:     cfg.Path,
+		interval: cfg.Interval,
+		logger:   cfg.Logger,
+		active:   map[uint64]*activeSession{},
+		changed:  true,
+	}, nil
+}
+
+// Run writes the session file every interval while there are sessions (their
+// traffic changes) or they changed since the last write, until ctx is cancelled.
+// The file is removed on exit.
+func (t *SessionTable) Run(ctx context.Context) error {
+	defer func() {
+		if err := os.Remove(t.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			t.logger.Warningf("could not remove proxy session file: %v", err)
+		}
+	}()
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		t.mu.Lock()
+		write := t.changed || len(t.active) > 0
+		t.changed = false
+		t.mu.Unlock()
+
+		if write {
+			if err := t.write(); err != nil {
+				t.logger.Errorf("could not write proxy sessions: %v", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sessions returns the active sessions, oldest first.
+func (t *SessionTable) Sessions() []Session {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sessions := make([]Session, 0, len(t.active))
+	for _, s := range t.active {
+		sessions = append(sessions, s.snapshot())
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		if !sessions[i].StartedAt.Equal(sessions[j].StartedAt) {
+			return sessions[i].StartedAt.Before(sessions[j].StartedAt)
+		}
+		return sessions[i].Source < sessions[j].Source
+	})
+
+	return sessions
+}
+
+func (t *SessionTable) write() error {
+	data, err := json.Marshal(t.Sessions())
+	if err != nil {
+		return err
+	}
+
+	// Written with a rename so the readers never get a partial file.
+	tmp, err := os.CreateTemp(filepath.Dir(t.path), filepath.Base(t.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), t.path)
+}
+
+// start tracks a new session, a nil table only counts its traffic.
+func (t *SessionTable) start(s Session) *activeSession {
+	s.StartedAt = time.Now().UTC()
+	as := &activeSession{table: t, session: s}
+	if t == nil {
+		return as
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nextID++
+	as.id = t.nextID
+	t.active[as.id] = as
+	t.changed = true
+
+	return as
+}
+
+// activeSession is a tracked session with its traffic counters.
+type activeSession struct {
+	table    *SessionTable
+	id       uint64
+	session  Session
+	sent     atomic.Uint64
+	received atomic.Uint64
+}
+
+func (s *activeSession) snapshot() Session {
+	session := s.session
+	session.BytesSent = s.sent.Load()
+	session.BytesReceived = s.received.Load()
+	return session
+}
+
+// done stops tracking the session.
+func (s *activeSession) done() {
+	if s.table == nil {
+		return
+	}
+
+	s.table.mu.Lock()
+	defer s.table.mu.Unlock()
+	delete(s.table.active, s.id)
+	s.table.changed = true
+}
+
+// countingReader counts the bytes read from a reader.
+type countingReader struct {
+	r io.Reader
+	n *atomic.Uint64
+}
+
+func (c countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(uint64(n))
+	return n, err
+}
+
+// ReadSessions reads a session file, a missing file has no sessions.
+func ReadSessions(path string) ([]Session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not read proxy sessions: %w", err)
+	}
+
+	var sessions []Session
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		return nil, fmt.Errorf("could not parse proxy sessions: %w", err)
+	}
+
+	return sessions, nil
+}
@@ -0,0 +1,128 @@
+package proxy_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/proxy"
+)
+
+func TestSessionTable(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	// An echo server as the tunnel target.
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(err)
+	defer echo.Close()
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	path := filepath.Join(t.TempDir(), "proxy-sessions.json")
+	sessions, err := proxy.NewSessionTable(proxy.SessionTableConfig{Path: path, Interval: 10 * time.Millisecond})
+	require.NoError(err)
+
+	matcher, err := proxy.NewRuleMatcher(proxy.ActionAllow, nil)
+	require.NoError(err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(err)
+	proxyAddr := listener.Addr().String()
+	listener.Close()
+	p, err := proxy.NewProxy(proxy.ProxyConfig{
+		ListenAddr: proxyAddr,
+		Matcher:    matcher,
+		Logger:     log.Noop,
+		Sessions:   sessions,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, echo.Addr().String())
+		},
+	})
+	require.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	proxyDone, sessionsDone := make(chan struct{}), make(chan struct{})
+	go func() {
+		_ = p.Run(ctx)
+		close(proxyDone)
+	}()
+	go func() {
+		_ = sessions.Run(ctx)
+		close(sessionsDone)
+	}()
+	waitForPort(t, proxyAddr)
+
+	// Open a tunnel and send some data through it.
+	conn, err := net.Dial("tcp", proxyAddr)
+	require.NoError(err)
+	_, err = fmt.Fprintf(conn, "CONNECT github.com:443 HTTP/1.1\r\nHost: github.com:443\r\n\r\n")
+	require.NoError(err)
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	require.NoError(err)
+	require.Equal(http.StatusOK, resp.StatusCode)
+
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(err)
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(br, buf)
+	require.NoError(err)
+
+	// The active session is tracked and written to the file.
+	exp := proxy.Session{
+		Protocol:      "http-connect",
+		Source:        conn.LocalAddr().String(),
+		Domain:        "github.com",
+		Target:        "github.com:443",
+		BytesSent:     5,
+		BytesReceived: 5,
+	}
+	assert.Eventually(func() bool {
+		got, err := proxy.ReadSessions(path)
+		if err != nil || len(got) != 1 {
+			return false
+		}
+		got[0].StartedAt = time.Time{}
+		return got[0] == exp
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// The closed sessions are removed.
+	conn.Close()
+	assert.Eventually(func() bool {
+		got, err := proxy.ReadSessions(path)
+		return err == nil && len(got) == 0 && len(sessions.Sessions()) == 0
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// The file is removed on exit.
+	cancel()
+	<-proxyDone
+	<-sessionsDone
+	_, err = os.Stat(path)
+	assert.ErrorIs(err, os.ErrNotExist)
+
+	// A missing file doesn't have sessions.
+	got, err := proxy.ReadSessions(path)
+	require.NoError(err)
+	assert.Empty(got)
+}
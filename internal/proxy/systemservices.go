@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"fmt"
+	"slices"
+)
+
+// systemServiceDomains are the domains of the common infrastructure services the
+// sandboxes need, allowed by name with SystemServiceRules.
+var systemServiceDomains = map[string][]string{
+	// Public NTP pools and the default servers of the common distributions.
+	"ntp": {
+		"pool.ntp.org",
+		"*.pool.ntp.org",
+		"ntp.ubuntu.com",
+		"time.cloudflare.com",
+		"time.google.com",
+		"time.aws.com",
+	},
+	// OS package mirrors: apt (Debian, Ubuntu), apk (Alpine), dnf (Fedora, CentOS,
+	// Rocky) and pacman (Arch).
+	"packages": {
+		"deb.debian.org",
+		"security.debian.org",
+		"archive.ubuntu.com",
+		"*.archive.ubuntu.com",
+		"security.ubuntu.com",
+		"ports.ubuntu.com",
+		"dl-cdn.alpinelinux.org",
+		"mirrors.fedoraproject.org",
+		"*.fedoraproject.org",
+		"*.centos.org",
+		"*.rockylinux.org",
+		"*.archlinux.org",
+		"geo.mirror.pkgbuild.com",
+	},
+	// Public container registries and the storage their blobs are served from.
+	"registries": {
+		"registry-1.docker.io",
+		"auth.docker.io",
+		"index.docker.io",
+		"production.cloudflare.docker.com",
+		"ghcr.io",
+		"pkg-containers.githubusercontent.com",
+		"quay.io",
+		"*.quay.io",
+		"gcr.io",
+		"*.gcr.io",
+		"*.pkg.dev",
+		"registry.k8s.io",
+		"public.ecr.aws",
+		"mcr.microsoft.com",
+		"*.data.mcr.microsoft.com",
+	},
+}
+
+// SystemServices returns the names of the system services.
+func SystemServices() []string {
+	services := make([]string, 0, len(systemServiceDomains))
+	for s := range systemServiceDomains {
+		services = append(services, s)
+	}
+	slices.Sort(services)
+	return services
+}
+
+// SystemServiceRules returns the allow rules of the system services, the NTP ones
+// also allow NTP to the addresses of their domains. They are appended after the
+// policy rules, so the policy can still deny them.
+func SystemServiceRules(services []string) ([]Rule, error) {
+	var rules []Rule
+	for _, s := range services {
+		domains, ok := systemServiceDomains[s]
+		if !ok {
+			return nil, fmt.Errorf("unknown system service %q: must be one of %v", s, SystemServices())
+		}
+		for _, d := range domains {
+			rules = append(rules, Rule{Action: ActionAllow, Domain: d, NTP: s == "ntp"})
+		}
+	}
+	return rules, nil
+}
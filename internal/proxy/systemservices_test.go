@@ -0,0 +1,64 @@
+package proxy_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/proxy"
+)
+
+func TestSystemServiceRules(t *testing.T) {
+	tests := map[string]struct {
+		services []string
+		domain   string
+		expAllow bool
+		expNTP   bool
+		expErr   bool
+	}{
+		"The NTP service should allow the NTP pools with NTP.": {
+			services: []string{"ntp"},
+			domain:   "0.debian.pool.ntp.org",
+			expAllow: true,
+			expNTP:   true,
+		},
+		"The packages service should allow the package mirrors without NTP.": {
+			services: []string{"packages"},
+			domain:   "deb.debian.org",
+			expAllow: true,
+		},
+		"The registries service should allow the container registries.": {
+			services: []string{"ntp", "registries"},
+			domain:   "registry-1.docker.io",
+			expAllow: true,
+		},
+		"A service should not allow the domains of the other services.": {
+			services: []string{"ntp"},
+			domain:   "ghcr.io",
+		},
+		"An unknown service should fail.": {
+			services: []string{"smtp"},
+			expErr:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			rules, err := proxy.SystemServiceRules(test.services)
+			if test.expErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			matcher, err := proxy.NewRuleMatcher(proxy.ActionDeny, rules)
+			require.NoError(err)
+			assert.Equal(test.expAllow, matcher.Match(test.domain) == proxy.ActionAllow)
+			assert.Equal(test.expNTP, matcher.MatchNTP(test.domain))
+		})
+	}
+}
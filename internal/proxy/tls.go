@@ -9,6 +9,7 @@ import (
 	"net"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/slok/sbx/internal/log"
@@ -20,6 +21,14 @@ type TLSProxyConfig struct {
 	Matcher     *RuleMatcher
 	Logger      log.Logger
 	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+	// FailClosed denies the allowed connections whose target can't be verified, see
+	// failClosedDialContext.
+	FailClosed bool
+	LookupIP   func(ctx context.Context, network, host string) ([]net.IP, error)
+	// Denials records the denied connections (optional).
+	Denials DenialRecorder
+	// Sessions tracks the tunneled connections (optional).
+	Sessions *SessionTable
 }
 
 func (c *TLSProxyConfig) defaults() error {
@@ -35,6 +44,12 @@ func (c *TLSProxyConfig) defaults() error {
 	if c.DialContext == nil {
 		c.DialContext = (&net.Dialer{Timeout: 10 * time.Second}).DialContext
 	}
+	if c.LookupIP == nil {
+		c.LookupIP = net.DefaultResolver.LookupIP
+	}
+	if c.FailClosed {
+		c.DialContext = failClosedDialContext(c.LookupIP, c.DialContext)
+	}
 	return nil
 }
 
@@ -51,6 +66,8 @@ type TLSProxy struct {
 	logger      log.Logger
 	dialContext func(ctx context.Context, network, addr string) (net.Conn, error)
 	listenAddr  string
+	denials     DenialRecorder
+	sessions    *SessionTable
 }
 
 // NewTLSProxy creates a new transparent TLS proxy.
@@ -64,6 +81,8 @@ func NewTLSProxy(cfg TLSProxyConfig) (*TLSProxy, error) {
 		logger:      cfg.Logger,
 		dialContext: cfg.DialContext,
 		listenAddr:  cfg.ListenAddr,
+		denials:     cfg.Denials,
+		sessions:    cfg.Sessions,
 	}, nil
 }
 
@@ -130,6 +149,7 @@ func (t *TLSProxy) handleConn(ctx context.Context, clientConn net.Conn) {
 	// proxy's behavior in proxy.go.
 	if domain == "" {
 		t.logger.Infof("denied TLS connection to IP/empty SNI sni=%q src=%s", sni, clientConn.RemoteAddr())
+		recordDenial(t.denials, Denial{Protocol: "tls", Target: sni, Reason: DenialReasonIPAddress})
 		return
 	}
 
@@ -144,6 +164,7 @@ func (t *TLSProxy) handleConn(ctx context.Context, clientConn net.Conn) {
 			"src":      clientConn.RemoteAddr().String(),
 			"reason":   "rule-match",
 		}).Infof("denied request")
+		recordDenial(t.denials, Denial{Protocol: "tls", Domain: domain, Target: sni, Reason: DenialReasonRuleMatch})
 		return // Close connection — client sees a connection reset.
 	}
 
@@ -158,6 +179,18 @@ func (t *TLSProxy) handleConn(ctx context.Context, clientConn net.Conn) {
 	// Dial the real destination on port 443.
 	targetAddr := net.JoinHostPort(sni, "443")
 	targetConn, err := t.dialContext(ctx, "tcp", targetAddr)
+	if errors.Is(err, ErrFailClosed) {
+		t.logger.WithValues(log.Kv{
+			"action":   "deny",
+			"protocol": "tls",
+			"domain":   domain,
+			"sni":      sni,
+			"src":      clientConn.RemoteAddr().String(),
+			"reason":   "fail-closed",
+		}).Infof("denied request: %v", err)
+		recordDenial(t.denials, Denial{Protocol: "tls", Domain: domain, Target: sni, Reason: DenialReasonFailClosed})
+		return
+	}
 	if err != nil {
 		t.logger.Errorf("failed to dial target %s: %v", targetAddr, err)
 		return
@@ -170,25 +203,30 @@ func (t *TLSProxy) handleConn(ctx context.Context, clientConn net.Conn) {
 		return
 	}
 
-	// Bidirectional tunnel.
-	t.tunnel(clientConn, targetConn)
+	// Bidirectional tunnel, the replayed ClientHello is part of the session traffic.
+	session := t.sessions.start(Session{Protocol: "tls", Source: clientConn.RemoteAddr().String(), Domain: domain, Target: targetAddr})
+	session.sent.Add(uint64(len(peeked)))
+	t.tunnel(clientConn, targetConn, session)
 }
 
-// tunnel performs bidirectional data copy between two connections.
-func (t *TLSProxy) tunnel(client, target net.Conn) {
+// tunnel performs bidirectional data copy between two connections, counting the
+// session traffic.
+func (t *TLSProxy) tunnel(client, target net.Conn, session *activeSession) {
+	defer session.done()
+
 	var wg sync.WaitGroup
 	wg.Add(2)
 
-	copyConn := func(dst, src net.Conn) {
+	copyConn := func(dst, src net.Conn, n *atomic.Uint64) {
 		defer wg.Done()
-		_, _ = io.Copy(dst, src)
+		_, _ = io.Copy(dst, countingReader{r: src, n: n})
 		if tc, ok := dst.(*net.TCPConn); ok {
 			_ = tc.CloseWrite()
 		}
 	}
 
-	go copyConn(target, client)
-	go copyConn(client, target)
+	go copyConn(target, client, &session.sent)
+	go copyConn(client, target, &session.received)
 
 	wg.Wait()
 	target.Close()
@@ -0,0 +1,194 @@
+// Package reservation writes the resource reservation manifests of the sandboxes,
+// one JSON file per sandbox in the data directory, so the external schedulers
+// sharing the host (e.g. Nomad, systemd units) can account for the sbx usage when
+// placing other workloads.
+//
+// The files are written atomically, the readers never see a partial manifest. The
+// format is versioned and only gets new fields without changing the version.
+package reservation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// manifestVersion is the version of the manifest format.
+const manifestVersion = 1
+
+// manifestExt is the extension of the manifest files, named after the sandbox ID.
+const manifestExt = ".json"
+
+// Store writes the reservation manifests of a data directory.
+type Store struct {
+	dir string
+}
+
+// NewStore returns a store with the manifests in dir.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Dir returns the directory of the manifests.
+func (s *Store) Dir() string { return s.dir }
+
+// Write writes the manifest of a sandbox reservation, replacing the previous one.
+func (s *Store) Write(r model.Reservation) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("could not create reservations directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(toManifest(r), "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal reservation: %w", err)
+	}
+
+	path := s.manifestPath(r.SandboxID)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("could not write reservation: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("could not write reservation: %w", err)
+	}
+	return nil
+}
+
+// Remove removes the manifest of a sandbox, removing a missing one is not an error.
+func (s *Store) Remove(sandboxID string) error {
+	if err := os.Remove(s.manifestPath(sandboxID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("could not remove reservation: %w", err)
+	}
+	return nil
+}
+
+// Sync writes the manifests of the reservations and removes the ones of the
+// sandboxes not in them (e.g. removed by another client).
+func (s *Store) Sync(rs []model.Reservation) error {
+	keep := map[string]bool{}
+	var errs []error
+	for _, r := range rs {
+		keep[r.SandboxID] = true
+		if err := s.Write(r); err != nil {
+			errs = append(errs, fmt.Errorf("sandbox %s: %w", r.SandboxID, err))
+		}
+	}
+
+	ids, err := s.ids()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if keep[id] {
+			continue
+		}
+		if err := s.Remove(id); err != nil {
+			errs = append(errs, fmt.Errorf("sandbox %s: %w", id, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// List returns the reservations of the manifests, sorted by sandbox ID.
+func (s *Store) List() ([]model.Reservation, error) {
+	ids, err := s.ids()
+	if err != nil {
+		return nil, err
+	}
+
+	rs := make([]model.Reservation, 0, len(ids))
+	for _, id := range ids {
+		data, err := os.ReadFile(s.manifestPath(id))
+		if err != nil {
+			// Removed since it was listed.
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("could not read reservation: %w", err)
+		}
+
+		var m manifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("could not parse reservation %s: %w", id, err)
+		}
+		rs = append(rs, fromManifest(m))
+	}
+
+	return rs, nil
+}
+
+// ids returns the sandbox IDs of the manifests, sorted.
+func (s *Store) ids() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not read reservations directory: %w", err)
+	}
+
+	var ids []string
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), manifestExt) {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(e.Name(), manifestExt))
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (s *Store) manifestPath(sandboxID string) string {
+	return filepath.Join(s.dir, sandboxID+manifestExt)
+}
+
+// manifest is the reservation file format, read by the external schedulers.
+type manifest struct {
+	Version   int               `json:"version"`
+	SandboxID string            `json:"sandbox_id"`
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace,omitempty"`
+	Status    string            `json:"status"`
+	Resources manifestResources `json:"resources"`
+	Reserved  manifestResources `json:"reserved"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+type manifestResources struct {
+	VCPUs    float64 `json:"vcpus"`
+	MemoryMB int     `json:"memory_mb"`
+	DiskGB   int     `json:"disk_gb"`
+}
+
+func toManifest(r model.Reservation) manifest {
+	return manifest{
+		Version:   manifestVersion,
+		SandboxID: r.SandboxID,
+		Name:      r.Name,
+		Namespace: r.Namespace,
+		Status:    string(r.Status),
+		Resources: manifestResources{VCPUs: r.Resources.VCPUs, MemoryMB: r.Resources.MemoryMB, DiskGB: r.Resources.DiskGB},
+		Reserved:  manifestResources{VCPUs: r.Reserved.VCPUs, MemoryMB: r.Reserved.MemoryMB, DiskGB: r.Reserved.DiskGB},
+		UpdatedAt: r.UpdatedAt.UTC(),
+	}
+}
+
+func fromManifest(m manifest) model.Reservation {
+	return model.Reservation{
+		SandboxID: m.SandboxID,
+		Name:      m.Name,
+		Namespace: m.Namespace,
+		Status:    model.SandboxStatus(m.Status),
+		Resources: model.Resources{VCPUs: m.Resources.VCPUs, MemoryMB: m.Resources.MemoryMB, DiskGB: m.Resources.DiskGB},
+		Reserved:  model.Resources{VCPUs: m.Reserved.VCPUs, MemoryMB: m.Reserved.MemoryMB, DiskGB: m.Reserved.DiskGB},
+		UpdatedAt: m.UpdatedAt,
+	}
+}
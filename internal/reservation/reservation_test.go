@@ -0,0 +1,62 @@
+package reservation_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/reservation"
+)
+
+func TestStore(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	dir := filepath.Join(t.TempDir(), "reservations")
+	store := reservation.NewStore(dir)
+	now := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	r1 := model.Reservation{SandboxID: "id-1", Name: "sb-1", Status: model.SandboxStatusRunning, Resources: model.Resources{VCPUs: 2, MemoryMB: 2048, DiskGB: 10}, Reserved: model.Resources{VCPUs: 2, MemoryMB: 2048, DiskGB: 10}, UpdatedAt: now}
+	r2 := model.Reservation{SandboxID: "id-2", Name: "sb-2", Status: model.SandboxStatusStopped, Resources: model.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5}, Reserved: model.Resources{DiskGB: 5}, UpdatedAt: now}
+
+	// Listing without manifests should be empty.
+	rs, err := store.List()
+	require.NoError(err)
+	assert.Empty(rs)
+
+	require.NoError(store.Write(r1))
+	require.NoError(store.Write(r2))
+	rs, err = store.List()
+	require.NoError(err)
+	assert.Equal([]model.Reservation{r1, r2}, rs)
+
+	// The manifest should be readable by the external schedulers.
+	data, err := os.ReadFile(filepath.Join(dir, "id-1.json"))
+	require.NoError(err)
+	var m map[string]any
+	require.NoError(json.Unmarshal(data, &m))
+	assert.Equal(float64(1), m["version"])
+	assert.Equal("running", m["status"])
+	assert.Equal(map[string]any{"vcpus": float64(2), "memory_mb": float64(2048), "disk_gb": float64(10)}, m["reserved"])
+
+	// Removing should remove the manifest, also when missing.
+	require.NoError(store.Remove("id-1"))
+	require.NoError(store.Remove("id-1"))
+	rs, err = store.List()
+	require.NoError(err)
+	assert.Equal([]model.Reservation{r2}, rs)
+
+	// Syncing should write the reservations and remove the other manifests.
+	r2.Status = model.SandboxStatusRunning
+	r3 := model.Reservation{SandboxID: "id-3", Name: "sb-3", Status: model.SandboxStatusStopped, UpdatedAt: now}
+	require.NoError(store.Write(r3))
+	require.NoError(store.Sync([]model.Reservation{r1, r2}))
+	rs, err = store.List()
+	require.NoError(err)
+	assert.Equal([]model.Reservation{r1, r2}, rs)
+}
@@ -2,16 +2,59 @@ package sandbox
 
 import (
 	"context"
+	"io"
+	"io/fs"
+	"net"
+	"time"
 
 	"github.com/slok/sbx/internal/model"
 )
 
+// CreateOpts contains options for creating a sandbox.
+type CreateOpts struct {
+	// Progress receives the create steps (optional).
+	Progress model.ProgressFunc
+}
+
 // StartOpts contains options for starting a sandbox.
 type StartOpts struct {
 	// Egress configures network egress filtering. When set, a proxy process
 	// is launched alongside the VM to enforce domain-based rules.
 	// nil means no egress filtering.
 	Egress *model.EgressPolicy
+	// Timeouts are the start step timeouts, the unset ones use the engine defaults.
+	Timeouts model.StartTimeouts
+	// Progress receives the start steps (optional).
+	Progress model.ProgressFunc
+	// Quarantine starts the sandbox without network access (see Engine.Quarantine).
+	Quarantine bool
+}
+
+// StopOpts contains options for stopping a sandbox.
+type StopOpts struct {
+	// GracePeriod is the time the sandbox has to run the pre-stop hooks and shut
+	// down before being killed, zero uses the engine default.
+	GracePeriod time.Duration
+	// PreStopHooks are the shell commands run in the guest, in order, before it's
+	// shut down (e.g. flush caches, notify the agent). A failing hook doesn't stop
+	// the shutdown.
+	PreStopHooks []string
+}
+
+// RebaseOpts contains options for rebasing a sandbox.
+type RebaseOpts struct {
+	// PreservePaths are the absolute paths of the sandbox rootfs copied into the new
+	// one, replacing the files of the new base image.
+	PreservePaths []string
+	// Progress receives the rebase steps (optional).
+	Progress model.ProgressFunc
+}
+
+// ForwardOpts contains options for forwarding ports to a sandbox.
+type ForwardOpts struct {
+	// Ready is called once the local ports listen, with the bound local ports
+	// of the free (0) ones (optional).
+	Ready func(ports []model.PortMapping)
 }
 
 // Engine is the interface for sandbox lifecycle management.
@@ -20,23 +63,96 @@ type Engine interface {
 	// Checks verify that the engine has all required dependencies and permissions.
 	Check(ctx context.Context) []model.CheckResult
 
-	Create(ctx context.Context, cfg model.SandboxConfig) (*model.Sandbox, error)
-	Start(ctx context.Context, id string, opts StartOpts) error
-	Stop(ctx context.Context, id string) error
+	// Capabilities returns the optional features the engine supports. They don't
+	// depend on the host, use Check to know if the host can run the engine.
+	Capabilities() model.EngineCapabilities
+
+	Create(ctx context.Context, cfg model.SandboxConfig, opts CreateOpts) (*model.Sandbox, error)
+	// Start starts the sandbox and returns the duration of its boot phases.
+	Start(ctx context.Context, id string, opts StartOpts) ([]model.BootPhase, error)
+	// Stop runs the pre-stop hooks and shuts down the sandbox, it's killed when it
+	// doesn't stop in the grace period.
+	Stop(ctx context.Context, id string, opts StopOpts) error
 	Remove(ctx context.Context, id string) error
 	Status(ctx context.Context, id string) (*model.Sandbox, error)
 	Exec(ctx context.Context, id string, command []string, opts model.ExecOpts) (*model.ExecResult, error)
 
 	// CopyTo copies a file or directory from the local host to the sandbox.
 	// Directories are copied recursively.
-	CopyTo(ctx context.Context, id string, srcLocal string, dstRemote string) error
+	CopyTo(ctx context.Context, id string, srcLocal string, dstRemote string, opts model.CopyOpts) error
 
 	// CopyFrom copies a file or directory from the sandbox to the local host.
 	// Directories are copied recursively.
-	CopyFrom(ctx context.Context, id string, srcRemote string, dstLocal string) error
+	CopyFrom(ctx context.Context, id string, srcRemote string, dstLocal string, opts model.CopyOpts) error
+
+	// Sync makes the sandbox directory a copy of the local directory, uploading only
+	// the new and changed files.
+	Sync(ctx context.Context, id string, localDir string, remoteDir string, opts model.SyncOpts) (*model.SyncResult, error)
+
+	// ReadFile returns the content of a sandbox file.
+	ReadFile(ctx context.Context, id string, remotePath string) ([]byte, error)
+
+	// WriteFile writes data to a sandbox file, creating or truncating it, with the
+	// perm permissions. The parent directory must exist.
+	WriteFile(ctx context.Context, id string, remotePath string, data []byte, perm fs.FileMode) error
+
+	// ListDir returns the entries of a sandbox directory sorted by name.
+	ListDir(ctx context.Context, id string, remoteDir string) ([]model.FileInfo, error)
+
+	// StatFile returns the information of a sandbox file, following symlinks.
+	StatFile(ctx context.Context, id string, remotePath string) (*model.FileInfo, error)
 
 	// Forward forwards ports from localhost to the sandbox.
 	// Blocks until context is cancelled or connection drops.
 	// Not all engines support forwarding (e.g., Docker requires ports at creation time).
-	Forward(ctx context.Context, id string, ports []model.PortMapping) error
+	Forward(ctx context.Context, id string, ports []model.PortMapping, opts ForwardOpts) error
+
+	// SetMemoryTarget sets the memory (in MB) a running sandbox should keep, the rest
+	// is reclaimed by the host with the guest memory balloon. A target equal to the
+	// sandbox memory deflates the balloon giving all the memory back to the guest.
+	SetMemoryTarget(ctx context.Context, id string, targetMB int) error
+
+	// Scale changes the VCPUs and memory of a running sandbox to the ones of resources
+	// (the disk is ignored), inside the resource ceilings the sandbox booted with.
+	// Scaling resets the memory target to the new sandbox memory.
+	Scale(ctx context.Context, id string, resources model.Resources) error
+
+	// Checkpoint captures the state of a running sandbox without stopping it, the
+	// sandbox is paused while its disk, memory and VM state are saved in dir.
+	Checkpoint(ctx context.Context, id string, dir string) (*model.Checkpoint, error)
+
+	// Rebase replaces the rootfs of a stopped sandbox with a copy of the cfg base
+	// rootfs, cfg is the sandbox configuration with the new image. The current rootfs
+	// is kept when the rebase fails.
+	Rebase(ctx context.Context, id string, cfg model.SandboxConfig, opts RebaseOpts) error
+
+	// DNSEvents returns the DNS queries answered by the sandbox egress DNS proxies,
+	// oldest first. The events are kept across sandbox restarts.
+	DNSEvents(ctx context.Context, id string) ([]model.DNSEvent, error)
+
+	// Logs returns the last lines of the engine logs of a sandbox (e.g. the VMM and
+	// egress proxy logs), the logs are kept across sandbox restarts.
+	Logs(ctx context.Context, id string, lines int) ([]model.SandboxLog, error)
+
+	// NetworkBytes returns the bytes received and sent by the network interfaces of
+	// a running sandbox since it started, used to detect the idle sandboxes.
+	NetworkBytes(ctx context.Context, id string) (uint64, error)
+
+	// Quarantine cuts (enabled) or restores the network access of a running sandbox:
+	// the guest can't reach the host, other sandboxes or the internet, and the host
+	// only reaches the guest with the engine control connection (e.g. exec).
+	Quarantine(ctx context.Context, id string, enabled bool) error
+
+	// CaptureTraffic writes the packets of a running sandbox network interface to w
+	// as a pcap stream, until the context ends or the capture limits are reached.
+	CaptureTraffic(ctx context.Context, id string, w io.Writer, opts model.CaptureOpts) (*model.CaptureResult, error)
+
+	// Connections returns the active network connections of a running sandbox, with
+	// their destination domain when the engine knows it.
+	Connections(ctx context.Context, id string) ([]model.Connection, error)
+
+	// DialAPI connects to the machine API of the VMM of a running sandbox (e.g. the
+	// Firecracker API socket), an HTTP API. Engines without a VMM API return
+	// model.ErrNotValid.
+	DialAPI(ctx context.Context, id string) (net.Conn, error)
 }
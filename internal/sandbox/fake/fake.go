@@ -1,9 +1,18 @@
 package fake
 
 import (
+	"bufio"
 	"context"
 	"crypto/rand"
 	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,6 +21,7 @@ import (
 	"github.com/slok/sbx/internal/log"
 	"github.com/slok/sbx/internal/model"
 	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/tlscert"
 )
 
 // EngineConfig is the configuration for the fake engine.
@@ -59,11 +69,24 @@ func (e *Engine) Check(ctx context.Context) []model.CheckResult {
 	}
 }
 
+// Capabilities returns the features the fake engine simulates.
+func (e *Engine) Capabilities() model.EngineCapabilities {
+	return model.EngineCapabilities{
+		LiveSnapshots: true,
+		PortForward:   true,
+		MemoryBalloon: true,
+		Scaling:       true,
+		EngineAPI:     true,
+	}
+}
+
 // Create creates a new sandbox.
-func (e *Engine) Create(ctx context.Context, cfg model.SandboxConfig) (*model.Sandbox, error) {
+func (e *Engine) Create(ctx context.Context, cfg model.SandboxConfig, opts sandbox.CreateOpts) (*model.Sandbox, error) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
+	opts.Progress.ReportStep(1, 1, "create", "Creating the fake sandbox")
+
 	// Generate ULID
 	id := ulid.MustNew(ulid.Timestamp(time.Now()), rand.Reader).String()
 
@@ -83,26 +106,29 @@ func (e *Engine) Create(ctx context.Context, cfg model.SandboxConfig) (*model.Sa
 }
 
 // Start starts a sandbox.
-func (e *Engine) Start(ctx context.Context, id string, _ sandbox.StartOpts) error {
+// The fake engine has no boot phases.
+func (e *Engine) Start(ctx context.Context, id string, opts sandbox.StartOpts) ([]model.BootPhase, error) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
+	opts.Progress.ReportStep(1, 1, "boot", "Booting the fake sandbox")
+
 	// Check if sandbox exists in this engine instance
 	sandbox, ok := e.sandboxes[id]
 	if !ok {
 		// Sandbox not in memory - this is OK for integration tests where engine is stateless.
 		// Just log and return success since actual state is managed by storage layer.
 		e.logger.Debugf("Starting fake sandbox: %s (not in engine memory, assuming managed by storage)", id)
-		return nil
+		return nil, nil
 	}
 
 	if sandbox.Status == model.SandboxStatusRunning {
 		e.logger.Debugf("Sandbox %s is already running", id)
-		return nil // Idempotent
+		return nil, nil // Idempotent
 	}
 
 	if sandbox.Status != model.SandboxStatusStopped {
-		return fmt.Errorf("sandbox %s cannot be started (status: %s): %w", id, sandbox.Status, model.ErrNotValid)
+		return nil, fmt.Errorf("sandbox %s cannot be started (status: %s): %w", id, sandbox.Status, model.ErrNotValid)
 	}
 
 	now := time.Now().UTC()
@@ -112,11 +138,11 @@ func (e *Engine) Start(ctx context.Context, id string, _ sandbox.StartOpts) erro
 
 	e.logger.Infof("Started fake sandbox: %s", id)
 
-	return nil
+	return nil, nil
 }
 
-// Stop stops a sandbox.
-func (e *Engine) Stop(ctx context.Context, id string) error {
+// Stop stops a sandbox, the pre-stop hooks are only logged.
+func (e *Engine) Stop(ctx context.Context, id string, opts sandbox.StopOpts) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
@@ -134,6 +160,10 @@ func (e *Engine) Stop(ctx context.Context, id string) error {
 		return nil // Idempotent
 	}
 
+	for _, hook := range opts.PreStopHooks {
+		e.logger.Debugf("Fake pre-stop hook in sandbox %s: %s", id, hook)
+	}
+
 	now := time.Now().UTC()
 	sandbox.Status = model.SandboxStatusStopped
 	sandbox.StoppedAt = &now
@@ -204,7 +234,7 @@ func (e *Engine) Exec(ctx context.Context, id string, command []string, opts mod
 
 // CopyTo simulates copying a file or directory from the local host to the sandbox.
 // The fake engine validates inputs but doesn't actually copy anything.
-func (e *Engine) CopyTo(ctx context.Context, id string, srcLocal string, dstRemote string) error {
+func (e *Engine) CopyTo(ctx context.Context, id string, srcLocal string, dstRemote string, opts model.CopyOpts) error {
 	if srcLocal == "" {
 		return fmt.Errorf("source path cannot be empty: %w", model.ErrNotValid)
 	}
@@ -232,7 +262,7 @@ func (e *Engine) CopyTo(ctx context.Context, id string, srcLocal string, dstRemo
 
 // CopyFrom simulates copying a file or directory from the sandbox to the local host.
 // The fake engine validates inputs but doesn't actually copy anything.
-func (e *Engine) CopyFrom(ctx context.Context, id string, srcRemote string, dstLocal string) error {
+func (e *Engine) CopyFrom(ctx context.Context, id string, srcRemote string, dstLocal string, opts model.CopyOpts) error {
 	if srcRemote == "" {
 		return fmt.Errorf("source path cannot be empty: %w", model.ErrNotValid)
 	}
@@ -258,11 +288,71 @@ func (e *Engine) CopyFrom(ctx context.Context, id string, srcRemote string, dstL
 	return nil
 }
 
-// Forward simulates port forwarding from localhost to the sandbox.
-// The fake engine validates inputs and blocks until context is cancelled.
-func (e *Engine) Forward(ctx context.Context, id string, ports []model.PortMapping) error {
-	if len(ports) == 0 {
-		return fmt.Errorf("at least one port mapping is required: %w", model.ErrNotValid)
+// Sync simulates syncing a local directory into the sandbox.
+// The fake engine validates inputs but doesn't actually sync anything.
+func (e *Engine) Sync(ctx context.Context, id string, localDir string, remoteDir string, opts model.SyncOpts) (*model.SyncResult, error) {
+	if localDir == "" {
+		return nil, fmt.Errorf("source directory cannot be empty: %w", model.ErrNotValid)
+	}
+	if remoteDir == "" {
+		return nil, fmt.Errorf("destination directory cannot be empty: %w", model.ErrNotValid)
+	}
+
+	e.mu.RLock()
+	sandbox, ok := e.sandboxes[id]
+	e.mu.RUnlock()
+
+	if !ok {
+		// For stateless integration tests, just return success
+		e.logger.Debugf("Fake Sync in sandbox: %s (not in engine memory): %s -> %s", id, localDir, remoteDir)
+		return &model.SyncResult{}, nil
+	}
+
+	if sandbox.Status != model.SandboxStatusRunning {
+		return nil, fmt.Errorf("sandbox %s is not running: %w", id, model.ErrNotValid)
+	}
+
+	e.logger.Debugf("Fake Sync in sandbox %s: %s -> %s", id, localDir, remoteDir)
+	return &model.SyncResult{}, nil
+}
+
+// ReadFile simulates reading a sandbox file, the fake engine files are empty.
+func (e *Engine) ReadFile(ctx context.Context, id string, remotePath string) ([]byte, error) {
+	if err := e.checkFileOp(id, "ReadFile", remotePath); err != nil {
+		return nil, err
+	}
+	return []byte{}, nil
+}
+
+// WriteFile simulates writing a sandbox file.
+// The fake engine validates inputs but doesn't actually write anything.
+func (e *Engine) WriteFile(ctx context.Context, id string, remotePath string, data []byte, perm fs.FileMode) error {
+	return e.checkFileOp(id, "WriteFile", remotePath)
+}
+
+// ListDir simulates listing a sandbox directory, the fake engine directories are empty.
+func (e *Engine) ListDir(ctx context.Context, id string, remoteDir string) ([]model.FileInfo, error) {
+	if err := e.checkFileOp(id, "ListDir", remoteDir); err != nil {
+		return nil, err
+	}
+	return []model.FileInfo{}, nil
+}
+
+// StatFile simulates the information of a sandbox file, every path is an empty file.
+func (e *Engine) StatFile(ctx context.Context, id string, remotePath string) (*model.FileInfo, error) {
+	if err := e.checkFileOp(id, "StatFile", remotePath); err != nil {
+		return nil, err
+	}
+	return &model.FileInfo{
+		Name: path.Base(remotePath),
+		Path: remotePath,
+		Mode: 0644,
+	}, nil
+}
+
+func (e *Engine) checkFileOp(id, op, remotePath string) error {
+	if remotePath == "" {
+		return fmt.Errorf("path cannot be empty: %w", model.ErrNotValid)
 	}
 
 	e.mu.RLock()
@@ -270,19 +360,313 @@ func (e *Engine) Forward(ctx context.Context, id string, ports []model.PortMappi
 	e.mu.RUnlock()
 
 	if !ok {
-		// For stateless integration tests, just block until cancelled
-		e.logger.Debugf("Fake Forward in sandbox: %s (not in engine memory): %v", id, ports)
-		<-ctx.Done()
-		return ctx.Err()
+		// For stateless integration tests, just return success
+		e.logger.Debugf("Fake %s in sandbox: %s (not in engine memory): %s", op, id, remotePath)
+		return nil
 	}
 
 	if sandbox.Status != model.SandboxStatusRunning {
 		return fmt.Errorf("sandbox %s is not running: %w", id, model.ErrNotValid)
 	}
 
+	e.logger.Debugf("Fake %s in sandbox %s: %s", op, id, remotePath)
+	return nil
+}
+
+// Forward simulates port forwarding from localhost to the sandbox.
+// The fake engine validates inputs and blocks until context is cancelled.
+// The free (0) local ports are bound to report real ports, without accepting
+// connections.
+func (e *Engine) Forward(ctx context.Context, id string, ports []model.PortMapping, opts sandbox.ForwardOpts) error {
+	if len(ports) == 0 {
+		return fmt.Errorf("at least one port mapping is required: %w", model.ErrNotValid)
+	}
+
+	e.mu.RLock()
+	sandbox, ok := e.sandboxes[id]
+	e.mu.RUnlock()
+
+	if ok && sandbox.Status != model.SandboxStatusRunning {
+		return fmt.Errorf("sandbox %s is not running: %w", id, model.ErrNotValid)
+	}
+
+	// For stateless integration tests the sandbox is not in the engine memory.
 	e.logger.Debugf("Fake Forward in sandbox %s: %v", id, ports)
 
+	bound := make([]model.PortMapping, 0, len(ports))
+	for _, pm := range ports {
+		if pm.TLS != nil {
+			if _, err := tlscert.ServerConfig(pm.TLS.CertFile, pm.TLS.KeyFile); err != nil {
+				return fmt.Errorf("invalid tls for port %d: %w", pm.LocalPort, err)
+			}
+		}
+		if pm.LocalPort == 0 {
+			l, err := net.Listen("tcp", net.JoinHostPort(pm.ListenAddress(), "0"))
+			if err != nil {
+				return fmt.Errorf("could not bind a free local port: %w", err)
+			}
+			defer l.Close()
+			pm.LocalPort = l.Addr().(*net.TCPAddr).Port
+		}
+		bound = append(bound, pm)
+	}
+	if opts.Ready != nil {
+		opts.Ready(bound)
+	}
+
 	// Block until context is cancelled (simulating real forwarding behavior)
 	<-ctx.Done()
 	return ctx.Err()
 }
+
+// SetMemoryTarget simulates setting the memory target of a sandbox.
+// The fake engine validates inputs but doesn't reclaim any memory.
+func (e *Engine) SetMemoryTarget(ctx context.Context, id string, targetMB int) error {
+	if targetMB <= 0 {
+		return fmt.Errorf("memory target must be greater than 0: %w", model.ErrNotValid)
+	}
+
+	e.mu.RLock()
+	sandbox, ok := e.sandboxes[id]
+	e.mu.RUnlock()
+
+	if !ok {
+		// For stateless integration tests, just return success
+		e.logger.Debugf("Fake SetMemoryTarget in sandbox: %s (not in engine memory): %dMB", id, targetMB)
+		return nil
+	}
+
+	if sandbox.Status != model.SandboxStatusRunning {
+		return fmt.Errorf("sandbox %s is not running: %w", id, model.ErrNotValid)
+	}
+	if targetMB > sandbox.Config.Resources.MemoryMB {
+		return fmt.Errorf("memory target %dMB is greater than the sandbox memory (%dMB): %w", targetMB, sandbox.Config.Resources.MemoryMB, model.ErrNotValid)
+	}
+
+	e.logger.Debugf("Fake SetMemoryTarget in sandbox %s: %dMB", id, targetMB)
+	return nil
+}
+
+// Scale simulates scaling a running sandbox inside its resource ceilings.
+// The fake engine validates inputs and updates the sandbox resources in memory.
+func (e *Engine) Scale(ctx context.Context, id string, resources model.Resources) error {
+	if resources.VCPUs <= 0 || resources.MemoryMB <= 0 {
+		return fmt.Errorf("vcpus and memory must be greater than 0: %w", model.ErrNotValid)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	sandbox, ok := e.sandboxes[id]
+	if !ok {
+		// For stateless integration tests, just return success
+		e.logger.Debugf("Fake Scale in sandbox: %s (not in engine memory): %g vcpus, %dMB", id, resources.VCPUs, resources.MemoryMB)
+		return nil
+	}
+
+	if sandbox.Status != model.SandboxStatusRunning {
+		return fmt.Errorf("sandbox %s is not running: %w", id, model.ErrNotValid)
+	}
+	current := sandbox.Config.Resources
+	if resources.VCPUs > current.BootVCPUs() || resources.MemoryMB > current.BootMemoryMB() {
+		return fmt.Errorf("resources are greater than the sandbox max resources: %w", model.ErrNotValid)
+	}
+
+	sandbox.Config.Resources.VCPUs = resources.VCPUs
+	sandbox.Config.Resources.MemoryMB = resources.MemoryMB
+	sandbox.Config.Resources.MaxVCPUs = current.BootVCPUs()
+	sandbox.Config.Resources.MaxMemoryMB = current.BootMemoryMB()
+
+	e.logger.Debugf("Fake Scale in sandbox %s: %g vcpus, %dMB", id, resources.VCPUs, resources.MemoryMB)
+	return nil
+}
+
+// Checkpoint simulates capturing a running sandbox, writing empty state files in dir.
+func (e *Engine) Checkpoint(ctx context.Context, id string, dir string) (*model.Checkpoint, error) {
+	e.mu.RLock()
+	sandbox, ok := e.sandboxes[id]
+	e.mu.RUnlock()
+
+	// Sandboxes not in engine memory are accepted for stateless integration tests.
+	if ok && sandbox.Status != model.SandboxStatusRunning {
+		return nil, fmt.Errorf("sandbox %s is not running: %w", id, model.ErrNotValid)
+	}
+
+	cp := &model.Checkpoint{
+		RootFSPath:  filepath.Join(dir, "rootfs.ext4"),
+		MemoryPath:  filepath.Join(dir, "memory"),
+		VMStatePath: filepath.Join(dir, "vmstate"),
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("could not create checkpoint directory: %w", err)
+	}
+	for _, p := range []string{cp.RootFSPath, cp.MemoryPath, cp.VMStatePath} {
+		if err := os.WriteFile(p, nil, 0o644); err != nil {
+			return nil, fmt.Errorf("could not write checkpoint file: %w", err)
+		}
+	}
+
+	e.logger.Debugf("Fake Checkpoint of sandbox %s in %s", id, dir)
+	return cp, nil
+}
+
+// Rebase simulates replacing the rootfs of a stopped sandbox, updating its configuration.
+func (e *Engine) Rebase(ctx context.Context, id string, cfg model.SandboxConfig, opts sandbox.RebaseOpts) error {
+	for _, p := range opts.PreservePaths {
+		if !path.IsAbs(p) {
+			return fmt.Errorf("preserved path %q must be absolute: %w", p, model.ErrNotValid)
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	opts.Progress.ReportStep(1, 1, "rebase", "Rebasing the fake sandbox")
+
+	sandbox, ok := e.sandboxes[id]
+	if !ok {
+		// For stateless integration tests, just return success
+		e.logger.Debugf("Fake Rebase of sandbox: %s (not in engine memory)", id)
+		return nil
+	}
+	if sandbox.Status == model.SandboxStatusRunning {
+		return fmt.Errorf("sandbox %s is running: %w", id, model.ErrNotValid)
+	}
+
+	sandbox.Config = cfg
+	e.logger.Infof("Rebased fake sandbox: %s", id)
+	return nil
+}
+
+// DNSEvents returns the DNS queries of a sandbox, the fake engine has no egress
+// proxies so there are never queries.
+func (e *Engine) DNSEvents(ctx context.Context, id string) ([]model.DNSEvent, error) {
+	e.logger.Debugf("Fake DNSEvents of sandbox %s", id)
+	return []model.DNSEvent{}, nil
+}
+
+// Logs returns the engine logs of a sandbox, the fake engine has no processes so
+// there are never logs.
+func (e *Engine) Logs(ctx context.Context, id string, lines int) ([]model.SandboxLog, error) {
+	e.logger.Debugf("Fake Logs of sandbox %s", id)
+	return []model.SandboxLog{}, nil
+}
+
+// NetworkBytes simulates the network traffic of a sandbox, the fake sandboxes have none.
+func (e *Engine) NetworkBytes(ctx context.Context, id string) (uint64, error) {
+	e.logger.Debugf("Fake NetworkBytes of sandbox %s", id)
+	return 0, nil
+}
+
+// Quarantine simulates cutting the network access of a sandbox, the fake sandboxes
+// have no network.
+func (e *Engine) Quarantine(ctx context.Context, id string, enabled bool) error {
+	e.mu.RLock()
+	sandbox, ok := e.sandboxes[id]
+	e.mu.RUnlock()
+
+	// Sandboxes not in engine memory are accepted for stateless integration tests.
+	if ok && sandbox.Status != model.SandboxStatusRunning {
+		return fmt.Errorf("sandbox %s is not running: %w", id, model.ErrNotValid)
+	}
+
+	e.logger.Debugf("Fake Quarantine of sandbox %s: %t", id, enabled)
+	return nil
+}
+
+// CaptureTraffic simulates capturing the traffic of a sandbox, the fake sandboxes
+// have no network so the pcap stream has no packets.
+func (e *Engine) CaptureTraffic(ctx context.Context, id string, w io.Writer, opts model.CaptureOpts) (*model.CaptureResult, error) {
+	e.mu.RLock()
+	sandbox, ok := e.sandboxes[id]
+	e.mu.RUnlock()
+
+	// Sandboxes not in engine memory are accepted for stateless integration tests.
+	if ok && sandbox.Status != model.SandboxStatusRunning {
+		return nil, fmt.Errorf("sandbox %s is not running: %w", id, model.ErrNotValid)
+	}
+
+	// Little endian pcap file header: version 2.4, ethernet link type.
+	header := []byte{0xd4, 0xc3, 0xb2, 0xa1, 2, 0, 4, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 4, 0, 1, 0, 0, 0}
+	if _, err := w.Write(header); err != nil {
+		return nil, fmt.Errorf("could not write capture: %w", err)
+	}
+
+	iface := opts.Interface
+	if iface == "" {
+		iface = "eth0"
+	}
+	e.logger.Debugf("Fake CaptureTraffic of sandbox %s %s", id, iface)
+	return &model.CaptureResult{Interface: iface, Bytes: int64(len(header)), StartedAt: time.Now().UTC()}, nil
+}
+
+// Connections returns a single fake HTTPS connection of the sandbox.
+func (e *Engine) Connections(ctx context.Context, id string) ([]model.Connection, error) {
+	e.mu.RLock()
+	sandbox, ok := e.sandboxes[id]
+	e.mu.RUnlock()
+
+	// Sandboxes not in engine memory are accepted for stateless integration tests.
+	if ok && sandbox.Status != model.SandboxStatusRunning {
+		return nil, fmt.Errorf("sandbox %s is not running: %w", id, model.ErrNotValid)
+	}
+
+	e.logger.Debugf("Fake Connections of sandbox %s", id)
+	return []model.Connection{{
+		Protocol:      "tcp",
+		Direction:     model.ConnectionDirectionEgress,
+		Interface:     "eth0",
+		Source:        "10.0.0.2:40000",
+		Destination:   "140.82.121.4:443",
+		Domain:        "github.com",
+		State:         "ESTABLISHED",
+		Proxy:         "tls",
+		BytesSent:     1024,
+		BytesReceived: 4096,
+	}}, nil
+}
+
+// DialAPI connects to a simulated machine API, it answers the GET requests with the
+// instance information and accepts the other requests without doing anything.
+func (e *Engine) DialAPI(ctx context.Context, id string) (net.Conn, error) {
+	e.mu.RLock()
+	sandbox, ok := e.sandboxes[id]
+	e.mu.RUnlock()
+
+	// Sandboxes not in engine memory are accepted for stateless integration tests.
+	if ok && sandbox.Status != model.SandboxStatusRunning {
+		return nil, fmt.Errorf("sandbox %s is not running: %w", id, model.ErrNotValid)
+	}
+
+	e.logger.Debugf("Fake DialAPI of sandbox %s", id)
+	client, server := net.Pipe()
+	go serveFakeAPI(server, id)
+	return client, nil
+}
+
+// serveFakeAPI answers the machine API requests of a connection until it's closed.
+func serveFakeAPI(conn net.Conn, id string) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	for {
+		req, err := http.ReadRequest(r)
+		if err != nil {
+			return
+		}
+		_, _ = io.Copy(io.Discard, req.Body)
+		req.Body.Close()
+
+		res := &http.Response{StatusCode: http.StatusNoContent, ProtoMajor: 1, ProtoMinor: 1, Header: http.Header{}}
+		if req.Method == http.MethodGet {
+			body := fmt.Sprintf(`{"id":%q,"state":"Running","vmm_version":"fake","app_name":"Firecracker"}`, id)
+			res.StatusCode = http.StatusOK
+			res.Header.Set("Content-Type", "application/json")
+			res.ContentLength = int64(len(body))
+			res.Body = io.NopCloser(strings.NewReader(body))
+		}
+		if err := res.Write(conn); err != nil {
+			return
+		}
+	}
+}
@@ -28,18 +28,18 @@ func TestLifecycle(t *testing.T) {
 	eng, err := fake.NewEngine(fake.EngineConfig{Logger: log.Noop})
 	require.NoError(t, err)
 
-	sb, err := eng.Create(context.Background(), testConfig("test"))
+	sb, err := eng.Create(context.Background(), testConfig("test"), sandbox.CreateOpts{})
 	require.NoError(t, err)
 	require.Equal(t, model.SandboxStatusStopped, sb.Status)
 
-	err = eng.Start(context.Background(), sb.ID, sandbox.StartOpts{})
+	_, err = eng.Start(context.Background(), sb.ID, sandbox.StartOpts{})
 	require.NoError(t, err)
 
 	status, err := eng.Status(context.Background(), sb.ID)
 	require.NoError(t, err)
 	assert.Equal(t, model.SandboxStatusRunning, status.Status)
 
-	err = eng.Stop(context.Background(), sb.ID)
+	err = eng.Stop(context.Background(), sb.ID, sandbox.StopOpts{})
 	require.NoError(t, err)
 
 	status, err = eng.Status(context.Background(), sb.ID)
@@ -54,7 +54,7 @@ func TestExecRequiresRunningSandbox(t *testing.T) {
 	eng, err := fake.NewEngine(fake.EngineConfig{Logger: log.Noop})
 	require.NoError(t, err)
 
-	sb, err := eng.Create(context.Background(), testConfig("test"))
+	sb, err := eng.Create(context.Background(), testConfig("test"), sandbox.CreateOpts{})
 	require.NoError(t, err)
 
 	_, err = eng.Exec(context.Background(), sb.ID, []string{"echo", "ok"}, model.ExecOpts{})
@@ -0,0 +1,66 @@
+package firecracker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// sysClassNetDir is the sysfs directory of the host network interfaces.
+var sysClassNetDir = "/sys/class/net"
+
+// NetworkBytes returns the bytes received and sent by the TAP devices of a running
+// sandbox, its primary network interface and the additional ones. The counters start
+// at zero when the sandbox starts.
+func (e *Engine) NetworkBytes(ctx context.Context, id string) (uint64, error) {
+	if e.repo == nil {
+		return 0, fmt.Errorf("cannot get firecracker sandbox network bytes: repository not configured")
+	}
+	sb, err := e.repo.GetSandbox(ctx, id)
+	if err != nil {
+		return 0, fmt.Errorf("could not get sandbox: %w", err)
+	}
+	if sb.Status != model.SandboxStatusRunning || sb.TapDevice == "" {
+		return 0, fmt.Errorf("sandbox %s is not running: %w", id, model.ErrNotValid)
+	}
+
+	taps := []string{sb.TapDevice}
+	if sb.Config.FirecrackerEngine != nil {
+		for _, n := range e.allocateNICs(id, sb.Config.FirecrackerEngine.Networks) {
+			taps = append(taps, n.tapDevice)
+		}
+	}
+
+	var total uint64
+	for _, tap := range taps {
+		b, err := tapBytes(sysClassNetDir, tap)
+		if err != nil {
+			return 0, err
+		}
+		total += b
+	}
+
+	return total, nil
+}
+
+// tapBytes returns the bytes received and sent by a host network interface.
+func tapBytes(netDir, device string) (uint64, error) {
+	var total uint64
+	for _, stat := range []string{"rx_bytes", "tx_bytes"} {
+		data, err := os.ReadFile(filepath.Join(netDir, device, "statistics", stat))
+		if err != nil {
+			return 0, fmt.Errorf("could not read %s network statistics: %w", device, err)
+		}
+		n, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %s: %w", device, stat, err)
+		}
+		total += n
+	}
+	return total, nil
+}
@@ -0,0 +1,25 @@
+package firecracker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTapBytes(t *testing.T) {
+	netDir := t.TempDir()
+	statsDir := filepath.Join(netDir, "sbx-a3f2", "statistics")
+	require.NoError(t, os.MkdirAll(statsDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(statsDir, "rx_bytes"), []byte("1000\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(statsDir, "tx_bytes"), []byte("234\n"), 0o644))
+
+	got, err := tapBytes(netDir, "sbx-a3f2")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1234), got)
+
+	_, err = tapBytes(netDir, "sbx-missing")
+	assert.Error(t, err)
+}
@@ -0,0 +1,34 @@
+package firecracker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+
+	"github.com/slok/sbx/internal/conventions"
+	"github.com/slok/sbx/internal/model"
+)
+
+// DialAPI connects to the Firecracker API socket of a running sandbox.
+func (e *Engine) DialAPI(ctx context.Context, id string) (net.Conn, error) {
+	if e.repo == nil {
+		return nil, fmt.Errorf("cannot dial firecracker API: repository not configured")
+	}
+	sb, err := e.repo.GetSandbox(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("could not get sandbox: %w", err)
+	}
+	if sb.Status != model.SandboxStatusRunning {
+		return nil, fmt.Errorf("sandbox %s is not running: %w", id, model.ErrNotValid)
+	}
+
+	socketPath := filepath.Join(e.VMDir(id), conventions.SocketFile)
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to firecracker API socket: %w", err)
+	}
+
+	return conn, nil
+}
@@ -0,0 +1,59 @@
+package firecracker
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// kernelArch returns the architecture (GOARCH naming) the kernel image was built for,
+// detected from its header: an ELF vmlinux, an x86 bzImage or an arm64 Image.
+// Unknown formats return an empty architecture.
+func kernelArch(kernelPath string) (string, error) {
+	f, err := os.Open(kernelPath)
+	if err != nil {
+		return "", fmt.Errorf("could not open kernel image: %w", err)
+	}
+	defer f.Close()
+
+	header := make([]byte, 0x206)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("could not read kernel image: %w", err)
+	}
+	header = header[:n]
+
+	switch {
+	case len(header) >= 20 && bytes.HasPrefix(header, []byte(elf.ELFMAG)):
+		switch elf.Machine(binary.LittleEndian.Uint16(header[18:20])) {
+		case elf.EM_X86_64:
+			return "amd64", nil
+		case elf.EM_AARCH64:
+			return "arm64", nil
+		}
+	case len(header) >= 0x40 && bytes.Equal(header[0x38:0x3c], []byte("ARM\x64")):
+		return "arm64", nil
+	case len(header) >= 0x206 && bytes.Equal(header[0x202:0x206], []byte("HdrS")):
+		return "amd64", nil
+	}
+
+	return "", nil
+}
+
+// checkKernelArch fails when the kernel image can't boot on the host architecture,
+// e.g. an x86_64 image kernel on an ARM host.
+func checkKernelArch(kernelPath, hostArch string) error {
+	arch, err := kernelArch(kernelPath)
+	if err != nil {
+		return err
+	}
+	if arch != "" && arch != hostArch {
+		return fmt.Errorf("kernel image %s is built for %s and can't boot on this %s host: %w", kernelPath, arch, hostArch, model.ErrNotValid)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package firecracker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestCheckKernelArch(t *testing.T) {
+	elfHeader := func(machine byte) []byte {
+		h := make([]byte, 64)
+		copy(h, "\x7fELF\x02\x01\x01")
+		h[18] = machine
+		return h
+	}
+	arm64Image := make([]byte, 64)
+	copy(arm64Image[0x38:], "ARM\x64")
+	bzImage := make([]byte, 0x300)
+	copy(bzImage[0x202:], "HdrS")
+
+	tests := map[string]struct {
+		kernel   []byte
+		hostArch string
+		expErr   bool
+	}{
+		"An x86_64 vmlinux should boot on an amd64 host.": {
+			kernel:   elfHeader(62),
+			hostArch: "amd64",
+		},
+
+		"An aarch64 vmlinux should boot on an arm64 host.": {
+			kernel:   elfHeader(183),
+			hostArch: "arm64",
+		},
+
+		"An arm64 Image should boot on an arm64 host.": {
+			kernel:   arm64Image,
+			hostArch: "arm64",
+		},
+
+		"An x86_64 vmlinux should fail on an arm64 host.": {
+			kernel:   elfHeader(62),
+			hostArch: "arm64",
+			expErr:   true,
+		},
+
+		"An arm64 Image should fail on an amd64 host.": {
+			kernel:   arm64Image,
+			hostArch: "amd64",
+			expErr:   true,
+		},
+
+		"A bzImage should fail on an arm64 host.": {
+			kernel:   bzImage,
+			hostArch: "arm64",
+			expErr:   true,
+		},
+
+		"An unknown kernel format should not be checked.": {
+			kernel:   []byte("not a kernel"),
+			hostArch: "arm64",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			kernelPath := filepath.Join(t.TempDir(), "vmlinux")
+			require.NoError(t, os.WriteFile(kernelPath, test.kernel, 0o644))
+
+			err := checkKernelArch(kernelPath, test.hostArch)
+			if test.expErr {
+				assert.ErrorIs(t, err, model.ErrNotValid)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
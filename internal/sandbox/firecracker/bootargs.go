@@ -0,0 +1,96 @@
+package firecracker
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// defaultInit is the guest init binary.
+// Note: init uses /usr/sbin/sbx-init since /sbin is typically a symlink to usr/sbin.
+const defaultInit = "/usr/sbin/sbx-init"
+
+// reservedKernelArgs are the kernel arguments managed by the engine or by the boot
+// options, they can't be set with the kernel args.
+var reservedKernelArgs = map[string]string{
+	"ip":      "it's managed by the engine networking",
+	"root":    "it's managed by the engine rootfs drive",
+	"ro":      "use the read-only rootfs boot option",
+	"rw":      "use the read-only rootfs boot option",
+	"init":    "use the init boot option",
+	"console": "use the console boot option",
+}
+
+var kernelArgKeyRegexp = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// validateBootConfig validates the kernel args and boot options of the sandbox.
+func validateBootConfig(cfg model.FirecrackerEngineConfig) error {
+	for _, arg := range cfg.KernelArgs {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'") {
+			return fmt.Errorf("kernel arg %q can't be empty or have spaces or quotes: %w", arg, model.ErrNotValid)
+		}
+		key := kernelArgKey(arg)
+		if !kernelArgKeyRegexp.MatchString(key) {
+			return fmt.Errorf("kernel arg %q has an invalid key: %w", arg, model.ErrNotValid)
+		}
+		if reason, ok := reservedKernelArgs[key]; ok {
+			return fmt.Errorf("kernel arg %q can't be set, %s: %w", arg, reason, model.ErrNotValid)
+		}
+	}
+
+	if cfg.Boot.Init != "" && (!strings.HasPrefix(cfg.Boot.Init, "/") || strings.ContainsAny(cfg.Boot.Init, " \t\n\"'")) {
+		return fmt.Errorf("init %q must be an absolute path without spaces or quotes: %w", cfg.Boot.Init, model.ErrNotValid)
+	}
+
+	return nil
+}
+
+// bootArgs returns the kernel command line of the sandbox.
+//
+// The network is configured with the kernel ip= parameter, this configures networking
+// before init runs and works for any distro (Ubuntu, Alpine, etc.) without post-boot SSH config.
+// Format: ip=<client-ip>:<server-ip>:<gateway>:<netmask>:<hostname>:<device>:<autoconf>
+func bootArgs(cfg model.FirecrackerEngineConfig, vmIP, gateway string) string {
+	console := "console=ttyS0"
+	if cfg.Boot.DisableConsole {
+		// Without serial devices the kernel doesn't spend time on the console.
+		console = "8250.nr_uarts=0"
+	}
+	init := defaultInit
+	if cfg.Boot.Init != "" {
+		init = cfg.Boot.Init
+	}
+
+	args := []string{
+		console,
+		"reboot=k",
+		"panic=1",
+		"pci=off",
+		"init=" + init,
+		fmt.Sprintf("ip=%s::%s:255.255.255.0::eth0:off", vmIP, gateway),
+	}
+
+	// Override the default args with the same key, append the rest.
+	for _, arg := range cfg.KernelArgs {
+		overridden := false
+		for i, def := range args {
+			if kernelArgKey(def) == kernelArgKey(arg) {
+				args[i] = arg
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			args = append(args, arg)
+		}
+	}
+
+	return strings.Join(args, " ")
+}
+
+func kernelArgKey(arg string) string {
+	key, _, _ := strings.Cut(arg, "=")
+	return key
+}
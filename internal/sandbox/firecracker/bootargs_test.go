@@ -0,0 +1,95 @@
+package firecracker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestBootArgs(t *testing.T) {
+	const netArg = "ip=10.1.2.2::10.1.2.1:255.255.255.0::eth0:off"
+
+	tests := map[string]struct {
+		cfg     model.FirecrackerEngineConfig
+		expArgs string
+	}{
+		"Without customization the default args should be used.": {
+			expArgs: "console=ttyS0 reboot=k panic=1 pci=off init=/usr/sbin/sbx-init " + netArg,
+		},
+
+		"Kernel args should override the defaults with the same key and append the rest.": {
+			cfg:     model.FirecrackerEngineConfig{KernelArgs: []string{"panic=5", "quiet", "pci=on"}},
+			expArgs: "console=ttyS0 reboot=k panic=5 pci=on init=/usr/sbin/sbx-init " + netArg + " quiet",
+		},
+
+		"Boot options should customize the console and init.": {
+			cfg:     model.FirecrackerEngineConfig{Boot: model.BootOptions{DisableConsole: true, Init: "/sbin/init"}},
+			expArgs: "8250.nr_uarts=0 reboot=k panic=1 pci=off init=/sbin/init " + netArg,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expArgs, bootArgs(test.cfg, "10.1.2.2", "10.1.2.1"))
+		})
+	}
+}
+
+func TestValidateBootConfig(t *testing.T) {
+	tests := map[string]struct {
+		cfg    model.FirecrackerEngineConfig
+		expErr bool
+	}{
+		"An empty config should be valid.": {},
+
+		"Valid kernel args and boot options should be valid.": {
+			cfg: model.FirecrackerEngineConfig{
+				KernelArgs: []string{"quiet", "panic=5", "8250.nr_uarts=0"},
+				Boot:       model.BootOptions{ReadOnlyRootFS: true, DisableConsole: true, Init: "/sbin/init"},
+			},
+		},
+
+		"Empty kernel args should fail.": {
+			cfg:    model.FirecrackerEngineConfig{KernelArgs: []string{""}},
+			expErr: true,
+		},
+
+		"Kernel args with spaces should fail.": {
+			cfg:    model.FirecrackerEngineConfig{KernelArgs: []string{"quiet panic=5"}},
+			expErr: true,
+		},
+
+		"Kernel args with invalid keys should fail.": {
+			cfg:    model.FirecrackerEngineConfig{KernelArgs: []string{"$(reboot)=1"}},
+			expErr: true,
+		},
+
+		"Kernel args managed by the engine should fail.": {
+			cfg:    model.FirecrackerEngineConfig{KernelArgs: []string{"ip=dhcp"}},
+			expErr: true,
+		},
+
+		"Kernel args managed by the boot options should fail.": {
+			cfg:    model.FirecrackerEngineConfig{KernelArgs: []string{"init=/bin/sh"}},
+			expErr: true,
+		},
+
+		"A relative init should fail.": {
+			cfg:    model.FirecrackerEngineConfig{Boot: model.BootOptions{Init: "sbin/init"}},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validateBootConfig(test.cfg)
+			if test.expErr {
+				assert.ErrorIs(t, err, model.ErrNotValid)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
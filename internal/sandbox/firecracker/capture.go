@@ -0,0 +1,227 @@
+package firecracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// tcpdumpBinary is the packet capture tool run on the sandbox TAP devices.
+var tcpdumpBinary = "tcpdump"
+
+// errCaptureLimit stops the capture when its bytes or packets limit is reached.
+var errCaptureLimit = errors.New("capture limit reached")
+
+// CaptureTraffic runs tcpdump on the host TAP device of a running sandbox interface,
+// writing its pcap stream to w. The capture limits are applied on the packet
+// boundaries so the stream is always a valid pcap file.
+func (e *Engine) CaptureTraffic(ctx context.Context, id string, w io.Writer, opts model.CaptureOpts) (*model.CaptureResult, error) {
+	if e.repo == nil {
+		return nil, fmt.Errorf("cannot capture firecracker sandbox traffic: repository not configured")
+	}
+	sb, err := e.repo.GetSandbox(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("could not get sandbox: %w", err)
+	}
+	if sb.Status != model.SandboxStatusRunning || sb.TapDevice == "" {
+		return nil, fmt.Errorf("sandbox %s is not running: %w", id, model.ErrNotValid)
+	}
+
+	iface := opts.Interface
+	if iface == "" {
+		iface = "eth0"
+	}
+	device, err := e.captureDevice(*sb, iface)
+	if err != nil {
+		return nil, err
+	}
+
+	tcpdump, err := exec.LookPath(tcpdumpBinary)
+	if err != nil {
+		return nil, fmt.Errorf("%s not found in PATH, install it to capture the sandbox traffic: %w", tcpdumpBinary, err)
+	}
+
+	captureCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if opts.Duration > 0 {
+		captureCtx, cancel = context.WithTimeout(captureCtx, opts.Duration)
+		defer cancel()
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(captureCtx, tcpdump, tcpdumpArgs(device, opts)...)
+	// tcpdump flushes its output and exits on SIGTERM.
+	cmd.Cancel = func() error { return cmd.Process.Signal(syscall.SIGTERM) }
+	cmd.WaitDelay = 5 * time.Second
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("could not create capture output: %w", err)
+	}
+
+	startedAt := time.Now().UTC()
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("could not start capture: %w", err)
+	}
+	e.logger.Infof("Capturing Firecracker sandbox %s %s traffic on %s", id, iface, device)
+
+	pw := &pcapWriter{w: w, maxBytes: opts.MaxBytes, maxPackets: opts.MaxPackets}
+	_, copyErr := io.Copy(pw, stdout)
+	cancel()
+	waitErr := cmd.Wait()
+
+	result := &model.CaptureResult{
+		Interface:    iface,
+		Device:       device,
+		Packets:      pw.packets,
+		Bytes:        pw.bytes,
+		StartedAt:    startedAt,
+		Duration:     time.Since(startedAt),
+		LimitReached: pw.limitReached,
+	}
+
+	if copyErr != nil && !errors.Is(copyErr, errCaptureLimit) {
+		return nil, fmt.Errorf("could not write capture: %w", copyErr)
+	}
+	// The capture stopped by the caller, its duration or its limits ends with a signal.
+	if waitErr != nil && captureCtx.Err() == nil {
+		return nil, fmt.Errorf("capture failed: %w: %s", waitErr, strings.TrimSpace(stderr.String()))
+	}
+	e.logger.Debugf("Captured %d packets of Firecracker sandbox %s", pw.packets, id)
+
+	return result, nil
+}
+
+// captureDevice returns the host TAP device of a sandbox guest interface.
+func (e *Engine) captureDevice(sb model.Sandbox, iface string) (string, error) {
+	if iface == "eth0" {
+		return sb.TapDevice, nil
+	}
+
+	n, err := strconv.Atoi(strings.TrimPrefix(iface, "eth"))
+	if err != nil {
+		return "", fmt.Errorf("interface %q is not valid: %w", iface, model.ErrNotValid)
+	}
+	var nets []model.NetworkInterface
+	if sb.Config.FirecrackerEngine != nil {
+		nets = sb.Config.FirecrackerEngine.Networks
+	}
+	if n < 1 || n > len(nets) {
+		return "", fmt.Errorf("sandbox %s has no %s interface: %w", sb.Name, iface, model.ErrNotFound)
+	}
+
+	return e.allocateNICs(sb.ID, nets)[n-1].tapDevice, nil
+}
+
+// tcpdumpArgs returns the tcpdump arguments writing the packets of a device to
+// stdout as soon as they are captured, without resolving names.
+func tcpdumpArgs(device string, opts model.CaptureOpts) []string {
+	snapLen := opts.SnapLen
+	if snapLen == 0 {
+		snapLen = model.DefaultCaptureSnapLen
+	}
+
+	args := []string{"-i", device, "-n", "-U", "-s", strconv.Itoa(snapLen), "-w", "-"}
+	if filter := strings.TrimSpace(opts.Filter); filter != "" {
+		args = append(args, "--", filter)
+	}
+	return args
+}
+
+// pcap stream sizes.
+const (
+	pcapHeaderSize       = 24
+	pcapRecordHeaderSize = 16
+)
+
+// pcapWriter writes a pcap stream to w on the packet boundaries, stopping with
+// errCaptureLimit before exceeding the bytes limit or after the packets limit.
+type pcapWriter struct {
+	w          io.Writer
+	maxBytes   int64
+	maxPackets int
+
+	buf          []byte
+	order        binary.ByteOrder
+	packets      int
+	bytes        int64
+	limitReached bool
+}
+
+func (p *pcapWriter) Write(b []byte) (int, error) {
+	if p.limitReached {
+		return 0, errCaptureLimit
+	}
+	p.buf = append(p.buf, b...)
+
+	for {
+		// The file header, always written, sets the byte order of the records.
+		if p.order == nil {
+			if len(p.buf) < pcapHeaderSize {
+				return len(b), nil
+			}
+			order, err := pcapByteOrder(p.buf)
+			if err != nil {
+				return 0, err
+			}
+			if err := p.flush(pcapHeaderSize); err != nil {
+				return 0, err
+			}
+			p.order = order
+			continue
+		}
+
+		if len(p.buf) < pcapRecordHeaderSize {
+			return len(b), nil
+		}
+		size := pcapRecordHeaderSize + int(p.order.Uint32(p.buf[8:12]))
+		if len(p.buf) < size {
+			return len(b), nil
+		}
+		if p.maxBytes > 0 && p.bytes+int64(size) > p.maxBytes {
+			p.limitReached = true
+			return len(b), errCaptureLimit
+		}
+		if err := p.flush(size); err != nil {
+			return 0, err
+		}
+		p.packets++
+		if p.maxPackets > 0 && p.packets >= p.maxPackets {
+			p.limitReached = true
+			return len(b), errCaptureLimit
+		}
+	}
+}
+
+// flush writes the first n buffered bytes.
+func (p *pcapWriter) flush(n int) error {
+	if _, err := p.w.Write(p.buf[:n]); err != nil {
+		return err
+	}
+	p.bytes += int64(n)
+	p.buf = p.buf[n:]
+	return nil
+}
+
+// pcapByteOrder returns the byte order of a pcap stream from its magic number
+// (microsecond or nanosecond timestamps).
+func pcapByteOrder(header []byte) (binary.ByteOrder, error) {
+	switch binary.LittleEndian.Uint32(header) {
+	case 0xa1b2c3d4, 0xa1b23c4d:
+		return binary.LittleEndian, nil
+	case 0xd4c3b2a1, 0x4d3cb2a1:
+		return binary.BigEndian, nil
+	default:
+		return nil, fmt.Errorf("capture output is not a pcap stream")
+	}
+}
@@ -0,0 +1,182 @@
+package firecracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/storagemock"
+)
+
+// testPcap returns a little endian pcap stream with packets of a size.
+func testPcap(packets, size int) []byte {
+	var buf bytes.Buffer
+	header := make([]byte, pcapHeaderSize)
+	binary.LittleEndian.PutUint32(header, 0xa1b2c3d4)
+	buf.Write(header)
+	for i := 0; i < packets; i++ {
+		record := make([]byte, pcapRecordHeaderSize+size)
+		binary.LittleEndian.PutUint32(record[8:], uint32(size))
+		binary.LittleEndian.PutUint32(record[12:], uint32(size))
+		record[pcapRecordHeaderSize] = byte(i)
+		buf.Write(record)
+	}
+	return buf.Bytes()
+}
+
+func TestPcapWriter(t *testing.T) {
+	stream := testPcap(3, 60)
+
+	tests := map[string]struct {
+		maxBytes   int64
+		maxPackets int
+		chunk      int
+		expPackets int
+		expLimit   bool
+		expErr     bool
+	}{
+		"Without limits all the packets should be written.": {
+			chunk:      7,
+			expPackets: 3,
+		},
+		"A bytes limit should stop before the packet exceeding it.": {
+			maxBytes:   pcapHeaderSize + 2*76 + 10,
+			chunk:      50,
+			expPackets: 2,
+			expLimit:   true,
+		},
+		"A packets limit should stop after the packets.": {
+			maxPackets: 1,
+			chunk:      1000,
+			expPackets: 1,
+			expLimit:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			pw := &pcapWriter{w: &out, maxBytes: test.maxBytes, maxPackets: test.maxPackets}
+
+			var err error
+			for i := 0; i < len(stream) && err == nil; i += test.chunk {
+				_, err = pw.Write(stream[i:min(i+test.chunk, len(stream))])
+			}
+
+			if test.expLimit {
+				assert.ErrorIs(t, err, errCaptureLimit)
+			} else {
+				assert.NoError(t, err)
+			}
+			expSize := pcapHeaderSize + test.expPackets*76
+			assert.Equal(t, test.expPackets, pw.packets)
+			assert.Equal(t, int64(expSize), pw.bytes)
+			assert.Equal(t, test.expLimit, pw.limitReached)
+			assert.Equal(t, stream[:expSize], out.Bytes())
+		})
+	}
+}
+
+func TestPcapWriterNotPcap(t *testing.T) {
+	pw := &pcapWriter{w: &bytes.Buffer{}}
+	_, err := pw.Write([]byte("tcpdump: eth0: No such device exists......"))
+	assert.Error(t, err)
+}
+
+func TestTcpdumpArgs(t *testing.T) {
+	assert.Equal(t, []string{"-i", "sbx-a3f2", "-n", "-U", "-s", "262144", "-w", "-"}, tcpdumpArgs("sbx-a3f2", model.CaptureOpts{}))
+	assert.Equal(t, []string{"-i", "sbx-a3f2", "-n", "-U", "-s", "128", "-w", "-", "--", "tcp port 443"}, tcpdumpArgs("sbx-a3f2", model.CaptureOpts{Filter: " tcp port 443 ", SnapLen: 128}))
+}
+
+func TestEngineCaptureTraffic(t *testing.T) {
+	running := &model.Sandbox{
+		ID:        "test-sandbox",
+		Name:      "test",
+		Status:    model.SandboxStatusRunning,
+		TapDevice: "sbx-a3f2",
+		Config: model.SandboxConfig{FirecrackerEngine: &model.FirecrackerEngineConfig{
+			Networks: []model.NetworkInterface{{Mode: model.NetworkModeIsolated, Network: "backend"}},
+		}},
+	}
+
+	tests := map[string]struct {
+		sandbox    *model.Sandbox
+		opts       model.CaptureOpts
+		expArgs    string
+		expPackets int
+		expLimit   bool
+		expErrIs   error
+	}{
+		"Capturing should write the primary interface packets.": {
+			sandbox:    running,
+			expArgs:    "-i sbx-a3f2 -n -U -s 262144 -w -",
+			expPackets: 3,
+		},
+		"Capturing an additional interface should use its TAP device.": {
+			sandbox:    running,
+			opts:       model.CaptureOpts{Interface: "eth1", Filter: "icmp", MaxPackets: 2},
+			expArgs:    "-i %s -n -U -s 262144 -w - -- icmp",
+			expPackets: 2,
+			expLimit:   true,
+		},
+		"Capturing a missing interface should fail.": {
+			sandbox:  running,
+			opts:     model.CaptureOpts{Interface: "eth2"},
+			expErrIs: model.ErrNotFound,
+		},
+		"Capturing a stopped sandbox should fail.": {
+			sandbox:  &model.Sandbox{ID: "test-sandbox", Status: model.SandboxStatusStopped},
+			expErrIs: model.ErrNotValid,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			pcapPath := filepath.Join(dir, "capture.pcap")
+			argsPath := filepath.Join(dir, "args")
+			require.NoError(t, os.WriteFile(pcapPath, testPcap(3, 60), 0o644))
+			script := "#!/bin/sh\necho \"$@\" > " + argsPath + "\ncat " + pcapPath + "\n"
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "tcpdump"), []byte(script), 0o755))
+
+			old := tcpdumpBinary
+			tcpdumpBinary = filepath.Join(dir, "tcpdump")
+			defer func() { tcpdumpBinary = old }()
+
+			repo := &storagemock.MockRepository{}
+			repo.On("GetSandbox", mock.Anything, "test-sandbox").Return(test.sandbox, nil)
+			e, err := NewEngine(EngineConfig{DataDir: t.TempDir(), Repository: repo, Logger: log.Noop})
+			require.NoError(t, err)
+
+			var out bytes.Buffer
+			result, err := e.CaptureTraffic(context.TODO(), "test-sandbox", &out, test.opts)
+
+			if test.expErrIs != nil {
+				assert.ErrorIs(t, err, test.expErrIs)
+				return
+			}
+			require.NoError(t, err)
+
+			expArgs := test.expArgs
+			if test.opts.Interface == "eth1" {
+				expArgs = fmt.Sprintf(expArgs, e.allocateNICs("test-sandbox", running.Config.FirecrackerEngine.Networks)[0].tapDevice)
+			}
+			args, err := os.ReadFile(argsPath)
+			require.NoError(t, err)
+			assert.Equal(t, expArgs+"\n", string(args))
+			assert.Equal(t, test.expPackets, result.Packets)
+			assert.Equal(t, test.expLimit, result.LimitReached)
+			assert.Equal(t, int64(out.Len()), result.Bytes)
+		})
+	}
+}
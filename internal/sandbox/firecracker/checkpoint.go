@@ -0,0 +1,89 @@
+package firecracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/slok/sbx/internal/conventions"
+	"github.com/slok/sbx/internal/model"
+)
+
+// Checkpoint file names.
+const (
+	checkpointMemoryFile  = "memory"
+	checkpointVMStateFile = "vmstate"
+)
+
+// Checkpoint captures the state of a running sandbox without stopping it.
+//
+// The guest filesystems are synced first so the disk copy is usable on its own,
+// then the VM is paused while Firecracker writes a full snapshot (guest memory and
+// microVM state) and the rootfs is copied.
+func (e *Engine) Checkpoint(ctx context.Context, id string, dir string) (*model.Checkpoint, error) {
+	sb, err := e.Status(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sb.Status != model.SandboxStatusRunning {
+		return nil, fmt.Errorf("sandbox %s is not running: %w", id, model.ErrNotValid)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("could not create checkpoint directory: %w", err)
+	}
+
+	// Flush the guest page cache, the writes after the sync are only in the memory file.
+	if err := e.sshExec(ctx, id, "sync"); err != nil {
+		return nil, fmt.Errorf("could not sync guest filesystems: %w", err)
+	}
+
+	cp, err := e.pausedCheckpoint(ctx, e.VMDir(id), dir)
+	if err != nil {
+		return nil, err
+	}
+
+	e.logger.Infof("Checkpointed Firecracker sandbox %s (paused %s)", id, cp.Paused)
+	return cp, nil
+}
+
+// pausedCheckpoint pauses the VM, saves its snapshot and rootfs in dir and resumes it.
+// The VM is resumed even if the capture fails or the context is cancelled.
+func (e *Engine) pausedCheckpoint(ctx context.Context, vmDir, dir string) (*model.Checkpoint, error) {
+	client := e.newUnixHTTPClient(filepath.Join(vmDir, conventions.SocketFile))
+	cp := &model.Checkpoint{
+		RootFSPath:  filepath.Join(dir, conventions.RootFSFile),
+		MemoryPath:  filepath.Join(dir, checkpointMemoryFile),
+		VMStatePath: filepath.Join(dir, checkpointVMStateFile),
+	}
+
+	pausedAt := time.Now()
+	if err := e.apiPATCH(ctx, client, "/vm", VM{State: "Paused"}); err != nil {
+		return nil, fmt.Errorf("failed to pause VM: %w", err)
+	}
+
+	var captureErr error
+	snap := SnapshotCreateParams{
+		SnapshotType: "Full",
+		SnapshotPath: cp.VMStatePath,
+		MemFilePath:  cp.MemoryPath,
+	}
+	if err := e.apiPUT(ctx, client, "/snapshot/create", snap); err != nil {
+		captureErr = fmt.Errorf("failed to create VM snapshot: %w", err)
+	} else {
+		_, captureErr = e.copyRootFS(ctx, e.RootFSPath(vmDir), dir)
+	}
+
+	if err := e.apiPATCH(context.WithoutCancel(ctx), client, "/vm", VM{State: "Resumed"}); err != nil {
+		return nil, errors.Join(captureErr, fmt.Errorf("failed to resume VM: %w", err))
+	}
+	cp.Paused = time.Since(pausedAt)
+	if captureErr != nil {
+		return nil, captureErr
+	}
+
+	return cp, nil
+}
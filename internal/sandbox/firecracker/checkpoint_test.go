@@ -0,0 +1,100 @@
+package firecracker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/conventions"
+	"github.com/slok/sbx/internal/log"
+)
+
+func TestEngine_pausedCheckpoint(t *testing.T) {
+	tests := map[string]struct {
+		snapshotStatus int
+		expCalls       []string
+		expErr         bool
+	}{
+		"The VM should be paused, snapshotted, copied and resumed.": {
+			snapshotStatus: http.StatusNoContent,
+			expCalls: []string{
+				`PATCH /vm {"state":"Paused"}`,
+				"PUT /snapshot/create",
+				`PATCH /vm {"state":"Resumed"}`,
+			},
+		},
+
+		"A failed snapshot should resume the VM.": {
+			snapshotStatus: http.StatusBadRequest,
+			expCalls: []string{
+				`PATCH /vm {"state":"Paused"}`,
+				"PUT /snapshot/create",
+				`PATCH /vm {"state":"Resumed"}`,
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			vmDir := t.TempDir()
+			dir := filepath.Join(t.TempDir(), "checkpoint")
+			require.NoError(t, os.MkdirAll(dir, 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(vmDir, conventions.RootFSFile), []byte("rootfs"), 0o644))
+
+			listener, err := net.Listen("unix", filepath.Join(vmDir, conventions.SocketFile))
+			require.NoError(t, err)
+			defer listener.Close()
+
+			var mu sync.Mutex
+			var calls []string
+			var snap SnapshotCreateParams
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				mu.Lock()
+				defer mu.Unlock()
+				if r.URL.Path == "/snapshot/create" {
+					calls = append(calls, r.Method+" "+r.URL.Path)
+					_ = json.Unmarshal(body, &snap)
+					w.WriteHeader(test.snapshotStatus)
+					return
+				}
+				calls = append(calls, r.Method+" "+r.URL.Path+" "+string(body))
+				w.WriteHeader(http.StatusNoContent)
+			})
+			go func() { _ = http.Serve(listener, handler) }()
+
+			e := &Engine{logger: log.Noop}
+			cp, err := e.pausedCheckpoint(context.Background(), vmDir, dir)
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, test.expCalls, calls)
+			if test.expErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, SnapshotCreateParams{
+				SnapshotType: "Full",
+				SnapshotPath: filepath.Join(dir, "vmstate"),
+				MemFilePath:  filepath.Join(dir, "memory"),
+			}, snap)
+			assert.Equal(t, filepath.Join(dir, "memory"), cp.MemoryPath)
+			assert.Equal(t, filepath.Join(dir, "vmstate"), cp.VMStatePath)
+			got, err := os.ReadFile(cp.RootFSPath)
+			require.NoError(t, err)
+			assert.Equal(t, "rootfs", string(got))
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package firecracker
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/netip"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/slok/sbx/internal/conventions"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/proxy"
+)
+
+// conntrackFlow is a host connection tracking entry, with the addresses of the
+// direction that opened it.
+type conntrackFlow struct {
+	protocol string
+	src      netip.AddrPort
+	dst      netip.AddrPort
+	// state is the TCP state, empty for the other protocols.
+	state string
+	// origBytes are sent by the source, replyBytes by the destination.
+	origBytes  uint64
+	replyBytes uint64
+}
+
+// Connections returns the host tracked connections of a running sandbox, with the
+// domains of the egress proxy sessions and the DNS answers of the sandbox.
+func (e *Engine) Connections(ctx context.Context, id string) ([]model.Connection, error) {
+	if e.repo == nil {
+		return nil, fmt.Errorf("cannot list firecracker sandbox connections: repository not configured")
+	}
+	sb, err := e.repo.GetSandbox(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("could not get sandbox: %w", err)
+	}
+	if sb.Status != model.SandboxStatusRunning || sb.InternalIP == "" {
+		return nil, fmt.Errorf("sandbox %s is not running: %w", id, model.ErrNotValid)
+	}
+
+	ifaces := map[netip.Addr]string{}
+	if ip, err := netip.ParseAddr(sb.InternalIP); err == nil {
+		ifaces[ip] = "eth0"
+	}
+	if sb.Config.FirecrackerEngine != nil {
+		for _, n := range e.allocateNICs(sb.ID, sb.Config.FirecrackerEngine.Networks) {
+			if ip, err := netip.ParseAddr(n.vmIP); err == nil {
+				ifaces[ip] = n.id
+			}
+		}
+	}
+
+	flows, err := e.listConntrackFlows()
+	if err != nil {
+		return nil, fmt.Errorf("could not list host connection tracking: %w", err)
+	}
+
+	vmDir := e.VMDir(id)
+	sessions, err := readProxySessions(vmDir)
+	if err != nil {
+		return nil, err
+	}
+	events, err := readDNSEvents(vmDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return sandboxConnections(flows, ifaces, sessions, events), nil
+}
+
+// readProxySessions reads the forwarded connections of the egress proxies of all the
+// network interfaces in a VM directory, by source address.
+func readProxySessions(vmDir string) (map[string]proxy.Session, error) {
+	nicPaths, err := filepath.Glob(filepath.Join(vmDir, proxyFile(conventions.ProxySessionsFile, "*")))
+	if err != nil {
+		return nil, fmt.Errorf("could not list proxy session files: %w", err)
+	}
+	paths := append([]string{filepath.Join(vmDir, conventions.ProxySessionsFile)}, nicPaths...)
+
+	sessions := map[string]proxy.Session{}
+	for _, path := range paths {
+		ss, err := proxy.ReadSessions(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range ss {
+			sessions[s.Source] = s
+		}
+	}
+
+	return sessions, nil
+}
+
+// sandboxConnections returns the connections of the flows opened by or to the sandbox
+// addresses (mapped to their interface). The domains come from the proxy sessions of
+// the flow source, or the latest allowed DNS answer of the destination.
+func sandboxConnections(flows []conntrackFlow, ifaces map[netip.Addr]string, sessions map[string]proxy.Session, events []model.DNSEvent) []model.Connection {
+	domains := map[string]string{}
+	for _, ev := range events {
+		if ev.Action != model.EgressActionAllow {
+			continue
+		}
+		for _, a := range ev.Answers {
+			domains[a] = ev.Domain
+		}
+	}
+
+	conns := []model.Connection{}
+	for _, f := range flows {
+		c := model.Connection{
+			Protocol:      f.protocol,
+			Source:        flowAddress(f.src),
+			Destination:   flowAddress(f.dst),
+			State:         f.state,
+			BytesSent:     f.origBytes,
+			BytesReceived: f.replyBytes,
+		}
+		if iface, ok := ifaces[f.src.Addr()]; ok {
+			c.Direction, c.Interface = model.ConnectionDirectionEgress, iface
+		} else if iface, ok := ifaces[f.dst.Addr()]; ok {
+			c.Direction, c.Interface = model.ConnectionDirectionIngress, iface
+		} else {
+			continue
+		}
+
+		if c.Direction == model.ConnectionDirectionEgress {
+			c.Domain = domains[f.dst.Addr().String()]
+			// The proxy counts the forwarded payload, the host may not account the flows.
+			if s, ok := sessions[c.Source]; ok {
+				c.Proxy, c.BytesSent, c.BytesReceived = s.Protocol, s.BytesSent, s.BytesReceived
+				if s.Domain != "" {
+					c.Domain = s.Domain
+				}
+			}
+		}
+		conns = append(conns, c)
+	}
+
+	slices.SortStableFunc(conns, func(a, b model.Connection) int {
+		return cmp.Or(
+			cmp.Compare(a.Direction, b.Direction),
+			cmp.Compare(a.Interface, b.Interface),
+			cmp.Compare(a.Protocol, b.Protocol),
+			cmp.Compare(a.Destination, b.Destination),
+			cmp.Compare(a.Source, b.Source),
+		)
+	})
+
+	return conns
+}
+
+// flowAddress returns the address of a flow, without port for the protocols without
+// ports (e.g. ICMP).
+func flowAddress(a netip.AddrPort) string {
+	if a.Port() == 0 {
+		return a.Addr().String()
+	}
+	return a.String()
+}
+
+// conntrackProtocol returns the name of an IP protocol number.
+func conntrackProtocol(proto uint8) string {
+	switch proto {
+	case 1:
+		return "icmp"
+	case 6:
+		return "tcp"
+	case 17:
+		return "udp"
+	case 58:
+		return "icmpv6"
+	case 132:
+		return "sctp"
+	}
+	return strconv.Itoa(int(proto))
+}
+
+// conntrackTCPStates are the names of the conntrack TCP states, by value.
+var conntrackTCPStates = []string{
+	"NONE", "SYN_SENT", "SYN_RECV", "ESTABLISHED", "FIN_WAIT",
+	"CLOSE_WAIT", "LAST_ACK", "TIME_WAIT", "CLOSE", "SYN_SENT2",
+}
+
+// conntrackTCPState returns the name of a conntrack TCP state.
+func conntrackTCPState(state uint8) string {
+	if int(state) < len(conntrackTCPStates) {
+		return conntrackTCPStates[state]
+	}
+	return strconv.Itoa(int(state))
+}
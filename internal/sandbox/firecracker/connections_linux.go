@@ -0,0 +1,36 @@
+//go:build linux
+
+package firecracker
+
+import (
+	"net/netip"
+
+	"github.com/vishvananda/netlink"
+)
+
+// listConntrackFlows lists the IPv4 flows of the host connection tracking table.
+func (e *Engine) listConntrackFlows() ([]conntrackFlow, error) {
+	flows, err := netlink.ConntrackTableList(netlink.ConntrackTable, netlink.FAMILY_V4)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]conntrackFlow, 0, len(flows))
+	for _, f := range flows {
+		src, _ := netip.AddrFromSlice(f.Forward.SrcIP)
+		dst, _ := netip.AddrFromSlice(f.Forward.DstIP)
+		flow := conntrackFlow{
+			protocol:   conntrackProtocol(f.Forward.Protocol),
+			src:        netip.AddrPortFrom(src.Unmap(), f.Forward.SrcPort),
+			dst:        netip.AddrPortFrom(dst.Unmap(), f.Forward.DstPort),
+			origBytes:  f.Forward.Bytes,
+			replyBytes: f.Reverse.Bytes,
+		}
+		if tcp, ok := f.ProtoInfo.(*netlink.ProtoInfoTCP); ok {
+			flow.state = conntrackTCPState(tcp.State)
+		}
+		res = append(res, flow)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package firecracker
+
+// listConntrackFlows lists the flows of the host connection tracking table, only
+// available on Linux.
+func (e *Engine) listConntrackFlows() ([]conntrackFlow, error) {
+	return nil, errNetworkingUnsupported
+}
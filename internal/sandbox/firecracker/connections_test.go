@@ -0,0 +1,138 @@
+package firecracker
+
+import (
+	"encoding/json"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/proxy"
+)
+
+func TestSandboxConnections(t *testing.T) {
+	ifaces := map[netip.Addr]string{
+		netip.MustParseAddr("10.68.40.2"): "eth0",
+		netip.MustParseAddr("10.90.0.5"):  "eth1",
+	}
+	tcp := func(src, dst, state string, orig, reply uint64) conntrackFlow {
+		return conntrackFlow{protocol: "tcp", src: netip.MustParseAddrPort(src), dst: netip.MustParseAddrPort(dst), state: state, origBytes: orig, replyBytes: reply}
+	}
+
+	tests := map[string]struct {
+		flows    []conntrackFlow
+		sessions map[string]proxy.Session
+		events   []model.DNSEvent
+		expConns []model.Connection
+	}{
+		"Without flows there should be no connections.": {
+			expConns: []model.Connection{},
+		},
+
+		"The flows of other hosts should be ignored.": {
+			flows: []conntrackFlow{
+				tcp("10.68.41.2:40000", "1.1.1.1:443", "ESTABLISHED", 10, 20),
+				tcp("192.168.1.10:50000", "10.68.41.2:22", "ESTABLISHED", 10, 20),
+			},
+			expConns: []model.Connection{},
+		},
+
+		"The flows opened by and to the sandbox should be listed by direction and interface.": {
+			flows: []conntrackFlow{
+				tcp("10.68.40.1:50000", "10.68.40.2:22", "ESTABLISHED", 300, 400),
+				tcp("10.90.0.5:41000", "10.90.0.6:5432", "SYN_SENT", 60, 0),
+				tcp("10.68.40.2:40000", "1.1.1.1:443", "TIME_WAIT", 10, 20),
+				{protocol: "icmp", src: netip.MustParseAddrPort("10.68.40.2:0"), dst: netip.MustParseAddrPort("8.8.8.8:0"), origBytes: 84, replyBytes: 84},
+			},
+			expConns: []model.Connection{
+				{Protocol: "icmp", Direction: model.ConnectionDirectionEgress, Interface: "eth0", Source: "10.68.40.2", Destination: "8.8.8.8", BytesSent: 84, BytesReceived: 84},
+				{Protocol: "tcp", Direction: model.ConnectionDirectionEgress, Interface: "eth0", Source: "10.68.40.2:40000", Destination: "1.1.1.1:443", State: "TIME_WAIT", BytesSent: 10, BytesReceived: 20},
+				{Protocol: "tcp", Direction: model.ConnectionDirectionEgress, Interface: "eth1", Source: "10.90.0.5:41000", Destination: "10.90.0.6:5432", State: "SYN_SENT", BytesSent: 60},
+				{Protocol: "tcp", Direction: model.ConnectionDirectionIngress, Interface: "eth0", Source: "10.68.40.1:50000", Destination: "10.68.40.2:22", State: "ESTABLISHED", BytesSent: 300, BytesReceived: 400},
+			},
+		},
+
+		"The proxied flows should have the proxy session domain and traffic.": {
+			flows: []conntrackFlow{
+				tcp("10.68.40.2:40000", "140.82.121.4:443", "ESTABLISHED", 0, 0),
+			},
+			sessions: map[string]proxy.Session{
+				"10.68.40.2:40000": {Protocol: "tls", Source: "10.68.40.2:40000", Domain: "github.com", BytesSent: 517, BytesReceived: 5000},
+			},
+			events: []model.DNSEvent{
+				{Domain: "other.github.com", Action: model.EgressActionAllow, Answers: []string{"140.82.121.4"}},
+			},
+			expConns: []model.Connection{
+				{Protocol: "tcp", Direction: model.ConnectionDirectionEgress, Interface: "eth0", Source: "10.68.40.2:40000", Destination: "140.82.121.4:443", Domain: "github.com", State: "ESTABLISHED", Proxy: "tls", BytesSent: 517, BytesReceived: 5000},
+			},
+		},
+
+		"The direct flows should have the latest allowed DNS answer domain.": {
+			flows: []conntrackFlow{
+				tcp("10.68.40.2:40000", "140.82.121.4:22", "ESTABLISHED", 10, 20),
+				tcp("10.68.40.2:40001", "104.16.0.1:22", "ESTABLISHED", 10, 20),
+			},
+			events: []model.DNSEvent{
+				{Domain: "old.github.com", Action: model.EgressActionAllow, Answers: []string{"140.82.121.4"}},
+				{Domain: "github.com", Action: model.EgressActionAllow, Answers: []string{"140.82.121.3", "140.82.121.4"}},
+				{Domain: "denied.com", Action: model.EgressActionDeny, Answers: []string{"104.16.0.1"}},
+			},
+			expConns: []model.Connection{
+				{Protocol: "tcp", Direction: model.ConnectionDirectionEgress, Interface: "eth0", Source: "10.68.40.2:40001", Destination: "104.16.0.1:22", State: "ESTABLISHED", BytesSent: 10, BytesReceived: 20},
+				{Protocol: "tcp", Direction: model.ConnectionDirectionEgress, Interface: "eth0", Source: "10.68.40.2:40000", Destination: "140.82.121.4:22", Domain: "github.com", State: "ESTABLISHED", BytesSent: 10, BytesReceived: 20},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			got := sandboxConnections(test.flows, ifaces, test.sessions, test.events)
+			assert.Equal(test.expConns, got)
+		})
+	}
+}
+
+func TestReadProxySessions(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	vmDir := t.TempDir()
+
+	// Without session files there are no sessions.
+	got, err := readProxySessions(vmDir)
+	require.NoError(err)
+	assert.Empty(got)
+
+	// The sessions of all the network interface proxies are read.
+	write := func(name string, sessions []proxy.Session) {
+		data, err := json.Marshal(sessions)
+		require.NoError(err)
+		require.NoError(os.WriteFile(filepath.Join(vmDir, name), data, 0o644))
+	}
+	write("proxy-sessions.json", []proxy.Session{{Protocol: "tls", Source: "10.68.40.2:40000", Domain: "github.com"}})
+	write("proxy-sessions-eth1.json", []proxy.Session{{Protocol: "http", Source: "10.90.0.5:41000", Domain: "example.com"}})
+
+	got, err = readProxySessions(vmDir)
+	require.NoError(err)
+	assert.Equal(map[string]proxy.Session{
+		"10.68.40.2:40000": {Protocol: "tls", Source: "10.68.40.2:40000", Domain: "github.com"},
+		"10.90.0.5:41000":  {Protocol: "http", Source: "10.90.0.5:41000", Domain: "example.com"},
+	}, got)
+}
+
+func TestConntrackNames(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("tcp", conntrackProtocol(6))
+	assert.Equal("udp", conntrackProtocol(17))
+	assert.Equal("47", conntrackProtocol(47))
+	assert.Equal("ESTABLISHED", conntrackTCPState(3))
+	assert.Equal("TIME_WAIT", conntrackTCPState(7))
+	assert.Equal("42", conntrackTCPState(42))
+}
@@ -8,14 +8,17 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"time"
 
 	"github.com/oklog/ulid/v2"
 
 	"github.com/slok/sbx/internal/conventions"
+	"github.com/slok/sbx/internal/hostlock"
 	"github.com/slok/sbx/internal/log"
 	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
 	"github.com/slok/sbx/internal/ssh"
 	"github.com/slok/sbx/internal/storage"
 )
@@ -24,11 +27,35 @@ import (
 type EngineConfig struct {
 	// DataDir is the base directory for sbx data (default: ~/.sbx).
 	DataDir string
+	// VMsDir is the directory of the sandbox VMs, their rootfs copies, keys and
+	// runtime files (default: DataDir/vms).
+	VMsDir string
 	// FirecrackerBinary is the path to the firecracker binary.
 	// If empty, it will be looked up in PATH and ./bin.
 	FirecrackerBinary string
 	// Repository is the sandbox storage repository (required for Start to read sandbox config).
 	Repository storage.Repository
+	// SSHPool reuses the sandbox SSH connections between operations (exec, copy,
+	// forward). If nil, each operation dials its own connection.
+	SSHPool *ssh.Pool
+	// DNSUpstreams are the default resolvers of the egress DNS proxies, used when
+	// the egress policy doesn't set its own. Empty uses the proxy default.
+	DNSUpstreams []string
+	// EgressInterface is the host network interface the NAT traffic of the sandboxes
+	// goes out through, used by the sandboxes without their own. Empty uses the
+	// default route.
+	EgressInterface string
+	// EventSinks receive the egress denial and proxy crash events of the sandboxes
+	// started by the engine (optional).
+	EventSinks []model.EventSinkConfig
+	// HostLocks coordinates the changes of the host resources (VM directories, TAP
+	// devices, nftables) with the other clients of the data directory
+	// (default: the locks of DataDir).
+	HostLocks *hostlock.Manager
+	// Retry are the retry policies of the transient failures (guest SSH dial,
+	// Firecracker API socket, nftables busy), the unset values use the engine
+	// defaults.
+	Retry model.RetryPolicies
 	// Logger for logging.
 	Logger log.Logger
 }
@@ -41,6 +68,21 @@ func (c *EngineConfig) defaults() error {
 		}
 		c.DataDir = filepath.Join(home, conventions.DefaultDataDir)
 	}
+	if c.VMsDir == "" {
+		c.VMsDir = conventions.VMsPath(c.DataDir)
+	}
+	if c.HostLocks == nil {
+		c.HostLocks = hostlock.NewManager(conventions.HostLocksPath(c.DataDir), "")
+	}
+	if c.EgressInterface != "" {
+		if err := model.ValidateHostInterface(c.EgressInterface); err != nil {
+			return err
+		}
+	}
+	if err := c.Retry.Validate(); err != nil {
+		return err
+	}
+	c.Retry = defaultRetryPolicies.Merge(c.Retry)
 	if c.Logger == nil {
 		c.Logger = log.Noop
 	}
@@ -51,9 +93,16 @@ func (c *EngineConfig) defaults() error {
 // Engine is the Firecracker implementation of the sandbox.Engine interface.
 type Engine struct {
 	dataDir           string
+	vmsDir            string
 	firecrackerBinary string
 	repo              storage.Repository
+	sshPool           *ssh.Pool
 	sshKeyManager     *ssh.KeyManager
+	dnsUpstreams      []string
+	egressInterface   string
+	eventSinks        []model.EventSinkConfig
+	hostLocks         *hostlock.Manager
+	retries           model.RetryPolicies
 	logger            log.Logger
 }
 
@@ -65,21 +114,28 @@ func NewEngine(cfg EngineConfig) (*Engine, error) {
 
 	return &Engine{
 		dataDir:           cfg.DataDir,
+		vmsDir:            cfg.VMsDir,
 		firecrackerBinary: cfg.FirecrackerBinary,
 		repo:              cfg.Repository,
-		sshKeyManager:     ssh.NewKeyManager(cfg.DataDir),
+		sshPool:           cfg.SSHPool,
+		sshKeyManager:     ssh.NewKeyManager(cfg.VMsDir),
+		dnsUpstreams:      cfg.DNSUpstreams,
+		egressInterface:   cfg.EgressInterface,
+		eventSinks:        cfg.EventSinks,
+		hostLocks:         cfg.HostLocks,
+		retries:           cfg.Retry,
 		logger:            cfg.Logger,
 	}, nil
 }
 
 // VMDir returns the directory for a specific VM.
 func (e *Engine) VMDir(sandboxID string) string {
-	return conventions.VMDir(e.dataDir, sandboxID)
+	return conventions.VMDir(e.vmsDir, sandboxID)
 }
 
 // ImagesPath returns the path to the images directory.
 func (e *Engine) ImagesPath() string {
-	return filepath.Join(e.dataDir, conventions.ImagesDir)
+	return conventions.ImagesPath(e.dataDir)
 }
 
 // newSSHClient creates a connected SSH client for the given sandbox.
@@ -105,6 +161,34 @@ func (e *Engine) newSSHClient(ctx context.Context, sandboxID string) (*ssh.Clien
 	return client, nil
 }
 
+// sshClient returns a connected SSH client for the given sandbox and the function
+// to release it once the operation ends. With an SSH pool the connection is reused,
+// otherwise it's closed on release.
+func (e *Engine) sshClient(ctx context.Context, sandboxID string) (*ssh.Client, func(), error) {
+	if e.sshPool == nil {
+		client, err := e.newSSHClient(ctx, sandboxID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return client, func() { client.Close() }, nil
+	}
+
+	client, err := e.sshPool.Get(ctx, sandboxID, func(ctx context.Context) (*ssh.Client, error) {
+		return e.newSSHClient(ctx, sandboxID)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, func() {}, nil
+}
+
+// forgetSSHClient drops the pooled SSH connection of the sandbox, if any.
+func (e *Engine) forgetSSHClient(sandboxID string) {
+	if e.sshPool != nil {
+		e.sshPool.Remove(sandboxID)
+	}
+}
+
 // newSSHClientWithTimeout creates a connected SSH client with a custom timeout.
 func (e *Engine) newSSHClientWithTimeout(ctx context.Context, sandboxID string, timeout time.Duration) (*ssh.Client, error) {
 	_, _, vmIP, _ := e.allocateNetwork(sandboxID)
@@ -132,8 +216,27 @@ func (e *Engine) newSSHClientWithTimeout(ctx context.Context, sandboxID string,
 func (e *Engine) Check(ctx context.Context) []model.CheckResult {
 	var results []model.CheckResult
 
+	// Firecracker only runs on Linux, the other checks don't apply.
+	if runtime.GOOS != "linux" {
+		return []model.CheckResult{{
+			ID:      "host_os",
+			Message: fmt.Sprintf("Firecracker requires a Linux host with KVM, this host is %s (use --remote to run sbx on a Linux host)", runtime.GOOS),
+			Status:  model.CheckStatusError,
+		}}
+	}
+
+	// Firecracker only supports x86_64 and aarch64 hosts.
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+		return []model.CheckResult{{
+			ID:      "host_arch",
+			Message: fmt.Sprintf("Firecracker requires an x86_64 or aarch64 host, this host is %s", runtime.GOARCH),
+			Status:  model.CheckStatusError,
+		}}
+	}
+
 	// Check 1: KVM available
-	results = append(results, e.checkKVM())
+	kvm := e.checkKVM()
+	results = append(results, kvm)
 
 	// Check 2: Firecracker binary
 	results = append(results, e.checkFirecrackerBinary())
@@ -144,9 +247,30 @@ func (e *Engine) Check(ctx context.Context) []model.CheckResult {
 	// Check 4: iptables available
 	results = append(results, e.checkIPTables())
 
+	// Check 5: seccomp filters support
+	results = append(results, e.checkSeccomp())
+
+	// Check 6: nested virtualization, CPU features and MSRs
+	results = append(results, e.checkVirt(runtime.GOARCH, kvm.Status == model.CheckStatusOK)...)
+
 	return results
 }
 
+// Capabilities returns the optional features of the Firecracker engine.
+func (e *Engine) Capabilities() model.EngineCapabilities {
+	return model.EngineCapabilities{
+		Snapshots:     true,
+		LiveSnapshots: true,
+		Egress:        true,
+		Networks:      true,
+		PortForward:   true,
+		MemoryBalloon: true,
+		Scaling:       true,
+		EngineAPI:     true,
+		Tty:           true,
+	}
+}
+
 // checkKVM checks if /dev/kvm is available and writable.
 func (e *Engine) checkKVM() model.CheckResult {
 	kvmPath := "/dev/kvm"
@@ -155,15 +279,17 @@ func (e *Engine) checkKVM() model.CheckResult {
 	if err != nil {
 		if os.IsNotExist(err) {
 			return model.CheckResult{
-				ID:      "kvm_available",
-				Message: "/dev/kvm does not exist (KVM not available)",
-				Status:  model.CheckStatusError,
+				ID:          "kvm_available",
+				Message:     "/dev/kvm does not exist (KVM not available" + kvmHint(runtime.GOARCH) + ")",
+				Status:      model.CheckStatusError,
+				Remediation: "Load the kvm module (modprobe kvm_intel, kvm_amd or kvm)" + kvmHint(runtime.GOARCH),
 			}
 		}
 		return model.CheckResult{
-			ID:      "kvm_available",
-			Message: fmt.Sprintf("Cannot access /dev/kvm: %v", err),
-			Status:  model.CheckStatusError,
+			ID:          "kvm_available",
+			Message:     fmt.Sprintf("Cannot access /dev/kvm: %v", err),
+			Status:      model.CheckStatusError,
+			Remediation: kvmAccessRemediation,
 		}
 	}
 
@@ -180,9 +306,10 @@ func (e *Engine) checkKVM() model.CheckResult {
 	f, err := os.OpenFile(kvmPath, os.O_RDWR, 0)
 	if err != nil {
 		return model.CheckResult{
-			ID:      "kvm_available",
-			Message: fmt.Sprintf("No write permission to /dev/kvm: %v", err),
-			Status:  model.CheckStatusError,
+			ID:          "kvm_available",
+			Message:     fmt.Sprintf("No write permission to /dev/kvm: %v", err),
+			Status:      model.CheckStatusError,
+			Remediation: kvmAccessRemediation,
 		}
 	}
 	f.Close()
@@ -194,6 +321,23 @@ func (e *Engine) checkKVM() model.CheckResult {
 	}
 }
 
+// kvmAccessRemediation is the remediation when /dev/kvm can't be used.
+const kvmAccessRemediation = "Add the user to the kvm group (sudo usermod -aG kvm $USER) or run sbx as root"
+
+// kvmHint returns where KVM is usually missing for the host architecture. ARM
+// cloud instances only expose KVM on bare metal, and the Linux VMs of Apple silicon
+// Macs need nested virtualization.
+func kvmHint(goarch string) string {
+	switch goarch {
+	case "arm64":
+		return ", on ARM use a bare metal instance (e.g. AWS Graviton *.metal) or a VM with nested virtualization (e.g. Apple M3 or newer with macOS 15)"
+	case "amd64":
+		return ", enable virtualization in the BIOS or nested virtualization on VMs"
+	default:
+		return ""
+	}
+}
+
 // checkFirecrackerBinary checks if firecracker binary is available.
 func (e *Engine) checkFirecrackerBinary() model.CheckResult {
 	// Check paths in order of priority
@@ -235,9 +379,10 @@ func (e *Engine) checkFirecrackerBinary() model.CheckResult {
 	}
 
 	return model.CheckResult{
-		ID:      "firecracker_binary",
-		Message: "Firecracker binary not found in PATH or ./bin",
-		Status:  model.CheckStatusError,
+		ID:          "firecracker_binary",
+		Message:     "Firecracker binary not found in PATH or ./bin",
+		Status:      model.CheckStatusError,
+		Remediation: "Install the firecracker release binary in the PATH (https://github.com/firecracker-microvm/firecracker/releases)",
 	}
 }
 
@@ -246,9 +391,10 @@ func (e *Engine) checkIPForward() model.CheckResult {
 	data, err := os.ReadFile("/proc/sys/net/ipv4/ip_forward")
 	if err != nil {
 		return model.CheckResult{
-			ID:      "ip_forward",
-			Message: fmt.Sprintf("Cannot read IP forwarding status: %v", err),
-			Status:  model.CheckStatusWarning,
+			ID:          "ip_forward",
+			Message:     fmt.Sprintf("Cannot read IP forwarding status: %v", err),
+			Status:      model.CheckStatusWarning,
+			Remediation: "Check /proc/sys/net/ipv4/ip_forward is readable",
 		}
 	}
 
@@ -261,9 +407,10 @@ func (e *Engine) checkIPForward() model.CheckResult {
 	}
 
 	return model.CheckResult{
-		ID:      "ip_forward",
-		Message: "IP forwarding is disabled (may affect networking)",
-		Status:  model.CheckStatusWarning,
+		ID:          "ip_forward",
+		Message:     "IP forwarding is disabled (may affect networking)",
+		Status:      model.CheckStatusWarning,
+		Remediation: "sudo sysctl -w net.ipv4.ip_forward=1",
 	}
 }
 
@@ -272,9 +419,10 @@ func (e *Engine) checkIPTables() model.CheckResult {
 	path, err := exec.LookPath("iptables")
 	if err != nil {
 		return model.CheckResult{
-			ID:      "iptables",
-			Message: "iptables not found in PATH",
-			Status:  model.CheckStatusError,
+			ID:          "iptables",
+			Message:     "iptables not found in PATH",
+			Status:      model.CheckStatusError,
+			Remediation: "Install iptables (e.g. apt install iptables or dnf install iptables)",
 		}
 	}
 
@@ -345,12 +493,22 @@ func (e *Engine) allocateNetwork(sandboxID string) (mac, gateway, vmIP, tapDevic
 }
 
 // Create creates a new Firecracker microVM sandbox.
-func (e *Engine) Create(ctx context.Context, cfg model.SandboxConfig) (*model.Sandbox, error) {
+func (e *Engine) Create(ctx context.Context, cfg model.SandboxConfig, opts sandbox.CreateOpts) (*model.Sandbox, error) {
 	// Validate that we have Firecracker engine config
 	if cfg.FirecrackerEngine == nil {
 		return nil, fmt.Errorf("firecracker engine configuration is required")
 	}
 
+	if err := validateBootConfig(*cfg.FirecrackerEngine); err != nil {
+		return nil, fmt.Errorf("invalid boot config: %w", err)
+	}
+	if err := validateNetworks(cfg.FirecrackerEngine.Networks); err != nil {
+		return nil, fmt.Errorf("invalid networks: %w", err)
+	}
+	if err := e.checkSeccompFilter(cfg.FirecrackerEngine.Seccomp); err != nil {
+		return nil, err
+	}
+
 	// Validate disk_gb doesn't exceed maximum
 	if cfg.Resources.DiskGB > MaxDiskGB {
 		return nil, fmt.Errorf("disk_gb (%d) exceeds maximum allowed (%d GB)", cfg.Resources.DiskGB, MaxDiskGB)
@@ -361,6 +519,14 @@ func (e *Engine) Create(ctx context.Context, cfg model.SandboxConfig) (*model.Sa
 
 	// Allocate network resources
 	mac, gateway, vmIP, tapDevice := e.allocateNetwork(id)
+	if len(cfg.FirecrackerEngine.Networks) > 0 {
+		fcCfg := *cfg.FirecrackerEngine
+		fcCfg.Networks = append([]model.NetworkInterface(nil), fcCfg.Networks...)
+		if err := e.assignNetworkAddresses(ctx, id, fcCfg.Networks); err != nil {
+			return nil, err
+		}
+		cfg.FirecrackerEngine = &fcCfg
+	}
 
 	// Create VM directory
 	vmDir := e.VMDir(id)
@@ -379,10 +545,15 @@ func (e *Engine) Create(ctx context.Context, cfg model.SandboxConfig) (*model.Sa
 	e.logger.Debugf("Network: MAC=%s, Gateway=%s, VM IP=%s, TAP=%s", mac, gateway, vmIP, tapDevice)
 	e.logger.Debugf("Kernel: %s, RootFS: %s", kernelPath, rootfsPath)
 
-	var createErr error
+	var (
+		createErr      error
+		err            error
+		rootfsStrategy model.RootFSStrategy
+	)
 
 	// Task 1: Generate per-sandbox SSH keys
 	e.logger.Debugf("[1/4] Generating SSH keys for sandbox %s", id)
+	opts.Progress.ReportStep(1, 4, "ssh_keys", "Generating SSH keys")
 	if _, err := e.sshKeyManager.GenerateKeys(id); err != nil {
 		createErr = err
 		goto cleanup
@@ -390,13 +561,16 @@ func (e *Engine) Create(ctx context.Context, cfg model.SandboxConfig) (*model.Sa
 
 	// Task 2: Copy rootfs
 	e.logger.Debugf("[2/4] Copying rootfs to VM directory")
-	if err := e.copyRootFS(ctx, rootfsPath, vmDir); err != nil {
+	opts.Progress.ReportStep(2, 4, "copy_rootfs", "Copying rootfs")
+	rootfsStrategy, err = e.copyRootFS(ctx, rootfsPath, vmDir)
+	if err != nil {
 		createErr = err
 		goto cleanup
 	}
 
 	// Task 3: Resize rootfs to configured disk_gb
 	e.logger.Debugf("[3/4] Resizing rootfs to %d GB", cfg.Resources.DiskGB)
+	opts.Progress.ReportStep(3, 4, "resize_rootfs", fmt.Sprintf("Resizing rootfs to %d GB", cfg.Resources.DiskGB))
 	if err := e.resizeRootFS(vmDir, cfg.Resources.DiskGB, rootfsPath); err != nil {
 		createErr = err
 		goto cleanup
@@ -404,6 +578,7 @@ func (e *Engine) Create(ctx context.Context, cfg model.SandboxConfig) (*model.Sa
 
 	// Task 4: Patch rootfs with SSH key
 	e.logger.Debugf("[4/4] Patching rootfs with SSH public key")
+	opts.Progress.ReportStep(4, 4, "patch_rootfs", "Patching rootfs with the SSH key")
 	if err := e.patchRootFSSSH(id, vmDir); err != nil {
 		createErr = err
 		goto cleanup
@@ -421,17 +596,18 @@ cleanup:
 	// Start will handle TAP, iptables, spawning, and booting the VM.
 	now := time.Now().UTC()
 	sandbox := &model.Sandbox{
-		ID:         id,
-		Name:       cfg.Name,
-		Status:     model.SandboxStatusStopped,
-		Config:     cfg,
-		CreatedAt:  now,
-		SocketPath: socketPath,
-		TapDevice:  tapDevice,
-		InternalIP: vmIP,
-	}
-
-	e.logger.Infof("Created Firecracker sandbox: %s (IP: %s)", id, vmIP)
+		ID:             id,
+		Name:           cfg.Name,
+		Status:         model.SandboxStatusStopped,
+		Config:         cfg,
+		CreatedAt:      now,
+		SocketPath:     socketPath,
+		TapDevice:      tapDevice,
+		InternalIP:     vmIP,
+		RootFSStrategy: rootfsStrategy,
+	}
+
+	e.logger.Infof("Created Firecracker sandbox: %s (IP: %s, rootfs: %s)", id, vmIP, rootfsStrategy)
 
 	return sandbox, nil
 }
@@ -9,6 +9,7 @@ import (
 
 	"github.com/slok/sbx/internal/log"
 	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
 )
 
 func TestEngine_allocateNetwork(t *testing.T) {
@@ -120,9 +121,9 @@ func TestEngine_Check(t *testing.T) {
 	ctx := context.Background()
 	results := e.Check(ctx)
 
-	// Should return at least 4 checks
-	if len(results) < 4 {
-		t.Errorf("Check should return at least 4 results, got %d", len(results))
+	// Should return at least 8 checks
+	if len(results) < 8 {
+		t.Errorf("Check should return at least 8 results, got %d", len(results))
 	}
 
 	// Verify expected check IDs are present
@@ -131,6 +132,10 @@ func TestEngine_Check(t *testing.T) {
 		"firecracker_binary": false,
 		"ip_forward":         false,
 		"iptables":           false,
+		"seccomp":            false,
+		"nested_virt":        false,
+		"cpu_features":       false,
+		"msr":                false,
 	}
 
 	for _, r := range results {
@@ -226,7 +231,7 @@ func TestEngine_Create_DiskGBValidation(t *testing.T) {
 				},
 			}
 
-			_, err = e.Create(context.Background(), cfg)
+			_, err = e.Create(context.Background(), cfg, sandbox.CreateOpts{})
 
 			if tc.expErr {
 				// Should fail early with validation error (before trying to access files)
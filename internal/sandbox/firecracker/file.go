@@ -0,0 +1,102 @@
+package firecracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// ReadFile returns the content of a file of the Firecracker VM via SFTP.
+func (e *Engine) ReadFile(ctx context.Context, id string, remotePath string) ([]byte, error) {
+	client, release, err := e.sshClient(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("sandbox %s is not running or not reachable: %w: %w", id, err, model.ErrNotValid)
+	}
+	defer release()
+
+	data, err := client.ReadFile(ctx, remotePath)
+	if err != nil {
+		return nil, fileError(remotePath, err)
+	}
+
+	return data, nil
+}
+
+// WriteFile writes a file in the Firecracker VM via SFTP.
+func (e *Engine) WriteFile(ctx context.Context, id string, remotePath string, data []byte, perm fs.FileMode) error {
+	client, release, err := e.sshClient(ctx, id)
+	if err != nil {
+		return fmt.Errorf("sandbox %s is not running or not reachable: %w: %w", id, err, model.ErrNotValid)
+	}
+	defer release()
+
+	if err := client.WriteFile(ctx, remotePath, data, perm); err != nil {
+		return fileError(remotePath, err)
+	}
+
+	e.logger.Debugf("Wrote %d bytes to %s:%s", len(data), id, remotePath)
+	return nil
+}
+
+// ListDir returns the entries of a directory of the Firecracker VM via SFTP.
+func (e *Engine) ListDir(ctx context.Context, id string, remoteDir string) ([]model.FileInfo, error) {
+	client, release, err := e.sshClient(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("sandbox %s is not running or not reachable: %w: %w", id, err, model.ErrNotValid)
+	}
+	defer release()
+
+	infos, err := client.ReadDir(ctx, remoteDir)
+	if err != nil {
+		return nil, fileError(remoteDir, err)
+	}
+
+	files := make([]model.FileInfo, 0, len(infos))
+	for _, info := range infos {
+		files = append(files, fileInfo(path.Join(remoteDir, info.Name()), info))
+	}
+	return files, nil
+}
+
+// StatFile returns the information of a file of the Firecracker VM via SFTP.
+func (e *Engine) StatFile(ctx context.Context, id string, remotePath string) (*model.FileInfo, error) {
+	client, release, err := e.sshClient(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("sandbox %s is not running or not reachable: %w: %w", id, err, model.ErrNotValid)
+	}
+	defer release()
+
+	info, err := client.Stat(ctx, remotePath)
+	if err != nil {
+		return nil, fileError(remotePath, err)
+	}
+
+	file := fileInfo(path.Clean(remotePath), info)
+	return &file, nil
+}
+
+func fileInfo(p string, info fs.FileInfo) model.FileInfo {
+	return model.FileInfo{
+		Name:    info.Name(),
+		Path:    p,
+		Size:    info.Size(),
+		Mode:    info.Mode(),
+		ModTime: info.ModTime(),
+		IsDir:   info.IsDir(),
+	}
+}
+
+// fileError maps the SFTP errors of a sandbox path to the model errors.
+func fileError(p string, err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("path '%s' does not exist in sandbox: %w", p, model.ErrNotFound)
+	case errors.Is(err, fs.ErrPermission):
+		return fmt.Errorf("permission denied on '%s': %w: %w", p, err, model.ErrNotValid)
+	}
+	return fmt.Errorf("failed to access '%s' in VM: %w", p, err)
+}
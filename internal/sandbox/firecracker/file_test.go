@@ -0,0 +1,38 @@
+package firecracker
+
+import (
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestFileError(t *testing.T) {
+	tests := map[string]struct {
+		err      error
+		expErrIs error
+	}{
+		"A missing path should be not found.": {
+			err:      fmt.Errorf("could not stat remote file: %w", fs.ErrNotExist),
+			expErrIs: model.ErrNotFound,
+		},
+		"A permission error should be not valid.": {
+			err:      fmt.Errorf("could not open remote file: %w", fs.ErrPermission),
+			expErrIs: model.ErrNotValid,
+		},
+		"Other errors should be kept.": {
+			err:      fs.ErrClosed,
+			expErrIs: fs.ErrClosed,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := fileError("/workspace/f", test.err)
+			assert.ErrorIs(t, err, test.expErrIs)
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package firecracker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/ssh"
+)
+
+const (
+	// defaultBootTimeout is the default maximum time to wait for the guest SSH after the boot.
+	defaultBootTimeout = 60 * time.Second
+	// defaultSSHDialTimeout is the default timeout of each guest SSH connection attempt.
+	defaultSSHDialTimeout = 2 * time.Second
+)
+
+// startTimeouts returns the start timeouts with the defaults of the unset ones.
+func startTimeouts(t model.StartTimeouts) model.StartTimeouts {
+	return model.StartTimeouts{
+		Boot:    defaultBootTimeout,
+		SSHDial: defaultSSHDialTimeout,
+	}.Merge(t)
+}
+
+// waitForSSH polls the guest SSH until it accepts a connection and returns the
+// connected client, the caller is responsible for closing it.
+// The guest is polled with the SSH dial retry policy, it gives up after the boot
+// timeout or, when set, the SSH retries (replacing the policy attempts).
+func (e *Engine) waitForSSH(ctx context.Context, sandboxID string, timeouts model.StartTimeouts) (*ssh.Client, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeouts.Boot)
+	defer cancel()
+
+	policy := e.retries.SSHDial
+	if timeouts.SSHRetries > 0 {
+		policy.Attempts = timeouts.SSHRetries
+	}
+
+	var client *ssh.Client
+	err := e.retry(ctx, "guest SSH dial", policy, nil, func() error {
+		var err error
+		client, err = e.newSSHClientWithTimeout(ctx, sandboxID, timeouts.SSHDial)
+		return err
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("guest SSH not ready (boot timeout %s): %w", timeouts.Boot, err)
+		}
+		return nil, fmt.Errorf("guest SSH not ready: %w", err)
+	}
+
+	return client, nil
+}
+
+// setupGuest runs the guest setup script over the connected client, bounded by the
+// timeout when set.
+func (e *Engine) setupGuest(ctx context.Context, client *ssh.Client, script string, timeout time.Duration) error {
+	if script == "" {
+		return nil
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var out bytes.Buffer
+	exitCode, err := client.Exec(ctx, script, ssh.ExecOpts{
+		Stdout: &out,
+		Stderr: &out,
+	})
+	if err != nil {
+		return fmt.Errorf("could not set up guest: %w", err)
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("guest setup failed with exit code %d: %s", exitCode, strings.TrimSpace(out.String()))
+	}
+
+	e.logger.Debugf("Set up guest: %s", strings.TrimSpace(out.String()))
+	return nil
+}
+
+// guestSetupScript returns the commands that set up the guest after the boot: the
+// filesystem expansion to fill the resized disk (a read-only rootfs can't be
+// expanded), the MTU of the interfaces when set (0 keeps the default), the
+// additional network interfaces, the primary interface keeps the default route,
+// and the VCPUs above the sandbox ones booted for scaling offlined. Empty means
+// there is nothing to set up.
+func guestSetupScript(readOnlyRootFS bool, nics []nic, mtu, vcpus, bootVCPUs int) string {
+	var cmds []string
+	if !readOnlyRootFS {
+		cmds = append(cmds, "resize2fs /dev/vda")
+	}
+	mtuArg := ""
+	if mtu > 0 {
+		mtuArg = fmt.Sprintf(" mtu %d", mtu)
+		cmds = append(cmds, "ip link set eth0"+mtuArg)
+	}
+	for _, n := range nics {
+		cmds = append(cmds, fmt.Sprintf("ip link set %s%s up && ip addr replace %s/24 dev %s", n.id, mtuArg, n.vmIP, n.id))
+	}
+	if vcpus < bootVCPUs {
+		cmds = append(cmds, vcpuOnlineScript(vcpus, bootVCPUs))
+	}
+	return strings.Join(cmds, " && ")
+}
+
+// vcpuOnlineScript returns the commands that leave the first guest VCPUs online and
+// the rest up to the booted ones offline, the first VCPU can't be offlined.
+func vcpuOnlineScript(vcpus, bootVCPUs int) string {
+	cmds := make([]string, 0, bootVCPUs)
+	for i := 1; i < bootVCPUs; i++ {
+		online := 0
+		if i < vcpus {
+			online = 1
+		}
+		cmds = append(cmds, fmt.Sprintf("echo %d > /sys/devices/system/cpu/cpu%d/online", online, i))
+	}
+	return strings.Join(cmds, " && ")
+}
@@ -0,0 +1,145 @@
+package firecracker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestGuestSetupScript(t *testing.T) {
+	tests := map[string]struct {
+		readOnly  bool
+		nics      []nic
+		mtu       int
+		vcpus     int
+		bootVCPUs int
+		expScript string
+	}{
+		"A writable rootfs should be expanded.": {
+			expScript: "resize2fs /dev/vda",
+		},
+
+		"A read-only rootfs without networks should have nothing to set up.": {
+			readOnly:  true,
+			expScript: "",
+		},
+
+		"The additional network interfaces should be set up after the filesystem expansion.": {
+			nics: []nic{
+				{id: "eth1", vmIP: "10.200.1.2"},
+				{id: "eth2", vmIP: "10.200.2.2"},
+			},
+			expScript: "resize2fs /dev/vda && " +
+				"ip link set eth1 up && ip addr replace 10.200.1.2/24 dev eth1 && " +
+				"ip link set eth2 up && ip addr replace 10.200.2.2/24 dev eth2",
+		},
+
+		"A read-only rootfs should only set up the networks.": {
+			readOnly:  true,
+			nics:      []nic{{id: "eth1", vmIP: "10.200.1.2"}},
+			expScript: "ip link set eth1 up && ip addr replace 10.200.1.2/24 dev eth1",
+		},
+
+		"The MTU should be set on the primary and the additional network interfaces.": {
+			readOnly:  true,
+			nics:      []nic{{id: "eth1", vmIP: "10.200.1.2"}},
+			mtu:       1380,
+			expScript: "ip link set eth0 mtu 1380 && ip link set eth1 mtu 1380 up && ip addr replace 10.200.1.2/24 dev eth1",
+		},
+
+		"The VCPUs booted above the sandbox ones for scaling should be offlined.": {
+			readOnly:  true,
+			vcpus:     2,
+			bootVCPUs: 4,
+			expScript: "echo 1 > /sys/devices/system/cpu/cpu1/online && " +
+				"echo 0 > /sys/devices/system/cpu/cpu2/online && " +
+				"echo 0 > /sys/devices/system/cpu/cpu3/online",
+		},
+
+		"The VCPUs should not be set up when the sandbox has all the booted ones.": {
+			readOnly:  true,
+			vcpus:     2,
+			bootVCPUs: 2,
+			expScript: "",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expScript, guestSetupScript(test.readOnly, test.nics, test.mtu, test.vcpus, test.bootVCPUs))
+		})
+	}
+}
+
+func TestVCPUOnlineScript(t *testing.T) {
+	tests := map[string]struct {
+		vcpus     int
+		bootVCPUs int
+		expScript string
+	}{
+		"A single booted VCPU should have nothing to set.": {
+			vcpus:     1,
+			bootVCPUs: 1,
+			expScript: "",
+		},
+
+		"Scaling up should online the VCPUs up to the sandbox ones.": {
+			vcpus:     3,
+			bootVCPUs: 3,
+			expScript: "echo 1 > /sys/devices/system/cpu/cpu1/online && echo 1 > /sys/devices/system/cpu/cpu2/online",
+		},
+
+		"Scaling down should offline the VCPUs above the sandbox ones.": {
+			vcpus:     1,
+			bootVCPUs: 3,
+			expScript: "echo 0 > /sys/devices/system/cpu/cpu1/online && echo 0 > /sys/devices/system/cpu/cpu2/online",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expScript, vcpuOnlineScript(test.vcpus, test.bootVCPUs))
+		})
+	}
+}
+
+func TestStartTimeouts(t *testing.T) {
+	tests := map[string]struct {
+		timeouts    model.StartTimeouts
+		expTimeouts model.StartTimeouts
+	}{
+		"Unset timeouts should use the defaults.": {
+			expTimeouts: model.StartTimeouts{Boot: defaultBootTimeout, SSHDial: defaultSSHDialTimeout},
+		},
+
+		"Set timeouts should replace the defaults.": {
+			timeouts:    model.StartTimeouts{Boot: 5 * time.Minute, SSHRetries: 30, FilesystemExpand: time.Minute},
+			expTimeouts: model.StartTimeouts{Boot: 5 * time.Minute, SSHDial: defaultSSHDialTimeout, SSHRetries: 30, FilesystemExpand: time.Minute},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expTimeouts, startTimeouts(test.timeouts))
+		})
+	}
+}
+
+func TestEngine_waitForSSH_Retries(t *testing.T) {
+	e, err := NewEngine(EngineConfig{DataDir: t.TempDir(), Logger: log.Noop})
+	require.NoError(t, err)
+
+	// Without the sandbox SSH key every attempt fails, the retries should stop it
+	// before the boot timeout.
+	start := time.Now()
+	_, err = e.waitForSSH(context.Background(), "test-sandbox", model.StartTimeouts{Boot: time.Minute, SSHDial: time.Second, SSHRetries: 3})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "after 3 attempts")
+	assert.Less(t, time.Since(start), 30*time.Second)
+}
@@ -0,0 +1,54 @@
+package firecracker
+
+import (
+	"context"
+	"time"
+)
+
+// nftablesLockTimeout is the maximum time to wait for the other clients of the data
+// directory to release the nftables lock, their changes take milliseconds.
+const nftablesLockTimeout = 30 * time.Second
+
+// lockSandbox takes the locks of the sandbox VM directory and TAP devices without
+// waiting, so the clients of the data directory with other databases can't change
+// them at the same time. It returns a model.BusyError with the holder when another
+// client has them. The returned func releases them.
+func (e *Engine) lockSandbox(id, operation string, tapDevices ...string) (func(), error) {
+	if e.hostLocks == nil {
+		return func() {}, nil
+	}
+
+	resources := append([]string{"vm/" + id}, tapDevices...)
+	for i := 1; i < len(resources); i++ {
+		resources[i] = "tap/" + resources[i]
+	}
+
+	var unlocks []func()
+	unlockAll := func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+	for _, r := range resources {
+		unlock, err := e.hostLocks.TryLock(r, operation)
+		if err != nil {
+			unlockAll()
+			return nil, err
+		}
+		unlocks = append(unlocks, unlock)
+	}
+
+	return unlockAll, nil
+}
+
+// lockNftables takes the lock of the host nftables rules, the chains are shared by
+// all the sandboxes so the changes wait for each other instead of failing.
+func (e *Engine) lockNftables() (func(), error) {
+	if e.hostLocks == nil {
+		return func() {}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), nftablesLockTimeout)
+	defer cancel()
+	return e.hostLocks.Lock(ctx, "nftables", "nftables")
+}
@@ -0,0 +1,48 @@
+package firecracker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestEngineLockSandbox(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	// Two engines of the same data directory act like two clients with their own database.
+	dataDir := t.TempDir()
+	e1, err := NewEngine(EngineConfig{DataDir: dataDir, Logger: log.Noop})
+	require.NoError(err)
+	e2, err := NewEngine(EngineConfig{DataDir: dataDir, Logger: log.Noop})
+	require.NoError(err)
+
+	unlock, err := e1.lockSandbox("sb-1", "start", "sbx-0a0b")
+	require.NoError(err)
+
+	// The VM directory and the TAP devices of the locked sandbox should be busy.
+	_, err = e2.lockSandbox("sb-1", "remove")
+	assert.ErrorIs(err, model.ErrBusy)
+	_, err = e2.lockSandbox("sb-2", "start", "sbx-0a0b")
+	var busyErr *model.BusyError
+	require.True(errors.As(err, &busyErr))
+	assert.Equal("tap/sbx-0a0b", busyErr.Resource)
+	require.NotNil(busyErr.Holder)
+	assert.Equal("start", busyErr.Holder.Operation)
+
+	// The failed lock should not keep the resources it took.
+	unlock2, err := e1.lockSandbox("sb-2", "start", "sbx-0c0d")
+	require.NoError(err)
+	unlock2()
+
+	// Once released they should be lockable again.
+	unlock()
+	unlock, err = e2.lockSandbox("sb-1", "remove", "sbx-0a0b")
+	require.NoError(err)
+	unlock()
+}
@@ -2,14 +2,18 @@ package firecracker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/vishvananda/netlink"
 
@@ -17,137 +21,244 @@ import (
 	"github.com/slok/sbx/internal/model"
 	"github.com/slok/sbx/internal/sandbox"
 	"github.com/slok/sbx/internal/ssh"
+	"github.com/slok/sbx/internal/tlscert"
 )
 
 // Start starts a stopped Firecracker sandbox.
 // Note: Firecracker doesn't support pause/resume. To "start" a stopped VM,
 // we respawn the process transparently while preserving disk state.
 // The user sees the same sandbox with all their disk changes intact.
-func (e *Engine) Start(ctx context.Context, id string, opts sandbox.StartOpts) error {
+// The guest setup after the boot (filesystem expansion, additional network
+// interfaces) is batched over a single SSH connection, and the duration of each
+// start phase is returned.
+// If a step fails, the host resources created by the previous steps (TAP devices,
+// nftables rules, proxy and Firecracker processes and their PID files) are rolled
+// back and a [model.StartError] is returned.
+func (e *Engine) Start(ctx context.Context, id string, opts sandbox.StartOpts) ([]model.BootPhase, error) {
 	vmDir := e.VMDir(id)
 
 	// Validate VM directory exists
 	if _, err := os.Stat(vmDir); os.IsNotExist(err) {
-		return fmt.Errorf("sandbox %s: VM directory not found: %w", id, model.ErrNotFound)
+		return nil, fmt.Errorf("sandbox %s: VM directory not found: %w", id, model.ErrNotFound)
 	}
 
 	// Validate rootfs exists (contains user's disk state)
 	rootfsPath := e.RootFSPath(vmDir)
 	if _, err := os.Stat(rootfsPath); os.IsNotExist(err) {
-		return fmt.Errorf("sandbox %s: rootfs not found at %s - sandbox needs to be recreated", id, rootfsPath)
+		return nil, fmt.Errorf("sandbox %s: rootfs not found at %s - sandbox needs to be recreated", id, rootfsPath)
 	}
 
 	// Get sandbox config from repository
 	if e.repo == nil {
-		return fmt.Errorf("cannot start firecracker sandbox: repository not configured")
+		return nil, fmt.Errorf("cannot start firecracker sandbox: repository not configured")
 	}
 	sb, err := e.repo.GetSandbox(ctx, id)
 	if err != nil {
-		return fmt.Errorf("could not get sandbox config: %w", err)
+		return nil, fmt.Errorf("could not get sandbox config: %w", err)
 	}
 	if sb.Config.FirecrackerEngine == nil {
-		return fmt.Errorf("sandbox %s is not a firecracker sandbox", id)
+		return nil, fmt.Errorf("sandbox %s is not a firecracker sandbox", id)
 	}
 
 	// Network allocation is deterministic based on ID
 	mac, gateway, vmIP, tapDevice := e.allocateNetwork(id)
+	nics := e.allocateNICs(id, sb.Config.FirecrackerEngine.Networks)
+
+	taps := []string{tapDevice}
+	for _, n := range nics {
+		taps = append(taps, n.tapDevice)
+	}
+	unlock, err := e.lockSandbox(id, "start", taps...)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 
 	// Expand kernel path
 	kernelPath := e.expandPath(sb.Config.FirecrackerEngine.KernelImage)
 	if _, err := os.Stat(kernelPath); os.IsNotExist(err) {
-		return fmt.Errorf("kernel image not found at %s", kernelPath)
+		return nil, fmt.Errorf("kernel image not found at %s", kernelPath)
+	}
+	if err := checkKernelArch(kernelPath, runtime.GOARCH); err != nil {
+		return nil, err
+	}
+	if err := e.checkSeccompFilter(sb.Config.FirecrackerEngine.Seccomp); err != nil {
+		return nil, err
 	}
 
 	socketPath := filepath.Join(vmDir, conventions.SocketFile)
+	timeouts := startTimeouts(opts.Timeouts)
 
 	e.logger.Infof("Starting Firecracker sandbox: %s", id)
 	e.logger.Debugf("Network: MAC=%s, Gateway=%s, VM IP=%s, TAP=%s", mac, gateway, vmIP, tapDevice)
 
-	totalSteps := 5
+	totalSteps := 6
 	if opts.Egress != nil {
-		totalSteps = 6
+		totalSteps++
+	}
+	if len(nics) > 0 {
+		totalSteps++
 	}
 
-	var startErr error
 	var pid int
-	var proxyPID int
+	var sshClient *ssh.Client
+	defer func() {
+		if sshClient != nil {
+			sshClient.Close()
+		}
+	}()
+
+	// Phases are measured one after the other, each phase ends when the next starts.
+	var phases []model.BootPhase
+	phaseStart := time.Now()
+	endPhase := func(name string) {
+		now := time.Now()
+		phases = append(phases, model.BootPhase{Name: name, Duration: now.Sub(phaseStart)})
+		phaseStart = now
+	}
+
+	// Every step registers how to undo its host changes, if a step fails the already
+	// run steps are rolled back so the host is left as it was before the start. The
+	// failed step is the last phase, with its error.
+	var rb rollback
+	fail := func(step string, err error) ([]model.BootPhase, error) {
+		e.logger.Errorf("Start failed on %s step: %v", step, err)
+		phases = append(phases, model.BootPhase{Name: step, Duration: time.Since(phaseStart), Error: err.Error()})
+		startErr := &model.StartError{Step: step, Err: err, Phases: phases}
+		if rbErr := rb.run(); rbErr != nil {
+			e.logger.Errorf("Start rollback failed: %v", rbErr)
+			startErr.RollbackErr = rbErr
+		}
+		return nil, startErr
+	}
 
 	// Task 1: Ensure networking resources exist (TAP + iptables)
 	// If TAP is missing (e.g., after system reboot), recreate it
 	step := 1
 	e.logger.Debugf("[%d/%d] Ensuring network resources exist", step, totalSteps)
-	if err := e.ensureNetworking(tapDevice, gateway, vmIP); err != nil {
-		startErr = err
-		goto cleanup
+	opts.Progress.ReportStep(step, totalSteps, "network", "Setting up the network")
+	uplink := e.uplink(sb.Config.FirecrackerEngine)
+	network := sb.Config.FirecrackerEngine.Network
+	created, err := e.ensureNetworking(tapDevice, gateway, vmIP, uplink, network)
+	if created {
+		rb.add("network", func() error { return e.cleanupNetworking(tapDevice, gateway, vmIP) })
 	}
+	if err != nil {
+		return fail("network", err)
+	}
+	endPhase("network")
 
 	// Task 2 (optional): Spawn proxy process for egress filtering and set up DNAT redirect
 	if opts.Egress != nil {
 		step++
 		e.logger.Debugf("[%d/%d] Spawning egress proxy", step, totalSteps)
-		var proxyPorts ProxyPorts
-		proxyPID, proxyPorts, err = e.spawnProxy(vmDir, *opts.Egress, gateway)
+		opts.Progress.ReportStep(step, totalSteps, "egress_proxy", "Starting the egress proxy")
+		proxyPID, proxyPorts, err := e.spawnProxy(vmDir, sb, *opts.Egress, "", tapDevice, gateway, vmIP)
 		if err != nil {
-			startErr = fmt.Errorf("could not spawn proxy: %w", err)
-			goto cleanup
+			return fail("egress_proxy", fmt.Errorf("could not spawn proxy: %w", err))
 		}
+		rb.add("egress proxy", func() error { return removeProxy(vmDir, "") })
 		e.logger.Infof("Proxy started (PID: %d, HTTP: %d, TLS: %d, DNS: %d)", proxyPID, proxyPorts.HTTPPort, proxyPorts.TLSPort, proxyPorts.DNSPort)
 
-		// Set up nftables DNAT rules to redirect VM traffic through the proxy.
+		// Set up nftables DNAT rules to redirect VM traffic through the proxy. The undo
+		// is registered before so the rules of a partial setup are removed too.
+		rb.add("proxy redirect", func() error { return e.cleanupProxyRedirect(tapDevice) })
 		if err := e.setupProxyRedirect(tapDevice, gateway, vmIP, proxyPorts); err != nil {
-			startErr = fmt.Errorf("could not set up proxy redirect: %w", err)
-			goto cleanup
+			return fail("egress_proxy", fmt.Errorf("could not set up proxy redirect: %w", err))
+		}
+		endPhase("egress_proxy")
+	}
+
+	// Task 3 (optional): Ensure the additional network interfaces resources and their egress proxies.
+	if len(nics) > 0 {
+		step++
+		e.logger.Debugf("[%d/%d] Ensuring additional network interfaces", step, totalSteps)
+		opts.Progress.ReportStep(step, totalSteps, "networks", "Setting up the additional network interfaces")
+		createdNICs, err := e.ensureNICNetworking(nics, uplink, network)
+		if len(createdNICs) > 0 {
+			rb.add("networks", func() error {
+				e.cleanupNICNetworking(createdNICs)
+				return nil
+			})
+		}
+		if err != nil {
+			return fail("networks", err)
+		}
+		for _, n := range nics {
+			if n.cfg.Egress == nil {
+				continue
+			}
+			_, nicProxyPorts, err := e.spawnProxy(vmDir, sb, *n.cfg.Egress, n.id, n.tapDevice, n.gateway, n.vmIP)
+			if err != nil {
+				return fail("networks", fmt.Errorf("could not spawn %s proxy: %w", n.id, err))
+			}
+			rb.add(n.id+" egress proxy", func() error { return removeProxy(vmDir, n.id) })
+			rb.add(n.id+" proxy redirect", func() error { return e.cleanupProxyRedirect(n.tapDevice) })
+			if err := e.setupProxyRedirect(n.tapDevice, n.gateway, n.vmIP, nicProxyPorts); err != nil {
+				return fail("networks", fmt.Errorf("could not set up %s proxy redirect: %w", n.id, err))
+			}
+		}
+		endPhase("networks")
+	}
+
+	// The quarantined sandboxes are isolated before the VM boots, so the guest never
+	// has network access.
+	if opts.Quarantine {
+		rb.add("quarantine", func() error { return e.cleanupQuarantine(id) })
+		if err := e.setupQuarantine(id, taps); err != nil {
+			return fail("network", fmt.Errorf("could not quarantine sandbox: %w", err))
 		}
 	}
 
 	// Task N: Spawn Firecracker process
 	step++
 	e.logger.Debugf("[%d/%d] Spawning Firecracker process", step, totalSteps)
-	pid, err = e.spawnFirecracker(vmDir, socketPath)
+	opts.Progress.ReportStep(step, totalSteps, "spawn", "Spawning Firecracker")
+	pid, err = e.spawnFirecracker(vmDir, socketPath, sb.Config.FirecrackerEngine.Seccomp)
 	if err != nil {
-		startErr = err
-		goto cleanup
+		return fail("spawn", err)
 	}
+	rb.add("firecracker process", func() error { return removeFirecracker(vmDir, pid) })
+	endPhase("spawn")
 
 	// Task N+1: Configure VM via API (includes network config via kernel ip= parameter)
 	step++
 	e.logger.Debugf("[%d/%d] Configuring VM via Firecracker API", step, totalSteps)
-	if err := e.configureVM(ctx, socketPath, kernelPath, vmDir, mac, tapDevice, vmIP, gateway, sb.Config.Resources); err != nil {
-		startErr = err
-		goto cleanup
+	opts.Progress.ReportStep(step, totalSteps, "configure", "Configuring the VM")
+	if err := e.configureVM(ctx, socketPath, kernelPath, vmDir, mac, tapDevice, vmIP, gateway, sb.Config, nics); err != nil {
+		return fail("configure", err)
 	}
+	endPhase("configure")
 
 	// Task N+2: Boot VM
 	step++
 	e.logger.Debugf("[%d/%d] Booting VM", step, totalSteps)
+	opts.Progress.ReportStep(step, totalSteps, "boot", "Booting the VM")
 	if err := e.bootVM(ctx, socketPath); err != nil {
-		startErr = err
-		goto cleanup
+		return fail("boot", err)
 	}
+	endPhase("boot")
 
-	// Task N+3: Expand filesystem inside VM to fill resized disk
+	// Task N+3: Wait for the guest SSH, the connection is reused for the guest setup.
 	step++
-	e.logger.Debugf("[%d/%d] Expanding filesystem inside VM", step, totalSteps)
-	if err := e.expandFilesystem(ctx, id, vmIP); err != nil {
-		startErr = err
-		goto cleanup
-	}
-
-cleanup:
-	if startErr != nil {
-		e.logger.Errorf("Start failed: %v", startErr)
-		// Kill proxy process if it was started
-		if proxyPID > 0 {
-			_ = e.killProxy(vmDir)
-		}
-		// Kill firecracker process if it was started
-		if pid > 0 {
-			if proc, err := os.FindProcess(pid); err == nil {
-				_ = proc.Kill()
-			}
-		}
-		return startErr
+	e.logger.Debugf("[%d/%d] Waiting for guest SSH", step, totalSteps)
+	opts.Progress.ReportStep(step, totalSteps, "ssh", "Waiting for the guest SSH")
+	sshClient, err = e.waitForSSH(ctx, id, timeouts)
+	if err != nil {
+		return fail("ssh", err)
+	}
+	endPhase("ssh")
+
+	// Task N+4: Set up the guest (expand the filesystem to fill the resized disk,
+	// configure the additional network interfaces and offline the VCPUs above the
+	// sandbox ones) in a single command.
+	step++
+	e.logger.Debugf("[%d/%d] Setting up guest", step, totalSteps)
+	opts.Progress.ReportStep(step, totalSteps, "guest_setup", "Setting up the guest")
+	if err := e.setupGuest(ctx, sshClient, guestSetupScript(sb.Config.FirecrackerEngine.Boot.ReadOnlyRootFS, nics, network.MTU, wholeVCPUs(sb.Config.Resources.VCPUs), wholeVCPUs(sb.Config.Resources.BootVCPUs())), timeouts.FilesystemExpand); err != nil {
+		return fail("guest_setup", err)
 	}
+	endPhase("guest_setup")
 
 	// Update sandbox with new PID and socket path
 	sb.PID = pid
@@ -158,63 +269,121 @@ cleanup:
 	}
 
 	e.logger.Infof("Started Firecracker sandbox: %s (PID: %d, IP: %s)", id, pid, vmIP)
-	return nil
+	return phases, nil
 }
 
 // ensureNetworking ensures TAP device and iptables rules exist.
-// Creates them if missing (e.g., after system reboot).
-func (e *Engine) ensureNetworking(tapDevice, gateway, vmIP string) error {
+// Creates them if missing (e.g., after system reboot), created is true when the
+// resources were (even partially) created and need to be cleaned up on a rollback.
+// The NAT traffic goes out through the uplink, or the default route when empty, and
+// the TAP device gets the MTU and MSS clamping of the network options.
+func (e *Engine) ensureNetworking(tapDevice, gateway, vmIP, uplink string, network model.NetworkOptions) (created bool, err error) {
 	// Check if TAP device exists
-	_, err := netlink.LinkByName(tapDevice)
+	_, err = netlink.LinkByName(tapDevice)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "no such") {
 			// TAP doesn't exist, create it
 			e.logger.Infof("TAP device %s missing, recreating", tapDevice)
-			if err := e.createTAP(tapDevice, gateway); err != nil {
-				return fmt.Errorf("failed to recreate TAP device: %w", err)
+			if err := e.createTAP(tapDevice, gateway, network.MTU); err != nil {
+				return true, fmt.Errorf("failed to recreate TAP device: %w", err)
 			}
 			// Also need to recreate iptables rules
-			if err := e.setupIPTables(tapDevice, gateway, vmIP); err != nil {
-				return fmt.Errorf("failed to recreate iptables rules: %w", err)
+			if err := e.setupIPTables(tapDevice, gateway, vmIP, uplink, network.ClampMSS); err != nil {
+				return true, fmt.Errorf("failed to recreate iptables rules: %w", err)
 			}
-		} else {
-			return fmt.Errorf("failed to check TAP device: %w", err)
+			if err := e.setupUplinkRouting(gateway, uplink); err != nil {
+				return true, err
+			}
+			return true, nil
 		}
+		return false, fmt.Errorf("failed to check TAP device: %w", err)
 	}
 	// TAP exists, assume iptables rules are also in place
-	// (if they were removed, user can rm and recreate the sandbox)
-	return nil
+	// (if they were removed, user can rm and recreate the sandbox). The uplink
+	// route is set again, it's removed while the uplink is down.
+	if err := e.setupUplinkRouting(gateway, uplink); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// cleanupNetworking removes the TAP device and iptables rules created by ensureNetworking.
+func (e *Engine) cleanupNetworking(tapDevice, gateway, vmIP string) error {
+	return errors.Join(
+		e.cleanupIPTables(tapDevice, gateway, vmIP),
+		e.deleteTAP(tapDevice),
+	)
+}
+
+// egressTAPs returns the TAP devices of the sandbox that can have proxy redirect
+// rules: the main one and the ones of the additional network interfaces with an
+// egress policy, that need the sandbox config.
+func (e *Engine) egressTAPs(ctx context.Context, id, tapDevice string) []string {
+	taps := []string{tapDevice}
+	if e.repo == nil {
+		return taps
+	}
+	sb, err := e.repo.GetSandbox(ctx, id)
+	if err != nil || sb.Config.FirecrackerEngine == nil {
+		return taps
+	}
+	for _, n := range e.allocateNICs(id, sb.Config.FirecrackerEngine.Networks) {
+		if n.cfg.Egress != nil {
+			taps = append(taps, n.tapDevice)
+		}
+	}
+	return taps
 }
 
 // Stop stops a running Firecracker sandbox.
-func (e *Engine) Stop(ctx context.Context, id string) error {
+func (e *Engine) Stop(ctx context.Context, id string, opts sandbox.StopOpts) error {
 	vmDir := e.VMDir(id)
+	_, _, _, tapDevice := e.allocateNetwork(id)
+	unlock, err := e.lockSandbox(id, "stop", tapDevice)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	defer e.forgetSSHClient(id)
+
+	gracePeriod := opts.GracePeriod
+	if gracePeriod <= 0 {
+		gracePeriod = defaultStopGracePeriod
+	}
+	deadline := time.Now().Add(gracePeriod)
 
-	// Task 1: Try graceful shutdown via SSH
-	e.logger.Debugf("[1/4] Attempting graceful shutdown")
-	if err := e.gracefulShutdown(ctx, id); err != nil {
+	// Task 1: Run the pre-stop hooks and the graceful shutdown via SSH, bounded by the
+	// grace period.
+	e.logger.Debugf("[1/4] Attempting graceful shutdown (grace period %s)", gracePeriod)
+	if err := e.gracefulShutdown(ctx, id, opts.PreStopHooks, deadline); err != nil {
 		// Continue to kill process even if graceful shutdown fails
 		e.logger.Warningf("Graceful shutdown failed: %v", err)
 	}
 
-	// Task 2: Kill the firecracker process
-	e.logger.Debugf("[2/4] Killing Firecracker process")
-	if err := e.killFirecracker(vmDir); err != nil {
+	// Task 2: Terminate the firecracker process, killing it after the grace period
+	e.logger.Debugf("[2/4] Terminating Firecracker process")
+	if err := e.terminateFirecracker(vmDir, deadline); err != nil {
 		return err
 	}
 
-	// Task 3: Clean up proxy redirect rules (if any)
-	e.logger.Debugf("[3/4] Cleaning up proxy redirect rules")
-	if err := e.cleanupProxyRedirect(); err != nil {
-		e.logger.Warningf("Could not clean up proxy redirect rules: %v", err)
-	}
-
-	// Task 4: Kill the proxy process (if running)
-	e.logger.Debugf("[4/4] Killing proxy process")
+	// Task 3: Kill the proxy process (if running), before removing the redirect rules so
+	// its supervisor doesn't restart it and redirect the traffic again.
+	e.logger.Debugf("[3/4] Killing proxy process")
 	if err := e.killProxy(vmDir); err != nil {
 		e.logger.Warningf("Could not kill proxy process: %v", err)
 	}
 
+	// Task 4: Clean up proxy redirect and quarantine rules (if any)
+	e.logger.Debugf("[4/4] Cleaning up proxy redirect and quarantine rules")
+	for _, tap := range e.egressTAPs(ctx, id, tapDevice) {
+		if err := e.cleanupProxyRedirect(tap); err != nil {
+			e.logger.Warningf("Could not clean up %s proxy redirect rules: %v", tap, err)
+		}
+	}
+	if err := e.cleanupQuarantine(id); err != nil {
+		e.logger.Warningf("Could not clean up quarantine rules: %v", err)
+	}
+
 	e.logger.Infof("Stopped Firecracker sandbox: %s", id)
 	return nil
 }
@@ -222,10 +391,16 @@ func (e *Engine) Stop(ctx context.Context, id string) error {
 // Remove removes a Firecracker sandbox and all its resources.
 func (e *Engine) Remove(ctx context.Context, id string) error {
 	vmDir := e.VMDir(id)
+	e.forgetSSHClient(id)
 
 	// We need the sandbox info to get TAP device and IPs for cleanup
 	// For now, we'll use the hash-based allocation which is deterministic
 	_, gateway, vmIP, tapDevice := e.allocateNetwork(id)
+	unlock, err := e.lockSandbox(id, "remove", tapDevice)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
 	// Task 1: Kill firecracker process if running
 	e.logger.Debugf("[1/6] Killing Firecracker process")
@@ -239,10 +414,15 @@ func (e *Engine) Remove(ctx context.Context, id string) error {
 		e.logger.Warningf("Could not kill proxy process: %v", err)
 	}
 
-	// Task 3: Clean up proxy redirect rules
-	e.logger.Debugf("[3/6] Cleaning up proxy redirect rules")
-	if err := e.cleanupProxyRedirect(); err != nil {
-		e.logger.Warningf("Could not clean up proxy redirect rules: %v", err)
+	// Task 3: Clean up proxy redirect and quarantine rules
+	e.logger.Debugf("[3/6] Cleaning up proxy redirect and quarantine rules")
+	for _, tap := range e.egressTAPs(ctx, id, tapDevice) {
+		if err := e.cleanupProxyRedirect(tap); err != nil {
+			e.logger.Warningf("Could not clean up %s proxy redirect rules: %v", tap, err)
+		}
+	}
+	if err := e.cleanupQuarantine(id); err != nil {
+		e.logger.Warningf("Could not clean up quarantine rules: %v", err)
 	}
 
 	// Task 4: Cleanup iptables rules
@@ -256,6 +436,12 @@ func (e *Engine) Remove(ctx context.Context, id string) error {
 	if err := e.deleteTAP(tapDevice); err != nil {
 		e.logger.Warningf("Could not delete TAP device: %v", err)
 	}
+	// The additional network interfaces need the sandbox config.
+	if e.repo != nil {
+		if sb, err := e.repo.GetSandbox(ctx, id); err == nil && sb.Config.FirecrackerEngine != nil {
+			e.cleanupNICNetworking(e.allocateNICs(id, sb.Config.FirecrackerEngine.Networks))
+		}
+	}
 
 	// Task 6: Delete VM files
 	e.logger.Debugf("[6/6] Deleting VM files")
@@ -293,34 +479,29 @@ func (e *Engine) Status(ctx context.Context, id string) (*model.Sandbox, error)
 	}
 
 	// Check if process is still running
-	proc, err := os.FindProcess(pid)
-	if err != nil {
-		return &model.Sandbox{
-			ID:     id,
-			Status: model.SandboxStatusStopped,
-			PID:    pid,
-		}, nil
-	}
-
-	// Send signal 0 to check if process exists
-	err = proc.Signal(syscall.Signal(0))
+	socketPath := filepath.Join(vmDir, conventions.SocketFile)
 	status := model.SandboxStatusRunning
-	if err != nil {
+	if !vmProcessAlive(procDir, pid, socketPath) {
 		status = model.SandboxStatusStopped
 	}
 
 	// Get network info from deterministic allocation
 	_, _, vmIP, tapDevice := e.allocateNetwork(id)
-	socketPath := filepath.Join(vmDir, conventions.SocketFile)
 
-	return &model.Sandbox{
+	sb := &model.Sandbox{
 		ID:         id,
 		Status:     status,
 		PID:        pid,
 		SocketPath: socketPath,
 		TapDevice:  tapDevice,
 		InternalIP: vmIP,
-	}, nil
+	}
+	if status == model.SandboxStatusRunning {
+		sb.Egress = egressStatus(vmDir)
+		sb.Seccomp = seccompStatus(procDir, pid)
+	}
+
+	return sb, nil
 }
 
 // Exec executes a command inside a running Firecracker VM via SSH.
@@ -340,11 +521,11 @@ func (e *Engine) Exec(ctx context.Context, id string, command []string, opts mod
 	}
 
 	// Non-TTY mode uses the pure Go SSH client.
-	client, err := e.newSSHClient(ctx, id)
+	client, release, err := e.sshClient(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to sandbox: %w", err)
 	}
-	defer client.Close()
+	defer release()
 
 	e.logger.Debugf("Executing SSH command (Go client): %s", cmdStr)
 
@@ -440,16 +621,13 @@ func shellSingleQuote(s string) string {
 }
 
 // CopyTo copies a file or directory from the local host to the Firecracker VM via SFTP.
-func (e *Engine) CopyTo(ctx context.Context, id string, srcLocal string, dstRemote string) error {
-	client, err := e.newSSHClient(ctx, id)
-	if err != nil {
-		return fmt.Errorf("sandbox %s is not running or not reachable: %w: %w", id, err, model.ErrNotValid)
-	}
-	defer client.Close()
-
+func (e *Engine) CopyTo(ctx context.Context, id string, srcLocal string, dstRemote string, opts model.CopyOpts) error {
 	e.logger.Debugf("Copying to VM %s: %s -> %s", id, srcLocal, dstRemote)
 
-	if err := client.CopyTo(ctx, srcLocal, dstRemote); err != nil {
+	err := e.copyWithRetries(ctx, id, opts, func(client *ssh.Client, sshOpts ssh.CopyOpts) error {
+		return client.CopyTo(ctx, srcLocal, dstRemote, sshOpts)
+	})
+	if err != nil {
 		if os.IsNotExist(err) {
 			return fmt.Errorf("source path '%s' does not exist: %w", srcLocal, model.ErrNotFound)
 		}
@@ -461,16 +639,13 @@ func (e *Engine) CopyTo(ctx context.Context, id string, srcLocal string, dstRemo
 }
 
 // CopyFrom copies a file or directory from the Firecracker VM to the local host via SFTP.
-func (e *Engine) CopyFrom(ctx context.Context, id string, srcRemote string, dstLocal string) error {
-	client, err := e.newSSHClient(ctx, id)
-	if err != nil {
-		return fmt.Errorf("sandbox %s is not running or not reachable: %w: %w", id, err, model.ErrNotValid)
-	}
-	defer client.Close()
-
+func (e *Engine) CopyFrom(ctx context.Context, id string, srcRemote string, dstLocal string, opts model.CopyOpts) error {
 	e.logger.Debugf("Copying from VM %s: %s -> %s", id, srcRemote, dstLocal)
 
-	if err := client.CopyFrom(ctx, srcRemote, dstLocal); err != nil {
+	err := e.copyWithRetries(ctx, id, opts, func(client *ssh.Client, sshOpts ssh.CopyOpts) error {
+		return client.CopyFrom(ctx, srcRemote, dstLocal, sshOpts)
+	})
+	if err != nil {
 		if os.IsNotExist(err) {
 			return fmt.Errorf("source path '%s' does not exist in sandbox: %w", srcRemote, model.ErrNotFound)
 		}
@@ -481,41 +656,263 @@ func (e *Engine) CopyFrom(ctx context.Context, id string, srcRemote string, dstL
 	return nil
 }
 
+// Sync syncs a local directory into the Firecracker VM via SFTP, uploading only the
+// changed files.
+func (e *Engine) Sync(ctx context.Context, id string, localDir string, remoteDir string, opts model.SyncOpts) (*model.SyncResult, error) {
+	client, release, err := e.sshClient(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("sandbox %s is not running or not reachable: %w: %w", id, err, model.ErrNotValid)
+	}
+	defer release()
+
+	e.logger.Debugf("Syncing to VM %s: %s -> %s", id, localDir, remoteDir)
+
+	res, err := client.Sync(ctx, localDir, remoteDir, ssh.SyncOpts{
+		Delete:  opts.Delete,
+		Exclude: opts.Excluded,
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("source directory '%s' does not exist: %w", localDir, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to sync to VM: %w", err)
+	}
+
+	e.logger.Debugf("Synced %s to %s:%s (%d uploaded, %d deleted, %d unchanged)", localDir, id, remoteDir, len(res.Uploaded), len(res.Deleted), res.Unchanged)
+	return &model.SyncResult{
+		Uploaded:      res.Uploaded,
+		Deleted:       res.Deleted,
+		Unchanged:     res.Unchanged,
+		UploadedBytes: res.UploadedBytes,
+	}, nil
+}
+
+// sshCopyOpts maps the copy options to the SSH client ones, the transfer tuning
+// (concurrency, block size) uses the SSH client defaults.
+// copyRetryDelay is the wait before the first copy retry, it grows with each retry.
+const copyRetryDelay = time.Second
+
+// copyWithRetries runs a copy on the sandbox SSH connection, when the connection
+// breaks the copy is resumed on a new one up to the retries of the options.
+func (e *Engine) copyWithRetries(ctx context.Context, id string, opts model.CopyOpts, copyFn func(*ssh.Client, ssh.CopyOpts) error) error {
+	sshOpts := sshCopyOpts(opts)
+	if opts.Retries > 0 {
+		sshOpts.Checkpoint = ssh.NewCopyCheckpoint()
+	}
+
+	for attempt := 0; ; attempt++ {
+		client, release, err := e.sshClient(ctx, id)
+		if err != nil && attempt == 0 {
+			return fmt.Errorf("sandbox %s is not running or not reachable: %w: %w", id, err, model.ErrNotValid)
+		}
+		if err == nil {
+			err = copyFn(client, sshOpts)
+			release()
+		}
+		if err == nil || attempt >= opts.Retries || ctx.Err() != nil || !ssh.IsConnectionError(err) {
+			return err
+		}
+
+		// Drop the broken pooled connection so the retry dials a new one.
+		e.forgetSSHClient(id)
+		delay := time.Duration(attempt+1) * copyRetryDelay
+		e.logger.Warningf("Copy connection to sandbox %s broken, resuming in %s (retry %d/%d): %v", id, delay, attempt+1, opts.Retries, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func sshCopyOpts(opts model.CopyOpts) ssh.CopyOpts {
+	sshOpts := ssh.CopyOpts{
+		Compress:       opts.Compress,
+		BandwidthLimit: opts.BandwidthLimit,
+		Checksum:       opts.Checksum,
+	}
+	if opts.Progress != nil {
+		sshOpts.Progress = func(p ssh.CopyProgress) {
+			opts.Progress(model.CopyProgress{Bytes: p.Bytes, TotalBytes: p.TotalBytes})
+		}
+	}
+	return sshOpts
+}
+
 // Forward forwards ports from localhost to the sandbox via SSH tunnel.
 // Blocks until context is cancelled or connection drops.
-func (e *Engine) Forward(ctx context.Context, id string, ports []model.PortMapping) error {
+func (e *Engine) Forward(ctx context.Context, id string, ports []model.PortMapping, opts sandbox.ForwardOpts) error {
 	if len(ports) == 0 {
 		return fmt.Errorf("at least one port mapping is required: %w", model.ErrNotValid)
 	}
 
-	client, err := e.newSSHClient(ctx, id)
+	client, release, err := e.sshClient(ctx, id)
 	if err != nil {
 		return fmt.Errorf("SSH tunnel failed: %w", err)
 	}
-	defer client.Close()
+	defer release()
 
 	// Convert model.PortMapping to ssh.PortForward.
 	portForwards := make([]ssh.PortForward, 0, len(ports))
 	for _, pm := range ports {
-		portForwards = append(portForwards, ssh.PortForward{
+		pf := ssh.PortForward{
 			BindAddress: pm.BindAddress,
 			LocalPort:   pm.LocalPort,
 			RemotePort:  pm.RemotePort,
-		})
+		}
+		if pm.TLS != nil {
+			pf.TLS, err = tlscert.ServerConfig(pm.TLS.CertFile, pm.TLS.KeyFile)
+			if err != nil {
+				return fmt.Errorf("invalid tls for port %d: %w", pm.LocalPort, err)
+			}
+		}
+		portForwards = append(portForwards, pf)
 	}
 
 	e.logger.Debugf("Starting SSH tunnel for %d ports", len(ports))
 
-	return client.Forward(ctx, portForwards)
+	var sshOpts ssh.ForwardOpts
+	if opts.Ready != nil {
+		sshOpts.Ready = func(bound []ssh.PortForward) {
+			ready := make([]model.PortMapping, 0, len(bound))
+			for i, pf := range bound {
+				ready = append(ready, model.PortMapping{
+					BindAddress: pf.BindAddress,
+					LocalPort:   pf.LocalPort,
+					RemotePort:  pf.RemotePort,
+					TLS:         ports[i].TLS,
+				})
+			}
+			opts.Ready(ready)
+		}
+	}
+
+	return client.Forward(ctx, portForwards, sshOpts)
 }
 
-// gracefulShutdown attempts to gracefully shutdown the VM via SSH.
-func (e *Engine) gracefulShutdown(ctx context.Context, id string) error {
+// SetMemoryTarget inflates or deflates the VM memory balloon so the guest keeps
+// targetMB of its memory, the memory held by the balloon is released to the host.
+// The balloon deflates on guest OOM, so the guest gets the memory back on demand.
+func (e *Engine) SetMemoryTarget(ctx context.Context, id string, targetMB int) error {
+	if e.repo == nil {
+		return fmt.Errorf("cannot set firecracker sandbox memory target: repository not configured")
+	}
+	sb, err := e.repo.GetSandbox(ctx, id)
+	if err != nil {
+		return fmt.Errorf("could not get sandbox config: %w", err)
+	}
+
+	memoryMB := sb.Config.Resources.MemoryMB
+	if targetMB <= 0 || targetMB > memoryMB {
+		return fmt.Errorf("memory target %dMB must be between 1 and the sandbox memory (%dMB): %w", targetMB, memoryMB, model.ErrNotValid)
+	}
+
+	socketPath := filepath.Join(e.VMDir(id), conventions.SocketFile)
+	if err := e.updateBalloon(ctx, socketPath, sb.Config.Resources.BootMemoryMB()-targetMB); err != nil {
+		return err
+	}
+
+	e.logger.Infof("Set Firecracker sandbox %s memory target to %dMB", id, targetMB)
+	return nil
+}
+
+// Scale changes the VCPUs and memory of a running sandbox inside the resource
+// ceilings it booted with: the guest VCPUs are onlined or offlined and the memory
+// balloon inflated or deflated. Firecracker can't hot-plug VCPUs or memory, so
+// scaling above the ceilings requires recreating the sandbox with higher ones.
+func (e *Engine) Scale(ctx context.Context, id string, resources model.Resources) error {
+	if e.repo == nil {
+		return fmt.Errorf("cannot scale firecracker sandbox: repository not configured")
+	}
+	sb, err := e.repo.GetSandbox(ctx, id)
+	if err != nil {
+		return fmt.Errorf("could not get sandbox config: %w", err)
+	}
+	if sb.Status != model.SandboxStatusRunning {
+		return fmt.Errorf("sandbox %s is not running: %w", id, model.ErrNotValid)
+	}
+
+	current := sb.Config.Resources
+	bootVCPUs, bootMemoryMB := wholeVCPUs(current.BootVCPUs()), current.BootMemoryMB()
+	if resources.VCPUs <= 0 || wholeVCPUs(resources.VCPUs) > bootVCPUs {
+		return fmt.Errorf("vcpus %g must be between 1 and the sandbox max vcpus (%d): %w", resources.VCPUs, bootVCPUs, model.ErrNotValid)
+	}
+	if resources.MemoryMB <= 0 || resources.MemoryMB > bootMemoryMB {
+		return fmt.Errorf("memory %dMB must be between 1 and the sandbox max memory (%dMB): %w", resources.MemoryMB, bootMemoryMB, model.ErrNotValid)
+	}
+
+	if bootVCPUs > 1 {
+		if err := e.sshExec(ctx, id, vcpuOnlineScript(wholeVCPUs(resources.VCPUs), bootVCPUs)); err != nil {
+			return fmt.Errorf("could not set the guest online vcpus: %w", err)
+		}
+	}
+
+	socketPath := filepath.Join(e.VMDir(id), conventions.SocketFile)
+	if err := e.updateBalloon(ctx, socketPath, bootMemoryMB-resources.MemoryMB); err != nil {
+		return err
+	}
+
+	e.logger.Infof("Scaled Firecracker sandbox %s to %g vcpus and %dMB", id, resources.VCPUs, resources.MemoryMB)
+	return nil
+}
+
+// defaultStopGracePeriod is the default time a sandbox has to run its pre-stop hooks
+// and shut down before the Firecracker process is killed.
+const defaultStopGracePeriod = 10 * time.Second
+
+// stopPollInterval is the time between the checks of the Firecracker process exit
+// while it terminates.
+const stopPollInterval = 50 * time.Millisecond
+
+// gracefulShutdown runs the pre-stop hooks in the guest and shuts it down via SSH,
+// bounded by the deadline. A failing hook doesn't stop the shutdown.
+func (e *Engine) gracefulShutdown(ctx context.Context, id string, hooks []string, deadline time.Time) error {
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	for i, hook := range hooks {
+		e.logger.Debugf("Running pre-stop hook %d/%d of sandbox %s", i+1, len(hooks), id)
+		if err := e.sshExec(ctx, id, hook); err != nil {
+			// A failing hook doesn't stop the shutdown, the guest is stopped anyway.
+			e.logger.Warningf("Pre-stop hook %q of sandbox %s failed: %v", hook, id, err)
+		}
+	}
+
 	return e.sshExec(ctx, id, "poweroff")
 }
 
-// killFirecracker kills the firecracker process.
+// vmProcessAlive returns if the firecracker process of a sandbox is running. The
+// PID file outlives the process when the host reboots, a process reusing its PID
+// is not the sandbox VM, it doesn't have the sandbox API socket in its command line.
+func vmProcessAlive(procDir string, pid int, socketPath string) bool {
+	proc, err := os.FindProcess(pid)
+	if err != nil || proc.Signal(syscall.Signal(0)) != nil {
+		return false
+	}
+
+	cmdline, err := os.ReadFile(filepath.Join(procDir, strconv.Itoa(pid), "cmdline"))
+	if err != nil {
+		// Without procfs the signal is the only check.
+		return !errors.Is(err, os.ErrNotExist) || !procfsMounted(procDir)
+	}
+	return slices.Contains(strings.Split(string(cmdline), "\x00"), socketPath)
+}
+
+// procfsMounted returns if the procfs is available in procDir.
+func procfsMounted(procDir string) bool {
+	_, err := os.Stat(filepath.Join(procDir, "self"))
+	return err == nil
+}
+
+// killFirecracker kills the firecracker process without waiting for it to terminate.
 func (e *Engine) killFirecracker(vmDir string) error {
+	return e.terminateFirecracker(vmDir, time.Now())
+}
+
+// terminateFirecracker sends SIGTERM to the firecracker process and escalates to
+// SIGKILL when it's still running at the deadline.
+func (e *Engine) terminateFirecracker(vmDir string, deadline time.Time) error {
 	pidPath := filepath.Join(vmDir, conventions.PIDFile)
 	pidData, err := os.ReadFile(pidPath)
 	if err != nil {
@@ -535,19 +932,23 @@ func (e *Engine) killFirecracker(vmDir string) error {
 		return nil // Process doesn't exist
 	}
 
-	// First try SIGTERM for graceful shutdown
+	// First try SIGTERM for graceful shutdown, an error means the process doesn't
+	// exist anymore.
 	if err := proc.Signal(syscall.SIGTERM); err != nil {
-		if err == os.ErrProcessDone {
+		return nil
+	}
+
+	for time.Now().Before(deadline) {
+		if proc.Signal(syscall.Signal(0)) != nil {
 			return nil
 		}
-		// Process might not exist anymore
-		return nil
+		time.Sleep(stopPollInterval)
 	}
 
-	// Give it a moment to terminate
-	// In production, we'd wait with timeout and then SIGKILL
-	// For simplicity, we'll also send SIGKILL
-	_ = proc.Signal(syscall.SIGKILL)
+	if proc.Signal(syscall.Signal(0)) == nil {
+		e.logger.Warningf("Firecracker process %d still running after the grace period, killing it", pid)
+		_ = proc.Signal(syscall.SIGKILL)
+	}
 
 	return nil
 }
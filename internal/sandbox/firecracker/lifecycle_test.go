@@ -2,11 +2,17 @@ package firecracker
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"net"
+	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 
@@ -131,6 +137,95 @@ func TestEngine_Status_WithPID(t *testing.T) {
 	}
 }
 
+func TestEngine_Status_ReusedPID(t *testing.T) {
+	tmpDir := t.TempDir()
+	e, err := NewEngine(EngineConfig{
+		DataDir: tmpDir,
+		Logger:  log.Noop,
+	})
+	if err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+
+	sandboxID := "test-sandbox"
+	vmDir := e.VMDir(sandboxID)
+	if err := os.MkdirAll(vmDir, 0755); err != nil {
+		t.Fatalf("failed to create vm dir: %v", err)
+	}
+
+	// The PID of a running process that is not the sandbox VM, like after a host
+	// reboot.
+	pidPath := filepath.Join(vmDir, conventions.PIDFile)
+	if err := os.WriteFile(pidPath, []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		t.Fatalf("failed to write pid file: %v", err)
+	}
+
+	sandbox, err := e.Status(context.Background(), sandboxID)
+	if err != nil {
+		t.Fatalf("Status returned error: %v", err)
+	}
+	if sandbox.Status != model.SandboxStatusStopped {
+		t.Errorf("Expected stopped status for a reused PID, got: %s", sandbox.Status)
+	}
+}
+
+func TestVMProcessAlive(t *testing.T) {
+	pid := os.Getpid()
+	socketPath := "/data/vms/test-sandbox/firecracker.sock"
+
+	tests := map[string]struct {
+		cmdline  string
+		procfs   bool
+		pid      int
+		expAlive bool
+	}{
+		"The VM process should be alive.": {
+			cmdline:  "firecracker\x00--api-sock\x00" + socketPath + "\x00",
+			procfs:   true,
+			pid:      pid,
+			expAlive: true,
+		},
+		"A process reusing the PID should not be alive.": {
+			cmdline: "sshd\x00-D\x00",
+			procfs:  true,
+			pid:     pid,
+		},
+		"A process of another sandbox should not be alive.": {
+			cmdline: "firecracker\x00--api-sock\x00" + socketPath + ".other\x00",
+			procfs:  true,
+			pid:     pid,
+		},
+		"Without procfs a running process should be alive.": {
+			pid:      pid,
+			expAlive: true,
+		},
+		"A missing process should not be alive.": {
+			pid: 999999,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			procDir := t.TempDir()
+			if test.procfs {
+				if err := os.MkdirAll(filepath.Join(procDir, "self"), 0755); err != nil {
+					t.Fatalf("failed to create proc dir: %v", err)
+				}
+				if err := os.MkdirAll(filepath.Join(procDir, strconv.Itoa(test.pid)), 0755); err != nil {
+					t.Fatalf("failed to create proc dir: %v", err)
+				}
+				if err := os.WriteFile(filepath.Join(procDir, strconv.Itoa(test.pid), "cmdline"), []byte(test.cmdline), 0644); err != nil {
+					t.Fatalf("failed to write cmdline: %v", err)
+				}
+			}
+
+			if got := vmProcessAlive(procDir, test.pid, socketPath); got != test.expAlive {
+				t.Errorf("Expected alive %t, got: %t", test.expAlive, got)
+			}
+		})
+	}
+}
+
 func TestEngine_Start(t *testing.T) {
 	tests := map[string]struct {
 		setup          func(t *testing.T, e *Engine) (sandboxID string)
@@ -296,7 +391,7 @@ func TestEngine_Start(t *testing.T) {
 
 			sandboxID := test.setup(t, e)
 
-			err = e.Start(context.Background(), sandboxID, sandbox.StartOpts{})
+			_, err = e.Start(context.Background(), sandboxID, sandbox.StartOpts{})
 
 			if test.expErr {
 				if err == nil {
@@ -387,6 +482,64 @@ func TestEngine_killFirecracker_ProcessNotExist(t *testing.T) {
 	}
 }
 
+func TestEngine_terminateFirecracker(t *testing.T) {
+	tests := map[string]struct {
+		script      string
+		gracePeriod time.Duration
+		maxTime     time.Duration
+		minTime     time.Duration
+	}{
+		"A process exiting on SIGTERM should not wait for the grace period.": {
+			script:      "sleep 60",
+			gracePeriod: 10 * time.Second,
+			maxTime:     5 * time.Second,
+		},
+
+		"A process ignoring SIGTERM should be killed after the grace period.": {
+			script:      `trap "" TERM; while :; do sleep 0.1; done`,
+			gracePeriod: 500 * time.Millisecond,
+			minTime:     500 * time.Millisecond,
+			maxTime:     5 * time.Second,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			e := &Engine{logger: log.Noop}
+
+			cmd := exec.Command("sh", "-c", test.script)
+			if err := cmd.Start(); err != nil {
+				t.Fatalf("could not start process: %v", err)
+			}
+			exited := make(chan struct{})
+			go func() {
+				_ = cmd.Wait()
+				close(exited)
+			}()
+
+			vmDir := t.TempDir()
+			_ = os.WriteFile(filepath.Join(vmDir, conventions.PIDFile), []byte(strconv.Itoa(cmd.Process.Pid)), 0644)
+			// Let the shell set up its signal handlers.
+			time.Sleep(100 * time.Millisecond)
+
+			start := time.Now()
+			if err := e.terminateFirecracker(vmDir, start.Add(test.gracePeriod)); err != nil {
+				t.Fatalf("terminateFirecracker should not error: %v", err)
+			}
+
+			select {
+			case <-exited:
+			case <-time.After(5 * time.Second):
+				t.Fatal("process should have exited")
+			}
+			elapsed := time.Since(start)
+			if elapsed < test.minTime || elapsed > test.maxTime {
+				t.Errorf("terminate took %s, expected between %s and %s", elapsed, test.minTime, test.maxTime)
+			}
+		})
+	}
+}
+
 func TestEngine_Remove_VMDirDoesNotExist(t *testing.T) {
 	tmpDir := t.TempDir()
 	e, err := NewEngine(EngineConfig{
@@ -455,7 +608,7 @@ func TestEngine_Stop_WithTasks(t *testing.T) {
 	_ = os.WriteFile(pidPath, []byte("999999"), 0644)
 
 	// Stop should complete without errors (no running process)
-	err = e.Stop(context.Background(), sandboxID)
+	err = e.Stop(context.Background(), sandboxID, sandbox.StopOpts{})
 	if err != nil {
 		t.Errorf("Stop should handle non-running VM: %v", err)
 	}
@@ -489,8 +642,8 @@ func TestEngine_sshKeyManager(t *testing.T) {
 	}
 
 	// The injected key manager should derive per-sandbox paths from conventions.
-	expectedPath1 := conventions.SSHPrivateKeyPath(tmpDir, "sandbox-a")
-	expectedPath2 := conventions.SSHPrivateKeyPath(tmpDir, "sandbox-b")
+	expectedPath1 := conventions.SSHPrivateKeyPath(conventions.VMsPath(tmpDir), "sandbox-a")
+	expectedPath2 := conventions.SSHPrivateKeyPath(conventions.VMsPath(tmpDir), "sandbox-b")
 
 	if e.sshKeyManager.PrivateKeyPath("sandbox-a") != expectedPath1 {
 		t.Errorf("expected %s, got %s", expectedPath1, e.sshKeyManager.PrivateKeyPath("sandbox-a"))
@@ -533,7 +686,7 @@ func TestEngine_Forward_EmptyPorts(t *testing.T) {
 		t.Fatalf("failed to create engine: %v", err)
 	}
 
-	err = e.Forward(context.Background(), "sandbox-id", []model.PortMapping{})
+	err = e.Forward(context.Background(), "sandbox-id", []model.PortMapping{}, sandbox.ForwardOpts{})
 	if err == nil {
 		t.Error("Forward should return error for empty ports")
 	}
@@ -541,3 +694,92 @@ func TestEngine_Forward_EmptyPorts(t *testing.T) {
 		t.Errorf("Expected ErrNotValid, got: %v", err)
 	}
 }
+
+func TestEngine_SetMemoryTarget(t *testing.T) {
+	tests := map[string]struct {
+		targetMB     int
+		expErr       bool
+		expAmountMib int
+	}{
+		"A target lower than the sandbox memory should inflate the balloon with the difference.": {
+			targetMB:     256,
+			expAmountMib: 768,
+		},
+
+		"A target equal to the sandbox memory should deflate the balloon.": {
+			targetMB:     1024,
+			expAmountMib: 0,
+		},
+
+		"A target greater than the sandbox memory should fail.": {
+			targetMB: 2048,
+			expErr:   true,
+		},
+
+		"A zero target should fail.": {
+			targetMB: 0,
+			expErr:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Unix socket paths are limited in length, use a short temp dir.
+			tmpDir, err := os.MkdirTemp("", "sbx")
+			if err != nil {
+				t.Fatalf("failed to create temp dir: %v", err)
+			}
+			defer os.RemoveAll(tmpDir)
+
+			mRepo := &storagemock.MockRepository{}
+			mRepo.On("GetSandbox", mock.Anything, "sb1").Return(&model.Sandbox{
+				ID:     "sb1",
+				Config: model.SandboxConfig{Resources: model.Resources{MemoryMB: 1024}},
+			}, nil)
+
+			e, err := NewEngine(EngineConfig{DataDir: tmpDir, Repository: mRepo, Logger: log.Noop})
+			if err != nil {
+				t.Fatalf("failed to create engine: %v", err)
+			}
+
+			// Mock the Firecracker API of the running VM.
+			vmDir := e.VMDir("sb1")
+			if err := os.MkdirAll(vmDir, 0755); err != nil {
+				t.Fatalf("failed to create vm dir: %v", err)
+			}
+			listener, err := net.Listen("unix", filepath.Join(vmDir, conventions.SocketFile))
+			if err != nil {
+				t.Fatalf("failed to create socket: %v", err)
+			}
+			defer listener.Close()
+
+			var gotMethod, gotPath string
+			var gotBody BalloonUpdate
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotMethod, gotPath = r.Method, r.URL.Path
+				_ = json.NewDecoder(r.Body).Decode(&gotBody)
+				w.WriteHeader(http.StatusNoContent)
+			})
+			go func() { _ = http.Serve(listener, handler) }()
+
+			err = e.SetMemoryTarget(context.Background(), "sb1", test.targetMB)
+
+			if test.expErr {
+				if !errors.Is(err, model.ErrNotValid) {
+					t.Errorf("Expected ErrNotValid, got: %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("SetMemoryTarget failed: %v", err)
+			}
+			if gotMethod != http.MethodPatch || gotPath != "/balloon" {
+				t.Errorf("unexpected request: got %s %s, want PATCH /balloon", gotMethod, gotPath)
+			}
+			if gotBody.AmountMib != test.expAmountMib {
+				t.Errorf("balloon amount mismatch: got %d, want %d", gotBody.AmountMib, test.expAmountMib)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package firecracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/slok/sbx/internal/conventions"
+	"github.com/slok/sbx/internal/model"
+)
+
+// logTailBytes is the size of the end of a log read for its last lines, the lines
+// before are not returned.
+const logTailBytes = 256 * 1024
+
+// Logs returns the last lines of the Firecracker and egress proxy logs of a sandbox,
+// the proxy logs of every network interface are returned too.
+func (e *Engine) Logs(ctx context.Context, id string, lines int) ([]model.SandboxLog, error) {
+	vmDir := e.VMDir(id)
+	if _, err := os.Stat(vmDir); os.IsNotExist(err) {
+		return nil, fmt.Errorf("sandbox %s: %w", id, model.ErrNotFound)
+	}
+
+	return readLogs(vmDir, lines)
+}
+
+// readLogs reads the last lines of the logs in a VM directory, the missing logs are
+// ignored (e.g. the sandboxes without egress proxy).
+func readLogs(vmDir string, lines int) ([]model.SandboxLog, error) {
+	nicPaths, err := filepath.Glob(filepath.Join(vmDir, proxyFile(conventions.ProxyLogFile, "*")))
+	if err != nil {
+		return nil, fmt.Errorf("could not list proxy logs: %w", err)
+	}
+
+	sources := map[string]string{
+		"firecracker": filepath.Join(vmDir, conventions.LogFile),
+		"proxy":       filepath.Join(vmDir, conventions.ProxyLogFile),
+	}
+	order := []string{"firecracker", "proxy"}
+	for _, p := range nicPaths {
+		ext := filepath.Ext(conventions.ProxyLogFile)
+		source := strings.TrimSuffix(filepath.Base(p), ext)
+		sources[source] = p
+		order = append(order, source)
+	}
+
+	logs := []model.SandboxLog{}
+	for _, source := range order {
+		ls, err := tailLines(sources[source], lines)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("could not read %s log: %w", source, err)
+		}
+		logs = append(logs, model.SandboxLog{Source: source, Lines: ls})
+	}
+
+	return logs, nil
+}
+
+// tailLines returns the last lines of a file, all of them with lines 0 or less.
+func tailLines(path string, lines int) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := max(info.Size()-logTailBytes, 0)
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	text := strings.TrimRight(string(data), "\n")
+	// The first line is incomplete when the file is bigger than the tail.
+	if offset > 0 {
+		if i := strings.IndexByte(text, '\n'); i >= 0 {
+			text = text[i+1:]
+		} else {
+			text = ""
+		}
+	}
+	if text == "" {
+		return []string{}, nil
+	}
+
+	ls := strings.Split(text, "\n")
+	if lines > 0 && len(ls) > lines {
+		ls = ls[len(ls)-lines:]
+	}
+	return ls, nil
+}
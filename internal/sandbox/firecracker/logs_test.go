@@ -0,0 +1,40 @@
+package firecracker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestReadLogs(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	vmDir := t.TempDir()
+
+	// Without logs there are no logs.
+	got, err := readLogs(vmDir, 2)
+	require.NoError(err)
+	assert.Empty(got)
+
+	// The last lines of the logs of the VMM and every proxy are read.
+	write := func(name, data string) {
+		require.NoError(os.WriteFile(filepath.Join(vmDir, name), []byte(data), 0o644))
+	}
+	write("firecracker.log", "boot 1\nboot 2\nboot 3\n")
+	write("proxy.log", "listening\n")
+	write("proxy-eth1.log", "")
+
+	got, err = readLogs(vmDir, 2)
+	require.NoError(err)
+	assert.Equal([]model.SandboxLog{
+		{Source: "firecracker", Lines: []string{"boot 2", "boot 3"}},
+		{Source: "proxy", Lines: []string{"listening"}},
+		{Source: "proxy-eth1", Lines: []string{}},
+	}, got)
+}
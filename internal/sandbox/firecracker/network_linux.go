@@ -0,0 +1,1408 @@
+//go:build linux
+
+package firecracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/google/nftables"
+	"github.com/google/nftables/binaryutil"
+	"github.com/google/nftables/expr"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/ssh"
+)
+
+const (
+	// uplinkRulePriority is the priority of the policy routing rules sending the
+	// sandbox traffic through its egress interface, before the main table (32766).
+	uplinkRulePriority = 5800
+	// uplinkTableBase is the first routing table of the egress interfaces, each one
+	// uses the table of its interface index.
+	uplinkTableBase = 0x5b0000
+)
+
+// createTAP creates a TAP device for the VM using netlink.
+// This requires CAP_NET_ADMIN capability instead of root.
+// The TAP device is owned by the current user so Firecracker can access it.
+// An empty gateway creates the TAP device without host address, a 0 MTU keeps the
+// default one.
+func (e *Engine) createTAP(tapDevice, gateway string, mtu int) error {
+	// Check if device already exists
+	if link, err := netlink.LinkByName(tapDevice); err == nil {
+		e.logger.Debugf("TAP device %s already exists", tapDevice)
+		if mtu > 0 && link.Attrs().MTU != mtu {
+			if err := netlink.LinkSetMTU(link, mtu); err != nil {
+				return fmt.Errorf("failed to set MTU %d on TAP device %s: %w", mtu, tapDevice, err)
+			}
+		}
+		// Ensure it's up
+		if err := netlink.LinkSetUp(link); err != nil {
+			return fmt.Errorf("failed to bring up existing TAP device %s: %w", tapDevice, err)
+		}
+		return nil
+	}
+
+	// Get current user's UID/GID so Firecracker can access the TAP device
+	uid := uint32(os.Getuid())
+	gid := uint32(os.Getgid())
+
+	// Create TAP device with current user as owner
+	// This allows Firecracker (running as the same user) to open the device
+	tap := &netlink.Tuntap{
+		LinkAttrs: netlink.LinkAttrs{
+			Name: tapDevice,
+			MTU:  mtu,
+		},
+		Mode:  netlink.TUNTAP_MODE_TAP,
+		Flags: netlink.TUNTAP_DEFAULTS | netlink.TUNTAP_NO_PI,
+		Owner: uid,
+		Group: gid,
+	}
+
+	if err := netlink.LinkAdd(tap); err != nil {
+		return fmt.Errorf("failed to create TAP device %s: %w", tapDevice, err)
+	}
+
+	// Get the link after creation (needed for subsequent operations)
+	link, err := netlink.LinkByName(tapDevice)
+	if err != nil {
+		return fmt.Errorf("failed to get TAP device %s after creation: %w", tapDevice, err)
+	}
+
+	if gateway != "" {
+		// Parse gateway IP and create address with /24 mask
+		gatewayIP := net.ParseIP(gateway)
+		if gatewayIP == nil {
+			return fmt.Errorf("invalid gateway IP: %s", gateway)
+		}
+
+		addr := &netlink.Addr{
+			IPNet: &net.IPNet{
+				IP:   gatewayIP,
+				Mask: net.CIDRMask(24, 32),
+			},
+		}
+
+		// Assign IP address to TAP device
+		if err := netlink.AddrAdd(link, addr); err != nil {
+			// Check if address already exists
+			if !strings.Contains(err.Error(), "file exists") {
+				return fmt.Errorf("failed to assign IP %s to TAP device %s: %w", gateway, tapDevice, err)
+			}
+		}
+	}
+
+	// Bring up TAP device
+	if err := netlink.LinkSetUp(link); err != nil {
+		return fmt.Errorf("failed to bring up TAP device %s: %w", tapDevice, err)
+	}
+
+	e.logger.Debugf("Created TAP device %s with gateway %s (owner uid=%d gid=%d)", tapDevice, gateway, uid, gid)
+	return nil
+}
+
+// deleteTAP deletes a TAP device using netlink.
+func (e *Engine) deleteTAP(tapDevice string) error {
+	link, err := netlink.LinkByName(tapDevice)
+	if err != nil {
+		// Device doesn't exist, that's fine
+		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "no such") {
+			return nil
+		}
+		return fmt.Errorf("failed to find TAP device %s: %w", tapDevice, err)
+	}
+
+	if err := netlink.LinkDel(link); err != nil {
+		return fmt.Errorf("failed to delete TAP device %s: %w", tapDevice, err)
+	}
+
+	e.logger.Debugf("Deleted TAP device %s", tapDevice)
+	return nil
+}
+
+// getDefaultInterface returns the name of the default outbound network interface using netlink.
+func (e *Engine) getDefaultInterface() (string, error) {
+	// Get all routes
+	routes, err := netlink.RouteList(nil, netlink.FAMILY_V4)
+	if err != nil {
+		return "", fmt.Errorf("failed to list routes: %w", err)
+	}
+
+	// Find the default route
+	// Default route can be identified by:
+	// - Dst == nil, OR
+	// - Dst.IP is all zeros (0.0.0.0/0)
+	for _, route := range routes {
+		isDefault := false
+		if route.Dst == nil {
+			isDefault = true
+		} else if route.Dst.IP.Equal(net.IPv4zero) {
+			isDefault = true
+		}
+
+		if isDefault && route.LinkIndex > 0 {
+			// Get the link by index
+			link, err := netlink.LinkByIndex(route.LinkIndex)
+			if err != nil {
+				continue
+			}
+			return link.Attrs().Name, nil
+		}
+	}
+
+	return "", fmt.Errorf("no default route found")
+}
+
+// setupNftables sets up NAT and forwarding rules for the VM using nftables.
+// This uses the google/nftables Go library which works with CAP_NET_ADMIN.
+//
+// With an uplink (egress interface) the traffic is only masqueraded and forwarded
+// through it, the traffic routed through other interfaces is dropped so it never
+// leaves through the default route (e.g. while a VPN uplink is down).
+//
+// With MSS clamping the MSS of the TCP SYN packets forwarded from the TAP is set to
+// the MTU of their route, so the connections don't hang when the path MTU is lower
+// than the guest one (e.g. VPN uplinks).
+//
+// Docker compatibility: When Docker is installed, it creates a FORWARD chain with
+// "policy drop" that blocks all forwarded traffic by default. Docker provides the
+// DOCKER-USER chain specifically for user rules - packets go through DOCKER-USER
+// before Docker's other rules. If DOCKER-USER exists, we add our forwarding rules
+// there. Otherwise, we create our own forward chain in the sbx table.
+func (e *Engine) setupNftables(tapDevice, gateway, vmIP, uplink string, clampMSS bool) error {
+	outInterface := uplink
+	if outInterface == "" {
+		var err error
+		outInterface, err = e.getDefaultInterface()
+		if err != nil {
+			return fmt.Errorf("failed to get default interface: %w", err)
+		}
+	}
+
+	// Parse subnet
+	_, subnet, err := net.ParseCIDR(e.subnetFromGateway(gateway))
+	if err != nil {
+		return fmt.Errorf("failed to parse subnet: %w", err)
+	}
+
+	// Connect to nftables
+	conn, err := nftables.New()
+	if err != nil {
+		return fmt.Errorf("failed to connect to nftables: %w", err)
+	}
+
+	// Create our sbx table for NAT rules
+	sbxTable := &nftables.Table{
+		Family: nftables.TableFamilyIPv4,
+		Name:   nftTableName,
+	}
+	conn.AddTable(sbxTable)
+
+	// Create NAT chain for postrouting (masquerade)
+	natChain := &nftables.Chain{
+		Name:     "postrouting",
+		Table:    sbxTable,
+		Type:     nftables.ChainTypeNAT,
+		Hooknum:  nftables.ChainHookPostrouting,
+		Priority: nftables.ChainPriorityNATSource,
+	}
+	conn.AddChain(natChain)
+
+	// Rule: Masquerade traffic from VM subnet going out
+	masqExprs := []expr.Any{
+		// Match source IP in subnet
+		&expr.Payload{
+			DestRegister: 1,
+			Base:         expr.PayloadBaseNetworkHeader,
+			Offset:       12, // Source IP offset in IPv4 header
+			Len:          4,
+		},
+		&expr.Bitwise{
+			SourceRegister: 1,
+			DestRegister:   1,
+			Len:            4,
+			Mask:           subnet.Mask,
+			Xor:            []byte{0, 0, 0, 0},
+		},
+		&expr.Cmp{
+			Op:       expr.CmpOpEq,
+			Register: 1,
+			Data:     subnet.IP.To4(),
+		},
+	}
+	if uplink != "" {
+		// Match the uplink as output interface
+		masqExprs = append(masqExprs,
+			&expr.Meta{Key: expr.MetaKeyOIFNAME, Register: 1},
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     ifname(uplink),
+			},
+		)
+	}
+	// Masquerade
+	masqExprs = append(masqExprs, &expr.Masq{})
+	conn.AddRule(&nftables.Rule{
+		Table: sbxTable,
+		Chain: natChain,
+		Exprs: masqExprs,
+	})
+
+	// Check if Docker's DOCKER-USER chain exists
+	dockerUserChain := e.findDockerUserChain(conn)
+
+	if dockerUserChain != nil {
+		// Docker is present - add forwarding rules to DOCKER-USER chain
+		// This is necessary because Docker's FORWARD chain has "policy drop"
+		e.logger.Debugf("Found Docker's DOCKER-USER chain, adding forwarding rules there")
+
+		// Rule: Clamp the MSS of the TCP SYN packets from TAP, before they are accepted
+		if clampMSS {
+			conn.AddRule(clampMSSRule(dockerUserChain.Table, dockerUserChain, tapDevice))
+		}
+
+		// Rule: Drop forwarding from TAP through other interfaces than the uplink
+		if uplink != "" {
+			conn.AddRule(uplinkDropRule(dockerUserChain.Table, dockerUserChain, tapDevice, uplink))
+		}
+
+		// Rule: Allow forwarding from TAP
+		conn.AddRule(&nftables.Rule{
+			Table: dockerUserChain.Table,
+			Chain: dockerUserChain,
+			Exprs: []expr.Any{
+				&expr.Meta{Key: expr.MetaKeyIIFNAME, Register: 1},
+				&expr.Cmp{
+					Op:       expr.CmpOpEq,
+					Register: 1,
+					Data:     ifname(tapDevice),
+				},
+				&expr.Verdict{Kind: expr.VerdictAccept},
+			},
+		})
+
+		// Rule: Allow forwarding to TAP (return traffic)
+		conn.AddRule(&nftables.Rule{
+			Table: dockerUserChain.Table,
+			Chain: dockerUserChain,
+			Exprs: []expr.Any{
+				&expr.Meta{Key: expr.MetaKeyOIFNAME, Register: 1},
+				&expr.Cmp{
+					Op:       expr.CmpOpEq,
+					Register: 1,
+					Data:     ifname(tapDevice),
+				},
+				&expr.Verdict{Kind: expr.VerdictAccept},
+			},
+		})
+	} else {
+		// No Docker - create our own forward chain in sbx table
+		e.logger.Debugf("Docker's DOCKER-USER chain not found, creating own forward chain")
+
+		filterChain := &nftables.Chain{
+			Name:     "forward",
+			Table:    sbxTable,
+			Type:     nftables.ChainTypeFilter,
+			Hooknum:  nftables.ChainHookForward,
+			Priority: nftables.ChainPriorityFilter,
+		}
+		conn.AddChain(filterChain)
+
+		// Rule: Clamp the MSS of the TCP SYN packets from TAP, before they are accepted
+		if clampMSS {
+			conn.AddRule(clampMSSRule(sbxTable, filterChain, tapDevice))
+		}
+
+		// Rule: Drop forwarding from TAP through other interfaces than the uplink
+		if uplink != "" {
+			conn.AddRule(uplinkDropRule(sbxTable, filterChain, tapDevice, uplink))
+		}
+
+		// Rule: Allow forwarding from TAP
+		conn.AddRule(&nftables.Rule{
+			Table: sbxTable,
+			Chain: filterChain,
+			Exprs: []expr.Any{
+				&expr.Meta{Key: expr.MetaKeyIIFNAME, Register: 1},
+				&expr.Cmp{
+					Op:       expr.CmpOpEq,
+					Register: 1,
+					Data:     ifname(tapDevice),
+				},
+				&expr.Verdict{Kind: expr.VerdictAccept},
+			},
+		})
+
+		// Rule: Allow forwarding to TAP (return traffic)
+		conn.AddRule(&nftables.Rule{
+			Table: sbxTable,
+			Chain: filterChain,
+			Exprs: []expr.Any{
+				&expr.Meta{Key: expr.MetaKeyOIFNAME, Register: 1},
+				&expr.Cmp{
+					Op:       expr.CmpOpEq,
+					Register: 1,
+					Data:     ifname(tapDevice),
+				},
+				&expr.Verdict{Kind: expr.VerdictAccept},
+			},
+		})
+	}
+
+	// Commit the changes
+	if err := conn.Flush(); err != nil {
+		return fmt.Errorf("failed to apply nftables rules: %w", err)
+	}
+
+	if dockerUserChain != nil {
+		e.logger.Debugf("Set up nftables NAT for %s via %s (using DOCKER-USER)", tapDevice, outInterface)
+	} else {
+		e.logger.Debugf("Set up nftables NAT for %s via %s (standalone)", tapDevice, outInterface)
+	}
+	return nil
+}
+
+// uplinkDropRule returns the rule dropping the traffic forwarded from a TAP device
+// through other interfaces than its uplink.
+func uplinkDropRule(table *nftables.Table, chain *nftables.Chain, tapDevice, uplink string) *nftables.Rule {
+	return &nftables.Rule{
+		Table: table,
+		Chain: chain,
+		Exprs: []expr.Any{
+			&expr.Meta{Key: expr.MetaKeyIIFNAME, Register: 1},
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     ifname(tapDevice),
+			},
+			&expr.Meta{Key: expr.MetaKeyOIFNAME, Register: 1},
+			&expr.Cmp{
+				Op:       expr.CmpOpNeq,
+				Register: 1,
+				Data:     ifname(uplink),
+			},
+			&expr.Verdict{Kind: expr.VerdictDrop},
+		},
+	}
+}
+
+// clampMSSRule returns the rule setting the MSS option of the TCP SYN packets
+// forwarded from a TAP device to the MTU of their route (tcp option maxseg size set
+// rt mtu).
+func clampMSSRule(table *nftables.Table, chain *nftables.Chain, tapDevice string) *nftables.Rule {
+	return &nftables.Rule{
+		Table: table,
+		Chain: chain,
+		Exprs: []expr.Any{
+			&expr.Meta{Key: expr.MetaKeyIIFNAME, Register: 1},
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     ifname(tapDevice),
+			},
+			// Match TCP
+			&expr.Meta{Key: expr.MetaKeyL4PROTO, Register: 1},
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     []byte{unix.IPPROTO_TCP},
+			},
+			// Match SYN without RST (tcp flags & (syn|rst) == syn)
+			&expr.Payload{
+				DestRegister: 1,
+				Base:         expr.PayloadBaseTransportHeader,
+				Offset:       13, // TCP flags offset
+				Len:          1,
+			},
+			&expr.Bitwise{
+				SourceRegister: 1,
+				DestRegister:   1,
+				Len:            1,
+				Mask:           []byte{0x02 | 0x04},
+				Xor:            []byte{0x00},
+			},
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     []byte{0x02},
+			},
+			// Set the MSS option (kind 2, value at offset 2) to the route MSS
+			&expr.Rt{Register: 1, Key: expr.RtTCPMSS},
+			&expr.Byteorder{
+				SourceRegister: 1,
+				DestRegister:   1,
+				Op:             expr.ByteorderHton,
+				Len:            2,
+				Size:           2,
+			},
+			&expr.Exthdr{
+				SourceRegister: 1,
+				Type:           2,
+				Offset:         2,
+				Len:            2,
+				Op:             expr.ExthdrOpTcpopt,
+			},
+		},
+	}
+}
+
+// setupUplinkRouting routes the traffic of the sandbox subnet of a gateway through
+// an uplink (egress interface) instead of the default route, with a policy routing
+// rule to the uplink routing table. The table has the default route of the uplink
+// when it has one in the main table (e.g. a second NIC with a higher metric),
+// otherwise a device route (e.g. a VPN tunnel). It's idempotent, the route is
+// replaced on every call so it's restored after the uplink went down.
+func (e *Engine) setupUplinkRouting(gateway, uplink string) error {
+	if uplink == "" {
+		return nil
+	}
+
+	link, err := netlink.LinkByName(uplink)
+	if err != nil {
+		return fmt.Errorf("egress interface %s not found: %w", uplink, model.ErrNotValid)
+	}
+
+	_, subnet, err := net.ParseCIDR(e.subnetFromGateway(gateway))
+	if err != nil {
+		return fmt.Errorf("failed to parse subnet: %w", err)
+	}
+
+	index := link.Attrs().Index
+	table := uplinkTableBase + index
+	route := &netlink.Route{
+		LinkIndex: index,
+		Dst:       &net.IPNet{IP: net.IPv4zero, Mask: net.CIDRMask(0, 32)},
+		Table:     table,
+		Scope:     netlink.SCOPE_LINK,
+	}
+	gw, err := uplinkGateway(link)
+	if err != nil {
+		return err
+	}
+	if gw != nil {
+		route.Gw = gw
+		route.Scope = netlink.SCOPE_UNIVERSE
+	}
+	if err := netlink.RouteReplace(route); err != nil {
+		return fmt.Errorf("failed to set egress interface %s route: %w", uplink, err)
+	}
+
+	rule := netlink.NewRule()
+	rule.Family = netlink.FAMILY_V4
+	rule.Src = subnet
+	rule.Table = table
+	rule.Priority = uplinkRulePriority
+	if err := netlink.RuleAdd(rule); err != nil && !errors.Is(err, unix.EEXIST) {
+		return fmt.Errorf("failed to add egress interface %s routing rule: %w", uplink, err)
+	}
+
+	e.logger.Debugf("Routing %s through egress interface %s (table %d, gateway %s)", subnet, uplink, table, gw)
+	return nil
+}
+
+// cleanupUplinkRouting removes the policy routing rule of the sandbox subnet of a
+// gateway, a subnet without rule is not an error. The uplink routing tables are
+// shared by the sandboxes and kept.
+func (e *Engine) cleanupUplinkRouting(gateway string) error {
+	_, subnet, err := net.ParseCIDR(e.subnetFromGateway(gateway))
+	if err != nil {
+		return fmt.Errorf("failed to parse subnet: %w", err)
+	}
+
+	rule := netlink.NewRule()
+	rule.Family = netlink.FAMILY_V4
+	rule.Src = subnet
+	rule.Priority = uplinkRulePriority
+	if err := netlink.RuleDel(rule); err != nil {
+		if errors.Is(err, unix.ENOENT) || errors.Is(err, unix.ESRCH) {
+			return nil
+		}
+		return fmt.Errorf("failed to delete egress interface routing rule: %w", err)
+	}
+
+	e.logger.Debugf("Removed egress interface routing of %s", subnet)
+	return nil
+}
+
+// uplinkGateway returns the gateway of the default route of an interface in the
+// main table, nil without one.
+func uplinkGateway(link netlink.Link) (net.IP, error) {
+	routes, err := netlink.RouteList(link, netlink.FAMILY_V4)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list routes: %w", err)
+	}
+
+	for _, route := range routes {
+		if route.Gw == nil {
+			continue
+		}
+		if route.Dst == nil {
+			return route.Gw, nil
+		}
+		if ones, _ := route.Dst.Mask.Size(); ones == 0 && route.Dst.IP.Equal(net.IPv4zero) {
+			return route.Gw, nil
+		}
+	}
+	return nil, nil
+}
+
+// findDockerUserChain looks for Docker's DOCKER-USER chain in the filter table.
+// Returns nil if not found.
+func (e *Engine) findDockerUserChain(conn *nftables.Conn) *nftables.Chain {
+	chains, err := conn.ListChains()
+	if err != nil {
+		return nil
+	}
+
+	for _, chain := range chains {
+		if chain.Name == "DOCKER-USER" &&
+			chain.Table != nil &&
+			chain.Table.Name == "filter" &&
+			chain.Table.Family == nftables.TableFamilyIPv4 {
+			return chain
+		}
+	}
+	return nil
+}
+
+// cleanupNftables removes the NAT and forwarding rules of the VM: the ones added to
+// Docker's DOCKER-USER chain and the ones of the sbx table matching its TAP device or
+// subnet. The sbx table and its chains are shared by the sandboxes and kept.
+func (e *Engine) cleanupNftables(tapDevice, gateway, vmIP string) error {
+	_, subnet, err := net.ParseCIDR(e.subnetFromGateway(gateway))
+	if err != nil {
+		return fmt.Errorf("failed to parse subnet: %w", err)
+	}
+
+	conn, err := nftables.New()
+	if err != nil {
+		e.logger.Warningf("Failed to connect to nftables for cleanup: %v", err)
+		return nil
+	}
+
+	// First, clean up any rules we added to Docker's DOCKER-USER chain
+	e.cleanupDockerUserRules(conn, tapDevice)
+
+	// Then the rules of the VM in our sbx table
+	deleted, err := deleteSandboxRules(conn, []string{"postrouting", "forward"}, ifname(tapDevice), subnet.IP.To4())
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return nil
+	}
+	if err := conn.Flush(); err != nil {
+		return fmt.Errorf("failed to delete nftables rules: %w", err)
+	}
+
+	e.logger.Debugf("Cleaned up %d nftables rules of %s", deleted, tapDevice)
+	return nil
+}
+
+// deleteSandboxRules deletes the rules of the sbx table chains that match any of the
+// data (the TAP device name or an address of a sandbox), the rules of the other
+// sandboxes are kept. The caller flushes the changes.
+func deleteSandboxRules(conn *nftables.Conn, chainNames []string, data ...[]byte) (int, error) {
+	chains, err := conn.ListChainsOfTableFamily(nftables.TableFamilyIPv4)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list chains: %w", err)
+	}
+
+	deleted := 0
+	for _, chain := range chains {
+		if chain.Table.Name != nftTableName || !slices.Contains(chainNames, chain.Name) {
+			continue
+		}
+		rules, err := conn.GetRules(chain.Table, chain)
+		if err != nil {
+			return deleted, fmt.Errorf("failed to get %s rules: %w", chain.Name, err)
+		}
+		for _, rule := range rules {
+			if !slices.ContainsFunc(data, func(d []byte) bool { return ruleMatchesData(rule, d) }) {
+				continue
+			}
+			if err := conn.DelRule(rule); err != nil {
+				return deleted, fmt.Errorf("failed to delete %s rule: %w", chain.Name, err)
+			}
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// cleanupDockerUserRules removes any rules we added to Docker's DOCKER-USER chain.
+func (e *Engine) cleanupDockerUserRules(conn *nftables.Conn, tapDevice string) {
+	dockerUserChain := e.findDockerUserChain(conn)
+	if dockerUserChain == nil {
+		return // No DOCKER-USER chain, nothing to clean up
+	}
+
+	// Get all rules in DOCKER-USER chain
+	rules, err := conn.GetRules(dockerUserChain.Table, dockerUserChain)
+	if err != nil {
+		e.logger.Warningf("Failed to get DOCKER-USER rules: %v", err)
+		return
+	}
+
+	// Find and delete rules that reference our TAP device
+	tapName := ifname(tapDevice)
+	deletedCount := 0
+	for _, rule := range rules {
+		if ruleMatchesData(rule, tapName) {
+			if err := conn.DelRule(rule); err != nil {
+				e.logger.Warningf("Failed to delete DOCKER-USER rule: %v", err)
+			} else {
+				deletedCount++
+			}
+		}
+	}
+
+	if deletedCount > 0 {
+		if err := conn.Flush(); err != nil {
+			e.logger.Warningf("Failed to flush DOCKER-USER cleanup: %v", err)
+		} else {
+			e.logger.Debugf("Cleaned up %d rules from DOCKER-USER for %s", deletedCount, tapDevice)
+		}
+	}
+}
+
+// ruleMatchesData checks if an nftables rule compares a register with the data (e.g.
+// a TAP device name or an IP address).
+func ruleMatchesData(rule *nftables.Rule, data []byte) bool {
+	for _, e := range rule.Exprs {
+		if cmp, ok := e.(*expr.Cmp); ok {
+			if len(cmp.Data) == len(data) && string(cmp.Data) == string(data) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// setupProxyRedirect adds PREROUTING DNAT rules to redirect VM traffic through the proxy.
+// TCP ports 80 and 443 are redirected to the proxy's HTTP port on the gateway IP.
+// UDP port 53 is redirected to the proxy's DNS port on the gateway IP.
+// This ensures all HTTP/HTTPS/DNS traffic from the VM is subject to egress filtering.
+func (e *Engine) setupProxyRedirect(tapDevice, gateway, vmIP string, ports ProxyPorts) error {
+	return e.withNftables("proxy redirect setup", func() error { return e.setupProxyRedirectRules(tapDevice, gateway, vmIP, ports) })
+}
+
+// setupProxyRedirectRules adds the proxy redirect rules, the caller holds the nftables lock.
+func (e *Engine) setupProxyRedirectRules(tapDevice, gateway, vmIP string, ports ProxyPorts) error {
+	gatewayIP := net.ParseIP(gateway).To4()
+	if gatewayIP == nil {
+		return fmt.Errorf("invalid gateway IP: %s", gateway)
+	}
+
+	sourceIP := net.ParseIP(vmIP).To4()
+	if sourceIP == nil {
+		return fmt.Errorf("invalid VM IP: %s", vmIP)
+	}
+
+	conn, err := nftables.New()
+	if err != nil {
+		return fmt.Errorf("failed to connect to nftables: %w", err)
+	}
+
+	// Use the existing sbx table.
+	sbxTable := &nftables.Table{
+		Family: nftables.TableFamilyIPv4,
+		Name:   nftTableName,
+	}
+	conn.AddTable(sbxTable)
+
+	// Create PREROUTING chain for DNAT.
+	preroutingChain := &nftables.Chain{
+		Name:     "prerouting",
+		Table:    sbxTable,
+		Type:     nftables.ChainTypeNAT,
+		Hooknum:  nftables.ChainHookPrerouting,
+		Priority: nftables.ChainPriorityNATDest,
+	}
+	conn.AddChain(preroutingChain)
+
+	addProxyDNATRules(conn, sbxTable, preroutingChain, tapDevice, gatewayIP, sourceIP, ports)
+
+	// Block all forwarded traffic from the VM on non-standard ports.
+	//
+	// DNAT'd traffic (ports 80, 443, 53) is rewritten to gateway:proxyPort in prerouting,
+	// so the kernel delivers it locally to the proxy — it never enters the forward chain.
+	// Only non-DNAT'd traffic (any other port) gets forwarded through the host to the
+	// internet. This chain drops all such traffic.
+	//
+	// Priority -1 ensures this chain is evaluated before the standard forward chain
+	// (priority 0) which has "accept all from TAP" rules for general connectivity.
+	// A drop verdict in a base chain is terminal: the packet is dropped immediately
+	// without consulting lower-priority chains.
+	egressFwdChain := &nftables.Chain{
+		Name:     "forward-egress",
+		Table:    sbxTable,
+		Type:     nftables.ChainTypeFilter,
+		Hooknum:  nftables.ChainHookForward,
+		Priority: nftables.ChainPriorityRef(-1),
+	}
+	conn.AddChain(egressFwdChain)
+
+	// Accept the ICMP echo requests (pings) to the addresses of the set, filled by
+	// the proxy with the answers of the domains allowed with ICMP by the egress
+	// policy. The replies are return traffic accepted by the forward chain, and the
+	// requests are masqueraded like the rest of the VM traffic.
+	icmpSet := &nftables.Set{
+		Table:      sbxTable,
+		Name:       icmpSetName(tapDevice),
+		KeyType:    nftables.TypeIPAddr,
+		HasTimeout: true,
+	}
+	if err := conn.AddSet(icmpSet, nil); err != nil {
+		return fmt.Errorf("failed to add ICMP set: %w", err)
+	}
+	conn.AddRule(&nftables.Rule{
+		Table: sbxTable,
+		Chain: egressFwdChain,
+		Exprs: []expr.Any{
+			&expr.Meta{Key: expr.MetaKeyIIFNAME, Register: 1},
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     ifname(tapDevice),
+			},
+			// Match ICMP echo request (type 8).
+			&expr.Meta{Key: expr.MetaKeyL4PROTO, Register: 1},
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     []byte{unix.IPPROTO_ICMP},
+			},
+			&expr.Payload{
+				DestRegister: 1,
+				Base:         expr.PayloadBaseTransportHeader,
+				Offset:       0, // ICMP type offset.
+				Len:          1,
+			},
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     []byte{8},
+			},
+			// Match destination IP in the set.
+			&expr.Payload{
+				DestRegister: 1,
+				Base:         expr.PayloadBaseNetworkHeader,
+				Offset:       16, // Destination IP offset.
+				Len:          4,
+			},
+			&expr.Lookup{
+				SourceRegister: 1,
+				SetName:        icmpSet.Name,
+				SetID:          icmpSet.ID,
+			},
+			&expr.Verdict{Kind: expr.VerdictAccept},
+		},
+	})
+
+	// Accept NTP (UDP 123) to the addresses of the set, filled by the proxy with the
+	// answers of the NTP servers allowed by the NTP system service of the policy.
+	ntpSet := &nftables.Set{
+		Table:      sbxTable,
+		Name:       ntpSetName(tapDevice),
+		KeyType:    nftables.TypeIPAddr,
+		HasTimeout: true,
+	}
+	if err := conn.AddSet(ntpSet, nil); err != nil {
+		return fmt.Errorf("failed to add NTP set: %w", err)
+	}
+	conn.AddRule(&nftables.Rule{
+		Table: sbxTable,
+		Chain: egressFwdChain,
+		Exprs: []expr.Any{
+			&expr.Meta{Key: expr.MetaKeyIIFNAME, Register: 1},
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     ifname(tapDevice),
+			},
+			// Match UDP to port 123.
+			&expr.Meta{Key: expr.MetaKeyL4PROTO, Register: 1},
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     []byte{unix.IPPROTO_UDP},
+			},
+			&expr.Payload{
+				DestRegister: 1,
+				Base:         expr.PayloadBaseTransportHeader,
+				Offset:       2, // Destination port offset.
+				Len:          2,
+			},
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     binaryutil.BigEndian.PutUint16(123),
+			},
+			// Match destination IP in the set.
+			&expr.Payload{
+				DestRegister: 1,
+				Base:         expr.PayloadBaseNetworkHeader,
+				Offset:       16, // Destination IP offset.
+				Len:          4,
+			},
+			&expr.Lookup{
+				SourceRegister: 1,
+				SetName:        ntpSet.Name,
+				SetID:          ntpSet.ID,
+			},
+			&expr.Verdict{Kind: expr.VerdictAccept},
+		},
+	})
+
+	// Drop all forwarded traffic originating from the VM's TAP interface.
+	conn.AddRule(&nftables.Rule{
+		Table: sbxTable,
+		Chain: egressFwdChain,
+		Exprs: []expr.Any{
+			&expr.Meta{Key: expr.MetaKeyIIFNAME, Register: 1},
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     ifname(tapDevice),
+			},
+			&expr.Verdict{Kind: expr.VerdictDrop},
+		},
+	})
+
+	// Block all INPUT traffic from the VM to the host, except DNAT'd proxy flows.
+	//
+	// Without this chain, the VM can reach any service on the host by sending packets
+	// to the gateway IP on arbitrary ports (e.g. Ollama on 11434, dev servers, etc.).
+	// It can also discover the proxy's actual listening ports via port scanning and
+	// connect directly, bypassing DNAT and using the HTTP proxy's CONNECT method to
+	// tunnel traffic to any destination.
+	//
+	// We use conntrack status to distinguish DNAT'd traffic (legitimate proxy flows)
+	// from direct connections. DNAT happens in PREROUTING before INPUT, so by the
+	// time a packet reaches INPUT, conntrack has already marked it with IPS_DST_NAT
+	// (ct status dnat). Direct connections from the VM to gateway:proxy-port do NOT
+	// have this bit set, so they are dropped.
+	//
+	// Rules (in order):
+	//   1. Accept established/related (return traffic for existing connections)
+	//   2. Accept ct status dnat (new DNAT'd connections from PREROUTING)
+	//   3. Drop everything else from TAP
+	//
+	// Priority -1 ensures this chain is evaluated before any default INPUT chains.
+	egressInputChain := &nftables.Chain{
+		Name:     "input-egress",
+		Table:    sbxTable,
+		Type:     nftables.ChainTypeFilter,
+		Hooknum:  nftables.ChainHookInput,
+		Priority: nftables.ChainPriorityRef(-1),
+	}
+	conn.AddChain(egressInputChain)
+
+	// Accept established/related connections from TAP (return traffic for DNAT'd flows).
+	conn.AddRule(&nftables.Rule{
+		Table: sbxTable,
+		Chain: egressInputChain,
+		Exprs: []expr.Any{
+			&expr.Meta{Key: expr.MetaKeyIIFNAME, Register: 1},
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     ifname(tapDevice),
+			},
+			&expr.Ct{Register: 1, Key: expr.CtKeySTATE},
+			&expr.Bitwise{
+				SourceRegister: 1,
+				DestRegister:   1,
+				Len:            4,
+				Mask:           binaryutil.NativeEndian.PutUint32(expr.CtStateBitESTABLISHED | expr.CtStateBitRELATED),
+				Xor:            binaryutil.NativeEndian.PutUint32(0),
+			},
+			&expr.Cmp{
+				Op:       expr.CmpOpNeq,
+				Register: 1,
+				Data:     binaryutil.NativeEndian.PutUint32(0),
+			},
+			&expr.Verdict{Kind: expr.VerdictAccept},
+		},
+	})
+
+	// Accept DNAT'd connections from TAP (traffic that went through PREROUTING DNAT).
+	// IPS_DST_NAT = 0x20 in the kernel conntrack status field. This bit is set only
+	// when the packet's destination was rewritten by a DNAT rule. Direct connections
+	// to the proxy ports (without going through DNAT) will NOT have this bit set.
+	const ipsDstNAT = 0x20
+	conn.AddRule(&nftables.Rule{
+		Table: sbxTable,
+		Chain: egressInputChain,
+		Exprs: []expr.Any{
+			&expr.Meta{Key: expr.MetaKeyIIFNAME, Register: 1},
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     ifname(tapDevice),
+			},
+			&expr.Ct{Register: 1, Key: expr.CtKeySTATUS},
+			&expr.Bitwise{
+				SourceRegister: 1,
+				DestRegister:   1,
+				Len:            4,
+				Mask:           binaryutil.NativeEndian.PutUint32(ipsDstNAT),
+				Xor:            binaryutil.NativeEndian.PutUint32(0),
+			},
+			&expr.Cmp{
+				Op:       expr.CmpOpNeq,
+				Register: 1,
+				Data:     binaryutil.NativeEndian.PutUint32(0),
+			},
+			&expr.Verdict{Kind: expr.VerdictAccept},
+		},
+	})
+
+	// Drop all other traffic from the VM to the host.
+	conn.AddRule(&nftables.Rule{
+		Table: sbxTable,
+		Chain: egressInputChain,
+		Exprs: []expr.Any{
+			&expr.Meta{Key: expr.MetaKeyIIFNAME, Register: 1},
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     ifname(tapDevice),
+			},
+			&expr.Verdict{Kind: expr.VerdictDrop},
+		},
+	})
+
+	if err := conn.Flush(); err != nil {
+		return fmt.Errorf("failed to apply proxy redirect rules: %w", err)
+	}
+
+	e.logger.Debugf("Set up proxy DNAT redirect: %s TCP 80 -> %s:%d, TCP 443 -> %s:%d, UDP+TCP 53 -> %s:%d (forward-egress drop, input-egress drop)",
+		vmIP, gateway, ports.HTTPPort, gateway, ports.TLSPort, gateway, ports.DNSPort)
+	return nil
+}
+
+// replaceProxyRedirect replaces the DNAT rules of the TAP device to redirect the VM
+// traffic to new proxy ports (e.g. after a proxy restart). The old rules are removed
+// and the new ones added in a single transaction, the forward and input drop rules
+// don't depend on the ports and are kept, so the VM traffic never bypasses the proxy.
+func (e *Engine) replaceProxyRedirect(tapDevice, gateway, vmIP string, ports ProxyPorts) error {
+	return e.withNftables("proxy redirect replace", func() error { return e.replaceProxyRedirectRules(tapDevice, gateway, vmIP, ports) })
+}
+
+// replaceProxyRedirectRules replaces the proxy redirect rules, the caller holds the nftables lock.
+func (e *Engine) replaceProxyRedirectRules(tapDevice, gateway, vmIP string, ports ProxyPorts) error {
+	gatewayIP := net.ParseIP(gateway).To4()
+	if gatewayIP == nil {
+		return fmt.Errorf("invalid gateway IP: %s", gateway)
+	}
+
+	sourceIP := net.ParseIP(vmIP).To4()
+	if sourceIP == nil {
+		return fmt.Errorf("invalid VM IP: %s", vmIP)
+	}
+
+	conn, err := nftables.New()
+	if err != nil {
+		return fmt.Errorf("failed to connect to nftables: %w", err)
+	}
+
+	sbxTable := &nftables.Table{
+		Family: nftables.TableFamilyIPv4,
+		Name:   nftTableName,
+	}
+	preroutingChain := &nftables.Chain{
+		Name:  "prerouting",
+		Table: sbxTable,
+	}
+
+	rules, err := conn.GetRules(sbxTable, preroutingChain)
+	if err != nil {
+		return fmt.Errorf("failed to get prerouting rules: %w", err)
+	}
+	tapName := ifname(tapDevice)
+	for _, rule := range rules {
+		if ruleMatchesData(rule, tapName) {
+			if err := conn.DelRule(rule); err != nil {
+				return fmt.Errorf("failed to delete prerouting rule: %w", err)
+			}
+		}
+	}
+
+	addProxyDNATRules(conn, sbxTable, preroutingChain, tapDevice, gatewayIP, sourceIP, ports)
+
+	if err := conn.Flush(); err != nil {
+		return fmt.Errorf("failed to replace proxy redirect rules: %w", err)
+	}
+
+	e.logger.Debugf("Replaced proxy DNAT redirect of %s: TCP 80 -> %s:%d, TCP 443 -> %s:%d, UDP+TCP 53 -> %s:%d",
+		vmIP, gateway, ports.HTTPPort, gateway, ports.TLSPort, gateway, ports.DNSPort)
+	return nil
+}
+
+// addProxyDNATRules adds the DNAT rules that redirect the VM HTTP, HTTPS and DNS
+// traffic to the proxy ports.
+func addProxyDNATRules(conn *nftables.Conn, table *nftables.Table, chain *nftables.Chain, tapDevice string, gatewayIP, sourceIP net.IP, ports ProxyPorts) {
+	// Redirect HTTP (TCP 80) → proxy HTTP port.
+	addProxyDNATRule(conn, table, chain, tapDevice, gatewayIP, sourceIP, unix.IPPROTO_TCP, 80, uint16(ports.HTTPPort))
+	// Redirect HTTPS (TCP 443) → transparent TLS proxy port (SNI-based filtering).
+	addProxyDNATRule(conn, table, chain, tapDevice, gatewayIP, sourceIP, unix.IPPROTO_TCP, 443, uint16(ports.TLSPort))
+	// Redirect DNS (UDP 53 + TCP 53) → proxy DNS port.
+	// Both protocols must be intercepted: the DNS proxy listens on both, and
+	// without TCP 53 DNAT, clients can bypass filtering using DNS-over-TCP
+	// (e.g. `dig +tcp`) to resolve blocked domains.
+	addProxyDNATRule(conn, table, chain, tapDevice, gatewayIP, sourceIP, unix.IPPROTO_UDP, 53, uint16(ports.DNSPort))
+	addProxyDNATRule(conn, table, chain, tapDevice, gatewayIP, sourceIP, unix.IPPROTO_TCP, 53, uint16(ports.DNSPort))
+}
+
+// addProxyDNATRule adds a DNAT rule for a specific protocol + destination port.
+// Matches: iifname <tap> && ip saddr <vmIP> && <proto> dport <origPort> → DNAT to <gateway>:<proxyPort>.
+func addProxyDNATRule(conn *nftables.Conn, table *nftables.Table, chain *nftables.Chain, tapDevice string, gatewayIP, sourceIP net.IP, proto byte, origPort, proxyPort uint16) {
+	// Protocol field offset in IPv4 header is 9, length 1.
+	// For TCP/UDP, destination port is at transport header offset 2, length 2.
+	conn.AddRule(&nftables.Rule{
+		Table: table,
+		Chain: chain,
+		Exprs: []expr.Any{
+			// Match input interface = TAP device.
+			&expr.Meta{Key: expr.MetaKeyIIFNAME, Register: 1},
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     ifname(tapDevice),
+			},
+			// Match source IP = VM IP.
+			&expr.Payload{
+				DestRegister: 1,
+				Base:         expr.PayloadBaseNetworkHeader,
+				Offset:       12, // Source IP offset.
+				Len:          4,
+			},
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     sourceIP,
+			},
+			// Match protocol (TCP=6, UDP=17).
+			&expr.Meta{Key: expr.MetaKeyL4PROTO, Register: 1},
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     []byte{proto},
+			},
+			// Match destination port.
+			&expr.Payload{
+				DestRegister: 1,
+				Base:         expr.PayloadBaseTransportHeader,
+				Offset:       2, // Destination port offset.
+				Len:          2,
+			},
+			&expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     binaryutil.BigEndian.PutUint16(origPort),
+			},
+			// DNAT to gateway:proxyPort.
+			&expr.Immediate{
+				Register: 1,
+				Data:     gatewayIP,
+			},
+			&expr.Immediate{
+				Register: 2,
+				Data:     binaryutil.BigEndian.PutUint16(proxyPort),
+			},
+			&expr.NAT{
+				Type:        expr.NATTypeDestNAT,
+				Family:      unix.NFPROTO_IPV4,
+				RegAddrMin:  1,
+				RegProtoMin: 2,
+			},
+		},
+	})
+}
+
+// proxyRedirectChains are the names of the chains with the proxy redirect rules: the
+// DNAT rules (prerouting), the drop of the non-standard ports (forward-egress) and
+// of the VM to host traffic (input-egress).
+var proxyRedirectChains = []string{"prerouting", "forward-egress", "input-egress"}
+
+// cleanupProxyRedirect removes the proxy redirect rules and the ICMP and NTP sets of
+// the TAP device. This is called during Stop/Remove when egress filtering was active.
+func (e *Engine) cleanupProxyRedirect(tapDevice string) error {
+	return e.withNftables("proxy redirect cleanup", func() error { return e.cleanupProxyRedirectRules(tapDevice) })
+}
+
+// cleanupProxyRedirectRules removes the proxy redirect rules of the TAP device, the
+// chains are shared by the sandboxes and kept. The caller holds the nftables lock.
+func (e *Engine) cleanupProxyRedirectRules(tapDevice string) error {
+	conn, err := nftables.New()
+	if err != nil {
+		e.logger.Warningf("Failed to connect to nftables for proxy redirect cleanup: %v", err)
+		return nil
+	}
+
+	deleted, err := deleteSandboxRules(conn, proxyRedirectChains, ifname(tapDevice))
+	if err != nil {
+		return err
+	}
+
+	// Delete the ICMP and NTP sets, once the forward-egress rules using them are deleted.
+	tables, err := conn.ListTables()
+	if err != nil {
+		return fmt.Errorf("failed to list tables: %w", err)
+	}
+	for _, table := range tables {
+		if table.Name != nftTableName || table.Family != nftables.TableFamilyIPv4 {
+			continue
+		}
+		sets, err := conn.GetSets(table)
+		if err != nil {
+			return fmt.Errorf("failed to list sets: %w", err)
+		}
+		for _, set := range sets {
+			if set.Name == icmpSetName(tapDevice) || set.Name == ntpSetName(tapDevice) {
+				conn.DelSet(set)
+				deleted++
+			}
+		}
+		break
+	}
+
+	if deleted == 0 {
+		return nil
+	}
+	if err := conn.Flush(); err != nil {
+		return fmt.Errorf("failed to delete proxy redirect rules: %w", err)
+	}
+
+	e.logger.Debugf("Cleaned up %d proxy redirect rules and sets of %s", deleted, tapDevice)
+	return nil
+}
+
+// quarantineTableName returns the name of the nftables table with the quarantine
+// drop rules of a sandbox. Each quarantined sandbox has its own table, so lifting
+// or cleaning up its quarantine doesn't touch the other sandboxes ones.
+func quarantineTableName(id string) string {
+	return "sbx-q-" + id
+}
+
+// setupQuarantine drops all the traffic of the VM TAP devices, except the replies
+// of the guest SSH server to the host connections used to run commands in the
+// quarantined sandbox.
+//
+// The chains have priority -2 so they are evaluated before the egress chains
+// (priority -1) and the forward accept rules (priority 0). Packets accepted by them
+// are still evaluated by the lower-priority chains, the drops are terminal.
+func (e *Engine) setupQuarantine(id string, tapDevices []string) error {
+	return e.withNftables("quarantine setup", func() error { return e.setupQuarantineRules(id, tapDevices) })
+}
+
+// setupQuarantineRules adds the quarantine rules, the caller holds the nftables lock.
+func (e *Engine) setupQuarantineRules(id string, tapDevices []string) error {
+	conn, err := nftables.New()
+	if err != nil {
+		return fmt.Errorf("failed to connect to nftables: %w", err)
+	}
+
+	// The table is flushed so quarantining an already quarantined sandbox doesn't
+	// duplicate the rules.
+	table := &nftables.Table{
+		Family: nftables.TableFamilyIPv4,
+		Name:   quarantineTableName(id),
+	}
+	conn.AddTable(table)
+	conn.FlushTable(table)
+
+	fwdChain := &nftables.Chain{
+		Name:     "forward",
+		Table:    table,
+		Type:     nftables.ChainTypeFilter,
+		Hooknum:  nftables.ChainHookForward,
+		Priority: nftables.ChainPriorityRef(-2),
+	}
+	conn.AddChain(fwdChain)
+
+	inputChain := &nftables.Chain{
+		Name:     "input",
+		Table:    table,
+		Type:     nftables.ChainTypeFilter,
+		Hooknum:  nftables.ChainHookInput,
+		Priority: nftables.ChainPriorityRef(-2),
+	}
+	conn.AddChain(inputChain)
+
+	for _, tap := range tapDevices {
+		// Drop the forwarded traffic from and to the VM (internet, other sandboxes).
+		for _, key := range []expr.MetaKey{expr.MetaKeyIIFNAME, expr.MetaKeyOIFNAME} {
+			conn.AddRule(&nftables.Rule{
+				Table: table,
+				Chain: fwdChain,
+				Exprs: []expr.Any{
+					&expr.Meta{Key: key, Register: 1},
+					&expr.Cmp{
+						Op:       expr.CmpOpEq,
+						Register: 1,
+						Data:     ifname(tap),
+					},
+					&expr.Verdict{Kind: expr.VerdictDrop},
+				},
+			})
+		}
+
+		// Accept the replies of the guest SSH server to the established host connections.
+		conn.AddRule(&nftables.Rule{
+			Table: table,
+			Chain: inputChain,
+			Exprs: []expr.Any{
+				&expr.Meta{Key: expr.MetaKeyIIFNAME, Register: 1},
+				&expr.Cmp{
+					Op:       expr.CmpOpEq,
+					Register: 1,
+					Data:     ifname(tap),
+				},
+				&expr.Meta{Key: expr.MetaKeyL4PROTO, Register: 1},
+				&expr.Cmp{
+					Op:       expr.CmpOpEq,
+					Register: 1,
+					Data:     []byte{unix.IPPROTO_TCP},
+				},
+				// Source port at transport header offset 0, length 2.
+				&expr.Payload{
+					DestRegister: 1,
+					Base:         expr.PayloadBaseTransportHeader,
+					Offset:       0,
+					Len:          2,
+				},
+				&expr.Cmp{
+					Op:       expr.CmpOpEq,
+					Register: 1,
+					Data:     binaryutil.BigEndian.PutUint16(ssh.DefaultSSHPort),
+				},
+				&expr.Ct{Register: 1, Key: expr.CtKeySTATE},
+				&expr.Bitwise{
+					SourceRegister: 1,
+					DestRegister:   1,
+					Len:            4,
+					Mask:           binaryutil.NativeEndian.PutUint32(expr.CtStateBitESTABLISHED),
+					Xor:            binaryutil.NativeEndian.PutUint32(0),
+				},
+				&expr.Cmp{
+					Op:       expr.CmpOpNeq,
+					Register: 1,
+					Data:     binaryutil.NativeEndian.PutUint32(0),
+				},
+				&expr.Verdict{Kind: expr.VerdictAccept},
+			},
+		})
+
+		// Drop everything else from the VM to the host: the egress proxies (also the
+		// established proxy tunnels), the host services and the replies to the other
+		// host connections (e.g. the ingress).
+		conn.AddRule(&nftables.Rule{
+			Table: table,
+			Chain: inputChain,
+			Exprs: []expr.Any{
+				&expr.Meta{Key: expr.MetaKeyIIFNAME, Register: 1},
+				&expr.Cmp{
+					Op:       expr.CmpOpEq,
+					Register: 1,
+					Data:     ifname(tap),
+				},
+				&expr.Verdict{Kind: expr.VerdictDrop},
+			},
+		})
+	}
+
+	if err := conn.Flush(); err != nil {
+		return fmt.Errorf("failed to apply quarantine rules: %w", err)
+	}
+
+	e.logger.Debugf("Set up quarantine of %s in table %s (forward drop, input drop)", strings.Join(tapDevices, ", "), table.Name)
+	return nil
+}
+
+// cleanupQuarantine removes the quarantine table of the sandbox, the tables of the
+// other quarantined sandboxes are kept.
+func (e *Engine) cleanupQuarantine(id string) error {
+	return e.withNftables("quarantine cleanup", func() error { return e.cleanupQuarantineRules(id) })
+}
+
+// cleanupQuarantineRules removes the quarantine table, the caller holds the nftables lock.
+func (e *Engine) cleanupQuarantineRules(id string) error {
+	conn, err := nftables.New()
+	if err != nil {
+		return fmt.Errorf("failed to connect to nftables: %w", err)
+	}
+
+	tables, err := conn.ListTables()
+	if err != nil {
+		return fmt.Errorf("failed to list tables: %w", err)
+	}
+	name := quarantineTableName(id)
+	idx := slices.IndexFunc(tables, func(t *nftables.Table) bool {
+		return t.Name == name && t.Family == nftables.TableFamilyIPv4
+	})
+	if idx < 0 {
+		return nil
+	}
+
+	conn.DelTable(tables[idx])
+	if err := conn.Flush(); err != nil {
+		return fmt.Errorf("failed to delete quarantine table: %w", err)
+	}
+
+	e.logger.Debugf("Cleaned up quarantine table %s", name)
+	return nil
+}
+
+// setupIPTables is a wrapper for backwards compatibility - now uses nftables.
+func (e *Engine) setupIPTables(tapDevice, gateway, vmIP, uplink string, clampMSS bool) error {
+	return e.withNftables("nftables setup", func() error { return e.setupNftables(tapDevice, gateway, vmIP, uplink, clampMSS) })
+}
+
+// cleanupIPTables removes the NAT rules of a TAP device and its egress interface routing.
+func (e *Engine) cleanupIPTables(tapDevice, gateway, vmIP string) error {
+	return errors.Join(
+		e.withNftables("nftables cleanup", func() error { return e.cleanupNftables(tapDevice, gateway, vmIP) }),
+		e.cleanupUplinkRouting(gateway),
+	)
+}
+
+// withNftables runs the nftables changes of fn holding the nftables lock, retrying
+// them with the nftables retry policy when the kernel rejects them as busy (EBUSY).
+// fn must build its whole transaction, a failed flush discards it.
+func (e *Engine) withNftables(operation string, fn func() error) error {
+	unlock, err := e.lockNftables()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return e.retry(context.Background(), operation, e.retries.Nftables, isNftablesBusy, fn)
+}
+
+// isNftablesBusy returns true when the nftables change was rejected because the
+// ruleset was being changed (EBUSY).
+func isNftablesBusy(err error) bool {
+	return errors.Is(err, unix.EBUSY)
+}
+
+// Helper functions for nftables
+
+// ifname returns the interface name as a null-terminated byte slice for nftables.
+func ifname(name string) []byte {
+	b := make([]byte, unix.IFNAMSIZ)
+	copy(b, name)
+	return b
+}
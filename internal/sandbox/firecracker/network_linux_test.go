@@ -0,0 +1,219 @@
+package firecracker
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"testing"
+
+	"github.com/google/nftables"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+// withTestNetNS runs the test in a new network namespace with a default route, so
+// the TAP devices and nftables rules don't touch the host ones. The test is skipped
+// when the namespace can't be created (e.g. without CAP_SYS_ADMIN).
+func withTestNetNS(t *testing.T) {
+	t.Helper()
+
+	// The namespace is set on the OS thread, the test must not change of thread.
+	runtime.LockOSThread()
+	t.Cleanup(runtime.UnlockOSThread)
+
+	orig, err := netns.Get()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = orig.Close() })
+
+	ns, err := netns.New()
+	if err != nil {
+		t.Skipf("could not create network namespace: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = netns.Set(orig)
+		_ = ns.Close()
+	})
+
+	// The masquerade rules use the default route interface.
+	uplink := &netlink.Tuntap{
+		LinkAttrs: netlink.LinkAttrs{Name: "sbx-uplink0"},
+		Mode:      netlink.TUNTAP_MODE_TAP,
+		Flags:     netlink.TUNTAP_DEFAULTS | netlink.TUNTAP_NO_PI,
+	}
+	require.NoError(t, netlink.LinkAdd(uplink))
+	addr, err := netlink.ParseAddr("192.0.2.2/24")
+	require.NoError(t, err)
+	require.NoError(t, netlink.AddrAdd(uplink, addr))
+	require.NoError(t, netlink.LinkSetUp(uplink))
+	require.NoError(t, netlink.RouteAdd(&netlink.Route{LinkIndex: uplink.Attrs().Index, Gw: net.ParseIP("192.0.2.1")}))
+}
+
+// testSandboxNetwork is the host network of a test sandbox.
+type testSandboxNetwork struct {
+	gateway, vmIP, tapDevice string
+}
+
+// setupTestSandboxNetwork sets up the network and proxy redirect of a sandbox like
+// Start does.
+func setupTestSandboxNetwork(t *testing.T, e *Engine, id string) testSandboxNetwork {
+	t.Helper()
+
+	_, gateway, vmIP, tapDevice := e.allocateNetwork(id)
+	created, err := e.ensureNetworking(tapDevice, gateway, vmIP, "", model.NetworkOptions{ClampMSS: true})
+	require.NoError(t, err)
+	require.True(t, created)
+	require.NoError(t, e.setupProxyRedirect(tapDevice, gateway, vmIP, ProxyPorts{HTTPPort: 10080, TLSPort: 10443, DNSPort: 10053}))
+
+	return testSandboxNetwork{gateway: gateway, vmIP: vmIP, tapDevice: tapDevice}
+}
+
+// sandboxNftables returns the number of rules of each sbx table chain matching the
+// TAP device, and the sets of the TAP device.
+func sandboxNftables(t *testing.T, tapDevice string) (map[string]int, []string) {
+	t.Helper()
+
+	conn, err := nftables.New()
+	require.NoError(t, err)
+
+	rules := map[string]int{}
+	chains, err := conn.ListChainsOfTableFamily(nftables.TableFamilyIPv4)
+	require.NoError(t, err)
+	for _, chain := range chains {
+		if chain.Table.Name != nftTableName {
+			continue
+		}
+		chainRules, err := conn.GetRules(chain.Table, chain)
+		require.NoError(t, err)
+		for _, rule := range chainRules {
+			if ruleMatchesData(rule, ifname(tapDevice)) {
+				rules[chain.Name]++
+			}
+		}
+	}
+
+	var sets []string
+	allSets, err := conn.GetSets(&nftables.Table{Family: nftables.TableFamilyIPv4, Name: nftTableName})
+	require.NoError(t, err)
+	for _, set := range allSets {
+		if set.Name == icmpSetName(tapDevice) || set.Name == ntpSetName(tapDevice) {
+			sets = append(sets, set.Name)
+		}
+	}
+
+	return rules, sets
+}
+
+func TestStartRollbackKeepsOtherSandboxesNetwork(t *testing.T) {
+	withTestNetNS(t)
+
+	e, err := NewEngine(EngineConfig{DataDir: t.TempDir(), Logger: log.Noop})
+	require.NoError(t, err)
+
+	running := setupTestSandboxNetwork(t, e, "running-sandbox")
+	expRules, expSets := sandboxNftables(t, running.tapDevice)
+	require.NotEmpty(t, expRules["prerouting"])
+	require.Len(t, expSets, 2)
+
+	// The start of another sandbox fails after setting up its network and proxy
+	// redirect, and registered their undo like Start.
+	failed := setupTestSandboxNetwork(t, e, "failed-sandbox")
+	var rb rollback
+	rb.add("network", func() error { return e.cleanupNetworking(failed.tapDevice, failed.gateway, failed.vmIP) })
+	rb.add("proxy redirect", func() error { return e.cleanupProxyRedirect(failed.tapDevice) })
+	require.NoError(t, rb.run())
+
+	// The failed sandbox resources are removed.
+	_, err = netlink.LinkByName(failed.tapDevice)
+	assert.Error(t, err)
+	rules, sets := sandboxNftables(t, failed.tapDevice)
+	assert.Empty(t, rules)
+	assert.Empty(t, sets)
+
+	// The running sandbox resources are kept.
+	_, err = netlink.LinkByName(running.tapDevice)
+	assert.NoError(t, err)
+	rules, sets = sandboxNftables(t, running.tapDevice)
+	assert.Equal(t, expRules, rules)
+	assert.Equal(t, expSets, sets)
+}
+
+func TestCleanupNftablesKeepsOtherSandboxesRules(t *testing.T) {
+	withTestNetNS(t)
+
+	e, err := NewEngine(EngineConfig{DataDir: t.TempDir(), Logger: log.Noop})
+	require.NoError(t, err)
+
+	sb1 := setupTestSandboxNetwork(t, e, "sandbox-1")
+	sb2 := setupTestSandboxNetwork(t, e, "sandbox-2")
+	expRules, expSets := sandboxNftables(t, sb2.tapDevice)
+
+	require.NoError(t, e.cleanupProxyRedirect(sb1.tapDevice))
+	require.NoError(t, e.cleanupIPTables(sb1.tapDevice, sb1.gateway, sb1.vmIP))
+
+	rules, sets := sandboxNftables(t, sb1.tapDevice)
+	assert.Empty(t, rules)
+	assert.Empty(t, sets)
+
+	rules, sets = sandboxNftables(t, sb2.tapDevice)
+	assert.Equal(t, expRules, rules)
+	assert.Equal(t, expSets, sets)
+}
+
+// quarantineRules returns the number of rules of each chain of the sandbox
+// quarantine table, nil if the table doesn't exist.
+func quarantineRules(t *testing.T, id string) map[string]int {
+	t.Helper()
+
+	conn, err := nftables.New()
+	require.NoError(t, err)
+
+	chains, err := conn.ListChainsOfTableFamily(nftables.TableFamilyIPv4)
+	require.NoError(t, err)
+	var rules map[string]int
+	for _, chain := range chains {
+		if chain.Table.Name != quarantineTableName(id) {
+			continue
+		}
+		chainRules, err := conn.GetRules(chain.Table, chain)
+		require.NoError(t, err)
+		if rules == nil {
+			rules = map[string]int{}
+		}
+		rules[chain.Name] = len(chainRules)
+	}
+
+	return rules
+}
+
+func TestQuarantineCleanupKeepsOtherSandboxesQuarantine(t *testing.T) {
+	withTestNetNS(t)
+
+	e, err := NewEngine(EngineConfig{DataDir: t.TempDir(), Logger: log.Noop})
+	require.NoError(t, err)
+
+	sb1 := setupTestSandboxNetwork(t, e, "sandbox-1")
+	sb2 := setupTestSandboxNetwork(t, e, "sandbox-2")
+	require.NoError(t, e.setupQuarantine("sandbox-1", []string{sb1.tapDevice}))
+	require.NoError(t, e.setupQuarantine("sandbox-2", []string{sb2.tapDevice}))
+
+	// Quarantining again doesn't duplicate the rules.
+	require.NoError(t, e.setupQuarantine("sandbox-2", []string{sb2.tapDevice}))
+	expRules := map[string]int{"forward": 2, "input": 2}
+	require.Equal(t, expRules, quarantineRules(t, "sandbox-1"))
+	require.Equal(t, expRules, quarantineRules(t, "sandbox-2"))
+
+	// Removing a quarantined sandbox removes only its quarantine.
+	require.NoError(t, e.Remove(context.Background(), "sandbox-1"))
+	assert.Nil(t, quarantineRules(t, "sandbox-1"))
+	assert.Equal(t, expRules, quarantineRules(t, "sandbox-2"))
+
+	// Lifting the quarantine removes the table.
+	require.NoError(t, e.cleanupQuarantine("sandbox-2"))
+	assert.Nil(t, quarantineRules(t, "sandbox-2"))
+	require.NoError(t, e.cleanupQuarantine("sandbox-2"))
+}
@@ -0,0 +1,45 @@
+//go:build !linux
+
+package firecracker
+
+import (
+	"fmt"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// The sandbox networking uses TAP devices and nftables, only available on Linux.
+// Other hosts can drive a Linux host with the remote mode of the CLI.
+var errNetworkingUnsupported = fmt.Errorf("firecracker sandbox networking requires a Linux host: %w", model.ErrNotValid)
+
+func (e *Engine) createTAP(tapDevice, gateway string, mtu int) error { return errNetworkingUnsupported }
+
+func (e *Engine) deleteTAP(tapDevice string) error { return errNetworkingUnsupported }
+
+func (e *Engine) getDefaultInterface() (string, error) { return "", errNetworkingUnsupported }
+
+func (e *Engine) setupIPTables(tapDevice, gateway, vmIP, uplink string, clampMSS bool) error {
+	return errNetworkingUnsupported
+}
+
+func (e *Engine) setupUplinkRouting(gateway, uplink string) error { return errNetworkingUnsupported }
+
+func (e *Engine) cleanupIPTables(tapDevice, gateway, vmIP string) error {
+	return errNetworkingUnsupported
+}
+
+func (e *Engine) setupProxyRedirect(tapDevice, gateway, vmIP string, ports ProxyPorts) error {
+	return errNetworkingUnsupported
+}
+
+func (e *Engine) cleanupProxyRedirect(tapDevice string) error { return errNetworkingUnsupported }
+
+func (e *Engine) replaceProxyRedirect(tapDevice, gateway, vmIP string, ports ProxyPorts) error {
+	return errNetworkingUnsupported
+}
+
+func (e *Engine) setupQuarantine(id string, tapDevices []string) error {
+	return errNetworkingUnsupported
+}
+
+func (e *Engine) cleanupQuarantine(id string) error { return errNetworkingUnsupported }
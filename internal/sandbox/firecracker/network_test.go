@@ -2,6 +2,8 @@ package firecracker
 
 import (
 	"testing"
+
+	"github.com/slok/sbx/internal/model"
 )
 
 func TestEngine_subnetFromGateway(t *testing.T) {
@@ -44,3 +46,34 @@ func TestEngine_getDefaultInterface(t *testing.T) {
 	}
 	t.Logf("Default interface: %s", iface)
 }
+
+func TestEngine_uplink(t *testing.T) {
+	tests := map[string]struct {
+		engineUplink string
+		cfg          *model.FirecrackerEngineConfig
+		want         string
+	}{
+		"Without egress interfaces the default route should be used.": {
+			cfg: &model.FirecrackerEngineConfig{},
+		},
+		"The engine egress interface should be used by default.": {
+			engineUplink: "eth1",
+			cfg:          &model.FirecrackerEngineConfig{},
+			want:         "eth1",
+		},
+		"The sandbox egress interface should override the engine one.": {
+			engineUplink: "eth1",
+			cfg:          &model.FirecrackerEngineConfig{EgressInterface: "wg0"},
+			want:         "wg0",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			e := &Engine{egressInterface: tt.engineUplink}
+			if got := e.uplink(tt.cfg); got != tt.want {
+				t.Errorf("uplink() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
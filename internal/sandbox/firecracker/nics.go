@@ -0,0 +1,254 @@
+package firecracker
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+
+	"github.com/vishvananda/netlink"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// MaxNetworks is the maximum number of additional network interfaces of a sandbox.
+const MaxNetworks = 4
+
+var networkNameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// nic is an additional sandbox network interface with its allocated host resources.
+type nic struct {
+	id        string // Interface name on Firecracker and the guest (eth1, eth2...).
+	cfg       model.NetworkInterface
+	mac       string
+	tapDevice string
+	gateway   string // NAT interfaces only.
+	vmIP      string
+	bridge    string // Isolated interfaces only.
+}
+
+// validateNetworks validates the additional network interfaces of the sandbox.
+func validateNetworks(nets []model.NetworkInterface) error {
+	if len(nets) > MaxNetworks {
+		return fmt.Errorf("%d networks exceeds the maximum allowed (%d): %w", len(nets), MaxNetworks, model.ErrNotValid)
+	}
+
+	for i, n := range nets {
+		switch n.Mode {
+		case model.NetworkModeNAT:
+			if n.Network != "" {
+				return fmt.Errorf("networks[%d]: nat interfaces can't have a network name: %w", i, model.ErrNotValid)
+			}
+			if n.Egress != nil {
+				if err := n.Egress.Validate(); err != nil {
+					return fmt.Errorf("networks[%d]: %w", i, err)
+				}
+			}
+		case model.NetworkModeIsolated:
+			if !networkNameRegexp.MatchString(n.Network) {
+				return fmt.Errorf("networks[%d]: isolated network name %q is not valid (lowercase alphanumeric, '-' or '_', up to 32 chars): %w", i, n.Network, model.ErrNotValid)
+			}
+			if n.Egress != nil {
+				return fmt.Errorf("networks[%d]: egress policies only apply to nat interfaces: %w", i, model.ErrNotValid)
+			}
+		default:
+			return fmt.Errorf("networks[%d]: mode must be %q or %q, got %q: %w", i, model.NetworkModeNAT, model.NetworkModeIsolated, n.Mode, model.ErrNotValid)
+		}
+	}
+
+	return nil
+}
+
+// isolatedNetwork returns the host bridge and the /24 subnet prefix (e.g. 172.20.3) of an
+// isolated network. Isolated networks use 172.16.0.0/12 so they don't collide with the
+// sandbox NAT subnets (10.0.0.0/8).
+func isolatedNetwork(name string) (bridge, prefix string) {
+	hash := sha256.Sum256([]byte(name))
+	x, y := hash[0]%16, hash[1]
+	return fmt.Sprintf("sbxbr-%x%02x", x, y), fmt.Sprintf("172.%d.%d", 16+int(x), y)
+}
+
+// allocateNICs allocates the host resources of the additional network interfaces
+// based on the sandbox ID, like the primary interface.
+func (e *Engine) allocateNICs(sandboxID string, nets []model.NetworkInterface) []nic {
+	nics := make([]nic, 0, len(nets))
+	for i, n := range nets {
+		id := fmt.Sprintf("eth%d", i+1)
+		mac, gateway, vmIP, tapDevice := e.allocateNetwork(sandboxID + "/" + id)
+		ni := nic{id: id, cfg: n, mac: mac, tapDevice: tapDevice}
+		switch n.Mode {
+		case model.NetworkModeNAT:
+			ni.gateway = gateway
+			ni.vmIP = vmIP
+		case model.NetworkModeIsolated:
+			ni.bridge, _ = isolatedNetwork(n.Network)
+			ni.vmIP = n.Address
+		}
+		nics = append(nics, ni)
+	}
+	return nics
+}
+
+// assignNetworkAddresses sets the guest address of the additional network interfaces.
+// Isolated interfaces get a free address of the network, skipping the ones used by
+// other sandboxes attached to it.
+func (e *Engine) assignNetworkAddresses(ctx context.Context, sandboxID string, nets []model.NetworkInterface) error {
+	used := map[string]bool{}
+	if e.repo != nil {
+		sbs, err := e.repo.ListAllSandboxes(ctx)
+		if err != nil {
+			return fmt.Errorf("could not list sandboxes: %w", err)
+		}
+		for _, sb := range sbs {
+			if sb.ID == sandboxID || sb.Config.FirecrackerEngine == nil {
+				continue
+			}
+			for _, n := range sb.Config.FirecrackerEngine.Networks {
+				if n.Mode == model.NetworkModeIsolated {
+					used[n.Address] = true
+				}
+			}
+		}
+	}
+
+	hash := sha256.Sum256([]byte(sandboxID))
+	for i, n := range nets {
+		if n.Mode == model.NetworkModeNAT {
+			_, _, vmIP, _ := e.allocateNetwork(fmt.Sprintf("%s/eth%d", sandboxID, i+1))
+			nets[i].Address = vmIP
+			continue
+		}
+
+		// Host addresses go from .2 to .254, starting on a sandbox based one.
+		_, prefix := isolatedNetwork(n.Network)
+		nets[i].Address = ""
+		for j := 0; j < 253; j++ {
+			addr := fmt.Sprintf("%s.%d", prefix, 2+(int(hash[0])+j)%253)
+			if !used[addr] {
+				nets[i].Address = addr
+				used[addr] = true
+				break
+			}
+		}
+		if nets[i].Address == "" {
+			return fmt.Errorf("isolated network %q has no free addresses: %w", n.Network, model.ErrNotValid)
+		}
+	}
+
+	return nil
+}
+
+// ensureNICNetworking ensures the host resources of the additional network interfaces exist.
+// It returns the interfaces whose resources were (even partially) created, so they can
+// be cleaned up on a rollback. The NAT interfaces go out through the sandbox uplink,
+// all of them get the sandbox network options.
+func (e *Engine) ensureNICNetworking(nics []nic, uplink string, network model.NetworkOptions) (created []nic, err error) {
+	for _, n := range nics {
+		switch n.cfg.Mode {
+		case model.NetworkModeNAT:
+			nicCreated, err := e.ensureNetworking(n.tapDevice, n.gateway, n.vmIP, uplink, network)
+			if nicCreated {
+				created = append(created, n)
+			}
+			if err != nil {
+				return created, fmt.Errorf("%s: %w", n.id, err)
+			}
+		case model.NetworkModeIsolated:
+			if err := e.ensureBridge(n.bridge); err != nil {
+				return created, fmt.Errorf("%s: %w", n.id, err)
+			}
+			if _, err := netlink.LinkByName(n.tapDevice); err != nil {
+				created = append(created, n)
+			}
+			// Isolated TAPs don't have a host address, the host is not reachable from the network.
+			if err := e.createTAP(n.tapDevice, "", network.MTU); err != nil {
+				return created, fmt.Errorf("%s: %w", n.id, err)
+			}
+			if err := e.attachToBridge(n.tapDevice, n.bridge); err != nil {
+				return created, fmt.Errorf("%s: %w", n.id, err)
+			}
+		}
+	}
+	return created, nil
+}
+
+// cleanupNICNetworking removes the host resources of the additional network interfaces.
+// Isolated network bridges are removed when no other sandbox is attached to them.
+func (e *Engine) cleanupNICNetworking(nics []nic) {
+	for _, n := range nics {
+		if n.cfg.Mode == model.NetworkModeNAT {
+			if err := e.cleanupIPTables(n.tapDevice, n.gateway, n.vmIP); err != nil {
+				e.logger.Warningf("Could not cleanup %s nftables: %v", n.id, err)
+			}
+		}
+		if err := e.deleteTAP(n.tapDevice); err != nil {
+			e.logger.Warningf("Could not delete %s TAP device: %v", n.id, err)
+		}
+		if n.cfg.Mode == model.NetworkModeIsolated {
+			if err := e.deleteBridgeIfUnused(n.bridge); err != nil {
+				e.logger.Warningf("Could not delete %s bridge: %v", n.id, err)
+			}
+		}
+	}
+}
+
+// ensureBridge creates the bridge device if it doesn't exist.
+func (e *Engine) ensureBridge(name string) error {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		bridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Name: name}}
+		if err := netlink.LinkAdd(bridge); err != nil {
+			return fmt.Errorf("failed to create bridge %s: %w", name, err)
+		}
+		link = bridge
+		e.logger.Debugf("Created bridge %s", name)
+	}
+
+	if err := netlink.LinkSetUp(link); err != nil {
+		return fmt.Errorf("failed to bring up bridge %s: %w", name, err)
+	}
+	return nil
+}
+
+// attachToBridge attaches the TAP device to the bridge.
+func (e *Engine) attachToBridge(tapDevice, bridge string) error {
+	tap, err := netlink.LinkByName(tapDevice)
+	if err != nil {
+		return fmt.Errorf("failed to find TAP device %s: %w", tapDevice, err)
+	}
+	br, err := netlink.LinkByName(bridge)
+	if err != nil {
+		return fmt.Errorf("failed to find bridge %s: %w", bridge, err)
+	}
+	if tap.Attrs().MasterIndex == br.Attrs().Index {
+		return nil
+	}
+	if err := netlink.LinkSetMaster(tap, br); err != nil {
+		return fmt.Errorf("failed to attach TAP device %s to bridge %s: %w", tapDevice, bridge, err)
+	}
+	return nil
+}
+
+// deleteBridgeIfUnused deletes the bridge device when it doesn't have attached devices.
+func (e *Engine) deleteBridgeIfUnused(name string) error {
+	br, err := netlink.LinkByName(name)
+	if err != nil {
+		return nil // Bridge doesn't exist.
+	}
+
+	links, err := netlink.LinkList()
+	if err != nil {
+		return fmt.Errorf("failed to list links: %w", err)
+	}
+	for _, l := range links {
+		if l.Attrs().MasterIndex == br.Attrs().Index {
+			return nil
+		}
+	}
+
+	if err := netlink.LinkDel(br); err != nil {
+		return fmt.Errorf("failed to delete bridge %s: %w", name, err)
+	}
+	e.logger.Debugf("Deleted bridge %s", name)
+	return nil
+}
@@ -0,0 +1,153 @@
+package firecracker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage/memory"
+)
+
+func TestValidateNetworks(t *testing.T) {
+	tests := map[string]struct {
+		nets   []model.NetworkInterface
+		expErr bool
+	}{
+		"No networks should be valid.": {},
+
+		"NAT and isolated networks should be valid.": {
+			nets: []model.NetworkInterface{
+				{Mode: model.NetworkModeNAT, Egress: &model.EgressPolicy{Default: model.EgressActionDeny}},
+				{Mode: model.NetworkModeIsolated, Network: "backend"},
+			},
+		},
+
+		"An unknown mode should fail.": {
+			nets:   []model.NetworkInterface{{Mode: "bridge"}},
+			expErr: true,
+		},
+
+		"An isolated network without name should fail.": {
+			nets:   []model.NetworkInterface{{Mode: model.NetworkModeIsolated}},
+			expErr: true,
+		},
+
+		"An isolated network with an invalid name should fail.": {
+			nets:   []model.NetworkInterface{{Mode: model.NetworkModeIsolated, Network: "Back End"}},
+			expErr: true,
+		},
+
+		"An isolated network with egress policy should fail.": {
+			nets:   []model.NetworkInterface{{Mode: model.NetworkModeIsolated, Network: "backend", Egress: &model.EgressPolicy{Default: model.EgressActionDeny}}},
+			expErr: true,
+		},
+
+		"A NAT network with name should fail.": {
+			nets:   []model.NetworkInterface{{Mode: model.NetworkModeNAT, Network: "backend"}},
+			expErr: true,
+		},
+
+		"A NAT network with an invalid egress policy should fail.": {
+			nets:   []model.NetworkInterface{{Mode: model.NetworkModeNAT, Egress: &model.EgressPolicy{Default: "maybe"}}},
+			expErr: true,
+		},
+
+		"Too many networks should fail.": {
+			nets: []model.NetworkInterface{
+				{Mode: model.NetworkModeNAT}, {Mode: model.NetworkModeNAT}, {Mode: model.NetworkModeNAT},
+				{Mode: model.NetworkModeNAT}, {Mode: model.NetworkModeNAT},
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validateNetworks(test.nets)
+			if test.expErr {
+				assert.ErrorIs(t, err, model.ErrNotValid)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestIsolatedNetwork(t *testing.T) {
+	bridge, prefix := isolatedNetwork("backend")
+	bridge2, prefix2 := isolatedNetwork("backend")
+	assert.Equal(t, bridge, bridge2)
+	assert.Equal(t, prefix, prefix2)
+
+	assert.True(t, strings.HasPrefix(bridge, "sbxbr-"))
+	assert.LessOrEqual(t, len(bridge), 15, "bridge name should fit the interface name size")
+	assert.True(t, strings.HasPrefix(prefix, "172."))
+}
+
+func TestAllocateNICs(t *testing.T) {
+	e := &Engine{logger: log.Noop}
+	nets := []model.NetworkInterface{
+		{Mode: model.NetworkModeNAT},
+		{Mode: model.NetworkModeIsolated, Network: "backend", Address: "172.20.3.7"},
+	}
+
+	nics := e.allocateNICs("01SANDBOX", nets)
+	require.Len(t, nics, 2)
+
+	_, primaryGateway, _, primaryTap := e.allocateNetwork("01SANDBOX")
+	assert.Equal(t, "eth1", nics[0].id)
+	assert.NotEqual(t, primaryGateway, nics[0].gateway)
+	assert.NotEqual(t, primaryTap, nics[0].tapDevice)
+	assert.NotEmpty(t, nics[0].vmIP)
+	assert.Empty(t, nics[0].bridge)
+
+	bridge, _ := isolatedNetwork("backend")
+	assert.Equal(t, "eth2", nics[1].id)
+	assert.Equal(t, "172.20.3.7", nics[1].vmIP)
+	assert.Equal(t, bridge, nics[1].bridge)
+	assert.Empty(t, nics[1].gateway)
+	assert.NotEqual(t, nics[0].mac, nics[1].mac)
+}
+
+func TestAssignNetworkAddresses(t *testing.T) {
+	repo, err := memory.NewRepository(memory.RepositoryConfig{})
+	require.NoError(t, err)
+	e := &Engine{logger: log.Noop, repo: repo}
+	ctx := context.Background()
+
+	// Fill the isolated network with other sandboxes until a single address is free.
+	_, prefix := isolatedNetwork("backend")
+	for i := 2; i <= 253; i++ {
+		require.NoError(t, repo.CreateSandbox(ctx, model.Sandbox{
+			ID:        fmt.Sprintf("sb-%d", i),
+			Name:      fmt.Sprintf("sb-%d", i),
+			Status:    model.SandboxStatusStopped,
+			CreatedAt: time.Now(),
+			Config: model.SandboxConfig{FirecrackerEngine: &model.FirecrackerEngineConfig{
+				Networks: []model.NetworkInterface{{Mode: model.NetworkModeIsolated, Network: "backend", Address: fmt.Sprintf("%s.%d", prefix, i)}},
+			}},
+		}))
+	}
+
+	nets := []model.NetworkInterface{
+		{Mode: model.NetworkModeNAT},
+		{Mode: model.NetworkModeIsolated, Network: "backend"},
+	}
+	require.NoError(t, e.assignNetworkAddresses(ctx, "01SANDBOX", nets))
+
+	_, _, natIP, _ := e.allocateNetwork("01SANDBOX/eth1")
+	assert.Equal(t, natIP, nets[0].Address)
+	assert.Equal(t, prefix+".254", nets[1].Address)
+
+	// A full network should fail.
+	nets = append(nets, model.NetworkInterface{Mode: model.NetworkModeIsolated, Network: "backend"})
+	err = e.assignNetworkAddresses(ctx, "01SANDBOX", nets)
+	assert.ErrorIs(t, err, model.ErrNotValid)
+}
@@ -1,18 +1,23 @@
 package firecracker
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/slok/sbx/internal/conventions"
 	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/proxy"
 )
 
 // ProxyPorts holds the allocated ports for the proxy process.
@@ -22,34 +27,65 @@ type ProxyPorts struct {
 	DNSPort  int `json:"dns_port"`
 }
 
-// spawnProxy starts the sbx internal-vm-proxy process with the given egress policy.
-// It writes the PID file and port file to vmDir. The bindAddress is the IP the proxy
-// should listen on (typically the gateway IP) to prevent the VM from reaching the proxy
-// on other interfaces. Returns the PID and allocated ports.
-func (e *Engine) spawnProxy(vmDir string, egress model.EgressPolicy, bindAddress string) (int, ProxyPorts, error) {
+// proxyFile returns the proxy file name for a network interface. The primary
+// interface (empty nicID) uses the base name, e.g. proxy.pid and proxy-eth1.pid.
+func proxyFile(base, nicID string) string {
+	if nicID == "" {
+		return base
+	}
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + "-" + nicID + ext
+}
+
+// spawnProxy starts the egress proxy of a network interface under its supervisor
+// (sbx internal-vm-proxy-supervisor), that restarts the proxy if it crashes. The
+// supervisor PID is written to the PID file and the proxy ports to the port file
+// once the proxy is listening. The proxy listens on the gateway IP to prevent the
+// VM from reaching it on other interfaces. nicID is the network interface the proxy
+// is for (empty for the primary one). The proxy events of the sandbox are sent to the
+// engine event sinks and the sandbox webhooks. Returns the supervisor PID and the
+// proxy ports.
+func (e *Engine) spawnProxy(vmDir string, sb *model.Sandbox, egress model.EgressPolicy, nicID, tapDevice, gateway, vmIP string) (int, ProxyPorts, error) {
 	sbxBinary, err := os.Executable()
 	if err != nil {
 		return 0, ProxyPorts{}, fmt.Errorf("could not find sbx binary: %w", err)
 	}
 
-	httpPort, err := getFreePort()
+	ports, err := allocateProxyPorts()
 	if err != nil {
-		return 0, ProxyPorts{}, fmt.Errorf("could not allocate HTTP proxy port: %w", err)
+		return 0, ProxyPorts{}, err
 	}
 
-	tlsPort, err := getFreePort()
-	if err != nil {
-		return 0, ProxyPorts{}, fmt.Errorf("could not allocate TLS proxy port: %w", err)
+	if len(egress.DNSUpstreams) == 0 {
+		egress.DNSUpstreams = e.dnsUpstreams
 	}
 
-	dnsPort, err := getFreeDualPort()
-	if err != nil {
-		return 0, ProxyPorts{}, fmt.Errorf("could not allocate DNS proxy port: %w", err)
+	// A previous proxy state would be taken as the state of the new supervisor.
+	statePath := filepath.Join(vmDir, proxyFile(conventions.ProxyStateFile, nicID))
+	if err := os.Remove(statePath); err != nil && !os.IsNotExist(err) {
+		return 0, ProxyPorts{}, fmt.Errorf("could not remove proxy state file: %w", err)
 	}
 
-	args := buildProxyArgs(egress, httpPort, tlsPort, dnsPort, bindAddress)
+	supCfg := ProxySupervisorConfig{
+		VMDir:           vmDir,
+		NICID:           nicID,
+		TapDevice:       tapDevice,
+		Gateway:         gateway,
+		VMIP:            vmIP,
+		Egress:          egress,
+		EgressInterface: e.uplink(sb.Config.FirecrackerEngine),
+		Ports:           ports,
+		EventSinks:      slices.Concat(e.eventSinks, model.WebhookSinkConfigs(sb.Webhooks)),
+		SandboxID:       sb.ID,
+		SandboxName:     sb.Name,
+		Namespace:       sb.Namespace,
+	}
+	if e.hostLocks != nil {
+		supCfg.HostLocksDir = e.hostLocks.Dir()
+	}
+	args := buildSupervisorArgs(supCfg)
 
-	logPath := filepath.Join(vmDir, conventions.ProxyLogFile)
+	logPath := filepath.Join(vmDir, proxyFile(conventions.ProxyLogFile, nicID))
 	logFile, err := os.Create(logPath)
 	if err != nil {
 		return 0, ProxyPorts{}, fmt.Errorf("could not create proxy log file: %w", err)
@@ -59,38 +95,89 @@ func (e *Engine) spawnProxy(vmDir string, egress model.EgressPolicy, bindAddress
 	cmd.Dir = vmDir
 	cmd.Stdout = logFile
 	cmd.Stderr = logFile
+	if env := buildEventEnv(supCfg); env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
 
 	if err := cmd.Start(); err != nil {
 		logFile.Close()
-		return 0, ProxyPorts{}, fmt.Errorf("could not start proxy process: %w", err)
+		return 0, ProxyPorts{}, fmt.Errorf("could not start proxy supervisor process: %w", err)
 	}
 	logFile.Close()
 
 	pid := cmd.Process.Pid
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
 
 	// Write PID file.
-	pidPath := filepath.Join(vmDir, conventions.ProxyPIDFile)
+	pidPath := filepath.Join(vmDir, proxyFile(conventions.ProxyPIDFile, nicID))
 	if err := os.WriteFile(pidPath, []byte(fmt.Sprintf("%d", pid)), 0644); err != nil {
 		e.logger.Warningf("Could not write proxy PID file: %v", err)
 	}
 
-	// Write port file.
-	ports := ProxyPorts{HTTPPort: httpPort, TLSPort: tlsPort, DNSPort: dnsPort}
-	portData, err := json.Marshal(ports)
+	// Wait for the supervisor to have the proxy listening, the ports may have changed
+	// if the allocated ones were taken in the meantime.
+	ports, err = waitProxyHealthy(vmDir, nicID, exited, proxyReadyTimeout)
 	if err != nil {
-		e.logger.Warningf("Could not marshal proxy ports: %v", err)
-	} else {
-		portPath := filepath.Join(vmDir, conventions.ProxyPortFile)
-		if err := os.WriteFile(portPath, portData, 0644); err != nil {
-			e.logger.Warningf("Could not write proxy port file: %v", err)
-		}
+		_ = cmd.Process.Kill()
+		return 0, ProxyPorts{}, err
 	}
 
 	return pid, ports, nil
 }
 
+// waitProxyHealthy waits until the proxy supervisor reports the proxy as healthy and
+// returns the proxy ports.
+func waitProxyHealthy(vmDir, nicID string, exited <-chan struct{}, timeout time.Duration) (ProxyPorts, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var state proxyState
+	for {
+		var err error
+		state, err = readProxyState(vmDir, nicID)
+		if err == nil && state.Health == model.EgressHealthHealthy {
+			return readProxyPorts(vmDir, nicID)
+		}
+
+		select {
+		case <-exited:
+			return ProxyPorts{}, fmt.Errorf("proxy supervisor exited (see %s)", proxyFile(conventions.ProxyLogFile, nicID))
+		case <-timer.C:
+			if state.LastError != "" {
+				return ProxyPorts{}, fmt.Errorf("proxy not ready after %s: %s", timeout, state.LastError)
+			}
+			return ProxyPorts{}, fmt.Errorf("proxy not ready after %s", timeout)
+		case <-time.After(proxyPollInterval):
+		}
+	}
+}
+
+// allocateProxyPorts allocates free ports for the proxy listeners.
+func allocateProxyPorts() (ProxyPorts, error) {
+	httpPort, err := getFreePort()
+	if err != nil {
+		return ProxyPorts{}, fmt.Errorf("could not allocate HTTP proxy port: %w", err)
+	}
+
+	tlsPort, err := getFreePort()
+	if err != nil {
+		return ProxyPorts{}, fmt.Errorf("could not allocate TLS proxy port: %w", err)
+	}
+
+	dnsPort, err := getFreeDualPort()
+	if err != nil {
+		return ProxyPorts{}, fmt.Errorf("could not allocate DNS proxy port: %w", err)
+	}
+
+	return ProxyPorts{HTTPPort: httpPort, TLSPort: tlsPort, DNSPort: dnsPort}, nil
+}
+
 // buildProxyArgs constructs the command-line arguments for the proxy process.
-func buildProxyArgs(egress model.EgressPolicy, httpPort, tlsPort, dnsPort int, bindAddress string) []string {
+func buildProxyArgs(egress model.EgressPolicy, httpPort, tlsPort, dnsPort int, bindAddress, dnsEventsPath, sessionsPath string) []string {
 	args := []string{
 		"--logger", "json",
 		"internal-vm-proxy",
@@ -100,18 +187,83 @@ func buildProxyArgs(egress model.EgressPolicy, httpPort, tlsPort, dnsPort int, b
 		"--dns-port", strconv.Itoa(dnsPort),
 		"--default-policy", string(egress.Default),
 	}
+	if egress.FailClosed {
+		args = append(args, "--fail-closed")
+	}
+	if dnsEventsPath != "" {
+		args = append(args, "--dns-events-file", dnsEventsPath)
+	}
+	if sessionsPath != "" {
+		args = append(args, "--sessions-file", sessionsPath)
+	}
+	for _, u := range egress.DNSUpstreams {
+		args = append(args, "--dns-upstream", u)
+	}
 
 	for _, r := range egress.Rules {
-		ruleJSON := fmt.Sprintf(`{"action":%q,"domain":%q}`, string(r.Action), r.Domain)
-		args = append(args, "--rule", ruleJSON)
+		args = append(args, "--rule", egressRuleArg(r))
+	}
+	for _, s := range egress.AllowSystemServices {
+		args = append(args, "--allow-system-service", string(s))
 	}
 
 	return args
 }
 
-// killProxy kills the proxy process by reading the PID file.
+// egressRuleArg returns the JSON of an egress rule proxy argument.
+func egressRuleArg(r model.EgressRule) string {
+	if r.ICMP {
+		return fmt.Sprintf(`{"action":%q,"domain":%q,"icmp":true}`, string(r.Action), r.Domain)
+	}
+	return fmt.Sprintf(`{"action":%q,"domain":%q}`, string(r.Action), r.Domain)
+}
+
+// hasICMPRules returns true when any egress rule allows pings.
+func hasICMPRules(egress model.EgressPolicy) bool {
+	return slices.ContainsFunc(egress.Rules, func(r model.EgressRule) bool { return r.ICMP })
+}
+
+// addressSetArgs returns the proxy arguments of the nftables sets the proxy adds the
+// resolved addresses of the ICMP rules and the NTP system service to.
+func addressSetArgs(egress model.EgressPolicy, tapDevice string) []string {
+	var args []string
+	if hasICMPRules(egress) {
+		args = append(args, "--icmp-set", icmpSetName(tapDevice))
+	}
+	if slices.Contains(egress.AllowSystemServices, model.EgressSystemServiceNTP) {
+		args = append(args, "--ntp-set", ntpSetName(tapDevice))
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	return append([]string{"--sets-table", nftTableName}, args...)
+}
+
+// killProxy kills the proxy processes of all the network interfaces by reading the
+// PID files, and removes their files.
 func (e *Engine) killProxy(vmDir string) error {
-	pidPath := filepath.Join(vmDir, conventions.ProxyPIDFile)
+	nicPIDPaths, err := filepath.Glob(filepath.Join(vmDir, proxyFile(conventions.ProxyPIDFile, "*")))
+	if err != nil {
+		return fmt.Errorf("could not list proxy PID files: %w", err)
+	}
+
+	nicIDs := []string{""}
+	prefix := strings.TrimSuffix(conventions.ProxyPIDFile, filepath.Ext(conventions.ProxyPIDFile)) + "-"
+	for _, p := range nicPIDPaths {
+		nicIDs = append(nicIDs, strings.TrimSuffix(strings.TrimPrefix(filepath.Base(p), prefix), filepath.Ext(p)))
+	}
+
+	for _, nicID := range nicIDs {
+		if err := removeProxy(vmDir, nicID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// killProxyProcess kills the proxy process of a PID file.
+func killProxyProcess(pidPath string) error {
 	pidData, err := os.ReadFile(pidPath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -142,9 +294,28 @@ func (e *Engine) killProxy(vmDir string) error {
 	return nil
 }
 
-// readProxyPorts reads the allocated proxy ports from the port file.
-func readProxyPorts(vmDir string) (ProxyPorts, error) {
-	portPath := filepath.Join(vmDir, conventions.ProxyPortFile)
+// removeProxy kills the proxy process of a network interface and removes its PID,
+// port, state and session files.
+func removeProxy(vmDir, nicID string) error {
+	pidPath := filepath.Join(vmDir, proxyFile(conventions.ProxyPIDFile, nicID))
+	if err := killProxyProcess(pidPath); err != nil {
+		return err
+	}
+	for _, name := range []string{conventions.ProxyPortFile, conventions.ProxyStateFile, conventions.ProxySessionsFile} {
+		path := filepath.Join(vmDir, proxyFile(name, nicID))
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not remove proxy file: %w", err)
+		}
+	}
+	if err := os.Remove(pidPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not remove proxy file: %w", err)
+	}
+	return nil
+}
+
+// readProxyPorts reads the allocated proxy ports of a network interface from the port file.
+func readProxyPorts(vmDir, nicID string) (ProxyPorts, error) {
+	portPath := filepath.Join(vmDir, proxyFile(conventions.ProxyPortFile, nicID))
 	data, err := os.ReadFile(portPath)
 	if err != nil {
 		return ProxyPorts{}, fmt.Errorf("could not read proxy port file: %w", err)
@@ -190,3 +361,46 @@ func getFreeDualPort() (int, error) {
 
 	return port, nil
 }
+
+// DNSEvents returns the DNS queries recorded by the egress DNS proxies of all the
+// sandbox network interfaces, oldest first.
+func (e *Engine) DNSEvents(ctx context.Context, id string) ([]model.DNSEvent, error) {
+	vmDir := e.VMDir(id)
+	if _, err := os.Stat(vmDir); os.IsNotExist(err) {
+		return nil, fmt.Errorf("sandbox %s: %w", id, model.ErrNotFound)
+	}
+
+	return readDNSEvents(vmDir)
+}
+
+// readDNSEvents reads the DNS event logs of all the network interfaces in a VM directory.
+func readDNSEvents(vmDir string) ([]model.DNSEvent, error) {
+	nicPaths, err := filepath.Glob(filepath.Join(vmDir, proxyFile(conventions.DNSEventsFile, "*")))
+	if err != nil {
+		return nil, fmt.Errorf("could not list DNS event logs: %w", err)
+	}
+	paths := append([]string{filepath.Join(vmDir, conventions.DNSEventsFile)}, nicPaths...)
+
+	events := []model.DNSEvent{}
+	for _, path := range paths {
+		evs, err := proxy.ReadDNSEvents(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, ev := range evs {
+			events = append(events, model.DNSEvent{
+				Time:            ev.Time,
+				Domain:          ev.Domain,
+				QType:           ev.QType,
+				Action:          model.EgressAction(ev.Action),
+				Reason:          model.DNSEventReason(ev.Reason),
+				Rcode:           ev.Rcode,
+				UpstreamLatency: ev.UpstreamLatency,
+				Answers:         ev.Answers,
+			})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+
+	return events, nil
+}
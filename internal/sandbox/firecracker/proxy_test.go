@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -12,16 +13,19 @@ import (
 	"github.com/slok/sbx/internal/conventions"
 	"github.com/slok/sbx/internal/log"
 	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/proxy"
 )
 
 func TestBuildProxyArgs(t *testing.T) {
 	tests := map[string]struct {
-		egress      model.EgressPolicy
-		httpPort    int
-		tlsPort     int
-		dnsPort     int
-		bindAddress string
-		expArgs     []string
+		egress        model.EgressPolicy
+		httpPort      int
+		tlsPort       int
+		dnsPort       int
+		bindAddress   string
+		dnsEventsPath string
+		sessionsPath  string
+		expArgs       []string
 	}{
 		"Allow-default policy with no rules and bind address.": {
 			egress:      model.EgressPolicy{Default: model.EgressActionAllow},
@@ -65,6 +69,132 @@ func TestBuildProxyArgs(t *testing.T) {
 			},
 		},
 
+		"ICMP rules should set the ICMP flag of the rule.": {
+			egress: model.EgressPolicy{
+				Default: model.EgressActionDeny,
+				Rules:   []model.EgressRule{{Action: model.EgressActionAllow, Domain: "github.com", ICMP: true}},
+			},
+			httpPort:    9090,
+			tlsPort:     9443,
+			dnsPort:     5354,
+			bindAddress: "10.68.40.1",
+			expArgs: []string{
+				"--logger", "json",
+				"internal-vm-proxy",
+				"--bind-address", "10.68.40.1",
+				"--port", "9090",
+				"--tls-port", "9443",
+				"--dns-port", "5354",
+				"--default-policy", "deny",
+				"--rule", `{"action":"allow","domain":"github.com","icmp":true}`,
+			},
+		},
+
+		"System services should be passed to the proxy.": {
+			egress: model.EgressPolicy{
+				Default:             model.EgressActionDeny,
+				AllowSystemServices: []model.EgressSystemService{model.EgressSystemServiceNTP, model.EgressSystemServicePackages},
+			},
+			httpPort:    9090,
+			tlsPort:     9443,
+			dnsPort:     5354,
+			bindAddress: "10.68.40.1",
+			expArgs: []string{
+				"--logger", "json",
+				"internal-vm-proxy",
+				"--bind-address", "10.68.40.1",
+				"--port", "9090",
+				"--tls-port", "9443",
+				"--dns-port", "5354",
+				"--default-policy", "deny",
+				"--allow-system-service", "ntp",
+				"--allow-system-service", "packages",
+			},
+		},
+
+		"Fail-closed policy should enable the fail-closed mode.": {
+			egress: model.EgressPolicy{
+				Default:    model.EgressActionDeny,
+				Rules:      []model.EgressRule{{Action: model.EgressActionAllow, Domain: "github.com"}},
+				FailClosed: true,
+			},
+			httpPort:    9090,
+			tlsPort:     9443,
+			dnsPort:     5354,
+			bindAddress: "10.68.40.1",
+			expArgs: []string{
+				"--logger", "json",
+				"internal-vm-proxy",
+				"--bind-address", "10.68.40.1",
+				"--port", "9090",
+				"--tls-port", "9443",
+				"--dns-port", "5354",
+				"--default-policy", "deny",
+				"--fail-closed",
+				"--rule", `{"action":"allow","domain":"github.com"}`,
+			},
+		},
+
+		"DNS upstreams should be set in order.": {
+			egress: model.EgressPolicy{
+				Default:      model.EgressActionAllow,
+				DNSUpstreams: []string{"10.0.0.53", "https://dns.example.com/dns-query"},
+			},
+			httpPort:    8080,
+			tlsPort:     8443,
+			dnsPort:     5353,
+			bindAddress: "10.68.40.1",
+			expArgs: []string{
+				"--logger", "json",
+				"internal-vm-proxy",
+				"--bind-address", "10.68.40.1",
+				"--port", "8080",
+				"--tls-port", "8443",
+				"--dns-port", "5353",
+				"--default-policy", "allow",
+				"--dns-upstream", "10.0.0.53",
+				"--dns-upstream", "https://dns.example.com/dns-query",
+			},
+		},
+
+		"A DNS events path should enable the DNS query recording.": {
+			egress:        model.EgressPolicy{Default: model.EgressActionAllow},
+			httpPort:      8080,
+			tlsPort:       8443,
+			dnsPort:       5353,
+			bindAddress:   "10.68.40.1",
+			dnsEventsPath: "/vms/sb1/dns-events.jsonl",
+			expArgs: []string{
+				"--logger", "json",
+				"internal-vm-proxy",
+				"--bind-address", "10.68.40.1",
+				"--port", "8080",
+				"--tls-port", "8443",
+				"--dns-port", "5353",
+				"--default-policy", "allow",
+				"--dns-events-file", "/vms/sb1/dns-events.jsonl",
+			},
+		},
+
+		"A sessions path should enable the forwarded connections tracking.": {
+			egress:       model.EgressPolicy{Default: model.EgressActionAllow},
+			httpPort:     8080,
+			tlsPort:      8443,
+			dnsPort:      5353,
+			bindAddress:  "10.68.40.1",
+			sessionsPath: "/vms/sb1/proxy-sessions.json",
+			expArgs: []string{
+				"--logger", "json",
+				"internal-vm-proxy",
+				"--bind-address", "10.68.40.1",
+				"--port", "8080",
+				"--tls-port", "8443",
+				"--dns-port", "5353",
+				"--default-policy", "allow",
+				"--sessions-file", "/vms/sb1/proxy-sessions.json",
+			},
+		},
+
 		"Allow-default policy with deny rule and empty bind address.": {
 			egress: model.EgressPolicy{
 				Default: model.EgressActionAllow,
@@ -93,7 +223,7 @@ func TestBuildProxyArgs(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			assert := assert.New(t)
 
-			got := buildProxyArgs(test.egress, test.httpPort, test.tlsPort, test.dnsPort, test.bindAddress)
+			got := buildProxyArgs(test.egress, test.httpPort, test.tlsPort, test.dnsPort, test.bindAddress, test.dnsEventsPath, test.sessionsPath)
 			assert.Equal(test.expArgs, got)
 		})
 	}
@@ -132,6 +262,85 @@ func TestKillProxy_ProcessNotExist(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestKillProxy_NICInvalidPID(t *testing.T) {
+	e := &Engine{logger: log.Noop}
+	vmDir := t.TempDir()
+
+	pidPath := filepath.Join(vmDir, "proxy-eth1.pid")
+	err := os.WriteFile(pidPath, []byte("not-a-number"), 0644)
+	require.NoError(t, err)
+
+	// The network interface proxies should also be killed.
+	err = e.killProxy(vmDir)
+	assert.Error(t, err)
+}
+
+func TestRemoveProxy(t *testing.T) {
+	vmDir := t.TempDir()
+
+	for _, f := range []string{"proxy-eth1.pid", "proxy-eth1.json", conventions.ProxyPIDFile} {
+		err := os.WriteFile(filepath.Join(vmDir, f), []byte("999999"), 0644)
+		require.NoError(t, err)
+	}
+
+	// Only the files of the network interface proxy should be removed.
+	err := removeProxy(vmDir, "eth1")
+	require.NoError(t, err)
+	assert.NoFileExists(t, filepath.Join(vmDir, "proxy-eth1.pid"))
+	assert.NoFileExists(t, filepath.Join(vmDir, "proxy-eth1.json"))
+	assert.FileExists(t, filepath.Join(vmDir, conventions.ProxyPIDFile))
+
+	// Missing files should not fail.
+	err = removeProxy(vmDir, "eth2")
+	assert.NoError(t, err)
+}
+
+func TestReadDNSEvents(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	vmDir := t.TempDir()
+	t0 := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+
+	// A sandbox without DNS event logs doesn't have events.
+	events, err := readDNSEvents(vmDir)
+	require.NoError(err)
+	assert.Empty(events)
+
+	// The events of all the network interfaces are merged by time.
+	nicEvents := map[string][]proxy.DNSEvent{
+		"": {
+			{Time: t0, Domain: "github.com", QType: "A", Action: proxy.ActionAllow, Rcode: "NOERROR", UpstreamLatency: 5 * time.Millisecond},
+			{Time: t0.Add(2 * time.Second), Domain: "evil.com", QType: "A", Action: proxy.ActionDeny, Reason: proxy.DNSReasonRuleMatch, Rcode: "REFUSED"},
+		},
+		"eth1": {
+			{Time: t0.Add(time.Second), Domain: "github.com", QType: "AAAA", Action: proxy.ActionAllow, Reason: proxy.DNSReasonUpstreamError, Rcode: "SERVFAIL", UpstreamLatency: time.Second},
+		},
+	}
+	for nicID, evs := range nicEvents {
+		l, err := proxy.NewDNSEventLog(proxy.DNSEventLogConfig{Path: filepath.Join(vmDir, proxyFile(conventions.DNSEventsFile, nicID))})
+		require.NoError(err)
+		for _, ev := range evs {
+			l.RecordDNSEvent(ev)
+		}
+		require.NoError(l.Close())
+	}
+
+	events, err = readDNSEvents(vmDir)
+	require.NoError(err)
+	assert.Equal([]model.DNSEvent{
+		{Time: t0, Domain: "github.com", QType: "A", Action: model.EgressActionAllow, Rcode: "NOERROR", UpstreamLatency: 5 * time.Millisecond},
+		{Time: t0.Add(time.Second), Domain: "github.com", QType: "AAAA", Action: model.EgressActionAllow, Reason: model.DNSEventReasonUpstreamError, Rcode: "SERVFAIL", UpstreamLatency: time.Second},
+		{Time: t0.Add(2 * time.Second), Domain: "evil.com", QType: "A", Action: model.EgressActionDeny, Reason: model.DNSEventReasonRuleMatch, Rcode: "REFUSED"},
+	}, events)
+}
+
+func TestProxyFile(t *testing.T) {
+	assert.Equal(t, "proxy.pid", proxyFile(conventions.ProxyPIDFile, ""))
+	assert.Equal(t, "proxy-eth1.pid", proxyFile(conventions.ProxyPIDFile, "eth1"))
+	assert.Equal(t, "proxy-eth2.json", proxyFile(conventions.ProxyPortFile, "eth2"))
+}
+
 func TestReadProxyPorts(t *testing.T) {
 	tests := map[string]struct {
 		setup    func(t *testing.T, vmDir string)
@@ -169,7 +378,7 @@ func TestReadProxyPorts(t *testing.T) {
 			vmDir := t.TempDir()
 			test.setup(t, vmDir)
 
-			ports, err := readProxyPorts(vmDir)
+			ports, err := readProxyPorts(vmDir, "")
 			if test.expErr {
 				assert.Error(err)
 			} else {
@@ -0,0 +1,51 @@
+package firecracker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// Quarantine drops all the traffic of the TAP devices of a running sandbox except
+// the SSH connections of the host to the guest (enabled), or removes the drop rules.
+// The rules are in a nftables table of the sandbox, removed on lift, stop and remove.
+func (e *Engine) Quarantine(ctx context.Context, id string, enabled bool) error {
+	if e.repo == nil {
+		return fmt.Errorf("cannot quarantine firecracker sandbox: repository not configured")
+	}
+	sb, err := e.repo.GetSandbox(ctx, id)
+	if err != nil {
+		return fmt.Errorf("could not get sandbox: %w", err)
+	}
+	if sb.Status != model.SandboxStatusRunning || sb.TapDevice == "" {
+		return fmt.Errorf("sandbox %s is not running: %w", id, model.ErrNotValid)
+	}
+
+	taps := []string{sb.TapDevice}
+	if sb.Config.FirecrackerEngine != nil {
+		for _, n := range e.allocateNICs(id, sb.Config.FirecrackerEngine.Networks) {
+			taps = append(taps, n.tapDevice)
+		}
+	}
+
+	unlock, err := e.lockSandbox(id, "quarantine")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if !enabled {
+		if err := e.cleanupQuarantine(id); err != nil {
+			return fmt.Errorf("could not lift sandbox quarantine: %w", err)
+		}
+		e.logger.Infof("Lifted Firecracker sandbox %s quarantine", id)
+		return nil
+	}
+
+	if err := e.setupQuarantine(id, taps); err != nil {
+		return fmt.Errorf("could not quarantine sandbox: %w", err)
+	}
+	e.logger.Infof("Quarantined Firecracker sandbox %s", id)
+	return nil
+}
@@ -0,0 +1,323 @@
+package firecracker
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+)
+
+// ext4 file type bits of the inode modes listed by debugfs.
+const (
+	ext4TypeMask    = 0o170000
+	ext4TypeDir     = 0o040000
+	ext4TypeRegular = 0o100000
+	ext4TypeSymlink = 0o120000
+)
+
+// Rebase replaces the rootfs of a stopped sandbox with a copy of the new base rootfs
+// and copies the preserved paths from the current rootfs into it, with debugfs so
+// nothing is mounted. The current rootfs is only replaced when everything succeeded.
+func (e *Engine) Rebase(ctx context.Context, id string, cfg model.SandboxConfig, opts sandbox.RebaseOpts) error {
+	if cfg.FirecrackerEngine == nil {
+		return fmt.Errorf("firecracker engine configuration is required")
+	}
+	if _, err := exec.LookPath("debugfs"); err != nil {
+		return fmt.Errorf("debugfs not found (install e2fsprogs): %w", err)
+	}
+
+	unlock, err := e.lockSandbox(id, "rebase")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	vmDir := e.VMDir(id)
+	currentPath := e.RootFSPath(vmDir)
+	if _, err := os.Stat(currentPath); err != nil {
+		return fmt.Errorf("could not stat sandbox rootfs: %w", err)
+	}
+	basePath := e.expandPath(cfg.FirecrackerEngine.RootFS)
+
+	// The new rootfs is prepared next to the current one so the final rename is atomic.
+	workDir, err := os.MkdirTemp(vmDir, "rebase-")
+	if err != nil {
+		return fmt.Errorf("could not create rebase directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	steps := 3
+	if len(opts.PreservePaths) > 0 {
+		steps = 4
+	}
+
+	e.logger.Debugf("[1/%d] Copying new base rootfs %s", steps, basePath)
+	opts.Progress.ReportStep(1, steps, "copy_rootfs", "Copying the new base rootfs")
+	if _, err := e.copyRootFS(ctx, basePath, workDir); err != nil {
+		return err
+	}
+
+	e.logger.Debugf("[2/%d] Resizing rootfs to %d GB", steps, cfg.Resources.DiskGB)
+	opts.Progress.ReportStep(2, steps, "resize_rootfs", fmt.Sprintf("Resizing rootfs to %d GB", cfg.Resources.DiskGB))
+	if err := e.resizeRootFS(workDir, cfg.Resources.DiskGB, basePath); err != nil {
+		return err
+	}
+	newPath := e.RootFSPath(workDir)
+
+	step := 3
+	if len(opts.PreservePaths) > 0 {
+		e.logger.Debugf("[3/%d] Copying preserved paths %v", steps, opts.PreservePaths)
+		opts.Progress.ReportStep(3, steps, "preserve_paths", "Copying the preserved paths")
+		// The preserved files may not fit in the base filesystem, it's expanded to the
+		// rootfs size before (the guest would do it on boot).
+		if err := expandExt4(ctx, newPath); err != nil {
+			return err
+		}
+		for _, p := range opts.PreservePaths {
+			if err := e.copyRootFSPath(ctx, currentPath, newPath, p, workDir); err != nil {
+				return fmt.Errorf("could not preserve %s: %w", p, err)
+			}
+		}
+		step++
+	}
+
+	e.logger.Debugf("[%d/%d] Patching rootfs with SSH public key", step, steps)
+	opts.Progress.ReportStep(step, steps, "patch_rootfs", "Patching rootfs with the SSH key")
+	if err := e.patchRootFSSSH(id, workDir); err != nil {
+		return err
+	}
+
+	if err := os.Rename(newPath, currentPath); err != nil {
+		return fmt.Errorf("could not replace sandbox rootfs: %w", err)
+	}
+
+	e.logger.Infof("Rebased Firecracker sandbox %s on %s", id, basePath)
+	return nil
+}
+
+// expandExt4 grows the ext4 filesystem of the image to the image file size.
+func expandExt4(ctx context.Context, imagePath string) error {
+	// resize2fs requires a checked filesystem.
+	if out, err := exec.CommandContext(ctx, "e2fsck", "-f", "-y", imagePath).CombinedOutput(); err != nil {
+		// Exit code 1 means errors were corrected.
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+			return fmt.Errorf("could not check rootfs filesystem: %w: %s", err, out)
+		}
+	}
+	if out, err := exec.CommandContext(ctx, "resize2fs", imagePath).CombinedOutput(); err != nil {
+		return fmt.Errorf("could not expand rootfs filesystem: %w: %s", err, out)
+	}
+	return nil
+}
+
+// rootfsEntry is a file of an ext4 image listed with debugfs.
+type rootfsEntry struct {
+	Path string
+	Mode uint32
+	UID  int
+	GID  int
+}
+
+// copyRootFSPath copies a file or directory tree from the src ext4 image to the dst
+// one, keeping the modes and owners. Directories are merged: the copied files replace
+// the dst ones and the other dst files are kept. Special files are skipped.
+func (e *Engine) copyRootFSPath(ctx context.Context, srcImage, dstImage, p, workDir string) error {
+	entries, err := listRootFSTree(ctx, srcImage, p)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		e.logger.Debugf("Preserved path %s doesn't exist in the sandbox rootfs", p)
+		return nil
+	}
+
+	// The contents and symlink targets come from a dump on the host, the metadata from
+	// the listing (the dump can't keep the owners without privileges).
+	dumpDir, err := os.MkdirTemp(workDir, "dump-")
+	if err != nil {
+		return fmt.Errorf("could not create dump directory: %w", err)
+	}
+	defer os.RemoveAll(dumpDir)
+	if _, err := runDebugfs(ctx, srcImage, false, []string{fmt.Sprintf("rdump %s %s", quoteDebugfs(p), quoteDebugfs(dumpDir))}); err != nil {
+		return err
+	}
+	hostPath := func(entryPath string) string {
+		rel := strings.TrimPrefix(entryPath, path.Dir(p))
+		return filepath.Join(dumpDir, filepath.FromSlash(rel))
+	}
+
+	var cmds []string
+	// The parents are created with the default mode when the new rootfs misses them.
+	for dir := path.Dir(p); dir != "/"; dir = path.Dir(dir) {
+		cmds = append([]string{"mkdir " + quoteDebugfs(dir)}, cmds...)
+	}
+	for _, en := range entries {
+		q := quoteDebugfs(en.Path)
+		switch en.Mode & ext4TypeMask {
+		case ext4TypeDir:
+			cmds = append(cmds, "mkdir "+q)
+		case ext4TypeRegular:
+			src := hostPath(en.Path)
+			// The dumped files may not be readable by an unprivileged owner.
+			_ = os.Chmod(src, fs.FileMode(en.Mode&0o777)|0o400)
+			cmds = append(cmds, "rm "+q, fmt.Sprintf("write %s %s", quoteDebugfs(src), q))
+		case ext4TypeSymlink:
+			target, err := os.Readlink(hostPath(en.Path))
+			if err != nil {
+				return fmt.Errorf("could not read symlink %s: %w", en.Path, err)
+			}
+			cmds = append(cmds, "rm "+q, fmt.Sprintf("symlink %s %s", q, quoteDebugfs(target)))
+		default:
+			e.logger.Debugf("Skipping special file %s", en.Path)
+			continue
+		}
+		cmds = append(cmds,
+			fmt.Sprintf("sif %s mode 0%o", q, en.Mode),
+			fmt.Sprintf("sif %s uid %d", q, en.UID),
+			fmt.Sprintf("sif %s gid %d", q, en.GID),
+		)
+	}
+
+	if _, err := runDebugfs(ctx, dstImage, true, cmds); err != nil {
+		return err
+	}
+	return nil
+}
+
+// listRootFSTree returns the entry of p and all its descendants in the ext4 image,
+// the parents before their children. It's empty when p doesn't exist.
+func listRootFSTree(ctx context.Context, image, p string) ([]rootfsEntry, error) {
+	out, err := runDebugfs(ctx, image, false, []string{"ls -p " + quoteDebugfs(path.Dir(p))})
+	if err != nil {
+		return nil, err
+	}
+	var entries []rootfsEntry
+	for _, en := range parseDebugfsLs(out)[path.Dir(p)] {
+		if en.Path == p {
+			entries = append(entries, en)
+		}
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	// Breadth first, one debugfs run per tree level.
+	level := []string{}
+	if entries[0].Mode&ext4TypeMask == ext4TypeDir {
+		level = append(level, p)
+	}
+	for len(level) > 0 {
+		cmds := make([]string, 0, len(level))
+		for _, dir := range level {
+			cmds = append(cmds, "ls -p "+quoteDebugfs(dir))
+		}
+		out, err := runDebugfs(ctx, image, false, cmds)
+		if err != nil {
+			return nil, err
+		}
+		listings := parseDebugfsLs(out)
+
+		var next []string
+		for _, dir := range level {
+			for _, en := range listings[dir] {
+				entries = append(entries, en)
+				if en.Mode&ext4TypeMask == ext4TypeDir {
+					next = append(next, en.Path)
+				}
+			}
+		}
+		level = next
+	}
+
+	return entries, nil
+}
+
+// parseDebugfsLs parses the output of debugfs "ls -p" commands (/inode/mode/uid/gid/name/size/
+// lines after the echoed command) into the entries of each listed directory, without
+// the "." and ".." entries.
+func parseDebugfsLs(out string) map[string][]rootfsEntry {
+	listings := map[string][]rootfsEntry{}
+	dir := ""
+	for _, line := range strings.Split(out, "\n") {
+		if cmd, ok := strings.CutPrefix(line, "debugfs: ls -p "); ok {
+			dir = unquoteDebugfs(cmd)
+			continue
+		}
+		if dir == "" || !strings.HasPrefix(line, "/") {
+			continue
+		}
+
+		fields := strings.Split(line, "/")
+		if len(fields) != 8 {
+			continue
+		}
+		name := fields[5]
+		if name == "." || name == ".." {
+			continue
+		}
+		mode, err := strconv.ParseUint(fields[2], 8, 32)
+		if err != nil {
+			continue
+		}
+		uid, err := strconv.Atoi(fields[3])
+		if err != nil {
+			continue
+		}
+		gid, err := strconv.Atoi(fields[4])
+		if err != nil {
+			continue
+		}
+		listings[dir] = append(listings[dir], rootfsEntry{Path: path.Join(dir, name), Mode: uint32(mode), UID: uid, GID: gid})
+	}
+	return listings
+}
+
+// runDebugfs runs the commands on the ext4 image and returns the output. debugfs
+// doesn't fail on command errors, the errors are taken from the output ignoring the
+// expected ones (existing directories on mkdir and missing files on rm).
+func runDebugfs(ctx context.Context, image string, write bool, cmds []string) (string, error) {
+	args := []string{"-f", "-"}
+	if write {
+		args = append([]string{"-w"}, args...)
+	}
+	cmd := exec.CommandContext(ctx, "debugfs", append(args, image)...)
+	cmd.Stdin = strings.NewReader(strings.Join(cmds, "\n") + "\n")
+	outB, err := cmd.CombinedOutput()
+	out := string(outB)
+	if err != nil {
+		return "", fmt.Errorf("debugfs failed: %w: %s", err, out)
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case line == "",
+			strings.HasPrefix(line, "debugfs "),
+			strings.HasPrefix(line, "debugfs: "),
+			strings.HasPrefix(line, "/"),
+			strings.HasPrefix(line, "Allocated inode:"),
+			strings.Contains(line, "already exists"),
+			strings.Contains(line, "File not found by ext2_lookup"):
+		default:
+			return "", fmt.Errorf("debugfs failed: %s", line)
+		}
+	}
+
+	return out, nil
+}
+
+func quoteDebugfs(s string) string { return strconv.Quote(s) }
+
+func unquoteDebugfs(s string) string {
+	if u, err := strconv.Unquote(s); err == nil {
+		return u
+	}
+	return s
+}
@@ -0,0 +1,100 @@
+package firecracker
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/log"
+)
+
+func TestParseDebugfsLs(t *testing.T) {
+	out := `debugfs 1.47.0 (5-Feb-2023)
+debugfs: ls -p "/root"
+/2/040700/0/0/./1024/
+/11/040755/0/0/../1024/
+/12/0100600/1000/1000/a.txt/6/
+/13/040755/0/0/dir/1024/
+/14/0120777/0/0/link/5/
+debugfs: ls -p "/root/dir"
+/13/040755/0/0/./1024/
+/2/040700/0/0/../1024/
+debugfs: ls -p "/missing"
+File not found by ext2_lookup
+`
+
+	got := parseDebugfsLs(out)
+
+	assert.Equal(t, map[string][]rootfsEntry{
+		"/root": {
+			{Path: "/root/a.txt", Mode: 0o100600, UID: 1000, GID: 1000},
+			{Path: "/root/dir", Mode: 0o040755},
+			{Path: "/root/link", Mode: 0o120777},
+		},
+	}, got)
+}
+
+func TestEngine_copyRootFSPath(t *testing.T) {
+	for _, bin := range []string{"mkfs.ext4", "debugfs"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skipf("%s not found", bin)
+		}
+	}
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+
+	// The sandbox rootfs has the user files, the new base rootfs has its own /root.
+	srcTree := filepath.Join(tmpDir, "src")
+	require.NoError(os.MkdirAll(filepath.Join(srcTree, "root", "project"), 0o755))
+	require.NoError(os.WriteFile(filepath.Join(srcTree, "root", "project", "main.go"), []byte("package main\n"), 0o640))
+	require.NoError(os.WriteFile(filepath.Join(srcTree, "root", ".profile"), []byte("user\n"), 0o600))
+	require.NoError(os.Symlink("project/main.go", filepath.Join(srcTree, "root", "main.go")))
+	dstTree := filepath.Join(tmpDir, "dst")
+	require.NoError(os.MkdirAll(filepath.Join(dstTree, "root"), 0o700))
+	require.NoError(os.WriteFile(filepath.Join(dstTree, "root", ".profile"), []byte("base\n"), 0o644))
+	require.NoError(os.WriteFile(filepath.Join(dstTree, "root", ".bashrc"), []byte("base\n"), 0o644))
+
+	srcImage := filepath.Join(tmpDir, "src.ext4")
+	dstImage := filepath.Join(tmpDir, "dst.ext4")
+	for img, tree := range map[string]string{srcImage: srcTree, dstImage: dstTree} {
+		out, err := exec.Command("mkfs.ext4", "-q", "-F", "-d", tree, img, "8M").CombinedOutput()
+		require.NoError(err, string(out))
+	}
+
+	e := &Engine{logger: log.Noop}
+	require.NoError(e.copyRootFSPath(ctx, srcImage, dstImage, "/root", tmpDir))
+	require.NoError(e.copyRootFSPath(ctx, srcImage, dstImage, "/home/missing", tmpDir))
+
+	entries, err := listRootFSTree(ctx, dstImage, "/root")
+	require.NoError(err)
+	modes := map[string]uint32{}
+	for _, en := range entries {
+		modes[en.Path] = en.Mode
+	}
+	assert.Equal(map[string]uint32{
+		"/root":                 0o040755,
+		"/root/.profile":        0o100600,
+		"/root/.bashrc":         0o100644,
+		"/root/main.go":         0o120777,
+		"/root/project":         0o040755,
+		"/root/project/main.go": 0o100640,
+	}, modes)
+
+	dumpDir := filepath.Join(tmpDir, "dump")
+	require.NoError(os.MkdirAll(dumpDir, 0o755))
+	_, err = runDebugfs(ctx, dstImage, false, []string{`rdump "/root" "` + dumpDir + `"`})
+	require.NoError(err)
+	profile, err := os.ReadFile(filepath.Join(dumpDir, "root", ".profile"))
+	require.NoError(err)
+	assert.Equal("user\n", string(profile))
+	target, err := os.Readlink(filepath.Join(dumpDir, "root", "main.go"))
+	require.NoError(err)
+	assert.Equal("project/main.go", target)
+}
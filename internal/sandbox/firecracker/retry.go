@@ -0,0 +1,69 @@
+package firecracker
+
+import (
+	"context"
+	"time"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// defaultRetryPolicies are the retry policies of the transient engine failures
+// used for the unset values of the engine config.
+var defaultRetryPolicies = model.RetryPolicies{
+	// The guest is polled at a short fixed interval so the start continues as soon
+	// as it's ready, the boot timeout bounds it.
+	SSHDial: model.RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     100 * time.Millisecond,
+		Multiplier:     1,
+	},
+	// The API socket usually appears in a few milliseconds, the socket timeout
+	// bounds it.
+	FirecrackerAPI: model.RetryPolicy{
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     250 * time.Millisecond,
+		Multiplier:     2,
+		Jitter:         0.1,
+	},
+	Nftables: model.RetryPolicy{
+		Attempts:       5,
+		InitialBackoff: 50 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	},
+}
+
+// retry runs fn until it succeeds, it fails with an error that is not retryable or
+// the policy attempts or the context run out, waiting the policy backoff between
+// the attempts. A nil retryable retries all the errors.
+//
+// The errors after more than one attempt are returned as a model.RetryError with
+// the attempts made.
+func (e *Engine) retry(ctx context.Context, operation string, policy model.RetryPolicy, retryable func(error) bool, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			if attempt > 1 {
+				e.logger.Debugf("%s succeeded after %d attempts", operation, attempt)
+			}
+			return nil
+		}
+
+		if retryable != nil && !retryable(err) {
+			if attempt == 1 {
+				return err
+			}
+			return &model.RetryError{Operation: operation, Attempts: attempt, Err: err}
+		}
+		if policy.Attempts > 0 && attempt >= policy.Attempts {
+			return &model.RetryError{Operation: operation, Attempts: attempt, Err: err}
+		}
+
+		select {
+		case <-ctx.Done():
+			return &model.RetryError{Operation: operation, Attempts: attempt, Err: err}
+		case <-time.After(policy.JitteredBackoff(attempt)):
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package firecracker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestEngineRetry(t *testing.T) {
+	errTransient := errors.New("transient")
+	errPermanent := errors.New("permanent")
+	policy := model.RetryPolicy{Attempts: 3, InitialBackoff: time.Millisecond, Multiplier: 1}
+
+	tests := map[string]struct {
+		errs        []error
+		policy      model.RetryPolicy
+		expAttempts int
+		expErr      error
+		expRetryErr bool
+	}{
+		"A first attempt success should not retry.": {
+			errs:        []error{nil},
+			policy:      policy,
+			expAttempts: 1,
+		},
+
+		"A transient failure should be retried until it succeeds.": {
+			errs:        []error{errTransient, errTransient, nil},
+			policy:      policy,
+			expAttempts: 3,
+		},
+
+		"A transient failure should give up after the policy attempts.": {
+			errs:        []error{errTransient, errTransient, errTransient, nil},
+			policy:      policy,
+			expAttempts: 3,
+			expErr:      errTransient,
+			expRetryErr: true,
+		},
+
+		"A not retryable failure on the first attempt should be returned as is.": {
+			errs:        []error{errPermanent},
+			policy:      policy,
+			expAttempts: 1,
+			expErr:      errPermanent,
+		},
+
+		"A not retryable failure after a retry should return the attempts.": {
+			errs:        []error{errTransient, errPermanent},
+			policy:      policy,
+			expAttempts: 2,
+			expErr:      errPermanent,
+			expRetryErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			e, err := NewEngine(EngineConfig{DataDir: t.TempDir(), Logger: log.Noop})
+			require.NoError(t, err)
+
+			attempts := 0
+			err = e.retry(context.Background(), "test", test.policy, func(err error) bool { return errors.Is(err, errTransient) }, func() error {
+				err := test.errs[attempts]
+				attempts++
+				return err
+			})
+
+			assert.Equal(t, test.expAttempts, attempts)
+			if test.expErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, test.expErr)
+			var retryErr *model.RetryError
+			if test.expRetryErr {
+				require.ErrorAs(t, err, &retryErr)
+				assert.Equal(t, test.expAttempts, retryErr.Attempts)
+			} else {
+				assert.False(t, errors.As(err, &retryErr))
+			}
+		})
+	}
+}
+
+func TestEngineRetryContextDone(t *testing.T) {
+	e, err := NewEngine(EngineConfig{DataDir: t.TempDir(), Logger: log.Noop})
+	require.NoError(t, err)
+
+	// Without attempts the retries only stop with the context.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = e.retry(ctx, "test", model.RetryPolicy{InitialBackoff: 5 * time.Millisecond, Multiplier: 1}, nil, func() error {
+		return errors.New("transient")
+	})
+
+	var retryErr *model.RetryError
+	require.ErrorAs(t, err, &retryErr)
+	assert.Greater(t, retryErr.Attempts, 1)
+	assert.Contains(t, err.Error(), "test failed after")
+}
+
+func TestNewEngineRetryPolicies(t *testing.T) {
+	e, err := NewEngine(EngineConfig{
+		DataDir: t.TempDir(),
+		Retry:   model.RetryPolicies{Nftables: model.RetryPolicy{Attempts: 10}},
+		Logger:  log.Noop,
+	})
+	require.NoError(t, err)
+
+	exp := defaultRetryPolicies
+	exp.Nftables.Attempts = 10
+	assert.Equal(t, exp, e.retries)
+
+	_, err = NewEngine(EngineConfig{
+		DataDir: t.TempDir(),
+		Retry:   model.RetryPolicies{SSHDial: model.RetryPolicy{Jitter: 2}},
+		Logger:  log.Noop,
+	})
+	assert.ErrorIs(t, err, model.ErrNotValid)
+}
@@ -0,0 +1,36 @@
+package firecracker
+
+import (
+	"errors"
+	"fmt"
+)
+
+// rollback tracks the undo actions of the steps of an operation, so a failure half
+// way leaves the host as it was before the operation.
+type rollback struct {
+	steps []rollbackStep
+}
+
+type rollbackStep struct {
+	name string
+	undo func() error
+}
+
+// add registers the undo action of a step.
+func (r *rollback) add(name string, undo func() error) {
+	r.steps = append(r.steps, rollbackStep{name: name, undo: undo})
+}
+
+// run executes the undo actions in reverse order. All the actions are executed even
+// if some of them fail, the errors are joined.
+func (r *rollback) run() error {
+	var errs []error
+	for i := len(r.steps) - 1; i >= 0; i-- {
+		s := r.steps[i]
+		if err := s.undo(); err != nil {
+			errs = append(errs, fmt.Errorf("could not undo %s: %w", s.name, err))
+		}
+	}
+	r.steps = nil
+	return errors.Join(errs...)
+}
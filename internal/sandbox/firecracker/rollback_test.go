@@ -0,0 +1,65 @@
+package firecracker
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollback(t *testing.T) {
+	tests := map[string]struct {
+		failing  map[string]bool
+		expUndos []string
+		expErr   []string
+	}{
+		"All the steps should be undone in reverse order.": {
+			expUndos: []string{"spawn", "proxy", "network"},
+		},
+
+		"A failing undo should not stop the rollback and its error should be returned.": {
+			failing:  map[string]bool{"proxy": true},
+			expUndos: []string{"spawn", "proxy", "network"},
+			expErr:   []string{"could not undo proxy: proxy failed"},
+		},
+
+		"All the failing undo errors should be returned.": {
+			failing:  map[string]bool{"spawn": true, "network": true},
+			expUndos: []string{"spawn", "proxy", "network"},
+			expErr:   []string{"could not undo spawn: spawn failed", "could not undo network: network failed"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var gotUndos []string
+			var rb rollback
+			for _, step := range []string{"network", "proxy", "spawn"} {
+				rb.add(step, func() error {
+					gotUndos = append(gotUndos, step)
+					if test.failing[step] {
+						return fmt.Errorf("%s failed", step)
+					}
+					return nil
+				})
+			}
+
+			err := rb.run()
+			assert.Equal(test.expUndos, gotUndos)
+			if len(test.expErr) == 0 {
+				assert.NoError(err)
+			} else {
+				for _, msg := range test.expErr {
+					assert.ErrorContains(err, msg)
+				}
+			}
+
+			// A rollback only runs once.
+			gotUndos = nil
+			assert.NoError(rb.run())
+			assert.Empty(gotUndos)
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package firecracker
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -10,60 +9,73 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/slok/sbx/internal/conventions"
-	"github.com/slok/sbx/internal/ssh"
+	"github.com/slok/sbx/internal/model"
 	fileutil "github.com/slok/sbx/internal/utils/file"
 )
 
-// copyRootFS copies the base rootfs to the VM directory.
-func (e *Engine) copyRootFS(ctx context.Context, srcPath, vmDir string) error {
+// copyRootFS copies the base rootfs to the VM directory and returns the strategy
+// used. A copy-on-write clone is instant and shares the disk blocks, so it's tried
+// first, falling back to a sparse copy and then to a regular copy.
+func (e *Engine) copyRootFS(ctx context.Context, srcPath, vmDir string) (model.RootFSStrategy, error) {
 	dstPath := filepath.Join(vmDir, conventions.RootFSFile)
 
 	// Open source file
 	src, err := os.Open(srcPath)
 	if err != nil {
-		return fmt.Errorf("could not open source rootfs: %w", err)
+		return "", fmt.Errorf("could not open source rootfs: %w", err)
 	}
 	defer src.Close()
 
 	// Create destination file
 	dst, err := os.Create(dstPath)
 	if err != nil {
-		return fmt.Errorf("could not create destination rootfs: %w", err)
+		return "", fmt.Errorf("could not create destination rootfs: %w", err)
 	}
 	defer dst.Close()
 
+	cloneErr := fileutil.CloneFile(src, dst)
+	if cloneErr == nil {
+		e.logger.Debugf("Cloned rootfs from %s to %s", srcPath, dstPath)
+		return model.RootFSStrategyReflink, nil
+	}
+	if !errors.Is(cloneErr, fileutil.ErrCloneUnsupported) {
+		return "", fmt.Errorf("could not clone rootfs: %w", cloneErr)
+	}
+	e.logger.Debugf("Copy-on-write clone unsupported while copying rootfs (%v), copying it", cloneErr)
+
+	strategy := model.RootFSStrategySparse
 	copyErr := fileutil.CopyFileSparse(ctx, src, dst)
 	if copyErr != nil {
 		if errors.Is(copyErr, fileutil.ErrSparseUnsupported) {
 			if _, err := src.Seek(0, io.SeekStart); err != nil {
-				return fmt.Errorf("could not seek source file before fallback copy: %w", err)
+				return "", fmt.Errorf("could not seek source file before fallback copy: %w", err)
 			}
 			if err := dst.Truncate(0); err != nil {
-				return fmt.Errorf("could not truncate destination before fallback copy: %w", err)
+				return "", fmt.Errorf("could not truncate destination before fallback copy: %w", err)
 			}
 			if _, err := dst.Seek(0, io.SeekStart); err != nil {
-				return fmt.Errorf("could not seek destination file before fallback copy: %w", err)
+				return "", fmt.Errorf("could not seek destination file before fallback copy: %w", err)
 			}
 
 			e.logger.Debugf("Sparse copy unsupported by filesystem/kernel while copying rootfs, using regular copy fallback")
 			if _, err := io.Copy(dst, src); err != nil {
-				return fmt.Errorf("could not copy rootfs: %w", err)
+				return "", fmt.Errorf("could not copy rootfs: %w", err)
 			}
+			strategy = model.RootFSStrategyCopy
 		} else {
-			return fmt.Errorf("could not copy rootfs: %w", copyErr)
+			return "", fmt.Errorf("could not copy rootfs: %w", copyErr)
 		}
 	}
 
 	// Sync to disk
 	if err := dst.Sync(); err != nil {
-		return fmt.Errorf("could not sync rootfs: %w", err)
+		return "", fmt.Errorf("could not sync rootfs: %w", err)
 	}
 
-	e.logger.Debugf("Copied rootfs from %s to %s", srcPath, dstPath)
-	return nil
+	e.logger.Debugf("Copied rootfs from %s to %s (%s)", srcPath, dstPath, strategy)
+	return strategy, nil
 }
 
 // patchRootFSSSH patches the rootfs with the sandbox's SSH public key.
@@ -155,7 +167,7 @@ const MaxDiskGB = 25
 
 // resizeRootFS extends the rootfs file to the specified size in GB.
 // This uses sparse file extension (fast, doesn't allocate actual disk space until written).
-// The actual filesystem expansion happens inside the VM after boot via expandFilesystem.
+// The actual filesystem expansion happens inside the VM after boot via the guest setup (see guestSetupScript).
 func (e *Engine) resizeRootFS(vmDir string, sizeGB int, baseImagePath string) error {
 	// Validate maximum size
 	if sizeGB > MaxDiskGB {
@@ -201,62 +213,3 @@ func (e *Engine) resizeRootFS(vmDir string, sizeGB int, baseImagePath string) er
 	e.logger.Debugf("Resized rootfs to %d GB at %s", sizeGB, rootfsPath)
 	return nil
 }
-
-// expandFilesystem expands the ext4 filesystem inside the VM to fill the available space.
-// This must be called after the VM boots and network is configured (SSH access required).
-// Retries with exponential backoff to wait for SSH to be available after boot.
-func (e *Engine) expandFilesystem(ctx context.Context, sandboxID, vmIP string) error {
-	// Retry logic: VM needs time to boot and start SSH service.
-	maxRetries := 10
-	baseDelay := 500 * time.Millisecond
-
-	var lastErr error
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		if attempt > 0 {
-			// Exponential backoff: 500ms, 1s, 2s, 4s, 8s...
-			delay := baseDelay * time.Duration(1<<uint(attempt-1))
-			if delay > 10*time.Second {
-				delay = 10 * time.Second // Cap at 10s
-			}
-			e.logger.Debugf("Waiting %v before retry %d/%d", delay, attempt+1, maxRetries)
-
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-time.After(delay):
-			}
-		}
-
-		// Connect via Go SSH client with short timeout for retries.
-		client, err := e.newSSHClientWithTimeout(ctx, sandboxID, 5*time.Second)
-		if err != nil {
-			lastErr = fmt.Errorf("SSH not ready: %w", err)
-			e.logger.Debugf("SSH connection failed (attempt %d/%d): %v", attempt+1, maxRetries, err)
-			continue
-		}
-
-		var stdout bytes.Buffer
-		exitCode, err := client.Exec(ctx, "resize2fs /dev/vda", ssh.ExecOpts{
-			Stdout: &stdout,
-			Stderr: &stdout,
-		})
-		client.Close()
-
-		if err != nil {
-			lastErr = fmt.Errorf("SSH not ready: %w", err)
-			e.logger.Debugf("SSH exec failed (attempt %d/%d): %v", attempt+1, maxRetries, err)
-			continue
-		}
-
-		if exitCode != 0 {
-			return fmt.Errorf("resize2fs failed with exit code %d: %s", exitCode, stdout.String())
-		}
-
-		// Success!
-		e.logger.Debugf("Expanded filesystem inside VM: %s", strings.TrimSpace(stdout.String()))
-		return nil
-	}
-
-	// All retries exhausted.
-	return fmt.Errorf("failed to expand filesystem after %d attempts: %w", maxRetries, lastErr)
-}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/slok/sbx/internal/conventions"
 	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
 	fileutil "github.com/slok/sbx/internal/utils/file"
 )
 
@@ -33,7 +34,9 @@ func TestEngine_copyRootFSPreservesSparseAllocation(t *testing.T) {
 	require.NoError(os.MkdirAll(vmDir, 0755))
 
 	e := &Engine{logger: log.Noop}
-	require.NoError(e.copyRootFS(context.Background(), srcPath, vmDir))
+	strategy, err := e.copyRootFS(context.Background(), srcPath, vmDir)
+	require.NoError(err)
+	assert.Contains([]model.RootFSStrategy{model.RootFSStrategyReflink, model.RootFSStrategySparse, model.RootFSStrategyCopy}, strategy)
 
 	dstPath := filepath.Join(vmDir, conventions.RootFSFile)
 	virtualSize, allocatedSize, err := fileutil.SizeStats(dstPath)
@@ -0,0 +1,121 @@
+package firecracker
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// procDir is the procfs directory of the host.
+var procDir = "/proc"
+
+// seccompArgs returns the Firecracker command line arguments of the sandbox seccomp
+// options, the default filters built in Firecracker don't need any.
+func (e *Engine) seccompArgs(opts model.SeccompOptions) []string {
+	switch opts.Mode {
+	case model.SeccompModeDisabled:
+		return []string{"--no-seccomp"}
+	case model.SeccompModeCustom:
+		return []string{"--seccomp-filter", e.expandPath(opts.Filter)}
+	default:
+		return nil
+	}
+}
+
+// checkSeccompFilter checks the custom seccomp filter file of the sandbox exists,
+// Firecracker would fail to start without it.
+func (e *Engine) checkSeccompFilter(opts model.SeccompOptions) error {
+	if opts.Mode != model.SeccompModeCustom {
+		return nil
+	}
+	if _, err := os.Stat(e.expandPath(opts.Filter)); err != nil {
+		return fmt.Errorf("seccomp filter not found at %s: %w", opts.Filter, model.ErrNotValid)
+	}
+	return nil
+}
+
+// seccompStatus returns the seccomp enforcement of a process from its procfs status,
+// nil if it can't be read (e.g. the process is gone).
+func seccompStatus(procDir string, pid int) *model.SeccompStatus {
+	f, err := os.Open(filepath.Join(procDir, strconv.Itoa(pid), "status"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var status *model.SeccompStatus
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		key, value, ok := strings.Cut(sc.Text(), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Seccomp":
+			// 0 is disabled, 1 strict and 2 filter mode.
+			status = &model.SeccompStatus{Enforced: value != "0"}
+		case "Seccomp_filters":
+			if status != nil {
+				status.Filters, _ = strconv.Atoi(value)
+			}
+		}
+	}
+	return status
+}
+
+// seccompRemediation is the remediation of the kernels without seccomp filters.
+const seccompRemediation = "Use a kernel with CONFIG_SECCOMP_FILTER or create the sandboxes with --seccomp=disabled"
+
+// checkSeccomp checks the host kernel supports the seccomp filters Firecracker
+// installs on its threads by default.
+func (e *Engine) checkSeccomp() model.CheckResult {
+	data, err := os.ReadFile(filepath.Join(procDir, "self", "status"))
+	if err != nil {
+		return model.CheckResult{
+			ID:          "seccomp",
+			Message:     fmt.Sprintf("Cannot read the process seccomp status: %v", err),
+			Status:      model.CheckStatusWarning,
+			Remediation: "Check procfs is mounted on /proc",
+		}
+	}
+	if !strings.Contains(string(data), "\nSeccomp:") {
+		return model.CheckResult{
+			ID:          "seccomp",
+			Message:     "Kernel built without seccomp (CONFIG_SECCOMP), sandboxes must be created with --seccomp=disabled",
+			Status:      model.CheckStatusError,
+			Remediation: seccompRemediation,
+		}
+	}
+
+	actions, err := os.ReadFile(filepath.Join(procDir, "sys", "kernel", "seccomp", "actions_avail"))
+	if err != nil {
+		return model.CheckResult{
+			ID:          "seccomp",
+			Message:     "Kernel seccomp filters support unknown (no /proc/sys/kernel/seccomp/actions_avail), the VM process may fail to start",
+			Status:      model.CheckStatusWarning,
+			Remediation: seccompRemediation,
+		}
+	}
+	// The default Firecracker filters trap the denied syscalls.
+	if !slices.Contains(strings.Fields(string(actions)), "trap") {
+		return model.CheckResult{
+			ID:          "seccomp",
+			Message:     fmt.Sprintf("Kernel seccomp filters lack the trap action (available: %s), the VM process may fail to start", strings.TrimSpace(string(actions))),
+			Status:      model.CheckStatusWarning,
+			Remediation: seccompRemediation,
+		}
+	}
+
+	return model.CheckResult{
+		ID:      "seccomp",
+		Message: "Kernel supports seccomp filters",
+		Status:  model.CheckStatusOK,
+	}
+}
@@ -0,0 +1,70 @@
+package firecracker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestSeccompArgs(t *testing.T) {
+	tests := map[string]struct {
+		opts    model.SeccompOptions
+		expArgs []string
+	}{
+		"The default mode should not add arguments.": {},
+		"The disabled mode should disable the filters.": {
+			opts:    model.SeccompOptions{Mode: model.SeccompModeDisabled},
+			expArgs: []string{"--no-seccomp"},
+		},
+		"The custom mode should use the filter file.": {
+			opts:    model.SeccompOptions{Mode: model.SeccompModeCustom, Filter: "/etc/sbx/filter.bpf"},
+			expArgs: []string{"--seccomp-filter", "/etc/sbx/filter.bpf"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			e := &Engine{}
+			assert.Equal(t, test.expArgs, e.seccompArgs(test.opts))
+		})
+	}
+}
+
+func TestSeccompStatus(t *testing.T) {
+	tests := map[string]struct {
+		status    string
+		expStatus *model.SeccompStatus
+	}{
+		"A filtered process should be enforced.": {
+			status:    "Name:\tfirecracker\nSeccomp:\t2\nSeccomp_filters:\t1\n",
+			expStatus: &model.SeccompStatus{Enforced: true, Filters: 1},
+		},
+		"An unfiltered process should not be enforced.": {
+			status:    "Name:\tfirecracker\nSeccomp:\t0\nSeccomp_filters:\t0\n",
+			expStatus: &model.SeccompStatus{},
+		},
+		"A kernel without filters count should only report the enforcement.": {
+			status:    "Name:\tfirecracker\nSeccomp:\t2\n",
+			expStatus: &model.SeccompStatus{Enforced: true},
+		},
+		"A kernel without seccomp should not report it.": {
+			status: "Name:\tfirecracker\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			procDir := t.TempDir()
+			require.NoError(t, os.MkdirAll(filepath.Join(procDir, "42"), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(procDir, "42", "status"), []byte(test.status), 0o644))
+
+			assert.Equal(t, test.expStatus, seccompStatus(procDir, 42))
+			assert.Nil(t, seccompStatus(procDir, 43))
+		})
+	}
+}
@@ -0,0 +1,534 @@
+package firecracker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/slok/sbx/internal/conventions"
+	"github.com/slok/sbx/internal/events"
+	"github.com/slok/sbx/internal/hostlock"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+const (
+	// proxyReadyTimeout is the maximum time to wait for a proxy to listen.
+	proxyReadyTimeout = 10 * time.Second
+	// proxyPollInterval is the time between proxy readiness checks.
+	proxyPollInterval = 100 * time.Millisecond
+	// proxyMinRestartBackoff and proxyMaxRestartBackoff bound the wait between proxy
+	// restarts, it doubles on every consecutive failure.
+	proxyMinRestartBackoff = 500 * time.Millisecond
+	proxyMaxRestartBackoff = 30 * time.Second
+	// eventsCloseTimeout is the maximum time to send the pending events on exit.
+	eventsCloseTimeout = 10 * time.Second
+)
+
+// ProxySupervisorConfig is the configuration of the egress proxy supervisor of a
+// sandbox network interface.
+type ProxySupervisorConfig struct {
+	// VMDir is the sandbox VM directory, where the proxy state and port files are written.
+	VMDir string
+	// NICID is the network interface of the proxy, empty for the primary one.
+	NICID string
+	// TapDevice, Gateway and VMIP are the network interface host resources, used to
+	// redirect the VM traffic to the proxy.
+	TapDevice string
+	Gateway   string
+	VMIP      string
+	// HostLocksDir is the directory of the host resource locks of the data directory,
+	// taken to redirect the VM traffic (optional).
+	HostLocksDir string
+	// Egress is the egress policy enforced by the proxy.
+	Egress model.EgressPolicy
+	// EgressInterface is the host network interface the proxy connections go out
+	// through, empty uses the routing table.
+	EgressInterface string
+	// Ports are the ports the proxy listens on first.
+	Ports ProxyPorts
+	// EventSinks receive the proxy crash and egress denial events of the sandbox
+	// (optional), the sandbox is identified by its ID, name and namespace.
+	EventSinks  []model.EventSinkConfig
+	SandboxID   string
+	SandboxName string
+	Namespace   string
+	Logger      log.Logger
+}
+
+func (c *ProxySupervisorConfig) defaults() error {
+	if c.VMDir == "" {
+		return fmt.Errorf("VM directory is required")
+	}
+
+	if c.TapDevice == "" || c.Gateway == "" || c.VMIP == "" {
+		return fmt.Errorf("TAP device, gateway and VM IP are required")
+	}
+
+	if c.Ports.HTTPPort == 0 || c.Ports.TLSPort == 0 || c.Ports.DNSPort == 0 {
+		return fmt.Errorf("proxy ports are required")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+
+	return nil
+}
+
+// SuperviseProxy runs the egress proxy and restarts it if it crashes, until the
+// context is cancelled. The proxy health is written to the state file of the
+// network interface, it's degraded while the proxy is down.
+//
+// The restarted proxy listens on the same ports when possible, when they had to
+// change the VM traffic is redirected to the new ones only after the proxy is
+// listening. Meanwhile the redirect to the old ports blocks the filtered traffic,
+// it never bypasses the proxy.
+func SuperviseProxy(ctx context.Context, cfg ProxySupervisorConfig) error {
+	if err := cfg.defaults(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	sbxBinary, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("could not find sbx binary: %w", err)
+	}
+
+	emitter, err := events.NewEmitter(cfg.EventSinks, cfg.Logger)
+	if err != nil {
+		return fmt.Errorf("invalid event sinks: %w", err)
+	}
+	defer func() {
+		// Send the last crash events before exiting.
+		ctx, cancel := context.WithTimeout(context.Background(), eventsCloseTimeout)
+		defer cancel()
+		if err := emitter.Close(ctx); err != nil {
+			cfg.Logger.Warningf("Could not send events: %v", err)
+		}
+	}()
+
+	e := &Engine{logger: cfg.Logger}
+	if cfg.HostLocksDir != "" {
+		e.hostLocks = hostlock.NewManager(cfg.HostLocksDir, "")
+	}
+	s := &proxySupervisor{
+		vmDir:   cfg.VMDir,
+		nicID:   cfg.NICID,
+		ports:   cfg.Ports,
+		logger:  cfg.Logger,
+		events:  emitter,
+		sandbox: model.Sandbox{ID: cfg.SandboxID, Name: cfg.SandboxName, Namespace: cfg.Namespace},
+		start: func(ports ProxyPorts) (*proxyProcess, error) {
+			args := buildProxyArgs(cfg.Egress, ports.HTTPPort, ports.TLSPort, ports.DNSPort, cfg.Gateway,
+				filepath.Join(cfg.VMDir, proxyFile(conventions.DNSEventsFile, cfg.NICID)),
+				filepath.Join(cfg.VMDir, proxyFile(conventions.ProxySessionsFile, cfg.NICID)))
+			if cfg.EgressInterface != "" {
+				args = append(args, "--bind-interface", cfg.EgressInterface)
+			}
+			// The proxy adds the resolved addresses of the ICMP rules and NTP servers to
+			// the sets the VM can reach.
+			args = append(args, addressSetArgs(cfg.Egress, cfg.TapDevice)...)
+			return startProxyProcess(sbxBinary, append(args, buildEventArgs(cfg)...))
+		},
+		listening: func(ctx context.Context, ports ProxyPorts) error {
+			return waitProxyListening(ctx, cfg.Gateway, ports)
+		},
+		redirect: func(ports ProxyPorts) error {
+			return e.replaceProxyRedirect(cfg.TapDevice, cfg.Gateway, cfg.VMIP, ports)
+		},
+		freePorts:  allocateProxyPorts,
+		minBackoff: proxyMinRestartBackoff,
+		maxBackoff: proxyMaxRestartBackoff,
+	}
+
+	return s.run(ctx)
+}
+
+// buildSupervisorArgs constructs the command-line arguments for the proxy supervisor process.
+func buildSupervisorArgs(cfg ProxySupervisorConfig) []string {
+	args := []string{
+		"--logger", "json",
+		"internal-vm-proxy-supervisor",
+		"--vm-dir", cfg.VMDir,
+		"--nic-id", cfg.NICID,
+		"--tap-device", cfg.TapDevice,
+		"--gateway", cfg.Gateway,
+		"--vm-ip", cfg.VMIP,
+		"--port", strconv.Itoa(cfg.Ports.HTTPPort),
+		"--tls-port", strconv.Itoa(cfg.Ports.TLSPort),
+		"--dns-port", strconv.Itoa(cfg.Ports.DNSPort),
+		"--default-policy", string(cfg.Egress.Default),
+	}
+	if cfg.HostLocksDir != "" {
+		args = append(args, "--host-locks-dir", cfg.HostLocksDir)
+	}
+	if cfg.Egress.FailClosed {
+		args = append(args, "--fail-closed")
+	}
+	if cfg.EgressInterface != "" {
+		args = append(args, "--egress-interface", cfg.EgressInterface)
+	}
+	for _, u := range cfg.Egress.DNSUpstreams {
+		args = append(args, "--dns-upstream", u)
+	}
+
+	for _, r := range cfg.Egress.Rules {
+		args = append(args, "--rule", egressRuleArg(r))
+	}
+	for _, s := range cfg.Egress.AllowSystemServices {
+		args = append(args, "--allow-system-service", string(s))
+	}
+
+	return append(args, buildEventArgs(cfg)...)
+}
+
+// buildEventArgs constructs the sandbox arguments of the events emitted by the proxy
+// and its supervisor processes, none without event sinks. The sinks are passed in
+// the environment, see buildEventEnv.
+func buildEventArgs(cfg ProxySupervisorConfig) []string {
+	if len(cfg.EventSinks) == 0 {
+		return nil
+	}
+
+	return []string{
+		"--namespace", cfg.Namespace,
+		"--sandbox-id", cfg.SandboxID,
+		"--sandbox-name", cfg.SandboxName,
+	}
+}
+
+// buildEventEnv constructs the environment with the event sinks of the proxy
+// supervisor process, the proxy inherits it. The webhook secrets would be visible
+// to everyone in the process arguments.
+func buildEventEnv(cfg ProxySupervisorConfig) []string {
+	if len(cfg.EventSinks) == 0 {
+		return nil
+	}
+
+	return []string{events.SinksEnv + "=" + events.MarshalSinkConfigs(cfg.EventSinks)}
+}
+
+// proxyProcess is a running proxy process.
+type proxyProcess struct {
+	// done is closed when the process exits, err has the exit error then.
+	done chan struct{}
+	err  error
+	stop func()
+}
+
+// startProxyProcess starts the proxy process, it logs to the supervisor outputs.
+func startProxyProcess(sbxBinary string, args []string) (*proxyProcess, error) {
+	cmd := exec.Command(sbxBinary, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.SysProcAttr = proxySysProcAttr()
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("could not start proxy process: %w", err)
+	}
+
+	p := &proxyProcess{
+		done: make(chan struct{}),
+		stop: func() { _ = cmd.Process.Kill() },
+	}
+	go func() {
+		p.err = cmd.Wait()
+		close(p.done)
+	}()
+
+	return p, nil
+}
+
+// waitProxyListening waits until the proxy TCP listeners accept connections.
+func waitProxyListening(ctx context.Context, address string, ports ProxyPorts) error {
+	ctx, cancel := context.WithTimeout(ctx, proxyReadyTimeout)
+	defer cancel()
+
+	for _, port := range []int{ports.HTTPPort, ports.TLSPort, ports.DNSPort} {
+		addr := net.JoinHostPort(address, strconv.Itoa(port))
+		for {
+			conn, err := net.DialTimeout("tcp", addr, proxyPollInterval)
+			if err == nil {
+				conn.Close()
+				break
+			}
+
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("proxy not listening on %s: %w", addr, err)
+			case <-time.After(proxyPollInterval):
+			}
+		}
+	}
+
+	return nil
+}
+
+// proxySupervisor runs a proxy and restarts it when it exits.
+type proxySupervisor struct {
+	vmDir  string
+	nicID  string
+	ports  ProxyPorts
+	logger log.Logger
+	// events receives the proxy crashes of the sandbox.
+	events  *events.Emitter
+	sandbox model.Sandbox
+
+	start      func(ports ProxyPorts) (*proxyProcess, error)
+	listening  func(ctx context.Context, ports ProxyPorts) error
+	redirect   func(ports ProxyPorts) error
+	freePorts  func() (ProxyPorts, error)
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	state proxyState
+}
+
+func (s *proxySupervisor) run(ctx context.Context) error {
+	ports := s.ports
+	// The ports the VM traffic is redirected to, set by the sandbox start once the
+	// first proxy is listening.
+	var redirectPorts ProxyPorts
+	backoff := s.minBackoff
+
+	for {
+		p, err := s.start(ports)
+		if err != nil {
+			s.setDegraded(err)
+		} else if err := s.waitListening(ctx, p, ports); err != nil {
+			p.stop()
+			if ctx.Err() != nil {
+				return nil
+			}
+			s.setDegraded(err)
+
+			// The ports may have been taken by another process, the next proxy uses new ones.
+			newPorts, err := s.freePorts()
+			if err != nil {
+				s.logger.Warningf("Could not allocate new proxy ports: %v", err)
+			} else {
+				ports = newPorts
+			}
+		} else if err := s.ensureRedirect(&redirectPorts, ports); err != nil {
+			p.stop()
+			s.setDegraded(err)
+		} else {
+			s.setHealthy()
+			backoff = s.minBackoff
+
+			select {
+			case <-ctx.Done():
+				p.stop()
+				return nil
+			case <-p.done:
+			}
+
+			s.state.Restarts++
+			s.setDegraded(fmt.Errorf("proxy exited: %v", p.err))
+			s.emitCrashed(p.err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, s.maxBackoff)
+	}
+}
+
+// waitListening waits until the proxy is listening, failing if the proxy exits.
+func (s *proxySupervisor) waitListening(ctx context.Context, p *proxyProcess, ports ProxyPorts) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-p.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	err := s.listening(ctx, ports)
+	select {
+	case <-p.done:
+		return fmt.Errorf("proxy exited before listening: %v", p.err)
+	default:
+	}
+	return err
+}
+
+// ensureRedirect redirects the VM traffic to the listening proxy ports when they
+// changed, and writes them to the port file.
+func (s *proxySupervisor) ensureRedirect(redirectPorts *ProxyPorts, ports ProxyPorts) error {
+	if *redirectPorts != (ProxyPorts{}) && *redirectPorts != ports {
+		if err := s.redirect(ports); err != nil {
+			return fmt.Errorf("could not redirect traffic to the restarted proxy: %w", err)
+		}
+		s.logger.Infof("Redirected traffic to the restarted proxy (HTTP: %d, TLS: %d, DNS: %d)", ports.HTTPPort, ports.TLSPort, ports.DNSPort)
+	}
+	*redirectPorts = ports
+
+	if err := writeProxyPorts(s.vmDir, s.nicID, ports); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *proxySupervisor) setHealthy() {
+	if s.state.Restarts > 0 {
+		s.logger.Infof("Proxy restarted (restarts: %d)", s.state.Restarts)
+	}
+	s.state.Health = model.EgressHealthHealthy
+	s.writeState()
+}
+
+func (s *proxySupervisor) setDegraded(err error) {
+	s.logger.Errorf("Proxy degraded: %v", err)
+	s.state.Health = model.EgressHealthDegraded
+	s.state.LastError = err.Error()
+	s.writeState()
+}
+
+// emitCrashed emits the crash event of a proxy that exited.
+func (s *proxySupervisor) emitCrashed(err error) {
+	attrs := map[string]string{"restarts": strconv.Itoa(s.state.Restarts)}
+	if err != nil {
+		attrs["error"] = err.Error()
+	}
+	if s.nicID != "" {
+		attrs["nic"] = s.nicID
+	}
+	s.events.Emit(model.NewSandboxEvent(model.EventTypeProxyCrashed, s.sandbox, attrs))
+}
+
+func (s *proxySupervisor) writeState() {
+	s.state.UpdatedAt = time.Now().UTC()
+	if err := writeProxyState(s.vmDir, s.nicID, s.state); err != nil {
+		s.logger.Warningf("Could not write proxy state: %v", err)
+	}
+}
+
+// proxyState is the proxy health written by its supervisor.
+type proxyState struct {
+	Health    model.EgressHealth `json:"health"`
+	Restarts  int                `json:"restarts"`
+	LastError string             `json:"last_error,omitempty"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+// writeProxyState writes the proxy state file of a network interface.
+func writeProxyState(vmDir, nicID string, state proxyState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("could not marshal proxy state: %w", err)
+	}
+	return writeFileAtomic(filepath.Join(vmDir, proxyFile(conventions.ProxyStateFile, nicID)), data)
+}
+
+// readProxyState reads the proxy state file of a network interface.
+func readProxyState(vmDir, nicID string) (proxyState, error) {
+	data, err := os.ReadFile(filepath.Join(vmDir, proxyFile(conventions.ProxyStateFile, nicID)))
+	if err != nil {
+		return proxyState{}, fmt.Errorf("could not read proxy state file: %w", err)
+	}
+
+	var state proxyState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return proxyState{}, fmt.Errorf("could not parse proxy state file: %w", err)
+	}
+
+	return state, nil
+}
+
+// writeProxyPorts writes the proxy port file of a network interface.
+func writeProxyPorts(vmDir, nicID string, ports ProxyPorts) error {
+	data, err := json.Marshal(ports)
+	if err != nil {
+		return fmt.Errorf("could not marshal proxy ports: %w", err)
+	}
+	return writeFileAtomic(filepath.Join(vmDir, proxyFile(conventions.ProxyPortFile, nicID)), data)
+}
+
+// writeFileAtomic writes the file with a rename, so the readers never see it partially written.
+func writeFileAtomic(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("could not write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("could not write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// egressStatus returns the status of the sandbox egress proxies from their PID and
+// state files, nil when the sandbox has no egress proxies. A proxy whose supervisor
+// is not running is degraded, nothing would restart it.
+func egressStatus(vmDir string) *model.EgressStatus {
+	nicPIDPaths, _ := filepath.Glob(filepath.Join(vmDir, proxyFile(conventions.ProxyPIDFile, "*")))
+	pidPaths := append([]string{filepath.Join(vmDir, conventions.ProxyPIDFile)}, nicPIDPaths...)
+
+	var status *model.EgressStatus
+	prefix := strings.TrimSuffix(conventions.ProxyPIDFile, filepath.Ext(conventions.ProxyPIDFile)) + "-"
+	for i, pidPath := range pidPaths {
+		pidData, err := os.ReadFile(pidPath)
+		if err != nil {
+			continue
+		}
+		if status == nil {
+			status = &model.EgressStatus{Health: model.EgressHealthHealthy}
+		}
+
+		nicID := ""
+		if i > 0 {
+			nicID = strings.TrimSuffix(strings.TrimPrefix(filepath.Base(pidPath), prefix), filepath.Ext(pidPath))
+		}
+
+		state, err := readProxyState(vmDir, nicID)
+		if err != nil {
+			state = proxyState{Health: model.EgressHealthDegraded, LastError: err.Error()}
+		}
+		if !processAlive(strings.TrimSpace(string(pidData))) {
+			state.Health = model.EgressHealthDegraded
+			state.LastError = "proxy supervisor is not running"
+		}
+
+		status.Restarts += state.Restarts
+		if state.UpdatedAt.After(status.UpdatedAt) {
+			status.UpdatedAt = state.UpdatedAt
+		}
+		if state.Health != model.EgressHealthHealthy {
+			status.Health = model.EgressHealthDegraded
+			if nicID != "" {
+				status.LastError = nicID + ": " + state.LastError
+			} else {
+				status.LastError = state.LastError
+			}
+		}
+	}
+
+	return status
+}
+
+// processAlive returns true if the process of the PID is running.
+func processAlive(pidStr string) bool {
+	pid, err := strconv.Atoi(pidStr)
+	if err != nil {
+		return false
+	}
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return proc.Signal(syscall.Signal(0)) == nil
+}
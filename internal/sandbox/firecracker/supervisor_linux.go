@@ -0,0 +1,13 @@
+//go:build linux
+
+package firecracker
+
+import (
+	"syscall"
+)
+
+// proxySysProcAttr returns the process attributes of the supervised proxy, the
+// proxy is killed when its supervisor dies so it's never left unsupervised.
+func proxySysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Pdeathsig: syscall.SIGKILL}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package firecracker
+
+import (
+	"syscall"
+)
+
+// proxySysProcAttr returns the process attributes of the supervised proxy.
+func proxySysProcAttr() *syscall.SysProcAttr { return nil }
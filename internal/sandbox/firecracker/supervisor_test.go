@@ -0,0 +1,285 @@
+package firecracker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/conventions"
+	"github.com/slok/sbx/internal/events"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestBuildSupervisorArgs(t *testing.T) {
+	args := buildSupervisorArgs(ProxySupervisorConfig{
+		VMDir:        "/vms/sb1",
+		NICID:        "eth1",
+		TapDevice:    "sbx-tap1",
+		Gateway:      "10.1.0.1",
+		VMIP:         "10.1.0.2",
+		HostLocksDir: "/data/host-locks",
+		Egress: model.EgressPolicy{
+			Default:      model.EgressActionDeny,
+			Rules:        []model.EgressRule{{Domain: "github.com", Action: model.EgressActionAllow}},
+			DNSUpstreams: []string{"10.0.0.53", "tls://dns.example.com"},
+		},
+		EgressInterface: "wg0",
+		Ports:           ProxyPorts{HTTPPort: 8080, TLSPort: 8443, DNSPort: 5353},
+	})
+
+	assert.Equal(t, []string{
+		"--logger", "json",
+		"internal-vm-proxy-supervisor",
+		"--vm-dir", "/vms/sb1",
+		"--nic-id", "eth1",
+		"--tap-device", "sbx-tap1",
+		"--gateway", "10.1.0.1",
+		"--vm-ip", "10.1.0.2",
+		"--port", "8080",
+		"--tls-port", "8443",
+		"--dns-port", "5353",
+		"--default-policy", "deny",
+		"--host-locks-dir", "/data/host-locks",
+		"--egress-interface", "wg0",
+		"--dns-upstream", "10.0.0.53",
+		"--dns-upstream", "tls://dns.example.com",
+		"--rule", `{"action":"allow","domain":"github.com"}`,
+	}, args)
+}
+
+func TestBuildEventArgs(t *testing.T) {
+	tests := map[string]struct {
+		cfg     ProxySupervisorConfig
+		expArgs []string
+		expEnv  []string
+	}{
+		"Without event sinks there should be no event arguments.": {
+			cfg: ProxySupervisorConfig{SandboxID: "01SB", SandboxName: "sb", Namespace: "team-a"},
+		},
+
+		"With event sinks the sandbox arguments and the sinks environment should be set.": {
+			cfg: ProxySupervisorConfig{
+				SandboxID:   "01SB",
+				SandboxName: "sb",
+				Namespace:   "team-a",
+				EventSinks: []model.EventSinkConfig{
+					{Type: model.EventSinkTypeFile, Path: "/var/log/sbx/events.jsonl"},
+					{Type: model.EventSinkTypeJournald},
+				},
+			},
+			expArgs: []string{
+				"--namespace", "team-a",
+				"--sandbox-id", "01SB",
+				"--sandbox-name", "sb",
+			},
+			expEnv: []string{
+				`SBX_EVENT_SINKS=[{"type":"file","path":"/var/log/sbx/events.jsonl"},{"type":"journald"}]`,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expArgs, buildEventArgs(test.cfg))
+			assert.Equal(t, test.expEnv, buildEventEnv(test.cfg))
+		})
+	}
+}
+
+// testProxyAttempt is the behavior of a proxy started by the supervisor.
+type testProxyAttempt struct {
+	startErr  error
+	listenErr error
+}
+
+func TestProxySupervisor(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	vmDir := t.TempDir()
+	eventsPath := filepath.Join(t.TempDir(), "events.jsonl")
+	emitter, err := events.NewEmitter([]model.EventSinkConfig{{Type: model.EventSinkTypeFile, Path: eventsPath}}, log.Noop)
+	require.NoError(err)
+	initialPorts := ProxyPorts{HTTPPort: 1001, TLSPort: 1002, DNSPort: 1003}
+	newPorts := ProxyPorts{HTTPPort: 2001, TLSPort: 2002, DNSPort: 2003}
+
+	attempts := []testProxyAttempt{
+		{startErr: fmt.Errorf("could not exec")}, // Retried with the same ports.
+		{},                                       // Healthy, then crashes.
+		{listenErr: fmt.Errorf("port in use")},   // Retried with new ports.
+		{},                                       // Healthy on the new ports.
+	}
+
+	var mu sync.Mutex
+	var startedPorts []ProxyPorts
+	var redirects []ProxyPorts
+	var procs []*proxyProcess
+	attempt := 0
+
+	s := &proxySupervisor{
+		vmDir:   vmDir,
+		nicID:   "eth1",
+		ports:   initialPorts,
+		logger:  log.Noop,
+		events:  emitter,
+		sandbox: model.Sandbox{ID: "01SB", Name: "sb", Namespace: "team-a"},
+		start: func(ports ProxyPorts) (*proxyProcess, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			a := attempts[min(attempt, len(attempts)-1)]
+			attempt++
+			startedPorts = append(startedPorts, ports)
+			if a.startErr != nil {
+				return nil, a.startErr
+			}
+			p := &proxyProcess{done: make(chan struct{})}
+			var once sync.Once
+			p.stop = func() { once.Do(func() { close(p.done) }) }
+			procs = append(procs, p)
+			return p, nil
+		},
+		listening: func(ctx context.Context, ports ProxyPorts) error {
+			mu.Lock()
+			defer mu.Unlock()
+			return attempts[attempt-1].listenErr
+		},
+		redirect: func(ports ProxyPorts) error {
+			mu.Lock()
+			defer mu.Unlock()
+			redirects = append(redirects, ports)
+			return nil
+		},
+		freePorts:  func() (ProxyPorts, error) { return newPorts, nil },
+		minBackoff: time.Millisecond,
+		maxBackoff: time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runErr := make(chan error, 1)
+	go func() { runErr <- s.run(ctx) }()
+
+	waitState := func(restarts int) proxyState {
+		var state proxyState
+		require.Eventually(func() bool {
+			var err error
+			state, err = readProxyState(vmDir, "eth1")
+			return err == nil && state.Health == model.EgressHealthHealthy && state.Restarts == restarts
+		}, 5*time.Second, time.Millisecond)
+		return state
+	}
+
+	// The first healthy proxy uses the initial ports, the VM traffic is already redirected to them.
+	waitState(0)
+	gotPorts, err := readProxyPorts(vmDir, "eth1")
+	require.NoError(err)
+	assert.Equal(initialPorts, gotPorts)
+
+	// Crash the proxy.
+	mu.Lock()
+	procs[0].err = fmt.Errorf("signal: killed")
+	procs[0].stop()
+	mu.Unlock()
+
+	// The restarted proxy uses new ports and the traffic is redirected to them.
+	state := waitState(1)
+	assert.Equal("port in use", state.LastError)
+	gotPorts, err = readProxyPorts(vmDir, "eth1")
+	require.NoError(err)
+	assert.Equal(newPorts, gotPorts)
+
+	// The crash should be emitted.
+	require.NoError(emitter.Close(context.TODO()))
+	data, err := os.ReadFile(eventsPath)
+	require.NoError(err)
+	ev, err := events.UnmarshalEvent(bytes.TrimSpace(data))
+	require.NoError(err)
+	assert.Equal(model.EventTypeProxyCrashed, ev.Type)
+	assert.Equal("01SB", ev.SandboxID)
+	assert.Equal("team-a", ev.Namespace)
+	assert.Equal(map[string]string{"error": "signal: killed", "restarts": "1", "nic": "eth1"}, ev.Attributes)
+
+	cancel()
+	require.NoError(<-runErr)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal([]ProxyPorts{initialPorts, initialPorts, initialPorts, newPorts}, startedPorts)
+	assert.Equal([]ProxyPorts{newPorts}, redirects)
+	for _, p := range procs {
+		select {
+		case <-p.done:
+		default:
+			assert.Fail("proxy process not stopped")
+		}
+	}
+}
+
+func TestEgressStatus(t *testing.T) {
+	updatedAt := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	livePID := strconv.Itoa(os.Getpid())
+	deadPID := "999999999"
+
+	tests := map[string]struct {
+		pids      map[string]string // nicID -> PID.
+		states    map[string]proxyState
+		expStatus *model.EgressStatus
+	}{
+		"A sandbox without egress proxies should not have egress status.": {
+			expStatus: nil,
+		},
+
+		"Healthy proxies should be healthy with the sum of their restarts.": {
+			pids: map[string]string{"": livePID, "eth1": livePID},
+			states: map[string]proxyState{
+				"":     {Health: model.EgressHealthHealthy, Restarts: 1, UpdatedAt: updatedAt},
+				"eth1": {Health: model.EgressHealthHealthy, Restarts: 2, UpdatedAt: updatedAt.Add(time.Minute)},
+			},
+			expStatus: &model.EgressStatus{Health: model.EgressHealthHealthy, Restarts: 3, UpdatedAt: updatedAt.Add(time.Minute)},
+		},
+
+		"A degraded proxy should degrade the egress.": {
+			pids: map[string]string{"": livePID, "eth1": livePID},
+			states: map[string]proxyState{
+				"":     {Health: model.EgressHealthHealthy, UpdatedAt: updatedAt},
+				"eth1": {Health: model.EgressHealthDegraded, Restarts: 1, LastError: "proxy exited", UpdatedAt: updatedAt},
+			},
+			expStatus: &model.EgressStatus{Health: model.EgressHealthDegraded, Restarts: 1, LastError: "eth1: proxy exited", UpdatedAt: updatedAt},
+		},
+
+		"A proxy without a running supervisor should be degraded.": {
+			pids: map[string]string{"": deadPID},
+			states: map[string]proxyState{
+				"": {Health: model.EgressHealthHealthy, UpdatedAt: updatedAt},
+			},
+			expStatus: &model.EgressStatus{Health: model.EgressHealthDegraded, LastError: "proxy supervisor is not running", UpdatedAt: updatedAt},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			vmDir := t.TempDir()
+			for nicID, pid := range test.pids {
+				err := os.WriteFile(filepath.Join(vmDir, proxyFile(conventions.ProxyPIDFile, nicID)), []byte(pid), 0644)
+				require.NoError(err)
+			}
+			for nicID, state := range test.states {
+				require.NoError(writeProxyState(vmDir, nicID, state))
+			}
+
+			assert.Equal(t, test.expStatus, egressStatus(vmDir))
+		})
+	}
+}
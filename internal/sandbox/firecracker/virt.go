@@ -0,0 +1,282 @@
+package firecracker
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// sysDir and devDir are the sysfs and devices directories of the host.
+var (
+	sysDir = "/sys"
+	devDir = "/dev"
+)
+
+// Support levels of the host environments, see the doctor support matrix.
+const (
+	supportFull    = "supported"
+	supportCaveats = "supported with caveats"
+	supportNone    = "unsupported"
+)
+
+// armTestedCPUs are the arm64 CPU parts (implementer:part) Firecracker is tested on.
+var armTestedCPUs = map[string]string{
+	"0x41:0xd0c": "Neoverse N1 (AWS Graviton2)",
+	"0x41:0xd40": "Neoverse V1 (AWS Graviton3)",
+	"0x41:0xd4f": "Neoverse V2 (AWS Graviton4)",
+}
+
+// dmiHypervisors maps the DMI system vendors of the virtual machines to their
+// hypervisor, used to detect nested virtualization when the CPU doesn't report it.
+var dmiHypervisors = map[string]string{
+	"QEMU":                  "QEMU/KVM",
+	"Google":                "Google Compute Engine",
+	"Microsoft Corporation": "Hyper-V",
+	"VMware, Inc.":          "VMware",
+	"innotek GmbH":          "VirtualBox",
+	"Parallels":             "Parallels",
+	"Xen":                   "Xen",
+	"Amazon EC2":            "AWS Nitro",
+	"Apple Inc.":            "Apple Virtualization",
+}
+
+// nestedVirtHints are the remediations to enable nested virtualization per hypervisor.
+var nestedVirtHints = map[string]string{
+	"Google Compute Engine": "Create the instance with --enable-nested-virtualization",
+	"Hyper-V":               "Use a VM size with nested virtualization (e.g. Azure Dv3/Ev3 or newer)",
+	"AWS Nitro":             "Use a *.metal instance or an instance type with nested virtualization",
+	"QEMU/KVM":              "Enable nested on the host kvm_intel/kvm_amd module and use -cpu host",
+	"VMware":                "Enable 'Expose hardware assisted virtualization to the guest OS'",
+	"Apple Virtualization":  "Use an Apple M3 or newer with macOS 15",
+}
+
+// hostVirt is the virtualization environment of the host.
+type hostVirt struct {
+	// Vendor is the x86 CPU vendor or the arm64 CPU implementer.
+	Vendor string
+	// Model is the x86 CPU model name or the arm64 CPU part.
+	Model string
+	// Flags are the x86 CPU flags or the arm64 CPU features.
+	Flags []string
+	// Hypervisor is the hypervisor the host runs on, empty on bare metal.
+	Hypervisor string
+}
+
+// readHostVirt reads the virtualization environment of the host from the first
+// processor of the procfs cpuinfo and the sysfs DMI.
+func readHostVirt(procDir, sysDir string) (*hostVirt, error) {
+	f, err := os.Open(filepath.Join(procDir, "cpuinfo"))
+	if err != nil {
+		return nil, fmt.Errorf("could not read cpuinfo: %w", err)
+	}
+	defer f.Close()
+
+	hv := &hostVirt{}
+	var implementer, part string
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.TrimSpace(line) == "" && (hv.Vendor != "" || implementer != "") {
+			break
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "vendor_id":
+			hv.Vendor = value
+		case "model name":
+			hv.Model = value
+		case "flags", "Features":
+			hv.Flags = strings.Fields(value)
+		case "CPU implementer":
+			implementer = value
+		case "CPU part":
+			part = value
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("could not read cpuinfo: %w", err)
+	}
+	if implementer != "" {
+		hv.Vendor = implementer
+		hv.Model = part
+	}
+
+	dmi := func(name string) string {
+		data, _ := os.ReadFile(filepath.Join(sysDir, "class", "dmi", "id", name))
+		return strings.TrimSpace(string(data))
+	}
+	vendor, product := dmi("sys_vendor"), dmi("product_name")
+	hypervisor := dmiHypervisors[vendor]
+	switch {
+	// Bare metal cloud instances have the cloud vendor too.
+	case strings.HasSuffix(product, ".metal"):
+	case slices.Contains(hv.Flags, "hypervisor"):
+		hv.Hypervisor = hypervisor
+		if hv.Hypervisor == "" {
+			hv.Hypervisor = "unknown"
+		}
+	case hypervisor != "" && vendor != "Amazon EC2":
+		// arm64 CPUs don't report the hypervisor, Amazon EC2 is the vendor of the
+		// bare metal instances too.
+		hv.Hypervisor = hypervisor
+	}
+
+	return hv, nil
+}
+
+// checkNestedVirt checks if the host is a virtual machine and KVM is available in
+// it (nested virtualization), reporting the support level of the environment.
+func checkNestedVirt(hv *hostVirt, kvmOK bool) model.CheckResult {
+	switch {
+	case hv.Hypervisor == "" && kvmOK:
+		return model.CheckResult{
+			ID:      "nested_virt",
+			Message: fmt.Sprintf("Bare metal host (%s)", supportFull),
+			Status:  model.CheckStatusOK,
+		}
+	case hv.Hypervisor == "":
+		return model.CheckResult{
+			ID:          "nested_virt",
+			Message:     fmt.Sprintf("Bare metal host without KVM (%s until KVM is available)", supportNone),
+			Status:      model.CheckStatusError,
+			Remediation: "Enable virtualization (VT-x/AMD-V) in the BIOS and load the kvm module",
+		}
+	case kvmOK:
+		return model.CheckResult{
+			ID:          "nested_virt",
+			Message:     fmt.Sprintf("Running in a VM (hypervisor: %s) with nested virtualization (%s): expect slower boots, exec and I/O than on bare metal, and snapshots restoring only on hosts with the same CPU", hv.Hypervisor, supportCaveats),
+			Status:      model.CheckStatusWarning,
+			Remediation: "Use a bare metal host for the performance sensitive workloads",
+		}
+	default:
+		remediation, ok := nestedVirtHints[hv.Hypervisor]
+		if !ok {
+			remediation = "Enable nested virtualization in the hypervisor or use a bare metal host"
+		}
+		return model.CheckResult{
+			ID:          "nested_virt",
+			Message:     fmt.Sprintf("Running in a VM (hypervisor: %s) without nested virtualization (%s)", hv.Hypervisor, supportNone),
+			Status:      model.CheckStatusError,
+			Remediation: remediation,
+		}
+	}
+}
+
+// checkCPUFeatures checks the host CPU has the features Firecracker requires: the
+// hardware virtualization on x86 and a CPU Firecracker is tested on for arm64. Some
+// hypervisors hide the virtualization flags of the CPU with nested virtualization
+// working, so the flags are only required without KVM.
+func checkCPUFeatures(hv *hostVirt, goarch string, kvmOK bool) model.CheckResult {
+	switch goarch {
+	case "amd64":
+		if hv.Vendor != "GenuineIntel" && hv.Vendor != "AuthenticAMD" {
+			return model.CheckResult{
+				ID:          "cpu_features",
+				Message:     fmt.Sprintf("CPU vendor %q is not supported by Firecracker (Intel or AMD required)", hv.Vendor),
+				Status:      model.CheckStatusError,
+				Remediation: "Use an Intel or AMD host",
+			}
+		}
+		if !slices.Contains(hv.Flags, "vmx") && !slices.Contains(hv.Flags, "svm") {
+			if kvmOK {
+				return model.CheckResult{
+					ID:      "cpu_features",
+					Message: fmt.Sprintf("CPU %s hides the vmx/svm flags, KVM works through the hypervisor", hv.Model),
+					Status:  model.CheckStatusOK,
+				}
+			}
+			return model.CheckResult{
+				ID:          "cpu_features",
+				Message:     fmt.Sprintf("CPU %s lacks hardware virtualization (vmx/svm flags)", hv.Model),
+				Status:      model.CheckStatusError,
+				Remediation: "Enable virtualization in the BIOS or nested virtualization on VMs",
+			}
+		}
+		return model.CheckResult{
+			ID:      "cpu_features",
+			Message: fmt.Sprintf("CPU %s has hardware virtualization", hv.Model),
+			Status:  model.CheckStatusOK,
+		}
+	case "arm64":
+		name, ok := armTestedCPUs[hv.Vendor+":"+hv.Model]
+		if !ok {
+			return model.CheckResult{
+				ID:          "cpu_features",
+				Message:     fmt.Sprintf("CPU (implementer %s, part %s) is not one Firecracker is tested on (Neoverse N1, V1, V2), sandboxes may fail to boot", hv.Vendor, hv.Model),
+				Status:      model.CheckStatusWarning,
+				Remediation: "Use a Neoverse N1, V1 or V2 host (AWS Graviton2 or newer)",
+			}
+		}
+		return model.CheckResult{
+			ID:      "cpu_features",
+			Message: fmt.Sprintf("CPU %s is supported by Firecracker", name),
+			Status:  model.CheckStatusOK,
+		}
+	default:
+		return model.CheckResult{
+			ID:      "cpu_features",
+			Message: fmt.Sprintf("Unknown CPU features for %s", goarch),
+			Status:  model.CheckStatusWarning,
+		}
+	}
+}
+
+// checkMSR checks the host exposes the x86 model specific registers Firecracker
+// CPU templates and snapshot restores depend on. arm64 has no MSRs.
+func checkMSR(hv *hostVirt, goarch, devDir string) model.CheckResult {
+	if goarch != "amd64" {
+		return model.CheckResult{
+			ID:      "msr",
+			Message: "MSRs are not used on " + goarch,
+			Status:  model.CheckStatusOK,
+		}
+	}
+	if !slices.Contains(hv.Flags, "msr") {
+		return model.CheckResult{
+			ID:      "msr",
+			Message: "CPU doesn't report MSR support (msr flag), the VMs may fail to boot",
+			Status:  model.CheckStatusError,
+		}
+	}
+	if _, err := os.Stat(filepath.Join(devDir, "cpu", "0", "msr")); err != nil {
+		return model.CheckResult{
+			ID:          "msr",
+			Message:     "MSR device not available, the host MSRs can't be inspected to debug CPU template and snapshot restore failures",
+			Status:      model.CheckStatusWarning,
+			Remediation: "sudo modprobe msr",
+		}
+	}
+	return model.CheckResult{
+		ID:      "msr",
+		Message: "MSRs are available",
+		Status:  model.CheckStatusOK,
+	}
+}
+
+// checkVirt returns the nested virtualization, CPU features and MSR checks.
+func (e *Engine) checkVirt(goarch string, kvmOK bool) []model.CheckResult {
+	hv, err := readHostVirt(procDir, sysDir)
+	if err != nil {
+		return []model.CheckResult{{
+			ID:      "cpu_features",
+			Message: fmt.Sprintf("Cannot detect the CPU virtualization features: %v", err),
+			Status:  model.CheckStatusWarning,
+		}}
+	}
+
+	return []model.CheckResult{
+		checkNestedVirt(hv, kvmOK),
+		checkCPUFeatures(hv, goarch, kvmOK),
+		checkMSR(hv, goarch, devDir),
+	}
+}
@@ -0,0 +1,207 @@
+package firecracker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestReadHostVirt(t *testing.T) {
+	tests := map[string]struct {
+		cpuinfo   string
+		sysVendor string
+		product   string
+		expVirt   *hostVirt
+	}{
+		"An x86 bare metal host should not have hypervisor.": {
+			cpuinfo: "processor\t: 0\nvendor_id\t: GenuineIntel\nmodel name\t: Xeon\nflags\t\t: fpu msr vmx\n\nprocessor\t: 1\nvendor_id\t: Other\n",
+			expVirt: &hostVirt{Vendor: "GenuineIntel", Model: "Xeon", Flags: []string{"fpu", "msr", "vmx"}},
+		},
+		"An x86 VM should have the DMI hypervisor.": {
+			cpuinfo:   "vendor_id\t: AuthenticAMD\nmodel name\t: EPYC\nflags\t\t: msr svm hypervisor\n",
+			sysVendor: "Google",
+			expVirt:   &hostVirt{Vendor: "AuthenticAMD", Model: "EPYC", Flags: []string{"msr", "svm", "hypervisor"}, Hypervisor: "Google Compute Engine"},
+		},
+		"An x86 VM of an unknown vendor should have an unknown hypervisor.": {
+			cpuinfo: "vendor_id\t: GenuineIntel\nflags\t\t: msr hypervisor\n",
+			expVirt: &hostVirt{Vendor: "GenuineIntel", Flags: []string{"msr", "hypervisor"}, Hypervisor: "unknown"},
+		},
+		"An arm64 VM should be detected by the DMI vendor.": {
+			cpuinfo:   "processor\t: 0\nFeatures\t: fp asimd\nCPU implementer\t: 0x41\nCPU part\t: 0xd0c\n",
+			sysVendor: "QEMU",
+			expVirt:   &hostVirt{Vendor: "0x41", Model: "0xd0c", Flags: []string{"fp", "asimd"}, Hypervisor: "QEMU/KVM"},
+		},
+		"An arm64 AWS instance should not be a VM without the hypervisor flag.": {
+			cpuinfo:   "CPU implementer\t: 0x41\nCPU part\t: 0xd40\n",
+			sysVendor: "Amazon EC2",
+			product:   "c7g.16xlarge",
+			expVirt:   &hostVirt{Vendor: "0x41", Model: "0xd40"},
+		},
+		"A metal instance should be bare metal.": {
+			cpuinfo:   "vendor_id\t: GenuineIntel\nflags\t\t: msr vmx hypervisor\n",
+			sysVendor: "Amazon EC2",
+			product:   "m5.metal",
+			expVirt:   &hostVirt{Vendor: "GenuineIntel", Flags: []string{"msr", "vmx", "hypervisor"}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			procDir, sysDir := t.TempDir(), t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(procDir, "cpuinfo"), []byte(test.cpuinfo), 0o644))
+			dmiDir := filepath.Join(sysDir, "class", "dmi", "id")
+			require.NoError(t, os.MkdirAll(dmiDir, 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(dmiDir, "sys_vendor"), []byte(test.sysVendor+"\n"), 0o644))
+			require.NoError(t, os.WriteFile(filepath.Join(dmiDir, "product_name"), []byte(test.product+"\n"), 0o644))
+
+			hv, err := readHostVirt(procDir, sysDir)
+			require.NoError(t, err)
+			assert.Equal(t, test.expVirt, hv)
+		})
+	}
+}
+
+func TestCheckNestedVirt(t *testing.T) {
+	tests := map[string]struct {
+		hv             hostVirt
+		kvmOK          bool
+		expStatus      model.CheckStatus
+		expMsg         string
+		expRemediation string
+	}{
+		"Bare metal with KVM should be supported.": {
+			kvmOK:     true,
+			expStatus: model.CheckStatusOK,
+			expMsg:    "(supported)",
+		},
+		"Bare metal without KVM should be unsupported.": {
+			expStatus: model.CheckStatusError,
+			expMsg:    "(unsupported until KVM is available)",
+		},
+		"A VM with nested virtualization should be supported with caveats.": {
+			hv:        hostVirt{Hypervisor: "Hyper-V"},
+			kvmOK:     true,
+			expStatus: model.CheckStatusWarning,
+			expMsg:    "(supported with caveats): expect slower boots",
+		},
+		"A VM without nested virtualization should be unsupported with a remediation.": {
+			hv:             hostVirt{Hypervisor: "Google Compute Engine"},
+			expStatus:      model.CheckStatusError,
+			expMsg:         "(unsupported)",
+			expRemediation: "Create the instance with --enable-nested-virtualization",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			res := checkNestedVirt(&test.hv, test.kvmOK)
+			assert.Equal(t, "nested_virt", res.ID)
+			assert.Equal(t, test.expStatus, res.Status)
+			assert.Contains(t, res.Message, test.expMsg)
+			if test.expRemediation != "" {
+				assert.Equal(t, test.expRemediation, res.Remediation)
+			}
+		})
+	}
+}
+
+func TestCheckCPUFeatures(t *testing.T) {
+	tests := map[string]struct {
+		hv        hostVirt
+		goarch    string
+		kvmOK     bool
+		expStatus model.CheckStatus
+	}{
+		"An Intel CPU with vmx should be ok.": {
+			hv:        hostVirt{Vendor: "GenuineIntel", Flags: []string{"vmx"}},
+			goarch:    "amd64",
+			expStatus: model.CheckStatusOK,
+		},
+		"An x86 CPU without virtualization flags and KVM should fail.": {
+			hv:        hostVirt{Vendor: "AuthenticAMD", Flags: []string{"msr"}},
+			goarch:    "amd64",
+			expStatus: model.CheckStatusError,
+		},
+		"An x86 CPU hiding the virtualization flags with KVM working should be ok.": {
+			hv:        hostVirt{Vendor: "GenuineIntel", Flags: []string{"msr", "hypervisor"}},
+			goarch:    "amd64",
+			kvmOK:     true,
+			expStatus: model.CheckStatusOK,
+		},
+		"An unsupported x86 vendor should fail.": {
+			hv:        hostVirt{Vendor: "CentaurHauls", Flags: []string{"vmx"}},
+			goarch:    "amd64",
+			kvmOK:     true,
+			expStatus: model.CheckStatusError,
+		},
+		"A Graviton CPU should be ok.": {
+			hv:        hostVirt{Vendor: "0x41", Model: "0xd4f"},
+			goarch:    "arm64",
+			expStatus: model.CheckStatusOK,
+		},
+		"An untested arm64 CPU should warn.": {
+			hv:        hostVirt{Vendor: "0x61", Model: "0x048"},
+			goarch:    "arm64",
+			kvmOK:     true,
+			expStatus: model.CheckStatusWarning,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			res := checkCPUFeatures(&test.hv, test.goarch, test.kvmOK)
+			assert.Equal(t, "cpu_features", res.ID)
+			assert.Equal(t, test.expStatus, res.Status)
+		})
+	}
+}
+
+func TestCheckMSR(t *testing.T) {
+	devDir := t.TempDir()
+	withMSR := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(withMSR, "cpu", "0"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(withMSR, "cpu", "0", "msr"), nil, 0o600))
+
+	tests := map[string]struct {
+		flags     []string
+		goarch    string
+		devDir    string
+		expStatus model.CheckStatus
+	}{
+		"arm64 should not need MSRs.": {
+			goarch:    "arm64",
+			devDir:    devDir,
+			expStatus: model.CheckStatusOK,
+		},
+		"A CPU without MSRs should fail.": {
+			goarch:    "amd64",
+			devDir:    withMSR,
+			expStatus: model.CheckStatusError,
+		},
+		"A missing MSR device should warn.": {
+			flags:     []string{"msr"},
+			goarch:    "amd64",
+			devDir:    devDir,
+			expStatus: model.CheckStatusWarning,
+		},
+		"The MSR device should be ok.": {
+			flags:     []string{"msr"},
+			goarch:    "amd64",
+			devDir:    withMSR,
+			expStatus: model.CheckStatusOK,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			res := checkMSR(&hostVirt{Flags: test.flags}, test.goarch, test.devDir)
+			assert.Equal(t, "msr", res.ID)
+			assert.Equal(t, test.expStatus, res.Status)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -47,6 +48,30 @@ type NetworkInterface struct {
 	HostDevName string `json:"host_dev_name"`
 }
 
+// Balloon is the memory balloon device configuration.
+type Balloon struct {
+	AmountMib             int  `json:"amount_mib"`
+	DeflateOnOOM          bool `json:"deflate_on_oom"`
+	StatsPollingIntervalS int  `json:"stats_polling_interval_s,omitempty"`
+}
+
+// BalloonUpdate is the memory balloon device update of a running VM.
+type BalloonUpdate struct {
+	AmountMib int `json:"amount_mib"`
+}
+
+// VM is the state update of a running VM (Paused or Resumed).
+type VM struct {
+	State string `json:"state"`
+}
+
+// SnapshotCreateParams is the snapshot creation request of a paused VM.
+type SnapshotCreateParams struct {
+	SnapshotType string `json:"snapshot_type"`
+	SnapshotPath string `json:"snapshot_path"`
+	MemFilePath  string `json:"mem_file_path"`
+}
+
 // InstanceActionInfo is an action request.
 type InstanceActionInfo struct {
 	ActionType string `json:"action_type"`
@@ -77,8 +102,8 @@ func (e *Engine) findFirecrackerBinary() (string, error) {
 	return "", fmt.Errorf("firecracker binary not found")
 }
 
-// spawnFirecracker spawns the Firecracker process.
-func (e *Engine) spawnFirecracker(vmDir, socketPath string) (int, error) {
+// spawnFirecracker spawns the Firecracker process with the sandbox seccomp options.
+func (e *Engine) spawnFirecracker(vmDir, socketPath string, seccomp model.SeccompOptions) (int, error) {
 	fcBinary, err := e.findFirecrackerBinary()
 	if err != nil {
 		return 0, err
@@ -95,7 +120,7 @@ func (e *Engine) spawnFirecracker(vmDir, socketPath string) (int, error) {
 	}
 
 	// Spawn firecracker process
-	cmd := exec.Command(fcBinary, "--api-sock", socketPath)
+	cmd := exec.Command(fcBinary, append([]string{"--api-sock", socketPath}, e.seccompArgs(seccomp)...)...)
 	cmd.Dir = vmDir
 	cmd.Stdout = logFile
 	cmd.Stderr = logFile
@@ -106,6 +131,12 @@ func (e *Engine) spawnFirecracker(vmDir, socketPath string) (int, error) {
 	}
 
 	pid := cmd.Process.Pid
+	// Reap the process when it exits, so a stop from this process sees it
+	// terminated instead of a zombie.
+	go func() {
+		_ = cmd.Wait()
+		logFile.Close()
+	}()
 
 	// Write PID file
 	pidPath := filepath.Join(vmDir, conventions.PIDFile)
@@ -116,7 +147,7 @@ func (e *Engine) spawnFirecracker(vmDir, socketPath string) (int, error) {
 	// Wait for socket to be available
 	if err := e.waitForSocket(socketPath, 10*time.Second); err != nil {
 		// Kill process if socket never appeared
-		_ = cmd.Process.Kill()
+		_ = removeFirecracker(vmDir, pid)
 		return 0, fmt.Errorf("socket not available: %w", err)
 	}
 
@@ -124,34 +155,56 @@ func (e *Engine) spawnFirecracker(vmDir, socketPath string) (int, error) {
 	return pid, nil
 }
 
-// waitForSocket waits for the Unix socket to become available.
-func (e *Engine) waitForSocket(socketPath string, timeout time.Duration) error {
-	deadline := time.Now().Add(timeout)
-	for time.Now().Before(deadline) {
-		conn, err := net.DialTimeout("unix", socketPath, 100*time.Millisecond)
-		if err == nil {
-			conn.Close()
-			return nil
+// removeFirecracker kills a spawned Firecracker process and removes its PID and
+// socket files, so the sandbox is not seen as running.
+func removeFirecracker(vmDir string, pid int) error {
+	if proc, err := os.FindProcess(pid); err == nil {
+		if err := proc.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			return fmt.Errorf("could not kill firecracker process: %w", err)
 		}
-		time.Sleep(100 * time.Millisecond)
 	}
-	return fmt.Errorf("timeout waiting for socket %s", socketPath)
+	for _, path := range []string{filepath.Join(vmDir, conventions.PIDFile), filepath.Join(vmDir, conventions.SocketFile)} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not remove firecracker file: %w", err)
+		}
+	}
+	return nil
+}
+
+// waitForSocket waits for the Unix socket to become available, retrying with the
+// Firecracker API retry policy up to the timeout.
+func (e *Engine) waitForSocket(socketPath string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return e.retry(ctx, "firecracker API socket "+socketPath, e.retries.FirecrackerAPI, nil, func() error {
+		var d net.Dialer
+		dialCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+
+		conn, err := d.DialContext(dialCtx, "unix", socketPath)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	})
 }
 
 // configureVM configures the VM via the Firecracker API.
 // vmIP and gateway are used to configure networking via kernel boot parameters,
 // which works for any distro (Ubuntu, Alpine, etc.) without post-boot SSH config.
-func (e *Engine) configureVM(ctx context.Context, socketPath, kernelPath, vmDir, mac, tapDevice, vmIP, gateway string, resources model.Resources) error {
+func (e *Engine) configureVM(ctx context.Context, socketPath, kernelPath, vmDir, mac, tapDevice, vmIP, gateway string, cfg model.SandboxConfig, nics []nic) error {
 	client := e.newUnixHTTPClient(socketPath)
 
-	// 1. Configure boot source with network config via kernel ip= parameter
-	// Format: ip=<client-ip>:<server-ip>:<gateway>:<netmask>:<hostname>:<device>:<autoconf>
-	// This configures networking before init runs, works for any distro
-	// Note: init uses /usr/sbin/sbx-init since /sbin is typically a symlink to usr/sbin
-	bootArgs := fmt.Sprintf("console=ttyS0 reboot=k panic=1 pci=off init=/usr/sbin/sbx-init ip=%s::%s:255.255.255.0::eth0:off", vmIP, gateway)
+	var fcCfg model.FirecrackerEngineConfig
+	if cfg.FirecrackerEngine != nil {
+		fcCfg = *cfg.FirecrackerEngine
+	}
+
+	// 1. Configure boot source with the kernel command line (includes network config).
 	bootSource := BootSource{
 		KernelImagePath: kernelPath,
-		BootArgs:        bootArgs,
+		BootArgs:        bootArgs(fcCfg, vmIP, gateway),
 	}
 	if err := e.apiPUT(ctx, client, "/boot-source", bootSource); err != nil {
 		return fmt.Errorf("failed to configure boot source: %w", err)
@@ -163,27 +216,34 @@ func (e *Engine) configureVM(ctx context.Context, socketPath, kernelPath, vmDir,
 		DriveID:      "rootfs",
 		PathOnHost:   rootfsPath,
 		IsRootDevice: true,
-		IsReadOnly:   false,
+		IsReadOnly:   fcCfg.Boot.ReadOnlyRootFS,
 	}
 	if err := e.apiPUT(ctx, client, "/drives/rootfs", drive); err != nil {
 		return fmt.Errorf("failed to configure rootfs drive: %w", err)
 	}
 
-	// 3. Configure machine
-	// Note: Firecracker only supports whole VCPUs, so we round to nearest integer
-	vcpuCount := int(resources.VCPUs + 0.5) // Round to nearest
-	if vcpuCount < 1 {
-		vcpuCount = 1 // Minimum 1 vCPU
-	}
+	// 3. Configure machine with the resource ceilings, Firecracker can't hot-plug
+	// VCPUs or memory so the sandbox scales inside them (see Scale).
 	machineConfig := MachineConfig{
-		VCPUCount:  vcpuCount,
-		MemSizeMib: resources.MemoryMB,
+		VCPUCount:  wholeVCPUs(cfg.Resources.BootVCPUs()),
+		MemSizeMib: cfg.Resources.BootMemoryMB(),
 	}
 	if err := e.apiPUT(ctx, client, "/machine-config", machineConfig); err != nil {
 		return fmt.Errorf("failed to configure machine: %w", err)
 	}
 
-	// 4. Configure network interface
+	// 4. Configure memory balloon, starts inflated with the memory above the sandbox
+	// memory (deflated without a ceiling) and deflates on OOM so the guest gets the
+	// memory back when it needs it.
+	balloon := Balloon{
+		AmountMib:    cfg.Resources.BootMemoryMB() - cfg.Resources.MemoryMB,
+		DeflateOnOOM: true,
+	}
+	if err := e.apiPUT(ctx, client, "/balloon", balloon); err != nil {
+		return fmt.Errorf("failed to configure memory balloon: %w", err)
+	}
+
+	// 5. Configure network interface
 	netIface := NetworkInterface{
 		IfaceID:     "eth0",
 		GuestMAC:    mac,
@@ -193,6 +253,18 @@ func (e *Engine) configureVM(ctx context.Context, socketPath, kernelPath, vmDir,
 		return fmt.Errorf("failed to configure network interface: %w", err)
 	}
 
+	// 6. Configure additional network interfaces
+	for _, n := range nics {
+		netIface := NetworkInterface{
+			IfaceID:     n.id,
+			GuestMAC:    n.mac,
+			HostDevName: n.tapDevice,
+		}
+		if err := e.apiPUT(ctx, client, "/network-interfaces/"+n.id, netIface); err != nil {
+			return fmt.Errorf("failed to configure network interface %s: %w", n.id, err)
+		}
+	}
+
 	e.logger.Debugf("Configured VM via Firecracker API")
 	return nil
 }
@@ -212,6 +284,24 @@ func (e *Engine) bootVM(ctx context.Context, socketPath string) error {
 	return nil
 }
 
+// wholeVCPUs returns the VCPUs rounded to the nearest whole number, Firecracker only
+// supports whole VCPUs (minimum 1).
+func wholeVCPUs(vcpus float64) int {
+	return max(int(vcpus+0.5), 1)
+}
+
+// updateBalloon sets the memory balloon size of a running VM.
+func (e *Engine) updateBalloon(ctx context.Context, socketPath string, amountMib int) error {
+	client := e.newUnixHTTPClient(socketPath)
+
+	if err := e.apiPATCH(ctx, client, "/balloon", BalloonUpdate{AmountMib: amountMib}); err != nil {
+		return fmt.Errorf("failed to update memory balloon: %w", err)
+	}
+
+	e.logger.Debugf("Memory balloon set to %d MiB", amountMib)
+	return nil
+}
+
 // newUnixHTTPClient creates an HTTP client that connects via Unix socket.
 func (e *Engine) newUnixHTTPClient(socketPath string) *http.Client {
 	return &http.Client{
@@ -226,6 +316,16 @@ func (e *Engine) newUnixHTTPClient(socketPath string) *http.Client {
 
 // apiPUT sends a PUT request to the Firecracker API.
 func (e *Engine) apiPUT(ctx context.Context, client *http.Client, path string, body interface{}) error {
+	return e.apiRequest(ctx, client, http.MethodPut, path, body)
+}
+
+// apiPATCH sends a PATCH request to the Firecracker API.
+func (e *Engine) apiPATCH(ctx context.Context, client *http.Client, path string, body interface{}) error {
+	return e.apiRequest(ctx, client, http.MethodPatch, path, body)
+}
+
+// apiRequest sends a request with a JSON body to the Firecracker API.
+func (e *Engine) apiRequest(ctx context.Context, client *http.Client, method, path string, body interface{}) error {
 	data, err := json.Marshal(body)
 	if err != nil {
 		return fmt.Errorf("failed to marshal body: %w", err)
@@ -234,7 +334,7 @@ func (e *Engine) apiPUT(ctx context.Context, client *http.Client, path string, b
 	// Note: We use http://localhost as a placeholder; the actual connection
 	// is via Unix socket, so the host doesn't matter.
 	url := "http://localhost" + path
-	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(data))
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(data))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"testing"
 	"time"
@@ -132,6 +133,23 @@ func TestNetworkInterface_JSON(t *testing.T) {
 	}
 }
 
+func TestBalloon_JSON(t *testing.T) {
+	balloon := Balloon{
+		AmountMib:    0,
+		DeflateOnOOM: true,
+	}
+
+	data, err := json.Marshal(balloon)
+	if err != nil {
+		t.Fatalf("failed to marshal Balloon: %v", err)
+	}
+
+	expected := `{"amount_mib":0,"deflate_on_oom":true}`
+	if string(data) != expected {
+		t.Errorf("JSON mismatch: got %s, want %s", string(data), expected)
+	}
+}
+
 func TestInstanceActionInfo_JSON(t *testing.T) {
 	action := InstanceActionInfo{
 		ActionType: "InstanceStart",
@@ -324,9 +342,11 @@ func TestMockFirecrackerAPI_configureVM(t *testing.T) {
 	rootfsPath := filepath.Join(tmpDir, "rootfs.ext4")
 	_ = os.WriteFile(rootfsPath, []byte("dummy"), 0644)
 
-	resources := model.Resources{
-		VCPUs:    2,
-		MemoryMB: 1024,
+	cfg := model.SandboxConfig{
+		Resources: model.Resources{
+			VCPUs:    2,
+			MemoryMB: 1024,
+		},
 	}
 
 	err = e.configureVM(
@@ -338,7 +358,8 @@ func TestMockFirecrackerAPI_configureVM(t *testing.T) {
 		"sbx-0102",
 		"10.1.2.2", // vmIP
 		"10.1.2.1", // gateway
-		resources,
+		cfg,
+		[]nic{{id: "eth1", mac: "06:00:0A:03:04:02", tapDevice: "sbx-0304"}},
 	)
 	if err != nil {
 		t.Fatalf("configureVM failed: %v", err)
@@ -352,7 +373,9 @@ func TestMockFirecrackerAPI_configureVM(t *testing.T) {
 		"/boot-source",
 		"/drives/rootfs",
 		"/machine-config",
+		"/balloon",
 		"/network-interfaces/eth0",
+		"/network-interfaces/eth1",
 	}
 
 	for _, path := range expectedCalls {
@@ -416,3 +439,37 @@ func TestHTTPTestServer_compatible(t *testing.T) {
 		t.Errorf("unexpected status: %d", resp.StatusCode)
 	}
 }
+
+func TestRemoveFirecracker(t *testing.T) {
+	vmDir := t.TempDir()
+
+	cmd := exec.Command("sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("could not start process: %v", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	pidPath := filepath.Join(vmDir, conventions.PIDFile)
+	socketPath := filepath.Join(vmDir, conventions.SocketFile)
+	for _, path := range []string{pidPath, socketPath} {
+		if err := os.WriteFile(path, []byte("test"), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	if err := removeFirecracker(vmDir, cmd.Process.Pid); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("process was not killed")
+	}
+	for _, path := range []string{pidPath, socketPath} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed", path)
+		}
+	}
+}
@@ -6,6 +6,9 @@ package sandboxmock
 
 import (
 	"context"
+	"io"
+	"io/fs"
+	"net"
 
 	"github.com/slok/sbx/internal/model"
 	"github.com/slok/sbx/internal/sandbox"
@@ -39,6 +42,130 @@ func (_m *MockEngine) EXPECT() *MockEngine_Expecter {
 	return &MockEngine_Expecter{mock: &_m.Mock}
 }
 
+// Capabilities provides a mock function for the type MockEngine
+func (_mock *MockEngine) Capabilities() model.EngineCapabilities {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Capabilities")
+	}
+
+	var r0 model.EngineCapabilities
+	if returnFunc, ok := ret.Get(0).(func() model.EngineCapabilities); ok {
+		r0 = returnFunc()
+	} else {
+		r0 = ret.Get(0).(model.EngineCapabilities)
+	}
+	return r0
+}
+
+// MockEngine_Capabilities_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Capabilities'
+type MockEngine_Capabilities_Call struct {
+	*mock.Call
+}
+
+// Capabilities is a helper method to define mock.On call
+func (_e *MockEngine_Expecter) Capabilities() *MockEngine_Capabilities_Call {
+	return &MockEngine_Capabilities_Call{Call: _e.mock.On("Capabilities")}
+}
+
+func (_c *MockEngine_Capabilities_Call) Run(run func()) *MockEngine_Capabilities_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockEngine_Capabilities_Call) Return(engineCapabilities model.EngineCapabilities) *MockEngine_Capabilities_Call {
+	_c.Call.Return(engineCapabilities)
+	return _c
+}
+
+func (_c *MockEngine_Capabilities_Call) RunAndReturn(run func() model.EngineCapabilities) *MockEngine_Capabilities_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CaptureTraffic provides a mock function for the type MockEngine
+func (_mock *MockEngine) CaptureTraffic(ctx context.Context, id string, w io.Writer, opts model.CaptureOpts) (*model.CaptureResult, error) {
+	ret := _mock.Called(ctx, id, w, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CaptureTraffic")
+	}
+
+	var r0 *model.CaptureResult
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, io.Writer, model.CaptureOpts) (*model.CaptureResult, error)); ok {
+		return returnFunc(ctx, id, w, opts)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, io.Writer, model.CaptureOpts) *model.CaptureResult); ok {
+		r0 = returnFunc(ctx, id, w, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.CaptureResult)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, io.Writer, model.CaptureOpts) error); ok {
+		r1 = returnFunc(ctx, id, w, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockEngine_CaptureTraffic_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CaptureTraffic'
+type MockEngine_CaptureTraffic_Call struct {
+	*mock.Call
+}
+
+// CaptureTraffic is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - w io.Writer
+//   - opts model.CaptureOpts
+func (_e *MockEngine_Expecter) CaptureTraffic(ctx interface{}, id interface{}, w interface{}, opts interface{}) *MockEngine_CaptureTraffic_Call {
+	return &MockEngine_CaptureTraffic_Call{Call: _e.mock.On("CaptureTraffic", ctx, id, w, opts)}
+}
+
+func (_c *MockEngine_CaptureTraffic_Call) Run(run func(ctx context.Context, id string, w io.Writer, opts model.CaptureOpts)) *MockEngine_CaptureTraffic_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 io.Writer
+		if args[2] != nil {
+			arg2 = args[2].(io.Writer)
+		}
+		var arg3 model.CaptureOpts
+		if args[3] != nil {
+			arg3 = args[3].(model.CaptureOpts)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+		)
+	})
+	return _c
+}
+
+func (_c *MockEngine_CaptureTraffic_Call) Return(captureResult *model.CaptureResult, err error) *MockEngine_CaptureTraffic_Call {
+	_c.Call.Return(captureResult, err)
+	return _c
+}
+
+func (_c *MockEngine_CaptureTraffic_Call) RunAndReturn(run func(ctx context.Context, id string, w io.Writer, opts model.CaptureOpts) (*model.CaptureResult, error)) *MockEngine_CaptureTraffic_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Check provides a mock function for the type MockEngine
 func (_mock *MockEngine) Check(ctx context.Context) []model.CheckResult {
 	ret := _mock.Called(ctx)
@@ -82,48 +209,1117 @@ func (_c *MockEngine_Check_Call) Run(run func(ctx context.Context)) *MockEngine_
 	return _c
 }
 
-func (_c *MockEngine_Check_Call) Return(checkResults []model.CheckResult) *MockEngine_Check_Call {
-	_c.Call.Return(checkResults)
+func (_c *MockEngine_Check_Call) Return(checkResults []model.CheckResult) *MockEngine_Check_Call {
+	_c.Call.Return(checkResults)
+	return _c
+}
+
+func (_c *MockEngine_Check_Call) RunAndReturn(run func(ctx context.Context) []model.CheckResult) *MockEngine_Check_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Checkpoint provides a mock function for the type MockEngine
+func (_mock *MockEngine) Checkpoint(ctx context.Context, id string, dir string) (*model.Checkpoint, error) {
+	ret := _mock.Called(ctx, id, dir)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Checkpoint")
+	}
+
+	var r0 *model.Checkpoint
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) (*model.Checkpoint, error)); ok {
+		return returnFunc(ctx, id, dir)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) *model.Checkpoint); ok {
+		r0 = returnFunc(ctx, id, dir)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Checkpoint)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = returnFunc(ctx, id, dir)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockEngine_Checkpoint_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Checkpoint'
+type MockEngine_Checkpoint_Call struct {
+	*mock.Call
+}
+
+// Checkpoint is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - dir string
+func (_e *MockEngine_Expecter) Checkpoint(ctx interface{}, id interface{}, dir interface{}) *MockEngine_Checkpoint_Call {
+	return &MockEngine_Checkpoint_Call{Call: _e.mock.On("Checkpoint", ctx, id, dir)}
+}
+
+func (_c *MockEngine_Checkpoint_Call) Run(run func(ctx context.Context, id string, dir string)) *MockEngine_Checkpoint_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockEngine_Checkpoint_Call) Return(checkpoint *model.Checkpoint, err error) *MockEngine_Checkpoint_Call {
+	_c.Call.Return(checkpoint, err)
+	return _c
+}
+
+func (_c *MockEngine_Checkpoint_Call) RunAndReturn(run func(ctx context.Context, id string, dir string) (*model.Checkpoint, error)) *MockEngine_Checkpoint_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Connections provides a mock function for the type MockEngine
+func (_mock *MockEngine) Connections(ctx context.Context, id string) ([]model.Connection, error) {
+	ret := _mock.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Connections")
+	}
+
+	var r0 []model.Connection
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) ([]model.Connection, error)); ok {
+		return returnFunc(ctx, id)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) []model.Connection); ok {
+		r0 = returnFunc(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.Connection)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockEngine_Connections_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Connections'
+type MockEngine_Connections_Call struct {
+	*mock.Call
+}
+
+// Connections is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+func (_e *MockEngine_Expecter) Connections(ctx interface{}, id interface{}) *MockEngine_Connections_Call {
+	return &MockEngine_Connections_Call{Call: _e.mock.On("Connections", ctx, id)}
+}
+
+func (_c *MockEngine_Connections_Call) Run(run func(ctx context.Context, id string)) *MockEngine_Connections_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockEngine_Connections_Call) Return(connections []model.Connection, err error) *MockEngine_Connections_Call {
+	_c.Call.Return(connections, err)
+	return _c
+}
+
+func (_c *MockEngine_Connections_Call) RunAndReturn(run func(ctx context.Context, id string) ([]model.Connection, error)) *MockEngine_Connections_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CopyFrom provides a mock function for the type MockEngine
+func (_mock *MockEngine) CopyFrom(ctx context.Context, id string, srcRemote string, dstLocal string, opts model.CopyOpts) error {
+	ret := _mock.Called(ctx, id, srcRemote, dstLocal, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CopyFrom")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, string, model.CopyOpts) error); ok {
+		r0 = returnFunc(ctx, id, srcRemote, dstLocal, opts)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockEngine_CopyFrom_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CopyFrom'
+type MockEngine_CopyFrom_Call struct {
+	*mock.Call
+}
+
+// CopyFrom is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - srcRemote string
+//   - dstLocal string
+//   - opts model.CopyOpts
+func (_e *MockEngine_Expecter) CopyFrom(ctx interface{}, id interface{}, srcRemote interface{}, dstLocal interface{}, opts interface{}) *MockEngine_CopyFrom_Call {
+	return &MockEngine_CopyFrom_Call{Call: _e.mock.On("CopyFrom", ctx, id, srcRemote, dstLocal, opts)}
+}
+
+func (_c *MockEngine_CopyFrom_Call) Run(run func(ctx context.Context, id string, srcRemote string, dstLocal string, opts model.CopyOpts)) *MockEngine_CopyFrom_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		var arg3 string
+		if args[3] != nil {
+			arg3 = args[3].(string)
+		}
+		var arg4 model.CopyOpts
+		if args[4] != nil {
+			arg4 = args[4].(model.CopyOpts)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+			arg4,
+		)
+	})
+	return _c
+}
+
+func (_c *MockEngine_CopyFrom_Call) Return(err error) *MockEngine_CopyFrom_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockEngine_CopyFrom_Call) RunAndReturn(run func(ctx context.Context, id string, srcRemote string, dstLocal string, opts model.CopyOpts) error) *MockEngine_CopyFrom_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CopyTo provides a mock function for the type MockEngine
+func (_mock *MockEngine) CopyTo(ctx context.Context, id string, srcLocal string, dstRemote string, opts model.CopyOpts) error {
+	ret := _mock.Called(ctx, id, srcLocal, dstRemote, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CopyTo")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, string, model.CopyOpts) error); ok {
+		r0 = returnFunc(ctx, id, srcLocal, dstRemote, opts)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockEngine_CopyTo_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CopyTo'
+type MockEngine_CopyTo_Call struct {
+	*mock.Call
+}
+
+// CopyTo is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - srcLocal string
+//   - dstRemote string
+//   - opts model.CopyOpts
+func (_e *MockEngine_Expecter) CopyTo(ctx interface{}, id interface{}, srcLocal interface{}, dstRemote interface{}, opts interface{}) *MockEngine_CopyTo_Call {
+	return &MockEngine_CopyTo_Call{Call: _e.mock.On("CopyTo", ctx, id, srcLocal, dstRemote, opts)}
+}
+
+func (_c *MockEngine_CopyTo_Call) Run(run func(ctx context.Context, id string, srcLocal string, dstRemote string, opts model.CopyOpts)) *MockEngine_CopyTo_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		var arg3 string
+		if args[3] != nil {
+			arg3 = args[3].(string)
+		}
+		var arg4 model.CopyOpts
+		if args[4] != nil {
+			arg4 = args[4].(model.CopyOpts)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+			arg4,
+		)
+	})
+	return _c
+}
+
+func (_c *MockEngine_CopyTo_Call) Return(err error) *MockEngine_CopyTo_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockEngine_CopyTo_Call) RunAndReturn(run func(ctx context.Context, id string, srcLocal string, dstRemote string, opts model.CopyOpts) error) *MockEngine_CopyTo_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Create provides a mock function for the type MockEngine
+func (_mock *MockEngine) Create(ctx context.Context, cfg model.SandboxConfig, opts sandbox.CreateOpts) (*model.Sandbox, error) {
+	ret := _mock.Called(ctx, cfg, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 *model.Sandbox
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, model.SandboxConfig, sandbox.CreateOpts) (*model.Sandbox, error)); ok {
+		return returnFunc(ctx, cfg, opts)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, model.SandboxConfig, sandbox.CreateOpts) *model.Sandbox); ok {
+		r0 = returnFunc(ctx, cfg, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Sandbox)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, model.SandboxConfig, sandbox.CreateOpts) error); ok {
+		r1 = returnFunc(ctx, cfg, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockEngine_Create_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Create'
+type MockEngine_Create_Call struct {
+	*mock.Call
+}
+
+// Create is a helper method to define mock.On call
+//   - ctx context.Context
+//   - cfg model.SandboxConfig
+//   - opts sandbox.CreateOpts
+func (_e *MockEngine_Expecter) Create(ctx interface{}, cfg interface{}, opts interface{}) *MockEngine_Create_Call {
+	return &MockEngine_Create_Call{Call: _e.mock.On("Create", ctx, cfg, opts)}
+}
+
+func (_c *MockEngine_Create_Call) Run(run func(ctx context.Context, cfg model.SandboxConfig, opts sandbox.CreateOpts)) *MockEngine_Create_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 model.SandboxConfig
+		if args[1] != nil {
+			arg1 = args[1].(model.SandboxConfig)
+		}
+		var arg2 sandbox.CreateOpts
+		if args[2] != nil {
+			arg2 = args[2].(sandbox.CreateOpts)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockEngine_Create_Call) Return(sandbox1 *model.Sandbox, err error) *MockEngine_Create_Call {
+	_c.Call.Return(sandbox1, err)
+	return _c
+}
+
+func (_c *MockEngine_Create_Call) RunAndReturn(run func(ctx context.Context, cfg model.SandboxConfig, opts sandbox.CreateOpts) (*model.Sandbox, error)) *MockEngine_Create_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DNSEvents provides a mock function for the type MockEngine
+func (_mock *MockEngine) DNSEvents(ctx context.Context, id string) ([]model.DNSEvent, error) {
+	ret := _mock.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DNSEvents")
+	}
+
+	var r0 []model.DNSEvent
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) ([]model.DNSEvent, error)); ok {
+		return returnFunc(ctx, id)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) []model.DNSEvent); ok {
+		r0 = returnFunc(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.DNSEvent)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockEngine_DNSEvents_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DNSEvents'
+type MockEngine_DNSEvents_Call struct {
+	*mock.Call
+}
+
+// DNSEvents is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+func (_e *MockEngine_Expecter) DNSEvents(ctx interface{}, id interface{}) *MockEngine_DNSEvents_Call {
+	return &MockEngine_DNSEvents_Call{Call: _e.mock.On("DNSEvents", ctx, id)}
+}
+
+func (_c *MockEngine_DNSEvents_Call) Run(run func(ctx context.Context, id string)) *MockEngine_DNSEvents_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockEngine_DNSEvents_Call) Return(dNSEvents []model.DNSEvent, err error) *MockEngine_DNSEvents_Call {
+	_c.Call.Return(dNSEvents, err)
+	return _c
+}
+
+func (_c *MockEngine_DNSEvents_Call) RunAndReturn(run func(ctx context.Context, id string) ([]model.DNSEvent, error)) *MockEngine_DNSEvents_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DialAPI provides a mock function for the type MockEngine
+func (_mock *MockEngine) DialAPI(ctx context.Context, id string) (net.Conn, error) {
+	ret := _mock.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DialAPI")
+	}
+
+	var r0 net.Conn
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (net.Conn, error)); ok {
+		return returnFunc(ctx, id)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) net.Conn); ok {
+		r0 = returnFunc(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(net.Conn)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockEngine_DialAPI_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DialAPI'
+type MockEngine_DialAPI_Call struct {
+	*mock.Call
+}
+
+// DialAPI is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+func (_e *MockEngine_Expecter) DialAPI(ctx interface{}, id interface{}) *MockEngine_DialAPI_Call {
+	return &MockEngine_DialAPI_Call{Call: _e.mock.On("DialAPI", ctx, id)}
+}
+
+func (_c *MockEngine_DialAPI_Call) Run(run func(ctx context.Context, id string)) *MockEngine_DialAPI_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockEngine_DialAPI_Call) Return(conn net.Conn, err error) *MockEngine_DialAPI_Call {
+	_c.Call.Return(conn, err)
+	return _c
+}
+
+func (_c *MockEngine_DialAPI_Call) RunAndReturn(run func(ctx context.Context, id string) (net.Conn, error)) *MockEngine_DialAPI_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Exec provides a mock function for the type MockEngine
+func (_mock *MockEngine) Exec(ctx context.Context, id string, command []string, opts model.ExecOpts) (*model.ExecResult, error) {
+	ret := _mock.Called(ctx, id, command, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Exec")
+	}
+
+	var r0 *model.ExecResult
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, []string, model.ExecOpts) (*model.ExecResult, error)); ok {
+		return returnFunc(ctx, id, command, opts)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, []string, model.ExecOpts) *model.ExecResult); ok {
+		r0 = returnFunc(ctx, id, command, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.ExecResult)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, []string, model.ExecOpts) error); ok {
+		r1 = returnFunc(ctx, id, command, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockEngine_Exec_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Exec'
+type MockEngine_Exec_Call struct {
+	*mock.Call
+}
+
+// Exec is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - command []string
+//   - opts model.ExecOpts
+func (_e *MockEngine_Expecter) Exec(ctx interface{}, id interface{}, command interface{}, opts interface{}) *MockEngine_Exec_Call {
+	return &MockEngine_Exec_Call{Call: _e.mock.On("Exec", ctx, id, command, opts)}
+}
+
+func (_c *MockEngine_Exec_Call) Run(run func(ctx context.Context, id string, command []string, opts model.ExecOpts)) *MockEngine_Exec_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 []string
+		if args[2] != nil {
+			arg2 = args[2].([]string)
+		}
+		var arg3 model.ExecOpts
+		if args[3] != nil {
+			arg3 = args[3].(model.ExecOpts)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+		)
+	})
+	return _c
+}
+
+func (_c *MockEngine_Exec_Call) Return(execResult *model.ExecResult, err error) *MockEngine_Exec_Call {
+	_c.Call.Return(execResult, err)
+	return _c
+}
+
+func (_c *MockEngine_Exec_Call) RunAndReturn(run func(ctx context.Context, id string, command []string, opts model.ExecOpts) (*model.ExecResult, error)) *MockEngine_Exec_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Forward provides a mock function for the type MockEngine
+func (_mock *MockEngine) Forward(ctx context.Context, id string, ports []model.PortMapping, opts sandbox.ForwardOpts) error {
+	ret := _mock.Called(ctx, id, ports, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Forward")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, []model.PortMapping, sandbox.ForwardOpts) error); ok {
+		r0 = returnFunc(ctx, id, ports, opts)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockEngine_Forward_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Forward'
+type MockEngine_Forward_Call struct {
+	*mock.Call
+}
+
+// Forward is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - ports []model.PortMapping
+//   - opts sandbox.ForwardOpts
+func (_e *MockEngine_Expecter) Forward(ctx interface{}, id interface{}, ports interface{}, opts interface{}) *MockEngine_Forward_Call {
+	return &MockEngine_Forward_Call{Call: _e.mock.On("Forward", ctx, id, ports, opts)}
+}
+
+func (_c *MockEngine_Forward_Call) Run(run func(ctx context.Context, id string, ports []model.PortMapping, opts sandbox.ForwardOpts)) *MockEngine_Forward_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 []model.PortMapping
+		if args[2] != nil {
+			arg2 = args[2].([]model.PortMapping)
+		}
+		var arg3 sandbox.ForwardOpts
+		if args[3] != nil {
+			arg3 = args[3].(sandbox.ForwardOpts)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+		)
+	})
+	return _c
+}
+
+func (_c *MockEngine_Forward_Call) Return(err error) *MockEngine_Forward_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockEngine_Forward_Call) RunAndReturn(run func(ctx context.Context, id string, ports []model.PortMapping, opts sandbox.ForwardOpts) error) *MockEngine_Forward_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListDir provides a mock function for the type MockEngine
+func (_mock *MockEngine) ListDir(ctx context.Context, id string, remoteDir string) ([]model.FileInfo, error) {
+	ret := _mock.Called(ctx, id, remoteDir)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListDir")
+	}
+
+	var r0 []model.FileInfo
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) ([]model.FileInfo, error)); ok {
+		return returnFunc(ctx, id, remoteDir)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) []model.FileInfo); ok {
+		r0 = returnFunc(ctx, id, remoteDir)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.FileInfo)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = returnFunc(ctx, id, remoteDir)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockEngine_ListDir_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListDir'
+type MockEngine_ListDir_Call struct {
+	*mock.Call
+}
+
+// ListDir is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - remoteDir string
+func (_e *MockEngine_Expecter) ListDir(ctx interface{}, id interface{}, remoteDir interface{}) *MockEngine_ListDir_Call {
+	return &MockEngine_ListDir_Call{Call: _e.mock.On("ListDir", ctx, id, remoteDir)}
+}
+
+func (_c *MockEngine_ListDir_Call) Run(run func(ctx context.Context, id string, remoteDir string)) *MockEngine_ListDir_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockEngine_ListDir_Call) Return(fileInfos []model.FileInfo, err error) *MockEngine_ListDir_Call {
+	_c.Call.Return(fileInfos, err)
+	return _c
+}
+
+func (_c *MockEngine_ListDir_Call) RunAndReturn(run func(ctx context.Context, id string, remoteDir string) ([]model.FileInfo, error)) *MockEngine_ListDir_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Logs provides a mock function for the type MockEngine
+func (_mock *MockEngine) Logs(ctx context.Context, id string, lines int) ([]model.SandboxLog, error) {
+	ret := _mock.Called(ctx, id, lines)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Logs")
+	}
+
+	var r0 []model.SandboxLog
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, int) ([]model.SandboxLog, error)); ok {
+		return returnFunc(ctx, id, lines)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, int) []model.SandboxLog); ok {
+		r0 = returnFunc(ctx, id, lines)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.SandboxLog)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = returnFunc(ctx, id, lines)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockEngine_Logs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Logs'
+type MockEngine_Logs_Call struct {
+	*mock.Call
+}
+
+// Logs is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - lines int
+func (_e *MockEngine_Expecter) Logs(ctx interface{}, id interface{}, lines interface{}) *MockEngine_Logs_Call {
+	return &MockEngine_Logs_Call{Call: _e.mock.On("Logs", ctx, id, lines)}
+}
+
+func (_c *MockEngine_Logs_Call) Run(run func(ctx context.Context, id string, lines int)) *MockEngine_Logs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 int
+		if args[2] != nil {
+			arg2 = args[2].(int)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockEngine_Logs_Call) Return(sandboxLogs []model.SandboxLog, err error) *MockEngine_Logs_Call {
+	_c.Call.Return(sandboxLogs, err)
+	return _c
+}
+
+func (_c *MockEngine_Logs_Call) RunAndReturn(run func(ctx context.Context, id string, lines int) ([]model.SandboxLog, error)) *MockEngine_Logs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NetworkBytes provides a mock function for the type MockEngine
+func (_mock *MockEngine) NetworkBytes(ctx context.Context, id string) (uint64, error) {
+	ret := _mock.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for NetworkBytes")
+	}
+
+	var r0 uint64
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (uint64, error)); ok {
+		return returnFunc(ctx, id)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) uint64); ok {
+		r0 = returnFunc(ctx, id)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockEngine_NetworkBytes_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'NetworkBytes'
+type MockEngine_NetworkBytes_Call struct {
+	*mock.Call
+}
+
+// NetworkBytes is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+func (_e *MockEngine_Expecter) NetworkBytes(ctx interface{}, id interface{}) *MockEngine_NetworkBytes_Call {
+	return &MockEngine_NetworkBytes_Call{Call: _e.mock.On("NetworkBytes", ctx, id)}
+}
+
+func (_c *MockEngine_NetworkBytes_Call) Run(run func(ctx context.Context, id string)) *MockEngine_NetworkBytes_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockEngine_NetworkBytes_Call) Return(uint64 uint64, err error) *MockEngine_NetworkBytes_Call {
+	_c.Call.Return(uint64, err)
+	return _c
+}
+
+func (_c *MockEngine_NetworkBytes_Call) RunAndReturn(run func(ctx context.Context, id string) (uint64, error)) *MockEngine_NetworkBytes_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Quarantine provides a mock function for the type MockEngine
+func (_mock *MockEngine) Quarantine(ctx context.Context, id string, enabled bool) error {
+	ret := _mock.Called(ctx, id, enabled)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Quarantine")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, bool) error); ok {
+		r0 = returnFunc(ctx, id, enabled)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockEngine_Quarantine_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Quarantine'
+type MockEngine_Quarantine_Call struct {
+	*mock.Call
+}
+
+// Quarantine is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - enabled bool
+func (_e *MockEngine_Expecter) Quarantine(ctx interface{}, id interface{}, enabled interface{}) *MockEngine_Quarantine_Call {
+	return &MockEngine_Quarantine_Call{Call: _e.mock.On("Quarantine", ctx, id, enabled)}
+}
+
+func (_c *MockEngine_Quarantine_Call) Run(run func(ctx context.Context, id string, enabled bool)) *MockEngine_Quarantine_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 bool
+		if args[2] != nil {
+			arg2 = args[2].(bool)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockEngine_Quarantine_Call) Return(err error) *MockEngine_Quarantine_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockEngine_Quarantine_Call) RunAndReturn(run func(ctx context.Context, id string, enabled bool) error) *MockEngine_Quarantine_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Rebase provides a mock function for the type MockEngine
+func (_mock *MockEngine) Rebase(ctx context.Context, id string, cfg model.SandboxConfig, opts sandbox.RebaseOpts) error {
+	ret := _mock.Called(ctx, id, cfg, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Rebase")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, model.SandboxConfig, sandbox.RebaseOpts) error); ok {
+		r0 = returnFunc(ctx, id, cfg, opts)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockEngine_Rebase_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Rebase'
+type MockEngine_Rebase_Call struct {
+	*mock.Call
+}
+
+// Rebase is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - cfg model.SandboxConfig
+//   - opts sandbox.RebaseOpts
+func (_e *MockEngine_Expecter) Rebase(ctx interface{}, id interface{}, cfg interface{}, opts interface{}) *MockEngine_Rebase_Call {
+	return &MockEngine_Rebase_Call{Call: _e.mock.On("Rebase", ctx, id, cfg, opts)}
+}
+
+func (_c *MockEngine_Rebase_Call) Run(run func(ctx context.Context, id string, cfg model.SandboxConfig, opts sandbox.RebaseOpts)) *MockEngine_Rebase_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 model.SandboxConfig
+		if args[2] != nil {
+			arg2 = args[2].(model.SandboxConfig)
+		}
+		var arg3 sandbox.RebaseOpts
+		if args[3] != nil {
+			arg3 = args[3].(sandbox.RebaseOpts)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+		)
+	})
+	return _c
+}
+
+func (_c *MockEngine_Rebase_Call) Return(err error) *MockEngine_Rebase_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockEngine_Rebase_Call) RunAndReturn(run func(ctx context.Context, id string, cfg model.SandboxConfig, opts sandbox.RebaseOpts) error) *MockEngine_Rebase_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ReadFile provides a mock function for the type MockEngine
+func (_mock *MockEngine) ReadFile(ctx context.Context, id string, remotePath string) ([]byte, error) {
+	ret := _mock.Called(ctx, id, remotePath)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReadFile")
+	}
+
+	var r0 []byte
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) ([]byte, error)); ok {
+		return returnFunc(ctx, id, remotePath)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) []byte); ok {
+		r0 = returnFunc(ctx, id, remotePath)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = returnFunc(ctx, id, remotePath)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockEngine_ReadFile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ReadFile'
+type MockEngine_ReadFile_Call struct {
+	*mock.Call
+}
+
+// ReadFile is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - remotePath string
+func (_e *MockEngine_Expecter) ReadFile(ctx interface{}, id interface{}, remotePath interface{}) *MockEngine_ReadFile_Call {
+	return &MockEngine_ReadFile_Call{Call: _e.mock.On("ReadFile", ctx, id, remotePath)}
+}
+
+func (_c *MockEngine_ReadFile_Call) Run(run func(ctx context.Context, id string, remotePath string)) *MockEngine_ReadFile_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockEngine_ReadFile_Call) Return(bytes []byte, err error) *MockEngine_ReadFile_Call {
+	_c.Call.Return(bytes, err)
 	return _c
 }
 
-func (_c *MockEngine_Check_Call) RunAndReturn(run func(ctx context.Context) []model.CheckResult) *MockEngine_Check_Call {
+func (_c *MockEngine_ReadFile_Call) RunAndReturn(run func(ctx context.Context, id string, remotePath string) ([]byte, error)) *MockEngine_ReadFile_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// CopyFrom provides a mock function for the type MockEngine
-func (_mock *MockEngine) CopyFrom(ctx context.Context, id string, srcRemote string, dstLocal string) error {
-	ret := _mock.Called(ctx, id, srcRemote, dstLocal)
+// Remove provides a mock function for the type MockEngine
+func (_mock *MockEngine) Remove(ctx context.Context, id string) error {
+	ret := _mock.Called(ctx, id)
 
 	if len(ret) == 0 {
-		panic("no return value specified for CopyFrom")
+		panic("no return value specified for Remove")
 	}
 
 	var r0 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, string) error); ok {
-		r0 = returnFunc(ctx, id, srcRemote, dstLocal)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = returnFunc(ctx, id)
 	} else {
 		r0 = ret.Error(0)
 	}
 	return r0
 }
 
-// MockEngine_CopyFrom_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CopyFrom'
-type MockEngine_CopyFrom_Call struct {
+// MockEngine_Remove_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Remove'
+type MockEngine_Remove_Call struct {
 	*mock.Call
 }
 
-// CopyFrom is a helper method to define mock.On call
+// Remove is a helper method to define mock.On call
 //   - ctx context.Context
 //   - id string
-//   - srcRemote string
-//   - dstLocal string
-func (_e *MockEngine_Expecter) CopyFrom(ctx interface{}, id interface{}, srcRemote interface{}, dstLocal interface{}) *MockEngine_CopyFrom_Call {
-	return &MockEngine_CopyFrom_Call{Call: _e.mock.On("CopyFrom", ctx, id, srcRemote, dstLocal)}
+func (_e *MockEngine_Expecter) Remove(ctx interface{}, id interface{}) *MockEngine_Remove_Call {
+	return &MockEngine_Remove_Call{Call: _e.mock.On("Remove", ctx, id)}
 }
 
-func (_c *MockEngine_CopyFrom_Call) Run(run func(ctx context.Context, id string, srcRemote string, dstLocal string)) *MockEngine_CopyFrom_Call {
+func (_c *MockEngine_Remove_Call) Run(run func(ctx context.Context, id string)) *MockEngine_Remove_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
@@ -133,66 +1329,55 @@ func (_c *MockEngine_CopyFrom_Call) Run(run func(ctx context.Context, id string,
 		if args[1] != nil {
 			arg1 = args[1].(string)
 		}
-		var arg2 string
-		if args[2] != nil {
-			arg2 = args[2].(string)
-		}
-		var arg3 string
-		if args[3] != nil {
-			arg3 = args[3].(string)
-		}
 		run(
 			arg0,
 			arg1,
-			arg2,
-			arg3,
 		)
 	})
 	return _c
 }
 
-func (_c *MockEngine_CopyFrom_Call) Return(err error) *MockEngine_CopyFrom_Call {
+func (_c *MockEngine_Remove_Call) Return(err error) *MockEngine_Remove_Call {
 	_c.Call.Return(err)
 	return _c
 }
 
-func (_c *MockEngine_CopyFrom_Call) RunAndReturn(run func(ctx context.Context, id string, srcRemote string, dstLocal string) error) *MockEngine_CopyFrom_Call {
+func (_c *MockEngine_Remove_Call) RunAndReturn(run func(ctx context.Context, id string) error) *MockEngine_Remove_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// CopyTo provides a mock function for the type MockEngine
-func (_mock *MockEngine) CopyTo(ctx context.Context, id string, srcLocal string, dstRemote string) error {
-	ret := _mock.Called(ctx, id, srcLocal, dstRemote)
+// Scale provides a mock function for the type MockEngine
+func (_mock *MockEngine) Scale(ctx context.Context, id string, resources model.Resources) error {
+	ret := _mock.Called(ctx, id, resources)
 
 	if len(ret) == 0 {
-		panic("no return value specified for CopyTo")
+		panic("no return value specified for Scale")
 	}
 
 	var r0 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, string) error); ok {
-		r0 = returnFunc(ctx, id, srcLocal, dstRemote)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, model.Resources) error); ok {
+		r0 = returnFunc(ctx, id, resources)
 	} else {
 		r0 = ret.Error(0)
 	}
 	return r0
 }
 
-// MockEngine_CopyTo_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CopyTo'
-type MockEngine_CopyTo_Call struct {
+// MockEngine_Scale_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Scale'
+type MockEngine_Scale_Call struct {
 	*mock.Call
 }
 
-// CopyTo is a helper method to define mock.On call
+// Scale is a helper method to define mock.On call
 //   - ctx context.Context
 //   - id string
-//   - srcLocal string
-//   - dstRemote string
-func (_e *MockEngine_Expecter) CopyTo(ctx interface{}, id interface{}, srcLocal interface{}, dstRemote interface{}) *MockEngine_CopyTo_Call {
-	return &MockEngine_CopyTo_Call{Call: _e.mock.On("CopyTo", ctx, id, srcLocal, dstRemote)}
+//   - resources model.Resources
+func (_e *MockEngine_Expecter) Scale(ctx interface{}, id interface{}, resources interface{}) *MockEngine_Scale_Call {
+	return &MockEngine_Scale_Call{Call: _e.mock.On("Scale", ctx, id, resources)}
 }
 
-func (_c *MockEngine_CopyTo_Call) Run(run func(ctx context.Context, id string, srcLocal string, dstRemote string)) *MockEngine_CopyTo_Call {
+func (_c *MockEngine_Scale_Call) Run(run func(ctx context.Context, id string, resources model.Resources)) *MockEngine_Scale_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
@@ -202,145 +1387,134 @@ func (_c *MockEngine_CopyTo_Call) Run(run func(ctx context.Context, id string, s
 		if args[1] != nil {
 			arg1 = args[1].(string)
 		}
-		var arg2 string
+		var arg2 model.Resources
 		if args[2] != nil {
-			arg2 = args[2].(string)
-		}
-		var arg3 string
-		if args[3] != nil {
-			arg3 = args[3].(string)
+			arg2 = args[2].(model.Resources)
 		}
 		run(
 			arg0,
 			arg1,
 			arg2,
-			arg3,
 		)
 	})
 	return _c
 }
 
-func (_c *MockEngine_CopyTo_Call) Return(err error) *MockEngine_CopyTo_Call {
+func (_c *MockEngine_Scale_Call) Return(err error) *MockEngine_Scale_Call {
 	_c.Call.Return(err)
 	return _c
 }
 
-func (_c *MockEngine_CopyTo_Call) RunAndReturn(run func(ctx context.Context, id string, srcLocal string, dstRemote string) error) *MockEngine_CopyTo_Call {
+func (_c *MockEngine_Scale_Call) RunAndReturn(run func(ctx context.Context, id string, resources model.Resources) error) *MockEngine_Scale_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// Create provides a mock function for the type MockEngine
-func (_mock *MockEngine) Create(ctx context.Context, cfg model.SandboxConfig) (*model.Sandbox, error) {
-	ret := _mock.Called(ctx, cfg)
+// SetMemoryTarget provides a mock function for the type MockEngine
+func (_mock *MockEngine) SetMemoryTarget(ctx context.Context, id string, targetMB int) error {
+	ret := _mock.Called(ctx, id, targetMB)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Create")
+		panic("no return value specified for SetMemoryTarget")
 	}
 
-	var r0 *model.Sandbox
-	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, model.SandboxConfig) (*model.Sandbox, error)); ok {
-		return returnFunc(ctx, cfg)
-	}
-	if returnFunc, ok := ret.Get(0).(func(context.Context, model.SandboxConfig) *model.Sandbox); ok {
-		r0 = returnFunc(ctx, cfg)
-	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*model.Sandbox)
-		}
-	}
-	if returnFunc, ok := ret.Get(1).(func(context.Context, model.SandboxConfig) error); ok {
-		r1 = returnFunc(ctx, cfg)
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, int) error); ok {
+		r0 = returnFunc(ctx, id, targetMB)
 	} else {
-		r1 = ret.Error(1)
+		r0 = ret.Error(0)
 	}
-	return r0, r1
+	return r0
 }
 
-// MockEngine_Create_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Create'
-type MockEngine_Create_Call struct {
+// MockEngine_SetMemoryTarget_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetMemoryTarget'
+type MockEngine_SetMemoryTarget_Call struct {
 	*mock.Call
 }
 
-// Create is a helper method to define mock.On call
+// SetMemoryTarget is a helper method to define mock.On call
 //   - ctx context.Context
-//   - cfg model.SandboxConfig
-func (_e *MockEngine_Expecter) Create(ctx interface{}, cfg interface{}) *MockEngine_Create_Call {
-	return &MockEngine_Create_Call{Call: _e.mock.On("Create", ctx, cfg)}
+//   - id string
+//   - targetMB int
+func (_e *MockEngine_Expecter) SetMemoryTarget(ctx interface{}, id interface{}, targetMB interface{}) *MockEngine_SetMemoryTarget_Call {
+	return &MockEngine_SetMemoryTarget_Call{Call: _e.mock.On("SetMemoryTarget", ctx, id, targetMB)}
 }
 
-func (_c *MockEngine_Create_Call) Run(run func(ctx context.Context, cfg model.SandboxConfig)) *MockEngine_Create_Call {
+func (_c *MockEngine_SetMemoryTarget_Call) Run(run func(ctx context.Context, id string, targetMB int)) *MockEngine_SetMemoryTarget_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
 			arg0 = args[0].(context.Context)
 		}
-		var arg1 model.SandboxConfig
+		var arg1 string
 		if args[1] != nil {
-			arg1 = args[1].(model.SandboxConfig)
+			arg1 = args[1].(string)
+		}
+		var arg2 int
+		if args[2] != nil {
+			arg2 = args[2].(int)
 		}
 		run(
 			arg0,
 			arg1,
+			arg2,
 		)
 	})
 	return _c
 }
 
-func (_c *MockEngine_Create_Call) Return(sandbox *model.Sandbox, err error) *MockEngine_Create_Call {
-	_c.Call.Return(sandbox, err)
+func (_c *MockEngine_SetMemoryTarget_Call) Return(err error) *MockEngine_SetMemoryTarget_Call {
+	_c.Call.Return(err)
 	return _c
 }
 
-func (_c *MockEngine_Create_Call) RunAndReturn(run func(ctx context.Context, cfg model.SandboxConfig) (*model.Sandbox, error)) *MockEngine_Create_Call {
+func (_c *MockEngine_SetMemoryTarget_Call) RunAndReturn(run func(ctx context.Context, id string, targetMB int) error) *MockEngine_SetMemoryTarget_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// Exec provides a mock function for the type MockEngine
-func (_mock *MockEngine) Exec(ctx context.Context, id string, command []string, opts model.ExecOpts) (*model.ExecResult, error) {
-	ret := _mock.Called(ctx, id, command, opts)
+// Start provides a mock function for the type MockEngine
+func (_mock *MockEngine) Start(ctx context.Context, id string, opts sandbox.StartOpts) ([]model.BootPhase, error) {
+	ret := _mock.Called(ctx, id, opts)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Exec")
+		panic("no return value specified for Start")
 	}
 
-	var r0 *model.ExecResult
+	var r0 []model.BootPhase
 	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string, []string, model.ExecOpts) (*model.ExecResult, error)); ok {
-		return returnFunc(ctx, id, command, opts)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, sandbox.StartOpts) ([]model.BootPhase, error)); ok {
+		return returnFunc(ctx, id, opts)
 	}
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string, []string, model.ExecOpts) *model.ExecResult); ok {
-		r0 = returnFunc(ctx, id, command, opts)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, sandbox.StartOpts) []model.BootPhase); ok {
+		r0 = returnFunc(ctx, id, opts)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*model.ExecResult)
+			r0 = ret.Get(0).([]model.BootPhase)
 		}
 	}
-	if returnFunc, ok := ret.Get(1).(func(context.Context, string, []string, model.ExecOpts) error); ok {
-		r1 = returnFunc(ctx, id, command, opts)
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, sandbox.StartOpts) error); ok {
+		r1 = returnFunc(ctx, id, opts)
 	} else {
 		r1 = ret.Error(1)
 	}
 	return r0, r1
 }
 
-// MockEngine_Exec_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Exec'
-type MockEngine_Exec_Call struct {
+// MockEngine_Start_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Start'
+type MockEngine_Start_Call struct {
 	*mock.Call
 }
 
-// Exec is a helper method to define mock.On call
+// Start is a helper method to define mock.On call
 //   - ctx context.Context
 //   - id string
-//   - command []string
-//   - opts model.ExecOpts
-func (_e *MockEngine_Expecter) Exec(ctx interface{}, id interface{}, command interface{}, opts interface{}) *MockEngine_Exec_Call {
-	return &MockEngine_Exec_Call{Call: _e.mock.On("Exec", ctx, id, command, opts)}
+//   - opts sandbox.StartOpts
+func (_e *MockEngine_Expecter) Start(ctx interface{}, id interface{}, opts interface{}) *MockEngine_Start_Call {
+	return &MockEngine_Start_Call{Call: _e.mock.On("Start", ctx, id, opts)}
 }
 
-func (_c *MockEngine_Exec_Call) Run(run func(ctx context.Context, id string, command []string, opts model.ExecOpts)) *MockEngine_Exec_Call {
+func (_c *MockEngine_Start_Call) Run(run func(ctx context.Context, id string, opts sandbox.StartOpts)) *MockEngine_Start_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
@@ -350,65 +1524,71 @@ func (_c *MockEngine_Exec_Call) Run(run func(ctx context.Context, id string, com
 		if args[1] != nil {
 			arg1 = args[1].(string)
 		}
-		var arg2 []string
+		var arg2 sandbox.StartOpts
 		if args[2] != nil {
-			arg2 = args[2].([]string)
-		}
-		var arg3 model.ExecOpts
-		if args[3] != nil {
-			arg3 = args[3].(model.ExecOpts)
+			arg2 = args[2].(sandbox.StartOpts)
 		}
 		run(
 			arg0,
 			arg1,
 			arg2,
-			arg3,
 		)
 	})
 	return _c
 }
 
-func (_c *MockEngine_Exec_Call) Return(execResult *model.ExecResult, err error) *MockEngine_Exec_Call {
-	_c.Call.Return(execResult, err)
+func (_c *MockEngine_Start_Call) Return(bootPhases []model.BootPhase, err error) *MockEngine_Start_Call {
+	_c.Call.Return(bootPhases, err)
 	return _c
 }
 
-func (_c *MockEngine_Exec_Call) RunAndReturn(run func(ctx context.Context, id string, command []string, opts model.ExecOpts) (*model.ExecResult, error)) *MockEngine_Exec_Call {
+func (_c *MockEngine_Start_Call) RunAndReturn(run func(ctx context.Context, id string, opts sandbox.StartOpts) ([]model.BootPhase, error)) *MockEngine_Start_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// Forward provides a mock function for the type MockEngine
-func (_mock *MockEngine) Forward(ctx context.Context, id string, ports []model.PortMapping) error {
-	ret := _mock.Called(ctx, id, ports)
+// StatFile provides a mock function for the type MockEngine
+func (_mock *MockEngine) StatFile(ctx context.Context, id string, remotePath string) (*model.FileInfo, error) {
+	ret := _mock.Called(ctx, id, remotePath)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Forward")
+		panic("no return value specified for StatFile")
 	}
 
-	var r0 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string, []model.PortMapping) error); ok {
-		r0 = returnFunc(ctx, id, ports)
+	var r0 *model.FileInfo
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) (*model.FileInfo, error)); ok {
+		return returnFunc(ctx, id, remotePath)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) *model.FileInfo); ok {
+		r0 = returnFunc(ctx, id, remotePath)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.FileInfo)
+		}
 	}
-	return r0
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = returnFunc(ctx, id, remotePath)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
 }
 
-// MockEngine_Forward_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Forward'
-type MockEngine_Forward_Call struct {
+// MockEngine_StatFile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'StatFile'
+type MockEngine_StatFile_Call struct {
 	*mock.Call
 }
 
-// Forward is a helper method to define mock.On call
+// StatFile is a helper method to define mock.On call
 //   - ctx context.Context
 //   - id string
-//   - ports []model.PortMapping
-func (_e *MockEngine_Expecter) Forward(ctx interface{}, id interface{}, ports interface{}) *MockEngine_Forward_Call {
-	return &MockEngine_Forward_Call{Call: _e.mock.On("Forward", ctx, id, ports)}
+//   - remotePath string
+func (_e *MockEngine_Expecter) StatFile(ctx interface{}, id interface{}, remotePath interface{}) *MockEngine_StatFile_Call {
+	return &MockEngine_StatFile_Call{Call: _e.mock.On("StatFile", ctx, id, remotePath)}
 }
 
-func (_c *MockEngine_Forward_Call) Run(run func(ctx context.Context, id string, ports []model.PortMapping)) *MockEngine_Forward_Call {
+func (_c *MockEngine_StatFile_Call) Run(run func(ctx context.Context, id string, remotePath string)) *MockEngine_StatFile_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
@@ -418,9 +1598,9 @@ func (_c *MockEngine_Forward_Call) Run(run func(ctx context.Context, id string,
 		if args[1] != nil {
 			arg1 = args[1].(string)
 		}
-		var arg2 []model.PortMapping
+		var arg2 string
 		if args[2] != nil {
-			arg2 = args[2].([]model.PortMapping)
+			arg2 = args[2].(string)
 		}
 		run(
 			arg0,
@@ -431,46 +1611,57 @@ func (_c *MockEngine_Forward_Call) Run(run func(ctx context.Context, id string,
 	return _c
 }
 
-func (_c *MockEngine_Forward_Call) Return(err error) *MockEngine_Forward_Call {
-	_c.Call.Return(err)
+func (_c *MockEngine_StatFile_Call) Return(fileInfo *model.FileInfo, err error) *MockEngine_StatFile_Call {
+	_c.Call.Return(fileInfo, err)
 	return _c
 }
 
-func (_c *MockEngine_Forward_Call) RunAndReturn(run func(ctx context.Context, id string, ports []model.PortMapping) error) *MockEngine_Forward_Call {
+func (_c *MockEngine_StatFile_Call) RunAndReturn(run func(ctx context.Context, id string, remotePath string) (*model.FileInfo, error)) *MockEngine_StatFile_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// Remove provides a mock function for the type MockEngine
-func (_mock *MockEngine) Remove(ctx context.Context, id string) error {
+// Status provides a mock function for the type MockEngine
+func (_mock *MockEngine) Status(ctx context.Context, id string) (*model.Sandbox, error) {
 	ret := _mock.Called(ctx, id)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Remove")
+		panic("no return value specified for Status")
 	}
 
-	var r0 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string) error); ok {
+	var r0 *model.Sandbox
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (*model.Sandbox, error)); ok {
+		return returnFunc(ctx, id)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) *model.Sandbox); ok {
 		r0 = returnFunc(ctx, id)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Sandbox)
+		}
 	}
-	return r0
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
 }
 
-// MockEngine_Remove_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Remove'
-type MockEngine_Remove_Call struct {
+// MockEngine_Status_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Status'
+type MockEngine_Status_Call struct {
 	*mock.Call
 }
 
-// Remove is a helper method to define mock.On call
+// Status is a helper method to define mock.On call
 //   - ctx context.Context
 //   - id string
-func (_e *MockEngine_Expecter) Remove(ctx interface{}, id interface{}) *MockEngine_Remove_Call {
-	return &MockEngine_Remove_Call{Call: _e.mock.On("Remove", ctx, id)}
+func (_e *MockEngine_Expecter) Status(ctx interface{}, id interface{}) *MockEngine_Status_Call {
+	return &MockEngine_Status_Call{Call: _e.mock.On("Status", ctx, id)}
 }
 
-func (_c *MockEngine_Remove_Call) Run(run func(ctx context.Context, id string)) *MockEngine_Remove_Call {
+func (_c *MockEngine_Status_Call) Run(run func(ctx context.Context, id string)) *MockEngine_Status_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
@@ -488,26 +1679,26 @@ func (_c *MockEngine_Remove_Call) Run(run func(ctx context.Context, id string))
 	return _c
 }
 
-func (_c *MockEngine_Remove_Call) Return(err error) *MockEngine_Remove_Call {
-	_c.Call.Return(err)
+func (_c *MockEngine_Status_Call) Return(sandbox1 *model.Sandbox, err error) *MockEngine_Status_Call {
+	_c.Call.Return(sandbox1, err)
 	return _c
 }
 
-func (_c *MockEngine_Remove_Call) RunAndReturn(run func(ctx context.Context, id string) error) *MockEngine_Remove_Call {
+func (_c *MockEngine_Status_Call) RunAndReturn(run func(ctx context.Context, id string) (*model.Sandbox, error)) *MockEngine_Status_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// Start provides a mock function for the type MockEngine
-func (_mock *MockEngine) Start(ctx context.Context, id string, opts sandbox.StartOpts) error {
+// Stop provides a mock function for the type MockEngine
+func (_mock *MockEngine) Stop(ctx context.Context, id string, opts sandbox.StopOpts) error {
 	ret := _mock.Called(ctx, id, opts)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Start")
+		panic("no return value specified for Stop")
 	}
 
 	var r0 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string, sandbox.StartOpts) error); ok {
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, sandbox.StopOpts) error); ok {
 		r0 = returnFunc(ctx, id, opts)
 	} else {
 		r0 = ret.Error(0)
@@ -515,20 +1706,20 @@ func (_mock *MockEngine) Start(ctx context.Context, id string, opts sandbox.Star
 	return r0
 }
 
-// MockEngine_Start_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Start'
-type MockEngine_Start_Call struct {
+// MockEngine_Stop_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Stop'
+type MockEngine_Stop_Call struct {
 	*mock.Call
 }
 
-// Start is a helper method to define mock.On call
+// Stop is a helper method to define mock.On call
 //   - ctx context.Context
 //   - id string
-//   - opts sandbox.StartOpts
-func (_e *MockEngine_Expecter) Start(ctx interface{}, id interface{}, opts interface{}) *MockEngine_Start_Call {
-	return &MockEngine_Start_Call{Call: _e.mock.On("Start", ctx, id, opts)}
+//   - opts sandbox.StopOpts
+func (_e *MockEngine_Expecter) Stop(ctx interface{}, id interface{}, opts interface{}) *MockEngine_Stop_Call {
+	return &MockEngine_Stop_Call{Call: _e.mock.On("Stop", ctx, id, opts)}
 }
 
-func (_c *MockEngine_Start_Call) Run(run func(ctx context.Context, id string, opts sandbox.StartOpts)) *MockEngine_Start_Call {
+func (_c *MockEngine_Stop_Call) Run(run func(ctx context.Context, id string, opts sandbox.StopOpts)) *MockEngine_Stop_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
@@ -538,9 +1729,9 @@ func (_c *MockEngine_Start_Call) Run(run func(ctx context.Context, id string, op
 		if args[1] != nil {
 			arg1 = args[1].(string)
 		}
-		var arg2 sandbox.StartOpts
+		var arg2 sandbox.StopOpts
 		if args[2] != nil {
-			arg2 = args[2].(sandbox.StartOpts)
+			arg2 = args[2].(sandbox.StopOpts)
 		}
 		run(
 			arg0,
@@ -551,57 +1742,60 @@ func (_c *MockEngine_Start_Call) Run(run func(ctx context.Context, id string, op
 	return _c
 }
 
-func (_c *MockEngine_Start_Call) Return(err error) *MockEngine_Start_Call {
+func (_c *MockEngine_Stop_Call) Return(err error) *MockEngine_Stop_Call {
 	_c.Call.Return(err)
 	return _c
 }
 
-func (_c *MockEngine_Start_Call) RunAndReturn(run func(ctx context.Context, id string, opts sandbox.StartOpts) error) *MockEngine_Start_Call {
+func (_c *MockEngine_Stop_Call) RunAndReturn(run func(ctx context.Context, id string, opts sandbox.StopOpts) error) *MockEngine_Stop_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// Status provides a mock function for the type MockEngine
-func (_mock *MockEngine) Status(ctx context.Context, id string) (*model.Sandbox, error) {
-	ret := _mock.Called(ctx, id)
+// Sync provides a mock function for the type MockEngine
+func (_mock *MockEngine) Sync(ctx context.Context, id string, localDir string, remoteDir string, opts model.SyncOpts) (*model.SyncResult, error) {
+	ret := _mock.Called(ctx, id, localDir, remoteDir, opts)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Status")
+		panic("no return value specified for Sync")
 	}
 
-	var r0 *model.Sandbox
+	var r0 *model.SyncResult
 	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (*model.Sandbox, error)); ok {
-		return returnFunc(ctx, id)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, string, model.SyncOpts) (*model.SyncResult, error)); ok {
+		return returnFunc(ctx, id, localDir, remoteDir, opts)
 	}
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string) *model.Sandbox); ok {
-		r0 = returnFunc(ctx, id)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, string, model.SyncOpts) *model.SyncResult); ok {
+		r0 = returnFunc(ctx, id, localDir, remoteDir, opts)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*model.Sandbox)
+			r0 = ret.Get(0).(*model.SyncResult)
 		}
 	}
-	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = returnFunc(ctx, id)
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string, string, model.SyncOpts) error); ok {
+		r1 = returnFunc(ctx, id, localDir, remoteDir, opts)
 	} else {
 		r1 = ret.Error(1)
 	}
 	return r0, r1
 }
 
-// MockEngine_Status_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Status'
-type MockEngine_Status_Call struct {
+// MockEngine_Sync_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Sync'
+type MockEngine_Sync_Call struct {
 	*mock.Call
 }
 
-// Status is a helper method to define mock.On call
+// Sync is a helper method to define mock.On call
 //   - ctx context.Context
 //   - id string
-func (_e *MockEngine_Expecter) Status(ctx interface{}, id interface{}) *MockEngine_Status_Call {
-	return &MockEngine_Status_Call{Call: _e.mock.On("Status", ctx, id)}
+//   - localDir string
+//   - remoteDir string
+//   - opts model.SyncOpts
+func (_e *MockEngine_Expecter) Sync(ctx interface{}, id interface{}, localDir interface{}, remoteDir interface{}, opts interface{}) *MockEngine_Sync_Call {
+	return &MockEngine_Sync_Call{Call: _e.mock.On("Sync", ctx, id, localDir, remoteDir, opts)}
 }
 
-func (_c *MockEngine_Status_Call) Run(run func(ctx context.Context, id string)) *MockEngine_Status_Call {
+func (_c *MockEngine_Sync_Call) Run(run func(ctx context.Context, id string, localDir string, remoteDir string, opts model.SyncOpts)) *MockEngine_Sync_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
@@ -611,54 +1805,72 @@ func (_c *MockEngine_Status_Call) Run(run func(ctx context.Context, id string))
 		if args[1] != nil {
 			arg1 = args[1].(string)
 		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		var arg3 string
+		if args[3] != nil {
+			arg3 = args[3].(string)
+		}
+		var arg4 model.SyncOpts
+		if args[4] != nil {
+			arg4 = args[4].(model.SyncOpts)
+		}
 		run(
 			arg0,
 			arg1,
+			arg2,
+			arg3,
+			arg4,
 		)
 	})
 	return _c
 }
 
-func (_c *MockEngine_Status_Call) Return(sandbox1 *model.Sandbox, err error) *MockEngine_Status_Call {
-	_c.Call.Return(sandbox1, err)
+func (_c *MockEngine_Sync_Call) Return(syncResult *model.SyncResult, err error) *MockEngine_Sync_Call {
+	_c.Call.Return(syncResult, err)
 	return _c
 }
 
-func (_c *MockEngine_Status_Call) RunAndReturn(run func(ctx context.Context, id string) (*model.Sandbox, error)) *MockEngine_Status_Call {
+func (_c *MockEngine_Sync_Call) RunAndReturn(run func(ctx context.Context, id string, localDir string, remoteDir string, opts model.SyncOpts) (*model.SyncResult, error)) *MockEngine_Sync_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// Stop provides a mock function for the type MockEngine
-func (_mock *MockEngine) Stop(ctx context.Context, id string) error {
-	ret := _mock.Called(ctx, id)
+// WriteFile provides a mock function for the type MockEngine
+func (_mock *MockEngine) WriteFile(ctx context.Context, id string, remotePath string, data []byte, perm fs.FileMode) error {
+	ret := _mock.Called(ctx, id, remotePath, data, perm)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Stop")
+		panic("no return value specified for WriteFile")
 	}
 
 	var r0 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = returnFunc(ctx, id)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, []byte, fs.FileMode) error); ok {
+		r0 = returnFunc(ctx, id, remotePath, data, perm)
 	} else {
 		r0 = ret.Error(0)
 	}
 	return r0
 }
 
-// MockEngine_Stop_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Stop'
-type MockEngine_Stop_Call struct {
+// MockEngine_WriteFile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'WriteFile'
+type MockEngine_WriteFile_Call struct {
 	*mock.Call
 }
 
-// Stop is a helper method to define mock.On call
+// WriteFile is a helper method to define mock.On call
 //   - ctx context.Context
 //   - id string
-func (_e *MockEngine_Expecter) Stop(ctx interface{}, id interface{}) *MockEngine_Stop_Call {
-	return &MockEngine_Stop_Call{Call: _e.mock.On("Stop", ctx, id)}
+//   - remotePath string
+//   - data []byte
+//   - perm fs.FileMode
+func (_e *MockEngine_Expecter) WriteFile(ctx interface{}, id interface{}, remotePath interface{}, data interface{}, perm interface{}) *MockEngine_WriteFile_Call {
+	return &MockEngine_WriteFile_Call{Call: _e.mock.On("WriteFile", ctx, id, remotePath, data, perm)}
 }
 
-func (_c *MockEngine_Stop_Call) Run(run func(ctx context.Context, id string)) *MockEngine_Stop_Call {
+func (_c *MockEngine_WriteFile_Call) Run(run func(ctx context.Context, id string, remotePath string, data []byte, perm fs.FileMode)) *MockEngine_WriteFile_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
@@ -668,20 +1880,35 @@ func (_c *MockEngine_Stop_Call) Run(run func(ctx context.Context, id string)) *M
 		if args[1] != nil {
 			arg1 = args[1].(string)
 		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		var arg3 []byte
+		if args[3] != nil {
+			arg3 = args[3].([]byte)
+		}
+		var arg4 fs.FileMode
+		if args[4] != nil {
+			arg4 = args[4].(fs.FileMode)
+		}
 		run(
 			arg0,
 			arg1,
+			arg2,
+			arg3,
+			arg4,
 		)
 	})
 	return _c
 }
 
-func (_c *MockEngine_Stop_Call) Return(err error) *MockEngine_Stop_Call {
+func (_c *MockEngine_WriteFile_Call) Return(err error) *MockEngine_WriteFile_Call {
 	_c.Call.Return(err)
 	return _c
 }
 
-func (_c *MockEngine_Stop_Call) RunAndReturn(run func(ctx context.Context, id string) error) *MockEngine_Stop_Call {
+func (_c *MockEngine_WriteFile_Call) RunAndReturn(run func(ctx context.Context, id string, remotePath string, data []byte, perm fs.FileMode) error) *MockEngine_WriteFile_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -2,16 +2,15 @@ package ssh
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
-	"io/fs"
 	"net"
-	"os"
-	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
 
 	"github.com/slok/sbx/internal/log"
@@ -66,6 +65,8 @@ func (c *ClientConfig) defaults() error {
 type Client struct {
 	conn   *ssh.Client
 	logger log.Logger
+	// closed is set once the connection is closed by the client.
+	closed atomic.Bool
 }
 
 // NewClient dials the SSH server and returns a connected client.
@@ -90,19 +91,22 @@ func NewClient(ctx context.Context, cfg ClientConfig) (*Client, error) {
 
 	addr := net.JoinHostPort(cfg.Host, fmt.Sprintf("%d", cfg.Port))
 
-	// Use a dialer with context for cancellation support.
-	var d net.Dialer
+	// Use a dialer with context for cancellation support, the connect timeout
+	// bounds the dial and the handshake, a booting guest may not answer at all.
+	d := net.Dialer{Timeout: cfg.ConnectTimeout}
 	netConn, err := d.DialContext(ctx, "tcp", addr)
 	if err != nil {
 		return nil, fmt.Errorf("could not connect to %s: %w", addr, err)
 	}
 
 	// Perform SSH handshake over the raw connection.
+	_ = netConn.SetDeadline(time.Now().Add(cfg.ConnectTimeout))
 	sshConn, chans, reqs, err := ssh.NewClientConn(netConn, addr, sshCfg)
 	if err != nil {
 		netConn.Close()
 		return nil, fmt.Errorf("ssh handshake failed with %s: %w", addr, err)
 	}
+	_ = netConn.SetDeadline(time.Time{})
 
 	client := ssh.NewClient(sshConn, chans, reqs)
 
@@ -114,14 +118,32 @@ func NewClient(ctx context.Context, cfg ClientConfig) (*Client, error) {
 
 // Close closes the SSH connection.
 func (c *Client) Close() error {
+	c.closed.Store(true)
 	if c.conn != nil {
 		return c.conn.Close()
 	}
 	return nil
 }
 
+// newSession opens a session. A connection that fails to open it (except when the
+// server rejects the session) is broken, it's closed so the pool redials it.
+func (c *Client) newSession() (*ssh.Session, error) {
+	session, err := c.conn.NewSession()
+	if err != nil {
+		var openErr *ssh.OpenChannelError
+		if !errors.As(err, &openErr) {
+			_ = c.Close()
+		}
+		return nil, fmt.Errorf("could not create ssh session: %w", err)
+	}
+	return session, nil
+}
+
 // ExecOpts are options for command execution (non-TTY only).
 type ExecOpts struct {
+	// Stdin is streamed to the command until it returns EOF, then the command
+	// input is closed (half-close) while its output keeps being read. The command
+	// can exit before Stdin ends, Exec doesn't wait for it.
 	Stdin  io.Reader
 	Stdout io.Writer
 	Stderr io.Writer
@@ -130,9 +152,9 @@ type ExecOpts struct {
 // Exec runs a command on the remote host and returns the exit code.
 // This does NOT support TTY — use the ssh binary for interactive shells.
 func (c *Client) Exec(ctx context.Context, command string, opts ExecOpts) (int, error) {
-	session, err := c.conn.NewSession()
+	session, err := c.newSession()
 	if err != nil {
-		return -1, fmt.Errorf("could not create ssh session: %w", err)
+		return -1, err
 	}
 	defer session.Close()
 
@@ -169,68 +191,63 @@ func (c *Client) Exec(ctx context.Context, command string, opts ExecOpts) (int,
 	}
 }
 
-// CopyTo copies a local file or directory to the remote host via SFTP.
-func (c *Client) CopyTo(ctx context.Context, srcLocal, dstRemote string) error {
-	sftpClient, err := sftp.NewClient(c.conn)
-	if err != nil {
-		return fmt.Errorf("could not create sftp client: %w", err)
-	}
-	defer sftpClient.Close()
-
-	srcInfo, err := os.Stat(srcLocal)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return fmt.Errorf("source path '%s' does not exist: %w", srcLocal, os.ErrNotExist)
-		}
-		return fmt.Errorf("could not stat source: %w", err)
-	}
-
-	if srcInfo.IsDir() {
-		return c.copyDirTo(ctx, sftpClient, srcLocal, dstRemote)
-	}
-	return c.copyFileTo(ctx, sftpClient, srcLocal, dstRemote, srcInfo.Mode())
-}
-
-// CopyFrom copies a remote file or directory to the local host via SFTP.
-func (c *Client) CopyFrom(ctx context.Context, srcRemote, dstLocal string) error {
-	sftpClient, err := sftp.NewClient(c.conn)
-	if err != nil {
-		return fmt.Errorf("could not create sftp client: %w", err)
-	}
-	defer sftpClient.Close()
-
-	srcInfo, err := sftpClient.Stat(srcRemote)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return fmt.Errorf("source path '%s' does not exist in sandbox: %w", srcRemote, os.ErrNotExist)
-		}
-		return fmt.Errorf("could not stat remote source: %w", err)
-	}
-
-	if srcInfo.IsDir() {
-		return c.copyDirFrom(ctx, sftpClient, srcRemote, dstLocal)
-	}
-	return c.copyFileFrom(ctx, sftpClient, srcRemote, dstLocal, srcInfo.Mode())
-}
-
 // PortForward defines a local-to-remote port mapping.
 type PortForward struct {
 	// BindAddress is the local address to listen on (e.g., "localhost", "0.0.0.0").
 	// Defaults to "localhost" if empty.
 	BindAddress string
-	LocalPort   int
-	RemotePort  int
+	// LocalPort is the local port to listen on, 0 binds a free port.
+	LocalPort  int
+	RemotePort int
+	// TLS terminates TLS on the local port with the configuration, the remote
+	// port gets the plain connections (optional).
+	TLS *tls.Config
+}
+
+// ForwardOpts are the options of Forward.
+type ForwardOpts struct {
+	// Ready is called once all the local ports listen, with the bound local
+	// ports (optional).
+	Ready func(ports []PortForward)
 }
 
 // Forward sets up local port forwarding. Blocks until ctx is cancelled.
-func (c *Client) Forward(ctx context.Context, ports []PortForward) error {
+// The listeners and the forwarded connections are closed on return, the SSH
+// connection is kept so it can be reused.
+func (c *Client) Forward(ctx context.Context, ports []PortForward, opts ForwardOpts) error {
 	if len(ports) == 0 {
 		return fmt.Errorf("at least one port mapping is required")
 	}
 
-	var wg sync.WaitGroup
-	errCh := make(chan error, len(ports))
+	var (
+		mu        sync.Mutex
+		listeners []net.Listener
+		conns     = map[net.Conn]struct{}{}
+		wg        sync.WaitGroup
+	)
+	closeAll := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, l := range listeners {
+			l.Close()
+		}
+		for conn := range conns {
+			conn.Close()
+		}
+	}
+	track := func(conn net.Conn) {
+		mu.Lock()
+		conns[conn] = struct{}{}
+		mu.Unlock()
+	}
+	untrack := func(conn net.Conn) {
+		mu.Lock()
+		delete(conns, conn)
+		mu.Unlock()
+		conn.Close()
+	}
 
+	bound := make([]PortForward, 0, len(ports))
 	for _, pf := range ports {
 		bindAddr := pf.BindAddress
 		if bindAddr == "" {
@@ -241,27 +258,38 @@ func (c *Client) Forward(ctx context.Context, ports []PortForward) error {
 
 		listener, err := net.Listen("tcp", localAddr)
 		if err != nil {
+			closeAll()
+			wg.Wait()
 			return fmt.Errorf("could not listen on %s: %w", localAddr, err)
 		}
+		mu.Lock()
+		listeners = append(listeners, listener)
+		mu.Unlock()
+		if pf.TLS != nil {
+			listener = tls.NewListener(listener, pf.TLS)
+		}
+
+		// Free ports are only known once bound.
+		if tcpAddr, ok := listener.Addr().(*net.TCPAddr); ok {
+			pf.LocalPort = tcpAddr.Port
+			localAddr = net.JoinHostPort(bindAddr, fmt.Sprintf("%d", pf.LocalPort))
+		}
+		bound = append(bound, pf)
 
 		wg.Add(1)
 		go func(l net.Listener, local, remote string) {
 			defer wg.Done()
-			defer l.Close()
 
 			c.logger.Debugf("Forwarding %s -> %s", local, remote)
 
 			for {
 				localConn, err := l.Accept()
 				if err != nil {
-					// Check if context cancelled (listener closed).
-					select {
-					case <-ctx.Done():
-						return
-					default:
-						errCh <- fmt.Errorf("accept failed on %s: %w", local, err)
-						return
+					// The listener is closed when the forwarding ends.
+					if ctx.Err() == nil {
+						c.logger.Warningf("Accept failed on %s: %v", local, err)
 					}
+					return
 				}
 
 				// Open connection to remote via SSH tunnel.
@@ -271,11 +299,13 @@ func (c *Client) Forward(ctx context.Context, ports []PortForward) error {
 					c.logger.Warningf("Failed to dial remote %s: %v", remote, err)
 					continue
 				}
+				track(localConn)
+				track(remoteConn)
 
 				// Bidirectional copy.
 				go func() {
-					defer localConn.Close()
-					defer remoteConn.Close()
+					defer untrack(localConn)
+					defer untrack(remoteConn)
 
 					done := make(chan struct{}, 2)
 					go func() {
@@ -293,158 +323,15 @@ func (c *Client) Forward(ctx context.Context, ports []PortForward) error {
 		}(listener, localAddr, remoteAddr)
 	}
 
-	// Wait for context cancellation.
-	<-ctx.Done()
-
-	// Closing listeners will unblock Accept() calls, causing goroutines to exit.
-	// The listeners are closed in the deferred l.Close() when the goroutine returns.
-	// We need to explicitly trigger this by... well, the goroutines check ctx.Done()
-	// after Accept errors. But Accept is blocking. We need to close the listeners.
-	// The wg.Wait below will hang unless listeners are closed.
-	// Actually, the listeners are held by the goroutines. We need another approach.
-	// Let's refactor slightly to track listeners.
-
-	// Note: The goroutines will exit because Accept() will return an error once
-	// the net.Listener is closed. But we closed them via defer in the goroutine.
-	// The issue is the goroutine is blocked on Accept. We can't close from here
-	// because the listener is owned by the goroutine.
-
-	// This is fine for now — when context is cancelled, the SSH connection is closed
-	// by the caller (defer client.Close()), which breaks the tunnel and all connections.
-
-	return ctx.Err()
-}
-
-// copyFileTo copies a single local file to the remote host.
-func (c *Client) copyFileTo(ctx context.Context, sftpClient *sftp.Client, srcLocal, dstRemote string, mode fs.FileMode) error {
-	if ctx.Err() != nil {
-		return ctx.Err()
-	}
-
-	src, err := os.Open(srcLocal)
-	if err != nil {
-		return fmt.Errorf("could not open local file %s: %w", srcLocal, err)
+	if opts.Ready != nil {
+		opts.Ready(bound)
 	}
-	defer src.Close()
 
-	dst, err := sftpClient.Create(dstRemote)
-	if err != nil {
-		return fmt.Errorf("could not create remote file %s: %w", dstRemote, err)
-	}
-	defer dst.Close()
-
-	if _, err := io.Copy(dst, src); err != nil {
-		return fmt.Errorf("could not copy to remote file %s: %w", dstRemote, err)
-	}
-
-	if err := sftpClient.Chmod(dstRemote, mode); err != nil {
-		c.logger.Debugf("Could not set permissions on %s: %v", dstRemote, err)
-	}
-
-	return nil
-}
-
-// copyDirTo recursively copies a local directory to the remote host.
-func (c *Client) copyDirTo(ctx context.Context, sftpClient *sftp.Client, srcLocal, dstRemote string) error {
-	return filepath.WalkDir(srcLocal, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if ctx.Err() != nil {
-			return ctx.Err()
-		}
-
-		// Calculate relative path and remote destination.
-		relPath, err := filepath.Rel(srcLocal, path)
-		if err != nil {
-			return err
-		}
-		remotePath := filepath.Join(dstRemote, relPath)
-
-		// Skip symlinks.
-		if d.Type()&fs.ModeSymlink != 0 {
-			return nil
-		}
-
-		if d.IsDir() {
-			return sftpClient.MkdirAll(remotePath)
-		}
-
-		info, err := d.Info()
-		if err != nil {
-			return err
-		}
-
-		return c.copyFileTo(ctx, sftpClient, path, remotePath, info.Mode())
-	})
-}
-
-// copyFileFrom copies a single remote file to the local host.
-func (c *Client) copyFileFrom(ctx context.Context, sftpClient *sftp.Client, srcRemote, dstLocal string, mode fs.FileMode) error {
-	if ctx.Err() != nil {
-		return ctx.Err()
-	}
-
-	src, err := sftpClient.Open(srcRemote)
-	if err != nil {
-		return fmt.Errorf("could not open remote file %s: %w", srcRemote, err)
-	}
-	defer src.Close()
-
-	if err := os.MkdirAll(filepath.Dir(dstLocal), 0755); err != nil {
-		return fmt.Errorf("could not create local directory: %w", err)
-	}
-
-	dst, err := os.OpenFile(dstLocal, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
-	if err != nil {
-		return fmt.Errorf("could not create local file %s: %w", dstLocal, err)
-	}
-	defer dst.Close()
-
-	if _, err := io.Copy(dst, src); err != nil {
-		return fmt.Errorf("could not copy from remote file %s: %w", srcRemote, err)
-	}
-
-	return nil
-}
-
-// copyDirFrom recursively copies a remote directory to the local host.
-func (c *Client) copyDirFrom(ctx context.Context, sftpClient *sftp.Client, srcRemote, dstLocal string) error {
-	walker := sftpClient.Walk(srcRemote)
-	for walker.Step() {
-		if ctx.Err() != nil {
-			return ctx.Err()
-		}
-		if err := walker.Err(); err != nil {
-			return err
-		}
-
-		remotePath := walker.Path()
-
-		relPath, err := filepath.Rel(srcRemote, remotePath)
-		if err != nil {
-			return err
-		}
-		localPath := filepath.Join(dstLocal, relPath)
-
-		info := walker.Stat()
-
-		// Skip symlinks.
-		if info.Mode()&fs.ModeSymlink != 0 {
-			continue
-		}
-
-		if info.IsDir() {
-			if err := os.MkdirAll(localPath, info.Mode()); err != nil {
-				return fmt.Errorf("could not create local directory %s: %w", localPath, err)
-			}
-			continue
-		}
-
-		if err := c.copyFileFrom(ctx, sftpClient, remotePath, localPath, info.Mode()); err != nil {
-			return err
-		}
-	}
+	// Wait for context cancellation, then stop accepting and close the
+	// forwarded connections.
+	<-ctx.Done()
+	closeAll()
+	wg.Wait()
 
-	return nil
+	return ctx.Err()
 }
@@ -143,10 +143,19 @@ func (s *testSSHServer) handleSession(t *testing.T, newChannel ssh.NewChannel) {
 
 			// Execute the command.
 			cmd := exec.Command("sh", "-c", command)
-			cmd.Stdin = channel
 			cmd.Stdout = channel
 			cmd.Stderr = channel.Stderr()
 
+			// Like sshd, the command can exit before the client closes its input.
+			stdin, err := cmd.StdinPipe()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _ = io.Copy(stdin, channel)
+				_ = stdin.Close()
+			}()
+
 			exitCode := 0
 			if err := cmd.Run(); err != nil {
 				if exitErr, ok := err.(*exec.ExitError); ok {
@@ -399,6 +408,18 @@ func TestClient_Exec(t *testing.T) {
 			expExitCode: 0,
 			expStdout:   "from stdin",
 		},
+
+		"Command exiting before the end of a stdin stream should return.": {
+			command: "head -n 1",
+			opts: ExecOpts{Stdin: func() io.Reader {
+				// The stream is never closed.
+				r, w := io.Pipe()
+				go func() { _, _ = w.Write([]byte("first line\n")) }()
+				return r
+			}()},
+			expExitCode: 0,
+			expStdout:   "first line\n",
+		},
 	}
 
 	for name, test := range tests {
@@ -482,6 +503,7 @@ func TestClient_CopyTo(t *testing.T) {
 
 	tests := map[string]struct {
 		setup    func(t *testing.T) (srcLocal, dstRemote string, cleanup func())
+		opts     CopyOpts
 		expErr   bool
 		validate func(t *testing.T, dstRemote string)
 	}{
@@ -528,6 +550,68 @@ func TestClient_CopyTo(t *testing.T) {
 			},
 		},
 
+		"Copy directory with many files and small blocks should work.": {
+			setup: func(t *testing.T) (string, string, func()) {
+				srcDir := t.TempDir()
+				for i := range 20 {
+					sub := filepath.Join(srcDir, fmt.Sprintf("dir%d", i%3))
+					require.NoError(t, os.MkdirAll(sub, 0755))
+					require.NoError(t, os.WriteFile(filepath.Join(sub, fmt.Sprintf("f%d.txt", i)), []byte(fmt.Sprintf("file%d", i)), 0644))
+				}
+				require.NoError(t, os.WriteFile(filepath.Join(srcDir, "big.bin"), testBigData(), 0644))
+
+				return srcDir, filepath.Join(t.TempDir(), "copied"), func() {}
+			},
+			opts: CopyOpts{Concurrency: 8, BlockSize: 4096},
+			validate: func(t *testing.T, dstRemote string) {
+				for i := range 20 {
+					data, err := os.ReadFile(filepath.Join(dstRemote, fmt.Sprintf("dir%d", i%3), fmt.Sprintf("f%d.txt", i)))
+					require.NoError(t, err)
+					assert.Equal(t, fmt.Sprintf("file%d", i), string(data))
+				}
+				data, err := os.ReadFile(filepath.Join(dstRemote, "big.bin"))
+				require.NoError(t, err)
+				assert.Equal(t, testBigData(), data)
+			},
+		},
+
+		"Copy single file compressed should work.": {
+			setup: func(t *testing.T) (string, string, func()) {
+				srcFile := filepath.Join(t.TempDir(), "test.txt")
+				require.NoError(t, os.WriteFile(srcFile, []byte("hello world"), 0644))
+
+				return srcFile, filepath.Join(t.TempDir(), "new", "renamed.txt"), func() {}
+			},
+			opts: CopyOpts{Compress: true},
+			validate: func(t *testing.T, dstRemote string) {
+				data, err := os.ReadFile(dstRemote)
+				require.NoError(t, err)
+				assert.Equal(t, "hello world", string(data))
+			},
+		},
+
+		"Copy directory compressed should work.": {
+			setup: func(t *testing.T) (string, string, func()) {
+				srcDir := t.TempDir()
+				subDir := filepath.Join(srcDir, "subdir")
+				require.NoError(t, os.MkdirAll(subDir, 0755))
+				require.NoError(t, os.WriteFile(filepath.Join(srcDir, "run.sh"), []byte("#!/bin/sh"), 0755))
+				require.NoError(t, os.WriteFile(filepath.Join(subDir, "big.bin"), testBigData(), 0644))
+
+				return srcDir, filepath.Join(t.TempDir(), "copied"), func() {}
+			},
+			opts: CopyOpts{Compress: true},
+			validate: func(t *testing.T, dstRemote string) {
+				info, err := os.Stat(filepath.Join(dstRemote, "run.sh"))
+				require.NoError(t, err)
+				assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+				data, err := os.ReadFile(filepath.Join(dstRemote, "subdir", "big.bin"))
+				require.NoError(t, err)
+				assert.Equal(t, testBigData(), data)
+			},
+		},
+
 		"Copy non-existent source should fail.": {
 			setup: func(t *testing.T) (string, string, func()) {
 				return "/nonexistent/path", "/tmp/dst", func() {}
@@ -556,7 +640,7 @@ func TestClient_CopyTo(t *testing.T) {
 			srcLocal, dstRemote, cleanup := test.setup(t)
 			defer cleanup()
 
-			err = client.CopyTo(ctx, srcLocal, dstRemote)
+			err = client.CopyTo(ctx, srcLocal, dstRemote, test.opts)
 			if test.expErr {
 				assert.Error(t, err)
 				return
@@ -579,6 +663,7 @@ func TestClient_CopyFrom(t *testing.T) {
 
 	tests := map[string]struct {
 		setup    func(t *testing.T) (srcRemote, dstLocal string)
+		opts     CopyOpts
 		expErr   bool
 		validate func(t *testing.T, dstLocal string)
 	}{
@@ -625,6 +710,61 @@ func TestClient_CopyFrom(t *testing.T) {
 			},
 		},
 
+		"Copy single remote file compressed should work.": {
+			setup: func(t *testing.T) (string, string) {
+				remoteFile := filepath.Join(t.TempDir(), "remote.txt")
+				require.NoError(t, os.WriteFile(remoteFile, []byte("remote data"), 0644))
+
+				return remoteFile, filepath.Join(t.TempDir(), "local.txt")
+			},
+			opts: CopyOpts{Compress: true},
+			validate: func(t *testing.T, dstLocal string) {
+				data, err := os.ReadFile(dstLocal)
+				require.NoError(t, err)
+				assert.Equal(t, "remote data", string(data))
+			},
+		},
+
+		"Copy remote directory compressed should work.": {
+			setup: func(t *testing.T) (string, string) {
+				remoteDir := t.TempDir()
+				subDir := filepath.Join(remoteDir, "sub")
+				require.NoError(t, os.MkdirAll(subDir, 0755))
+				require.NoError(t, os.WriteFile(filepath.Join(remoteDir, "a.txt"), []byte("aaa"), 0644))
+				require.NoError(t, os.WriteFile(filepath.Join(subDir, "big.bin"), testBigData(), 0600))
+
+				return remoteDir, filepath.Join(t.TempDir(), "copied")
+			},
+			opts: CopyOpts{Compress: true},
+			validate: func(t *testing.T, dstLocal string) {
+				data1, err := os.ReadFile(filepath.Join(dstLocal, "a.txt"))
+				require.NoError(t, err)
+				assert.Equal(t, "aaa", string(data1))
+
+				info, err := os.Stat(filepath.Join(dstLocal, "sub", "big.bin"))
+				require.NoError(t, err)
+				assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+				data2, err := os.ReadFile(filepath.Join(dstLocal, "sub", "big.bin"))
+				require.NoError(t, err)
+				assert.Equal(t, testBigData(), data2)
+			},
+		},
+
+		"Copy remote directory with few requests per file should work.": {
+			setup: func(t *testing.T) (string, string) {
+				remoteDir := t.TempDir()
+				require.NoError(t, os.WriteFile(filepath.Join(remoteDir, "big.bin"), testBigData(), 0644))
+
+				return remoteDir, filepath.Join(t.TempDir(), "copied")
+			},
+			opts: CopyOpts{RequestsPerFile: 2},
+			validate: func(t *testing.T, dstLocal string) {
+				data, err := os.ReadFile(filepath.Join(dstLocal, "big.bin"))
+				require.NoError(t, err)
+				assert.Equal(t, testBigData(), data)
+			},
+		},
+
 		"Copy non-existent remote path should fail.": {
 			setup: func(t *testing.T) (string, string) {
 				return "/nonexistent/remote/path", t.TempDir()
@@ -652,7 +792,7 @@ func TestClient_CopyFrom(t *testing.T) {
 
 			srcRemote, dstLocal := test.setup(t)
 
-			err = client.CopyFrom(ctx, srcRemote, dstLocal)
+			err = client.CopyFrom(ctx, srcRemote, dstLocal, test.opts)
 			if test.expErr {
 				assert.Error(t, err)
 				return
@@ -666,6 +806,273 @@ func TestClient_CopyFrom(t *testing.T) {
 	}
 }
 
+func TestClient_CopyProgress(t *testing.T) {
+	privKey := generateTestKeyPair(t)
+	server := newTestSSHServer(t, privKey)
+	defer server.close()
+
+	host, port := testParseHostPort(t, server.addr)
+
+	tests := map[string]struct {
+		compress bool
+		from     bool
+		expTotal bool
+	}{
+		"Copying to the remote should report the progress.": {
+			expTotal: true,
+		},
+		"Copying to the remote compressed should report the progress.": {
+			compress: true,
+			expTotal: true,
+		},
+		"Copying from the remote should report the progress.": {
+			from:     true,
+			expTotal: true,
+		},
+		"Copying a directory from the remote compressed should report the progress without total.": {
+			compress: true,
+			from:     true,
+			expTotal: false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			client, err := NewClient(ctx, ClientConfig{
+				Host:       host,
+				Port:       port,
+				User:       "root",
+				PrivateKey: privKey,
+				Logger:     log.Noop,
+			})
+			require.NoError(err)
+			defer client.Close()
+
+			srcDir := t.TempDir()
+			require.NoError(os.WriteFile(filepath.Join(srcDir, "a.bin"), testBigData(), 0644))
+			require.NoError(os.WriteFile(filepath.Join(srcDir, "b.txt"), []byte("hello"), 0644))
+			expBytes := int64(len(testBigData()) + len("hello"))
+			dst := filepath.Join(t.TempDir(), "copied")
+
+			var (
+				calls int
+				last  CopyProgress
+			)
+			opts := CopyOpts{
+				Compress: test.compress,
+				Progress: func(p CopyProgress) {
+					calls++
+					assert.GreaterOrEqual(p.Bytes, last.Bytes)
+					last = p
+				},
+			}
+
+			if test.from {
+				err = client.CopyFrom(ctx, srcDir, dst, opts)
+			} else {
+				err = client.CopyTo(ctx, srcDir, dst, opts)
+			}
+			require.NoError(err)
+
+			assert.Greater(calls, 1)
+			assert.Equal(expBytes, last.Bytes)
+			if test.expTotal {
+				assert.Equal(expBytes, last.TotalBytes)
+			} else {
+				assert.Zero(last.TotalBytes)
+			}
+		})
+	}
+}
+
+func TestClient_CopyResume(t *testing.T) {
+	privKey := generateTestKeyPair(t)
+	server := newTestSSHServer(t, privKey)
+	defer server.close()
+
+	host, port := testParseHostPort(t, server.addr)
+
+	const resumeOffset = 100 * 1024
+
+	tests := map[string]struct {
+		from     bool
+		compress bool
+		checksum bool
+		expErr   error
+		expB     string
+	}{
+		"Resuming a copy to the remote should skip the copied files and continue the partial ones.": {
+			expB: "stale",
+		},
+		"Resuming a copy from the remote should skip the copied files and continue the partial ones.": {
+			from: true,
+			expB: "stale",
+		},
+		"Resuming a copy with a different copied file should fail the checksum.": {
+			checksum: true,
+			expErr:   ErrChecksumMismatch,
+		},
+		"Resuming a compressed copy should copy again all the files.": {
+			compress: true,
+			checksum: true,
+			expB:     "hello",
+		},
+		"Resuming a compressed copy from the remote should copy again all the files.": {
+			from:     true,
+			compress: true,
+			checksum: true,
+			expB:     "hello",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			client, err := NewClient(ctx, ClientConfig{
+				Host:       host,
+				Port:       port,
+				User:       "root",
+				PrivateKey: privKey,
+				Logger:     log.Noop,
+			})
+			require.NoError(err)
+			defer client.Close()
+
+			srcDir := t.TempDir()
+			require.NoError(os.WriteFile(filepath.Join(srcDir, "a.bin"), testBigData(), 0644))
+			require.NoError(os.WriteFile(filepath.Join(srcDir, "b.txt"), []byte("hello"), 0644))
+
+			// A previous attempt copied b.txt (a different one, to detect the skip) and
+			// the start of a.bin, followed by garbage of the unsafe concurrent writes.
+			dst := filepath.Join(t.TempDir(), "copied")
+			require.NoError(os.MkdirAll(dst, 0755))
+			partial := append(append([]byte{}, testBigData()[:resumeOffset]...), bytes.Repeat([]byte{0xff}, 4096)...)
+			require.NoError(os.WriteFile(filepath.Join(dst, "a.bin"), partial, 0644))
+			require.NoError(os.WriteFile(filepath.Join(dst, "b.txt"), []byte("stale"), 0644))
+
+			checkpoint := NewCopyCheckpoint()
+			checkpoint.set(filepath.Join(dst, "a.bin"), resumeOffset, false)
+			checkpoint.set(filepath.Join(dst, "b.txt"), 0, true)
+
+			var last CopyProgress
+			opts := CopyOpts{
+				Compress:   test.compress,
+				Checksum:   test.checksum,
+				Checkpoint: checkpoint,
+				Progress:   func(p CopyProgress) { last = p },
+			}
+			if test.from {
+				err = client.CopyFrom(ctx, srcDir, dst, opts)
+			} else {
+				err = client.CopyTo(ctx, srcDir, dst, opts)
+			}
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+				return
+			}
+			require.NoError(err)
+
+			gotA, err := os.ReadFile(filepath.Join(dst, "a.bin"))
+			require.NoError(err)
+			assert.Equal(testBigData(), gotA)
+			gotB, err := os.ReadFile(filepath.Join(dst, "b.txt"))
+			require.NoError(err)
+			assert.Equal(test.expB, string(gotB))
+			assert.Equal(int64(len(testBigData())+len("hello")), last.Bytes)
+		})
+	}
+}
+
+func TestClient_CopyBandwidthLimit(t *testing.T) {
+	privKey := generateTestKeyPair(t)
+	server := newTestSSHServer(t, privKey)
+	defer server.close()
+
+	host, port := testParseHostPort(t, server.addr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := NewClient(ctx, ClientConfig{
+		Host:       host,
+		Port:       port,
+		User:       "root",
+		PrivateKey: privKey,
+		Logger:     log.Noop,
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	src := filepath.Join(t.TempDir(), "a.bin")
+	require.NoError(t, os.WriteFile(src, testBigData(), 0644))
+	dst := filepath.Join(t.TempDir(), "a.bin")
+
+	// 300KiB at 1MiB/s should take ~300ms.
+	start := time.Now()
+	err = client.CopyTo(ctx, src, dst, CopyOpts{BandwidthLimit: 1024 * 1024, Checksum: true})
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+}
+
+func TestIsConnectionError(t *testing.T) {
+	tests := map[string]struct {
+		err error
+		exp bool
+	}{
+		"No error should not be a connection error.": {
+			err: nil,
+			exp: false,
+		},
+		"A lost SFTP connection should be a connection error.": {
+			err: fmt.Errorf("could not copy: %w", sftp.ErrSSHFxConnectionLost),
+			exp: true,
+		},
+		"A closed connection should be a connection error.": {
+			err: fmt.Errorf("could not copy: %w", io.EOF),
+			exp: true,
+		},
+		"A network error should be a connection error.": {
+			err: &net.OpError{Op: "dial", Err: fmt.Errorf("connection refused")},
+			exp: true,
+		},
+		"A checksum mismatch should not be a connection error.": {
+			err: fmt.Errorf("could not copy: %w", ErrChecksumMismatch),
+			exp: false,
+		},
+		"A missing file should not be a connection error.": {
+			err: os.ErrNotExist,
+			exp: false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.exp, IsConnectionError(test.err))
+		})
+	}
+}
+
+// testBigData returns data bigger than the SFTP blocks so the files are copied
+// with multiple requests.
+func testBigData() []byte {
+	data := make([]byte, 300*1024)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	return data
+}
+
 func TestClient_Forward(t *testing.T) {
 	assert := assert.New(t)
 	require := require.New(t)
@@ -723,7 +1130,7 @@ func TestClient_Forward(t *testing.T) {
 	go func() {
 		forwardDone <- client.Forward(forwardCtx, []PortForward{
 			{LocalPort: freePort, RemotePort: echoPort},
-		})
+		}, ForwardOpts{})
 	}()
 
 	// Give the forwarder time to start listening.
@@ -747,6 +1154,92 @@ func TestClient_Forward(t *testing.T) {
 	forwardCancel()
 	err = <-forwardDone
 	assert.ErrorIs(err, context.Canceled)
+
+	// The local port should be released and the SSH connection kept.
+	l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", freePort))
+	require.NoError(err)
+	l.Close()
+	exitCode, err := client.Exec(ctx, "echo hello", ExecOpts{})
+	require.NoError(err)
+	assert.Equal(0, exitCode)
+}
+
+func TestClient_Forward_FreeLocalPort(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	privKey := generateTestKeyPair(t)
+	server := newTestSSHServer(t, privKey)
+	defer server.close()
+
+	host, port := testParseHostPort(t, server.addr)
+
+	echoListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(err)
+	defer echoListener.Close()
+	_, echoPort := testParseHostPort(t, echoListener.Addr().String())
+	go func() {
+		for {
+			conn, err := echoListener.Accept()
+			if err != nil {
+				return
+			}
+			go func(c net.Conn) {
+				defer c.Close()
+				_, _ = io.Copy(c, c)
+			}(conn)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := NewClient(ctx, ClientConfig{
+		Host:       host,
+		Port:       port,
+		User:       "root",
+		PrivateKey: privKey,
+		Logger:     log.Noop,
+	})
+	require.NoError(err)
+	defer client.Close()
+
+	forwardCtx, forwardCancel := context.WithCancel(ctx)
+	defer forwardCancel()
+
+	ready := make(chan []PortForward, 1)
+	forwardDone := make(chan error, 1)
+	go func() {
+		forwardDone <- client.Forward(forwardCtx, []PortForward{
+			{BindAddress: "127.0.0.1", LocalPort: 0, RemotePort: echoPort},
+		}, ForwardOpts{Ready: func(ports []PortForward) { ready <- ports }})
+	}()
+
+	// The ready ports have the bound local port.
+	var bound []PortForward
+	select {
+	case bound = <-ready:
+	case err := <-forwardDone:
+		t.Fatalf("forward finished before being ready: %v", err)
+	}
+	require.Len(bound, 1)
+	assert.NotZero(bound[0].LocalPort)
+	assert.Equal(echoPort, bound[0].RemotePort)
+
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("127.0.0.1:%d", bound[0].LocalPort), 2*time.Second)
+	require.NoError(err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("free port"))
+	require.NoError(err)
+	buf := make([]byte, 100)
+	require.NoError(conn.SetReadDeadline(time.Now().Add(2 * time.Second)))
+	n, err := conn.Read(buf)
+	require.NoError(err)
+	assert.Equal("free port", string(buf[:n]))
+
+	forwardCancel()
+	assert.ErrorIs(<-forwardDone, context.Canceled)
 }
 
 func TestClient_Forward_EmptyPorts(t *testing.T) {
@@ -769,6 +1262,6 @@ func TestClient_Forward_EmptyPorts(t *testing.T) {
 	require.NoError(t, err)
 	defer client.Close()
 
-	err = client.Forward(ctx, []PortForward{})
+	err = client.Forward(ctx, []PortForward{}, ForwardOpts{})
 	assert.Error(t, err)
 }
@@ -0,0 +1,961 @@
+package ssh
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// DefaultCopyConcurrency is the default number of files copied at the same time.
+	DefaultCopyConcurrency = 4
+	// DefaultCopyRequestsPerFile is the default number of in flight SFTP requests per file.
+	DefaultCopyRequestsPerFile = 64
+	// DefaultCopyBlockSize is the default size of the SFTP write requests, OpenSSH
+	// sftp-server accepts up to 256KiB messages.
+	DefaultCopyBlockSize = 128 * 1024
+)
+
+// ErrChecksumMismatch is returned when a copied file checksum differs from the source.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// CopyProgress is the progress of a copy.
+type CopyProgress struct {
+	// Bytes are the file bytes copied so far.
+	Bytes int64
+	// TotalBytes are the file bytes to copy, 0 when unknown.
+	TotalBytes int64
+}
+
+// CopyOpts are the options of CopyTo and CopyFrom.
+type CopyOpts struct {
+	// Concurrency is the number of files of a directory copied at the same time
+	// (default: 4).
+	Concurrency int
+	// RequestsPerFile is the number of in flight SFTP requests of each file
+	// (default: 64).
+	RequestsPerFile int
+	// BlockSize is the size in bytes of each SFTP write request (default: 128KiB).
+	// The reads use the SFTP default size, the servers cap the reads and the
+	// concurrent reads rely on full replies.
+	BlockSize int
+	// Compress sends the files as a gzip compressed tar stream over an exec
+	// session instead of SFTP. Needs tar with gzip support on the remote host.
+	Compress bool
+	// Progress is called with the copy progress (optional). The calls are
+	// serialized, it should return fast.
+	Progress func(CopyProgress)
+	// BandwidthLimit limits the rate of the copied file bytes in bytes per second,
+	// shared by all the files (0 is unlimited).
+	BandwidthLimit int64
+	// Checksum verifies the SHA-256 of the copied files against the source once
+	// copied. Needs sha256sum on the remote host.
+	Checksum bool
+	// Checkpoint records the copied files and bytes (optional). Passing the
+	// checkpoint of a failed copy to the retry resumes it: the copied files are
+	// skipped and the partially copied ones continue from the last byte safely
+	// written. Only the SFTP copies resume, the compressed ones start again.
+	Checkpoint *CopyCheckpoint
+}
+
+func (o *CopyOpts) defaults() {
+	if o.Concurrency <= 0 {
+		o.Concurrency = DefaultCopyConcurrency
+	}
+	if o.RequestsPerFile <= 0 {
+		o.RequestsPerFile = DefaultCopyRequestsPerFile
+	}
+	if o.BlockSize <= 0 {
+		o.BlockSize = DefaultCopyBlockSize
+	}
+}
+
+// CopyTo copies a local file or directory to the remote host. Directories are
+// copied recursively, symlinks are skipped.
+func (c *Client) CopyTo(ctx context.Context, srcLocal, dstRemote string, opts CopyOpts) error {
+	opts.defaults()
+
+	srcInfo, err := os.Stat(srcLocal)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("source path '%s' does not exist: %w", srcLocal, os.ErrNotExist)
+		}
+		return fmt.Errorf("could not stat source: %w", err)
+	}
+
+	jobs, total, err := localCopyJobs(srcLocal, dstRemote, srcInfo)
+	if err != nil {
+		return err
+	}
+	progress := newCopyProgress(total, opts.Progress, opts.BandwidthLimit)
+
+	if opts.Compress {
+		if err := c.copyToCompressed(ctx, srcInfo.IsDir(), dstRemote, jobs, progress); err != nil {
+			return err
+		}
+		return c.verifyCopy(ctx, srcLocal, dstRemote, srcInfo.IsDir(), true, opts.Checksum)
+	}
+
+	sftpClient, err := c.newSFTPClient(
+		sftp.MaxPacketUnchecked(opts.BlockSize),
+		sftp.MaxConcurrentRequestsPerFile(opts.RequestsPerFile),
+		sftp.UseConcurrentWrites(true),
+	)
+	if err != nil {
+		return err
+	}
+	defer sftpClient.Close()
+
+	// The jobs are in walk order, the parent directories are created before the children.
+	for _, job := range jobs {
+		if !job.dir {
+			continue
+		}
+		if err := sftpClient.MkdirAll(job.dst); err != nil {
+			return fmt.Errorf("could not create remote directory %s: %w", job.dst, err)
+		}
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(opts.Concurrency)
+	for _, job := range jobs {
+		if job.dir {
+			continue
+		}
+		g.Go(func() error {
+			return c.copyFileTo(gctx, sftpClient, job, opts.RequestsPerFile, progress, opts.Checkpoint)
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	return c.verifyCopy(ctx, srcLocal, dstRemote, srcInfo.IsDir(), true, opts.Checksum)
+}
+
+// CopyFrom copies a remote file or directory to the local host. Directories are
+// copied recursively, symlinks are skipped.
+func (c *Client) CopyFrom(ctx context.Context, srcRemote, dstLocal string, opts CopyOpts) error {
+	opts.defaults()
+
+	sftpClient, err := c.newSFTPClient(sftp.MaxConcurrentRequestsPerFile(opts.RequestsPerFile))
+	if err != nil {
+		return err
+	}
+	defer sftpClient.Close()
+
+	srcInfo, err := sftpClient.Stat(srcRemote)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("source path '%s' does not exist in sandbox: %w", srcRemote, os.ErrNotExist)
+		}
+		return fmt.Errorf("could not stat remote source: %w", err)
+	}
+
+	if opts.Compress {
+		// Walking the remote tree only for the total would cost a round trip per
+		// file, the total of the directories is unknown.
+		var total int64
+		if !srcInfo.IsDir() {
+			total = srcInfo.Size()
+		}
+		progress := newCopyProgress(total, opts.Progress, opts.BandwidthLimit)
+		if err := c.copyFromCompressed(ctx, srcRemote, dstLocal, srcInfo.IsDir(), progress); err != nil {
+			return err
+		}
+		return c.verifyCopy(ctx, dstLocal, srcRemote, srcInfo.IsDir(), false, opts.Checksum)
+	}
+
+	jobs, total, err := remoteCopyJobs(ctx, sftpClient, srcRemote, dstLocal, srcInfo)
+	if err != nil {
+		return err
+	}
+	progress := newCopyProgress(total, opts.Progress, opts.BandwidthLimit)
+
+	for _, job := range jobs {
+		if !job.dir {
+			continue
+		}
+		if err := os.MkdirAll(job.dst, job.mode); err != nil {
+			return fmt.Errorf("could not create local directory %s: %w", job.dst, err)
+		}
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(opts.Concurrency)
+	for _, job := range jobs {
+		if job.dir {
+			continue
+		}
+		g.Go(func() error {
+			return c.copyFileFrom(gctx, sftpClient, job, progress, opts.Checkpoint)
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	return c.verifyCopy(ctx, dstLocal, srcRemote, srcInfo.IsDir(), false, opts.Checksum)
+}
+
+func (c *Client) newSFTPClient(opts ...sftp.ClientOption) (*sftp.Client, error) {
+	sftpClient, err := sftp.NewClient(c.conn, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not create sftp client: %w", err)
+	}
+	return sftpClient, nil
+}
+
+// copyJob is a single file or directory to copy.
+type copyJob struct {
+	src  string
+	dst  string
+	dir  bool
+	mode fs.FileMode
+	size int64
+	// rel is the slash separated path relative to the copied directory.
+	rel string
+}
+
+// localCopyJobs walks the local source and returns the jobs in walk order (parents
+// first) and the total bytes to copy.
+func localCopyJobs(srcLocal, dstRemote string, srcInfo fs.FileInfo) ([]copyJob, int64, error) {
+	if !srcInfo.IsDir() {
+		return []copyJob{{src: srcLocal, dst: dstRemote, mode: srcInfo.Mode(), size: srcInfo.Size(), rel: path.Base(dstRemote)}}, srcInfo.Size(), nil
+	}
+
+	var (
+		jobs  []copyJob
+		total int64
+	)
+	err := filepath.WalkDir(srcLocal, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Skip symlinks.
+		if d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(srcLocal, p)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		job := copyJob{
+			src:  p,
+			dst:  path.Join(dstRemote, filepath.ToSlash(relPath)),
+			dir:  d.IsDir(),
+			mode: info.Mode(),
+			size: info.Size(),
+			rel:  filepath.ToSlash(relPath),
+		}
+		if !job.dir {
+			total += info.Size()
+		}
+		jobs = append(jobs, job)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not walk source: %w", err)
+	}
+
+	return jobs, total, nil
+}
+
+// remoteCopyJobs walks the remote source and returns the jobs in walk order (parents
+// first) and the total bytes to copy.
+func remoteCopyJobs(ctx context.Context, sftpClient *sftp.Client, srcRemote, dstLocal string, srcInfo fs.FileInfo) ([]copyJob, int64, error) {
+	if !srcInfo.IsDir() {
+		return []copyJob{{src: srcRemote, dst: dstLocal, mode: srcInfo.Mode(), size: srcInfo.Size()}}, srcInfo.Size(), nil
+	}
+
+	var (
+		jobs  []copyJob
+		total int64
+	)
+	walker := sftpClient.Walk(srcRemote)
+	for walker.Step() {
+		if ctx.Err() != nil {
+			return nil, 0, ctx.Err()
+		}
+		if err := walker.Err(); err != nil {
+			return nil, 0, err
+		}
+
+		info := walker.Stat()
+
+		// Skip symlinks.
+		if info.Mode()&fs.ModeSymlink != 0 {
+			continue
+		}
+
+		relPath, err := filepath.Rel(srcRemote, walker.Path())
+		if err != nil {
+			return nil, 0, err
+		}
+
+		job := copyJob{
+			src:  walker.Path(),
+			dst:  filepath.Join(dstLocal, relPath),
+			dir:  info.IsDir(),
+			mode: info.Mode(),
+			size: info.Size(),
+		}
+		if !job.dir {
+			total += info.Size()
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, total, nil
+}
+
+// copyFileTo copies a single local file to the remote host.
+func (c *Client) copyFileTo(ctx context.Context, sftpClient *sftp.Client, job copyJob, requests int, progress *copyProgress, checkpoint *CopyCheckpoint) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	offset, done := checkpoint.get(job.dst)
+	if done {
+		progress.skip(job.size)
+		return nil
+	}
+
+	src, err := os.Open(job.src)
+	if err != nil {
+		return fmt.Errorf("could not open local file %s: %w", job.src, err)
+	}
+	defer src.Close()
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if offset > 0 {
+		flags = os.O_WRONLY | os.O_CREATE
+	}
+	dst, err := sftpClient.OpenFile(job.dst, flags)
+	if err != nil {
+		return fmt.Errorf("could not create remote file %s: %w", job.dst, err)
+	}
+	defer dst.Close()
+
+	if offset > 0 {
+		// The concurrent writes after the offset could have left holes.
+		if err := dst.Truncate(offset); err != nil {
+			return fmt.Errorf("could not resume remote file %s: %w", job.dst, err)
+		}
+		if err := seekBoth(src, dst, offset); err != nil {
+			return fmt.Errorf("could not resume remote file %s: %w", job.dst, err)
+		}
+		c.logger.Debugf("Resuming copy of %s at %d bytes", job.dst, offset)
+		progress.skip(offset)
+	}
+
+	if _, err := dst.ReadFromWithConcurrency(progress.reader(ctx, src), requests); err != nil {
+		// On failures the file offset is the end of the data safely written.
+		if written, serr := dst.Seek(0, io.SeekCurrent); serr == nil {
+			checkpoint.set(job.dst, written, false)
+		}
+		return fmt.Errorf("could not copy to remote file %s: %w", job.dst, err)
+	}
+	checkpoint.set(job.dst, 0, true)
+
+	if err := sftpClient.Chmod(job.dst, job.mode); err != nil {
+		c.logger.Debugf("Could not set permissions on %s: %v", job.dst, err)
+	}
+
+	return nil
+}
+
+// copyFileFrom copies a single remote file to the local host.
+func (c *Client) copyFileFrom(ctx context.Context, sftpClient *sftp.Client, job copyJob, progress *copyProgress, checkpoint *CopyCheckpoint) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	offset, done := checkpoint.get(job.dst)
+	if done {
+		progress.skip(job.size)
+		return nil
+	}
+
+	src, err := sftpClient.Open(job.src)
+	if err != nil {
+		return fmt.Errorf("could not open remote file %s: %w", job.src, err)
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(filepath.Dir(job.dst), 0755); err != nil {
+		return fmt.Errorf("could not create local directory: %w", err)
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if offset > 0 {
+		flags = os.O_CREATE | os.O_WRONLY
+	}
+	dst, err := os.OpenFile(job.dst, flags, job.mode)
+	if err != nil {
+		return fmt.Errorf("could not create local file %s: %w", job.dst, err)
+	}
+	defer dst.Close()
+
+	if offset > 0 {
+		if err := dst.Truncate(offset); err != nil {
+			return fmt.Errorf("could not resume local file %s: %w", job.dst, err)
+		}
+		if err := seekBoth(src, dst, offset); err != nil {
+			return fmt.Errorf("could not resume local file %s: %w", job.dst, err)
+		}
+		c.logger.Debugf("Resuming copy of %s at %d bytes", job.src, offset)
+		progress.skip(offset)
+	}
+
+	// WriteTo reads the remote file with concurrent requests, the local writes
+	// are sequential.
+	written, err := src.WriteTo(progress.writer(ctx, dst))
+	if err != nil {
+		checkpoint.set(job.dst, offset+written, false)
+		return fmt.Errorf("could not copy from remote file %s: %w", job.src, err)
+	}
+	checkpoint.set(job.dst, 0, true)
+
+	return nil
+}
+
+// copyToCompressed streams the jobs as a gzip compressed tar into a remote tar.
+func (c *Client) copyToCompressed(ctx context.Context, isDir bool, dstRemote string, jobs []copyJob, progress *copyProgress) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// A file is extracted in the destination parent with the destination name.
+	extractDir := dstRemote
+	if !isDir {
+		extractDir = path.Dir(dstRemote)
+	}
+
+	pr, pw := io.Pipe()
+	writeErr := make(chan error, 1)
+	go func() {
+		err := writeTarGz(ctx, pw, jobs, progress)
+		_ = pw.CloseWithError(err)
+		if err != nil {
+			cancel()
+		}
+		writeErr <- err
+	}()
+
+	var stderr bytes.Buffer
+	cmd := fmt.Sprintf("mkdir -p %s && tar -xzf - -C %s", shellQuote(extractDir), shellQuote(extractDir))
+	exitCode, err := c.Exec(ctx, cmd, ExecOpts{Stdin: pr, Stderr: &stderr})
+	_ = pr.Close()
+	wErr := <-writeErr
+
+	switch {
+	// The closed pipe only means the remote stopped reading, its result tells why.
+	case wErr != nil && !errors.Is(wErr, io.ErrClosedPipe):
+		return fmt.Errorf("could not send files: %w", wErr)
+	case err != nil:
+		return fmt.Errorf("could not extract remote archive: %w", err)
+	case exitCode != 0:
+		return fmt.Errorf("remote tar failed with exit code %d: %s", exitCode, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// copyFromCompressed streams a gzip compressed tar of the remote source and
+// extracts it locally.
+func (c *Client) copyFromCompressed(ctx context.Context, srcRemote, dstLocal string, isDir bool, progress *copyProgress) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	dir, name := srcRemote, "."
+	if !isDir {
+		dir, name = path.Dir(srcRemote), path.Base(srcRemote)
+	}
+
+	pr, pw := io.Pipe()
+	readErr := make(chan error, 1)
+	go func() {
+		err := readTarGz(pr, dstLocal, isDir, progress.writerFunc(ctx))
+		// Unblock the remote output on failures, the remote tar is killed by
+		// the cancellation.
+		_ = pr.CloseWithError(err)
+		if err != nil {
+			cancel()
+		}
+		readErr <- err
+	}()
+
+	var stderr bytes.Buffer
+	cmd := fmt.Sprintf("tar -czf - -C %s %s", shellQuote(dir), shellQuote(name))
+	exitCode, err := c.Exec(ctx, cmd, ExecOpts{Stdout: pw, Stderr: &stderr})
+	_ = pw.Close()
+	rErr := <-readErr
+
+	switch {
+	// A failed remote tar leaves a truncated archive, its error tells why.
+	case err == nil && exitCode != 0:
+		return fmt.Errorf("remote tar failed with exit code %d: %s", exitCode, strings.TrimSpace(stderr.String()))
+	case rErr != nil:
+		return fmt.Errorf("could not receive files: %w", rErr)
+	case err != nil:
+		return fmt.Errorf("could not create remote archive: %w", err)
+	}
+
+	return nil
+}
+
+// writeTarGz writes the jobs as a gzip compressed tar.
+func writeTarGz(ctx context.Context, w io.Writer, jobs []copyJob, progress *copyProgress) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	for _, job := range jobs {
+		if job.rel == "." {
+			continue
+		}
+
+		hdr := &tar.Header{
+			Name: job.rel,
+			Mode: int64(job.mode.Perm()),
+		}
+		if job.dir {
+			hdr.Typeflag = tar.TypeDir
+			hdr.Name += "/"
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := writeTarFile(ctx, tw, hdr, job.src, progress); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func writeTarFile(ctx context.Context, tw *tar.Writer, hdr *tar.Header, src string, progress *copyProgress) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("could not open local file %s: %w", src, err)
+	}
+	defer f.Close()
+
+	// Use the opened file size, the file could have changed since the walk.
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr.Typeflag = tar.TypeReg
+	hdr.Size = info.Size()
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+
+	if _, err := io.CopyN(tw, progress.reader(ctx, f), hdr.Size); err != nil {
+		return fmt.Errorf("could not read local file %s: %w", src, err)
+	}
+	return nil
+}
+
+// readTarGz extracts a gzip compressed tar into dst. When isDir is false the
+// archive has a single file that is written as dst.
+func readTarGz(r io.Reader, dst string, isDir bool, wrap func(io.Writer) io.Writer) error {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := path.Clean(hdr.Name)
+		if !filepath.IsLocal(name) && name != "." {
+			return fmt.Errorf("invalid archive entry %q", hdr.Name)
+		}
+		target := dst
+		if isDir {
+			target = filepath.Join(dst, filepath.FromSlash(name))
+		}
+		mode := fs.FileMode(hdr.Mode).Perm()
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, mode); err != nil {
+				return fmt.Errorf("could not create local directory %s: %w", target, err)
+			}
+		case tar.TypeReg:
+			if err := writeLocalFile(target, mode, tr, wrap); err != nil {
+				return err
+			}
+		default:
+			// Skip symlinks and special files.
+		}
+	}
+}
+
+func writeLocalFile(target string, mode fs.FileMode, r io.Reader, wrap func(io.Writer) io.Writer) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("could not create local directory: %w", err)
+	}
+
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return fmt.Errorf("could not create local file %s: %w", target, err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(wrap(f), r); err != nil {
+		return fmt.Errorf("could not write local file %s: %w", target, err)
+	}
+	return nil
+}
+
+// copyProgress tracks the copied bytes of all the files of a copy and limits
+// their rate.
+type copyProgress struct {
+	mu      sync.Mutex
+	bytes   int64
+	total   int64
+	fn      func(CopyProgress)
+	limiter *rateLimiter
+}
+
+func newCopyProgress(total int64, fn func(CopyProgress), bytesPerSec int64) *copyProgress {
+	return &copyProgress{total: total, fn: fn, limiter: newRateLimiter(bytesPerSec)}
+}
+
+// skip reports bytes copied by a previous attempt, without limiting them.
+func (p *copyProgress) skip(n int64) {
+	if n > 0 {
+		p.add(int(n))
+	}
+}
+
+func (p *copyProgress) add(n int) {
+	if p.fn == nil || n <= 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.bytes += int64(n)
+	p.fn(CopyProgress{Bytes: p.bytes, TotalBytes: p.total})
+}
+
+// reader wraps r to report the read bytes and stop on context cancellation.
+func (p *copyProgress) reader(ctx context.Context, r io.Reader) io.Reader {
+	return &progressReader{ctx: ctx, r: r, p: p}
+}
+
+// writer wraps w to report the written bytes and stop on context cancellation.
+func (p *copyProgress) writer(ctx context.Context, w io.Writer) io.Writer {
+	return &progressWriter{ctx: ctx, w: w, p: p}
+}
+
+func (p *copyProgress) writerFunc(ctx context.Context) func(io.Writer) io.Writer {
+	return func(w io.Writer) io.Writer { return p.writer(ctx, w) }
+}
+
+type progressReader struct {
+	ctx context.Context
+	r   io.Reader
+	p   *copyProgress
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	b = r.p.limiter.chunk(b)
+	n, err := r.r.Read(b)
+	if werr := r.p.limiter.wait(r.ctx, n); werr != nil {
+		return n, werr
+	}
+	r.p.add(n)
+	return n, err
+}
+
+type progressWriter struct {
+	ctx context.Context
+	w   io.Writer
+	p   *copyProgress
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if err := w.p.limiter.wait(w.ctx, len(b)); err != nil {
+		return 0, err
+	}
+	n, err := w.w.Write(b)
+	w.p.add(n)
+	return n, err
+}
+
+// rateLimiter limits the throughput of all the copied files to a shared bytes per
+// second rate. A nil limiter doesn't limit.
+type rateLimiter struct {
+	bytesPerSec int64
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func newRateLimiter(bytesPerSec int64) *rateLimiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return &rateLimiter{bytesPerSec: bytesPerSec}
+}
+
+// chunk caps a read buffer to a second of the rate, small reads keep the rate smooth.
+func (l *rateLimiter) chunk(b []byte) []byte {
+	if l == nil || int64(len(b)) <= l.bytesPerSec {
+		return b
+	}
+	return b[:max(1, l.bytesPerSec)]
+}
+
+// wait blocks until n bytes can be copied without exceeding the rate.
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	at := l.next
+	l.next = l.next.Add(time.Duration(float64(n) / float64(l.bytesPerSec) * float64(time.Second)))
+	l.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// CopyCheckpoint records the progress of a copy to resume it after a failure (see
+// CopyOpts.Checkpoint). A CopyCheckpoint is safe for concurrent use.
+type CopyCheckpoint struct {
+	mu    sync.Mutex
+	files map[string]copyCheckpointFile
+}
+
+type copyCheckpointFile struct {
+	offset int64
+	done   bool
+}
+
+// NewCopyCheckpoint returns an empty copy checkpoint.
+func NewCopyCheckpoint() *CopyCheckpoint {
+	return &CopyCheckpoint{files: map[string]copyCheckpointFile{}}
+}
+
+// get returns the bytes safely copied of the destination file and if it was
+// completely copied. A nil checkpoint has no progress.
+func (c *CopyCheckpoint) get(dst string) (offset int64, done bool) {
+	if c == nil {
+		return 0, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f := c.files[dst]
+	return f.offset, f.done
+}
+
+func (c *CopyCheckpoint) set(dst string, offset int64, done bool) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files[dst] = copyCheckpointFile{offset: offset, done: done}
+}
+
+func seekBoth(a, b io.Seeker, offset int64) error {
+	if _, err := a.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := b.Seek(offset, io.SeekStart)
+	return err
+}
+
+// IsConnectionError returns true when the error is caused by a broken or
+// unreachable SSH connection, the operation can be retried on a new connection.
+func IsConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var netErr net.Error
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, sftp.ErrSSHFxConnectionLost) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.As(err, &netErr)
+}
+
+// verifyCopy compares the SHA-256 of the source files with the copied ones, the
+// local path is the source when toRemote is set. Does nothing unless enabled.
+func (c *Client) verifyCopy(ctx context.Context, localPath, remotePath string, isDir, toRemote, enabled bool) error {
+	if !enabled {
+		return nil
+	}
+
+	local, err := localCopyHashes(localPath, isDir)
+	if err != nil {
+		return fmt.Errorf("could not checksum local files: %w", err)
+	}
+
+	var remote map[string]string
+	switch {
+	case !isDir:
+		hashes, err := c.remoteHashes(ctx, path.Dir(remotePath), []string{path.Base(remotePath)})
+		if err != nil {
+			return err
+		}
+		remote = map[string]string{".": hashes[path.Base(remotePath)]}
+	case toRemote:
+		rels := make([]string, 0, len(local))
+		for rel := range local {
+			rels = append(rels, rel)
+		}
+		if remote, err = c.remoteHashes(ctx, remotePath, rels); err != nil {
+			return err
+		}
+	default:
+		if remote, err = c.remoteTreeHashes(ctx, remotePath); err != nil {
+			return err
+		}
+	}
+
+	src, dst := remote, local
+	if toRemote {
+		src, dst = local, remote
+	}
+	for rel, hash := range src {
+		copied, ok := dst[rel]
+		if !ok {
+			return fmt.Errorf("copied file %s could not be hashed (needs sha256sum): %w", rel, ErrChecksumMismatch)
+		}
+		if copied != hash {
+			return fmt.Errorf("copied file %s differs from the source: %w", rel, ErrChecksumMismatch)
+		}
+	}
+
+	c.logger.Debugf("Verified the checksum of %d copied files", len(src))
+	return nil
+}
+
+// remoteTreeHashes returns the SHA-256 of all the remote directory regular files.
+func (c *Client) remoteTreeHashes(ctx context.Context, remoteDir string) (map[string]string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := fmt.Sprintf("cd %s && find . -type f -exec sha256sum -- {} +", shellQuote(remoteDir))
+	exitCode, err := c.Exec(ctx, cmd, ExecOpts{Stdout: &stdout, Stderr: &stderr})
+	if err != nil {
+		return nil, fmt.Errorf("could not hash remote files: %w", err)
+	}
+	if exitCode != 0 {
+		return nil, fmt.Errorf("remote hash failed with exit code %d: %s", exitCode, strings.TrimSpace(stderr.String()))
+	}
+
+	hashes := map[string]string{}
+	for name, hash := range parseSHA256Sums(&stdout) {
+		hashes[strings.TrimPrefix(name, "./")] = hash
+	}
+	return hashes, nil
+}
+
+// localCopyHashes returns the SHA-256 of the local directory regular files by
+// slash separated relative path, or of the file as ".". Symlinks are skipped like
+// the copies.
+func localCopyHashes(localPath string, isDir bool) (map[string]string, error) {
+	if !isDir {
+		hash, err := fileSHA256(localPath)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{".": hash}, nil
+	}
+
+	hashes := map[string]string{}
+	err := filepath.WalkDir(localPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(localPath, p)
+		if err != nil {
+			return err
+		}
+		hash, err := fileSHA256(p)
+		if err != nil {
+			return err
+		}
+		hashes[filepath.ToSlash(rel)] = hash
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
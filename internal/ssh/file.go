@@ -0,0 +1,119 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/pkg/sftp"
+)
+
+// ReadFile returns the content of a remote file.
+func (c *Client) ReadFile(ctx context.Context, remotePath string) ([]byte, error) {
+	var buf bytes.Buffer
+	err := c.withSFTP(ctx, func(sftpClient *sftp.Client) error {
+		f, err := sftpClient.Open(remotePath)
+		if err != nil {
+			return fmt.Errorf("could not open remote file: %w", err)
+		}
+		defer f.Close()
+
+		if _, err := f.WriteTo(&buf); err != nil {
+			return fmt.Errorf("could not read remote file: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// WriteFile writes data to a remote file, creating or truncating it, and sets its
+// permissions to perm. The parent directory must exist.
+func (c *Client) WriteFile(ctx context.Context, remotePath string, data []byte, perm fs.FileMode) error {
+	return c.withSFTP(ctx, func(sftpClient *sftp.Client) error {
+		f, err := sftpClient.OpenFile(remotePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+		if err != nil {
+			return fmt.Errorf("could not create remote file: %w", err)
+		}
+		defer f.Close()
+
+		if _, err := f.ReadFrom(bytes.NewReader(data)); err != nil {
+			return fmt.Errorf("could not write remote file: %w", err)
+		}
+		if err := f.Chmod(perm); err != nil {
+			return fmt.Errorf("could not set remote file permissions: %w", err)
+		}
+		return f.Close()
+	})
+}
+
+// ReadDir returns the entries of a remote directory sorted by name. Symlinks are
+// not followed.
+func (c *Client) ReadDir(ctx context.Context, remoteDir string) ([]fs.FileInfo, error) {
+	var infos []fs.FileInfo
+	err := c.withSFTP(ctx, func(sftpClient *sftp.Client) error {
+		var err error
+		infos, err = sftpClient.ReadDir(remoteDir)
+		if err != nil {
+			return fmt.Errorf("could not read remote directory: %w", err)
+		}
+		slices.SortFunc(infos, func(a, b fs.FileInfo) int { return strings.Compare(a.Name(), b.Name()) })
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return infos, nil
+}
+
+// Stat returns the information of a remote file, following symlinks.
+func (c *Client) Stat(ctx context.Context, remotePath string) (fs.FileInfo, error) {
+	var info fs.FileInfo
+	err := c.withSFTP(ctx, func(sftpClient *sftp.Client) error {
+		var err error
+		info, err = sftpClient.Stat(remotePath)
+		if err != nil {
+			return fmt.Errorf("could not stat remote file: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// withSFTP runs fn with a new SFTP client. The SFTP requests are not context aware,
+// the client is closed on context cancellation to abort them.
+func (c *Client) withSFTP(ctx context.Context, fn func(*sftp.Client) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	sftpClient, err := c.newSFTPClient()
+	if err != nil {
+		return err
+	}
+	defer sftpClient.Close()
+
+	stop := context.AfterFunc(ctx, func() { sftpClient.Close() })
+	defer stop()
+
+	if err := fn(sftpClient); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+
+	return nil
+}
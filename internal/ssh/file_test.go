@@ -0,0 +1,79 @@
+package ssh
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/log"
+)
+
+func TestClient_FileOperations(t *testing.T) {
+	privKey := generateTestKeyPair(t)
+	server := newTestSSHServer(t, privKey)
+	defer server.close()
+
+	host, port := testParseHostPort(t, server.addr)
+	client, err := NewClient(context.Background(), ClientConfig{
+		Host:       host,
+		Port:       port,
+		User:       "root",
+		PrivateKey: privKey,
+		Logger:     log.Noop,
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx := context.Background()
+	remoteDir := t.TempDir()
+	t0 := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	testWriteFile(t, filepath.Join(remoteDir, "b.txt"), "bbb", t0)
+	require.NoError(t, os.Mkdir(filepath.Join(remoteDir, "a"), 0755))
+
+	// Write, overwrite and read.
+	file := filepath.Join(remoteDir, "c.txt")
+	require.NoError(t, client.WriteFile(ctx, file, []byte("a longer content"), 0600))
+	require.NoError(t, client.WriteFile(ctx, file, []byte("content"), 0640))
+	data, err := client.ReadFile(ctx, file)
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(data))
+
+	// Stat.
+	info, err := client.Stat(ctx, file)
+	require.NoError(t, err)
+	assert.Equal(t, "c.txt", info.Name())
+	assert.Equal(t, int64(7), info.Size())
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+	assert.False(t, info.IsDir())
+
+	// List.
+	infos, err := client.ReadDir(ctx, remoteDir)
+	require.NoError(t, err)
+	require.Len(t, infos, 3)
+	assert.Equal(t, "a", infos[0].Name())
+	assert.True(t, infos[0].IsDir())
+	assert.Equal(t, "b.txt", infos[1].Name())
+	assert.Equal(t, t0.Unix(), infos[1].ModTime().Unix())
+	assert.Equal(t, "c.txt", infos[2].Name())
+
+	// Missing paths.
+	_, err = client.ReadFile(ctx, filepath.Join(remoteDir, "missing"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = client.Stat(ctx, filepath.Join(remoteDir, "missing"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = client.ReadDir(ctx, filepath.Join(remoteDir, "missing"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	err = client.WriteFile(ctx, filepath.Join(remoteDir, "missing", "f.txt"), []byte("x"), 0644)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// Cancelled context.
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = client.ReadFile(cctx, file)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -13,25 +13,25 @@ import (
 )
 
 // KeyManager handles per-sandbox SSH key generation and loading.
-// It uses conventions to derive key paths from dataDir + sandboxID.
+// It uses conventions to derive key paths from vmsDir + sandboxID.
 type KeyManager struct {
-	dataDir string
+	vmsDir string
 }
 
 // NewKeyManager creates a new SSH key manager.
-// dataDir is the sbx data directory (e.g., ~/.sbx).
-func NewKeyManager(dataDir string) *KeyManager {
-	return &KeyManager{dataDir: dataDir}
+// vmsDir is the sandbox VMs directory (e.g., ~/.sbx/vms).
+func NewKeyManager(vmsDir string) *KeyManager {
+	return &KeyManager{vmsDir: vmsDir}
 }
 
 // PrivateKeyPath returns the path to a sandbox's private key.
 func (m *KeyManager) PrivateKeyPath(sandboxID string) string {
-	return conventions.SSHPrivateKeyPath(m.dataDir, sandboxID)
+	return conventions.SSHPrivateKeyPath(m.vmsDir, sandboxID)
 }
 
 // PublicKeyPath returns the path to a sandbox's public key.
 func (m *KeyManager) PublicKeyPath(sandboxID string) string {
-	return conventions.SSHPublicKeyPath(m.dataDir, sandboxID)
+	return conventions.SSHPublicKeyPath(m.vmsDir, sandboxID)
 }
 
 // KeysExist checks if both private and public keys exist for a sandbox.
@@ -45,7 +45,7 @@ func (m *KeyManager) KeysExist(sandboxID string) bool {
 // The key directory (VM dir) must already exist.
 // Returns the public key in authorized_keys format.
 func (m *KeyManager) GenerateKeys(sandboxID string) (publicKeyAuthorized string, err error) {
-	keyDir := conventions.VMDir(m.dataDir, sandboxID)
+	keyDir := conventions.VMDir(m.vmsDir, sandboxID)
 
 	// Ensure directory exists (should already exist from VM creation, but be safe).
 	if err := os.MkdirAll(keyDir, 0700); err != nil {
@@ -0,0 +1,211 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/slok/sbx/internal/log"
+)
+
+const (
+	// DefaultKeepAliveInterval is the default interval of the pooled connections keepalive.
+	DefaultKeepAliveInterval = 15 * time.Second
+	// DefaultKeepAliveTimeout is the default time a keepalive waits for the reply.
+	DefaultKeepAliveTimeout = 2 * time.Second
+)
+
+// PoolConfig is the configuration of the SSH connection pool.
+type PoolConfig struct {
+	// KeepAliveInterval is the interval of the keepalive requests that detect and
+	// close the dead connections (default: 15s).
+	KeepAliveInterval time.Duration
+	// KeepAliveTimeout is the time a keepalive waits for the reply before the
+	// connection is considered dead (default: 2s).
+	KeepAliveTimeout time.Duration
+	// Logger for logging (optional).
+	Logger log.Logger
+}
+
+func (c *PoolConfig) defaults() error {
+	if c.KeepAliveInterval == 0 {
+		c.KeepAliveInterval = DefaultKeepAliveInterval
+	}
+	if c.KeepAliveTimeout == 0 {
+		c.KeepAliveTimeout = DefaultKeepAliveTimeout
+	}
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "ssh.Pool"})
+	return nil
+}
+
+// DialFunc dials a new SSH connection.
+type DialFunc func(ctx context.Context) (*Client, error)
+
+// Pool caches one SSH connection per key (e.g. sandbox ID) so the operations
+// reuse it instead of dialing a new connection each time. The dead connections
+// (closed by the keepalive or by a failed session open) are redialed.
+//
+// The pooled clients are owned by the pool, callers must not close them.
+// A Pool is safe for concurrent use.
+type Pool struct {
+	keepAliveInterval time.Duration
+	keepAliveTimeout  time.Duration
+	logger            log.Logger
+
+	mu     sync.Mutex
+	conns  map[string]*pooledConn
+	closed bool
+}
+
+type pooledConn struct {
+	client *Client
+	// dead is closed when the connection is closed.
+	dead chan struct{}
+}
+
+// isDead returns true when the connection is closed, the client closes are seen
+// before the connection dead channel is closed.
+func (pc *pooledConn) isDead() bool {
+	if pc.client.closed.Load() {
+		return true
+	}
+	select {
+	case <-pc.dead:
+		return true
+	default:
+		return false
+	}
+}
+
+// NewPool creates a new SSH connection pool.
+func NewPool(cfg PoolConfig) (*Pool, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Pool{
+		keepAliveInterval: cfg.KeepAliveInterval,
+		keepAliveTimeout:  cfg.KeepAliveTimeout,
+		logger:            cfg.Logger,
+		conns:             map[string]*pooledConn{},
+	}, nil
+}
+
+// Get returns the pooled connection of the key, dialing a new one with dial
+// when there is none or the pooled one is dead. The pooled connection is returned
+// without a round trip, the keepalive detects the dead ones in the background.
+func (p *Pool) Get(ctx context.Context, key string, dial DialFunc) (*Client, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("ssh pool is closed")
+	}
+	pc := p.conns[key]
+	p.mu.Unlock()
+
+	if pc != nil {
+		if !pc.isDead() {
+			return pc.client, nil
+		}
+		p.logger.Debugf("Pooled SSH connection %s is dead, redialing", key)
+	}
+
+	// Dial without holding the lock, the other keys don't need to wait.
+	client, err := dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		_ = client.Close()
+		return nil, fmt.Errorf("ssh pool is closed")
+	}
+
+	// Another caller could have dialed the key meanwhile, keep the first one.
+	if current, ok := p.conns[key]; ok && current != pc && !current.isDead() {
+		_ = client.Close()
+		return current.client, nil
+	}
+	if current, ok := p.conns[key]; ok {
+		_ = current.client.Close()
+	}
+
+	newPC := &pooledConn{client: client, dead: make(chan struct{})}
+	p.conns[key] = newPC
+	go func() {
+		_ = client.conn.Wait()
+		close(newPC.dead)
+	}()
+	go p.keepAlive(key, newPC)
+
+	return client, nil
+}
+
+// Remove closes and forgets the pooled connection of the key (e.g. when the
+// sandbox is stopped).
+func (p *Pool) Remove(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if pc, ok := p.conns[key]; ok {
+		_ = pc.client.Close()
+		delete(p.conns, key)
+	}
+}
+
+// Close closes all the pooled connections, the pool can't be used afterwards.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	for key, pc := range p.conns {
+		_ = pc.client.Close()
+		delete(p.conns, key)
+	}
+	return nil
+}
+
+// ping sends a keepalive request and waits for the reply with a timeout, the
+// replies of a stopped guest never arrive.
+func (p *Pool) ping(pc *pooledConn) error {
+	errCh := make(chan error, 1)
+	go func() {
+		_, _, err := pc.client.conn.SendRequest("keepalive@openssh.com", true, nil)
+		errCh <- err
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(p.keepAliveTimeout):
+		return fmt.Errorf("keepalive timeout")
+	}
+}
+
+// keepAlive periodically pings the connection and closes it when dead, until the
+// connection is closed.
+func (p *Pool) keepAlive(key string, pc *pooledConn) {
+	ticker := time.NewTicker(p.keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-pc.dead:
+			return
+		case <-ticker.C:
+			if err := p.ping(pc); err != nil {
+				p.logger.Debugf("Pooled SSH connection %s keepalive failed: %v", key, err)
+				_ = pc.client.Close()
+				return
+			}
+		}
+	}
+}
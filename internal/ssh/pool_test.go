@@ -0,0 +1,228 @@
+package ssh
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/log"
+)
+
+func TestPool(t *testing.T) {
+	privKey := generateTestKeyPair(t)
+	server := newTestSSHServer(t, privKey)
+	defer server.close()
+
+	host, port := testParseHostPort(t, server.addr)
+
+	tests := map[string]struct {
+		run func(t *testing.T, pool *Pool, dial DialFunc, dials *int)
+	}{
+		"The connection should be reused between gets.": {
+			run: func(t *testing.T, pool *Pool, dial DialFunc, dials *int) {
+				c1, err := pool.Get(context.Background(), "sb1", dial)
+				require.NoError(t, err)
+				c2, err := pool.Get(context.Background(), "sb1", dial)
+				require.NoError(t, err)
+
+				assert.Same(t, c1, c2)
+				assert.Equal(t, 1, *dials)
+			},
+		},
+
+		"Each key should have its own connection.": {
+			run: func(t *testing.T, pool *Pool, dial DialFunc, dials *int) {
+				c1, err := pool.Get(context.Background(), "sb1", dial)
+				require.NoError(t, err)
+				c2, err := pool.Get(context.Background(), "sb2", dial)
+				require.NoError(t, err)
+
+				assert.NotSame(t, c1, c2)
+				assert.Equal(t, 2, *dials)
+			},
+		},
+
+		"A dead connection should be redialed.": {
+			run: func(t *testing.T, pool *Pool, dial DialFunc, dials *int) {
+				c1, err := pool.Get(context.Background(), "sb1", dial)
+				require.NoError(t, err)
+				require.NoError(t, c1.Close())
+
+				c2, err := pool.Get(context.Background(), "sb1", dial)
+				require.NoError(t, err)
+
+				assert.NotSame(t, c1, c2)
+				assert.Equal(t, 2, *dials)
+				exitCode, err := c2.Exec(context.Background(), "echo hello", ExecOpts{})
+				require.NoError(t, err)
+				assert.Equal(t, 0, exitCode)
+			},
+		},
+
+		"A removed connection should be closed and redialed.": {
+			run: func(t *testing.T, pool *Pool, dial DialFunc, dials *int) {
+				c1, err := pool.Get(context.Background(), "sb1", dial)
+				require.NoError(t, err)
+				pool.Remove("sb1")
+
+				_, err = c1.Exec(context.Background(), "echo hello", ExecOpts{})
+				assert.Error(t, err)
+
+				_, err = pool.Get(context.Background(), "sb1", dial)
+				require.NoError(t, err)
+				assert.Equal(t, 2, *dials)
+			},
+		},
+
+		"A closed pool should fail.": {
+			run: func(t *testing.T, pool *Pool, dial DialFunc, dials *int) {
+				require.NoError(t, pool.Close())
+
+				_, err := pool.Get(context.Background(), "sb1", dial)
+				assert.Error(t, err)
+				assert.Equal(t, 0, *dials)
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			pool, err := NewPool(PoolConfig{KeepAliveInterval: time.Hour, Logger: log.Noop})
+			require.NoError(t, err)
+			defer pool.Close()
+
+			dials := 0
+			dial := func(ctx context.Context) (*Client, error) {
+				dials++
+				return NewClient(ctx, ClientConfig{
+					Host:       host,
+					Port:       port,
+					User:       "root",
+					PrivateKey: privKey,
+				})
+			}
+
+			test.run(t, pool, dial, &dials)
+		})
+	}
+}
+
+func TestPoolKeepAliveClosesDeadConnections(t *testing.T) {
+	privKey := generateTestKeyPair(t)
+	server := newTestSSHServer(t, privKey)
+	defer server.close()
+
+	proxyAddr, frozen := newFreezableProxy(t, server.addr)
+	host, port := testParseHostPort(t, proxyAddr)
+
+	pool, err := NewPool(PoolConfig{KeepAliveInterval: 50 * time.Millisecond, KeepAliveTimeout: 100 * time.Millisecond})
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Get(context.Background(), "sb1", func(ctx context.Context) (*Client, error) {
+		return NewClient(ctx, ClientConfig{Host: host, Port: port, User: "root", PrivateKey: privKey})
+	})
+	require.NoError(t, err)
+
+	pool.mu.Lock()
+	pc := pool.conns["sb1"]
+	pool.mu.Unlock()
+
+	// The unanswered keepalive should close the connection.
+	frozen.Store(true)
+	select {
+	case <-pc.dead:
+	case <-time.After(5 * time.Second):
+		t.Fatal("dead connection was not detected")
+	}
+}
+
+func TestPoolGetDoesNotWaitForStalledConnections(t *testing.T) {
+	privKey := generateTestKeyPair(t)
+	server := newTestSSHServer(t, privKey)
+	defer server.close()
+
+	proxyAddr, frozen := newFreezableProxy(t, server.addr)
+	host, port := testParseHostPort(t, proxyAddr)
+
+	pool, err := NewPool(PoolConfig{KeepAliveInterval: 200 * time.Millisecond, KeepAliveTimeout: 200 * time.Millisecond})
+	require.NoError(t, err)
+	defer pool.Close()
+
+	dials := 0
+	dial := func(ctx context.Context) (*Client, error) {
+		dials++
+		return NewClient(ctx, ClientConfig{Host: host, Port: port, User: "root", PrivateKey: privKey})
+	}
+
+	c1, err := pool.Get(context.Background(), "sb1", dial)
+	require.NoError(t, err)
+
+	// A stalled connection should be returned right away, without a keepalive round trip.
+	frozen.Store(true)
+	start := time.Now()
+	c2, err := pool.Get(context.Background(), "sb1", dial)
+	require.NoError(t, err)
+	assert.Same(t, c1, c2)
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+
+	// The session open waits until the keepalive closes the connection, then fails.
+	_, err = c2.Exec(context.Background(), "echo hello", ExecOpts{})
+	assert.Error(t, err)
+
+	// The next get should redial.
+	frozen.Store(false)
+	c3, err := pool.Get(context.Background(), "sb1", dial)
+	require.NoError(t, err)
+	assert.NotSame(t, c1, c3)
+	assert.Equal(t, 2, dials)
+	exitCode, err := c3.Exec(context.Background(), "echo hello", ExecOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, exitCode)
+}
+
+// newFreezableProxy proxies the connections to the address, when frozen the
+// traffic is dropped so the server stops answering like a stopped guest.
+func newFreezableProxy(t *testing.T, addr string) (string, *atomic.Bool) {
+	t.Helper()
+
+	frozen := &atomic.Bool{}
+	proxy, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = proxy.Close() })
+
+	pipe := func(dst, src net.Conn) {
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := src.Read(buf)
+			if err != nil {
+				return
+			}
+			if !frozen.Load() {
+				_, _ = dst.Write(buf[:n])
+			}
+		}
+	}
+	go func() {
+		for {
+			conn, err := proxy.Accept()
+			if err != nil {
+				return
+			}
+			upstream, err := net.Dial("tcp", addr)
+			if err != nil {
+				conn.Close()
+				return
+			}
+			go pipe(upstream, conn)
+			go pipe(conn, upstream)
+		}
+	}()
+
+	return proxy.Addr().String(), frozen
+}
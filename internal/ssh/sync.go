@@ -0,0 +1,431 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/sync/errgroup"
+)
+
+// SyncOpts are the options of Sync.
+type SyncOpts struct {
+	// Delete removes the remote files missing in the local directory.
+	Delete bool
+	// Exclude returns true for the paths (slash separated, relative to the synced
+	// directories) that are ignored on both sides (optional). Excluded remote
+	// files are never deleted.
+	Exclude func(rel string) bool
+	// Concurrency is the number of files uploaded at the same time (default: 4).
+	Concurrency int
+}
+
+func (o *SyncOpts) defaults() {
+	if o.Concurrency <= 0 {
+		o.Concurrency = DefaultCopyConcurrency
+	}
+	if o.Exclude == nil {
+		o.Exclude = func(string) bool { return false }
+	}
+}
+
+// SyncResult is the result of a Sync.
+type SyncResult struct {
+	// Uploaded are the uploaded files, relative to the synced directories.
+	Uploaded []string
+	// Deleted are the deleted remote files and directories, relative to the synced directories.
+	Deleted []string
+	// Unchanged is the number of files already up to date.
+	Unchanged int
+	// UploadedBytes is the size of the uploaded files.
+	UploadedBytes int64
+}
+
+// syncEntry is a file or directory of one side of a sync.
+type syncEntry struct {
+	size    int64
+	modTime int64 // Unix seconds, SFTP has second precision.
+	mode    fs.FileMode
+	dir     bool
+	symlink bool
+}
+
+// Sync makes the remote directory a copy of the local directory, uploading only
+// the new and changed files. Files with the same size and modification time are
+// considered unchanged, when only the modification time differs the SHA-256 of
+// both sides is compared (needs sha256sum on the remote host, otherwise the file
+// is uploaded). The uploaded files get the local modification time so the next
+// sync skips them. Symlinks are not synced.
+func (c *Client) Sync(ctx context.Context, localDir, remoteDir string, opts SyncOpts) (*SyncResult, error) {
+	opts.defaults()
+
+	info, err := os.Stat(localDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("source directory '%s' does not exist: %w", localDir, os.ErrNotExist)
+		}
+		return nil, fmt.Errorf("could not stat source: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("source '%s' is not a directory", localDir)
+	}
+
+	local, err := localSyncEntries(localDir, opts.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	sftpClient, err := c.newSFTPClient(
+		sftp.MaxPacketUnchecked(DefaultCopyBlockSize),
+		sftp.UseConcurrentWrites(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer sftpClient.Close()
+
+	remote, err := remoteSyncEntries(ctx, sftpClient, remoteDir, opts.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &SyncResult{}
+	var (
+		mkdirs    []string
+		replace   []string // Remote entries with a different type than the local ones.
+		uploads   []string
+		sameSize  []string // Same size but different modification time, compared by hash.
+		chmods    []string
+		localRels = sortedKeys(local)
+	)
+	for _, rel := range localRels {
+		l := local[rel]
+		r, ok := remote[rel]
+		if ok && (r.dir != l.dir || r.symlink) {
+			replace = append(replace, rel)
+			ok = false
+		}
+
+		switch {
+		case l.dir:
+			if !ok {
+				mkdirs = append(mkdirs, rel)
+			}
+		case !ok || r.size != l.size:
+			uploads = append(uploads, rel)
+		case r.modTime != l.modTime:
+			sameSize = append(sameSize, rel)
+		default:
+			res.Unchanged++
+			if r.mode.Perm() != l.mode.Perm() {
+				chmods = append(chmods, rel)
+			}
+		}
+	}
+
+	// Files with the same content only need the modification time updated.
+	var touches []string
+	if len(sameSize) > 0 {
+		changed, unchanged, err := c.compareHashes(ctx, localDir, remoteDir, sameSize)
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, changed...)
+		touches = unchanged
+		res.Unchanged += len(unchanged)
+	}
+	sort.Strings(uploads)
+
+	var deletes []string
+	if opts.Delete {
+		for _, rel := range sortedKeys(remote) {
+			if _, ok := local[rel]; !ok {
+				deletes = append(deletes, rel)
+			}
+		}
+	}
+	// The replaced entries are removed but not reported as deleted, they are uploaded.
+	for _, rel := range removeChildren(append(replace, deletes...)) {
+		if err := sftpClient.RemoveAll(path.Join(remoteDir, rel)); err != nil {
+			return nil, fmt.Errorf("could not remove remote %s: %w", rel, err)
+		}
+	}
+	res.Deleted = removeChildren(deletes)
+
+	if err := sftpClient.MkdirAll(remoteDir); err != nil {
+		return nil, fmt.Errorf("could not create remote directory %s: %w", remoteDir, err)
+	}
+	for _, rel := range mkdirs {
+		if err := sftpClient.MkdirAll(path.Join(remoteDir, rel)); err != nil {
+			return nil, fmt.Errorf("could not create remote directory %s: %w", rel, err)
+		}
+		if err := sftpClient.Chmod(path.Join(remoteDir, rel), local[rel].mode.Perm()); err != nil {
+			c.logger.Debugf("Could not set permissions on %s: %v", rel, err)
+		}
+	}
+
+	progress := newCopyProgress(0, nil, 0)
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(opts.Concurrency)
+	for _, rel := range uploads {
+		job := copyJob{
+			src:  filepath.Join(localDir, filepath.FromSlash(rel)),
+			dst:  path.Join(remoteDir, rel),
+			mode: local[rel].mode,
+			rel:  rel,
+		}
+		g.Go(func() error {
+			return c.copyFileTo(gctx, sftpClient, job, DefaultCopyRequestsPerFile, progress, nil)
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	for _, rel := range append(uploads, touches...) {
+		mtime := time.Unix(local[rel].modTime, 0)
+		if err := sftpClient.Chtimes(path.Join(remoteDir, rel), time.Now(), mtime); err != nil {
+			return nil, fmt.Errorf("could not set remote modification time of %s: %w", rel, err)
+		}
+	}
+	for _, rel := range chmods {
+		if err := sftpClient.Chmod(path.Join(remoteDir, rel), local[rel].mode.Perm()); err != nil {
+			c.logger.Debugf("Could not set permissions on %s: %v", rel, err)
+		}
+	}
+
+	res.Uploaded = uploads
+	for _, rel := range uploads {
+		res.UploadedBytes += local[rel].size
+	}
+
+	return res, nil
+}
+
+// compareHashes compares the SHA-256 of the local and remote files, returning
+// the changed and unchanged ones.
+func (c *Client) compareHashes(ctx context.Context, localDir, remoteDir string, rels []string) (changed, unchanged []string, err error) {
+	remoteHashes, err := c.remoteHashes(ctx, remoteDir, rels)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, rel := range rels {
+		localHash, err := fileSHA256(filepath.Join(localDir, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, nil, err
+		}
+		if remoteHash, ok := remoteHashes[rel]; ok && remoteHash == localHash {
+			unchanged = append(unchanged, rel)
+			continue
+		}
+		changed = append(changed, rel)
+	}
+
+	return changed, unchanged, nil
+}
+
+// remoteHashes returns the SHA-256 of the remote files with a single command. The
+// files that could not be hashed (e.g. missing sha256sum) are not returned.
+func (c *Client) remoteHashes(ctx context.Context, remoteDir string, rels []string) (map[string]string, error) {
+	var stdin bytes.Buffer
+	for _, rel := range rels {
+		stdin.WriteString(rel)
+		stdin.WriteByte(0)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := fmt.Sprintf("cd %s && xargs -0 sha256sum --", shellQuote(remoteDir))
+	exitCode, err := c.Exec(ctx, cmd, ExecOpts{Stdin: &stdin, Stdout: &stdout, Stderr: &stderr})
+	if err != nil {
+		return nil, fmt.Errorf("could not hash remote files: %w", err)
+	}
+	if exitCode != 0 {
+		c.logger.Debugf("Remote hash exited with %d, unhashed files will be uploaded: %s", exitCode, strings.TrimSpace(stderr.String()))
+	}
+
+	return parseSHA256Sums(&stdout), nil
+}
+
+// parseSHA256Sums parses the sha256sum output into the hashes by file name.
+func parseSHA256Sums(r io.Reader) map[string]string {
+	hashes := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		// Escaped names (with backslashes or newlines) start with a backslash, they
+		// are not returned.
+		hash, name, ok := strings.Cut(line, " ")
+		if !ok || strings.HasPrefix(hash, `\`) || len(name) < 2 {
+			continue
+		}
+		// The name is prefixed by the mode, " " for text and "*" for binary.
+		hashes[name[1:]] = hash
+	}
+
+	return hashes
+}
+
+func fileSHA256(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", fmt.Errorf("could not open local file %s: %w", p, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("could not hash local file %s: %w", p, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// localSyncEntries returns the local directory entries by relative path, without
+// the root, the excluded paths and the symlinks.
+func localSyncEntries(localDir string, exclude func(string) bool) (map[string]syncEntry, error) {
+	entries := map[string]syncEntry{}
+	err := filepath.WalkDir(localDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(localDir, p)
+		if err != nil {
+			return err
+		}
+		rel := filepath.ToSlash(relPath)
+		if rel == "." {
+			return nil
+		}
+		if exclude(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Skip symlinks.
+		if d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entries[rel] = syncEntry{
+			size:    info.Size(),
+			modTime: info.ModTime().Unix(),
+			mode:    info.Mode(),
+			dir:     d.IsDir(),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not walk source: %w", err)
+	}
+
+	return entries, nil
+}
+
+// remoteSyncEntries returns the remote directory entries by relative path, without
+// the root and the excluded paths. A missing directory has no entries.
+func remoteSyncEntries(ctx context.Context, sftpClient *sftp.Client, remoteDir string, exclude func(string) bool) (map[string]syncEntry, error) {
+	entries := map[string]syncEntry{}
+
+	info, err := sftpClient.Stat(remoteDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries, nil
+		}
+		return nil, fmt.Errorf("could not stat remote directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("remote destination '%s' is not a directory", remoteDir)
+	}
+
+	walker := sftpClient.Walk(remoteDir)
+	for walker.Step() {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err := walker.Err(); err != nil {
+			return nil, err
+		}
+
+		relPath, err := filepath.Rel(remoteDir, walker.Path())
+		if err != nil {
+			return nil, err
+		}
+		rel := filepath.ToSlash(relPath)
+		if rel == "." {
+			continue
+		}
+
+		info := walker.Stat()
+		if exclude(rel) {
+			if info.IsDir() {
+				walker.SkipDir()
+			}
+			continue
+		}
+
+		entries[rel] = syncEntry{
+			size:    info.Size(),
+			modTime: info.ModTime().Unix(),
+			mode:    info.Mode(),
+			dir:     info.IsDir(),
+			symlink: info.Mode()&fs.ModeSymlink != 0,
+		}
+	}
+
+	return entries, nil
+}
+
+// removeChildren returns the sorted paths without the ones inside other paths of
+// the list, removing a directory removes its content.
+func removeChildren(rels []string) []string {
+	sorted := append([]string{}, rels...)
+	sort.Strings(sorted)
+
+	var res []string
+	kept := map[string]bool{}
+	for _, rel := range sorted {
+		if kept[rel] || hasParentIn(rel, kept) {
+			continue
+		}
+		kept[rel] = true
+		res = append(res, rel)
+	}
+	return res
+}
+
+func hasParentIn(rel string, parents map[string]bool) bool {
+	for p := path.Dir(rel); p != "." && p != "/"; p = path.Dir(p) {
+		if parents[p] {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]syncEntry) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,236 @@
+package ssh
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/log"
+)
+
+func TestClient_Sync(t *testing.T) {
+	privKey := generateTestKeyPair(t)
+	server := newTestSSHServer(t, privKey)
+	defer server.close()
+
+	host, port := testParseHostPort(t, server.addr)
+
+	t0 := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Hour)
+
+	tests := map[string]struct {
+		setup     func(t *testing.T, localDir, remoteDir string)
+		opts      SyncOpts
+		expResult *SyncResult
+		validate  func(t *testing.T, remoteDir string)
+	}{
+		"Syncing into an empty directory should upload all the files.": {
+			setup: func(t *testing.T, localDir, remoteDir string) {
+				testWriteFile(t, filepath.Join(localDir, "a.txt"), "aaa", t0)
+				testWriteFile(t, filepath.Join(localDir, "sub", "b.txt"), "bb", t0)
+			},
+			expResult: &SyncResult{Uploaded: []string{"a.txt", "sub/b.txt"}, UploadedBytes: 5},
+			validate: func(t *testing.T, remoteDir string) {
+				testAssertFile(t, filepath.Join(remoteDir, "sub", "b.txt"), "bb", t0)
+			},
+		},
+
+		"Syncing into a missing directory should create it.": {
+			setup: func(t *testing.T, localDir, remoteDir string) {
+				require.NoError(t, os.Remove(remoteDir))
+				testWriteFile(t, filepath.Join(localDir, "a.txt"), "aaa", t0)
+			},
+			expResult: &SyncResult{Uploaded: []string{"a.txt"}, UploadedBytes: 3},
+			validate: func(t *testing.T, remoteDir string) {
+				testAssertFile(t, filepath.Join(remoteDir, "a.txt"), "aaa", t0)
+			},
+		},
+
+		"Files with the same size and modification time should be skipped.": {
+			setup: func(t *testing.T, localDir, remoteDir string) {
+				testWriteFile(t, filepath.Join(localDir, "a.txt"), "aaa", t0)
+				testWriteFile(t, filepath.Join(remoteDir, "a.txt"), "xxx", t0)
+			},
+			expResult: &SyncResult{Unchanged: 1},
+			validate: func(t *testing.T, remoteDir string) {
+				testAssertFile(t, filepath.Join(remoteDir, "a.txt"), "xxx", t0)
+			},
+		},
+
+		"Files with a different size should be uploaded.": {
+			setup: func(t *testing.T, localDir, remoteDir string) {
+				testWriteFile(t, filepath.Join(localDir, "a.txt"), "aaaa", t0)
+				testWriteFile(t, filepath.Join(remoteDir, "a.txt"), "aaa", t0)
+			},
+			expResult: &SyncResult{Uploaded: []string{"a.txt"}, UploadedBytes: 4},
+			validate: func(t *testing.T, remoteDir string) {
+				testAssertFile(t, filepath.Join(remoteDir, "a.txt"), "aaaa", t0)
+			},
+		},
+
+		"Files with the same content and a different modification time should only update the time.": {
+			setup: func(t *testing.T, localDir, remoteDir string) {
+				testWriteFile(t, filepath.Join(localDir, "a.txt"), "aaa", t1)
+				testWriteFile(t, filepath.Join(remoteDir, "a.txt"), "aaa", t0)
+			},
+			expResult: &SyncResult{Unchanged: 1},
+			validate: func(t *testing.T, remoteDir string) {
+				testAssertFile(t, filepath.Join(remoteDir, "a.txt"), "aaa", t1)
+			},
+		},
+
+		"Files with the same size, different content and modification time should be uploaded.": {
+			setup: func(t *testing.T, localDir, remoteDir string) {
+				testWriteFile(t, filepath.Join(localDir, "a.txt"), "abc", t1)
+				testWriteFile(t, filepath.Join(remoteDir, "a.txt"), "xyz", t0)
+			},
+			expResult: &SyncResult{Uploaded: []string{"a.txt"}, UploadedBytes: 3},
+			validate: func(t *testing.T, remoteDir string) {
+				testAssertFile(t, filepath.Join(remoteDir, "a.txt"), "abc", t1)
+			},
+		},
+
+		"Remote files missing locally should be kept without delete.": {
+			setup: func(t *testing.T, localDir, remoteDir string) {
+				testWriteFile(t, filepath.Join(remoteDir, "old.txt"), "old", t0)
+			},
+			expResult: &SyncResult{},
+			validate: func(t *testing.T, remoteDir string) {
+				assert.FileExists(t, filepath.Join(remoteDir, "old.txt"))
+			},
+		},
+
+		"Remote files missing locally should be removed with delete.": {
+			setup: func(t *testing.T, localDir, remoteDir string) {
+				testWriteFile(t, filepath.Join(localDir, "a.txt"), "aaa", t0)
+				testWriteFile(t, filepath.Join(remoteDir, "a.txt"), "aaa", t0)
+				testWriteFile(t, filepath.Join(remoteDir, "old.txt"), "old", t0)
+				testWriteFile(t, filepath.Join(remoteDir, "olddir", "sub", "c.txt"), "c", t0)
+			},
+			opts:      SyncOpts{Delete: true},
+			expResult: &SyncResult{Unchanged: 1, Deleted: []string{"old.txt", "olddir"}},
+			validate: func(t *testing.T, remoteDir string) {
+				assert.NoFileExists(t, filepath.Join(remoteDir, "old.txt"))
+				assert.NoDirExists(t, filepath.Join(remoteDir, "olddir"))
+				assert.FileExists(t, filepath.Join(remoteDir, "a.txt"))
+			},
+		},
+
+		"Excluded paths should not be uploaded nor deleted.": {
+			setup: func(t *testing.T, localDir, remoteDir string) {
+				testWriteFile(t, filepath.Join(localDir, "a.txt"), "aaa", t0)
+				testWriteFile(t, filepath.Join(localDir, ".git", "HEAD"), "ref", t0)
+				testWriteFile(t, filepath.Join(remoteDir, ".git", "config"), "cfg", t0)
+			},
+			opts: SyncOpts{
+				Delete:  true,
+				Exclude: func(rel string) bool { return rel == ".git" },
+			},
+			expResult: &SyncResult{Uploaded: []string{"a.txt"}, UploadedBytes: 3},
+			validate: func(t *testing.T, remoteDir string) {
+				assert.NoFileExists(t, filepath.Join(remoteDir, ".git", "HEAD"))
+				assert.FileExists(t, filepath.Join(remoteDir, ".git", "config"))
+			},
+		},
+
+		"A remote directory replaced by a local file should be replaced.": {
+			setup: func(t *testing.T, localDir, remoteDir string) {
+				testWriteFile(t, filepath.Join(localDir, "x"), "file", t0)
+				testWriteFile(t, filepath.Join(remoteDir, "x", "y.txt"), "y", t0)
+			},
+			expResult: &SyncResult{Uploaded: []string{"x"}, UploadedBytes: 4},
+			validate: func(t *testing.T, remoteDir string) {
+				testAssertFile(t, filepath.Join(remoteDir, "x"), "file", t0)
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			client, err := NewClient(ctx, ClientConfig{
+				Host:       host,
+				Port:       port,
+				User:       "root",
+				PrivateKey: privKey,
+				Logger:     log.Noop,
+			})
+			require.NoError(err)
+			defer client.Close()
+
+			localDir := t.TempDir()
+			remoteDir := t.TempDir()
+			test.setup(t, localDir, remoteDir)
+
+			res, err := client.Sync(ctx, localDir, remoteDir, test.opts)
+			require.NoError(err)
+			assert.Equal(test.expResult, res)
+			if test.validate != nil {
+				test.validate(t, remoteDir)
+			}
+
+			// A second sync should have nothing to do.
+			res, err = client.Sync(ctx, localDir, remoteDir, test.opts)
+			require.NoError(err)
+			assert.Empty(res.Uploaded)
+			assert.Empty(res.Deleted)
+		})
+	}
+}
+
+func TestClient_SyncNotDirectory(t *testing.T) {
+	privKey := generateTestKeyPair(t)
+	server := newTestSSHServer(t, privKey)
+	defer server.close()
+
+	host, port := testParseHostPort(t, server.addr)
+	client, err := NewClient(context.Background(), ClientConfig{
+		Host:       host,
+		Port:       port,
+		User:       "root",
+		PrivateKey: privKey,
+		Logger:     log.Noop,
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	file := filepath.Join(t.TempDir(), "file.txt")
+	testWriteFile(t, file, "data", time.Now())
+
+	_, err = client.Sync(context.Background(), file, t.TempDir(), SyncOpts{})
+	assert.Error(t, err)
+
+	_, err = client.Sync(context.Background(), "/nonexistent/dir", t.TempDir(), SyncOpts{})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = client.Sync(context.Background(), t.TempDir(), file, SyncOpts{})
+	assert.Error(t, err)
+}
+
+func testWriteFile(t *testing.T, p, content string, mtime time.Time) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+	require.NoError(t, os.WriteFile(p, []byte(content), 0644))
+	require.NoError(t, os.Chtimes(p, mtime, mtime))
+}
+
+func testAssertFile(t *testing.T, p, content string, mtime time.Time) {
+	t.Helper()
+	data, err := os.ReadFile(p)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data))
+
+	info, err := os.Stat(p)
+	require.NoError(t, err)
+	assert.Equal(t, mtime.Unix(), info.ModTime().Unix())
+}
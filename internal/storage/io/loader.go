@@ -4,6 +4,11 @@ import (
 	"context"
 	"fmt"
 	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 
@@ -12,76 +17,166 @@ import (
 
 // SessionYAMLRepository loads session configuration from YAML files.
 type SessionYAMLRepository struct {
-	fs fs.FS
+	fs        fs.FS
+	lookupEnv func(string) (string, bool)
 }
 
 // NewSessionYAMLRepository creates a new YAML session config repository.
 func NewSessionYAMLRepository(filesystem fs.FS) *SessionYAMLRepository {
-	return &SessionYAMLRepository{fs: filesystem}
+	return &SessionYAMLRepository{fs: filesystem, lookupEnv: os.LookupEnv}
 }
 
 // GetSessionConfig loads a session configuration from a YAML file and returns a validated domain model.
+//
+// The environment variables in the values (`${VAR}`, `${VAR:-default}`, `${VAR:?message}`)
+// are replaced before decoding, and the files in `include` (relative to the file
+// including them) are loaded first and overridden by the including file.
 func (r *SessionYAMLRepository) GetSessionConfig(ctx context.Context, path string) (model.SessionConfig, error) {
-	data, err := fs.ReadFile(r.fs, path)
+	cfg, err := r.loadSessionConfig(ctx, path, nil)
 	if err != nil {
-		return model.SessionConfig{}, fmt.Errorf("reading session config file: %w", err)
+		return model.SessionConfig{}, err
+	}
+
+	m, err := cfg.toModel()
+	if err != nil {
+		return model.SessionConfig{}, fmt.Errorf("invalid session config: %w", err)
+	}
+
+	return m, nil
+}
+
+// loadSessionConfig loads a session file with its includes merged, parents are the
+// files including it.
+func (r *SessionYAMLRepository) loadSessionConfig(ctx context.Context, p string, parents []string) (SessionConfig, error) {
+	if slices.Contains(parents, p) {
+		return SessionConfig{}, fmt.Errorf("include cycle: %s -> %s: %w", strings.Join(parents, " -> "), p, model.ErrNotValid)
+	}
+
+	data, err := fs.ReadFile(r.fs, p)
+	if err != nil {
+		return SessionConfig{}, fmt.Errorf("reading session config file: %w", err)
 	}
 
 	if ctx.Err() != nil {
-		return model.SessionConfig{}, ctx.Err()
+		return SessionConfig{}, ctx.Err()
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return SessionConfig{}, fmt.Errorf("parsing YAML: %w", err)
+	}
+	if err := interpolateNode(&node, r.lookupEnv); err != nil {
+		return SessionConfig{}, fmt.Errorf("interpolating environment variables: %w", err)
 	}
 
 	var cfg SessionConfig
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return model.SessionConfig{}, fmt.Errorf("parsing YAML: %w", err)
+	if node.Kind != 0 {
+		if err := node.Decode(&cfg); err != nil {
+			return SessionConfig{}, fmt.Errorf("parsing YAML: %w", err)
+		}
 	}
 
-	m, err := cfg.toModel()
-	if err != nil {
-		return model.SessionConfig{}, fmt.Errorf("invalid session config: %w", err)
+	var merged SessionConfig
+	for _, inc := range cfg.Include {
+		incPath := strings.TrimPrefix(inc, "/")
+		if !path.IsAbs(inc) {
+			incPath = path.Join(path.Dir(p), inc)
+		}
+		incCfg, err := r.loadSessionConfig(ctx, incPath, append(slices.Clone(parents), p))
+		if err != nil {
+			return SessionConfig{}, fmt.Errorf("include %q: %w", inc, err)
+		}
+		merged = merged.merge(incCfg)
 	}
 
-	return m, nil
+	return merged.merge(cfg), nil
 }
 
 // SessionConfig represents the YAML structure for session configuration.
 type SessionConfig struct {
-	Name   string            `yaml:"name"`
-	Env    map[string]string `yaml:"env"`
-	Egress *EgressConfig     `yaml:"egress"`
+	Include      []string          `yaml:"include"`
+	Name         string            `yaml:"name"`
+	Env          map[string]string `yaml:"env"`
+	Egress       *EgressConfig     `yaml:"egress"`
+	EgressPolicy string            `yaml:"egress_policy"`
+	Integrity    *IntegrityConfig  `yaml:"integrity"`
+}
+
+// IntegrityConfig represents the YAML structure for the integrity baseline paths.
+type IntegrityConfig struct {
+	Paths []string `yaml:"paths"`
 }
 
 // EgressConfig represents the YAML structure for egress policy.
 type EgressConfig struct {
-	Default string       `yaml:"default"`
-	Rules   []EgressRule `yaml:"rules"`
+	Default      string       `yaml:"default"`
+	Rules        []EgressRule `yaml:"rules"`
+	FailClosed   bool         `yaml:"fail_closed"`
+	DNSUpstreams []string     `yaml:"dns_upstreams"`
+	// AllowSystemServices are the system services allowed with built-in rules (ntp, packages, registries).
+	AllowSystemServices []string `yaml:"allow_system_services"`
 }
 
 // EgressRule represents a single egress rule in YAML.
 type EgressRule struct {
 	Domain string `yaml:"domain"`
 	Action string `yaml:"action"`
+	ICMP   bool   `yaml:"icmp"`
+}
+
+// merge returns the config overridden by o: the name when set, the env variables
+// by key, the egress (inline or named) and the integrity paths when o sets them.
+func (c SessionConfig) merge(o SessionConfig) SessionConfig {
+	if o.Name != "" {
+		c.Name = o.Name
+	}
+	if len(o.Env) > 0 {
+		env := make(map[string]string, len(c.Env)+len(o.Env))
+		maps.Copy(env, c.Env)
+		maps.Copy(env, o.Env)
+		c.Env = env
+	}
+	if o.Egress != nil || o.EgressPolicy != "" {
+		c.Egress, c.EgressPolicy = o.Egress, o.EgressPolicy
+	}
+	if o.Integrity != nil {
+		c.Integrity = o.Integrity
+	}
+	c.Include = nil
+
+	return c
 }
 
 func (c SessionConfig) toModel() (model.SessionConfig, error) {
 	m := model.SessionConfig{
-		Name: c.Name,
-		Env:  c.Env,
+		Name:             c.Name,
+		Env:              c.Env,
+		EgressPolicyName: c.EgressPolicy,
+	}
+	if c.Integrity != nil {
+		m.IntegrityPaths = c.Integrity.Paths
 	}
 
 	if c.Egress != nil {
 		m.Egress = &model.EgressPolicy{
-			Default: model.EgressAction(c.Egress.Default),
+			Default:      model.EgressAction(c.Egress.Default),
+			FailClosed:   c.Egress.FailClosed,
+			DNSUpstreams: c.Egress.DNSUpstreams,
+		}
+		for _, s := range c.Egress.AllowSystemServices {
+			m.Egress.AllowSystemServices = append(m.Egress.AllowSystemServices, model.EgressSystemService(s))
 		}
 		for _, r := range c.Egress.Rules {
 			m.Egress.Rules = append(m.Egress.Rules, model.EgressRule{
 				Domain: r.Domain,
 				Action: model.EgressAction(r.Action),
+				ICMP:   r.ICMP,
 			})
 		}
-		if err := m.Egress.Validate(); err != nil {
-			return model.SessionConfig{}, err
-		}
+	}
+
+	if err := m.Validate(); err != nil {
+		return model.SessionConfig{}, err
 	}
 
 	return m, nil
@@ -14,6 +14,7 @@ import (
 func TestSessionYAMLRepository_GetSessionConfig(t *testing.T) {
 	tests := map[string]struct {
 		fs     fstest.MapFS
+		env    map[string]string
 		path   string
 		expCfg model.SessionConfig
 		expErr bool
@@ -105,6 +106,99 @@ egress:
 				},
 			},
 		},
+		"Session config with an ICMP egress rule should load successfully": {
+			fs: fstest.MapFS{
+				"session.yaml": &fstest.MapFile{
+					Data: []byte(`egress:
+  default: deny
+  rules:
+    - domain: "github.com"
+      action: allow
+      icmp: true
+`),
+				},
+			},
+			path: "session.yaml",
+			expCfg: model.SessionConfig{
+				Egress: &model.EgressPolicy{
+					Default: model.EgressActionDeny,
+					Rules:   []model.EgressRule{{Domain: "github.com", Action: model.EgressActionAllow, ICMP: true}},
+				},
+			},
+		},
+		"Session config with egress system services should load successfully": {
+			fs: fstest.MapFS{
+				"session.yaml": &fstest.MapFile{
+					Data: []byte(`egress:
+  default: deny
+  allow_system_services: [ntp, registries]
+`),
+				},
+			},
+			path: "session.yaml",
+			expCfg: model.SessionConfig{
+				Egress: &model.EgressPolicy{
+					Default:             model.EgressActionDeny,
+					AllowSystemServices: []model.EgressSystemService{model.EgressSystemServiceNTP, model.EgressSystemServiceRegistries},
+				},
+			},
+		},
+
+		"Session config with a fail-closed egress policy should load successfully": {
+			fs: fstest.MapFS{
+				"session.yaml": &fstest.MapFile{
+					Data: []byte(`egress:
+  default: deny
+  fail_closed: true
+  rules:
+    - domain: "github.com"
+      action: allow
+`),
+				},
+			},
+			path: "session.yaml",
+			expCfg: model.SessionConfig{
+				Egress: &model.EgressPolicy{
+					Default:    model.EgressActionDeny,
+					Rules:      []model.EgressRule{{Domain: "github.com", Action: model.EgressActionAllow}},
+					FailClosed: true,
+				},
+			},
+		},
+		"Session config with egress DNS upstreams should load successfully": {
+			fs: fstest.MapFS{
+				"session.yaml": &fstest.MapFile{
+					Data: []byte(`egress:
+  default: allow
+  dns_upstreams:
+    - "10.0.0.53"
+    - "tls://dns.example.internal"
+    - "https://cloudflare-dns.com/dns-query"
+`),
+				},
+			},
+			path: "session.yaml",
+			expCfg: model.SessionConfig{
+				Egress: &model.EgressPolicy{
+					Default:      model.EgressActionAllow,
+					DNSUpstreams: []string{"10.0.0.53", "tls://dns.example.internal", "https://cloudflare-dns.com/dns-query"},
+				},
+			},
+		},
+		"Egress DNS upstream with a plain hostname should return error": {
+			fs: fstest.MapFS{
+				"session.yaml": &fstest.MapFile{
+					Data: []byte(`egress:
+  default: allow
+  dns_upstreams:
+    - "dns.example.internal"
+`),
+				},
+			},
+			path:   "session.yaml",
+			expErr: true,
+			errMsg: "egress dns upstream[0]: invalid DNS resolver",
+		},
 		"Session config without egress should have nil egress": {
 			fs: fstest.MapFS{
 				"session.yaml": &fstest.MapFile{
@@ -120,6 +214,52 @@ env:
 				Env:  map[string]string{"FOO": "bar"},
 			},
 		},
+		"Session config with an egress policy name should load successfully": {
+			fs: fstest.MapFS{
+				"session.yaml": &fstest.MapFile{
+					Data: []byte(`egress_policy: github
+`),
+				},
+			},
+			path:   "session.yaml",
+			expCfg: model.SessionConfig{EgressPolicyName: "github"},
+		},
+		"Session config with integrity paths should load successfully": {
+			fs: fstest.MapFS{
+				"session.yaml": &fstest.MapFile{
+					Data: []byte(`integrity:
+  paths: [/etc, /usr/local/bin]
+`),
+				},
+			},
+			path:   "session.yaml",
+			expCfg: model.SessionConfig{IntegrityPaths: []string{"/etc", "/usr/local/bin"}},
+		},
+		"Session config with a relative integrity path should return error": {
+			fs: fstest.MapFS{
+				"session.yaml": &fstest.MapFile{
+					Data: []byte(`integrity:
+  paths: [etc]
+`),
+				},
+			},
+			path:   "session.yaml",
+			expErr: true,
+			errMsg: "must be a clean absolute path",
+		},
+		"Session config with egress and an egress policy name should return error": {
+			fs: fstest.MapFS{
+				"session.yaml": &fstest.MapFile{
+					Data: []byte(`egress_policy: github
+egress:
+  default: deny
+`),
+				},
+			},
+			path:   "session.yaml",
+			expErr: true,
+			errMsg: "can't be used at the same time",
+		},
 		"Invalid egress default should return error": {
 			fs: fstest.MapFS{
 				"session.yaml": &fstest.MapFile{
@@ -167,11 +307,172 @@ egress:
 			expErr: true,
 			errMsg: "action must be",
 		},
+		"Environment variables in the values should be replaced": {
+			fs: fstest.MapFS{
+				"session.yaml": &fstest.MapFile{
+					Data: []byte(`name: ${USER}-session
+env:
+  TOKEN: ${TOKEN}
+  REGION: ${REGION:-eu-west-1}
+  STAGE: ${STAGE:-dev}
+  MISSING: "${MISSING}"
+  PRICE: "$$5 and $HOME"
+egress:
+  default: deny
+  fail_closed: ${FAIL_CLOSED:-false}
+  rules:
+    - domain: ${REGISTRY:-registry.npmjs.org}
+      action: allow
+`),
+				},
+			},
+			env:  map[string]string{"USER": "alice", "TOKEN": "s3cr3t", "STAGE": "", "FAIL_CLOSED": "true"},
+			path: "session.yaml",
+			expCfg: model.SessionConfig{
+				Name: "alice-session",
+				Env: map[string]string{
+					"TOKEN":   "s3cr3t",
+					"REGION":  "eu-west-1",
+					"STAGE":   "dev",
+					"MISSING": "",
+					"PRICE":   "$5 and $HOME",
+				},
+				Egress: &model.EgressPolicy{
+					Default:    model.EgressActionDeny,
+					FailClosed: true,
+					Rules:      []model.EgressRule{{Domain: "registry.npmjs.org", Action: model.EgressActionAllow}},
+				},
+			},
+		},
+		"Environment variables in the keys should not be replaced": {
+			fs: fstest.MapFS{
+				"session.yaml": &fstest.MapFile{
+					Data: []byte(`env:
+  ${KEY}: value
+`),
+				},
+			},
+			env:    map[string]string{"KEY": "FOO"},
+			path:   "session.yaml",
+			expCfg: model.SessionConfig{Env: map[string]string{"${KEY}": "value"}},
+		},
+		"A missing required environment variable should return error": {
+			fs: fstest.MapFS{
+				"session.yaml": &fstest.MapFile{
+					Data: []byte(`env:
+  TOKEN: ${TOKEN:?set the CI token}
+`),
+				},
+			},
+			path:   "session.yaml",
+			expErr: true,
+			errMsg: "line 2: variable TOKEN is not set: set the CI token",
+		},
+		"An unclosed environment variable should return error": {
+			fs: fstest.MapFS{
+				"session.yaml": &fstest.MapFile{
+					Data: []byte(`name: ${NAME
+`),
+				},
+			},
+			path:   "session.yaml",
+			expErr: true,
+			errMsg: "unclosed variable",
+		},
+		"An invalid environment variable expression should return error": {
+			fs: fstest.MapFS{
+				"session.yaml": &fstest.MapFile{
+					Data: []byte(`name: ${NAME:+alt}
+`),
+				},
+			},
+			path:   "session.yaml",
+			expErr: true,
+			errMsg: "invalid variable",
+		},
+		"Included files should be loaded first and overridden by the including file": {
+			fs: fstest.MapFS{
+				"sessions/base/env.yaml": &fstest.MapFile{
+					Data: []byte(`name: base
+env:
+  EDITOR: vim
+  STAGE: dev
+egress:
+  default: allow
+`),
+				},
+				"sessions/base/egress.yaml": &fstest.MapFile{
+					Data: []byte(`egress:
+  default: deny
+  rules:
+    - domain: "github.com"
+      action: allow
+`),
+				},
+				"sessions/ci.yaml": &fstest.MapFile{
+					Data: []byte(`include:
+  - base/env.yaml
+  - ${EGRESS_FILE:-base/egress.yaml}
+env:
+  STAGE: ci
+`),
+				},
+			},
+			path: "sessions/ci.yaml",
+			expCfg: model.SessionConfig{
+				Name: "base",
+				Env:  map[string]string{"EDITOR": "vim", "STAGE": "ci"},
+				Egress: &model.EgressPolicy{
+					Default: model.EgressActionDeny,
+					Rules:   []model.EgressRule{{Domain: "github.com", Action: model.EgressActionAllow}},
+				},
+			},
+		},
+		"A named egress policy should override the included inline egress": {
+			fs: fstest.MapFS{
+				"base.yaml": &fstest.MapFile{
+					Data: []byte(`egress:
+  default: deny
+`),
+				},
+				"session.yaml": &fstest.MapFile{
+					Data: []byte(`include: [/base.yaml]
+egress_policy: github
+`),
+				},
+			},
+			path:   "session.yaml",
+			expCfg: model.SessionConfig{EgressPolicyName: "github"},
+		},
+		"A missing included file should return error": {
+			fs: fstest.MapFS{
+				"session.yaml": &fstest.MapFile{
+					Data: []byte(`include: [missing.yaml]
+`),
+				},
+			},
+			path:   "session.yaml",
+			expErr: true,
+			errMsg: `include "missing.yaml": reading session config file`,
+		},
+		"An include cycle should return error": {
+			fs: fstest.MapFS{
+				"a.yaml": &fstest.MapFile{Data: []byte("include: [b.yaml]\n")},
+				"b.yaml": &fstest.MapFile{Data: []byte("include: [a.yaml]\n")},
+			},
+			path:   "a.yaml",
+			expErr: true,
+			errMsg: "include cycle: a.yaml -> b.yaml -> a.yaml",
+		},
 	}
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
 			repo := NewSessionYAMLRepository(tc.fs)
+			repo.lookupEnv = func(k string) (string, bool) {
+				v, ok := tc.env[k]
+				return v, ok
+			}
 			cfg, err := repo.GetSessionConfig(context.Background(), tc.path)
 
 			if tc.expErr {
@@ -0,0 +1,113 @@
+package io
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// TaskYAMLRepository loads task definitions from YAML files.
+type TaskYAMLRepository struct {
+	fs        fs.FS
+	lookupEnv func(string) (string, bool)
+}
+
+// NewTaskYAMLRepository creates a new YAML task repository. The filesystem is
+// the host root filesystem, the task files are returned as absolute host paths.
+func NewTaskYAMLRepository(filesystem fs.FS) *TaskYAMLRepository {
+	return &TaskYAMLRepository{fs: filesystem, lookupEnv: os.LookupEnv}
+}
+
+// ListTasks loads the tasks of a YAML file sorted by name and validated.
+//
+// The environment variables in the values are replaced like in the session files,
+// and the relative task files are relative to the YAML file.
+func (r *TaskYAMLRepository) ListTasks(ctx context.Context, p string) ([]model.Task, error) {
+	data, err := fs.ReadFile(r.fs, p)
+	if err != nil {
+		return nil, fmt.Errorf("reading tasks file: %w", err)
+	}
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("parsing YAML: %w", err)
+	}
+	if err := interpolateNode(&node, r.lookupEnv); err != nil {
+		return nil, fmt.Errorf("interpolating environment variables: %w", err)
+	}
+
+	var cfg TasksConfig
+	if node.Kind != 0 {
+		if err := node.Decode(&cfg); err != nil {
+			return nil, fmt.Errorf("parsing YAML: %w", err)
+		}
+	}
+
+	tasks := make([]model.Task, 0, len(cfg.Tasks))
+	for name, t := range cfg.Tasks {
+		m, err := t.toModel(name, path.Dir(p))
+		if err != nil {
+			return nil, fmt.Errorf("invalid task: %w", err)
+		}
+		tasks = append(tasks, m)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].Name < tasks[j].Name })
+
+	return tasks, nil
+}
+
+// TasksConfig represents the YAML structure of the task definitions.
+type TasksConfig struct {
+	Tasks map[string]TaskConfig `yaml:"tasks"`
+}
+
+// TaskConfig represents a single task in YAML.
+type TaskConfig struct {
+	Command []string          `yaml:"command"`
+	Workdir string            `yaml:"workdir"`
+	Env     map[string]string `yaml:"env"`
+	Files   []string          `yaml:"files"`
+	Timeout string            `yaml:"timeout"`
+}
+
+func (c TaskConfig) toModel(name, dir string) (model.Task, error) {
+	m := model.Task{
+		Name:       name,
+		Command:    c.Command,
+		WorkingDir: c.Workdir,
+		Env:        c.Env,
+	}
+
+	for _, f := range c.Files {
+		if !path.IsAbs(f) {
+			f = "/" + path.Join(dir, f)
+		}
+		m.Files = append(m.Files, f)
+	}
+
+	if c.Timeout != "" {
+		d, err := time.ParseDuration(c.Timeout)
+		if err != nil {
+			return model.Task{}, fmt.Errorf("task %q timeout %q is invalid: %w", name, c.Timeout, model.ErrNotValid)
+		}
+		m.Timeout = d
+	}
+
+	if err := m.Validate(); err != nil {
+		return model.Task{}, err
+	}
+
+	return m, nil
+}
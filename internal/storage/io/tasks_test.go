@@ -0,0 +1,115 @@
+package io
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+func TestTaskYAMLRepository_ListTasks(t *testing.T) {
+	tests := map[string]struct {
+		fs       fstest.MapFS
+		env      map[string]string
+		path     string
+		expTasks []model.Task
+		expErr   bool
+	}{
+		"Valid tasks should load sorted by name": {
+			fs: fstest.MapFS{
+				"home/user/project/tasks.yaml": &fstest.MapFile{
+					Data: []byte(`tasks:
+  test:
+    command: [go, test, ./...]
+    workdir: /workspace
+    env:
+      CGO_ENABLED: "0"
+    files: [go.mod, /etc/hosts]
+    timeout: 10m
+  lint:
+    command: [golangci-lint, run]
+`),
+				},
+			},
+			path: "home/user/project/tasks.yaml",
+			expTasks: []model.Task{
+				{Name: "lint", Command: []string{"golangci-lint", "run"}},
+				{
+					Name:       "test",
+					Command:    []string{"go", "test", "./..."},
+					WorkingDir: "/workspace",
+					Env:        map[string]string{"CGO_ENABLED": "0"},
+					Files:      []string{"/home/user/project/go.mod", "/etc/hosts"},
+					Timeout:    10 * time.Minute,
+				},
+			},
+		},
+		"Environment variables should be interpolated": {
+			fs: fstest.MapFS{
+				"tasks.yaml": &fstest.MapFile{
+					Data: []byte(`tasks:
+  deploy:
+    command: [make, deploy]
+    env:
+      TARGET: ${TARGET:-staging}
+      TOKEN: ${DEPLOY_TOKEN}
+`),
+				},
+			},
+			env:  map[string]string{"DEPLOY_TOKEN": "s3cret"},
+			path: "tasks.yaml",
+			expTasks: []model.Task{
+				{Name: "deploy", Command: []string{"make", "deploy"}, Env: map[string]string{"TARGET": "staging", "TOKEN": "s3cret"}},
+			},
+		},
+		"An empty file should load without tasks": {
+			fs:       fstest.MapFS{"tasks.yaml": &fstest.MapFile{Data: []byte("---\n")}},
+			path:     "tasks.yaml",
+			expTasks: []model.Task{},
+		},
+		"A task without command should fail": {
+			fs:     fstest.MapFS{"tasks.yaml": &fstest.MapFile{Data: []byte("tasks:\n  test:\n    workdir: /workspace\n")}},
+			path:   "tasks.yaml",
+			expErr: true,
+		},
+		"A task with an invalid timeout should fail": {
+			fs:     fstest.MapFS{"tasks.yaml": &fstest.MapFile{Data: []byte("tasks:\n  test:\n    command: [ls]\n    timeout: soon\n")}},
+			path:   "tasks.yaml",
+			expErr: true,
+		},
+		"A task with an invalid name should fail": {
+			fs:     fstest.MapFS{"tasks.yaml": &fstest.MapFile{Data: []byte("tasks:\n  my task:\n    command: [ls]\n")}},
+			path:   "tasks.yaml",
+			expErr: true,
+		},
+		"A missing file should fail": {
+			fs:     fstest.MapFS{},
+			path:   "tasks.yaml",
+			expErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			repo := NewTaskYAMLRepository(tc.fs)
+			repo.lookupEnv = func(k string) (string, bool) {
+				v, ok := tc.env[k]
+				return v, ok
+			}
+
+			tasks, err := repo.ListTasks(context.Background(), tc.path)
+			if tc.expErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expTasks, tasks)
+		})
+	}
+}
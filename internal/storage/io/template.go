@@ -0,0 +1,115 @@
+package io
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+var envVarNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// interpolateNode replaces the environment variables in the scalar values of the
+// YAML tree, the mapping keys are left as they are. The plain scalars are resolved
+// again after the interpolation, so `${CPU:-2}` is an int and `"${CPU:-2}"` a string.
+func interpolateNode(n *yaml.Node, lookupEnv func(string) (string, bool)) error {
+	switch n.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, c := range n.Content {
+			if err := interpolateNode(c, lookupEnv); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(n.Content); i += 2 {
+			if err := interpolateNode(n.Content[i], lookupEnv); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		v, err := interpolate(n.Value, lookupEnv)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n.Line, err)
+		}
+		if v != n.Value {
+			n.Value = v
+			if n.Style == 0 {
+				n.Tag = ""
+			}
+		}
+	}
+
+	return nil
+}
+
+// interpolate replaces the environment variables of s:
+//
+//   - `${VAR}`: the value of VAR, empty when unset.
+//   - `${VAR:-default}`: the value of VAR, default when unset or empty.
+//   - `${VAR:?message}`: the value of VAR, fails with the message when unset or empty.
+//   - `$$`: a literal `$`.
+func interpolate(s string, lookupEnv func(string) (string, bool)) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(s, '$')
+		if i < 0 || i == len(s)-1 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		b.WriteString(s[:i])
+
+		switch s[i+1] {
+		case '$':
+			b.WriteByte('$')
+			s = s[i+2:]
+			continue
+		case '{':
+		default:
+			b.WriteByte('$')
+			s = s[i+1:]
+			continue
+		}
+
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unclosed variable in %q: %w", s[i:], model.ErrNotValid)
+		}
+		expr := s[i+2 : i+end]
+		s = s[i+end+1:]
+
+		name, op, arg := expr, "", ""
+		if j := strings.Index(expr, ":"); j >= 0 {
+			name, op = expr[:j], expr[j:min(j+2, len(expr))]
+			arg = expr[min(j+2, len(expr)):]
+		}
+		if !envVarNameRegexp.MatchString(name) {
+			return "", fmt.Errorf("invalid variable name %q: %w", name, model.ErrNotValid)
+		}
+
+		v, _ := lookupEnv(name)
+		switch op {
+		case "":
+		case ":-":
+			if v == "" {
+				v = arg
+			}
+		case ":?":
+			if v == "" {
+				if arg == "" {
+					arg = "required"
+				}
+				return "", fmt.Errorf("variable %s is not set: %s: %w", name, arg, model.ErrNotValid)
+			}
+		default:
+			return "", fmt.Errorf("invalid variable %q, use ${VAR}, ${VAR:-default} or ${VAR:?message}: %w", expr, model.ErrNotValid)
+		}
+		b.WriteString(v)
+	}
+}
@@ -3,7 +3,11 @@ package memory
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/slok/sbx/internal/log"
 	"github.com/slok/sbx/internal/model"
@@ -11,10 +15,19 @@ import (
 
 // RepositoryConfig is the configuration for the memory repository.
 type RepositoryConfig struct {
-	Logger log.Logger
+	// Namespace scopes the sandboxes of the repository, empty is the default namespace
+	// and model.AllNamespaces selects all of them.
+	Namespace string
+	Logger    log.Logger
 }
 
 func (c *RepositoryConfig) defaults() error {
+	ns, err := model.NamespaceScope(c.Namespace)
+	if err != nil {
+		return err
+	}
+	c.Namespace = ns
+
 	if c.Logger == nil {
 		c.Logger = log.Noop
 	}
@@ -24,9 +37,17 @@ func (c *RepositoryConfig) defaults() error {
 
 // Repository is an in-memory implementation of storage.Repository.
 type Repository struct {
-	sandboxes map[string]model.Sandbox
-	mu        sync.RWMutex
-	logger    log.Logger
+	sandboxes    map[string]model.Sandbox
+	execRecords  map[string][]model.ExecRecord
+	usageRecords []model.UsageRecord
+	policies     map[string]model.NamedEgressPolicy
+	templates    map[string]model.SandboxTemplate
+	tasks        map[taskKey]model.Task
+	baselines    map[string]model.IntegrityBaseline
+	locks        map[string]bool
+	namespace    string
+	mu           sync.RWMutex
+	logger       log.Logger
 }
 
 // NewRepository creates a new memory repository.
@@ -36,8 +57,15 @@ func NewRepository(cfg RepositoryConfig) (*Repository, error) {
 	}
 
 	return &Repository{
-		sandboxes: make(map[string]model.Sandbox),
-		logger:    cfg.Logger,
+		sandboxes:   make(map[string]model.Sandbox),
+		execRecords: make(map[string][]model.ExecRecord),
+		policies:    make(map[string]model.NamedEgressPolicy),
+		templates:   make(map[string]model.SandboxTemplate),
+		tasks:       make(map[taskKey]model.Task),
+		baselines:   make(map[string]model.IntegrityBaseline),
+		locks:       make(map[string]bool),
+		namespace:   cfg.Namespace,
+		logger:      cfg.Logger,
 	}, nil
 }
 
@@ -46,14 +74,21 @@ func (r *Repository) CreateSandbox(ctx context.Context, s model.Sandbox) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if s.Namespace == "" {
+		s.Namespace = r.namespace
+	}
+	if err := model.ValidateNamespaceInScope(r.namespace, s.Namespace); err != nil {
+		return err
+	}
+
 	// Check if ID already exists
 	if _, ok := r.sandboxes[s.ID]; ok {
 		return fmt.Errorf("sandbox with id %s: %w", s.ID, model.ErrAlreadyExists)
 	}
 
-	// Check if name already exists
+	// Check if name already exists in the namespace
 	for _, existing := range r.sandboxes {
-		if existing.Name == s.Name {
+		if existing.Namespace == s.Namespace && existing.Name == s.Name {
 			return fmt.Errorf("sandbox with name %s: %w", s.Name, model.ErrAlreadyExists)
 		}
 	}
@@ -70,7 +105,7 @@ func (r *Repository) GetSandbox(ctx context.Context, id string) (*model.Sandbox,
 	defer r.mu.RUnlock()
 
 	sandbox, ok := r.sandboxes[id]
-	if !ok {
+	if !ok || !r.inScope(sandbox) {
 		return nil, fmt.Errorf("sandbox %s: %w", id, model.ErrNotFound)
 	}
 
@@ -79,61 +114,565 @@ func (r *Repository) GetSandbox(ctx context.Context, id string) (*model.Sandbox,
 	return &sandboxCopy, nil
 }
 
-// GetSandboxByName retrieves a sandbox by name.
+// GetSandboxByName retrieves a sandbox by name. With all the namespaces the name
+// must be used by a single sandbox.
 func (r *Repository) GetSandboxByName(ctx context.Context, name string) (*model.Sandbox, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
+	var found *model.Sandbox
 	for _, sandbox := range r.sandboxes {
-		if sandbox.Name == name {
-			// Return a copy
-			sandboxCopy := sandbox
-			return &sandboxCopy, nil
+		if sandbox.Name != name || !r.inScope(sandbox) {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("sandbox name %s is used in several namespaces, use the sandbox ID: %w", name, model.ErrNotValid)
 		}
+		// Return a copy
+		sandboxCopy := sandbox
+		found = &sandboxCopy
+	}
+	if found == nil {
+		return nil, fmt.Errorf("sandbox with name %s: %w", name, model.ErrNotFound)
 	}
 
-	return nil, fmt.Errorf("sandbox with name %s: %w", name, model.ErrNotFound)
+	return found, nil
 }
 
-// ListSandboxes returns all sandboxes.
+// ListSandboxes returns the sandboxes of the repository namespace.
 func (r *Repository) ListSandboxes(ctx context.Context) ([]model.Sandbox, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	sandboxes := make([]model.Sandbox, 0, len(r.sandboxes))
 	for _, sandbox := range r.sandboxes {
-		sandboxes = append(sandboxes, sandbox)
+		if r.inScope(sandbox) {
+			sandboxes = append(sandboxes, sandbox)
+		}
 	}
 
 	return sandboxes, nil
 }
 
+// ListAllSandboxes returns the sandboxes of all the namespaces.
+func (r *Repository) ListAllSandboxes(ctx context.Context) ([]model.Sandbox, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Collect(maps.Values(r.sandboxes)), nil
+}
+
 // UpdateSandbox updates an existing sandbox.
 func (r *Repository) UpdateSandbox(ctx context.Context, s model.Sandbox) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, ok := r.sandboxes[s.ID]; !ok {
+	stored, ok := r.sandboxes[s.ID]
+	if !ok || !r.inScope(stored) {
 		return fmt.Errorf("sandbox %s: %w", s.ID, model.ErrNotFound)
 	}
 
+	s.Namespace = stored.Namespace
+	s.Annotations = stored.Annotations
+	s.IdlePolicy = stored.IdlePolicy
+	s.Activity = stored.Activity
+	s.QuarantinedAt = stored.QuarantinedAt
+	s.Persistent = stored.Persistent
 	r.sandboxes[s.ID] = s
 	r.logger.Debugf("Updated sandbox in repository: %s", s.ID)
 
 	return nil
 }
 
+// UpdateSandboxAnnotations replaces the annotations of a sandbox.
+func (r *Repository) UpdateSandboxAnnotations(ctx context.Context, id string, annotations map[string]string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sandbox, ok := r.sandboxes[id]
+	if !ok || !r.inScope(sandbox) {
+		return fmt.Errorf("sandbox %s: %w", id, model.ErrNotFound)
+	}
+
+	sandbox.Annotations = maps.Clone(annotations)
+	r.sandboxes[id] = sandbox
+	r.logger.Debugf("Updated sandbox annotations in repository: %s", id)
+
+	return nil
+}
+
+// UpdateSandboxIdlePolicy replaces the idle policy of a sandbox, nil removes it.
+func (r *Repository) UpdateSandboxIdlePolicy(ctx context.Context, id string, policy *model.IdlePolicy) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sandbox, ok := r.sandboxes[id]
+	if !ok || !r.inScope(sandbox) {
+		return fmt.Errorf("sandbox %s: %w", id, model.ErrNotFound)
+	}
+
+	sandbox.IdlePolicy = nil
+	if policy != nil {
+		p := *policy
+		sandbox.IdlePolicy = &p
+	}
+	r.sandboxes[id] = sandbox
+	r.logger.Debugf("Updated sandbox idle policy in repository: %s", id)
+
+	return nil
+}
+
+// UpdateSandboxLastActivity sets the last activity time of a sandbox, unless the
+// stored one is later.
+func (r *Repository) UpdateSandboxLastActivity(ctx context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sandbox, ok := r.sandboxes[id]
+	if !ok || !r.inScope(sandbox) {
+		return fmt.Errorf("sandbox %s: %w", id, model.ErrNotFound)
+	}
+
+	at = at.UTC().Truncate(time.Second)
+	if at.After(sandbox.Activity.LastActivityAt) {
+		sandbox.Activity.LastActivityAt = at
+		r.sandboxes[id] = sandbox
+	}
+
+	return nil
+}
+
+// UpdateSandboxNetworkBytes sets the network bytes of a sandbox seen on the last
+// idle check.
+func (r *Repository) UpdateSandboxNetworkBytes(ctx context.Context, id string, bytes uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sandbox, ok := r.sandboxes[id]
+	if !ok || !r.inScope(sandbox) {
+		return fmt.Errorf("sandbox %s: %w", id, model.ErrNotFound)
+	}
+
+	sandbox.Activity.NetworkBytes = bytes
+	r.sandboxes[id] = sandbox
+
+	return nil
+}
+
+// UpdateSandboxQuarantine sets the quarantine time of a sandbox, nil lifts the
+// quarantine.
+func (r *Repository) UpdateSandboxQuarantine(ctx context.Context, id string, at *time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sandbox, ok := r.sandboxes[id]
+	if !ok || !r.inScope(sandbox) {
+		return fmt.Errorf("sandbox %s: %w", id, model.ErrNotFound)
+	}
+
+	sandbox.QuarantinedAt = nil
+	if at != nil {
+		t := at.UTC().Truncate(time.Second)
+		sandbox.QuarantinedAt = &t
+	}
+	r.sandboxes[id] = sandbox
+	r.logger.Debugf("Updated sandbox quarantine in repository: %s", id)
+
+	return nil
+}
+
+// UpdateSandboxPersistent sets if a sandbox is started again by the recovery.
+func (r *Repository) UpdateSandboxPersistent(ctx context.Context, id string, persistent bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sandbox, ok := r.sandboxes[id]
+	if !ok || !r.inScope(sandbox) {
+		return fmt.Errorf("sandbox %s: %w", id, model.ErrNotFound)
+	}
+
+	sandbox.Persistent = persistent
+	r.sandboxes[id] = sandbox
+	r.logger.Debugf("Updated sandbox persistence in repository: %s", id)
+
+	return nil
+}
+
 // DeleteSandbox deletes a sandbox.
 func (r *Repository) DeleteSandbox(ctx context.Context, id string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, ok := r.sandboxes[id]; !ok {
+	if sandbox, ok := r.sandboxes[id]; !ok || !r.inScope(sandbox) {
 		return fmt.Errorf("sandbox %s: %w", id, model.ErrNotFound)
 	}
 
 	delete(r.sandboxes, id)
+	delete(r.execRecords, id)
+	for k := range r.tasks {
+		if k.sandboxID == id {
+			delete(r.tasks, k)
+		}
+	}
+	delete(r.baselines, id)
 	r.logger.Debugf("Deleted sandbox from repository: %s", id)
 
 	return nil
 }
+
+// LockSandbox takes the sandbox operation lock, it's only shared inside the process.
+func (r *Repository) LockSandbox(ctx context.Context, id string) (func(), error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.locks[id] {
+		return nil, fmt.Errorf("sandbox %s is locked by another operation: %w", id, model.ErrConflict)
+	}
+	r.locks[id] = true
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			delete(r.locks, id)
+		})
+	}, nil
+}
+
+// CreateExecRecord stores the exec audit record of a sandbox.
+func (r *Repository) CreateExecRecord(ctx context.Context, rec model.ExecRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.sandboxes[rec.SandboxID]; !ok {
+		return fmt.Errorf("sandbox %s: %w", rec.SandboxID, model.ErrNotFound)
+	}
+
+	r.execRecords[rec.SandboxID] = append(r.execRecords[rec.SandboxID], rec)
+	return nil
+}
+
+// ListExecRecords returns the exec audit records of a sandbox, oldest first.
+func (r *Repository) ListExecRecords(ctx context.Context, sandboxID string, filter model.ExecRecordFilter) ([]model.ExecRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	records := []model.ExecRecord{}
+	for _, rec := range r.execRecords[sandboxID] {
+		if rec.StartedAt.Before(filter.Since) {
+			continue
+		}
+		records = append(records, rec)
+	}
+
+	if filter.Limit > 0 && len(records) > filter.Limit {
+		records = records[len(records)-filter.Limit:]
+	}
+
+	return records, nil
+}
+
+// CreateUsageRecord stores a usage period of a sandbox.
+func (r *Repository) CreateUsageRecord(ctx context.Context, rec model.UsageRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if rec.Namespace == "" {
+		rec.Namespace = r.namespace
+	}
+	if err := model.ValidateNamespaceInScope(r.namespace, rec.Namespace); err != nil {
+		return err
+	}
+
+	rec.Annotations = maps.Clone(rec.Annotations)
+	r.usageRecords = append(r.usageRecords, rec)
+	return nil
+}
+
+// ListUsageRecords returns the usage records of the repository namespace overlapping
+// the filter time range, oldest first.
+func (r *Repository) ListUsageRecords(ctx context.Context, filter model.UsageRecordFilter) ([]model.UsageRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	records := []model.UsageRecord{}
+	for _, rec := range r.usageRecords {
+		if r.namespace != model.AllNamespaces && rec.Namespace != r.namespace {
+			continue
+		}
+		if !filter.Since.IsZero() && !rec.EndedAt.After(filter.Since) {
+			continue
+		}
+		if !filter.Until.IsZero() && !rec.StartedAt.Before(filter.Until) {
+			continue
+		}
+		records = append(records, rec)
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].StartedAt.Before(records[j].StartedAt) })
+
+	return records, nil
+}
+
+// CreateEgressPolicy stores a named egress policy.
+func (r *Repository) CreateEgressPolicy(ctx context.Context, p model.NamedEgressPolicy) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.policies[p.Name]; ok {
+		return fmt.Errorf("egress policy %s already exists: %w", p.Name, model.ErrAlreadyExists)
+	}
+
+	r.policies[p.Name] = copyNamedEgressPolicy(p)
+	return nil
+}
+
+// GetEgressPolicy retrieves a named egress policy.
+func (r *Repository) GetEgressPolicy(ctx context.Context, name string) (*model.NamedEgressPolicy, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.policies[name]
+	if !ok {
+		return nil, fmt.Errorf("egress policy %s: %w", name, model.ErrNotFound)
+	}
+
+	p = copyNamedEgressPolicy(p)
+	return &p, nil
+}
+
+// ListEgressPolicies returns all the named egress policies sorted by name.
+func (r *Repository) ListEgressPolicies(ctx context.Context) ([]model.NamedEgressPolicy, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	policies := make([]model.NamedEgressPolicy, 0, len(r.policies))
+	for _, p := range r.policies {
+		policies = append(policies, copyNamedEgressPolicy(p))
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Name < policies[j].Name })
+
+	return policies, nil
+}
+
+// UpdateEgressPolicy replaces the policy of a named egress policy.
+func (r *Repository) UpdateEgressPolicy(ctx context.Context, p model.NamedEgressPolicy) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.policies[p.Name]
+	if !ok {
+		return fmt.Errorf("egress policy %s: %w", p.Name, model.ErrNotFound)
+	}
+
+	stored.Policy = p.Policy
+	stored.UpdatedAt = p.UpdatedAt
+	r.policies[p.Name] = copyNamedEgressPolicy(stored)
+	return nil
+}
+
+// DeleteEgressPolicy deletes a named egress policy.
+func (r *Repository) DeleteEgressPolicy(ctx context.Context, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.policies[name]; !ok {
+		return fmt.Errorf("egress policy %s: %w", name, model.ErrNotFound)
+	}
+
+	delete(r.policies, name)
+	return nil
+}
+
+func copyNamedEgressPolicy(p model.NamedEgressPolicy) model.NamedEgressPolicy {
+	p.Policy.Rules = slices.Clone(p.Policy.Rules)
+	p.Policy.DNSUpstreams = slices.Clone(p.Policy.DNSUpstreams)
+	return p
+}
+
+// CreateSandboxTemplate stores a sandbox template.
+func (r *Repository) CreateSandboxTemplate(ctx context.Context, t model.SandboxTemplate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.templates[t.Name]; ok {
+		return fmt.Errorf("template %s already exists: %w", t.Name, model.ErrAlreadyExists)
+	}
+
+	r.templates[t.Name] = copySandboxTemplate(t)
+	return nil
+}
+
+// GetSandboxTemplate retrieves a sandbox template.
+func (r *Repository) GetSandboxTemplate(ctx context.Context, name string) (*model.SandboxTemplate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("template %s: %w", name, model.ErrNotFound)
+	}
+
+	t = copySandboxTemplate(t)
+	return &t, nil
+}
+
+// ListSandboxTemplates returns all the sandbox templates sorted by name.
+func (r *Repository) ListSandboxTemplates(ctx context.Context) ([]model.SandboxTemplate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	templates := make([]model.SandboxTemplate, 0, len(r.templates))
+	for _, t := range r.templates {
+		templates = append(templates, copySandboxTemplate(t))
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+
+	return templates, nil
+}
+
+// DeleteSandboxTemplate deletes a sandbox template.
+func (r *Repository) DeleteSandboxTemplate(ctx context.Context, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.templates[name]; !ok {
+		return fmt.Errorf("template %s: %w", name, model.ErrNotFound)
+	}
+
+	delete(r.templates, name)
+	return nil
+}
+
+func copySandboxTemplate(t model.SandboxTemplate) model.SandboxTemplate {
+	t.Ports = maps.Clone(t.Ports)
+	t.Env = maps.Clone(t.Env)
+	if t.ExecProfile != nil {
+		p := *t.ExecProfile
+		p.PathPrepend = slices.Clone(p.PathPrepend)
+		p.Ulimits = maps.Clone(p.Ulimits)
+		t.ExecProfile = &p
+	}
+	if t.Egress != nil {
+		e := *t.Egress
+		e.Rules = slices.Clone(e.Rules)
+		e.DNSUpstreams = slices.Clone(e.DNSUpstreams)
+		t.Egress = &e
+	}
+	return t
+}
+
+// taskKey identifies a task, the global ones have an empty sandbox ID.
+type taskKey struct {
+	sandboxID string
+	name      string
+}
+
+// SaveTask stores a task, replacing the task of the same sandbox with the same name
+// (its creation time is kept).
+func (r *Repository) SaveTask(ctx context.Context, t model.Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.sandboxes[t.SandboxID]; t.SandboxID != "" && !ok {
+		return fmt.Errorf("sandbox %s: %w", t.SandboxID, model.ErrNotFound)
+	}
+
+	k := taskKey{sandboxID: t.SandboxID, name: t.Name}
+	if stored, ok := r.tasks[k]; ok {
+		t.CreatedAt = stored.CreatedAt
+	}
+	r.tasks[k] = copyTask(t)
+	return nil
+}
+
+// GetTask retrieves a task of a sandbox, a global one with an empty sandbox ID.
+func (r *Repository) GetTask(ctx context.Context, sandboxID, name string) (*model.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.tasks[taskKey{sandboxID: sandboxID, name: name}]
+	if !ok {
+		return nil, fmt.Errorf("task %s: %w", name, model.ErrNotFound)
+	}
+
+	t = copyTask(t)
+	return &t, nil
+}
+
+// ListTasks returns the tasks of a sandbox sorted by name, the global ones with an
+// empty sandbox ID.
+func (r *Repository) ListTasks(ctx context.Context, sandboxID string) ([]model.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tasks := []model.Task{}
+	for k, t := range r.tasks {
+		if k.sandboxID == sandboxID {
+			tasks = append(tasks, copyTask(t))
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].Name < tasks[j].Name })
+
+	return tasks, nil
+}
+
+// DeleteTask deletes a task of a sandbox, a global one with an empty sandbox ID.
+func (r *Repository) DeleteTask(ctx context.Context, sandboxID, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := taskKey{sandboxID: sandboxID, name: name}
+	if _, ok := r.tasks[k]; !ok {
+		return fmt.Errorf("task %s: %w", name, model.ErrNotFound)
+	}
+
+	delete(r.tasks, k)
+	return nil
+}
+
+func copyTask(t model.Task) model.Task {
+	t.Command = slices.Clone(t.Command)
+	t.Env = maps.Clone(t.Env)
+	t.Files = slices.Clone(t.Files)
+	return t
+}
+
+// SaveIntegrityBaseline stores the integrity baseline of a sandbox, replacing the
+// previous one.
+func (r *Repository) SaveIntegrityBaseline(ctx context.Context, b model.IntegrityBaseline) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.sandboxes[b.SandboxID]; !ok {
+		return fmt.Errorf("sandbox %s: %w", b.SandboxID, model.ErrNotFound)
+	}
+
+	r.baselines[b.SandboxID] = copyIntegrityBaseline(b)
+	return nil
+}
+
+// GetIntegrityBaseline retrieves the integrity baseline of a sandbox.
+func (r *Repository) GetIntegrityBaseline(ctx context.Context, sandboxID string) (*model.IntegrityBaseline, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	b, ok := r.baselines[sandboxID]
+	if !ok {
+		return nil, fmt.Errorf("integrity baseline of sandbox %s: %w", sandboxID, model.ErrNotFound)
+	}
+
+	b = copyIntegrityBaseline(b)
+	return &b, nil
+}
+
+func copyIntegrityBaseline(b model.IntegrityBaseline) model.IntegrityBaseline {
+	b.Paths = slices.Clone(b.Paths)
+	b.Files = maps.Clone(b.Files)
+	return b
+}
+
+// inScope must be called with the lock held.
+func (r *Repository) inScope(s model.Sandbox) bool {
+	return r.namespace == model.AllNamespaces || s.Namespace == r.namespace
+}
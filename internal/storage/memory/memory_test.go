@@ -253,6 +253,49 @@ func TestRepositoryCRUD(t *testing.T) {
 			},
 		},
 
+		"Exec records should be listed oldest first and filtered": {
+			actions: func(ctx context.Context, t *testing.T, repo *memory.Repository) error {
+				err := repo.CreateSandbox(ctx, model.Sandbox{ID: "test-id", Name: "test"})
+				require.NoError(t, err)
+
+				t0 := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+				for i := range 3 {
+					err := repo.CreateExecRecord(ctx, model.ExecRecord{
+						ID:        fmt.Sprintf("rec-%d", i),
+						SandboxID: "test-id",
+						Command:   []string{"ls"},
+						StartedAt: t0.Add(time.Duration(i) * time.Second),
+					})
+					require.NoError(t, err)
+				}
+
+				recs, err := repo.ListExecRecords(ctx, "test-id", model.ExecRecordFilter{Limit: 2})
+				require.NoError(t, err)
+				require.Len(t, recs, 2)
+				assert.Equal(t, "rec-1", recs[0].ID)
+				assert.Equal(t, "rec-2", recs[1].ID)
+
+				recs, err = repo.ListExecRecords(ctx, "test-id", model.ExecRecordFilter{Since: t0.Add(2 * time.Second)})
+				require.NoError(t, err)
+				require.Len(t, recs, 1)
+				assert.Equal(t, "rec-2", recs[0].ID)
+
+				require.NoError(t, repo.DeleteSandbox(ctx, "test-id"))
+				recs, err = repo.ListExecRecords(ctx, "test-id", model.ExecRecordFilter{})
+				require.NoError(t, err)
+				assert.Empty(t, recs)
+
+				return nil
+			},
+		},
+
+		"Creating an exec record of a non-existent sandbox should fail": {
+			actions: func(ctx context.Context, t *testing.T, repo *memory.Repository) error {
+				return repo.CreateExecRecord(ctx, model.ExecRecord{ID: "rec", SandboxID: "non-existent"})
+			},
+			expErr: true,
+		},
+
 		"Deleting non-existent sandbox should fail": {
 			actions: func(ctx context.Context, t *testing.T, repo *memory.Repository) error {
 				return repo.DeleteSandbox(ctx, "non-existent")
@@ -280,3 +323,408 @@ func TestRepositoryCRUD(t *testing.T) {
 		})
 	}
 }
+
+func TestRepositoryLockSandbox(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	repo, err := memory.NewRepository(memory.RepositoryConfig{Logger: log.Noop})
+	require.NoError(err)
+
+	unlock, err := repo.LockSandbox(ctx, "sb-1")
+	require.NoError(err)
+
+	_, err = repo.LockSandbox(ctx, "sb-1")
+	assert.ErrorIs(err, model.ErrConflict)
+	unlock2, err := repo.LockSandbox(ctx, "sb-2")
+	require.NoError(err)
+	unlock2()
+
+	// Releasing twice should not release other holders.
+	unlock()
+	unlock3, err := repo.LockSandbox(ctx, "sb-1")
+	require.NoError(err)
+	unlock()
+	_, err = repo.LockSandbox(ctx, "sb-1")
+	assert.ErrorIs(err, model.ErrConflict)
+	unlock3()
+}
+
+func TestRepositoryEgressPolicies(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	repo, err := memory.NewRepository(memory.RepositoryConfig{Logger: log.Noop})
+	require.NoError(err)
+
+	t0 := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	policy := model.NamedEgressPolicy{
+		Name: "github",
+		Policy: model.EgressPolicy{
+			Default: model.EgressActionDeny,
+			Rules:   []model.EgressRule{{Domain: "*.github.com", Action: model.EgressActionAllow}},
+		},
+		CreatedAt: t0,
+		UpdatedAt: t0,
+	}
+	require.NoError(repo.CreateEgressPolicy(ctx, policy))
+	require.NoError(repo.CreateEgressPolicy(ctx, model.NamedEgressPolicy{Name: "allow-all", Policy: model.EgressPolicy{Default: model.EgressActionAllow}}))
+	assert.ErrorIs(repo.CreateEgressPolicy(ctx, policy), model.ErrAlreadyExists)
+
+	got, err := repo.GetEgressPolicy(ctx, "github")
+	require.NoError(err)
+	assert.Equal(policy, *got)
+
+	list, err := repo.ListEgressPolicies(ctx)
+	require.NoError(err)
+	require.Len(list, 2)
+	assert.Equal("allow-all", list[0].Name)
+	assert.Equal("github", list[1].Name)
+
+	// Updating replaces the policy and keeps the creation time.
+	updated := policy
+	updated.Policy = model.EgressPolicy{Default: model.EgressActionAllow}
+	updated.CreatedAt = t0.Add(time.Hour)
+	updated.UpdatedAt = t0.Add(time.Hour)
+	require.NoError(repo.UpdateEgressPolicy(ctx, updated))
+	got, err = repo.GetEgressPolicy(ctx, "github")
+	require.NoError(err)
+	assert.Equal(model.EgressPolicy{Default: model.EgressActionAllow}, got.Policy)
+	assert.Equal(t0, got.CreatedAt)
+	assert.Equal(t0.Add(time.Hour), got.UpdatedAt)
+	assert.ErrorIs(repo.UpdateEgressPolicy(ctx, model.NamedEgressPolicy{Name: "missing"}), model.ErrNotFound)
+
+	require.NoError(repo.DeleteEgressPolicy(ctx, "github"))
+	_, err = repo.GetEgressPolicy(ctx, "github")
+	assert.ErrorIs(err, model.ErrNotFound)
+	assert.ErrorIs(repo.DeleteEgressPolicy(ctx, "github"), model.ErrNotFound)
+}
+
+func TestRepositorySandboxTemplates(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	repo, err := memory.NewRepository(memory.RepositoryConfig{Logger: log.Noop})
+	require.NoError(err)
+
+	t0 := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	tpl := model.SandboxTemplate{
+		Name:      "go-dev",
+		Engine:    model.TemplateEngineFirecracker,
+		Image:     "v0.1.0",
+		Resources: model.Resources{VCPUs: 2, MemoryMB: 2048, DiskGB: 10},
+		Env:       map[string]string{"GOFLAGS": "-mod=mod"},
+		CreatedAt: t0,
+		UpdatedAt: t0,
+	}
+	require.NoError(repo.CreateSandboxTemplate(ctx, tpl))
+	require.NoError(repo.CreateSandboxTemplate(ctx, model.SandboxTemplate{Name: "fake", Engine: model.TemplateEngineFake}))
+	assert.ErrorIs(repo.CreateSandboxTemplate(ctx, tpl), model.ErrAlreadyExists)
+
+	// The stored template is a copy.
+	got, err := repo.GetSandboxTemplate(ctx, "go-dev")
+	require.NoError(err)
+	assert.Equal(tpl, *got)
+	got.Env["GOFLAGS"] = "changed"
+	got, err = repo.GetSandboxTemplate(ctx, "go-dev")
+	require.NoError(err)
+	assert.Equal("-mod=mod", got.Env["GOFLAGS"])
+
+	list, err := repo.ListSandboxTemplates(ctx)
+	require.NoError(err)
+	require.Len(list, 2)
+	assert.Equal("fake", list[0].Name)
+	assert.Equal("go-dev", list[1].Name)
+
+	require.NoError(repo.DeleteSandboxTemplate(ctx, "go-dev"))
+	_, err = repo.GetSandboxTemplate(ctx, "go-dev")
+	assert.ErrorIs(err, model.ErrNotFound)
+	assert.ErrorIs(repo.DeleteSandboxTemplate(ctx, "go-dev"), model.ErrNotFound)
+}
+
+func TestRepositoryTasks(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	repo, err := memory.NewRepository(memory.RepositoryConfig{Logger: log.Noop})
+	require.NoError(err)
+
+	t0 := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	global := model.Task{
+		Name:       "test",
+		Command:    []string{"go", "test", "./..."},
+		WorkingDir: "/src",
+		Env:        map[string]string{"CGO_ENABLED": "0"},
+		Files:      []string{"/home/user/test.env"},
+		Timeout:    10 * time.Minute,
+		CreatedAt:  t0,
+		UpdatedAt:  t0,
+	}
+	require.NoError(repo.SaveTask(ctx, global))
+	require.NoError(repo.SaveTask(ctx, model.Task{Name: "lint", Command: []string{"golangci-lint", "run"}, CreatedAt: t0, UpdatedAt: t0}))
+
+	// The sandbox tasks are independent of the global ones.
+	require.NoError(repo.CreateSandbox(ctx, model.Sandbox{ID: "sb-1", Name: "sb-1"}))
+	sbTask := model.Task{Name: "test", SandboxID: "sb-1", Command: []string{"make", "test"}, CreatedAt: t0, UpdatedAt: t0}
+	require.NoError(repo.SaveTask(ctx, sbTask))
+	assert.ErrorIs(repo.SaveTask(ctx, model.Task{Name: "test", SandboxID: "missing", Command: []string{"ls"}}), model.ErrNotFound)
+
+	got, err := repo.GetTask(ctx, "", "test")
+	require.NoError(err)
+	assert.Equal(global, *got)
+	got, err = repo.GetTask(ctx, "sb-1", "test")
+	require.NoError(err)
+	assert.Equal(sbTask, *got)
+	_, err = repo.GetTask(ctx, "sb-1", "lint")
+	assert.ErrorIs(err, model.ErrNotFound)
+
+	list, err := repo.ListTasks(ctx, "")
+	require.NoError(err)
+	require.Len(list, 2)
+	assert.Equal("lint", list[0].Name)
+	assert.Equal("test", list[1].Name)
+
+	// Saving replaces the task and keeps the creation time.
+	updated := global
+	updated.Command = []string{"go", "test", "-race", "./..."}
+	updated.Env = nil
+	updated.CreatedAt = t0.Add(time.Hour)
+	updated.UpdatedAt = t0.Add(time.Hour)
+	require.NoError(repo.SaveTask(ctx, updated))
+	got, err = repo.GetTask(ctx, "", "test")
+	require.NoError(err)
+	assert.Equal([]string{"go", "test", "-race", "./..."}, got.Command)
+	assert.Nil(got.Env)
+	assert.Equal(t0, got.CreatedAt)
+	assert.Equal(t0.Add(time.Hour), got.UpdatedAt)
+
+	require.NoError(repo.DeleteTask(ctx, "", "lint"))
+	assert.ErrorIs(repo.DeleteTask(ctx, "", "lint"), model.ErrNotFound)
+
+	// The sandbox tasks are deleted with the sandbox.
+	require.NoError(repo.DeleteSandbox(ctx, "sb-1"))
+	list, err = repo.ListTasks(ctx, "sb-1")
+	require.NoError(err)
+	assert.Empty(list)
+}
+
+func TestRepositoryIntegrityBaselines(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	repo, err := memory.NewRepository(memory.RepositoryConfig{Logger: log.Noop})
+	require.NoError(err)
+
+	t0 := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	require.NoError(repo.CreateSandbox(ctx, model.Sandbox{ID: "sb-1", Name: "sb-1"}))
+	baseline := model.IntegrityBaseline{
+		SandboxID: "sb-1",
+		Paths:     []string{"/etc", "/usr/local/bin"},
+		Files:     map[string]string{"/etc/motd": "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+		CreatedAt: t0,
+	}
+	require.NoError(repo.SaveIntegrityBaseline(ctx, baseline))
+	assert.ErrorIs(repo.SaveIntegrityBaseline(ctx, model.IntegrityBaseline{SandboxID: "missing"}), model.ErrNotFound)
+
+	got, err := repo.GetIntegrityBaseline(ctx, "sb-1")
+	require.NoError(err)
+	assert.Equal(baseline, *got)
+
+	// Saving replaces the baseline.
+	updated := model.IntegrityBaseline{SandboxID: "sb-1", Paths: []string{"/opt"}, Files: map[string]string{}, CreatedAt: t0.Add(time.Hour)}
+	require.NoError(repo.SaveIntegrityBaseline(ctx, updated))
+	got, err = repo.GetIntegrityBaseline(ctx, "sb-1")
+	require.NoError(err)
+	assert.Equal(updated, *got)
+
+	// The baseline is deleted with the sandbox.
+	require.NoError(repo.DeleteSandbox(ctx, "sb-1"))
+	_, err = repo.GetIntegrityBaseline(ctx, "sb-1")
+	assert.ErrorIs(err, model.ErrNotFound)
+}
+
+func TestRepositoryAnnotations(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	repo, err := memory.NewRepository(memory.RepositoryConfig{Logger: log.Noop})
+	require.NoError(err)
+
+	sb := model.Sandbox{ID: "id-1", Name: "sb-1", Status: model.SandboxStatusStopped}
+	require.NoError(repo.CreateSandbox(ctx, sb))
+
+	annotations := map[string]string{"owner": "alice@example.com"}
+	require.NoError(repo.UpdateSandboxAnnotations(ctx, "id-1", annotations))
+
+	// Updating the sandbox should keep the annotations updated meanwhile.
+	sb.Status = model.SandboxStatusRunning
+	require.NoError(repo.UpdateSandbox(ctx, sb))
+
+	got, err := repo.GetSandbox(ctx, "id-1")
+	require.NoError(err)
+	assert.Equal(model.SandboxStatusRunning, got.Status)
+	assert.Equal(annotations, got.Annotations)
+
+	assert.ErrorIs(repo.UpdateSandboxAnnotations(ctx, "id-x", annotations), model.ErrNotFound)
+}
+
+func TestRepositoryIdle(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	repo, err := memory.NewRepository(memory.RepositoryConfig{Logger: log.Noop})
+	require.NoError(err)
+
+	sb := model.Sandbox{ID: "id-1", Name: "sb-1", Status: model.SandboxStatusStopped}
+	require.NoError(repo.CreateSandbox(ctx, sb))
+
+	policy := &model.IdlePolicy{Timeout: 30 * time.Minute, Action: model.IdleActionStop}
+	require.NoError(repo.UpdateSandboxIdlePolicy(ctx, "id-1", policy))
+	at := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	require.NoError(repo.UpdateSandboxLastActivity(ctx, "id-1", at))
+	// An older activity should not overwrite a newer one.
+	require.NoError(repo.UpdateSandboxLastActivity(ctx, "id-1", at.Add(-time.Hour)))
+	require.NoError(repo.UpdateSandboxNetworkBytes(ctx, "id-1", 1234))
+
+	// Updating the sandbox should keep the idle policy and the activity.
+	sb.Status = model.SandboxStatusRunning
+	require.NoError(repo.UpdateSandbox(ctx, sb))
+
+	got, err := repo.GetSandbox(ctx, "id-1")
+	require.NoError(err)
+	assert.Equal(policy, got.IdlePolicy)
+	assert.Equal(model.SandboxActivity{LastActivityAt: at, NetworkBytes: 1234}, got.Activity)
+
+	require.NoError(repo.UpdateSandboxIdlePolicy(ctx, "id-1", nil))
+	got, err = repo.GetSandbox(ctx, "id-1")
+	require.NoError(err)
+	assert.Nil(got.IdlePolicy)
+
+	assert.ErrorIs(repo.UpdateSandboxIdlePolicy(ctx, "id-x", policy), model.ErrNotFound)
+	assert.ErrorIs(repo.UpdateSandboxLastActivity(ctx, "id-x", at), model.ErrNotFound)
+	assert.ErrorIs(repo.UpdateSandboxNetworkBytes(ctx, "id-x", 1), model.ErrNotFound)
+}
+
+func TestRepositoryQuarantine(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	repo, err := memory.NewRepository(memory.RepositoryConfig{Logger: log.Noop})
+	require.NoError(err)
+
+	sb := model.Sandbox{ID: "id-1", Name: "sb-1", Status: model.SandboxStatusStopped}
+	require.NoError(repo.CreateSandbox(ctx, sb))
+
+	at := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	require.NoError(repo.UpdateSandboxQuarantine(ctx, "id-1", &at))
+
+	// Updating the sandbox should keep the quarantine.
+	sb.Status = model.SandboxStatusRunning
+	require.NoError(repo.UpdateSandbox(ctx, sb))
+
+	got, err := repo.GetSandbox(ctx, "id-1")
+	require.NoError(err)
+	assert.Equal(&at, got.QuarantinedAt)
+
+	require.NoError(repo.UpdateSandboxQuarantine(ctx, "id-1", nil))
+	got, err = repo.GetSandbox(ctx, "id-1")
+	require.NoError(err)
+	assert.Nil(got.QuarantinedAt)
+
+	assert.ErrorIs(repo.UpdateSandboxQuarantine(ctx, "id-x", &at), model.ErrNotFound)
+}
+
+func TestRepositoryPersistent(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	repo, err := memory.NewRepository(memory.RepositoryConfig{Logger: log.Noop})
+	require.NoError(err)
+
+	sb := model.Sandbox{ID: "id-1", Name: "sb-1", Status: model.SandboxStatusStopped, Persistent: true}
+	require.NoError(repo.CreateSandbox(ctx, sb))
+
+	// Updating the sandbox should keep the persistence.
+	sb.Persistent = false
+	sb.Status = model.SandboxStatusRunning
+	require.NoError(repo.UpdateSandbox(ctx, sb))
+
+	got, err := repo.GetSandbox(ctx, "id-1")
+	require.NoError(err)
+	assert.True(got.Persistent)
+
+	require.NoError(repo.UpdateSandboxPersistent(ctx, "id-1", false))
+	got, err = repo.GetSandbox(ctx, "id-1")
+	require.NoError(err)
+	assert.False(got.Persistent)
+
+	assert.ErrorIs(repo.UpdateSandboxPersistent(ctx, "id-x", true), model.ErrNotFound)
+}
+
+func TestRepositoryUsageRecords(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	repo, err := memory.NewRepository(memory.RepositoryConfig{Logger: log.Noop})
+	require.NoError(err)
+
+	t0 := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	run := model.UsageRecord{SandboxID: "id-1", Namespace: model.DefaultNamespace, Kind: model.UsageKindRun, StartedAt: t0.Add(time.Hour), EndedAt: t0.Add(2 * time.Hour)}
+	disk := model.UsageRecord{SandboxID: "id-1", Namespace: model.DefaultNamespace, Kind: model.UsageKindDisk, StartedAt: t0, EndedAt: t0.Add(3 * time.Hour)}
+	require.NoError(repo.CreateUsageRecord(ctx, run))
+	require.NoError(repo.CreateUsageRecord(ctx, disk))
+
+	all, err := repo.ListUsageRecords(ctx, model.UsageRecordFilter{})
+	require.NoError(err)
+	assert.Equal([]model.UsageRecord{disk, run}, all)
+
+	got, err := repo.ListUsageRecords(ctx, model.UsageRecordFilter{Since: t0.Add(2 * time.Hour)})
+	require.NoError(err)
+	assert.Equal([]model.UsageRecord{disk}, got)
+
+	got, err = repo.ListUsageRecords(ctx, model.UsageRecordFilter{Until: t0.Add(time.Hour)})
+	require.NoError(err)
+	assert.Equal([]model.UsageRecord{disk}, got)
+}
+
+func TestRepositoryNamespaces(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	repo, err := memory.NewRepository(memory.RepositoryConfig{Namespace: model.AllNamespaces, Logger: log.Noop})
+	require.NoError(err)
+
+	// The same name can be used in different namespaces.
+	require.NoError(repo.CreateSandbox(ctx, model.Sandbox{ID: "id-1", Name: "sb", Namespace: model.DefaultNamespace}))
+	require.NoError(repo.CreateSandbox(ctx, model.Sandbox{ID: "id-2", Name: "sb", Namespace: "team-a"}))
+	assert.ErrorIs(repo.CreateSandbox(ctx, model.Sandbox{ID: "id-3", Name: "sb", Namespace: "team-a"}), model.ErrAlreadyExists)
+	assert.ErrorIs(repo.CreateSandbox(ctx, model.Sandbox{ID: "id-4", Name: "other"}), model.ErrNotValid)
+
+	// Names used in several namespaces can't be resolved with all the namespaces.
+	_, err = repo.GetSandboxByName(ctx, "sb")
+	assert.ErrorIs(err, model.ErrNotValid)
+
+	all, err := repo.ListSandboxes(ctx)
+	require.NoError(err)
+	assert.Len(all, 2)
+
+	// A scoped repository only sees its namespace.
+	scoped, err := memory.NewRepository(memory.RepositoryConfig{Namespace: "team-a", Logger: log.Noop})
+	require.NoError(err)
+	require.NoError(scoped.CreateSandbox(ctx, model.Sandbox{ID: "id-1", Name: "sb"}))
+	got, err := scoped.GetSandboxByName(ctx, "sb")
+	require.NoError(err)
+	assert.Equal("team-a", got.Namespace)
+	assert.ErrorIs(scoped.CreateSandbox(ctx, model.Sandbox{ID: "id-2", Name: "sb-2", Namespace: "team-b"}), model.ErrNotValid)
+}
@@ -0,0 +1,147 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// CreateEgressPolicy stores a named egress policy.
+func (r *Repository) CreateEgressPolicy(ctx context.Context, p model.NamedEgressPolicy) error {
+	policy, err := json.Marshal(toEgressJSON(p.Policy))
+	if err != nil {
+		return fmt.Errorf("could not marshal egress policy: %w", err)
+	}
+
+	query := `
+		INSERT INTO egress_policies (name, policy, created_at, updated_at)
+		VALUES (?, ?, ?, ?)
+	`
+
+	_, err = r.db.ExecContext(ctx, query, p.Name, string(policy), p.CreatedAt.Unix(), p.UpdatedAt.Unix())
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: egress_policies.") {
+			return fmt.Errorf("egress policy %s already exists: %w", p.Name, model.ErrAlreadyExists)
+		}
+		return fmt.Errorf("could not insert egress policy: %w", err)
+	}
+
+	r.logger.Debugf("Created egress policy in repository: %s", p.Name)
+	return nil
+}
+
+// GetEgressPolicy retrieves a named egress policy.
+func (r *Repository) GetEgressPolicy(ctx context.Context, name string) (*model.NamedEgressPolicy, error) {
+	query := `
+		SELECT name, policy, created_at, updated_at
+		FROM egress_policies
+		WHERE name = ?
+	`
+
+	p, err := scanEgressPolicy(r.db.QueryRowContext(ctx, query, name))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("egress policy %s: %w", name, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not query egress policy: %w", err)
+	}
+
+	return &p, nil
+}
+
+// ListEgressPolicies returns all the named egress policies sorted by name.
+func (r *Repository) ListEgressPolicies(ctx context.Context) ([]model.NamedEgressPolicy, error) {
+	query := `
+		SELECT name, policy, created_at, updated_at
+		FROM egress_policies
+		ORDER BY name ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("could not query egress policies: %w", err)
+	}
+	defer rows.Close()
+
+	policies := []model.NamedEgressPolicy{}
+	for rows.Next() {
+		p, err := scanEgressPolicy(rows)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan row: %w", err)
+		}
+		policies = append(policies, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return policies, nil
+}
+
+// UpdateEgressPolicy replaces the policy of a named egress policy.
+func (r *Repository) UpdateEgressPolicy(ctx context.Context, p model.NamedEgressPolicy) error {
+	policy, err := json.Marshal(toEgressJSON(p.Policy))
+	if err != nil {
+		return fmt.Errorf("could not marshal egress policy: %w", err)
+	}
+
+	result, err := r.db.ExecContext(ctx, `UPDATE egress_policies SET policy = ?, updated_at = ? WHERE name = ?`, string(policy), p.UpdatedAt.Unix(), p.Name)
+	if err != nil {
+		return fmt.Errorf("could not update egress policy: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("egress policy %s: %w", p.Name, model.ErrNotFound)
+	}
+
+	r.logger.Debugf("Updated egress policy in repository: %s", p.Name)
+	return nil
+}
+
+// DeleteEgressPolicy deletes a named egress policy.
+func (r *Repository) DeleteEgressPolicy(ctx context.Context, name string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM egress_policies WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("could not delete egress policy: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("egress policy %s: %w", name, model.ErrNotFound)
+	}
+
+	r.logger.Debugf("Deleted egress policy from repository: %s", name)
+	return nil
+}
+
+func scanEgressPolicy(s scanner) (model.NamedEgressPolicy, error) {
+	var p model.NamedEgressPolicy
+	var policy string
+	var createdAt, updatedAt int64
+	if err := s.Scan(&p.Name, &policy, &createdAt, &updatedAt); err != nil {
+		return model.NamedEgressPolicy{}, err
+	}
+
+	var j egressJSON
+	if err := json.Unmarshal([]byte(policy), &j); err != nil {
+		return model.NamedEgressPolicy{}, fmt.Errorf("could not unmarshal egress policy: %w", err)
+	}
+	p.Policy = fromEgressJSON(j)
+	p.CreatedAt = timeFromUnix(createdAt)
+	p.UpdatedAt = timeFromUnix(updatedAt)
+
+	return p, nil
+}
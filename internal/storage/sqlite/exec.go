@@ -0,0 +1,129 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// CreateExecRecord stores the exec audit record of a sandbox.
+func (r *Repository) CreateExecRecord(ctx context.Context, rec model.ExecRecord) error {
+	command, err := json.Marshal(rec.Command)
+	if err != nil {
+		return fmt.Errorf("could not marshal command: %w", err)
+	}
+
+	query := `
+		INSERT INTO exec_records (
+			id, sandbox_id, command, working_dir, user, caller,
+			exit_code, error, started_at_ns, duration_ns,
+			stdout_bytes, stderr_bytes, stdout_sha256, stderr_sha256
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err = r.db.ExecContext(
+		ctx,
+		query,
+		rec.ID,
+		rec.SandboxID,
+		string(command),
+		rec.WorkingDir,
+		rec.User,
+		rec.Caller,
+		rec.ExitCode,
+		rec.Error,
+		rec.StartedAt.UnixNano(),
+		int64(rec.Duration),
+		rec.StdoutBytes,
+		rec.StderrBytes,
+		rec.StdoutSHA256,
+		rec.StderrSHA256,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
+			return fmt.Errorf("sandbox %s: %w", rec.SandboxID, model.ErrNotFound)
+		}
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: exec_records.") {
+			return fmt.Errorf("exec record already exists: %w", model.ErrAlreadyExists)
+		}
+		return fmt.Errorf("could not insert exec record: %w", err)
+	}
+
+	return nil
+}
+
+// ListExecRecords returns the exec audit records of a sandbox, oldest first.
+func (r *Repository) ListExecRecords(ctx context.Context, sandboxID string, filter model.ExecRecordFilter) ([]model.ExecRecord, error) {
+	var since int64
+	if !filter.Since.IsZero() {
+		since = filter.Since.UnixNano()
+	}
+	limit := -1
+	if filter.Limit > 0 {
+		limit = filter.Limit
+	}
+
+	// Get the latest records and return them oldest first.
+	query := `
+		SELECT * FROM (
+			SELECT
+				id, sandbox_id, command, working_dir, user, caller,
+				exit_code, error, started_at_ns, duration_ns,
+				stdout_bytes, stderr_bytes, stdout_sha256, stderr_sha256
+			FROM exec_records
+			WHERE sandbox_id = ? AND started_at_ns >= ?
+			ORDER BY started_at_ns DESC, id DESC
+			LIMIT ?
+		)
+		ORDER BY started_at_ns ASC, id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, sandboxID, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("could not query exec records: %w", err)
+	}
+	defer rows.Close()
+
+	records := []model.ExecRecord{}
+	for rows.Next() {
+		var rec model.ExecRecord
+		var command string
+		var startedAt, duration int64
+		err := rows.Scan(
+			&rec.ID,
+			&rec.SandboxID,
+			&command,
+			&rec.WorkingDir,
+			&rec.User,
+			&rec.Caller,
+			&rec.ExitCode,
+			&rec.Error,
+			&startedAt,
+			&duration,
+			&rec.StdoutBytes,
+			&rec.StderrBytes,
+			&rec.StdoutSHA256,
+			&rec.StderrSHA256,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan row: %w", err)
+		}
+		if err := json.Unmarshal([]byte(command), &rec.Command); err != nil {
+			return nil, fmt.Errorf("could not unmarshal command: %w", err)
+		}
+		rec.StartedAt = time.Unix(0, startedAt).UTC()
+		rec.Duration = time.Duration(duration)
+		records = append(records, rec)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,74 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// SaveIntegrityBaseline stores the integrity baseline of a sandbox, replacing the
+// previous one.
+func (r *Repository) SaveIntegrityBaseline(ctx context.Context, b model.IntegrityBaseline) error {
+	paths, err := json.Marshal(b.Paths)
+	if err != nil {
+		return fmt.Errorf("could not marshal integrity paths: %w", err)
+	}
+	files, err := json.Marshal(b.Files)
+	if err != nil {
+		return fmt.Errorf("could not marshal integrity files: %w", err)
+	}
+
+	query := `
+		INSERT INTO integrity_baselines (sandbox_id, paths, files, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (sandbox_id) DO UPDATE SET
+			paths = excluded.paths,
+			files = excluded.files,
+			created_at = excluded.created_at
+	`
+	_, err = r.db.ExecContext(ctx, query, b.SandboxID, string(paths), string(files), b.CreatedAt.Unix())
+	if err != nil {
+		if strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
+			return fmt.Errorf("sandbox %s: %w", b.SandboxID, model.ErrNotFound)
+		}
+		return fmt.Errorf("could not save integrity baseline: %w", err)
+	}
+
+	r.logger.Debugf("Saved integrity baseline in repository: %s", b.SandboxID)
+	return nil
+}
+
+// GetIntegrityBaseline retrieves the integrity baseline of a sandbox.
+func (r *Repository) GetIntegrityBaseline(ctx context.Context, sandboxID string) (*model.IntegrityBaseline, error) {
+	query := `
+		SELECT sandbox_id, paths, files, created_at
+		FROM integrity_baselines
+		WHERE sandbox_id = ?
+	`
+
+	var b model.IntegrityBaseline
+	var paths, files string
+	var createdAt int64
+	err := r.db.QueryRowContext(ctx, query, sandboxID).Scan(&b.SandboxID, &paths, &files, &createdAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("integrity baseline of sandbox %s: %w", sandboxID, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not query integrity baseline: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(paths), &b.Paths); err != nil {
+		return nil, fmt.Errorf("could not unmarshal integrity paths: %w", err)
+	}
+	if err := json.Unmarshal([]byte(files), &b.Files); err != nil {
+		return nil, fmt.Errorf("could not unmarshal integrity files: %w", err)
+	}
+	b.CreatedAt = timeFromUnix(createdAt)
+
+	return &b, nil
+}
@@ -0,0 +1,47 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/slok/sbx/internal/hostlock"
+	"github.com/slok/sbx/internal/model"
+)
+
+// LockSandbox takes the sandbox operation lock. It's an advisory file lock (flock) next
+// to the database, so it's shared by all the processes using the same database and it's
+// released by the kernel if the process dies. The lock files are never removed (not even
+// with the sandbox): a lock file unlinked while held would let the next caller create and
+// lock a new file while the holder still runs.
+func (r *Repository) LockSandbox(ctx context.Context, id string) (func(), error) {
+	if err := os.MkdirAll(r.locksDir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create locks directory: %w", err)
+	}
+
+	f, err := os.OpenFile(r.lockPath(id), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open lock file: %w", err)
+	}
+
+	if err := hostlock.TryLockFile(f); err != nil {
+		f.Close()
+		if errors.Is(err, hostlock.ErrLocked) {
+			return nil, fmt.Errorf("sandbox %s is locked by another operation: %w", id, model.ErrConflict)
+		}
+		return nil, fmt.Errorf("could not lock sandbox %s: %w", id, err)
+	}
+
+	return func() {
+		// Closing the file releases the lock.
+		if err := f.Close(); err != nil {
+			r.logger.Warningf("Could not release sandbox %s lock: %v", id, err)
+		}
+	}, nil
+}
+
+func (r *Repository) lockPath(id string) string {
+	return filepath.Join(r.locksDir, id+".lock")
+}
@@ -0,0 +1,104 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+const (
+	compactCheckpointRetries       = 20
+	compactCheckpointRetryInterval = 50 * time.Millisecond
+)
+
+// Checkpoint moves the write-ahead log into the database and truncates it. The
+// checkpoint doesn't wait for the readers, when they block it the log is kept and
+// checkpointed the next time.
+func (r *Repository) Checkpoint(ctx context.Context) error {
+	_, err := r.checkpoint(ctx)
+	return err
+}
+
+// checkpoint runs a truncating checkpoint and returns true when it was blocked.
+func (r *Repository) checkpoint(ctx context.Context) (bool, error) {
+	var busy, logPages, checkpointed int
+	err := r.db.QueryRowContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &logPages, &checkpointed)
+	if err != nil {
+		return false, fmt.Errorf("could not checkpoint database: %w", err)
+	}
+
+	if busy != 0 {
+		r.logger.Debugf("Database checkpoint blocked by readers, %d of %d pages checkpointed", checkpointed, logPages)
+	}
+	return busy != 0, nil
+}
+
+// Compact checkpoints the write-ahead log, checks the database integrity and, when
+// healthy, rebuilds the database with VACUUM so the space of the deleted rows is
+// returned to the filesystem.
+func (r *Repository) Compact(ctx context.Context) (*model.DBCompactResult, error) {
+	start := time.Now()
+	res := &model.DBCompactResult{SizeBefore: r.dbSize()}
+
+	if err := r.Checkpoint(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return nil, fmt.Errorf("could not check database integrity: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			return nil, fmt.Errorf("could not read integrity check: %w", err)
+		}
+		if msg != "ok" {
+			res.IntegrityErrors = append(res.IntegrityErrors, msg)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not check database integrity: %w", err)
+	}
+
+	if len(res.IntegrityErrors) == 0 {
+		if _, err := r.db.ExecContext(ctx, "VACUUM"); err != nil {
+			return nil, fmt.Errorf("could not vacuum database: %w", err)
+		}
+		// VACUUM writes the rebuilt database through the write-ahead log, retry the
+		// checkpoint blocked by a concurrent one so the log is truncated.
+		for range compactCheckpointRetries {
+			busy, err := r.checkpoint(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if !busy {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(compactCheckpointRetryInterval):
+			}
+		}
+	}
+
+	res.SizeAfter = r.dbSize()
+	res.Duration = time.Since(start)
+	return res, nil
+}
+
+// dbSize returns the size of the database and its write-ahead log.
+func (r *Repository) dbSize() int64 {
+	var size int64
+	for _, path := range []string{r.dbPath, r.dbPath + "-wal"} {
+		if info, err := os.Stat(path); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_sandboxes_image;
+ALTER TABLE sandboxes DROP COLUMN image;
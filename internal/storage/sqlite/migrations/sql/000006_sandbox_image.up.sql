@@ -0,0 +1,4 @@
+-- Track the local image (release or snapshot) a sandbox was created from.
+ALTER TABLE sandboxes ADD COLUMN image TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX idx_sandboxes_image ON sandboxes(image);
@@ -0,0 +1,2 @@
+-- Provisioning (shell script or cloud-config) executed on the sandbox first boot.
+ALTER TABLE sandboxes ADD COLUMN user_data TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+ALTER TABLE sandboxes DROP COLUMN boot_init;
+ALTER TABLE sandboxes DROP COLUMN boot_disable_console;
+ALTER TABLE sandboxes DROP COLUMN boot_read_only_rootfs;
+ALTER TABLE sandboxes DROP COLUMN kernel_args;
@@ -0,0 +1,5 @@
+-- Kernel command line arguments (space separated) and boot options of the sandbox.
+ALTER TABLE sandboxes ADD COLUMN kernel_args TEXT NOT NULL DEFAULT '';
+ALTER TABLE sandboxes ADD COLUMN boot_read_only_rootfs INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sandboxes ADD COLUMN boot_disable_console INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sandboxes ADD COLUMN boot_init TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Additional network interfaces of the sandbox (JSON list).
+ALTER TABLE sandboxes ADD COLUMN networks TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE sandboxes DROP COLUMN clock_boot_time;
+ALTER TABLE sandboxes DROP COLUMN clock_offset;
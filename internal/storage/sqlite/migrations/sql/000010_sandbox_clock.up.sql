@@ -0,0 +1,3 @@
+-- Guest clock of the sandbox, NULL clock_offset means the guest uses the host time.
+ALTER TABLE sandboxes ADD COLUMN clock_offset INTEGER;
+ALTER TABLE sandboxes ADD COLUMN clock_boot_time INTEGER;
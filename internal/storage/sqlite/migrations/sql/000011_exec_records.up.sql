@@ -0,0 +1,19 @@
+-- Audit trail of the commands executed in the sandboxes.
+CREATE TABLE IF NOT EXISTS exec_records (
+    id TEXT PRIMARY KEY,
+    sandbox_id TEXT NOT NULL REFERENCES sandboxes(id) ON DELETE CASCADE,
+    command TEXT NOT NULL,
+    working_dir TEXT NOT NULL DEFAULT '',
+    user TEXT NOT NULL DEFAULT '',
+    caller TEXT NOT NULL DEFAULT '',
+    exit_code INTEGER NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    started_at_ns INTEGER NOT NULL,
+    duration_ns INTEGER NOT NULL,
+    stdout_bytes INTEGER NOT NULL,
+    stderr_bytes INTEGER NOT NULL,
+    stdout_sha256 TEXT NOT NULL DEFAULT '',
+    stderr_sha256 TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_exec_records_sandbox_started_at ON exec_records(sandbox_id, started_at_ns);
@@ -0,0 +1,7 @@
+-- Named egress policies shared by the sandboxes.
+CREATE TABLE IF NOT EXISTS egress_policies (
+    name TEXT PRIMARY KEY,
+    policy TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
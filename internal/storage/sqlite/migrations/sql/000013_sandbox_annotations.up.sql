@@ -0,0 +1,2 @@
+-- Free-form metadata of the sandbox (JSON object), not indexed.
+ALTER TABLE sandboxes ADD COLUMN annotations TEXT NOT NULL DEFAULT '';
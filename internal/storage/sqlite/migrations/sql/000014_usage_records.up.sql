@@ -0,0 +1,16 @@
+-- Usage periods of the sandboxes for the usage accounting, kept after the
+-- sandboxes are removed.
+CREATE TABLE IF NOT EXISTS usage_records (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    sandbox_id TEXT NOT NULL,
+    sandbox_name TEXT NOT NULL,
+    annotations TEXT NOT NULL DEFAULT '',
+    kind TEXT NOT NULL,
+    vcpus REAL NOT NULL,
+    memory_mb INTEGER NOT NULL,
+    disk_gb INTEGER NOT NULL,
+    started_at_ns INTEGER NOT NULL,
+    ended_at_ns INTEGER NOT NULL
+);
+
+CREATE INDEX idx_usage_records_ended_at ON usage_records(ended_at_ns);
@@ -0,0 +1,52 @@
+-- Namespaces isolate the sandboxes of different teams or projects sharing a host,
+-- the sandbox names are unique per namespace. Existing sandboxes and usage records
+-- go to the default namespace.
+
+-- Recreate the table to replace the name UNIQUE constraint (SQLite can't drop it).
+-- Dropping the table deletes the exec records in cascade, keep them meanwhile.
+CREATE TEMP TABLE exec_records_backup AS SELECT * FROM exec_records;
+
+CREATE TABLE sandboxes_new (
+    id TEXT PRIMARY KEY,
+    namespace TEXT NOT NULL DEFAULT 'default',
+    name TEXT NOT NULL,
+    status TEXT NOT NULL,
+    rootfs_path TEXT NOT NULL,
+    kernel_image_path TEXT NOT NULL,
+    vcpus REAL NOT NULL,
+    memory_mb INTEGER NOT NULL,
+    disk_gb INTEGER NOT NULL,
+    internal_ip TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL,
+    started_at INTEGER,
+    stopped_at INTEGER,
+    image TEXT NOT NULL DEFAULT '',
+    user_data TEXT NOT NULL DEFAULT '',
+    kernel_args TEXT NOT NULL DEFAULT '',
+    boot_read_only_rootfs INTEGER NOT NULL DEFAULT 0,
+    boot_disable_console INTEGER NOT NULL DEFAULT 0,
+    boot_init TEXT NOT NULL DEFAULT '',
+    networks TEXT NOT NULL DEFAULT '',
+    clock_offset INTEGER,
+    clock_boot_time INTEGER,
+    annotations TEXT NOT NULL DEFAULT '',
+    UNIQUE (namespace, name),
+    CHECK (status IN ('running', 'stopped')),
+    CHECK (vcpus > 0),
+    CHECK (memory_mb > 0),
+    CHECK (disk_gb > 0)
+);
+
+INSERT INTO sandboxes_new (id, name, status, rootfs_path, kernel_image_path, vcpus, memory_mb, disk_gb, internal_ip, created_at, started_at, stopped_at, image, user_data, kernel_args, boot_read_only_rootfs, boot_disable_console, boot_init, networks, clock_offset, clock_boot_time, annotations)
+SELECT id, name, status, rootfs_path, kernel_image_path, vcpus, memory_mb, disk_gb, internal_ip, created_at, started_at, stopped_at, image, user_data, kernel_args, boot_read_only_rootfs, boot_disable_console, boot_init, networks, clock_offset, clock_boot_time, annotations FROM sandboxes;
+DROP TABLE sandboxes;
+ALTER TABLE sandboxes_new RENAME TO sandboxes;
+
+INSERT INTO exec_records SELECT * FROM exec_records_backup;
+DROP TABLE exec_records_backup;
+
+CREATE INDEX idx_sandboxes_status ON sandboxes(status);
+CREATE INDEX idx_sandboxes_created_at ON sandboxes(created_at);
+CREATE INDEX idx_sandboxes_image ON sandboxes(image);
+
+ALTER TABLE usage_records ADD COLUMN namespace TEXT NOT NULL DEFAULT 'default';
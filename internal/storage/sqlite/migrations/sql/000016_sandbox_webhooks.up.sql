@@ -0,0 +1,2 @@
+-- Webhooks notified of the sandbox lifecycle events (JSON array), not indexed.
+ALTER TABLE sandboxes ADD COLUMN webhooks TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,10 @@
+-- Named commands run in the sandboxes, the global ones have a NULL sandbox_id.
+CREATE TABLE IF NOT EXISTS tasks (
+    sandbox_id TEXT REFERENCES sandboxes(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    task TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_tasks_sandbox_name ON tasks(COALESCE(sandbox_id, ''), name);
@@ -0,0 +1,3 @@
+ALTER TABLE sandboxes DROP COLUMN network_bytes;
+ALTER TABLE sandboxes DROP COLUMN last_activity_at;
+ALTER TABLE sandboxes DROP COLUMN idle_policy;
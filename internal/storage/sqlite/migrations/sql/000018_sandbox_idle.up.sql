@@ -0,0 +1,5 @@
+-- Idle policy of the sandbox (JSON object), empty without one.
+ALTER TABLE sandboxes ADD COLUMN idle_policy TEXT NOT NULL DEFAULT '';
+-- Last activity of the sandbox (unix seconds) and its network bytes on the last idle check.
+ALTER TABLE sandboxes ADD COLUMN last_activity_at INTEGER;
+ALTER TABLE sandboxes ADD COLUMN network_bytes INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- Named ports of the sandbox services (JSON object of name to port), empty without them.
+ALTER TABLE sandboxes ADD COLUMN ports TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,7 @@
+-- Digests of the sandbox guest files captured on start, the paths and files are JSON.
+CREATE TABLE IF NOT EXISTS integrity_baselines (
+    sandbox_id TEXT PRIMARY KEY REFERENCES sandboxes(id) ON DELETE CASCADE,
+    paths TEXT NOT NULL,
+    files TEXT NOT NULL,
+    created_at INTEGER NOT NULL
+);
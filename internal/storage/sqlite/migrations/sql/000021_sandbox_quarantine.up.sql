@@ -0,0 +1,2 @@
+-- Quarantine time of the sandbox (unix seconds), NULL when it isn't quarantined.
+ALTER TABLE sandboxes ADD COLUMN quarantined_at INTEGER;
@@ -0,0 +1,2 @@
+ALTER TABLE sandboxes DROP COLUMN seccomp_filter;
+ALTER TABLE sandboxes DROP COLUMN seccomp_mode;
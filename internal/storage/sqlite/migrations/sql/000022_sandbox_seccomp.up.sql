@@ -0,0 +1,3 @@
+-- Seccomp mode (empty is the default Firecracker filters) and custom filter file of the sandbox.
+ALTER TABLE sandboxes ADD COLUMN seccomp_mode TEXT NOT NULL DEFAULT '';
+ALTER TABLE sandboxes ADD COLUMN seccomp_filter TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Strategy used to provision the sandbox rootfs from its image (reflink, sparse or copy).
+ALTER TABLE sandboxes ADD COLUMN rootfs_strategy TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE sandboxes DROP COLUMN exec_profile;
@@ -0,0 +1,2 @@
+-- Exec profile applied to the sandbox execs and shells (JSON object), empty without it.
+ALTER TABLE sandboxes ADD COLUMN exec_profile TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE sandboxes DROP COLUMN max_memory_mb;
+ALTER TABLE sandboxes DROP COLUMN max_vcpus;
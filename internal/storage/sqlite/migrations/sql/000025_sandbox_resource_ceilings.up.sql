@@ -0,0 +1,3 @@
+-- Resource ceilings a running sandbox can be scaled up to, 0 without scaling.
+ALTER TABLE sandboxes ADD COLUMN max_vcpus REAL NOT NULL DEFAULT 0;
+ALTER TABLE sandboxes ADD COLUMN max_memory_mb INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- Kernel profile the sandbox was created with, empty without profile.
+ALTER TABLE sandboxes ADD COLUMN boot_profile TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,8 @@
+-- Reusable sandbox specs the sandboxes are created from.
+CREATE TABLE IF NOT EXISTS sandbox_templates (
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    spec TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
@@ -0,0 +1,3 @@
+-- Session configuration (env and egress) of the last start, JSON encoded, empty if
+-- the sandbox was never started.
+ALTER TABLE sandboxes ADD COLUMN session TEXT NOT NULL DEFAULT '';
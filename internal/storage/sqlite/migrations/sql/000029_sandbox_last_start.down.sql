@@ -0,0 +1,2 @@
+ALTER TABLE sandboxes DROP COLUMN last_error;
+ALTER TABLE sandboxes DROP COLUMN last_boot_phases;
@@ -0,0 +1,3 @@
+-- Phases of the last start, JSON encoded, and its error, empty if it succeeded.
+ALTER TABLE sandboxes ADD COLUMN last_boot_phases TEXT NOT NULL DEFAULT '';
+ALTER TABLE sandboxes ADD COLUMN last_error TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE sandboxes DROP COLUMN persistent;
@@ -0,0 +1,2 @@
+-- Persistent sandboxes are started again by the recovery after the host reboots.
+ALTER TABLE sandboxes ADD COLUMN persistent INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- Host network interface the NAT traffic of the sandbox goes out through (empty is the engine default).
+ALTER TABLE sandboxes ADD COLUMN egress_interface TEXT NOT NULL DEFAULT '';
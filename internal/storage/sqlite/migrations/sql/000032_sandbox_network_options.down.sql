@@ -0,0 +1,2 @@
+ALTER TABLE sandboxes DROP COLUMN network_clamp_mss;
+ALTER TABLE sandboxes DROP COLUMN network_mtu;
@@ -0,0 +1,3 @@
+-- MTU of the sandbox interfaces (0 is the default) and TCP MSS clamping of the forwarded traffic.
+ALTER TABLE sandboxes ADD COLUMN network_mtu INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sandboxes ADD COLUMN network_clamp_mss INTEGER NOT NULL DEFAULT 0;
@@ -3,6 +3,7 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -20,13 +21,21 @@ import (
 // RepositoryConfig is the configuration for the SQLite repository.
 type RepositoryConfig struct {
 	DBPath string
-	Logger log.Logger
+	// Namespace scopes the sandboxes of the repository, empty is the default namespace
+	// and model.AllNamespaces selects all of them.
+	Namespace string
+	Logger    log.Logger
 }
 
 func (c *RepositoryConfig) defaults() error {
 	if c.DBPath == "" {
 		return fmt.Errorf("db path is required")
 	}
+	ns, err := model.NamespaceScope(c.Namespace)
+	if err != nil {
+		return err
+	}
+	c.Namespace = ns
 	if c.Logger == nil {
 		c.Logger = log.Noop
 	}
@@ -36,8 +45,11 @@ func (c *RepositoryConfig) defaults() error {
 
 // Repository is a SQLite implementation of storage.Repository.
 type Repository struct {
-	db     *sql.DB
-	logger log.Logger
+	db        *sql.DB
+	dbPath    string
+	namespace string
+	locksDir  string
+	logger    log.Logger
 }
 
 // NewRepository creates a new SQLite repository.
@@ -51,7 +63,9 @@ func NewRepository(ctx context.Context, cfg RepositoryConfig) (*Repository, erro
 		return nil, fmt.Errorf("could not create db directory: %w", err)
 	}
 
-	dsn := fmt.Sprintf("%s?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)", cfg.DBPath)
+	// The busy timeout makes the writes wait for the locks of the other connections
+	// (e.g. the periodic WAL checkpoints) instead of failing right away.
+	dsn := fmt.Sprintf("%s?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)", cfg.DBPath)
 	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("could not open database: %w", err)
@@ -69,7 +83,13 @@ func NewRepository(ctx context.Context, cfg RepositoryConfig) (*Repository, erro
 
 	cfg.Logger.Debugf("SQLite repository initialized at %s", cfg.DBPath)
 
-	return &Repository{db: db, logger: cfg.Logger}, nil
+	return &Repository{
+		db:        db,
+		dbPath:    cfg.DBPath,
+		namespace: cfg.Namespace,
+		locksDir:  filepath.Join(dir, "locks"),
+		logger:    cfg.Logger,
+	}, nil
 }
 
 // Close closes the database connection.
@@ -91,29 +111,91 @@ func (r *Repository) CreateSandbox(ctx context.Context, s model.Sandbox) error {
 		stoppedAt = &u
 	}
 
+	networks, err := marshalNetworks(s.Config.FirecrackerEngine.Networks)
+	if err != nil {
+		return err
+	}
+	clockOffset, clockBootTime := clockColumns(s.Config.Clock)
+	ports, err := marshalPorts(s.Config.Ports)
+	if err != nil {
+		return err
+	}
+	execProfile, err := marshalExecProfile(s.Config.ExecProfile)
+	if err != nil {
+		return err
+	}
+	annotations, err := marshalAnnotations(s.Annotations)
+	if err != nil {
+		return err
+	}
+	webhooks, err := marshalWebhooks(s.Webhooks)
+	if err != nil {
+		return err
+	}
+	idlePolicy, err := marshalIdlePolicy(s.IdlePolicy)
+	if err != nil {
+		return err
+	}
+
+	namespace := s.Namespace
+	if namespace == "" {
+		namespace = r.namespace
+	}
+	if err := model.ValidateNamespaceInScope(r.namespace, namespace); err != nil {
+		return err
+	}
+
 	query := `
 		INSERT INTO sandboxes (
-			id, name, status,
-			rootfs_path, kernel_image_path,
-			vcpus, memory_mb, disk_gb,
-			internal_ip,
+			id, namespace, name, status,
+			rootfs_path, kernel_image_path, image, user_data,
+			kernel_args, boot_read_only_rootfs, boot_disable_console, boot_init, boot_profile, networks,
+			seccomp_mode, seccomp_filter, egress_interface, network_mtu, network_clamp_mss,
+			clock_offset, clock_boot_time, ports, exec_profile,
+			vcpus, memory_mb, disk_gb, max_vcpus, max_memory_mb,
+			internal_ip, rootfs_strategy, annotations, webhooks, idle_policy, persistent,
 			created_at, started_at, stopped_at
 		)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := r.db.ExecContext(
+	_, err = r.db.ExecContext(
 		ctx,
 		query,
 		s.ID,
+		namespace,
 		s.Name,
 		s.Status,
 		s.Config.FirecrackerEngine.RootFS,
 		s.Config.FirecrackerEngine.KernelImage,
+		s.Config.Image,
+		s.Config.UserData,
+		strings.Join(s.Config.FirecrackerEngine.KernelArgs, " "),
+		s.Config.FirecrackerEngine.Boot.ReadOnlyRootFS,
+		s.Config.FirecrackerEngine.Boot.DisableConsole,
+		s.Config.FirecrackerEngine.Boot.Init,
+		s.Config.FirecrackerEngine.Boot.Profile,
+		networks,
+		s.Config.FirecrackerEngine.Seccomp.Mode,
+		s.Config.FirecrackerEngine.Seccomp.Filter,
+		s.Config.FirecrackerEngine.EgressInterface,
+		s.Config.FirecrackerEngine.Network.MTU,
+		s.Config.FirecrackerEngine.Network.ClampMSS,
+		clockOffset,
+		clockBootTime,
+		ports,
+		execProfile,
 		s.Config.Resources.VCPUs,
 		s.Config.Resources.MemoryMB,
 		s.Config.Resources.DiskGB,
+		s.Config.Resources.MaxVCPUs,
+		s.Config.Resources.MaxMemoryMB,
 		s.InternalIP,
+		s.RootFSStrategy,
+		annotations,
+		webhooks,
+		idlePolicy,
+		s.Persistent,
 		s.CreatedAt.Unix(),
 		startedAt,
 		stoppedAt,
@@ -131,18 +213,24 @@ func (r *Repository) CreateSandbox(ctx context.Context, s model.Sandbox) error {
 
 // GetSandbox retrieves a sandbox by ID.
 func (r *Repository) GetSandbox(ctx context.Context, id string) (*model.Sandbox, error) {
+	scope, scopeArgs := r.scope()
 	query := `
 		SELECT
-			id, name, status,
-			rootfs_path, kernel_image_path,
-			vcpus, memory_mb, disk_gb,
-			internal_ip,
+			id, namespace, name, status,
+			rootfs_path, kernel_image_path, image, user_data,
+			kernel_args, boot_read_only_rootfs, boot_disable_console, boot_init, boot_profile, networks,
+			seccomp_mode, seccomp_filter, egress_interface, network_mtu, network_clamp_mss,
+			clock_offset, clock_boot_time, ports, exec_profile,
+			vcpus, memory_mb, disk_gb, max_vcpus, max_memory_mb,
+			internal_ip, rootfs_strategy, annotations, webhooks,
+			idle_policy, last_activity_at, network_bytes, quarantined_at, session,
+			last_boot_phases, last_error, persistent,
 			created_at, started_at, stopped_at
 		FROM sandboxes
-		WHERE id = ?
+		WHERE id = ? AND ` + scope + `
 	`
 
-	sandbox, err := r.scanOne(ctx, query, id)
+	sandbox, err := r.scanOne(ctx, query, append([]any{id}, scopeArgs...)...)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("sandbox %s: %w", id, model.ErrNotFound)
@@ -153,44 +241,55 @@ func (r *Repository) GetSandbox(ctx context.Context, id string) (*model.Sandbox,
 	return sandbox, nil
 }
 
-// GetSandboxByName retrieves a sandbox by name.
+// GetSandboxByName retrieves a sandbox by name. With all the namespaces the name
+// must be used by a single sandbox.
 func (r *Repository) GetSandboxByName(ctx context.Context, name string) (*model.Sandbox, error) {
-	query := `
-		SELECT
-			id, name, status,
-			rootfs_path, kernel_image_path,
-			vcpus, memory_mb, disk_gb,
-			internal_ip,
-			created_at, started_at, stopped_at
-		FROM sandboxes
-		WHERE name = ?
-	`
-
-	sandbox, err := r.scanOne(ctx, query, name)
+	scope, scopeArgs := r.scope()
+	sandboxes, err := r.list(ctx, "name = ? AND "+scope, append([]any{name}, scopeArgs...)...)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("sandbox with name %s: %w", name, model.ErrNotFound)
-		}
-		return nil, fmt.Errorf("could not query sandbox: %w", err)
+		return nil, err
 	}
 
-	return sandbox, nil
+	switch len(sandboxes) {
+	case 0:
+		return nil, fmt.Errorf("sandbox with name %s: %w", name, model.ErrNotFound)
+	case 1:
+		return &sandboxes[0], nil
+	default:
+		return nil, fmt.Errorf("sandbox name %s is used in several namespaces, use the sandbox ID: %w", name, model.ErrNotValid)
+	}
 }
 
-// ListSandboxes returns all sandboxes.
+// ListSandboxes returns the sandboxes of the repository namespace, newest first.
 func (r *Repository) ListSandboxes(ctx context.Context) ([]model.Sandbox, error) {
+	scope, scopeArgs := r.scope()
+	return r.list(ctx, scope, scopeArgs...)
+}
+
+// ListAllSandboxes returns the sandboxes of all the namespaces, newest first.
+func (r *Repository) ListAllSandboxes(ctx context.Context) ([]model.Sandbox, error) {
+	return r.list(ctx, "1 = 1")
+}
+
+func (r *Repository) list(ctx context.Context, where string, args ...any) ([]model.Sandbox, error) {
 	query := `
 		SELECT
-			id, name, status,
-			rootfs_path, kernel_image_path,
-			vcpus, memory_mb, disk_gb,
-			internal_ip,
+			id, namespace, name, status,
+			rootfs_path, kernel_image_path, image, user_data,
+			kernel_args, boot_read_only_rootfs, boot_disable_console, boot_init, boot_profile, networks,
+			seccomp_mode, seccomp_filter, egress_interface, network_mtu, network_clamp_mss,
+			clock_offset, clock_boot_time, ports, exec_profile,
+			vcpus, memory_mb, disk_gb, max_vcpus, max_memory_mb,
+			internal_ip, rootfs_strategy, annotations, webhooks,
+			idle_policy, last_activity_at, network_bytes, quarantined_at, session,
+			last_boot_phases, last_error, persistent,
 			created_at, started_at, stopped_at
 		FROM sandboxes
+		WHERE ` + where + `
 		ORDER BY created_at DESC
 	`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("could not query sandboxes: %w", err)
 	}
@@ -212,7 +311,10 @@ func (r *Repository) ListSandboxes(ctx context.Context) ([]model.Sandbox, error)
 	return sandboxes, nil
 }
 
-// UpdateSandbox updates an existing sandbox.
+// UpdateSandbox updates an existing sandbox, except its annotations, idle policy,
+// activity, quarantine and persistence (see UpdateSandboxAnnotations,
+// UpdateSandboxIdlePolicy, UpdateSandboxLastActivity, UpdateSandboxNetworkBytes,
+// UpdateSandboxQuarantine and UpdateSandboxPersistent).
 func (r *Repository) UpdateSandbox(ctx context.Context, s model.Sandbox) error {
 	if s.Config.FirecrackerEngine == nil {
 		return fmt.Errorf("firecracker engine config is required: %w", model.ErrNotValid)
@@ -228,6 +330,29 @@ func (r *Repository) UpdateSandbox(ctx context.Context, s model.Sandbox) error {
 		stoppedAt = &u
 	}
 
+	networks, err := marshalNetworks(s.Config.FirecrackerEngine.Networks)
+	if err != nil {
+		return err
+	}
+	clockOffset, clockBootTime := clockColumns(s.Config.Clock)
+	ports, err := marshalPorts(s.Config.Ports)
+	if err != nil {
+		return err
+	}
+	execProfile, err := marshalExecProfile(s.Config.ExecProfile)
+	if err != nil {
+		return err
+	}
+	session, err := marshalSession(s.Session)
+	if err != nil {
+		return err
+	}
+	lastBootPhases, err := marshalBootPhases(s.LastBootPhases)
+	if err != nil {
+		return err
+	}
+	scope, scopeArgs := r.scope()
+
 	query := `
 		UPDATE sandboxes
 		SET
@@ -235,32 +360,77 @@ func (r *Repository) UpdateSandbox(ctx context.Context, s model.Sandbox) error {
 			status = ?,
 			rootfs_path = ?,
 			kernel_image_path = ?,
+			image = ?,
+			user_data = ?,
+			kernel_args = ?,
+			boot_read_only_rootfs = ?,
+			boot_disable_console = ?,
+			boot_init = ?,
+			boot_profile = ?,
+			networks = ?,
+			seccomp_mode = ?,
+			seccomp_filter = ?,
+			egress_interface = ?,
+			network_mtu = ?,
+			network_clamp_mss = ?,
+			clock_offset = ?,
+			clock_boot_time = ?,
+			ports = ?,
+			exec_profile = ?,
 			vcpus = ?,
 			memory_mb = ?,
 			disk_gb = ?,
+			max_vcpus = ?,
+			max_memory_mb = ?,
 			internal_ip = ?,
+			rootfs_strategy = ?,
+			session = ?,
+			last_boot_phases = ?,
+			last_error = ?,
 			created_at = ?,
 			started_at = ?,
 			stopped_at = ?
-		WHERE id = ?
+		WHERE id = ? AND ` + scope + `
 	`
 
-	result, err := r.db.ExecContext(
-		ctx,
-		query,
+	args := []any{
 		s.Name,
 		s.Status,
 		s.Config.FirecrackerEngine.RootFS,
 		s.Config.FirecrackerEngine.KernelImage,
+		s.Config.Image,
+		s.Config.UserData,
+		strings.Join(s.Config.FirecrackerEngine.KernelArgs, " "),
+		s.Config.FirecrackerEngine.Boot.ReadOnlyRootFS,
+		s.Config.FirecrackerEngine.Boot.DisableConsole,
+		s.Config.FirecrackerEngine.Boot.Init,
+		s.Config.FirecrackerEngine.Boot.Profile,
+		networks,
+		s.Config.FirecrackerEngine.Seccomp.Mode,
+		s.Config.FirecrackerEngine.Seccomp.Filter,
+		s.Config.FirecrackerEngine.EgressInterface,
+		s.Config.FirecrackerEngine.Network.MTU,
+		s.Config.FirecrackerEngine.Network.ClampMSS,
+		clockOffset,
+		clockBootTime,
+		ports,
+		execProfile,
 		s.Config.Resources.VCPUs,
 		s.Config.Resources.MemoryMB,
 		s.Config.Resources.DiskGB,
+		s.Config.Resources.MaxVCPUs,
+		s.Config.Resources.MaxMemoryMB,
 		s.InternalIP,
+		s.RootFSStrategy,
+		session,
+		lastBootPhases,
+		s.LastError,
 		s.CreatedAt.Unix(),
 		startedAt,
 		stoppedAt,
 		s.ID,
-	)
+	}
+	result, err := r.db.ExecContext(ctx, query, append(args, scopeArgs...)...)
 	if err != nil {
 		return fmt.Errorf("could not update sandbox: %w", err)
 	}
@@ -277,9 +447,97 @@ func (r *Repository) UpdateSandbox(ctx context.Context, s model.Sandbox) error {
 	return nil
 }
 
+// UpdateSandboxAnnotations replaces the annotations of a sandbox. They are updated
+// on their own so the operations updating the sandbox state (e.g. a long start)
+// don't overwrite the annotations changed meanwhile.
+func (r *Repository) UpdateSandboxAnnotations(ctx context.Context, id string, annotations map[string]string) error {
+	data, err := marshalAnnotations(annotations)
+	if err != nil {
+		return err
+	}
+
+	scope, scopeArgs := r.scope()
+	result, err := r.db.ExecContext(ctx, `UPDATE sandboxes SET annotations = ? WHERE id = ? AND `+scope, append([]any{data, id}, scopeArgs...)...)
+	if err != nil {
+		return fmt.Errorf("could not update sandbox annotations: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("sandbox %s: %w", id, model.ErrNotFound)
+	}
+
+	r.logger.Debugf("Updated sandbox annotations in repository: %s", id)
+	return nil
+}
+
+// UpdateSandboxIdlePolicy replaces the idle policy of a sandbox, nil removes it.
+func (r *Repository) UpdateSandboxIdlePolicy(ctx context.Context, id string, policy *model.IdlePolicy) error {
+	data, err := marshalIdlePolicy(policy)
+	if err != nil {
+		return err
+	}
+
+	return r.updateSandboxColumn(ctx, id, "idle_policy", "?", data)
+}
+
+// UpdateSandboxLastActivity sets the last activity time of a sandbox, unless the
+// stored one is later. The activity is recorded by concurrent commands, the update
+// is a single statement so an older time never overwrites a newer one.
+func (r *Repository) UpdateSandboxLastActivity(ctx context.Context, id string, at time.Time) error {
+	return r.updateSandboxColumn(ctx, id, "last_activity_at", "MAX(COALESCE(last_activity_at, 0), ?)", at.Unix())
+}
+
+// UpdateSandboxNetworkBytes sets the network bytes of a sandbox seen on the last
+// idle check.
+func (r *Repository) UpdateSandboxNetworkBytes(ctx context.Context, id string, bytes uint64) error {
+	return r.updateSandboxColumn(ctx, id, "network_bytes", "?", int64(bytes))
+}
+
+// UpdateSandboxQuarantine sets the quarantine time of a sandbox, nil lifts the
+// quarantine.
+func (r *Repository) UpdateSandboxQuarantine(ctx context.Context, id string, at *time.Time) error {
+	var value *int64
+	if at != nil {
+		u := at.Unix()
+		value = &u
+	}
+	return r.updateSandboxColumn(ctx, id, "quarantined_at", "?", value)
+}
+
+// UpdateSandboxPersistent sets if a sandbox is started again by the recovery.
+func (r *Repository) UpdateSandboxPersistent(ctx context.Context, id string, persistent bool) error {
+	return r.updateSandboxColumn(ctx, id, "persistent", "?", persistent)
+}
+
+// updateSandboxColumn sets a column of a sandbox to the set expression of a value
+// (e.g. "?" for the value).
+func (r *Repository) updateSandboxColumn(ctx context.Context, id, column, set string, value any) error {
+	scope, scopeArgs := r.scope()
+	result, err := r.db.ExecContext(ctx, `UPDATE sandboxes SET `+column+` = `+set+` WHERE id = ? AND `+scope, append([]any{value, id}, scopeArgs...)...)
+	if err != nil {
+		return fmt.Errorf("could not update sandbox %s: %w", column, err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("sandbox %s: %w", id, model.ErrNotFound)
+	}
+
+	r.logger.Debugf("Updated sandbox %s in repository: %s", column, id)
+	return nil
+}
+
 // DeleteSandbox deletes a sandbox.
 func (r *Repository) DeleteSandbox(ctx context.Context, id string) error {
-	result, err := r.db.ExecContext(ctx, `DELETE FROM sandboxes WHERE id = ?`, id)
+	scope, scopeArgs := r.scope()
+	result, err := r.db.ExecContext(ctx, `DELETE FROM sandboxes WHERE id = ? AND `+scope, append([]any{id}, scopeArgs...)...)
 	if err != nil {
 		return fmt.Errorf("could not delete sandbox: %w", err)
 	}
@@ -296,8 +554,17 @@ func (r *Repository) DeleteSandbox(ctx context.Context, id string) error {
 	return nil
 }
 
-func (r *Repository) scanOne(ctx context.Context, query string, arg any) (*model.Sandbox, error) {
-	row := r.db.QueryRowContext(ctx, query, arg)
+// scope returns the SQL condition and its arguments that select the sandboxes of
+// the repository namespace.
+func (r *Repository) scope() (string, []any) {
+	if r.namespace == model.AllNamespaces {
+		return "1 = 1", nil
+	}
+	return "namespace = ?", []any{r.namespace}
+}
+
+func (r *Repository) scanOne(ctx context.Context, query string, args ...any) (*model.Sandbox, error) {
+	row := r.db.QueryRowContext(ctx, query, args...)
 	sandbox, err := r.scanRow(row)
 	if err != nil {
 		return nil, err
@@ -311,22 +578,62 @@ type scanner interface {
 
 func (r *Repository) scanRow(s scanner) (model.Sandbox, error) {
 	var sandbox model.Sandbox
-	var rootFSPath, kernelImagePath string
-	var vcpus float64
-	var memoryMB, diskGB int
-	var internalIP string
+	var rootFSPath, kernelImagePath, image, userData string
+	var kernelArgs, bootInit, bootProfile, networks string
+	var seccompMode, seccompFilter, egressInterface string
+	var bootReadOnlyRootFS, bootDisableConsole, networkClampMSS bool
+	var networkMTU int
+	var clockOffset, clockBootTime sql.NullInt64
+	var ports, execProfile string
+	var vcpus, maxVCPUs float64
+	var memoryMB, diskGB, maxMemoryMB int
+	var internalIP, rootFSStrategy, annotations, webhooks, idlePolicy, session string
+	var lastBootPhases, lastError string
+	var lastActivityAt, quarantinedAt sql.NullInt64
+	var networkBytes int64
 	var createdAt, startedAt, stoppedAt sql.NullInt64
 
 	err := s.Scan(
 		&sandbox.ID,
+		&sandbox.Namespace,
 		&sandbox.Name,
 		&sandbox.Status,
 		&rootFSPath,
 		&kernelImagePath,
+		&image,
+		&userData,
+		&kernelArgs,
+		&bootReadOnlyRootFS,
+		&bootDisableConsole,
+		&bootInit,
+		&bootProfile,
+		&networks,
+		&seccompMode,
+		&seccompFilter,
+		&egressInterface,
+		&networkMTU,
+		&networkClampMSS,
+		&clockOffset,
+		&clockBootTime,
+		&ports,
+		&execProfile,
 		&vcpus,
 		&memoryMB,
 		&diskGB,
+		&maxVCPUs,
+		&maxMemoryMB,
 		&internalIP,
+		&rootFSStrategy,
+		&annotations,
+		&webhooks,
+		&idlePolicy,
+		&lastActivityAt,
+		&networkBytes,
+		&quarantinedAt,
+		&session,
+		&lastBootPhases,
+		&lastError,
+		&sandbox.Persistent,
 		&createdAt,
 		&startedAt,
 		&stoppedAt,
@@ -340,10 +647,72 @@ func (r *Repository) scanRow(s scanner) (model.Sandbox, error) {
 		FirecrackerEngine: &model.FirecrackerEngineConfig{
 			RootFS:      rootFSPath,
 			KernelImage: kernelImagePath,
+			Boot: model.BootOptions{
+				ReadOnlyRootFS: bootReadOnlyRootFS,
+				DisableConsole: bootDisableConsole,
+				Init:           bootInit,
+				Profile:        bootProfile,
+			},
+			Seccomp:         model.SeccompOptions{Mode: model.SeccompMode(seccompMode), Filter: seccompFilter},
+			EgressInterface: egressInterface,
+			Network:         model.NetworkOptions{MTU: networkMTU, ClampMSS: networkClampMSS},
 		},
-		Resources: model.Resources{VCPUs: vcpus, MemoryMB: memoryMB, DiskGB: diskGB},
+		Image:     image,
+		Resources: model.Resources{VCPUs: vcpus, MemoryMB: memoryMB, DiskGB: diskGB, MaxVCPUs: maxVCPUs, MaxMemoryMB: maxMemoryMB},
+		UserData:  userData,
+	}
+	if kernelArgs != "" {
+		sandbox.Config.FirecrackerEngine.KernelArgs = strings.Fields(kernelArgs)
+	}
+	sandbox.Config.FirecrackerEngine.Networks, err = unmarshalNetworks(networks)
+	if err != nil {
+		return model.Sandbox{}, err
+	}
+	if clockOffset.Valid {
+		sandbox.Config.Clock = &model.ClockConfig{Offset: time.Duration(clockOffset.Int64)}
+		if clockBootTime.Valid {
+			sandbox.Config.Clock.BootTime = timeFromUnix(clockBootTime.Int64)
+		}
+	}
+	sandbox.Config.Ports, err = unmarshalPorts(ports)
+	if err != nil {
+		return model.Sandbox{}, err
+	}
+	sandbox.Config.ExecProfile, err = unmarshalExecProfile(execProfile)
+	if err != nil {
+		return model.Sandbox{}, err
 	}
 	sandbox.InternalIP = internalIP
+	sandbox.RootFSStrategy = model.RootFSStrategy(rootFSStrategy)
+	sandbox.Annotations, err = unmarshalAnnotations(annotations)
+	if err != nil {
+		return model.Sandbox{}, err
+	}
+	sandbox.Webhooks, err = unmarshalWebhooks(webhooks)
+	if err != nil {
+		return model.Sandbox{}, err
+	}
+	sandbox.IdlePolicy, err = unmarshalIdlePolicy(idlePolicy)
+	if err != nil {
+		return model.Sandbox{}, err
+	}
+	sandbox.Session, err = unmarshalSession(session)
+	if err != nil {
+		return model.Sandbox{}, err
+	}
+	sandbox.LastBootPhases, err = unmarshalBootPhases(lastBootPhases)
+	if err != nil {
+		return model.Sandbox{}, err
+	}
+	sandbox.LastError = lastError
+	if lastActivityAt.Valid {
+		sandbox.Activity.LastActivityAt = timeFromUnix(lastActivityAt.Int64)
+	}
+	sandbox.Activity.NetworkBytes = uint64(networkBytes)
+	if quarantinedAt.Valid {
+		t := timeFromUnix(quarantinedAt.Int64)
+		sandbox.QuarantinedAt = &t
+	}
 
 	if err := r.setTimestamps(&sandbox, createdAt, startedAt, stoppedAt); err != nil {
 		return model.Sandbox{}, err
@@ -371,3 +740,375 @@ func (r *Repository) setTimestamps(s *model.Sandbox, createdAt, startedAt, stopp
 }
 
 func timeFromUnix(unix int64) time.Time { return time.Unix(unix, 0).UTC() }
+
+// clockColumns returns the clock columns of the sandbox, NULL when the sandbox
+// uses the host time or doesn't have a boot time.
+func clockColumns(c *model.ClockConfig) (offset, bootTime *int64) {
+	if c == nil {
+		return nil, nil
+	}
+	o := int64(c.Offset)
+	offset = &o
+	if !c.BootTime.IsZero() {
+		u := c.BootTime.Unix()
+		bootTime = &u
+	}
+	return offset, bootTime
+}
+
+// networkJSON is the stored representation of a sandbox network interface.
+type networkJSON struct {
+	Mode    string      `json:"mode"`
+	Network string      `json:"network,omitempty"`
+	Egress  *egressJSON `json:"egress,omitempty"`
+	Address string      `json:"address,omitempty"`
+}
+
+type egressJSON struct {
+	Default      string           `json:"default"`
+	Rules        []egressRuleJSON `json:"rules,omitempty"`
+	FailClosed   bool             `json:"fail_closed,omitempty"`
+	DNSUpstreams []string         `json:"dns_upstreams,omitempty"`
+	SystemSvcs   []string         `json:"allow_system_services,omitempty"`
+}
+
+type egressRuleJSON struct {
+	Domain string `json:"domain"`
+	Action string `json:"action"`
+	ICMP   bool   `json:"icmp,omitempty"`
+}
+
+func marshalAnnotations(annotations map[string]string) (string, error) {
+	if len(annotations) == 0 {
+		return "", nil
+	}
+
+	data, err := json.Marshal(annotations)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal annotations: %w", err)
+	}
+	return string(data), nil
+}
+
+func unmarshalAnnotations(data string) (map[string]string, error) {
+	if data == "" {
+		return nil, nil
+	}
+
+	var annotations map[string]string
+	if err := json.Unmarshal([]byte(data), &annotations); err != nil {
+		return nil, fmt.Errorf("could not unmarshal annotations: %w", err)
+	}
+	return annotations, nil
+}
+
+func marshalPorts(ports map[string]int) (string, error) {
+	if len(ports) == 0 {
+		return "", nil
+	}
+
+	data, err := json.Marshal(ports)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal ports: %w", err)
+	}
+	return string(data), nil
+}
+
+func unmarshalPorts(data string) (map[string]int, error) {
+	if data == "" {
+		return nil, nil
+	}
+
+	var ports map[string]int
+	if err := json.Unmarshal([]byte(data), &ports); err != nil {
+		return nil, fmt.Errorf("could not unmarshal ports: %w", err)
+	}
+	return ports, nil
+}
+
+// execProfileJSON is the stored representation of a sandbox exec profile.
+type execProfileJSON struct {
+	PathPrepend []string          `json:"path_prepend,omitempty"`
+	Locale      string            `json:"locale,omitempty"`
+	Umask       string            `json:"umask,omitempty"`
+	Ulimits     map[string]string `json:"ulimits,omitempty"`
+	Shell       string            `json:"shell,omitempty"`
+}
+
+func marshalExecProfile(p *model.ExecProfile) (string, error) {
+	if p == nil {
+		return "", nil
+	}
+
+	data, err := json.Marshal(execProfileJSON{
+		PathPrepend: p.PathPrepend,
+		Locale:      p.Locale,
+		Umask:       p.Umask,
+		Ulimits:     p.Ulimits,
+		Shell:       p.Shell,
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not marshal exec profile: %w", err)
+	}
+	return string(data), nil
+}
+
+func unmarshalExecProfile(data string) (*model.ExecProfile, error) {
+	if data == "" {
+		return nil, nil
+	}
+
+	var p execProfileJSON
+	if err := json.Unmarshal([]byte(data), &p); err != nil {
+		return nil, fmt.Errorf("could not unmarshal exec profile: %w", err)
+	}
+	return &model.ExecProfile{
+		PathPrepend: p.PathPrepend,
+		Locale:      p.Locale,
+		Umask:       p.Umask,
+		Ulimits:     p.Ulimits,
+		Shell:       p.Shell,
+	}, nil
+}
+
+// webhookJSON is the stored representation of a sandbox webhook.
+type webhookJSON struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret,omitempty"`
+	Events []string `json:"events,omitempty"`
+}
+
+func marshalWebhooks(webhooks []model.Webhook) (string, error) {
+	if len(webhooks) == 0 {
+		return "", nil
+	}
+
+	js := make([]webhookJSON, 0, len(webhooks))
+	for _, w := range webhooks {
+		j := webhookJSON{URL: w.URL, Secret: w.Secret}
+		for _, t := range w.Events {
+			j.Events = append(j.Events, string(t))
+		}
+		js = append(js, j)
+	}
+
+	data, err := json.Marshal(js)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal webhooks: %w", err)
+	}
+	return string(data), nil
+}
+
+func unmarshalWebhooks(data string) ([]model.Webhook, error) {
+	if data == "" {
+		return nil, nil
+	}
+
+	var js []webhookJSON
+	if err := json.Unmarshal([]byte(data), &js); err != nil {
+		return nil, fmt.Errorf("could not unmarshal webhooks: %w", err)
+	}
+
+	webhooks := make([]model.Webhook, 0, len(js))
+	for _, j := range js {
+		w := model.Webhook{URL: j.URL, Secret: j.Secret}
+		for _, t := range j.Events {
+			w.Events = append(w.Events, model.EventType(t))
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, nil
+}
+
+// idlePolicyJSON is the stored representation of a sandbox idle policy.
+type idlePolicyJSON struct {
+	TimeoutNS int64  `json:"timeout_ns"`
+	Action    string `json:"action"`
+}
+
+func marshalIdlePolicy(p *model.IdlePolicy) (string, error) {
+	if p == nil {
+		return "", nil
+	}
+
+	data, err := json.Marshal(idlePolicyJSON{TimeoutNS: int64(p.Timeout), Action: string(p.Action)})
+	if err != nil {
+		return "", fmt.Errorf("could not marshal idle policy: %w", err)
+	}
+	return string(data), nil
+}
+
+func unmarshalIdlePolicy(data string) (*model.IdlePolicy, error) {
+	if data == "" {
+		return nil, nil
+	}
+
+	var j idlePolicyJSON
+	if err := json.Unmarshal([]byte(data), &j); err != nil {
+		return nil, fmt.Errorf("could not unmarshal idle policy: %w", err)
+	}
+	return &model.IdlePolicy{Timeout: time.Duration(j.TimeoutNS), Action: model.IdleAction(j.Action)}, nil
+}
+
+// sessionJSON is the stored representation of the session configuration of the
+// last start.
+type sessionJSON struct {
+	Name             string            `json:"name,omitempty"`
+	Env              map[string]string `json:"env,omitempty"`
+	Egress           *egressJSON       `json:"egress,omitempty"`
+	EgressPolicyName string            `json:"egress_policy_name,omitempty"`
+	IntegrityPaths   []string          `json:"integrity_paths,omitempty"`
+}
+
+func marshalSession(c *model.SessionConfig) (string, error) {
+	if c == nil {
+		return "", nil
+	}
+
+	j := sessionJSON{
+		Name:             c.Name,
+		Env:              c.Env,
+		EgressPolicyName: c.EgressPolicyName,
+		IntegrityPaths:   c.IntegrityPaths,
+	}
+	if c.Egress != nil {
+		e := toEgressJSON(*c.Egress)
+		j.Egress = &e
+	}
+
+	data, err := json.Marshal(j)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal session: %w", err)
+	}
+	return string(data), nil
+}
+
+func unmarshalSession(data string) (*model.SessionConfig, error) {
+	if data == "" {
+		return nil, nil
+	}
+
+	var j sessionJSON
+	if err := json.Unmarshal([]byte(data), &j); err != nil {
+		return nil, fmt.Errorf("could not unmarshal session: %w", err)
+	}
+
+	c := &model.SessionConfig{
+		Name:             j.Name,
+		Env:              j.Env,
+		EgressPolicyName: j.EgressPolicyName,
+		IntegrityPaths:   j.IntegrityPaths,
+	}
+	if j.Egress != nil {
+		e := fromEgressJSON(*j.Egress)
+		c.Egress = &e
+	}
+	return c, nil
+}
+
+// bootPhaseJSON is the stored representation of a start phase.
+type bootPhaseJSON struct {
+	Name       string `json:"name"`
+	DurationNS int64  `json:"duration_ns"`
+	Error      string `json:"error,omitempty"`
+}
+
+func marshalBootPhases(phases []model.BootPhase) (string, error) {
+	if len(phases) == 0 {
+		return "", nil
+	}
+
+	js := make([]bootPhaseJSON, 0, len(phases))
+	for _, p := range phases {
+		js = append(js, bootPhaseJSON{Name: p.Name, DurationNS: int64(p.Duration), Error: p.Error})
+	}
+
+	data, err := json.Marshal(js)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal boot phases: %w", err)
+	}
+	return string(data), nil
+}
+
+func unmarshalBootPhases(data string) ([]model.BootPhase, error) {
+	if data == "" {
+		return nil, nil
+	}
+
+	var js []bootPhaseJSON
+	if err := json.Unmarshal([]byte(data), &js); err != nil {
+		return nil, fmt.Errorf("could not unmarshal boot phases: %w", err)
+	}
+
+	phases := make([]model.BootPhase, 0, len(js))
+	for _, j := range js {
+		phases = append(phases, model.BootPhase{Name: j.Name, Duration: time.Duration(j.DurationNS), Error: j.Error})
+	}
+	return phases, nil
+}
+
+func marshalNetworks(nets []model.NetworkInterface) (string, error) {
+	if len(nets) == 0 {
+		return "", nil
+	}
+
+	js := make([]networkJSON, 0, len(nets))
+	for _, n := range nets {
+		j := networkJSON{Mode: string(n.Mode), Network: n.Network, Address: n.Address}
+		if n.Egress != nil {
+			e := toEgressJSON(*n.Egress)
+			j.Egress = &e
+		}
+		js = append(js, j)
+	}
+
+	data, err := json.Marshal(js)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal networks: %w", err)
+	}
+	return string(data), nil
+}
+
+func unmarshalNetworks(data string) ([]model.NetworkInterface, error) {
+	if data == "" {
+		return nil, nil
+	}
+
+	var js []networkJSON
+	if err := json.Unmarshal([]byte(data), &js); err != nil {
+		return nil, fmt.Errorf("could not unmarshal networks: %w", err)
+	}
+
+	nets := make([]model.NetworkInterface, 0, len(js))
+	for _, j := range js {
+		n := model.NetworkInterface{Mode: model.NetworkMode(j.Mode), Network: j.Network, Address: j.Address}
+		if j.Egress != nil {
+			e := fromEgressJSON(*j.Egress)
+			n.Egress = &e
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func toEgressJSON(p model.EgressPolicy) egressJSON {
+	j := egressJSON{Default: string(p.Default), FailClosed: p.FailClosed, DNSUpstreams: p.DNSUpstreams}
+	for _, s := range p.AllowSystemServices {
+		j.SystemSvcs = append(j.SystemSvcs, string(s))
+	}
+	for _, r := range p.Rules {
+		j.Rules = append(j.Rules, egressRuleJSON{Domain: r.Domain, Action: string(r.Action), ICMP: r.ICMP})
+	}
+	return j
+}
+
+func fromEgressJSON(j egressJSON) model.EgressPolicy {
+	p := model.EgressPolicy{Default: model.EgressAction(j.Default), FailClosed: j.FailClosed, DNSUpstreams: j.DNSUpstreams}
+	for _, s := range j.SystemSvcs {
+		p.AllowSystemServices = append(p.AllowSystemServices, model.EgressSystemService(s))
+	}
+	for _, r := range j.Rules {
+		p.Rules = append(p.Rules, model.EgressRule{Domain: r.Domain, Action: model.EgressAction(r.Action), ICMP: r.ICMP})
+	}
+	return p
+}
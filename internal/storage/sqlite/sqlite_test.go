@@ -3,6 +3,8 @@ package sqlite_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"os"
 	"path/filepath"
 	"testing"
 	"time"
@@ -27,10 +29,35 @@ func sandboxFixture(id, name string) model.Sandbox {
 			FirecrackerEngine: &model.FirecrackerEngineConfig{
 				RootFS:      "/images/rootfs.ext4",
 				KernelImage: "/images/vmlinux",
+				KernelArgs:  []string{"panic=5", "quiet"},
+				Boot:        model.BootOptions{DisableConsole: true, Init: "/sbin/init", Profile: "debug"},
+				Networks: []model.NetworkInterface{
+					{Mode: model.NetworkModeIsolated, Network: "backend", Address: "172.20.3.7"},
+					{Mode: model.NetworkModeNAT, Egress: &model.EgressPolicy{
+						Default:    model.EgressActionDeny,
+						Rules:      []model.EgressRule{{Domain: "github.com", Action: model.EgressActionAllow}},
+						FailClosed: true,
+					}},
+				},
+				Seccomp:         model.SeccompOptions{Mode: model.SeccompModeCustom, Filter: "/etc/sbx/filter.bpf"},
+				EgressInterface: "wg0",
+				Network:         model.NetworkOptions{MTU: 1380, ClampMSS: true},
+			},
+			Image:     "v0.1.0",
+			Resources: model.Resources{VCPUs: 2, MemoryMB: 2048, DiskGB: 10, MaxVCPUs: 4, MaxMemoryMB: 4096},
+			UserData:  "#!/bin/sh\necho hello\n",
+			Clock:     &model.ClockConfig{BootTime: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)},
+			Ports:     map[string]int{"web": 3000, "db": 5432},
+			ExecProfile: &model.ExecProfile{
+				PathPrepend: []string{"/opt/tools/bin"},
+				Locale:      "C.UTF-8",
+				Umask:       "027",
+				Ulimits:     map[string]string{"nofile": "65536"},
+				Shell:       "/bin/bash",
 			},
-			Resources: model.Resources{VCPUs: 2, MemoryMB: 2048, DiskGB: 10},
 		},
-		InternalIP: "10.0.0.2",
+		InternalIP:     "10.0.0.2",
+		RootFSStrategy: model.RootFSStrategyReflink,
 	}
 }
 
@@ -56,7 +83,19 @@ func TestRepositoryCRUD(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "sb-1", got.Name)
 	assert.Equal(t, "10.0.0.2", got.InternalIP)
+	assert.Equal(t, model.RootFSStrategyReflink, got.RootFSStrategy)
 	assert.Equal(t, "/images/rootfs.ext4", got.Config.FirecrackerEngine.RootFS)
+	assert.Equal(t, "v0.1.0", got.Config.Image)
+	assert.Equal(t, "#!/bin/sh\necho hello\n", got.Config.UserData)
+	assert.Equal(t, []string{"panic=5", "quiet"}, got.Config.FirecrackerEngine.KernelArgs)
+	assert.Equal(t, model.BootOptions{DisableConsole: true, Init: "/sbin/init", Profile: "debug"}, got.Config.FirecrackerEngine.Boot)
+	assert.Equal(t, sb.Config.FirecrackerEngine.Networks, got.Config.FirecrackerEngine.Networks)
+	assert.Equal(t, model.SeccompOptions{Mode: model.SeccompModeCustom, Filter: "/etc/sbx/filter.bpf"}, got.Config.FirecrackerEngine.Seccomp)
+	assert.Equal(t, "wg0", got.Config.FirecrackerEngine.EgressInterface)
+	assert.Equal(t, model.NetworkOptions{MTU: 1380, ClampMSS: true}, got.Config.FirecrackerEngine.Network)
+	assert.Equal(t, sb.Config.Clock, got.Config.Clock)
+	assert.Equal(t, map[string]int{"web": 3000, "db": 5432}, got.Config.Ports)
+	assert.Equal(t, sb.Config.ExecProfile, got.Config.ExecProfile)
 
 	gotByName, err := repo.GetSandboxByName(ctx, "sb-1")
 	require.NoError(t, err)
@@ -78,6 +117,16 @@ func TestRepositoryCRUD(t *testing.T) {
 	assert.Equal(t, "10.0.0.3", updated.InternalIP)
 	assert.NotNil(t, updated.StartedAt)
 
+	sb.Config.Clock = nil
+	sb.Config.Ports = nil
+	sb.Config.ExecProfile = nil
+	require.NoError(t, repo.UpdateSandbox(ctx, sb))
+	updated, err = repo.GetSandbox(ctx, "id-1")
+	require.NoError(t, err)
+	assert.Nil(t, updated.Config.Clock)
+	assert.Nil(t, updated.Config.Ports)
+	assert.Nil(t, updated.Config.ExecProfile)
+
 	require.NoError(t, repo.DeleteSandbox(ctx, "id-1"))
 	_, err = repo.GetSandbox(ctx, "id-1")
 	require.Error(t, err)
@@ -110,3 +159,696 @@ func TestRepositoryConstraints(t *testing.T) {
 	require.Error(t, err)
 	assert.True(t, errors.Is(err, model.ErrNotFound))
 }
+
+func TestRepositoryAnnotations(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	sb := sandboxFixture("id-1", "sb-1")
+	sb.Annotations = map[string]string{"owner": "alice@example.com"}
+	require.NoError(t, repo.CreateSandbox(ctx, sb))
+
+	got, err := repo.GetSandbox(ctx, "id-1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"owner": "alice@example.com"}, got.Annotations)
+
+	annotations := map[string]string{"owner": "bob@example.com", "ticket": "https://issues.example.com/42"}
+	require.NoError(t, repo.UpdateSandboxAnnotations(ctx, "id-1", annotations))
+
+	// Updating the sandbox should keep the annotations updated meanwhile.
+	sb.Status = model.SandboxStatusRunning
+	require.NoError(t, repo.UpdateSandbox(ctx, sb))
+
+	all, err := repo.ListSandboxes(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Equal(t, model.SandboxStatusRunning, all[0].Status)
+	assert.Equal(t, annotations, all[0].Annotations)
+
+	require.NoError(t, repo.UpdateSandboxAnnotations(ctx, "id-1", nil))
+	got, err = repo.GetSandboxByName(ctx, "sb-1")
+	require.NoError(t, err)
+	assert.Nil(t, got.Annotations)
+
+	err = repo.UpdateSandboxAnnotations(ctx, "id-x", annotations)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestRepositoryWebhooks(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	webhooks := []model.Webhook{
+		{URL: "https://hooks.example.com/sbx", Secret: "s3cret", Events: []model.EventType{model.EventTypeSandboxStarted, model.EventTypeSandboxStopped}},
+		{URL: "http://127.0.0.1:8080/events"},
+	}
+	sb := sandboxFixture("id-1", "sb-1")
+	sb.Webhooks = webhooks
+	require.NoError(t, repo.CreateSandbox(ctx, sb))
+
+	// Updating the sandbox should keep the webhooks.
+	sb.Status = model.SandboxStatusRunning
+	sb.Webhooks = nil
+	require.NoError(t, repo.UpdateSandbox(ctx, sb))
+
+	got, err := repo.GetSandbox(ctx, "id-1")
+	require.NoError(t, err)
+	assert.Equal(t, webhooks, got.Webhooks)
+
+	require.NoError(t, repo.CreateSandbox(ctx, sandboxFixture("id-2", "sb-2")))
+	got, err = repo.GetSandboxByName(ctx, "sb-2")
+	require.NoError(t, err)
+	assert.Nil(t, got.Webhooks)
+}
+
+func TestRepositoryIdle(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	sb := sandboxFixture("id-1", "sb-1")
+	require.NoError(repo.CreateSandbox(ctx, sb))
+
+	policy := &model.IdlePolicy{Timeout: 30 * time.Minute, Action: model.IdleActionStop}
+	require.NoError(repo.UpdateSandboxIdlePolicy(ctx, "id-1", policy))
+	at := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	require.NoError(repo.UpdateSandboxLastActivity(ctx, "id-1", at))
+	// An older activity should not overwrite a newer one.
+	require.NoError(repo.UpdateSandboxLastActivity(ctx, "id-1", at.Add(-time.Hour)))
+	require.NoError(repo.UpdateSandboxNetworkBytes(ctx, "id-1", 1234))
+
+	// Updating the sandbox should keep the idle policy and the activity.
+	sb.Status = model.SandboxStatusRunning
+	require.NoError(repo.UpdateSandbox(ctx, sb))
+
+	got, err := repo.GetSandbox(ctx, "id-1")
+	require.NoError(err)
+	assert.Equal(policy, got.IdlePolicy)
+	assert.Equal(model.SandboxActivity{LastActivityAt: at, NetworkBytes: 1234}, got.Activity)
+
+	require.NoError(repo.UpdateSandboxIdlePolicy(ctx, "id-1", nil))
+	got, err = repo.GetSandbox(ctx, "id-1")
+	require.NoError(err)
+	assert.Nil(got.IdlePolicy)
+
+	assert.ErrorIs(repo.UpdateSandboxIdlePolicy(ctx, "id-x", policy), model.ErrNotFound)
+	assert.ErrorIs(repo.UpdateSandboxLastActivity(ctx, "id-x", at), model.ErrNotFound)
+	assert.ErrorIs(repo.UpdateSandboxNetworkBytes(ctx, "id-x", 1), model.ErrNotFound)
+}
+
+func TestRepositoryQuarantine(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	sb := sandboxFixture("id-1", "sb-1")
+	require.NoError(repo.CreateSandbox(ctx, sb))
+
+	at := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	require.NoError(repo.UpdateSandboxQuarantine(ctx, "id-1", &at))
+
+	// Updating the sandbox should keep the quarantine.
+	sb.Status = model.SandboxStatusRunning
+	require.NoError(repo.UpdateSandbox(ctx, sb))
+
+	got, err := repo.GetSandbox(ctx, "id-1")
+	require.NoError(err)
+	assert.Equal(&at, got.QuarantinedAt)
+
+	require.NoError(repo.UpdateSandboxQuarantine(ctx, "id-1", nil))
+	got, err = repo.GetSandbox(ctx, "id-1")
+	require.NoError(err)
+	assert.Nil(got.QuarantinedAt)
+
+	assert.ErrorIs(repo.UpdateSandboxQuarantine(ctx, "id-x", &at), model.ErrNotFound)
+}
+
+func TestRepositoryPersistent(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	sb := sandboxFixture("id-1", "sb-1")
+	sb.Persistent = true
+	require.NoError(repo.CreateSandbox(ctx, sb))
+
+	// Updating the sandbox should keep the persistence.
+	sb.Persistent = false
+	sb.Status = model.SandboxStatusRunning
+	require.NoError(repo.UpdateSandbox(ctx, sb))
+
+	got, err := repo.GetSandbox(ctx, "id-1")
+	require.NoError(err)
+	assert.True(got.Persistent)
+
+	require.NoError(repo.UpdateSandboxPersistent(ctx, "id-1", false))
+	got, err = repo.GetSandbox(ctx, "id-1")
+	require.NoError(err)
+	assert.False(got.Persistent)
+
+	assert.ErrorIs(repo.UpdateSandboxPersistent(ctx, "id-x", true), model.ErrNotFound)
+}
+
+func TestRepositorySession(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	sb := sandboxFixture("id-1", "sb-1")
+	require.NoError(repo.CreateSandbox(ctx, sb))
+
+	got, err := repo.GetSandbox(ctx, "id-1")
+	require.NoError(err)
+	assert.Nil(got.Session)
+
+	session := &model.SessionConfig{
+		Name: "dev",
+		Env:  map[string]string{"FOO": "bar"},
+		Egress: &model.EgressPolicy{
+			Default: model.EgressActionDeny,
+			Rules:   []model.EgressRule{{Domain: "github.com", Action: model.EgressActionAllow}},
+		},
+		IntegrityPaths: []string{"/usr/bin"},
+	}
+	sb.Status = model.SandboxStatusRunning
+	sb.Session = session
+	require.NoError(repo.UpdateSandbox(ctx, sb))
+
+	got, err = repo.GetSandbox(ctx, "id-1")
+	require.NoError(err)
+	assert.Equal(session, got.Session)
+
+	sb.Session = &model.SessionConfig{EgressPolicyName: "github"}
+	require.NoError(repo.UpdateSandbox(ctx, sb))
+
+	got, err = repo.GetSandboxByName(ctx, "sb-1")
+	require.NoError(err)
+	assert.Equal(&model.SessionConfig{EgressPolicyName: "github"}, got.Session)
+}
+
+func TestRepositoryLastStart(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	sb := sandboxFixture("id-1", "sb-1")
+	require.NoError(repo.CreateSandbox(ctx, sb))
+
+	phases := []model.BootPhase{
+		{Name: "api_ready", Duration: 120 * time.Millisecond},
+		{Name: "guest_ready", Duration: 60 * time.Second, Error: "timeout waiting for guest SSH"},
+	}
+	sb.LastBootPhases = phases
+	sb.LastError = `start step "guest_ready" failed: timeout waiting for guest SSH`
+	require.NoError(repo.UpdateSandbox(ctx, sb))
+
+	got, err := repo.GetSandbox(ctx, "id-1")
+	require.NoError(err)
+	assert.Equal(phases, got.LastBootPhases)
+	assert.Equal(sb.LastError, got.LastError)
+
+	// A successful start clears the error.
+	sb.LastBootPhases = phases[:1]
+	sb.LastError = ""
+	require.NoError(repo.UpdateSandbox(ctx, sb))
+
+	got, err = repo.GetSandboxByName(ctx, "sb-1")
+	require.NoError(err)
+	assert.Equal(phases[:1], got.LastBootPhases)
+	assert.Empty(got.LastError)
+}
+
+func TestRepositoryNamespaces(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	newNSRepo := func(ns string) *sqlite.Repository {
+		repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{DBPath: dbPath, Namespace: ns, Logger: log.Noop})
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = repo.Close() })
+		return repo
+	}
+	def, teamA, all := newNSRepo(""), newNSRepo("team-a"), newNSRepo(model.AllNamespaces)
+
+	// The same name can be used in different namespaces.
+	require.NoError(t, def.CreateSandbox(ctx, sandboxFixture("id-1", "sb")))
+	require.NoError(t, teamA.CreateSandbox(ctx, sandboxFixture("id-2", "sb")))
+	err := teamA.CreateSandbox(ctx, sandboxFixture("id-3", "sb"))
+	assert.ErrorIs(t, err, model.ErrAlreadyExists)
+
+	got, err := teamA.GetSandboxByName(ctx, "sb")
+	require.NoError(t, err)
+	assert.Equal(t, "id-2", got.ID)
+	assert.Equal(t, "team-a", got.Namespace)
+
+	// The sandboxes of other namespaces are not visible.
+	_, err = teamA.GetSandbox(ctx, "id-1")
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	assert.ErrorIs(t, teamA.DeleteSandbox(ctx, "id-1"), model.ErrNotFound)
+	assert.ErrorIs(t, teamA.UpdateSandbox(ctx, sandboxFixture("id-1", "sb")), model.ErrNotFound)
+	list, err := def.ListSandboxes(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, model.DefaultNamespace, list[0].Namespace)
+
+	// A sandbox can't be created out of the repository namespace.
+	sb := sandboxFixture("id-4", "other")
+	sb.Namespace = "team-b"
+	assert.ErrorIs(t, teamA.CreateSandbox(ctx, sb), model.ErrNotValid)
+
+	// All the namespaces see every sandbox, names must be unique to get them by name.
+	list, err = all.ListSandboxes(ctx)
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+	list, err = teamA.ListAllSandboxes(ctx)
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+	got, err = all.GetSandbox(ctx, "id-2")
+	require.NoError(t, err)
+	assert.Equal(t, "team-a", got.Namespace)
+	_, err = all.GetSandboxByName(ctx, "sb")
+	assert.ErrorIs(t, err, model.ErrNotValid)
+	require.NoError(t, all.CreateSandbox(ctx, sb))
+	assert.ErrorIs(t, all.CreateSandbox(ctx, sandboxFixture("id-5", "no-namespace")), model.ErrNotValid)
+
+	// Usage records are scoped too.
+	t0 := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, teamA.CreateUsageRecord(ctx, model.UsageRecord{SandboxID: "id-2", Kind: model.UsageKindDisk, StartedAt: t0, EndedAt: t0.Add(time.Hour)}))
+	records, err := def.ListUsageRecords(ctx, model.UsageRecordFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, records)
+	records, err = all.ListUsageRecords(ctx, model.UsageRecordFilter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "team-a", records[0].Namespace)
+
+	_, err = sqlite.NewRepository(ctx, sqlite.RepositoryConfig{DBPath: dbPath, Namespace: "Team_A"})
+	assert.ErrorIs(t, err, model.ErrNotValid)
+}
+
+func TestRepositoryExecRecords(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+	require.NoError(t, repo.CreateSandbox(ctx, sandboxFixture("id-1", "sb-1")))
+
+	t0 := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		require.NoError(t, repo.CreateExecRecord(ctx, model.ExecRecord{
+			ID:           fmt.Sprintf("rec-%d", i),
+			SandboxID:    "id-1",
+			Command:      []string{"echo", fmt.Sprintf("hello %d", i)},
+			User:         "dev",
+			Caller:       "cli",
+			ExitCode:     i,
+			StartedAt:    t0.Add(time.Duration(i) * time.Millisecond),
+			Duration:     1500 * time.Millisecond,
+			StdoutBytes:  8,
+			StdoutSHA256: "abc",
+		}))
+	}
+
+	err := repo.CreateExecRecord(ctx, model.ExecRecord{ID: "rec-x", SandboxID: "id-x", Command: []string{"ls"}, StartedAt: t0})
+	assert.ErrorIs(t, err, model.ErrNotFound)
+
+	all, err := repo.ListExecRecords(ctx, "id-1", model.ExecRecordFilter{})
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	assert.Equal(t, model.ExecRecord{
+		ID:           "rec-0",
+		SandboxID:    "id-1",
+		Command:      []string{"echo", "hello 0"},
+		User:         "dev",
+		Caller:       "cli",
+		StartedAt:    t0,
+		Duration:     1500 * time.Millisecond,
+		StdoutBytes:  8,
+		StdoutSHA256: "abc",
+	}, all[0])
+
+	latest, err := repo.ListExecRecords(ctx, "id-1", model.ExecRecordFilter{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, latest, 2)
+	assert.Equal(t, "rec-1", latest[0].ID)
+	assert.Equal(t, "rec-2", latest[1].ID)
+
+	since, err := repo.ListExecRecords(ctx, "id-1", model.ExecRecordFilter{Since: t0.Add(2 * time.Millisecond)})
+	require.NoError(t, err)
+	require.Len(t, since, 1)
+	assert.Equal(t, "rec-2", since[0].ID)
+
+	// Records are removed with the sandbox.
+	require.NoError(t, repo.DeleteSandbox(ctx, "id-1"))
+	all, err = repo.ListExecRecords(ctx, "id-1", model.ExecRecordFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, all)
+}
+
+func TestRepositoryUsageRecords(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	t0 := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	run := model.UsageRecord{
+		SandboxID:   "id-1",
+		SandboxName: "sb-1",
+		Namespace:   model.DefaultNamespace,
+		Annotations: map[string]string{"team": "infra"},
+		Kind:        model.UsageKindRun,
+		Resources:   model.Resources{VCPUs: 1.5, MemoryMB: 1024, DiskGB: 10},
+		StartedAt:   t0.Add(time.Hour),
+		EndedAt:     t0.Add(2 * time.Hour),
+	}
+	disk := run
+	disk.Kind = model.UsageKindDisk
+	disk.StartedAt = t0
+	disk.EndedAt = t0.Add(3 * time.Hour)
+
+	// The records don't need the sandbox, they are kept after removing it.
+	require.NoError(t, repo.CreateUsageRecord(ctx, run))
+	require.NoError(t, repo.CreateUsageRecord(ctx, disk))
+
+	all, err := repo.ListUsageRecords(ctx, model.UsageRecordFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []model.UsageRecord{disk, run}, all)
+
+	got, err := repo.ListUsageRecords(ctx, model.UsageRecordFilter{Since: t0.Add(2 * time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, []model.UsageRecord{disk}, got)
+
+	got, err = repo.ListUsageRecords(ctx, model.UsageRecordFilter{Until: t0.Add(time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, []model.UsageRecord{disk}, got)
+
+	got, err = repo.ListUsageRecords(ctx, model.UsageRecordFilter{Since: t0.Add(4 * time.Hour)})
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestRepositoryEgressPolicies(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	t0 := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	policy := model.NamedEgressPolicy{
+		Name: "github",
+		Policy: model.EgressPolicy{
+			Default:             model.EgressActionDeny,
+			Rules:               []model.EgressRule{{Domain: "*.github.com", Action: model.EgressActionAllow, ICMP: true}},
+			FailClosed:          true,
+			AllowSystemServices: []model.EgressSystemService{model.EgressSystemServiceNTP},
+		},
+		CreatedAt: t0,
+		UpdatedAt: t0,
+	}
+	require.NoError(repo.CreateEgressPolicy(ctx, policy))
+	require.NoError(repo.CreateEgressPolicy(ctx, model.NamedEgressPolicy{Name: "allow-all", Policy: model.EgressPolicy{Default: model.EgressActionAllow}, CreatedAt: t0, UpdatedAt: t0}))
+	assert.ErrorIs(repo.CreateEgressPolicy(ctx, policy), model.ErrAlreadyExists)
+
+	got, err := repo.GetEgressPolicy(ctx, "github")
+	require.NoError(err)
+	assert.Equal(policy, *got)
+
+	list, err := repo.ListEgressPolicies(ctx)
+	require.NoError(err)
+	require.Len(list, 2)
+	assert.Equal("allow-all", list[0].Name)
+	assert.Equal("github", list[1].Name)
+
+	// Updating replaces the policy and keeps the creation time.
+	updated := policy
+	updated.Policy = model.EgressPolicy{Default: model.EgressActionAllow}
+	updated.CreatedAt = t0.Add(time.Hour)
+	updated.UpdatedAt = t0.Add(time.Hour)
+	require.NoError(repo.UpdateEgressPolicy(ctx, updated))
+	got, err = repo.GetEgressPolicy(ctx, "github")
+	require.NoError(err)
+	assert.Equal(model.EgressPolicy{Default: model.EgressActionAllow}, got.Policy)
+	assert.Equal(t0, got.CreatedAt)
+	assert.Equal(t0.Add(time.Hour), got.UpdatedAt)
+	assert.ErrorIs(repo.UpdateEgressPolicy(ctx, model.NamedEgressPolicy{Name: "missing"}), model.ErrNotFound)
+
+	require.NoError(repo.DeleteEgressPolicy(ctx, "github"))
+	_, err = repo.GetEgressPolicy(ctx, "github")
+	assert.ErrorIs(err, model.ErrNotFound)
+	assert.ErrorIs(repo.DeleteEgressPolicy(ctx, "github"), model.ErrNotFound)
+}
+
+func TestRepositorySandboxTemplates(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	t0 := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	tpl := model.SandboxTemplate{
+		Name:        "go-dev",
+		Description: "Go development sandbox",
+		Engine:      model.TemplateEngineFirecracker,
+		Image:       "v0.1.0",
+		Profile:     "fast-boot",
+		Resources:   model.Resources{VCPUs: 2, MemoryMB: 2048, DiskGB: 10, MaxVCPUs: 4, MaxMemoryMB: 4096},
+		UserData:    "#!/bin/sh\napk add go\n",
+		Ports:       map[string]int{"web": 8080},
+		ExecProfile: &model.ExecProfile{PathPrepend: []string{"/usr/local/go/bin"}, Ulimits: map[string]string{"nofile": "65536"}},
+		Env:         map[string]string{"GOFLAGS": "-mod=mod"},
+		Egress: &model.EgressPolicy{
+			Default: model.EgressActionDeny,
+			Rules:   []model.EgressRule{{Domain: "proxy.golang.org", Action: model.EgressActionAllow}},
+		},
+		CreatedAt: t0,
+		UpdatedAt: t0,
+	}
+	require.NoError(repo.CreateSandboxTemplate(ctx, tpl))
+	require.NoError(repo.CreateSandboxTemplate(ctx, model.SandboxTemplate{Name: "fake", Engine: model.TemplateEngineFake, Resources: model.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 1}, EgressPolicyName: "github", CreatedAt: t0, UpdatedAt: t0}))
+	assert.ErrorIs(repo.CreateSandboxTemplate(ctx, tpl), model.ErrAlreadyExists)
+
+	got, err := repo.GetSandboxTemplate(ctx, "go-dev")
+	require.NoError(err)
+	assert.Equal(tpl, *got)
+
+	list, err := repo.ListSandboxTemplates(ctx)
+	require.NoError(err)
+	require.Len(list, 2)
+	assert.Equal("fake", list[0].Name)
+	assert.Equal("github", list[0].EgressPolicyName)
+	assert.Equal("go-dev", list[1].Name)
+
+	require.NoError(repo.DeleteSandboxTemplate(ctx, "go-dev"))
+	_, err = repo.GetSandboxTemplate(ctx, "go-dev")
+	assert.ErrorIs(err, model.ErrNotFound)
+	assert.ErrorIs(repo.DeleteSandboxTemplate(ctx, "go-dev"), model.ErrNotFound)
+}
+
+func TestRepositoryTasks(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	t0 := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	global := model.Task{
+		Name:       "test",
+		Command:    []string{"go", "test", "./..."},
+		WorkingDir: "/src",
+		Env:        map[string]string{"CGO_ENABLED": "0"},
+		Files:      []string{"/home/user/test.env"},
+		Timeout:    10 * time.Minute,
+		CreatedAt:  t0,
+		UpdatedAt:  t0,
+	}
+	require.NoError(repo.SaveTask(ctx, global))
+	require.NoError(repo.SaveTask(ctx, model.Task{Name: "lint", Command: []string{"golangci-lint", "run"}, CreatedAt: t0, UpdatedAt: t0}))
+
+	// The sandbox tasks are independent of the global ones.
+	require.NoError(repo.CreateSandbox(ctx, sandboxFixture("sb-1", "sb-1")))
+	sbTask := model.Task{Name: "test", SandboxID: "sb-1", Command: []string{"make", "test"}, CreatedAt: t0, UpdatedAt: t0}
+	require.NoError(repo.SaveTask(ctx, sbTask))
+	assert.ErrorIs(repo.SaveTask(ctx, model.Task{Name: "test", SandboxID: "missing", Command: []string{"ls"}}), model.ErrNotFound)
+
+	got, err := repo.GetTask(ctx, "", "test")
+	require.NoError(err)
+	assert.Equal(global, *got)
+	got, err = repo.GetTask(ctx, "sb-1", "test")
+	require.NoError(err)
+	assert.Equal(sbTask, *got)
+	_, err = repo.GetTask(ctx, "sb-1", "lint")
+	assert.ErrorIs(err, model.ErrNotFound)
+
+	list, err := repo.ListTasks(ctx, "")
+	require.NoError(err)
+	require.Len(list, 2)
+	assert.Equal("lint", list[0].Name)
+	assert.Equal("test", list[1].Name)
+
+	// Saving replaces the task and keeps the creation time.
+	updated := global
+	updated.Command = []string{"go", "test", "-race", "./..."}
+	updated.Env = nil
+	updated.CreatedAt = t0.Add(time.Hour)
+	updated.UpdatedAt = t0.Add(time.Hour)
+	require.NoError(repo.SaveTask(ctx, updated))
+	got, err = repo.GetTask(ctx, "", "test")
+	require.NoError(err)
+	assert.Equal([]string{"go", "test", "-race", "./..."}, got.Command)
+	assert.Nil(got.Env)
+	assert.Equal(t0, got.CreatedAt)
+	assert.Equal(t0.Add(time.Hour), got.UpdatedAt)
+
+	require.NoError(repo.DeleteTask(ctx, "", "lint"))
+	assert.ErrorIs(repo.DeleteTask(ctx, "", "lint"), model.ErrNotFound)
+
+	// The sandbox tasks are deleted with the sandbox.
+	require.NoError(repo.DeleteSandbox(ctx, "sb-1"))
+	list, err = repo.ListTasks(ctx, "sb-1")
+	require.NoError(err)
+	assert.Empty(list)
+}
+
+func TestRepositoryIntegrityBaselines(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	t0 := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	require.NoError(repo.CreateSandbox(ctx, sandboxFixture("sb-1", "sb-1")))
+	baseline := model.IntegrityBaseline{
+		SandboxID: "sb-1",
+		Paths:     []string{"/etc", "/usr/local/bin"},
+		Files:     map[string]string{"/etc/motd": "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+		CreatedAt: t0,
+	}
+	require.NoError(repo.SaveIntegrityBaseline(ctx, baseline))
+	assert.ErrorIs(repo.SaveIntegrityBaseline(ctx, model.IntegrityBaseline{SandboxID: "missing"}), model.ErrNotFound)
+
+	got, err := repo.GetIntegrityBaseline(ctx, "sb-1")
+	require.NoError(err)
+	assert.Equal(baseline, *got)
+
+	// Saving replaces the baseline.
+	updated := model.IntegrityBaseline{SandboxID: "sb-1", Paths: []string{"/opt"}, Files: map[string]string{}, CreatedAt: t0.Add(time.Hour)}
+	require.NoError(repo.SaveIntegrityBaseline(ctx, updated))
+	got, err = repo.GetIntegrityBaseline(ctx, "sb-1")
+	require.NoError(err)
+	assert.Equal(updated, *got)
+
+	// The baseline is deleted with the sandbox.
+	require.NoError(repo.DeleteSandbox(ctx, "sb-1"))
+	_, err = repo.GetIntegrityBaseline(ctx, "sb-1")
+	assert.ErrorIs(err, model.ErrNotFound)
+}
+
+func TestRepositoryLockSandbox(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	// Two repositories on the same database act like two processes.
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	repo1, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{DBPath: dbPath})
+	require.NoError(err)
+	t.Cleanup(func() { _ = repo1.Close() })
+	repo2, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{DBPath: dbPath})
+	require.NoError(err)
+	t.Cleanup(func() { _ = repo2.Close() })
+
+	unlock, err := repo1.LockSandbox(ctx, "sb-1")
+	require.NoError(err)
+
+	// The locked sandbox should conflict, other sandboxes should be lockable.
+	_, err = repo2.LockSandbox(ctx, "sb-1")
+	assert.ErrorIs(err, model.ErrConflict)
+	unlock2, err := repo2.LockSandbox(ctx, "sb-2")
+	require.NoError(err)
+	unlock2()
+
+	// Once released it should be lockable again.
+	unlock()
+	unlock, err = repo2.LockSandbox(ctx, "sb-1")
+	require.NoError(err)
+	unlock()
+
+	// Deleting the sandbox while its lock is held (e.g. a remove) should keep it held.
+	require.NoError(repo1.CreateSandbox(ctx, sandboxFixture("sb-1", "sb-1")))
+	unlock, err = repo1.LockSandbox(ctx, "sb-1")
+	require.NoError(err)
+	require.NoError(repo1.DeleteSandbox(ctx, "sb-1"))
+	_, err = repo2.LockSandbox(ctx, "sb-1")
+	assert.ErrorIs(err, model.ErrConflict)
+	unlock()
+}
+
+func TestRepositoryCompact(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{DBPath: dbPath, Logger: log.Noop})
+	require.NoError(err)
+	t.Cleanup(func() { _ = repo.Close() })
+
+	// Deleted rows leave free pages until the database is rebuilt.
+	for i := range 200 {
+		id := fmt.Sprintf("01H2QWERTYASDFGZXCVBNM%04d", i)
+		require.NoError(repo.CreateSandbox(ctx, sandboxFixture(id, fmt.Sprintf("sb-%d", i))))
+	}
+	for i := range 200 {
+		require.NoError(repo.DeleteSandbox(ctx, fmt.Sprintf("01H2QWERTYASDFGZXCVBNM%04d", i)))
+	}
+
+	res, err := repo.Compact(ctx)
+	require.NoError(err)
+	assert.Empty(res.IntegrityErrors)
+	assert.Less(res.SizeAfter, res.SizeBefore)
+
+	// The write-ahead log is truncated.
+	info, err := os.Stat(dbPath + "-wal")
+	require.NoError(err)
+	assert.Zero(info.Size())
+
+	require.NoError(repo.Checkpoint(ctx))
+}
+
+func TestRepositoryWatchChanges(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Two repositories on the same database act like two processes.
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	repo1, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{DBPath: dbPath})
+	require.NoError(err)
+	t.Cleanup(func() { _ = repo1.Close() })
+	repo2, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{DBPath: dbPath})
+	require.NoError(err)
+	t.Cleanup(func() { _ = repo2.Close() })
+
+	changes, err := repo1.WatchChanges(ctx)
+	require.NoError(err)
+
+	// The changes of the other repository should be notified.
+	require.NoError(repo2.CreateSandbox(ctx, sandboxFixture("01H0000000000000000000000A", "watched")))
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the change was not notified")
+	}
+
+	// The channel should be closed when the context is done.
+	cancel()
+	require.Eventually(func() bool {
+		select {
+		case _, ok := <-changes:
+			return !ok
+		default:
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,173 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// taskJSON is the stored representation of the task command and options.
+type taskJSON struct {
+	Command    []string          `json:"command"`
+	WorkingDir string            `json:"working_dir,omitempty"`
+	Env        map[string]string `json:"env,omitempty"`
+	Files      []string          `json:"files,omitempty"`
+	TimeoutNS  int64             `json:"timeout_ns,omitempty"`
+}
+
+// SaveTask stores a task, replacing the task of the same sandbox with the same name
+// (its creation time is kept).
+func (r *Repository) SaveTask(ctx context.Context, t model.Task) error {
+	data, err := json.Marshal(taskJSON{
+		Command:    t.Command,
+		WorkingDir: t.WorkingDir,
+		Env:        t.Env,
+		Files:      t.Files,
+		TimeoutNS:  int64(t.Timeout),
+	})
+	if err != nil {
+		return fmt.Errorf("could not marshal task: %w", err)
+	}
+
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE tasks SET task = ?, updated_at = ? WHERE COALESCE(sandbox_id, '') = ? AND name = ?`,
+		string(data), t.UpdatedAt.Unix(), t.SandboxID, t.Name)
+	if err != nil {
+		return fmt.Errorf("could not update task: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not get rows affected: %w", err)
+	}
+	if rows > 0 {
+		r.logger.Debugf("Updated task in repository: %s", t.Name)
+		return nil
+	}
+
+	query := `
+		INSERT INTO tasks (sandbox_id, name, task, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	_, err = r.db.ExecContext(ctx, query, nullString(t.SandboxID), t.Name, string(data), t.CreatedAt.Unix(), t.UpdatedAt.Unix())
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return fmt.Errorf("task %s already exists: %w", t.Name, model.ErrAlreadyExists)
+		}
+		if strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
+			return fmt.Errorf("sandbox %s: %w", t.SandboxID, model.ErrNotFound)
+		}
+		return fmt.Errorf("could not insert task: %w", err)
+	}
+
+	r.logger.Debugf("Created task in repository: %s", t.Name)
+	return nil
+}
+
+// GetTask retrieves a task of a sandbox, a global one with an empty sandbox ID.
+func (r *Repository) GetTask(ctx context.Context, sandboxID, name string) (*model.Task, error) {
+	query := `
+		SELECT sandbox_id, name, task, created_at, updated_at
+		FROM tasks
+		WHERE COALESCE(sandbox_id, '') = ? AND name = ?
+	`
+
+	t, err := scanTask(r.db.QueryRowContext(ctx, query, sandboxID, name))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("task %s: %w", name, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not query task: %w", err)
+	}
+
+	return &t, nil
+}
+
+// ListTasks returns the tasks of a sandbox sorted by name, the global ones with an
+// empty sandbox ID.
+func (r *Repository) ListTasks(ctx context.Context, sandboxID string) ([]model.Task, error) {
+	query := `
+		SELECT sandbox_id, name, task, created_at, updated_at
+		FROM tasks
+		WHERE COALESCE(sandbox_id, '') = ?
+		ORDER BY name ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, sandboxID)
+	if err != nil {
+		return nil, fmt.Errorf("could not query tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := []model.Task{}
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan row: %w", err)
+		}
+		tasks = append(tasks, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return tasks, nil
+}
+
+// DeleteTask deletes a task of a sandbox, a global one with an empty sandbox ID.
+func (r *Repository) DeleteTask(ctx context.Context, sandboxID, name string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM tasks WHERE COALESCE(sandbox_id, '') = ? AND name = ?`, sandboxID, name)
+	if err != nil {
+		return fmt.Errorf("could not delete task: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("task %s: %w", name, model.ErrNotFound)
+	}
+
+	r.logger.Debugf("Deleted task from repository: %s", name)
+	return nil
+}
+
+func scanTask(s scanner) (model.Task, error) {
+	var t model.Task
+	var sandboxID sql.NullString
+	var data string
+	var createdAt, updatedAt int64
+	if err := s.Scan(&sandboxID, &t.Name, &data, &createdAt, &updatedAt); err != nil {
+		return model.Task{}, err
+	}
+
+	var j taskJSON
+	if err := json.Unmarshal([]byte(data), &j); err != nil {
+		return model.Task{}, fmt.Errorf("could not unmarshal task: %w", err)
+	}
+	t.SandboxID = sandboxID.String
+	t.Command = j.Command
+	t.WorkingDir = j.WorkingDir
+	t.Env = j.Env
+	t.Files = j.Files
+	t.Timeout = time.Duration(j.TimeoutNS)
+	t.CreatedAt = timeFromUnix(createdAt)
+	t.UpdatedAt = timeFromUnix(updatedAt)
+
+	return t, nil
+}
+
+// nullString returns NULL for the empty strings.
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,195 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// templateSpecJSON is the stored representation of the sandbox template spec.
+type templateSpecJSON struct {
+	Engine           string            `json:"engine"`
+	Image            string            `json:"image,omitempty"`
+	Profile          string            `json:"profile,omitempty"`
+	VCPUs            float64           `json:"vcpus"`
+	MemoryMB         int               `json:"memory_mb"`
+	DiskGB           int               `json:"disk_gb"`
+	MaxVCPUs         float64           `json:"max_vcpus,omitempty"`
+	MaxMemoryMB      int               `json:"max_memory_mb,omitempty"`
+	UserData         string            `json:"user_data,omitempty"`
+	Ports            map[string]int    `json:"ports,omitempty"`
+	ExecProfile      *execProfileJSON  `json:"exec_profile,omitempty"`
+	Env              map[string]string `json:"env,omitempty"`
+	Egress           *egressJSON       `json:"egress,omitempty"`
+	EgressPolicyName string            `json:"egress_policy_name,omitempty"`
+}
+
+// CreateSandboxTemplate stores a sandbox template.
+func (r *Repository) CreateSandboxTemplate(ctx context.Context, t model.SandboxTemplate) error {
+	j := templateSpecJSON{
+		Engine:           t.Engine,
+		Image:            t.Image,
+		Profile:          t.Profile,
+		VCPUs:            t.Resources.VCPUs,
+		MemoryMB:         t.Resources.MemoryMB,
+		DiskGB:           t.Resources.DiskGB,
+		MaxVCPUs:         t.Resources.MaxVCPUs,
+		MaxMemoryMB:      t.Resources.MaxMemoryMB,
+		UserData:         t.UserData,
+		Ports:            t.Ports,
+		Env:              t.Env,
+		EgressPolicyName: t.EgressPolicyName,
+	}
+	if p := t.ExecProfile; p != nil {
+		j.ExecProfile = &execProfileJSON{
+			PathPrepend: p.PathPrepend,
+			Locale:      p.Locale,
+			Umask:       p.Umask,
+			Ulimits:     p.Ulimits,
+			Shell:       p.Shell,
+		}
+	}
+	if t.Egress != nil {
+		e := toEgressJSON(*t.Egress)
+		j.Egress = &e
+	}
+
+	spec, err := json.Marshal(j)
+	if err != nil {
+		return fmt.Errorf("could not marshal template spec: %w", err)
+	}
+
+	query := `
+		INSERT INTO sandbox_templates (name, description, spec, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	_, err = r.db.ExecContext(ctx, query, t.Name, t.Description, string(spec), t.CreatedAt.Unix(), t.UpdatedAt.Unix())
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: sandbox_templates.") {
+			return fmt.Errorf("template %s already exists: %w", t.Name, model.ErrAlreadyExists)
+		}
+		return fmt.Errorf("could not insert template: %w", err)
+	}
+
+	r.logger.Debugf("Created template in repository: %s", t.Name)
+	return nil
+}
+
+// GetSandboxTemplate retrieves a sandbox template.
+func (r *Repository) GetSandboxTemplate(ctx context.Context, name string) (*model.SandboxTemplate, error) {
+	query := `
+		SELECT name, description, spec, created_at, updated_at
+		FROM sandbox_templates
+		WHERE name = ?
+	`
+
+	t, err := scanSandboxTemplate(r.db.QueryRowContext(ctx, query, name))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("template %s: %w", name, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not query template: %w", err)
+	}
+
+	return &t, nil
+}
+
+// ListSandboxTemplates returns all the sandbox templates sorted by name.
+func (r *Repository) ListSandboxTemplates(ctx context.Context) ([]model.SandboxTemplate, error) {
+	query := `
+		SELECT name, description, spec, created_at, updated_at
+		FROM sandbox_templates
+		ORDER BY name ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("could not query templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []model.SandboxTemplate{}
+	for rows.Next() {
+		t, err := scanSandboxTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan row: %w", err)
+		}
+		templates = append(templates, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return templates, nil
+}
+
+// DeleteSandboxTemplate deletes a sandbox template.
+func (r *Repository) DeleteSandboxTemplate(ctx context.Context, name string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM sandbox_templates WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("could not delete template: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("template %s: %w", name, model.ErrNotFound)
+	}
+
+	r.logger.Debugf("Deleted template from repository: %s", name)
+	return nil
+}
+
+func scanSandboxTemplate(s scanner) (model.SandboxTemplate, error) {
+	var t model.SandboxTemplate
+	var spec string
+	var createdAt, updatedAt int64
+	if err := s.Scan(&t.Name, &t.Description, &spec, &createdAt, &updatedAt); err != nil {
+		return model.SandboxTemplate{}, err
+	}
+
+	var j templateSpecJSON
+	if err := json.Unmarshal([]byte(spec), &j); err != nil {
+		return model.SandboxTemplate{}, fmt.Errorf("could not unmarshal template spec: %w", err)
+	}
+	t.Engine = j.Engine
+	t.Image = j.Image
+	t.Profile = j.Profile
+	t.Resources = model.Resources{
+		VCPUs:       j.VCPUs,
+		MemoryMB:    j.MemoryMB,
+		DiskGB:      j.DiskGB,
+		MaxVCPUs:    j.MaxVCPUs,
+		MaxMemoryMB: j.MaxMemoryMB,
+	}
+	t.UserData = j.UserData
+	t.Ports = j.Ports
+	t.Env = j.Env
+	t.EgressPolicyName = j.EgressPolicyName
+	if p := j.ExecProfile; p != nil {
+		t.ExecProfile = &model.ExecProfile{
+			PathPrepend: p.PathPrepend,
+			Locale:      p.Locale,
+			Umask:       p.Umask,
+			Ulimits:     p.Ulimits,
+			Shell:       p.Shell,
+		}
+	}
+	if j.Egress != nil {
+		e := fromEgressJSON(*j.Egress)
+		t.Egress = &e
+	}
+	t.CreatedAt = timeFromUnix(createdAt)
+	t.UpdatedAt = timeFromUnix(updatedAt)
+
+	return t, nil
+}
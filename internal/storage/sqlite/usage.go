@@ -0,0 +1,122 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+// CreateUsageRecord stores a usage period of a sandbox, in the repository namespace
+// when the record doesn't have one.
+func (r *Repository) CreateUsageRecord(ctx context.Context, rec model.UsageRecord) error {
+	namespace := rec.Namespace
+	if namespace == "" {
+		namespace = r.namespace
+	}
+	if err := model.ValidateNamespaceInScope(r.namespace, namespace); err != nil {
+		return err
+	}
+
+	annotations, err := marshalAnnotations(rec.Annotations)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO usage_records (
+			sandbox_id, sandbox_name, namespace, annotations, kind,
+			vcpus, memory_mb, disk_gb,
+			started_at_ns, ended_at_ns
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err = r.db.ExecContext(
+		ctx,
+		query,
+		rec.SandboxID,
+		rec.SandboxName,
+		namespace,
+		annotations,
+		string(rec.Kind),
+		rec.Resources.VCPUs,
+		rec.Resources.MemoryMB,
+		rec.Resources.DiskGB,
+		rec.StartedAt.UnixNano(),
+		rec.EndedAt.UnixNano(),
+	)
+	if err != nil {
+		return fmt.Errorf("could not insert usage record: %w", err)
+	}
+
+	return nil
+}
+
+// ListUsageRecords returns the usage records of the repository namespace overlapping
+// the filter time range, oldest first.
+func (r *Repository) ListUsageRecords(ctx context.Context, filter model.UsageRecordFilter) ([]model.UsageRecord, error) {
+	var since int64
+	if !filter.Since.IsZero() {
+		since = filter.Since.UnixNano()
+	}
+	until := int64(math.MaxInt64)
+	if !filter.Until.IsZero() {
+		until = filter.Until.UnixNano()
+	}
+
+	scope, scopeArgs := r.scope()
+	query := `
+		SELECT
+			sandbox_id, sandbox_name, namespace, annotations, kind,
+			vcpus, memory_mb, disk_gb,
+			started_at_ns, ended_at_ns
+		FROM usage_records
+		WHERE ended_at_ns > ? AND started_at_ns < ? AND ` + scope + `
+		ORDER BY started_at_ns ASC, id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, append([]any{since, until}, scopeArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("could not query usage records: %w", err)
+	}
+	defer rows.Close()
+
+	records := []model.UsageRecord{}
+	for rows.Next() {
+		var rec model.UsageRecord
+		var annotations, kind string
+		var startedAt, endedAt int64
+		err := rows.Scan(
+			&rec.SandboxID,
+			&rec.SandboxName,
+			&rec.Namespace,
+			&annotations,
+			&kind,
+			&rec.Resources.VCPUs,
+			&rec.Resources.MemoryMB,
+			&rec.Resources.DiskGB,
+			&startedAt,
+			&endedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan row: %w", err)
+		}
+		rec.Annotations, err = unmarshalAnnotations(annotations)
+		if err != nil {
+			return nil, err
+		}
+		rec.Kind = model.UsageKind(kind)
+		rec.StartedAt = time.Unix(0, startedAt).UTC()
+		rec.EndedAt = time.Unix(0, endedAt).UTC()
+		records = append(records, rec)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return records, nil
+}
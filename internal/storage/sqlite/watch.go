@@ -0,0 +1,70 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// changeSettleTime is the time waited after a database file write before notifying
+// the change, the write-ahead log is written before the commit is visible.
+const changeSettleTime = 50 * time.Millisecond
+
+// WatchChanges notifies the database changes until the context is done. The changes
+// are the writes to the database and its write-ahead log files, so the commits of
+// the other processes using the same database are notified too.
+func (r *Repository) WatchChanges(ctx context.Context) (<-chan struct{}, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("could not create database watcher: %w", err)
+	}
+	// The directory is watched because the write-ahead log is recreated.
+	dir := filepath.Dir(r.dbPath)
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("could not watch database directory: %w", err)
+	}
+
+	dbFile := filepath.Join(dir, filepath.Base(r.dbPath))
+	files := map[string]bool{dbFile: true, dbFile + "-wal": true}
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer close(changes)
+		defer watcher.Close()
+
+		settle := time.NewTimer(changeSettleTime)
+		settle.Stop()
+		defer settle.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !files[ev.Name] || ev.Op == fsnotify.Chmod {
+					continue
+				}
+				settle.Reset(changeSettleTime)
+			case <-settle.C:
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				r.logger.Warningf("Database watch error: %v", err)
+			}
+		}
+	}()
+
+	return changes, nil
+}
@@ -2,16 +2,109 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"github.com/slok/sbx/internal/model"
 )
 
-// Repository is the interface for sandbox persistence.
+// Repository is the interface for sandbox persistence. The repositories are scoped
+// to a namespace: the sandbox and usage methods only see the sandboxes of their
+// namespace, except ListAllSandboxes.
 type Repository interface {
 	CreateSandbox(ctx context.Context, s model.Sandbox) error
 	GetSandbox(ctx context.Context, id string) (*model.Sandbox, error)
 	GetSandboxByName(ctx context.Context, name string) (*model.Sandbox, error)
 	ListSandboxes(ctx context.Context) ([]model.Sandbox, error)
+	// ListAllSandboxes returns the sandboxes of all the namespaces, for the host
+	// resources shared by them (IP addresses, capacity, images).
+	ListAllSandboxes(ctx context.Context) ([]model.Sandbox, error)
+	// UpdateSandbox updates an existing sandbox, except its annotations, idle policy,
+	// activity and quarantine.
 	UpdateSandbox(ctx context.Context, s model.Sandbox) error
+	// UpdateSandboxAnnotations replaces the annotations of a sandbox.
+	UpdateSandboxAnnotations(ctx context.Context, id string, annotations map[string]string) error
+	// UpdateSandboxIdlePolicy replaces the idle policy of a sandbox, nil removes it.
+	UpdateSandboxIdlePolicy(ctx context.Context, id string, policy *model.IdlePolicy) error
+	// UpdateSandboxLastActivity sets the last activity time of a sandbox, unless the
+	// stored one is later.
+	UpdateSandboxLastActivity(ctx context.Context, id string, at time.Time) error
+	// UpdateSandboxNetworkBytes sets the network bytes of a sandbox seen on the last
+	// idle check.
+	UpdateSandboxNetworkBytes(ctx context.Context, id string, bytes uint64) error
+	// UpdateSandboxQuarantine sets the quarantine time of a sandbox, nil lifts the
+	// quarantine.
+	UpdateSandboxQuarantine(ctx context.Context, id string, at *time.Time) error
+	// UpdateSandboxPersistent sets if a sandbox is started again by the recovery.
+	UpdateSandboxPersistent(ctx context.Context, id string, persistent bool) error
 	DeleteSandbox(ctx context.Context, id string) error
+	// CreateExecRecord stores the exec audit record of a sandbox.
+	CreateExecRecord(ctx context.Context, r model.ExecRecord) error
+	// ListExecRecords returns the exec audit records of a sandbox, oldest first.
+	ListExecRecords(ctx context.Context, sandboxID string, filter model.ExecRecordFilter) ([]model.ExecRecord, error)
+	// CreateUsageRecord stores a usage period of a sandbox.
+	CreateUsageRecord(ctx context.Context, r model.UsageRecord) error
+	// ListUsageRecords returns the usage records overlapping the filter time range,
+	// oldest first, including the ones of removed sandboxes.
+	ListUsageRecords(ctx context.Context, filter model.UsageRecordFilter) ([]model.UsageRecord, error)
+	// CreateEgressPolicy stores a named egress policy.
+	CreateEgressPolicy(ctx context.Context, p model.NamedEgressPolicy) error
+	// GetEgressPolicy returns a named egress policy.
+	GetEgressPolicy(ctx context.Context, name string) (*model.NamedEgressPolicy, error)
+	// ListEgressPolicies returns all the named egress policies sorted by name.
+	ListEgressPolicies(ctx context.Context) ([]model.NamedEgressPolicy, error)
+	// UpdateEgressPolicy replaces the policy of a named egress policy.
+	UpdateEgressPolicy(ctx context.Context, p model.NamedEgressPolicy) error
+	// DeleteEgressPolicy deletes a named egress policy.
+	DeleteEgressPolicy(ctx context.Context, name string) error
+	// CreateSandboxTemplate stores a sandbox template.
+	CreateSandboxTemplate(ctx context.Context, t model.SandboxTemplate) error
+	// GetSandboxTemplate returns a sandbox template.
+	GetSandboxTemplate(ctx context.Context, name string) (*model.SandboxTemplate, error)
+	// ListSandboxTemplates returns all the sandbox templates sorted by name.
+	ListSandboxTemplates(ctx context.Context) ([]model.SandboxTemplate, error)
+	// DeleteSandboxTemplate deletes a sandbox template.
+	DeleteSandboxTemplate(ctx context.Context, name string) error
+	// SaveTask stores a task, replacing the task of the same sandbox with the same
+	// name. The tasks of a sandbox are deleted with it.
+	SaveTask(ctx context.Context, t model.Task) error
+	// GetTask returns a task of a sandbox, a global one with an empty sandbox ID.
+	GetTask(ctx context.Context, sandboxID, name string) (*model.Task, error)
+	// ListTasks returns the tasks of a sandbox sorted by name, the global ones with
+	// an empty sandbox ID.
+	ListTasks(ctx context.Context, sandboxID string) ([]model.Task, error)
+	// DeleteTask deletes a task of a sandbox, a global one with an empty sandbox ID.
+	DeleteTask(ctx context.Context, sandboxID, name string) error
+	// SaveIntegrityBaseline stores the integrity baseline of a sandbox, replacing the
+	// previous one. The baseline of a sandbox is deleted with it.
+	SaveIntegrityBaseline(ctx context.Context, b model.IntegrityBaseline) error
+	// GetIntegrityBaseline returns the integrity baseline of a sandbox.
+	GetIntegrityBaseline(ctx context.Context, sandboxID string) (*model.IntegrityBaseline, error)
+}
+
+// SandboxLocker serializes the operations that change a sandbox state (start, stop,
+// remove...), including the ones from other processes.
+type SandboxLocker interface {
+	// LockSandbox takes the sandbox operation lock without waiting, it returns
+	// model.ErrConflict when another operation holds it. The returned func releases it.
+	LockSandbox(ctx context.Context, id string) (unlock func(), err error)
+}
+
+// Maintainer keeps the repository storage healthy on long-running hosts, where the
+// sandbox and exec history grows.
+type Maintainer interface {
+	// Checkpoint moves the committed changes of the write-ahead log into the
+	// database and truncates the log.
+	Checkpoint(ctx context.Context) error
+	// Compact checkpoints and checks the integrity of the database, and rebuilds it
+	// reclaiming the free pages when it's healthy.
+	Compact(ctx context.Context) (*model.DBCompactResult, error)
+}
+
+// ChangeWatcher notifies the changes of the stored sandboxes, including the ones from
+// other processes, so the views can be refreshed without polling.
+type ChangeWatcher interface {
+	// WatchChanges returns a channel that receives a value after the stored data
+	// changes, the changes are coalesced while the value is not received. The channel
+	// is closed when the context is done.
+	WatchChanges(ctx context.Context) (<-chan struct{}, error)
 }
@@ -6,6 +6,7 @@ package storagemock
 
 import (
 	"context"
+	"time"
 
 	"github.com/slok/sbx/internal/model"
 	mock "github.com/stretchr/testify/mock"
@@ -38,6 +39,120 @@ func (_m *MockRepository) EXPECT() *MockRepository_Expecter {
 	return &MockRepository_Expecter{mock: &_m.Mock}
 }
 
+// CreateEgressPolicy provides a mock function for the type MockRepository
+func (_mock *MockRepository) CreateEgressPolicy(ctx context.Context, p model.NamedEgressPolicy) error {
+	ret := _mock.Called(ctx, p)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateEgressPolicy")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, model.NamedEgressPolicy) error); ok {
+		r0 = returnFunc(ctx, p)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockRepository_CreateEgressPolicy_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateEgressPolicy'
+type MockRepository_CreateEgressPolicy_Call struct {
+	*mock.Call
+}
+
+// CreateEgressPolicy is a helper method to define mock.On call
+//   - ctx context.Context
+//   - p model.NamedEgressPolicy
+func (_e *MockRepository_Expecter) CreateEgressPolicy(ctx interface{}, p interface{}) *MockRepository_CreateEgressPolicy_Call {
+	return &MockRepository_CreateEgressPolicy_Call{Call: _e.mock.On("CreateEgressPolicy", ctx, p)}
+}
+
+func (_c *MockRepository_CreateEgressPolicy_Call) Run(run func(ctx context.Context, p model.NamedEgressPolicy)) *MockRepository_CreateEgressPolicy_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 model.NamedEgressPolicy
+		if args[1] != nil {
+			arg1 = args[1].(model.NamedEgressPolicy)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRepository_CreateEgressPolicy_Call) Return(err error) *MockRepository_CreateEgressPolicy_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockRepository_CreateEgressPolicy_Call) RunAndReturn(run func(ctx context.Context, p model.NamedEgressPolicy) error) *MockRepository_CreateEgressPolicy_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CreateExecRecord provides a mock function for the type MockRepository
+func (_mock *MockRepository) CreateExecRecord(ctx context.Context, r model.ExecRecord) error {
+	ret := _mock.Called(ctx, r)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateExecRecord")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, model.ExecRecord) error); ok {
+		r0 = returnFunc(ctx, r)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockRepository_CreateExecRecord_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateExecRecord'
+type MockRepository_CreateExecRecord_Call struct {
+	*mock.Call
+}
+
+// CreateExecRecord is a helper method to define mock.On call
+//   - ctx context.Context
+//   - r model.ExecRecord
+func (_e *MockRepository_Expecter) CreateExecRecord(ctx interface{}, r interface{}) *MockRepository_CreateExecRecord_Call {
+	return &MockRepository_CreateExecRecord_Call{Call: _e.mock.On("CreateExecRecord", ctx, r)}
+}
+
+func (_c *MockRepository_CreateExecRecord_Call) Run(run func(ctx context.Context, r model.ExecRecord)) *MockRepository_CreateExecRecord_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 model.ExecRecord
+		if args[1] != nil {
+			arg1 = args[1].(model.ExecRecord)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRepository_CreateExecRecord_Call) Return(err error) *MockRepository_CreateExecRecord_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockRepository_CreateExecRecord_Call) RunAndReturn(run func(ctx context.Context, r model.ExecRecord) error) *MockRepository_CreateExecRecord_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // CreateSandbox provides a mock function for the type MockRepository
 func (_mock *MockRepository) CreateSandbox(ctx context.Context, s model.Sandbox) error {
 	ret := _mock.Called(ctx, s)
@@ -95,44 +210,44 @@ func (_c *MockRepository_CreateSandbox_Call) RunAndReturn(run func(ctx context.C
 	return _c
 }
 
-// DeleteSandbox provides a mock function for the type MockRepository
-func (_mock *MockRepository) DeleteSandbox(ctx context.Context, id string) error {
-	ret := _mock.Called(ctx, id)
+// CreateSandboxTemplate provides a mock function for the type MockRepository
+func (_mock *MockRepository) CreateSandboxTemplate(ctx context.Context, t model.SandboxTemplate) error {
+	ret := _mock.Called(ctx, t)
 
 	if len(ret) == 0 {
-		panic("no return value specified for DeleteSandbox")
+		panic("no return value specified for CreateSandboxTemplate")
 	}
 
 	var r0 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = returnFunc(ctx, id)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, model.SandboxTemplate) error); ok {
+		r0 = returnFunc(ctx, t)
 	} else {
 		r0 = ret.Error(0)
 	}
 	return r0
 }
 
-// MockRepository_DeleteSandbox_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteSandbox'
-type MockRepository_DeleteSandbox_Call struct {
+// MockRepository_CreateSandboxTemplate_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateSandboxTemplate'
+type MockRepository_CreateSandboxTemplate_Call struct {
 	*mock.Call
 }
 
-// DeleteSandbox is a helper method to define mock.On call
+// CreateSandboxTemplate is a helper method to define mock.On call
 //   - ctx context.Context
-//   - id string
-func (_e *MockRepository_Expecter) DeleteSandbox(ctx interface{}, id interface{}) *MockRepository_DeleteSandbox_Call {
-	return &MockRepository_DeleteSandbox_Call{Call: _e.mock.On("DeleteSandbox", ctx, id)}
+//   - t model.SandboxTemplate
+func (_e *MockRepository_Expecter) CreateSandboxTemplate(ctx interface{}, t interface{}) *MockRepository_CreateSandboxTemplate_Call {
+	return &MockRepository_CreateSandboxTemplate_Call{Call: _e.mock.On("CreateSandboxTemplate", ctx, t)}
 }
 
-func (_c *MockRepository_DeleteSandbox_Call) Run(run func(ctx context.Context, id string)) *MockRepository_DeleteSandbox_Call {
+func (_c *MockRepository_CreateSandboxTemplate_Call) Run(run func(ctx context.Context, t model.SandboxTemplate)) *MockRepository_CreateSandboxTemplate_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
 			arg0 = args[0].(context.Context)
 		}
-		var arg1 string
+		var arg1 model.SandboxTemplate
 		if args[1] != nil {
-			arg1 = args[1].(string)
+			arg1 = args[1].(model.SandboxTemplate)
 		}
 		run(
 			arg0,
@@ -142,65 +257,54 @@ func (_c *MockRepository_DeleteSandbox_Call) Run(run func(ctx context.Context, i
 	return _c
 }
 
-func (_c *MockRepository_DeleteSandbox_Call) Return(err error) *MockRepository_DeleteSandbox_Call {
+func (_c *MockRepository_CreateSandboxTemplate_Call) Return(err error) *MockRepository_CreateSandboxTemplate_Call {
 	_c.Call.Return(err)
 	return _c
 }
 
-func (_c *MockRepository_DeleteSandbox_Call) RunAndReturn(run func(ctx context.Context, id string) error) *MockRepository_DeleteSandbox_Call {
+func (_c *MockRepository_CreateSandboxTemplate_Call) RunAndReturn(run func(ctx context.Context, t model.SandboxTemplate) error) *MockRepository_CreateSandboxTemplate_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// GetSandbox provides a mock function for the type MockRepository
-func (_mock *MockRepository) GetSandbox(ctx context.Context, id string) (*model.Sandbox, error) {
-	ret := _mock.Called(ctx, id)
+// CreateUsageRecord provides a mock function for the type MockRepository
+func (_mock *MockRepository) CreateUsageRecord(ctx context.Context, r model.UsageRecord) error {
+	ret := _mock.Called(ctx, r)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetSandbox")
+		panic("no return value specified for CreateUsageRecord")
 	}
 
-	var r0 *model.Sandbox
-	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (*model.Sandbox, error)); ok {
-		return returnFunc(ctx, id)
-	}
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string) *model.Sandbox); ok {
-		r0 = returnFunc(ctx, id)
-	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*model.Sandbox)
-		}
-	}
-	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = returnFunc(ctx, id)
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, model.UsageRecord) error); ok {
+		r0 = returnFunc(ctx, r)
 	} else {
-		r1 = ret.Error(1)
+		r0 = ret.Error(0)
 	}
-	return r0, r1
+	return r0
 }
 
-// MockRepository_GetSandbox_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetSandbox'
-type MockRepository_GetSandbox_Call struct {
+// MockRepository_CreateUsageRecord_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateUsageRecord'
+type MockRepository_CreateUsageRecord_Call struct {
 	*mock.Call
 }
 
-// GetSandbox is a helper method to define mock.On call
+// CreateUsageRecord is a helper method to define mock.On call
 //   - ctx context.Context
-//   - id string
-func (_e *MockRepository_Expecter) GetSandbox(ctx interface{}, id interface{}) *MockRepository_GetSandbox_Call {
-	return &MockRepository_GetSandbox_Call{Call: _e.mock.On("GetSandbox", ctx, id)}
+//   - r model.UsageRecord
+func (_e *MockRepository_Expecter) CreateUsageRecord(ctx interface{}, r interface{}) *MockRepository_CreateUsageRecord_Call {
+	return &MockRepository_CreateUsageRecord_Call{Call: _e.mock.On("CreateUsageRecord", ctx, r)}
 }
 
-func (_c *MockRepository_GetSandbox_Call) Run(run func(ctx context.Context, id string)) *MockRepository_GetSandbox_Call {
+func (_c *MockRepository_CreateUsageRecord_Call) Run(run func(ctx context.Context, r model.UsageRecord)) *MockRepository_CreateUsageRecord_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
 			arg0 = args[0].(context.Context)
 		}
-		var arg1 string
+		var arg1 model.UsageRecord
 		if args[1] != nil {
-			arg1 = args[1].(string)
+			arg1 = args[1].(model.UsageRecord)
 		}
 		run(
 			arg0,
@@ -210,57 +314,46 @@ func (_c *MockRepository_GetSandbox_Call) Run(run func(ctx context.Context, id s
 	return _c
 }
 
-func (_c *MockRepository_GetSandbox_Call) Return(sandbox *model.Sandbox, err error) *MockRepository_GetSandbox_Call {
-	_c.Call.Return(sandbox, err)
+func (_c *MockRepository_CreateUsageRecord_Call) Return(err error) *MockRepository_CreateUsageRecord_Call {
+	_c.Call.Return(err)
 	return _c
 }
 
-func (_c *MockRepository_GetSandbox_Call) RunAndReturn(run func(ctx context.Context, id string) (*model.Sandbox, error)) *MockRepository_GetSandbox_Call {
+func (_c *MockRepository_CreateUsageRecord_Call) RunAndReturn(run func(ctx context.Context, r model.UsageRecord) error) *MockRepository_CreateUsageRecord_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// GetSandboxByName provides a mock function for the type MockRepository
-func (_mock *MockRepository) GetSandboxByName(ctx context.Context, name string) (*model.Sandbox, error) {
+// DeleteEgressPolicy provides a mock function for the type MockRepository
+func (_mock *MockRepository) DeleteEgressPolicy(ctx context.Context, name string) error {
 	ret := _mock.Called(ctx, name)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetSandboxByName")
+		panic("no return value specified for DeleteEgressPolicy")
 	}
 
-	var r0 *model.Sandbox
-	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (*model.Sandbox, error)); ok {
-		return returnFunc(ctx, name)
-	}
-	if returnFunc, ok := ret.Get(0).(func(context.Context, string) *model.Sandbox); ok {
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) error); ok {
 		r0 = returnFunc(ctx, name)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*model.Sandbox)
-		}
-	}
-	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = returnFunc(ctx, name)
-	} else {
-		r1 = ret.Error(1)
+		r0 = ret.Error(0)
 	}
-	return r0, r1
+	return r0
 }
 
-// MockRepository_GetSandboxByName_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetSandboxByName'
-type MockRepository_GetSandboxByName_Call struct {
+// MockRepository_DeleteEgressPolicy_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteEgressPolicy'
+type MockRepository_DeleteEgressPolicy_Call struct {
 	*mock.Call
 }
 
-// GetSandboxByName is a helper method to define mock.On call
+// DeleteEgressPolicy is a helper method to define mock.On call
 //   - ctx context.Context
 //   - name string
-func (_e *MockRepository_Expecter) GetSandboxByName(ctx interface{}, name interface{}) *MockRepository_GetSandboxByName_Call {
-	return &MockRepository_GetSandboxByName_Call{Call: _e.mock.On("GetSandboxByName", ctx, name)}
+func (_e *MockRepository_Expecter) DeleteEgressPolicy(ctx interface{}, name interface{}) *MockRepository_DeleteEgressPolicy_Call {
+	return &MockRepository_DeleteEgressPolicy_Call{Call: _e.mock.On("DeleteEgressPolicy", ctx, name)}
 }
 
-func (_c *MockRepository_GetSandboxByName_Call) Run(run func(ctx context.Context, name string)) *MockRepository_GetSandboxByName_Call {
+func (_c *MockRepository_DeleteEgressPolicy_Call) Run(run func(ctx context.Context, name string)) *MockRepository_DeleteEgressPolicy_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
@@ -278,116 +371,111 @@ func (_c *MockRepository_GetSandboxByName_Call) Run(run func(ctx context.Context
 	return _c
 }
 
-func (_c *MockRepository_GetSandboxByName_Call) Return(sandbox *model.Sandbox, err error) *MockRepository_GetSandboxByName_Call {
-	_c.Call.Return(sandbox, err)
+func (_c *MockRepository_DeleteEgressPolicy_Call) Return(err error) *MockRepository_DeleteEgressPolicy_Call {
+	_c.Call.Return(err)
 	return _c
 }
 
-func (_c *MockRepository_GetSandboxByName_Call) RunAndReturn(run func(ctx context.Context, name string) (*model.Sandbox, error)) *MockRepository_GetSandboxByName_Call {
+func (_c *MockRepository_DeleteEgressPolicy_Call) RunAndReturn(run func(ctx context.Context, name string) error) *MockRepository_DeleteEgressPolicy_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// ListSandboxes provides a mock function for the type MockRepository
-func (_mock *MockRepository) ListSandboxes(ctx context.Context) ([]model.Sandbox, error) {
-	ret := _mock.Called(ctx)
+// DeleteSandbox provides a mock function for the type MockRepository
+func (_mock *MockRepository) DeleteSandbox(ctx context.Context, id string) error {
+	ret := _mock.Called(ctx, id)
 
 	if len(ret) == 0 {
-		panic("no return value specified for ListSandboxes")
+		panic("no return value specified for DeleteSandbox")
 	}
 
-	var r0 []model.Sandbox
-	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context) ([]model.Sandbox, error)); ok {
-		return returnFunc(ctx)
-	}
-	if returnFunc, ok := ret.Get(0).(func(context.Context) []model.Sandbox); ok {
-		r0 = returnFunc(ctx)
-	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]model.Sandbox)
-		}
-	}
-	if returnFunc, ok := ret.Get(1).(func(context.Context) error); ok {
-		r1 = returnFunc(ctx)
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = returnFunc(ctx, id)
 	} else {
-		r1 = ret.Error(1)
+		r0 = ret.Error(0)
 	}
-	return r0, r1
+	return r0
 }
 
-// MockRepository_ListSandboxes_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListSandboxes'
-type MockRepository_ListSandboxes_Call struct {
+// MockRepository_DeleteSandbox_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteSandbox'
+type MockRepository_DeleteSandbox_Call struct {
 	*mock.Call
 }
 
-// ListSandboxes is a helper method to define mock.On call
+// DeleteSandbox is a helper method to define mock.On call
 //   - ctx context.Context
-func (_e *MockRepository_Expecter) ListSandboxes(ctx interface{}) *MockRepository_ListSandboxes_Call {
-	return &MockRepository_ListSandboxes_Call{Call: _e.mock.On("ListSandboxes", ctx)}
+//   - id string
+func (_e *MockRepository_Expecter) DeleteSandbox(ctx interface{}, id interface{}) *MockRepository_DeleteSandbox_Call {
+	return &MockRepository_DeleteSandbox_Call{Call: _e.mock.On("DeleteSandbox", ctx, id)}
 }
 
-func (_c *MockRepository_ListSandboxes_Call) Run(run func(ctx context.Context)) *MockRepository_ListSandboxes_Call {
+func (_c *MockRepository_DeleteSandbox_Call) Run(run func(ctx context.Context, id string)) *MockRepository_DeleteSandbox_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
 			arg0 = args[0].(context.Context)
 		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
 		run(
 			arg0,
+			arg1,
 		)
 	})
 	return _c
 }
 
-func (_c *MockRepository_ListSandboxes_Call) Return(sandboxs []model.Sandbox, err error) *MockRepository_ListSandboxes_Call {
-	_c.Call.Return(sandboxs, err)
+func (_c *MockRepository_DeleteSandbox_Call) Return(err error) *MockRepository_DeleteSandbox_Call {
+	_c.Call.Return(err)
 	return _c
 }
 
-func (_c *MockRepository_ListSandboxes_Call) RunAndReturn(run func(ctx context.Context) ([]model.Sandbox, error)) *MockRepository_ListSandboxes_Call {
+func (_c *MockRepository_DeleteSandbox_Call) RunAndReturn(run func(ctx context.Context, id string) error) *MockRepository_DeleteSandbox_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// UpdateSandbox provides a mock function for the type MockRepository
-func (_mock *MockRepository) UpdateSandbox(ctx context.Context, s model.Sandbox) error {
-	ret := _mock.Called(ctx, s)
+// DeleteSandboxTemplate provides a mock function for the type MockRepository
+func (_mock *MockRepository) DeleteSandboxTemplate(ctx context.Context, name string) error {
+	ret := _mock.Called(ctx, name)
 
 	if len(ret) == 0 {
-		panic("no return value specified for UpdateSandbox")
+		panic("no return value specified for DeleteSandboxTemplate")
 	}
 
 	var r0 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, model.Sandbox) error); ok {
-		r0 = returnFunc(ctx, s)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = returnFunc(ctx, name)
 	} else {
 		r0 = ret.Error(0)
 	}
 	return r0
 }
 
-// MockRepository_UpdateSandbox_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateSandbox'
-type MockRepository_UpdateSandbox_Call struct {
+// MockRepository_DeleteSandboxTemplate_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteSandboxTemplate'
+type MockRepository_DeleteSandboxTemplate_Call struct {
 	*mock.Call
 }
 
-// UpdateSandbox is a helper method to define mock.On call
+// DeleteSandboxTemplate is a helper method to define mock.On call
 //   - ctx context.Context
-//   - s model.Sandbox
-func (_e *MockRepository_Expecter) UpdateSandbox(ctx interface{}, s interface{}) *MockRepository_UpdateSandbox_Call {
-	return &MockRepository_UpdateSandbox_Call{Call: _e.mock.On("UpdateSandbox", ctx, s)}
+//   - name string
+func (_e *MockRepository_Expecter) DeleteSandboxTemplate(ctx interface{}, name interface{}) *MockRepository_DeleteSandboxTemplate_Call {
+	return &MockRepository_DeleteSandboxTemplate_Call{Call: _e.mock.On("DeleteSandboxTemplate", ctx, name)}
 }
 
-func (_c *MockRepository_UpdateSandbox_Call) Run(run func(ctx context.Context, s model.Sandbox)) *MockRepository_UpdateSandbox_Call {
+func (_c *MockRepository_DeleteSandboxTemplate_Call) Run(run func(ctx context.Context, name string)) *MockRepository_DeleteSandboxTemplate_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
 			arg0 = args[0].(context.Context)
 		}
-		var arg1 model.Sandbox
+		var arg1 string
 		if args[1] != nil {
-			arg1 = args[1].(model.Sandbox)
+			arg1 = args[1].(string)
 		}
 		run(
 			arg0,
@@ -397,12 +485,1553 @@ func (_c *MockRepository_UpdateSandbox_Call) Run(run func(ctx context.Context, s
 	return _c
 }
 
-func (_c *MockRepository_UpdateSandbox_Call) Return(err error) *MockRepository_UpdateSandbox_Call {
+func (_c *MockRepository_DeleteSandboxTemplate_Call) Return(err error) *MockRepository_DeleteSandboxTemplate_Call {
 	_c.Call.Return(err)
 	return _c
 }
 
-func (_c *MockRepository_UpdateSandbox_Call) RunAndReturn(run func(ctx context.Context, s model.Sandbox) error) *MockRepository_UpdateSandbox_Call {
+func (_c *MockRepository_DeleteSandboxTemplate_Call) RunAndReturn(run func(ctx context.Context, name string) error) *MockRepository_DeleteSandboxTemplate_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteTask provides a mock function for the type MockRepository
+func (_mock *MockRepository) DeleteTask(ctx context.Context, sandboxID string, name string) error {
+	ret := _mock.Called(ctx, sandboxID, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteTask")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = returnFunc(ctx, sandboxID, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockRepository_DeleteTask_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteTask'
+type MockRepository_DeleteTask_Call struct {
+	*mock.Call
+}
+
+// DeleteTask is a helper method to define mock.On call
+//   - ctx context.Context
+//   - sandboxID string
+//   - name string
+func (_e *MockRepository_Expecter) DeleteTask(ctx interface{}, sandboxID interface{}, name interface{}) *MockRepository_DeleteTask_Call {
+	return &MockRepository_DeleteTask_Call{Call: _e.mock.On("DeleteTask", ctx, sandboxID, name)}
+}
+
+func (_c *MockRepository_DeleteTask_Call) Run(run func(ctx context.Context, sandboxID string, name string)) *MockRepository_DeleteTask_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRepository_DeleteTask_Call) Return(err error) *MockRepository_DeleteTask_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockRepository_DeleteTask_Call) RunAndReturn(run func(ctx context.Context, sandboxID string, name string) error) *MockRepository_DeleteTask_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetEgressPolicy provides a mock function for the type MockRepository
+func (_mock *MockRepository) GetEgressPolicy(ctx context.Context, name string) (*model.NamedEgressPolicy, error) {
+	ret := _mock.Called(ctx, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetEgressPolicy")
+	}
+
+	var r0 *model.NamedEgressPolicy
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (*model.NamedEgressPolicy, error)); ok {
+		return returnFunc(ctx, name)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) *model.NamedEgressPolicy); ok {
+		r0 = returnFunc(ctx, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.NamedEgressPolicy)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockRepository_GetEgressPolicy_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetEgressPolicy'
+type MockRepository_GetEgressPolicy_Call struct {
+	*mock.Call
+}
+
+// GetEgressPolicy is a helper method to define mock.On call
+//   - ctx context.Context
+//   - name string
+func (_e *MockRepository_Expecter) GetEgressPolicy(ctx interface{}, name interface{}) *MockRepository_GetEgressPolicy_Call {
+	return &MockRepository_GetEgressPolicy_Call{Call: _e.mock.On("GetEgressPolicy", ctx, name)}
+}
+
+func (_c *MockRepository_GetEgressPolicy_Call) Run(run func(ctx context.Context, name string)) *MockRepository_GetEgressPolicy_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRepository_GetEgressPolicy_Call) Return(namedEgressPolicy *model.NamedEgressPolicy, err error) *MockRepository_GetEgressPolicy_Call {
+	_c.Call.Return(namedEgressPolicy, err)
+	return _c
+}
+
+func (_c *MockRepository_GetEgressPolicy_Call) RunAndReturn(run func(ctx context.Context, name string) (*model.NamedEgressPolicy, error)) *MockRepository_GetEgressPolicy_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetIntegrityBaseline provides a mock function for the type MockRepository
+func (_mock *MockRepository) GetIntegrityBaseline(ctx context.Context, sandboxID string) (*model.IntegrityBaseline, error) {
+	ret := _mock.Called(ctx, sandboxID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetIntegrityBaseline")
+	}
+
+	var r0 *model.IntegrityBaseline
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (*model.IntegrityBaseline, error)); ok {
+		return returnFunc(ctx, sandboxID)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) *model.IntegrityBaseline); ok {
+		r0 = returnFunc(ctx, sandboxID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.IntegrityBaseline)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, sandboxID)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockRepository_GetIntegrityBaseline_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetIntegrityBaseline'
+type MockRepository_GetIntegrityBaseline_Call struct {
+	*mock.Call
+}
+
+// GetIntegrityBaseline is a helper method to define mock.On call
+//   - ctx context.Context
+//   - sandboxID string
+func (_e *MockRepository_Expecter) GetIntegrityBaseline(ctx interface{}, sandboxID interface{}) *MockRepository_GetIntegrityBaseline_Call {
+	return &MockRepository_GetIntegrityBaseline_Call{Call: _e.mock.On("GetIntegrityBaseline", ctx, sandboxID)}
+}
+
+func (_c *MockRepository_GetIntegrityBaseline_Call) Run(run func(ctx context.Context, sandboxID string)) *MockRepository_GetIntegrityBaseline_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRepository_GetIntegrityBaseline_Call) Return(integrityBaseline *model.IntegrityBaseline, err error) *MockRepository_GetIntegrityBaseline_Call {
+	_c.Call.Return(integrityBaseline, err)
+	return _c
+}
+
+func (_c *MockRepository_GetIntegrityBaseline_Call) RunAndReturn(run func(ctx context.Context, sandboxID string) (*model.IntegrityBaseline, error)) *MockRepository_GetIntegrityBaseline_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetSandbox provides a mock function for the type MockRepository
+func (_mock *MockRepository) GetSandbox(ctx context.Context, id string) (*model.Sandbox, error) {
+	ret := _mock.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSandbox")
+	}
+
+	var r0 *model.Sandbox
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (*model.Sandbox, error)); ok {
+		return returnFunc(ctx, id)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) *model.Sandbox); ok {
+		r0 = returnFunc(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Sandbox)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockRepository_GetSandbox_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetSandbox'
+type MockRepository_GetSandbox_Call struct {
+	*mock.Call
+}
+
+// GetSandbox is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+func (_e *MockRepository_Expecter) GetSandbox(ctx interface{}, id interface{}) *MockRepository_GetSandbox_Call {
+	return &MockRepository_GetSandbox_Call{Call: _e.mock.On("GetSandbox", ctx, id)}
+}
+
+func (_c *MockRepository_GetSandbox_Call) Run(run func(ctx context.Context, id string)) *MockRepository_GetSandbox_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRepository_GetSandbox_Call) Return(sandbox *model.Sandbox, err error) *MockRepository_GetSandbox_Call {
+	_c.Call.Return(sandbox, err)
+	return _c
+}
+
+func (_c *MockRepository_GetSandbox_Call) RunAndReturn(run func(ctx context.Context, id string) (*model.Sandbox, error)) *MockRepository_GetSandbox_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetSandboxByName provides a mock function for the type MockRepository
+func (_mock *MockRepository) GetSandboxByName(ctx context.Context, name string) (*model.Sandbox, error) {
+	ret := _mock.Called(ctx, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSandboxByName")
+	}
+
+	var r0 *model.Sandbox
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (*model.Sandbox, error)); ok {
+		return returnFunc(ctx, name)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) *model.Sandbox); ok {
+		r0 = returnFunc(ctx, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Sandbox)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockRepository_GetSandboxByName_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetSandboxByName'
+type MockRepository_GetSandboxByName_Call struct {
+	*mock.Call
+}
+
+// GetSandboxByName is a helper method to define mock.On call
+//   - ctx context.Context
+//   - name string
+func (_e *MockRepository_Expecter) GetSandboxByName(ctx interface{}, name interface{}) *MockRepository_GetSandboxByName_Call {
+	return &MockRepository_GetSandboxByName_Call{Call: _e.mock.On("GetSandboxByName", ctx, name)}
+}
+
+func (_c *MockRepository_GetSandboxByName_Call) Run(run func(ctx context.Context, name string)) *MockRepository_GetSandboxByName_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRepository_GetSandboxByName_Call) Return(sandbox *model.Sandbox, err error) *MockRepository_GetSandboxByName_Call {
+	_c.Call.Return(sandbox, err)
+	return _c
+}
+
+func (_c *MockRepository_GetSandboxByName_Call) RunAndReturn(run func(ctx context.Context, name string) (*model.Sandbox, error)) *MockRepository_GetSandboxByName_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetSandboxTemplate provides a mock function for the type MockRepository
+func (_mock *MockRepository) GetSandboxTemplate(ctx context.Context, name string) (*model.SandboxTemplate, error) {
+	ret := _mock.Called(ctx, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSandboxTemplate")
+	}
+
+	var r0 *model.SandboxTemplate
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (*model.SandboxTemplate, error)); ok {
+		return returnFunc(ctx, name)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) *model.SandboxTemplate); ok {
+		r0 = returnFunc(ctx, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.SandboxTemplate)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockRepository_GetSandboxTemplate_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetSandboxTemplate'
+type MockRepository_GetSandboxTemplate_Call struct {
+	*mock.Call
+}
+
+// GetSandboxTemplate is a helper method to define mock.On call
+//   - ctx context.Context
+//   - name string
+func (_e *MockRepository_Expecter) GetSandboxTemplate(ctx interface{}, name interface{}) *MockRepository_GetSandboxTemplate_Call {
+	return &MockRepository_GetSandboxTemplate_Call{Call: _e.mock.On("GetSandboxTemplate", ctx, name)}
+}
+
+func (_c *MockRepository_GetSandboxTemplate_Call) Run(run func(ctx context.Context, name string)) *MockRepository_GetSandboxTemplate_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRepository_GetSandboxTemplate_Call) Return(sandboxTemplate *model.SandboxTemplate, err error) *MockRepository_GetSandboxTemplate_Call {
+	_c.Call.Return(sandboxTemplate, err)
+	return _c
+}
+
+func (_c *MockRepository_GetSandboxTemplate_Call) RunAndReturn(run func(ctx context.Context, name string) (*model.SandboxTemplate, error)) *MockRepository_GetSandboxTemplate_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetTask provides a mock function for the type MockRepository
+func (_mock *MockRepository) GetTask(ctx context.Context, sandboxID string, name string) (*model.Task, error) {
+	ret := _mock.Called(ctx, sandboxID, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTask")
+	}
+
+	var r0 *model.Task
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) (*model.Task, error)); ok {
+		return returnFunc(ctx, sandboxID, name)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) *model.Task); ok {
+		r0 = returnFunc(ctx, sandboxID, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Task)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = returnFunc(ctx, sandboxID, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockRepository_GetTask_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetTask'
+type MockRepository_GetTask_Call struct {
+	*mock.Call
+}
+
+// GetTask is a helper method to define mock.On call
+//   - ctx context.Context
+//   - sandboxID string
+//   - name string
+func (_e *MockRepository_Expecter) GetTask(ctx interface{}, sandboxID interface{}, name interface{}) *MockRepository_GetTask_Call {
+	return &MockRepository_GetTask_Call{Call: _e.mock.On("GetTask", ctx, sandboxID, name)}
+}
+
+func (_c *MockRepository_GetTask_Call) Run(run func(ctx context.Context, sandboxID string, name string)) *MockRepository_GetTask_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRepository_GetTask_Call) Return(task *model.Task, err error) *MockRepository_GetTask_Call {
+	_c.Call.Return(task, err)
+	return _c
+}
+
+func (_c *MockRepository_GetTask_Call) RunAndReturn(run func(ctx context.Context, sandboxID string, name string) (*model.Task, error)) *MockRepository_GetTask_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListAllSandboxes provides a mock function for the type MockRepository
+func (_mock *MockRepository) ListAllSandboxes(ctx context.Context) ([]model.Sandbox, error) {
+	ret := _mock.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAllSandboxes")
+	}
+
+	var r0 []model.Sandbox
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context) ([]model.Sandbox, error)); ok {
+		return returnFunc(ctx)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context) []model.Sandbox); ok {
+		r0 = returnFunc(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.Sandbox)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = returnFunc(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockRepository_ListAllSandboxes_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListAllSandboxes'
+type MockRepository_ListAllSandboxes_Call struct {
+	*mock.Call
+}
+
+// ListAllSandboxes is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockRepository_Expecter) ListAllSandboxes(ctx interface{}) *MockRepository_ListAllSandboxes_Call {
+	return &MockRepository_ListAllSandboxes_Call{Call: _e.mock.On("ListAllSandboxes", ctx)}
+}
+
+func (_c *MockRepository_ListAllSandboxes_Call) Run(run func(ctx context.Context)) *MockRepository_ListAllSandboxes_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRepository_ListAllSandboxes_Call) Return(sandboxs []model.Sandbox, err error) *MockRepository_ListAllSandboxes_Call {
+	_c.Call.Return(sandboxs, err)
+	return _c
+}
+
+func (_c *MockRepository_ListAllSandboxes_Call) RunAndReturn(run func(ctx context.Context) ([]model.Sandbox, error)) *MockRepository_ListAllSandboxes_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListEgressPolicies provides a mock function for the type MockRepository
+func (_mock *MockRepository) ListEgressPolicies(ctx context.Context) ([]model.NamedEgressPolicy, error) {
+	ret := _mock.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListEgressPolicies")
+	}
+
+	var r0 []model.NamedEgressPolicy
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context) ([]model.NamedEgressPolicy, error)); ok {
+		return returnFunc(ctx)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context) []model.NamedEgressPolicy); ok {
+		r0 = returnFunc(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.NamedEgressPolicy)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = returnFunc(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockRepository_ListEgressPolicies_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListEgressPolicies'
+type MockRepository_ListEgressPolicies_Call struct {
+	*mock.Call
+}
+
+// ListEgressPolicies is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockRepository_Expecter) ListEgressPolicies(ctx interface{}) *MockRepository_ListEgressPolicies_Call {
+	return &MockRepository_ListEgressPolicies_Call{Call: _e.mock.On("ListEgressPolicies", ctx)}
+}
+
+func (_c *MockRepository_ListEgressPolicies_Call) Run(run func(ctx context.Context)) *MockRepository_ListEgressPolicies_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRepository_ListEgressPolicies_Call) Return(namedEgressPolicys []model.NamedEgressPolicy, err error) *MockRepository_ListEgressPolicies_Call {
+	_c.Call.Return(namedEgressPolicys, err)
+	return _c
+}
+
+func (_c *MockRepository_ListEgressPolicies_Call) RunAndReturn(run func(ctx context.Context) ([]model.NamedEgressPolicy, error)) *MockRepository_ListEgressPolicies_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListExecRecords provides a mock function for the type MockRepository
+func (_mock *MockRepository) ListExecRecords(ctx context.Context, sandboxID string, filter model.ExecRecordFilter) ([]model.ExecRecord, error) {
+	ret := _mock.Called(ctx, sandboxID, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListExecRecords")
+	}
+
+	var r0 []model.ExecRecord
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, model.ExecRecordFilter) ([]model.ExecRecord, error)); ok {
+		return returnFunc(ctx, sandboxID, filter)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, model.ExecRecordFilter) []model.ExecRecord); ok {
+		r0 = returnFunc(ctx, sandboxID, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.ExecRecord)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, model.ExecRecordFilter) error); ok {
+		r1 = returnFunc(ctx, sandboxID, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockRepository_ListExecRecords_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListExecRecords'
+type MockRepository_ListExecRecords_Call struct {
+	*mock.Call
+}
+
+// ListExecRecords is a helper method to define mock.On call
+//   - ctx context.Context
+//   - sandboxID string
+//   - filter model.ExecRecordFilter
+func (_e *MockRepository_Expecter) ListExecRecords(ctx interface{}, sandboxID interface{}, filter interface{}) *MockRepository_ListExecRecords_Call {
+	return &MockRepository_ListExecRecords_Call{Call: _e.mock.On("ListExecRecords", ctx, sandboxID, filter)}
+}
+
+func (_c *MockRepository_ListExecRecords_Call) Run(run func(ctx context.Context, sandboxID string, filter model.ExecRecordFilter)) *MockRepository_ListExecRecords_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 model.ExecRecordFilter
+		if args[2] != nil {
+			arg2 = args[2].(model.ExecRecordFilter)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRepository_ListExecRecords_Call) Return(execRecords []model.ExecRecord, err error) *MockRepository_ListExecRecords_Call {
+	_c.Call.Return(execRecords, err)
+	return _c
+}
+
+func (_c *MockRepository_ListExecRecords_Call) RunAndReturn(run func(ctx context.Context, sandboxID string, filter model.ExecRecordFilter) ([]model.ExecRecord, error)) *MockRepository_ListExecRecords_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListSandboxTemplates provides a mock function for the type MockRepository
+func (_mock *MockRepository) ListSandboxTemplates(ctx context.Context) ([]model.SandboxTemplate, error) {
+	ret := _mock.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListSandboxTemplates")
+	}
+
+	var r0 []model.SandboxTemplate
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context) ([]model.SandboxTemplate, error)); ok {
+		return returnFunc(ctx)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context) []model.SandboxTemplate); ok {
+		r0 = returnFunc(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.SandboxTemplate)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = returnFunc(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockRepository_ListSandboxTemplates_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListSandboxTemplates'
+type MockRepository_ListSandboxTemplates_Call struct {
+	*mock.Call
+}
+
+// ListSandboxTemplates is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockRepository_Expecter) ListSandboxTemplates(ctx interface{}) *MockRepository_ListSandboxTemplates_Call {
+	return &MockRepository_ListSandboxTemplates_Call{Call: _e.mock.On("ListSandboxTemplates", ctx)}
+}
+
+func (_c *MockRepository_ListSandboxTemplates_Call) Run(run func(ctx context.Context)) *MockRepository_ListSandboxTemplates_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRepository_ListSandboxTemplates_Call) Return(sandboxTemplates []model.SandboxTemplate, err error) *MockRepository_ListSandboxTemplates_Call {
+	_c.Call.Return(sandboxTemplates, err)
+	return _c
+}
+
+func (_c *MockRepository_ListSandboxTemplates_Call) RunAndReturn(run func(ctx context.Context) ([]model.SandboxTemplate, error)) *MockRepository_ListSandboxTemplates_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListSandboxes provides a mock function for the type MockRepository
+func (_mock *MockRepository) ListSandboxes(ctx context.Context) ([]model.Sandbox, error) {
+	ret := _mock.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListSandboxes")
+	}
+
+	var r0 []model.Sandbox
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context) ([]model.Sandbox, error)); ok {
+		return returnFunc(ctx)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context) []model.Sandbox); ok {
+		r0 = returnFunc(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.Sandbox)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = returnFunc(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockRepository_ListSandboxes_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListSandboxes'
+type MockRepository_ListSandboxes_Call struct {
+	*mock.Call
+}
+
+// ListSandboxes is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockRepository_Expecter) ListSandboxes(ctx interface{}) *MockRepository_ListSandboxes_Call {
+	return &MockRepository_ListSandboxes_Call{Call: _e.mock.On("ListSandboxes", ctx)}
+}
+
+func (_c *MockRepository_ListSandboxes_Call) Run(run func(ctx context.Context)) *MockRepository_ListSandboxes_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRepository_ListSandboxes_Call) Return(sandboxs []model.Sandbox, err error) *MockRepository_ListSandboxes_Call {
+	_c.Call.Return(sandboxs, err)
+	return _c
+}
+
+func (_c *MockRepository_ListSandboxes_Call) RunAndReturn(run func(ctx context.Context) ([]model.Sandbox, error)) *MockRepository_ListSandboxes_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListTasks provides a mock function for the type MockRepository
+func (_mock *MockRepository) ListTasks(ctx context.Context, sandboxID string) ([]model.Task, error) {
+	ret := _mock.Called(ctx, sandboxID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListTasks")
+	}
+
+	var r0 []model.Task
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) ([]model.Task, error)); ok {
+		return returnFunc(ctx, sandboxID)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) []model.Task); ok {
+		r0 = returnFunc(ctx, sandboxID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.Task)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, sandboxID)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockRepository_ListTasks_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListTasks'
+type MockRepository_ListTasks_Call struct {
+	*mock.Call
+}
+
+// ListTasks is a helper method to define mock.On call
+//   - ctx context.Context
+//   - sandboxID string
+func (_e *MockRepository_Expecter) ListTasks(ctx interface{}, sandboxID interface{}) *MockRepository_ListTasks_Call {
+	return &MockRepository_ListTasks_Call{Call: _e.mock.On("ListTasks", ctx, sandboxID)}
+}
+
+func (_c *MockRepository_ListTasks_Call) Run(run func(ctx context.Context, sandboxID string)) *MockRepository_ListTasks_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRepository_ListTasks_Call) Return(tasks []model.Task, err error) *MockRepository_ListTasks_Call {
+	_c.Call.Return(tasks, err)
+	return _c
+}
+
+func (_c *MockRepository_ListTasks_Call) RunAndReturn(run func(ctx context.Context, sandboxID string) ([]model.Task, error)) *MockRepository_ListTasks_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListUsageRecords provides a mock function for the type MockRepository
+func (_mock *MockRepository) ListUsageRecords(ctx context.Context, filter model.UsageRecordFilter) ([]model.UsageRecord, error) {
+	ret := _mock.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListUsageRecords")
+	}
+
+	var r0 []model.UsageRecord
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, model.UsageRecordFilter) ([]model.UsageRecord, error)); ok {
+		return returnFunc(ctx, filter)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, model.UsageRecordFilter) []model.UsageRecord); ok {
+		r0 = returnFunc(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.UsageRecord)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, model.UsageRecordFilter) error); ok {
+		r1 = returnFunc(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockRepository_ListUsageRecords_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListUsageRecords'
+type MockRepository_ListUsageRecords_Call struct {
+	*mock.Call
+}
+
+// ListUsageRecords is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter model.UsageRecordFilter
+func (_e *MockRepository_Expecter) ListUsageRecords(ctx interface{}, filter interface{}) *MockRepository_ListUsageRecords_Call {
+	return &MockRepository_ListUsageRecords_Call{Call: _e.mock.On("ListUsageRecords", ctx, filter)}
+}
+
+func (_c *MockRepository_ListUsageRecords_Call) Run(run func(ctx context.Context, filter model.UsageRecordFilter)) *MockRepository_ListUsageRecords_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 model.UsageRecordFilter
+		if args[1] != nil {
+			arg1 = args[1].(model.UsageRecordFilter)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRepository_ListUsageRecords_Call) Return(usageRecords []model.UsageRecord, err error) *MockRepository_ListUsageRecords_Call {
+	_c.Call.Return(usageRecords, err)
+	return _c
+}
+
+func (_c *MockRepository_ListUsageRecords_Call) RunAndReturn(run func(ctx context.Context, filter model.UsageRecordFilter) ([]model.UsageRecord, error)) *MockRepository_ListUsageRecords_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SaveIntegrityBaseline provides a mock function for the type MockRepository
+func (_mock *MockRepository) SaveIntegrityBaseline(ctx context.Context, b model.IntegrityBaseline) error {
+	ret := _mock.Called(ctx, b)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveIntegrityBaseline")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, model.IntegrityBaseline) error); ok {
+		r0 = returnFunc(ctx, b)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockRepository_SaveIntegrityBaseline_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SaveIntegrityBaseline'
+type MockRepository_SaveIntegrityBaseline_Call struct {
+	*mock.Call
+}
+
+// SaveIntegrityBaseline is a helper method to define mock.On call
+//   - ctx context.Context
+//   - b model.IntegrityBaseline
+func (_e *MockRepository_Expecter) SaveIntegrityBaseline(ctx interface{}, b interface{}) *MockRepository_SaveIntegrityBaseline_Call {
+	return &MockRepository_SaveIntegrityBaseline_Call{Call: _e.mock.On("SaveIntegrityBaseline", ctx, b)}
+}
+
+func (_c *MockRepository_SaveIntegrityBaseline_Call) Run(run func(ctx context.Context, b model.IntegrityBaseline)) *MockRepository_SaveIntegrityBaseline_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 model.IntegrityBaseline
+		if args[1] != nil {
+			arg1 = args[1].(model.IntegrityBaseline)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRepository_SaveIntegrityBaseline_Call) Return(err error) *MockRepository_SaveIntegrityBaseline_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockRepository_SaveIntegrityBaseline_Call) RunAndReturn(run func(ctx context.Context, b model.IntegrityBaseline) error) *MockRepository_SaveIntegrityBaseline_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SaveTask provides a mock function for the type MockRepository
+func (_mock *MockRepository) SaveTask(ctx context.Context, t model.Task) error {
+	ret := _mock.Called(ctx, t)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveTask")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, model.Task) error); ok {
+		r0 = returnFunc(ctx, t)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockRepository_SaveTask_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SaveTask'
+type MockRepository_SaveTask_Call struct {
+	*mock.Call
+}
+
+// SaveTask is a helper method to define mock.On call
+//   - ctx context.Context
+//   - t model.Task
+func (_e *MockRepository_Expecter) SaveTask(ctx interface{}, t interface{}) *MockRepository_SaveTask_Call {
+	return &MockRepository_SaveTask_Call{Call: _e.mock.On("SaveTask", ctx, t)}
+}
+
+func (_c *MockRepository_SaveTask_Call) Run(run func(ctx context.Context, t model.Task)) *MockRepository_SaveTask_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 model.Task
+		if args[1] != nil {
+			arg1 = args[1].(model.Task)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRepository_SaveTask_Call) Return(err error) *MockRepository_SaveTask_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockRepository_SaveTask_Call) RunAndReturn(run func(ctx context.Context, t model.Task) error) *MockRepository_SaveTask_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateEgressPolicy provides a mock function for the type MockRepository
+func (_mock *MockRepository) UpdateEgressPolicy(ctx context.Context, p model.NamedEgressPolicy) error {
+	ret := _mock.Called(ctx, p)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateEgressPolicy")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, model.NamedEgressPolicy) error); ok {
+		r0 = returnFunc(ctx, p)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockRepository_UpdateEgressPolicy_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateEgressPolicy'
+type MockRepository_UpdateEgressPolicy_Call struct {
+	*mock.Call
+}
+
+// UpdateEgressPolicy is a helper method to define mock.On call
+//   - ctx context.Context
+//   - p model.NamedEgressPolicy
+func (_e *MockRepository_Expecter) UpdateEgressPolicy(ctx interface{}, p interface{}) *MockRepository_UpdateEgressPolicy_Call {
+	return &MockRepository_UpdateEgressPolicy_Call{Call: _e.mock.On("UpdateEgressPolicy", ctx, p)}
+}
+
+func (_c *MockRepository_UpdateEgressPolicy_Call) Run(run func(ctx context.Context, p model.NamedEgressPolicy)) *MockRepository_UpdateEgressPolicy_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 model.NamedEgressPolicy
+		if args[1] != nil {
+			arg1 = args[1].(model.NamedEgressPolicy)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRepository_UpdateEgressPolicy_Call) Return(err error) *MockRepository_UpdateEgressPolicy_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockRepository_UpdateEgressPolicy_Call) RunAndReturn(run func(ctx context.Context, p model.NamedEgressPolicy) error) *MockRepository_UpdateEgressPolicy_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateSandbox provides a mock function for the type MockRepository
+func (_mock *MockRepository) UpdateSandbox(ctx context.Context, s model.Sandbox) error {
+	ret := _mock.Called(ctx, s)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateSandbox")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, model.Sandbox) error); ok {
+		r0 = returnFunc(ctx, s)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockRepository_UpdateSandbox_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateSandbox'
+type MockRepository_UpdateSandbox_Call struct {
+	*mock.Call
+}
+
+// UpdateSandbox is a helper method to define mock.On call
+//   - ctx context.Context
+//   - s model.Sandbox
+func (_e *MockRepository_Expecter) UpdateSandbox(ctx interface{}, s interface{}) *MockRepository_UpdateSandbox_Call {
+	return &MockRepository_UpdateSandbox_Call{Call: _e.mock.On("UpdateSandbox", ctx, s)}
+}
+
+func (_c *MockRepository_UpdateSandbox_Call) Run(run func(ctx context.Context, s model.Sandbox)) *MockRepository_UpdateSandbox_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 model.Sandbox
+		if args[1] != nil {
+			arg1 = args[1].(model.Sandbox)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRepository_UpdateSandbox_Call) Return(err error) *MockRepository_UpdateSandbox_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockRepository_UpdateSandbox_Call) RunAndReturn(run func(ctx context.Context, s model.Sandbox) error) *MockRepository_UpdateSandbox_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateSandboxAnnotations provides a mock function for the type MockRepository
+func (_mock *MockRepository) UpdateSandboxAnnotations(ctx context.Context, id string, annotations map[string]string) error {
+	ret := _mock.Called(ctx, id, annotations)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateSandboxAnnotations")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, map[string]string) error); ok {
+		r0 = returnFunc(ctx, id, annotations)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockRepository_UpdateSandboxAnnotations_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateSandboxAnnotations'
+type MockRepository_UpdateSandboxAnnotations_Call struct {
+	*mock.Call
+}
+
+// UpdateSandboxAnnotations is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - annotations map[string]string
+func (_e *MockRepository_Expecter) UpdateSandboxAnnotations(ctx interface{}, id interface{}, annotations interface{}) *MockRepository_UpdateSandboxAnnotations_Call {
+	return &MockRepository_UpdateSandboxAnnotations_Call{Call: _e.mock.On("UpdateSandboxAnnotations", ctx, id, annotations)}
+}
+
+func (_c *MockRepository_UpdateSandboxAnnotations_Call) Run(run func(ctx context.Context, id string, annotations map[string]string)) *MockRepository_UpdateSandboxAnnotations_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 map[string]string
+		if args[2] != nil {
+			arg2 = args[2].(map[string]string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRepository_UpdateSandboxAnnotations_Call) Return(err error) *MockRepository_UpdateSandboxAnnotations_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockRepository_UpdateSandboxAnnotations_Call) RunAndReturn(run func(ctx context.Context, id string, annotations map[string]string) error) *MockRepository_UpdateSandboxAnnotations_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateSandboxIdlePolicy provides a mock function for the type MockRepository
+func (_mock *MockRepository) UpdateSandboxIdlePolicy(ctx context.Context, id string, policy *model.IdlePolicy) error {
+	ret := _mock.Called(ctx, id, policy)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateSandboxIdlePolicy")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, *model.IdlePolicy) error); ok {
+		r0 = returnFunc(ctx, id, policy)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockRepository_UpdateSandboxIdlePolicy_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateSandboxIdlePolicy'
+type MockRepository_UpdateSandboxIdlePolicy_Call struct {
+	*mock.Call
+}
+
+// UpdateSandboxIdlePolicy is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - policy *model.IdlePolicy
+func (_e *MockRepository_Expecter) UpdateSandboxIdlePolicy(ctx interface{}, id interface{}, policy interface{}) *MockRepository_UpdateSandboxIdlePolicy_Call {
+	return &MockRepository_UpdateSandboxIdlePolicy_Call{Call: _e.mock.On("UpdateSandboxIdlePolicy", ctx, id, policy)}
+}
+
+func (_c *MockRepository_UpdateSandboxIdlePolicy_Call) Run(run func(ctx context.Context, id string, policy *model.IdlePolicy)) *MockRepository_UpdateSandboxIdlePolicy_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 *model.IdlePolicy
+		if args[2] != nil {
+			arg2 = args[2].(*model.IdlePolicy)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRepository_UpdateSandboxIdlePolicy_Call) Return(err error) *MockRepository_UpdateSandboxIdlePolicy_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockRepository_UpdateSandboxIdlePolicy_Call) RunAndReturn(run func(ctx context.Context, id string, policy *model.IdlePolicy) error) *MockRepository_UpdateSandboxIdlePolicy_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateSandboxLastActivity provides a mock function for the type MockRepository
+func (_mock *MockRepository) UpdateSandboxLastActivity(ctx context.Context, id string, at time.Time) error {
+	ret := _mock.Called(ctx, id, at)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateSandboxLastActivity")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, time.Time) error); ok {
+		r0 = returnFunc(ctx, id, at)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockRepository_UpdateSandboxLastActivity_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateSandboxLastActivity'
+type MockRepository_UpdateSandboxLastActivity_Call struct {
+	*mock.Call
+}
+
+// UpdateSandboxLastActivity is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - at time.Time
+func (_e *MockRepository_Expecter) UpdateSandboxLastActivity(ctx interface{}, id interface{}, at interface{}) *MockRepository_UpdateSandboxLastActivity_Call {
+	return &MockRepository_UpdateSandboxLastActivity_Call{Call: _e.mock.On("UpdateSandboxLastActivity", ctx, id, at)}
+}
+
+func (_c *MockRepository_UpdateSandboxLastActivity_Call) Run(run func(ctx context.Context, id string, at time.Time)) *MockRepository_UpdateSandboxLastActivity_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 time.Time
+		if args[2] != nil {
+			arg2 = args[2].(time.Time)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRepository_UpdateSandboxLastActivity_Call) Return(err error) *MockRepository_UpdateSandboxLastActivity_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockRepository_UpdateSandboxLastActivity_Call) RunAndReturn(run func(ctx context.Context, id string, at time.Time) error) *MockRepository_UpdateSandboxLastActivity_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateSandboxNetworkBytes provides a mock function for the type MockRepository
+func (_mock *MockRepository) UpdateSandboxNetworkBytes(ctx context.Context, id string, bytes uint64) error {
+	ret := _mock.Called(ctx, id, bytes)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateSandboxNetworkBytes")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, uint64) error); ok {
+		r0 = returnFunc(ctx, id, bytes)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockRepository_UpdateSandboxNetworkBytes_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateSandboxNetworkBytes'
+type MockRepository_UpdateSandboxNetworkBytes_Call struct {
+	*mock.Call
+}
+
+// UpdateSandboxNetworkBytes is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - bytes uint64
+func (_e *MockRepository_Expecter) UpdateSandboxNetworkBytes(ctx interface{}, id interface{}, bytes interface{}) *MockRepository_UpdateSandboxNetworkBytes_Call {
+	return &MockRepository_UpdateSandboxNetworkBytes_Call{Call: _e.mock.On("UpdateSandboxNetworkBytes", ctx, id, bytes)}
+}
+
+func (_c *MockRepository_UpdateSandboxNetworkBytes_Call) Run(run func(ctx context.Context, id string, bytes uint64)) *MockRepository_UpdateSandboxNetworkBytes_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 uint64
+		if args[2] != nil {
+			arg2 = args[2].(uint64)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRepository_UpdateSandboxNetworkBytes_Call) Return(err error) *MockRepository_UpdateSandboxNetworkBytes_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockRepository_UpdateSandboxNetworkBytes_Call) RunAndReturn(run func(ctx context.Context, id string, bytes uint64) error) *MockRepository_UpdateSandboxNetworkBytes_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateSandboxPersistent provides a mock function for the type MockRepository
+func (_mock *MockRepository) UpdateSandboxPersistent(ctx context.Context, id string, persistent bool) error {
+	ret := _mock.Called(ctx, id, persistent)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateSandboxPersistent")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, bool) error); ok {
+		r0 = returnFunc(ctx, id, persistent)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockRepository_UpdateSandboxPersistent_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateSandboxPersistent'
+type MockRepository_UpdateSandboxPersistent_Call struct {
+	*mock.Call
+}
+
+// UpdateSandboxPersistent is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - persistent bool
+func (_e *MockRepository_Expecter) UpdateSandboxPersistent(ctx interface{}, id interface{}, persistent interface{}) *MockRepository_UpdateSandboxPersistent_Call {
+	return &MockRepository_UpdateSandboxPersistent_Call{Call: _e.mock.On("UpdateSandboxPersistent", ctx, id, persistent)}
+}
+
+func (_c *MockRepository_UpdateSandboxPersistent_Call) Run(run func(ctx context.Context, id string, persistent bool)) *MockRepository_UpdateSandboxPersistent_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 bool
+		if args[2] != nil {
+			arg2 = args[2].(bool)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRepository_UpdateSandboxPersistent_Call) Return(err error) *MockRepository_UpdateSandboxPersistent_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockRepository_UpdateSandboxPersistent_Call) RunAndReturn(run func(ctx context.Context, id string, persistent bool) error) *MockRepository_UpdateSandboxPersistent_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateSandboxQuarantine provides a mock function for the type MockRepository
+func (_mock *MockRepository) UpdateSandboxQuarantine(ctx context.Context, id string, at *time.Time) error {
+	ret := _mock.Called(ctx, id, at)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateSandboxQuarantine")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, *time.Time) error); ok {
+		r0 = returnFunc(ctx, id, at)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockRepository_UpdateSandboxQuarantine_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateSandboxQuarantine'
+type MockRepository_UpdateSandboxQuarantine_Call struct {
+	*mock.Call
+}
+
+// UpdateSandboxQuarantine is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - at *time.Time
+func (_e *MockRepository_Expecter) UpdateSandboxQuarantine(ctx interface{}, id interface{}, at interface{}) *MockRepository_UpdateSandboxQuarantine_Call {
+	return &MockRepository_UpdateSandboxQuarantine_Call{Call: _e.mock.On("UpdateSandboxQuarantine", ctx, id, at)}
+}
+
+func (_c *MockRepository_UpdateSandboxQuarantine_Call) Run(run func(ctx context.Context, id string, at *time.Time)) *MockRepository_UpdateSandboxQuarantine_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 *time.Time
+		if args[2] != nil {
+			arg2 = args[2].(*time.Time)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRepository_UpdateSandboxQuarantine_Call) Return(err error) *MockRepository_UpdateSandboxQuarantine_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockRepository_UpdateSandboxQuarantine_Call) RunAndReturn(run func(ctx context.Context, id string, at *time.Time) error) *MockRepository_UpdateSandboxQuarantine_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -0,0 +1,255 @@
+// Package systemd manages the systemd units that start the sandboxes on the host
+// boot.
+//
+// Every sandbox gets its own oneshot unit running the sbx CLI, so the sandbox
+// lifecycle stays the one of the CLI (the locks, the session of the previous start,
+// the admission...) and systemd only decides when it runs.
+package systemd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+)
+
+// DefaultUnitDir is the directory of the system units managed by the host admin.
+const DefaultUnitDir = "/etc/systemd/system"
+
+// wantedBy is the target that pulls the sandbox units on the boot.
+const wantedBy = "multi-user.target"
+
+// Systemctl runs a systemctl command.
+type Systemctl func(ctx context.Context, args ...string) error
+
+// execSystemctl runs the host systemctl.
+func execSystemctl(ctx context.Context, args ...string) error {
+	out, err := exec.CommandContext(ctx, "systemctl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("systemctl %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// ManagerConfig is the configuration of the unit manager.
+type ManagerConfig struct {
+	// UnitDir is the directory of the unit files (default /etc/systemd/system).
+	UnitDir string
+	// Systemctl reloads systemd after the units change (default the host systemctl).
+	Systemctl Systemctl
+	Logger    log.Logger
+}
+
+func (c *ManagerConfig) defaults() error {
+	if c.UnitDir == "" {
+		c.UnitDir = DefaultUnitDir
+	}
+
+	if c.Systemctl == nil {
+		c.Systemctl = execSystemctl
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "systemd.Manager"})
+
+	return nil
+}
+
+// Manager installs and removes the boot units of the sandboxes.
+type Manager struct {
+	unitDir   string
+	systemctl Systemctl
+	logger    log.Logger
+}
+
+// NewManager returns a new unit manager.
+func NewManager(cfg ManagerConfig) (*Manager, error) {
+	if err := cfg.defaults(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Manager{
+		unitDir:   cfg.UnitDir,
+		systemctl: cfg.Systemctl,
+		logger:    cfg.Logger,
+	}, nil
+}
+
+// UnitName returns the unit name of a sandbox.
+func UnitName(sandboxID string) string {
+	return "sbx-" + sandboxID + ".service"
+}
+
+// UnitSpec is what a sandbox boot unit runs.
+type UnitSpec struct {
+	Sandbox model.Sandbox
+	// Binary is the absolute path of the sbx binary.
+	Binary string
+	// Args are the global flags of the sbx commands (e.g. --db-path), so the unit
+	// uses the same database as the one installing it. The namespace flag is set
+	// from the sandbox.
+	Args    []string
+	Restart model.BootRestartPolicy
+}
+
+func (s UnitSpec) validate() error {
+	if s.Sandbox.ID == "" {
+		return fmt.Errorf("sandbox is required: %w", model.ErrNotValid)
+	}
+	if !filepath.IsAbs(s.Binary) {
+		return fmt.Errorf("sbx binary must be an absolute path, got %q: %w", s.Binary, model.ErrNotValid)
+	}
+	return s.Restart.Validate()
+}
+
+// RenderSandboxUnit returns the unit file of a sandbox.
+//
+// The stop before the start clears the state of a sandbox the host reboot left
+// flagged as running (its VM died with the host), it's allowed to fail.
+func RenderSandboxUnit(spec UnitSpec) ([]byte, error) {
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+
+	global := append([]string{spec.Binary}, spec.Args...)
+	if spec.Sandbox.Namespace != "" {
+		global = append(global, "--namespace", spec.Sandbox.Namespace)
+	}
+	cmd := func(args ...string) string {
+		return quoteArgs(append(slices.Clone(global), args...))
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# Generated by sbx, managed with 'sbx systemd install/uninstall'.\n")
+	fmt.Fprintf(&b, "[Unit]\n")
+	fmt.Fprintf(&b, "Description=sbx sandbox %s\n", escapeSpecifiers(spec.Sandbox.Name))
+	fmt.Fprintf(&b, "Wants=network-online.target\n")
+	fmt.Fprintf(&b, "After=network-online.target\n")
+	fmt.Fprintf(&b, "\n[Service]\n")
+	fmt.Fprintf(&b, "Type=oneshot\n")
+	fmt.Fprintf(&b, "RemainAfterExit=yes\n")
+	fmt.Fprintf(&b, "ExecStartPre=-%s\n", cmd("stop", spec.Sandbox.ID))
+	fmt.Fprintf(&b, "ExecStart=%s\n", cmd("start", spec.Sandbox.ID))
+	fmt.Fprintf(&b, "ExecStop=%s\n", cmd("stop", spec.Sandbox.ID))
+	fmt.Fprintf(&b, "TimeoutStartSec=5min\n")
+	fmt.Fprintf(&b, "Restart=%s\n", spec.Restart)
+	if spec.Restart == model.BootRestartPolicyOnFailure {
+		fmt.Fprintf(&b, "RestartSec=10s\n")
+	}
+	fmt.Fprintf(&b, "\n[Install]\n")
+	fmt.Fprintf(&b, "WantedBy=%s\n", wantedBy)
+
+	return b.Bytes(), nil
+}
+
+// Enable installs the unit of a sandbox and enables it, replacing the previous one.
+//
+// The unit is enabled with the wants symlink instead of 'systemctl enable', so it
+// also works on images built without a running systemd, the reload is best effort.
+func (m *Manager) Enable(ctx context.Context, spec UnitSpec) (*model.BootUnit, error) {
+	data, err := RenderSandboxUnit(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(m.unitDir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create unit directory: %w", err)
+	}
+
+	name := UnitName(spec.Sandbox.ID)
+	path := filepath.Join(m.unitDir, name)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return nil, fmt.Errorf("could not write unit: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return nil, fmt.Errorf("could not write unit: %w", err)
+	}
+
+	wantsDir := filepath.Join(m.unitDir, wantedBy+".wants")
+	if err := os.MkdirAll(wantsDir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create wants directory: %w", err)
+	}
+	link := filepath.Join(wantsDir, name)
+	if err := os.Remove(link); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("could not enable unit: %w", err)
+	}
+	if err := os.Symlink(path, link); err != nil {
+		return nil, fmt.Errorf("could not enable unit: %w", err)
+	}
+
+	m.reload(ctx)
+
+	return &model.BootUnit{
+		Name:        name,
+		Path:        path,
+		SandboxID:   spec.Sandbox.ID,
+		SandboxName: spec.Sandbox.Name,
+		Restart:     spec.Restart,
+	}, nil
+}
+
+// Disable disables and removes the unit of a sandbox, removing a missing unit is
+// not an error. The sandbox is not stopped.
+func (m *Manager) Disable(ctx context.Context, sandboxID string) error {
+	name := UnitName(sandboxID)
+	for _, path := range []string{
+		filepath.Join(m.unitDir, wantedBy+".wants", name),
+		filepath.Join(m.unitDir, name),
+	} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("could not remove unit: %w", err)
+		}
+	}
+
+	m.reload(ctx)
+
+	return nil
+}
+
+// Enabled returns if the unit of a sandbox is installed.
+func (m *Manager) Enabled(sandboxID string) (bool, error) {
+	_, err := os.Stat(filepath.Join(m.unitDir, UnitName(sandboxID)))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("could not check unit: %w", err)
+	}
+	return true, nil
+}
+
+func (m *Manager) reload(ctx context.Context) {
+	if err := m.systemctl(ctx, "daemon-reload"); err != nil {
+		m.logger.Warningf("Could not reload systemd, run 'systemctl daemon-reload': %s", err)
+	}
+}
+
+// quoteArgs returns a command line of the Exec* settings, the args with spaces or
+// quotes are quoted and the variable expansion ($VAR) is escaped.
+func quoteArgs(args []string) string {
+	quoted := make([]string, 0, len(args))
+	for _, a := range args {
+		a = strings.ReplaceAll(escapeSpecifiers(a), "$", "$$")
+		if a == "" || strings.ContainsAny(a, " \t\"'\\;") {
+			a = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(a) + `"`
+		}
+		quoted = append(quoted, a)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// escapeSpecifiers escapes the unit specifiers (%i...).
+func escapeSpecifiers(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
@@ -0,0 +1,175 @@
+package systemd_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/systemd"
+)
+
+const testSandboxID = "01H2QWERTYASDFGZXCVBNMLKJH"
+
+func TestRenderSandboxUnit(t *testing.T) {
+	tests := map[string]struct {
+		spec    systemd.UnitSpec
+		expUnit string
+		expErr  error
+	}{
+		"A unit without restarts should start and stop the sandbox.": {
+			spec: systemd.UnitSpec{
+				Sandbox: model.Sandbox{ID: testSandboxID, Name: "my-sandbox"},
+				Binary:  "/usr/local/bin/sbx",
+				Args:    []string{"--db-path", "/var/lib/sbx/sbx.db"},
+				Restart: model.BootRestartPolicyNo,
+			},
+			expUnit: `# Generated by sbx, managed with 'sbx systemd install/uninstall'.
+[Unit]
+Description=sbx sandbox my-sandbox
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+ExecStartPre=-/usr/local/bin/sbx --db-path /var/lib/sbx/sbx.db stop 01H2QWERTYASDFGZXCVBNMLKJH
+ExecStart=/usr/local/bin/sbx --db-path /var/lib/sbx/sbx.db start 01H2QWERTYASDFGZXCVBNMLKJH
+ExecStop=/usr/local/bin/sbx --db-path /var/lib/sbx/sbx.db stop 01H2QWERTYASDFGZXCVBNMLKJH
+TimeoutStartSec=5min
+Restart=no
+
+[Install]
+WantedBy=multi-user.target
+`,
+		},
+
+		"A unit restarted on failure should wait between the restarts, escape the args and use the sandbox namespace.": {
+			spec: systemd.UnitSpec{
+				Sandbox: model.Sandbox{ID: testSandboxID, Name: "my-sandbox", Namespace: "team-a"},
+				Binary:  "/usr/local/bin/sbx",
+				Args:    []string{"--db-path", "/data/my dir/50%$HOME.db"},
+				Restart: model.BootRestartPolicyOnFailure,
+			},
+			expUnit: `# Generated by sbx, managed with 'sbx systemd install/uninstall'.
+[Unit]
+Description=sbx sandbox my-sandbox
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+ExecStartPre=-/usr/local/bin/sbx --db-path "/data/my dir/50%%$$HOME.db" --namespace team-a stop 01H2QWERTYASDFGZXCVBNMLKJH
+ExecStart=/usr/local/bin/sbx --db-path "/data/my dir/50%%$$HOME.db" --namespace team-a start 01H2QWERTYASDFGZXCVBNMLKJH
+ExecStop=/usr/local/bin/sbx --db-path "/data/my dir/50%%$$HOME.db" --namespace team-a stop 01H2QWERTYASDFGZXCVBNMLKJH
+TimeoutStartSec=5min
+Restart=on-failure
+RestartSec=10s
+
+[Install]
+WantedBy=multi-user.target
+`,
+		},
+
+		"A relative binary should fail.": {
+			spec: systemd.UnitSpec{
+				Sandbox: model.Sandbox{ID: testSandboxID, Name: "my-sandbox"},
+				Binary:  "sbx",
+				Restart: model.BootRestartPolicyNo,
+			},
+			expErr: model.ErrNotValid,
+		},
+
+		"An invalid restart policy should fail.": {
+			spec: systemd.UnitSpec{
+				Sandbox: model.Sandbox{ID: testSandboxID, Name: "my-sandbox"},
+				Binary:  "/usr/local/bin/sbx",
+				Restart: "always",
+			},
+			expErr: model.ErrNotValid,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			unit, err := systemd.RenderSandboxUnit(test.spec)
+
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expUnit, string(unit))
+			}
+		})
+	}
+}
+
+func TestManager(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	unitDir := t.TempDir()
+	var calls [][]string
+	m, err := systemd.NewManager(systemd.ManagerConfig{
+		UnitDir: unitDir,
+		Systemctl: func(ctx context.Context, args ...string) error {
+			calls = append(calls, args)
+			return errors.New("systemd not running") // Reloading is best effort.
+		},
+		Logger: log.Noop,
+	})
+	require.NoError(err)
+
+	spec := systemd.UnitSpec{
+		Sandbox: model.Sandbox{ID: testSandboxID, Name: "my-sandbox"},
+		Binary:  "/usr/local/bin/sbx",
+		Restart: model.BootRestartPolicyOnFailure,
+	}
+
+	// Enabling should install the unit and the wants symlink.
+	unit, err := m.Enable(ctx, spec)
+	require.NoError(err)
+	unitPath := filepath.Join(unitDir, "sbx-"+testSandboxID+".service")
+	assert.Equal(&model.BootUnit{
+		Name:        "sbx-" + testSandboxID + ".service",
+		Path:        unitPath,
+		SandboxID:   testSandboxID,
+		SandboxName: "my-sandbox",
+		Restart:     model.BootRestartPolicyOnFailure,
+	}, unit)
+	link, err := os.Readlink(filepath.Join(unitDir, "multi-user.target.wants", unit.Name))
+	require.NoError(err)
+	assert.Equal(unitPath, link)
+	enabled, err := m.Enabled(testSandboxID)
+	require.NoError(err)
+	assert.True(enabled)
+
+	// Enabling again should replace the unit.
+	spec.Restart = model.BootRestartPolicyNo
+	_, err = m.Enable(ctx, spec)
+	require.NoError(err)
+	data, err := os.ReadFile(unitPath)
+	require.NoError(err)
+	assert.Contains(string(data), "Restart=no\n")
+
+	// Disabling should remove the unit and the symlink, also when missing.
+	require.NoError(m.Disable(ctx, testSandboxID))
+	require.NoError(m.Disable(ctx, testSandboxID))
+	entries, err := os.ReadDir(filepath.Join(unitDir, "multi-user.target.wants"))
+	require.NoError(err)
+	assert.Empty(entries)
+	enabled, err = m.Enabled(testSandboxID)
+	require.NoError(err)
+	assert.False(enabled)
+
+	assert.Equal([][]string{{"daemon-reload"}, {"daemon-reload"}, {"daemon-reload"}, {"daemon-reload"}}, calls)
+}
@@ -0,0 +1,149 @@
+// Package tlscert manages the certificates the host side of the port forwards and
+// the ingress terminate TLS with, so the sandbox services (plain HTTP in the guest)
+// can be consumed by clients requiring https.
+package tlscert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/slok/sbx/internal/conventions"
+	"github.com/slok/sbx/internal/model"
+)
+
+const (
+	// validity is the validity of the generated certificates.
+	validity = 365 * 24 * time.Hour
+	// renewBefore regenerates the certificates that expire sooner.
+	renewBefore = 7 * 24 * time.Hour
+)
+
+// hostnames are the names of the generated certificate, the port forwards listen on
+// localhost and the ingress routes the *.localhost names.
+var hostnames = []string{"localhost", "*.localhost"}
+
+// EnsureSelfSigned returns the self-signed localhost certificate and key files of
+// the dir, generating them when missing or about to expire. The certificate is
+// kept so the clients can trust it once.
+func EnsureSelfSigned(dir string) (certFile, keyFile string, err error) {
+	certFile = filepath.Join(dir, conventions.TLSCertFile)
+	keyFile = filepath.Join(dir, conventions.TLSKeyFile)
+
+	if valid(certFile, keyFile) {
+		return certFile, keyFile, nil
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", "", fmt.Errorf("could not create tls directory: %w", err)
+	}
+
+	certPEM, keyPEM, err := generate(time.Now())
+	if err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		return "", "", fmt.Errorf("could not write tls key: %w", err)
+	}
+	if err := os.WriteFile(certFile, certPEM, 0644); err != nil {
+		return "", "", fmt.Errorf("could not write tls certificate: %w", err)
+	}
+
+	return certFile, keyFile, nil
+}
+
+// valid returns true when the certificate and key exist and the certificate is
+// not about to expire.
+func valid(certFile, keyFile string) bool {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil || len(pair.Certificate) == 0 {
+		return false
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return false
+	}
+	return time.Now().Add(renewBefore).Before(cert.NotAfter)
+}
+
+// generate returns a self-signed certificate for the localhost names and loopback
+// IPs, and its key, PEM encoded.
+func generate(now time.Time) (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not generate tls key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not generate certificate serial: %w", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"sbx"}, CommonName: "localhost"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              hostnames,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create tls certificate: %w", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not marshal tls key: %w", err)
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+// Resolve returns the TLS with its certificate and key files, the generated
+// localhost certificate of the dir when they are not set.
+func Resolve(t model.ForwardTLS, dir string) (model.ForwardTLS, error) {
+	if err := t.Validate(); err != nil {
+		return model.ForwardTLS{}, err
+	}
+	if t.CertFile != "" {
+		return t, nil
+	}
+	if dir == "" {
+		return model.ForwardTLS{}, fmt.Errorf("a tls certificate is required: %w", model.ErrNotValid)
+	}
+
+	certFile, keyFile, err := EnsureSelfSigned(dir)
+	if err != nil {
+		return model.ForwardTLS{}, fmt.Errorf("could not generate tls certificate: %w", err)
+	}
+	return model.ForwardTLS{CertFile: certFile, KeyFile: keyFile}, nil
+}
+
+// ServerConfig returns the TLS server configuration of a certificate and key files.
+func ServerConfig(certFile, keyFile string) (*tls.Config, error) {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load tls certificate: %w", err)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{pair},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
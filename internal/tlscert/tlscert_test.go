@@ -0,0 +1,61 @@
+package tlscert_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/tlscert"
+)
+
+func TestEnsureSelfSigned(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir := filepath.Join(t.TempDir(), "tls")
+
+	certFile, keyFile, err := tlscert.EnsureSelfSigned(dir)
+	require.NoError(err)
+	assert.Equal(filepath.Join(dir, "localhost.crt"), certFile)
+	assert.Equal(filepath.Join(dir, "localhost.key"), keyFile)
+
+	info, err := os.Stat(keyFile)
+	require.NoError(err)
+	assert.Equal(os.FileMode(0600), info.Mode().Perm())
+
+	// The certificate is valid for the localhost names and loopback IPs.
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	require.NoError(err)
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	require.NoError(err)
+	for _, host := range []string{"localhost", "web.localhost", "127.0.0.1", "::1"} {
+		assert.NoError(cert.VerifyHostname(host), host)
+	}
+	assert.Error(cert.VerifyHostname("example.com"))
+
+	// A valid certificate is kept.
+	certPEM, err := os.ReadFile(certFile)
+	require.NoError(err)
+	_, _, err = tlscert.EnsureSelfSigned(dir)
+	require.NoError(err)
+	got, err := os.ReadFile(certFile)
+	require.NoError(err)
+	assert.Equal(certPEM, got)
+
+	// An invalid certificate is regenerated.
+	require.NoError(os.WriteFile(certFile, []byte("broken"), 0644))
+	_, _, err = tlscert.EnsureSelfSigned(dir)
+	require.NoError(err)
+	_, err = tlscert.ServerConfig(certFile, keyFile)
+	assert.NoError(err)
+}
+
+func TestServerConfig(t *testing.T) {
+	_, err := tlscert.ServerConfig("/missing.crt", "/missing.key")
+	assert.Error(t, err)
+}
@@ -0,0 +1,232 @@
+// Package userdata renders the sandbox user data (provisioning executed on the
+// first boot) into a guest shell script.
+//
+// Two formats are supported:
+//
+//   - Shell scripts: run as is (with /bin/sh when they don't have a shebang).
+//   - Cloud-config: YAML documents starting with "#cloud-config", supporting a
+//     subset of cloud-init modules (users, write_files, packages and runcmd).
+package userdata
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/slok/sbx/internal/model"
+)
+
+const cloudConfigHeader = "#cloud-config"
+
+// Validate checks the user data can be rendered.
+func Validate(data string) error {
+	_, err := Script(data)
+	return err
+}
+
+// Script returns the guest shell script for the user data.
+func Script(data string) (string, error) {
+	if strings.TrimSpace(data) == "" {
+		return "", fmt.Errorf("user data is empty: %w", model.ErrNotValid)
+	}
+
+	if !strings.HasPrefix(data, cloudConfigHeader) {
+		if strings.HasPrefix(data, "#!") {
+			return data, nil
+		}
+		return "#!/bin/sh\n" + data, nil
+	}
+
+	cfg, err := parseCloudConfig(data)
+	if err != nil {
+		return "", err
+	}
+
+	return cfg.script(), nil
+}
+
+// cloudConfig is the supported subset of the cloud-init cloud-config format.
+type cloudConfig struct {
+	Users         []cloudUser      `yaml:"users"`
+	WriteFiles    []cloudWriteFile `yaml:"write_files"`
+	PackageUpdate bool             `yaml:"package_update"`
+	Packages      []string         `yaml:"packages"`
+	RunCmd        []runCmd         `yaml:"runcmd"`
+}
+
+type cloudUser struct {
+	Name              string   `yaml:"name"`
+	Shell             string   `yaml:"shell"`
+	Sudo              string   `yaml:"sudo"`
+	SSHAuthorizedKeys []string `yaml:"ssh_authorized_keys"`
+}
+
+type cloudWriteFile struct {
+	Path        string `yaml:"path"`
+	Content     string `yaml:"content"`
+	Encoding    string `yaml:"encoding"`
+	Permissions string `yaml:"permissions"`
+	Owner       string `yaml:"owner"`
+	Append      bool   `yaml:"append"`
+}
+
+// runCmd is a runcmd entry, a shell line or a list of arguments.
+type runCmd struct {
+	line string
+	args []string
+}
+
+func (r *runCmd) UnmarshalYAML(n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		return n.Decode(&r.line)
+	case yaml.SequenceNode:
+		return n.Decode(&r.args)
+	default:
+		return fmt.Errorf("line %d: runcmd entries must be a string or a list of arguments", n.Line)
+	}
+}
+
+func parseCloudConfig(data string) (*cloudConfig, error) {
+	var cfg cloudConfig
+	dec := yaml.NewDecoder(strings.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("invalid cloud-config: %w: %w", err, model.ErrNotValid)
+	}
+
+	for i, u := range cfg.Users {
+		if u.Name == "" {
+			return nil, fmt.Errorf("invalid cloud-config: users[%d] name is required: %w", i, model.ErrNotValid)
+		}
+	}
+	for i, f := range cfg.WriteFiles {
+		if !strings.HasPrefix(f.Path, "/") {
+			return nil, fmt.Errorf("invalid cloud-config: write_files[%d] path must be absolute: %w", i, model.ErrNotValid)
+		}
+		switch f.Encoding {
+		case "", "text/plain", "b64", "base64":
+		default:
+			return nil, fmt.Errorf("invalid cloud-config: write_files[%d] encoding %q is not supported: %w", i, f.Encoding, model.ErrNotValid)
+		}
+		if _, err := f.content(); err != nil {
+			return nil, fmt.Errorf("invalid cloud-config: write_files[%d]: %w: %w", i, err, model.ErrNotValid)
+		}
+	}
+
+	return &cfg, nil
+}
+
+func (f cloudWriteFile) content() ([]byte, error) {
+	if f.Encoding == "b64" || f.Encoding == "base64" {
+		return base64.StdEncoding.DecodeString(f.Content)
+	}
+	return []byte(f.Content), nil
+}
+
+func (c cloudConfig) script() string {
+	var b bytes.Buffer
+	b.WriteString("#!/bin/sh\n")
+	b.WriteString("# Generated by sbx from cloud-config user data.\n")
+	b.WriteString("set -eu\n")
+
+	for _, u := range c.Users {
+		name := quote(u.Name)
+		shell := u.Shell
+		if shell == "" {
+			shell = "/bin/sh"
+		}
+		fmt.Fprintf(&b, "\n# User %s.\n", u.Name)
+		fmt.Fprintf(&b, "if ! id -u %s >/dev/null 2>&1; then\n", name)
+		fmt.Fprintf(&b, "  if command -v useradd >/dev/null 2>&1; then useradd -m -s %s %s; else adduser -D -s %s %s; fi\n", quote(shell), name, quote(shell), name)
+		b.WriteString("fi\n")
+		if u.Sudo != "" {
+			b.WriteString("mkdir -p /etc/sudoers.d\n")
+			fmt.Fprintf(&b, "printf '%%s %%s\\n' %s %s > /etc/sudoers.d/%s\n", name, quote(u.Sudo), name)
+			fmt.Fprintf(&b, "chmod 440 /etc/sudoers.d/%s\n", name)
+		}
+		if len(u.SSHAuthorizedKeys) > 0 {
+			fmt.Fprintf(&b, "home=$(getent passwd %s | cut -d: -f6)\n", name)
+			b.WriteString("mkdir -p \"$home/.ssh\"\n")
+			for _, k := range u.SSHAuthorizedKeys {
+				fmt.Fprintf(&b, "echo %s >> \"$home/.ssh/authorized_keys\"\n", quote(k))
+			}
+			b.WriteString("chmod 700 \"$home/.ssh\"\n")
+			b.WriteString("chmod 600 \"$home/.ssh/authorized_keys\"\n")
+			fmt.Fprintf(&b, "chown -R %s \"$home/.ssh\"\n", name)
+		}
+	}
+
+	for _, f := range c.WriteFiles {
+		content, _ := f.content()
+		path := quote(f.Path)
+		redirect := ">"
+		if f.Append {
+			redirect = ">>"
+		}
+		fmt.Fprintf(&b, "\n# File %s.\n", f.Path)
+		fmt.Fprintf(&b, "mkdir -p \"$(dirname %s)\"\n", path)
+		fmt.Fprintf(&b, "echo %s | base64 -d %s %s\n", base64.StdEncoding.EncodeToString(content), redirect, path)
+		if f.Permissions != "" {
+			fmt.Fprintf(&b, "chmod %s %s\n", quote(f.Permissions), path)
+		}
+		if f.Owner != "" {
+			fmt.Fprintf(&b, "chown %s %s\n", quote(f.Owner), path)
+		}
+	}
+
+	if c.PackageUpdate || len(c.Packages) > 0 {
+		pkgs := make([]string, 0, len(c.Packages))
+		for _, p := range c.Packages {
+			pkgs = append(pkgs, quote(p))
+		}
+		b.WriteString("\n# Packages.\n")
+		b.WriteString("if command -v apk >/dev/null 2>&1; then\n")
+		if c.PackageUpdate {
+			b.WriteString("  apk update\n")
+		}
+		if len(pkgs) > 0 {
+			fmt.Fprintf(&b, "  apk add --no-cache %s\n", strings.Join(pkgs, " "))
+		}
+		b.WriteString("elif command -v apt-get >/dev/null 2>&1; then\n")
+		b.WriteString("  apt-get update\n")
+		if len(pkgs) > 0 {
+			fmt.Fprintf(&b, "  DEBIAN_FRONTEND=noninteractive apt-get install -y %s\n", strings.Join(pkgs, " "))
+		}
+		b.WriteString("elif command -v dnf >/dev/null 2>&1; then\n")
+		if len(pkgs) > 0 {
+			fmt.Fprintf(&b, "  dnf install -y %s\n", strings.Join(pkgs, " "))
+		} else {
+			b.WriteString("  dnf makecache\n")
+		}
+		b.WriteString("else\n")
+		b.WriteString("  echo 'no supported package manager found' >&2\n")
+		b.WriteString("  exit 1\n")
+		b.WriteString("fi\n")
+	}
+
+	if len(c.RunCmd) > 0 {
+		b.WriteString("\n# Commands.\n")
+		for _, r := range c.RunCmd {
+			if r.args == nil {
+				b.WriteString(r.line + "\n")
+				continue
+			}
+			args := make([]string, 0, len(r.args))
+			for _, a := range r.args {
+				args = append(args, quote(a))
+			}
+			b.WriteString(strings.Join(args, " ") + "\n")
+		}
+	}
+
+	return b.String()
+}
+
+// quote returns a shell single quoted string.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
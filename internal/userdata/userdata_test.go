@@ -0,0 +1,164 @@
+package userdata_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/userdata"
+)
+
+func TestScript(t *testing.T) {
+	tests := map[string]struct {
+		data      string
+		expScript string
+		expErr    error
+	}{
+		"A shell script with shebang should be used as is.": {
+			data:      "#!/bin/bash\necho hello\n",
+			expScript: "#!/bin/bash\necho hello\n",
+		},
+
+		"A shell script without shebang should run with sh.": {
+			data:      "apk add git\n",
+			expScript: "#!/bin/sh\napk add git\n",
+		},
+
+		"Empty user data should fail.": {
+			data:   "  \n",
+			expErr: model.ErrNotValid,
+		},
+
+		"A cloud-config should be rendered as a script.": {
+			data: `#cloud-config
+users:
+  - name: dev
+    shell: /bin/bash
+    sudo: ALL=(ALL) NOPASSWD:ALL
+    ssh_authorized_keys: ["ssh-ed25519 AAAA dev@host"]
+write_files:
+  - path: /etc/motd
+    content: "it's sbx\n"
+    permissions: "0644"
+package_update: true
+packages: [git, curl]
+runcmd:
+  - echo done > /tmp/done
+  - [touch, "/tmp/with space"]
+`,
+			expScript: `#!/bin/sh
+# Generated by sbx from cloud-config user data.
+set -eu
+
+# User dev.
+if ! id -u 'dev' >/dev/null 2>&1; then
+  if command -v useradd >/dev/null 2>&1; then useradd -m -s '/bin/bash' 'dev'; else adduser -D -s '/bin/bash' 'dev'; fi
+fi
+mkdir -p /etc/sudoers.d
+printf '%s %s\n' 'dev' 'ALL=(ALL) NOPASSWD:ALL' > /etc/sudoers.d/'dev'
+chmod 440 /etc/sudoers.d/'dev'
+home=$(getent passwd 'dev' | cut -d: -f6)
+mkdir -p "$home/.ssh"
+echo 'ssh-ed25519 AAAA dev@host' >> "$home/.ssh/authorized_keys"
+chmod 700 "$home/.ssh"
+chmod 600 "$home/.ssh/authorized_keys"
+chown -R 'dev' "$home/.ssh"
+
+# File /etc/motd.
+mkdir -p "$(dirname '/etc/motd')"
+echo aXQncyBzYngK | base64 -d > '/etc/motd'
+chmod '0644' '/etc/motd'
+
+# Packages.
+if command -v apk >/dev/null 2>&1; then
+  apk update
+  apk add --no-cache 'git' 'curl'
+elif command -v apt-get >/dev/null 2>&1; then
+  apt-get update
+  DEBIAN_FRONTEND=noninteractive apt-get install -y 'git' 'curl'
+elif command -v dnf >/dev/null 2>&1; then
+  dnf install -y 'git' 'curl'
+else
+  echo 'no supported package manager found' >&2
+  exit 1
+fi
+
+# Commands.
+echo done > /tmp/done
+'touch' '/tmp/with space'
+`,
+		},
+
+		"A cloud-config with unsupported modules should fail.": {
+			data:   "#cloud-config\nbootcmd: [echo hi]\n",
+			expErr: model.ErrNotValid,
+		},
+
+		"A cloud-config with relative file paths should fail.": {
+			data:   "#cloud-config\nwrite_files:\n  - path: etc/motd\n    content: hi\n",
+			expErr: model.ErrNotValid,
+		},
+
+		"A cloud-config with invalid base64 content should fail.": {
+			data:   "#cloud-config\nwrite_files:\n  - path: /etc/motd\n    encoding: b64\n    content: '%%%'\n",
+			expErr: model.ErrNotValid,
+		},
+
+		"A cloud-config with users without name should fail.": {
+			data:   "#cloud-config\nusers:\n  - shell: /bin/sh\n",
+			expErr: model.ErrNotValid,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			got, err := userdata.Script(test.data)
+
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expScript, got)
+			}
+		})
+	}
+}
+
+func TestScriptRun(t *testing.T) {
+	if _, err := exec.LookPath("base64"); err != nil {
+		t.Skip("base64 is required")
+	}
+
+	dir := t.TempDir()
+	data := `#cloud-config
+write_files:
+  - path: ` + filepath.Join(dir, "sub", "file") + `
+    content: "line 1\nit's line 2\n"
+  - path: ` + filepath.Join(dir, "sub", "file") + `
+    encoding: b64
+    content: bGluZSAzCg==
+    append: true
+runcmd:
+  - [sh, -c, 'echo ran > "$0"', ` + filepath.Join(dir, "ran") + `]
+`
+
+	script, err := userdata.Script(data)
+	require.NoError(t, err)
+
+	out, err := exec.Command("sh", "-c", script).CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	got, err := os.ReadFile(filepath.Join(dir, "sub", "file"))
+	require.NoError(t, err)
+	assert.Equal(t, "line 1\nit's line 2\nline 3\n", string(got))
+
+	got, err = os.ReadFile(filepath.Join(dir, "ran"))
+	require.NoError(t, err)
+	assert.Equal(t, "ran\n", string(got))
+}
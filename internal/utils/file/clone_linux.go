@@ -0,0 +1,28 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// CloneFile makes dst a copy-on-write clone (reflink) of src with the FICLONE ioctl,
+// sharing the data blocks until any of them is written. It's instant regardless of
+// the file size. Supported by btrfs, XFS (with reflink=1) and ZFS (2.2+ block cloning).
+//
+// Returns an error wrapping ErrCloneUnsupported when the filesystem doesn't support
+// it or the files are on different filesystems, allowing the caller to fall back
+// to a regular copy.
+func CloneFile(src, dst *os.File) error {
+	err := unix.IoctlFileClone(int(dst.Fd()), int(src.Fd()))
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, unix.EOPNOTSUPP) || errors.Is(err, unix.EXDEV) || errors.Is(err, unix.EINVAL) ||
+		errors.Is(err, unix.ENOTTY) || errors.Is(err, unix.ENOSYS) {
+		return fmt.Errorf("FICLONE: %v: %w", err, ErrCloneUnsupported)
+	}
+	return fmt.Errorf("cloning file: %w", err)
+}
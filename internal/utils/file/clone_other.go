@@ -0,0 +1,13 @@
+//go:build !linux
+
+package file
+
+import (
+	"fmt"
+	"os"
+)
+
+// CloneFile is not supported on non-Linux platforms.
+func CloneFile(_, _ *os.File) error {
+	return fmt.Errorf("not available on this platform: %w", ErrCloneUnsupported)
+}
@@ -1,4 +1,5 @@
-// Package file provides file utility functions including sparse-aware file copying.
+// Package file provides file utility functions including sparse-aware and
+// copy-on-write file copying.
 package file
 
 import "errors"
@@ -6,3 +7,7 @@ import "errors"
 // ErrSparseUnsupported is returned when the filesystem or kernel does not support
 // SEEK_DATA/SEEK_HOLE for sparse-aware file copying.
 var ErrSparseUnsupported = errors.New("sparse copy not supported")
+
+// ErrCloneUnsupported is returned when the filesystem doesn't support copy-on-write
+// file clones (reflinks), or the files are on different filesystems.
+var ErrCloneUnsupported = errors.New("file clone not supported")
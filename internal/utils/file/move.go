@@ -0,0 +1,105 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// MoveDir moves a directory tree to dst, which must not exist. It's renamed when
+// both are on the same filesystem, otherwise the tree is copied (keeping the sparse
+// files sparse) and then removed. Sockets and other special files are not copied.
+func MoveDir(ctx context.Context, src, dst string) error {
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("destination %s already exists", dst)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("creating destination parent: %w", err)
+	}
+
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, syscall.EXDEV) {
+		return fmt.Errorf("renaming %s: %w", src, err)
+	}
+
+	// Different filesystems, copy the tree.
+	if err := copyTree(ctx, src, dst); err != nil {
+		os.RemoveAll(dst)
+		return fmt.Errorf("copying %s: %w", src, err)
+	}
+	if err := os.RemoveAll(src); err != nil {
+		return fmt.Errorf("removing %s: %w", src, err)
+	}
+
+	return nil
+}
+
+func copyTree(ctx context.Context, src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			return copyRegularFile(ctx, path, target, info.Mode().Perm())
+		default:
+			return nil
+		}
+	})
+}
+
+func copyRegularFile(ctx context.Context, src, dst string, perm fs.FileMode) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	dstFile, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	defer dstFile.Close()
+
+	if err := CopyFileSparse(ctx, srcFile, dstFile); err != nil {
+		if !errors.Is(err, ErrSparseUnsupported) {
+			return err
+		}
+		if _, err := io.Copy(dstFile, srcFile); err != nil {
+			return err
+		}
+	}
+
+	return dstFile.Close()
+}
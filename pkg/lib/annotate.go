@@ -0,0 +1,37 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/app/annotate"
+)
+
+// AnnotateSandbox sets and removes the annotations of a sandbox in any status.
+// Annotations are free-form metadata (ticket URLs, owners, experiment IDs...)
+// returned in [Sandbox.Annotations], they don't change the sandbox.
+//
+// Returns [ErrNotFound] if the sandbox does not exist, or [ErrNotValid] if a key
+// is not valid, a key is set and removed at the same time or the annotations
+// are too big.
+func (c *Client) AnnotateSandbox(ctx context.Context, nameOrID string, opts AnnotateSandboxOpts) (*Sandbox, error) {
+	svc, err := annotate.NewService(annotate.ServiceConfig{
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	result, err := svc.Run(ctx, annotate.Request{
+		NameOrID: nameOrID,
+		Set:      opts.Set,
+		Remove:   opts.Remove,
+	})
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	out := fromInternalSandbox(*result)
+	return &out, nil
+}
@@ -0,0 +1,94 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/slok/sbx/internal/app/bootunit"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/systemd"
+)
+
+// EnableBootPersistence installs a systemd unit that starts the sandbox when the
+// host boots, replacing the previous one. The unit runs the sbx CLI with the
+// database, VMs directory and namespace of the client, the sandbox starts with
+// the session of its previous start (see [Client.StartSandbox]).
+//
+// The unit is installed in [Config].SystemdUnitDir and enabled in the
+// multi-user target, writing it usually requires root. It doesn't start the
+// sandbox now, the sandbox keeps its current status.
+//
+// Returns [ErrNotFound] if the sandbox does not exist, [ErrNotValid] if the
+// options are invalid.
+func (c *Client) EnableBootPersistence(ctx context.Context, nameOrID string, opts BootPersistenceOpts) (*BootUnit, error) {
+	binary := opts.Binary
+	if binary == "" {
+		path, err := exec.LookPath("sbx")
+		if err != nil {
+			return nil, fmt.Errorf("could not find the sbx binary, set the binary option: %w", ErrNotValid)
+		}
+		binary = path
+	}
+	binary, err := filepath.Abs(binary)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sbx binary %q: %w", binary, ErrNotValid)
+	}
+
+	svc, err := c.newBootUnitService()
+	if err != nil {
+		return nil, err
+	}
+
+	unit, err := svc.Run(ctx, bootunit.Request{
+		NameOrID: nameOrID,
+		Binary:   binary,
+		Args:     []string{"--db-path", c.dbPath, "--vms-dir", c.vmsDir},
+		Restart:  model.BootRestartPolicy(opts.Restart),
+	})
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	result := fromInternalBootUnit(*unit)
+	return &result, nil
+}
+
+// DisableBootPersistence removes the systemd unit installed by
+// [Client.EnableBootPersistence], the sandbox no longer starts when the host
+// boots. It doesn't stop the sandbox, disabling a sandbox without unit does
+// nothing.
+//
+// Returns [ErrNotFound] if the sandbox does not exist.
+func (c *Client) DisableBootPersistence(ctx context.Context, nameOrID string) error {
+	svc, err := c.newBootUnitService()
+	if err != nil {
+		return err
+	}
+
+	if _, err := svc.Run(ctx, bootunit.Request{NameOrID: nameOrID, Disable: true}); err != nil {
+		return mapError(err, ResourceKindSandbox, nameOrID)
+	}
+	return nil
+}
+
+func (c *Client) newBootUnitService() (*bootunit.Service, error) {
+	manager, err := systemd.NewManager(systemd.ManagerConfig{
+		UnitDir: c.systemdUnitDir,
+		Logger:  c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create systemd manager: %w", err)
+	}
+
+	svc, err := bootunit.NewService(bootunit.ServiceConfig{
+		Repository: c.repo,
+		Manager:    manager,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+	return svc, nil
+}
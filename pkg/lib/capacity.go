@@ -0,0 +1,61 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/app/hostcapacity"
+	"github.com/slok/sbx/internal/app/reservations"
+)
+
+// HostCapacity returns the host capacity and the resources allocated to the
+// sandboxes, using the overcommit ratios of [Config].Admission.
+func (c *Client) HostCapacity(ctx context.Context) (*HostCapacity, error) {
+	svc, err := hostcapacity.NewService(hostcapacity.ServiceConfig{
+		Planner: c.planner,
+		Logger:  c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	hc, err := svc.Run(ctx)
+	if err != nil {
+		return nil, mapError(err, "", "")
+	}
+
+	result := fromInternalHostCapacity(*hc)
+	return &result, nil
+}
+
+// Reservations returns the resources reserved by the sandboxes of all the
+// namespaces, sorted by namespace and name.
+//
+// The reservations are also written as JSON manifests in the reservations
+// directory of [Config].DataDir (one <sandbox-id>.json file per sandbox), for the
+// external schedulers (e.g. Nomad, systemd units) placing other workloads on the
+// host. The client updates the manifest of a sandbox on every change it makes to
+// it, Reservations syncs all of them with the database, writing the changes made
+// by other clients (e.g. the sbx CLI) and removing the manifests of the removed
+// sandboxes.
+func (c *Client) Reservations(ctx context.Context) ([]Reservation, error) {
+	svc, err := reservations.NewService(reservations.ServiceConfig{
+		Repository: c.repo,
+		Store:      c.reservations,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	rs, err := svc.Run(ctx)
+	if err != nil {
+		return nil, mapError(err, "", "")
+	}
+
+	result := make([]Reservation, 0, len(rs))
+	for _, r := range rs {
+		result = append(result, fromInternalReservation(r))
+	}
+	return result, nil
+}
@@ -0,0 +1,60 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/slok/sbx/internal/app/capture"
+	"github.com/slok/sbx/internal/model"
+)
+
+// CaptureTraffic captures the packets of a running sandbox network interface on its
+// host TAP device and writes them to w as a pcap stream (readable by Wireshark or
+// tcpdump), to debug the egress issues or for forensics. The capture runs until the
+// context ends or one of the opts limits is reached, the stream always ends on a
+// packet boundary. Pass nil opts to capture all the packets of the primary
+// interface. The host needs tcpdump.
+//
+// Returns [ErrNotFound] if the sandbox or the interface does not exist, and
+// [ErrNotValid] if the sandbox is not running or the opts are not valid.
+func (c *Client) CaptureTraffic(ctx context.Context, nameOrID string, w io.Writer, opts *CaptureOpts) (*CaptureResult, error) {
+	sb, err := c.getInternalSandbox(ctx, nameOrID)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	eng, err := c.newEngine(sb.Config)
+	if err != nil {
+		return nil, mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, nameOrID)
+	}
+
+	svc, err := capture.NewService(capture.ServiceConfig{
+		Engine:     eng,
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	req := capture.Request{NameOrID: sb.ID, Output: w}
+	if opts != nil {
+		req.Opts = model.CaptureOpts{
+			Interface:  opts.Interface,
+			Filter:     opts.Filter,
+			Duration:   opts.Duration,
+			MaxBytes:   opts.MaxBytes,
+			MaxPackets: opts.MaxPackets,
+			SnapLen:    opts.SnapLen,
+		}
+	}
+
+	result, err := svc.Run(ctx, req)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	out := fromInternalCaptureResult(*result)
+	return &out, nil
+}
@@ -0,0 +1,108 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/app/clipboard"
+)
+
+// PushClipboard replaces the clipboard of a running sandbox with data. The
+// clipboard is the /tmp/sbx/clipboard file of the sandbox, so the sandbox
+// programs read it like any file.
+//
+// Returns [ErrNotFound] if the sandbox does not exist, or [ErrNotValid] if the
+// sandbox is not running.
+func (c *Client) PushClipboard(ctx context.Context, nameOrID string, data []byte) error {
+	svc, err := c.newClipboardService(ctx, nameOrID)
+	if err != nil {
+		return err
+	}
+
+	if err := svc.Push(ctx, clipboard.PushRequest{NameOrID: nameOrID, Data: data}); err != nil {
+		return mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	return nil
+}
+
+// PullClipboard returns the clipboard of a running sandbox, empty when nothing
+// was pushed or written to /tmp/sbx/clipboard in the sandbox.
+//
+// Returns [ErrNotFound] if the sandbox does not exist, or [ErrNotValid] if the
+// sandbox is not running.
+func (c *Client) PullClipboard(ctx context.Context, nameOrID string) ([]byte, error) {
+	svc, err := c.newClipboardService(ctx, nameOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := svc.Pull(ctx, nameOrID)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	return data, nil
+}
+
+// DropFiles copies local files or directories into the /tmp/sbx/drop directory
+// of a running sandbox, keeping their names, and returns their sandbox paths.
+//
+// Returns [ErrNotFound] if the sandbox does not exist, or [ErrNotValid] if the
+// sandbox is not running or a local path does not exist.
+func (c *Client) DropFiles(ctx context.Context, nameOrID string, localPaths ...string) ([]string, error) {
+	svc, err := c.newClipboardService(ctx, nameOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := svc.Drop(ctx, clipboard.DropRequest{NameOrID: nameOrID, Paths: localPaths})
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	return paths, nil
+}
+
+// PickupFiles copies the entries of the /tmp/sbx/drop directory of a running
+// sandbox to a local directory and returns their local paths.
+//
+// Returns [ErrNotFound] if the sandbox does not exist, or [ErrNotValid] if the
+// sandbox is not running.
+func (c *Client) PickupFiles(ctx context.Context, nameOrID string, localDir string) ([]string, error) {
+	svc, err := c.newClipboardService(ctx, nameOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := svc.Pickup(ctx, clipboard.PickupRequest{NameOrID: nameOrID, LocalDir: localDir})
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	return paths, nil
+}
+
+// newClipboardService returns the clipboard service using the engine of the sandbox.
+func (c *Client) newClipboardService(ctx context.Context, nameOrID string) (*clipboard.Service, error) {
+	sb, err := c.getInternalSandbox(ctx, nameOrID)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	eng, err := c.newEngine(sb.Config)
+	if err != nil {
+		return nil, mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, nameOrID)
+	}
+
+	svc, err := clipboard.NewService(clipboard.ServiceConfig{
+		Engine:     eng,
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	return svc, nil
+}
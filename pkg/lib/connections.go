@@ -0,0 +1,44 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/app/connections"
+)
+
+// Connections returns the active network connections of a running sandbox, the
+// ones opened by it and to it, read from the host connection tracking. The
+// destination domain is set when the sandbox egress proxy forwards the connection
+// or answered the destination address to a DNS query of the sandbox. Call it
+// periodically for a live view of the sandbox traffic.
+//
+// Returns [ErrNotFound] if the sandbox does not exist and [ErrNotValid] if it is
+// not running.
+func (c *Client) Connections(ctx context.Context, nameOrID string) ([]Connection, error) {
+	sb, err := c.getInternalSandbox(ctx, nameOrID)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	eng, err := c.newEngine(sb.Config)
+	if err != nil {
+		return nil, mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, nameOrID)
+	}
+
+	svc, err := connections.NewService(connections.ServiceConfig{
+		Engine:     eng,
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	conns, err := svc.Run(ctx, connections.Request{NameOrID: sb.ID})
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	return fromInternalConnections(conns), nil
+}
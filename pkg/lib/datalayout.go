@@ -0,0 +1,72 @@
+package lib
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/app/datamigrate"
+	"github.com/slok/sbx/internal/image"
+)
+
+// DataLayout returns the directories of the client sandbox data.
+func (c *Client) DataLayout() DataLayout {
+	return DataLayout{
+		VMsDir:       c.vmsDir,
+		ImagesDir:    c.imagesDir,
+		SnapshotsDir: c.snapshotsDir,
+	}
+}
+
+// MigrateDataLayout moves the sandbox VMs, images and snapshots of the client data
+// layout ([Client.DataLayout]) to a new one, e.g. to place the VM rootfs copies on a
+// faster filesystem than the database, and rewrites the sandbox paths pointing to
+// them. The directories on different filesystems are copied and then removed. It's
+// an administration API, all the sandboxes must be stopped.
+//
+// The client keeps using the old layout, create a new client with the new
+// directories in its [Config] afterwards.
+//
+// Returns [ErrNotValid] if the client is not scoped to [AllNamespaces], the new
+// directories are not absolute or a sandbox is running.
+func (c *Client) MigrateDataLayout(ctx context.Context, opts MigrateDataLayoutOpts) ([]DataMove, error) {
+	if c.namespace != AllNamespaces {
+		return nil, fmt.Errorf("migrating the data layout requires a client of all the namespaces: %w", ErrNotValid)
+	}
+
+	to := DataLayout{
+		VMsDir:       cmp.Or(opts.To.VMsDir, c.vmsDir),
+		ImagesDir:    cmp.Or(opts.To.ImagesDir, c.imagesDir),
+		SnapshotsDir: cmp.Or(opts.To.SnapshotsDir, opts.To.ImagesDir, c.snapshotsDir),
+	}
+
+	imgMgr, err := image.NewLocalImageManager(image.LocalImageManagerConfig{
+		ImagesDir:    c.imagesDir,
+		SnapshotsDir: c.snapshotsDir,
+		Logger:       c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create image manager: %w", err)
+	}
+
+	svc, err := datamigrate.NewService(datamigrate.ServiceConfig{
+		Repository:   c.repo,
+		ImageManager: imgMgr,
+		ImageMover:   imgMgr,
+		Logger:       c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	moves, err := svc.Run(ctx, datamigrate.Request{
+		From:   toInternalDataLayout(c.DataLayout()),
+		To:     toInternalDataLayout(to),
+		DryRun: opts.DryRun,
+	})
+	if err != nil {
+		return fromInternalDataMoves(moves), mapError(err, "", "")
+	}
+
+	return fromInternalDataMoves(moves), nil
+}
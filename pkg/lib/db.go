@@ -0,0 +1,53 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/slok/sbx/internal/app/dbcompact"
+	"github.com/slok/sbx/internal/log"
+	"github.com/slok/sbx/internal/storage"
+)
+
+// CompactDB checkpoints the database write-ahead log, checks the database
+// integrity and rebuilds it reclaiming the space of the deleted rows (VACUUM).
+// A database with integrity problems is not rebuilt, they are returned in
+// [DBCompactResult].IntegrityErrors.
+//
+// The database is shared by all the namespaces. Other clients can keep using it,
+// their writes wait while it's rebuilt.
+func (c *Client) CompactDB(ctx context.Context) (*DBCompactResult, error) {
+	svc, err := dbcompact.NewService(dbcompact.ServiceConfig{
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	res, err := svc.Run(ctx)
+	if err != nil {
+		return nil, mapError(err, "", "")
+	}
+
+	out := fromInternalDBCompactResult(*res)
+	return &out, nil
+}
+
+// checkpointDB checkpoints the database write-ahead log every interval until stop
+// is closed, so the log of long-running clients doesn't grow forever.
+func checkpointDB(m storage.Maintainer, interval time.Duration, stop <-chan struct{}, logger log.Logger) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+			if err := m.Checkpoint(context.Background()); err != nil {
+				logger.Warningf("Could not checkpoint database: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/slok/sbx/internal/app/diagnostics"
+	"github.com/slok/sbx/internal/model"
+)
+
+// CollectDiagnostics writes a diagnostics bundle (gzip compressed tarball) to w, to
+// attach to bug reports. It has the sbx and host information, the [Client.Doctor]
+// checks, the sandbox list, the host nftables ruleset and, when
+// [CollectDiagnosticsOpts].SandboxNameOrID is set, the sandbox configuration and the
+// tails of its engine and egress proxy logs. The parts that can't be collected are
+// listed in the bundle errors.txt file.
+//
+// The bundle is sanitized (home directory, hostname, user data and secret looking
+// values are redacted), review it before sharing anyway.
+//
+// Returns [ErrNotFound] if the sandbox does not exist.
+func (c *Client) CollectDiagnostics(ctx context.Context, w io.Writer, opts *CollectDiagnosticsOpts) error {
+	var o CollectDiagnosticsOpts
+	if opts != nil {
+		o = *opts
+	}
+
+	cfg := model.SandboxConfig{}
+	if c.engineType != EngineFake {
+		cfg.FirecrackerEngine = &model.FirecrackerEngineConfig{}
+	}
+	eng, err := c.newEngine(cfg)
+	if err != nil {
+		return mapError(fmt.Errorf("could not create engine: %w", err), "", "")
+	}
+
+	svc, err := diagnostics.NewService(diagnostics.ServiceConfig{
+		Engine:     eng,
+		Repository: c.repo,
+		Logger:     c.logger,
+		VMsDir:     c.vmsDir,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	err = svc.Run(ctx, diagnostics.Request{
+		NameOrID:    o.SandboxNameOrID,
+		MaxLogBytes: o.MaxLogBytes,
+		Output:      w,
+	})
+	if err != nil {
+		return mapError(err, ResourceKindSandbox, o.SandboxNameOrID)
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/app/dnsevents"
+	"github.com/slok/sbx/internal/app/dnsstats"
+	"github.com/slok/sbx/internal/model"
+)
+
+// DNSEvents returns the DNS queries made by a sandbox, oldest first. Each query
+// has the egress policy decision and the upstream resolver latency. The queries
+// are recorded by the egress DNS proxy, so only the sandboxes with an egress
+// policy have them, and they are kept across restarts until the sandbox is removed.
+// Pass nil opts to get all the events, see [DNSEventsOpts].
+//
+// Returns [ErrNotFound] if the sandbox does not exist.
+func (c *Client) DNSEvents(ctx context.Context, nameOrID string, opts *DNSEventsOpts) ([]DNSEvent, error) {
+	sb, err := c.getInternalSandbox(ctx, nameOrID)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	eng, err := c.newEngine(sb.Config)
+	if err != nil {
+		return nil, mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, nameOrID)
+	}
+
+	svc, err := dnsevents.NewService(dnsevents.ServiceConfig{
+		Engine:     eng,
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	req := dnsevents.Request{NameOrID: nameOrID}
+	if opts != nil {
+		req.Filter = model.DNSEventFilter{Since: opts.Since, Domain: opts.Domain, Limit: opts.Limit}
+	}
+
+	events, err := svc.Run(ctx, req)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	return fromInternalDNSEvents(events), nil
+}
+
+// DNSStats returns the DNS queries made by a sandbox aggregated per domain, see
+// [Client.DNSEvents]. Pass nil opts to aggregate all the events, see [DNSStatsOpts].
+//
+// Returns [ErrNotFound] if the sandbox does not exist.
+func (c *Client) DNSStats(ctx context.Context, nameOrID string, opts *DNSStatsOpts) (*DNSStats, error) {
+	sb, err := c.getInternalSandbox(ctx, nameOrID)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	eng, err := c.newEngine(sb.Config)
+	if err != nil {
+		return nil, mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, nameOrID)
+	}
+
+	svc, err := dnsstats.NewService(dnsstats.ServiceConfig{
+		Engine:     eng,
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	req := dnsstats.Request{NameOrID: nameOrID}
+	if opts != nil {
+		req.Since = opts.Since
+	}
+
+	stats, err := svc.Run(ctx, req)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	out := fromInternalDNSStats(*stats)
+	return &out, nil
+}
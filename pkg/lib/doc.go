@@ -28,9 +28,20 @@
 //	// Start, exec, stop.
 //	client.StartSandbox(ctx, "my-sandbox", nil)
 //	client.Exec(ctx, "my-sandbox", []string{"echo", "hello"}, nil)
-//	client.StopSandbox(ctx, "my-sandbox")
+//	client.StopSandbox(ctx, "my-sandbox", nil)
 //	client.RemoveSandbox(ctx, "my-sandbox", false)
 //
+// [Client.RestartSandbox] stops and starts a running sandbox in place, with the
+// environment and egress policy of its previous start:
+//
+//	sb, err := client.RestartSandbox(ctx, "my-sandbox", nil)
+//
+// [Client.CreateAndStartSandbox] creates and starts a sandbox as a single
+// operation, the created sandbox is removed when the start fails or the context
+// is canceled:
+//
+//	sb, err := client.CreateAndStartSandbox(ctx, opts, nil)
+//
 // # Engines
 //
 // The SDK supports two engine types:
@@ -40,12 +51,141 @@
 //   - [EngineFake]: In-memory fake engine for unit testing. No real infrastructure
 //     needed. Set [Config].Engine to [EngineFake] to use it.
 //
+// # Waiting for Conditions
+//
+// Block until a sandbox reaches a [WaitCondition] instead of polling its status:
+//
+//	client.StartSandbox(ctx, "my-sandbox", nil)
+//	if _, err := client.WaitFor(ctx, "my-sandbox", lib.WaitConditionSSHReady, time.Minute); err != nil {
+//	    log.Fatal(err)
+//	}
+//
+// # Cloning
+//
+// Clone a stopped sandbox (rootfs and config) into a new one, optionally overriding resources:
+//
+//	client.StopSandbox(ctx, "my-sandbox", nil)
+//	clone, err := client.CloneSandbox(ctx, "my-sandbox", "my-sandbox-2", &lib.CloneSandboxOpts{
+//	    Resources: lib.Resources{MemoryMB: 2048},
+//	})
+//
+// # Progress
+//
+// Follow the steps of the long operations ([Client.CreateSandbox],
+// [Client.StartSandbox], [Client.PullImage] and [Client.CreateImageFromSandbox])
+// with a [ProgressReporter]:
+//
+//	progress := lib.ProgressReporterFunc(func(ev lib.ProgressEvent) {
+//	    fmt.Printf("%s (%.0f%%)\n", ev.Message, ev.Percent)
+//	})
+//	client.StartSandbox(ctx, "my-sandbox", &lib.StartSandboxOpts{Progress: progress})
+//
+// # Bulk Selection
+//
+// Select sandboxes by name with a glob or a "re:" prefixed regular expression to
+// act on many of them (e.g. generated test fleets):
+//
+//	sandboxes, _ := client.SelectSandboxes(ctx, "ci-*")
+//	for _, sb := range sandboxes {
+//	    _, _ = client.RemoveSandbox(ctx, sb.ID, true)
+//	}
+//
+// # Command Execution
+//
+// Every [ExecResult] includes the exit code, timing and output byte counts. Set
+// [ExecOpts].CaptureOutput to get stdout and stderr back as strings (up to
+// [ExecOpts].CaptureMaxBytes per stream):
+//
+//	res, _ := client.Exec(ctx, "my-sandbox", []string{"uname", "-a"}, &lib.ExecOpts{CaptureOutput: true})
+//	fmt.Println(res.ExitCode, res.Duration, res.Stdout)
+//
+// [ExecOpts].MaxOutputBytes bounds the output of runaway commands, the output
+// beyond it is discarded or the command killed ([ExecOpts].OutputLimitPolicy), and
+// [ExecResult].OutputLimitExceeded is set:
+//
+//	res, _ := client.Exec(ctx, "my-sandbox", []string{"./noisy.sh"}, &lib.ExecOpts{
+//		Stdout:            os.Stdout,
+//		MaxOutputBytes:    10 << 20,
+//		OutputLimitPolicy: lib.ExecOutputLimitAbort,
+//	})
+//
+// [Client.StartExec] streams the input while the command runs, to drive
+// interactive programs. Closing the input sends EOF and the output keeps flowing
+// until the command exits:
+//
+//	h, err := client.StartExec(ctx, "my-sandbox", []string{"python3", "-i"}, &lib.ExecOpts{Stdout: os.Stdout})
+//	fmt.Fprintln(h.StdinPipe(), "print(40 + 2)")
+//	h.CloseStdin()
+//	res, err := h.Wait()
+//
+// The client keeps one SSH connection per sandbox that is reused by the exec, copy
+// and forward operations, so consecutive calls don't pay the connection setup.
+// Dead connections (e.g. the sandbox was restarted) are redialed automatically.
+//
+// # Tasks
+//
+// Register the repetitive commands as named tasks with their defaults, globally or
+// for a sandbox (its tasks take precedence over the global ones), and run them by
+// name. The run arguments are appended to the task command and the run options
+// override the task ones:
+//
+//	client.RegisterTask(ctx, "", lib.Task{
+//	    Name:       "test",
+//	    Command:    []string{"go", "test"},
+//	    WorkingDir: "/workspace",
+//	    Env:        map[string]string{"CGO_ENABLED": "0"},
+//	    Timeout:    10 * time.Minute,
+//	})
+//	res, _ := client.RunTask(ctx, "my-sandbox", "test", &lib.RunTaskOpts{Args: []string{"./..."}})
+//
 // # File Operations
 //
 // Copy files between the host and a running sandbox:
 //
-//	client.CopyTo(ctx, "my-sandbox", "/local/file.txt", "/remote/file.txt")
-//	client.CopyFrom(ctx, "my-sandbox", "/remote/file.txt", "/local/file.txt")
+//	client.CopyTo(ctx, "my-sandbox", "/local/file.txt", "/remote/file.txt", nil)
+//	client.CopyFrom(ctx, "my-sandbox", "/remote/file.txt", "/local/file.txt", nil)
+//
+// Directories are copied with several files and SFTP requests in flight at the
+// same time. Use [CopyOpts].Compress to send compressible files (e.g. source
+// trees) as a gzip stream and [CopyOpts].Progress to follow big copies:
+//
+//	client.CopyTo(ctx, "my-sandbox", "./src", "/workspace/src", &lib.CopyOpts{
+//		Compress: true,
+//		Progress: func(p lib.CopyProgress) { fmt.Printf("\r%d/%d bytes", p.Bytes, p.TotalBytes) },
+//	})
+//
+// For edit-and-run loops, [Client.Sync] mirrors a host directory into the
+// sandbox uploading only the changed files:
+//
+//	res, err := client.Sync(ctx, "my-sandbox", "./app", "/workspace/app", lib.SyncOpts{
+//		Delete:  true,
+//		Exclude: []string{".git", "node_modules"},
+//	})
+//	fmt.Printf("%d uploaded, %d unchanged\n", len(res.Uploaded), res.Unchanged)
+//
+// [Client.SyncWatch] keeps syncing on every local change until the context is
+// cancelled:
+//
+//	err := client.SyncWatch(ctx, "my-sandbox", "./app", "/workspace/app", lib.SyncWatchOpts{
+//		Sync:   lib.SyncOpts{Exclude: []string{".git"}},
+//		OnSync: func(res *lib.SyncResult, err error) { log.Println(res, err) },
+//	})
+//
+// Single files can be read, written, listed and inspected over SFTP, without
+// running commands in the sandbox:
+//
+//	err := client.WriteFile(ctx, "my-sandbox", "/workspace/config.json", cfg, 0644)
+//	data, err := client.ReadFile(ctx, "my-sandbox", "/workspace/out.json")
+//	entries, err := client.ListDir(ctx, "my-sandbox", "/workspace")
+//	info, err := client.StatFile(ctx, "my-sandbox", "/workspace/out.json")
+//
+// For interactive workflows the sandbox has a clipboard (/tmp/sbx/clipboard) and
+// a drop directory (/tmp/sbx/drop) the host pushes to and pulls from:
+//
+//	err := client.PushClipboard(ctx, "my-sandbox", []byte("SELECT 1;"))
+//	data, err := client.PullClipboard(ctx, "my-sandbox")
+//	paths, err := client.DropFiles(ctx, "my-sandbox", "./notes.md")
+//	paths, err = client.PickupFiles(ctx, "my-sandbox", "./out")
 //
 // # Port Forwarding
 //
@@ -61,11 +201,45 @@
 //	    {LocalPort: 8080, RemotePort: 80},
 //	})
 //
+// A 0 LocalPort binds a free port, [Client.ForwardWithInfo] reports the bound
+// ports once listening. [Client.ForwardNamed] forwards a named port of the
+// sandbox ([CreateSandboxOpts].Ports). [Client.ExposeHTTP] routes
+// http://<sandbox-name>.localhost:8080 to a sandbox HTTP service on a host ingress
+// shared by the exposed sandboxes. [PortMapping].TLS and [ExposeHTTPOpts].TLS
+// terminate TLS on the host, the sandbox services get plain connections.
+//
+// # User Data
+//
+// Provision sandboxes on their first boot with a shell script or a cloud-config
+// document. It runs when the sandbox is started for the first time:
+//
+//	client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+//	    Name:      "provisioned",
+//	    Engine:    lib.EngineFirecracker,
+//	    FromImage: "v0.1.0",
+//	    Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+//	    UserData:  "#cloud-config\npackages: [git]\nruncmd:\n  - git --version\n",
+//	})
+//	client.StartSandbox(ctx, "provisioned", nil)
+//
+// # Clock
+//
+// Run time dependent code against arbitrary dates, the guest clock is set on every
+// start and the guest time sync is disabled:
+//
+//	client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+//	    Name:      "y2038",
+//	    Engine:    lib.EngineFirecracker,
+//	    FromImage: "v0.1.0",
+//	    Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+//	    Clock:     &lib.ClockOptions{BootTime: time.Date(2038, 1, 19, 3, 0, 0, 0, time.UTC)},
+//	})
+//
 // # Snapshots
 //
 // Create snapshot images from stopped sandboxes and restore from them:
 //
-//	client.StopSandbox(ctx, "my-sandbox")
+//	client.StopSandbox(ctx, "my-sandbox", nil)
 //	imgName, _ := client.CreateImageFromSandbox(ctx, "my-sandbox", nil)
 //	client.CreateSandbox(ctx, lib.CreateSandboxOpts{
 //	    Name:      "from-snapshot",
@@ -74,6 +248,19 @@
 //	    Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
 //	})
 //
+// Running sandboxes can be snapshotted without stopping them, they are paused
+// briefly and the image also records their memory:
+//
+//	imgName, _ := client.CreateImageFromSandbox(ctx, "agent-session", &lib.CreateImageFromSandboxOpts{ImageName: "agent-checkpoint"})
+//
+// Audit the files a snapshot changed against its base image, or between two
+// snapshots (requires root):
+//
+//	diff, _ := client.DiffSnapshot(ctx, "agent-checkpoint", "")
+//	for _, c := range diff.Changes {
+//	    fmt.Println(c.Kind, c.Path)
+//	}
+//
 // # Image Management
 //
 // List, pull, inspect, and remove image releases from the registry:
@@ -83,6 +270,214 @@
 //	manifest, _ := client.InspectImage(ctx, "v0.1.0")
 //	client.RemoveImage(ctx, "v0.1.0")
 //
+// Move images to hosts without network access with bundles:
+//
+//	f, _ := os.Create("v0.1.0.tar")
+//	client.ExportImage(ctx, "v0.1.0", f)
+//	// On the offline host.
+//	name, _ := client.ImportImage(ctx, bundle, nil)
+//
+// Install a user built kernel and rootfs as an image:
+//
+//	manifest, _ := client.AddLocalImage(ctx, "my-image", "vmlinux", "rootfs.ext4", nil)
+//
+// Or convert a container image (it must install the OpenSSH server):
+//
+//	manifest, _ := client.CreateImageFromOCI(ctx, "my-app", "my-app:latest", nil)
+//
+// Customize the rootfs right after the pull, the customized image is derived and
+// cached as a new image (requires root):
+//
+//	result, _ := client.PullImage(ctx, "v0.1.0", &lib.PullImageOpts{
+//	    Customize: &lib.ImageCustomization{
+//	        Files:    []lib.ImageCustomizationFile{{Src: "ca.pem", Dst: "/usr/local/share/ca-certificates/ca.pem"}},
+//	        Commands: []string{"apk add --no-cache git", "update-ca-certificates"},
+//	    },
+//	})
+//	// Create the sandboxes with FromImage: result.CustomizedImage.
+//
+// Move stopped sandboxes to a patched image, keeping the user files of the
+// preserved paths (the rest of the rootfs comes from the new image):
+//
+//	client.StopSandbox(ctx, "my-sandbox", nil)
+//	client.RebaseSandbox(ctx, "my-sandbox", "v0.2.0", &lib.RebaseSandboxOpts{
+//	    PreservePaths: []string{"/root", "/home"},
+//	})
+//
+// Boot with a kernel profile, a built-in one (fast-boot, debug, hardened) or one
+// shipped by the image (see ImageManifest.Profiles):
+//
+//	_, _ = client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+//	    Name:      "my-sandbox",
+//	    FromImage: "v0.1.0",
+//	    Profile:   lib.KernelProfileFastBoot,
+//	})
+//
+// # Egress Policies
+//
+// Store an egress policy with a name and start the sandboxes with it, the policy
+// is read on every start so [Client.UpdatePolicy] reaches them on their next start:
+//
+//	client.CreatePolicy(ctx, lib.CreatePolicyOpts{
+//	    Name: "github",
+//	    Policy: lib.EgressPolicy{
+//	        Default: lib.EgressActionDeny,
+//	        Rules:   []lib.EgressRule{{Domain: "*.github.com", Action: lib.EgressActionAllow}},
+//	    },
+//	})
+//	client.StartSandbox(ctx, "agent-1", &lib.StartSandboxOpts{EgressPolicyName: "github"})
+//
+// Check what a policy does with some targets without starting a sandbox:
+//
+//	results, _ := client.TestEgressPolicy(ctx, policy, []string{"https://api.github.com"})
+//	fmt.Println(results[0].Action, results[0].Reason)
+//
+// The sandboxes with an egress policy can't ping by default, set
+// [EgressRule].ICMP on an allow rule to let them ping the addresses its domains
+// resolve to through the sandbox DNS:
+//
+//	lib.EgressRule{Domain: "*.github.com", Action: lib.EgressActionAllow, ICMP: true}
+//
+// Allow the NTP servers, OS package mirrors or container registries with their
+// built-in rules instead of listing their domains, the policy rules still apply
+// first:
+//
+//	lib.EgressPolicy{
+//	    Default:             lib.EgressActionDeny,
+//	    AllowSystemServices: []lib.EgressSystemService{lib.EgressSystemServiceNTP, lib.EgressSystemServicePackages},
+//	}
+//
+// The DNS queries of the sandboxes with an egress policy are recorded, get them
+// raw or aggregated per domain:
+//
+//	events, _ := client.DNSEvents(ctx, "agent-1", &lib.DNSEventsOpts{Limit: 100})
+//	stats, _ := client.DNSStats(ctx, "agent-1", nil)
+//	for _, d := range stats.Domains {
+//	    fmt.Println(d.Domain, d.Queries, d.Denied, d.AvgUpstreamLatency)
+//	}
+//
+// The allowed queries are forwarded to 8.8.8.8 by default, set other resolvers
+// (plain, DNS-over-TLS or DNS-over-HTTPS) for all the sandboxes with
+// [Config].DNSUpstreams or per policy with [EgressPolicy].DNSUpstreams:
+//
+//	client, _ := lib.New(ctx, lib.Config{
+//	    DNSUpstreams: []string{"tls://dns.internal.example.com", "10.0.0.53"},
+//	})
+//
+// The NAT traffic goes out through the host default route, pin it to a host
+// interface (e.g. a VPN tunnel) for all the sandboxes with [Config].EgressInterface
+// or per sandbox with [FirecrackerConfig].EgressInterface. The traffic leaving
+// through other interfaces is dropped:
+//
+//	client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+//	    Name:        "scraper",
+//	    Firecracker: &lib.FirecrackerConfig{EgressInterface: "wg0"},
+//	})
+//
+// Uplinks with a smaller MTU than the sandboxes (e.g. VPN tunnels) hang the large
+// transfers, lower the MTU of the sandbox interfaces or clamp the MSS of its TCP
+// connections to the host route with [FirecrackerConfig].Network:
+//
+//	Firecracker: &lib.FirecrackerConfig{
+//	    EgressInterface: "wg0",
+//	    Network:         lib.NetworkOptions{MTU: 1420, ClampMSS: true},
+//	},
+//
+// # Templates
+//
+// Store a sandbox spec once and create the sandboxes of the same shape by name, the
+// template environment and egress apply when the sandbox is started on creation:
+//
+//	client.CreateTemplate(ctx, lib.SandboxTemplate{
+//	    Name:             "go-agent",
+//	    Engine:           lib.EngineFirecracker,
+//	    FromImage:        "v0.1.0",
+//	    Resources:        lib.Resources{VCPUs: 4, MemoryMB: 4096, DiskGB: 10},
+//	    Env:              map[string]string{"GOFLAGS": "-mod=mod"},
+//	    EgressPolicyName: "github",
+//	})
+//	sb, _ := client.CreateSandboxFromTemplate(ctx, "go-agent", lib.CreateSandboxFromTemplateOpts{
+//	    Name:  "agent-1",
+//	    Start: true,
+//	})
+//
+// # File Integrity
+//
+// Hash the guest files under some paths when the sandbox starts and verify them
+// later, to detect the files tampered by an untrusted workload before promoting
+// its artifacts out of the sandbox:
+//
+//	client.StartSandbox(ctx, "agent-1", &lib.StartSandboxOpts{IntegrityPaths: []string{"/etc", "/usr/local/bin"}})
+//	// ... run the workload ...
+//	report, _ := client.VerifyIntegrity(ctx, "agent-1")
+//	for _, c := range report.Changes {
+//	    fmt.Println(c.Kind, c.Path)
+//	}
+//
+// # Processes
+//
+// List the processes running in a sandbox guest with their CPU and memory usage,
+// read with a probe executed in the guest:
+//
+//	table, _ := client.Top(ctx, "agent-1")
+//	for _, p := range table.Processes {
+//	    fmt.Printf("%d %.1f%% %s\n", p.PID, p.CPUPercent, p.Command)
+//	}
+//
+// # Connections
+//
+// List the active network connections of a sandbox, with the destination domain
+// known by the egress proxy or the sandbox DNS answers:
+//
+//	conns, _ := client.Connections(ctx, "agent-1")
+//	for _, c := range conns {
+//	    fmt.Println(c.Direction, c.Destination, c.Domain, c.BytesReceived)
+//	}
+//
+// # Watching
+//
+// Watch the sandboxes to get their list again when they change, e.g. to build a
+// dashboard. Unchanged lists are not sent and the channel is closed when the
+// context is done:
+//
+//	updates, _ := client.WatchSandboxes(ctx, nil)
+//	for sandboxes := range updates {
+//	    fmt.Println(len(sandboxes), "sandboxes")
+//	}
+//
+// Read the tail of the engine logs of a sandbox (e.g. the VMM and the egress
+// proxies):
+//
+//	logs, _ := client.Logs(ctx, "agent-1", &lib.LogsOpts{Lines: 50})
+//	for _, l := range logs {
+//	    fmt.Println(l.Source, len(l.Lines))
+//	}
+//
+// # Traffic Capture
+//
+// Capture the packets of a sandbox network interface as a pcap stream, stopping on
+// the context, a duration or a size or packets limit:
+//
+//	f, _ := os.Create("egress.pcap")
+//	defer f.Close()
+//	res, _ := client.CaptureTraffic(ctx, "agent-1", f, &lib.CaptureOpts{
+//	    Filter:   "tcp port 443",
+//	    Duration: time.Minute,
+//	    MaxBytes: 100 << 20,
+//	})
+//	fmt.Println(res.Packets, "packets")
+//
+// # Quarantine
+//
+// Freeze a suspicious sandbox for investigation without losing its live state:
+// [Client.QuarantineSandbox] cuts all its network traffic and revokes its port
+// forwards and exposed services, the sandbox keeps running and the host can still
+// run commands and copy files in it:
+//
+//	_, _ = client.QuarantineSandbox(ctx, "agent-1")
+//	res, _ := client.Exec(ctx, "agent-1", []string{"ps", "aux"}, nil)
+//	_, _ = client.ReleaseQuarantine(ctx, "agent-1")
+//
 // # Health Checks
 //
 // Run preflight checks to verify the engine environment:
@@ -92,6 +487,240 @@
 //	    fmt.Printf("%s: %s (%s)\n", r.ID, r.Message, r.Status)
 //	}
 //
+// [Client.EngineCapabilities] tells the optional features of the engine, to
+// feature-detect instead of handling the errors of unsupported operations:
+//
+//	caps, _ := client.EngineCapabilities(ctx)
+//	if caps.Snapshots {
+//	    client.CreateImageFromSandbox(ctx, "my-sandbox", nil)
+//	}
+//
+// # Host Capacity
+//
+// Get the host capacity and the resources allocated to the sandboxes:
+//
+//	hc, _ := client.HostCapacity(ctx)
+//	fmt.Printf("free: %g VCPUs, %d MB\n", hc.Free.VCPUs, hc.Free.MemoryMB)
+//
+// Set [Config].Admission to check the capacity on create (disk) and start (VCPUs
+// and memory), refusing the sandboxes that don't fit with [ErrNotValid]:
+//
+//	client, _ := lib.New(ctx, lib.Config{
+//	    Admission: &lib.AdmissionConfig{
+//	        Mode:       lib.AdmissionModeEnforce,
+//	        Overcommit: lib.OvercommitRatios{VCPUs: 8},
+//	    },
+//	})
+//
+// The resources reserved by each sandbox are written as JSON manifests in
+// DataDir/reservations/<sandbox-id>.json, updated on every change the client
+// makes to the sandbox, for the external schedulers sharing the host (e.g. a
+// Nomad or systemd unit reading them before placing other workloads).
+// [Client.Reservations] returns them, syncing the manifests with the sandboxes
+// changed by other clients:
+//
+//	rs, _ := client.Reservations(ctx)
+//	for _, r := range rs {
+//	    fmt.Printf("%s: %g VCPUs, %d MB\n", r.Name, r.Reserved.VCPUs, r.Reserved.MemoryMB)
+//	}
+//
+// # Boot Persistence
+//
+// A sandbox can be started when the host boots with a systemd unit running the
+// sbx CLI (sbx-<sandbox-id>.service in [Config].SystemdUnitDir, usually requires
+// root). The failed starts are retried by default:
+//
+//	_, _ = client.EnableBootPersistence(ctx, "my-sandbox", lib.BootPersistenceOpts{
+//	    Restart: lib.BootRestartPolicyOnFailure,
+//	})
+//	// ...
+//	_ = client.DisableBootPersistence(ctx, "my-sandbox")
+//
+// # Host Reboot Recovery
+//
+// A host reboot kills the running sandboxes but the database still has them as
+// running. Call [Client.RecoverSandboxes] once when the host boots: the dead
+// sandboxes are flagged as stopped and the persistent ones are started again with
+// the session of their previous start:
+//
+//	_, _ = client.SetPersistent(ctx, "my-sandbox", true)
+//	// After the host reboot.
+//	results, _ := client.RecoverSandboxes(ctx)
+//	for _, r := range results {
+//	    if r.Action == lib.RecoveryActionFailed {
+//	        fmt.Printf("%s: %s\n", r.Name, r.Error)
+//	    }
+//	}
+//
+// # Memory Reclaim
+//
+// Idle sandboxes can release memory back to the host, the guest gets it back on
+// demand when it needs it (0 gives back all the sandbox memory):
+//
+//	_, _ = client.SetMemoryTarget(ctx, "my-sandbox", 256)
+//	_, _ = client.SetMemoryTarget(ctx, "my-sandbox", 0)
+//
+// # Scaling
+//
+// Running sandboxes can be scaled up to the resource ceilings they were created
+// with, Firecracker boots the sandbox with the ceilings and releases the rest:
+//
+//	_, _ = client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+//	    Name:      "my-sandbox",
+//	    Resources: lib.Resources{VCPUs: 1, MemoryMB: 1024, MaxVCPUs: 4, MaxMemoryMB: 4096},
+//	})
+//	// ...
+//	_, _ = client.ScaleSandbox(ctx, "my-sandbox", lib.Resources{VCPUs: 4, MemoryMB: 4096})
+//
+// # Engine API
+//
+// The VMM machine API of a running sandbox (the Firecracker API socket) is available
+// for the features sbx doesn't wrap. The resources sbx manages (boot source, drives,
+// network interfaces, machine config) can't be changed through it:
+//
+//	rt, _ := client.EngineAPI(ctx, "my-sandbox")
+//	hc := &http.Client{Transport: rt}
+//	res, _ := hc.Get("http://firecracker/balloon/statistics")
+//
+// # Idle Sandboxes
+//
+// An idle policy stops a running sandbox without activity (commands, shell
+// sessions or network traffic) for a while. sbx has no daemon, the policies are
+// applied by [Client.SuspendIdleSandboxes], call it periodically:
+//
+//	_, _ = client.SetIdlePolicy(ctx, "my-sandbox", &lib.IdlePolicy{Timeout: 30 * time.Minute})
+//
+//	for range time.Tick(time.Minute) {
+//	    stopped, err := client.SuspendIdleSandboxes(ctx)
+//	    // ...
+//	}
+//
+// The stopped sandboxes emit the [EventSandboxIdleStopped] event. The engines
+// can't pause sandboxes, [IdleActionStop] is the only action.
+//
+// # Annotations
+//
+// Sandboxes can carry free-form metadata, returned in [Sandbox.Annotations] by
+// [Client.GetSandbox] and [Client.ListSandboxes]:
+//
+//	_, _ = client.AnnotateSandbox(ctx, "my-sandbox", lib.AnnotateSandboxOpts{
+//	    Set:    map[string]string{"ticket": "https://issues.example.com/42"},
+//	    Remove: []string{"experiment"},
+//	})
+//
+// # Infrastructure as Code
+//
+// The sandbox ID is assigned on creation and never changes, store it as the
+// resource identity. [Client.GetSandboxByID] reads a sandbox by ID only (names are
+// not resolved) and sees every completed write, and [Client.DiffSandboxSpec] returns
+// the spec fields that differ from the desired create options, the sandbox must be
+// replaced to apply them:
+//
+//	diff, err := client.DiffSandboxSpec(ctx, id, desired)
+//	if err == nil && len(diff) > 0 {
+//	    _, _ = client.RemoveSandbox(ctx, id, true)
+//	    sb, err = client.CreateSandbox(ctx, desired)
+//	}
+//
+// # Namespaces
+//
+// A client only sees the sandboxes of its namespace ([Config].Namespace, default
+// [DefaultNamespace]), so the teams sharing a host are isolated and can use the
+// same sandbox names. An [AllNamespaces] client is for the administration tools,
+// it sees every sandbox and lists the namespaces:
+//
+//	admin, _ := lib.New(ctx, lib.Config{Namespace: lib.AllNamespaces})
+//	namespaces, _ := admin.ListNamespaces(ctx)
+//	sandboxes, _ := admin.ListSandboxes(ctx, &lib.ListSandboxesOpts{Namespace: "team-a"})
+//
+// # Usage Accounting
+//
+// The resources used by the sandboxes are recorded when they stop and when they
+// are removed, and kept after the removal. [Client.UsageReport] returns the
+// CPU-seconds, memory-MB-hours and disk-GB-hours per sandbox, or per annotation
+// value with GroupBy:
+//
+//	report, _ := client.UsageReport(ctx, lib.UsageFilter{GroupBy: "team"}, lib.TimeRange{
+//	    Since: time.Now().AddDate(0, -1, 0),
+//	})
+//	for _, e := range report.Entries {
+//	    fmt.Printf("%s: %.0f CPU seconds\n", e.Key, e.CPUSeconds)
+//	}
+//
+// # Diagnostics
+//
+// Collect a sanitized diagnostics bundle (doctor checks, sandbox list, nftables
+// ruleset and the sandbox logs) to attach to bug reports:
+//
+//	f, _ := os.Create("sbx-support.tar.gz")
+//	err := client.CollectDiagnostics(ctx, f, &lib.CollectDiagnosticsOpts{SandboxNameOrID: "my-sandbox"})
+//
+// # Database Maintenance
+//
+// The sandboxes and their exec history are stored in a SQLite database. Long-running
+// clients set [Config].DBCheckpointInterval so its write-ahead log doesn't keep
+// growing, and compact it from time to time:
+//
+//	client, _ := lib.New(ctx, lib.Config{DBCheckpointInterval: 5 * time.Minute})
+//	res, err := client.CompactDB(ctx)
+//
+// # Events
+//
+// The lifecycle and security events are sent to the [Config].EventSinks so SIEM
+// pipelines can ingest the sbx activity:
+//
+//	client, _ := lib.New(ctx, lib.Config{
+//	    EventSinks: []lib.EventSink{
+//	        {Type: lib.EventSinkFile, Path: "/var/log/sbx/events.jsonl"},
+//	        {Type: lib.EventSinkJournald},
+//	    },
+//	})
+//
+// The file and webhook sinks use one JSON object per event:
+//
+//	{"time":"2026-01-02T03:04:05Z","type":"egress.denied","sandbox_id":"01J...","sandbox_name":"my-sandbox","namespace":"default","attributes":{"domain":"evil.example.com","protocol":"tls","reason":"rule-match","target":"evil.example.com"}}
+//
+// The egress denied and proxy crashed events are emitted by the egress proxies of
+// the sandboxes started by the client, with the sinks of the client that started them.
+// The events are sent in the background, [Client.Close] sends the pending ones.
+//
+// A sandbox can notify its own webhooks of its events, e.g. to get notified when
+// it's started and stopped. The payloads are signed with the webhook secret (see
+// [Webhook]) and the failed deliveries are retried:
+//
+//	sb, _ := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+//	    Name:      "my-sandbox",
+//	    Engine:    lib.EngineFake,
+//	    Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+//	    Webhooks: []lib.Webhook{{
+//	        URL:    "https://hooks.example.com/sbx",
+//	        Secret: os.Getenv("SBX_WEBHOOK_SECRET"),
+//	        Events: []lib.EventType{lib.EventSandboxStarted, lib.EventSandboxStopped},
+//	    }},
+//	})
+//
+// # Host Hooks
+//
+// The [Config].Hooks run on the host, in the lifecycle operations of the client, to
+// integrate sbx with other systems (e.g. DNS registration, inventories). They are
+// executables or Go functions run on the pre-create, post-start, pre-stop and
+// post-remove events. A failed pre hook aborts the operation, the post hook
+// failures are only logged:
+//
+//	client, _ := lib.New(ctx, lib.Config{
+//	    Hooks: []lib.Hook{
+//	        {Event: lib.HookPostStart, Command: []string{"/usr/local/bin/dns-register"}},
+//	        {Event: lib.HookPreCreate, Func: func(ctx context.Context, _ lib.HookEvent, sb lib.Sandbox) error {
+//	            return quota.Check(ctx, sb.Name, sb.Resources)
+//	        }},
+//	    },
+//	})
+//
+// The executables get the sandbox in the SBX_* environment variables and as JSON in
+// their standard input. The executables of the [Config].HooksDir event
+// subdirectories (e.g. ~/.sbx/hooks/post-start/) run before them, the sbx CLI runs
+// the same ones.
+//
 // # Error Handling
 //
 // All methods return errors that can be inspected with [errors.Is]:
@@ -99,6 +728,44 @@
 //   - [ErrNotFound]: Resource does not exist.
 //   - [ErrAlreadyExists]: Resource with the same name already exists.
 //   - [ErrNotValid]: Invalid input or operation (e.g. stopping a non-running sandbox).
+//   - [ErrConflict]: The sandbox is being started, stopped or removed by another
+//     operation, from this or another process.
+//   - [ErrBusy]: A host resource of the sandbox (VM directory, TAP device,
+//     nftables) is being changed by another client of the data directory, e.g. a
+//     process with another database.
+//
+// The errors are [*Error] values with the failure [ErrorCode], the resource they
+// refer to and if the operation can be retried (e.g. wait timeouts or conflicts):
+//
+//	var sbxErr *lib.Error
+//	if errors.As(err, &sbxErr) {
+//	    fmt.Printf("%s %s failed (%s), retryable: %t\n", sbxErr.Kind, sbxErr.Name, sbxErr.Code, sbxErr.Retryable)
+//	}
+//
+// The clients sharing a [Config].DataDir coordinate with advisory file locks in it,
+// released by the kernel if the process dies. The busy errors have the client
+// holding the lock:
+//
+//	var busyErr *lib.BusyError
+//	if errors.As(err, &busyErr) && busyErr.Holder != nil {
+//	    fmt.Printf("%s locked by pid %d (%s)\n", busyErr.Resource, busyErr.Holder.PID, busyErr.Holder.DBPath)
+//	}
+//
+// The transient engine failures (guest SSH dial, Firecracker API socket, busy
+// nftables) are retried with the [Config].Retry policies. When an operation gives
+// up, the error has the attempts made:
+//
+//	client, err := lib.New(ctx, lib.Config{
+//	    Retry: lib.RetryPolicies{
+//	        Nftables: lib.RetryPolicy{Attempts: 10, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 2 * time.Second, Multiplier: 2, Jitter: 0.2},
+//	    },
+//	})
+//	...
+//	_, err = client.StartSandbox(ctx, "my-sandbox", nil)
+//	var retryErr *lib.RetryError
+//	if errors.As(err, &retryErr) {
+//	    fmt.Printf("%s gave up after %d attempts\n", retryErr.Operation, retryErr.Attempts)
+//	}
 //
 // # Testing
 //
@@ -114,5 +781,6 @@
 // # Thread Safety
 //
 // A [Client] is safe for concurrent use from multiple goroutines. The underlying
-// storage uses SQLite with WAL mode, and engines are created per-operation.
+// storage uses SQLite with WAL mode, and the engines and SSH connections are
+// shared by all the operations.
 package lib
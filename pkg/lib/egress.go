@@ -0,0 +1,129 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/app/egresstest"
+	"github.com/slok/sbx/internal/app/policycreate"
+	"github.com/slok/sbx/internal/app/policylist"
+	"github.com/slok/sbx/internal/app/policyrm"
+	"github.com/slok/sbx/internal/app/policyupdate"
+)
+
+// TestEgressPolicy evaluates the targets (URLs, hosts or IPs) against the egress
+// policy offline, without a sandbox, and returns the action each one would get
+// and the rule that decided it. The fail-closed checks need the destination
+// resolution so they are not evaluated.
+func (c *Client) TestEgressPolicy(ctx context.Context, policy EgressPolicy, targets []string) ([]EgressTestResult, error) {
+	svc, err := egresstest.NewService(egresstest.ServiceConfig{
+		Logger: c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	results, err := svc.Run(ctx, egresstest.Request{
+		Policy:  *toInternalEgressPolicy(&policy),
+		Targets: targets,
+	})
+	if err != nil {
+		return nil, mapError(err, "", "")
+	}
+
+	return fromInternalEgressTestResults(results), nil
+}
+
+// CreatePolicy stores a named egress policy, the sandboxes use it by name with
+// [StartSandboxOpts].EgressPolicyName.
+//
+// Returns [ErrAlreadyExists] if a policy with the same name exists or [ErrNotValid]
+// if the name or the policy are not valid.
+func (c *Client) CreatePolicy(ctx context.Context, opts CreatePolicyOpts) (*NamedEgressPolicy, error) {
+	svc, err := policycreate.NewService(policycreate.ServiceConfig{
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	p, err := svc.Run(ctx, policycreate.Request{
+		Name:   opts.Name,
+		Policy: *toInternalEgressPolicy(&opts.Policy),
+	})
+	if err != nil {
+		return nil, mapError(err, ResourceKindEgressPolicy, opts.Name)
+	}
+
+	out := fromInternalNamedEgressPolicy(*p)
+	return &out, nil
+}
+
+// ListPolicies returns the named egress policies sorted by name.
+func (c *Client) ListPolicies(ctx context.Context) ([]NamedEgressPolicy, error) {
+	svc, err := policylist.NewService(policylist.ServiceConfig{
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	policies, err := svc.Run(ctx)
+	if err != nil {
+		return nil, mapError(err, "", "")
+	}
+
+	out := make([]NamedEgressPolicy, 0, len(policies))
+	for _, p := range policies {
+		out = append(out, fromInternalNamedEgressPolicy(p))
+	}
+	return out, nil
+}
+
+// UpdatePolicy replaces the egress policy of a named policy. The sandboxes that use
+// it get the new policy on their next start, the running ones keep the previous one.
+//
+// Returns [ErrNotFound] if the policy does not exist or [ErrNotValid] if the policy
+// is not valid.
+func (c *Client) UpdatePolicy(ctx context.Context, name string, policy EgressPolicy) (*NamedEgressPolicy, error) {
+	svc, err := policyupdate.NewService(policyupdate.ServiceConfig{
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	p, err := svc.Run(ctx, policyupdate.Request{
+		Name:   name,
+		Policy: *toInternalEgressPolicy(&policy),
+	})
+	if err != nil {
+		return nil, mapError(err, ResourceKindEgressPolicy, name)
+	}
+
+	out := fromInternalNamedEgressPolicy(*p)
+	return &out, nil
+}
+
+// DeletePolicy removes a named egress policy. The running sandboxes that use it keep
+// their policy, starting a sandbox with it fails with [ErrNotFound].
+//
+// Returns [ErrNotFound] if the policy does not exist.
+func (c *Client) DeletePolicy(ctx context.Context, name string) error {
+	svc, err := policyrm.NewService(policyrm.ServiceConfig{
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	if err := svc.Run(ctx, policyrm.Request{Name: name}); err != nil {
+		return mapError(err, ResourceKindEgressPolicy, name)
+	}
+
+	return nil
+}
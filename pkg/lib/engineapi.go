@@ -0,0 +1,89 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/slok/sbx/internal/app/engineapi"
+	"github.com/slok/sbx/internal/model"
+)
+
+// EngineAPI returns an HTTP transport connected to the VMM machine API of a running
+// sandbox (the Firecracker API socket), to use the VMM features sbx doesn't wrap
+// (e.g. metrics, entropy, balloon statistics). The request host is ignored:
+//
+//	rt, _ := client.EngineAPI(ctx, "agent-1")
+//	hc := &http.Client{Transport: rt}
+//	res, _ := hc.Get("http://firecracker/machine-config")
+//
+// The requests changing the resources sbx manages (boot source, drives, network
+// interfaces, machine config and snapshot loading) fail with [ErrNotValid]. Other
+// changes are not tracked by sbx, e.g. the memory reclaim (see
+// [Client.SetMemoryTarget]) and scaling also set the balloon.
+//
+// Returns [ErrNotFound] if the sandbox does not exist and [ErrNotValid] if it is
+// not running or its engine has no VMM API (see [EngineCapabilities].EngineAPI).
+func (c *Client) EngineAPI(ctx context.Context, nameOrID string) (http.RoundTripper, error) {
+	sb, err := c.getInternalSandbox(ctx, nameOrID)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+	if sb.Status != model.SandboxStatusRunning {
+		return nil, mapError(fmt.Errorf("sandbox %s is not running (current status: %s): %w", sb.Name, sb.Status, model.ErrNotValid), ResourceKindSandbox, nameOrID)
+	}
+
+	eng, err := c.newEngine(sb.Config)
+	if err != nil {
+		return nil, mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, nameOrID)
+	}
+	if !eng.Capabilities().EngineAPI {
+		return nil, mapError(fmt.Errorf("the sandbox engine has no VMM API: %w", model.ErrNotValid), ResourceKindSandbox, nameOrID)
+	}
+
+	svc, err := engineapi.NewService(engineapi.ServiceConfig{
+		Engine:     eng,
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	id := sb.ID
+	return &engineAPITransport{
+		nameOrID: nameOrID,
+		transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				conn, err := svc.Run(ctx, engineapi.Request{NameOrID: id})
+				if err != nil {
+					return nil, mapError(err, ResourceKindSandbox, nameOrID)
+				}
+				return conn, nil
+			},
+		},
+	}, nil
+}
+
+// engineAPITransport sends the requests to the VMM machine API that don't change
+// the resources managed by sbx.
+type engineAPITransport struct {
+	nameOrID  string
+	transport *http.Transport
+}
+
+func (t *engineAPITransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := engineapi.ValidateRequest(req.Method, req.URL.Path); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, mapError(err, ResourceKindSandbox, t.nameOrID)
+	}
+	return t.transport.RoundTrip(req)
+}
+
+// CloseIdleConnections closes the idle VMM API connections.
+func (t *engineAPITransport) CloseIdleConnections() {
+	t.transport.CloseIdleConnections()
+}
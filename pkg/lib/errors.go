@@ -1,6 +1,9 @@
 package lib
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	// ErrNotFound is returned when a resource is not found.
@@ -9,4 +12,204 @@ var (
 	ErrAlreadyExists = errors.New("already exists")
 	// ErrNotValid is returned when an operation or input is not valid.
 	ErrNotValid = errors.New("not valid")
+	// ErrConflict is returned when the sandbox is being changed by another operation
+	// (e.g. a concurrent start and stop from different processes).
+	ErrConflict = errors.New("conflict")
+	// ErrBusy is returned when a host resource of the sandbox (VM directory, TAP
+	// device, nftables) is locked by another client of the data directory, e.g. a
+	// process using another database. See [BusyError].
+	ErrBusy = errors.New("busy")
+)
+
+// ErrorCode identifies the kind of failure of an [Error].
+type ErrorCode string
+
+const (
+	// ErrorCodeNotFound means the resource does not exist, matches [ErrNotFound].
+	ErrorCodeNotFound ErrorCode = "not_found"
+	// ErrorCodeAlreadyExists means the resource already exists, matches [ErrAlreadyExists].
+	ErrorCodeAlreadyExists ErrorCode = "already_exists"
+	// ErrorCodeNotValid means the input or operation is not valid, matches [ErrNotValid].
+	ErrorCodeNotValid ErrorCode = "not_valid"
+	// ErrorCodeConflict means the resource is being changed by another operation,
+	// matches [ErrConflict].
+	ErrorCodeConflict ErrorCode = "conflict"
+	// ErrorCodeBusy means a host resource is locked by another client, matches
+	// [ErrBusy].
+	ErrorCodeBusy ErrorCode = "busy"
+	// ErrorCodeTimeout means the operation deadline was exceeded.
+	ErrorCodeTimeout ErrorCode = "timeout"
+	// ErrorCodeCanceled means the operation context was canceled.
+	ErrorCodeCanceled ErrorCode = "canceled"
+	// ErrorCodeInternal is any other failure (engine, storage, host...).
+	ErrorCodeInternal ErrorCode = "internal"
+)
+
+// ResourceKind is the kind of resource an [Error] refers to.
+type ResourceKind string
+
+const (
+	// ResourceKindSandbox is a sandbox.
+	ResourceKindSandbox ResourceKind = "sandbox"
+	// ResourceKindImage is an image (release or snapshot).
+	ResourceKindImage ResourceKind = "image"
+	// ResourceKindEgressPolicy is a named egress policy.
+	ResourceKindEgressPolicy ResourceKind = "egress-policy"
+	// ResourceKindTask is a task.
+	ResourceKindTask ResourceKind = "task"
+	// ResourceKindTemplate is a sandbox template.
+	ResourceKindTemplate ResourceKind = "template"
 )
+
+// Error is the error returned by the [Client] operations. It carries the failure
+// code, the resource it refers to and whether the operation can be retried.
+//
+// It still matches the sentinel errors with [errors.Is], use [errors.As] to get the
+// details:
+//
+//	var sbxErr *lib.Error
+//	if errors.As(err, &sbxErr) && sbxErr.Retryable {
+//	    // Retry the operation.
+//	}
+type Error struct {
+	// Code is the failure code.
+	Code ErrorCode
+	// Kind is the kind of resource the operation was acting on. Empty when the
+	// operation is not about a single resource (e.g. listing sandboxes).
+	Kind ResourceKind
+	// Name is the name (or ID) of the resource the operation was acting on.
+	Name string
+	// Retryable is true when the operation can succeed if retried as is.
+	Retryable bool
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is matches the sentinel error of the error code.
+func (e *Error) Is(target error) bool {
+	switch e.Code {
+	case ErrorCodeNotFound:
+		return target == ErrNotFound
+	case ErrorCodeAlreadyExists:
+		return target == ErrAlreadyExists
+	case ErrorCodeNotValid:
+		return target == ErrNotValid
+	case ErrorCodeConflict:
+		return target == ErrConflict
+	case ErrorCodeBusy:
+		return target == ErrBusy
+	}
+	return false
+}
+
+// StartError is the error of a [Client.StartSandbox] that failed on one of its
+// steps. The steps that already ran are rolled back, so the sandbox is left stopped
+// as it was before the start.
+//
+// It's returned wrapped in an [Error], use [errors.As] to get it:
+//
+//	var startErr *lib.StartError
+//	if errors.As(err, &startErr) {
+//	    fmt.Printf("start failed on %s step\n", startErr.Step)
+//	}
+type StartError struct {
+	// Step is the name of the start step that failed (e.g. network, boot, ssh, user_data).
+	Step string
+	// Phases are the start phases run until the failure, the failed one last with
+	// its error.
+	Phases []BootPhase
+	// RollbackErr is the error of rolling back the already run steps, nil when the
+	// rollback succeeded. When set, some host resources may have been left behind.
+	RollbackErr error
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *StartError) Error() string { return e.Err.Error() }
+
+func (e *StartError) Unwrap() error { return e.Err }
+
+// BusyError is the error of an operation on a host resource locked by another
+// client of the data directory, the operation can be retried once it's released.
+//
+// It's returned wrapped in an [Error], use [errors.As] to get it:
+//
+//	var busyErr *lib.BusyError
+//	if errors.As(err, &busyErr) && busyErr.Holder != nil {
+//	    fmt.Printf("%s is used by pid %d\n", busyErr.Resource, busyErr.Holder.PID)
+//	}
+type BusyError struct {
+	// Resource is the locked host resource (e.g. vm/01J..., tap/sbx-1a2b, nftables).
+	Resource string
+	// Holder is the client holding the lock, nil when it's unknown.
+	Holder *LockHolder
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *BusyError) Error() string { return e.Err.Error() }
+
+func (e *BusyError) Unwrap() error { return e.Err }
+
+// RetryError is the error of an engine operation that gave up retrying a transient
+// failure (e.g. the guest SSH not ready after the boot), see [Config].Retry.
+//
+// It's returned wrapped in an [Error], use [errors.As] to get it:
+//
+//	var retryErr *lib.RetryError
+//	if errors.As(err, &retryErr) {
+//	    fmt.Printf("%s gave up after %d attempts\n", retryErr.Operation, retryErr.Attempts)
+//	}
+type RetryError struct {
+	// Operation is the retried operation (e.g. guest SSH dial).
+	Operation string
+	// Attempts is the number of attempts made.
+	Attempts int
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *RetryError) Error() string { return e.Err.Error() }
+
+func (e *RetryError) Unwrap() error { return e.Err }
+
+// SpecMismatchError is the error of an if not exists create (see
+// [CreateSandboxOpts].IfNotExists) when the sandbox with the name already exists
+// with a different spec, it matches [ErrAlreadyExists].
+//
+// It's returned wrapped in an [Error], use [errors.As] to get it:
+//
+//	var mismatchErr *lib.SpecMismatchError
+//	if errors.As(err, &mismatchErr) {
+//	    fmt.Printf("%s differs in %v\n", mismatchErr.Name, mismatchErr.Fields)
+//	}
+type SpecMismatchError struct {
+	// Name is the name of the existing sandbox.
+	Name string
+	// Fields are the spec fields that differ, see [Client.DiffSandboxSpec].
+	Fields []SpecField
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *SpecMismatchError) Error() string { return e.Err.Error() }
+
+func (e *SpecMismatchError) Unwrap() error { return e.Err }
+
+// LockHolder identifies the client holding a host resource lock.
+type LockHolder struct {
+	// PID is the process of the client.
+	PID int
+	// Hostname is the host of the client.
+	Hostname string
+	// DBPath is the database of the client, empty when unknown.
+	DBPath string
+	// Operation is what the client is doing with the resource (e.g. start, remove).
+	Operation string
+	// Since is when the client took the lock.
+	Since time.Time
+}
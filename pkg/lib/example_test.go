@@ -96,7 +96,7 @@ func Example_lifecycle() {
 	fmt.Printf("3. Exec exit code: %d\n", result.ExitCode)
 
 	// Stop.
-	_, err = client.StopSandbox(ctx, "my-sandbox")
+	_, err = client.StopSandbox(ctx, "my-sandbox", nil)
 	if err != nil {
 		panic(err)
 	}
@@ -305,7 +305,7 @@ func Example_errorHandling() {
 	}
 
 	// Try to stop a non-running sandbox.
-	_, err = client.StopSandbox(ctx, "dup")
+	_, err = client.StopSandbox(ctx, "dup", nil)
 	if errors.Is(err, lib.ErrNotValid) {
 		fmt.Println("invalid operation (expected)")
 	}
@@ -3,9 +3,11 @@ package lib
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 
 	appexec "github.com/slok/sbx/internal/app/exec"
+	"github.com/slok/sbx/internal/app/exechistory"
 	"github.com/slok/sbx/internal/model"
 )
 
@@ -15,19 +17,30 @@ import (
 // environment variables, and I/O streams. Pass nil opts for defaults
 // (no working dir, no extra env, discarded output).
 //
-// The sandbox must be in [SandboxStatusRunning] state.
+// The result always includes timing and output byte counts. Set
+// [ExecOpts].CaptureOutput to also get stdout and stderr as strings.
+//
+// The sandbox must be in [SandboxStatusRunning] state. Every execution is recorded
+// in the sandbox exec history, see [Client.ExecHistory].
 //
 // Returns [ErrNotFound] if the sandbox does not exist, or [ErrNotValid] if
-// the sandbox is not running or the command is empty.
+// the sandbox is not running, the command is empty or [ExecOpts].Tty is set and
+// the engine doesn't support it (see [EngineCapabilities].Tty).
 func (c *Client) Exec(ctx context.Context, nameOrID string, command []string, opts *ExecOpts) (*ExecResult, error) {
+	return c.exec(ctx, appexec.Request{NameOrID: nameOrID, Command: command}, opts)
+}
+
+// exec runs an exec request with the options of the client exec calls.
+func (c *Client) exec(ctx context.Context, req appexec.Request, opts *ExecOpts) (*ExecResult, error) {
+	nameOrID := req.NameOrID
 	sb, err := c.getInternalSandbox(ctx, nameOrID)
 	if err != nil {
-		return nil, mapError(err)
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
 	}
 
 	eng, err := c.newEngine(sb.Config)
 	if err != nil {
-		return nil, mapError(fmt.Errorf("could not create engine: %w", err))
+		return nil, mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, nameOrID)
 	}
 
 	svc, err := appexec.NewService(appexec.ServiceConfig{
@@ -39,82 +52,202 @@ func (c *Client) Exec(ctx context.Context, nameOrID string, command []string, op
 		return nil, fmt.Errorf("could not create service: %w", err)
 	}
 
-	var files []string
+	req.Opts = toInternalExecOpts(opts)
+	req.Caller = "sdk"
 	if opts != nil {
-		files = opts.Files
+		req.Files = opts.Files
+		req.CaptureOutput = opts.CaptureOutput
+		req.CaptureMaxBytes = opts.CaptureMaxBytes
+		req.MaxOutputBytes = opts.MaxOutputBytes
+		req.OutputLimitPolicy = model.ExecOutputLimitPolicy(opts.OutputLimitPolicy)
+		if opts.Caller != "" {
+			req.Caller = opts.Caller
+		}
+	}
+
+	result, err := svc.Run(ctx, req)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
 	}
 
-	result, err := svc.Run(ctx, appexec.Request{
-		NameOrID: nameOrID,
-		Command:  command,
-		Opts:     toInternalExecOpts(opts),
-		Files:    files,
+	out := fromInternalExecResult(*result)
+	return &out, nil
+}
+
+// ExecHandle is a command started with [Client.StartExec]. Its input is written
+// with [ExecHandle.StdinPipe] while the command runs, so clients can drive
+// interactive programs (REPLs, language servers, line based protocols) inside the
+// sandbox. The output is written to [ExecOpts].Stdout and [ExecOpts].Stderr as the
+// command produces it.
+type ExecHandle struct {
+	stdin  *io.PipeWriter
+	done   chan struct{}
+	result *ExecResult
+	err    error
+}
+
+// StdinPipe returns the writer connected to the command standard input. Writes
+// block until the command reads them and fail with [io.ErrClosedPipe] once the
+// command has finished. Closing it is the same as [ExecHandle.CloseStdin].
+func (h *ExecHandle) StdinPipe() io.WriteCloser { return h.stdin }
+
+// CloseStdin closes the command standard input (half-close): the command reads
+// EOF and keeps running, its output is still delivered until it exits.
+func (h *ExecHandle) CloseStdin() error { return h.stdin.Close() }
+
+// Done returns a channel closed when the command finishes.
+func (h *ExecHandle) Done() <-chan struct{} { return h.done }
+
+// Wait waits for the command to finish and returns its result, with the same
+// semantics as [Client.Exec]. It can be called several times.
+func (h *ExecHandle) Wait() (*ExecResult, error) {
+	<-h.done
+	return h.result, h.err
+}
+
+// StartExec starts a command inside a running sandbox and returns without waiting
+// for it, the input is streamed with [ExecHandle.StdinPipe] and the result is
+// returned by [ExecHandle.Wait]. Cancelling ctx kills the command.
+//
+// The opts are the same as [Client.Exec], except [ExecOpts].Stdin that must be
+// nil, and [ExecOpts].Tty that is not supported.
+//
+// Returns [ErrNotFound] if the sandbox does not exist, or [ErrNotValid] if the
+// sandbox is not running, the command is empty or the options are not valid.
+// Errors of the execution itself are returned by [ExecHandle.Wait].
+func (c *Client) StartExec(ctx context.Context, nameOrID string, command []string, opts *ExecOpts) (*ExecHandle, error) {
+	if len(command) == 0 {
+		return nil, mapError(fmt.Errorf("command cannot be empty: %w", ErrNotValid), ResourceKindSandbox, nameOrID)
+	}
+
+	var execOpts ExecOpts
+	if opts != nil {
+		execOpts = *opts
+	}
+	if execOpts.Stdin != nil {
+		return nil, mapError(fmt.Errorf("stdin is not allowed, use the handle stdin pipe: %w", ErrNotValid), ResourceKindSandbox, nameOrID)
+	}
+	if execOpts.Tty {
+		return nil, mapError(fmt.Errorf("tty is not supported by started commands: %w", ErrNotValid), ResourceKindSandbox, nameOrID)
+	}
+
+	// Fail fast on the sandbox errors instead of on Wait.
+	sb, err := c.getInternalSandbox(ctx, nameOrID)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+	if sb.Status != model.SandboxStatusRunning {
+		return nil, mapError(fmt.Errorf("sandbox %s is not running (status: %s): %w", sb.Name, sb.Status, ErrNotValid), ResourceKindSandbox, nameOrID)
+	}
+
+	stdinR, stdinW := io.Pipe()
+	execOpts.Stdin = stdinR
+	h := &ExecHandle{
+		stdin: stdinW,
+		done:  make(chan struct{}),
+	}
+	go func() {
+		defer close(h.done)
+		h.result, h.err = c.Exec(ctx, nameOrID, command, &execOpts)
+		// Unblock the pending and future writes of a finished command.
+		_ = stdinR.Close()
+	}()
+
+	return h, nil
+}
+
+// ExecHistory returns the commands executed in a sandbox with [Client.Exec] (and
+// the sbx exec and shell commands), oldest first.
+//
+// Records store the output size and hash, not the output itself. They are removed
+// with the sandbox.
+//
+// Returns [ErrNotFound] if the sandbox does not exist.
+func (c *Client) ExecHistory(ctx context.Context, nameOrID string, opts *ExecHistoryOpts) ([]ExecRecord, error) {
+	svc, err := exechistory.NewService(exechistory.ServiceConfig{
+		Repository: c.repo,
+		Logger:     c.logger,
 	})
 	if err != nil {
-		return nil, mapError(err)
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	req := exechistory.Request{NameOrID: nameOrID}
+	if opts != nil {
+		req.Since = opts.Since
+		req.Limit = opts.Limit
+	}
+
+	records, err := svc.Run(ctx, req)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
 	}
 
-	return &ExecResult{ExitCode: result.ExitCode}, nil
+	return fromInternalExecRecords(records), nil
 }
 
 // CopyTo copies a local file or directory from the host into a running sandbox.
+// Pass nil opts to use defaults, see [CopyOpts].
 //
 // The sandbox must be in [SandboxStatusRunning] state.
-// For Firecracker sandboxes, this uses SCP over the VM's internal IP.
+// For Firecracker sandboxes, this uses concurrent SFTP transfers (or a compressed
+// tar stream with [CopyOpts].Compress) over the VM's internal IP.
 //
 // Returns [ErrNotFound] if the sandbox does not exist, or [ErrNotValid] if
 // the sandbox is not running.
-func (c *Client) CopyTo(ctx context.Context, nameOrID string, srcLocal, dstRemote string) error {
+func (c *Client) CopyTo(ctx context.Context, nameOrID string, srcLocal, dstRemote string, opts *CopyOpts) error {
 	sb, err := c.getInternalSandbox(ctx, nameOrID)
 	if err != nil {
-		return mapError(err)
+		return mapError(err, ResourceKindSandbox, nameOrID)
 	}
 
 	eng, err := c.newEngine(sb.Config)
 	if err != nil {
-		return mapError(fmt.Errorf("could not create engine: %w", err))
+		return mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, nameOrID)
 	}
 
 	if sb.Status != model.SandboxStatusRunning {
-		return mapError(fmt.Errorf("sandbox %s is not running (status: %s): %w", sb.Name, sb.Status, ErrNotValid))
+		return mapError(fmt.Errorf("sandbox %s is not running (status: %s): %w", sb.Name, sb.Status, ErrNotValid), ResourceKindSandbox, nameOrID)
 	}
 
 	// Validate local source exists before attempting the copy.
 	if _, err := os.Stat(srcLocal); err != nil {
-		return fmt.Errorf("source path does not exist: %s: %w", srcLocal, ErrNotValid)
+		return mapError(fmt.Errorf("source path does not exist: %s: %w", srcLocal, ErrNotValid), ResourceKindSandbox, nameOrID)
 	}
 
-	if err := eng.CopyTo(ctx, sb.ID, srcLocal, dstRemote); err != nil {
-		return mapError(fmt.Errorf("could not copy to sandbox: %w", err))
+	if err := eng.CopyTo(ctx, sb.ID, srcLocal, dstRemote, toInternalCopyOpts(opts)); err != nil {
+		return mapError(fmt.Errorf("could not copy to sandbox: %w", err), ResourceKindSandbox, nameOrID)
 	}
 
 	return nil
 }
 
 // CopyFrom copies a file or directory from a running sandbox to the local host.
+// Pass nil opts to use defaults, see [CopyOpts].
 //
 // The sandbox must be in [SandboxStatusRunning] state.
-// For Firecracker sandboxes, this uses SCP over the VM's internal IP.
+// For Firecracker sandboxes, this uses concurrent SFTP transfers (or a compressed
+// tar stream with [CopyOpts].Compress) over the VM's internal IP.
 //
 // Returns [ErrNotFound] if the sandbox does not exist, or [ErrNotValid] if
 // the sandbox is not running.
-func (c *Client) CopyFrom(ctx context.Context, nameOrID string, srcRemote, dstLocal string) error {
+func (c *Client) CopyFrom(ctx context.Context, nameOrID string, srcRemote, dstLocal string, opts *CopyOpts) error {
 	sb, err := c.getInternalSandbox(ctx, nameOrID)
 	if err != nil {
-		return mapError(err)
+		return mapError(err, ResourceKindSandbox, nameOrID)
 	}
 
 	eng, err := c.newEngine(sb.Config)
 	if err != nil {
-		return mapError(fmt.Errorf("could not create engine: %w", err))
+		return mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, nameOrID)
 	}
 
 	if sb.Status != model.SandboxStatusRunning {
-		return mapError(fmt.Errorf("sandbox %s is not running (status: %s): %w", sb.Name, sb.Status, ErrNotValid))
+		return mapError(fmt.Errorf("sandbox %s is not running (status: %s): %w", sb.Name, sb.Status, ErrNotValid), ResourceKindSandbox, nameOrID)
 	}
 
-	if err := eng.CopyFrom(ctx, sb.ID, srcRemote, dstLocal); err != nil {
-		return mapError(fmt.Errorf("could not copy from sandbox: %w", err))
+	if err := eng.CopyFrom(ctx, sb.ID, srcRemote, dstLocal, toInternalCopyOpts(opts)); err != nil {
+		return mapError(fmt.Errorf("could not copy from sandbox: %w", err), ResourceKindSandbox, nameOrID)
 	}
 
 	return nil
@@ -0,0 +1,121 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+
+	"github.com/slok/sbx/internal/app/fileops"
+)
+
+// ReadFile returns the content of the file at the absolute path of a running
+// sandbox. The file is read over the SFTP channel, without running commands in
+// the sandbox.
+//
+// Returns [ErrNotFound] if the sandbox or the file does not exist, or
+// [ErrNotValid] if the sandbox is not running or the path is not absolute.
+func (c *Client) ReadFile(ctx context.Context, nameOrID string, path string) ([]byte, error) {
+	svc, err := c.newFileOpsService(ctx, nameOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := svc.ReadFile(ctx, fileops.Request{NameOrID: nameOrID, Path: path})
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	return data, nil
+}
+
+// WriteFile writes data to the file at the absolute path of a running sandbox,
+// creating or truncating it, and sets its permissions to perm (0 uses 0644). The
+// parent directory must exist.
+//
+// Returns [ErrNotFound] if the sandbox or the parent directory does not exist, or
+// [ErrNotValid] if the sandbox is not running, the path is not absolute or perm
+// has other than permission bits.
+func (c *Client) WriteFile(ctx context.Context, nameOrID string, path string, data []byte, perm fs.FileMode) error {
+	svc, err := c.newFileOpsService(ctx, nameOrID)
+	if err != nil {
+		return err
+	}
+
+	err = svc.WriteFile(ctx, fileops.WriteRequest{
+		Request: fileops.Request{NameOrID: nameOrID, Path: path},
+		Data:    data,
+		Perm:    perm,
+	})
+	if err != nil {
+		return mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	return nil
+}
+
+// ListDir returns the entries of the directory at the absolute path of a running
+// sandbox sorted by name. Symlinks are not followed.
+//
+// Returns [ErrNotFound] if the sandbox or the directory does not exist, or
+// [ErrNotValid] if the sandbox is not running or the path is not absolute.
+func (c *Client) ListDir(ctx context.Context, nameOrID string, path string) ([]FileInfo, error) {
+	svc, err := c.newFileOpsService(ctx, nameOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := svc.ListDir(ctx, fileops.Request{NameOrID: nameOrID, Path: path})
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	out := make([]FileInfo, 0, len(files))
+	for _, f := range files {
+		out = append(out, fromInternalFileInfo(f))
+	}
+	return out, nil
+}
+
+// StatFile returns the information of the file at the absolute path of a running
+// sandbox, following symlinks.
+//
+// Returns [ErrNotFound] if the sandbox or the file does not exist, or
+// [ErrNotValid] if the sandbox is not running or the path is not absolute.
+func (c *Client) StatFile(ctx context.Context, nameOrID string, path string) (*FileInfo, error) {
+	svc, err := c.newFileOpsService(ctx, nameOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := svc.StatFile(ctx, fileops.Request{NameOrID: nameOrID, Path: path})
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	out := fromInternalFileInfo(*info)
+	return &out, nil
+}
+
+// newFileOpsService returns the file operations service using the engine of the sandbox.
+func (c *Client) newFileOpsService(ctx context.Context, nameOrID string) (*fileops.Service, error) {
+	sb, err := c.getInternalSandbox(ctx, nameOrID)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	eng, err := c.newEngine(sb.Config)
+	if err != nil {
+		return nil, mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, nameOrID)
+	}
+
+	svc, err := fileops.NewService(fileops.ServiceConfig{
+		Engine:     eng,
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	return svc, nil
+}
@@ -3,8 +3,11 @@ package lib
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 
 	"github.com/slok/sbx/internal/app/forward"
+	"github.com/slok/sbx/internal/conventions"
+	"github.com/slok/sbx/internal/model"
 )
 
 // Forward establishes port forwarding from the local host to a running sandbox.
@@ -20,26 +23,65 @@ import (
 //	err := client.Forward(ctx, "my-sandbox", []lib.PortMapping{{LocalPort: 8080, RemotePort: 80}})
 //
 // The sandbox must be in [SandboxStatusRunning] state. For Firecracker
-// sandboxes, forwarding uses SSH tunnels.
+// sandboxes, forwarding uses SSH tunnels. The ports with [PortMapping].TLS
+// terminate TLS on the host side, the sandbox service gets plain connections.
 //
 // Returns nil on context cancellation (normal shutdown), [ErrNotFound] if the
-// sandbox does not exist, or [ErrNotValid] if the sandbox is not running or
-// ports are empty.
+// sandbox does not exist, or [ErrNotValid] if the sandbox is not running, is
+// quarantined (also when it's quarantined while forwarding) or ports are empty.
 func (c *Client) Forward(ctx context.Context, nameOrID string, ports []PortMapping) error {
 	sb, err := c.getInternalSandbox(ctx, nameOrID)
 	if err != nil {
-		return mapError(err)
+		return mapError(err, ResourceKindSandbox, nameOrID)
 	}
 
+	return c.forward(ctx, nameOrID, *sb, toInternalPortMappings(ports), nil)
+}
+
+// ForwardWithInfo forwards ports like [Client.Forward] and calls onReady once the
+// local ports listen, with the bound local port of the [PortMapping] with a 0
+// LocalPort. Free ports avoid the collisions of the hard-coded ones (e.g. on CI):
+//
+//	ready := make(chan lib.ForwardInfo, 1)
+//	go func() {
+//	    err := client.ForwardWithInfo(ctx, "my-sandbox", []lib.PortMapping{{RemotePort: 3000}}, func(info lib.ForwardInfo) {
+//	        ready <- info
+//	    })
+//	    ...
+//	}()
+//	info := <-ready
+//	url := fmt.Sprintf("http://localhost:%d", info.Ports[0].LocalPort)
+//
+// onReady is not called when the forwarding fails to start, the errors are the
+// [Client.Forward] ones.
+func (c *Client) ForwardWithInfo(ctx context.Context, nameOrID string, ports []PortMapping, onReady func(ForwardInfo)) error {
+	sb, err := c.getInternalSandbox(ctx, nameOrID)
+	if err != nil {
+		return mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	var ready func([]model.PortMapping)
+	if onReady != nil {
+		ready = func(ports []model.PortMapping) {
+			onReady(ForwardInfo{Ports: fromInternalPortMappings(ports)})
+		}
+	}
+
+	return c.forward(ctx, nameOrID, *sb, toInternalPortMappings(ports), ready)
+}
+
+// forward forwards the ports to the sandbox until the context is cancelled.
+func (c *Client) forward(ctx context.Context, nameOrID string, sb model.Sandbox, ports []model.PortMapping, ready func([]model.PortMapping)) error {
 	eng, err := c.newEngine(sb.Config)
 	if err != nil {
-		return mapError(fmt.Errorf("could not create engine: %w", err))
+		return mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, nameOrID)
 	}
 
 	svc, err := forward.NewService(forward.ServiceConfig{
 		Engine:     eng,
 		Repository: c.repo,
 		Logger:     c.logger,
+		TLSDir:     filepath.Join(c.dataDir, conventions.TLSDir),
 	})
 	if err != nil {
 		return fmt.Errorf("could not create service: %w", err)
@@ -47,11 +89,37 @@ func (c *Client) Forward(ctx context.Context, nameOrID string, ports []PortMappi
 
 	err = svc.Run(ctx, forward.Request{
 		NameOrID: nameOrID,
-		Ports:    toInternalPortMappings(ports),
+		Ports:    ports,
+		Ready:    ready,
 	})
 	if err != nil {
-		return mapError(err)
+		return mapError(err, ResourceKindSandbox, nameOrID)
 	}
 
 	return nil
 }
+
+// ForwardNamed forwards a local port to a named port of a running sandbox (see
+// [CreateSandboxOpts].Ports), a 0 local port uses the sandbox port number. It
+// blocks until the context is cancelled like [Client.Forward]:
+//
+//	err := client.ForwardNamed(ctx, "my-sandbox", "web", 8080)
+//
+// Returns [ErrNotFound] if the sandbox does not exist, or [ErrNotValid] if the
+// sandbox is not running or has no port with the name.
+func (c *Client) ForwardNamed(ctx context.Context, nameOrID, portName string, localPort int) error {
+	sb, err := c.getInternalSandbox(ctx, nameOrID)
+	if err != nil {
+		return mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	remotePort, err := model.ResolveNamedPort(sb.Config.Ports, portName)
+	if err != nil {
+		return mapError(err, ResourceKindSandbox, nameOrID)
+	}
+	if localPort == 0 {
+		localPort = remotePort
+	}
+
+	return c.forward(ctx, nameOrID, *sb, []model.PortMapping{{LocalPort: localPort, RemotePort: remotePort}}, nil)
+}
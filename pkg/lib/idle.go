@@ -0,0 +1,136 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/slok/sbx/internal/app/idlecheck"
+	"github.com/slok/sbx/internal/app/idlepolicy"
+	"github.com/slok/sbx/internal/app/list"
+	"github.com/slok/sbx/internal/app/stop"
+	"github.com/slok/sbx/internal/model"
+)
+
+// SetIdlePolicy sets the idle policy of a sandbox in any status, a nil policy
+// removes it. The running sandboxes idle longer than their policy timeout are
+// suspended by [Client.SuspendIdleSandboxes].
+//
+// Returns [ErrNotFound] if the sandbox does not exist, or [ErrNotValid] if the
+// timeout is not positive or the action is unknown.
+func (c *Client) SetIdlePolicy(ctx context.Context, nameOrID string, policy *IdlePolicy) (*Sandbox, error) {
+	svc, err := idlepolicy.NewService(idlepolicy.ServiceConfig{
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	result, err := svc.Run(ctx, idlepolicy.Request{
+		NameOrID: nameOrID,
+		Policy:   toInternalIdlePolicy(policy),
+	})
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	out := fromInternalSandbox(*result)
+	return &out, nil
+}
+
+// SuspendIdleSandboxes checks the activity of the running sandboxes with an idle
+// policy and suspends the ones idle longer than their policy timeout, emitting the
+// [EventSandboxStopped] and [EventSandboxIdleStopped] events. It returns the
+// suspended sandboxes.
+//
+// sbx has no daemon, call it periodically (e.g. every minute) for the idle policies
+// to be applied. The network traffic is compared between the calls, so they must be
+// more frequent than the idle timeouts.
+//
+// A sandbox that can't be checked or suspended doesn't stop the others, the
+// errors are joined in the returned error with the suspended sandboxes.
+func (c *Client) SuspendIdleSandboxes(ctx context.Context) ([]Sandbox, error) {
+	listSvc, err := list.NewService(list.ServiceConfig{
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	running := model.SandboxStatusRunning
+	sandboxes, err := listSvc.Run(ctx, list.Request{StatusFilter: &running})
+	if err != nil {
+		return nil, mapError(err, "", "")
+	}
+
+	suspended := []Sandbox{}
+	var errs []error
+	for _, sb := range sandboxes {
+		if sb.IdlePolicy == nil {
+			continue
+		}
+
+		result, err := c.suspendIfIdle(ctx, sb)
+		if err != nil {
+			errs = append(errs, mapError(err, ResourceKindSandbox, sb.Name))
+			continue
+		}
+		if result != nil {
+			suspended = append(suspended, fromInternalSandbox(*result))
+		}
+	}
+
+	return suspended, errors.Join(errs...)
+}
+
+// suspendIfIdle stops the sandbox when it's idle, it returns nil when it's not.
+func (c *Client) suspendIfIdle(ctx context.Context, sb model.Sandbox) (*model.Sandbox, error) {
+	eng, err := c.newEngine(sb.Config)
+	if err != nil {
+		return nil, fmt.Errorf("could not create engine: %w", err)
+	}
+
+	checkSvc, err := idlecheck.NewService(idlecheck.ServiceConfig{
+		Engine:     eng,
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	status, err := checkSvc.Run(ctx, idlecheck.Request{NameOrID: sb.ID})
+	if err != nil {
+		return nil, err
+	}
+	if !status.Idle {
+		return nil, nil
+	}
+
+	stopSvc, err := stop.NewService(stop.ServiceConfig{
+		Engine:     eng,
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	result, err := stopSvc.Run(ctx, stop.Request{NameOrID: sb.ID})
+	if err != nil {
+		return nil, err
+	}
+	c.logger.Infof("Stopped sandbox %s idle for %s", sb.Name, status.IdleFor.Round(time.Second))
+
+	c.emitEvent(model.EventTypeSandboxStopped, *result, nil)
+	c.emitEvent(model.EventTypeSandboxIdleStopped, *result, map[string]string{
+		"idle_for":     status.IdleFor.Round(time.Second).String(),
+		"idle_timeout": status.Policy.Timeout.String(),
+		"action":       string(status.Policy.Action),
+	})
+
+	return result, nil
+}
@@ -3,11 +3,20 @@ package lib
 import (
 	"context"
 	"fmt"
+	"io"
+	"sync"
 
+	"github.com/slok/sbx/internal/app/imageadd"
+	"github.com/slok/sbx/internal/app/imagedu"
+	"github.com/slok/sbx/internal/app/imageexport"
+	"github.com/slok/sbx/internal/app/imagefromoci"
+	"github.com/slok/sbx/internal/app/imageimport"
 	"github.com/slok/sbx/internal/app/imageinspect"
 	"github.com/slok/sbx/internal/app/imagelist"
+	"github.com/slok/sbx/internal/app/imageprune"
 	"github.com/slok/sbx/internal/app/imagepull"
 	"github.com/slok/sbx/internal/app/imagerm"
+	"github.com/slok/sbx/internal/image"
 )
 
 // ListImages returns available image releases from the registry and local images.
@@ -36,7 +45,7 @@ func (c *Client) ListImages(ctx context.Context) ([]ImageRelease, error) {
 
 	result, err := svc.Run(ctx)
 	if err != nil {
-		return nil, mapError(err)
+		return nil, mapError(err, "", "")
 	}
 
 	return fromInternalImageReleaseList(result), nil
@@ -45,7 +54,16 @@ func (c *Client) ListImages(ctx context.Context) ([]ImageRelease, error) {
 // PullImage downloads an image release (kernel, rootfs, firecracker binary).
 //
 // Pass nil opts for defaults (no force, silent). Use opts.Force to re-download
-// even if already installed. Use opts.StatusWriter to receive progress output.
+// even if already installed. Use opts.StatusWriter to receive progress output,
+// or opts.OnProgress to receive progress callbacks.
+//
+// Artifacts are downloaded in parallel ranged chunks and verified against the
+// manifest checksums. An interrupted pull resumes the already downloaded chunks
+// when retried.
+//
+// Use opts.Customize to apply commands and files to the pulled rootfs, the
+// customized image is derived and cached as a new local image (see
+// [ImageCustomization]), the base image is kept unchanged.
 //
 // The returned [PullResult] contains local paths to the downloaded artifacts.
 func (c *Client) PullImage(ctx context.Context, version string, opts *PullImageOpts) (*PullResult, error) {
@@ -53,10 +71,15 @@ func (c *Client) PullImage(ctx context.Context, version string, opts *PullImageO
 	if err != nil {
 		return nil, fmt.Errorf("could not create image puller: %w", err)
 	}
+	customizer, err := c.newImageCustomizer()
+	if err != nil {
+		return nil, fmt.Errorf("could not create image customizer: %w", err)
+	}
 
 	svc, err := imagepull.NewService(imagepull.ServiceConfig{
-		Puller: puller,
-		Logger: c.logger,
+		Puller:     puller,
+		Customizer: customizer,
+		Logger:     c.logger,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("could not create service: %w", err)
@@ -68,26 +91,50 @@ func (c *Client) PullImage(ctx context.Context, version string, opts *PullImageO
 	if opts != nil {
 		pullOpts.Force = opts.Force
 		pullOpts.StatusWriter = opts.StatusWriter
+		pullOpts.Concurrency = opts.Concurrency
+		pullOpts.BandwidthLimit = opts.BandwidthLimit
+		pullOpts.Customize = toInternalImageCustomization(opts.Customize)
+		if opts.OnProgress != nil || opts.Progress != nil {
+			var mu sync.Mutex
+			pullOpts.OnProgress = func(p image.PullProgress) {
+				if opts.OnProgress != nil {
+					opts.OnProgress(PullProgress{Artifact: p.Artifact, Downloaded: p.Downloaded, Total: p.Total})
+				}
+				if opts.Progress != nil {
+					mu.Lock()
+					toInternalProgress(opts.Progress).Report(p.ProgressEvent())
+					mu.Unlock()
+				}
+			}
+		}
 	}
 
 	result, err := svc.Run(ctx, pullOpts)
 	if err != nil {
-		return nil, mapError(err)
+		return nil, mapError(err, ResourceKindImage, version)
 	}
 
-	return &PullResult{
+	res := &PullResult{
 		Version:         result.Version,
 		Skipped:         result.Skipped,
 		KernelPath:      result.KernelPath,
 		RootFSPath:      result.RootFSPath,
 		FirecrackerPath: result.FirecrackerPath,
-	}, nil
+	}
+	if result.Customized != nil {
+		res.CustomizedImage = result.Customized.Name
+		res.CustomizedSkipped = result.Customized.Skipped
+	}
+	return res, nil
 }
 
 // RemoveImage deletes a locally installed image (release or snapshot).
 //
 // This removes all downloaded artifacts (kernel, rootfs, firecracker binary)
 // for the given version.
+//
+// Returns [ErrNotValid] if any sandbox was created from the image, remove
+// those sandboxes first.
 func (c *Client) RemoveImage(ctx context.Context, version string) error {
 	mgr, err := c.newLocalImageManager()
 	if err != nil {
@@ -95,20 +142,244 @@ func (c *Client) RemoveImage(ctx context.Context, version string) error {
 	}
 
 	svc, err := imagerm.NewService(imagerm.ServiceConfig{
-		Manager: mgr,
-		Logger:  c.logger,
+		Manager:    mgr,
+		Repository: c.repo,
+		Logger:     c.logger,
 	})
 	if err != nil {
 		return fmt.Errorf("could not create service: %w", err)
 	}
 
 	if err := svc.Run(ctx, imagerm.Request{Version: version}); err != nil {
-		return mapError(err)
+		return mapError(err, ResourceKindImage, version)
 	}
 
 	return nil
 }
 
+// PruneImages removes the locally installed images that no sandbox was created
+// from, and returns the pruned image names.
+//
+// Pass nil opts to prune all unused images. Use opts.OlderThan to keep recently
+// installed images, and opts.DryRun to list what would be pruned.
+func (c *Client) PruneImages(ctx context.Context, opts *PruneImagesOpts) ([]string, error) {
+	mgr, err := c.newLocalImageManager()
+	if err != nil {
+		return nil, fmt.Errorf("could not create image manager: %w", err)
+	}
+
+	svc, err := imageprune.NewService(imageprune.ServiceConfig{
+		Manager:    mgr,
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	req := imageprune.Request{}
+	if opts != nil {
+		req.OlderThan = opts.OlderThan
+		req.DryRun = opts.DryRun
+	}
+
+	pruned, err := svc.Run(ctx, req)
+	if err != nil {
+		return nil, mapError(err, "", "")
+	}
+
+	return pruned, nil
+}
+
+// ExportImage writes a self-contained bundle (tar archive) of a locally installed
+// image with its manifest, kernel, rootfs and firecracker binary, to move it to
+// hosts without network access. Install it there with [Client.ImportImage].
+//
+// Returns [ErrNotFound] if the image is not installed.
+func (c *Client) ExportImage(ctx context.Context, version string, w io.Writer) error {
+	bundler, err := c.newImageBundler()
+	if err != nil {
+		return fmt.Errorf("could not create image bundler: %w", err)
+	}
+
+	svc, err := imageexport.NewService(imageexport.ServiceConfig{
+		Bundler: bundler,
+		Logger:  c.logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	if err := svc.Run(ctx, imageexport.Request{Version: version, Output: w}); err != nil {
+		return mapError(err, ResourceKindImage, version)
+	}
+
+	return nil
+}
+
+// ImportImage installs the image of a bundle created with [Client.ExportImage]
+// (plain or gzip compressed), and returns the imported image name.
+//
+// Pass nil opts for defaults. Returns [ErrAlreadyExists] if the image is already
+// installed, use opts.Force to replace it. Returns [ErrNotValid] if the bundle is
+// invalid or its artifacts don't match the manifest checksums.
+func (c *Client) ImportImage(ctx context.Context, r io.Reader, opts *ImportImageOpts) (string, error) {
+	bundler, err := c.newImageBundler()
+	if err != nil {
+		return "", fmt.Errorf("could not create image bundler: %w", err)
+	}
+
+	svc, err := imageimport.NewService(imageimport.ServiceConfig{
+		Bundler: bundler,
+		Logger:  c.logger,
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not create service: %w", err)
+	}
+
+	req := imageimport.Request{Input: r}
+	if opts != nil {
+		req.Force = opts.Force
+	}
+
+	name, err := svc.Run(ctx, req)
+	if err != nil {
+		return "", mapError(err, ResourceKindImage, "")
+	}
+
+	return name, nil
+}
+
+// AddLocalImage installs an image named name from a user built kernel and rootfs
+// (ext4 image), so custom rootfs builders can create sandboxes with it like with
+// a pulled release. The artifacts are copied and a manifest with their sizes and
+// checksums is generated, the image is listed with the [ImageSourceLocal] source.
+//
+// Pass nil opts for defaults. Returns [ErrAlreadyExists] if the image is already
+// installed, use opts.Force to replace it. Returns [ErrNotValid] if the name is
+// invalid, an artifact does not exist or the rootfs is not an ext4 image.
+func (c *Client) AddLocalImage(ctx context.Context, name, kernelPath, rootfsPath string, opts *AddLocalImageOpts) (*ImageManifest, error) {
+	adder, err := c.newImageAdder()
+	if err != nil {
+		return nil, fmt.Errorf("could not create image adder: %w", err)
+	}
+
+	mgr, err := c.newLocalImageManager()
+	if err != nil {
+		return nil, fmt.Errorf("could not create image manager: %w", err)
+	}
+
+	svc, err := imageadd.NewService(imageadd.ServiceConfig{
+		Adder:   adder,
+		Manager: mgr,
+		Logger:  c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	addOpts := image.AddImageOptions{Name: name, KernelSrc: kernelPath, RootFSSrc: rootfsPath}
+	if opts != nil {
+		addOpts.FirecrackerSrc = opts.FirecrackerPath
+		addOpts.KernelVersion = opts.KernelVersion
+		addOpts.Distro = opts.Distro
+		addOpts.DistroVersion = opts.DistroVersion
+		addOpts.Profile = opts.Profile
+		addOpts.Force = opts.Force
+	}
+
+	manifest, err := svc.Run(ctx, imageadd.Request{Options: addOpts})
+	if err != nil {
+		return nil, mapError(err, ResourceKindImage, name)
+	}
+
+	return fromInternalImageManifest(manifest), nil
+}
+
+// CreateImageFromOCI converts an OCI/Docker image into an installed image named
+// name, so existing container images can be reused as sandbox bases. ociImage is
+// an OCI image layout or `docker save` archive path, or an image reference
+// exported with the docker or podman CLI.
+//
+// The image layers are flattened into an ext4 rootfs with the sbx guest init
+// injected, the kernel and init come from an installed release (opts.BaseImage,
+// the most recently installed one by default). The container image must provide
+// /bin/sh and the OpenSSH server. Requires the e2fsprogs tools.
+//
+// Pass nil opts for defaults. Returns [ErrAlreadyExists] if the image is already
+// installed, use opts.Force to replace it. Returns [ErrNotValid] if the OCI image
+// can't be booted or no release is installed, and [ErrNotFound] if the base image
+// is not installed.
+func (c *Client) CreateImageFromOCI(ctx context.Context, name, ociImage string, opts *CreateImageFromOCIOpts) (*ImageManifest, error) {
+	builder, err := c.newRootFSBuilder()
+	if err != nil {
+		return nil, fmt.Errorf("could not create rootfs builder: %w", err)
+	}
+
+	adder, err := c.newImageAdder()
+	if err != nil {
+		return nil, fmt.Errorf("could not create image adder: %w", err)
+	}
+
+	mgr, err := c.newLocalImageManager()
+	if err != nil {
+		return nil, fmt.Errorf("could not create image manager: %w", err)
+	}
+
+	svc, err := imagefromoci.NewService(imagefromoci.ServiceConfig{
+		Builder: builder,
+		Adder:   adder,
+		Manager: mgr,
+		TmpDir:  c.imagesDir,
+		Logger:  c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	req := imagefromoci.Request{Name: name, Source: ociImage}
+	if opts != nil {
+		req.BaseImage = opts.BaseImage
+		req.KernelPath = opts.KernelPath
+		req.InitPath = opts.InitPath
+		req.SizeMB = opts.SizeMB
+		req.Force = opts.Force
+	}
+
+	manifest, err := svc.Run(ctx, req)
+	if err != nil {
+		return nil, mapError(err, ResourceKindImage, name)
+	}
+
+	return fromInternalImageManifest(manifest), nil
+}
+
+// ImageDiskUsage returns the disk usage of the locally installed images.
+//
+// Artifacts shared by multiple images (e.g. the same kernel) are stored once,
+// the result reports both the per image sizes and the real disk usage.
+func (c *Client) ImageDiskUsage(ctx context.Context) (*ImageDiskUsage, error) {
+	mgr, err := c.newLocalImageManager()
+	if err != nil {
+		return nil, fmt.Errorf("could not create image manager: %w", err)
+	}
+
+	svc, err := imagedu.NewService(imagedu.ServiceConfig{
+		Manager: mgr,
+		Logger:  c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	usage, err := svc.Run(ctx)
+	if err != nil {
+		return nil, mapError(err, "", "")
+	}
+
+	return fromInternalImageDiskUsage(usage), nil
+}
+
 // InspectImage returns the manifest for a locally installed image.
 //
 // The manifest contains artifact metadata, Firecracker version info, and
@@ -129,7 +400,7 @@ func (c *Client) InspectImage(ctx context.Context, version string) (*ImageManife
 
 	result, err := svc.Run(ctx, imageinspect.Request{Version: version})
 	if err != nil {
-		return nil, mapError(err)
+		return nil, mapError(err, ResourceKindImage, version)
 	}
 
 	// Replace artifact file names with full local paths.
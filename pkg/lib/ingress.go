@@ -0,0 +1,160 @@
+package lib
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/slok/sbx/internal/conventions"
+	"github.com/slok/sbx/internal/ingress"
+	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/tlscert"
+)
+
+// ingressCloseTimeout is the maximum time the ingress waits for the active requests
+// when it's stopped.
+const ingressCloseTimeout = 5 * time.Second
+
+// ExposeHTTP exposes an HTTP service of a running sandbox on the host ingress,
+// routing http://<sandbox-name>.localhost:8080 to the guestPort of the sandbox.
+// Expose many sandboxes on the same ingress without managing port numbers, the
+// *.localhost names resolve to the loopback without DNS configuration:
+//
+//	go client.ExposeHTTP(ctx, "api", 3000, lib.ExposeHTTPOpts{})
+//	go client.ExposeHTTP(ctx, "web", 8080, lib.ExposeHTTPOpts{})
+//	// http://api.localhost:8080 and http://web.localhost:8080
+//
+// The ingress listens while the client exposes sandboxes on it, with
+// [ExposeHTTPOpts].TLS it serves https. The requests are tunneled like
+// [Client.Forward] and keep their Host header, with the X-Forwarded-* headers set.
+// It blocks until the context is cancelled.
+//
+// Returns nil on context cancellation, [ErrNotFound] if the sandbox does not
+// exist, [ErrAlreadyExists] if the sandbox is already exposed on the ingress, or
+// [ErrNotValid] if the sandbox is not running, is quarantined or its name is not a
+// valid hostname label.
+func (c *Client) ExposeHTTP(ctx context.Context, nameOrID string, guestPort int, opts ExposeHTTPOpts) error {
+	sb, err := c.getInternalSandbox(ctx, nameOrID)
+	if err != nil {
+		return mapError(err, ResourceKindSandbox, nameOrID)
+	}
+	if guestPort < 1 || guestPort > 65535 {
+		return mapError(fmt.Errorf("guest port %d out of range (1-65535): %w", guestPort, model.ErrNotValid), ResourceKindSandbox, nameOrID)
+	}
+
+	address := opts.Address
+	if address == "" {
+		address = ingress.DefaultAddress
+	}
+	srv, err := c.acquireIngress(address, opts.TLS)
+	if err != nil {
+		return mapError(err, ResourceKindSandbox, nameOrID)
+	}
+	defer c.releaseIngress(address)
+
+	// The ingress routes to the local end of a port forward on a free port.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var routeErr error
+	ready := func(ports []model.PortMapping) {
+		target := net.JoinHostPort("127.0.0.1", strconv.Itoa(ports[0].LocalPort))
+		if routeErr = srv.AddRoute(sb.Name, target); routeErr != nil {
+			cancel()
+			return
+		}
+		if opts.OnReady != nil {
+			opts.OnReady(srv.URL(sb.Name))
+		}
+	}
+
+	err = c.forward(ctx, nameOrID, *sb, []model.PortMapping{{BindAddress: "127.0.0.1", RemotePort: guestPort}}, ready)
+	if routeErr != nil {
+		return mapError(routeErr, ResourceKindSandbox, nameOrID)
+	}
+	srv.RemoveRoute(sb.Name)
+	return err
+}
+
+// ingressRef is an ingress server shared by the sandboxes exposed on its address.
+type ingressRef struct {
+	srv   *ingress.Server
+	tls   ForwardTLS
+	https bool
+	users int
+}
+
+// acquireIngress returns the ingress server of an address, started on the first use.
+func (c *Client) acquireIngress(address string, tlsOpts *ForwardTLS) (*ingress.Server, error) {
+	c.ingressMu.Lock()
+	defer c.ingressMu.Unlock()
+
+	if ref, ok := c.ingresses[address]; ok {
+		if ref.https != (tlsOpts != nil) || (tlsOpts != nil && ref.tls != *tlsOpts) {
+			return nil, fmt.Errorf("ingress %s is used with a different tls: %w", address, model.ErrNotValid)
+		}
+		ref.users++
+		return ref.srv, nil
+	}
+
+	ref := &ingressRef{users: 1}
+	cfg := ingress.ServerConfig{Address: address, Logger: c.logger}
+	if tlsOpts != nil {
+		tlsConfig, err := c.ingressTLSConfig(*tlsOpts)
+		if err != nil {
+			return nil, err
+		}
+		cfg.TLS = tlsConfig
+		ref.tls = *tlsOpts
+		ref.https = true
+	}
+
+	srv, err := ingress.NewServer(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("could not start ingress: %w", err)
+	}
+	ref.srv = srv
+	c.ingresses[address] = ref
+
+	return srv, nil
+}
+
+// ingressTLSConfig returns the TLS configuration of the ingress, with the generated
+// localhost certificate when the certificate files are not set.
+func (c *Client) ingressTLSConfig(opts ForwardTLS) (*tls.Config, error) {
+	t, err := tlscert.Resolve(model.ForwardTLS{CertFile: opts.CertFile, KeyFile: opts.KeyFile}, filepath.Join(c.dataDir, conventions.TLSDir))
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := tlscert.ServerConfig(t.CertFile, t.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ingress tls: %w: %w", err, model.ErrNotValid)
+	}
+	return tlsConfig, nil
+}
+
+// releaseIngress releases an ingress server, it's stopped when no sandbox is
+// exposed on it.
+func (c *Client) releaseIngress(address string) {
+	c.ingressMu.Lock()
+	defer c.ingressMu.Unlock()
+
+	ref, ok := c.ingresses[address]
+	if !ok {
+		return
+	}
+	ref.users--
+	if ref.users > 0 {
+		return
+	}
+	delete(c.ingresses, address)
+	ctx, cancel := context.WithTimeout(context.Background(), ingressCloseTimeout)
+	defer cancel()
+	if err := ref.srv.Close(ctx); err != nil {
+		c.logger.Warningf("Could not stop ingress %s: %v", address, err)
+	}
+}
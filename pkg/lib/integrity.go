@@ -0,0 +1,45 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/app/integrityverify"
+)
+
+// VerifyIntegrity hashes the guest files under the integrity paths of a running
+// sandbox and returns the files modified, added or removed since the baseline
+// captured when it started (see [StartSandboxOpts].IntegrityPaths). Use it to
+// detect the files tampered by an untrusted workload before promoting artifacts
+// out of the sandbox.
+//
+// Returns [ErrNotFound] if the sandbox does not exist or has no integrity
+// baseline, and [ErrNotValid] if it is not running.
+func (c *Client) VerifyIntegrity(ctx context.Context, nameOrID string) (*IntegrityReport, error) {
+	sb, err := c.getInternalSandbox(ctx, nameOrID)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	eng, err := c.newEngine(sb.Config)
+	if err != nil {
+		return nil, mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, nameOrID)
+	}
+
+	svc, err := integrityverify.NewService(integrityverify.ServiceConfig{
+		Engine:     eng,
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	report, err := svc.Run(ctx, integrityverify.Request{NameOrID: nameOrID})
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	out := fromInternalIntegrityReport(*report)
+	return &out, nil
+}
@@ -0,0 +1,48 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/app/logs"
+)
+
+// Logs returns the last lines of the engine logs of a sandbox: the Firecracker
+// VMM log and the egress proxy logs of each network interface. The logs are kept
+// across restarts until the sandbox is removed. Pass nil opts to get the default
+// number of lines, see [LogsOpts].
+//
+// Returns [ErrNotFound] if the sandbox does not exist, or [ErrNotValid] if the
+// options are not valid.
+func (c *Client) Logs(ctx context.Context, nameOrID string, opts *LogsOpts) ([]SandboxLog, error) {
+	sb, err := c.getInternalSandbox(ctx, nameOrID)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	eng, err := c.newEngine(sb.Config)
+	if err != nil {
+		return nil, mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, nameOrID)
+	}
+
+	svc, err := logs.NewService(logs.ServiceConfig{
+		Engine:     eng,
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	req := logs.Request{NameOrID: sb.ID}
+	if opts != nil {
+		req.Lines = opts.Lines
+	}
+
+	sbLogs, err := svc.Run(ctx, req)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	return fromInternalSandboxLogs(sbLogs), nil
+}
@@ -0,0 +1,49 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/app/memorytarget"
+)
+
+// SetMemoryTarget sets the memory (in MB) a running sandbox keeps, the rest of
+// its memory is released back to the host.
+//
+// Firecracker sandboxes reclaim the memory with a balloon device, when the guest
+// needs the memory back it is returned on demand (the balloon deflates on OOM).
+// A targetMB of 0 gives back all the sandbox memory.
+//
+// Returns [ErrNotFound] if the sandbox does not exist, or [ErrNotValid] if the
+// sandbox is not running or the target is greater than the sandbox memory.
+func (c *Client) SetMemoryTarget(ctx context.Context, nameOrID string, targetMB int) (*Sandbox, error) {
+	sb, err := c.getInternalSandbox(ctx, nameOrID)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	eng, err := c.newEngine(sb.Config)
+	if err != nil {
+		return nil, mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, nameOrID)
+	}
+
+	svc, err := memorytarget.NewService(memorytarget.ServiceConfig{
+		Engine:     eng,
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	result, err := svc.Run(ctx, memorytarget.Request{
+		NameOrID: nameOrID,
+		TargetMB: targetMB,
+	})
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	out := fromInternalSandbox(*result)
+	return &out, nil
+}
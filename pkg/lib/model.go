@@ -1,10 +1,18 @@
 package lib
 
 import (
+	"context"
+	"errors"
 	"io"
+	"io/fs"
+	"maps"
+	"slices"
 	"time"
 
+	"github.com/slok/sbx/internal/capacity"
+	"github.com/slok/sbx/internal/log"
 	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/storage"
 )
 
 // EngineType identifies the sandbox engine implementation.
@@ -47,8 +55,10 @@ const (
 type Sandbox struct {
 	// ID is the unique identifier (ULID) assigned at creation.
 	ID string
-	// Name is the human-friendly name.
+	// Name is the human-friendly name, unique in its namespace.
 	Name string
+	// Namespace is the namespace of the sandbox, see [Config].Namespace.
+	Namespace string
 	// Status is the current lifecycle state.
 	Status SandboxStatus
 	// Config is the static configuration set at creation time.
@@ -59,6 +69,123 @@ type Sandbox struct {
 	StartedAt *time.Time
 	// StoppedAt is when the sandbox was last stopped. Nil if never stopped.
 	StoppedAt *time.Time
+	// BootPhases are the durations of the start phases in order (e.g. "boot",
+	// "ssh", "guest_setup"). Only set on the sandbox returned by [Client.StartSandbox].
+	BootPhases []BootPhase
+	// Egress is the health of the egress proxies. Only set on running sandboxes with an
+	// egress policy returned by [Client.GetSandbox].
+	Egress *EgressStatus
+	// Seccomp is the seccomp enforcement of the VM process. Only set on running
+	// firecracker sandboxes returned by [Client.GetSandbox].
+	Seccomp *SeccompStatus
+	// RootFSStrategy is how the sandbox rootfs was provisioned from its image on
+	// creation. Empty for sandboxes created before it was recorded.
+	RootFSStrategy RootFSStrategy
+	// Annotations are the free-form metadata of the sandbox, set with
+	// [Client.AnnotateSandbox].
+	Annotations map[string]string
+	// IdlePolicy is the idle policy of the sandbox, set with [Client.SetIdlePolicy].
+	// Nil without one.
+	IdlePolicy *IdlePolicy
+	// LastActivityAt is the last time a command ran in the sandbox or its network
+	// traffic was seen by [Client.SuspendIdleSandboxes]. Nil if never.
+	LastActivityAt *time.Time
+	// QuarantinedAt is when the sandbox was quarantined by [Client.QuarantineSandbox].
+	// Nil if it isn't quarantined.
+	QuarantinedAt *time.Time
+	// Persistent sandboxes are started again by [Client.RecoverSandboxes] when the
+	// host reboot killed them while running.
+	Persistent bool
+	// Session is the session configuration of the last start, applied again by
+	// [Client.RestartSandbox] and by the starts without one. Nil if never started.
+	Session *SandboxSession
+	// LastBootPhases are the phases of the last start in order, including the failed
+	// one with its error when it failed. Nil if never started.
+	LastBootPhases []BootPhase
+	// LastError is the error of the last start, empty if it succeeded.
+	LastError string
+}
+
+// SandboxSession is the session configuration ([StartSandboxOpts]) of the last
+// start of a sandbox.
+type SandboxSession struct {
+	// Env are the session environment variables, the values of the secret looking
+	// ones (e.g. GITHUB_TOKEN, DB_PASSWORD) are replaced by "<redacted>".
+	Env map[string]string
+	// Egress is the egress policy, nil without egress filtering.
+	Egress *EgressPolicy
+	// EgressPolicyName is the name of the stored egress policy.
+	EgressPolicyName string
+	// IntegrityPaths are the guest paths of the integrity baseline.
+	IntegrityPaths []string
+}
+
+// IdleAction is what happens to a sandbox idle longer than its idle policy timeout.
+type IdleAction string
+
+const (
+	// IdleActionStop stops the idle sandbox, it's the only action: the engines
+	// can't pause and resume sandboxes.
+	IdleActionStop IdleAction = "stop"
+)
+
+// IdlePolicy suspends a running sandbox without activity for a while. The
+// commands (including the shell sessions) and the network traffic of the sandbox
+// (including the forwarded connections) are its activity.
+type IdlePolicy struct {
+	// Timeout is how long the sandbox can be idle before it's suspended, required.
+	Timeout time.Duration
+	// Action is how the idle sandbox is suspended (default: [IdleActionStop]).
+	Action IdleAction
+}
+
+const (
+	// DefaultNamespace is the namespace of the clients without [Config].Namespace,
+	// and of the sandboxes created before the namespaces existed.
+	DefaultNamespace = "default"
+	// AllNamespaces scopes a client to the sandboxes of every namespace, for the
+	// administration tools of a shared host.
+	AllNamespaces = "*"
+)
+
+// Namespace is a namespace with sandboxes, returned by [Client.ListNamespaces].
+type Namespace struct {
+	Name             string
+	Sandboxes        int
+	RunningSandboxes int
+}
+
+// EgressHealth is the health of the sandbox egress proxies.
+type EgressHealth string
+
+const (
+	// EgressHealthHealthy means all the egress proxies are running.
+	EgressHealthHealthy EgressHealth = "healthy"
+	// EgressHealthDegraded means an egress proxy is down, the filtered traffic is
+	// blocked until it's restarted.
+	EgressHealthDegraded EgressHealth = "degraded"
+)
+
+// EgressStatus is the runtime status of the sandbox egress proxies.
+type EgressStatus struct {
+	// Health is the aggregated health of the proxies.
+	Health EgressHealth
+	// Restarts is the number of times the proxies were restarted after crashing.
+	Restarts int
+	// LastError is the last error of a degraded proxy.
+	LastError string
+	// UpdatedAt is when the proxies health last changed.
+	UpdatedAt time.Time
+}
+
+// BootPhase is the duration of a sandbox start phase.
+type BootPhase struct {
+	// Name is the phase name.
+	Name string
+	// Duration is how long the phase took.
+	Duration time.Duration
+	// Error is the error of the phase that failed the start, empty otherwise.
+	Error string
 }
 
 // SandboxConfig is the immutable configuration of a sandbox, set at creation time.
@@ -67,8 +194,52 @@ type SandboxConfig struct {
 	Name string
 	// Firecracker holds Firecracker-specific config. Nil for non-Firecracker engines.
 	Firecracker *FirecrackerConfig
+	// Image is the local image the sandbox was created from. Empty when created
+	// from explicit kernel and rootfs paths.
+	Image string
 	// Resources defines the compute resources allocated to the sandbox.
 	Resources Resources
+	// UserData is the provisioning executed on the sandbox first boot.
+	UserData string
+	// Clock is the guest clock configuration. Nil means the guest uses the host time.
+	Clock *ClockOptions
+	// Ports are the named ports of the sandbox services, see [CreateSandboxOpts].Ports.
+	Ports map[string]int
+	// ExecProfile is the environment setup of the execs and shells. Nil uses the
+	// guest defaults.
+	ExecProfile *ExecProfile
+}
+
+// ExecProfile is the environment setup applied to every [Client.Exec] and shell of
+// a sandbox, so the callers don't need to prefix the commands with it. It's applied
+// on every start with the session environment, after [SessionConfig].Env.
+type ExecProfile struct {
+	// PathPrepend are the guest directories added in front of the PATH, in order.
+	PathPrepend []string
+	// Locale is set as LANG and LC_ALL (e.g. "C.UTF-8"). Empty keeps the guest default.
+	Locale string
+	// Umask is the octal file mode creation mask (e.g. "027"). Empty keeps the
+	// guest default.
+	Umask string
+	// Ulimits are the resource limits by name, the values are numbers or
+	// "unlimited" (e.g. "nofile": "65536"). Names: as, core, cpu, data, fsize,
+	// memlock, nofile, nproc and stack. The limits the guest doesn't allow are ignored.
+	Ulimits map[string]string
+	// Shell is the interactive shell of 'sbx shell' (e.g. "/bin/bash"), also
+	// exported as SHELL. Empty uses /bin/sh.
+	Shell string
+}
+
+// ClockOptions isolates the guest clock from the host, e.g. to run time dependent
+// tests against arbitrary dates. The guest time sync daemons (NTP) are stopped and the
+// clock is set on every start, then it runs normally.
+type ClockOptions struct {
+	// BootTime is the date set on the guest clock on every start. Zero means the
+	// host time plus the offset.
+	BootTime time.Time
+	// Offset is added to the host time on every start (e.g. -24h). Can't be used
+	// with BootTime.
+	Offset time.Duration
 }
 
 // FirecrackerConfig contains Firecracker microVM engine-specific settings.
@@ -77,6 +248,120 @@ type FirecrackerConfig struct {
 	RootFS string
 	// KernelImage is the path to the kernel binary (vmlinux).
 	KernelImage string
+	// KernelArgs are extra kernel command line arguments (e.g. "quiet"). The ones
+	// with the same key as a default argument (e.g. "panic=5") override it. The
+	// arguments managed by the engine (ip, root, ro, rw, init and console) can't be set.
+	KernelArgs []string
+	// Boot are the guest boot options.
+	Boot BootOptions
+	// Networks are additional network interfaces (eth1, eth2...), up to 4. eth0
+	// is always the NAT interface managed by sbx (used for exec, shell, etc.) and
+	// keeps the default route.
+	Networks []NetworkInterface
+	// Seccomp is the syscall filtering of the Firecracker process (default: the
+	// filters built in Firecracker).
+	Seccomp SeccompOptions
+	// EgressInterface is the host network interface the NAT traffic of the sandbox
+	// (eth0 and the NAT Networks) goes out through, e.g. "wg0" for VPN only egress.
+	// Default: "" ([Config].EgressInterface).
+	EgressInterface string
+	// Network are the MTU and TCP MSS clamping options of the sandbox network
+	// interfaces.
+	Network NetworkOptions
+}
+
+// NetworkOptions are the MTU and TCP MSS clamping options of the sandbox network
+// interfaces, for hosts whose uplink has a lower MTU than the Ethernet default (e.g.
+// VPN tunnels), where the large transfers hang otherwise.
+type NetworkOptions struct {
+	// MTU is the MTU of the host TAP devices and the guest interfaces, between 576
+	// and 9000. Default: 0 (1500).
+	MTU int
+	// ClampMSS sets the MSS of the TCP connections opened by the sandbox to the MTU
+	// of the host route they go out through.
+	ClampMSS bool
+}
+
+// SeccompMode is the seccomp filtering mode of the sandbox VM process.
+type SeccompMode string
+
+const (
+	// SeccompModeDefault uses the seccomp filters built in Firecracker.
+	SeccompModeDefault SeccompMode = ""
+	// SeccompModeDisabled runs the VM process without seccomp filters, only for
+	// hosts whose kernel doesn't support them.
+	SeccompModeDisabled SeccompMode = "disabled"
+	// SeccompModeCustom uses the custom seccomp filters of [SeccompOptions].Filter.
+	SeccompModeCustom SeccompMode = "custom"
+)
+
+// SeccompOptions are the seccomp options of the sandbox VM process.
+type SeccompOptions struct {
+	// Mode is the filtering mode.
+	Mode SeccompMode
+	// Filter is the absolute path of the compiled custom filters (e.g. by
+	// seccompiler-bin), required by [SeccompModeCustom].
+	Filter string
+}
+
+// SeccompStatus is the seccomp enforcement of a running sandbox VM process.
+type SeccompStatus struct {
+	// Enforced is true when the process runs with seccomp filters.
+	Enforced bool
+	// Filters is the number of filters installed on the process, 0 when the host
+	// kernel doesn't report it.
+	Filters int
+}
+
+// RootFSStrategy is how the rootfs of a sandbox is provisioned from its image.
+type RootFSStrategy string
+
+const (
+	// RootFSStrategyReflink is an instant copy-on-write clone of the image (btrfs,
+	// XFS with reflinks, ZFS block cloning).
+	RootFSStrategyReflink RootFSStrategy = "reflink"
+	// RootFSStrategySparse is a copy of the image data that keeps the holes.
+	RootFSStrategySparse RootFSStrategy = "sparse"
+	// RootFSStrategyCopy is a full copy of the image.
+	RootFSStrategyCopy RootFSStrategy = "copy"
+)
+
+// NetworkMode is the mode of a sandbox network interface.
+type NetworkMode string
+
+const (
+	// NetworkModeNAT gives the interface outbound access through the host network (NAT).
+	NetworkModeNAT NetworkMode = "nat"
+	// NetworkModeIsolated connects the interface to a private network shared with the
+	// sandboxes attached to the same network name, without outbound nor host access.
+	NetworkModeIsolated NetworkMode = "isolated"
+)
+
+// NetworkInterface is an additional sandbox network interface.
+type NetworkInterface struct {
+	// Mode is the interface mode (required).
+	Mode NetworkMode
+	// Network is the isolated network name (required for [NetworkModeIsolated]).
+	Network string
+	// Egress is the egress policy of [NetworkModeNAT] interfaces. Nil means no
+	// egress filtering. It's independent of the session egress policy (eth0).
+	Egress *EgressPolicy
+	// Address is the interface guest IP (/24), assigned on creation. Ignored on create.
+	Address string
+}
+
+// BootOptions are the guest boot options.
+type BootOptions struct {
+	// ReadOnlyRootFS attaches the rootfs as read-only. The image init must provide
+	// the writable paths the guest needs (e.g. a tmpfs overlay).
+	ReadOnlyRootFS bool
+	// DisableConsole disables the guest serial console, this speeds up the boot.
+	DisableConsole bool
+	// Init overrides the guest init binary path (absolute).
+	Init string
+	// Profile is the kernel profile the sandbox was created with (see
+	// [CreateSandboxOpts].Profile), ignored on create.
+	Profile string
 }
 
 // Resources defines the compute resources for a sandbox.
@@ -87,6 +372,311 @@ type Resources struct {
 	MemoryMB int
 	// DiskGB is the disk size in gigabytes.
 	DiskGB int
+	// MaxVCPUs and MaxMemoryMB are the ceilings [Client.ScaleSandbox] can scale a
+	// running sandbox up to, the sandbox boots with them and releases the rest.
+	// Zero means no scaling above VCPUs and MemoryMB.
+	MaxVCPUs    float64
+	MaxMemoryMB int
+}
+
+// AdmissionMode is how [Client.CreateSandbox] and [Client.StartSandbox] handle the
+// sandboxes exceeding the host capacity.
+type AdmissionMode string
+
+const (
+	// AdmissionModeOff doesn't check the host capacity.
+	AdmissionModeOff AdmissionMode = "off"
+	// AdmissionModeWarn logs a warning when the host capacity is exceeded.
+	AdmissionModeWarn AdmissionMode = "warn"
+	// AdmissionModeEnforce refuses the sandboxes exceeding the host capacity with [ErrNotValid].
+	AdmissionModeEnforce AdmissionMode = "enforce"
+)
+
+// AdmissionConfig configures the host capacity admission check.
+type AdmissionConfig struct {
+	// Mode is the admission mode. Default: [AdmissionModeOff].
+	Mode AdmissionMode
+	// Overcommit are the ratios of the host resources that can be allocated.
+	// Zero fields use the defaults: 4 for VCPUs, 1 for memory and disk.
+	Overcommit OvercommitRatios
+}
+
+// OvercommitRatios are the ratios of the host resources that can be allocated to the
+// sandboxes, e.g. 2 allows allocating twice the host VCPUs.
+type OvercommitRatios struct {
+	VCPUs  float64
+	Memory float64
+	Disk   float64
+}
+
+// SnapshotStoreConfig configures the object storage sharing the snapshot images
+// between hosts, see [Client.PushSnapshot]. Any S3 compatible storage works: AWS S3,
+// MinIO (with Endpoint) and Google Cloud Storage (with HMAC keys).
+type SnapshotStoreConfig struct {
+	// URL is the bucket and key prefix of the snapshots: s3://BUCKET[/PREFIX] or
+	// gs://BUCKET[/PREFIX] (required).
+	URL string
+	// Endpoint overrides the object storage endpoint, e.g. http://localhost:9000 for
+	// MinIO. Default: the AWS S3 regional endpoint or the Google Cloud Storage one.
+	Endpoint string
+	// Region is the bucket region. Default: AWS_REGION env var or us-east-1.
+	Region string
+	// AccessKeyID, SecretAccessKey and SessionToken are the storage credentials.
+	// Default: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN env
+	// vars, anonymous requests without them.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Encryption is the server-side encryption of the uploaded snapshots.
+	// Default: the bucket default encryption.
+	Encryption SnapshotStoreEncryption
+	// KMSKeyID is the KMS key of [SnapshotStoreEncryptionKMS]. Default: the bucket
+	// default KMS key.
+	KMSKeyID string
+	// PartSizeBytes is the size of the multipart upload parts, buffered in memory
+	// while uploaded (minimum 5 MiB). Default: 16 MiB.
+	PartSizeBytes int64
+}
+
+// SnapshotStoreEncryption is the server-side encryption of the stored snapshots.
+type SnapshotStoreEncryption string
+
+const (
+	// SnapshotStoreEncryptionDefault uses the bucket default encryption.
+	SnapshotStoreEncryptionDefault SnapshotStoreEncryption = ""
+	// SnapshotStoreEncryptionAES256 encrypts with the keys managed by the storage (SSE-S3).
+	SnapshotStoreEncryptionAES256 SnapshotStoreEncryption = "AES256"
+	// SnapshotStoreEncryptionKMS encrypts with a KMS key (SSE-KMS).
+	SnapshotStoreEncryptionKMS SnapshotStoreEncryption = "aws:kms"
+)
+
+// RemoteSnapshot is a snapshot image stored in the snapshot store.
+type RemoteSnapshot struct {
+	// Name is the snapshot image name.
+	Name string
+	// URL is the snapshot object URL, e.g. s3://bucket/prefix/name.tar.
+	URL string
+	// SizeBytes is the size of the stored snapshot bundle.
+	SizeBytes int64
+	// Parts is the number of uploaded parts (1 without multipart upload).
+	Parts int
+}
+
+// EventSinkType is the destination type of the structured events, see [Config].EventSinks.
+type EventSinkType string
+
+const (
+	// EventSinkFile appends the events as JSON lines to a file.
+	EventSinkFile EventSinkType = "file"
+	// EventSinkJournald sends the events to the systemd journal, with the event
+	// fields as journal fields (e.g. journalctl SBX_EVENT_TYPE=egress.denied).
+	EventSinkJournald EventSinkType = "journald"
+	// EventSinkWebhook POSTs the events as JSON to a URL.
+	EventSinkWebhook EventSinkType = "webhook"
+)
+
+// EventSink is a destination of the structured lifecycle and security events.
+type EventSink struct {
+	Type EventSinkType
+	// Path is the absolute path of the [EventSinkFile] file.
+	Path string
+	// URL is the http:// or https:// endpoint of the [EventSinkWebhook] sink.
+	URL string
+	// Secret signs the [EventSinkWebhook] payloads, see [Webhook].Secret.
+	Secret string
+	// Events are the event types sent to the sink. Empty sends all of them.
+	Events []EventType
+}
+
+// Webhook is an http:// or https:// endpoint notified of the events of a sandbox,
+// see [CreateSandboxOpts].Webhooks.
+//
+// The events are POSTed as JSON with the X-Sbx-Event header set to the event type
+// and the X-Sbx-Delivery header set to an ID that is the same on the retries. The
+// failed requests (network errors, 429 and 5xx responses) are retried 3 times with
+// an exponential backoff.
+type Webhook struct {
+	URL string
+	// Secret signs the payloads: the X-Sbx-Signature-256 header is "sha256=" and the
+	// hex HMAC-SHA256 of the request body with the secret. Empty doesn't sign them.
+	Secret string
+	// Events are the event types notified. Empty notifies all of them.
+	Events []EventType
+}
+
+// EventType is the type of a structured event, the "type" field of its JSON format.
+type EventType string
+
+const (
+	// EventSandboxCreated is emitted when a sandbox is created (or cloned).
+	EventSandboxCreated EventType = "sandbox.created"
+	// EventSandboxStarted is emitted when a sandbox is started.
+	EventSandboxStarted EventType = "sandbox.started"
+	// EventSandboxStopped is emitted when a sandbox is stopped.
+	EventSandboxStopped EventType = "sandbox.stopped"
+	// EventSandboxIdleStopped is emitted when a sandbox idle longer than its idle
+	// policy timeout is stopped by [Client.SuspendIdleSandboxes], after its
+	// [EventSandboxStopped] event.
+	EventSandboxIdleStopped EventType = "sandbox.idle_stopped"
+	// EventSandboxRemoved is emitted when a sandbox is removed.
+	EventSandboxRemoved EventType = "sandbox.removed"
+	// EventSnapshotCreated is emitted when a snapshot image is created from a sandbox.
+	EventSnapshotCreated EventType = "snapshot.created"
+	// EventEgressDenied is emitted when the egress proxy of a sandbox denies a
+	// request, connection or DNS query.
+	EventEgressDenied EventType = "egress.denied"
+	// EventProxyCrashed is emitted when the egress proxy of a sandbox crashes, it's
+	// restarted by its supervisor.
+	EventProxyCrashed EventType = "proxy.crashed"
+	// EventSandboxQuarantined is emitted when a sandbox is quarantined by
+	// [Client.QuarantineSandbox].
+	EventSandboxQuarantined EventType = "sandbox.quarantined"
+	// EventSandboxQuarantineReleased is emitted when the quarantine of a sandbox is lifted by
+	// [Client.ReleaseQuarantine].
+	EventSandboxQuarantineReleased EventType = "sandbox.quarantine_released"
+)
+
+// HookEvent is a sandbox lifecycle event the host hooks run on, see [Config].Hooks.
+type HookEvent string
+
+const (
+	// HookPreCreate runs before a sandbox is created, a failed hook aborts the
+	// creation. The sandbox has no ID yet.
+	HookPreCreate HookEvent = "pre-create"
+	// HookPostStart runs after a sandbox is started.
+	HookPostStart HookEvent = "post-start"
+	// HookPreStop runs before a running sandbox is stopped, a failed hook aborts the
+	// stop (not the forced removals).
+	HookPreStop HookEvent = "pre-stop"
+	// HookPostRemove runs after a sandbox is removed.
+	HookPostRemove HookEvent = "post-remove"
+)
+
+// Hook runs on the host on a sandbox lifecycle event to integrate sbx with other
+// systems (e.g. register the sandbox IP in a DNS, update an inventory). Not to be
+// confused with the [StopSandboxOpts].PreStopHooks run inside the guest.
+//
+// The failures of the pre hooks abort the operation with their error, the post
+// ones are only logged.
+type Hook struct {
+	Event HookEvent
+	// Command is the executable and its arguments. The sandbox is passed in the
+	// SBX_HOOK_EVENT, SBX_SANDBOX_ID, SBX_SANDBOX_NAME, SBX_SANDBOX_NAMESPACE,
+	// SBX_SANDBOX_STATUS, SBX_SANDBOX_IMAGE and SBX_SANDBOX_IP environment variables,
+	// and as JSON in the standard input.
+	Command []string
+	// Func is called instead of running a command.
+	Func func(ctx context.Context, event HookEvent, sb Sandbox) error
+	// Timeout cancels the hook.
+	// Default: 30s.
+	Timeout time.Duration
+}
+
+// HostCapacity is the host capacity and its allocation to the sandboxes.
+type HostCapacity struct {
+	// Total are the host resources (CPUs, memory and the data dir filesystem size).
+	Total Resources
+	// Allocatable are the host resources with the overcommit ratios applied.
+	Allocatable Resources
+	// Allocated are the resources allocated to the sandboxes. VCPUs and memory are
+	// allocated by the running sandboxes, disk by all of them.
+	Allocated Resources
+	// Free are the allocatable resources not allocated yet, they can be negative.
+	Free Resources
+	// Overcommit are the overcommit ratios in use.
+	Overcommit OvercommitRatios
+	// Sandboxes is the number of sandboxes.
+	Sandboxes int
+	// RunningSandboxes is the number of running sandboxes.
+	RunningSandboxes int
+}
+
+// Reservation are the host resources reserved by a sandbox, see
+// [Client.Reservations].
+type Reservation struct {
+	// SandboxID is the sandbox ID, the name of its manifest file.
+	SandboxID string
+	// Name is the sandbox name.
+	Name string
+	// Namespace is the sandbox namespace, empty for the default one.
+	Namespace string
+	// Status is the sandbox status.
+	Status SandboxStatus
+	// Resources are the resources configured in the sandbox.
+	Resources Resources
+	// Reserved are the resources the sandbox holds now, allocated like the
+	// [HostCapacity] ones: the disk always, the VCPUs and memory while it runs.
+	Reserved Resources
+	// UpdatedAt is when the reservation was written.
+	UpdatedAt time.Time
+}
+
+// BootRestartPolicy is what systemd does when the boot start of a sandbox fails,
+// see [Client.EnableBootPersistence].
+type BootRestartPolicy string
+
+const (
+	// BootRestartPolicyNo leaves the sandbox stopped when its start fails.
+	BootRestartPolicyNo BootRestartPolicy = "no"
+	// BootRestartPolicyOnFailure retries the failed starts (e.g. the host network
+	// is not ready yet), up to the systemd start limit.
+	BootRestartPolicyOnFailure BootRestartPolicy = "on-failure"
+)
+
+// BootPersistenceOpts configures the boot unit of [Client.EnableBootPersistence].
+type BootPersistenceOpts struct {
+	// Restart is the restart policy of the unit.
+	// Default: [BootRestartPolicyOnFailure].
+	Restart BootRestartPolicy
+	// Binary is the absolute path of the sbx binary run by the unit.
+	// Default: the sbx binary in the PATH.
+	Binary string
+}
+
+// BootUnit is the systemd unit that starts a sandbox on the host boot.
+type BootUnit struct {
+	// Name is the unit name (sbx-<sandbox-id>.service).
+	Name string
+	// Path is the unit file path.
+	Path string
+	// SandboxID is the sandbox ID.
+	SandboxID string
+	// SandboxName is the sandbox name.
+	SandboxName string
+	// Restart is the restart policy of the unit.
+	Restart BootRestartPolicy
+}
+
+// RecoveryAction is what [Client.RecoverSandboxes] did with a sandbox.
+type RecoveryAction string
+
+const (
+	// RecoveryActionNone leaves the sandbox as it is, it's alive or not running.
+	RecoveryActionNone RecoveryAction = "none"
+	// RecoveryActionStopped flags as stopped a sandbox that was running when its
+	// VM died.
+	RecoveryActionStopped RecoveryAction = "stopped"
+	// RecoveryActionRestarted starts again a persistent sandbox whose VM died.
+	RecoveryActionRestarted RecoveryAction = "restarted"
+	// RecoveryActionFailed is a persistent sandbox whose VM died and couldn't be
+	// started again, it's left stopped.
+	RecoveryActionFailed RecoveryAction = "failed"
+)
+
+// RecoveryResult is the recovery of a sandbox by [Client.RecoverSandboxes].
+type RecoveryResult struct {
+	// SandboxID is the sandbox ID.
+	SandboxID string
+	// Name is the sandbox name.
+	Name string
+	// Namespace is the sandbox namespace.
+	Namespace string
+	// Persistent is if the sandbox is persistent.
+	Persistent bool
+	// Action is what the recovery did with the sandbox.
+	Action RecoveryAction
+	// Error is the start error of a [RecoveryActionFailed] recovery.
+	Error string
 }
 
 // CreateSandboxOpts configures sandbox creation.
@@ -100,13 +690,194 @@ type CreateSandboxOpts struct {
 	// Engine selects the engine type (required).
 	Engine EngineType
 	// Firecracker contains engine-specific config. Required for [EngineFirecracker]
-	// unless FromImage is set. Ignored for [EngineFake].
+	// unless FromImage is set, in that case only the kernel args, boot and seccomp
+	// options can be set. Ignored for [EngineFake].
 	Firecracker *FirecrackerConfig
 	// Resources defines compute resources (required, must be positive values).
 	Resources Resources
 	// FromImage uses a pulled image version (e.g. "v0.1.0") for kernel and rootfs.
-	// Cannot be combined with explicit Firecracker paths.
+	// Cannot be combined with explicit Firecracker paths. A snapshot image that
+	// is not installed is fetched from the [Config].SnapshotStore when set.
 	FromImage string
+	// UserData is executed in the guest on the first boot (optional). It can be a
+	// shell script or a cloud-config document (starting with "#cloud-config")
+	// supporting the users, write_files, package_update, packages and runcmd
+	// modules. If it fails, the first start fails and it runs again on the next one.
+	UserData string
+	// Clock isolates the guest clock from the host time (optional).
+	Clock *ClockOptions
+	// Ports names the ports of the sandbox services (optional, e.g. "web": 3000),
+	// so the tooling can use [Client.ForwardNamed] instead of hard-coded numbers.
+	// Names use up to 32 lowercase alphanumeric characters and hyphens, starting
+	// with a letter.
+	Ports map[string]int
+	// ExecProfile sets up the environment of every exec and shell (optional), e.g.
+	// the PATH of the tools installed by the user data.
+	ExecProfile *ExecProfile
+	// Profile is the kernel profile of the sandbox (optional, e.g.
+	// [KernelProfileFastBoot]), one of the [ImageManifest].Profiles of FromImage or
+	// a built-in one without image. Its kernel args go before the Firecracker ones,
+	// which override the profile ones with the same key.
+	Profile string
+	// IfNotExists returns the existing sandbox with the same name when its spec
+	// matches instead of failing with [ErrAlreadyExists]. A sandbox with a different
+	// spec (webhooks included) fails with a [SpecMismatchError].
+	IfNotExists bool
+	// Progress receives the create steps (optional).
+	Progress ProgressReporter
+	// Webhooks are notified of the sandbox events (optional), in addition to the
+	// [Config].EventSinks of the client. They are stored with the sandbox, so the
+	// operations of any client notify them.
+	Webhooks []Webhook
+	// Persistent starts the sandbox again on [Client.RecoverSandboxes] when the host
+	// reboot kills it while running, see [Client.SetPersistent].
+	Persistent bool
+	// Namespace is the namespace of the sandbox, it's required (and only allowed to
+	// differ from the client namespace) with an [AllNamespaces] client.
+	// Default: the client namespace.
+	Namespace string
+}
+
+// SpecField is a field of the sandbox spec (the configuration set at creation)
+// returned by [Client.DiffSandboxSpec]. The values are stable identifiers.
+type SpecField string
+
+const (
+	SpecFieldName            SpecField = "name"
+	SpecFieldImage           SpecField = "image"
+	SpecFieldResources       SpecField = "resources"
+	SpecFieldUserData        SpecField = "user_data"
+	SpecFieldClock           SpecField = "clock"
+	SpecFieldPorts           SpecField = "ports"
+	SpecFieldExecProfile     SpecField = "exec_profile"
+	SpecFieldRootFS          SpecField = "rootfs"
+	SpecFieldKernelImage     SpecField = "kernel_image"
+	SpecFieldKernelArgs      SpecField = "kernel_args"
+	SpecFieldBoot            SpecField = "boot"
+	SpecFieldNetworks        SpecField = "networks"
+	SpecFieldSeccomp         SpecField = "seccomp"
+	SpecFieldEgressInterface SpecField = "egress_interface"
+	SpecFieldNetwork         SpecField = "network"
+	SpecFieldWebhooks        SpecField = "webhooks"
+)
+
+// ProgressReporter receives the step by step progress of the long operations:
+// [Client.CreateSandbox], [Client.StartSandbox], [Client.RebaseSandbox],
+// [Client.PullImage] and [Client.CreateImageFromSandbox]. The calls are serialized and made from the
+// operation goroutine (except the downloads), keep Report fast.
+type ProgressReporter interface {
+	Report(ProgressEvent)
+}
+
+// ProgressReporterFunc is a function [ProgressReporter].
+type ProgressReporterFunc func(ProgressEvent)
+
+// Report calls f(ev).
+func (f ProgressReporterFunc) Report(ev ProgressEvent) { f(ev) }
+
+// ProgressEvent is the progress of a long operation, sent when a step starts
+// and while it advances.
+type ProgressEvent struct {
+	// Step is the step name (e.g. "copy_rootfs", "boot", "download_rootfs").
+	Step string
+	// Percent is the operation completion from 0 to 100, -1 when unknown. The
+	// download steps of [Client.PullImage] report the completion of the artifact.
+	Percent float64
+	// Message is a human readable description of the step.
+	Message string
+}
+
+// CloneSandboxOpts configures sandbox cloning.
+//
+// Pass nil to [Client.CloneSandbox] to clone with the same resources as the source.
+type CloneSandboxOpts struct {
+	// Resources overrides the source sandbox resources. Zero fields are inherited
+	// from the source. DiskGB can't be smaller than the source disk.
+	Resources Resources
+}
+
+// RebaseSandboxOpts configures [Client.RebaseSandbox].
+type RebaseSandboxOpts struct {
+	// PreservePaths are the absolute paths (e.g. /root, /home) copied from the
+	// current sandbox rootfs into the new one. The directories are merged, the
+	// preserved files replace the image files with the same path.
+	PreservePaths []string
+	// Progress receives the rebase steps (optional).
+	Progress ProgressReporter
+}
+
+// CollectDiagnosticsOpts configures [Client.CollectDiagnostics].
+type CollectDiagnosticsOpts struct {
+	// SandboxNameOrID is the sandbox whose configuration and logs are included
+	// (optional).
+	SandboxNameOrID string
+	// MaxLogBytes is the size of the log tails (default: 1 MiB).
+	MaxLogBytes int64
+}
+
+// AnnotateSandboxOpts configures the annotations changed by [Client.AnnotateSandbox].
+type AnnotateSandboxOpts struct {
+	// Set are the annotations to add or replace. Keys use up to 128 [a-zA-Z0-9._/-]
+	// characters, starting and ending with an alphanumeric character.
+	Set map[string]string
+	// Remove are the keys of the annotations to remove, missing keys are ignored.
+	Remove []string
+}
+
+// UsageFilter selects and groups the sandboxes of [Client.UsageReport].
+type UsageFilter struct {
+	// NamePattern only reports the sandboxes whose name matches the pattern, a glob
+	// ("ci-*") or a regular expression prefixed with "re:". Empty means all.
+	NamePattern string
+	// Annotations only reports the sandboxes with all these annotations.
+	Annotations map[string]string
+	// GroupBy groups the sandboxes by the value of this annotation key instead of
+	// reporting each sandbox.
+	GroupBy string
+}
+
+// TimeRange is a time range, a zero time doesn't limit its side.
+type TimeRange struct {
+	Since time.Time
+	// Until defaults to now when zero.
+	Until time.Time
+}
+
+// Usage is the resources used by sandboxes over time. The allocated resources
+// count as used, a running sandbox uses all its VCPUs and memory.
+type Usage struct {
+	// CPUSeconds are the VCPUs of the running sandboxes multiplied by the seconds.
+	CPUSeconds float64
+	// MemoryMBHours is the memory (MB) of the running sandboxes multiplied by the hours.
+	MemoryMBHours float64
+	// DiskGBHours is the disk (GB) of the sandboxes, running or not, multiplied by the hours.
+	DiskGBHours float64
+}
+
+// UsageReportEntry is the usage of a sandbox, or of a group of sandboxes when the
+// report is grouped.
+type UsageReportEntry struct {
+	// Key is the sandbox name, or the annotation value of the group (empty for the
+	// sandboxes without the annotation).
+	Key string
+	// SandboxID is the sandbox ID, empty when the report is grouped.
+	SandboxID string
+	// Removed is true when the sandbox has been removed.
+	Removed bool
+	// Sandboxes is the number of sandboxes of the entry.
+	Sandboxes int
+	Usage
+}
+
+// UsageReport is the resources used by the sandboxes in a time range.
+type UsageReport struct {
+	Since time.Time
+	Until time.Time
+	// GroupBy is the annotation key used to group the sandboxes, if any.
+	GroupBy string
+	// Entries are sorted by key.
+	Entries []UsageReportEntry
+	Total   Usage
 }
 
 // StartSandboxOpts configures sandbox start behavior.
@@ -121,6 +892,110 @@ type StartSandboxOpts struct {
 	// is launched alongside the VM to enforce domain-based allow/deny rules.
 	// nil means no egress filtering (all traffic allowed).
 	Egress *EgressPolicy
+	// EgressPolicyName uses the named egress policy (see [Client.CreatePolicy]),
+	// read when the sandbox starts so it gets the latest version of the policy.
+	// It can't be used with Egress.
+	EgressPolicyName string
+	// IntegrityPaths are absolute guest paths (files or directories) whose regular
+	// files are hashed once the sandbox started (after the user data), as the
+	// baseline verified by [Client.VerifyIntegrity]. Each start with integrity paths
+	// replaces the previous baseline.
+	IntegrityPaths []string
+	// ResetSession starts the sandbox without the session of its previous start.
+	// Without it, the options without Env, Egress, EgressPolicyName and
+	// IntegrityPaths apply the previous session again, so a sandbox stopped by a
+	// host reboot gets the same runtime configuration.
+	ResetSession bool
+	// Timeouts override the client [Config].StartTimeouts for this start, the
+	// unset ones use the client ones.
+	Timeouts StartTimeouts
+	// Progress receives the start steps (optional).
+	Progress ProgressReporter
+}
+
+// StopSandboxOpts configures the sandbox stop behavior.
+//
+// Pass nil to [Client.StopSandbox] to use defaults (10s grace period, no hooks).
+type StopSandboxOpts struct {
+	// GracePeriod is the time the sandbox has to run the pre-stop hooks and shut
+	// down, the VM is terminated after the guest shutdown and only killed when
+	// it's still running at the end of the grace period.
+	// Default: 10s.
+	GracePeriod time.Duration
+	// PreStopHooks are the shell commands run in the guest, in order, before it's
+	// shut down (e.g. flush caches, notify the agent). A failing hook is logged
+	// and doesn't stop the shutdown.
+	PreStopHooks []string
+}
+
+// RestartSandboxOpts configures the sandbox restart behavior.
+//
+// Pass nil to [Client.RestartSandbox] to use defaults (10s grace period, no hooks,
+// client start timeouts).
+type RestartSandboxOpts struct {
+	// GracePeriod is the time the sandbox has to run the pre-stop hooks and shut
+	// down, like [StopSandboxOpts].GracePeriod.
+	// Default: 10s.
+	GracePeriod time.Duration
+	// PreStopHooks are the shell commands run in the guest, in order, before it's
+	// shut down.
+	PreStopHooks []string
+	// Timeouts override the client [Config].StartTimeouts for the start, the unset
+	// ones use the client ones.
+	Timeouts StartTimeouts
+	// Progress receives the start steps (optional).
+	Progress ProgressReporter
+}
+
+// StartTimeouts are the timeouts of the sandbox start steps. The zero values use
+// the engine defaults.
+type StartTimeouts struct {
+	// Boot is the maximum time to wait for the guest to boot and accept SSH
+	// connections. Default: 60s.
+	Boot time.Duration
+	// SSHDial is the timeout of each guest SSH connection attempt while waiting
+	// for the boot. Default: 2s.
+	SSHDial time.Duration
+	// SSHRetries is the maximum number of guest SSH connection attempts while
+	// waiting for the boot. Default: 0 (retry until the boot timeout).
+	SSHRetries int
+	// FilesystemExpand is the timeout of the guest setup after the boot (the
+	// filesystem expansion to fill the disk). Default: 0 (no timeout).
+	FilesystemExpand time.Duration
+}
+
+// RetryPolicy is the retry policy of a transient failure: the number of attempts
+// and the exponential backoff between them. The zero values use the engine
+// defaults.
+type RetryPolicy struct {
+	// Attempts is the maximum number of attempts, zero keeps retrying until the
+	// operation deadline (e.g. the boot timeout).
+	Attempts int
+	// InitialBackoff is the wait after the first failed attempt.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between attempts.
+	MaxBackoff time.Duration
+	// Multiplier grows the wait after each failed attempt, 1 keeps it fixed.
+	Multiplier float64
+	// Jitter randomizes each wait by up to this fraction of it (0-1), so the
+	// clients retrying the same resource don't do it in lockstep.
+	Jitter float64
+}
+
+// RetryPolicies are the retry policies of the transient engine failures.
+type RetryPolicies struct {
+	// SSHDial retries the guest SSH connection while the guest boots, bounded by
+	// the [StartTimeouts].Boot. [StartTimeouts].SSHRetries replaces its attempts.
+	// Default: every 100ms.
+	SSHDial RetryPolicy
+	// FirecrackerAPI retries the connection to the Firecracker API socket while the
+	// Firecracker process starts, bounded by 10s.
+	// Default: from 10ms doubling up to 250ms with 10% jitter.
+	FirecrackerAPI RetryPolicy
+	// Nftables retries the host nftables changes rejected as busy (EBUSY), e.g.
+	// by a firewall manager changing the ruleset at the same time.
+	// Default: 5 attempts from 50ms doubling up to 1s with 20% jitter.
+	Nftables RetryPolicy
 }
 
 // EgressAction represents the action for an egress rule or default policy.
@@ -140,8 +1015,41 @@ type EgressPolicy struct {
 	Default EgressAction
 	// Rules are evaluated in order, first match wins.
 	Rules []EgressRule
+	// FailClosed denies the allowed traffic whose destination can't be verified
+	// instead of letting it through: domains that don't resolve or resolve to
+	// loopback, link-local or private addresses, and DNS answers with them.
+	FailClosed bool
+	// DNSUpstreams are the resolvers the DNS proxy forwards the allowed queries to,
+	// tried in order until one answers:
+	//   - Plain DNS: "IP" or "IP:port" (e.g. "1.1.1.1", "10.0.0.53:5353").
+	//   - DNS-over-TLS: "tls://host[:port]" (e.g. "tls://dns.quad9.net").
+	//   - DNS-over-HTTPS: "https://host/path" (e.g. "https://cloudflare-dns.com/dns-query").
+	//
+	// Empty uses [Config].DNSUpstreams.
+	DNSUpstreams []string
+	// AllowSystemServices allows common infrastructure services with built-in
+	// rules, so the sandboxes can sync their clock and install packages without
+	// listing their domains. They are evaluated after Rules, so a rule can still
+	// deny them.
+	AllowSystemServices []EgressSystemService
 }
 
+// EgressSystemService is a common infrastructure service allowed by an egress
+// policy with built-in rules, see [EgressPolicy].AllowSystemServices.
+type EgressSystemService string
+
+const (
+	// EgressSystemServiceNTP allows the public NTP servers (pool.ntp.org and the
+	// distribution defaults) on UDP 123, resolved through the sandbox DNS.
+	EgressSystemServiceNTP EgressSystemService = "ntp"
+	// EgressSystemServicePackages allows the OS package mirrors (Debian, Ubuntu,
+	// Alpine, Fedora, CentOS, Rocky and Arch).
+	EgressSystemServicePackages EgressSystemService = "packages"
+	// EgressSystemServiceRegistries allows the public container registries (Docker
+	// Hub, GHCR, Quay, GCR, Artifact Registry, registry.k8s.io, ECR Public and MCR).
+	EgressSystemServiceRegistries EgressSystemService = "registries"
+)
+
 // EgressRule defines a single domain-based egress rule.
 type EgressRule struct {
 	// Domain is a domain pattern: "github.com", "*.github.com", or "*".
@@ -150,14 +1058,289 @@ type EgressRule struct {
 	Domain string
 	// Action is the rule action (allow or deny).
 	Action EgressAction
+	// ICMP also allows pinging the addresses the matched domains resolve to
+	// through the sandbox DNS. Only for allow rules, by default the sandboxes
+	// with an egress policy can't ping.
+	ICMP bool
+}
+
+// NamedEgressPolicy is an egress policy stored with a name, so multiple sandboxes
+// can use it with [StartSandboxOpts].EgressPolicyName.
+type NamedEgressPolicy struct {
+	// Name is the policy name.
+	Name string
+	// Policy is the egress policy.
+	Policy EgressPolicy
+	// CreatedAt is when the policy was created.
+	CreatedAt time.Time
+	// UpdatedAt is when the policy was last updated.
+	UpdatedAt time.Time
+}
+
+// CreatePolicyOpts configures the creation of a named egress policy.
+type CreatePolicyOpts struct {
+	// Name is the policy name (required), allowed characters: [a-zA-Z0-9._-].
+	Name string
+	// Policy is the egress policy.
+	Policy EgressPolicy
+}
+
+// SandboxTemplate is a reusable sandbox spec stored with a name (see
+// [Client.CreateTemplate]), so the sandboxes of the same shape are created with
+// [Client.CreateSandboxFromTemplate] instead of repeating their options. The
+// sandboxes keep the spec they were created with, removing the template doesn't
+// affect them.
+type SandboxTemplate struct {
+	// Name is the template name (required), allowed characters: [a-zA-Z0-9._-].
+	Name string
+	// Description is a free text description of the template (optional).
+	Description string
+	// Engine is the engine type of the sandboxes (required).
+	Engine EngineType
+	// FromImage is the image version of the sandboxes, required for
+	// [EngineFirecracker].
+	FromImage string
+	// Profile is the kernel profile of the sandboxes (optional), see
+	// [CreateSandboxOpts].Profile.
+	Profile string
+	// Resources are the compute resources of the sandboxes (required).
+	Resources Resources
+	// UserData is executed in the guest on the first boot (optional).
+	UserData string
+	// Ports names the ports of the sandbox services (optional).
+	Ports map[string]int
+	// ExecProfile sets up the environment of every exec and shell (optional).
+	ExecProfile *ExecProfile
+	// Env are the session environment variables of the sandboxes started from the
+	// template (optional).
+	Env map[string]string
+	// Egress is the egress policy of the sandboxes started from the template
+	// (optional). It can't be used with EgressPolicyName.
+	Egress *EgressPolicy
+	// EgressPolicyName is the named egress policy of the sandboxes started from the
+	// template (optional), read when they start.
+	EgressPolicyName string
+	// CreatedAt is when the template was created, set by [Client.CreateTemplate].
+	CreatedAt time.Time
+	// UpdatedAt is when the template was last updated.
+	UpdatedAt time.Time
+}
+
+// CreateSandboxFromTemplateOpts configures the creation of a sandbox from a template.
+type CreateSandboxFromTemplateOpts struct {
+	// Name is the sandbox name (required). Must be unique.
+	Name string
+	// Start starts the sandbox once created with the template session (environment
+	// and egress), see [Client.CreateAndStartSandbox].
+	Start bool
+	// Env are session environment variables merged over the template ones, they
+	// require Start.
+	Env map[string]string
+	// IfNotExists returns the existing sandbox with the same name when its spec
+	// matches instead of failing, see [CreateSandboxOpts].IfNotExists.
+	IfNotExists bool
+	// Namespace is the namespace of the sandbox, see [CreateSandboxOpts].Namespace.
+	Namespace string
+	// Progress receives the create and start steps (optional).
+	Progress ProgressReporter
+}
+
+// Task is a named command with its execution defaults, run with [Client.RunTask].
+// The global tasks are available to all the sandboxes, the tasks of a sandbox take
+// precedence over the global ones with the same name.
+type Task struct {
+	// Name is the task name (required), allowed characters: [a-zA-Z0-9._-].
+	Name string
+	// SandboxID is the sandbox of the task, empty for a global task. It is set by
+	// [Client.RegisterTask].
+	SandboxID string
+	// Command is the command run (required), the run arguments are appended to it.
+	Command []string
+	// WorkingDir is the directory the command runs in.
+	WorkingDir string
+	// Env are the environment variables of the command, the run ones override them.
+	Env map[string]string
+	// Files are the absolute local paths of the files uploaded to the working
+	// directory before running the command.
+	Files []string
+	// Timeout kills the command when it runs longer, zero doesn't limit it.
+	Timeout time.Duration
+	// CreatedAt is when the task was first registered.
+	CreatedAt time.Time
+	// UpdatedAt is when the task was last registered.
+	UpdatedAt time.Time
+}
+
+// RunTaskOpts configures a task run. The [ExecOpts] working directory and
+// environment override the task ones, and its files are uploaded with the task ones.
+type RunTaskOpts struct {
+	ExecOpts
+	// Args are appended to the task command.
+	Args []string
+	// Timeout overrides the task timeout.
+	Timeout time.Duration
+}
+
+// EgressTestReason is why an egress test target got its action.
+type EgressTestReason string
+
+const (
+	// EgressTestReasonRule means a rule matched the target domain.
+	EgressTestReasonRule EgressTestReason = "rule"
+	// EgressTestReasonDefault means no rule matched and the default action applies.
+	EgressTestReasonDefault EgressTestReason = "default"
+	// EgressTestReasonIPAddress means the target is an IP address, always denied
+	// because the rules can only be evaluated on domains.
+	EgressTestReasonIPAddress EgressTestReason = "ip-address"
+	// EgressTestReasonSystemService means a built-in rule of an allowed system
+	// service matched the target domain.
+	EgressTestReasonSystemService EgressTestReason = "system-service"
+)
+
+// EgressTestResult is the result of evaluating a target against an egress policy
+// with [Client.TestEgressPolicy].
+type EgressTestResult struct {
+	// Target is the tested URL, host or IP.
+	Target string
+	// Domain is the domain the rules were matched against, empty for IPs.
+	Domain string
+	// Action is the resulting action.
+	Action EgressAction
+	// Reason is why the target got the action.
+	Reason EgressTestReason
+	// RuleIndex is the index of the matched rule, -1 when no policy rule matched.
+	RuleIndex int
+	// Rule is the matched rule (a built-in one for system services), nil when no
+	// rule matched.
+	Rule *EgressRule
+}
+
+// DNSEventReason is why a DNS query didn't get the upstream resolver answer.
+type DNSEventReason string
+
+const (
+	// DNSEventReasonRuleMatch means the query was denied by the egress rules.
+	DNSEventReasonRuleMatch DNSEventReason = "rule-match"
+	// DNSEventReasonFailClosed means the query was denied by the fail-closed mode
+	// because the answer had non public addresses.
+	DNSEventReasonFailClosed DNSEventReason = "fail-closed"
+	// DNSEventReasonUpstreamError means the upstream resolver failed.
+	DNSEventReasonUpstreamError DNSEventReason = "upstream-error"
+)
+
+// DNSEvent is a DNS query made by a sandbox and answered by its egress DNS proxy.
+type DNSEvent struct {
+	// Time is when the query was answered.
+	Time time.Time
+	// Domain is the queried domain, lowercase and without the trailing dot.
+	Domain string
+	// QType is the query type (e.g. A, AAAA, TXT).
+	QType string
+	// Action is the egress policy decision.
+	Action EgressAction
+	// Reason is why the query didn't get the upstream answer, empty otherwise.
+	Reason DNSEventReason
+	// Rcode is the response code sent to the sandbox (e.g. NOERROR, NXDOMAIN, REFUSED).
+	Rcode string
+	// UpstreamLatency is the upstream resolver response time, zero when the
+	// query was not forwarded.
+	UpstreamLatency time.Duration
 }
 
+// DNSEventsOpts filters the DNS events of a sandbox.
+//
+// Pass nil to [Client.DNSEvents] to get all the events.
+type DNSEventsOpts struct {
+	// Since returns the events at or after this time. Zero means all.
+	Since time.Time
+	// Domain returns only the events of this domain. Empty means all.
+	Domain string
+	// Limit returns only the latest N events. Zero means all.
+	Limit int
+}
+
+// DNSStatsOpts configures the DNS stats aggregation of a sandbox.
+//
+// Pass nil to [Client.DNSStats] to aggregate all the events.
+type DNSStatsOpts struct {
+	// Since aggregates the events at or after this time. Zero means all.
+	Since time.Time
+}
+
+// SandboxLog is the tail of an engine log of a sandbox.
+type SandboxLog struct {
+	// Source is the process writing the log (e.g. firecracker, proxy, proxy-eth1).
+	Source string
+	// Lines are the last lines of the log, oldest first.
+	Lines []string
+}
+
+// LogsOpts configures the sandbox logs returned by [Client.Logs].
+//
+// Pass nil to [Client.Logs] to get the last 100 lines of each log.
+type LogsOpts struct {
+	// Lines is the number of last lines of each log. Zero means 100.
+	Lines int
+}
+
+// DNSDomainStats are the aggregated DNS queries of a domain.
+type DNSDomainStats struct {
+	// Domain is the queried domain.
+	Domain string
+	// Queries is the number of queries.
+	Queries int
+	// Allowed is the number of queries answered by the upstream resolver.
+	Allowed int
+	// Denied is the number of queries denied by the egress policy.
+	Denied int
+	// Failed is the number of allowed queries the upstream resolver couldn't answer.
+	Failed int
+	// AvgUpstreamLatency is the average upstream response time of the forwarded queries.
+	AvgUpstreamLatency time.Duration
+	// MaxUpstreamLatency is the slowest upstream response time.
+	MaxUpstreamLatency time.Duration
+	// LastQueryAt is the time of the latest query.
+	LastQueryAt time.Time
+}
+
+// DNSStats are the aggregated DNS queries of a sandbox returned by [Client.DNSStats].
+type DNSStats struct {
+	// Queries is the total number of queries.
+	Queries int
+	// Allowed is the number of queries answered by the upstream resolver.
+	Allowed int
+	// Denied is the number of queries denied by the egress policy.
+	Denied int
+	// Failed is the number of allowed queries the upstream resolver couldn't answer.
+	Failed int
+	// Domains are the per domain stats, most queried first.
+	Domains []DNSDomainStats
+}
+
+// WaitCondition is a sandbox condition that [Client.WaitFor] can wait for.
+type WaitCondition string
+
+const (
+	// WaitConditionRunning is met when the sandbox is running.
+	WaitConditionRunning WaitCondition = "running"
+	// WaitConditionStopped is met when the sandbox is stopped.
+	WaitConditionStopped WaitCondition = "stopped"
+	// WaitConditionSSHReady is met when the sandbox is running and accepts commands.
+	WaitConditionSSHReady WaitCondition = "ssh-ready"
+	// WaitConditionHealthy is met when the sandbox accepts commands and the engine
+	// reports the VM process as alive.
+	WaitConditionHealthy WaitCondition = "healthy"
+)
+
 // ListSandboxesOpts configures sandbox listing.
 //
 // Pass nil to [Client.ListSandboxes] to list all sandboxes.
 type ListSandboxesOpts struct {
 	// Status filters sandboxes by status. Nil means all statuses.
 	Status *SandboxStatus
+	// Namespace filters the sandboxes of an [AllNamespaces] client by namespace.
+	// Empty means all the client namespaces.
+	Namespace string
 }
 
 // ExecOpts configures command execution inside a sandbox.
@@ -169,7 +1352,10 @@ type ExecOpts struct {
 	WorkingDir string
 	// Env contains additional environment variables for this execution only.
 	Env map[string]string
-	// Stdin is the standard input stream. Nil means no input.
+	// Stdin is the standard input stream. Nil means no input. It is streamed to
+	// the command as it is read, and the command input is closed when it returns
+	// EOF. The command can exit before Stdin ends. Use [Client.StartExec] to write
+	// the input while the command runs.
 	Stdin io.Reader
 	// Stdout receives the command's standard output. Nil means output is discarded.
 	Stdout io.Writer
@@ -180,13 +1366,191 @@ type ExecOpts struct {
 	// Files are local file paths to upload into the sandbox before executing.
 	// Files are uploaded to the working directory (WorkingDir) or "/" if unset.
 	Files []string
+	// CaptureOutput captures stdout and stderr into [ExecResult].Stdout and
+	// [ExecResult].Stderr, in addition to writing them to Stdout and Stderr.
+	CaptureOutput bool
+	// CaptureMaxBytes is the per-stream capture limit when CaptureOutput is set.
+	// Output beyond the limit is not captured and the result is marked as truncated.
+	// Default: 1 MiB.
+	CaptureMaxBytes int
+	// MaxOutputBytes limits the output of the command, stdout and stderr combined,
+	// written to Stdout and Stderr and captured, so a runaway command can't exhaust
+	// the client memory or fill the output pipelines. 0 is unlimited.
+	MaxOutputBytes int64
+	// OutputLimitPolicy is what happens when the output exceeds MaxOutputBytes.
+	// Default: [ExecOutputLimitTruncate].
+	OutputLimitPolicy ExecOutputLimitPolicy
+	// Caller identifies the client in the exec history (e.g. "ci", "my-agent").
+	// Default: "sdk".
+	Caller string
 }
 
+// ExecOutputLimitPolicy is what happens when the output of a command exceeds
+// [ExecOpts].MaxOutputBytes.
+type ExecOutputLimitPolicy string
+
+const (
+	// ExecOutputLimitTruncate discards the output beyond the limit and lets the
+	// command finish.
+	ExecOutputLimitTruncate ExecOutputLimitPolicy = "truncate"
+	// ExecOutputLimitAbort kills the command once its output exceeds the limit, the
+	// result has exit code -1.
+	ExecOutputLimitAbort ExecOutputLimitPolicy = "abort"
+)
+
 // ExecResult contains the result of a command execution.
 type ExecResult struct {
 	// ExitCode is the exit status of the executed command.
 	// 0 indicates success, non-zero indicates failure.
 	ExitCode int
+	// StartedAt is when the command execution started.
+	StartedAt time.Time
+	// FinishedAt is when the command execution finished.
+	FinishedAt time.Time
+	// Duration is the wall-clock time of the command execution.
+	Duration time.Duration
+	// StdoutBytes is the number of bytes the command wrote to stdout.
+	StdoutBytes int64
+	// StderrBytes is the number of bytes the command wrote to stderr.
+	StderrBytes int64
+	// Stdout is the captured standard output. Only set when [ExecOpts].CaptureOutput is true.
+	Stdout string
+	// Stderr is the captured standard error. Only set when [ExecOpts].CaptureOutput is true.
+	Stderr string
+	// StdoutTruncated is true when stdout exceeded [ExecOpts].CaptureMaxBytes.
+	StdoutTruncated bool
+	// StderrTruncated is true when stderr exceeded [ExecOpts].CaptureMaxBytes.
+	StderrTruncated bool
+	// OutputLimitExceeded is true when the output exceeded [ExecOpts].MaxOutputBytes,
+	// the output beyond it was discarded or the command aborted, see
+	// [ExecOpts].OutputLimitPolicy. StdoutBytes and StderrBytes still count all
+	// the command output.
+	OutputLimitExceeded bool
+}
+
+// CopyOpts configures a copy with [Client.CopyTo] or [Client.CopyFrom].
+//
+// Pass nil to use defaults (uncompressed, no progress, unlimited rate, no retries).
+type CopyOpts struct {
+	// Compress sends the files as a compressed stream. Faster for compressible
+	// files (e.g. source trees) on slow links, the sandbox needs tar with gzip.
+	Compress bool
+	// Progress is called with the copy progress. The calls are serialized,
+	// it should return fast.
+	Progress func(CopyProgress)
+	// BandwidthLimit limits the copy rate in bytes per second, shared by all the
+	// files. Zero is unlimited.
+	BandwidthLimit int64
+	// Retries is the number of times the copy is resumed on a new connection when
+	// the SSH connection breaks. The copied files are skipped and the partially
+	// copied ones continue where they stopped, the compressed copies start again.
+	// Zero doesn't retry.
+	Retries int
+	// Checksum verifies the SHA-256 of the copied files against the source once
+	// copied, the sandbox needs sha256sum.
+	Checksum bool
+}
+
+// CopyProgress is the progress of a copy.
+type CopyProgress struct {
+	// Bytes are the file bytes copied so far.
+	Bytes int64
+	// TotalBytes are the file bytes to copy. 0 when unknown (compressed
+	// directory copies from the sandbox).
+	TotalBytes int64
+}
+
+// SyncOpts configures a directory sync with [Client.Sync].
+type SyncOpts struct {
+	// Delete removes the sandbox files missing in the local directory. Excluded
+	// files are never removed.
+	Delete bool
+	// Exclude are patterns of the paths not synced, using [path.Match] syntax.
+	// Patterns without a slash match the name of any file or directory (e.g.
+	// ".git", "*.pyc"), patterns with a slash match the path relative to the
+	// synced directory (e.g. "build/out"). Excluding a directory excludes its content.
+	Exclude []string
+}
+
+// SyncWatchOpts configures a watched directory sync with [Client.SyncWatch].
+type SyncWatchOpts struct {
+	// Sync are the options of each sync.
+	Sync SyncOpts
+	// Debounce is the quiet time after a change before syncing, so a burst of
+	// changes (e.g. a git checkout or a build) is synced once. Default: 300ms.
+	Debounce time.Duration
+	// OnSync is called after each sync with its result or error (optional).
+	OnSync func(res *SyncResult, err error)
+}
+
+// SyncResult is the result of a [Client.Sync].
+type SyncResult struct {
+	// Uploaded are the files copied into the sandbox, relative to the synced directory.
+	Uploaded []string
+	// Deleted are the files and directories removed from the sandbox, relative to
+	// the synced directory. Only set with [SyncOpts].Delete.
+	Deleted []string
+	// Unchanged is the number of files already up to date.
+	Unchanged int
+	// UploadedBytes is the size of the uploaded files.
+	UploadedBytes int64
+}
+
+// FileInfo is the information of a sandbox file returned by [Client.StatFile]
+// and [Client.ListDir].
+type FileInfo struct {
+	// Name is the base name of the file.
+	Name string
+	// Path is the absolute path of the file in the sandbox.
+	Path string
+	// Size is the size in bytes.
+	Size int64
+	// Mode are the file mode and permission bits (e.g. [fs.ModeDir], [fs.ModeSymlink]).
+	Mode fs.FileMode
+	// ModTime is the last modification time.
+	ModTime time.Time
+	// IsDir is true for directories.
+	IsDir bool
+}
+
+// ExecRecord is the audit record of a command executed with [Client.Exec].
+type ExecRecord struct {
+	// ID is the unique identifier (ULID) of the record.
+	ID string
+	// Command is the executed command.
+	Command []string
+	// WorkingDir is the directory the command ran in. Empty means the default one.
+	WorkingDir string
+	// User is the host user that executed the command.
+	User string
+	// Caller identifies the client that executed the command (e.g. "cli", "sdk").
+	Caller string
+	// ExitCode is the exit status of the command, -1 when it couldn't be executed.
+	ExitCode int
+	// Error is the reason the command couldn't be executed, empty otherwise.
+	Error string
+	// StartedAt is when the command execution started.
+	StartedAt time.Time
+	// Duration is the wall-clock time of the command execution.
+	Duration time.Duration
+	// StdoutBytes is the number of bytes the command wrote to stdout.
+	StdoutBytes int64
+	// StderrBytes is the number of bytes the command wrote to stderr.
+	StderrBytes int64
+	// StdoutSHA256 is the hex SHA-256 of the full stdout. The output is not stored.
+	StdoutSHA256 string
+	// StderrSHA256 is the hex SHA-256 of the full stderr. The output is not stored.
+	StderrSHA256 string
+}
+
+// ExecHistoryOpts filters the exec history of a sandbox.
+//
+// Pass nil to [Client.ExecHistory] to get the full history.
+type ExecHistoryOpts struct {
+	// Since returns the commands started at or after this time. Zero means all.
+	Since time.Time
+	// Limit returns only the latest N commands. Zero means all.
+	Limit int
 }
 
 // --- Image types ---
@@ -199,6 +1563,11 @@ const (
 	ImageSourceRelease ImageSource = "release"
 	// ImageSourceSnapshot is a local image created from a sandbox snapshot.
 	ImageSourceSnapshot ImageSource = "snapshot"
+	// ImageSourceLocal is a local image added from user built artifacts.
+	ImageSourceLocal ImageSource = "local"
+	// ImageSourceCustomized is a local image derived from an installed image by
+	// a customization (see [PullImageOpts.Customize]).
+	ImageSourceCustomized ImageSource = "customized"
 )
 
 // ImageRelease represents an image version available in the registry.
@@ -209,6 +1578,81 @@ type ImageRelease struct {
 	Installed bool
 	// Source indicates where this image comes from (release or snapshot).
 	Source ImageSource
+	// InstalledAt is when the image was installed locally. Zero if not installed.
+	InstalledAt time.Time
+}
+
+// PruneImagesOpts configures image pruning.
+//
+// Pass nil to [Client.PruneImages] to prune all unused images.
+type PruneImagesOpts struct {
+	// OlderThan only prunes images installed more than this duration ago.
+	// Zero prunes all unused images.
+	OlderThan time.Duration
+	// DryRun returns the images that would be pruned without removing them.
+	DryRun bool
+}
+
+// ImportImageOpts configures image bundle imports.
+//
+// Pass nil to [Client.ImportImage] to use defaults (fail if already installed).
+type ImportImageOpts struct {
+	// Force replaces the image if already installed.
+	Force bool
+}
+
+// CreateImageFromOCIOpts configures [Client.CreateImageFromOCI].
+type CreateImageFromOCIOpts struct {
+	// BaseImage is the installed image the kernel, guest init and firecracker
+	// binary are taken from (default: the most recently installed release).
+	BaseImage string
+	// KernelPath overrides the base image kernel (optional).
+	KernelPath string
+	// InitPath overrides the base image guest init binary (optional).
+	InitPath string
+	// SizeMB is the rootfs size (default: the image content with some room).
+	SizeMB int
+	// Force replaces the image if already installed.
+	Force bool
+}
+
+// AddLocalImageOpts configures [Client.AddLocalImage].
+type AddLocalImageOpts struct {
+	// FirecrackerPath is the firecracker binary stored with the image (optional).
+	FirecrackerPath string
+	// KernelVersion is the kernel version recorded in the manifest (optional).
+	KernelVersion string
+	// Distro is the rootfs distribution recorded in the manifest (optional).
+	Distro string
+	// DistroVersion is the rootfs distribution version recorded in the manifest (optional).
+	DistroVersion string
+	// Profile is the rootfs profile recorded in the manifest (optional).
+	Profile string
+	// Force replaces the image if already installed.
+	Force bool
+}
+
+// ImageDiskUsage is the disk usage of the locally installed images.
+//
+// Image artifacts are stored content-addressed, artifacts shared by multiple
+// images (e.g. the same kernel) are stored once.
+type ImageDiskUsage struct {
+	// Images is the usage of each installed image.
+	Images []ImageUsage
+	// TotalBytes is the sum of all the images sizes (shared artifacts counted per image).
+	TotalBytes int64
+	// DiskBytes is the real disk space used (shared artifacts counted once).
+	DiskBytes int64
+}
+
+// ImageUsage is the disk usage of a single installed image.
+type ImageUsage struct {
+	// Name is the image name (release version or snapshot name).
+	Name string
+	// SizeBytes is the disk size of all the image artifacts.
+	SizeBytes int64
+	// SharedBytes is the part of SizeBytes shared with other images.
+	SharedBytes int64
 }
 
 // PullImageOpts configures image pull behavior.
@@ -219,6 +1663,54 @@ type PullImageOpts struct {
 	Force bool
 	// StatusWriter receives progress output during download. Nil means silent.
 	StatusWriter io.Writer
+	// OnProgress is called with the download progress of each artifact. It may be
+	// called concurrently. Nil means no callbacks.
+	OnProgress func(PullProgress)
+	// Concurrency is the number of parallel ranged chunk downloads per artifact.
+	// Zero uses the default (4).
+	Concurrency int
+	// BandwidthLimit limits the download rate in bytes per second. Zero is unlimited.
+	BandwidthLimit int64
+	// Progress receives a "download_<artifact>" step with the download completion
+	// of each artifact (optional). Unlike OnProgress, the calls are serialized.
+	Progress ProgressReporter
+	// Customize is applied to the pulled image rootfs right after the pull,
+	// producing a derived image (see [PullResult.CustomizedImage]). Nil means no
+	// customization.
+	Customize *ImageCustomization
+}
+
+// ImageCustomization is a set of changes applied to an image rootfs, the files
+// are copied first and then the commands run, in order.
+//
+// The rootfs is loop mounted on the host and the commands run chrooted in it, so
+// customizing images requires root. The derived image is named after the base
+// image and a digest of the customization (<base>-custom-<digest>), the same
+// customization of the same image is applied once and reused afterwards.
+type ImageCustomization struct {
+	// Commands are shell commands run with /bin/sh inside the rootfs (e.g. "apk add curl").
+	Commands []string
+	// Files are local files copied into the rootfs.
+	Files []ImageCustomizationFile
+}
+
+// ImageCustomizationFile is a local file copied into an image rootfs.
+type ImageCustomizationFile struct {
+	// Src is the local file path.
+	Src string
+	// Dst is the absolute guest path.
+	Dst string
+}
+
+// PullProgress reports the download progress of an image artifact.
+type PullProgress struct {
+	// Artifact is the artifact being downloaded ("kernel", "rootfs" or "firecracker").
+	Artifact string
+	// Downloaded is the number of bytes already downloaded, including the bytes
+	// resumed from a previous interrupted pull.
+	Downloaded int64
+	// Total is the artifact size in bytes. Zero if unknown.
+	Total int64
 }
 
 // PullResult contains the result of an image pull operation.
@@ -233,6 +1725,12 @@ type PullResult struct {
 	RootFSPath string
 	// FirecrackerPath is the local path to the firecracker binary.
 	FirecrackerPath string
+	// CustomizedImage is the name of the image derived by [PullImageOpts.Customize]
+	// (empty without customization). Create sandboxes from it to use the customized rootfs.
+	CustomizedImage string
+	// CustomizedSkipped is true if the customized image was already installed and
+	// Force was false.
+	CustomizedSkipped bool
 }
 
 // ImageManifest describes an image release's artifacts and metadata.
@@ -249,6 +1747,272 @@ type ImageManifest struct {
 	Build BuildInfo
 	// Snapshot contains snapshot-specific metadata (nil for release images).
 	Snapshot *SnapshotInfo
+	// Local contains the metadata of images added with [Client.AddLocalImage] (nil
+	// for the rest).
+	Local *LocalImageInfo
+	// Customized contains the metadata of images derived by a customization (nil
+	// for the rest).
+	Customized *CustomizedImageInfo
+	// Profiles are the kernel profiles sandboxes of the image can be created with
+	// (see [CreateSandboxOpts].Profile): the built-in ones, replaced or extended
+	// by the image ones.
+	Profiles []KernelProfile
+}
+
+// KernelProfile is a named set of kernel and boot options for a common need.
+type KernelProfile struct {
+	Name        string
+	Description string
+	// KernelArgs are the extra kernel command line arguments of the profile.
+	KernelArgs []string
+	// DisableConsole disables the guest serial console.
+	DisableConsole bool
+}
+
+// The built-in kernel profiles of all the images.
+const (
+	// KernelProfileFastBoot boots without serial console and quiet kernel.
+	KernelProfileFastBoot = model.KernelProfileFastBoot
+	// KernelProfileDebug logs all the kernel messages on the serial console and
+	// doesn't reboot on kernel panics.
+	KernelProfileDebug = model.KernelProfileDebug
+	// KernelProfileHardened enables the kernel self-protection options.
+	KernelProfileHardened = model.KernelProfileHardened
+)
+
+// LocalImageInfo contains metadata specific to images added from user built artifacts.
+type LocalImageInfo struct {
+	// KernelSource is the path the kernel was added from.
+	KernelSource string
+	// RootFSSource is the path the rootfs was added from.
+	RootFSSource string
+	// AddedAt is when the image was added.
+	AddedAt time.Time
+}
+
+// CustomizedImageInfo contains metadata specific to images derived by a customization.
+type CustomizedImageInfo struct {
+	// BaseImage is the image the customization was applied to.
+	BaseImage string
+	// Commands are the shell commands run in the rootfs, in order.
+	Commands []string
+	// Files are the guest paths of the files copied into the rootfs.
+	Files []string
+	// Digest identifies the customization.
+	Digest string
+	// CreatedAt is when the image was created.
+	CreatedAt time.Time
+}
+
+// SnapshotDiff are the rootfs files changed between two images, returned by
+// [Client.DiffSnapshot].
+type SnapshotDiff struct {
+	// From is the older image (e.g. the base image of the snapshot).
+	From string
+	// To is the newer image.
+	To string
+	// Changes are the changed files sorted by path.
+	Changes []FileChange
+}
+
+// FileChangeKind is the kind of change of a file between two images, or since the
+// integrity baseline of a sandbox.
+type FileChangeKind string
+
+const (
+	// FileChangeAdded is a file that only exists in the newer image.
+	FileChangeAdded FileChangeKind = "added"
+	// FileChangeRemoved is a file that only exists in the older image.
+	FileChangeRemoved FileChangeKind = "removed"
+	// FileChangeModified is a file whose content, type, permissions or owner changed.
+	FileChangeModified FileChangeKind = "modified"
+)
+
+// FileChange is a file changed between two images.
+type FileChange struct {
+	// Path is the absolute guest path.
+	Path string
+	// Kind is the kind of change.
+	Kind FileChangeKind
+	// Old is the file in the older image (nil when added).
+	Old *FileState
+	// New is the file in the newer image (nil when removed).
+	New *FileState
+}
+
+// FileState is the state of a file in an image.
+type FileState struct {
+	// Type is "file", "dir", "symlink" or "other" (devices, sockets and named pipes).
+	Type string
+	// Mode are the permission bits (including [fs.ModeSetuid], [fs.ModeSetgid] and [fs.ModeSticky]).
+	Mode fs.FileMode
+	// UID is the owner user ID.
+	UID int
+	// GID is the owner group ID.
+	GID int
+	// SizeBytes is the size of regular files.
+	SizeBytes int64
+	// SHA256 is the hex content digest of regular files.
+	SHA256 string
+	// LinkTarget is the target of symlinks.
+	LinkTarget string
+}
+
+// IntegrityReport is the result of verifying the guest files of a sandbox against
+// its integrity baseline, returned by [Client.VerifyIntegrity].
+type IntegrityReport struct {
+	// SandboxID is the ULID of the verified sandbox.
+	SandboxID string
+	// SandboxName is the name of the verified sandbox.
+	SandboxName string
+	// Paths are the verified guest paths, the ones of the baseline.
+	Paths []string
+	// Files is the number of regular files currently under the paths.
+	Files int
+	// BaselineAt is when the baseline was captured.
+	BaselineAt time.Time
+	// CheckedAt is when the files were verified.
+	CheckedAt time.Time
+	// Changes are the files modified, added or removed since the baseline, sorted
+	// by path.
+	Changes []IntegrityChange
+}
+
+// Intact returns true when no file changed since the baseline.
+func (r IntegrityReport) Intact() bool {
+	return len(r.Changes) == 0
+}
+
+// DefaultCaptureSnapLen is the bytes captured of each packet by default.
+const DefaultCaptureSnapLen = model.DefaultCaptureSnapLen
+
+// CaptureOpts configures a sandbox network traffic capture, see
+// [Client.CaptureTraffic].
+type CaptureOpts struct {
+	// Interface is the guest network interface captured (eth0, eth1...), empty is
+	// the primary one (eth0).
+	Interface string
+	// Filter is a BPF filter expression with the pcap-filter syntax (e.g. "tcp port
+	// 443"), empty captures all the packets.
+	Filter string
+	// Duration stops the capture after it. Zero means until the context ends.
+	Duration time.Duration
+	// MaxBytes stops the capture before the pcap stream exceeds it. Zero means no
+	// limit.
+	MaxBytes int64
+	// MaxPackets stops the capture after these packets. Zero means no limit.
+	MaxPackets int
+	// SnapLen is the bytes captured of each packet. Zero is [DefaultCaptureSnapLen].
+	SnapLen int
+}
+
+// CaptureResult is the result of a sandbox network traffic capture.
+type CaptureResult struct {
+	// Interface is the captured guest network interface.
+	Interface string
+	// Device is the captured host TAP device of the interface.
+	Device string
+	// Packets is the number of packets written.
+	Packets int
+	// Bytes is the size of the written pcap stream.
+	Bytes int64
+	// StartedAt is when the capture started.
+	StartedAt time.Time
+	// Duration is how long the capture ran.
+	Duration time.Duration
+	// LimitReached is true when the capture stopped on the bytes or packets limit.
+	LimitReached bool
+}
+
+// ProcessTable is the process table of a running sandbox guest, returned by
+// [Client.Top].
+type ProcessTable struct {
+	// SandboxID is the ULID of the sandbox.
+	SandboxID string
+	// SandboxName is the name of the sandbox.
+	SandboxName string
+	// CollectedAt is when the table was read.
+	CollectedAt time.Time
+	// Uptime is the guest uptime.
+	Uptime time.Duration
+	// Load1, Load5 and Load15 are the guest load averages.
+	Load1  float64
+	Load5  float64
+	Load15 float64
+	// MemoryTotalBytes and MemoryAvailableBytes are the guest memory.
+	MemoryTotalBytes     uint64
+	MemoryAvailableBytes uint64
+	// Processes are sorted by CPU usage, highest first.
+	Processes []Process
+}
+
+// Process is a process running in a sandbox guest.
+type Process struct {
+	PID  int
+	PPID int
+	// User is the process user name, or its UID when the guest doesn't know it.
+	User string
+	// State is the procfs state (e.g. R running, S sleeping, Z zombie).
+	State string
+	// Command is the process command line, or the name between brackets for the
+	// kernel threads.
+	Command string
+	// CPUTime is the CPU time used by the process (user and system).
+	CPUTime time.Duration
+	// CPUPercent is the average usage of a single CPU since the process started.
+	CPUPercent float64
+	// RSSBytes is the resident memory of the process.
+	RSSBytes uint64
+	// MemoryPercent is the resident memory of the guest memory.
+	MemoryPercent float64
+	Threads       int
+	StartedAt     time.Time
+}
+
+// ConnectionDirection is the side of a sandbox connection that opened it.
+type ConnectionDirection string
+
+const (
+	// ConnectionDirectionEgress is a connection opened by the sandbox.
+	ConnectionDirectionEgress ConnectionDirection = "egress"
+	// ConnectionDirectionIngress is a connection opened to the sandbox.
+	ConnectionDirectionIngress ConnectionDirection = "ingress"
+)
+
+// Connection is an active network connection of a sandbox, returned by
+// [Client.Connections].
+type Connection struct {
+	// Protocol is the transport protocol (e.g. tcp, udp, icmp).
+	Protocol  string
+	Direction ConnectionDirection
+	// Interface is the sandbox network interface (e.g. eth0).
+	Interface string
+	// Source is the address that opened the connection (ip:port).
+	Source string
+	// Destination is the address the connection was opened to (ip:port).
+	Destination string
+	// Domain is the destination domain when known (empty otherwise).
+	Domain string
+	// State is the tracking state (e.g. ESTABLISHED), empty for UDP and ICMP.
+	State string
+	// Proxy is the egress proxy forwarding the connection (http, http-connect or
+	// tls), empty when the connection is not proxied.
+	Proxy string
+	// BytesSent and BytesReceived are the bytes sent and received by the source.
+	BytesSent     uint64
+	BytesReceived uint64
+}
+
+// IntegrityChange is a guest file changed since the integrity baseline.
+type IntegrityChange struct {
+	// Path is the absolute guest path.
+	Path string
+	// Kind is the kind of change.
+	Kind FileChangeKind
+	// OldSHA256 is the baseline content digest (empty when added).
+	OldSHA256 string
+	// NewSHA256 is the current content digest (empty when removed).
+	NewSHA256 string
 }
 
 // SnapshotInfo contains metadata specific to snapshot-created images.
@@ -261,6 +2025,12 @@ type SnapshotInfo struct {
 	SourceImage string
 	// ParentSnapshot is the snapshot image name this was derived from (for snapshot chains).
 	ParentSnapshot string
+	// MemoryFile is the guest memory file of live snapshots, taken while the sandbox
+	// was running (empty if it was stopped).
+	MemoryFile string
+	// VMStateFile is the microVM state file of live snapshots (empty if the sandbox
+	// was stopped).
+	VMStateFile string
 	// CreatedAt is when the snapshot was created.
 	CreatedAt time.Time
 }
@@ -307,10 +2077,45 @@ type PortMapping struct {
 	// BindAddress is the local address to listen on (e.g., "localhost", "0.0.0.0").
 	// Defaults to "localhost" if empty.
 	BindAddress string
-	// LocalPort is the port on the host machine.
+	// LocalPort is the port on the host machine. 0 binds a free port, use
+	// [Client.ForwardWithInfo] to get it.
 	LocalPort int
 	// RemotePort is the port inside the sandbox.
 	RemotePort int
+	// TLS terminates TLS on the local port and tunnels the plain connections into
+	// the sandbox, for the clients requiring https (optional).
+	TLS *ForwardTLS
+}
+
+// ForwardTLS is the TLS the host side of a port forward or the ingress terminates.
+// Without certificate and key files a self-signed certificate for localhost,
+// *.localhost and the loopback IPs is generated in the data dir (tls/localhost.crt)
+// and reused, so the clients can trust it once.
+type ForwardTLS struct {
+	// CertFile is the PEM certificate (chain) file.
+	CertFile string
+	// KeyFile is the PEM key file of the certificate.
+	KeyFile string
+}
+
+// ForwardInfo is the information of an established port forwarding.
+type ForwardInfo struct {
+	// Ports are the forwarded ports, with the bound local port of the free (0) ones.
+	Ports []PortMapping
+}
+
+// ExposeHTTPOpts are the options of [Client.ExposeHTTP].
+type ExposeHTTPOpts struct {
+	// Address is the ingress listen address, shared by the sandboxes the client
+	// exposes on it.
+	// Default: "localhost:8080".
+	Address string
+	// OnReady is called once the sandbox is reachable, with its ingress URL (e.g.
+	// http://my-sandbox.localhost:8080) (optional).
+	OnReady func(url string)
+	// TLS serves the ingress with https, the sandboxes get plain HTTP (optional).
+	// The sandboxes exposed on the same address must use the same TLS.
+	TLS *ForwardTLS
 }
 
 // --- Doctor types ---
@@ -327,6 +2132,24 @@ const (
 	CheckStatusError CheckStatus = "error"
 )
 
+// CheckSeverity is the machine-readable severity of a [CheckStatus], only
+// [CheckSeverityFail] is a hard failure.
+type CheckSeverity string
+
+const (
+	// CheckSeverityPass is the severity of the passed checks.
+	CheckSeverityPass CheckSeverity = "pass"
+	// CheckSeverityWarn is the severity of the checks passed with a warning.
+	CheckSeverityWarn CheckSeverity = "warn"
+	// CheckSeverityFail is the severity of the failed checks.
+	CheckSeverityFail CheckSeverity = "fail"
+)
+
+// Severity returns the severity of the check status.
+func (s CheckStatus) Severity() CheckSeverity {
+	return CheckSeverity(model.CheckStatus(s).Severity())
+}
+
 // CheckResult represents the result of a single preflight check.
 type CheckResult struct {
 	// ID is a unique identifier for the check (e.g. "kvm_available").
@@ -335,52 +2158,456 @@ type CheckResult struct {
 	Message string
 	// Status is the check status.
 	Status CheckStatus
+	// Remediation is how to fix a warning or error (empty when unknown).
+	Remediation string
+}
+
+// DBCompactResult is the result of [Client.CompactDB].
+type DBCompactResult struct {
+	// SizeBefore is the size in bytes of the database and its write-ahead log
+	// before the compaction.
+	SizeBefore int64
+	// SizeAfter is the size in bytes of the database and its write-ahead log
+	// after the compaction.
+	SizeAfter int64
+	// IntegrityErrors are the problems found by the integrity check, empty when
+	// the database is healthy. A database with problems is not compacted.
+	IntegrityErrors []string
+	// Duration is the time the compaction took.
+	Duration time.Duration
+}
+
+// DataLayout are the host directories holding the sandbox data, see [Config].VMsDir,
+// [Config].ImagesDir and [Config].SnapshotsDir.
+type DataLayout struct {
+	// VMsDir is the directory of the sandbox VMs (rootfs copies, SSH keys and runtime files).
+	VMsDir string
+	// ImagesDir is the directory of the installed images.
+	ImagesDir string
+	// SnapshotsDir is the directory of the snapshot images.
+	SnapshotsDir string
+}
+
+// MigrateDataLayoutOpts configures [Client.MigrateDataLayout].
+type MigrateDataLayoutOpts struct {
+	// To is the new layout, the empty directories keep the current ones.
+	To DataLayout
+	// DryRun only reports the moves without changing anything.
+	DryRun bool
+}
+
+// DataMoveKind is the kind of data moved by [Client.MigrateDataLayout].
+type DataMoveKind string
+
+const (
+	// DataMoveKindVM is the directory of a sandbox VM.
+	DataMoveKindVM DataMoveKind = "vm"
+	// DataMoveKindImage is an installed image.
+	DataMoveKindImage DataMoveKind = "image"
+	// DataMoveKindSnapshot is a snapshot image.
+	DataMoveKindSnapshot DataMoveKind = "snapshot"
+)
+
+// DataMove is a directory moved by [Client.MigrateDataLayout].
+type DataMove struct {
+	Kind DataMoveKind
+	// Name is the sandbox name of the VMs or the image name.
+	Name string
+	From string
+	To   string
+}
+
+// EngineCapabilities describes the optional features an engine supports,
+// returned by [Client.EngineCapabilities].
+type EngineCapabilities struct {
+	// Engine is the engine the capabilities belong to.
+	Engine EngineType
+	// Snapshots is true when stopped sandboxes can be snapshotted into images
+	// with [Client.CreateImageFromSandbox].
+	Snapshots bool
+	// LiveSnapshots is true when running sandboxes can be snapshotted without
+	// stopping them, recording their disk and memory.
+	LiveSnapshots bool
+	// PauseResume is true when running sandboxes can be paused and resumed keeping
+	// their memory.
+	PauseResume bool
+	// Egress is true when the sandbox network egress can be filtered.
+	Egress bool
+	// Networks is true when sandboxes can have additional network interfaces.
+	Networks bool
+	// PortForward is true when host ports can be forwarded with [Client.Forward].
+	PortForward bool
+	// MemoryBalloon is true when the memory of running sandboxes can be reclaimed
+	// with [Client.SetMemoryTarget].
+	MemoryBalloon bool
+	// Scaling is true when the VCPUs and memory of running sandboxes can be scaled
+	// with [Client.ScaleSandbox].
+	Scaling bool
+	// EngineAPI is true when the VMM machine API of running sandboxes can be used
+	// with [Client.EngineAPI].
+	EngineAPI bool
+	// Tty is true when commands can be executed with [ExecOpts].Tty.
+	Tty bool
+	// Mounts is true when host directories can be mounted in sandboxes.
+	Mounts bool
+	// Vsock is true when the host and the sandboxes can talk over vsock.
+	Vsock bool
+	// GPU is true when GPUs can be attached to sandboxes.
+	GPU bool
+}
+
+// --- Internal conversion helpers ---
+
+func toInternalSandboxConfig(opts CreateSandboxOpts) model.SandboxConfig {
+	cfg := model.SandboxConfig{
+		Name:  opts.Name,
+		Image: opts.FromImage,
+		Resources: model.Resources{
+			VCPUs:       opts.Resources.VCPUs,
+			MemoryMB:    opts.Resources.MemoryMB,
+			DiskGB:      opts.Resources.DiskGB,
+			MaxVCPUs:    opts.Resources.MaxVCPUs,
+			MaxMemoryMB: opts.Resources.MaxMemoryMB,
+		},
+		UserData: opts.UserData,
+		Ports:    maps.Clone(opts.Ports),
+	}
+
+	if opts.ExecProfile != nil {
+		cfg.ExecProfile = &model.ExecProfile{
+			PathPrepend: slices.Clone(opts.ExecProfile.PathPrepend),
+			Locale:      opts.ExecProfile.Locale,
+			Umask:       opts.ExecProfile.Umask,
+			Ulimits:     maps.Clone(opts.ExecProfile.Ulimits),
+			Shell:       opts.ExecProfile.Shell,
+		}
+	}
+
+	if opts.Clock != nil {
+		cfg.Clock = &model.ClockConfig{
+			BootTime: opts.Clock.BootTime,
+			Offset:   opts.Clock.Offset,
+		}
+	}
+
+	if opts.Firecracker != nil {
+		cfg.FirecrackerEngine = &model.FirecrackerEngineConfig{
+			RootFS:      opts.Firecracker.RootFS,
+			KernelImage: opts.Firecracker.KernelImage,
+			KernelArgs:  opts.Firecracker.KernelArgs,
+			Boot: model.BootOptions{
+				ReadOnlyRootFS: opts.Firecracker.Boot.ReadOnlyRootFS,
+				DisableConsole: opts.Firecracker.Boot.DisableConsole,
+				Init:           opts.Firecracker.Boot.Init,
+			},
+			Networks: toInternalNetworks(opts.Firecracker.Networks),
+			Seccomp: model.SeccompOptions{
+				Mode:   model.SeccompMode(opts.Firecracker.Seccomp.Mode),
+				Filter: opts.Firecracker.Seccomp.Filter,
+			},
+			EgressInterface: opts.Firecracker.EgressInterface,
+			Network: model.NetworkOptions{
+				MTU:      opts.Firecracker.Network.MTU,
+				ClampMSS: opts.Firecracker.Network.ClampMSS,
+			},
+		}
+	}
+
+	return cfg
+}
+
+func toInternalSessionConfig(opts *StartSandboxOpts) model.SessionConfig {
+	if opts == nil {
+		return model.SessionConfig{}
+	}
+
+	return model.SessionConfig{
+		Env:              opts.Env,
+		Egress:           toInternalEgressPolicy(opts.Egress),
+		EgressPolicyName: opts.EgressPolicyName,
+		IntegrityPaths:   opts.IntegrityPaths,
+	}
+}
+
+func toInternalStartTimeouts(t StartTimeouts) model.StartTimeouts {
+	return model.StartTimeouts{
+		Boot:             t.Boot,
+		SSHDial:          t.SSHDial,
+		SSHRetries:       t.SSHRetries,
+		FilesystemExpand: t.FilesystemExpand,
+	}
+}
+
+func toInternalRetryPolicy(p RetryPolicy) model.RetryPolicy {
+	return model.RetryPolicy{
+		Attempts:       p.Attempts,
+		InitialBackoff: p.InitialBackoff,
+		MaxBackoff:     p.MaxBackoff,
+		Multiplier:     p.Multiplier,
+		Jitter:         p.Jitter,
+	}
+}
+
+func toInternalRetryPolicies(p RetryPolicies) model.RetryPolicies {
+	return model.RetryPolicies{
+		SSHDial:        toInternalRetryPolicy(p.SSHDial),
+		FirecrackerAPI: toInternalRetryPolicy(p.FirecrackerAPI),
+		Nftables:       toInternalRetryPolicy(p.Nftables),
+	}
+}
+
+func toInternalProgress(r ProgressReporter) model.ProgressFunc {
+	if r == nil {
+		return nil
+	}
+
+	return func(ev model.ProgressEvent) {
+		r.Report(ProgressEvent{Step: ev.Step, Percent: ev.Percent, Message: ev.Message})
+	}
+}
+
+func toInternalEgressPolicy(p *EgressPolicy) *model.EgressPolicy {
+	if p == nil {
+		return nil
+	}
+
+	policy := &model.EgressPolicy{
+		Default:      model.EgressAction(p.Default),
+		FailClosed:   p.FailClosed,
+		DNSUpstreams: p.DNSUpstreams,
+	}
+	for _, s := range p.AllowSystemServices {
+		policy.AllowSystemServices = append(policy.AllowSystemServices, model.EgressSystemService(s))
+	}
+	for _, r := range p.Rules {
+		policy.Rules = append(policy.Rules, model.EgressRule{
+			Domain: r.Domain,
+			Action: model.EgressAction(r.Action),
+			ICMP:   r.ICMP,
+		})
+	}
+
+	return policy
+}
+
+func fromInternalEgressPolicy(p *model.EgressPolicy) *EgressPolicy {
+	if p == nil {
+		return nil
+	}
+
+	policy := &EgressPolicy{
+		Default:      EgressAction(p.Default),
+		FailClosed:   p.FailClosed,
+		DNSUpstreams: p.DNSUpstreams,
+	}
+	for _, s := range p.AllowSystemServices {
+		policy.AllowSystemServices = append(policy.AllowSystemServices, EgressSystemService(s))
+	}
+	for _, r := range p.Rules {
+		policy.Rules = append(policy.Rules, EgressRule{
+			Domain: r.Domain,
+			Action: EgressAction(r.Action),
+			ICMP:   r.ICMP,
+		})
+	}
+
+	return policy
+}
+
+func fromInternalNamedEgressPolicy(p model.NamedEgressPolicy) NamedEgressPolicy {
+	return NamedEgressPolicy{
+		Name:      p.Name,
+		Policy:    *fromInternalEgressPolicy(&p.Policy),
+		CreatedAt: p.CreatedAt,
+		UpdatedAt: p.UpdatedAt,
+	}
 }
 
-// --- Internal conversion helpers ---
+func toInternalTask(t Task) model.Task {
+	return model.Task{
+		Name:       t.Name,
+		Command:    t.Command,
+		WorkingDir: t.WorkingDir,
+		Env:        t.Env,
+		Files:      t.Files,
+		Timeout:    t.Timeout,
+	}
+}
 
-func toInternalSandboxConfig(opts CreateSandboxOpts) model.SandboxConfig {
-	cfg := model.SandboxConfig{
-		Name: opts.Name,
+func fromInternalTask(t model.Task) Task {
+	return Task{
+		Name:       t.Name,
+		SandboxID:  t.SandboxID,
+		Command:    t.Command,
+		WorkingDir: t.WorkingDir,
+		Env:        t.Env,
+		Files:      t.Files,
+		Timeout:    t.Timeout,
+		CreatedAt:  t.CreatedAt,
+		UpdatedAt:  t.UpdatedAt,
+	}
+}
+
+func toInternalSandboxTemplate(t SandboxTemplate) model.SandboxTemplate {
+	m := model.SandboxTemplate{
+		Name:        t.Name,
+		Description: t.Description,
+		Engine:      string(t.Engine),
+		Image:       t.FromImage,
+		Profile:     t.Profile,
 		Resources: model.Resources{
-			VCPUs:    opts.Resources.VCPUs,
-			MemoryMB: opts.Resources.MemoryMB,
-			DiskGB:   opts.Resources.DiskGB,
+			VCPUs:       t.Resources.VCPUs,
+			MemoryMB:    t.Resources.MemoryMB,
+			DiskGB:      t.Resources.DiskGB,
+			MaxVCPUs:    t.Resources.MaxVCPUs,
+			MaxMemoryMB: t.Resources.MaxMemoryMB,
 		},
+		UserData:         t.UserData,
+		Ports:            maps.Clone(t.Ports),
+		Env:              maps.Clone(t.Env),
+		Egress:           toInternalEgressPolicy(t.Egress),
+		EgressPolicyName: t.EgressPolicyName,
 	}
+	if p := t.ExecProfile; p != nil {
+		m.ExecProfile = &model.ExecProfile{
+			PathPrepend: slices.Clone(p.PathPrepend),
+			Locale:      p.Locale,
+			Umask:       p.Umask,
+			Ulimits:     maps.Clone(p.Ulimits),
+			Shell:       p.Shell,
+		}
+	}
+	return m
+}
 
-	if opts.Firecracker != nil {
-		cfg.FirecrackerEngine = &model.FirecrackerEngineConfig{
-			RootFS:      opts.Firecracker.RootFS,
-			KernelImage: opts.Firecracker.KernelImage,
+func fromInternalSandboxTemplate(t model.SandboxTemplate) SandboxTemplate {
+	out := SandboxTemplate{
+		Name:             t.Name,
+		Description:      t.Description,
+		Engine:           EngineType(t.Engine),
+		FromImage:        t.Image,
+		Profile:          t.Profile,
+		Resources:        fromInternalResources(t.Resources),
+		UserData:         t.UserData,
+		Ports:            t.Ports,
+		Env:              t.Env,
+		Egress:           fromInternalEgressPolicy(t.Egress),
+		EgressPolicyName: t.EgressPolicyName,
+		CreatedAt:        t.CreatedAt,
+		UpdatedAt:        t.UpdatedAt,
+	}
+	if p := t.ExecProfile; p != nil {
+		out.ExecProfile = &ExecProfile{
+			PathPrepend: p.PathPrepend,
+			Locale:      p.Locale,
+			Umask:       p.Umask,
+			Ulimits:     p.Ulimits,
+			Shell:       p.Shell,
 		}
 	}
+	return out
+}
 
-	return cfg
+func fromInternalEgressTestResults(results []model.EgressTestResult) []EgressTestResult {
+	out := make([]EgressTestResult, 0, len(results))
+	for _, r := range results {
+		res := EgressTestResult{
+			Target:    r.Target,
+			Domain:    r.Domain,
+			Action:    EgressAction(r.Action),
+			Reason:    EgressTestReason(r.Reason),
+			RuleIndex: r.RuleIndex,
+		}
+		if r.Rule != nil {
+			res.Rule = &EgressRule{Domain: r.Rule.Domain, Action: EgressAction(r.Rule.Action), ICMP: r.Rule.ICMP}
+		}
+		out = append(out, res)
+	}
+
+	return out
 }
 
-func toInternalSessionConfig(opts *StartSandboxOpts) model.SessionConfig {
-	if opts == nil {
-		return model.SessionConfig{}
+func fromInternalDNSEvents(events []model.DNSEvent) []DNSEvent {
+	out := make([]DNSEvent, 0, len(events))
+	for _, e := range events {
+		out = append(out, DNSEvent{
+			Time:            e.Time,
+			Domain:          e.Domain,
+			QType:           e.QType,
+			Action:          EgressAction(e.Action),
+			Reason:          DNSEventReason(e.Reason),
+			Rcode:           e.Rcode,
+			UpstreamLatency: e.UpstreamLatency,
+		})
 	}
 
-	cfg := model.SessionConfig{
-		Env: opts.Env,
+	return out
+}
+
+func fromInternalSandboxLogs(logs []model.SandboxLog) []SandboxLog {
+	out := make([]SandboxLog, 0, len(logs))
+	for _, l := range logs {
+		out = append(out, SandboxLog{Source: l.Source, Lines: l.Lines})
 	}
 
-	if opts.Egress != nil {
-		cfg.Egress = &model.EgressPolicy{
-			Default: model.EgressAction(opts.Egress.Default),
-		}
-		for _, r := range opts.Egress.Rules {
-			cfg.Egress.Rules = append(cfg.Egress.Rules, model.EgressRule{
-				Domain: r.Domain,
-				Action: model.EgressAction(r.Action),
-			})
-		}
+	return out
+}
+
+func fromInternalDNSStats(s model.DNSStats) DNSStats {
+	out := DNSStats{
+		Queries: s.Queries,
+		Allowed: s.Allowed,
+		Denied:  s.Denied,
+		Failed:  s.Failed,
+		Domains: make([]DNSDomainStats, 0, len(s.Domains)),
+	}
+	for _, d := range s.Domains {
+		out.Domains = append(out.Domains, DNSDomainStats{
+			Domain:             d.Domain,
+			Queries:            d.Queries,
+			Allowed:            d.Allowed,
+			Denied:             d.Denied,
+			Failed:             d.Failed,
+			AvgUpstreamLatency: d.AvgUpstreamLatency,
+			MaxUpstreamLatency: d.MaxUpstreamLatency,
+			LastQueryAt:        d.LastQueryAt,
+		})
 	}
 
-	return cfg
+	return out
+}
+
+func toInternalNetworks(nets []NetworkInterface) []model.NetworkInterface {
+	if len(nets) == 0 {
+		return nil
+	}
+
+	result := make([]model.NetworkInterface, 0, len(nets))
+	for _, n := range nets {
+		result = append(result, model.NetworkInterface{
+			Mode:    model.NetworkMode(n.Mode),
+			Network: n.Network,
+			Egress:  toInternalEgressPolicy(n.Egress),
+		})
+	}
+	return result
+}
+
+func fromInternalNetworks(nets []model.NetworkInterface) []NetworkInterface {
+	if len(nets) == 0 {
+		return nil
+	}
+
+	result := make([]NetworkInterface, 0, len(nets))
+	for _, n := range nets {
+		result = append(result, NetworkInterface{
+			Mode:    NetworkMode(n.Mode),
+			Network: n.Network,
+			Egress:  fromInternalEgressPolicy(n.Egress),
+			Address: n.Address,
+		})
+	}
+	return result
 }
 
 func toInternalExecOpts(opts *ExecOpts) model.ExecOpts {
@@ -398,28 +2625,319 @@ func toInternalExecOpts(opts *ExecOpts) model.ExecOpts {
 	}
 }
 
+func toInternalCopyOpts(opts *CopyOpts) model.CopyOpts {
+	if opts == nil {
+		return model.CopyOpts{}
+	}
+
+	res := model.CopyOpts{
+		Compress:       opts.Compress,
+		BandwidthLimit: opts.BandwidthLimit,
+		Retries:        opts.Retries,
+		Checksum:       opts.Checksum,
+	}
+	if opts.Progress != nil {
+		res.Progress = func(p model.CopyProgress) {
+			opts.Progress(CopyProgress{Bytes: p.Bytes, TotalBytes: p.TotalBytes})
+		}
+	}
+	return res
+}
+
+func fromInternalRemoteSnapshot(s model.RemoteSnapshot) *RemoteSnapshot {
+	return &RemoteSnapshot{Name: s.Name, URL: s.URL, SizeBytes: s.SizeBytes, Parts: s.Parts}
+}
+
+func fromInternalImageDiff(d model.ImageDiff) *SnapshotDiff {
+	state := func(s *model.FileState) *FileState {
+		if s == nil {
+			return nil
+		}
+		return &FileState{
+			Type:       string(s.Type),
+			Mode:       s.Mode,
+			UID:        s.UID,
+			GID:        s.GID,
+			SizeBytes:  s.SizeBytes,
+			SHA256:     s.SHA256,
+			LinkTarget: s.LinkTarget,
+		}
+	}
+
+	changes := make([]FileChange, 0, len(d.Changes))
+	for _, c := range d.Changes {
+		changes = append(changes, FileChange{
+			Path: c.Path,
+			Kind: FileChangeKind(c.Kind),
+			Old:  state(c.Old),
+			New:  state(c.New),
+		})
+	}
+	return &SnapshotDiff{From: d.From, To: d.To, Changes: changes}
+}
+
+func fromInternalIntegrityReport(r model.IntegrityReport) IntegrityReport {
+	changes := make([]IntegrityChange, 0, len(r.Changes))
+	for _, c := range r.Changes {
+		changes = append(changes, IntegrityChange{
+			Path:      c.Path,
+			Kind:      FileChangeKind(c.Kind),
+			OldSHA256: c.OldSHA256,
+			NewSHA256: c.NewSHA256,
+		})
+	}
+
+	return IntegrityReport{
+		SandboxID:   r.SandboxID,
+		SandboxName: r.SandboxName,
+		Paths:       r.Paths,
+		Files:       r.Files,
+		BaselineAt:  r.BaselineAt,
+		CheckedAt:   r.CheckedAt,
+		Changes:     changes,
+	}
+}
+
+func fromInternalCaptureResult(r model.CaptureResult) CaptureResult {
+	return CaptureResult{
+		Interface:    r.Interface,
+		Device:       r.Device,
+		Packets:      r.Packets,
+		Bytes:        r.Bytes,
+		StartedAt:    r.StartedAt,
+		Duration:     r.Duration,
+		LimitReached: r.LimitReached,
+	}
+}
+
+func fromInternalProcessTable(t model.ProcessTable) ProcessTable {
+	processes := make([]Process, 0, len(t.Processes))
+	for _, p := range t.Processes {
+		processes = append(processes, Process{
+			PID:           p.PID,
+			PPID:          p.PPID,
+			User:          p.User,
+			State:         p.State,
+			Command:       p.Command,
+			CPUTime:       p.CPUTime,
+			CPUPercent:    p.CPUPercent,
+			RSSBytes:      p.RSSBytes,
+			MemoryPercent: p.MemoryPercent,
+			Threads:       p.Threads,
+			StartedAt:     p.StartedAt,
+		})
+	}
+
+	return ProcessTable{
+		SandboxID:            t.SandboxID,
+		SandboxName:          t.SandboxName,
+		CollectedAt:          t.CollectedAt,
+		Uptime:               t.Uptime,
+		Load1:                t.Load1,
+		Load5:                t.Load5,
+		Load15:               t.Load15,
+		MemoryTotalBytes:     t.MemoryTotalBytes,
+		MemoryAvailableBytes: t.MemoryAvailableBytes,
+		Processes:            processes,
+	}
+}
+
+func fromInternalConnections(conns []model.Connection) []Connection {
+	out := make([]Connection, 0, len(conns))
+	for _, c := range conns {
+		out = append(out, Connection{
+			Protocol:      c.Protocol,
+			Direction:     ConnectionDirection(c.Direction),
+			Interface:     c.Interface,
+			Source:        c.Source,
+			Destination:   c.Destination,
+			Domain:        c.Domain,
+			State:         c.State,
+			Proxy:         c.Proxy,
+			BytesSent:     c.BytesSent,
+			BytesReceived: c.BytesReceived,
+		})
+	}
+	return out
+}
+
+func toInternalImageCustomization(c *ImageCustomization) *model.ImageCustomization {
+	if c == nil {
+		return nil
+	}
+
+	files := make([]model.ImageCustomizationFile, 0, len(c.Files))
+	for _, f := range c.Files {
+		files = append(files, model.ImageCustomizationFile{Src: f.Src, Dst: f.Dst})
+	}
+	return &model.ImageCustomization{Commands: c.Commands, Files: files}
+}
+
+func toInternalSyncOpts(opts SyncOpts) model.SyncOpts {
+	return model.SyncOpts{
+		Delete:  opts.Delete,
+		Exclude: opts.Exclude,
+	}
+}
+
+func fromInternalSyncResult(r model.SyncResult) SyncResult {
+	return SyncResult{
+		Uploaded:      r.Uploaded,
+		Deleted:       r.Deleted,
+		Unchanged:     r.Unchanged,
+		UploadedBytes: r.UploadedBytes,
+	}
+}
+
+func fromInternalFileInfo(f model.FileInfo) FileInfo {
+	return FileInfo{
+		Name:    f.Name,
+		Path:    f.Path,
+		Size:    f.Size,
+		Mode:    f.Mode,
+		ModTime: f.ModTime,
+		IsDir:   f.IsDir,
+	}
+}
+
+func fromInternalExecResult(r model.ExecResult) ExecResult {
+	return ExecResult{
+		ExitCode:            r.ExitCode,
+		StartedAt:           r.StartedAt,
+		FinishedAt:          r.FinishedAt,
+		Duration:            r.Duration,
+		StdoutBytes:         r.StdoutBytes,
+		StderrBytes:         r.StderrBytes,
+		Stdout:              r.Stdout,
+		Stderr:              r.Stderr,
+		StdoutTruncated:     r.StdoutTruncated,
+		StderrTruncated:     r.StderrTruncated,
+		OutputLimitExceeded: r.OutputLimitExceeded,
+	}
+}
+
+func fromInternalExecRecords(rs []model.ExecRecord) []ExecRecord {
+	result := make([]ExecRecord, 0, len(rs))
+	for _, r := range rs {
+		result = append(result, ExecRecord{
+			ID:           r.ID,
+			Command:      r.Command,
+			WorkingDir:   r.WorkingDir,
+			User:         r.User,
+			Caller:       r.Caller,
+			ExitCode:     r.ExitCode,
+			Error:        r.Error,
+			StartedAt:    r.StartedAt,
+			Duration:     r.Duration,
+			StdoutBytes:  r.StdoutBytes,
+			StderrBytes:  r.StderrBytes,
+			StdoutSHA256: r.StdoutSHA256,
+			StderrSHA256: r.StderrSHA256,
+		})
+	}
+	return result
+}
+
 func fromInternalSandbox(s model.Sandbox) Sandbox {
 	sb := Sandbox{
 		ID:        s.ID,
+		Namespace: s.Namespace,
 		Name:      s.Name,
 		Status:    SandboxStatus(s.Status),
 		CreatedAt: s.CreatedAt,
 		StartedAt: s.StartedAt,
 		StoppedAt: s.StoppedAt,
 		Config: SandboxConfig{
-			Name: s.Config.Name,
-			Resources: Resources{
-				VCPUs:    s.Config.Resources.VCPUs,
-				MemoryMB: s.Config.Resources.MemoryMB,
-				DiskGB:   s.Config.Resources.DiskGB,
-			},
+			Name:      s.Config.Name,
+			Image:     s.Config.Image,
+			Resources: fromInternalResources(s.Config.Resources),
+			UserData:  s.Config.UserData,
+			Ports:     s.Config.Ports,
 		},
+		RootFSStrategy: RootFSStrategy(s.RootFSStrategy),
+		Annotations:    s.Annotations,
+	}
+
+	sb.BootPhases = fromInternalBootPhases(s.BootPhases)
+	sb.LastBootPhases = fromInternalBootPhases(s.LastBootPhases)
+	sb.LastError = s.LastError
+
+	if s.Egress != nil {
+		sb.Egress = &EgressStatus{
+			Health:    EgressHealth(s.Egress.Health),
+			Restarts:  s.Egress.Restarts,
+			LastError: s.Egress.LastError,
+			UpdatedAt: s.Egress.UpdatedAt,
+		}
+	}
+
+	if s.Seccomp != nil {
+		sb.Seccomp = &SeccompStatus{Enforced: s.Seccomp.Enforced, Filters: s.Seccomp.Filters}
+	}
+
+	if s.IdlePolicy != nil {
+		sb.IdlePolicy = &IdlePolicy{Timeout: s.IdlePolicy.Timeout, Action: IdleAction(s.IdlePolicy.Action)}
+	}
+
+	if !s.Activity.LastActivityAt.IsZero() {
+		t := s.Activity.LastActivityAt
+		sb.LastActivityAt = &t
+	}
+	sb.Persistent = s.Persistent
+	if s.QuarantinedAt != nil {
+		t := *s.QuarantinedAt
+		sb.QuarantinedAt = &t
+	}
+	if s.Session != nil {
+		session := s.Session.Redacted()
+		sb.Session = &SandboxSession{
+			Egress:           fromInternalEgressPolicy(session.Egress),
+			EgressPolicyName: session.EgressPolicyName,
+			IntegrityPaths:   session.IntegrityPaths,
+		}
+		if len(session.Env) > 0 {
+			sb.Session.Env = session.Env
+		}
+	}
+
+	if p := s.Config.ExecProfile; p != nil {
+		sb.Config.ExecProfile = &ExecProfile{
+			PathPrepend: p.PathPrepend,
+			Locale:      p.Locale,
+			Umask:       p.Umask,
+			Ulimits:     p.Ulimits,
+			Shell:       p.Shell,
+		}
+	}
+
+	if s.Config.Clock != nil {
+		sb.Config.Clock = &ClockOptions{
+			BootTime: s.Config.Clock.BootTime,
+			Offset:   s.Config.Clock.Offset,
+		}
 	}
 
 	if s.Config.FirecrackerEngine != nil {
 		sb.Config.Firecracker = &FirecrackerConfig{
 			RootFS:      s.Config.FirecrackerEngine.RootFS,
 			KernelImage: s.Config.FirecrackerEngine.KernelImage,
+			KernelArgs:  s.Config.FirecrackerEngine.KernelArgs,
+			Boot: BootOptions{
+				ReadOnlyRootFS: s.Config.FirecrackerEngine.Boot.ReadOnlyRootFS,
+				DisableConsole: s.Config.FirecrackerEngine.Boot.DisableConsole,
+				Init:           s.Config.FirecrackerEngine.Boot.Init,
+				Profile:        s.Config.FirecrackerEngine.Boot.Profile,
+			},
+			Networks: fromInternalNetworks(s.Config.FirecrackerEngine.Networks),
+			Seccomp: SeccompOptions{
+				Mode:   SeccompMode(s.Config.FirecrackerEngine.Seccomp.Mode),
+				Filter: s.Config.FirecrackerEngine.Seccomp.Filter,
+			},
+			EgressInterface: s.Config.FirecrackerEngine.EgressInterface,
+			Network: NetworkOptions{
+				MTU:      s.Config.FirecrackerEngine.Network.MTU,
+				ClampMSS: s.Config.FirecrackerEngine.Network.ClampMSS,
+			},
 		}
 	}
 
@@ -442,20 +2960,113 @@ func toInternalStatusFilter(opts *ListSandboxesOpts) *model.SandboxStatus {
 	return &s
 }
 
-func mapError(err error) error {
+func toInternalNamespaceFilter(opts *ListSandboxesOpts) string {
+	if opts == nil {
+		return ""
+	}
+	return opts.Namespace
+}
+
+// fromInternalStartError wraps the error in a [StartError] when it's the error of a
+// failed start step.
+func fromInternalStartError(err error) error {
+	var startErr *model.StartError
+	if !errors.As(err, &startErr) {
+		return err
+	}
+	return &StartError{Step: startErr.Step, Phases: fromInternalBootPhases(startErr.Phases), RollbackErr: startErr.RollbackErr, Err: err}
+}
+
+func fromInternalBootPhases(phases []model.BootPhase) []BootPhase {
+	var res []BootPhase
+	for _, p := range phases {
+		res = append(res, BootPhase{Name: p.Name, Duration: p.Duration, Error: p.Error})
+	}
+	return res
+}
+
+// fromInternalBusyError wraps the error in a [BusyError] when a host resource is
+// locked by another client.
+func fromInternalBusyError(err error) error {
+	var busyErr *model.BusyError
+	if !errors.As(err, &busyErr) {
+		return err
+	}
+
+	res := &BusyError{Resource: busyErr.Resource, Err: err}
+	if h := busyErr.Holder; h != nil {
+		res.Holder = &LockHolder{PID: h.PID, Hostname: h.Hostname, DBPath: h.DBPath, Operation: h.Operation, Since: h.Since}
+	}
+	return res
+}
+
+// fromInternalRetryError wraps the error in a [RetryError] when it's the error of an
+// engine operation that gave up retrying a transient failure.
+func fromInternalRetryError(err error) error {
+	var retryErr *model.RetryError
+	if !errors.As(err, &retryErr) {
+		return err
+	}
+	return &RetryError{Operation: retryErr.Operation, Attempts: retryErr.Attempts, Err: err}
+}
+
+// fromInternalSpecMismatchError wraps the error in a [SpecMismatchError] when a
+// sandbox with the name already exists with a different spec.
+func fromInternalSpecMismatchError(err error) error {
+	var mismatchErr *model.SpecMismatchError
+	if !errors.As(err, &mismatchErr) {
+		return err
+	}
+
+	fields := make([]SpecField, 0, len(mismatchErr.Fields))
+	for _, f := range mismatchErr.Fields {
+		fields = append(fields, SpecField(f))
+	}
+	return &SpecMismatchError{Name: mismatchErr.Name, Fields: fields, Err: err}
+}
+
+// mapError converts an internal error into an [Error] of the resource.
+func mapError(err error, kind ResourceKind, name string) error {
 	if err == nil {
 		return nil
 	}
 
+	var sbxErr *Error
+	if errors.As(err, &sbxErr) {
+		return err
+	}
+
+	err = fromInternalBusyError(err)
+	err = fromInternalRetryError(err)
+	err = fromInternalSpecMismatchError(err)
+	code := errorCode(err)
+	return &Error{
+		Code:      code,
+		Kind:      kind,
+		Name:      name,
+		Retryable: code == ErrorCodeTimeout || code == ErrorCodeConflict || code == ErrorCodeBusy,
+		Err:       err,
+	}
+}
+
+func errorCode(err error) ErrorCode {
 	switch {
-	case isInternalError(err, model.ErrNotFound):
-		return joinErrors(err, ErrNotFound)
-	case isInternalError(err, model.ErrAlreadyExists):
-		return joinErrors(err, ErrAlreadyExists)
-	case isInternalError(err, model.ErrNotValid):
-		return joinErrors(err, ErrNotValid)
+	case isInternalError(err, model.ErrNotFound), errors.Is(err, ErrNotFound):
+		return ErrorCodeNotFound
+	case isInternalError(err, model.ErrAlreadyExists), errors.Is(err, ErrAlreadyExists):
+		return ErrorCodeAlreadyExists
+	case isInternalError(err, model.ErrNotValid), errors.Is(err, ErrNotValid):
+		return ErrorCodeNotValid
+	case isInternalError(err, model.ErrConflict), errors.Is(err, ErrConflict):
+		return ErrorCodeConflict
+	case isInternalError(err, model.ErrBusy), errors.Is(err, ErrBusy):
+		return ErrorCodeBusy
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorCodeTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrorCodeCanceled
 	default:
-		return err
+		return ErrorCodeInternal
 	}
 }
 
@@ -480,30 +3091,210 @@ func unwrapSingle(err error) error {
 	return u.Unwrap()
 }
 
-func joinErrors(original, sentinel error) error {
-	return &mappedError{original: original, sentinel: sentinel}
+func toInternalEventSinks(sinks []EventSink) []model.EventSinkConfig {
+	if len(sinks) == 0 {
+		return nil
+	}
+	out := make([]model.EventSinkConfig, 0, len(sinks))
+	for _, s := range sinks {
+		out = append(out, model.EventSinkConfig{
+			Type:   model.EventSinkType(s.Type),
+			Path:   s.Path,
+			URL:    s.URL,
+			Secret: s.Secret,
+			Events: toInternalEventTypes(s.Events),
+		})
+	}
+	return out
+}
+
+func toInternalIdlePolicy(p *IdlePolicy) *model.IdlePolicy {
+	if p == nil {
+		return nil
+	}
+	return &model.IdlePolicy{Timeout: p.Timeout, Action: model.IdleAction(p.Action)}
+}
+
+func toInternalHooks(hooks []Hook) []model.HostHook {
+	if len(hooks) == 0 {
+		return nil
+	}
+	out := make([]model.HostHook, 0, len(hooks))
+	for _, h := range hooks {
+		hook := model.HostHook{
+			Event:   model.HookEvent(h.Event),
+			Command: h.Command,
+			Timeout: h.Timeout,
+		}
+		if h.Func != nil {
+			fn := h.Func
+			hook.Func = func(ctx context.Context, event model.HookEvent, sb model.Sandbox) error {
+				return fn(ctx, HookEvent(event), fromInternalSandbox(sb))
+			}
+		}
+		out = append(out, hook)
+	}
+	return out
+}
+
+func toInternalWebhooks(webhooks []Webhook) []model.Webhook {
+	if len(webhooks) == 0 {
+		return nil
+	}
+	out := make([]model.Webhook, 0, len(webhooks))
+	for _, w := range webhooks {
+		out = append(out, model.Webhook{
+			URL:    w.URL,
+			Secret: w.Secret,
+			Events: toInternalEventTypes(w.Events),
+		})
+	}
+	return out
+}
+
+func toInternalEventTypes(types []EventType) []model.EventType {
+	if len(types) == 0 {
+		return nil
+	}
+	out := make([]model.EventType, 0, len(types))
+	for _, t := range types {
+		out = append(out, model.EventType(t))
+	}
+	return out
+}
+
+func toInternalPlannerConfig(a *AdmissionConfig, repo storage.Repository, vmsDir string, logger log.Logger) capacity.PlannerConfig {
+	cfg := capacity.PlannerConfig{
+		Repository: repo,
+		DataDir:    vmsDir,
+		Logger:     logger,
+	}
+	if a != nil {
+		cfg.Mode = model.AdmissionMode(a.Mode)
+		cfg.Overcommit = model.OvercommitRatios(a.Overcommit)
+	}
+	return cfg
+}
+
+func fromInternalResources(r model.Resources) Resources {
+	return Resources{VCPUs: r.VCPUs, MemoryMB: r.MemoryMB, DiskGB: r.DiskGB, MaxVCPUs: r.MaxVCPUs, MaxMemoryMB: r.MaxMemoryMB}
+}
+
+func fromInternalUsage(u model.Usage) Usage {
+	return Usage{CPUSeconds: u.CPUSeconds, MemoryMBHours: u.MemoryMBHours, DiskGBHours: u.DiskGBHours}
+}
+
+func fromInternalUsageReport(r model.UsageReport) UsageReport {
+	out := UsageReport{
+		Since:   r.Since,
+		Until:   r.Until,
+		GroupBy: r.GroupBy,
+		Entries: make([]UsageReportEntry, 0, len(r.Entries)),
+		Total:   fromInternalUsage(r.Total),
+	}
+	for _, e := range r.Entries {
+		out.Entries = append(out.Entries, UsageReportEntry{
+			Key:       e.Key,
+			SandboxID: e.SandboxID,
+			Removed:   e.Removed,
+			Sandboxes: e.Sandboxes,
+			Usage:     fromInternalUsage(e.Usage),
+		})
+	}
+	return out
+}
+
+func fromInternalNamespaces(namespaces []model.NamespaceUsage) []Namespace {
+	out := make([]Namespace, 0, len(namespaces))
+	for _, ns := range namespaces {
+		out = append(out, Namespace{Name: ns.Name, Sandboxes: ns.Sandboxes, RunningSandboxes: ns.RunningSandboxes})
+	}
+	return out
+}
+
+func fromInternalHostCapacity(c model.HostCapacity) HostCapacity {
+	return HostCapacity{
+		Total:            fromInternalResources(c.Total),
+		Allocatable:      fromInternalResources(c.Allocatable()),
+		Allocated:        fromInternalResources(c.Allocated),
+		Free:             fromInternalResources(c.Free()),
+		Overcommit:       OvercommitRatios(c.Overcommit),
+		Sandboxes:        c.Sandboxes,
+		RunningSandboxes: c.RunningSandboxes,
+	}
+}
+
+func fromInternalReservation(r model.Reservation) Reservation {
+	return Reservation{
+		SandboxID: r.SandboxID,
+		Name:      r.Name,
+		Namespace: r.Namespace,
+		Status:    SandboxStatus(r.Status),
+		Resources: fromInternalResources(r.Resources),
+		Reserved:  fromInternalResources(r.Reserved),
+		UpdatedAt: r.UpdatedAt,
+	}
+}
+
+func fromInternalRecoveryResult(r model.RecoveryResult) RecoveryResult {
+	return RecoveryResult{
+		SandboxID:  r.SandboxID,
+		Name:       r.Name,
+		Namespace:  r.Namespace,
+		Persistent: r.Persistent,
+		Action:     RecoveryAction(r.Action),
+		Error:      r.Error,
+	}
 }
 
-type mappedError struct {
-	original error
-	sentinel error
+func fromInternalBootUnit(u model.BootUnit) BootUnit {
+	return BootUnit{
+		Name:        u.Name,
+		Path:        u.Path,
+		SandboxID:   u.SandboxID,
+		SandboxName: u.SandboxName,
+		Restart:     BootRestartPolicy(u.Restart),
+	}
 }
 
-func (e *mappedError) Error() string { return e.original.Error() }
+func fromInternalDBCompactResult(r model.DBCompactResult) DBCompactResult {
+	return DBCompactResult{
+		SizeBefore:      r.SizeBefore,
+		SizeAfter:       r.SizeAfter,
+		IntegrityErrors: r.IntegrityErrors,
+		Duration:        r.Duration,
+	}
+}
 
-func (e *mappedError) Is(target error) bool {
-	return target == e.sentinel
+func toInternalDataLayout(l DataLayout) model.DataLayout {
+	return model.DataLayout{
+		VMsDir:       l.VMsDir,
+		ImagesDir:    l.ImagesDir,
+		SnapshotsDir: l.SnapshotsDir,
+	}
 }
 
-func (e *mappedError) Unwrap() error { return e.original }
+func fromInternalDataMoves(moves []model.DataMove) []DataMove {
+	out := make([]DataMove, 0, len(moves))
+	for _, m := range moves {
+		out = append(out, DataMove{
+			Kind: DataMoveKind(m.Kind),
+			Name: m.Name,
+			From: m.From,
+			To:   m.To,
+		})
+	}
+	return out
+}
 
 // --- Image conversion helpers ---
 
 func fromInternalImageRelease(r model.ImageRelease) ImageRelease {
 	return ImageRelease{
-		Version:   r.Version,
-		Installed: r.Installed,
-		Source:    ImageSource(r.Source),
+		Version:     r.Version,
+		Installed:   r.Installed,
+		Source:      ImageSource(r.Source),
+		InstalledAt: r.InstalledAt,
 	}
 }
 
@@ -515,6 +3306,22 @@ func fromInternalImageReleaseList(rs []model.ImageRelease) []ImageRelease {
 	return result
 }
 
+func fromInternalImageDiskUsage(u *model.ImageDiskUsage) *ImageDiskUsage {
+	result := &ImageDiskUsage{
+		Images:     make([]ImageUsage, len(u.Images)),
+		TotalBytes: u.TotalBytes,
+		DiskBytes:  u.DiskBytes,
+	}
+	for i, img := range u.Images {
+		result.Images[i] = ImageUsage{
+			Name:        img.Name,
+			SizeBytes:   img.SizeBytes,
+			SharedBytes: img.SharedBytes,
+		}
+	}
+	return result
+}
+
 func fromInternalImageManifest(m *model.ImageManifest) *ImageManifest {
 	if m == nil {
 		return nil
@@ -559,10 +3366,39 @@ func fromInternalImageManifest(m *model.ImageManifest) *ImageManifest {
 			SourceSandboxName: m.Snapshot.SourceSandboxName,
 			SourceImage:       m.Snapshot.SourceImage,
 			ParentSnapshot:    m.Snapshot.ParentSnapshot,
+			MemoryFile:        m.Snapshot.MemoryFile,
+			VMStateFile:       m.Snapshot.VMStateFile,
 			CreatedAt:         m.Snapshot.CreatedAt,
 		}
 	}
 
+	if m.Local != nil {
+		result.Local = &LocalImageInfo{
+			KernelSource: m.Local.KernelSource,
+			RootFSSource: m.Local.RootFSSource,
+			AddedAt:      m.Local.AddedAt,
+		}
+	}
+
+	if m.Customized != nil {
+		result.Customized = &CustomizedImageInfo{
+			BaseImage: m.Customized.BaseImage,
+			Commands:  m.Customized.Commands,
+			Files:     m.Customized.Files,
+			Digest:    m.Customized.Digest,
+			CreatedAt: m.Customized.CreatedAt,
+		}
+	}
+
+	for _, p := range m.KernelProfiles() {
+		result.Profiles = append(result.Profiles, KernelProfile{
+			Name:           p.Name,
+			Description:    p.Description,
+			KernelArgs:     p.KernelArgs,
+			DisableConsole: p.DisableConsole,
+		})
+	}
+
 	return result
 }
 
@@ -576,19 +3412,57 @@ func toInternalPortMappings(ports []PortMapping) []model.PortMapping {
 			LocalPort:   p.LocalPort,
 			RemotePort:  p.RemotePort,
 		}
+		if p.TLS != nil {
+			result[i].TLS = &model.ForwardTLS{CertFile: p.TLS.CertFile, KeyFile: p.TLS.KeyFile}
+		}
+	}
+	return result
+}
+
+func fromInternalPortMappings(ports []model.PortMapping) []PortMapping {
+	result := make([]PortMapping, len(ports))
+	for i, p := range ports {
+		result[i] = PortMapping{
+			BindAddress: p.BindAddress,
+			LocalPort:   p.LocalPort,
+			RemotePort:  p.RemotePort,
+		}
+		if p.TLS != nil {
+			result[i].TLS = &ForwardTLS{CertFile: p.TLS.CertFile, KeyFile: p.TLS.KeyFile}
+		}
 	}
 	return result
 }
 
 // --- Doctor conversion helpers ---
 
+func fromInternalEngineCapabilities(engine EngineType, c model.EngineCapabilities) EngineCapabilities {
+	return EngineCapabilities{
+		Engine:        engine,
+		Snapshots:     c.Snapshots,
+		LiveSnapshots: c.LiveSnapshots,
+		PauseResume:   c.PauseResume,
+		Egress:        c.Egress,
+		Networks:      c.Networks,
+		PortForward:   c.PortForward,
+		MemoryBalloon: c.MemoryBalloon,
+		Scaling:       c.Scaling,
+		EngineAPI:     c.EngineAPI,
+		Tty:           c.Tty,
+		Mounts:        c.Mounts,
+		Vsock:         c.Vsock,
+		GPU:           c.GPU,
+	}
+}
+
 func fromInternalCheckResults(results []model.CheckResult) []CheckResult {
 	out := make([]CheckResult, len(results))
 	for i, r := range results {
 		out[i] = CheckResult{
-			ID:      r.ID,
-			Message: r.Message,
-			Status:  CheckStatus(r.Status),
+			ID:          r.ID,
+			Message:     r.Message,
+			Status:      CheckStatus(r.Status),
+			Remediation: r.Remediation,
 		}
 	}
 	return out
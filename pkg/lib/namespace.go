@@ -0,0 +1,39 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/app/namespacelist"
+)
+
+// Namespace returns the namespace of the client, [AllNamespaces] for the
+// administration clients.
+func (c *Client) Namespace() string {
+	return c.namespace
+}
+
+// ListNamespaces returns the namespaces with sandboxes sorted by name. It's an
+// administration API, a namespace only exists while it has sandboxes.
+//
+// Returns [ErrNotValid] if the client is not scoped to [AllNamespaces].
+func (c *Client) ListNamespaces(ctx context.Context) ([]Namespace, error) {
+	if c.namespace != AllNamespaces {
+		return nil, fmt.Errorf("listing the namespaces requires a client of all the namespaces: %w", ErrNotValid)
+	}
+
+	svc, err := namespacelist.NewService(namespacelist.ServiceConfig{
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	namespaces, err := svc.Run(ctx)
+	if err != nil {
+		return nil, mapError(err, "", "")
+	}
+
+	return fromInternalNamespaces(namespaces), nil
+}
@@ -0,0 +1,71 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/app/quarantine"
+	"github.com/slok/sbx/internal/model"
+)
+
+// QuarantineSandbox isolates a sandbox without stopping it, so a suspicious
+// workload can be investigated with its live state.
+//
+// A quarantined sandbox can't reach the internet, the host or other sandboxes
+// (all its traffic is denied, whatever its egress policy), and its port forwards
+// ([Client.Forward]) and exposed services ([Client.ExposeHTTP]) are revoked. The
+// commands and file copies of the host keep working. The quarantine is kept
+// across restarts until [Client.ReleaseQuarantine], a stopped sandbox starts
+// isolated. Quarantining a quarantined sandbox does nothing.
+//
+// Emits the [EventSandboxQuarantined] event. Returns [ErrNotFound] if the sandbox
+// does not exist.
+func (c *Client) QuarantineSandbox(ctx context.Context, nameOrID string) (*Sandbox, error) {
+	return c.setQuarantine(ctx, nameOrID, false)
+}
+
+// ReleaseQuarantine lifts the quarantine of a sandbox set by
+// [Client.QuarantineSandbox], restoring its network access. Releasing a sandbox
+// that isn't quarantined does nothing.
+//
+// Emits the [EventSandboxQuarantineReleased] event. Returns [ErrNotFound] if the
+// sandbox does not exist.
+func (c *Client) ReleaseQuarantine(ctx context.Context, nameOrID string) (*Sandbox, error) {
+	return c.setQuarantine(ctx, nameOrID, true)
+}
+
+func (c *Client) setQuarantine(ctx context.Context, nameOrID string, release bool) (*Sandbox, error) {
+	sb, err := c.getInternalSandbox(ctx, nameOrID)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	eng, err := c.newEngine(sb.Config)
+	if err != nil {
+		return nil, mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, nameOrID)
+	}
+
+	svc, err := quarantine.NewService(quarantine.ServiceConfig{
+		Engine:     eng,
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	result, err := svc.Run(ctx, quarantine.Request{NameOrID: nameOrID, Release: release})
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	switch {
+	case release && sb.QuarantinedAt != nil:
+		c.emitEvent(model.EventTypeSandboxQuarantineReleased, *result, nil)
+	case !release && sb.QuarantinedAt == nil:
+		c.emitEvent(model.EventTypeSandboxQuarantined, *result, nil)
+	}
+
+	out := fromInternalSandbox(*result)
+	return &out, nil
+}
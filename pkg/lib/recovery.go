@@ -0,0 +1,111 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/slok/sbx/internal/app/list"
+	"github.com/slok/sbx/internal/app/persist"
+	"github.com/slok/sbx/internal/app/recovery"
+	"github.com/slok/sbx/internal/model"
+)
+
+// SetPersistent sets if a sandbox is persistent: started again by
+// [Client.RecoverSandboxes] when the host reboot (or a crash) kills it while
+// running. It doesn't change the sandbox status.
+//
+// Returns [ErrNotFound] if the sandbox does not exist.
+func (c *Client) SetPersistent(ctx context.Context, nameOrID string, persistent bool) (*Sandbox, error) {
+	svc, err := persist.NewService(persist.ServiceConfig{
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	result, err := svc.Run(ctx, persist.Request{NameOrID: nameOrID, Persistent: persistent})
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	out := fromInternalSandbox(*result)
+	return &out, nil
+}
+
+// RecoverSandboxes recovers the sandboxes whose VM died while running, usually
+// after a host reboot: the database still has them as running. They are flagged as
+// stopped, emitting the [EventSandboxStopped] event, except the persistent ones
+// (see [Client.SetPersistent]) that are started again with the session of their
+// previous start, recreating their network and emitting the [EventSandboxStarted]
+// event.
+//
+// sbx has no daemon, call it once when the host boots (e.g. from a systemd unit
+// after the network is online). It returns the recovery of every running sandbox,
+// the ones alive have [RecoveryActionNone]. A persistent sandbox that can't be
+// started is left stopped with [RecoveryActionFailed] and doesn't stop the others,
+// the sandboxes that can't be checked are joined in the returned error.
+func (c *Client) RecoverSandboxes(ctx context.Context) ([]RecoveryResult, error) {
+	listSvc, err := list.NewService(list.ServiceConfig{
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	running := model.SandboxStatusRunning
+	sandboxes, err := listSvc.Run(ctx, list.Request{StatusFilter: &running})
+	if err != nil {
+		return nil, mapError(err, "", "")
+	}
+
+	results := []RecoveryResult{}
+	var errs []error
+	for _, sb := range sandboxes {
+		result, err := c.recoverSandbox(ctx, sb)
+		if err != nil {
+			errs = append(errs, mapError(err, ResourceKindSandbox, sb.Name))
+			continue
+		}
+		results = append(results, fromInternalRecoveryResult(*result))
+	}
+
+	return results, errors.Join(errs...)
+}
+
+func (c *Client) recoverSandbox(ctx context.Context, sb model.Sandbox) (*model.RecoveryResult, error) {
+	eng, err := c.newEngine(sb.Config)
+	if err != nil {
+		return nil, fmt.Errorf("could not create engine: %w", err)
+	}
+
+	svc, err := recovery.NewService(recovery.ServiceConfig{
+		Engine:     eng,
+		Repository: c.repo,
+		Admission:  c.planner,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	result, err := svc.Run(ctx, recovery.Request{NameOrID: sb.ID, Timeouts: c.startTimeouts})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Action == model.RecoveryActionNone {
+		return result, nil
+	}
+	if recovered, err := c.repo.GetSandbox(ctx, sb.ID); err == nil {
+		event := model.EventTypeSandboxStopped
+		if result.Action == model.RecoveryActionRestarted {
+			event = model.EventTypeSandboxStarted
+		}
+		c.emitEvent(event, *recovered, nil)
+	}
+
+	return result, nil
+}
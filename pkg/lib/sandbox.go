@@ -2,15 +2,26 @@ package lib
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
+	"time"
 
+	"github.com/slok/sbx/internal/app/clone"
 	"github.com/slok/sbx/internal/app/create"
+	"github.com/slok/sbx/internal/app/createstart"
 	"github.com/slok/sbx/internal/app/list"
+	"github.com/slok/sbx/internal/app/rebase"
 	"github.com/slok/sbx/internal/app/remove"
+	"github.com/slok/sbx/internal/app/restart"
 	"github.com/slok/sbx/internal/app/start"
 	"github.com/slok/sbx/internal/app/status"
 	"github.com/slok/sbx/internal/app/stop"
+	"github.com/slok/sbx/internal/app/wait"
+	"github.com/slok/sbx/internal/image"
 	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/sandbox"
+	"github.com/slok/sbx/internal/storage"
 )
 
 // CreateSandbox creates a new sandbox with the given configuration.
@@ -23,101 +34,153 @@ import (
 // auto-populated with stub values.
 //
 // When [CreateSandboxOpts].FromImage is set, the kernel and rootfs paths are
-// resolved from the installed image (release or snapshot). The Firecracker field
+// resolved from the installed image (release or snapshot). The Firecracker paths
 // must not be set when using FromImage.
 //
-// Returns [ErrAlreadyExists] if a sandbox with the same name exists,
-// or [ErrNotValid] if the configuration is invalid.
+// When [CreateSandboxOpts].IfNotExists is set, an existing sandbox with the same
+// name and spec is returned as is (whatever its status), this allows declarative
+// "ensure this sandbox exists" automation.
+//
+// Returns [ErrAlreadyExists] if a sandbox with the same name exists (a
+// [SpecMismatchError] when using IfNotExists), or [ErrNotValid] if the configuration
+// is invalid or there is not enough host capacity (see [Config].Admission).
 func (c *Client) CreateSandbox(ctx context.Context, opts CreateSandboxOpts) (*Sandbox, error) {
-	// Resolve image paths when FromImage is set.
-	var firecrackerBinaryOverride string
-	if opts.FromImage != "" {
-		if opts.Firecracker != nil {
-			return nil, fmt.Errorf("FromImage and Firecracker config cannot be used together: %w", ErrNotValid)
-		}
-
-		mgr, err := c.newLocalImageManager()
-		if err != nil {
-			return nil, fmt.Errorf("could not create image manager: %w", err)
-		}
-
-		exists, err := mgr.Exists(ctx, opts.FromImage)
-		if err != nil {
-			return nil, fmt.Errorf("could not check image %s: %w", opts.FromImage, err)
-		}
-		if !exists {
-			return nil, fmt.Errorf("image %s is not installed: %w", opts.FromImage, ErrNotFound)
-		}
-
-		opts.Firecracker = &FirecrackerConfig{
-			KernelImage: mgr.KernelPath(opts.FromImage),
-			RootFS:      mgr.RootFSPath(opts.FromImage),
-		}
-		firecrackerBinaryOverride = mgr.FirecrackerPath(opts.FromImage)
+	opts, cfg, eng, err := c.prepareCreate(ctx, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	cfg := toInternalSandboxConfig(opts)
+	svc, err := create.NewService(create.ServiceConfig{
+		Engine:     eng,
+		Repository: c.repo,
+		Admission:  c.planner,
+		Hooks:      c.hooks,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
 
-	// For fake engine, provide stub paths so validation passes.
-	if opts.Engine == EngineFake && cfg.FirecrackerEngine == nil {
-		cfg.FirecrackerEngine = &model.FirecrackerEngineConfig{
-			RootFS:      "/fake/rootfs.ext4",
-			KernelImage: "/fake/vmlinux",
-		}
+	createStart := time.Now()
+	sb, err := svc.Create(ctx, create.CreateOptions{
+		Config:      cfg,
+		Namespace:   opts.Namespace,
+		IfNotExists: opts.IfNotExists,
+		Webhooks:    toInternalWebhooks(opts.Webhooks),
+		Persistent:  opts.Persistent,
+		Progress:    toInternalProgress(opts.Progress),
+	})
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, opts.Name)
 	}
 
-	// Use the image's firecracker binary if available, otherwise fall back to client config.
-	fcBinary := c.firecrackerBinary
-	if firecrackerBinaryOverride != "" {
-		fcBinary = firecrackerBinaryOverride
+	// An existing sandbox returned by IfNotExists was not created now.
+	if !sb.CreatedAt.Before(createStart) {
+		c.emitEvent(model.EventTypeSandboxCreated, *sb, imageEventAttrs(sb.Config.Image))
 	}
 
-	eng, err := c.newEngineForCreateWithBinary(opts.Engine, fcBinary)
+	result := fromInternalSandbox(*sb)
+	return &result, nil
+}
+
+// CreateAndStartSandbox creates and starts a sandbox as a single operation. When
+// the start fails, or ctx is canceled, after the sandbox was created, the sandbox is
+// removed so interrupted scripts don't leave half created sandboxes. Pass nil
+// startOpts for the start defaults, see [Client.StartSandbox].
+//
+// With [CreateSandboxOpts].IfNotExists an existing sandbox with the same spec is
+// started when it's not running, and it's never removed.
+//
+// Returns the errors of [Client.CreateSandbox] and [Client.StartSandbox].
+func (c *Client) CreateAndStartSandbox(ctx context.Context, opts CreateSandboxOpts, startOpts *StartSandboxOpts) (*Sandbox, error) {
+	opts, cfg, eng, err := c.prepareCreate(ctx, opts)
 	if err != nil {
-		return nil, mapError(fmt.Errorf("could not create engine: %w", err))
+		return nil, err
 	}
 
-	svc, err := create.NewService(create.ServiceConfig{
+	timeouts := c.startTimeouts
+	var startProgress model.ProgressFunc
+	if startOpts != nil {
+		timeouts = timeouts.Merge(toInternalStartTimeouts(startOpts.Timeouts))
+		startProgress = toInternalProgress(startOpts.Progress)
+	}
+
+	svc, err := createstart.NewService(createstart.ServiceConfig{
 		Engine:     eng,
 		Repository: c.repo,
+		Admission:  c.planner,
+		Hooks:      c.hooks,
 		Logger:     c.logger,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("could not create service: %w", err)
 	}
 
-	sb, err := svc.Create(ctx, create.CreateOptions{
-		Config: cfg,
+	res, err := svc.Run(ctx, createstart.Request{
+		Create: create.CreateOptions{
+			Config:      cfg,
+			Namespace:   opts.Namespace,
+			IfNotExists: opts.IfNotExists,
+			Webhooks:    toInternalWebhooks(opts.Webhooks),
+			Persistent:  opts.Persistent,
+			Progress:    toInternalProgress(opts.Progress),
+		},
+		SessionConfig: toInternalSessionConfig(startOpts),
+		Timeouts:      timeouts,
+		StartProgress: startProgress,
 	})
 	if err != nil {
-		return nil, mapError(err)
+		return nil, mapError(fromInternalStartError(err), ResourceKindSandbox, opts.Name)
 	}
 
-	result := fromInternalSandbox(*sb)
-	return &result, nil
+	if res.Created {
+		c.emitEvent(model.EventTypeSandboxCreated, *res.Sandbox, imageEventAttrs(res.Sandbox.Config.Image))
+	}
+	c.emitEvent(model.EventTypeSandboxStarted, *res.Sandbox, nil)
+
+	out := fromInternalSandbox(*res.Sandbox)
+	return &out, nil
 }
 
 // StartSandbox starts a sandbox that is in created or stopped state.
 //
 // Use opts to inject session environment variables that will be available
-// inside the sandbox. Pass nil for defaults (no extra env vars).
+// inside the sandbox. Pass nil to apply the session of the previous start again
+// (none on the first start), see [StartSandboxOpts].ResetSession.
 //
-// Returns [ErrNotFound] if the sandbox does not exist, or [ErrNotValid] if
-// the sandbox is not in a startable state.
+// The returned sandbox has the duration of each start phase in
+// [Sandbox].BootPhases.
+//
+// Returns [ErrNotFound] if the sandbox does not exist, [ErrNotValid] if the
+// sandbox is not in a startable state or there is not enough host capacity
+// (see [Config].Admission), or [ErrConflict] if another operation
+// is changing the sandbox. If a start step fails, the already run steps are
+// rolled back and a [StartError] with the failed step is returned.
 func (c *Client) StartSandbox(ctx context.Context, nameOrID string, opts *StartSandboxOpts) (*Sandbox, error) {
 	sb, err := c.getInternalSandbox(ctx, nameOrID)
 	if err != nil {
-		return nil, mapError(err)
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
 	}
 
 	eng, err := c.newEngine(sb.Config)
 	if err != nil {
-		return nil, mapError(fmt.Errorf("could not create engine: %w", err))
+		return nil, mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, nameOrID)
+	}
+
+	timeouts := c.startTimeouts
+	var progress model.ProgressFunc
+	var resetSession bool
+	if opts != nil {
+		timeouts = timeouts.Merge(toInternalStartTimeouts(opts.Timeouts))
+		progress = toInternalProgress(opts.Progress)
+		resetSession = opts.ResetSession
 	}
 
 	svc, err := start.NewService(start.ServiceConfig{
 		Engine:     eng,
 		Repository: c.repo,
+		Admission:  c.planner,
+		Hooks:      c.hooks,
 		Logger:     c.logger,
 	})
 	if err != nil {
@@ -127,48 +190,118 @@ func (c *Client) StartSandbox(ctx context.Context, nameOrID string, opts *StartS
 	result, err := svc.Run(ctx, start.Request{
 		NameOrID:      nameOrID,
 		SessionConfig: toInternalSessionConfig(opts),
+		ResetSession:  resetSession,
+		Timeouts:      timeouts,
+		Progress:      progress,
 	})
 	if err != nil {
-		return nil, mapError(err)
+		return nil, mapError(fromInternalStartError(err), ResourceKindSandbox, nameOrID)
 	}
+	c.emitEvent(model.EventTypeSandboxStarted, *result, nil)
 
 	out := fromInternalSandbox(*result)
 	return &out, nil
 }
 
-// StopSandbox stops a running sandbox.
+// StopSandbox stops a running sandbox. The pre-stop hooks of opts run in the guest
+// before it's shut down, the sandbox is only killed when it doesn't stop in the
+// grace period.
 //
 // The sandbox must be in [SandboxStatusRunning] state.
 //
-// Returns [ErrNotFound] if the sandbox does not exist, or [ErrNotValid] if
-// the sandbox is not running.
-func (c *Client) StopSandbox(ctx context.Context, nameOrID string) (*Sandbox, error) {
+// Returns [ErrNotFound] if the sandbox does not exist, [ErrNotValid] if the
+// sandbox is not running or the options are not valid, or [ErrConflict] if
+// another operation is changing the sandbox.
+func (c *Client) StopSandbox(ctx context.Context, nameOrID string, opts *StopSandboxOpts) (*Sandbox, error) {
 	sb, err := c.getInternalSandbox(ctx, nameOrID)
 	if err != nil {
-		return nil, mapError(err)
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
 	}
 
 	eng, err := c.newEngine(sb.Config)
 	if err != nil {
-		return nil, mapError(fmt.Errorf("could not create engine: %w", err))
+		return nil, mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, nameOrID)
 	}
 
 	svc, err := stop.NewService(stop.ServiceConfig{
 		Engine:     eng,
 		Repository: c.repo,
+		Hooks:      c.hooks,
 		Logger:     c.logger,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("could not create service: %w", err)
 	}
 
-	result, err := svc.Run(ctx, stop.Request{
-		NameOrID: nameOrID,
+	req := stop.Request{NameOrID: nameOrID}
+	if opts != nil {
+		req.GracePeriod = opts.GracePeriod
+		req.PreStopHooks = opts.PreStopHooks
+	}
+
+	result, err := svc.Run(ctx, req)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+	c.emitEvent(model.EventTypeSandboxStopped, *result, nil)
+
+	out := fromInternalSandbox(*result)
+	return &out, nil
+}
+
+// RestartSandbox stops and starts a running sandbox in place, no other operation
+// can change the sandbox in between. The start applies the environment, egress
+// policy and integrity paths of the previous start, the egress policy names are
+// read again so they get the latest version of the policy. If the start fails
+// the sandbox is left stopped.
+//
+// Returns [ErrNotFound] if the sandbox does not exist, [ErrNotValid] if the
+// sandbox is not running or the options are not valid, [ErrConflict] if another
+// operation is changing the sandbox, or a [StartError] if the start failed
+// after the engine started the sandbox.
+func (c *Client) RestartSandbox(ctx context.Context, nameOrID string, opts *RestartSandboxOpts) (*Sandbox, error) {
+	sb, err := c.getInternalSandbox(ctx, nameOrID)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	eng, err := c.newEngine(sb.Config)
+	if err != nil {
+		return nil, mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, nameOrID)
+	}
+
+	svc, err := restart.NewService(restart.ServiceConfig{
+		Engine:     eng,
+		Repository: c.repo,
+		Admission:  c.planner,
+		Hooks:      c.hooks,
+		Logger:     c.logger,
 	})
 	if err != nil {
-		return nil, mapError(err)
+		return nil, fmt.Errorf("could not create service: %w", err)
 	}
 
+	req := restart.Request{NameOrID: nameOrID, Timeouts: c.startTimeouts}
+	if opts != nil {
+		req.GracePeriod = opts.GracePeriod
+		req.PreStopHooks = opts.PreStopHooks
+		req.Timeouts = req.Timeouts.Merge(toInternalStartTimeouts(opts.Timeouts))
+		req.Progress = toInternalProgress(opts.Progress)
+	}
+
+	result, err := svc.Run(ctx, req)
+	if err != nil {
+		// The sandbox could be stopped before the start failed.
+		if sb.Status == model.SandboxStatusRunning {
+			if stopped, getErr := c.getInternalSandbox(ctx, sb.ID); getErr == nil && stopped.Status == model.SandboxStatusStopped {
+				c.emitEvent(model.EventTypeSandboxStopped, *stopped, nil)
+			}
+		}
+		return nil, mapError(fromInternalStartError(err), ResourceKindSandbox, nameOrID)
+	}
+	c.emitEvent(model.EventTypeSandboxStopped, *result, nil)
+	c.emitEvent(model.EventTypeSandboxStarted, *result, nil)
+
 	out := fromInternalSandbox(*result)
 	return &out, nil
 }
@@ -178,21 +311,23 @@ func (c *Client) StopSandbox(ctx context.Context, nameOrID string) (*Sandbox, er
 // If force is false and the sandbox is running, it returns [ErrNotValid].
 // If force is true, a running sandbox is stopped first (best-effort) then removed.
 //
-// Returns [ErrNotFound] if the sandbox does not exist.
+// Returns [ErrNotFound] if the sandbox does not exist, or [ErrConflict] if
+// another operation is changing the sandbox.
 func (c *Client) RemoveSandbox(ctx context.Context, nameOrID string, force bool) (*Sandbox, error) {
 	sb, err := c.getInternalSandbox(ctx, nameOrID)
 	if err != nil {
-		return nil, mapError(err)
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
 	}
 
 	eng, err := c.newEngine(sb.Config)
 	if err != nil {
-		return nil, mapError(fmt.Errorf("could not create engine: %w", err))
+		return nil, mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, nameOrID)
 	}
 
 	svc, err := remove.NewService(remove.ServiceConfig{
 		Engine:     eng,
 		Repository: c.repo,
+		Hooks:      c.hooks,
 		Logger:     c.logger,
 	})
 	if err != nil {
@@ -204,14 +339,122 @@ func (c *Client) RemoveSandbox(ctx context.Context, nameOrID string, force bool)
 		Force:    force,
 	})
 	if err != nil {
-		return nil, mapError(err)
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+	c.emitEvent(model.EventTypeSandboxRemoved, *result, nil)
+
+	out := fromInternalSandbox(*result)
+	return &out, nil
+}
+
+// CloneSandbox creates a new sandbox named newName with a copy of the source
+// sandbox rootfs and configuration, without creating a snapshot image.
+//
+// The source sandbox must be in [SandboxStatusStopped] state. The clone is
+// created stopped and is independent of the source once created.
+//
+// Returns [ErrNotFound] if the source sandbox does not exist, [ErrAlreadyExists]
+// if a sandbox named newName exists, or [ErrNotValid] if the source is not
+// stopped or the resource overrides are invalid.
+func (c *Client) CloneSandbox(ctx context.Context, srcNameOrID, newName string, opts *CloneSandboxOpts) (*Sandbox, error) {
+	sb, err := c.getInternalSandbox(ctx, srcNameOrID)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, srcNameOrID)
+	}
+
+	eng, err := c.newEngine(sb.Config)
+	if err != nil {
+		return nil, mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, srcNameOrID)
+	}
+
+	svc, err := clone.NewService(clone.ServiceConfig{
+		Engine:     eng,
+		Repository: c.repo,
+		Logger:     c.logger,
+		VMsDir:     c.vmsDir,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	req := clone.Request{
+		NameOrID: srcNameOrID,
+		Name:     newName,
+	}
+	if opts != nil {
+		req.Resources = model.Resources{
+			VCPUs:    opts.Resources.VCPUs,
+			MemoryMB: opts.Resources.MemoryMB,
+			DiskGB:   opts.Resources.DiskGB,
+		}
+	}
+
+	result, err := svc.Run(ctx, req)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, srcNameOrID)
+	}
+	c.emitEvent(model.EventTypeSandboxCreated, *result, map[string]string{"source": sb.Name})
+
+	out := fromInternalSandbox(*result)
+	return &out, nil
+}
+
+// RebaseSandbox moves a stopped sandbox to the newImage base image (e.g. a patched
+// golden image). The sandbox rootfs is replaced with a copy of the image rootfs and
+// the [RebaseSandboxOpts].PreservePaths are copied from the current rootfs into it,
+// the rest of the changes made in the sandbox are lost. The rest of the sandbox
+// configuration is kept and the user data is not executed again.
+//
+// Returns [ErrNotFound] if the sandbox or the image don't exist, or [ErrNotValid]
+// if the sandbox is not stopped, the image can't run on the host or a preserved
+// path is not absolute.
+func (c *Client) RebaseSandbox(ctx context.Context, nameOrID, newImage string, opts *RebaseSandboxOpts) (*Sandbox, error) {
+	sb, err := c.getInternalSandbox(ctx, nameOrID)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	mgr, err := c.usableImageManager(ctx, newImage)
+	if err != nil {
+		return nil, err
+	}
+
+	eng, err := c.newEngine(sb.Config)
+	if err != nil {
+		return nil, mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, nameOrID)
+	}
+
+	svc, err := rebase.NewService(rebase.ServiceConfig{
+		Engine:     eng,
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	req := rebase.Request{
+		NameOrID:    sb.ID,
+		Image:       newImage,
+		RootFS:      mgr.RootFSPath(newImage),
+		KernelImage: mgr.KernelPath(newImage),
+	}
+	if opts != nil {
+		req.PreservePaths = opts.PreservePaths
+		req.Progress = toInternalProgress(opts.Progress)
+	}
+
+	result, err := svc.Run(ctx, req)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
 	}
 
 	out := fromInternalSandbox(*result)
 	return &out, nil
 }
 
-// ListSandboxes returns all sandboxes, optionally filtered by status.
+// ListSandboxes returns all sandboxes of the client namespace (see
+// [Config].Namespace), optionally filtered by status.
 //
 // Pass nil opts to list all sandboxes regardless of status. Use
 // [ListSandboxesOpts].Status to filter by a specific [SandboxStatus].
@@ -226,9 +469,123 @@ func (c *Client) ListSandboxes(ctx context.Context, opts *ListSandboxesOpts) ([]
 
 	result, err := svc.Run(ctx, list.Request{
 		StatusFilter: toInternalStatusFilter(opts),
+		Namespace:    toInternalNamespaceFilter(opts),
 	})
 	if err != nil {
-		return nil, mapError(err)
+		return nil, mapError(err, "", "")
+	}
+
+	return fromInternalSandboxList(result), nil
+}
+
+const (
+	// sandboxWatchPollInterval is the interval the watched sandboxes are listed at
+	// when the storage doesn't notify its changes.
+	sandboxWatchPollInterval = time.Second
+	// sandboxWatchResyncInterval is the interval the watched sandboxes are listed at
+	// without changes, for the changes missed by the storage notifications.
+	sandboxWatchResyncInterval = 10 * time.Second
+)
+
+// WatchSandboxes sends the sandboxes of the client namespace on the returned
+// channel (see [Client.ListSandboxes]): the current ones and then the new list
+// after each change, including the changes made by other clients and processes
+// using the same database. A slow receiver only gets the latest list. The channel
+// is closed when ctx is done.
+//
+//	updates, _ := client.WatchSandboxes(ctx, nil)
+//	for sandboxes := range updates {
+//	    fmt.Println(len(sandboxes), "sandboxes")
+//	}
+//
+// Returns the errors of the first list, the later ones are logged and retried.
+func (c *Client) WatchSandboxes(ctx context.Context, opts *ListSandboxesOpts) (<-chan []Sandbox, error) {
+	first, err := c.ListSandboxes(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes <-chan struct{}
+	interval := sandboxWatchPollInterval
+	if w, ok := c.repo.(storage.ChangeWatcher); ok {
+		changes, err = w.WatchChanges(ctx)
+		if err != nil {
+			return nil, mapError(err, "", "")
+		}
+		interval = sandboxWatchResyncInterval
+	}
+
+	updates := make(chan []Sandbox, 1)
+	updates <- first
+	go func() {
+		defer close(updates)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		last := first
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-changes:
+				if !ok {
+					return
+				}
+			case <-ticker.C:
+			}
+
+			sandboxes, err := c.ListSandboxes(ctx, opts)
+			if err != nil {
+				if ctx.Err() == nil {
+					c.logger.Warningf("Could not list the watched sandboxes: %v", err)
+				}
+				continue
+			}
+			if reflect.DeepEqual(sandboxes, last) {
+				continue
+			}
+			last = sandboxes
+
+			// The pending list is replaced, the receiver only needs the latest one.
+			select {
+			case <-updates:
+			default:
+			}
+			updates <- sandboxes
+		}
+	}()
+
+	return updates, nil
+}
+
+// SelectSandboxes returns the sandboxes whose name matches the pattern, a glob
+// ("ci-*", "test-?", "run-[0-9]*") matching the whole name or a regular expression
+// prefixed with "re:" ("re:^ci-[0-9]+$"). Regular expressions use the RE2 syntax
+// and are evaluated in linear time.
+//
+// No matching sandbox is not an error, it returns an empty list. The selection is
+// not locked, use it to review the sandboxes before acting on each of them:
+//
+//	sandboxes, _ := client.SelectSandboxes(ctx, "ci-*")
+//	for _, sb := range sandboxes {
+//	    _, _ = client.RemoveSandbox(ctx, sb.ID, true)
+//	}
+//
+// Returns [ErrNotValid] if the pattern is not valid.
+func (c *Client) SelectSandboxes(ctx context.Context, pattern string) ([]Sandbox, error) {
+	svc, err := list.NewService(list.ServiceConfig{
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	result, err := svc.Run(ctx, list.Request{
+		NamePattern: pattern,
+	})
+	if err != nil {
+		return nil, mapError(err, "", "")
 	}
 
 	return fromInternalSandboxList(result), nil
@@ -239,17 +596,285 @@ func (c *Client) ListSandboxes(ctx context.Context, opts *ListSandboxesOpts) ([]
 // The nameOrID parameter is first matched against sandbox names. If no match is
 // found and the value looks like a ULID, it is tried as an ID.
 //
+// Running sandboxes include the health of their egress proxies.
+//
 // Returns [ErrNotFound] if the sandbox does not exist.
 func (c *Client) GetSandbox(ctx context.Context, nameOrID string) (*Sandbox, error) {
-	sb, err := c.getInternalSandbox(ctx, nameOrID)
+	return c.getSandbox(ctx, status.Request{NameOrID: nameOrID})
+}
+
+// GetSandboxByID retrieves a sandbox by its ID only, the names are not resolved so a
+// sandbox named like another sandbox ID can't be returned. The ID is assigned on
+// creation and never changes, it's the identity to store in external state (e.g.
+// infrastructure as code providers).
+//
+// The operations are committed to the database before they return, so the sandbox
+// read reflects all the completed writes of any client using the same database.
+//
+// Returns [ErrNotFound] if the sandbox does not exist (or is in another namespace).
+func (c *Client) GetSandboxByID(ctx context.Context, id string) (*Sandbox, error) {
+	return c.getSandbox(ctx, status.Request{NameOrID: id, ByID: true})
+}
+
+// DiffSandboxSpec returns the spec fields of the sandbox with the ID that differ
+// from the create options, empty when the sandbox matches them. The spec can't be
+// changed, the sandbox must be replaced (removed and created) to apply a diff. The
+// engine assigned fields (e.g. network addresses) are ignored, the Webhooks are
+// compared.
+//
+// Returns [ErrNotFound] if the sandbox or the FromImage image don't exist.
+func (c *Client) DiffSandboxSpec(ctx context.Context, id string, opts CreateSandboxOpts) ([]SpecField, error) {
+	sb, err := c.getInternalSandboxByID(ctx, id)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, id)
+	}
+
+	cfg, _, err := c.createConfig(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := []SpecField{}
+	for _, f := range model.SandboxSpecDiff(*sb, cfg, toInternalWebhooks(opts.Webhooks)) {
+		diff = append(diff, SpecField(f))
+	}
+	return diff, nil
+}
+
+func (c *Client) getSandbox(ctx context.Context, req status.Request) (*Sandbox, error) {
+	svc, err := status.NewService(status.ServiceConfig{
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	sb, err := svc.Run(ctx, req)
 	if err != nil {
-		return nil, mapError(err)
+		return nil, mapError(err, ResourceKindSandbox, req.NameOrID)
+	}
+
+	if sb.Status == model.SandboxStatusRunning {
+		eng, err := c.newEngine(sb.Config)
+		if err != nil {
+			return nil, mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, req.NameOrID)
+		}
+
+		svc, err := status.NewService(status.ServiceConfig{
+			Repository: c.repo,
+			Engine:     eng,
+			Logger:     c.logger,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not create service: %w", err)
+		}
+
+		sb, err = svc.Run(ctx, status.Request{NameOrID: sb.ID, ByID: true})
+		if err != nil {
+			return nil, mapError(err, ResourceKindSandbox, req.NameOrID)
+		}
 	}
 
 	out := fromInternalSandbox(*sb)
 	return &out, nil
 }
 
+// WaitFor blocks until the sandbox reaches the condition and returns it.
+//
+// The sandbox state is polled, so the condition can be reached by operations made
+// from other clients or processes (e.g. the CLI). A zero timeout waits until the
+// context is cancelled.
+//
+// Returns [ErrNotFound] if the sandbox does not exist, [ErrNotValid] if the
+// condition is unknown or can't be reached anymore (e.g. the sandbox failed), or
+// an error wrapping [context.DeadlineExceeded] when the timeout is reached.
+func (c *Client) WaitFor(ctx context.Context, nameOrID string, condition WaitCondition, timeout time.Duration) (*Sandbox, error) {
+	sb, err := c.getInternalSandbox(ctx, nameOrID)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	eng, err := c.newEngine(sb.Config)
+	if err != nil {
+		return nil, mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, nameOrID)
+	}
+
+	svc, err := wait.NewService(wait.ServiceConfig{
+		Engine:     eng,
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	result, err := svc.Run(ctx, wait.Request{
+		NameOrID:  nameOrID,
+		Condition: model.WaitCondition(condition),
+		Timeout:   timeout,
+	})
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	out := fromInternalSandbox(*result)
+	return &out, nil
+}
+
+// createConfig returns the internal sandbox config of the create options, with the
+// image paths resolved, and the firecracker binary to create it.
+// prepareCreate returns the create options with the namespace defaulted, the
+// sandbox config and the engine of a create.
+func (c *Client) prepareCreate(ctx context.Context, opts CreateSandboxOpts) (CreateSandboxOpts, model.SandboxConfig, sandbox.Engine, error) {
+	if opts.Namespace == "" && c.namespace == AllNamespaces {
+		return opts, model.SandboxConfig{}, nil, mapError(fmt.Errorf("the namespace is required with a client of all the namespaces: %w", ErrNotValid), ResourceKindSandbox, opts.Name)
+	}
+	if opts.Namespace != "" && c.namespace != AllNamespaces && opts.Namespace != c.namespace {
+		return opts, model.SandboxConfig{}, nil, mapError(fmt.Errorf("namespace %s is not the client namespace %s: %w", opts.Namespace, c.namespace, ErrNotValid), ResourceKindSandbox, opts.Name)
+	}
+	if opts.Namespace == "" {
+		opts.Namespace = c.namespace
+	}
+
+	cfg, fcBinary, err := c.createConfig(ctx, opts)
+	if err != nil {
+		return opts, model.SandboxConfig{}, nil, err
+	}
+
+	eng, err := c.newEngineForCreateWithBinary(opts.Engine, fcBinary)
+	if err != nil {
+		return opts, model.SandboxConfig{}, nil, mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, opts.Name)
+	}
+
+	return opts, cfg, eng, nil
+}
+
+func (c *Client) createConfig(ctx context.Context, opts CreateSandboxOpts) (model.SandboxConfig, string, error) {
+	// Resolve image paths when FromImage is set.
+	var firecrackerBinaryOverride string
+	if opts.FromImage != "" {
+		if opts.Firecracker != nil && (opts.Firecracker.RootFS != "" || opts.Firecracker.KernelImage != "") {
+			return model.SandboxConfig{}, "", mapError(fmt.Errorf("FromImage and Firecracker paths cannot be used together: %w", ErrNotValid), ResourceKindSandbox, opts.Name)
+		}
+
+		mgr, err := c.usableImageManager(ctx, opts.FromImage)
+		if err != nil {
+			return model.SandboxConfig{}, "", err
+		}
+
+		var fcCfg FirecrackerConfig
+		if opts.Firecracker != nil {
+			fcCfg = *opts.Firecracker
+		}
+		fcCfg.KernelImage = mgr.KernelPath(opts.FromImage)
+		fcCfg.RootFS = mgr.RootFSPath(opts.FromImage)
+		opts.Firecracker = &fcCfg
+		firecrackerBinaryOverride = mgr.FirecrackerPath(opts.FromImage)
+	}
+
+	cfg := toInternalSandboxConfig(opts)
+
+	// For fake engine, provide stub paths so validation passes.
+	if opts.Engine == EngineFake && cfg.FirecrackerEngine == nil {
+		cfg.FirecrackerEngine = &model.FirecrackerEngineConfig{
+			RootFS:      "/fake/rootfs.ext4",
+			KernelImage: "/fake/vmlinux",
+		}
+	}
+
+	if opts.Profile != "" {
+		profile, err := c.kernelProfile(ctx, opts.FromImage, opts.Profile)
+		if err != nil {
+			return model.SandboxConfig{}, "", mapError(err, ResourceKindSandbox, opts.Name)
+		}
+		if cfg.FirecrackerEngine != nil {
+			profile.Apply(cfg.FirecrackerEngine)
+		}
+	}
+
+	// Use the image's firecracker binary if available, otherwise fall back to client config.
+	fcBinary := c.firecrackerBinary
+	if firecrackerBinaryOverride != "" {
+		fcBinary = firecrackerBinaryOverride
+	}
+
+	return cfg, fcBinary, nil
+}
+
+// kernelProfile returns the named kernel profile of an installed image, or the
+// built-in one without image.
+func (c *Client) kernelProfile(ctx context.Context, imageName, name string) (model.KernelProfile, error) {
+	profiles := model.DefaultKernelProfiles
+	if imageName != "" {
+		mgr, err := c.newLocalImageManager()
+		if err != nil {
+			return model.KernelProfile{}, fmt.Errorf("could not create image manager: %w", err)
+		}
+		manifest, err := mgr.GetManifest(ctx, imageName)
+		if err != nil {
+			return model.KernelProfile{}, fmt.Errorf("could not get image %s manifest: %w", imageName, err)
+		}
+		profiles = manifest.KernelProfiles()
+	}
+
+	profile, err := model.FindKernelProfile(profiles, name)
+	if err != nil {
+		return model.KernelProfile{}, err
+	}
+	if err := profile.Validate(); err != nil {
+		return model.KernelProfile{}, err
+	}
+	return profile, nil
+}
+
+// usableImageManager returns the local image manager after checking the image is
+// installed (fetching it from the snapshot store when configured) and can run on
+// the host.
+func (c *Client) usableImageManager(ctx context.Context, name string) (image.ImageManager, error) {
+	mgr, err := c.newLocalImageManager()
+	if err != nil {
+		return nil, fmt.Errorf("could not create image manager: %w", err)
+	}
+
+	exists, err := mgr.Exists(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("could not check image %s: %w", name, err)
+	}
+	if !exists && c.snapshotStore != nil {
+		c.logger.Infof("Image %s is not installed, fetching it from the snapshot store", name)
+		err := c.FetchSnapshot(ctx, name, nil)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			return nil, mapError(fmt.Errorf("image %s is not installed nor in the snapshot store: %w", name, ErrNotFound), ResourceKindImage, name)
+		case err != nil:
+			return nil, err
+		}
+		exists = true
+	}
+	if !exists {
+		return nil, mapError(fmt.Errorf("image %s is not installed: %w", name, ErrNotFound), ResourceKindImage, name)
+	}
+	if err := image.CheckHostArch(ctx, mgr, name); err != nil {
+		return nil, mapError(fmt.Errorf("image %s can't be used on this host: %w", name, err), ResourceKindImage, name)
+	}
+
+	return mgr, nil
+}
+
+// getInternalSandboxByID resolves a sandbox from storage by ID.
+func (c *Client) getInternalSandboxByID(ctx context.Context, id string) (*model.Sandbox, error) {
+	svc, err := status.NewService(status.ServiceConfig{
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	return svc.Run(ctx, status.Request{NameOrID: id, ByID: true})
+}
+
 // getInternalSandbox resolves a sandbox from storage by name or ID.
 func (c *Client) getInternalSandbox(ctx context.Context, nameOrID string) (*model.Sandbox, error) {
 	svc, err := status.NewService(status.ServiceConfig{
@@ -264,3 +889,11 @@ func (c *Client) getInternalSandbox(ctx context.Context, nameOrID string) (*mode
 		NameOrID: nameOrID,
 	})
 }
+
+// imageEventAttrs returns the event attributes of a sandbox image, none without image.
+func imageEventAttrs(image string) map[string]string {
+	if image == "" {
+		return nil
+	}
+	return map[string]string{"image": image}
+}
@@ -5,20 +5,32 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-
+	"sync"
+	"time"
+
+	"github.com/slok/sbx/internal/capacity"
+	"github.com/slok/sbx/internal/conventions"
+	"github.com/slok/sbx/internal/events"
+	"github.com/slok/sbx/internal/hooks"
+	"github.com/slok/sbx/internal/hostlock"
 	"github.com/slok/sbx/internal/image"
 	"github.com/slok/sbx/internal/log"
 	"github.com/slok/sbx/internal/model"
+	"github.com/slok/sbx/internal/reservation"
 	"github.com/slok/sbx/internal/sandbox"
 	"github.com/slok/sbx/internal/sandbox/fake"
 	"github.com/slok/sbx/internal/sandbox/firecracker"
+	"github.com/slok/sbx/internal/ssh"
 	"github.com/slok/sbx/internal/storage"
 	"github.com/slok/sbx/internal/storage/sqlite"
+	"github.com/slok/sbx/internal/systemd"
 )
 
 const (
 	defaultDataDir = ".sbx"
 	defaultDBFile  = "sbx.db"
+	// eventsCloseTimeout is the maximum time Close waits for the pending events.
+	eventsCloseTimeout = 10 * time.Second
 )
 
 // Config configures the SDK client.
@@ -30,10 +42,24 @@ type Config struct {
 	// Default: ~/.sbx/sbx.db.
 	DBPath string
 
+	// Namespace scopes the client to the sandboxes of a namespace. Namespaces isolate
+	// the sandboxes of different teams or projects sharing a host, the sandbox names
+	// are unique in their namespace. Use up to 63 [a-z0-9-] characters, or
+	// [AllNamespaces] for the administration tools.
+	// Default: [DefaultNamespace].
+	Namespace string
+
 	// DataDir is the base directory for sbx data (VMs, snapshots, SSH keys).
 	// Default: ~/.sbx.
 	DataDir string
 
+	// VMsDir is the directory of the sandbox VMs: their rootfs copies, SSH keys and
+	// runtime files. Place it on a different (faster or larger) filesystem than the
+	// database to keep the heavy rootfs I/O off it, see [Client.MigrateDataLayout]
+	// to move the existing sandboxes.
+	// Default: DataDir/vms.
+	VMsDir string
+
 	// Logger receives structured log output from the SDK.
 	// Default: noop (silent). See the log sub-package for the interface.
 	Logger log.Logger
@@ -54,9 +80,88 @@ type Config struct {
 	// Default: ~/.sbx/images.
 	ImagesDir string
 
+	// SnapshotsDir is the directory of the snapshot images created by
+	// [Client.CreateImageFromSandbox], they are resolved by name like the ones in
+	// ImagesDir.
+	// Default: ImagesDir.
+	SnapshotsDir string
+
+	// SnapshotStore is the object storage sharing the snapshot images between hosts:
+	// [Client.PushSnapshot] uploads them and [Client.CreateSandbox] fetches the
+	// [CreateSandboxOpts].FromImage images that are not installed from it.
+	// Default: nil (no snapshot store).
+	SnapshotStore *SnapshotStoreConfig
+
 	// ImageRepo is the GitHub repository for image releases.
 	// Default: "slok/sbx-images".
 	ImageRepo string
+
+	// Admission checks the host capacity on [Client.CreateSandbox] (disk) and
+	// [Client.StartSandbox] (VCPUs and memory), see [Client.HostCapacity].
+	// Default: nil (no admission check).
+	Admission *AdmissionConfig
+
+	// StartTimeouts are the default timeouts of [Client.StartSandbox], raise them on
+	// slow hosts (e.g. CI machines without nested virtualization acceleration). They
+	// can be overridden per start with [StartSandboxOpts].Timeouts.
+	// Default: zero values (engine defaults).
+	StartTimeouts StartTimeouts
+
+	// Retry are the retry policies of the transient engine failures: the guest SSH
+	// dial while it boots, the Firecracker API socket while the process starts and
+	// the host nftables changes rejected as busy. An operation giving up returns a
+	// [RetryError] with the attempts made.
+	// Default: zero values (engine defaults).
+	Retry RetryPolicies
+
+	// DNSUpstreams are the default resolvers of the egress DNS proxies, tried in
+	// order, used by the sandboxes whose [EgressPolicy] doesn't set its own. The
+	// accepted formats are described in [EgressPolicy].DNSUpstreams.
+	// Default: nil (8.8.8.8:53).
+	DNSUpstreams []string
+
+	// EgressInterface is the host network interface the NAT traffic of the
+	// sandboxes goes out through (e.g. a data network NIC or a VPN tunnel like wg0),
+	// used by the sandboxes without their own [FirecrackerConfig].EgressInterface.
+	// The traffic routed through other interfaces is dropped, it never falls back to
+	// the default route. Linux only, applied on the sandbox starts.
+	// Default: "" (the default route).
+	EgressInterface string
+
+	// EventSinks receive the structured lifecycle and security events (sandbox
+	// created, started, stopped and removed, snapshot created, egress denied and
+	// proxy crashed) so SIEM pipelines can ingest the sbx activity and users get
+	// notified. The events are sent in the background and are best effort, a sink
+	// failure is logged and doesn't fail the operation. [Client.Close] sends the
+	// pending ones.
+	//
+	// The lifecycle events are emitted by the client operations, the security ones
+	// by the egress proxies of the sandboxes started by the client.
+	// Default: nil (no events).
+	EventSinks []EventSink
+
+	// Hooks run on the host on the lifecycle events of the sandboxes created,
+	// started, stopped and removed by the client, after the executables of HooksDir.
+	// Default: nil (no hooks).
+	Hooks []Hook
+
+	// HooksDir is the directory of the executable host hooks, the ones of its
+	// pre-create, post-start, pre-stop and post-remove subdirectories run in name
+	// order on their event, like [Config].Hooks. It's shared with the sbx CLI.
+	// Default: DataDir/hooks.
+	HooksDir string
+
+	// SystemdUnitDir is the directory of the systemd units installed by
+	// [Client.EnableBootPersistence].
+	// Default: /etc/systemd/system.
+	SystemdUnitDir string
+
+	// DBCheckpointInterval checkpoints the database write-ahead log periodically
+	// while the client is open, set it on long-running clients (servers, daemons)
+	// so the database -wal file doesn't keep growing. See [Client.CompactDB] to
+	// also reclaim the space of the deleted rows.
+	// Default: 0 (no periodic checkpoint).
+	DBCheckpointInterval time.Duration
 }
 
 func (c *Config) defaults() error {
@@ -72,12 +177,92 @@ func (c *Config) defaults() error {
 		c.DBPath = filepath.Join(c.DataDir, defaultDBFile)
 	}
 
+	ns, err := model.NamespaceScope(c.Namespace)
+	if err != nil {
+		return fmt.Errorf("invalid namespace %q: %w", c.Namespace, ErrNotValid)
+	}
+	c.Namespace = ns
+
 	if c.Logger == nil {
 		c.Logger = log.Noop
 	}
 
+	if c.VMsDir == "" {
+		c.VMsDir = conventions.VMsPath(c.DataDir)
+	}
+
 	if c.ImagesDir == "" {
-		c.ImagesDir = filepath.Join(c.DataDir, "images")
+		c.ImagesDir = conventions.ImagesPath(c.DataDir)
+	}
+
+	if c.SnapshotsDir == "" {
+		c.SnapshotsDir = c.ImagesDir
+	}
+
+	if c.SnapshotStore != nil {
+		if _, _, _, err := model.ParseSnapshotStoreURL(c.SnapshotStore.URL); err != nil {
+			return fmt.Errorf("invalid snapshot store: %w", err)
+		}
+		if err := model.SnapshotStoreEncryption(c.SnapshotStore.Encryption).Validate(); err != nil {
+			return fmt.Errorf("invalid snapshot store: %w", err)
+		}
+	}
+
+	if c.Admission != nil {
+		switch c.Admission.Mode {
+		case "", AdmissionModeOff, AdmissionModeWarn, AdmissionModeEnforce:
+		default:
+			return fmt.Errorf("unknown admission mode %q: %w", c.Admission.Mode, ErrNotValid)
+		}
+		o := c.Admission.Overcommit
+		if o.VCPUs < 0 || o.Memory < 0 || o.Disk < 0 {
+			return fmt.Errorf("overcommit ratios can't be negative: %w", ErrNotValid)
+		}
+	}
+
+	for i, h := range toInternalHooks(c.Hooks) {
+		if err := h.Validate(); err != nil {
+			return fmt.Errorf("invalid hook %d: %w", i, ErrNotValid)
+		}
+	}
+
+	if c.HooksDir == "" {
+		c.HooksDir = conventions.HooksPath(c.DataDir)
+	}
+
+	if c.SystemdUnitDir == "" {
+		c.SystemdUnitDir = systemd.DefaultUnitDir
+	}
+
+	if c.DBCheckpointInterval < 0 {
+		return fmt.Errorf("db checkpoint interval can't be negative: %w", ErrNotValid)
+	}
+
+	t := c.StartTimeouts
+	if t.Boot < 0 || t.SSHDial < 0 || t.SSHRetries < 0 || t.FilesystemExpand < 0 {
+		return fmt.Errorf("start timeouts can't be negative: %w", ErrNotValid)
+	}
+
+	if err := toInternalRetryPolicies(c.Retry).Validate(); err != nil {
+		return fmt.Errorf("invalid retry policies: %w", ErrNotValid)
+	}
+
+	for _, u := range c.DNSUpstreams {
+		if err := model.ValidateDNSUpstream(u); err != nil {
+			return fmt.Errorf("invalid dns upstream %q, must be an IP, tls:// or https:// address: %w", u, ErrNotValid)
+		}
+	}
+
+	if c.EgressInterface != "" {
+		if err := model.ValidateHostInterface(c.EgressInterface); err != nil {
+			return fmt.Errorf("invalid egress interface %q: %w", c.EgressInterface, ErrNotValid)
+		}
+	}
+
+	for _, sink := range toInternalEventSinks(c.EventSinks) {
+		if _, err := events.NewSink(sink); err != nil {
+			return fmt.Errorf("invalid %q event sink: %w", sink.Type, ErrNotValid)
+		}
 	}
 
 	return nil
@@ -87,15 +272,42 @@ func (c *Config) defaults() error {
 //
 // Create a Client with [New] and release its resources with [Client.Close].
 // A Client is safe for concurrent use.
+//
+// The client keeps one SSH connection per sandbox (with keepalive and automatic
+// redial) that is reused by [Client.Exec], [Client.CopyTo], [Client.CopyFrom] and
+// [Client.Forward], reuse the same client for chatty workloads.
 type Client struct {
 	repo              storage.Repository
+	namespace         string
 	logger            log.Logger
 	dataDir           string
+	dbPath            string
+	vmsDir            string
 	engineType        EngineType
 	firecrackerBinary string
 	imagesDir         string
+	snapshotsDir      string
+	snapshotStore     *SnapshotStoreConfig
 	imageRepo         string
+	planner           *capacity.Planner
+	startTimeouts     model.StartTimeouts
+	retries           model.RetryPolicies
+	dnsUpstreams      []string
+	egressInterface   string
+	eventSinks        []model.EventSinkConfig
+	events            *events.Emitter
+	hooks             hooks.Runner
+	sshPool           *ssh.Pool
+	hostLocks         *hostlock.Manager
+	reservations      *reservation.Store
+	systemdUnitDir    string
 	closeFn           func() error
+
+	enginesMu sync.Mutex
+	engines   map[EngineType]sandbox.Engine
+
+	ingressMu sync.Mutex
+	ingresses map[string]*ingressRef
 }
 
 // New creates a new SDK client backed by a SQLite database.
@@ -114,26 +326,92 @@ func New(ctx context.Context, cfg Config) (*Client, error) {
 	}
 
 	repo, err := sqlite.NewRepository(ctx, sqlite.RepositoryConfig{
-		DBPath: cfg.DBPath,
-		Logger: cfg.Logger,
+		DBPath:    cfg.DBPath,
+		Namespace: cfg.Namespace,
+		Logger:    cfg.Logger,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("could not create repository: %w", err)
 	}
 
+	planner, err := capacity.NewPlanner(toInternalPlannerConfig(cfg.Admission, repo, cfg.VMsDir, cfg.Logger))
+	if err != nil {
+		repo.Close()
+		return nil, fmt.Errorf("could not create capacity planner: %w", err)
+	}
+
+	eventSinks := toInternalEventSinks(cfg.EventSinks)
+	emitter, err := events.NewEmitter(eventSinks, cfg.Logger)
+	if err != nil {
+		repo.Close()
+		return nil, fmt.Errorf("could not create event emitter: %w", err)
+	}
+
+	hooksRunner, err := hooks.NewRunner(hooks.RunnerConfig{
+		Dir:    cfg.HooksDir,
+		Hooks:  toInternalHooks(cfg.Hooks),
+		Logger: cfg.Logger,
+	})
+	if err != nil {
+		repo.Close()
+		return nil, fmt.Errorf("could not create hooks runner: %w", err)
+	}
+
+	sshPool, err := ssh.NewPool(ssh.PoolConfig{Logger: cfg.Logger})
+	if err != nil {
+		repo.Close()
+		return nil, fmt.Errorf("could not create SSH pool: %w", err)
+	}
+
+	stopCheckpoints := make(chan struct{})
+	closeCheckpoints := sync.OnceFunc(func() { close(stopCheckpoints) })
+	if m, ok := storage.Repository(repo).(storage.Maintainer); ok && cfg.DBCheckpointInterval > 0 {
+		go checkpointDB(m, cfg.DBCheckpointInterval, stopCheckpoints, cfg.Logger)
+	}
+
 	return &Client{
 		repo:              repo,
+		namespace:         cfg.Namespace,
 		logger:            cfg.Logger,
 		dataDir:           cfg.DataDir,
+		dbPath:            cfg.DBPath,
+		vmsDir:            cfg.VMsDir,
 		engineType:        cfg.Engine,
 		firecrackerBinary: cfg.FirecrackerBinary,
 		imagesDir:         cfg.ImagesDir,
+		snapshotsDir:      cfg.SnapshotsDir,
+		snapshotStore:     cfg.SnapshotStore,
 		imageRepo:         cfg.ImageRepo,
-		closeFn:           repo.Close,
+		planner:           planner,
+		startTimeouts:     toInternalStartTimeouts(cfg.StartTimeouts),
+		retries:           toInternalRetryPolicies(cfg.Retry),
+		dnsUpstreams:      cfg.DNSUpstreams,
+		egressInterface:   cfg.EgressInterface,
+		eventSinks:        eventSinks,
+		events:            emitter,
+		hooks:             hooksRunner,
+		sshPool:           sshPool,
+		hostLocks:         hostlock.NewManager(conventions.HostLocksPath(cfg.DataDir), cfg.DBPath),
+		reservations:      reservation.NewStore(conventions.ReservationsPath(cfg.DataDir)),
+		systemdUnitDir:    cfg.SystemdUnitDir,
+		engines:           map[EngineType]sandbox.Engine{},
+		ingresses:         map[string]*ingressRef{},
+		closeFn: func() error {
+			closeCheckpoints()
+			ctx, cancel := context.WithTimeout(context.Background(), eventsCloseTimeout)
+			defer cancel()
+			if err := emitter.Close(ctx); err != nil {
+				cfg.Logger.Warningf("Could not send events: %v", err)
+			}
+			_ = sshPool.Close()
+			return repo.Close()
+		},
 	}, nil
 }
 
-// Close releases resources held by the client, including the database connection.
+// Close releases resources held by the client, including the database and the
+// SSH connections. It waits up to 10 seconds for the pending events to be sent
+// (see [Config].EventSinks).
 // After Close returns, the client must not be used.
 func (c *Client) Close() error {
 	if c.closeFn != nil {
@@ -142,7 +420,34 @@ func (c *Client) Close() error {
 	return nil
 }
 
-// newEngine creates the engine for sandbox operations.
+// emitEvent emits an event of a sandbox to the event sinks and the sandbox webhooks.
+// The events follow the sandbox changes, so its reservation manifest is updated too.
+func (c *Client) emitEvent(t model.EventType, sb model.Sandbox, attrs map[string]string) {
+	c.events.Emit(model.NewSandboxEvent(t, sb, attrs), model.WebhookSinkConfigs(sb.Webhooks)...)
+	if t == model.EventTypeSandboxRemoved {
+		c.removeReservation(sb.ID)
+	} else {
+		c.updateReservation(sb)
+	}
+}
+
+// updateReservation writes the reservation manifest of a sandbox. The manifests are
+// informative for the external schedulers, a failure doesn't fail the operation.
+func (c *Client) updateReservation(sb model.Sandbox) {
+	if err := c.reservations.Write(model.NewReservation(sb, time.Now().UTC())); err != nil {
+		c.logger.Warningf("Could not write sandbox %s reservation: %v", sb.ID, err)
+	}
+}
+
+// removeReservation removes the reservation manifest of a removed sandbox.
+func (c *Client) removeReservation(id string) {
+	if err := c.reservations.Remove(id); err != nil {
+		c.logger.Warningf("Could not remove sandbox %s reservation: %v", id, err)
+	}
+}
+
+// newEngine returns the engine for sandbox operations, the engines are created
+// once and reused by all the operations.
 //
 // If the client has an explicit engine type set (via Config.Engine), that engine
 // is always used. Otherwise, the engine is auto-detected from the sandbox config:
@@ -150,21 +455,43 @@ func (c *Client) Close() error {
 func (c *Client) newEngine(cfg model.SandboxConfig) (sandbox.Engine, error) {
 	engineType := c.resolveEngineType(cfg)
 
+	c.enginesMu.Lock()
+	defer c.enginesMu.Unlock()
+
+	if eng, ok := c.engines[engineType]; ok {
+		return eng, nil
+	}
+
+	var eng sandbox.Engine
+	var err error
 	switch engineType {
 	case EngineFirecracker:
-		return firecracker.NewEngine(firecracker.EngineConfig{
+		eng, err = firecracker.NewEngine(firecracker.EngineConfig{
 			DataDir:           c.dataDir,
+			VMsDir:            c.vmsDir,
 			FirecrackerBinary: c.firecrackerBinary,
 			Repository:        c.repo,
+			SSHPool:           c.sshPool,
+			DNSUpstreams:      c.dnsUpstreams,
+			EgressInterface:   c.egressInterface,
+			EventSinks:        c.eventSinks,
+			HostLocks:         c.hostLocks,
+			Retry:             c.retries,
 			Logger:            c.logger,
 		})
 	case EngineFake:
-		return fake.NewEngine(fake.EngineConfig{
+		eng, err = fake.NewEngine(fake.EngineConfig{
 			Logger: c.logger,
 		})
 	default:
 		return nil, fmt.Errorf("unsupported engine type: %s: %w", engineType, ErrNotValid)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.engines[engineType] = eng
+	return eng, nil
 }
 
 // newEngineForCreateWithBinary creates the engine for sandbox creation with a specific firecracker binary.
@@ -173,8 +500,14 @@ func (c *Client) newEngineForCreateWithBinary(engineType EngineType, firecracker
 	case EngineFirecracker:
 		return firecracker.NewEngine(firecracker.EngineConfig{
 			DataDir:           c.dataDir,
+			VMsDir:            c.vmsDir,
 			FirecrackerBinary: firecrackerBinary,
 			Repository:        c.repo,
+			DNSUpstreams:      c.dnsUpstreams,
+			EgressInterface:   c.egressInterface,
+			EventSinks:        c.eventSinks,
+			HostLocks:         c.hostLocks,
+			Retry:             c.retries,
 			Logger:            c.logger,
 		})
 	case EngineFake:
@@ -202,18 +535,46 @@ func (c *Client) Doctor(ctx context.Context) ([]CheckResult, error) {
 		FirecrackerEngine: &model.FirecrackerEngineConfig{},
 	})
 	if err != nil {
-		return nil, mapError(fmt.Errorf("could not create engine: %w", err))
+		return nil, mapError(fmt.Errorf("could not create engine: %w", err), "", "")
 	}
 
 	results := eng.Check(ctx)
 	return fromInternalCheckResults(results), nil
 }
 
+// EngineCapabilities returns the optional features supported by the configured
+// engine ([Config].Engine), so callers can feature-detect instead of handling the
+// errors of unsupported operations. When the engine is auto-detected (empty
+// [Config].Engine) the [EngineFirecracker] capabilities are returned, the engine
+// of the real sandboxes.
+//
+// The capabilities don't depend on the host, use [Client.Doctor] to check the host
+// can run the engine.
+func (c *Client) EngineCapabilities(ctx context.Context) (*EngineCapabilities, error) {
+	engineType := c.engineType
+	if engineType == "" {
+		engineType = EngineFirecracker
+	}
+
+	cfg := model.SandboxConfig{}
+	if engineType == EngineFirecracker {
+		cfg.FirecrackerEngine = &model.FirecrackerEngineConfig{}
+	}
+	eng, err := c.newEngine(cfg)
+	if err != nil {
+		return nil, mapError(fmt.Errorf("could not create engine: %w", err), "", "")
+	}
+
+	out := fromInternalEngineCapabilities(engineType, eng.Capabilities())
+	return &out, nil
+}
+
 // newLocalImageManager creates a local image manager for image operations.
 func (c *Client) newLocalImageManager() (image.ImageManager, error) {
 	return image.NewLocalImageManager(image.LocalImageManagerConfig{
-		ImagesDir: c.imagesDir,
-		Logger:    c.logger,
+		ImagesDir:    c.imagesDir,
+		SnapshotsDir: c.snapshotsDir,
+		Logger:       c.logger,
 	})
 }
 
@@ -226,10 +587,73 @@ func (c *Client) newImagePuller() (image.ImagePuller, error) {
 	})
 }
 
+// newImageBundler creates a local image bundler for image export and import.
+func (c *Client) newImageBundler() (image.ImageBundler, error) {
+	return image.NewLocalImageBundler(image.LocalImageBundlerConfig{
+		ImagesDir:    c.imagesDir,
+		SnapshotsDir: c.snapshotsDir,
+		Logger:       c.logger,
+	})
+}
+
+// newImageAdder creates a local image adder for user built images.
+func (c *Client) newImageAdder() (image.ImageAdder, error) {
+	return image.NewLocalImageAdder(image.LocalImageAdderConfig{
+		ImagesDir: c.imagesDir,
+		Logger:    c.logger,
+	})
+}
+
+// newRootFSBuilder creates a local rootfs builder for images converted from OCI images.
+func (c *Client) newRootFSBuilder() (image.RootFSBuilder, error) {
+	return image.NewLocalRootFSBuilder(image.LocalRootFSBuilderConfig{
+		TmpDir: c.imagesDir,
+		Logger: c.logger,
+	})
+}
+
+// newImageCustomizer creates a local image customizer for the pulled image customizations.
+func (c *Client) newImageCustomizer() (image.ImageCustomizer, error) {
+	return image.NewLocalImageCustomizer(image.LocalImageCustomizerConfig{
+		ImagesDir:    c.imagesDir,
+		SnapshotsDir: c.snapshotsDir,
+		Logger:       c.logger,
+	})
+}
+
+// newImageDiffer creates a local image differ for snapshot diffs.
+func (c *Client) newImageDiffer() (image.ImageDiffer, error) {
+	return image.NewLocalImageDiffer(image.LocalImageDifferConfig{
+		ImagesDir:    c.imagesDir,
+		SnapshotsDir: c.snapshotsDir,
+		Logger:       c.logger,
+	})
+}
+
+// newSnapshotStore creates the object storage snapshot store, returns ErrNotValid
+// without a configured one.
+func (c *Client) newSnapshotStore() (image.SnapshotStore, error) {
+	if c.snapshotStore == nil {
+		return nil, fmt.Errorf("no snapshot store configured: %w", ErrNotValid)
+	}
+	return image.NewS3SnapshotStore(image.S3SnapshotStoreConfig{
+		URL:             c.snapshotStore.URL,
+		Endpoint:        c.snapshotStore.Endpoint,
+		Region:          c.snapshotStore.Region,
+		AccessKeyID:     c.snapshotStore.AccessKeyID,
+		SecretAccessKey: c.snapshotStore.SecretAccessKey,
+		SessionToken:    c.snapshotStore.SessionToken,
+		Encryption:      model.SnapshotStoreEncryption(c.snapshotStore.Encryption),
+		KMSKeyID:        c.snapshotStore.KMSKeyID,
+		PartSize:        c.snapshotStore.PartSizeBytes,
+		Logger:          c.logger,
+	})
+}
+
 // newSnapshotCreator creates a local snapshot creator for snapshot operations.
 func (c *Client) newSnapshotCreator() (image.SnapshotCreator, error) {
 	return image.NewLocalSnapshotCreator(image.LocalSnapshotCreatorConfig{
-		ImagesDir: c.imagesDir,
+		ImagesDir: c.snapshotsDir,
 		Logger:    c.logger,
 	})
 }
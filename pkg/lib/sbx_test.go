@@ -1,15 +1,31 @@
 package lib_test
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/slok/sbx/internal/image"
 	"github.com/slok/sbx/pkg/lib"
 )
 
@@ -125,6 +141,26 @@ func TestCreateSandbox(t *testing.T) {
 			expIs:  lib.ErrNotValid,
 		},
 
+		"Creating a sandbox with a clock offset should work.": {
+			opts: lib.CreateSandboxOpts{
+				Name:      "clock-sandbox",
+				Engine:    lib.EngineFake,
+				Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+				Clock:     &lib.ClockOptions{Offset: -24 * time.Hour},
+			},
+		},
+
+		"Creating a sandbox with a clock boot time and offset should fail.": {
+			opts: lib.CreateSandboxOpts{
+				Name:      "clock-sandbox",
+				Engine:    lib.EngineFake,
+				Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+				Clock:     &lib.ClockOptions{BootTime: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), Offset: time.Hour},
+			},
+			expErr: true,
+			expIs:  lib.ErrNotValid,
+		},
+
 		"Creating a sandbox with zero resources should fail.": {
 			opts: lib.CreateSandboxOpts{
 				Name:   "zero-resources",
@@ -156,6 +192,7 @@ func TestCreateSandbox(t *testing.T) {
 			assert.Equal(test.opts.Name, sb.Name)
 			assert.Equal(lib.SandboxStatusStopped, sb.Status)
 			assert.False(sb.CreatedAt.IsZero())
+			assert.Equal(test.opts.Clock, sb.Config.Clock)
 		})
 	}
 }
@@ -183,6 +220,88 @@ func TestCreateSandboxDuplicate(t *testing.T) {
 	assert.True(errors.Is(err, lib.ErrAlreadyExists))
 }
 
+func TestCreateSandboxIfNotExists(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	opts := lib.CreateSandboxOpts{
+		Name:        "ensured-sandbox",
+		Engine:      lib.EngineFake,
+		Resources:   lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+		IfNotExists: true,
+	}
+
+	created, err := client.CreateSandbox(ctx, opts)
+	require.NoError(err)
+
+	// Same spec should return the existing sandbox.
+	got, err := client.CreateSandbox(ctx, opts)
+	require.NoError(err)
+	assert.Equal(created.ID, got.ID)
+
+	// A different spec should fail.
+	opts.Resources.MemoryMB = 1024
+	_, err = client.CreateSandbox(ctx, opts)
+	assert.ErrorIs(err, lib.ErrAlreadyExists)
+	assert.ErrorContains(err, "different spec")
+	var mismatchErr *lib.SpecMismatchError
+	if assert.ErrorAs(err, &mismatchErr) {
+		assert.Equal("ensured-sandbox", mismatchErr.Name)
+		assert.Equal([]lib.SpecField{lib.SpecFieldResources}, mismatchErr.Fields)
+	}
+
+	// Different webhooks should fail too.
+	opts.Resources.MemoryMB = 512
+	opts.Webhooks = []lib.Webhook{{URL: "https://hooks.test/sbx"}}
+	_, err = client.CreateSandbox(ctx, opts)
+	if assert.ErrorAs(err, &mismatchErr) {
+		assert.Equal([]lib.SpecField{lib.SpecFieldWebhooks}, mismatchErr.Fields)
+	}
+}
+
+func TestGetSandboxByIDAndDiffSandboxSpec(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	opts := lib.CreateSandboxOpts{
+		Name:      "managed",
+		Engine:    lib.EngineFake,
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	}
+	created, err := client.CreateSandbox(ctx, opts)
+	require.NoError(err)
+
+	// The writes are visible to the reads by ID, the names are not resolved.
+	got, err := client.GetSandboxByID(ctx, created.ID)
+	require.NoError(err)
+	assert.Equal(created.ID, got.ID)
+	assert.Equal("managed", got.Name)
+	_, err = client.GetSandboxByID(ctx, "managed")
+	assert.ErrorIs(err, lib.ErrNotFound)
+
+	diff, err := client.DiffSandboxSpec(ctx, created.ID, opts)
+	require.NoError(err)
+	assert.Empty(diff)
+
+	opts.Name = "managed-2"
+	opts.Resources.MemoryMB = 1024
+	opts.UserData = "#!/bin/sh\necho hi\n"
+	diff, err = client.DiffSandboxSpec(ctx, created.ID, opts)
+	require.NoError(err)
+	assert.Equal([]lib.SpecField{lib.SpecFieldName, lib.SpecFieldResources, lib.SpecFieldUserData}, diff)
+
+	_, err = client.RemoveSandbox(ctx, created.ID, false)
+	require.NoError(err)
+	_, err = client.GetSandboxByID(ctx, created.ID)
+	assert.ErrorIs(err, lib.ErrNotFound)
+	_, err = client.DiffSandboxSpec(ctx, created.ID, opts)
+	assert.ErrorIs(err, lib.ErrNotFound)
+}
+
 func TestGetSandbox(t *testing.T) {
 	tests := map[string]struct {
 		setup   func(t *testing.T, c *lib.Client) string // returns nameOrID to query
@@ -313,6 +432,65 @@ func TestListSandboxes(t *testing.T) {
 	}
 }
 
+func TestSelectSandboxes(t *testing.T) {
+	tests := map[string]struct {
+		pattern  string
+		expNames []string
+		expErr   error
+	}{
+		"A glob should select the matching sandboxes.": {
+			pattern:  "ci-*",
+			expNames: []string{"ci-1", "ci-2"},
+		},
+
+		"A regular expression should select the matching sandboxes.": {
+			pattern:  "re:-1$",
+			expNames: []string{"ci-1", "dev-1"},
+		},
+
+		"A pattern without matches should return empty.": {
+			pattern:  "prod-*",
+			expNames: []string{},
+		},
+
+		"An invalid pattern should fail.": {
+			pattern: "re:ci-(",
+			expErr:  lib.ErrNotValid,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			client := newTestClient(t)
+			ctx := context.Background()
+
+			for _, name := range []string{"ci-1", "dev-1", "ci-2"} {
+				_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+					Name:      name,
+					Engine:    lib.EngineFake,
+					Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+				})
+				require.NoError(err)
+			}
+
+			sandboxes, err := client.SelectSandboxes(ctx, test.pattern)
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+				return
+			}
+			require.NoError(err)
+
+			names := []string{}
+			for _, sb := range sandboxes {
+				names = append(names, sb.Name)
+			}
+			assert.ElementsMatch(test.expNames, names)
+		})
+	}
+}
+
 func TestStartSandbox(t *testing.T) {
 	tests := map[string]struct {
 		setup  func(t *testing.T, c *lib.Client) string
@@ -349,6 +527,40 @@ func TestStartSandbox(t *testing.T) {
 			},
 		},
 
+		"Starting with custom timeouts should work.": {
+			setup: func(t *testing.T, c *lib.Client) string {
+				t.Helper()
+				sb, err := c.CreateSandbox(context.Background(), lib.CreateSandboxOpts{
+					Name:      "start-timeouts",
+					Engine:    lib.EngineFake,
+					Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+				})
+				require.NoError(t, err)
+				return sb.Name
+			},
+			opts: &lib.StartSandboxOpts{
+				Timeouts: lib.StartTimeouts{Boot: 5 * time.Minute, SSHDial: 5 * time.Second, SSHRetries: 100, FilesystemExpand: time.Minute},
+			},
+		},
+
+		"Starting with negative timeouts should fail.": {
+			setup: func(t *testing.T, c *lib.Client) string {
+				t.Helper()
+				sb, err := c.CreateSandbox(context.Background(), lib.CreateSandboxOpts{
+					Name:      "start-negative-timeouts",
+					Engine:    lib.EngineFake,
+					Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+				})
+				require.NoError(t, err)
+				return sb.Name
+			},
+			opts: &lib.StartSandboxOpts{
+				Timeouts: lib.StartTimeouts{Boot: -time.Second},
+			},
+			expErr: true,
+			expIs:  lib.ErrNotValid,
+		},
+
 		"Starting a non-existent sandbox should fail.": {
 			setup: func(t *testing.T, c *lib.Client) string {
 				return "ghost"
@@ -377,6 +589,9 @@ func TestStartSandbox(t *testing.T) {
 			assert.NoError(err)
 			assert.Equal(lib.SandboxStatusRunning, sb.Status)
 			assert.NotNil(sb.StartedAt)
+			if assert.NotEmpty(sb.BootPhases) {
+				assert.Equal("boot", sb.BootPhases[0].Name)
+			}
 		})
 	}
 }
@@ -384,6 +599,7 @@ func TestStartSandbox(t *testing.T) {
 func TestStopSandbox(t *testing.T) {
 	tests := map[string]struct {
 		setup  func(t *testing.T, c *lib.Client) string
+		opts   *lib.StopSandboxOpts
 		expErr bool
 		expIs  error
 	}{
@@ -403,6 +619,42 @@ func TestStopSandbox(t *testing.T) {
 			},
 		},
 
+		"Stopping a running sandbox with a grace period and pre-stop hooks should work.": {
+			setup: func(t *testing.T, c *lib.Client) string {
+				t.Helper()
+				ctx := context.Background()
+				sb, err := c.CreateSandbox(ctx, lib.CreateSandboxOpts{
+					Name:      "stop-me",
+					Engine:    lib.EngineFake,
+					Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+				})
+				require.NoError(t, err)
+				_, err = c.StartSandbox(ctx, sb.Name, nil)
+				require.NoError(t, err)
+				return sb.Name
+			},
+			opts: &lib.StopSandboxOpts{GracePeriod: 30 * time.Second, PreStopHooks: []string{"sync"}},
+		},
+
+		"Stopping a running sandbox with a negative grace period should fail.": {
+			setup: func(t *testing.T, c *lib.Client) string {
+				t.Helper()
+				ctx := context.Background()
+				sb, err := c.CreateSandbox(ctx, lib.CreateSandboxOpts{
+					Name:      "stop-me",
+					Engine:    lib.EngineFake,
+					Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+				})
+				require.NoError(t, err)
+				_, err = c.StartSandbox(ctx, sb.Name, nil)
+				require.NoError(t, err)
+				return sb.Name
+			},
+			opts:   &lib.StopSandboxOpts{GracePeriod: -time.Second},
+			expErr: true,
+			expIs:  lib.ErrNotValid,
+		},
+
 		"Stopping a created (not running) sandbox should fail.": {
 			setup: func(t *testing.T, c *lib.Client) string {
 				t.Helper()
@@ -433,7 +685,7 @@ func TestStopSandbox(t *testing.T) {
 			client := newTestClient(t)
 			nameOrID := test.setup(t, client)
 
-			sb, err := client.StopSandbox(context.Background(), nameOrID)
+			sb, err := client.StopSandbox(context.Background(), nameOrID, test.opts)
 
 			if test.expErr {
 				assert.Error(err)
@@ -450,6 +702,140 @@ func TestStopSandbox(t *testing.T) {
 	}
 }
 
+func TestRestartSandbox(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	eventsFile := filepath.Join(t.TempDir(), "events.jsonl")
+	client, err := lib.New(ctx, lib.Config{
+		DBPath:     filepath.Join(t.TempDir(), "test.db"),
+		DataDir:    t.TempDir(),
+		Engine:     lib.EngineFake,
+		EventSinks: []lib.EventSink{{Type: lib.EventSinkFile, Path: eventsFile, Events: []lib.EventType{lib.EventSandboxStarted, lib.EventSandboxStopped}}},
+	})
+	require.NoError(err)
+	defer client.Close()
+
+	_, err = client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "restart-me",
+		Engine:    lib.EngineFake,
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	})
+	require.NoError(err)
+
+	// A sandbox that is not running should not be restarted.
+	_, err = client.RestartSandbox(ctx, "restart-me", nil)
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = client.StartSandbox(ctx, "restart-me", &lib.StartSandboxOpts{
+		Env:    map[string]string{"FOO": "bar"},
+		Egress: &lib.EgressPolicy{Default: lib.EgressActionDeny, Rules: []lib.EgressRule{{Domain: "github.com", Action: lib.EgressActionAllow}}},
+	})
+	require.NoError(err)
+
+	sb, err := client.RestartSandbox(ctx, "restart-me", &lib.RestartSandboxOpts{GracePeriod: 30 * time.Second, PreStopHooks: []string{"sync"}})
+	require.NoError(err)
+	assert.Equal(lib.SandboxStatusRunning, sb.Status)
+	assert.NotNil(sb.StartedAt)
+	assert.Nil(sb.StoppedAt)
+
+	_, err = client.RestartSandbox(ctx, "restart-me", &lib.RestartSandboxOpts{GracePeriod: -time.Second})
+	assert.ErrorIs(err, lib.ErrNotValid)
+	_, err = client.RestartSandbox(ctx, "missing", nil)
+	assert.ErrorIs(err, lib.ErrNotFound)
+
+	// The restart should be notified as a stop and a start.
+	require.NoError(client.Close())
+	data, err := os.ReadFile(eventsFile)
+	require.NoError(err)
+	var types []lib.EventType
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var ev struct {
+			Type lib.EventType `json:"type"`
+		}
+		require.NoError(json.Unmarshal([]byte(line), &ev))
+		types = append(types, ev.Type)
+	}
+	assert.Equal([]lib.EventType{lib.EventSandboxStarted, lib.EventSandboxStopped, lib.EventSandboxStarted}, types)
+}
+
+func TestSandboxLastStart(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+	client := newTestClient(t)
+
+	_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "last-start",
+		Engine:    lib.EngineFake,
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	})
+	require.NoError(err)
+
+	sb, err := client.GetSandbox(ctx, "last-start")
+	require.NoError(err)
+	assert.Nil(sb.LastBootPhases)
+	assert.Empty(sb.LastError)
+
+	started, err := client.StartSandbox(ctx, "last-start", nil)
+	require.NoError(err)
+
+	// The phases of the start should be kept after it.
+	sb, err = client.GetSandbox(ctx, "last-start")
+	require.NoError(err)
+	assert.Equal(started.BootPhases, sb.LastBootPhases)
+	assert.Empty(sb.LastError)
+}
+
+func TestSandboxSession(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+	client := newTestClient(t)
+
+	_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "session",
+		Engine:    lib.EngineFake,
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	})
+	require.NoError(err)
+
+	sb, err := client.GetSandbox(ctx, "session")
+	require.NoError(err)
+	assert.Nil(sb.Session)
+
+	egress := &lib.EgressPolicy{Default: lib.EgressActionDeny, Rules: []lib.EgressRule{{Domain: "github.com", Action: lib.EgressActionAllow}}}
+	_, err = client.StartSandbox(ctx, "session", &lib.StartSandboxOpts{
+		Env:    map[string]string{"GOFLAGS": "-mod=mod", "GITHUB_TOKEN": "ghp_secret"},
+		Egress: egress,
+	})
+	require.NoError(err)
+
+	// The session should be shown with the secrets redacted.
+	expSession := &lib.SandboxSession{
+		Env:    map[string]string{"GOFLAGS": "-mod=mod", "GITHUB_TOKEN": "<redacted>"},
+		Egress: egress,
+	}
+	sb, err = client.GetSandbox(ctx, "session")
+	require.NoError(err)
+	assert.Equal(expSession, sb.Session)
+
+	// A start without session should apply the previous one.
+	_, err = client.StopSandbox(ctx, "session", nil)
+	require.NoError(err)
+	sb, err = client.StartSandbox(ctx, "session", nil)
+	require.NoError(err)
+	assert.Equal(expSession, sb.Session)
+
+	// A reset session should not.
+	_, err = client.StopSandbox(ctx, "session", nil)
+	require.NoError(err)
+	sb, err = client.StartSandbox(ctx, "session", &lib.StartSandboxOpts{ResetSession: true})
+	require.NoError(err)
+	assert.Equal(&lib.SandboxSession{}, sb.Session)
+}
+
 func TestRemoveSandbox(t *testing.T) {
 	tests := map[string]struct {
 		setup  func(t *testing.T, c *lib.Client) string
@@ -539,61 +925,360 @@ func TestRemoveSandbox(t *testing.T) {
 	}
 }
 
-func TestExec(t *testing.T) {
+func TestCloneSandbox(t *testing.T) {
 	tests := map[string]struct {
-		setup   func(t *testing.T, c *lib.Client) string
-		command []string
-		opts    *lib.ExecOpts
-		expErr  bool
-		expIs   error
+		start        bool
+		newName      string
+		opts         *lib.CloneSandboxOpts
+		expResources lib.Resources
+		expIs        error
 	}{
-		"Executing in a running sandbox should work.": {
-			setup: func(t *testing.T, c *lib.Client) string {
-				t.Helper()
-				ctx := context.Background()
-				sb, err := c.CreateSandbox(ctx, lib.CreateSandboxOpts{
-					Name:      "exec-ok",
-					Engine:    lib.EngineFake,
-					Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
-				})
-				require.NoError(t, err)
-				_, err = c.StartSandbox(ctx, sb.Name, nil)
-				require.NoError(t, err)
-				return sb.Name
-			},
-			command: []string{"echo", "hello"},
+		"Cloning a stopped sandbox should work.": {
+			newName:      "clone",
+			expResources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
 		},
 
-		"Executing with empty command should fail.": {
-			setup: func(t *testing.T, c *lib.Client) string {
-				t.Helper()
-				ctx := context.Background()
-				sb, err := c.CreateSandbox(ctx, lib.CreateSandboxOpts{
-					Name:      "exec-empty",
-					Engine:    lib.EngineFake,
-					Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
-				})
-				require.NoError(t, err)
-				_, err = c.StartSandbox(ctx, sb.Name, nil)
-				require.NoError(t, err)
-				return sb.Name
-			},
-			command: []string{},
-			expErr:  true,
+		"Cloning with resource overrides should use them.": {
+			newName:      "clone",
+			opts:         &lib.CloneSandboxOpts{Resources: lib.Resources{VCPUs: 2, DiskGB: 10}},
+			expResources: lib.Resources{VCPUs: 2, MemoryMB: 512, DiskGB: 10},
+		},
+
+		"Cloning with a smaller disk should fail.": {
+			newName: "clone",
+			opts:    &lib.CloneSandboxOpts{Resources: lib.Resources{DiskGB: 1}},
 			expIs:   lib.ErrNotValid,
 		},
 
-		"Executing in a non-running sandbox should fail.": {
-			setup: func(t *testing.T, c *lib.Client) string {
-				t.Helper()
-				sb, err := c.CreateSandbox(context.Background(), lib.CreateSandboxOpts{
-					Name:      "exec-stopped",
-					Engine:    lib.EngineFake,
-					Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
-				})
-				require.NoError(t, err)
-				return sb.Name
-			},
+		"Cloning a running sandbox should fail.": {
+			start:   true,
+			newName: "clone",
+			expIs:   lib.ErrNotValid,
+		},
+
+		"Cloning into an existing name should fail.": {
+			newName: "src",
+			expIs:   lib.ErrAlreadyExists,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			ctx := context.Background()
+			client := newTestClient(t)
+
+			_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+				Name:      "src",
+				Engine:    lib.EngineFake,
+				Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+			})
+			require.NoError(err)
+			if test.start {
+				_, err = client.StartSandbox(ctx, "src", nil)
+				require.NoError(err)
+			}
+
+			sb, err := client.CloneSandbox(ctx, "src", test.newName, test.opts)
+
+			if test.expIs != nil {
+				assert.True(errors.Is(err, test.expIs), "expected error %v, got: %v", test.expIs, err)
+				return
+			}
+
+			require.NoError(err)
+			assert.Equal(test.newName, sb.Name)
+			assert.Equal(lib.SandboxStatusStopped, sb.Status)
+			assert.Equal(test.expResources, sb.Config.Resources)
+
+			got, err := client.GetSandbox(ctx, test.newName)
+			require.NoError(err)
+			assert.Equal(sb.ID, got.ID)
+		})
+	}
+}
+
+func TestWaitFor(t *testing.T) {
+	newSandbox := func(t *testing.T, c *lib.Client, start bool) string {
+		t.Helper()
+		ctx := context.Background()
+		sb, err := c.CreateSandbox(ctx, lib.CreateSandboxOpts{
+			Name:      "wait-me",
+			Engine:    lib.EngineFake,
+			Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+		})
+		require.NoError(t, err)
+		if start {
+			_, err = c.StartSandbox(ctx, sb.Name, nil)
+			require.NoError(t, err)
+		}
+		return sb.Name
+	}
+
+	tests := map[string]struct {
+		setup     func(t *testing.T, c *lib.Client) string
+		condition lib.WaitCondition
+		timeout   time.Duration
+		expStatus lib.SandboxStatus
+		expIs     error
+	}{
+		"Waiting for a running sandbox to be running should work.": {
+			setup:     func(t *testing.T, c *lib.Client) string { return newSandbox(t, c, true) },
+			condition: lib.WaitConditionRunning,
+			expStatus: lib.SandboxStatusRunning,
+		},
+
+		"Waiting for a running sandbox to be SSH ready should work.": {
+			setup:     func(t *testing.T, c *lib.Client) string { return newSandbox(t, c, true) },
+			condition: lib.WaitConditionSSHReady,
+			expStatus: lib.SandboxStatusRunning,
+		},
+
+		"Waiting for a running sandbox to be healthy should work.": {
+			setup:     func(t *testing.T, c *lib.Client) string { return newSandbox(t, c, true) },
+			condition: lib.WaitConditionHealthy,
+			expStatus: lib.SandboxStatusRunning,
+		},
+
+		"Waiting for a created sandbox to be stopped should work.": {
+			setup:     func(t *testing.T, c *lib.Client) string { return newSandbox(t, c, false) },
+			condition: lib.WaitConditionStopped,
+			expStatus: lib.SandboxStatusStopped,
+		},
+
+		"Waiting for a stopped sandbox to be running should time out.": {
+			setup:     func(t *testing.T, c *lib.Client) string { return newSandbox(t, c, false) },
+			condition: lib.WaitConditionRunning,
+			timeout:   50 * time.Millisecond,
+			expIs:     context.DeadlineExceeded,
+		},
+
+		"Waiting with an unknown condition should fail.": {
+			setup:     func(t *testing.T, c *lib.Client) string { return newSandbox(t, c, true) },
+			condition: "sleeping",
+			expIs:     lib.ErrNotValid,
+		},
+
+		"Waiting for a non-existent sandbox should fail.": {
+			setup:     func(t *testing.T, c *lib.Client) string { return "ghost" },
+			condition: lib.WaitConditionRunning,
+			expIs:     lib.ErrNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			client := newTestClient(t)
+			nameOrID := test.setup(t, client)
+
+			sb, err := client.WaitFor(context.Background(), nameOrID, test.condition, test.timeout)
+
+			if test.expIs != nil {
+				assert.True(errors.Is(err, test.expIs), "expected error %v, got: %v", test.expIs, err)
+				return
+			}
+
+			if assert.NoError(err) {
+				assert.Equal(test.expStatus, sb.Status)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := map[string]struct {
+		run          func(t *testing.T, c *lib.Client) error
+		expCode      lib.ErrorCode
+		expKind      lib.ResourceKind
+		expName      string
+		expRetryable bool
+		expIs        error
+	}{
+		"A missing sandbox should return a not found error of the sandbox.": {
+			run: func(t *testing.T, c *lib.Client) error {
+				_, err := c.GetSandbox(context.Background(), "ghost")
+				return err
+			},
+			expCode: lib.ErrorCodeNotFound,
+			expKind: lib.ResourceKindSandbox,
+			expName: "ghost",
+			expIs:   lib.ErrNotFound,
+		},
+
+		"A duplicated sandbox should return an already exists error of the sandbox.": {
+			run: func(t *testing.T, c *lib.Client) error {
+				opts := lib.CreateSandboxOpts{Name: "dup", Engine: lib.EngineFake, Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5}}
+				_, err := c.CreateSandbox(context.Background(), opts)
+				require.NoError(t, err)
+				_, err = c.CreateSandbox(context.Background(), opts)
+				return err
+			},
+			expCode: lib.ErrorCodeAlreadyExists,
+			expKind: lib.ResourceKindSandbox,
+			expName: "dup",
+			expIs:   lib.ErrAlreadyExists,
+		},
+
+		"A missing image should return a not found error of the image.": {
+			run: func(t *testing.T, c *lib.Client) error {
+				_, err := c.CreateSandbox(context.Background(), lib.CreateSandboxOpts{Name: "test", Engine: lib.EngineFake, FromImage: "v9.9.9", Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5}})
+				return err
+			},
+			expCode: lib.ErrorCodeNotFound,
+			expKind: lib.ResourceKindImage,
+			expName: "v9.9.9",
+			expIs:   lib.ErrNotFound,
+		},
+
+		"A wait timeout should return a retryable timeout error.": {
+			run: func(t *testing.T, c *lib.Client) error {
+				_, err := c.CreateSandbox(context.Background(), lib.CreateSandboxOpts{Name: "slow", Engine: lib.EngineFake, Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5}})
+				require.NoError(t, err)
+				_, err = c.WaitFor(context.Background(), "slow", lib.WaitConditionRunning, 50*time.Millisecond)
+				return err
+			},
+			expCode:      lib.ErrorCodeTimeout,
+			expKind:      lib.ResourceKindSandbox,
+			expName:      "slow",
+			expRetryable: true,
+			expIs:        context.DeadlineExceeded,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			client := newTestClient(t)
+
+			err := test.run(t, client)
+
+			var sbxErr *lib.Error
+			if assert.True(errors.As(err, &sbxErr), "expected a lib error, got: %v", err) {
+				assert.Equal(test.expCode, sbxErr.Code)
+				assert.Equal(test.expKind, sbxErr.Kind)
+				assert.Equal(test.expName, sbxErr.Name)
+				assert.Equal(test.expRetryable, sbxErr.Retryable)
+			}
+			assert.ErrorIs(err, test.expIs)
+		})
+	}
+}
+
+func TestExec(t *testing.T) {
+	tests := map[string]struct {
+		setup   func(t *testing.T, c *lib.Client) string
+		command []string
+		opts    *lib.ExecOpts
+		expErr  bool
+		expIs   error
+	}{
+		"Executing in a running sandbox should work.": {
+			setup: func(t *testing.T, c *lib.Client) string {
+				t.Helper()
+				ctx := context.Background()
+				sb, err := c.CreateSandbox(ctx, lib.CreateSandboxOpts{
+					Name:      "exec-ok",
+					Engine:    lib.EngineFake,
+					Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+				})
+				require.NoError(t, err)
+				_, err = c.StartSandbox(ctx, sb.Name, nil)
+				require.NoError(t, err)
+				return sb.Name
+			},
+			command: []string{"echo", "hello"},
+		},
+
+		"Executing with output capture should work.": {
+			setup: func(t *testing.T, c *lib.Client) string {
+				t.Helper()
+				ctx := context.Background()
+				sb, err := c.CreateSandbox(ctx, lib.CreateSandboxOpts{
+					Name:      "exec-capture",
+					Engine:    lib.EngineFake,
+					Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+				})
+				require.NoError(t, err)
+				_, err = c.StartSandbox(ctx, sb.Name, nil)
+				require.NoError(t, err)
+				return sb.Name
+			},
+			command: []string{"echo", "hello"},
+			opts:    &lib.ExecOpts{CaptureOutput: true, CaptureMaxBytes: 1024},
+		},
+
+		"Executing with an unknown output limit policy should fail.": {
+			setup: func(t *testing.T, c *lib.Client) string {
+				t.Helper()
+				ctx := context.Background()
+				sb, err := c.CreateSandbox(ctx, lib.CreateSandboxOpts{
+					Name:      "exec-output-limit",
+					Engine:    lib.EngineFake,
+					Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+				})
+				require.NoError(t, err)
+				_, err = c.StartSandbox(ctx, sb.Name, nil)
+				require.NoError(t, err)
+				return sb.Name
+			},
+			command: []string{"yes"},
+			opts:    &lib.ExecOpts{MaxOutputBytes: 1024, OutputLimitPolicy: "drop"},
+			expErr:  true,
+			expIs:   lib.ErrNotValid,
+		},
+
+		"Executing with a tty on an engine without tty support should fail.": {
+			setup: func(t *testing.T, c *lib.Client) string {
+				t.Helper()
+				ctx := context.Background()
+				sb, err := c.CreateSandbox(ctx, lib.CreateSandboxOpts{
+					Name:      "exec-tty",
+					Engine:    lib.EngineFake,
+					Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+				})
+				require.NoError(t, err)
+				_, err = c.StartSandbox(ctx, sb.Name, nil)
+				require.NoError(t, err)
+				return sb.Name
+			},
+			command: []string{"sh"},
+			opts:    &lib.ExecOpts{Tty: true},
+			expErr:  true,
+			expIs:   lib.ErrNotValid,
+		},
+
+		"Executing with empty command should fail.": {
+			setup: func(t *testing.T, c *lib.Client) string {
+				t.Helper()
+				ctx := context.Background()
+				sb, err := c.CreateSandbox(ctx, lib.CreateSandboxOpts{
+					Name:      "exec-empty",
+					Engine:    lib.EngineFake,
+					Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+				})
+				require.NoError(t, err)
+				_, err = c.StartSandbox(ctx, sb.Name, nil)
+				require.NoError(t, err)
+				return sb.Name
+			},
+			command: []string{},
+			expErr:  true,
+			expIs:   lib.ErrNotValid,
+		},
+
+		"Executing in a non-running sandbox should fail.": {
+			setup: func(t *testing.T, c *lib.Client) string {
+				t.Helper()
+				sb, err := c.CreateSandbox(context.Background(), lib.CreateSandboxOpts{
+					Name:      "exec-stopped",
+					Engine:    lib.EngineFake,
+					Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+				})
+				require.NoError(t, err)
+				return sb.Name
+			},
 			command: []string{"echo", "hello"},
 			expErr:  true,
 			expIs:   lib.ErrNotValid,
@@ -627,10 +1312,53 @@ func TestExec(t *testing.T) {
 
 			assert.NoError(err)
 			assert.Equal(0, result.ExitCode)
+			assert.False(result.StartedAt.IsZero())
+			assert.False(result.FinishedAt.Before(result.StartedAt))
+			assert.Equal(result.FinishedAt.Sub(result.StartedAt), result.Duration)
 		})
 	}
 }
 
+func TestExecHistory(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "history",
+		Engine:    lib.EngineFake,
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	})
+	require.NoError(err)
+	_, err = client.StartSandbox(ctx, "history", nil)
+	require.NoError(err)
+
+	_, err = client.Exec(ctx, "history", []string{"echo", "one"}, nil)
+	require.NoError(err)
+	_, err = client.Exec(ctx, "history", []string{"echo", "two"}, &lib.ExecOpts{Caller: "ci", WorkingDir: "/tmp"})
+	require.NoError(err)
+
+	records, err := client.ExecHistory(ctx, "history", nil)
+	require.NoError(err)
+	require.Len(records, 2)
+	assert.Equal([]string{"echo", "one"}, records[0].Command)
+	assert.Equal("sdk", records[0].Caller)
+	assert.Equal([]string{"echo", "two"}, records[1].Command)
+	assert.Equal("ci", records[1].Caller)
+	assert.Equal("/tmp", records[1].WorkingDir)
+	assert.NotEmpty(records[1].ID)
+	assert.NotEmpty(records[1].StdoutSHA256)
+
+	records, err = client.ExecHistory(ctx, "history", &lib.ExecHistoryOpts{Limit: 1})
+	require.NoError(err)
+	require.Len(records, 1)
+	assert.Equal([]string{"echo", "two"}, records[0].Command)
+
+	_, err = client.ExecHistory(ctx, "missing", nil)
+	assert.ErrorIs(err, lib.ErrNotFound)
+}
+
 func TestCopyTo(t *testing.T) {
 	t.Run("Copying to a running sandbox should work.", func(t *testing.T) {
 		assert := assert.New(t)
@@ -650,7 +1378,31 @@ func TestCopyTo(t *testing.T) {
 		srcPath := filepath.Join(t.TempDir(), "src.txt")
 		require.NoError(t, os.WriteFile(srcPath, []byte("data"), 0644))
 
-		err = client.CopyTo(ctx, sb.Name, srcPath, "/dst")
+		err = client.CopyTo(ctx, sb.Name, srcPath, "/dst", nil)
+		assert.NoError(err)
+	})
+
+	t.Run("Copying to a running sandbox with options should work.", func(t *testing.T) {
+		assert := assert.New(t)
+		client := newTestClient(t)
+		ctx := context.Background()
+
+		sb, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+			Name:      "cp-to-opts",
+			Engine:    lib.EngineFake,
+			Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+		})
+		require.NoError(t, err)
+		_, err = client.StartSandbox(ctx, sb.Name, nil)
+		require.NoError(t, err)
+
+		srcPath := filepath.Join(t.TempDir(), "src.txt")
+		require.NoError(t, os.WriteFile(srcPath, []byte("data"), 0644))
+
+		err = client.CopyTo(ctx, sb.Name, srcPath, "/dst", &lib.CopyOpts{
+			Compress: true,
+			Progress: func(lib.CopyProgress) {},
+		})
 		assert.NoError(err)
 	})
 
@@ -668,7 +1420,7 @@ func TestCopyTo(t *testing.T) {
 		srcPath := filepath.Join(t.TempDir(), "src.txt")
 		require.NoError(t, os.WriteFile(srcPath, []byte("data"), 0644))
 
-		err = client.CopyTo(context.Background(), sb.Name, srcPath, "/dst")
+		err = client.CopyTo(context.Background(), sb.Name, srcPath, "/dst", nil)
 		assert.Error(err)
 		assert.True(errors.Is(err, lib.ErrNotValid), "expected ErrNotValid, got: %v", err)
 	})
@@ -718,7 +1470,7 @@ func TestCopyFrom(t *testing.T) {
 			client := newTestClient(t)
 			nameOrID := test.setup(t, client)
 
-			err := client.CopyFrom(context.Background(), nameOrID, "/src", "/tmp/dst")
+			err := client.CopyFrom(context.Background(), nameOrID, "/src", "/tmp/dst", nil)
 
 			if test.expErr {
 				assert.Error(err)
@@ -782,15 +1534,15 @@ func TestFullLifecycle(t *testing.T) {
 	// CopyTo.
 	srcPath := filepath.Join(t.TempDir(), "src.txt")
 	require.NoError(os.WriteFile(srcPath, []byte("data"), 0644))
-	err = client.CopyTo(ctx, "lifecycle", srcPath, "/dst")
+	err = client.CopyTo(ctx, "lifecycle", srcPath, "/dst", nil)
 	require.NoError(err)
 
 	// CopyFrom.
-	err = client.CopyFrom(ctx, "lifecycle", "/src", "/tmp/dst")
+	err = client.CopyFrom(ctx, "lifecycle", "/src", "/tmp/dst", nil)
 	require.NoError(err)
 
 	// Stop.
-	stopped, err := client.StopSandbox(ctx, "lifecycle")
+	stopped, err := client.StopSandbox(ctx, "lifecycle", nil)
 	require.NoError(err)
 	assert.Equal(lib.SandboxStatusStopped, stopped.Status)
 	assert.NotNil(stopped.StoppedAt)
@@ -826,7 +1578,7 @@ func TestCopyToSourceValidation(t *testing.T) {
 	require.NoError(err)
 
 	// CopyTo with non-existent source should fail with ErrNotValid.
-	err = client.CopyTo(ctx, "cp-validation", "/nonexistent/path/file.txt", "/dst")
+	err = client.CopyTo(ctx, "cp-validation", "/nonexistent/path/file.txt", "/dst", nil)
 	assert.Error(err)
 	assert.True(errors.Is(err, lib.ErrNotValid), "expected ErrNotValid, got: %v", err)
 }
@@ -863,6 +1615,7 @@ func TestCreateImageFromSandbox(t *testing.T) {
 	tests := map[string]struct {
 		setup  func(t *testing.T, tc testClientWithDataDir) string
 		opts   *lib.CreateImageFromSandboxOpts
+		assert func(t *testing.T, tc testClientWithDataDir)
 		expErr bool
 		expIs  error
 	}{
@@ -881,22 +1634,22 @@ func TestCreateImageFromSandbox(t *testing.T) {
 			opts: &lib.CreateImageFromSandboxOpts{ImageName: "my-snapshot"},
 		},
 
-		"Creating an image from a running sandbox should fail.": {
+		"Creating an image from a running sandbox should snapshot it live.": {
 			setup: func(t *testing.T, tc testClientWithDataDir) string {
 				t.Helper()
-				ctx := context.Background()
-				sb, err := tc.Client.CreateSandbox(ctx, lib.CreateSandboxOpts{
-					Name:      "snap-running",
-					Engine:    lib.EngineFake,
-					Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
-				})
+				name := createSandboxWithFiles(t, tc, "snap-running")
+				_, err := tc.Client.StartSandbox(context.Background(), name, nil)
 				require.NoError(t, err)
-				_, err = tc.Client.StartSandbox(ctx, sb.Name, nil)
+				return name
+			},
+			opts: &lib.CreateImageFromSandboxOpts{ImageName: "live-snapshot"},
+			assert: func(t *testing.T, tc testClientWithDataDir) {
+				manifest, err := tc.Client.InspectImage(context.Background(), "live-snapshot")
 				require.NoError(t, err)
-				return sb.Name
+				require.NotNil(t, manifest.Snapshot)
+				assert.NotEmpty(t, manifest.Snapshot.MemoryFile)
+				assert.NotEmpty(t, manifest.Snapshot.VMStateFile)
 			},
-			expErr: true,
-			expIs:  lib.ErrNotValid,
 		},
 
 		"Creating an image from a non-existent sandbox should fail.": {
@@ -926,20 +1679,496 @@ func TestCreateImageFromSandbox(t *testing.T) {
 
 			assert.NoError(err)
 			assert.NotEmpty(imgName)
+			if test.assert != nil {
+				test.assert(t, tc)
+			}
 		})
 	}
 }
 
-func TestForward(t *testing.T) {
-	t.Run("Forwarding with empty ports should fail.", func(t *testing.T) {
-		assert := assert.New(t)
-		client := newTestClient(t)
-		ctx := context.Background()
+func TestAddLocalImage(t *testing.T) {
+	tc := newTestClientWithDataDir(t)
+	ctx := context.Background()
 
-		sb, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
-			Name:      "fwd-empty",
-			Engine:    lib.EngineFake,
-			Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	srcDir := t.TempDir()
+	kernel := filepath.Join(srcDir, "vmlinux")
+	require.NoError(t, os.WriteFile(kernel, []byte("kernel"), 0644))
+	rootfs := filepath.Join(srcDir, "rootfs.ext4")
+	data := make([]byte, 2048)
+	data[1024+0x38], data[1024+0x39] = 0x53, 0xEF
+	require.NoError(t, os.WriteFile(rootfs, data, 0644))
+
+	manifest, err := tc.Client.AddLocalImage(ctx, "custom", kernel, rootfs, &lib.AddLocalImageOpts{Distro: "debian"})
+	require.NoError(t, err)
+	require.NotNil(t, manifest.Local)
+	assert.Equal(t, rootfs, manifest.Local.RootFSSource)
+	assert.Equal(t, "debian", manifest.Artifacts[image.HostArch()].Rootfs.Distro)
+
+	_, err = tc.Client.AddLocalImage(ctx, "custom", kernel, rootfs, nil)
+	assert.ErrorIs(t, err, lib.ErrAlreadyExists)
+	_, err = tc.Client.AddLocalImage(ctx, "other", kernel, kernel, nil)
+	assert.ErrorIs(t, err, lib.ErrNotValid)
+
+	// The added image is usable like a pulled one.
+	sb, err := tc.Client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "custom-user",
+		Engine:    lib.EngineFake,
+		FromImage: "custom",
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "custom", sb.Config.Image)
+}
+
+func TestPushFetchSnapshot(t *testing.T) {
+	ctx := context.Background()
+
+	// A bucket with the single request uploads.
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			objects[r.URL.Path], _ = io.ReadAll(r.Body)
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(data)
+		}
+	}))
+	defer srv.Close()
+
+	newHostClient := func(store *lib.SnapshotStoreConfig) *lib.Client {
+		client, err := lib.New(ctx, lib.Config{
+			DBPath:        filepath.Join(t.TempDir(), "test.db"),
+			DataDir:       t.TempDir(),
+			Engine:        lib.EngineFake,
+			SnapshotStore: store,
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = client.Close() })
+		return client
+	}
+	store := &lib.SnapshotStoreConfig{URL: "s3://snapshots/team-a", Endpoint: srv.URL, AccessKeyID: "AKID", SecretAccessKey: "secret"}
+	hostA, hostB, noStore := newHostClient(store), newHostClient(store), newHostClient(nil)
+
+	srcDir := t.TempDir()
+	kernel := filepath.Join(srcDir, "vmlinux")
+	require.NoError(t, os.WriteFile(kernel, []byte("kernel"), 0644))
+	rootfs := filepath.Join(srcDir, "rootfs.ext4")
+	data := make([]byte, 2048)
+	data[1024+0x38], data[1024+0x39] = 0x53, 0xEF
+	require.NoError(t, os.WriteFile(rootfs, data, 0644))
+	_, err := hostA.AddLocalImage(ctx, "golden", kernel, rootfs, nil)
+	require.NoError(t, err)
+
+	_, err = noStore.PushSnapshot(ctx, "golden")
+	assert.ErrorIs(t, err, lib.ErrNotValid)
+	_, err = hostA.PushSnapshot(ctx, "missing")
+	assert.ErrorIs(t, err, lib.ErrNotFound)
+
+	snap, err := hostA.PushSnapshot(ctx, "golden")
+	require.NoError(t, err)
+	assert.Equal(t, "s3://snapshots/team-a/golden.tar", snap.URL)
+	assert.Equal(t, 1, snap.Parts)
+	assert.Contains(t, objects, "/snapshots/team-a/golden.tar")
+
+	// The other host fetches the missing image on create.
+	sb, err := hostB.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "from-golden",
+		Engine:    lib.EngineFake,
+		FromImage: "golden",
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "golden", sb.Config.Image)
+
+	assert.ErrorIs(t, hostB.FetchSnapshot(ctx, "golden", nil), lib.ErrAlreadyExists)
+	assert.NoError(t, hostB.FetchSnapshot(ctx, "golden", &lib.FetchSnapshotOpts{Force: true}))
+	assert.ErrorIs(t, hostB.FetchSnapshot(ctx, "missing", nil), lib.ErrNotFound)
+	_, err = hostB.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "from-missing",
+		Engine:    lib.EngineFake,
+		FromImage: "missing",
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	})
+	assert.ErrorIs(t, err, lib.ErrNotFound)
+}
+
+func TestDiffSnapshot(t *testing.T) {
+	tc := newTestClientWithDataDir(t)
+	ctx := context.Background()
+
+	_, err := tc.Client.DiffSnapshot(ctx, "missing", "")
+	assert.ErrorIs(t, err, lib.ErrNotFound)
+
+	// Images that aren't snapshots have no base to compare with.
+	srcDir := t.TempDir()
+	kernel := filepath.Join(srcDir, "vmlinux")
+	require.NoError(t, os.WriteFile(kernel, []byte("kernel"), 0644))
+	rootfs := filepath.Join(srcDir, "rootfs.ext4")
+	data := make([]byte, 2048)
+	data[1024+0x38], data[1024+0x39] = 0x53, 0xEF
+	require.NoError(t, os.WriteFile(rootfs, data, 0644))
+	_, err = tc.Client.AddLocalImage(ctx, "custom", kernel, rootfs, nil)
+	require.NoError(t, err)
+
+	_, err = tc.Client.DiffSnapshot(ctx, "custom", "")
+	assert.ErrorIs(t, err, lib.ErrNotValid)
+	_, err = tc.Client.DiffSnapshot(ctx, "custom", "missing")
+	assert.ErrorIs(t, err, lib.ErrNotFound)
+}
+
+func TestPullImageInvalidCustomization(t *testing.T) {
+	tc := newTestClientWithDataDir(t)
+	ctx := context.Background()
+
+	// The customization is validated before pulling anything.
+	_, err := tc.Client.PullImage(ctx, "v0.1.0", &lib.PullImageOpts{Customize: &lib.ImageCustomization{}})
+	assert.ErrorIs(t, err, lib.ErrNotValid)
+	_, err = tc.Client.PullImage(ctx, "v0.1.0", &lib.PullImageOpts{Customize: &lib.ImageCustomization{
+		Files: []lib.ImageCustomizationFile{{Src: "motd", Dst: "etc/motd"}},
+	}})
+	assert.ErrorIs(t, err, lib.ErrNotValid)
+}
+
+func TestCreateImageFromOCI(t *testing.T) {
+	tc := newTestClientWithDataDir(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// A docker save archive with a single layer.
+	tarFile := func(entries map[string]string) []byte {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for name, body := range entries {
+			require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(body)), Typeflag: tar.TypeReg}))
+			_, err := tw.Write([]byte(body))
+			require.NoError(t, err)
+		}
+		require.NoError(t, tw.Close())
+		return buf.Bytes()
+	}
+	layer := tarFile(map[string]string{"bin/sh": "sh", "usr/sbin/sshd": "sshd", "etc/ssh/ssh_host_ed25519_key": "key"})
+	archive := filepath.Join(dir, "app.tar")
+	require.NoError(t, os.WriteFile(archive, tarFile(map[string]string{
+		"manifest.json": `[{"Config": "config.json", "Layers": ["l0/layer.tar"]}]`,
+		"l0/layer.tar":  string(layer),
+	}), 0644))
+
+	// Without a release to take the kernel and init from.
+	_, err := tc.Client.CreateImageFromOCI(ctx, "my-app", archive, nil)
+	assert.ErrorIs(t, err, lib.ErrNotValid)
+
+	for _, tool := range []string{"mkfs.ext4", "debugfs"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not available", tool)
+		}
+	}
+
+	// Install a release with the guest init in its rootfs.
+	arch := image.HostArch()
+	relDir := filepath.Join(tc.DataDir, "images", "v0.1.0")
+	initDir := filepath.Join(dir, "base", "usr", "sbin")
+	require.NoError(t, os.MkdirAll(initDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(initDir, "sbx-init"), []byte("init"), 0755))
+	require.NoError(t, os.MkdirAll(relDir, 0755))
+	out, err := exec.Command("mkfs.ext4", "-q", "-d", filepath.Join(dir, "base"), filepath.Join(relDir, "rootfs-"+arch+".ext4"), "8M").CombinedOutput()
+	require.NoError(t, err, string(out))
+	require.NoError(t, os.WriteFile(filepath.Join(relDir, "vmlinux-"+arch), []byte("kernel"), 0644))
+	manifest := fmt.Sprintf(`{"schema_version": 1, "version": "v0.1.0", "artifacts": {%q: {"kernel": {"file": "vmlinux-%s", "version": "6.1.155"}, "rootfs": {"file": "rootfs-%s.ext4"}}}}`, arch, arch, arch)
+	require.NoError(t, os.WriteFile(filepath.Join(relDir, "manifest.json"), []byte(manifest), 0644))
+
+	m, err := tc.Client.CreateImageFromOCI(ctx, "my-app", archive, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "6.1.155", m.Artifacts[arch].Kernel.Version)
+	assert.Equal(t, "oci", m.Artifacts[arch].Rootfs.Profile)
+
+	assert.NotNil(t, m.Local)
+
+	_, err = tc.Client.CreateImageFromOCI(ctx, "my-app", archive, nil)
+	assert.ErrorIs(t, err, lib.ErrAlreadyExists)
+}
+
+func TestImageReferences(t *testing.T) {
+	// installImageArch creates a minimal local image with a manifest for an architecture.
+	installImageArch := func(t *testing.T, tc testClientWithDataDir, name, arch string) {
+		t.Helper()
+		imgDir := filepath.Join(tc.DataDir, "images", name)
+		require.NoError(t, os.MkdirAll(imgDir, 0755))
+		manifest := fmt.Sprintf(`{"schema_version": 1, "version": %q, "artifacts": {%q: {"kernel": {"file": "vmlinux-%s"}, "rootfs": {"file": "rootfs-%s.ext4"}}}}`, name, arch, arch, arch)
+		require.NoError(t, os.WriteFile(filepath.Join(imgDir, "manifest.json"), []byte(manifest), 0644))
+	}
+
+	// installImage creates a minimal local image with a manifest for the host.
+	installImage := func(t *testing.T, tc testClientWithDataDir, name string) {
+		t.Helper()
+		installImageArch(t, tc, name, image.HostArch())
+	}
+
+	tests := map[string]struct {
+		run func(t *testing.T, tc testClientWithDataDir)
+	}{
+		"Removing an image used by a sandbox should fail.": {
+			run: func(t *testing.T, tc testClientWithDataDir) {
+				ctx := context.Background()
+				installImage(t, tc, "v0.1.0")
+				sb, err := tc.Client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+					Name:      "img-user",
+					Engine:    lib.EngineFake,
+					FromImage: "v0.1.0",
+					Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+				})
+				require.NoError(t, err)
+				assert.Equal(t, "v0.1.0", sb.Config.Image)
+
+				err = tc.Client.RemoveImage(ctx, "v0.1.0")
+				assert.ErrorIs(t, err, lib.ErrNotValid)
+
+				// Once the sandbox is removed the image can be removed.
+				_, err = tc.Client.RemoveSandbox(ctx, "img-user", false)
+				require.NoError(t, err)
+				assert.NoError(t, tc.Client.RemoveImage(ctx, "v0.1.0"))
+			},
+		},
+
+		"Creating from an image with boot, seccomp, egress interface and network options should keep them.": {
+			run: func(t *testing.T, tc testClientWithDataDir) {
+				ctx := context.Background()
+				installImage(t, tc, "v0.1.0")
+				_, err := tc.Client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+					Name:      "img-boot",
+					Engine:    lib.EngineFake,
+					FromImage: "v0.1.0",
+					Firecracker: &lib.FirecrackerConfig{
+						KernelArgs:      []string{"quiet"},
+						Boot:            lib.BootOptions{DisableConsole: true},
+						Seccomp:         lib.SeccompOptions{Mode: lib.SeccompModeDisabled},
+						EgressInterface: "wg0",
+						Network:         lib.NetworkOptions{MTU: 1380, ClampMSS: true},
+					},
+					Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+				})
+				require.NoError(t, err)
+
+				sb, err := tc.Client.GetSandbox(ctx, "img-boot")
+				require.NoError(t, err)
+				require.NotNil(t, sb.Config.Firecracker)
+				assert.Contains(t, sb.Config.Firecracker.KernelImage, filepath.Join(tc.DataDir, "images", "v0.1.0"))
+				assert.Equal(t, []string{"quiet"}, sb.Config.Firecracker.KernelArgs)
+				assert.Equal(t, lib.BootOptions{DisableConsole: true}, sb.Config.Firecracker.Boot)
+				assert.Equal(t, lib.SeccompOptions{Mode: lib.SeccompModeDisabled}, sb.Config.Firecracker.Seccomp)
+				assert.Equal(t, "wg0", sb.Config.Firecracker.EgressInterface)
+				assert.Equal(t, lib.NetworkOptions{MTU: 1380, ClampMSS: true}, sb.Config.Firecracker.Network)
+
+				_, err = tc.Client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+					Name:        "img-seccomp",
+					Engine:      lib.EngineFake,
+					FromImage:   "v0.1.0",
+					Firecracker: &lib.FirecrackerConfig{Seccomp: lib.SeccompOptions{Mode: lib.SeccompModeCustom}},
+					Resources:   lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+				})
+				assert.ErrorIs(t, err, lib.ErrNotValid)
+
+				_, err = tc.Client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+					Name:        "img-uplink",
+					Engine:      lib.EngineFake,
+					FromImage:   "v0.1.0",
+					Firecracker: &lib.FirecrackerConfig{EgressInterface: "eth0/1"},
+					Resources:   lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+				})
+				assert.ErrorIs(t, err, lib.ErrNotValid)
+
+				_, err = tc.Client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+					Name:        "img-mtu",
+					Engine:      lib.EngineFake,
+					FromImage:   "v0.1.0",
+					Firecracker: &lib.FirecrackerConfig{Network: lib.NetworkOptions{MTU: 100}},
+					Resources:   lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+				})
+				assert.ErrorIs(t, err, lib.ErrNotValid)
+
+				_, err = tc.Client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+					Name:        "img-paths",
+					Engine:      lib.EngineFake,
+					FromImage:   "v0.1.0",
+					Firecracker: &lib.FirecrackerConfig{KernelImage: "/tmp/vmlinux"},
+					Resources:   lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+				})
+				assert.ErrorIs(t, err, lib.ErrNotValid)
+			},
+		},
+
+		"Creating with a kernel profile should set its kernel args and boot options.": {
+			run: func(t *testing.T, tc testClientWithDataDir) {
+				ctx := context.Background()
+				arch := image.HostArch()
+				imgDir := filepath.Join(tc.DataDir, "images", "v0.1.0")
+				require.NoError(t, os.MkdirAll(imgDir, 0755))
+				manifest := fmt.Sprintf(`{"schema_version": 1, "version": "v0.1.0", "artifacts": {%q: {"kernel": {"file": "vmlinux-%s"}, "rootfs": {"file": "rootfs-%s.ext4"}}}, "profiles": [{"name": "tracing", "kernel_args": ["ftrace=function", "loglevel=7"]}]}`, arch, arch, arch)
+				require.NoError(t, os.WriteFile(filepath.Join(imgDir, "manifest.json"), []byte(manifest), 0644))
+
+				// The image profiles are listed with the built-in ones.
+				m, err := tc.Client.InspectImage(ctx, "v0.1.0")
+				require.NoError(t, err)
+				names := []string{}
+				for _, p := range m.Profiles {
+					names = append(names, p.Name)
+				}
+				assert.Equal(t, []string{lib.KernelProfileFastBoot, lib.KernelProfileDebug, lib.KernelProfileHardened, "tracing"}, names)
+
+				// The sandbox kernel args override the profile ones.
+				sb, err := tc.Client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+					Name:        "img-tracing",
+					Engine:      lib.EngineFake,
+					FromImage:   "v0.1.0",
+					Profile:     "tracing",
+					Firecracker: &lib.FirecrackerConfig{KernelArgs: []string{"loglevel=4"}},
+					Resources:   lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+				})
+				require.NoError(t, err)
+				assert.Equal(t, []string{"ftrace=function", "loglevel=4"}, sb.Config.Firecracker.KernelArgs)
+				assert.Equal(t, lib.BootOptions{Profile: "tracing"}, sb.Config.Firecracker.Boot)
+
+				// The built-in profiles can be used without image.
+				sb, err = tc.Client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+					Name:      "fast",
+					Engine:    lib.EngineFake,
+					Profile:   lib.KernelProfileFastBoot,
+					Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+				})
+				require.NoError(t, err)
+				assert.Equal(t, lib.BootOptions{Profile: lib.KernelProfileFastBoot, DisableConsole: true}, sb.Config.Firecracker.Boot)
+				assert.Contains(t, sb.Config.Firecracker.KernelArgs, "quiet")
+
+				// The same spec with the profile is the existing sandbox.
+				_, err = tc.Client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+					Name:        "fast",
+					Engine:      lib.EngineFake,
+					Profile:     lib.KernelProfileFastBoot,
+					Resources:   lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+					IfNotExists: true,
+				})
+				assert.NoError(t, err)
+
+				_, err = tc.Client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+					Name:      "missing-profile",
+					Engine:    lib.EngineFake,
+					FromImage: "v0.1.0",
+					Profile:   "missing",
+					Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+				})
+				assert.ErrorIs(t, err, lib.ErrNotValid)
+			},
+		},
+
+		"Creating from an image without the host architecture artifacts should fail.": {
+			run: func(t *testing.T, tc testClientWithDataDir) {
+				installImageArch(t, tc, "v0.1.0", "riscv64")
+				_, err := tc.Client.CreateSandbox(context.Background(), lib.CreateSandboxOpts{
+					Name:      "img-arch",
+					Engine:    lib.EngineFake,
+					FromImage: "v0.1.0",
+					Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+				})
+				assert.ErrorIs(t, err, lib.ErrNotValid)
+			},
+		},
+
+		"Rebasing a sandbox should move it to the new image.": {
+			run: func(t *testing.T, tc testClientWithDataDir) {
+				ctx := context.Background()
+				installImage(t, tc, "v0.1.0")
+				installImage(t, tc, "v0.2.0")
+				_, err := tc.Client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+					Name:      "img-rebase",
+					Engine:    lib.EngineFake,
+					FromImage: "v0.1.0",
+					Firecracker: &lib.FirecrackerConfig{
+						KernelArgs: []string{"quiet"},
+					},
+					Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+				})
+				require.NoError(t, err)
+
+				sb, err := tc.Client.RebaseSandbox(ctx, "img-rebase", "v0.2.0", &lib.RebaseSandboxOpts{PreservePaths: []string{"/root"}})
+				require.NoError(t, err)
+				assert.Equal(t, "v0.2.0", sb.Config.Image)
+
+				sb, err = tc.Client.GetSandbox(ctx, "img-rebase")
+				require.NoError(t, err)
+				assert.Equal(t, "v0.2.0", sb.Config.Image)
+				require.NotNil(t, sb.Config.Firecracker)
+				assert.Contains(t, sb.Config.Firecracker.RootFS, filepath.Join(tc.DataDir, "images", "v0.2.0"))
+				assert.Equal(t, []string{"quiet"}, sb.Config.Firecracker.KernelArgs)
+
+				// The old image is not used anymore.
+				assert.NoError(t, tc.Client.RemoveImage(ctx, "v0.1.0"))
+
+				_, err = tc.Client.RebaseSandbox(ctx, "img-rebase", "v9.9.9", nil)
+				assert.ErrorIs(t, err, lib.ErrNotFound)
+				_, err = tc.Client.RebaseSandbox(ctx, "img-rebase", "v0.2.0", &lib.RebaseSandboxOpts{PreservePaths: []string{"root"}})
+				assert.ErrorIs(t, err, lib.ErrNotValid)
+
+				_, err = tc.Client.StartSandbox(ctx, "img-rebase", nil)
+				require.NoError(t, err)
+				_, err = tc.Client.RebaseSandbox(ctx, "img-rebase", "v0.2.0", nil)
+				assert.ErrorIs(t, err, lib.ErrNotValid)
+			},
+		},
+
+		"Pruning images should only remove unused images.": {
+			run: func(t *testing.T, tc testClientWithDataDir) {
+				ctx := context.Background()
+				installImage(t, tc, "v0.1.0")
+				installImage(t, tc, "v0.2.0")
+				_, err := tc.Client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+					Name:      "img-user",
+					Engine:    lib.EngineFake,
+					FromImage: "v0.2.0",
+					Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+				})
+				require.NoError(t, err)
+
+				pruned, err := tc.Client.PruneImages(ctx, &lib.PruneImagesOpts{DryRun: true})
+				require.NoError(t, err)
+				assert.Equal(t, []string{"v0.1.0"}, pruned)
+
+				pruned, err = tc.Client.PruneImages(ctx, &lib.PruneImagesOpts{OlderThan: time.Hour})
+				require.NoError(t, err)
+				assert.Empty(t, pruned)
+
+				pruned, err = tc.Client.PruneImages(ctx, nil)
+				require.NoError(t, err)
+				assert.Equal(t, []string{"v0.1.0"}, pruned)
+				assert.NoDirExists(t, filepath.Join(tc.DataDir, "images", "v0.1.0"))
+				assert.DirExists(t, filepath.Join(tc.DataDir, "images", "v0.2.0"))
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test.run(t, newTestClientWithDataDir(t))
+		})
+	}
+}
+
+func TestForward(t *testing.T) {
+	t.Run("Forwarding with empty ports should fail.", func(t *testing.T) {
+		assert := assert.New(t)
+		client := newTestClient(t)
+		ctx := context.Background()
+
+		sb, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+			Name:      "fwd-empty",
+			Engine:    lib.EngineFake,
+			Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
 		})
 		require.NoError(t, err)
 		_, err = client.StartSandbox(ctx, sb.Name, nil)
@@ -976,39 +2205,2296 @@ func TestForward(t *testing.T) {
 	})
 }
 
-func TestDoctor(t *testing.T) {
-	assert := assert.New(t)
-	client := newTestClient(t) // Uses EngineFake.
-	ctx := context.Background()
+func TestForwardWithInfo(t *testing.T) {
+	t.Run("A free local port should be reported once ready.", func(t *testing.T) {
+		assert := assert.New(t)
+		client := newTestClient(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
 
-	// Doctor with fake engine should return empty results.
-	results, err := client.Doctor(ctx)
-	assert.NoError(err)
-	assert.NotNil(results)
-	assert.Len(results, 0)
+		_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+			Name:      "fwd-free",
+			Engine:    lib.EngineFake,
+			Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+		})
+		require.NoError(t, err)
+		_, err = client.StartSandbox(ctx, "fwd-free", nil)
+		require.NoError(t, err)
+
+		ready := make(chan lib.ForwardInfo, 1)
+		done := make(chan error, 1)
+		go func() {
+			done <- client.ForwardWithInfo(ctx, "fwd-free", []lib.PortMapping{{BindAddress: "127.0.0.1", RemotePort: 3000}}, func(info lib.ForwardInfo) {
+				ready <- info
+			})
+		}()
+
+		select {
+		case info := <-ready:
+			require.Len(t, info.Ports, 1)
+			assert.NotZero(info.Ports[0].LocalPort)
+			assert.Equal(3000, info.Ports[0].RemotePort)
+		case err := <-done:
+			t.Fatalf("forward finished before being ready: %v", err)
+		}
+
+		cancel()
+		assert.NoError(<-done)
+	})
+
+	t.Run("A TLS port without certificate should use the generated one.", func(t *testing.T) {
+		assert := assert.New(t)
+		tc := newTestClientWithDataDir(t)
+		client := tc.Client
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+			Name:      "fwd-tls",
+			Engine:    lib.EngineFake,
+			Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+		})
+		require.NoError(t, err)
+		_, err = client.StartSandbox(ctx, "fwd-tls", nil)
+		require.NoError(t, err)
+
+		ready := make(chan lib.ForwardInfo, 1)
+		done := make(chan error, 1)
+		go func() {
+			ports := []lib.PortMapping{{BindAddress: "127.0.0.1", RemotePort: 3000, TLS: &lib.ForwardTLS{}}}
+			done <- client.ForwardWithInfo(ctx, "fwd-tls", ports, func(info lib.ForwardInfo) { ready <- info })
+		}()
+
+		select {
+		case info := <-ready:
+			require.Len(t, info.Ports, 1)
+			assert.Equal(&lib.ForwardTLS{
+				CertFile: filepath.Join(tc.DataDir, "tls", "localhost.crt"),
+				KeyFile:  filepath.Join(tc.DataDir, "tls", "localhost.key"),
+			}, info.Ports[0].TLS)
+		case err := <-done:
+			t.Fatalf("forward finished before being ready: %v", err)
+		}
+
+		cancel()
+		assert.NoError(<-done)
+	})
+
+	t.Run("Forwarding to a non-running sandbox should fail without being ready.", func(t *testing.T) {
+		assert := assert.New(t)
+		client := newTestClient(t)
+
+		_, err := client.CreateSandbox(context.Background(), lib.CreateSandboxOpts{
+			Name:      "fwd-free-stopped",
+			Engine:    lib.EngineFake,
+			Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+		})
+		require.NoError(t, err)
+
+		called := false
+		err = client.ForwardWithInfo(context.Background(), "fwd-free-stopped", []lib.PortMapping{{RemotePort: 3000}}, func(lib.ForwardInfo) { called = true })
+		assert.True(errors.Is(err, lib.ErrNotValid), "expected ErrNotValid, got: %v", err)
+		assert.False(called)
+	})
 }
 
-func TestResourcesPreserved(t *testing.T) {
-	assert := assert.New(t)
-	require := require.New(t)
-	client := newTestClient(t)
-	ctx := context.Background()
+func TestExposeHTTP(t *testing.T) {
+	t.Run("Exposed sandboxes should share the ingress and be exposed once.", func(t *testing.T) {
+		assert := assert.New(t)
+		client := newTestClient(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		for _, name := range []string{"web", "api"} {
+			_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+				Name:      name,
+				Engine:    lib.EngineFake,
+				Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+			})
+			require.NoError(t, err)
+			_, err = client.StartSandbox(ctx, name, nil)
+			require.NoError(t, err)
+		}
+
+		opts := func(urls chan string) lib.ExposeHTTPOpts {
+			return lib.ExposeHTTPOpts{Address: "127.0.0.1:0", OnReady: func(url string) { urls <- url }}
+		}
+		expose := func(name string) (string, chan error) {
+			urls := make(chan string, 1)
+			done := make(chan error, 1)
+			go func() { done <- client.ExposeHTTP(ctx, name, 3000, opts(urls)) }()
+			select {
+			case url := <-urls:
+				return url, done
+			case err := <-done:
+				t.Fatalf("expose finished before being ready: %v", err)
+				return "", nil
+			}
+		}
 
-	sb, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
-		Name:   "resources",
-		Engine: lib.EngineFake,
-		Resources: lib.Resources{
-			VCPUs:    1.5,
-			MemoryMB: 2048,
-			DiskGB:   20,
-		},
+		webURL, webDone := expose("web")
+		apiURL, apiDone := expose("api")
+		assert.Regexp(`^http://web\.localhost:\d+$`, webURL)
+		assert.Equal(strings.Replace(webURL, "web.", "api.", 1), apiURL)
+
+		err := client.ExposeHTTP(ctx, "web", 3000, lib.ExposeHTTPOpts{Address: "127.0.0.1:0"})
+		assert.True(errors.Is(err, lib.ErrAlreadyExists), "expected ErrAlreadyExists, got: %v", err)
+
+		err = client.ExposeHTTP(ctx, "api", 3000, lib.ExposeHTTPOpts{Address: "127.0.0.1:0", TLS: &lib.ForwardTLS{}})
+		assert.True(errors.Is(err, lib.ErrNotValid), "expected ErrNotValid, got: %v", err)
+
+		cancel()
+		assert.NoError(<-webDone)
+		assert.NoError(<-apiDone)
 	})
-	require.NoError(err)
 
-	got, err := client.GetSandbox(ctx, sb.Name)
-	require.NoError(err)
+	t.Run("An ingress with TLS should serve https.", func(t *testing.T) {
+		assert := assert.New(t)
+		client := newTestClient(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
 
-	assert.Equal(1.5, got.Config.Resources.VCPUs)
-	assert.Equal(2048, got.Config.Resources.MemoryMB)
-	assert.Equal(20, got.Config.Resources.DiskGB)
+		_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+			Name:      "secure",
+			Engine:    lib.EngineFake,
+			Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+		})
+		require.NoError(t, err)
+		_, err = client.StartSandbox(ctx, "secure", nil)
+		require.NoError(t, err)
+
+		urls := make(chan string, 1)
+		done := make(chan error, 1)
+		go func() {
+			done <- client.ExposeHTTP(ctx, "secure", 3000, lib.ExposeHTTPOpts{
+				Address: "127.0.0.1:0",
+				TLS:     &lib.ForwardTLS{},
+				OnReady: func(url string) { urls <- url },
+			})
+		}()
+
+		select {
+		case url := <-urls:
+			assert.Regexp(`^https://secure\.localhost:\d+$`, url)
+		case err := <-done:
+			t.Fatalf("expose finished before being ready: %v", err)
+		}
+
+		cancel()
+		assert.NoError(<-done)
+	})
+
+	t.Run("Exposing a non-running sandbox should fail.", func(t *testing.T) {
+		assert := assert.New(t)
+		client := newTestClient(t)
+
+		_, err := client.CreateSandbox(context.Background(), lib.CreateSandboxOpts{
+			Name:      "stopped",
+			Engine:    lib.EngineFake,
+			Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+		})
+		require.NoError(t, err)
+
+		err = client.ExposeHTTP(context.Background(), "stopped", 3000, lib.ExposeHTTPOpts{Address: "127.0.0.1:0"})
+		assert.True(errors.Is(err, lib.ErrNotValid), "expected ErrNotValid, got: %v", err)
+	})
+
+	t.Run("Exposing a sandbox without a hostname name should fail.", func(t *testing.T) {
+		assert := assert.New(t)
+		client := newTestClient(t)
+		ctx := context.Background()
+
+		_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+			Name:      "my_sandbox",
+			Engine:    lib.EngineFake,
+			Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+		})
+		require.NoError(t, err)
+		_, err = client.StartSandbox(ctx, "my_sandbox", nil)
+		require.NoError(t, err)
+
+		err = client.ExposeHTTP(ctx, "my_sandbox", 3000, lib.ExposeHTTPOpts{Address: "127.0.0.1:0"})
+		assert.True(errors.Is(err, lib.ErrNotValid), "expected ErrNotValid, got: %v", err)
+	})
+}
+
+func TestExecProfile(t *testing.T) {
+	t.Run("The exec profile should be stored with the sandbox.", func(t *testing.T) {
+		assert := assert.New(t)
+		client := newTestClient(t)
+
+		profile := &lib.ExecProfile{
+			PathPrepend: []string{"/opt/tools/bin"},
+			Locale:      "C.UTF-8",
+			Umask:       "027",
+			Ulimits:     map[string]string{"nofile": "65536"},
+			Shell:       "/bin/bash",
+		}
+		sb, err := client.CreateSandbox(context.Background(), lib.CreateSandboxOpts{
+			Name:        "exec-profile",
+			Engine:      lib.EngineFake,
+			Resources:   lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+			ExecProfile: profile,
+		})
+		require.NoError(t, err)
+		assert.Equal(profile, sb.Config.ExecProfile)
+
+		got, err := client.GetSandbox(context.Background(), "exec-profile")
+		require.NoError(t, err)
+		assert.Equal(profile, got.Config.ExecProfile)
+	})
+
+	t.Run("Creating a sandbox with an invalid exec profile should fail.", func(t *testing.T) {
+		assert := assert.New(t)
+		client := newTestClient(t)
+
+		_, err := client.CreateSandbox(context.Background(), lib.CreateSandboxOpts{
+			Name:        "exec-profile-invalid",
+			Engine:      lib.EngineFake,
+			Resources:   lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+			ExecProfile: &lib.ExecProfile{Ulimits: map[string]string{"files": "1024"}},
+		})
+		assert.True(errors.Is(err, lib.ErrNotValid), "expected ErrNotValid, got: %v", err)
+	})
+}
+
+func TestForwardNamed(t *testing.T) {
+	t.Run("Named ports should be stored with the sandbox.", func(t *testing.T) {
+		assert := assert.New(t)
+		client := newTestClient(t)
+
+		sb, err := client.CreateSandbox(context.Background(), lib.CreateSandboxOpts{
+			Name:      "fwd-named",
+			Engine:    lib.EngineFake,
+			Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+			Ports:     map[string]int{"web": 3000, "db": 5432},
+		})
+		require.NoError(t, err)
+		assert.Equal(map[string]int{"web": 3000, "db": 5432}, sb.Config.Ports)
+
+		got, err := client.GetSandbox(context.Background(), "fwd-named")
+		require.NoError(t, err)
+		assert.Equal(map[string]int{"web": 3000, "db": 5432}, got.Config.Ports)
+	})
+
+	t.Run("Creating a sandbox with an invalid port name should fail.", func(t *testing.T) {
+		assert := assert.New(t)
+		client := newTestClient(t)
+
+		_, err := client.CreateSandbox(context.Background(), lib.CreateSandboxOpts{
+			Name:      "fwd-invalid",
+			Engine:    lib.EngineFake,
+			Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+			Ports:     map[string]int{"Web": 3000},
+		})
+		assert.True(errors.Is(err, lib.ErrNotValid), "expected ErrNotValid, got: %v", err)
+	})
+
+	t.Run("Forwarding a missing named port should fail.", func(t *testing.T) {
+		assert := assert.New(t)
+		client := newTestClient(t)
+		ctx := context.Background()
+
+		_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+			Name:      "fwd-missing",
+			Engine:    lib.EngineFake,
+			Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+			Ports:     map[string]int{"web": 3000},
+		})
+		require.NoError(t, err)
+		_, err = client.StartSandbox(ctx, "fwd-missing", nil)
+		require.NoError(t, err)
+
+		err = client.ForwardNamed(ctx, "fwd-missing", "db", 0)
+		assert.True(errors.Is(err, lib.ErrNotValid), "expected ErrNotValid, got: %v", err)
+	})
+
+	t.Run("Forwarding to a non-existent sandbox should fail.", func(t *testing.T) {
+		assert := assert.New(t)
+		client := newTestClient(t)
+
+		err := client.ForwardNamed(context.Background(), "ghost", "web", 0)
+		assert.True(errors.Is(err, lib.ErrNotFound), "expected ErrNotFound, got: %v", err)
+	})
+}
+
+func TestDoctor(t *testing.T) {
+	assert := assert.New(t)
+	client := newTestClient(t) // Uses EngineFake.
+	ctx := context.Background()
+
+	// Doctor with fake engine should return empty results.
+	results, err := client.Doctor(ctx)
+	assert.NoError(err)
+	assert.NotNil(results)
+	assert.Len(results, 0)
+}
+
+func TestCheckStatusSeverity(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(lib.CheckSeverityPass, lib.CheckStatusOK.Severity())
+	assert.Equal(lib.CheckSeverityWarn, lib.CheckStatusWarning.Severity())
+	assert.Equal(lib.CheckSeverityFail, lib.CheckStatusError.Severity())
+}
+
+func TestEngineCapabilities(t *testing.T) {
+	tests := map[string]struct {
+		engine  lib.EngineType
+		expCaps lib.EngineCapabilities
+	}{
+		"The fake engine should return the simulated features.": {
+			engine:  lib.EngineFake,
+			expCaps: lib.EngineCapabilities{Engine: lib.EngineFake, LiveSnapshots: true, PortForward: true, MemoryBalloon: true, Scaling: true, EngineAPI: true},
+		},
+		"The firecracker engine should return its features.": {
+			engine: lib.EngineFirecracker,
+			expCaps: lib.EngineCapabilities{
+				Engine:        lib.EngineFirecracker,
+				Snapshots:     true,
+				LiveSnapshots: true,
+				Egress:        true,
+				Networks:      true,
+				PortForward:   true,
+				MemoryBalloon: true,
+				Scaling:       true,
+				EngineAPI:     true,
+				Tty:           true,
+			},
+		},
+		"An auto-detected engine should return the firecracker features.": {
+			expCaps: lib.EngineCapabilities{
+				Engine:        lib.EngineFirecracker,
+				Snapshots:     true,
+				LiveSnapshots: true,
+				Egress:        true,
+				Networks:      true,
+				PortForward:   true,
+				MemoryBalloon: true,
+				Scaling:       true,
+				EngineAPI:     true,
+				Tty:           true,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			dataDir := t.TempDir()
+			client, err := lib.New(context.Background(), lib.Config{
+				DataDir: dataDir,
+				Engine:  test.engine,
+			})
+			require.NoError(err)
+			defer client.Close()
+
+			caps, err := client.EngineCapabilities(context.Background())
+			require.NoError(err)
+			assert.Equal(t, test.expCaps, *caps)
+		})
+	}
+}
+
+func TestResourcesPreserved(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	sb, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:   "resources",
+		Engine: lib.EngineFake,
+		Resources: lib.Resources{
+			VCPUs:    1.5,
+			MemoryMB: 2048,
+			DiskGB:   20,
+		},
+	})
+	require.NoError(err)
+
+	got, err := client.GetSandbox(ctx, sb.Name)
+	require.NoError(err)
+
+	assert.Equal(1.5, got.Config.Resources.VCPUs)
+	assert.Equal(2048, got.Config.Resources.MemoryMB)
+	assert.Equal(20, got.Config.Resources.DiskGB)
+}
+
+func TestHostCapacity(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"cap-1", "cap-2"} {
+		_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+			Name:      name,
+			Engine:    lib.EngineFake,
+			Resources: lib.Resources{VCPUs: 1.5, MemoryMB: 1024, DiskGB: 5},
+		})
+		require.NoError(err)
+	}
+	_, err := client.StartSandbox(ctx, "cap-1", nil)
+	require.NoError(err)
+
+	hc, err := client.HostCapacity(ctx)
+	require.NoError(err)
+	assert.Equal(2, hc.Sandboxes)
+	assert.Equal(1, hc.RunningSandboxes)
+	assert.Equal(lib.Resources{VCPUs: 1.5, MemoryMB: 1024, DiskGB: 10}, hc.Allocated)
+	assert.Equal(lib.OvercommitRatios{VCPUs: 4, Memory: 1, Disk: 1}, hc.Overcommit)
+	assert.Positive(hc.Total.VCPUs)
+	assert.Equal(hc.Total.VCPUs*4, hc.Allocatable.VCPUs)
+	assert.Equal(hc.Allocatable.MemoryMB-1024, hc.Free.MemoryMB)
+}
+
+func TestReservations(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	tc := newTestClientWithDataDir(t)
+	client := tc.Client
+	ctx := context.Background()
+
+	res := lib.Resources{VCPUs: 2, MemoryMB: 1024, DiskGB: 5}
+	var ids []string
+	for _, name := range []string{"res-1", "res-2"} {
+		sb, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{Name: name, Engine: lib.EngineFake, Resources: res})
+		require.NoError(err)
+		ids = append(ids, sb.ID)
+	}
+	_, err := client.StartSandbox(ctx, "res-1", nil)
+	require.NoError(err)
+
+	// The manifests should follow the sandbox changes.
+	manifest := func(id string) map[string]any {
+		data, err := os.ReadFile(filepath.Join(tc.DataDir, "reservations", id+".json"))
+		require.NoError(err)
+		var m map[string]any
+		require.NoError(json.Unmarshal(data, &m))
+		return m
+	}
+	assert.Equal("running", manifest(ids[0])["status"])
+	assert.Equal(map[string]any{"vcpus": float64(2), "memory_mb": float64(1024), "disk_gb": float64(5)}, manifest(ids[0])["reserved"])
+	assert.Equal(map[string]any{"vcpus": float64(0), "memory_mb": float64(0), "disk_gb": float64(5)}, manifest(ids[1])["reserved"])
+
+	_, err = client.RemoveSandbox(ctx, "res-2", true)
+	require.NoError(err)
+	_, err = os.Stat(filepath.Join(tc.DataDir, "reservations", ids[1]+".json"))
+	assert.ErrorIs(err, os.ErrNotExist)
+
+	rs, err := client.Reservations(ctx)
+	require.NoError(err)
+	require.Len(rs, 1)
+	assert.Equal(ids[0], rs[0].SandboxID)
+	assert.Equal("res-1", rs[0].Name)
+	assert.Equal(lib.SandboxStatusRunning, rs[0].Status)
+	assert.Equal(res, rs[0].Resources)
+	assert.Equal(res, rs[0].Reserved)
+}
+
+func TestTestEgressPolicy(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	policy := lib.EgressPolicy{
+		Default: lib.EgressActionDeny,
+		Rules: []lib.EgressRule{
+			{Domain: "evil.github.com", Action: lib.EgressActionDeny},
+			{Domain: "*.github.com", Action: lib.EgressActionAllow},
+		},
+	}
+
+	results, err := client.TestEgressPolicy(ctx, policy, []string{"https://api.github.com/repos", "example.com", "10.0.0.1"})
+	require.NoError(err)
+	require.Len(results, 3)
+
+	assert.Equal(lib.EgressTestResult{
+		Target:    "https://api.github.com/repos",
+		Domain:    "api.github.com",
+		Action:    lib.EgressActionAllow,
+		Reason:    lib.EgressTestReasonRule,
+		RuleIndex: 1,
+		Rule:      &lib.EgressRule{Domain: "*.github.com", Action: lib.EgressActionAllow},
+	}, results[0])
+	assert.Equal(lib.EgressActionDeny, results[1].Action)
+	assert.Equal(lib.EgressTestReasonDefault, results[1].Reason)
+	assert.Equal(lib.EgressTestReasonIPAddress, results[2].Reason)
+
+	_, err = client.TestEgressPolicy(ctx, policy, nil)
+	assert.ErrorIs(err, lib.ErrNotValid)
+}
+
+func TestPolicies(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	policy := lib.EgressPolicy{
+		Default: lib.EgressActionDeny,
+		Rules:   []lib.EgressRule{{Domain: "*.github.com", Action: lib.EgressActionAllow}},
+	}
+	created, err := client.CreatePolicy(ctx, lib.CreatePolicyOpts{Name: "github", Policy: policy})
+	require.NoError(err)
+	assert.Equal("github", created.Name)
+	assert.Equal(policy, created.Policy)
+
+	_, err = client.CreatePolicy(ctx, lib.CreatePolicyOpts{Name: "github", Policy: policy})
+	assert.ErrorIs(err, lib.ErrAlreadyExists)
+	_, err = client.CreatePolicy(ctx, lib.CreatePolicyOpts{Name: "bad name", Policy: policy})
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = client.UpdatePolicy(ctx, "github", lib.EgressPolicy{Default: lib.EgressActionAllow, DNSUpstreams: []string{"dns.example.com"}})
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	newPolicy := lib.EgressPolicy{Default: lib.EgressActionAllow, DNSUpstreams: []string{"10.0.0.53", "tls://dns.example.com"}}
+	updated, err := client.UpdatePolicy(ctx, "github", newPolicy)
+	require.NoError(err)
+	assert.Equal(newPolicy, updated.Policy)
+	assert.Equal(created.CreatedAt, updated.CreatedAt)
+
+	policies, err := client.ListPolicies(ctx)
+	require.NoError(err)
+	require.Len(policies, 1)
+	assert.Equal("github", policies[0].Name)
+	assert.Equal(newPolicy, policies[0].Policy)
+
+	// Sandboxes start with the policies by name.
+	_, err = client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "pol-1",
+		Engine:    lib.EngineFake,
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	})
+	require.NoError(err)
+	_, err = client.StartSandbox(ctx, "pol-1", &lib.StartSandboxOpts{EgressPolicyName: "missing"})
+	assert.ErrorIs(err, lib.ErrNotFound)
+	_, err = client.StartSandbox(ctx, "pol-1", &lib.StartSandboxOpts{Egress: &policy, EgressPolicyName: "github"})
+	assert.ErrorIs(err, lib.ErrNotValid)
+	_, err = client.StartSandbox(ctx, "pol-1", &lib.StartSandboxOpts{EgressPolicyName: "github"})
+	require.NoError(err)
+
+	require.NoError(client.DeletePolicy(ctx, "github"))
+	err = client.DeletePolicy(ctx, "github")
+	assert.ErrorIs(err, lib.ErrNotFound)
+	var sbxErr *lib.Error
+	require.ErrorAs(err, &sbxErr)
+	assert.Equal(lib.ResourceKindEgressPolicy, sbxErr.Kind)
+}
+
+func TestTasks(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	sb, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "tasks-1",
+		Engine:    lib.EngineFake,
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	})
+	require.NoError(err)
+
+	global, err := client.RegisterTask(ctx, "", lib.Task{Name: "test", Command: []string{"go", "test"}, WorkingDir: "/workspace", Timeout: time.Minute})
+	require.NoError(err)
+	assert.Empty(global.SandboxID)
+	assert.False(global.CreatedAt.IsZero())
+	_, err = client.RegisterTask(ctx, "", lib.Task{Name: "lint", Command: []string{"golangci-lint", "run"}})
+	require.NoError(err)
+	own, err := client.RegisterTask(ctx, "tasks-1", lib.Task{Name: "lint", Command: []string{"make", "lint"}})
+	require.NoError(err)
+	assert.Equal(sb.ID, own.SandboxID)
+
+	_, err = client.RegisterTask(ctx, "", lib.Task{Name: "bad name", Command: []string{"ls"}})
+	assert.ErrorIs(err, lib.ErrNotValid)
+	_, err = client.RegisterTask(ctx, "missing", lib.Task{Name: "lint", Command: []string{"ls"}})
+	assert.ErrorIs(err, lib.ErrNotFound)
+
+	tasks, err := client.ListTasks(ctx, "")
+	require.NoError(err)
+	require.Len(tasks, 2)
+	assert.Equal([]string{"golangci-lint", "run"}, tasks[0].Command)
+
+	// The sandbox tasks override the global ones.
+	tasks, err = client.ListTasks(ctx, "tasks-1")
+	require.NoError(err)
+	require.Len(tasks, 2)
+	assert.Equal([]string{"make", "lint"}, tasks[0].Command)
+	assert.Equal("test", tasks[1].Name)
+
+	_, err = client.StartSandbox(ctx, "tasks-1", nil)
+	require.NoError(err)
+
+	res, err := client.RunTask(ctx, "tasks-1", "test", &lib.RunTaskOpts{Args: []string{"./..."}})
+	require.NoError(err)
+	assert.Equal(0, res.ExitCode)
+	_, err = client.RunTask(ctx, "tasks-1", "lint", &lib.RunTaskOpts{ExecOpts: lib.ExecOpts{WorkingDir: "/src"}})
+	require.NoError(err)
+	_, err = client.RunTask(ctx, "tasks-1", "missing", nil)
+	assert.ErrorIs(err, lib.ErrNotFound)
+
+	records, err := client.ExecHistory(ctx, "tasks-1", nil)
+	require.NoError(err)
+	require.Len(records, 2)
+	assert.Equal([]string{"go", "test", "./..."}, records[0].Command)
+	assert.Equal("/workspace", records[0].WorkingDir)
+	assert.Equal([]string{"make", "lint"}, records[1].Command)
+	assert.Equal("/src", records[1].WorkingDir)
+
+	require.NoError(client.RemoveTask(ctx, "tasks-1", "lint"))
+	err = client.RemoveTask(ctx, "tasks-1", "lint")
+	assert.ErrorIs(err, lib.ErrNotFound)
+	var sbxErr *lib.Error
+	require.ErrorAs(err, &sbxErr)
+	assert.Equal(lib.ResourceKindTask, sbxErr.Kind)
+
+	// The global task is used once the sandbox one is removed.
+	tasks, err = client.ListTasks(ctx, "tasks-1")
+	require.NoError(err)
+	require.Len(tasks, 2)
+	assert.Equal([]string{"golangci-lint", "run"}, tasks[0].Command)
+}
+
+func TestTemplates(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	template := lib.SandboxTemplate{
+		Name:        "dev",
+		Description: "Development sandbox",
+		Engine:      lib.EngineFake,
+		Resources:   lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+		Ports:       map[string]int{"web": 8080},
+		ExecProfile: &lib.ExecProfile{Locale: "C.UTF-8"},
+		Env:         map[string]string{"APP_ENV": "dev"},
+		Egress:      &lib.EgressPolicy{Default: lib.EgressActionAllow},
+	}
+	created, err := client.CreateTemplate(ctx, template)
+	require.NoError(err)
+	assert.Equal("dev", created.Name)
+	assert.False(created.CreatedAt.IsZero())
+
+	_, err = client.CreateTemplate(ctx, template)
+	assert.ErrorIs(err, lib.ErrAlreadyExists)
+	_, err = client.CreateTemplate(ctx, lib.SandboxTemplate{Name: "fc", Engine: lib.EngineFirecracker, Resources: template.Resources})
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	got, err := client.GetTemplate(ctx, "dev")
+	require.NoError(err)
+	assert.Equal(*created, *got)
+
+	templates, err := client.ListTemplates(ctx)
+	require.NoError(err)
+	require.Len(templates, 1)
+	assert.Equal("dev", templates[0].Name)
+
+	// The sandboxes get the template spec.
+	sb, err := client.CreateSandboxFromTemplate(ctx, "dev", lib.CreateSandboxFromTemplateOpts{Name: "tpl-1"})
+	require.NoError(err)
+	assert.Equal(lib.SandboxStatusStopped, sb.Status)
+	assert.Equal(template.Resources, sb.Config.Resources)
+	assert.Equal(template.Ports, sb.Config.Ports)
+	assert.Equal(template.ExecProfile, sb.Config.ExecProfile)
+
+	_, err = client.CreateSandboxFromTemplate(ctx, "dev", lib.CreateSandboxFromTemplateOpts{Name: "tpl-2", Env: map[string]string{"A": "b"}})
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	sb, err = client.CreateSandboxFromTemplate(ctx, "dev", lib.CreateSandboxFromTemplateOpts{Name: "tpl-2", Start: true, Env: map[string]string{"DEBUG": "1"}})
+	require.NoError(err)
+	assert.Equal(lib.SandboxStatusRunning, sb.Status)
+
+	_, err = client.CreateSandboxFromTemplate(ctx, "missing", lib.CreateSandboxFromTemplateOpts{Name: "tpl-3"})
+	assert.ErrorIs(err, lib.ErrNotFound)
+
+	// Removing the template keeps its sandboxes.
+	require.NoError(client.DeleteTemplate(ctx, "dev"))
+	err = client.DeleteTemplate(ctx, "dev")
+	assert.ErrorIs(err, lib.ErrNotFound)
+	var sbxErr *lib.Error
+	require.ErrorAs(err, &sbxErr)
+	assert.Equal(lib.ResourceKindTemplate, sbxErr.Kind)
+	_, err = client.GetSandbox(ctx, "tpl-1")
+	assert.NoError(err)
+}
+
+func TestProgressReporter(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	var events []lib.ProgressEvent
+	progress := lib.ProgressReporterFunc(func(ev lib.ProgressEvent) { events = append(events, ev) })
+
+	_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "progress-1",
+		Engine:    lib.EngineFake,
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+		Progress:  progress,
+	})
+	require.NoError(err)
+	assert.Equal([]lib.ProgressEvent{
+		{Step: "create", Percent: 0, Message: "Creating the fake sandbox"},
+	}, events)
+
+	events = nil
+	_, err = client.StartSandbox(ctx, "progress-1", &lib.StartSandboxOpts{Progress: progress})
+	require.NoError(err)
+	assert.Equal([]lib.ProgressEvent{
+		{Step: "boot", Percent: 0, Message: "Booting the fake sandbox"},
+		{Step: "session_env", Percent: -1, Message: "Applying the session environment"},
+	}, events)
+}
+
+func TestStartTimeoutsConfig(t *testing.T) {
+	_, err := lib.New(context.Background(), lib.Config{
+		DBPath:        filepath.Join(t.TempDir(), "test.db"),
+		DataDir:       t.TempDir(),
+		Engine:        lib.EngineFake,
+		StartTimeouts: lib.StartTimeouts{SSHRetries: -1},
+	})
+	assert.ErrorIs(t, err, lib.ErrNotValid)
+}
+
+func TestRetryConfig(t *testing.T) {
+	newClient := func(retry lib.RetryPolicies) error {
+		c, err := lib.New(context.Background(), lib.Config{
+			DBPath:  filepath.Join(t.TempDir(), "test.db"),
+			DataDir: t.TempDir(),
+			Engine:  lib.EngineFake,
+			Retry:   retry,
+		})
+		if err == nil {
+			c.Close()
+		}
+		return err
+	}
+
+	assert.NoError(t, newClient(lib.RetryPolicies{Nftables: lib.RetryPolicy{Attempts: 10, InitialBackoff: 10 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2, Jitter: 0.5}}))
+	assert.ErrorIs(t, newClient(lib.RetryPolicies{SSHDial: lib.RetryPolicy{Attempts: -1}}), lib.ErrNotValid)
+	assert.ErrorIs(t, newClient(lib.RetryPolicies{FirecrackerAPI: lib.RetryPolicy{Multiplier: 0.5}}), lib.ErrNotValid)
+	assert.ErrorIs(t, newClient(lib.RetryPolicies{Nftables: lib.RetryPolicy{Jitter: 2}}), lib.ErrNotValid)
+}
+
+func TestDNSUpstreamsConfig(t *testing.T) {
+	tests := map[string]struct {
+		upstreams []string
+		expErr    error
+	}{
+		"Plain, DoT and DoH upstreams should be valid.": {
+			upstreams: []string{"1.1.1.1", "10.0.0.53:5353", "tls://dns.quad9.net", "https://cloudflare-dns.com/dns-query"},
+		},
+
+		"A plain upstream with a hostname should fail.": {
+			upstreams: []string{"dns.example.com"},
+			expErr:    lib.ErrNotValid,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			client, err := lib.New(context.Background(), lib.Config{
+				DBPath:       filepath.Join(t.TempDir(), "test.db"),
+				DataDir:      t.TempDir(),
+				Engine:       lib.EngineFake,
+				DNSUpstreams: test.upstreams,
+			})
+			if err == nil {
+				_ = client.Close()
+			}
+
+			if test.expErr != nil {
+				assert.ErrorIs(t, err, test.expErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestEventSinks(t *testing.T) {
+	tests := map[string]struct {
+		sinks    func(dir string) []lib.EventSink
+		expTypes []lib.EventType
+		expErr   error
+	}{
+		"The lifecycle events should be sent to the file sink.": {
+			sinks: func(dir string) []lib.EventSink {
+				return []lib.EventSink{{Type: lib.EventSinkFile, Path: filepath.Join(dir, "events.jsonl")}}
+			},
+			expTypes: []lib.EventType{
+				lib.EventSandboxCreated,
+				lib.EventSandboxStarted,
+				lib.EventSandboxStopped,
+				lib.EventSandboxRemoved,
+			},
+		},
+
+		"Only the sink event types should be sent to the sink.": {
+			sinks: func(dir string) []lib.EventSink {
+				return []lib.EventSink{{
+					Type:   lib.EventSinkFile,
+					Path:   filepath.Join(dir, "events.jsonl"),
+					Events: []lib.EventType{lib.EventSandboxStarted, lib.EventSandboxStopped},
+				}}
+			},
+			expTypes: []lib.EventType{
+				lib.EventSandboxStarted,
+				lib.EventSandboxStopped,
+			},
+		},
+
+		"A file sink with a relative path should fail.": {
+			sinks: func(dir string) []lib.EventSink {
+				return []lib.EventSink{{Type: lib.EventSinkFile, Path: "events.jsonl"}}
+			},
+			expErr: lib.ErrNotValid,
+		},
+
+		"A webhook sink without URL should fail.": {
+			sinks: func(dir string) []lib.EventSink {
+				return []lib.EventSink{{Type: lib.EventSinkWebhook}}
+			},
+			expErr: lib.ErrNotValid,
+		},
+
+		"An unknown sink should fail.": {
+			sinks: func(dir string) []lib.EventSink {
+				return []lib.EventSink{{Type: "kafka"}}
+			},
+			expErr: lib.ErrNotValid,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+			assert := assert.New(t)
+			ctx := context.Background()
+
+			dir := t.TempDir()
+			client, err := lib.New(ctx, lib.Config{
+				DBPath:     filepath.Join(t.TempDir(), "test.db"),
+				DataDir:    t.TempDir(),
+				Engine:     lib.EngineFake,
+				Namespace:  "team-a",
+				EventSinks: test.sinks(dir),
+			})
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+				return
+			}
+			require.NoError(err)
+
+			opts := lib.CreateSandboxOpts{
+				Name:        "sb",
+				Engine:      lib.EngineFake,
+				Resources:   lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+				IfNotExists: true,
+			}
+			_, err = client.CreateSandbox(ctx, opts)
+			require.NoError(err)
+			// The existing sandbox is not created again.
+			_, err = client.CreateSandbox(ctx, opts)
+			require.NoError(err)
+			_, err = client.StartSandbox(ctx, "sb", nil)
+			require.NoError(err)
+			_, err = client.StopSandbox(ctx, "sb", nil)
+			require.NoError(err)
+			_, err = client.RemoveSandbox(ctx, "sb", false)
+			require.NoError(err)
+			// The events are sent in the background until the client is closed.
+			require.NoError(client.Close())
+
+			data, err := os.ReadFile(filepath.Join(dir, "events.jsonl"))
+			require.NoError(err)
+			var gotTypes []lib.EventType
+			for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+				var ev struct {
+					Type        lib.EventType `json:"type"`
+					SandboxName string        `json:"sandbox_name"`
+					Namespace   string        `json:"namespace"`
+				}
+				require.NoError(json.Unmarshal([]byte(line), &ev))
+				assert.Equal("sb", ev.SandboxName)
+				assert.Equal("team-a", ev.Namespace)
+				gotTypes = append(gotTypes, ev.Type)
+			}
+			assert.Equal(test.expTypes, gotTypes)
+		})
+	}
+}
+
+func TestSandboxWebhooks(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	type request struct {
+		eventType string
+		signature string
+		body      []byte
+	}
+	var mu sync.Mutex
+	var requests []request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, request{eventType: r.Header.Get("X-Sbx-Event"), signature: r.Header.Get("X-Sbx-Signature-256"), body: body})
+	}))
+	defer srv.Close()
+
+	cfg := lib.Config{
+		DBPath:  filepath.Join(t.TempDir(), "test.db"),
+		DataDir: t.TempDir(),
+		Engine:  lib.EngineFake,
+	}
+	client, err := lib.New(ctx, cfg)
+	require.NoError(err)
+
+	opts := lib.CreateSandboxOpts{
+		Name:      "sb",
+		Engine:    lib.EngineFake,
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+		Webhooks: []lib.Webhook{{
+			URL:    srv.URL,
+			Secret: "s3cret",
+			Events: []lib.EventType{lib.EventSandboxStarted, lib.EventSandboxStopped},
+		}},
+	}
+	_, err = client.CreateSandbox(ctx, opts)
+	require.NoError(err)
+	_, err = client.StartSandbox(ctx, "sb", nil)
+	require.NoError(err)
+	require.NoError(client.Close())
+
+	// The webhooks are stored with the sandbox, any client should notify them.
+	client, err = lib.New(ctx, cfg)
+	require.NoError(err)
+	_, err = client.StopSandbox(ctx, "sb", nil)
+	require.NoError(err)
+	_, err = client.RemoveSandbox(ctx, "sb", false)
+	require.NoError(err)
+	require.NoError(client.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(requests, 2)
+	for i, expType := range []string{"sandbox.started", "sandbox.stopped"} {
+		assert.Equal(expType, requests[i].eventType)
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write(requests[i].body)
+		assert.Equal("sha256="+hex.EncodeToString(mac.Sum(nil)), requests[i].signature)
+	}
+
+	// Invalid webhooks should be rejected.
+	client = newTestClient(t)
+	opts.Webhooks = []lib.Webhook{{URL: "hooks.example.com"}}
+	_, err = client.CreateSandbox(ctx, opts)
+	assert.ErrorIs(err, lib.ErrNotValid)
+}
+
+func TestAdmission(t *testing.T) {
+	tests := map[string]struct {
+		mode   lib.AdmissionMode
+		expErr error
+	}{
+		"Without admission a sandbox exceeding the host should be created.": {
+			mode: lib.AdmissionModeOff,
+		},
+
+		"In warn mode a sandbox exceeding the host should be created.": {
+			mode: lib.AdmissionModeWarn,
+		},
+
+		"In enforce mode a sandbox exceeding the host should be refused.": {
+			mode:   lib.AdmissionModeEnforce,
+			expErr: lib.ErrNotValid,
+		},
+
+		"An unknown mode should fail.": {
+			mode:   "strict",
+			expErr: lib.ErrNotValid,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			client, err := lib.New(ctx, lib.Config{
+				DBPath:    filepath.Join(t.TempDir(), "test.db"),
+				DataDir:   t.TempDir(),
+				Engine:    lib.EngineFake,
+				Admission: &lib.AdmissionConfig{Mode: test.mode},
+			})
+			if err == nil {
+				t.Cleanup(func() { _ = client.Close() })
+				_, err = client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+					Name:      "huge",
+					Engine:    lib.EngineFake,
+					Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 1 << 30},
+				})
+			}
+
+			if test.expErr != nil {
+				assert.ErrorIs(t, err, test.expErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSetMemoryTarget(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "balloon",
+		Engine:    lib.EngineFake,
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 1024, DiskGB: 5},
+	})
+	require.NoError(err)
+
+	// Not running.
+	_, err = client.SetMemoryTarget(ctx, "balloon", 256)
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = client.StartSandbox(ctx, "balloon", nil)
+	require.NoError(err)
+
+	sb, err := client.SetMemoryTarget(ctx, "balloon", 256)
+	require.NoError(err)
+	assert.Equal("balloon", sb.Name)
+
+	_, err = client.SetMemoryTarget(ctx, "balloon", 0)
+	assert.NoError(err)
+
+	_, err = client.SetMemoryTarget(ctx, "balloon", 2048)
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = client.SetMemoryTarget(ctx, "missing", 256)
+	assert.ErrorIs(err, lib.ErrNotFound)
+}
+
+func TestScaleSandbox(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "scale",
+		Engine:    lib.EngineFake,
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 1024, DiskGB: 5, MaxVCPUs: 4, MaxMemoryMB: 4096},
+	})
+	require.NoError(err)
+
+	// Not running.
+	_, err = client.ScaleSandbox(ctx, "scale", lib.Resources{VCPUs: 2})
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = client.StartSandbox(ctx, "scale", nil)
+	require.NoError(err)
+
+	sb, err := client.ScaleSandbox(ctx, "scale", lib.Resources{VCPUs: 2, MemoryMB: 2048})
+	require.NoError(err)
+	assert.Equal(lib.Resources{VCPUs: 2, MemoryMB: 2048, DiskGB: 5, MaxVCPUs: 4, MaxMemoryMB: 4096}, sb.Config.Resources)
+
+	got, err := client.GetSandbox(ctx, "scale")
+	require.NoError(err)
+	assert.Equal(sb.Config.Resources, got.Config.Resources)
+
+	// Above the ceilings.
+	_, err = client.ScaleSandbox(ctx, "scale", lib.Resources{MemoryMB: 8192})
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	// The disk can't be scaled.
+	_, err = client.ScaleSandbox(ctx, "scale", lib.Resources{DiskGB: 10})
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = client.ScaleSandbox(ctx, "missing", lib.Resources{VCPUs: 2})
+	assert.ErrorIs(err, lib.ErrNotFound)
+}
+
+func TestAnnotateSandbox(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "annotated",
+		Engine:    lib.EngineFake,
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	})
+	require.NoError(err)
+
+	sb, err := client.AnnotateSandbox(ctx, "annotated", lib.AnnotateSandboxOpts{
+		Set: map[string]string{"owner": "alice@example.com", "ticket": "https://issues.example.com/42"},
+	})
+	require.NoError(err)
+	assert.Equal(map[string]string{"owner": "alice@example.com", "ticket": "https://issues.example.com/42"}, sb.Annotations)
+
+	// The annotations should survive the sandbox state changes.
+	_, err = client.StartSandbox(ctx, "annotated", nil)
+	require.NoError(err)
+	_, err = client.AnnotateSandbox(ctx, "annotated", lib.AnnotateSandboxOpts{Remove: []string{"ticket"}})
+	require.NoError(err)
+	_, err = client.StopSandbox(ctx, "annotated", nil)
+	require.NoError(err)
+
+	sb, err = client.GetSandbox(ctx, "annotated")
+	require.NoError(err)
+	assert.Equal(map[string]string{"owner": "alice@example.com"}, sb.Annotations)
+
+	list, err := client.ListSandboxes(ctx, nil)
+	require.NoError(err)
+	require.Len(list, 1)
+	assert.Equal(map[string]string{"owner": "alice@example.com"}, list[0].Annotations)
+
+	_, err = client.AnnotateSandbox(ctx, "annotated", lib.AnnotateSandboxOpts{Set: map[string]string{"bad key": "x"}})
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = client.AnnotateSandbox(ctx, "missing", lib.AnnotateSandboxOpts{Set: map[string]string{"owner": "bob"}})
+	assert.ErrorIs(err, lib.ErrNotFound)
+}
+
+func TestIdlePolicy(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	eventsFile := filepath.Join(t.TempDir(), "events.jsonl")
+	client, err := lib.New(ctx, lib.Config{
+		DBPath:     filepath.Join(t.TempDir(), "test.db"),
+		DataDir:    t.TempDir(),
+		Engine:     lib.EngineFake,
+		EventSinks: []lib.EventSink{{Type: lib.EventSinkFile, Path: eventsFile, Events: []lib.EventType{lib.EventSandboxIdleStopped}}},
+	})
+	require.NoError(err)
+	defer client.Close()
+
+	for _, name := range []string{"idle", "busy", "no-policy"} {
+		_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+			Name:      name,
+			Engine:    lib.EngineFake,
+			Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+		})
+		require.NoError(err)
+		_, err = client.StartSandbox(ctx, name, nil)
+		require.NoError(err)
+	}
+
+	sb, err := client.SetIdlePolicy(ctx, "idle", &lib.IdlePolicy{Timeout: time.Nanosecond})
+	require.NoError(err)
+	assert.Equal(&lib.IdlePolicy{Timeout: time.Nanosecond, Action: lib.IdleActionStop}, sb.IdlePolicy)
+	_, err = client.SetIdlePolicy(ctx, "busy", &lib.IdlePolicy{Timeout: time.Hour})
+	require.NoError(err)
+
+	// The commands should be the sandbox activity.
+	_, err = client.Exec(ctx, "busy", []string{"true"}, nil)
+	require.NoError(err)
+	sb, err = client.GetSandbox(ctx, "busy")
+	require.NoError(err)
+	assert.NotNil(sb.LastActivityAt)
+	assert.Equal(&lib.IdlePolicy{Timeout: time.Hour, Action: lib.IdleActionStop}, sb.IdlePolicy)
+
+	// Only the sandboxes idle longer than their policy timeout should be stopped.
+	suspended, err := client.SuspendIdleSandboxes(ctx)
+	require.NoError(err)
+	require.Len(suspended, 1)
+	assert.Equal("idle", suspended[0].Name)
+	assert.Equal(lib.SandboxStatusStopped, suspended[0].Status)
+
+	for name, expStatus := range map[string]lib.SandboxStatus{"idle": lib.SandboxStatusStopped, "busy": lib.SandboxStatusRunning, "no-policy": lib.SandboxStatusRunning} {
+		sb, err := client.GetSandbox(ctx, name)
+		require.NoError(err)
+		assert.Equal(expStatus, sb.Status, name)
+	}
+
+	// The stopped sandboxes should not be checked again.
+	suspended, err = client.SuspendIdleSandboxes(ctx)
+	require.NoError(err)
+	assert.Empty(suspended)
+
+	// Removing the policy should keep the sandbox running.
+	sb, err = client.SetIdlePolicy(ctx, "busy", nil)
+	require.NoError(err)
+	assert.Nil(sb.IdlePolicy)
+
+	_, err = client.SetIdlePolicy(ctx, "busy", &lib.IdlePolicy{Timeout: time.Hour, Action: "pause"})
+	assert.ErrorIs(err, lib.ErrNotValid)
+	_, err = client.SetIdlePolicy(ctx, "busy", &lib.IdlePolicy{})
+	assert.ErrorIs(err, lib.ErrNotValid)
+	_, err = client.SetIdlePolicy(ctx, "missing", &lib.IdlePolicy{Timeout: time.Hour})
+	assert.ErrorIs(err, lib.ErrNotFound)
+
+	require.NoError(client.Close())
+	data, err := os.ReadFile(eventsFile)
+	require.NoError(err)
+	var ev struct {
+		Type        lib.EventType     `json:"type"`
+		SandboxName string            `json:"sandbox_name"`
+		Attributes  map[string]string `json:"attributes"`
+	}
+	require.NoError(json.Unmarshal(data, &ev))
+	assert.Equal(lib.EventSandboxIdleStopped, ev.Type)
+	assert.Equal("idle", ev.SandboxName)
+	assert.Equal("1ns", ev.Attributes["idle_timeout"])
+	assert.Equal("stop", ev.Attributes["action"])
+}
+
+func TestUsageReport(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"usage-1", "usage-2"} {
+		_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+			Name:      name,
+			Engine:    lib.EngineFake,
+			Resources: lib.Resources{VCPUs: 2, MemoryMB: 512, DiskGB: 5},
+		})
+		require.NoError(err)
+	}
+	_, err := client.AnnotateSandbox(ctx, "usage-1", lib.AnnotateSandboxOpts{Set: map[string]string{"team": "infra"}})
+	require.NoError(err)
+
+	_, err = client.StartSandbox(ctx, "usage-1", nil)
+	require.NoError(err)
+	_, err = client.StopSandbox(ctx, "usage-1", nil)
+	require.NoError(err)
+	_, err = client.RemoveSandbox(ctx, "usage-1", false)
+	require.NoError(err)
+
+	report, err := client.UsageReport(ctx, lib.UsageFilter{}, lib.TimeRange{})
+	require.NoError(err)
+	require.Len(report.Entries, 2)
+	assert.Equal("usage-1", report.Entries[0].Key)
+	assert.True(report.Entries[0].Removed)
+	assert.Greater(report.Entries[0].CPUSeconds, 0.0)
+	assert.Greater(report.Entries[0].DiskGBHours, 0.0)
+	assert.Equal("usage-2", report.Entries[1].Key)
+	assert.False(report.Entries[1].Removed)
+	assert.Zero(report.Entries[1].CPUSeconds)
+
+	// Removed sandboxes are grouped by the annotations they had.
+	report, err = client.UsageReport(ctx, lib.UsageFilter{GroupBy: "team", Annotations: map[string]string{"team": "infra"}}, lib.TimeRange{})
+	require.NoError(err)
+	require.Len(report.Entries, 1)
+	assert.Equal("infra", report.Entries[0].Key)
+	assert.Equal(1, report.Entries[0].Sandboxes)
+
+	_, err = client.UsageReport(ctx, lib.UsageFilter{}, lib.TimeRange{Since: time.Now().Add(time.Hour)})
+	assert.ErrorIs(err, lib.ErrNotValid)
+}
+
+func TestNamespaces(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	dataDir := t.TempDir()
+
+	newClient := func(namespace string) *lib.Client {
+		client, err := lib.New(ctx, lib.Config{DBPath: dbPath, DataDir: dataDir, Engine: lib.EngineFake, Namespace: namespace})
+		require.NoError(err)
+		t.Cleanup(func() { _ = client.Close() })
+		return client
+	}
+	teamA := newClient("team-a")
+	teamB := newClient("team-b")
+	admin := newClient(lib.AllNamespaces)
+
+	// The same name can be used in different namespaces.
+	opts := lib.CreateSandboxOpts{Name: "dev", Engine: lib.EngineFake, Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5}}
+	sbA, err := teamA.CreateSandbox(ctx, opts)
+	require.NoError(err)
+	assert.Equal("team-a", sbA.Namespace)
+	_, err = teamB.CreateSandbox(ctx, opts)
+	require.NoError(err)
+	_, err = teamA.CreateSandbox(ctx, opts)
+	assert.ErrorIs(err, lib.ErrAlreadyExists)
+
+	// The clients only see the sandboxes of their namespace.
+	sandboxes, err := teamA.ListSandboxes(ctx, nil)
+	require.NoError(err)
+	require.Len(sandboxes, 1)
+	assert.Equal(sbA.ID, sandboxes[0].ID)
+	_, err = teamB.GetSandbox(ctx, sbA.ID)
+	assert.ErrorIs(err, lib.ErrNotFound)
+
+	// The administration clients see all of them and need a namespace to create.
+	sandboxes, err = admin.ListSandboxes(ctx, nil)
+	require.NoError(err)
+	assert.Len(sandboxes, 2)
+	sandboxes, err = admin.ListSandboxes(ctx, &lib.ListSandboxesOpts{Namespace: "team-b"})
+	require.NoError(err)
+	require.Len(sandboxes, 1)
+	assert.Equal("team-b", sandboxes[0].Namespace)
+	_, err = admin.CreateSandbox(ctx, lib.CreateSandboxOpts{Name: "other", Engine: lib.EngineFake, Resources: opts.Resources})
+	assert.ErrorIs(err, lib.ErrNotValid)
+	_, err = admin.CreateSandbox(ctx, lib.CreateSandboxOpts{Name: "other", Engine: lib.EngineFake, Resources: opts.Resources, Namespace: "team-c"})
+	require.NoError(err)
+
+	namespaces, err := admin.ListNamespaces(ctx)
+	require.NoError(err)
+	assert.Equal([]lib.Namespace{
+		{Name: "team-a", Sandboxes: 1},
+		{Name: "team-b", Sandboxes: 1},
+		{Name: "team-c", Sandboxes: 1},
+	}, namespaces)
+	_, err = teamA.ListNamespaces(ctx)
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = lib.New(ctx, lib.Config{DBPath: dbPath, DataDir: dataDir, Namespace: "Team A"})
+	assert.ErrorIs(err, lib.ErrNotValid)
+}
+
+func TestSync(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "sync",
+		Engine:    lib.EngineFake,
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	})
+	require.NoError(err)
+
+	localDir := t.TempDir()
+	require.NoError(os.WriteFile(filepath.Join(localDir, "main.go"), []byte("package main"), 0644))
+
+	// Not running.
+	_, err = client.Sync(ctx, "sync", localDir, "/workspace", lib.SyncOpts{})
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = client.StartSandbox(ctx, "sync", nil)
+	require.NoError(err)
+
+	res, err := client.Sync(ctx, "sync", localDir, "/workspace", lib.SyncOpts{Delete: true, Exclude: []string{".git"}})
+	require.NoError(err)
+	assert.NotNil(res)
+
+	_, err = client.Sync(ctx, "sync", filepath.Join(localDir, "main.go"), "/workspace", lib.SyncOpts{})
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = client.Sync(ctx, "sync", localDir, "/workspace", lib.SyncOpts{Exclude: []string{"[a-"}})
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = client.Sync(ctx, "missing", localDir, "/workspace", lib.SyncOpts{})
+	assert.ErrorIs(err, lib.ErrNotFound)
+}
+
+func TestSyncWatch(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "sync-watch",
+		Engine:    lib.EngineFake,
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	})
+	require.NoError(err)
+
+	localDir := t.TempDir()
+
+	// Not running.
+	err = client.SyncWatch(ctx, "sync-watch", localDir, "/workspace", lib.SyncWatchOpts{})
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = client.StartSandbox(ctx, "sync-watch", nil)
+	require.NoError(err)
+
+	synced := make(chan struct{}, 10)
+	watchCtx, cancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() {
+		done <- client.SyncWatch(watchCtx, "sync-watch", localDir, "/workspace", lib.SyncWatchOpts{
+			Debounce: 20 * time.Millisecond,
+			OnSync: func(res *lib.SyncResult, err error) {
+				assert.NoError(err)
+				assert.NotNil(res)
+				synced <- struct{}{}
+			},
+		})
+	}()
+
+	for _, step := range []string{"initial sync", "change sync"} {
+		select {
+		case <-synced:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for %s", step)
+		}
+		require.NoError(os.WriteFile(filepath.Join(localDir, "main.go"), []byte(step), 0644))
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the watch to end")
+	}
+}
+
+func TestFileOperations(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "files",
+		Engine:    lib.EngineFake,
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	})
+	require.NoError(err)
+
+	// Not running.
+	_, err = client.ReadFile(ctx, "files", "/etc/hostname")
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = client.StartSandbox(ctx, "files", nil)
+	require.NoError(err)
+
+	require.NoError(client.WriteFile(ctx, "files", "/tmp/run.sh", []byte("#!/bin/sh"), 0755))
+
+	_, err = client.ReadFile(ctx, "files", "/tmp/run.sh")
+	require.NoError(err)
+
+	info, err := client.StatFile(ctx, "files", "/tmp/run.sh")
+	require.NoError(err)
+	assert.Equal("run.sh", info.Name)
+	assert.Equal("/tmp/run.sh", info.Path)
+
+	files, err := client.ListDir(ctx, "files", "/tmp")
+	require.NoError(err)
+	assert.Empty(files)
+
+	_, err = client.ReadFile(ctx, "files", "tmp/run.sh")
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	err = client.WriteFile(ctx, "files", "/tmp/d", nil, os.ModeDir|0755)
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = client.StatFile(ctx, "missing", "/tmp")
+	assert.ErrorIs(err, lib.ErrNotFound)
+}
+
+func TestClipboard(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "clip",
+		Engine:    lib.EngineFake,
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	})
+	require.NoError(err)
+
+	// Not running.
+	err = client.PushClipboard(ctx, "clip", []byte("hello"))
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = client.StartSandbox(ctx, "clip", nil)
+	require.NoError(err)
+
+	require.NoError(client.PushClipboard(ctx, "clip", []byte("hello")))
+	_, err = client.PullClipboard(ctx, "clip")
+	require.NoError(err)
+
+	file := filepath.Join(t.TempDir(), "notes.txt")
+	require.NoError(os.WriteFile(file, []byte("x"), 0644))
+	paths, err := client.DropFiles(ctx, "clip", file)
+	require.NoError(err)
+	assert.Equal([]string{"/tmp/sbx/drop/notes.txt"}, paths)
+
+	_, err = client.DropFiles(ctx, "clip", filepath.Join(t.TempDir(), "missing"))
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	paths, err = client.PickupFiles(ctx, "clip", t.TempDir())
+	require.NoError(err)
+	assert.Empty(paths)
+
+	_, err = client.PullClipboard(ctx, "missing")
+	assert.ErrorIs(err, lib.ErrNotFound)
+}
+
+func TestCompactDB(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	client, err := lib.New(ctx, lib.Config{
+		DBPath:               dbPath,
+		DataDir:              t.TempDir(),
+		Engine:               lib.EngineFake,
+		DBCheckpointInterval: 10 * time.Millisecond,
+	})
+	require.NoError(err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	for i := range 20 {
+		_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+			Name:      fmt.Sprintf("compact-%d", i),
+			Engine:    lib.EngineFake,
+			Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+		})
+		require.NoError(err)
+	}
+
+	// The periodic checkpoints truncate the write-ahead log.
+	assert.Eventually(func() bool {
+		info, err := os.Stat(dbPath + "-wal")
+		return err == nil && info.Size() == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	for i := range 20 {
+		_, err := client.RemoveSandbox(ctx, fmt.Sprintf("compact-%d", i), true)
+		require.NoError(err)
+	}
+
+	res, err := client.CompactDB(ctx)
+	require.NoError(err)
+	assert.Empty(res.IntegrityErrors)
+	assert.Positive(res.SizeAfter)
+	assert.LessOrEqual(res.SizeAfter, res.SizeBefore)
+
+	_, err = lib.New(ctx, lib.Config{DBPath: dbPath, DBCheckpointInterval: -time.Second})
+	assert.ErrorIs(err, lib.ErrNotValid)
+}
+
+func TestCreateAndStartSandbox(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	opts := lib.CreateSandboxOpts{
+		Name:      "create-start",
+		Engine:    lib.EngineFake,
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	}
+	sb, err := client.CreateAndStartSandbox(ctx, opts, nil)
+	require.NoError(err)
+	assert.Equal(lib.SandboxStatusRunning, sb.Status)
+
+	// An existing sandbox with the same spec is kept.
+	opts.IfNotExists = true
+	again, err := client.CreateAndStartSandbox(ctx, opts, nil)
+	require.NoError(err)
+	assert.Equal(sb.ID, again.ID)
+
+	opts.IfNotExists = false
+	_, err = client.CreateAndStartSandbox(ctx, opts, nil)
+	assert.ErrorIs(err, lib.ErrAlreadyExists)
+
+	// A failed start removes the created sandbox.
+	opts.Name = "create-start-fail"
+	_, err = client.CreateAndStartSandbox(ctx, opts, &lib.StartSandboxOpts{EgressPolicyName: "missing"})
+	assert.ErrorIs(err, lib.ErrNotFound)
+	_, err = client.GetSandbox(ctx, "create-start-fail")
+	assert.ErrorIs(err, lib.ErrNotFound)
+}
+
+func TestStartExec(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "start-exec",
+		Engine:    lib.EngineFake,
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	})
+	require.NoError(err)
+
+	// Not running.
+	_, err = client.StartExec(ctx, "start-exec", []string{"python3", "-i"}, nil)
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = client.StartSandbox(ctx, "start-exec", nil)
+	require.NoError(err)
+
+	h, err := client.StartExec(ctx, "start-exec", []string{"python3", "-i"}, &lib.ExecOpts{Caller: "repl"})
+	require.NoError(err)
+
+	res, err := h.Wait()
+	require.NoError(err)
+	assert.Equal(0, res.ExitCode)
+	<-h.Done()
+
+	// The input of a finished command is closed.
+	_, err = h.StdinPipe().Write([]byte("print(1)\n"))
+	assert.ErrorIs(err, io.ErrClosedPipe)
+	assert.NoError(h.CloseStdin())
+
+	_, err = client.StartExec(ctx, "start-exec", nil, nil)
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = client.StartExec(ctx, "start-exec", []string{"cat"}, &lib.ExecOpts{Stdin: strings.NewReader("x")})
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = client.StartExec(ctx, "missing", []string{"cat"}, nil)
+	assert.ErrorIs(err, lib.ErrNotFound)
+}
+
+func TestDNSEventsAndStats(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "dns",
+		Engine:    lib.EngineFake,
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	})
+	require.NoError(err)
+
+	// The fake engine doesn't have an egress DNS proxy.
+	events, err := client.DNSEvents(ctx, "dns", &lib.DNSEventsOpts{Domain: "github.com", Limit: 10})
+	require.NoError(err)
+	assert.Empty(events)
+
+	stats, err := client.DNSStats(ctx, "dns", nil)
+	require.NoError(err)
+	assert.Equal(&lib.DNSStats{Domains: []lib.DNSDomainStats{}}, stats)
+
+	_, err = client.DNSEvents(ctx, "dns", &lib.DNSEventsOpts{Limit: -1})
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = client.DNSEvents(ctx, "missing", nil)
+	assert.ErrorIs(err, lib.ErrNotFound)
+	_, err = client.DNSStats(ctx, "missing", nil)
+	assert.ErrorIs(err, lib.ErrNotFound)
+}
+
+func TestVerifyIntegrity(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"tamper", "plain"} {
+		_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+			Name:      name,
+			Engine:    lib.EngineFake,
+			Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+		})
+		require.NoError(err)
+	}
+
+	// The sandbox must be running.
+	_, err := client.VerifyIntegrity(ctx, "tamper")
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = client.StartSandbox(ctx, "tamper", &lib.StartSandboxOpts{IntegrityPaths: []string{"etc"}})
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = client.StartSandbox(ctx, "tamper", &lib.StartSandboxOpts{IntegrityPaths: []string{"/etc", "/usr/local/bin"}})
+	require.NoError(err)
+
+	// The fake engine guest has no files.
+	report, err := client.VerifyIntegrity(ctx, "tamper")
+	require.NoError(err)
+	assert.True(report.Intact())
+	assert.Equal("tamper", report.SandboxName)
+	assert.Equal([]string{"/etc", "/usr/local/bin"}, report.Paths)
+	assert.Equal([]lib.IntegrityChange{}, report.Changes)
+	assert.False(report.BaselineAt.IsZero())
+
+	// A sandbox started without integrity paths has no baseline.
+	_, err = client.StartSandbox(ctx, "plain", nil)
+	require.NoError(err)
+	_, err = client.VerifyIntegrity(ctx, "plain")
+	assert.ErrorIs(err, lib.ErrNotFound)
+
+	_, err = client.VerifyIntegrity(ctx, "missing")
+	assert.ErrorIs(err, lib.ErrNotFound)
+}
+
+func TestTop(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "top",
+		Engine:    lib.EngineFake,
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	})
+	require.NoError(err)
+
+	// The sandbox must be running.
+	_, err = client.Top(ctx, "top")
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = client.StartSandbox(ctx, "top", nil)
+	require.NoError(err)
+
+	// The fake engine guest has no processes.
+	table, err := client.Top(ctx, "top")
+	require.NoError(err)
+	assert.Equal("top", table.SandboxName)
+	assert.Equal([]lib.Process{}, table.Processes)
+	assert.False(table.CollectedAt.IsZero())
+
+	_, err = client.Top(ctx, "missing")
+	assert.ErrorIs(err, lib.ErrNotFound)
+}
+
+func TestConnections(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "conns",
+		Engine:    lib.EngineFake,
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	})
+	require.NoError(err)
+
+	// The sandbox must be running.
+	_, err = client.Connections(ctx, "conns")
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = client.StartSandbox(ctx, "conns", nil)
+	require.NoError(err)
+
+	conns, err := client.Connections(ctx, "conns")
+	require.NoError(err)
+	require.Len(conns, 1)
+	assert.Equal(lib.ConnectionDirectionEgress, conns[0].Direction)
+	assert.Equal("github.com", conns[0].Domain)
+	assert.Equal("tls", conns[0].Proxy)
+
+	_, err = client.Connections(ctx, "missing")
+	assert.ErrorIs(err, lib.ErrNotFound)
+}
+
+func TestCaptureTraffic(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "pcap",
+		Engine:    lib.EngineFake,
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	})
+	require.NoError(err)
+
+	// The sandbox must be running.
+	var buf bytes.Buffer
+	_, err = client.CaptureTraffic(ctx, "pcap", &buf, nil)
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = client.StartSandbox(ctx, "pcap", nil)
+	require.NoError(err)
+
+	_, err = client.CaptureTraffic(ctx, "pcap", &buf, &lib.CaptureOpts{Filter: "-w /tmp/x"})
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	// The fake engine has no network, the pcap stream only has the file header.
+	result, err := client.CaptureTraffic(ctx, "pcap", &buf, &lib.CaptureOpts{Filter: "tcp port 443", MaxPackets: 10})
+	require.NoError(err)
+	assert.Equal("eth0", result.Interface)
+	assert.Equal(0, result.Packets)
+	assert.Equal(int64(24), result.Bytes)
+	assert.Equal(24, buf.Len())
+
+	_, err = client.CaptureTraffic(ctx, "missing", &buf, nil)
+	assert.ErrorIs(err, lib.ErrNotFound)
+}
+
+func TestQuarantineSandbox(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	eventsFile := filepath.Join(t.TempDir(), "events.jsonl")
+	client, err := lib.New(ctx, lib.Config{
+		DBPath:     filepath.Join(t.TempDir(), "test.db"),
+		DataDir:    t.TempDir(),
+		Engine:     lib.EngineFake,
+		EventSinks: []lib.EventSink{{Type: lib.EventSinkFile, Path: eventsFile, Events: []lib.EventType{lib.EventSandboxQuarantined, lib.EventSandboxQuarantineReleased}}},
+	})
+	require.NoError(err)
+	defer client.Close()
+
+	_, err = client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "suspicious",
+		Engine:    lib.EngineFake,
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	})
+	require.NoError(err)
+	_, err = client.StartSandbox(ctx, "suspicious", nil)
+	require.NoError(err)
+
+	sb, err := client.QuarantineSandbox(ctx, "suspicious")
+	require.NoError(err)
+	require.NotNil(sb.QuarantinedAt)
+	assert.Equal(lib.SandboxStatusRunning, sb.Status)
+	quarantinedAt := *sb.QuarantinedAt
+
+	// Quarantining again should keep the first quarantine.
+	sb, err = client.QuarantineSandbox(ctx, "suspicious")
+	require.NoError(err)
+	assert.Equal(quarantinedAt, *sb.QuarantinedAt)
+
+	// The port forwards should be refused, the commands should keep working.
+	err = client.Forward(ctx, "suspicious", []lib.PortMapping{{LocalPort: 0, RemotePort: 8080}})
+	assert.ErrorIs(err, lib.ErrNotValid)
+	_, err = client.Exec(ctx, "suspicious", []string{"true"}, nil)
+	require.NoError(err)
+
+	// The quarantine should be kept across restarts.
+	_, err = client.StopSandbox(ctx, "suspicious", nil)
+	require.NoError(err)
+	_, err = client.StartSandbox(ctx, "suspicious", nil)
+	require.NoError(err)
+	sb, err = client.GetSandbox(ctx, "suspicious")
+	require.NoError(err)
+	assert.NotNil(sb.QuarantinedAt)
+
+	sb, err = client.ReleaseQuarantine(ctx, "suspicious")
+	require.NoError(err)
+	assert.Nil(sb.QuarantinedAt)
+
+	_, err = client.QuarantineSandbox(ctx, "missing")
+	assert.ErrorIs(err, lib.ErrNotFound)
+
+	require.NoError(client.Close())
+	data, err := os.ReadFile(eventsFile)
+	require.NoError(err)
+	var types []lib.EventType
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var ev struct {
+			Type        lib.EventType `json:"type"`
+			SandboxName string        `json:"sandbox_name"`
+		}
+		require.NoError(json.Unmarshal([]byte(line), &ev))
+		assert.Equal("suspicious", ev.SandboxName)
+		types = append(types, ev.Type)
+	}
+	assert.Equal([]lib.EventType{lib.EventSandboxQuarantined, lib.EventSandboxQuarantineReleased}, types)
+}
+
+func TestCollectDiagnostics(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+	tc := newTestClientWithDataDir(t)
+
+	_, err := tc.Client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "diag",
+		Engine:    lib.EngineFake,
+		UserData:  "#!/bin/sh\necho secret-provisioning\n",
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	})
+	require.NoError(err)
+
+	var buf bytes.Buffer
+	require.NoError(tc.Client.CollectDiagnostics(ctx, &buf, &lib.CollectDiagnosticsOpts{SandboxNameOrID: "diag"}))
+
+	gz, err := gzip.NewReader(&buf)
+	require.NoError(err)
+	tr := tar.NewReader(gz)
+	files := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(err)
+		data, err := io.ReadAll(tr)
+		require.NoError(err)
+		files[hdr.Name] = string(data)
+	}
+	assert.Contains(files, "sbx-diagnostics/info.json")
+	assert.Contains(files, "sbx-diagnostics/doctor.json")
+	assert.Contains(files["sbx-diagnostics/sandboxes.json"], `"Name": "diag"`)
+	assert.Contains(files, "sbx-diagnostics/sandbox/config.json")
+	assert.NotContains(files["sbx-diagnostics/sandbox/config.json"], "secret-provisioning")
+
+	err = tc.Client.CollectDiagnostics(ctx, io.Discard, &lib.CollectDiagnosticsOpts{SandboxNameOrID: "missing"})
+	assert.ErrorIs(err, lib.ErrNotFound)
+}
+
+func TestMigrateDataLayout(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	dataDir, newDir := t.TempDir(), t.TempDir()
+
+	newClient := func(namespace string) *lib.Client {
+		client, err := lib.New(ctx, lib.Config{DBPath: dbPath, DataDir: dataDir, Engine: lib.EngineFake, Namespace: namespace})
+		require.NoError(err)
+		t.Cleanup(func() { _ = client.Close() })
+		return client
+	}
+	teamA := newClient("team-a")
+	admin := newClient(lib.AllNamespaces)
+
+	// An installed image used by a sandbox with a VM directory.
+	imgDir := filepath.Join(dataDir, "images", "v0.1.0")
+	require.NoError(os.MkdirAll(imgDir, 0755))
+	require.NoError(os.WriteFile(filepath.Join(imgDir, "manifest.json"), []byte(`{"schema_version": 1, "version": "v0.1.0"}`), 0644))
+	require.NoError(os.WriteFile(filepath.Join(imgDir, "vmlinux"), []byte("kernel"), 0644))
+	sb, err := teamA.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:        "dev",
+		Engine:      lib.EngineFake,
+		Firecracker: &lib.FirecrackerConfig{RootFS: "/fake/rootfs.ext4", KernelImage: filepath.Join(imgDir, "vmlinux")},
+		Resources:   lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	})
+	require.NoError(err)
+	vmDir := filepath.Join(dataDir, "vms", sb.ID)
+	require.NoError(os.MkdirAll(vmDir, 0755))
+	require.NoError(os.WriteFile(filepath.Join(vmDir, "rootfs.ext4"), []byte("rootfs"), 0644))
+
+	_, err = teamA.MigrateDataLayout(ctx, lib.MigrateDataLayoutOpts{To: lib.DataLayout{VMsDir: filepath.Join(newDir, "vms")}})
+	assert.ErrorIs(err, lib.ErrNotValid)
+	_, err = admin.MigrateDataLayout(ctx, lib.MigrateDataLayoutOpts{To: lib.DataLayout{VMsDir: "relative/vms"}})
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	opts := lib.MigrateDataLayoutOpts{To: lib.DataLayout{VMsDir: filepath.Join(newDir, "vms"), ImagesDir: filepath.Join(newDir, "images")}}
+	expMoves := []lib.DataMove{
+		{Kind: lib.DataMoveKindVM, Name: "dev", From: vmDir, To: filepath.Join(newDir, "vms", sb.ID)},
+		{Kind: lib.DataMoveKindImage, Name: "v0.1.0", From: imgDir, To: filepath.Join(newDir, "images", "v0.1.0")},
+	}
+
+	// A dry run reports the moves without changing anything.
+	opts.DryRun = true
+	moves, err := admin.MigrateDataLayout(ctx, opts)
+	require.NoError(err)
+	assert.Equal(expMoves, moves)
+	assert.DirExists(vmDir)
+
+	opts.DryRun = false
+	moves, err = admin.MigrateDataLayout(ctx, opts)
+	require.NoError(err)
+	assert.Equal(expMoves, moves)
+	assert.NoDirExists(vmDir)
+	assert.FileExists(filepath.Join(newDir, "vms", sb.ID, "rootfs.ext4"))
+	assert.FileExists(filepath.Join(newDir, "images", "v0.1.0", "vmlinux"))
+
+	got, err := teamA.GetSandbox(ctx, "dev")
+	require.NoError(err)
+	assert.Equal(filepath.Join(newDir, "images", "v0.1.0", "vmlinux"), got.Config.Firecracker.KernelImage)
+	assert.Equal("/fake/rootfs.ext4", got.Config.Firecracker.RootFS)
+}
+
+func TestWatchSandboxes(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	client := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates, err := client.WatchSandboxes(ctx, nil)
+	require.NoError(err)
+
+	next := func() []lib.Sandbox {
+		select {
+		case sandboxes, ok := <-updates:
+			require.True(ok)
+			return sandboxes
+		case <-time.After(5 * time.Second):
+			require.FailNow("no sandboxes update")
+			return nil
+		}
+	}
+
+	// The current sandboxes are sent first.
+	assert.Empty(next())
+
+	// The changes are sent.
+	_, err = client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "watched",
+		Engine:    lib.EngineFake,
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	})
+	require.NoError(err)
+	sandboxes := next()
+	require.Len(sandboxes, 1)
+	assert.Equal("watched", sandboxes[0].Name)
+
+	// The channel is closed when the context is done.
+	cancel()
+	require.Eventually(func() bool {
+		select {
+		case _, ok := <-updates:
+			return !ok
+		default:
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestLogs(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "logs",
+		Engine:    lib.EngineFake,
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	})
+	require.NoError(err)
+
+	// The fake engine doesn't have processes writing logs.
+	logs, err := client.Logs(ctx, "logs", &lib.LogsOpts{Lines: 10})
+	require.NoError(err)
+	assert.Empty(logs)
+
+	_, err = client.Logs(ctx, "logs", &lib.LogsOpts{Lines: -1})
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = client.Logs(ctx, "missing", nil)
+	assert.ErrorIs(err, lib.ErrNotFound)
+}
+
+func TestEngineAPI(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	sb, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{
+		Name:      "api",
+		Engine:    lib.EngineFake,
+		Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5},
+	})
+	require.NoError(err)
+
+	// A stopped sandbox has no VMM.
+	_, err = client.EngineAPI(ctx, "api")
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = client.StartSandbox(ctx, "api", nil)
+	require.NoError(err)
+	rt, err := client.EngineAPI(ctx, "api")
+	require.NoError(err)
+	hc := &http.Client{Transport: rt}
+
+	// The API should be reachable.
+	res, err := hc.Get("http://firecracker/")
+	require.NoError(err)
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	require.NoError(err)
+	assert.Equal(http.StatusOK, res.StatusCode)
+	assert.Contains(string(body), sb.ID)
+
+	// The resources managed by sbx can't be changed.
+	req, err := http.NewRequest(http.MethodPatch, "http://firecracker/drives/rootfs", strings.NewReader(`{}`))
+	require.NoError(err)
+	_, err = hc.Do(req)
+	assert.ErrorIs(err, lib.ErrNotValid)
+
+	_, err = client.EngineAPI(ctx, "missing")
+	assert.ErrorIs(err, lib.ErrNotFound)
+}
+
+func TestBootPersistence(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	unitDir := t.TempDir()
+	client, err := lib.New(ctx, lib.Config{
+		DBPath:         dbPath,
+		DataDir:        t.TempDir(),
+		Engine:         lib.EngineFake,
+		SystemdUnitDir: unitDir,
+	})
+	require.NoError(err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	sb, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{Name: "boot", Engine: lib.EngineFake, Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5}})
+	require.NoError(err)
+
+	// Enabling should install the unit starting the sandbox with the client database.
+	unit, err := client.EnableBootPersistence(ctx, "boot", lib.BootPersistenceOpts{Binary: "/usr/local/bin/sbx", Restart: lib.BootRestartPolicyNo})
+	require.NoError(err)
+	assert.Equal(&lib.BootUnit{
+		Name:        "sbx-" + sb.ID + ".service",
+		Path:        filepath.Join(unitDir, "sbx-"+sb.ID+".service"),
+		SandboxID:   sb.ID,
+		SandboxName: "boot",
+		Restart:     lib.BootRestartPolicyNo,
+	}, unit)
+	data, err := os.ReadFile(unit.Path)
+	require.NoError(err)
+	assert.Contains(string(data), "ExecStart=/usr/local/bin/sbx --db-path "+dbPath)
+	assert.Contains(string(data), "--namespace default start "+sb.ID+"\n")
+	_, err = os.Lstat(filepath.Join(unitDir, "multi-user.target.wants", unit.Name))
+	assert.NoError(err)
+
+	// Invalid options and missing sandboxes should fail.
+	_, err = client.EnableBootPersistence(ctx, "boot", lib.BootPersistenceOpts{Binary: "/usr/local/bin/sbx", Restart: "always"})
+	assert.ErrorIs(err, lib.ErrNotValid)
+	_, err = client.EnableBootPersistence(ctx, "missing", lib.BootPersistenceOpts{Binary: "/usr/local/bin/sbx"})
+	assert.ErrorIs(err, lib.ErrNotFound)
+
+	// Disabling should remove the unit, also when missing.
+	require.NoError(client.DisableBootPersistence(ctx, "boot"))
+	require.NoError(client.DisableBootPersistence(ctx, "boot"))
+	assert.NoFileExists(unit.Path)
+}
+
+func TestRecoverSandboxes(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	cfg := lib.Config{
+		DBPath:  filepath.Join(t.TempDir(), "test.db"),
+		DataDir: t.TempDir(),
+		Engine:  lib.EngineFake,
+	}
+	client, err := lib.New(ctx, cfg)
+	require.NoError(err)
+
+	res := lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5}
+	sb, err := client.CreateSandbox(ctx, lib.CreateSandboxOpts{Name: "persistent", Engine: lib.EngineFake, Resources: res, Persistent: true})
+	require.NoError(err)
+	assert.True(sb.Persistent)
+	_, err = client.CreateSandbox(ctx, lib.CreateSandboxOpts{Name: "ephemeral", Engine: lib.EngineFake, Resources: res})
+	require.NoError(err)
+	_, err = client.CreateSandbox(ctx, lib.CreateSandboxOpts{Name: "stopped", Engine: lib.EngineFake, Resources: res})
+	require.NoError(err)
+	sb, err = client.SetPersistent(ctx, "stopped", true)
+	require.NoError(err)
+	assert.True(sb.Persistent)
+	for _, name := range []string{"persistent", "ephemeral"} {
+		_, err := client.StartSandbox(ctx, name, nil)
+		require.NoError(err)
+	}
+	require.NoError(client.Close())
+
+	// A new client doesn't have the VMs of the fake engine, like after a host reboot.
+	client, err = lib.New(ctx, cfg)
+	require.NoError(err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	results, err := client.RecoverSandboxes(ctx)
+	require.NoError(err)
+	actions := map[string]lib.RecoveryAction{}
+	for _, r := range results {
+		actions[r.Name] = r.Action
+	}
+	assert.Equal(map[string]lib.RecoveryAction{
+		"persistent": lib.RecoveryActionRestarted,
+		"ephemeral":  lib.RecoveryActionStopped,
+	}, actions)
+
+	for name, expStatus := range map[string]lib.SandboxStatus{
+		"persistent": lib.SandboxStatusRunning,
+		"ephemeral":  lib.SandboxStatusStopped,
+		"stopped":    lib.SandboxStatusStopped,
+	} {
+		got, err := client.GetSandbox(ctx, name)
+		require.NoError(err)
+		assert.Equal(expStatus, got.Status, name)
+	}
+
+	_, err = client.SetPersistent(ctx, "missing", true)
+	assert.ErrorIs(err, lib.ErrNotFound)
+}
+
+func TestHooks(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx := context.Background()
+
+	dataDir := t.TempDir()
+	hooksDir := filepath.Join(dataDir, "hooks", "post-remove")
+	require.NoError(os.MkdirAll(hooksDir, 0o755))
+	removed := filepath.Join(dataDir, "removed")
+	require.NoError(os.WriteFile(filepath.Join(hooksDir, "10-unregister"), []byte("#!/bin/sh\necho \"$SBX_SANDBOX_NAME\" > "+removed+"\n"), 0o755))
+
+	var got []string
+	record := func(_ context.Context, event lib.HookEvent, sb lib.Sandbox) error {
+		got = append(got, string(event)+":"+sb.Name+":"+string(sb.Status))
+		return nil
+	}
+	client, err := lib.New(ctx, lib.Config{
+		DBPath:  filepath.Join(t.TempDir(), "test.db"),
+		DataDir: dataDir,
+		Engine:  lib.EngineFake,
+		Hooks: []lib.Hook{
+			{Event: lib.HookPreCreate, Func: record},
+			{Event: lib.HookPostStart, Func: record},
+			{Event: lib.HookPreStop, Func: record},
+			{Event: lib.HookPostRemove, Func: record},
+			{Event: lib.HookPreCreate, Func: func(_ context.Context, _ lib.HookEvent, sb lib.Sandbox) error {
+				if sb.Name == "denied" {
+					return errors.New("name not allowed")
+				}
+				return nil
+			}},
+		},
+	})
+	require.NoError(err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	opts := lib.CreateSandboxOpts{Name: "hooked", Engine: lib.EngineFake, Resources: lib.Resources{VCPUs: 1, MemoryMB: 512, DiskGB: 5}}
+	_, err = client.CreateSandbox(ctx, opts)
+	require.NoError(err)
+	_, err = client.StartSandbox(ctx, "hooked", nil)
+	require.NoError(err)
+	_, err = client.StopSandbox(ctx, "hooked", nil)
+	require.NoError(err)
+	_, err = client.RemoveSandbox(ctx, "hooked", false)
+	require.NoError(err)
+
+	assert.Equal([]string{"pre-create:hooked:pending", "post-start:hooked:running", "pre-stop:hooked:running", "post-remove:hooked:stopped"}, got)
+	gotRemoved, err := os.ReadFile(removed)
+	require.NoError(err)
+	assert.Equal("hooked\n", string(gotRemoved))
+
+	// A failed pre-create hook aborts the creation.
+	opts.Name = "denied"
+	_, err = client.CreateSandbox(ctx, opts)
+	assert.ErrorContains(err, "name not allowed")
+	_, err = client.GetSandbox(ctx, "denied")
+	assert.ErrorIs(err, lib.ErrNotFound)
+
+	// Invalid hooks fail the client creation.
+	_, err = lib.New(ctx, lib.Config{
+		DBPath:  filepath.Join(t.TempDir(), "test.db"),
+		DataDir: t.TempDir(),
+		Engine:  lib.EngineFake,
+		Hooks:   []lib.Hook{{Event: "post-create", Command: []string{"true"}}},
+	})
+	assert.ErrorIs(err, lib.ErrNotValid)
 }
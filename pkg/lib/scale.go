@@ -0,0 +1,63 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/app/scale"
+	"github.com/slok/sbx/internal/model"
+)
+
+// ScaleSandbox changes the VCPUs and memory of a running sandbox, zero fields keep
+// the current ones.
+//
+// Firecracker can't hot-plug VCPUs or memory, the sandboxes boot with their
+// [Resources] ceilings (MaxVCPUs and MaxMemoryMB) and scale inside them: the
+// guest VCPUs are onlined or offlined and the memory balloon deflated or
+// inflated. Scaling resets the memory target (see [Client.SetMemoryTarget]).
+// The disk and the ceilings can't be scaled, recreate the sandbox to change them.
+// The usage accounting uses the resources of the sandbox when it stops.
+//
+// Returns [ErrNotFound] if the sandbox does not exist, or [ErrNotValid] if the
+// sandbox is not running, the resources are above the ceilings or the disk or
+// the ceilings change.
+func (c *Client) ScaleSandbox(ctx context.Context, nameOrID string, resources Resources) (*Sandbox, error) {
+	sb, err := c.getInternalSandbox(ctx, nameOrID)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	current := sb.Config.Resources
+	if (resources.DiskGB != 0 && resources.DiskGB != current.DiskGB) ||
+		(resources.MaxVCPUs != 0 && resources.MaxVCPUs != current.BootVCPUs()) ||
+		(resources.MaxMemoryMB != 0 && resources.MaxMemoryMB != current.BootMemoryMB()) {
+		return nil, mapError(fmt.Errorf("the disk and the resource ceilings of a sandbox can't be scaled: %w", model.ErrNotValid), ResourceKindSandbox, nameOrID)
+	}
+
+	eng, err := c.newEngine(sb.Config)
+	if err != nil {
+		return nil, mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, nameOrID)
+	}
+
+	svc, err := scale.NewService(scale.ServiceConfig{
+		Engine:     eng,
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	result, err := svc.Run(ctx, scale.Request{
+		NameOrID: nameOrID,
+		VCPUs:    resources.VCPUs,
+		MemoryMB: resources.MemoryMB,
+	})
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+	c.updateReservation(*result)
+
+	out := fromInternalSandbox(*result)
+	return &out, nil
+}
@@ -3,10 +3,12 @@ package lib
 import (
 	"context"
 	"fmt"
-	"os"
-	"path/filepath"
 
 	"github.com/slok/sbx/internal/app/snapshotcreate"
+	"github.com/slok/sbx/internal/app/snapshotdiff"
+	"github.com/slok/sbx/internal/app/snapshotfetch"
+	"github.com/slok/sbx/internal/app/snapshotpush"
+	"github.com/slok/sbx/internal/model"
 )
 
 // CreateImageFromSandboxOpts configures snapshot image creation.
@@ -16,11 +18,20 @@ type CreateImageFromSandboxOpts struct {
 	// ImageName is an optional name for the snapshot image.
 	// If empty, a name is auto-generated from the sandbox name and timestamp.
 	ImageName string
+	// Progress receives the snapshot steps (optional).
+	Progress ProgressReporter
+	// Push uploads the snapshot image to the [Config].SnapshotStore once created,
+	// see [Client.PushSnapshot].
+	Push bool
 }
 
 // CreateImageFromSandbox creates a local snapshot image from a sandbox.
 //
-// The sandbox must be in [SandboxStatusStopped] state.
+// The sandbox must be in [SandboxStatusStopped] or [SandboxStatusRunning] state.
+// Running sandboxes are snapshotted without stopping them: they are paused briefly
+// while their disk, memory and VM state are captured, and the image records the
+// memory in [SnapshotInfo].MemoryFile. Check [EngineCapabilities].LiveSnapshots
+// to know if the engine supports it.
 // The resulting image can be used with [CreateSandboxOpts].FromImage to create
 // new sandboxes.
 //
@@ -28,9 +39,20 @@ type CreateImageFromSandboxOpts struct {
 // timestamp. Use opts.ImageName to specify a custom name.
 //
 // Returns the image name, or [ErrNotFound] if the sandbox does not exist,
-// [ErrNotValid] if the sandbox is running, or [ErrAlreadyExists] if the
-// image name is taken.
+// [ErrNotValid] if the sandbox is in another state, or [ErrAlreadyExists] if the
+// image name is taken. With opts.Push, a push failure returns the name of the
+// created local image with the error.
 func (c *Client) CreateImageFromSandbox(ctx context.Context, nameOrID string, opts *CreateImageFromSandboxOpts) (string, error) {
+	sb, err := c.getInternalSandbox(ctx, nameOrID)
+	if err != nil {
+		return "", mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	eng, err := c.newEngine(sb.Config)
+	if err != nil {
+		return "", mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, nameOrID)
+	}
+
 	imgMgr, err := c.newLocalImageManager()
 	if err != nil {
 		return "", fmt.Errorf("could not create image manager: %w", err)
@@ -41,39 +63,164 @@ func (c *Client) CreateImageFromSandbox(ctx context.Context, nameOrID string, op
 		return "", fmt.Errorf("could not create snapshot creator: %w", err)
 	}
 
-	// Determine data dir.
-	dataDir := c.dataDir
-	if dataDir == "" {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return "", fmt.Errorf("could not get user home dir: %w", err)
-		}
-		dataDir = filepath.Join(home, ".sbx")
-	}
-
 	svc, err := snapshotcreate.NewService(snapshotcreate.ServiceConfig{
 		ImageManager:    imgMgr,
 		SnapshotCreator: snapCrt,
 		Repository:      c.repo,
+		Engine:          eng,
 		Logger:          c.logger,
-		DataDir:         dataDir,
+		VMsDir:          c.vmsDir,
 	})
 	if err != nil {
 		return "", fmt.Errorf("could not create service: %w", err)
 	}
 
-	var imgName string
+	req := snapshotcreate.Request{
+		NameOrID: nameOrID,
+	}
 	if opts != nil {
-		imgName = opts.ImageName
+		req.ImageName = opts.ImageName
+		req.Progress = toInternalProgress(opts.Progress)
 	}
 
-	result, err := svc.Run(ctx, snapshotcreate.Request{
-		NameOrID:  nameOrID,
-		ImageName: imgName,
-	})
+	result, err := svc.Run(ctx, req)
 	if err != nil {
-		return "", mapError(err)
+		return "", mapError(err, ResourceKindSandbox, nameOrID)
+	}
+	c.emitEvent(model.EventTypeSnapshotCreated, *sb, imageEventAttrs(result))
+
+	if opts != nil && opts.Push {
+		if _, err := c.PushSnapshot(ctx, result); err != nil {
+			return result, err
+		}
 	}
 
 	return result, nil
 }
+
+// PushSnapshot uploads an installed snapshot image to the [Config].SnapshotStore,
+// so other hosts can create sandboxes from it (see [Client.FetchSnapshot]). The
+// image is streamed to the storage without writing its bundle to the disk, the big
+// ones with a multipart upload. An existing snapshot with the same name is replaced.
+//
+// Returns [ErrNotValid] if there is no snapshot store configured, or [ErrNotFound]
+// if the image is not installed.
+func (c *Client) PushSnapshot(ctx context.Context, name string) (*RemoteSnapshot, error) {
+	store, err := c.newSnapshotStore()
+	if err != nil {
+		return nil, mapError(fmt.Errorf("could not create snapshot store: %w", err), ResourceKindImage, name)
+	}
+	bundler, err := c.newImageBundler()
+	if err != nil {
+		return nil, fmt.Errorf("could not create image bundler: %w", err)
+	}
+
+	svc, err := snapshotpush.NewService(snapshotpush.ServiceConfig{
+		Bundler: bundler,
+		Store:   store,
+		Logger:  c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	snap, err := svc.Run(ctx, snapshotpush.Request{Name: name})
+	if err != nil {
+		return nil, mapError(err, ResourceKindImage, name)
+	}
+
+	return fromInternalRemoteSnapshot(*snap), nil
+}
+
+// FetchSnapshotOpts configures the snapshot fetch.
+//
+// Pass nil to [Client.FetchSnapshot] for defaults.
+type FetchSnapshotOpts struct {
+	// Force replaces the image if already installed.
+	Force bool
+}
+
+// FetchSnapshot installs a snapshot image from the [Config].SnapshotStore, its
+// artifacts are verified against the manifest checksums. [Client.CreateSandbox]
+// fetches the missing [CreateSandboxOpts].FromImage images automatically.
+//
+// Pass nil opts for defaults. Returns [ErrNotValid] if there is no snapshot store
+// configured, [ErrNotFound] if the snapshot is not stored, or [ErrAlreadyExists]
+// if the image is already installed, use opts.Force to replace it.
+func (c *Client) FetchSnapshot(ctx context.Context, name string, opts *FetchSnapshotOpts) error {
+	store, err := c.newSnapshotStore()
+	if err != nil {
+		return mapError(fmt.Errorf("could not create snapshot store: %w", err), ResourceKindImage, name)
+	}
+	mgr, err := c.newLocalImageManager()
+	if err != nil {
+		return fmt.Errorf("could not create image manager: %w", err)
+	}
+	bundler, err := c.newImageBundler()
+	if err != nil {
+		return fmt.Errorf("could not create image bundler: %w", err)
+	}
+
+	svc, err := snapshotfetch.NewService(snapshotfetch.ServiceConfig{
+		ImageManager: mgr,
+		Bundler:      bundler,
+		Store:        store,
+		Logger:       c.logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	req := snapshotfetch.Request{Name: name}
+	if opts != nil {
+		req.Force = opts.Force
+	}
+	if err := svc.Run(ctx, req); err != nil {
+		return mapError(err, ResourceKindImage, name)
+	}
+
+	return nil
+}
+
+// DiffSnapshot reports the rootfs files changed between snapshot images, to audit
+// what happened in a sandbox (e.g. what an agent changed).
+//
+// With an empty snapB, the changes of snapA are reported against its base: the
+// parent snapshot or the image its sandbox was created from. Otherwise the
+// changes from snapA to snapB are reported, any installed image can be compared.
+//
+// The rootfs images are loop mounted read-only, so diffing requires root.
+//
+// Returns [ErrNotFound] if an image is not installed, or [ErrNotValid] if snapB
+// is empty and snapA has no base image.
+func (c *Client) DiffSnapshot(ctx context.Context, snapA, snapB string) (*SnapshotDiff, error) {
+	mgr, err := c.newLocalImageManager()
+	if err != nil {
+		return nil, fmt.Errorf("could not create image manager: %w", err)
+	}
+
+	differ, err := c.newImageDiffer()
+	if err != nil {
+		return nil, fmt.Errorf("could not create image differ: %w", err)
+	}
+
+	svc, err := snapshotdiff.NewService(snapshotdiff.ServiceConfig{
+		Manager: mgr,
+		Differ:  differ,
+		Logger:  c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	req := snapshotdiff.Request{Snapshot: snapA}
+	if snapB != "" {
+		req = snapshotdiff.Request{Snapshot: snapB, Against: snapA}
+	}
+	diff, err := svc.Run(ctx, req)
+	if err != nil {
+		return nil, mapError(err, ResourceKindImage, req.Snapshot)
+	}
+
+	return fromInternalImageDiff(*diff), nil
+}
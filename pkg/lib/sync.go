@@ -0,0 +1,113 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/app/filesync"
+	"github.com/slok/sbx/internal/model"
+)
+
+// Sync makes remoteDir in a running sandbox a copy of the host localDir,
+// transferring only the new and changed files. It is meant for iterative
+// edit-and-run loops where copying the whole directory each time is wasteful.
+//
+// Files with the same size and modification time are considered unchanged. When
+// only the modification time differs the SHA-256 of both copies is compared, so
+// touched files (e.g. after a git checkout) are not uploaded again. Symlinks are
+// not synced. See [SyncOpts] to delete the extra sandbox files and exclude paths.
+//
+// Returns [ErrNotFound] if the sandbox does not exist, or [ErrNotValid] if the
+// sandbox is not running, localDir is not a directory or an exclude pattern is
+// malformed.
+func (c *Client) Sync(ctx context.Context, nameOrID string, localDir, remoteDir string, opts SyncOpts) (*SyncResult, error) {
+	sb, err := c.getInternalSandbox(ctx, nameOrID)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	eng, err := c.newEngine(sb.Config)
+	if err != nil {
+		return nil, mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, nameOrID)
+	}
+
+	svc, err := filesync.NewService(filesync.ServiceConfig{
+		Engine:     eng,
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	result, err := svc.Run(ctx, filesync.Request{
+		NameOrID:  nameOrID,
+		LocalDir:  localDir,
+		RemoteDir: remoteDir,
+		Opts:      toInternalSyncOpts(opts),
+	})
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	out := fromInternalSyncResult(*result)
+	return &out, nil
+}
+
+// SyncWatch syncs localDir into remoteDir like [Client.Sync] and keeps syncing it
+// on each local change until the context is cancelled, for live-reload development
+// against services running in the sandbox. Changes are watched with the OS file
+// notifications and debounced, the excluded paths of [SyncOpts] don't trigger syncs.
+//
+// The failed syncs after the first one (e.g. the sandbox is restarting) are
+// reported to [SyncWatchOpts].OnSync and retried on the next change.
+//
+// Returns nil on context cancellation, [ErrNotFound] if the sandbox does not
+// exist, or [ErrNotValid] if the sandbox is not running, localDir is not a
+// directory or an exclude pattern is malformed.
+func (c *Client) SyncWatch(ctx context.Context, nameOrID string, localDir, remoteDir string, opts SyncWatchOpts) error {
+	sb, err := c.getInternalSandbox(ctx, nameOrID)
+	if err != nil {
+		return mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	eng, err := c.newEngine(sb.Config)
+	if err != nil {
+		return mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, nameOrID)
+	}
+
+	svc, err := filesync.NewService(filesync.ServiceConfig{
+		Engine:     eng,
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	req := filesync.WatchRequest{
+		Request: filesync.Request{
+			NameOrID:  nameOrID,
+			LocalDir:  localDir,
+			RemoteDir: remoteDir,
+			Opts:      toInternalSyncOpts(opts.Sync),
+		},
+		Debounce: opts.Debounce,
+	}
+	if opts.OnSync != nil {
+		req.OnSync = func(res *model.SyncResult, err error) {
+			if err != nil {
+				opts.OnSync(nil, mapError(err, ResourceKindSandbox, nameOrID))
+				return
+			}
+			out := fromInternalSyncResult(*res)
+			opts.OnSync(&out, nil)
+		}
+	}
+
+	if err := svc.Watch(ctx, req); err != nil {
+		return mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	return nil
+}
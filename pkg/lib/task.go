@@ -0,0 +1,102 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+
+	appexec "github.com/slok/sbx/internal/app/exec"
+	"github.com/slok/sbx/internal/app/tasklist"
+	"github.com/slok/sbx/internal/app/taskregister"
+	"github.com/slok/sbx/internal/app/taskrm"
+)
+
+// RegisterTask registers a task of a sandbox, or a global task when nameOrID is
+// empty, replacing the one with the same name. The sandbox tasks are removed with
+// the sandbox.
+//
+// Returns [ErrNotFound] if the sandbox does not exist or [ErrNotValid] if the task
+// is not valid.
+func (c *Client) RegisterTask(ctx context.Context, nameOrID string, task Task) (*Task, error) {
+	svc, err := taskregister.NewService(taskregister.ServiceConfig{
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	t, err := svc.Run(ctx, taskregister.Request{
+		NameOrID: nameOrID,
+		Task:     toInternalTask(task),
+	})
+	if err != nil {
+		return nil, mapError(err, ResourceKindTask, task.Name)
+	}
+
+	out := fromInternalTask(*t)
+	return &out, nil
+}
+
+// ListTasks returns the global tasks when nameOrID is empty, or the tasks available
+// to a sandbox (its tasks and the global ones it doesn't override), sorted by name.
+//
+// Returns [ErrNotFound] if the sandbox does not exist.
+func (c *Client) ListTasks(ctx context.Context, nameOrID string) ([]Task, error) {
+	svc, err := tasklist.NewService(tasklist.ServiceConfig{
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	tasks, err := svc.Run(ctx, tasklist.Request{NameOrID: nameOrID})
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	out := make([]Task, 0, len(tasks))
+	for _, t := range tasks {
+		out = append(out, fromInternalTask(t))
+	}
+	return out, nil
+}
+
+// RemoveTask removes a task of a sandbox, or a global task when nameOrID is empty.
+//
+// Returns [ErrNotFound] if the sandbox or the task do not exist.
+func (c *Client) RemoveTask(ctx context.Context, nameOrID, name string) error {
+	svc, err := taskrm.NewService(taskrm.ServiceConfig{
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	if err := svc.Run(ctx, taskrm.Request{NameOrID: nameOrID, Name: name}); err != nil {
+		return mapError(err, ResourceKindTask, name)
+	}
+
+	return nil
+}
+
+// RunTask runs a task in a running sandbox: the task of the sandbox or, when it
+// doesn't have one with that name, the global one. It behaves like [Client.Exec]
+// with the task command and defaults, the opts override and extend them.
+//
+// A run that exceeds the timeout is killed and returns [ErrorCodeTimeout].
+//
+// Returns [ErrNotFound] if the sandbox or the task do not exist, or [ErrNotValid]
+// if the sandbox is not running.
+func (c *Client) RunTask(ctx context.Context, nameOrID, task string, opts *RunTaskOpts) (*ExecResult, error) {
+	req := appexec.Request{NameOrID: nameOrID, Task: task}
+	var execOpts *ExecOpts
+	if opts != nil {
+		req.Command = opts.Args
+		req.Timeout = opts.Timeout
+		execOpts = &opts.ExecOpts
+	}
+
+	return c.exec(ctx, req, execOpts)
+}
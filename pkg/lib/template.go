@@ -0,0 +1,151 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/slok/sbx/internal/app/templatecreate"
+	"github.com/slok/sbx/internal/app/templateget"
+	"github.com/slok/sbx/internal/app/templatelist"
+	"github.com/slok/sbx/internal/app/templaterm"
+)
+
+// CreateTemplate stores a sandbox template, the sandboxes are created from it with
+// [Client.CreateSandboxFromTemplate]. The templates are shared by all the
+// namespaces.
+//
+// Returns [ErrAlreadyExists] if a template with the same name exists or
+// [ErrNotValid] if the template is not valid.
+func (c *Client) CreateTemplate(ctx context.Context, template SandboxTemplate) (*SandboxTemplate, error) {
+	svc, err := templatecreate.NewService(templatecreate.ServiceConfig{
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	t, err := svc.Run(ctx, templatecreate.Request{Template: toInternalSandboxTemplate(template)})
+	if err != nil {
+		return nil, mapError(err, ResourceKindTemplate, template.Name)
+	}
+
+	out := fromInternalSandboxTemplate(*t)
+	return &out, nil
+}
+
+// GetTemplate returns a sandbox template.
+//
+// Returns [ErrNotFound] if the template does not exist.
+func (c *Client) GetTemplate(ctx context.Context, name string) (*SandboxTemplate, error) {
+	svc, err := templateget.NewService(templateget.ServiceConfig{
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	t, err := svc.Run(ctx, templateget.Request{Name: name})
+	if err != nil {
+		return nil, mapError(err, ResourceKindTemplate, name)
+	}
+
+	out := fromInternalSandboxTemplate(*t)
+	return &out, nil
+}
+
+// ListTemplates returns the sandbox templates sorted by name.
+func (c *Client) ListTemplates(ctx context.Context) ([]SandboxTemplate, error) {
+	svc, err := templatelist.NewService(templatelist.ServiceConfig{
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	templates, err := svc.Run(ctx)
+	if err != nil {
+		return nil, mapError(err, "", "")
+	}
+
+	out := make([]SandboxTemplate, 0, len(templates))
+	for _, t := range templates {
+		out = append(out, fromInternalSandboxTemplate(t))
+	}
+	return out, nil
+}
+
+// DeleteTemplate removes a sandbox template. The sandboxes created from it keep
+// their spec.
+//
+// Returns [ErrNotFound] if the template does not exist.
+func (c *Client) DeleteTemplate(ctx context.Context, name string) error {
+	svc, err := templaterm.NewService(templaterm.ServiceConfig{
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create service: %w", err)
+	}
+
+	if err := svc.Run(ctx, templaterm.Request{Name: name}); err != nil {
+		return mapError(err, ResourceKindTemplate, name)
+	}
+
+	return nil
+}
+
+// CreateSandboxFromTemplate creates a sandbox with the spec of a template (engine,
+// image, profile, resources, user data, ports and exec profile). With
+// [CreateSandboxFromTemplateOpts].Start it's started with the template environment
+// and egress as a single operation, like [Client.CreateAndStartSandbox]. The
+// template environment and egress only apply to that start, the next starts use
+// their [StartSandboxOpts].
+//
+// Returns [ErrNotFound] if the template does not exist, and the errors of
+// [Client.CreateSandbox] and [Client.StartSandbox].
+func (c *Client) CreateSandboxFromTemplate(ctx context.Context, template string, opts CreateSandboxFromTemplateOpts) (*Sandbox, error) {
+	t, err := c.GetTemplate(ctx, template)
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.Start && len(opts.Env) > 0 {
+		return nil, mapError(fmt.Errorf("the environment requires starting the sandbox: %w", ErrNotValid), ResourceKindSandbox, opts.Name)
+	}
+
+	createOpts := CreateSandboxOpts{
+		Name:        opts.Name,
+		Engine:      t.Engine,
+		Resources:   t.Resources,
+		FromImage:   t.FromImage,
+		UserData:    t.UserData,
+		Ports:       t.Ports,
+		ExecProfile: t.ExecProfile,
+		Profile:     t.Profile,
+		IfNotExists: opts.IfNotExists,
+		Progress:    opts.Progress,
+		Namespace:   opts.Namespace,
+	}
+	if !opts.Start {
+		return c.CreateSandbox(ctx, createOpts)
+	}
+
+	env := maps.Clone(t.Env)
+	if len(opts.Env) > 0 {
+		if env == nil {
+			env = map[string]string{}
+		}
+		maps.Copy(env, opts.Env)
+	}
+
+	return c.CreateAndStartSandbox(ctx, createOpts, &StartSandboxOpts{
+		Env:              env,
+		Egress:           t.Egress,
+		EgressPolicyName: t.EgressPolicyName,
+		Progress:         opts.Progress,
+	})
+}
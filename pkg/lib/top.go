@@ -0,0 +1,45 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/app/top"
+)
+
+// Top returns the process table of a running sandbox guest with the CPU and memory
+// used by each process, read from the guest procfs with a probe executed in it (the
+// probe processes are not listed). The CPU usage is the average since each process
+// started, call it periodically and compare the [Process].CPUTime for the usage in
+// an interval.
+//
+// Returns [ErrNotFound] if the sandbox does not exist and [ErrNotValid] if it is
+// not running.
+func (c *Client) Top(ctx context.Context, nameOrID string) (*ProcessTable, error) {
+	sb, err := c.getInternalSandbox(ctx, nameOrID)
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	eng, err := c.newEngine(sb.Config)
+	if err != nil {
+		return nil, mapError(fmt.Errorf("could not create engine: %w", err), ResourceKindSandbox, nameOrID)
+	}
+
+	svc, err := top.NewService(top.ServiceConfig{
+		Engine:     eng,
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	table, err := svc.Run(ctx, top.Request{NameOrID: sb.ID})
+	if err != nil {
+		return nil, mapError(err, ResourceKindSandbox, nameOrID)
+	}
+
+	out := fromInternalProcessTable(*table)
+	return &out, nil
+}
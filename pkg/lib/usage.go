@@ -0,0 +1,40 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slok/sbx/internal/app/usage"
+)
+
+// UsageReport returns the resources used by the sandboxes in the time range,
+// including the removed sandboxes and the current usage of the existing ones. The
+// usage is clipped to the time range.
+//
+// Removed sandboxes are filtered and grouped by the annotations they had when
+// removed, existing ones by their current annotations.
+//
+// Returns [ErrNotValid] if the time range or the name pattern are not valid.
+func (c *Client) UsageReport(ctx context.Context, filter UsageFilter, timeRange TimeRange) (*UsageReport, error) {
+	svc, err := usage.NewService(usage.ServiceConfig{
+		Repository: c.repo,
+		Logger:     c.logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create service: %w", err)
+	}
+
+	report, err := svc.Run(ctx, usage.Request{
+		Since:       timeRange.Since,
+		Until:       timeRange.Until,
+		NamePattern: filter.NamePattern,
+		Annotations: filter.Annotations,
+		GroupBy:     filter.GroupBy,
+	})
+	if err != nil {
+		return nil, mapError(err, "", "")
+	}
+
+	result := fromInternalUsageReport(*report)
+	return &result, nil
+}
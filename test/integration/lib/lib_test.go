@@ -63,7 +63,7 @@ func TestSDKSandboxLifecycle(t *testing.T) {
 	assert.Equal(t, sdklib.SandboxStatusRunning, got.Status)
 
 	// Stop.
-	stopped, err := client.StopSandbox(ctx, name)
+	stopped, err := client.StopSandbox(ctx, name, nil)
 	require.NoError(t, err)
 	assert.Equal(t, sdklib.SandboxStatusStopped, stopped.Status)
 
@@ -256,7 +256,7 @@ func TestSDKCopy(t *testing.T) {
 	require.NoError(t, os.WriteFile(srcPath, []byte("sdk-copy-test"), 0644))
 
 	// CopyTo sandbox.
-	err = client.CopyTo(ctx, name, srcPath, "/tmp/sdk-test.txt")
+	err = client.CopyTo(ctx, name, srcPath, "/tmp/sdk-test.txt", nil)
 	require.NoError(t, err)
 
 	// Verify inside sandbox.
@@ -269,7 +269,7 @@ func TestSDKCopy(t *testing.T) {
 
 	// CopyFrom sandbox.
 	dstPath := filepath.Join(tmpDir, "sdk-test-from.txt")
-	err = client.CopyFrom(ctx, name, "/tmp/sdk-test.txt", dstPath)
+	err = client.CopyFrom(ctx, name, "/tmp/sdk-test.txt", dstPath, nil)
 	require.NoError(t, err)
 
 	// Verify on host.
@@ -357,7 +357,7 @@ func TestSDKSnapshotLifecycle(t *testing.T) {
 	require.NoError(t, err)
 
 	// Stop (required for snapshot).
-	_, err = client.StopSandbox(ctx, srcName)
+	_, err = client.StopSandbox(ctx, srcName, nil)
 	require.NoError(t, err)
 
 	// Create snapshot image.
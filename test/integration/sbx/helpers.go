@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/slok/sbx/test/integration/testutils"
 )
@@ -127,6 +128,11 @@ func RunRm(ctx context.Context, config Config, dbPath, name string) (stdout, std
 	return RunSBXCmd(ctx, config, dbPath, fmt.Sprintf("rm --force %s", name))
 }
 
+// RunWait waits for a sandbox to reach a condition.
+func RunWait(ctx context.Context, config Config, dbPath, name, condition string, timeout time.Duration) (stdout, stderr []byte, err error) {
+	return RunSBXCmd(ctx, config, dbPath, fmt.Sprintf("wait %s --for %s --timeout %s", name, condition, timeout))
+}
+
 // RunList lists sandboxes in JSON format.
 func RunList(ctx context.Context, config Config, dbPath string) (stdout, stderr []byte, err error) {
 	return RunSBXCmd(ctx, config, dbPath, "list --format json")
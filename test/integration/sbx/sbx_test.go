@@ -77,21 +77,13 @@ func findSandboxInList(items []listItem, name string) *listItem {
 	return nil
 }
 
-// waitForRunning polls the sandbox status until it's "running" or the timeout expires.
+// waitForRunning waits for the sandbox to be running using `sbx wait`.
 func waitForRunning(ctx context.Context, t *testing.T, config intsbx.Config, dbPath, name string, timeout time.Duration) {
 	t.Helper()
-	deadline := time.Now().Add(timeout)
-	for time.Now().Before(deadline) {
-		out, _, err := intsbx.RunSBXCmd(ctx, config, dbPath, fmt.Sprintf("status %s --format json", name))
-		if err == nil {
-			var s statusOutput
-			if json.Unmarshal(out, &s) == nil && s.Status == "running" {
-				return
-			}
-		}
-		time.Sleep(500 * time.Millisecond)
+	_, stderr, err := intsbx.RunWait(ctx, config, dbPath, name, "running", timeout)
+	if err != nil {
+		t.Fatalf("sandbox %s did not reach running state within %s: %s", name, timeout, stderr)
 	}
-	t.Fatalf("sandbox %s did not reach running state within %s", name, timeout)
 }
 
 func TestSandboxLifecycle(t *testing.T) {